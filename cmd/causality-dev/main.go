@@ -0,0 +1,568 @@
+// Command causality-dev runs the HTTP gateway, reaction engine and warehouse
+// sink in one process for laptop development and demos. The components share
+// one NATS client and the warehouse writes Parquet files to a local
+// directory (S3_BACKEND=fs, the default here) instead of S3/MinIO.
+//
+// It needs NATS (or EMBEDDED_NATS=true in a binary built with -tags
+// embeddednats) and PostgreSQL with the causality_server and reaction_engine
+// schemas. Compaction, Delta Lake, the forecast job, the device registry, FX
+// rate normalization and the audit log are not run.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/caarlos0/env/v10"
+
+	"github.com/SebastienMelki/causality/internal/admingraphql"
+	"github.com/SebastienMelki/causality/internal/auth"
+	pgdb "github.com/SebastienMelki/causality/internal/db"
+	"github.com/SebastienMelki/causality/internal/dedup"
+	"github.com/SebastienMelki/causality/internal/dlq"
+	"github.com/SebastienMelki/causality/internal/gateway"
+	"github.com/SebastienMelki/causality/internal/nats"
+	"github.com/SebastienMelki/causality/internal/observability"
+	"github.com/SebastienMelki/causality/internal/outbox"
+	"github.com/SebastienMelki/causality/internal/profiles"
+	"github.com/SebastienMelki/causality/internal/reaction"
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+// Consumer names of the embedded services, matching
+// nats.DefaultConsumerConfigs and nats.PriorityConsumerConfigs.
+const (
+	warehouseConsumerName        = "warehouse-sink"
+	reactionConsumerName         = "analysis-engine"
+	reactionPriorityConsumerName = "analysis-engine-priority"
+)
+
+// Config holds all causality-dev configuration. Component settings use the
+// same environment variables as the standalone services, except the reaction
+// engine database, which is configured with REACTION_DATABASE_* because
+// DATABASE_* configures the gateway database.
+type Config struct {
+	// LogLevel is the log level (debug, info, warn, error).
+	LogLevel string `env:"LOG_LEVEL" envDefault:"info"`
+
+	// LogFormat is the log format (json, text).
+	LogFormat string `env:"LOG_FORMAT" envDefault:"text"`
+
+	// MetricsAddr is the address of the metrics server, which also serves
+	// the reaction engine admin API.
+	MetricsAddr string `env:"METRICS_ADDR" envDefault:":9091"`
+
+	// HTTP gateway configuration.
+	Gateway gateway.Config `envPrefix:""`
+
+	// NATS configuration.
+	NATS nats.Config `envPrefix:""`
+
+	// Embedded NATS server configuration.
+	EmbeddedNATS nats.EmbeddedConfig `envPrefix:""`
+
+	// Database is the gateway database (API keys).
+	Database pgdb.Config `envPrefix:"DATABASE_"`
+
+	// Auth configuration (signed request window).
+	Auth auth.Config `envPrefix:""`
+
+	// Dedup configuration.
+	Dedup dedup.Config `envPrefix:""`
+
+	// DLQ configuration.
+	DLQ dlq.Config `envPrefix:""`
+
+	// Reaction engine configuration. Its Database is replaced by
+	// ReactionDatabase. PAYLOAD_ENCRYPTION_PROVIDER defaults to local.
+	Reaction reaction.Config `envPrefix:""`
+
+	// ReactionDatabase is the reaction engine database.
+	ReactionDatabase db.Config `envPrefix:"REACTION_DATABASE_"`
+
+	// Warehouse configuration. S3_BACKEND defaults to fs.
+	Warehouse warehouse.Config `envPrefix:""`
+
+	// Admin GraphQL API on the gateway.
+	GraphQL admingraphql.Config `envPrefix:""`
+
+	// Runtime diagnostics (pprof, expvar, SIGQUIT goroutine dump).
+	Debug observability.DebugConfig `envPrefix:""`
+}
+
+func main() {
+	if err := run(); err != nil {
+		slog.Error("fatal error", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	// Load configuration from environment
+	var cfg Config
+	if err := env.Parse(&cfg); err != nil {
+		return err
+	}
+	cfg.Reaction.Database = cfg.ReactionDatabase
+	if _, ok := os.LookupEnv("S3_BACKEND"); !ok {
+		cfg.Warehouse.S3.Backend = warehouse.BackendFS
+	}
+	if _, ok := os.LookupEnv("PAYLOAD_ENCRYPTION_PROVIDER"); !ok {
+		cfg.Reaction.PayloadEncryption.Provider = reaction.PayloadKeyProviderLocal
+	}
+	cfg.NATS.Name = "causality-dev"
+
+	// Setup logger
+	logger := setupLogger(cfg.LogLevel, cfg.LogFormat)
+	slog.SetDefault(logger)
+
+	logger.Info("starting causality dev",
+		"log_level", cfg.LogLevel,
+		"http_addr", cfg.Gateway.Addr,
+		"metrics_addr", cfg.MetricsAddr,
+		"s3_backend", cfg.Warehouse.S3.Backend,
+		"embedded_nats", cfg.EmbeddedNATS.Enabled,
+	)
+
+	// Create context with cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Setup signal handling
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	observability.DumpGoroutinesOnSignal(ctx, cfg.Debug, logger)
+
+	// --- Observability ---
+	obs, err := observability.New("causality-dev")
+	if err != nil {
+		return fmt.Errorf("failed to create observability module: %w", err)
+	}
+	defer func() {
+		if shutErr := obs.Shutdown(context.Background()); shutErr != nil {
+			logger.Error("observability shutdown error", "error", shutErr)
+		}
+	}()
+
+	metrics, err := observability.NewMetrics(obs.Meter())
+	if err != nil {
+		return fmt.Errorf("failed to create metrics: %w", err)
+	}
+
+	// --- NATS (shared by all components) ---
+	if cfg.EmbeddedNATS.Enabled {
+		embedded, err := nats.StartEmbeddedServer(cfg.EmbeddedNATS, logger)
+		if err != nil {
+			return err
+		}
+		// Deferred before the client so it shuts down after the drain
+		defer embedded.Shutdown()
+		cfg.NATS.URL = embedded.ClientURL()
+	}
+
+	natsClient, err := nats.NewClient(ctx, cfg.NATS, logger)
+	if err != nil {
+		return err
+	}
+	defer natsClient.Close()
+
+	streamMgr := nats.NewStreamManager(natsClient.JetStream(), cfg.NATS.Stream, logger)
+	stream, err := streamMgr.EnsureStream(ctx)
+	if err != nil {
+		return err
+	}
+	if err := streamMgr.EnsureConsumers(ctx, stream, nats.DefaultConsumerConfigs()); err != nil {
+		return err
+	}
+	if _, err := streamMgr.EnsureDLQStream(ctx); err != nil {
+		return err
+	}
+	derivedStream, err := streamMgr.EnsureDerivedStream(ctx)
+	if err != nil {
+		return err
+	}
+	derivedConsumerConfigs := nats.DerivedConsumerConfigs()
+	if cfg.Reaction.Chain.Enabled {
+		derivedConsumerConfigs = append(derivedConsumerConfigs, cfg.Reaction.Chain.ConsumerConfig())
+	}
+	if err := streamMgr.EnsureConsumers(ctx, derivedStream, derivedConsumerConfigs); err != nil {
+		return err
+	}
+	if cfg.NATS.Stream.PriorityEnabled {
+		priorityStream, err := streamMgr.PriorityStream(ctx)
+		if err != nil {
+			return err
+		}
+		if err := streamMgr.EnsureConsumers(ctx, priorityStream, nats.PriorityConsumerConfigs()); err != nil {
+			return err
+		}
+	}
+	if cfg.Gateway.PublishProbe.Enabled {
+		if _, err := streamMgr.EnsureProbeStream(ctx); err != nil {
+			return err
+		}
+	}
+
+	// --- Gateway ---
+	gatewayPool, err := pgdb.Open(ctx, cfg.Database, logger)
+	if err != nil {
+		return err
+	}
+	defer gatewayPool.Close()
+
+	if err := gatewayPool.Migrate(ctx, auth.Migrations, profiles.Migrations, outbox.Migrations); err != nil {
+		return err
+	}
+	gatewayDB := gatewayPool.DB
+
+	authModule := auth.New(gatewayDB, cfg.Auth, logger)
+
+	// Reaction engine database, opened here so the gateway's admin GraphQL
+	// API can read from it
+	dbClient, err := db.NewClient(ctx, cfg.Reaction.Database, logger)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = dbClient.Close() }()
+
+	if err := dbClient.Migrate(ctx, db.Migrations, outbox.Migrations); err != nil {
+		return err
+	}
+
+	var graphqlModule *admingraphql.Module
+	if cfg.GraphQL.Enabled {
+		graphqlModule, err = admingraphql.New(authModule, dbClient, cfg.GraphQL, logger)
+		if err != nil {
+			return err
+		}
+	}
+
+	dedupModule := dedup.New(cfg.Dedup, metrics, logger)
+	dedupModule.Start(ctx)
+
+	publisher := nats.NewPublisher(natsClient.JetStream(), cfg.NATS.Stream.Name, logger)
+	if err := publisher.SetDurability(cfg.NATS.PublishDurability, cfg.NATS.AppPublishDurability); err != nil {
+		return err
+	}
+	publisher.SetPriorityRouting(cfg.NATS.Stream.PriorityEnabled)
+	publisher.SetPriorityStreamName(cfg.NATS.Stream.PriorityStreamName)
+
+	server, err := gateway.NewServer(cfg.Gateway, natsClient, publisher, logger, &gateway.ServerOpts{
+		AuthMiddleware: authModule.AuthMiddleware(),
+		MetricsHandler: obs.MetricsHandler(),
+		Metrics:        metrics,
+		Dedup:          dedupModule,
+		Database:       gatewayPool,
+		AdminRouteRegistrar: func(mux *http.ServeMux) {
+			authModule.RegisterAdminRoutes(mux)
+			if graphqlModule != nil {
+				graphqlModule.RegisterRoutes(mux)
+			}
+		},
+		DebugRouteRegistrar: func(mux *http.ServeMux) {
+			observability.RegisterDebugRoutes(mux, cfg.Debug)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// --- Reaction engine ---
+	dlqModule := dlq.New(
+		natsClient.JetStream(),
+		natsClient.Conn(),
+		cfg.NATS.Stream.Name,
+		[]string{reactionConsumerName},
+		cfg.DLQ,
+		metrics,
+		logger,
+	)
+	if err := dlqModule.Start(ctx); err != nil {
+		return err
+	}
+
+	ruleRepo := db.NewRuleRepository(dbClient)
+	webhookRepo := db.NewWebhookRepository(dbClient)
+	deliveryRepo := db.NewDeliveryRepository(dbClient)
+	anomalyConfigRepo := db.NewAnomalyConfigRepository(dbClient)
+
+	payloadCipher, err := reaction.NewPayloadCipherFromConfig(ctx, cfg.Reaction.PayloadEncryption)
+	if err != nil {
+		return err
+	}
+
+	engine := reaction.NewEngine(
+		ruleRepo,
+		webhookRepo,
+		deliveryRepo,
+		natsClient.JetStream(),
+		cfg.Reaction.Engine,
+		cfg.Reaction.Dispatcher,
+		payloadCipher,
+		logger,
+	)
+	engine.SetMeter(obs.Meter())
+	engine.SetDerivedStream(derivedStream)
+	engine.SetHistory(db.NewHistoryRepository(dbClient))
+
+	// Dispatch new webhook deliveries through the delivery stream instead
+	// of polling Postgres for them (DISPATCHER_QUEUE=nats)
+	deliveryQueue, deliveryConsumer, err := reaction.SetupDeliveryQueue(ctx, natsClient.JetStream(), streamMgr, cfg.Reaction.Dispatcher, logger)
+	if err != nil {
+		return err
+	}
+	if deliveryQueue != nil {
+		engine.SetDeliveryQueue(deliveryQueue)
+	}
+	if cfg.Reaction.Users.Enabled {
+		// Profiles live in the gateway database here
+		engine.SetUserLookup(profiles.NewReader(gatewayDB), cfg.Reaction.Users)
+	}
+	if cfg.Reaction.Engine.RuleChangeListen {
+		ruleChanges, listenErr := dbClient.ListenRuleChanges(ctx)
+		if listenErr != nil {
+			logger.Warn("rule change notifications unavailable, using interval refresh only", "error", listenErr)
+		} else {
+			engine.SetRuleChanges(ruleChanges)
+		}
+	}
+	if err := engine.Start(ctx); err != nil {
+		return err
+	}
+
+	dispatcher := reaction.NewDispatcher(
+		deliveryRepo,
+		webhookRepo,
+		cfg.Reaction.Dispatcher,
+		payloadCipher,
+		logger,
+	)
+	dispatcher.SetMetrics(metrics)
+	if deliveryConsumer != nil {
+		dispatcher.SetQueue(deliveryConsumer)
+	}
+	dispatcher.Start(ctx)
+
+	anomalyDetector := reaction.NewAnomalyDetector(
+		anomalyConfigRepo,
+		natsClient.JetStream(),
+		cfg.Reaction.Anomaly,
+		logger,
+	)
+	if err := anomalyDetector.Start(ctx); err != nil {
+		return err
+	}
+
+	reactionConsumer := reaction.NewConsumer(
+		natsClient.JetStream(),
+		engine,
+		anomalyDetector,
+		reactionConsumerName,
+		cfg.NATS.Stream.Name,
+		cfg.Reaction.Consumer,
+		cfg.Reaction.ShutdownTimeout,
+		logger,
+		metrics,
+	)
+	if cfg.Reaction.Consumer.TrackProcessed {
+		reactionConsumer.SetProcessedTracker(db.NewProcessedRepository(dbClient))
+	}
+	reactionConsumer.SetSkipPriority(cfg.NATS.Stream.PriorityEnabled)
+	if err := reactionConsumer.Start(ctx); err != nil {
+		return err
+	}
+	var reactionPriorityConsumer *reaction.Consumer
+	if cfg.NATS.Stream.PriorityEnabled {
+		reactionPriorityConsumer = reaction.NewConsumer(
+			natsClient.JetStream(),
+			engine,
+			anomalyDetector,
+			reactionPriorityConsumerName,
+			cfg.NATS.Stream.PriorityStreamName,
+			cfg.Reaction.Consumer,
+			cfg.Reaction.ShutdownTimeout,
+			logger,
+			metrics,
+		)
+		if cfg.Reaction.Consumer.TrackProcessed {
+			reactionPriorityConsumer.SetProcessedTracker(db.NewProcessedRepository(dbClient))
+		}
+		if err := reactionPriorityConsumer.Start(ctx); err != nil {
+			return err
+		}
+	}
+	var chainConsumer *reaction.ChainConsumer
+	if cfg.Reaction.Chain.Enabled {
+		chainConsumer = reaction.NewChainConsumer(natsClient.JetStream(), engine, cfg.NATS.Stream.DerivedStreamName, cfg.Reaction.Chain, logger)
+		if err := chainConsumer.Start(ctx); err != nil {
+			return err
+		}
+	}
+
+	// --- Warehouse sink ---
+	s3Client, err := warehouse.NewS3Client(ctx, cfg.Warehouse.S3, logger)
+	if err != nil {
+		return err
+	}
+	if err := s3Client.EnsureBucket(ctx); err != nil {
+		return err
+	}
+
+	warehouseConsumer := warehouse.NewConsumer(
+		natsClient.JetStream(),
+		cfg.Warehouse,
+		s3Client,
+		nil,
+		warehouseConsumerName,
+		cfg.NATS.Stream.Name,
+		logger,
+		metrics,
+	)
+
+	// Promote frequent custom event property keys to dedicated columns
+	if cfg.Warehouse.Promotion.Enabled {
+		promoter := warehouse.NewColumnPromoter(s3Client.RawClient(), cfg.Warehouse.S3, cfg.Warehouse.Promotion, logger)
+		if err := promoter.Load(ctx); err != nil {
+			return err
+		}
+		warehouseConsumer.SetColumnPromoter(promoter)
+	}
+
+	if err := warehouseConsumer.Start(ctx); err != nil {
+		return err
+	}
+
+	// Expire old anomaly events and webhook deliveries, archiving them to
+	// the warehouse bucket first when RETENTION_ARCHIVE is set
+	retention := reaction.NewRetention(anomalyConfigRepo, deliveryRepo, cfg.Reaction.Retention, cfg.Reaction.Anomaly, logger)
+	if cfg.Reaction.Retention.Archive {
+		retention.SetArchive(reaction.NewArchive(s3Client.RawClient(), cfg.Warehouse.S3, cfg.Reaction.Retention.ArchivePrefix))
+	}
+	retention.Start(ctx)
+
+	// --- Metrics and reaction admin server ---
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", obs.MetricsHandler())
+	observability.RegisterDebugRoutes(metricsMux, cfg.Debug)
+	metricsMux.Handle("/debug/metrics-summary", metrics.SummaryHandler())
+	metricsMux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+	reaction.NewRuleHandler(ruleRepo, engine, logger).RegisterRoutes(metricsMux)
+	resourceSyncer := reaction.NewResourceSyncer(ruleRepo, webhookRepo, anomalyConfigRepo)
+	reaction.NewResourceHandler(resourceSyncer, engine, logger).RegisterRoutes(metricsMux)
+	reaction.NewSubjectHandler(streamMgr, logger).RegisterRoutes(metricsMux)
+
+	metricsServer := &http.Server{
+		Addr:    cfg.MetricsAddr,
+		Handler: metricsMux,
+	}
+	go func() {
+		logger.Info("starting metrics server", "addr", cfg.MetricsAddr)
+		if srvErr := metricsServer.ListenAndServe(); srvErr != nil && srvErr != http.ErrServerClosed {
+			logger.Error("metrics server error", "error", srvErr)
+		}
+	}()
+
+	// Start gateway in goroutine
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Start()
+	}()
+
+	logger.Info("causality dev started",
+		"http_addr", cfg.Gateway.Addr,
+		"metrics_addr", cfg.MetricsAddr,
+		"s3_backend", cfg.Warehouse.S3.Backend,
+	)
+
+	// Wait for shutdown signal or error
+	select {
+	case sig := <-sigCh:
+		logger.Info("received shutdown signal", "signal", sig)
+	case err := <-errCh:
+		if err != nil {
+			logger.Error("server error", "error", err)
+		}
+	}
+
+	// Graceful shutdown: stop ingestion first, then the consumers so the
+	// warehouse flushes what the gateway published.
+	logger.Info("initiating graceful shutdown")
+	cancel()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Warehouse.ShutdownTimeout)
+	defer shutdownCancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("server shutdown error", "error", err)
+	}
+	dedupModule.Stop()
+
+	if err := reactionConsumer.Stop(shutdownCtx); err != nil {
+		logger.Error("reaction consumer stop error", "error", err)
+	}
+	if reactionPriorityConsumer != nil {
+		if err := reactionPriorityConsumer.Stop(shutdownCtx); err != nil {
+			logger.Error("reaction priority consumer stop error", "error", err)
+		}
+	}
+	if chainConsumer != nil {
+		chainConsumer.Stop()
+	}
+	retention.Stop()
+	anomalyDetector.Stop()
+	dispatcher.Stop()
+	engine.Stop()
+	dlqModule.Stop()
+
+	if err := warehouseConsumer.Stop(shutdownCtx); err != nil {
+		logger.Error("warehouse consumer stop error", "error", err)
+	}
+
+	if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("metrics server shutdown error", "error", err)
+	}
+
+	if err := natsClient.Drain(); err != nil {
+		logger.Error("NATS drain error", "error", err)
+	}
+
+	logger.Info("causality dev stopped")
+	return nil
+}
+
+// setupLogger creates a logger based on configuration.
+func setupLogger(level, format string) *slog.Logger {
+	var logLevel slog.Level
+	switch level {
+	case "debug":
+		logLevel = slog.LevelDebug
+	case "warn":
+		logLevel = slog.LevelWarn
+	case "error":
+		logLevel = slog.LevelError
+	default:
+		logLevel = slog.LevelInfo
+	}
+
+	opts := &slog.HandlerOptions{
+		Level: logLevel,
+	}
+
+	var handler slog.Handler
+	if format == "text" {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	}
+
+	return slog.New(handler)
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// anomaliesCommand manages anomaly detection configs in the reaction
+// database.
+func anomaliesCommand(c *cli) *cobra.Command {
+	return group("anomalies", "manage anomaly detection configs",
+		anomaliesListCommand(c),
+		anomaliesGetCommand(c),
+		anomaliesCreateCommand(c),
+		anomaliesUpdateCommand(c),
+		anomaliesDeleteCommand(c),
+		anomaliesSetEnabledCommand(c, true),
+		anomaliesSetEnabledCommand(c, false),
+	)
+}
+
+// anomalyConfigRepository opens the anomaly config repository.
+func (c *cli) anomalyConfigRepository(ctx context.Context) (*db.AnomalyConfigRepository, error) {
+	client, err := c.reactionDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.NewAnomalyConfigRepository(client), nil
+}
+
+func anomaliesListCommand(c *cli) *cobra.Command {
+	var limit, offset int
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list anomaly configs",
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+			repo, err := c.anomalyConfigRepository(ctx)
+			if err != nil {
+				return err
+			}
+			configs, err := repo.List(ctx, limit, offset)
+			if err != nil {
+				return fmt.Errorf("failed to list anomaly configs: %w", err)
+			}
+
+			rows := make([][]string, len(configs))
+			for i, config := range configs {
+				rows[i] = []string{
+					config.ID, config.Name, deref(config.AppID), deref(config.EventCategory), deref(config.EventType),
+					string(config.DetectionType), strconv.FormatBool(config.Enabled), strconv.Itoa(config.CooldownSeconds),
+					string(config.Severity),
+				}
+			}
+			return printTable(c.out, []string{"ID", "NAME", "APP", "CATEGORY", "TYPE", "DETECTION", "ENABLED", "COOLDOWN_S", "SEVERITY"}, rows)
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 100, "maximum number of configs")
+	cmd.Flags().IntVar(&offset, "offset", 0, "number of configs to skip")
+	return cmd
+}
+
+func anomaliesGetCommand(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get CONFIG_ID",
+		Short: "print an anomaly config",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			repo, err := c.anomalyConfigRepository(ctx)
+			if err != nil {
+				return err
+			}
+			config, err := repo.GetByID(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			return printJSON(c.out, config)
+		},
+	}
+}
+
+func anomaliesCreateCommand(c *cli) *cobra.Command {
+	var file string
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "create an anomaly config from a JSON file",
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if file == "" {
+				return errUsageFlag(cmd, "file")
+			}
+
+			var config db.AnomalyConfig
+			if err := readJSONFile(file, &config); err != nil {
+				return err
+			}
+
+			ctx := cmd.Context()
+			repo, err := c.anomalyConfigRepository(ctx)
+			if err != nil {
+				return err
+			}
+			if err := repo.Create(ctx, &config); err != nil {
+				return fmt.Errorf("failed to create anomaly config: %w", err)
+			}
+			return printJSON(c.out, config)
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", `anomaly config JSON file, or "-" for stdin (required)`)
+	return cmd
+}
+
+func anomaliesUpdateCommand(c *cli) *cobra.Command {
+	var file string
+	cmd := &cobra.Command{
+		Use:   "update CONFIG_ID",
+		Short: "replace an anomaly config's definition from a JSON file",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if file == "" {
+				return errUsageFlag(cmd, "file")
+			}
+
+			var config db.AnomalyConfig
+			if err := readJSONFile(file, &config); err != nil {
+				return err
+			}
+			config.ID = args[0]
+
+			ctx := cmd.Context()
+			repo, err := c.anomalyConfigRepository(ctx)
+			if err != nil {
+				return err
+			}
+			if err := repo.Update(ctx, &config); err != nil {
+				return fmt.Errorf("failed to update anomaly config: %w", err)
+			}
+			return printJSON(c.out, config)
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", `anomaly config JSON file, or "-" for stdin (required)`)
+	return cmd
+}
+
+func anomaliesDeleteCommand(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete CONFIG_ID",
+		Short: "delete an anomaly config",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			repo, err := c.anomalyConfigRepository(ctx)
+			if err != nil {
+				return err
+			}
+			if err := repo.Delete(ctx, args[0]); err != nil {
+				return err
+			}
+			fmt.Fprintf(c.out, "deleted %s\n", args[0])
+			return nil
+		},
+	}
+}
+
+// anomaliesSetEnabledCommand returns the "anomalies enable" or
+// "anomalies disable" command.
+func anomaliesSetEnabledCommand(c *cli, enabled bool) *cobra.Command {
+	use, short := "disable CONFIG_ID", "disable an anomaly config"
+	if enabled {
+		use, short = "enable CONFIG_ID", "enable an anomaly config"
+	}
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			repo, err := c.anomalyConfigRepository(ctx)
+			if err != nil {
+				return err
+			}
+			config, err := repo.GetByID(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			config.Enabled = enabled
+			if err := repo.Update(ctx, config); err != nil {
+				return fmt.Errorf("failed to update anomaly config: %w", err)
+			}
+			fmt.Fprintf(c.out, "%s enabled=%t\n", config.ID, config.Enabled)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v2"
+
+	"github.com/SebastienMelki/causality/internal/reaction"
+)
+
+// applyResponse is the reaction engine's resource sync response.
+type applyResponse struct {
+	DryRun  bool                      `json:"dry_run"`
+	Changes []reaction.ResourceChange `json:"changes"`
+}
+
+// runApply reconciles the rules, webhooks and anomaly configs in the reaction
+// database with a YAML (or JSON) spec file and prints the changes.
+func applyCommand(c *cli) *cobra.Command {
+	var (
+		file, authorFlag string
+		dryRun, prune    bool
+	)
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "reconcile rules, webhooks and anomaly configs with a YAML spec",
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if file == "" {
+				return errUsageFlag(cmd, "file")
+			}
+
+			spec, err := readResourceSpec(file)
+			if err != nil {
+				return err
+			}
+
+			body := map[string]any{
+				"spec":    spec,
+				"dry_run": dryRun,
+				"prune":   prune,
+				"author":  author(authorFlag),
+			}
+			var resp applyResponse
+			if err := c.adminRequest(cmd.Context(), http.MethodPost, c.cfg.ReactionAdmin, "/api/admin/resources/apply", nil, body, &resp); err != nil {
+				return err
+			}
+
+			printPlan(c.out, resp)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", `resource spec YAML file, or "-" for stdin (required)`)
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the changes without applying them")
+	cmd.Flags().BoolVar(&prune, "prune", false, "delete resources the spec does not declare")
+	cmd.Flags().StringVar(&authorFlag, "author", "", "author recorded on rule versions (default $USER)")
+	return cmd
+}
+
+func readResourceSpec(path string) (*reaction.ResourceSpec, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	// Round-trip through JSON so the spec's JSON field names and embedded
+	// JSON (auth_config, config) apply unchanged.
+	data, err = json.Marshal(yamlToJSON(doc))
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %s: %w", path, err)
+	}
+	var spec reaction.ResourceSpec
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return &spec, nil
+}
+
+// yamlToJSON converts the map[interface{}]interface{} values produced by the
+// YAML decoder to map[string]any so they can be encoded as JSON.
+func yamlToJSON(v any) any {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = yamlToJSON(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range v {
+			v[i] = yamlToJSON(value)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// planSymbols prefixes each change in a printed plan.
+var planSymbols = map[string]string{
+	reaction.SyncCreate: "+",
+	reaction.SyncUpdate: "~",
+	reaction.SyncDelete: "-",
+}
+
+// printPlan writes the changes of a sync, one per line with changed fields
+// indented below, followed by a summary.
+func printPlan(w io.Writer, resp applyResponse) {
+	if len(resp.Changes) == 0 {
+		fmt.Fprintln(w, "No changes. Resources match the spec.")
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, change := range resp.Changes {
+		counts[change.Action]++
+		fmt.Fprintf(w, "%s %s %s\n", planSymbols[change.Action], change.Kind, change.Name)
+		for _, field := range change.Fields {
+			fmt.Fprintf(w, "    %s: %s -> %s\n", field.Field, compactJSON(field.Old), compactJSON(field.New))
+		}
+	}
+
+	format := "\nApplied: %d created, %d updated, %d deleted.\n"
+	if resp.DryRun {
+		format = "\nPlan: %d to create, %d to update, %d to delete.\n"
+	}
+	fmt.Fprintf(w, format, counts[reaction.SyncCreate], counts[reaction.SyncUpdate], counts[reaction.SyncDelete])
+}
+
+// compactJSON renders a JSON value on one line.
+func compactJSON(raw json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return strings.TrimSpace(string(raw))
+	}
+	return buf.String()
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+)
+
+// compactCommand starts a compaction run on the warehouse sink. The run
+// proceeds in the background; progress is reported in the sink's logs and
+// metrics.
+func compactCommand(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "compact",
+		Short: "start a compaction run on the warehouse sink",
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := c.adminRequest(cmd.Context(), http.MethodPost, c.cfg.SinkAdmin, "/api/admin/compaction/run", nil, nil, nil); err != nil {
+				return err
+			}
+			fmt.Fprintln(c.out, "compaction started")
+			return nil
+		},
+	}
+}
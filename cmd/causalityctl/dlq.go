@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SebastienMelki/causality/internal/dlq"
+)
+
+// dlqCommand inspects and replays the dead-letter queue.
+func dlqCommand(c *cli) *cobra.Command {
+	return group("dlq", "inspect and replay dead-lettered messages",
+		dlqListCommand(c),
+		dlqReplayCommand(c),
+	)
+}
+
+func (c *cli) dlqModule(ctx context.Context) (*dlq.Module, error) {
+	client, err := c.natsClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return dlq.New(client.JetStream(), client.Conn(), c.cfg.NATS.Stream.Name, nil, c.cfg.DLQ, nil, c.logger), nil
+}
+
+func dlqListCommand(c *cli) *cobra.Command {
+	var limit int
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list dead-lettered messages",
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+			module, err := c.dlqModule(ctx)
+			if err != nil {
+				return err
+			}
+			messages, err := module.ListMessages(ctx, limit)
+			if err != nil {
+				return err
+			}
+
+			rows := make([][]string, len(messages))
+			for i, msg := range messages {
+				rows[i] = []string{
+					strconv.FormatUint(msg.Sequence, 10),
+					msg.Subject,
+					msg.Consumer,
+					strconv.FormatUint(msg.OriginalSequence, 10),
+					strconv.FormatUint(msg.Deliveries, 10),
+					strconv.Itoa(msg.Size),
+					msg.Time.Format("2006-01-02T15:04:05Z07:00"),
+				}
+			}
+			return printTable(c.out, []string{"SEQ", "SUBJECT", "CONSUMER", "ORIGINAL_SEQ", "DELIVERIES", "BYTES", "TIME"}, rows)
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 100, "maximum number of messages")
+	return cmd
+}
+
+func dlqReplayCommand(c *cli) *cobra.Command {
+	var (
+		all   bool
+		limit int
+	)
+	cmd := &cobra.Command{
+		Use:   "replay [SEQ...]",
+		Short: "republish dead-lettered messages to their original subject",
+		Args:  cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if all == (len(args) > 0) {
+				return fmt.Errorf("%w: dlq replay takes either --all or sequence numbers", errUsage)
+			}
+
+			var seqs []uint64
+			for _, arg := range args {
+				seq, err := strconv.ParseUint(arg, 10, 64)
+				if err != nil {
+					return fmt.Errorf("%w: invalid sequence %q", errUsage, arg)
+				}
+				seqs = append(seqs, seq)
+			}
+
+			ctx := cmd.Context()
+			module, err := c.dlqModule(ctx)
+			if err != nil {
+				return err
+			}
+			if all {
+				messages, err := module.ListMessages(ctx, limit)
+				if err != nil {
+					return err
+				}
+				for _, msg := range messages {
+					seqs = append(seqs, msg.Sequence)
+				}
+			}
+
+			for _, seq := range seqs {
+				if err := module.Replay(ctx, seq); err != nil {
+					return err
+				}
+				fmt.Fprintf(c.out, "replayed %d\n", seq)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&all, "all", false, "replay every message in the DLQ (up to --limit)")
+	cmd.Flags().IntVar(&limit, "limit", 1000, "maximum number of messages replayed with --all")
+	return cmd
+}
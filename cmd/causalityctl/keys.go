@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// keysCommand manages API keys through the gateway admin API.
+func keysCommand(c *cli) *cobra.Command {
+	return group("keys", "manage API keys",
+		keysListCommand(c),
+		keysCreateCommand(c),
+		keysRevokeCommand(c),
+	)
+}
+
+// apiKey is an API key as listed by the gateway.
+type apiKey struct {
+	ID                string   `json:"id"`
+	AppID             string   `json:"app_id"`
+	Name              string   `json:"name"`
+	AllowedEventTypes []string `json:"allowed_event_types"`
+	Signed            bool     `json:"signed"`
+	Revoked           bool     `json:"revoked"`
+	CreatedAt         string   `json:"created_at"`
+}
+
+func keysListCommand(c *cli) *cobra.Command {
+	var appID string
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list an app's API keys",
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if appID == "" {
+				return errUsageFlag(cmd, "app")
+			}
+
+			var resp struct {
+				Keys []apiKey `json:"keys"`
+			}
+			if err := c.adminRequest(cmd.Context(), http.MethodGet, c.cfg.Server, "/api/admin/keys", url.Values{"app_id": {appID}}, nil, &resp); err != nil {
+				return err
+			}
+
+			rows := make([][]string, len(resp.Keys))
+			for i, key := range resp.Keys {
+				scope := strings.Join(key.AllowedEventTypes, ",")
+				if scope == "" {
+					scope = "*"
+				}
+				rows[i] = []string{key.ID, key.Name, scope, strconv.FormatBool(key.Signed), strconv.FormatBool(key.Revoked), key.CreatedAt}
+			}
+			return printTable(c.out, []string{"ID", "NAME", "EVENTS", "SIGNED", "REVOKED", "CREATED"}, rows)
+		},
+	}
+	cmd.Flags().StringVar(&appID, "app", "", "app ID (required)")
+	return cmd
+}
+
+func keysCreateCommand(c *cli) *cobra.Command {
+	var (
+		appID, name, eventTypes string
+		signed                  bool
+	)
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "create an API key",
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if appID == "" {
+				return errUsageFlag(cmd, "app")
+			}
+
+			req := map[string]any{
+				"app_id":              appID,
+				"name":                name,
+				"allowed_event_types": splitList(eventTypes),
+				"signed":              signed,
+			}
+			var resp map[string]any
+			if err := c.adminRequest(cmd.Context(), http.MethodPost, c.cfg.Server, "/api/admin/keys", nil, req, &resp); err != nil {
+				return err
+			}
+			return printJSON(c.out, resp)
+		},
+	}
+	cmd.Flags().StringVar(&appID, "app", "", "app ID (required)")
+	cmd.Flags().StringVar(&name, "name", "", "key name")
+	cmd.Flags().StringVar(&eventTypes, "events", "", "comma-separated allowed categories or category.type pairs (default: all)")
+	cmd.Flags().BoolVar(&signed, "signed", false, "require HMAC-signed requests")
+	return cmd
+}
+
+func keysRevokeCommand(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "revoke KEY_ID",
+		Short: "revoke an API key",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.adminRequest(cmd.Context(), http.MethodDelete, c.cfg.Server, "/api/admin/keys/"+url.PathEscape(args[0]), nil, nil, nil); err != nil {
+				return err
+			}
+			fmt.Fprintf(c.out, "revoked %s\n", args[0])
+			return nil
+		},
+	}
+}
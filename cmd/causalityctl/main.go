@@ -0,0 +1,261 @@
+// Command causalityctl is the operator CLI for Causality. It wraps the admin
+// APIs of the gateway, reaction engine and warehouse sink, manages rules,
+// webhooks and anomaly configs in the reaction engine database, and inspects
+// JetStream streams, the dead-letter queue and live events.
+//
+// Usage:
+//
+//	causalityctl [--server URL] [--reaction URL] [--sink URL] COMMAND [SUBCOMMAND] [flags] [ARGS]
+//
+// Commands:
+//
+//	keys list|create|revoke                              API keys (gateway admin API)
+//	rules list|get|create|update|delete|enable|disable   rules (reaction database)
+//	rules versions|rollback|shadow                       rule history (reaction engine admin API)
+//	webhooks list|get|create|update|delete|enable|disable
+//	anomalies list|get|create|update|delete|enable|disable
+//	apply -f FILE [--dry-run] [--prune]                  declarative sync (reaction engine admin API)
+//	streams                                              stream sizes and consumer lag
+//	compact                                              start a compaction run (warehouse sink admin API)
+//	dlq list|replay                                      dead-letter queue
+//	tail                                                 print events as they are published
+//	verify [--prefix PREFIX]                             check lake files against stream sequences
+//
+// NATS, S3 and the reaction database are configured with the same
+// environment variables as the services (NATS_URL, NATS_STREAM_NAME, S3_BUCKET,
+// DATABASE_HOST, ...).
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/caarlos0/env/v10"
+	"github.com/spf13/cobra"
+
+	"github.com/SebastienMelki/causality/internal/dlq"
+	"github.com/SebastienMelki/causality/internal/nats"
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+// errUsage reports invalid command-line usage; the process exits with 2.
+var errUsage = errors.New("usage error")
+
+// Config holds causalityctl configuration loaded from environment variables.
+// The admin URLs can be overridden with flags.
+type Config struct {
+	// Server is the gateway base URL (API key admin API).
+	Server string `env:"CAUSALITYCTL_SERVER" envDefault:"http://localhost:8080"`
+
+	// ReactionAdmin is the reaction engine metrics server base URL (rule
+	// history admin API).
+	ReactionAdmin string `env:"CAUSALITYCTL_REACTION_ADMIN" envDefault:"http://localhost:9091"`
+
+	// SinkAdmin is the warehouse sink metrics server base URL (compaction
+	// admin API).
+	SinkAdmin string `env:"CAUSALITYCTL_SINK_ADMIN" envDefault:"http://localhost:9090"`
+
+	// Timeout bounds each admin API request.
+	Timeout time.Duration `env:"CAUSALITYCTL_TIMEOUT" envDefault:"30s"`
+
+	// NATS configuration.
+	NATS nats.Config `envPrefix:""`
+
+	// DLQ configuration.
+	DLQ dlq.Config `envPrefix:""`
+
+	// S3 is the event lake verified by the verify command.
+	S3 warehouse.S3Config `envPrefix:"S3_"`
+
+	// Delta restricts verification to the active files of the Delta table.
+	Delta warehouse.DeltaConfig `envPrefix:"DELTA_"`
+
+	// SinkConsumer is the warehouse sink's consumer name.
+	SinkConsumer string `env:"CONSUMER_NAME" envDefault:"warehouse-sink"`
+
+	// Database is the reaction engine database.
+	Database db.Config `envPrefix:"DATABASE_"`
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run executes the command line and returns the process exit code.
+func run(args []string) int {
+	var cfg Config
+	if err := env.Parse(&cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "causalityctl: %v\n", err)
+		return 1
+	}
+	cfg.NATS.Name = "causalityctl"
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	c := newCLI(cfg, os.Stdout)
+	defer c.close()
+
+	root := rootCommand(c)
+	root.SetArgs(args)
+	cmd, err := root.ExecuteContextC(ctx)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage):
+		fmt.Fprint(os.Stderr, cmd.UsageString())
+		fmt.Fprintf(os.Stderr, "causalityctl: %v\n", err)
+		return 2
+	default:
+		fmt.Fprintf(os.Stderr, "causalityctl: %v\n", err)
+		return 1
+	}
+}
+
+// rootCommand returns the causalityctl command tree. The global flags
+// override the admin URLs of c's configuration.
+func rootCommand(c *cli) *cobra.Command {
+	root := group("causalityctl", "Causality operator CLI",
+		keysCommand(c),
+		rulesCommand(c),
+		webhooksCommand(c),
+		anomaliesCommand(c),
+		applyCommand(c),
+		streamsCommand(c),
+		compactCommand(c),
+		dlqCommand(c),
+		tailCommand(c),
+		verifyCommand(c),
+	)
+	root.SilenceErrors = true
+	root.SilenceUsage = true
+	root.PersistentFlags().StringVar(&c.cfg.Server, "server", c.cfg.Server, "gateway base URL")
+	root.PersistentFlags().StringVar(&c.cfg.ReactionAdmin, "reaction", c.cfg.ReactionAdmin, "reaction engine admin base URL")
+	root.PersistentFlags().StringVar(&c.cfg.SinkAdmin, "sink", c.cfg.SinkAdmin, "warehouse sink admin base URL")
+	root.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return fmt.Errorf("%w: %s: %v", errUsage, cmd.CommandPath(), err)
+	})
+	return root
+}
+
+// group returns a command grouping subcommands. Run without a known
+// subcommand, it fails with errUsage.
+func group(use, short string, subs ...*cobra.Command) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("%w: %s requires a command", errUsage, cmd.CommandPath())
+			}
+			return fmt.Errorf("%w: unknown command %q", errUsage, cmd.CommandPath()+" "+args[0])
+		},
+	}
+	cmd.AddCommand(subs...)
+	return cmd
+}
+
+// exactArgs checks that a command has exactly n positional arguments.
+func exactArgs(n int) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if len(args) != n {
+			return fmt.Errorf("%w: %s takes %d argument(s), got %d", errUsage, cmd.CommandPath(), n, len(args))
+		}
+		return nil
+	}
+}
+
+// errUsageFlag reports a missing required flag.
+func errUsageFlag(cmd *cobra.Command, flagName string) error {
+	return fmt.Errorf("%w: %s requires --%s", errUsage, cmd.CommandPath(), flagName)
+}
+
+// cli holds configuration and lazily opened connections shared by commands.
+type cli struct {
+	cfg    Config
+	out    io.Writer
+	http   *http.Client
+	logger *slog.Logger
+
+	nats *nats.Client
+	db   *db.Client
+}
+
+// newCLI creates a cli writing command output to out. Service logs only
+// surface warnings, on stderr.
+func newCLI(cfg Config, out io.Writer) *cli {
+	return &cli{
+		cfg:    cfg,
+		out:    out,
+		http:   &http.Client{Timeout: cfg.Timeout},
+		logger: slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
+	}
+}
+
+// natsClient connects to NATS on first use.
+func (c *cli) natsClient(ctx context.Context) (*nats.Client, error) {
+	if c.nats == nil {
+		client, err := nats.NewClient(ctx, c.cfg.NATS, c.logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+		}
+		c.nats = client
+	}
+	return c.nats, nil
+}
+
+// reactionDB connects to the reaction engine database on first use.
+func (c *cli) reactionDB(ctx context.Context) (*db.Client, error) {
+	if c.db == nil {
+		client, err := db.NewClient(ctx, c.cfg.Database, c.logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to reaction database: %w", err)
+		}
+		c.db = client
+	}
+	return c.db, nil
+}
+
+// close closes open connections.
+func (c *cli) close() {
+	if c.nats != nil {
+		c.nats.Close()
+	}
+	if c.db != nil {
+		_ = c.db.Close()
+	}
+}
+
+// author returns the author recorded on rule versions: the --author flag if
+// set, otherwise the operator's user name.
+func author(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	return "causalityctl"
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// rulesCommand manages rules in the reaction database, and their history
+// through the reaction engine admin API.
+func rulesCommand(c *cli) *cobra.Command {
+	return group("rules", "manage reaction rules",
+		rulesListCommand(c),
+		rulesGetCommand(c),
+		rulesCreateCommand(c),
+		rulesUpdateCommand(c),
+		rulesDeleteCommand(c),
+		rulesSetEnabledCommand(c, true),
+		rulesSetEnabledCommand(c, false),
+		rulesVersionsCommand(c),
+		rulesRollbackCommand(c),
+		rulesShadowCommand(c),
+	)
+}
+
+// ruleRepository opens the rule repository.
+func (c *cli) ruleRepository(ctx context.Context) (*db.RuleRepository, error) {
+	client, err := c.reactionDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.NewRuleRepository(client), nil
+}
+
+func rulesListCommand(c *cli) *cobra.Command {
+	var limit, offset int
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list rules",
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+			repo, err := c.ruleRepository(ctx)
+			if err != nil {
+				return err
+			}
+			rules, err := repo.List(ctx, limit, offset)
+			if err != nil {
+				return fmt.Errorf("failed to list rules: %w", err)
+			}
+
+			rows := make([][]string, len(rules))
+			for i, rule := range rules {
+				rows[i] = []string{
+					rule.ID, rule.Name, deref(rule.AppID), deref(rule.EventCategory), deref(rule.EventType),
+					strconv.FormatBool(rule.Enabled), strconv.FormatBool(rule.Shadow), strconv.Itoa(rule.Version),
+				}
+			}
+			return printTable(c.out, []string{"ID", "NAME", "APP", "CATEGORY", "TYPE", "ENABLED", "SHADOW", "VERSION"}, rows)
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 100, "maximum number of rules")
+	cmd.Flags().IntVar(&offset, "offset", 0, "number of rules to skip")
+	return cmd
+}
+
+func rulesGetCommand(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get RULE_ID",
+		Short: "print a rule",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			repo, err := c.ruleRepository(ctx)
+			if err != nil {
+				return err
+			}
+			rule, err := repo.GetByID(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			return printJSON(c.out, rule)
+		},
+	}
+}
+
+func rulesCreateCommand(c *cli) *cobra.Command {
+	var file, by string
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "create a rule from a JSON file",
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if file == "" {
+				return errUsageFlag(cmd, "file")
+			}
+
+			var rule db.Rule
+			if err := readJSONFile(file, &rule); err != nil {
+				return err
+			}
+
+			ctx := cmd.Context()
+			repo, err := c.ruleRepository(ctx)
+			if err != nil {
+				return err
+			}
+			if err := repo.Create(ctx, &rule, author(by)); err != nil {
+				return fmt.Errorf("failed to create rule: %w", err)
+			}
+			return printJSON(c.out, rule)
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", `rule JSON file, or "-" for stdin (required)`)
+	cmd.Flags().StringVar(&by, "author", "", "author recorded on the version (default: $USER)")
+	return cmd
+}
+
+func rulesUpdateCommand(c *cli) *cobra.Command {
+	var file, by string
+	cmd := &cobra.Command{
+		Use:   "update RULE_ID",
+		Short: "replace a rule's definition from a JSON file",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if file == "" {
+				return errUsageFlag(cmd, "file")
+			}
+
+			var rule db.Rule
+			if err := readJSONFile(file, &rule); err != nil {
+				return err
+			}
+			rule.ID = args[0]
+
+			ctx := cmd.Context()
+			repo, err := c.ruleRepository(ctx)
+			if err != nil {
+				return err
+			}
+			if err := repo.Update(ctx, &rule, author(by)); err != nil {
+				return fmt.Errorf("failed to update rule: %w", err)
+			}
+			return printJSON(c.out, rule)
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", `rule JSON file, or "-" for stdin (required)`)
+	cmd.Flags().StringVar(&by, "author", "", "author recorded on the version (default: $USER)")
+	return cmd
+}
+
+func rulesDeleteCommand(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete RULE_ID",
+		Short: "delete a rule",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			repo, err := c.ruleRepository(ctx)
+			if err != nil {
+				return err
+			}
+			if err := repo.Delete(ctx, args[0]); err != nil {
+				return err
+			}
+			fmt.Fprintf(c.out, "deleted %s\n", args[0])
+			return nil
+		},
+	}
+}
+
+// rulesSetEnabledCommand returns the "rules enable" or "rules disable"
+// command. The change is recorded as a new rule version.
+func rulesSetEnabledCommand(c *cli, enabled bool) *cobra.Command {
+	use, short := "disable RULE_ID", "disable a rule"
+	if enabled {
+		use, short = "enable RULE_ID", "enable a rule"
+	}
+	var by string
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			repo, err := c.ruleRepository(ctx)
+			if err != nil {
+				return err
+			}
+			rule, err := repo.GetByID(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			rule.Enabled = enabled
+			if err := repo.Update(ctx, rule, author(by)); err != nil {
+				return fmt.Errorf("failed to update rule: %w", err)
+			}
+			fmt.Fprintf(c.out, "%s enabled=%t version=%d\n", rule.ID, rule.Enabled, rule.Version)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&by, "author", "", "author recorded on the version (default: $USER)")
+	return cmd
+}
+
+func rulesVersionsCommand(c *cli) *cobra.Command {
+	var limit int
+	cmd := &cobra.Command{
+		Use:   "versions RULE_ID",
+		Short: "list a rule's versions",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var resp struct {
+				Versions []*db.RuleVersion `json:"versions"`
+			}
+			path := "/api/admin/rules/" + url.PathEscape(args[0]) + "/versions"
+			if err := c.adminRequest(cmd.Context(), http.MethodGet, c.cfg.ReactionAdmin, path, url.Values{"limit": {strconv.Itoa(limit)}}, nil, &resp); err != nil {
+				return err
+			}
+
+			rows := make([][]string, len(resp.Versions))
+			for i, v := range resp.Versions {
+				changed := make([]string, len(v.Diff))
+				for j, change := range v.Diff {
+					changed[j] = change.Field
+				}
+				rows[i] = []string{strconv.Itoa(v.Version), v.Author, v.CreatedAt.Format("2006-01-02T15:04:05Z07:00"), joinOrDash(changed)}
+			}
+			return printTable(c.out, []string{"VERSION", "AUTHOR", "CREATED", "CHANGED"}, rows)
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 50, "maximum number of versions")
+	return cmd
+}
+
+func rulesRollbackCommand(c *cli) *cobra.Command {
+	var (
+		version int
+		by      string
+	)
+	cmd := &cobra.Command{
+		Use:   "rollback RULE_ID",
+		Short: "restore a prior rule version",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if version <= 0 {
+				return errUsageFlag(cmd, "version")
+			}
+
+			req := map[string]any{"version": version, "author": author(by)}
+			var rule db.Rule
+			path := "/api/admin/rules/" + url.PathEscape(args[0]) + "/rollback"
+			if err := c.adminRequest(cmd.Context(), http.MethodPost, c.cfg.ReactionAdmin, path, nil, req, &rule); err != nil {
+				return err
+			}
+			return printJSON(c.out, rule)
+		},
+	}
+	cmd.Flags().IntVar(&version, "version", 0, "version to restore (required)")
+	cmd.Flags().StringVar(&by, "author", "", "author recorded on the version (default: $USER)")
+	return cmd
+}
+
+func rulesShadowCommand(c *cli) *cobra.Command {
+	var limit int
+	cmd := &cobra.Command{
+		Use:   "shadow RULE_ID",
+		Short: "show a shadow rule's matches",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var resp map[string]any
+			path := "/api/admin/rules/" + url.PathEscape(args[0]) + "/shadow"
+			if err := c.adminRequest(cmd.Context(), http.MethodGet, c.cfg.ReactionAdmin, path, url.Values{"limit": {strconv.Itoa(limit)}}, nil, &resp); err != nil {
+				return err
+			}
+			return printJSON(c.out, resp)
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 10, "maximum number of samples")
+	return cmd
+}
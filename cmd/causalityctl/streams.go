@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/spf13/cobra"
+)
+
+// runStreams prints the size of the event, derived, DLQ and audit streams and the lag
+// of each consumer: messages not yet delivered (pending) and delivered but
+// not acknowledged (ack pending).
+func streamsCommand(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "streams",
+		Short: "show stream sizes and consumer lag",
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runStreams(cmd.Context(), c)
+		},
+	}
+}
+
+func runStreams(ctx context.Context, c *cli) error {
+	client, err := c.natsClient(ctx)
+	if err != nil {
+		return err
+	}
+	js := client.JetStream()
+
+	names := []string{c.cfg.NATS.Stream.Name, c.cfg.NATS.Stream.DerivedStreamName, c.cfg.NATS.Stream.DLQStreamName, c.cfg.NATS.Stream.AuditStreamName}
+	var streamRows, consumerRows [][]string
+	for _, name := range names {
+		stream, err := js.Stream(ctx, name)
+		if errors.Is(err, jetstream.ErrStreamNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get stream %s: %w", name, err)
+		}
+
+		info, err := stream.Info(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get stream %s info: %w", name, err)
+		}
+		streamRows = append(streamRows, []string{
+			name,
+			strconv.FormatUint(info.State.Msgs, 10),
+			strconv.FormatUint(info.State.Bytes, 10),
+			strconv.FormatUint(info.State.FirstSeq, 10),
+			strconv.FormatUint(info.State.LastSeq, 10),
+			strconv.Itoa(info.State.Consumers),
+		})
+
+		consumers := stream.ListConsumers(ctx)
+		for consumer := range consumers.Info() {
+			consumerRows = append(consumerRows, []string{
+				name,
+				consumer.Name,
+				strconv.FormatUint(consumer.NumPending, 10),
+				strconv.Itoa(consumer.NumAckPending),
+				strconv.Itoa(consumer.NumRedelivered),
+				strconv.FormatUint(consumer.AckFloor.Stream, 10),
+			})
+		}
+		if err := consumers.Err(); err != nil {
+			return fmt.Errorf("failed to list consumers of %s: %w", name, err)
+		}
+	}
+
+	if err := printTable(c.out, []string{"STREAM", "MESSAGES", "BYTES", "FIRST_SEQ", "LAST_SEQ", "CONSUMERS"}, streamRows); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.out)
+	return printTable(c.out, []string{"STREAM", "CONSUMER", "PENDING", "ACK_PENDING", "REDELIVERED", "ACK_FLOOR"}, consumerRows)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/SebastienMelki/causality/internal/events"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+func tailCommand(c *cli) *cobra.Command {
+	var subject, appID string
+	cmd := &cobra.Command{
+		Use:   "tail",
+		Short: "print events as they are published",
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runTail(cmd.Context(), c, subject, appID)
+		},
+	}
+	cmd.Flags().StringVar(&subject, "subject", "events.>", "subject to tail")
+	cmd.Flags().StringVar(&appID, "app", "", "only print events of this app")
+	return cmd
+}
+
+// runTail prints events published to subject as JSON lines until
+// interrupted. It uses a core NATS subscription, so it neither creates a
+// consumer nor affects delivery to the services.
+func runTail(ctx context.Context, c *cli, subject, appID string) error {
+	client, err := c.natsClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	msgs := make(chan *natsgo.Msg, 256)
+	sub, err := client.Conn().ChanSubscribe(subject, msgs)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", subject, err)
+	}
+	defer func() { _ = sub.Unsubscribe() }()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-msgs:
+			var event pb.EventEnvelope
+			if err := events.Decode(msg.Data, &event); err != nil {
+				fmt.Fprintf(c.out, "%s: undecodable message: %v\n", msg.Subject, err)
+				continue
+			}
+			if appID != "" && event.GetAppId() != appID {
+				continue
+			}
+
+			data, err := protojson.Marshal(&event)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(c.out, "%s %s\n", msg.Subject, data)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/spf13/cobra"
+
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+// errIntegrity reports a verification that found issues, after the report
+// was printed.
+var errIntegrity = errors.New("integrity issues found")
+
+func verifyCommand(c *cli) *cobra.Command {
+	var prefix, consumerName string
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "check lake files against stream sequences",
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runVerify(cmd.Context(), c, prefix, consumerName)
+		},
+	}
+	cmd.Flags().StringVar(&prefix, "prefix", "", "only verify files under this partition prefix (e.g. app_id=demo/year=2026); skips gap checks")
+	cmd.Flags().StringVar(&consumerName, "consumer", c.cfg.SinkConsumer, "warehouse sink consumer whose ack floor bounds the gap check")
+	return cmd
+}
+
+// runVerify reads the footers of the lake's Parquet files and checks their
+// row counts and recorded stream sequences against each other and the
+// event stream, printing a JSON report. It fails if any issue is found.
+func runVerify(ctx context.Context, c *cli, prefix, consumerName string) error {
+	bounds, err := c.streamBounds(ctx, consumerName)
+	if err != nil {
+		return err
+	}
+
+	s3Client, err := warehouse.NewS3Client(ctx, c.cfg.S3, c.logger)
+	if err != nil {
+		return fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	// With Delta enabled, files removed by compaction stay in the bucket
+	// until vacuumed; only the active files of the table are verified.
+	var include func(string) bool
+	if c.cfg.Delta.Enabled {
+		snapshot, err := warehouse.NewDeltaLog(s3Client.RawClient(), c.cfg.S3, c.cfg.Delta, c.logger).Snapshot(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read Delta snapshot: %w", err)
+		}
+		include = snapshot.Contains
+	}
+
+	files, unreadable, err := warehouse.ScanFileIntegrity(ctx, s3Client.RawClient(), c.cfg.S3, strings.TrimPrefix(prefix, "/"), include)
+	if err != nil {
+		return err
+	}
+
+	report := warehouse.CheckIntegrity(files, bounds, prefix == "")
+	if len(unreadable) > 0 {
+		report.Files += len(unreadable)
+		report.Issues = append(unreadable, report.Issues...)
+		report.OK = false
+	}
+
+	if err := printJSON(c.out, report); err != nil {
+		return err
+	}
+	if !report.OK {
+		return fmt.Errorf("%w: %d issue(s)", errIntegrity, len(report.Issues))
+	}
+	return nil
+}
+
+// streamBounds returns the retained sequences of the event stream and the
+// ack floor of the named consumer. A missing consumer leaves the ack floor
+// zero.
+func (c *cli) streamBounds(ctx context.Context, consumerName string) (warehouse.StreamBounds, error) {
+	name := c.cfg.NATS.Stream.Name
+	bounds := warehouse.StreamBounds{Name: name}
+
+	client, err := c.natsClient(ctx)
+	if err != nil {
+		return bounds, err
+	}
+
+	stream, err := client.JetStream().Stream(ctx, name)
+	if err != nil {
+		return bounds, fmt.Errorf("failed to get stream %s: %w", name, err)
+	}
+	info, err := stream.Info(ctx)
+	if err != nil {
+		return bounds, fmt.Errorf("failed to get stream %s info: %w", name, err)
+	}
+	bounds.FirstSeq = info.State.FirstSeq
+	bounds.LastSeq = info.State.LastSeq
+
+	consumer, err := stream.Consumer(ctx, consumerName)
+	if errors.Is(err, jetstream.ErrConsumerNotFound) {
+		return bounds, nil
+	}
+	if err != nil {
+		return bounds, fmt.Errorf("failed to get consumer %s: %w", consumerName, err)
+	}
+	consumerInfo, err := consumer.Info(ctx)
+	if err != nil {
+		return bounds, fmt.Errorf("failed to get consumer %s info: %w", consumerName, err)
+	}
+	bounds.AckFloor = consumerInfo.AckFloor.Stream
+	return bounds, nil
+}
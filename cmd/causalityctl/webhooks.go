@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// webhooksCommand manages webhooks in the reaction database.
+func webhooksCommand(c *cli) *cobra.Command {
+	return group("webhooks", "manage webhooks",
+		webhooksListCommand(c),
+		webhooksGetCommand(c),
+		webhooksCreateCommand(c),
+		webhooksUpdateCommand(c),
+		webhooksDeleteCommand(c),
+		webhooksSetEnabledCommand(c, true),
+		webhooksSetEnabledCommand(c, false),
+	)
+}
+
+// webhookRepository opens the webhook repository.
+func (c *cli) webhookRepository(ctx context.Context) (*db.WebhookRepository, error) {
+	client, err := c.reactionDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.NewWebhookRepository(client), nil
+}
+
+func webhooksListCommand(c *cli) *cobra.Command {
+	var limit, offset int
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list webhooks",
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+			repo, err := c.webhookRepository(ctx)
+			if err != nil {
+				return err
+			}
+			webhooks, err := repo.List(ctx, limit, offset)
+			if err != nil {
+				return fmt.Errorf("failed to list webhooks: %w", err)
+			}
+
+			// Auth configuration holds secrets and is only shown by "get"
+			rows := make([][]string, len(webhooks))
+			for i, webhook := range webhooks {
+				rows[i] = []string{
+					webhook.ID, webhook.Name, webhook.URL, webhook.AuthType,
+					strconv.FormatBool(webhook.Enabled), strconv.FormatFloat(webhook.MaxRPS, 'f', -1, 64), strconv.Itoa(webhook.BatchSize),
+				}
+			}
+			return printTable(c.out, []string{"ID", "NAME", "URL", "AUTH", "ENABLED", "MAX_RPS", "BATCH"}, rows)
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 100, "maximum number of webhooks")
+	cmd.Flags().IntVar(&offset, "offset", 0, "number of webhooks to skip")
+	return cmd
+}
+
+func webhooksGetCommand(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get WEBHOOK_ID",
+		Short: "print a webhook",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			repo, err := c.webhookRepository(ctx)
+			if err != nil {
+				return err
+			}
+			webhook, err := repo.GetByID(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			return printJSON(c.out, webhook)
+		},
+	}
+}
+
+func webhooksCreateCommand(c *cli) *cobra.Command {
+	var file string
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "create a webhook from a JSON file",
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if file == "" {
+				return errUsageFlag(cmd, "file")
+			}
+
+			var webhook db.Webhook
+			if err := readJSONFile(file, &webhook); err != nil {
+				return err
+			}
+
+			ctx := cmd.Context()
+			repo, err := c.webhookRepository(ctx)
+			if err != nil {
+				return err
+			}
+			if err := repo.Create(ctx, &webhook); err != nil {
+				return fmt.Errorf("failed to create webhook: %w", err)
+			}
+			return printJSON(c.out, webhook)
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", `webhook JSON file, or "-" for stdin (required)`)
+	return cmd
+}
+
+func webhooksUpdateCommand(c *cli) *cobra.Command {
+	var file string
+	cmd := &cobra.Command{
+		Use:   "update WEBHOOK_ID",
+		Short: "replace a webhook's definition from a JSON file",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if file == "" {
+				return errUsageFlag(cmd, "file")
+			}
+
+			var webhook db.Webhook
+			if err := readJSONFile(file, &webhook); err != nil {
+				return err
+			}
+			webhook.ID = args[0]
+
+			ctx := cmd.Context()
+			repo, err := c.webhookRepository(ctx)
+			if err != nil {
+				return err
+			}
+			if err := repo.Update(ctx, &webhook); err != nil {
+				return fmt.Errorf("failed to update webhook: %w", err)
+			}
+			return printJSON(c.out, webhook)
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", `webhook JSON file, or "-" for stdin (required)`)
+	return cmd
+}
+
+func webhooksDeleteCommand(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete WEBHOOK_ID",
+		Short: "delete a webhook",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			repo, err := c.webhookRepository(ctx)
+			if err != nil {
+				return err
+			}
+			if err := repo.Delete(ctx, args[0]); err != nil {
+				return err
+			}
+			fmt.Fprintf(c.out, "deleted %s\n", args[0])
+			return nil
+		},
+	}
+}
+
+// webhooksSetEnabledCommand returns the "webhooks enable" or
+// "webhooks disable" command.
+func webhooksSetEnabledCommand(c *cli, enabled bool) *cobra.Command {
+	use, short := "disable WEBHOOK_ID", "disable a webhook"
+	if enabled {
+		use, short = "enable WEBHOOK_ID", "enable a webhook"
+	}
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			repo, err := c.webhookRepository(ctx)
+			if err != nil {
+				return err
+			}
+			webhook, err := repo.GetByID(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			webhook.Enabled = enabled
+			if err := repo.Update(ctx, webhook); err != nil {
+				return fmt.Errorf("failed to update webhook: %w", err)
+			}
+			fmt.Fprintf(c.out, "%s enabled=%t\n", webhook.ID, webhook.Enabled)
+			return nil
+		},
+	}
+}
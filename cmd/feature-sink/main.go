@@ -0,0 +1,216 @@
+// Command feature-sink computes rolling per-user feature vectors for ML.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/caarlos0/env/v10"
+
+	pgdb "github.com/SebastienMelki/causality/internal/db"
+	"github.com/SebastienMelki/causality/internal/features"
+	"github.com/SebastienMelki/causality/internal/nats"
+	"github.com/SebastienMelki/causality/internal/observability"
+)
+
+// Config holds all feature sink configuration.
+type Config struct {
+	// LogLevel is the log level (debug, info, warn, error).
+	LogLevel string `env:"LOG_LEVEL" envDefault:"info"`
+
+	// LogFormat is the log format (json, text).
+	LogFormat string `env:"LOG_FORMAT" envDefault:"json"`
+
+	// HTTPAddr is the address for the health and metrics endpoints.
+	HTTPAddr string `env:"HTTP_ADDR" envDefault:":8083"`
+
+	// ShutdownTimeout is the maximum time to wait for graceful shutdown.
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"30s"`
+
+	// NATS configuration.
+	NATS nats.Config `envPrefix:""`
+
+	// Database configuration for feature tables.
+	Database pgdb.Config `envPrefix:"DATABASE_"`
+
+	// Feature extraction configuration.
+	Features features.Config `envPrefix:""`
+
+	// Runtime diagnostics (pprof, expvar, SIGQUIT goroutine dump).
+	Debug observability.DebugConfig `envPrefix:""`
+}
+
+func main() {
+	if err := run(); err != nil {
+		slog.Error("fatal error", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	// Load configuration from environment
+	var cfg Config
+	if err := env.Parse(&cfg); err != nil {
+		return err
+	}
+
+	// Setup logger
+	logger := setupLogger(cfg.LogLevel, cfg.LogFormat)
+	slog.SetDefault(logger)
+
+	logger.Info("starting feature sink",
+		"log_level", cfg.LogLevel,
+		"http_addr", cfg.HTTPAddr,
+		"nats_url", cfg.NATS.URL,
+		"consumer", cfg.Features.ConsumerName,
+		"window_days", cfg.Features.WindowDays,
+		"refresh_interval", cfg.Features.RefreshInterval,
+	)
+
+	// Create context with cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Setup signal handling
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	observability.DumpGoroutinesOnSignal(ctx, cfg.Debug, logger)
+
+	// Initialize observability (OTel + Prometheus)
+	obs, err := observability.New("feature-sink")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if shutErr := obs.Shutdown(context.Background()); shutErr != nil {
+			logger.Error("observability shutdown error", "error", shutErr)
+		}
+	}()
+
+	// --- Database connection ---
+	pool, err := pgdb.Open(ctx, cfg.Database, logger)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	if err := pool.Migrate(ctx, features.Migrations); err != nil {
+		return err
+	}
+	db := pool.DB
+
+	// --- NATS ---
+	natsClient, err := nats.NewClient(ctx, cfg.NATS, logger)
+	if err != nil {
+		return err
+	}
+	defer natsClient.Close()
+
+	streamMgr := nats.NewStreamManager(natsClient.JetStream(), cfg.NATS.Stream, logger)
+	stream, err := streamMgr.EnsureStream(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := streamMgr.EnsureConsumers(ctx, stream, []nats.ConsumerConfig{
+		{
+			Name:          cfg.Features.ConsumerName,
+			FilterSubject: cfg.Features.FilterSubject,
+			AckWait:       30 * time.Second,
+			MaxAckPending: 10000,
+			MaxDeliver:    5,
+		},
+	}); err != nil {
+		return err
+	}
+
+	// --- Features module ---
+	featuresModule := features.New(db, natsClient.JetStream(), cfg.NATS.Stream.Name, cfg.Features, logger)
+	if err := featuresModule.Start(ctx); err != nil {
+		return err
+	}
+
+	// --- HTTP server (health, metrics) ---
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", obs.MetricsHandler())
+	observability.RegisterDebugRoutes(mux, cfg.Debug)
+	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	httpServer := &http.Server{
+		Addr:    cfg.HTTPAddr,
+		Handler: mux,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info("starting HTTP server", "addr", cfg.HTTPAddr)
+		if srvErr := httpServer.ListenAndServe(); srvErr != nil && srvErr != http.ErrServerClosed {
+			errCh <- srvErr
+		}
+	}()
+
+	logger.Info("feature sink started")
+
+	// Wait for shutdown signal or error
+	select {
+	case sig := <-sigCh:
+		logger.Info("received shutdown signal", "signal", sig)
+	case err := <-errCh:
+		logger.Error("HTTP server error", "error", err)
+	}
+
+	// Graceful shutdown
+	logger.Info("initiating graceful shutdown")
+	cancel()
+
+	featuresModule.Stop()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer shutdownCancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("HTTP server shutdown error", "error", err)
+	}
+
+	if err := natsClient.Drain(); err != nil {
+		logger.Error("NATS drain error", "error", err)
+	}
+
+	logger.Info("feature sink stopped")
+	return nil
+}
+
+// setupLogger creates a logger based on configuration.
+func setupLogger(level, format string) *slog.Logger {
+	var logLevel slog.Level
+	switch level {
+	case "debug":
+		logLevel = slog.LevelDebug
+	case "warn":
+		logLevel = slog.LevelWarn
+	case "error":
+		logLevel = slog.LevelError
+	default:
+		logLevel = slog.LevelInfo
+	}
+
+	opts := &slog.HandlerOptions{
+		Level: logLevel,
+	}
+
+	var handler slog.Handler
+	if format == "text" {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	}
+
+	return slog.New(handler)
+}
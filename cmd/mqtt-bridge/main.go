@@ -0,0 +1,236 @@
+// Command mqtt-bridge ingests events that IoT-style devices publish over
+// MQTT, publishing them to NATS through the ingest service shared with the
+// gateway.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/caarlos0/env/v10"
+
+	"github.com/SebastienMelki/causality/internal/dedup"
+	"github.com/SebastienMelki/causality/internal/ingest"
+	"github.com/SebastienMelki/causality/internal/mqtt"
+	"github.com/SebastienMelki/causality/internal/nats"
+	"github.com/SebastienMelki/causality/internal/observability"
+)
+
+// Config holds all MQTT bridge configuration.
+type Config struct {
+	// LogLevel is the log level (debug, info, warn, error).
+	LogLevel string `env:"LOG_LEVEL" envDefault:"info"`
+
+	// LogFormat is the log format (json, text).
+	LogFormat string `env:"LOG_FORMAT" envDefault:"json"`
+
+	// HTTPAddr is the address for the health and metrics endpoints.
+	HTTPAddr string `env:"HTTP_ADDR" envDefault:":8087"`
+
+	// ShutdownTimeout is the maximum time to wait for graceful shutdown.
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"30s"`
+
+	// MaxBatchEvents is the maximum number of events in a single message,
+	// as for the gateway's batch endpoint.
+	MaxBatchEvents int `env:"MAX_BATCH_EVENTS" envDefault:"1000"`
+
+	// PublishTimeout bounds the time spent publishing a message's events
+	// to NATS (0 disables the deadline).
+	PublishTimeout time.Duration `env:"PUBLISH_TIMEOUT" envDefault:"2s"`
+
+	// Per-event size limits, shared with the gateway.
+	EventLimits ingest.LimitsConfig `envPrefix:"EVENT_LIMIT_"`
+
+	// NATS configuration.
+	NATS nats.Config `envPrefix:""`
+
+	// Dedup configuration.
+	Dedup dedup.Config `envPrefix:""`
+
+	// MQTT broker and bridge configuration.
+	MQTT mqtt.Config `envPrefix:""`
+
+	// Runtime diagnostics (pprof, expvar, SIGQUIT goroutine dump).
+	Debug observability.DebugConfig `envPrefix:""`
+}
+
+func main() {
+	if err := run(); err != nil {
+		slog.Error("fatal error", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	// Load configuration from environment
+	var cfg Config
+	if err := env.Parse(&cfg); err != nil {
+		return err
+	}
+
+	// Setup logger
+	logger := setupLogger(cfg.LogLevel, cfg.LogFormat)
+	slog.SetDefault(logger)
+
+	logger.Info("starting MQTT bridge",
+		"log_level", cfg.LogLevel,
+		"http_addr", cfg.HTTPAddr,
+		"nats_url", cfg.NATS.URL,
+		"broker_url", cfg.MQTT.BrokerURL,
+		"topics", cfg.MQTT.Topics,
+	)
+
+	// Create context with cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Setup signal handling
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	observability.DumpGoroutinesOnSignal(ctx, cfg.Debug, logger)
+
+	// Initialize observability (OTel + Prometheus)
+	obs, err := observability.New("mqtt-bridge")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if shutErr := obs.Shutdown(context.Background()); shutErr != nil {
+			logger.Error("observability shutdown error", "error", shutErr)
+		}
+	}()
+
+	metrics, err := observability.NewMetrics(obs.Meter())
+	if err != nil {
+		return err
+	}
+
+	// --- Dedup module ---
+	dedupModule := dedup.New(cfg.Dedup, metrics, logger)
+	dedupModule.Start(ctx)
+
+	// --- NATS ---
+	natsClient, err := nats.NewClient(ctx, cfg.NATS, logger)
+	if err != nil {
+		return err
+	}
+	defer natsClient.Close()
+
+	streamMgr := nats.NewStreamManager(natsClient.JetStream(), cfg.NATS.Stream, logger)
+	if _, err := streamMgr.EnsureStream(ctx); err != nil {
+		return err
+	}
+
+	publisher := nats.NewPublisher(natsClient.JetStream(), cfg.NATS.Stream.Name, logger)
+	if err := publisher.SetDurability(cfg.NATS.PublishDurability, cfg.NATS.AppPublishDurability); err != nil {
+		return err
+	}
+	publisher.SetPriorityRouting(cfg.NATS.Stream.PriorityEnabled)
+	publisher.SetPriorityStreamName(cfg.NATS.Stream.PriorityStreamName)
+
+	// --- Ingest service shared with the gateway ---
+	limiter, err := ingest.NewLimiter(cfg.EventLimits)
+	if err != nil {
+		return err
+	}
+	ingestService := ingest.NewService(ingest.Config{
+		MaxBatchEvents: cfg.MaxBatchEvents,
+		PublishTimeout: cfg.PublishTimeout,
+	}, publisher, logger, &ingest.Options{
+		Dedup:   dedupModule,
+		Limits:  limiter,
+		Metrics: metrics,
+	})
+
+	// --- MQTT module ---
+	mqttModule, err := mqtt.New(cfg.MQTT, ingestService, logger)
+	if err != nil {
+		return err
+	}
+	mqttModule.Start(ctx)
+
+	// --- HTTP server (health, metrics) ---
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", obs.MetricsHandler())
+	observability.RegisterDebugRoutes(mux, cfg.Debug)
+	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	httpServer := &http.Server{
+		Addr:    cfg.HTTPAddr,
+		Handler: mux,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info("starting HTTP server", "addr", cfg.HTTPAddr)
+		if srvErr := httpServer.ListenAndServe(); srvErr != nil && srvErr != http.ErrServerClosed {
+			errCh <- srvErr
+		}
+	}()
+
+	logger.Info("MQTT bridge started")
+
+	// Wait for shutdown signal or error
+	select {
+	case sig := <-sigCh:
+		logger.Info("received shutdown signal", "signal", sig)
+	case err := <-errCh:
+		logger.Error("HTTP server error", "error", err)
+	}
+
+	// Graceful shutdown
+	logger.Info("initiating graceful shutdown")
+	cancel()
+
+	mqttModule.Stop()
+	dedupModule.Stop()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer shutdownCancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("HTTP server shutdown error", "error", err)
+	}
+
+	if err := natsClient.Drain(); err != nil {
+		logger.Error("NATS drain error", "error", err)
+	}
+
+	logger.Info("MQTT bridge stopped")
+	return nil
+}
+
+// setupLogger creates a logger based on configuration.
+func setupLogger(level, format string) *slog.Logger {
+	var logLevel slog.Level
+	switch level {
+	case "debug":
+		logLevel = slog.LevelDebug
+	case "warn":
+		logLevel = slog.LevelWarn
+	case "error":
+		logLevel = slog.LevelError
+	default:
+		logLevel = slog.LevelInfo
+	}
+
+	opts := &slog.HandlerOptions{
+		Level: logLevel,
+	}
+
+	var handler slog.Handler
+	if format == "text" {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	}
+
+	return slog.New(handler)
+}
@@ -0,0 +1,100 @@
+// Command parquet-stats prints the footer statistics and page index coverage
+// of Parquet files written by the warehouse sink or compaction, and can verify
+// that the pruning columns carry min/max statistics.
+//
+// Usage:
+//
+//	parquet-stats [-columns timestamp_ms,app_id,event_type] [-all] [-verify] FILE...
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+func main() {
+	columns := flag.String("columns", strings.Join(warehouse.StatsColumns, ","), "comma-separated columns to report and verify")
+	all := flag.Bool("all", false, "report every column instead of only -columns")
+	verify := flag.Bool("verify", false, "exit non-zero if any -columns lack min/max statistics or page index bounds")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] FILE...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	wanted := strings.Split(*columns, ",")
+	failed := false
+
+	for _, path := range flag.Args() {
+		missing, err := inspectFile(os.Stdout, path, wanted, *all)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed = true
+			continue
+		}
+		if *verify && len(missing) > 0 {
+			for _, m := range missing {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", path, m)
+			}
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// inspectFile prints the statistics of a single file and returns any missing
+// statistics for the wanted columns.
+func inspectFile(w io.Writer, path string, wanted []string, all bool) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := warehouse.InspectParquet(f, info.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(w, "%s\n  created_by: %s\n  rows: %d\n  row_groups: %d\n",
+		path, stats.CreatedBy, stats.NumRows, stats.RowGroups)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  RG\tCOLUMN\tVALUES\tNULLS\tMIN\tMAX\tPAGES\tPAGES_WITH_BOUNDS")
+	for _, cs := range stats.Columns {
+		if !all && !slices.Contains(wanted, cs.Column) {
+			continue
+		}
+		minValue, maxValue := "-", "-"
+		if cs.HasMinMax {
+			minValue, maxValue = cs.Min, cs.Max
+		}
+		fmt.Fprintf(tw, "  %d\t%s\t%d\t%d\t%s\t%s\t%d\t%d\n",
+			cs.RowGroup, cs.Column, cs.NumValues, cs.NullCount, minValue, maxValue, cs.Pages, cs.PagesWithBounds)
+	}
+	if err := tw.Flush(); err != nil {
+		return nil, err
+	}
+
+	return stats.MissingStats(wanted), nil
+}
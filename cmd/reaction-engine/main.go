@@ -8,14 +8,23 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/caarlos0/env/v10"
 
+	"github.com/SebastienMelki/causality/internal/devices"
+	"github.com/SebastienMelki/causality/internal/digest"
 	"github.com/SebastienMelki/causality/internal/dlq"
+	"github.com/SebastienMelki/causality/internal/forecast"
+	"github.com/SebastienMelki/causality/internal/fx"
 	"github.com/SebastienMelki/causality/internal/nats"
 	"github.com/SebastienMelki/causality/internal/observability"
+	"github.com/SebastienMelki/causality/internal/outbox"
+	"github.com/SebastienMelki/causality/internal/profiles"
+	"github.com/SebastienMelki/causality/internal/push"
 	"github.com/SebastienMelki/causality/internal/reaction"
 	"github.com/SebastienMelki/causality/internal/reaction/db"
+	"github.com/SebastienMelki/causality/internal/warehouse"
 )
 
 // Config holds all reaction engine configuration.
@@ -38,8 +47,42 @@ type Config struct {
 	// Reaction engine configuration.
 	Reaction reaction.Config `envPrefix:""`
 
+	// Forecast configuration for the anomaly baseline job.
+	Forecast forecast.Config `envPrefix:""`
+
+	// Devices configuration for the device registry.
+	Devices devices.Config `envPrefix:""`
+
+	// FX configuration for normalizing purchase amounts to USD.
+	FX fx.Config `envPrefix:""`
+
+	// Push configuration for the push token registry and push_notification
+	// rule actions.
+	Push push.Config `envPrefix:""`
+
+	// Digest configuration for Slack/email digests of anomaly and rule
+	// match notifications.
+	Digest digest.Config `envPrefix:""`
+
+	// Outbox relay configuration (rule change events).
+	Outbox outbox.Config `envPrefix:""`
+
+	// S3 configuration of the event lake, only used by the forecast job and
+	// the retention archive.
+	S3 warehouse.S3Config `envPrefix:"S3_"`
+
+	// Delta Lake configuration, only used by the forecast job to skip files
+	// removed by compaction.
+	Delta warehouse.DeltaConfig `envPrefix:"DELTA_"`
+
 	// ConsumerName is the NATS consumer name.
 	ConsumerName string `env:"CONSUMER_NAME" envDefault:"analysis-engine"`
+
+	// PriorityConsumerName is the NATS consumer name on the priority stream.
+	PriorityConsumerName string `env:"PRIORITY_CONSUMER_NAME" envDefault:"analysis-engine-priority"`
+
+	// Runtime diagnostics (pprof, expvar, SIGQUIT goroutine dump).
+	Debug observability.DebugConfig `envPrefix:""`
 }
 
 func main() {
@@ -91,6 +134,8 @@ func run() error {
 	// Start metrics and health HTTP server
 	metricsMux := http.NewServeMux()
 	metricsMux.Handle("/metrics", obs.MetricsHandler())
+	observability.RegisterDebugRoutes(metricsMux, cfg.Debug)
+	metricsMux.Handle("/debug/metrics-summary", metrics.SummaryHandler())
 	metricsMux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok"))
@@ -109,6 +154,7 @@ func run() error {
 	// Setup signal handling
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	observability.DumpGoroutinesOnSignal(ctx, cfg.Debug, logger)
 
 	// Connect to NATS
 	natsClient, err := nats.NewClient(ctx, cfg.NATS, logger)
@@ -126,15 +172,60 @@ func run() error {
 
 	// Create consumers
 	consumerConfigs := nats.DefaultConsumerConfigs()
+	if cfg.Devices.Enabled {
+		consumerConfigs = append(consumerConfigs, nats.ConsumerConfig{
+			Name:          cfg.Devices.ConsumerName,
+			FilterSubject: cfg.Devices.FilterSubject,
+			AckWait:       30 * time.Second,
+			MaxAckPending: 10000,
+			MaxDeliver:    5,
+		})
+	}
+	if cfg.Push.Enabled {
+		consumerConfigs = append(consumerConfigs, nats.ConsumerConfig{
+			Name:          cfg.Push.ConsumerName,
+			FilterSubject: cfg.Push.FilterSubject,
+			AckWait:       30 * time.Second,
+			MaxAckPending: 1000,
+			MaxDeliver:    5,
+		})
+	}
 	if err := streamMgr.EnsureConsumers(ctx, stream, consumerConfigs); err != nil {
 		return err
 	}
 
+	// Purchases and crashes have their own stream and consumer
+	if cfg.NATS.Stream.PriorityEnabled {
+		priorityStream, err := streamMgr.PriorityStream(ctx)
+		if err != nil {
+			return err
+		}
+		if err := streamMgr.EnsureConsumers(ctx, priorityStream, nats.PriorityConsumerConfigs()); err != nil {
+			return err
+		}
+	}
+
 	// Ensure DLQ stream exists
 	if _, err := streamMgr.EnsureDLQStream(ctx); err != nil {
 		return err
 	}
 
+	// Ensure the derived stream for rule reactions and anomalies exists
+	derivedStream, err := streamMgr.EnsureDerivedStream(ctx)
+	if err != nil {
+		return err
+	}
+	derivedConsumerConfigs := nats.DerivedConsumerConfigs()
+	if cfg.Digest.Enabled {
+		derivedConsumerConfigs = append(derivedConsumerConfigs, cfg.Digest.ConsumerConfig())
+	}
+	if cfg.Reaction.Chain.Enabled {
+		derivedConsumerConfigs = append(derivedConsumerConfigs, cfg.Reaction.Chain.ConsumerConfig())
+	}
+	if err := streamMgr.EnsureConsumers(ctx, derivedStream, derivedConsumerConfigs); err != nil {
+		return err
+	}
+
 	// Create and start DLQ module
 	dlqModule := dlq.New(
 		natsClient.JetStream(),
@@ -156,11 +247,51 @@ func run() error {
 	}
 	defer func() { _ = dbClient.Close() }()
 
+	// Apply the schema of the engine and of the modules sharing its database
+	if err := dbClient.Migrate(ctx,
+		db.Migrations,
+		devices.Migrations,
+		fx.Migrations,
+		push.Migrations,
+		forecast.Migrations,
+		outbox.Migrations,
+	); err != nil {
+		return err
+	}
+
 	// Create repositories
 	ruleRepo := db.NewRuleRepository(dbClient)
 	webhookRepo := db.NewWebhookRepository(dbClient)
 	deliveryRepo := db.NewDeliveryRepository(dbClient)
 	anomalyConfigRepo := db.NewAnomalyConfigRepository(dbClient)
+	escalationPolicyRepo := db.NewEscalationPolicyRepository(dbClient)
+	maintenanceRepo := db.NewMaintenanceWindowRepository(dbClient)
+
+	// Create webhook payload cipher (nil when encryption is not configured)
+	encryption := cfg.Reaction.PayloadEncryption
+	payloadCipher, err := reaction.NewPayloadCipherFromConfig(ctx, encryption)
+	if err != nil {
+		return err
+	}
+	if payloadCipher != nil {
+		keyID := encryption.KMS.KeyID
+		if encryption.Provider == reaction.PayloadKeyProviderLocal {
+			keyID = encryption.KeyID
+			logger.Warn("webhook payloads use a local key-encryption key, which is meant for development; use PAYLOAD_ENCRYPTION_PROVIDER=kms")
+		}
+		logger.Info("webhook payload encryption configured",
+			"enabled", encryption.Enabled,
+			"provider", encryption.Provider,
+			"key_id", keyID,
+		)
+	}
+
+	// Load maintenance windows suppressing rule actions and anomaly alerts
+	maintenance := reaction.NewMaintenanceSchedule(maintenanceRepo, cfg.Reaction.Maintenance, logger)
+	if err := maintenance.Start(ctx); err != nil {
+		return err
+	}
+	reaction.NewMaintenanceHandler(maintenanceRepo, maintenance, logger).RegisterRoutes(metricsMux)
 
 	// Create rule engine
 	engine := reaction.NewEngine(
@@ -170,19 +301,131 @@ func run() error {
 		natsClient.JetStream(),
 		cfg.Reaction.Engine,
 		cfg.Reaction.Dispatcher,
+		payloadCipher,
 		logger,
 	)
+	engine.SetMeter(obs.Meter())
+	engine.SetMaintenance(maintenance)
+	engine.SetDerivedStream(derivedStream)
+	engine.SetHistory(db.NewHistoryRepository(dbClient))
+
+	// Dispatch new webhook deliveries through the delivery stream instead
+	// of polling Postgres for them (DISPATCHER_QUEUE=nats)
+	deliveryQueue, deliveryConsumer, err := reaction.SetupDeliveryQueue(ctx, natsClient.JetStream(), streamMgr, cfg.Reaction.Dispatcher, logger)
+	if err != nil {
+		return err
+	}
+	if deliveryQueue != nil {
+		engine.SetDeliveryQueue(deliveryQueue)
+	}
+	if cfg.Reaction.Engine.RuleChangeListen {
+		ruleChanges, listenErr := dbClient.ListenRuleChanges(ctx)
+		if listenErr != nil {
+			logger.Warn("rule change notifications unavailable, using interval refresh only", "error", listenErr)
+		} else {
+			engine.SetRuleChanges(ruleChanges)
+		}
+	}
+
+	// Relay rule change events, enqueued by the rule repository, to the
+	// audit stream. Rules changed with causalityctl are relayed here too.
+	var outboxRelay *outbox.Relay
+	if cfg.Outbox.Enabled {
+		if _, err := streamMgr.EnsureAuditStream(ctx); err != nil {
+			return err
+		}
+		outboxRelay = outbox.NewRelay(dbClient.DB(), natsClient.JetStream(), cfg.Outbox, logger)
+		outboxRelay.Start(ctx)
+	}
+
+	// Create device registry, read by rule conditions with the "device" source
+	var devicesModule *devices.Module
+	if cfg.Devices.Enabled {
+		devicesModule = devices.New(dbClient.DB(), natsClient.JetStream(), cfg.NATS.Stream.Name, cfg.Devices, logger)
+		if err := devicesModule.Start(ctx); err != nil {
+			return err
+		}
+		engine.SetDeviceLookup(devicesModule)
+		devicesModule.RegisterRoutes(metricsMux)
+	}
+
+	// Read the profile store maintained by profile-sink, resolving the users
+	// of devices for rule conditions with the "user" source
+	if cfg.Reaction.Users.Enabled {
+		usersDBConfig := cfg.Reaction.Database
+		usersDBConfig.Name = cfg.Reaction.Users.DatabaseName
+		usersDB, usersErr := db.NewClient(ctx, usersDBConfig, logger)
+		if usersErr != nil {
+			return usersErr
+		}
+		defer func() { _ = usersDB.Close() }()
+		engine.SetUserLookup(profiles.NewReader(usersDB.DB()), cfg.Reaction.Users)
+	}
+
+	// Create FX rate module, adding amount_usd to purchase_complete events
+	var fxModule *fx.Module
+	if cfg.FX.Enabled {
+		fxModule, err = fx.New(dbClient.DB(), cfg.FX, logger)
+		if err != nil {
+			return err
+		}
+		if err := fxModule.Start(ctx); err != nil {
+			return err
+		}
+		engine.SetCurrencyConverter(fxModule)
+	}
+
+	// Create push token registry, sending push_notification rule actions
+	var pushModule *push.Module
+	if cfg.Push.Enabled {
+		pushModule, err = push.New(dbClient.DB(), natsClient.JetStream(), cfg.NATS.Stream.Name, cfg.Push, logger)
+		if err != nil {
+			return err
+		}
+		if err := pushModule.Start(ctx); err != nil {
+			return err
+		}
+		engine.SetPushSender(pushModule)
+	}
+
+	// Create notification digests of anomaly and rule match notifications
+	var digestModule *digest.Module
+	if cfg.Digest.Enabled {
+		digestModule, err = digest.New(natsClient.JetStream(), cfg.NATS.Stream.DerivedStreamName, cfg.Digest, logger)
+		if err != nil {
+			return err
+		}
+		if err := digestModule.Start(ctx); err != nil {
+			return err
+		}
+	}
+
 	if err := engine.Start(ctx); err != nil {
 		return err
 	}
 
+	// Mount rule admin endpoints (version history, rollback) on the metrics server
+	reaction.NewRuleHandler(ruleRepo, engine, logger).RegisterRoutes(metricsMux)
+
+	// Mount declarative resource sync (rules, webhooks, anomaly configs as code)
+	resourceSyncer := reaction.NewResourceSyncer(ruleRepo, webhookRepo, anomalyConfigRepo)
+	reaction.NewResourceHandler(resourceSyncer, engine, logger).RegisterRoutes(metricsMux)
+
+	// Mount the derived subject catalog
+	reaction.NewSubjectHandler(streamMgr, logger).RegisterRoutes(metricsMux)
+
 	// Create webhook dispatcher
 	dispatcher := reaction.NewDispatcher(
 		deliveryRepo,
 		webhookRepo,
 		cfg.Reaction.Dispatcher,
+		payloadCipher,
 		logger,
 	)
+	dispatcher.SetMetrics(metrics)
+	if deliveryConsumer != nil {
+		dispatcher.SetQueue(deliveryConsumer)
+	}
 	dispatcher.Start(ctx)
 
 	// Create anomaly detector
@@ -192,10 +435,67 @@ func run() error {
 		cfg.Reaction.Anomaly,
 		logger,
 	)
+	if fxModule != nil {
+		anomalyDetector.SetCurrencyConverter(fxModule)
+	}
+	anomalyDetector.SetMaintenance(maintenance)
 	if err := anomalyDetector.Start(ctx); err != nil {
 		return err
 	}
 
+	// Mount anomaly config admin endpoints (CRUD, previews against the hot store)
+	reaction.NewAnomalyConfigHandler(anomalyConfigRepo, anomalyDetector, cfg.Reaction.Anomaly, logger).RegisterRoutes(metricsMux)
+
+	// Create anomaly escalator notifying policy steps of unacknowledged alerts
+	escalator := reaction.NewEscalator(
+		anomalyConfigRepo,
+		escalationPolicyRepo,
+		deliveryRepo,
+		cfg.Reaction.Anomaly,
+		cfg.Reaction.Dispatcher,
+		payloadCipher,
+		logger,
+	)
+	if deliveryQueue != nil {
+		escalator.SetDeliveryQueue(deliveryQueue)
+	}
+	escalator.Start(ctx)
+
+	// Mount escalation policy and alert acknowledgement admin endpoints
+	reaction.NewEscalationHandler(escalationPolicyRepo, anomalyConfigRepo, webhookRepo, logger).RegisterRoutes(metricsMux)
+
+	// Expire old anomaly events and webhook deliveries, archiving them to
+	// the event bucket first when RETENTION_ARCHIVE is set
+	retention := reaction.NewRetention(anomalyConfigRepo, deliveryRepo, cfg.Reaction.Retention, cfg.Reaction.Anomaly, logger)
+	if cfg.Reaction.Retention.Archive {
+		archiveClient, s3Err := warehouse.NewS3Client(ctx, cfg.S3, logger)
+		if s3Err != nil {
+			return s3Err
+		}
+		retention.SetArchive(reaction.NewArchive(archiveClient.RawClient(), cfg.S3, cfg.Reaction.Retention.ArchivePrefix))
+	}
+	retention.Start(ctx)
+
+	// Create anomaly forecast job learning baselines from the warehouse
+	var forecastModule *forecast.Module
+	if cfg.Forecast.Enabled {
+		s3Client, s3Err := warehouse.NewS3Client(ctx, cfg.S3, logger)
+		if s3Err != nil {
+			return s3Err
+		}
+
+		var deltaLog *warehouse.DeltaLog
+		if cfg.Delta.Enabled {
+			deltaLog = warehouse.NewDeltaLog(s3Client.RawClient(), cfg.S3, cfg.Delta, logger)
+		}
+
+		forecastModule, err = forecast.New(s3Client.RawClient(), cfg.S3, deltaLog, dbClient.DB(), cfg.Forecast, logger)
+		if err != nil {
+			return err
+		}
+		forecastModule.Start(ctx)
+	}
+
 	// Create and start consumer
 	consumer := reaction.NewConsumer(
 		natsClient.JetStream(),
@@ -208,11 +508,53 @@ func run() error {
 		logger,
 		metrics,
 	)
+	if cfg.Reaction.Consumer.TrackProcessed {
+		consumer.SetProcessedTracker(db.NewProcessedRepository(dbClient))
+	}
+	consumer.SetSkipPriority(cfg.NATS.Stream.PriorityEnabled)
 
 	if err := consumer.Start(ctx); err != nil {
 		return err
 	}
 
+	// Priority events are consumed separately, so a backlog of other events
+	// cannot delay them
+	var priorityConsumer *reaction.Consumer
+	if cfg.NATS.Stream.PriorityEnabled {
+		priorityConsumer = reaction.NewConsumer(
+			natsClient.JetStream(),
+			engine,
+			anomalyDetector,
+			cfg.PriorityConsumerName,
+			cfg.NATS.Stream.PriorityStreamName,
+			cfg.Reaction.Consumer,
+			cfg.Reaction.ShutdownTimeout,
+			logger,
+			metrics,
+		)
+		if cfg.Reaction.Consumer.TrackProcessed {
+			priorityConsumer.SetProcessedTracker(db.NewProcessedRepository(dbClient))
+		}
+		if err := priorityConsumer.Start(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Chained rules match events published by rules and anomaly detection
+	var chainConsumer *reaction.ChainConsumer
+	if cfg.Reaction.Chain.Enabled {
+		chainConsumer = reaction.NewChainConsumer(
+			natsClient.JetStream(),
+			engine,
+			cfg.NATS.Stream.DerivedStreamName,
+			cfg.Reaction.Chain,
+			logger,
+		)
+		if err := chainConsumer.Start(ctx); err != nil {
+			return err
+		}
+	}
+
 	logger.Info("reaction engine started")
 
 	// Wait for shutdown signal
@@ -226,10 +568,39 @@ func run() error {
 	if err := consumer.Stop(context.Background()); err != nil {
 		logger.Error("consumer stop error", "error", err)
 	}
+	if priorityConsumer != nil {
+		if err := priorityConsumer.Stop(context.Background()); err != nil {
+			logger.Error("priority consumer stop error", "error", err)
+		}
+	}
+	if chainConsumer != nil {
+		chainConsumer.Stop()
+	}
 
+	if forecastModule != nil {
+		forecastModule.Stop()
+	}
+	if devicesModule != nil {
+		devicesModule.Stop()
+	}
+	if fxModule != nil {
+		fxModule.Stop()
+	}
+	if pushModule != nil {
+		pushModule.Stop()
+	}
+	if outboxRelay != nil {
+		outboxRelay.Stop()
+	}
+	retention.Stop()
+	escalator.Stop()
 	anomalyDetector.Stop()
 	dispatcher.Stop()
 	engine.Stop()
+	maintenance.Stop()
+	if digestModule != nil {
+		digestModule.Stop(cfg.Reaction.ShutdownTimeout)
+	}
 	dlqModule.Stop()
 
 	// Stop metrics server
@@ -0,0 +1,214 @@
+// Command search-sink indexes the last hours of events into a local SQLite
+// database and serves the event search API.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/caarlos0/env/v10"
+
+	"github.com/SebastienMelki/causality/internal/nats"
+	"github.com/SebastienMelki/causality/internal/observability"
+	"github.com/SebastienMelki/causality/internal/search"
+)
+
+// Config holds all search sink configuration.
+type Config struct {
+	// LogLevel is the log level (debug, info, warn, error).
+	LogLevel string `env:"LOG_LEVEL" envDefault:"info"`
+
+	// LogFormat is the log format (json, text).
+	LogFormat string `env:"LOG_FORMAT" envDefault:"json"`
+
+	// HTTPAddr is the address for the search API, health, and metrics endpoints.
+	HTTPAddr string `env:"HTTP_ADDR" envDefault:":8086"`
+
+	// ShutdownTimeout is the maximum time to wait for graceful shutdown.
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"30s"`
+
+	// NATS configuration.
+	NATS nats.Config `envPrefix:""`
+
+	// Search index configuration.
+	Search search.Config `envPrefix:""`
+
+	// Runtime diagnostics (pprof, expvar, SIGQUIT goroutine dump).
+	Debug observability.DebugConfig `envPrefix:""`
+}
+
+func main() {
+	if err := run(); err != nil {
+		slog.Error("fatal error", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	// Load configuration from environment
+	var cfg Config
+	if err := env.Parse(&cfg); err != nil {
+		return err
+	}
+
+	// Setup logger
+	logger := setupLogger(cfg.LogLevel, cfg.LogFormat)
+	slog.SetDefault(logger)
+
+	logger.Info("starting search sink",
+		"log_level", cfg.LogLevel,
+		"http_addr", cfg.HTTPAddr,
+		"nats_url", cfg.NATS.URL,
+		"consumer", cfg.Search.ConsumerName,
+		"filter_subject", cfg.Search.FilterSubject,
+		"db_path", cfg.Search.DBPath,
+		"retention", cfg.Search.Retention,
+	)
+
+	// Create context with cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Setup signal handling
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	observability.DumpGoroutinesOnSignal(ctx, cfg.Debug, logger)
+
+	// Initialize observability (OTel + Prometheus)
+	obs, err := observability.New("search-sink")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if shutErr := obs.Shutdown(context.Background()); shutErr != nil {
+			logger.Error("observability shutdown error", "error", shutErr)
+		}
+	}()
+
+	// --- Search index ---
+	db, err := search.Open(ctx, cfg.Search.DBPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	logger.Info("opened search index", "path", cfg.Search.DBPath)
+
+	// --- NATS ---
+	natsClient, err := nats.NewClient(ctx, cfg.NATS, logger)
+	if err != nil {
+		return err
+	}
+	defer natsClient.Close()
+
+	streamMgr := nats.NewStreamManager(natsClient.JetStream(), cfg.NATS.Stream, logger)
+	stream, err := streamMgr.EnsureStream(ctx)
+	if err != nil {
+		return err
+	}
+
+	// A new index only needs the events still within retention.
+	startTime := time.Now().Add(-cfg.Search.Retention)
+	if err := streamMgr.EnsureConsumers(ctx, stream, []nats.ConsumerConfig{
+		{
+			Name:          cfg.Search.ConsumerName,
+			FilterSubject: cfg.Search.FilterSubject,
+			AckWait:       30 * time.Second,
+			MaxAckPending: 10000,
+			MaxDeliver:    5,
+			StartTime:     &startTime,
+		},
+	}); err != nil {
+		return err
+	}
+
+	// --- Search module ---
+	searchModule := search.New(db, natsClient.JetStream(), cfg.NATS.Stream.Name, cfg.Search, logger)
+	if err := searchModule.Start(ctx); err != nil {
+		return err
+	}
+
+	// --- HTTP server (search API, health, metrics) ---
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", obs.MetricsHandler())
+	observability.RegisterDebugRoutes(mux, cfg.Debug)
+	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+	searchModule.RegisterRoutes(mux)
+
+	httpServer := &http.Server{
+		Addr:    cfg.HTTPAddr,
+		Handler: mux,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info("starting HTTP server", "addr", cfg.HTTPAddr)
+		if srvErr := httpServer.ListenAndServe(); srvErr != nil && srvErr != http.ErrServerClosed {
+			errCh <- srvErr
+		}
+	}()
+
+	logger.Info("search sink started")
+
+	// Wait for shutdown signal or error
+	select {
+	case sig := <-sigCh:
+		logger.Info("received shutdown signal", "signal", sig)
+	case err := <-errCh:
+		logger.Error("HTTP server error", "error", err)
+	}
+
+	// Graceful shutdown
+	logger.Info("initiating graceful shutdown")
+	cancel()
+
+	searchModule.Stop()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer shutdownCancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("HTTP server shutdown error", "error", err)
+	}
+
+	if err := natsClient.Drain(); err != nil {
+		logger.Error("NATS drain error", "error", err)
+	}
+
+	logger.Info("search sink stopped")
+	return nil
+}
+
+// setupLogger creates a logger based on configuration.
+func setupLogger(level, format string) *slog.Logger {
+	var logLevel slog.Level
+	switch level {
+	case "debug":
+		logLevel = slog.LevelDebug
+	case "warn":
+		logLevel = slog.LevelWarn
+	case "error":
+		logLevel = slog.LevelError
+	default:
+		logLevel = slog.LevelInfo
+	}
+
+	opts := &slog.HandlerOptions{
+		Level: logLevel,
+	}
+
+	var handler slog.Handler
+	if format == "text" {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	}
+
+	return slog.New(handler)
+}
@@ -3,21 +3,27 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/caarlos0/env/v10"
-	_ "github.com/lib/pq"
 
+	"github.com/SebastienMelki/causality/internal/admingraphql"
+	"github.com/SebastienMelki/causality/internal/audit"
 	"github.com/SebastienMelki/causality/internal/auth"
+	pgdb "github.com/SebastienMelki/causality/internal/db"
 	"github.com/SebastienMelki/causality/internal/dedup"
 	"github.com/SebastienMelki/causality/internal/gateway"
 	"github.com/SebastienMelki/causality/internal/nats"
 	"github.com/SebastienMelki/causality/internal/observability"
+	"github.com/SebastienMelki/causality/internal/outbox"
+	"github.com/SebastienMelki/causality/internal/quality"
+	reactiondb "github.com/SebastienMelki/causality/internal/reaction/db"
+	"github.com/SebastienMelki/causality/internal/sdkpolicy"
 )
 
 // Config holds all server configuration.
@@ -34,29 +40,39 @@ type Config struct {
 	// NATS configuration.
 	NATS nats.Config `envPrefix:""`
 
+	// Embedded NATS server for single-binary evaluation deployments.
+	EmbeddedNATS nats.EmbeddedConfig `envPrefix:""`
+
 	// Database configuration for auth module.
-	Database DatabaseConfig `envPrefix:"DATABASE_"`
+	Database pgdb.Config `envPrefix:"DATABASE_"`
+
+	// Auth configuration (signed request window).
+	Auth auth.Config `envPrefix:""`
 
 	// Dedup configuration.
 	Dedup dedup.Config `envPrefix:""`
-}
 
-// DatabaseConfig holds PostgreSQL connection configuration.
-type DatabaseConfig struct {
-	Host     string `env:"HOST"     envDefault:"localhost"`
-	Port     int    `env:"PORT"     envDefault:"5432"`
-	User     string `env:"USER"     envDefault:"hive"`
-	Password string `env:"PASSWORD" envDefault:"hive"`
-	Name     string `env:"NAME"     envDefault:"causality_server"`
-	SSLMode  string `env:"SSL_MODE" envDefault:"disable"`
-}
+	// Ingestion audit log configuration.
+	Audit audit.Config `envPrefix:""`
 
-// DSN returns the PostgreSQL connection string.
-func (c DatabaseConfig) DSN() string {
-	return fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		c.Host, c.Port, c.User, c.Password, c.Name, c.SSLMode,
-	)
+	// Per-app data-quality metrics configuration.
+	Quality quality.Config `envPrefix:""`
+
+	// Per-app minimum SDK version configuration.
+	SDKPolicy sdkpolicy.Config `envPrefix:""`
+
+	// Outbox relay configuration (API key change events).
+	Outbox outbox.Config `envPrefix:""`
+
+	// Admin GraphQL API (reads the reaction engine database).
+	GraphQL admingraphql.Config `envPrefix:""`
+
+	// ReactionDatabase is the reaction engine database, used by the admin
+	// GraphQL API.
+	ReactionDatabase reactiondb.Config `envPrefix:"REACTION_DATABASE_"`
+
+	// Runtime diagnostics (pprof, expvar, SIGQUIT goroutine dump).
+	Debug observability.DebugConfig `envPrefix:""`
 }
 
 func main() {
@@ -92,6 +108,7 @@ func run() error {
 	// Setup signal handling
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	observability.DumpGoroutinesOnSignal(ctx, cfg.Debug, logger)
 
 	// --- Observability module ---
 	obs, err := observability.New("causality-server")
@@ -105,25 +122,56 @@ func run() error {
 	}
 
 	// --- Database connection ---
-	db, err := sql.Open("postgres", cfg.Database.DSN())
+	pool, err := pgdb.Open(ctx, cfg.Database, logger)
 	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+		return err
 	}
-	defer db.Close()
-
-	if err := db.PingContext(ctx); err != nil {
-		return fmt.Errorf("failed to ping database: %w", err)
+	defer pool.Close()
+
+	if err := pool.Migrate(ctx,
+		auth.Migrations,
+		audit.Migrations,
+		quality.Migrations,
+		sdkpolicy.Migrations,
+		outbox.Migrations,
+	); err != nil {
+		return err
 	}
-	logger.Info("connected to database", "host", cfg.Database.Host, "name", cfg.Database.Name)
+	db := pool.DB
 
 	// --- Auth module ---
-	authModule := auth.New(db, logger)
+	authModule := auth.New(db, cfg.Auth, logger)
+
+	// --- Admin GraphQL API ---
+	var graphqlModule *admingraphql.Module
+	if cfg.GraphQL.Enabled {
+		reactionDB, err := reactiondb.NewClient(ctx, cfg.ReactionDatabase, logger)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = reactionDB.Close() }()
+
+		graphqlModule, err = admingraphql.New(authModule, reactionDB, cfg.GraphQL, logger)
+		if err != nil {
+			return err
+		}
+	}
 
 	// --- Dedup module ---
 	dedupModule := dedup.New(cfg.Dedup, metrics, logger)
 	dedupModule.Start(ctx)
 
 	// --- NATS ---
+	if cfg.EmbeddedNATS.Enabled {
+		embedded, err := nats.StartEmbeddedServer(cfg.EmbeddedNATS, logger)
+		if err != nil {
+			return err
+		}
+		// Deferred before the client so it shuts down after the drain
+		defer embedded.Shutdown()
+		cfg.NATS.URL = embedded.ClientURL()
+	}
+
 	natsClient, err := nats.NewClient(ctx, cfg.NATS, logger)
 	if err != nil {
 		return err
@@ -136,16 +184,87 @@ func run() error {
 		return err
 	}
 
+	// The gateway's publish probe needs its own stream
+	if cfg.Gateway.PublishProbe.Enabled {
+		if _, err := streamMgr.EnsureProbeStream(ctx); err != nil {
+			return err
+		}
+	}
+
 	// Create publisher
 	publisher := nats.NewPublisher(natsClient.JetStream(), cfg.NATS.Stream.Name, logger)
+	if err := publisher.SetDurability(cfg.NATS.PublishDurability, cfg.NATS.AppPublishDurability); err != nil {
+		return err
+	}
+	publisher.SetPriorityRouting(cfg.NATS.Stream.PriorityEnabled)
+	publisher.SetPriorityStreamName(cfg.NATS.Stream.PriorityStreamName)
+
+	// --- Audit module ---
+	var auditModule *audit.Module
+	if cfg.Audit.Enabled {
+		if _, err := streamMgr.EnsureAuditStream(ctx); err != nil {
+			return err
+		}
+		auditModule = audit.New(natsClient.JetStream(), db, cfg.Audit, logger)
+		auditModule.Start(ctx)
+	}
+
+	// --- Outbox relay ---
+	// Admin change events are published to the audit stream
+	var outboxRelay *outbox.Relay
+	if cfg.Outbox.Enabled {
+		if _, err := streamMgr.EnsureAuditStream(ctx); err != nil {
+			return err
+		}
+		outboxRelay = outbox.NewRelay(db, natsClient.JetStream(), cfg.Outbox, logger)
+		outboxRelay.Start(ctx)
+	}
+
+	// --- Data-quality module ---
+	var qualityModule *quality.Module
+	if cfg.Quality.Enabled {
+		qualityModule = quality.New(db, cfg.Quality, logger)
+		qualityModule.Start(ctx)
+	}
+
+	// --- SDK version policy module ---
+	var sdkPolicyModule *sdkpolicy.Module
+	if cfg.SDKPolicy.Enabled {
+		sdkPolicyModule = sdkpolicy.New(db, cfg.SDKPolicy, logger)
+		sdkPolicyModule.Start(ctx)
+	}
 
 	// --- HTTP Server ---
 	serverOpts := &gateway.ServerOpts{
-		AuthMiddleware:      authModule.AuthMiddleware(),
-		MetricsHandler:      obs.MetricsHandler(),
-		Metrics:             metrics,
-		Dedup:               dedupModule,
-		AdminRouteRegistrar: authModule.RegisterAdminRoutes,
+		AuthMiddleware: authModule.AuthMiddleware(),
+		MetricsHandler: obs.MetricsHandler(),
+		Metrics:        metrics,
+		Dedup:          dedupModule,
+		Database:       pool,
+		AdminRouteRegistrar: func(mux *http.ServeMux) {
+			authModule.RegisterAdminRoutes(mux)
+			if graphqlModule != nil {
+				graphqlModule.RegisterRoutes(mux)
+			}
+			if qualityModule != nil {
+				qualityModule.RegisterRoutes(mux)
+			}
+			if sdkPolicyModule != nil {
+				sdkPolicyModule.RegisterRoutes(mux)
+			}
+		},
+		DebugRouteRegistrar: func(mux *http.ServeMux) {
+			observability.RegisterDebugRoutes(mux, cfg.Debug)
+		},
+	}
+	if auditModule != nil {
+		serverOpts.Audit = auditModule
+	}
+	if qualityModule != nil {
+		serverOpts.Quality = qualityModule
+	}
+	if sdkPolicyModule != nil {
+		serverOpts.SDKPolicy = sdkPolicyModule
 	}
 
 	server, err := gateway.NewServer(cfg.Gateway, natsClient, publisher, logger, serverOpts)
@@ -165,6 +284,12 @@ func run() error {
 		"dedup_window", cfg.Dedup.Window.String(),
 		"max_body_size", cfg.Gateway.MaxBodySize,
 		"rate_limit_per_key_rps", cfg.Gateway.RateLimit.PerKeyRPS,
+		"audit", cfg.Audit.Enabled,
+		"audit_postgres", cfg.Audit.PostgresEnabled,
+		"data_quality", cfg.Quality.Enabled,
+		"sdk_policy", cfg.SDKPolicy.Enabled,
+		"outbox_relay", cfg.Outbox.Enabled,
+		"graphql", cfg.GraphQL.Enabled,
 	)
 
 	// Wait for shutdown signal or error
@@ -188,6 +313,26 @@ func run() error {
 	dedupModule.Stop()
 	logger.Info("dedup module stopped")
 
+	if auditModule != nil {
+		auditModule.Stop()
+		logger.Info("audit module stopped")
+	}
+
+	if qualityModule != nil {
+		qualityModule.Stop()
+		logger.Info("quality module stopped")
+	}
+
+	if sdkPolicyModule != nil {
+		sdkPolicyModule.Stop()
+		logger.Info("sdk policy module stopped")
+	}
+
+	if outboxRelay != nil {
+		outboxRelay.Stop()
+		logger.Info("outbox relay stopped")
+	}
+
 	if err := obs.Shutdown(context.Background()); err != nil {
 		logger.Error("observability shutdown error", "error", err)
 	}
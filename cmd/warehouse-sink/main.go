@@ -3,6 +3,7 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"log/slog"
 	"net/http"
 	"os"
@@ -12,8 +13,12 @@ import (
 	"github.com/caarlos0/env/v10"
 
 	"github.com/SebastienMelki/causality/internal/compaction"
+	pgdb "github.com/SebastienMelki/causality/internal/db"
+	"github.com/SebastienMelki/causality/internal/export"
+	"github.com/SebastienMelki/causality/internal/fx"
 	"github.com/SebastienMelki/causality/internal/nats"
 	"github.com/SebastienMelki/causality/internal/observability"
+	"github.com/SebastienMelki/causality/internal/rollup"
 	"github.com/SebastienMelki/causality/internal/warehouse"
 )
 
@@ -37,8 +42,25 @@ type Config struct {
 	// Compaction configuration.
 	Compaction compaction.Config `envPrefix:""`
 
+	// FX configuration for normalizing purchase amounts to USD.
+	FX fx.Config `envPrefix:""`
+
+	// Export configuration for loading partitions into external warehouses.
+	Export export.Config `envPrefix:""`
+
+	// Rollup configuration for hourly and daily aggregate rollups.
+	Rollup rollup.Config `envPrefix:""`
+
+	// Database configuration, only used when compaction schedules are
+	// loaded from PostgreSQL (COMPACTION_SCHEDULE_SOURCE=postgres), FX
+	// rates are enabled, or partitions are exported.
+	Database pgdb.Config `envPrefix:"DATABASE_"`
+
 	// ConsumerName is the NATS consumer name.
 	ConsumerName string `env:"CONSUMER_NAME" envDefault:"warehouse-sink"`
+
+	// Runtime diagnostics (pprof, expvar, SIGQUIT goroutine dump).
+	Debug observability.DebugConfig `envPrefix:""`
 }
 
 func main() {
@@ -92,6 +114,8 @@ func run() error {
 	// Start metrics and health HTTP server
 	metricsMux := http.NewServeMux()
 	metricsMux.Handle("/metrics", obs.MetricsHandler())
+	observability.RegisterDebugRoutes(metricsMux, cfg.Debug)
+	metricsMux.Handle("/debug/metrics-summary", metrics.SummaryHandler())
 	metricsMux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok"))
@@ -110,6 +134,7 @@ func run() error {
 	// Setup signal handling
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	observability.DumpGoroutinesOnSignal(ctx, cfg.Debug, logger)
 
 	// Connect to NATS
 	natsClient, err := nats.NewClient(ctx, cfg.NATS, logger)
@@ -131,6 +156,11 @@ func run() error {
 		return err
 	}
 
+	// Ensure the derived stream compaction alerts are published to exists
+	if _, err := streamMgr.EnsureDerivedStream(ctx); err != nil {
+		return err
+	}
+
 	// Create S3 client
 	s3Client, err := warehouse.NewS3Client(ctx, cfg.Warehouse.S3, logger)
 	if err != nil {
@@ -142,10 +172,39 @@ func run() error {
 		return err
 	}
 
+	// Initialize Delta Lake transaction log (nil when disabled)
+	var deltaLog *warehouse.DeltaLog
+	if cfg.Warehouse.Delta.Enabled {
+		deltaLog = warehouse.NewDeltaLog(s3Client.RawClient(), cfg.Warehouse.S3, cfg.Warehouse.Delta, logger)
+		if err := deltaLog.Init(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Connect to the database when compaction schedules, FX rates or export
+	// watermarks live in PostgreSQL
+	var db *sql.DB
+	if cfg.FX.Enabled || cfg.Export.Enabled || (cfg.Compaction.Enabled && cfg.Compaction.ScheduleSource == compaction.ScheduleSourcePostgres) {
+		pool, err := pgdb.Open(ctx, cfg.Database, logger)
+		if err != nil {
+			return err
+		}
+		defer pool.Close()
+
+		if err := pool.Migrate(ctx, compaction.Migrations, fx.Migrations, export.Migrations); err != nil {
+			return err
+		}
+		db = pool.DB
+	}
+
 	// Create and start compaction module
 	compactionMod := compaction.New(
 		s3Client.RawClient(),
 		cfg.Warehouse.S3,
+		cfg.Warehouse.Parquet,
+		deltaLog,
+		natsClient.JetStream(),
+		db,
 		cfg.Compaction,
 		metrics,
 		logger,
@@ -154,17 +213,57 @@ func run() error {
 		return err
 	}
 
+	// Mount the manual compaction trigger on the metrics server
+	compactionMod.RegisterRoutes(metricsMux)
+
+	// Create and start the export of settled partitions to external warehouses
+	exportMod, err := export.New(s3Client.RawClient(), cfg.Warehouse.S3, deltaLog, db, cfg.Export, logger)
+	if err != nil {
+		return err
+	}
+	exportMod.Start(ctx)
+
+	// Create and start the hourly and daily aggregate rollups
+	rollupMod, err := rollup.New(s3Client.RawClient(), cfg.Warehouse.S3, deltaLog, cfg.Rollup, logger)
+	if err != nil {
+		return err
+	}
+	rollupMod.Start(ctx)
+
 	// Create and start consumer
 	consumer := warehouse.NewConsumer(
 		natsClient.JetStream(),
 		cfg.Warehouse,
 		s3Client,
+		deltaLog,
 		cfg.ConsumerName,
 		cfg.NATS.Stream.Name,
 		logger,
 		metrics,
 	)
 
+	// Promote frequent custom event property keys to dedicated columns
+	if cfg.Warehouse.Promotion.Enabled {
+		promoter := warehouse.NewColumnPromoter(s3Client.RawClient(), cfg.Warehouse.S3, cfg.Warehouse.Promotion, logger)
+		if err := promoter.Load(ctx); err != nil {
+			return err
+		}
+		consumer.SetColumnPromoter(promoter)
+	}
+
+	// Create and start the FX rate module normalizing purchase amounts to USD
+	var fxModule *fx.Module
+	if cfg.FX.Enabled {
+		fxModule, err = fx.New(db, cfg.FX, logger)
+		if err != nil {
+			return err
+		}
+		if err := fxModule.Start(ctx); err != nil {
+			return err
+		}
+		consumer.SetCurrencyConverter(fxModule)
+	}
+
 	if err := consumer.Start(ctx); err != nil {
 		return err
 	}
@@ -179,8 +278,13 @@ func run() error {
 	logger.Info("initiating graceful shutdown")
 	cancel()
 
-	// Stop compaction before consumer
+	// Stop compaction, export and rollups before consumer
 	compactionMod.Stop()
+	exportMod.Stop()
+	rollupMod.Stop()
+	if fxModule != nil {
+		fxModule.Stop()
+	}
 
 	// Stop consumer with shutdown timeout
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Warehouse.ShutdownTimeout)
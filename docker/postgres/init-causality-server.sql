@@ -11,6 +11,10 @@ CREATE TABLE IF NOT EXISTS api_keys (
     app_id     TEXT NOT NULL,
     key_hash   TEXT NOT NULL UNIQUE,
     name       TEXT NOT NULL DEFAULT '',
+    -- Event categories or category.type pairs the key may send; empty allows all
+    allowed_event_types TEXT[] NOT NULL DEFAULT '{}',
+    -- HMAC secret for signed requests; empty means the key is not signed
+    signing_secret TEXT NOT NULL DEFAULT '',
     revoked    BOOLEAN NOT NULL DEFAULT false,
     created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
     revoked_at TIMESTAMPTZ
@@ -30,6 +34,225 @@ INSERT INTO api_keys (app_id, key_hash, name) VALUES
     ('dev-app', '247d08f3e13938b244f5ecd8966f1778e5e72b175820f46ba86c9c039272affa', 'Development key (all examples)')
 ON CONFLICT (key_hash) DO NOTHING;
 
+-- Per-request ingestion audit log (optional, AUDIT_POSTGRES_ENABLED=true)
+CREATE TABLE IF NOT EXISTS ingest_audit_log (
+    id              BIGSERIAL PRIMARY KEY,
+    request_id      TEXT NOT NULL,
+    api_key_id      TEXT NOT NULL DEFAULT '',
+    app_id          TEXT NOT NULL DEFAULT '',
+    method          TEXT NOT NULL,
+    path            TEXT NOT NULL,
+    client_ip       TEXT NOT NULL DEFAULT '',
+    user_agent      TEXT NOT NULL DEFAULT '',
+    sdk_name        TEXT NOT NULL DEFAULT '',
+    sdk_version     TEXT NOT NULL DEFAULT '',
+    sdk_platform    TEXT NOT NULL DEFAULT '',
+    sdk_capabilities JSONB NOT NULL DEFAULT '[]',
+    status_code     INTEGER NOT NULL,
+    decision        TEXT NOT NULL,
+    event_count     INTEGER NOT NULL DEFAULT 0,
+    accepted_count  INTEGER NOT NULL DEFAULT 0,
+    rejected_count  INTEGER NOT NULL DEFAULT 0,
+    duplicate_count INTEGER NOT NULL DEFAULT 0,
+    reasons         JSONB NOT NULL DEFAULT '[]',
+    duration_ms     BIGINT NOT NULL DEFAULT 0,
+    received_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_ingest_audit_log_app_received ON ingest_audit_log(app_id, received_at);
+CREATE INDEX idx_ingest_audit_log_key_received ON ingest_audit_log(api_key_id, received_at);
+CREATE INDEX idx_ingest_audit_log_request_id ON ingest_audit_log(request_id);
+
+-- Per-app hourly ingestion data-quality counters (gateway)
+CREATE TABLE IF NOT EXISTS data_quality_hourly (
+    app_id       TEXT NOT NULL,
+    hour         TIMESTAMPTZ NOT NULL,
+    accepted     BIGINT NOT NULL DEFAULT 0,
+    deduplicated BIGINT NOT NULL DEFAULT 0,
+    rejected     BIGINT NOT NULL DEFAULT 0,
+    late         BIGINT NOT NULL DEFAULT 0,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (app_id, hour)
+);
+
+CREATE INDEX idx_data_quality_hourly_hour ON data_quality_hourly(hour);
+
+-- Per-app hourly rejection code, clock skew and SDK distributions (gateway)
+CREATE TABLE IF NOT EXISTS data_quality_buckets (
+    app_id    TEXT NOT NULL,
+    hour      TIMESTAMPTZ NOT NULL,
+    dimension TEXT NOT NULL,
+    bucket    TEXT NOT NULL,
+    count     BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (app_id, hour, dimension, bucket)
+);
+
+CREATE INDEX idx_data_quality_buckets_hour ON data_quality_buckets(hour);
+
+-- Per-app minimum SDK versions (gateway)
+CREATE TABLE IF NOT EXISTS sdk_version_policies (
+    app_id      TEXT NOT NULL,
+    sdk_name    TEXT NOT NULL,
+    min_version TEXT NOT NULL,
+    action      TEXT NOT NULL DEFAULT 'reject' CHECK (action IN ('reject', 'flag')),
+    message     TEXT NOT NULL DEFAULT '',
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (app_id, sdk_name)
+);
+
+-- Per-app daily usage counters (usage-meter)
+CREATE TABLE IF NOT EXISTS usage_daily (
+    app_id          TEXT NOT NULL,
+    day             DATE NOT NULL,
+    accepted_events BIGINT NOT NULL DEFAULT 0,
+    ingested_bytes  BIGINT NOT NULL DEFAULT 0,
+    reported_events BIGINT NOT NULL DEFAULT 0,
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (app_id, day)
+);
+
+CREATE INDEX idx_usage_daily_day ON usage_daily(day);
+
+-- App to Stripe metered subscription item mapping (optional)
+CREATE TABLE IF NOT EXISTS usage_stripe_items (
+    app_id               TEXT PRIMARY KEY,
+    subscription_item_id TEXT NOT NULL,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Per-app hourly distinct device and user sketches (usage-meter)
+CREATE TABLE IF NOT EXISTS usage_hourly_sketches (
+    app_id     TEXT NOT NULL,
+    hour       TIMESTAMPTZ NOT NULL,
+    devices    BYTEA NOT NULL,
+    users      BYTEA NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (app_id, hour)
+);
+
+CREATE INDEX idx_usage_hourly_sketches_hour ON usage_hourly_sketches(hour);
+
+-- Per-user daily activity, aggregated from the event stream (feature-sink)
+CREATE TABLE IF NOT EXISTS user_activity_daily (
+    app_id         TEXT NOT NULL,
+    device_id      TEXT NOT NULL,
+    day            DATE NOT NULL,
+    user_id        TEXT NOT NULL DEFAULT '',
+    events         BIGINT NOT NULL DEFAULT 0,
+    sessions       BIGINT NOT NULL DEFAULT 0,
+    purchases      BIGINT NOT NULL DEFAULT 0,
+    revenue_cents  BIGINT NOT NULL DEFAULT 0,
+    ios_events     BIGINT NOT NULL DEFAULT 0,
+    android_events BIGINT NOT NULL DEFAULT 0,
+    web_events     BIGINT NOT NULL DEFAULT 0,
+    other_events   BIGINT NOT NULL DEFAULT 0,
+    last_event_at  TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (app_id, device_id, day)
+);
+
+-- Retention pruning and window scans by day
+CREATE INDEX idx_user_activity_daily_day ON user_activity_daily(day);
+
+-- Rolling per-user feature vectors, recomputed on a schedule
+CREATE TABLE IF NOT EXISTS user_features (
+    app_id         TEXT NOT NULL,
+    device_id      TEXT NOT NULL,
+    user_id        TEXT NOT NULL DEFAULT '',
+    recency_days   DOUBLE PRECISION NOT NULL,
+    active_days    BIGINT NOT NULL,
+    events         BIGINT NOT NULL,
+    sessions       BIGINT NOT NULL,
+    purchases      BIGINT NOT NULL,
+    monetary_cents BIGINT NOT NULL,
+    ios_share      DOUBLE PRECISION NOT NULL,
+    android_share  DOUBLE PRECISION NOT NULL,
+    web_share      DOUBLE PRECISION NOT NULL,
+    other_share    DOUBLE PRECISION NOT NULL,
+    first_seen_day DATE NOT NULL,
+    last_seen_at   TIMESTAMPTZ NOT NULL,
+    window_days    INTEGER NOT NULL,
+    computed_at    TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (app_id, device_id)
+);
+
+-- Compaction schedules ('*' is the default, other rows are per-app overrides)
+CREATE TABLE IF NOT EXISTS compaction_schedules (
+    app_id      TEXT PRIMARY KEY,
+    cron        TEXT NOT NULL DEFAULT '',
+    target_size BIGINT NOT NULL DEFAULT 0,
+    min_files   INTEGER NOT NULL DEFAULT 0,
+    blackouts   JSONB NOT NULL DEFAULT '[]',
+    enabled     BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Warehouse export progress per destination and app (warehouse-sink)
+CREATE TABLE IF NOT EXISTS export_watermarks (
+    destination    TEXT NOT NULL,
+    app_id         TEXT NOT NULL,
+    exported_until TIMESTAMPTZ NOT NULL,
+    attempts       INTEGER NOT NULL DEFAULT 0,
+    last_error     TEXT NOT NULL DEFAULT '',
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (destination, app_id)
+);
+
+-- Per-user profiles, maintained from identity events (profile-sink)
+CREATE TABLE IF NOT EXISTS user_profiles (
+    app_id        TEXT NOT NULL,
+    user_id       TEXT NOT NULL,
+    traits        JSONB NOT NULL DEFAULT '{}',
+    first_seen_at TIMESTAMPTZ NOT NULL,
+    last_seen_at  TIMESTAMPTZ NOT NULL,
+    signed_up_at  TIMESTAMPTZ,
+    last_login_at TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (app_id, user_id)
+);
+
+-- Devices each user has been seen on
+CREATE TABLE IF NOT EXISTS user_profile_devices (
+    app_id        TEXT NOT NULL,
+    user_id       TEXT NOT NULL,
+    device_id     TEXT NOT NULL,
+    platform      TEXT NOT NULL DEFAULT '',
+    os_version    TEXT NOT NULL DEFAULT '',
+    app_version   TEXT NOT NULL DEFAULT '',
+    device_model  TEXT NOT NULL DEFAULT '',
+    first_seen_at TIMESTAMPTZ NOT NULL,
+    last_seen_at  TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (app_id, user_id, device_id),
+    FOREIGN KEY (app_id, user_id) REFERENCES user_profiles(app_id, user_id) ON DELETE CASCADE
+);
+
+-- Profile lookup by device
+CREATE INDEX idx_user_profile_devices_device ON user_profile_devices(app_id, device_id);
+
+-- Daily foreign exchange rates used to normalize purchase amounts to USD
+CREATE TABLE IF NOT EXISTS fx_rates (
+    rate_date DATE NOT NULL,
+    currency CHAR(3) NOT NULL,
+    units_per_usd DOUBLE PRECISION NOT NULL,
+    fetched_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (rate_date, currency)
+);
+
+-- Admin change events awaiting publication to NATS (auth outbox relay)
+CREATE TABLE IF NOT EXISTS outbox (
+    id           BIGSERIAL PRIMARY KEY,
+    event_id     TEXT NOT NULL UNIQUE,
+    subject      TEXT NOT NULL,
+    payload      JSONB NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    published_at TIMESTAMPTZ
+);
+
+-- Relay scans: pending events in insertion order
+CREATE INDEX idx_outbox_pending ON outbox(id) WHERE published_at IS NULL;
+
+-- Retention pruning of published events
+CREATE INDEX idx_outbox_published_at ON outbox(published_at) WHERE published_at IS NOT NULL;
+
 -- Grant permissions
 GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA public TO hive;
 GRANT ALL PRIVILEGES ON ALL SEQUENCES IN SCHEMA public TO hive;
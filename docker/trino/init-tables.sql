@@ -1,7 +1,10 @@
 -- Create schema
 CREATE SCHEMA IF NOT EXISTS hive.causality WITH (location = 's3a://causality-events/');
 
--- Create events table with Hive partitioning
+-- Create events table with Hive partitioning. partitioned_by must list the
+-- columns of S3_PARTITION_TEMPLATE in order (app_id/date/hour by default), and
+-- Trino requires partition columns last: move event_category down for a
+-- category template and add user_bucket INTEGER for a user_bucket template.
 CREATE TABLE IF NOT EXISTS hive.causality.events (
     id VARCHAR,
     app_id VARCHAR,
@@ -25,6 +28,13 @@ CREATE TABLE IF NOT EXISTS hive.causality.events (
     is_jailbroken BOOLEAN,
     is_emulator BOOLEAN,
     sdk_version VARCHAR,
+    clock_skew_ms BIGINT,
+    sdk_name VARCHAR,
+    country VARCHAR,
+    region VARCHAR,
+    amount_usd DOUBLE,
+    experiment_id VARCHAR,
+    experiment_variant VARCHAR,
     payload_json VARCHAR,
     year INTEGER,
     month INTEGER,
@@ -0,0 +1,123 @@
+package admingraphql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	graphql "github.com/graph-gophers/graphql-go"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// ErrInvalidPage is returned for list fields with a limit outside 1 to 500
+// or a negative offset.
+var ErrInvalidPage = errors.New("limit must be between 1 and 500 and offset must be non-negative")
+
+// Config holds the admin GraphQL API configuration.
+//
+// Environment variable overrides:
+//   - GRAPHQL_ENABLED: serve POST /api/admin/graphql (default: false)
+//   - GRAPHQL_MAX_DEPTH: maximum query nesting depth (default: 8)
+type Config struct {
+	// Enabled mounts the GraphQL endpoint. It requires the reaction engine
+	// database.
+	Enabled bool `env:"GRAPHQL_ENABLED" envDefault:"false"`
+
+	// MaxDepth bounds query nesting so a single request cannot fan out into
+	// an unbounded number of queries.
+	MaxDepth int `env:"GRAPHQL_MAX_DEPTH" envDefault:"8"`
+}
+
+// Module is the admin GraphQL API facade.
+type Module struct {
+	schema *graphql.Schema
+	logger *slog.Logger
+}
+
+// New creates the admin GraphQL module reading apps and keys from keys and
+// all other resources from the reaction engine database.
+func New(keys KeySource, reactionDB *db.Client, cfg Config, logger *slog.Logger) (*Module, error) {
+	return newModule(&sources{
+		keys:       keys,
+		rules:      db.NewRuleRepository(reactionDB),
+		webhooks:   db.NewWebhookRepository(reactionDB),
+		deliveries: db.NewDeliveryRepository(reactionDB),
+		anomalies:  db.NewAnomalyConfigRepository(reactionDB),
+	}, cfg, logger)
+}
+
+// newModule creates the module over the given sources. Tests use it to
+// inject in-memory stores.
+func newModule(src *sources, cfg Config, logger *slog.Logger) (*Module, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.MaxDepth <= 0 {
+		cfg.MaxDepth = 8
+	}
+
+	s, err := graphql.ParseSchema(schema, &queryResolver{src: src},
+		graphql.MaxDepth(cfg.MaxDepth),
+		graphql.UseStringDescriptions(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse admin GraphQL schema: %w", err)
+	}
+
+	return &Module{
+		schema: s,
+		logger: logger.With("component", "admin-graphql"),
+	}, nil
+}
+
+// RegisterRoutes mounts the GraphQL endpoint on the given ServeMux:
+//   - POST /api/admin/graphql - Execute a query
+//
+// TODO(phase-3): Protect this endpoint with session auth + RBAC.
+func (m *Module) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/admin/graphql", m.handleQuery)
+}
+
+// queryRequest is a GraphQL request body.
+type queryRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// handleQuery handles POST /api/admin/graphql. Query errors are reported in
+// the GraphQL response body with status 200, as GraphQL clients expect.
+func (m *Module) handleQuery(w http.ResponseWriter, r *http.Request) {
+	var req queryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "invalid request body: " + err.Error(),
+		})
+		return
+	}
+	if req.Query == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "query is required",
+		})
+		return
+	}
+
+	resp := m.schema.Exec(r.Context(), req.Query, req.OperationName, req.Variables)
+	if len(resp.Errors) > 0 {
+		m.logger.Debug("GraphQL query returned errors",
+			"operation", req.OperationName,
+			"errors", len(resp.Errors),
+			"first_error", resp.Errors[0].Message,
+		)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
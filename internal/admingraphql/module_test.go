@@ -0,0 +1,387 @@
+package admingraphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/auth"
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+type fakeKeys struct {
+	keys []auth.APIKey
+}
+
+func (f *fakeKeys) ListApps(_ context.Context) ([]string, error) {
+	var apps []string
+	seen := make(map[string]bool)
+	for _, k := range f.keys {
+		if !seen[k.AppID] {
+			seen[k.AppID] = true
+			apps = append(apps, k.AppID)
+		}
+	}
+	return apps, nil
+}
+
+func (f *fakeKeys) ListKeys(_ context.Context, appID string) ([]auth.APIKey, error) {
+	var keys []auth.APIKey
+	for _, k := range f.keys {
+		if k.AppID == appID {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+type fakeStore struct {
+	rules      []*db.Rule
+	webhooks   []*db.Webhook
+	deliveries []*db.WebhookDelivery
+	configs    []*db.AnomalyConfig
+	events     []*db.AnomalyEvent
+}
+
+type fakeRules struct{ *fakeStore }
+
+func (f fakeRules) GetByID(_ context.Context, id string) (*db.Rule, error) {
+	for _, r := range f.rules {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return nil, db.ErrRuleNotFound
+}
+
+func (f fakeRules) List(_ context.Context, limit, offset int) ([]*db.Rule, error) {
+	return paginate(f.rules, limit, offset), nil
+}
+
+func (f fakeRules) ListByAppID(_ context.Context, appID string, limit, offset int) ([]*db.Rule, error) {
+	var rules []*db.Rule
+	for _, r := range f.rules {
+		if r.AppID != nil && *r.AppID == appID {
+			rules = append(rules, r)
+		}
+	}
+	return paginate(rules, limit, offset), nil
+}
+
+type fakeWebhooks struct{ *fakeStore }
+
+func (f fakeWebhooks) GetByID(_ context.Context, id string) (*db.Webhook, error) {
+	for _, w := range f.webhooks {
+		if w.ID == id {
+			return w, nil
+		}
+	}
+	return nil, db.ErrWebhookNotFound
+}
+
+func (f fakeWebhooks) GetByIDs(_ context.Context, ids []string) ([]*db.Webhook, error) {
+	var webhooks []*db.Webhook
+	for _, w := range f.webhooks {
+		for _, id := range ids {
+			if w.ID == id {
+				webhooks = append(webhooks, w)
+			}
+		}
+	}
+	return webhooks, nil
+}
+
+func (f fakeWebhooks) List(_ context.Context, limit, offset int) ([]*db.Webhook, error) {
+	return paginate(f.webhooks, limit, offset), nil
+}
+
+type fakeDeliveries struct{ *fakeStore }
+
+func (f fakeDeliveries) GetByID(_ context.Context, id string) (*db.WebhookDelivery, error) {
+	for _, d := range f.deliveries {
+		if d.ID == id {
+			return d, nil
+		}
+	}
+	return nil, db.ErrDeliveryNotFound
+}
+
+func (f fakeDeliveries) List(_ context.Context, filter db.DeliveryFilter, limit, offset int) ([]*db.WebhookDelivery, error) {
+	var deliveries []*db.WebhookDelivery
+	for _, d := range f.deliveries {
+		if filter.WebhookID != "" && d.WebhookID != filter.WebhookID {
+			continue
+		}
+		if filter.RuleID != "" && (d.RuleID == nil || *d.RuleID != filter.RuleID) {
+			continue
+		}
+		if filter.AnomalyConfigID != "" && (d.AnomalyConfigID == nil || *d.AnomalyConfigID != filter.AnomalyConfigID) {
+			continue
+		}
+		if filter.Status != "" && d.Status != filter.Status {
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+	return paginate(deliveries, limit, offset), nil
+}
+
+type fakeAnomalies struct{ *fakeStore }
+
+func (f fakeAnomalies) GetByID(_ context.Context, id string) (*db.AnomalyConfig, error) {
+	for _, c := range f.configs {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, db.ErrAnomalyConfigNotFound
+}
+
+func (f fakeAnomalies) List(_ context.Context, limit, offset int) ([]*db.AnomalyConfig, error) {
+	return paginate(f.configs, limit, offset), nil
+}
+
+func (f fakeAnomalies) ListByAppID(_ context.Context, appID string, limit, offset int) ([]*db.AnomalyConfig, error) {
+	var configs []*db.AnomalyConfig
+	for _, c := range f.configs {
+		if c.AppID != nil && *c.AppID == appID {
+			configs = append(configs, c)
+		}
+	}
+	return paginate(configs, limit, offset), nil
+}
+
+func (f fakeAnomalies) GetAnomalyEvents(_ context.Context, configID string, limit, offset int) ([]*db.AnomalyEvent, error) {
+	var events []*db.AnomalyEvent
+	for _, e := range f.events {
+		if e.AnomalyConfigID == configID {
+			events = append(events, e)
+		}
+	}
+	return paginate(events, limit, offset), nil
+}
+
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
+func strPtr(s string) *string { return &s }
+
+func newTestModule(t *testing.T) *Module {
+	t.Helper()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := &fakeStore{
+		rules: []*db.Rule{{
+			ID:         "rule-1",
+			Name:       "big purchase",
+			AppID:      strPtr("shop"),
+			Conditions: []db.Condition{{Path: "$.amount", Operator: "gt", Value: 100.0}},
+			Actions:    db.Actions{Webhooks: []string{"hook-1", "hook-gone"}},
+			Enabled:    true,
+			Version:    3,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}},
+		webhooks: []*db.Webhook{{
+			ID:         "hook-1",
+			Name:       "slack",
+			URL:        "https://hooks.example.com/x",
+			AuthType:   "bearer",
+			AuthConfig: json.RawMessage(`{"token":"secret"}`),
+			Headers:    map[string]string{"X-Secret": "secret"},
+			TimeoutMs:  30000,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}},
+		deliveries: []*db.WebhookDelivery{
+			{ID: "del-1", WebhookID: "hook-1", RuleID: strPtr("rule-1"), Status: db.DeliveryStatusDelivered, Payload: json.RawMessage(`{"pii":"x"}`), CreatedAt: now},
+			{ID: "del-2", WebhookID: "hook-1", RuleID: strPtr("rule-1"), Status: db.DeliveryStatusDeadLetter, LastError: strPtr("timeout"), CreatedAt: now},
+			{ID: "del-3", WebhookID: "hook-1", AnomalyConfigID: strPtr("anom-1"), Status: db.DeliveryStatusPending, CreatedAt: now},
+		},
+		configs: []*db.AnomalyConfig{{
+			ID:            "anom-1",
+			Name:          "spike",
+			AppID:         strPtr("shop"),
+			DetectionType: db.DetectionTypeRate,
+			Config:        json.RawMessage(`{"max_per_minute":100}`),
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}},
+		events: []*db.AnomalyEvent{{
+			ID:              "ev-1",
+			AnomalyConfigID: "anom-1",
+			AppID:           strPtr("shop"),
+			DetectionType:   "rate",
+			Details:         json.RawMessage(`{"rate":120}`),
+			CreatedAt:       now,
+		}},
+	}
+	keys := &fakeKeys{keys: []auth.APIKey{
+		{ID: "key-1", AppID: "shop", Name: "iOS", SigningSecret: "s3cret", CreatedAt: now},
+		{ID: "key-2", AppID: "blog", Name: "web", CreatedAt: now},
+	}}
+
+	m, err := newModule(&sources{
+		keys:       keys,
+		rules:      fakeRules{store},
+		webhooks:   fakeWebhooks{store},
+		deliveries: fakeDeliveries{store},
+		anomalies:  fakeAnomalies{store},
+	}, Config{MaxDepth: 8}, nil)
+	if err != nil {
+		t.Fatalf("newModule() error = %v", err)
+	}
+	return m
+}
+
+type gqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func execQuery(t *testing.T, m *Module, query string, variables map[string]any) gqlResponse {
+	t.Helper()
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": variables})
+	mux := http.NewServeMux()
+	m.RegisterRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/admin/graphql", strings.NewReader(string(body))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp gqlResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %s: %v", rec.Body.String(), err)
+	}
+	return resp
+}
+
+func TestQuery_NestedResources(t *testing.T) {
+	m := newTestModule(t)
+	resp := execQuery(t, m, `{
+		app(id: "shop") {
+			id
+			keys { name signed }
+			rules {
+				name
+				version
+				conditions
+				webhooks {
+					name
+					deliveries(status: "dead_letter") { id lastError rule { name } }
+				}
+			}
+			anomalyConfigs {
+				name
+				config
+				events { details anomalyConfig { name } }
+				deliveries { id status }
+			}
+		}
+	}`, nil)
+	if len(resp.Errors) > 0 {
+		t.Fatalf("errors = %v", resp.Errors)
+	}
+
+	want := `{"app":{"id":"shop","keys":[{"name":"iOS","signed":true}],` +
+		`"rules":[{"name":"big purchase","version":3,"conditions":[{"path":"$.amount","operator":"gt","value":100}],` +
+		`"webhooks":[{"name":"slack","deliveries":[{"id":"del-2","lastError":"timeout","rule":{"name":"big purchase"}}]}]}],` +
+		`"anomalyConfigs":[{"name":"spike","config":{"max_per_minute":100},` +
+		`"events":[{"details":{"rate":120},"anomalyConfig":{"name":"spike"}}],` +
+		`"deliveries":[{"id":"del-3","status":"pending"}]}]}}`
+	if string(resp.Data) != want {
+		t.Errorf("data =\n%s\nwant\n%s", resp.Data, want)
+	}
+}
+
+func TestQuery_ListsAndPagination(t *testing.T) {
+	m := newTestModule(t)
+	resp := execQuery(t, m, `query($limit: Int!) {
+		apps { id }
+		deliveries(limit: $limit, offset: 1) { id }
+	}`, map[string]any{"limit": 1})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("errors = %v", resp.Errors)
+	}
+	want := `{"apps":[{"id":"shop"},{"id":"blog"}],"deliveries":[{"id":"del-2"}]}`
+	if string(resp.Data) != want {
+		t.Errorf("data = %s, want %s", resp.Data, want)
+	}
+}
+
+func TestQuery_MissingResourcesAreNull(t *testing.T) {
+	m := newTestModule(t)
+	resp := execQuery(t, m, `{
+		app(id: "nope") { id }
+		rule(id: "nope") { id }
+		webhook(id: "nope") { id }
+		delivery(id: "nope") { id }
+		anomalyConfig(id: "nope") { id }
+	}`, nil)
+	if len(resp.Errors) > 0 {
+		t.Fatalf("errors = %v", resp.Errors)
+	}
+	want := `{"app":null,"rule":null,"webhook":null,"delivery":null,"anomalyConfig":null}`
+	if string(resp.Data) != want {
+		t.Errorf("data = %s, want %s", resp.Data, want)
+	}
+}
+
+func TestQuery_InvalidLimit(t *testing.T) {
+	m := newTestModule(t)
+	resp := execQuery(t, m, `{ rules(limit: 501) { id } }`, nil)
+	if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, ErrInvalidPage.Error()) {
+		t.Errorf("errors = %v, want %v", resp.Errors, ErrInvalidPage)
+	}
+}
+
+func TestQuery_ReadOnlyAndNoSecrets(t *testing.T) {
+	m := newTestModule(t)
+	for _, query := range []string{
+		`mutation { deleteRule(id: "rule-1") }`,
+		`{ webhook(id: "hook-1") { authConfig } }`,
+		`{ webhook(id: "hook-1") { headers } }`,
+		`{ delivery(id: "del-1") { payload } }`,
+		`{ app(id: "shop") { keys { signingSecret } } }`,
+	} {
+		resp := execQuery(t, m, query, nil)
+		if len(resp.Errors) == 0 {
+			t.Errorf("query %q succeeded: %s", query, resp.Data)
+		}
+	}
+}
+
+func TestQuery_MaxDepth(t *testing.T) {
+	m := newTestModule(t)
+	resp := execQuery(t, m, `{ rules { webhooks { deliveries { rule { webhooks { deliveries { rule { webhooks { id } } } } } } } } }`, nil)
+	if len(resp.Errors) == 0 {
+		t.Error("query deeper than MaxDepth succeeded")
+	}
+}
+
+func TestHandleQuery_BadRequest(t *testing.T) {
+	m := newTestModule(t)
+	mux := http.NewServeMux()
+	m.RegisterRoutes(mux)
+
+	for _, body := range []string{`not json`, `{"query":""}`} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/admin/graphql", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("body %q: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
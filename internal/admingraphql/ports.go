@@ -0,0 +1,56 @@
+// Package admingraphql serves a read-only GraphQL API over the admin
+// resources of both services: apps and API keys from the gateway database,
+// and rules, webhooks, webhook deliveries and anomaly configs and events from
+// the reaction engine database. Dashboards can fetch nested resources (an
+// app's rules with their webhooks and recent deliveries) in one request
+// instead of stitching several REST endpoints.
+//
+// The schema has no mutations; writes go through the REST admin APIs. Webhook
+// credentials, custom headers and delivery payloads are never exposed.
+package admingraphql
+
+import (
+	"context"
+
+	"github.com/SebastienMelki/causality/internal/auth"
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// KeySource reads apps and their API keys. It is satisfied by *auth.Module.
+type KeySource interface {
+	// ListApps returns the IDs of all apps that have API keys.
+	ListApps(ctx context.Context) ([]string, error)
+
+	// ListKeys returns the API keys of an app, newest first.
+	ListKeys(ctx context.Context, appID string) ([]auth.APIKey, error)
+}
+
+// RuleSource reads rules. It is satisfied by *db.RuleRepository.
+type RuleSource interface {
+	GetByID(ctx context.Context, id string) (*db.Rule, error)
+	List(ctx context.Context, limit, offset int) ([]*db.Rule, error)
+	ListByAppID(ctx context.Context, appID string, limit, offset int) ([]*db.Rule, error)
+}
+
+// WebhookSource reads webhooks. It is satisfied by *db.WebhookRepository.
+type WebhookSource interface {
+	GetByID(ctx context.Context, id string) (*db.Webhook, error)
+	GetByIDs(ctx context.Context, ids []string) ([]*db.Webhook, error)
+	List(ctx context.Context, limit, offset int) ([]*db.Webhook, error)
+}
+
+// DeliverySource reads webhook deliveries. It is satisfied by
+// *db.DeliveryRepository.
+type DeliverySource interface {
+	GetByID(ctx context.Context, id string) (*db.WebhookDelivery, error)
+	List(ctx context.Context, filter db.DeliveryFilter, limit, offset int) ([]*db.WebhookDelivery, error)
+}
+
+// AnomalySource reads anomaly configs and events. It is satisfied by
+// *db.AnomalyConfigRepository.
+type AnomalySource interface {
+	GetByID(ctx context.Context, id string) (*db.AnomalyConfig, error)
+	List(ctx context.Context, limit, offset int) ([]*db.AnomalyConfig, error)
+	ListByAppID(ctx context.Context, appID string, limit, offset int) ([]*db.AnomalyConfig, error)
+	GetAnomalyEvents(ctx context.Context, configID string, limit, offset int) ([]*db.AnomalyEvent, error)
+}
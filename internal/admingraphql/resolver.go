@@ -0,0 +1,542 @@
+package admingraphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+
+	"github.com/SebastienMelki/causality/internal/auth"
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// maxPageSize is the largest limit accepted by list fields.
+const maxPageSize = 500
+
+// sources holds the stores the resolvers read from.
+type sources struct {
+	keys       KeySource
+	rules      RuleSource
+	webhooks   WebhookSource
+	deliveries DeliverySource
+	anomalies  AnomalySource
+}
+
+// idArgs are the arguments of single-resource fields.
+type idArgs struct {
+	ID graphql.ID
+}
+
+// pageArgs are the pagination arguments of list fields.
+type pageArgs struct {
+	Limit  int32
+	Offset int32
+}
+
+// page validates pagination arguments.
+func (a pageArgs) page() (limit, offset int, err error) {
+	if a.Limit < 1 || a.Limit > maxPageSize || a.Offset < 0 {
+		return 0, 0, ErrInvalidPage
+	}
+	return int(a.Limit), int(a.Offset), nil
+}
+
+// appPageArgs are the arguments of list fields filterable by app.
+type appPageArgs struct {
+	AppID *graphql.ID
+	pageArgs
+}
+
+// deliveryPageArgs are the arguments of delivery list fields.
+type deliveryPageArgs struct {
+	Status *string
+	pageArgs
+}
+
+// queryResolver resolves the root Query type.
+type queryResolver struct {
+	src *sources
+}
+
+func (q *queryResolver) Apps(ctx context.Context) ([]*appResolver, error) {
+	appIDs, err := q.src.keys.ListApps(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list apps: %w", err)
+	}
+	apps := make([]*appResolver, len(appIDs))
+	for i, id := range appIDs {
+		apps[i] = &appResolver{src: q.src, id: id}
+	}
+	return apps, nil
+}
+
+// App returns the app with the given ID, or null if it has no API keys.
+func (q *queryResolver) App(ctx context.Context, args idArgs) (*appResolver, error) {
+	keys, err := q.src.keys.ListKeys(ctx, string(args.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return &appResolver{src: q.src, id: string(args.ID), keys: keys}, nil
+}
+
+func (q *queryResolver) Rules(ctx context.Context, args appPageArgs) ([]*ruleResolver, error) {
+	limit, offset, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+	var rules []*db.Rule
+	if args.AppID != nil {
+		rules, err = q.src.rules.ListByAppID(ctx, string(*args.AppID), limit, offset)
+	} else {
+		rules, err = q.src.rules.List(ctx, limit, offset)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rules: %w", err)
+	}
+	return q.src.ruleResolvers(rules), nil
+}
+
+func (q *queryResolver) Rule(ctx context.Context, args idArgs) (*ruleResolver, error) {
+	return q.src.rule(ctx, string(args.ID))
+}
+
+func (q *queryResolver) Webhooks(ctx context.Context, args pageArgs) ([]*webhookResolver, error) {
+	limit, offset, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+	webhooks, err := q.src.webhooks.List(ctx, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	return q.src.webhookResolvers(webhooks), nil
+}
+
+func (q *queryResolver) Webhook(ctx context.Context, args idArgs) (*webhookResolver, error) {
+	return q.src.webhook(ctx, string(args.ID))
+}
+
+func (q *queryResolver) Deliveries(ctx context.Context, args deliveryPageArgs) ([]*deliveryResolver, error) {
+	return q.src.listDeliveries(ctx, db.DeliveryFilter{}, args)
+}
+
+func (q *queryResolver) Delivery(ctx context.Context, args idArgs) (*deliveryResolver, error) {
+	delivery, err := q.src.deliveries.GetByID(ctx, string(args.ID))
+	if err != nil {
+		if errors.Is(err, db.ErrDeliveryNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get delivery: %w", err)
+	}
+	return &deliveryResolver{src: q.src, d: delivery}, nil
+}
+
+func (q *queryResolver) AnomalyConfigs(ctx context.Context, args appPageArgs) ([]*anomalyConfigResolver, error) {
+	limit, offset, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+	var configs []*db.AnomalyConfig
+	if args.AppID != nil {
+		configs, err = q.src.anomalies.ListByAppID(ctx, string(*args.AppID), limit, offset)
+	} else {
+		configs, err = q.src.anomalies.List(ctx, limit, offset)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list anomaly configs: %w", err)
+	}
+	return q.src.anomalyConfigResolvers(configs), nil
+}
+
+func (q *queryResolver) AnomalyConfig(ctx context.Context, args idArgs) (*anomalyConfigResolver, error) {
+	return q.src.anomalyConfig(ctx, string(args.ID))
+}
+
+// rule returns a rule resolver, or nil if the rule does not exist.
+func (s *sources) rule(ctx context.Context, id string) (*ruleResolver, error) {
+	rule, err := s.rules.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, db.ErrRuleNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get rule: %w", err)
+	}
+	return &ruleResolver{src: s, r: rule}, nil
+}
+
+// webhook returns a webhook resolver, or nil if the webhook does not exist.
+func (s *sources) webhook(ctx context.Context, id string) (*webhookResolver, error) {
+	webhook, err := s.webhooks.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, db.ErrWebhookNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return &webhookResolver{src: s, w: webhook}, nil
+}
+
+// anomalyConfig returns an anomaly config resolver, or nil if the config
+// does not exist.
+func (s *sources) anomalyConfig(ctx context.Context, id string) (*anomalyConfigResolver, error) {
+	config, err := s.anomalies.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, db.ErrAnomalyConfigNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get anomaly config: %w", err)
+	}
+	return &anomalyConfigResolver{src: s, c: config}, nil
+}
+
+// listDeliveries lists deliveries matching filter and the status argument.
+func (s *sources) listDeliveries(ctx context.Context, filter db.DeliveryFilter, args deliveryPageArgs) ([]*deliveryResolver, error) {
+	limit, offset, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+	if args.Status != nil {
+		filter.Status = db.DeliveryStatus(*args.Status)
+	}
+	deliveries, err := s.deliveries.List(ctx, filter, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+	out := make([]*deliveryResolver, len(deliveries))
+	for i, d := range deliveries {
+		out[i] = &deliveryResolver{src: s, d: d}
+	}
+	return out, nil
+}
+
+func (s *sources) ruleResolvers(rules []*db.Rule) []*ruleResolver {
+	out := make([]*ruleResolver, len(rules))
+	for i, r := range rules {
+		out[i] = &ruleResolver{src: s, r: r}
+	}
+	return out
+}
+
+func (s *sources) webhookResolvers(webhooks []*db.Webhook) []*webhookResolver {
+	out := make([]*webhookResolver, len(webhooks))
+	for i, w := range webhooks {
+		out[i] = &webhookResolver{src: s, w: w}
+	}
+	return out
+}
+
+func (s *sources) anomalyConfigResolvers(configs []*db.AnomalyConfig) []*anomalyConfigResolver {
+	out := make([]*anomalyConfigResolver, len(configs))
+	for i, c := range configs {
+		out[i] = &anomalyConfigResolver{src: s, c: c}
+	}
+	return out
+}
+
+// appResolver resolves the App type. keys holds the keys already loaded
+// when the app was looked up by ID; otherwise they are loaded on demand.
+type appResolver struct {
+	src  *sources
+	id   string
+	keys []auth.APIKey
+}
+
+func (a *appResolver) ID() graphql.ID { return graphql.ID(a.id) }
+
+func (a *appResolver) Keys(ctx context.Context) ([]*apiKeyResolver, error) {
+	keys := a.keys
+	if keys == nil {
+		var err error
+		keys, err = a.src.keys.ListKeys(ctx, a.id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list keys: %w", err)
+		}
+	}
+	out := make([]*apiKeyResolver, len(keys))
+	for i := range keys {
+		out[i] = &apiKeyResolver{k: &keys[i]}
+	}
+	return out, nil
+}
+
+func (a *appResolver) Rules(ctx context.Context, args pageArgs) ([]*ruleResolver, error) {
+	id := graphql.ID(a.id)
+	return (&queryResolver{src: a.src}).Rules(ctx, appPageArgs{AppID: &id, pageArgs: args})
+}
+
+func (a *appResolver) AnomalyConfigs(ctx context.Context, args pageArgs) ([]*anomalyConfigResolver, error) {
+	id := graphql.ID(a.id)
+	return (&queryResolver{src: a.src}).AnomalyConfigs(ctx, appPageArgs{AppID: &id, pageArgs: args})
+}
+
+// apiKeyResolver resolves the APIKey type. The key hash and signing secret
+// are not exposed.
+type apiKeyResolver struct {
+	k *auth.APIKey
+}
+
+func (k *apiKeyResolver) ID() graphql.ID          { return graphql.ID(k.k.ID) }
+func (k *apiKeyResolver) AppID() graphql.ID       { return graphql.ID(k.k.AppID) }
+func (k *apiKeyResolver) Name() string            { return k.k.Name }
+func (k *apiKeyResolver) Signed() bool            { return k.k.SigningSecret != "" }
+func (k *apiKeyResolver) Revoked() bool           { return k.k.Revoked }
+func (k *apiKeyResolver) CreatedAt() graphql.Time { return graphql.Time{Time: k.k.CreatedAt} }
+func (k *apiKeyResolver) RevokedAt() *graphql.Time {
+	return optionalTime(k.k.RevokedAt)
+}
+
+func (k *apiKeyResolver) AllowedEventTypes() []string {
+	if k.k.AllowedEventTypes == nil {
+		return []string{}
+	}
+	return k.k.AllowedEventTypes
+}
+
+// ruleResolver resolves the Rule type.
+type ruleResolver struct {
+	src *sources
+	r   *db.Rule
+}
+
+func (r *ruleResolver) ID() graphql.ID          { return graphql.ID(r.r.ID) }
+func (r *ruleResolver) Name() string            { return r.r.Name }
+func (r *ruleResolver) Description() *string    { return r.r.Description }
+func (r *ruleResolver) AppID() *graphql.ID      { return optionalID(r.r.AppID) }
+func (r *ruleResolver) EventCategory() *string  { return r.r.EventCategory }
+func (r *ruleResolver) EventType() *string      { return r.r.EventType }
+func (r *ruleResolver) Priority() int32         { return int32(r.r.Priority) }
+func (r *ruleResolver) Enabled() bool           { return r.r.Enabled }
+func (r *ruleResolver) Shadow() bool            { return r.r.Shadow }
+func (r *ruleResolver) Version() int32          { return int32(r.r.Version) }
+func (r *ruleResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.r.CreatedAt} }
+func (r *ruleResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: r.r.UpdatedAt} }
+
+func (r *ruleResolver) Conditions() (JSON, error) {
+	conditions := r.r.Conditions
+	if conditions == nil {
+		conditions = []db.Condition{}
+	}
+	data, err := json.Marshal(conditions)
+	return JSON(data), err
+}
+
+func (r *ruleResolver) PublishSubjects() []string {
+	if r.r.Actions.PublishSubjects == nil {
+		return []string{}
+	}
+	return r.r.Actions.PublishSubjects
+}
+
+// Webhooks returns the rule's webhooks in action order. Webhooks that no
+// longer exist are skipped.
+func (r *ruleResolver) Webhooks(ctx context.Context) ([]*webhookResolver, error) {
+	webhooks, err := r.src.webhooks.GetByIDs(ctx, r.r.Actions.Webhooks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+	byID := make(map[string]*db.Webhook, len(webhooks))
+	for _, w := range webhooks {
+		byID[w.ID] = w
+	}
+	out := make([]*webhookResolver, 0, len(webhooks))
+	for _, id := range r.r.Actions.Webhooks {
+		if w, ok := byID[id]; ok {
+			out = append(out, &webhookResolver{src: r.src, w: w})
+		}
+	}
+	return out, nil
+}
+
+func (r *ruleResolver) Deliveries(ctx context.Context, args deliveryPageArgs) ([]*deliveryResolver, error) {
+	return r.src.listDeliveries(ctx, db.DeliveryFilter{RuleID: r.r.ID}, args)
+}
+
+// webhookResolver resolves the Webhook type. Auth config and headers are not
+// exposed.
+type webhookResolver struct {
+	src *sources
+	w   *db.Webhook
+}
+
+func (w *webhookResolver) ID() graphql.ID          { return graphql.ID(w.w.ID) }
+func (w *webhookResolver) Name() string            { return w.w.Name }
+func (w *webhookResolver) URL() string             { return w.w.URL }
+func (w *webhookResolver) AuthType() string        { return w.w.AuthType }
+func (w *webhookResolver) Enabled() bool           { return w.w.Enabled }
+func (w *webhookResolver) TimeoutMs() int32        { return int32(w.w.TimeoutMs) }
+func (w *webhookResolver) MaxRps() float64         { return w.w.MaxRPS }
+func (w *webhookResolver) BatchSize() int32        { return int32(w.w.BatchSize) }
+func (w *webhookResolver) CreatedAt() graphql.Time { return graphql.Time{Time: w.w.CreatedAt} }
+func (w *webhookResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: w.w.UpdatedAt} }
+
+func (w *webhookResolver) Deliveries(ctx context.Context, args deliveryPageArgs) ([]*deliveryResolver, error) {
+	return w.src.listDeliveries(ctx, db.DeliveryFilter{WebhookID: w.w.ID}, args)
+}
+
+// deliveryResolver resolves the WebhookDelivery type. The payload is not
+// exposed.
+type deliveryResolver struct {
+	src *sources
+	d   *db.WebhookDelivery
+}
+
+func (d *deliveryResolver) ID() graphql.ID              { return graphql.ID(d.d.ID) }
+func (d *deliveryResolver) RuleVersion() *int32         { return optionalInt(d.d.RuleVersion) }
+func (d *deliveryResolver) Status() string              { return string(d.d.Status) }
+func (d *deliveryResolver) Attempts() int32             { return int32(d.d.Attempts) }
+func (d *deliveryResolver) MaxAttempts() int32          { return int32(d.d.MaxAttempts) }
+func (d *deliveryResolver) NextAttemptAt() graphql.Time { return graphql.Time{Time: d.d.NextAttemptAt} }
+func (d *deliveryResolver) LastAttemptAt() *graphql.Time {
+	return optionalTime(d.d.LastAttemptAt)
+}
+func (d *deliveryResolver) LastError() *string      { return d.d.LastError }
+func (d *deliveryResolver) LastStatusCode() *int32  { return optionalInt(d.d.LastStatusCode) }
+func (d *deliveryResolver) CreatedAt() graphql.Time { return graphql.Time{Time: d.d.CreatedAt} }
+func (d *deliveryResolver) DeliveredAt() *graphql.Time {
+	return optionalTime(d.d.DeliveredAt)
+}
+
+func (d *deliveryResolver) Webhook(ctx context.Context) (*webhookResolver, error) {
+	return d.src.webhook(ctx, d.d.WebhookID)
+}
+
+func (d *deliveryResolver) Rule(ctx context.Context) (*ruleResolver, error) {
+	if d.d.RuleID == nil {
+		return nil, nil
+	}
+	return d.src.rule(ctx, *d.d.RuleID)
+}
+
+func (d *deliveryResolver) AnomalyConfig(ctx context.Context) (*anomalyConfigResolver, error) {
+	if d.d.AnomalyConfigID == nil {
+		return nil, nil
+	}
+	return d.src.anomalyConfig(ctx, *d.d.AnomalyConfigID)
+}
+
+// anomalyConfigResolver resolves the AnomalyConfig type.
+type anomalyConfigResolver struct {
+	src *sources
+	c   *db.AnomalyConfig
+}
+
+func (c *anomalyConfigResolver) ID() graphql.ID          { return graphql.ID(c.c.ID) }
+func (c *anomalyConfigResolver) Name() string            { return c.c.Name }
+func (c *anomalyConfigResolver) Description() *string    { return c.c.Description }
+func (c *anomalyConfigResolver) AppID() *graphql.ID      { return optionalID(c.c.AppID) }
+func (c *anomalyConfigResolver) EventCategory() *string  { return c.c.EventCategory }
+func (c *anomalyConfigResolver) EventType() *string      { return c.c.EventType }
+func (c *anomalyConfigResolver) DetectionType() string   { return string(c.c.DetectionType) }
+func (c *anomalyConfigResolver) Config() JSON            { return jsonOrEmpty(c.c.Config) }
+func (c *anomalyConfigResolver) CooldownSeconds() int32  { return int32(c.c.CooldownSeconds) }
+func (c *anomalyConfigResolver) Severity() string        { return string(c.c.Severity) }
+func (c *anomalyConfigResolver) Enabled() bool           { return c.c.Enabled }
+func (c *anomalyConfigResolver) CreatedAt() graphql.Time { return graphql.Time{Time: c.c.CreatedAt} }
+func (c *anomalyConfigResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: c.c.UpdatedAt} }
+
+func (c *anomalyConfigResolver) Events(ctx context.Context, args pageArgs) ([]*anomalyEventResolver, error) {
+	limit, offset, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+	events, err := c.src.anomalies.GetAnomalyEvents(ctx, c.c.ID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list anomaly events: %w", err)
+	}
+	out := make([]*anomalyEventResolver, len(events))
+	for i, e := range events {
+		out[i] = &anomalyEventResolver{src: c.src, e: e}
+	}
+	return out, nil
+}
+
+func (c *anomalyConfigResolver) Deliveries(ctx context.Context, args deliveryPageArgs) ([]*deliveryResolver, error) {
+	return c.src.listDeliveries(ctx, db.DeliveryFilter{AnomalyConfigID: c.c.ID}, args)
+}
+
+// anomalyEventResolver resolves the AnomalyEvent type.
+type anomalyEventResolver struct {
+	src *sources
+	e   *db.AnomalyEvent
+}
+
+func (e *anomalyEventResolver) ID() graphql.ID          { return graphql.ID(e.e.ID) }
+func (e *anomalyEventResolver) AppID() *graphql.ID      { return optionalID(e.e.AppID) }
+func (e *anomalyEventResolver) EventCategory() *string  { return e.e.EventCategory }
+func (e *anomalyEventResolver) EventType() *string      { return e.e.EventType }
+func (e *anomalyEventResolver) DetectionType() string   { return e.e.DetectionType }
+func (e *anomalyEventResolver) Details() JSON           { return jsonOrEmpty(e.e.Details) }
+func (e *anomalyEventResolver) CreatedAt() graphql.Time { return graphql.Time{Time: e.e.CreatedAt} }
+
+func (e *anomalyEventResolver) EventData() *JSON {
+	if len(e.e.EventData) == 0 || string(e.e.EventData) == "null" {
+		return nil
+	}
+	data := JSON(e.e.EventData)
+	return &data
+}
+
+func (e *anomalyEventResolver) AnomalyConfig(ctx context.Context) (*anomalyConfigResolver, error) {
+	return e.src.anomalyConfig(ctx, e.e.AnomalyConfigID)
+}
+
+// JSON is the GraphQL JSON scalar, holding an encoded JSON value.
+type JSON json.RawMessage
+
+// ImplementsGraphQLType maps JSON to the JSON scalar.
+func (JSON) ImplementsGraphQLType(name string) bool { return name == "JSON" }
+
+// UnmarshalGraphQL encodes an input value as JSON.
+func (j *JSON) UnmarshalGraphQL(input any) error {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	*j = data
+	return nil
+}
+
+// MarshalJSON returns the encoded value.
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+// jsonOrEmpty returns raw as a JSON value, or an empty object if unset.
+func jsonOrEmpty(raw json.RawMessage) JSON {
+	if len(raw) == 0 {
+		return JSON("{}")
+	}
+	return JSON(raw)
+}
+
+func optionalID(s *string) *graphql.ID {
+	if s == nil {
+		return nil
+	}
+	id := graphql.ID(*s)
+	return &id
+}
+
+func optionalInt(n *int) *int32 {
+	if n == nil {
+		return nil
+	}
+	v := int32(*n)
+	return &v
+}
+
+func optionalTime(t *time.Time) *graphql.Time {
+	if t == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *t}
+}
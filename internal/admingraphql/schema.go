@@ -0,0 +1,129 @@
+package admingraphql
+
+// schema is the GraphQL schema of the admin read API. List fields take limit
+// (1 to maxPageSize, default 50) and offset arguments.
+const schema = `
+schema {
+	query: Query
+}
+
+"RFC 3339 timestamp."
+scalar Time
+
+"Arbitrary JSON value."
+scalar JSON
+
+type Query {
+	apps: [App!]!
+	app(id: ID!): App
+	rules(appId: ID, limit: Int = 50, offset: Int = 0): [Rule!]!
+	rule(id: ID!): Rule
+	webhooks(limit: Int = 50, offset: Int = 0): [Webhook!]!
+	webhook(id: ID!): Webhook
+	deliveries(status: String, limit: Int = 50, offset: Int = 0): [WebhookDelivery!]!
+	delivery(id: ID!): WebhookDelivery
+	anomalyConfigs(appId: ID, limit: Int = 50, offset: Int = 0): [AnomalyConfig!]!
+	anomalyConfig(id: ID!): AnomalyConfig
+}
+
+"An app, identified by the app_id of its API keys."
+type App {
+	id: ID!
+	keys: [APIKey!]!
+	"Rules scoped to this app; rules for all apps are not included."
+	rules(limit: Int = 50, offset: Int = 0): [Rule!]!
+	"Anomaly configs scoped to this app; configs for all apps are not included."
+	anomalyConfigs(limit: Int = 50, offset: Int = 0): [AnomalyConfig!]!
+}
+
+type APIKey {
+	id: ID!
+	appId: ID!
+	name: String!
+	allowedEventTypes: [String!]!
+	signed: Boolean!
+	revoked: Boolean!
+	createdAt: Time!
+	revokedAt: Time
+}
+
+type Rule {
+	id: ID!
+	name: String!
+	description: String
+	appId: ID
+	eventCategory: String
+	eventType: String
+	conditions: JSON!
+	webhooks: [Webhook!]!
+	publishSubjects: [String!]!
+	priority: Int!
+	enabled: Boolean!
+	shadow: Boolean!
+	version: Int!
+	createdAt: Time!
+	updatedAt: Time!
+	deliveries(status: String, limit: Int = 50, offset: Int = 0): [WebhookDelivery!]!
+}
+
+type Webhook {
+	id: ID!
+	name: String!
+	url: String!
+	authType: String!
+	enabled: Boolean!
+	timeoutMs: Int!
+	maxRps: Float!
+	batchSize: Int!
+	createdAt: Time!
+	updatedAt: Time!
+	deliveries(status: String, limit: Int = 50, offset: Int = 0): [WebhookDelivery!]!
+}
+
+type WebhookDelivery {
+	id: ID!
+	webhook: Webhook
+	rule: Rule
+	ruleVersion: Int
+	anomalyConfig: AnomalyConfig
+	status: String!
+	attempts: Int!
+	maxAttempts: Int!
+	nextAttemptAt: Time!
+	lastAttemptAt: Time
+	lastError: String
+	lastStatusCode: Int
+	createdAt: Time!
+	deliveredAt: Time
+}
+
+type AnomalyConfig {
+	id: ID!
+	name: String!
+	description: String
+	appId: ID
+	eventCategory: String
+	eventType: String
+	detectionType: String!
+	config: JSON!
+	cooldownSeconds: Int!
+	severity: String!
+	enabled: Boolean!
+	createdAt: Time!
+	updatedAt: Time!
+	events(limit: Int = 50, offset: Int = 0): [AnomalyEvent!]!
+	deliveries(status: String, limit: Int = 50, offset: Int = 0): [WebhookDelivery!]!
+}
+
+type AnomalyEvent {
+	id: ID!
+	anomalyConfig: AnomalyConfig
+	appId: ID
+	eventCategory: String
+	eventType: String
+	detectionType: String!
+	details: JSON!
+	eventData: JSON
+	createdAt: Time!
+}
+`
@@ -0,0 +1,86 @@
+// Package domain contains the core types for ingestion audit records.
+package domain
+
+import (
+	"net/http"
+	"time"
+)
+
+// Decision summarizes the outcome of an ingestion request.
+type Decision string
+
+// Decision values recorded on audit records.
+const (
+	// DecisionAccepted means every event in the request was accepted.
+	DecisionAccepted Decision = "accepted"
+
+	// DecisionPartial means some events were accepted and some were rejected.
+	DecisionPartial Decision = "partial"
+
+	// DecisionRejected means the request or every event in it was rejected.
+	DecisionRejected Decision = "rejected"
+)
+
+// Record is a single per-request ingestion audit entry. One record is emitted
+// for every request to the ingestion endpoints, whether it was accepted or
+// rejected, so that abuse can be investigated and billing reconciled.
+type Record struct {
+	RequestID       string    `json:"request_id"`
+	KeyID           string    `json:"api_key_id,omitempty"`
+	AppID           string    `json:"app_id,omitempty"`
+	Method          string    `json:"method"`
+	Path            string    `json:"path"`
+	ClientIP        string    `json:"client_ip,omitempty"`
+	UserAgent       string    `json:"user_agent,omitempty"`
+	SDKName         string    `json:"sdk_name,omitempty"`
+	SDKVersion      string    `json:"sdk_version,omitempty"`
+	SDKPlatform     string    `json:"sdk_platform,omitempty"`
+	SDKCapabilities []string  `json:"sdk_capabilities,omitempty"`
+	StatusCode      int       `json:"status_code"`
+	Decision        Decision  `json:"decision"`
+	EventCount      int       `json:"event_count"`
+	AcceptedCount   int       `json:"accepted_count"`
+	RejectedCount   int       `json:"rejected_count"`
+	DuplicateCount  int       `json:"duplicate_count"`
+	Reasons         []string  `json:"reasons,omitempty"`
+	DurationMs      int64     `json:"duration_ms"`
+	ReceivedAt      time.Time `json:"received_at"`
+}
+
+// DecisionFor derives the request decision from the HTTP status code and the
+// per-event accepted/rejected counts. Any non-2xx status is a rejection.
+func DecisionFor(statusCode, accepted, rejected int) Decision {
+	if statusCode < http.StatusOK || statusCode >= http.StatusMultipleChoices {
+		return DecisionRejected
+	}
+	switch {
+	case rejected == 0:
+		return DecisionAccepted
+	case accepted == 0:
+		return DecisionRejected
+	default:
+		return DecisionPartial
+	}
+}
+
+// StatusReason returns a stable decision reason for a request that was
+// rejected at the HTTP layer before any events were evaluated (for example by
+// the auth or rate-limit middleware). Returns an empty string for 2xx codes.
+func StatusReason(statusCode int) string {
+	switch {
+	case statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices:
+		return ""
+	case statusCode == http.StatusUnauthorized:
+		return "unauthorized"
+	case statusCode == http.StatusForbidden:
+		return "forbidden"
+	case statusCode == http.StatusTooManyRequests:
+		return "rate_limited"
+	case statusCode == http.StatusRequestEntityTooLarge:
+		return "body_too_large"
+	case statusCode >= http.StatusInternalServerError:
+		return "internal_error"
+	default:
+		return "invalid_request"
+	}
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDecisionFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		accepted int
+		rejected int
+		want     Decision
+	}{
+		{"all accepted", http.StatusOK, 5, 0, DecisionAccepted},
+		{"partial", http.StatusOK, 3, 2, DecisionPartial},
+		{"all rejected with 200", http.StatusOK, 0, 4, DecisionRejected},
+		{"empty 200", http.StatusOK, 0, 0, DecisionAccepted},
+		{"unauthorized", http.StatusUnauthorized, 0, 0, DecisionRejected},
+		{"rate limited", http.StatusTooManyRequests, 0, 0, DecisionRejected},
+		{"server error with counts", http.StatusInternalServerError, 2, 0, DecisionRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DecisionFor(tt.status, tt.accepted, tt.rejected); got != tt.want {
+				t.Errorf("DecisionFor(%d, %d, %d) = %q, want %q",
+					tt.status, tt.accepted, tt.rejected, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusReason(t *testing.T) {
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{http.StatusOK, ""},
+		{http.StatusUnauthorized, "unauthorized"},
+		{http.StatusForbidden, "forbidden"},
+		{http.StatusTooManyRequests, "rate_limited"},
+		{http.StatusRequestEntityTooLarge, "body_too_large"},
+		{http.StatusBadRequest, "invalid_request"},
+		{http.StatusBadGateway, "internal_error"},
+	}
+
+	for _, tt := range tests {
+		if got := StatusReason(tt.status); got != tt.want {
+			t.Errorf("StatusReason(%d) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Package repo provides the PostgreSQL implementation of the audit Store port.
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/SebastienMelki/causality/internal/audit/internal/domain"
+)
+
+// RecordRepository implements the Store interface using PostgreSQL.
+type RecordRepository struct {
+	db *sql.DB
+}
+
+// NewRecordRepository creates a new RecordRepository backed by the given database.
+func NewRecordRepository(db *sql.DB) *RecordRepository {
+	return &RecordRepository{db: db}
+}
+
+// Insert appends an audit record to the ingest_audit_log table.
+func (r *RecordRepository) Insert(ctx context.Context, rec *domain.Record) error {
+	reasonsJSON, err := json.Marshal(rec.Reasons)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit reasons: %w", err)
+	}
+
+	capabilities := rec.SDKCapabilities
+	if capabilities == nil {
+		capabilities = []string{}
+	}
+	capabilitiesJSON, err := json.Marshal(capabilities)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit SDK capabilities: %w", err)
+	}
+
+	query := `
+		INSERT INTO ingest_audit_log (
+			request_id, api_key_id, app_id, method, path, client_ip, user_agent,
+			sdk_name, sdk_version, sdk_platform, sdk_capabilities,
+			status_code, decision, event_count, accepted_count, rejected_count,
+			duplicate_count, reasons, duration_ms, received_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
+	`
+
+	_, err = r.db.ExecContext(ctx, query,
+		rec.RequestID,
+		rec.KeyID,
+		rec.AppID,
+		rec.Method,
+		rec.Path,
+		rec.ClientIP,
+		rec.UserAgent,
+		rec.SDKName,
+		rec.SDKVersion,
+		rec.SDKPlatform,
+		capabilitiesJSON,
+		rec.StatusCode,
+		string(rec.Decision),
+		rec.EventCount,
+		rec.AcceptedCount,
+		rec.RejectedCount,
+		rec.DuplicateCount,
+		reasonsJSON,
+		rec.DurationMs,
+		rec.ReceivedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit record: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+// Package service implements the asynchronous audit record dispatcher that
+// publishes records to NATS and optionally persists them to PostgreSQL.
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/SebastienMelki/causality/internal/audit/internal/domain"
+	"github.com/SebastienMelki/causality/internal/events"
+)
+
+// unknownAppSubjectToken is the subject token used for records that have no
+// authenticated app_id (e.g., requests rejected by the auth middleware).
+const unknownAppSubjectToken = "unknown"
+
+// MessagePublisher is the subset of the JetStream API used to publish audit
+// records. It is satisfied by jetstream.JetStream.
+type MessagePublisher interface {
+	Publish(ctx context.Context, subject string, data []byte, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+}
+
+// RecordStore defines the persistence interface needed by AuditService.
+// This mirrors the audit.Store port to avoid import cycles.
+type RecordStore interface {
+	Insert(ctx context.Context, rec *domain.Record) error
+}
+
+// AuditService buffers audit records and dispatches them from a background
+// goroutine so that the ingestion request path never blocks on NATS or
+// PostgreSQL. Records are dropped (and logged) if the buffer is full.
+type AuditService struct {
+	publisher      MessagePublisher
+	store          RecordStore
+	subjectPrefix  string
+	publishTimeout time.Duration
+	logger         *slog.Logger
+
+	records  chan domain.Record
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewAuditService creates a new audit service. The store parameter is
+// optional; pass nil to publish to NATS only.
+func NewAuditService(
+	publisher MessagePublisher,
+	store RecordStore,
+	subjectPrefix string,
+	bufferSize int,
+	publishTimeout time.Duration,
+	logger *slog.Logger,
+) *AuditService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+
+	return &AuditService{
+		publisher:      publisher,
+		store:          store,
+		subjectPrefix:  subjectPrefix,
+		publishTimeout: publishTimeout,
+		logger:         logger,
+		records:        make(chan domain.Record, bufferSize),
+		stopCh:         make(chan struct{}),
+		doneCh:         make(chan struct{}),
+	}
+}
+
+// Record enqueues an audit record for dispatch. It never blocks; if the
+// buffer is full the record is dropped and a warning is logged.
+func (s *AuditService) Record(rec domain.Record) {
+	select {
+	case s.records <- rec:
+	default:
+		s.logger.Warn("audit buffer full, record dropped",
+			"request_id", rec.RequestID,
+			"app_id", rec.AppID,
+		)
+	}
+}
+
+// Start launches the background dispatch goroutine. The goroutine stops when
+// ctx is cancelled or Stop is called, draining any buffered records first.
+func (s *AuditService) Start(ctx context.Context) {
+	go func() {
+		defer close(s.doneCh)
+		for {
+			select {
+			case rec := <-s.records:
+				s.dispatch(ctx, &rec)
+			case <-ctx.Done():
+				s.drain()
+				return
+			case <-s.stopCh:
+				s.drain()
+				return
+			}
+		}
+	}()
+}
+
+// Stop signals the dispatch goroutine to stop and waits for buffered records
+// to be flushed.
+func (s *AuditService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+	})
+	<-s.doneCh
+}
+
+// Subject returns the NATS subject an audit record is published to:
+// "{prefix}.{app_id}", or "{prefix}.unknown" if the app is not known.
+func (s *AuditService) Subject(rec *domain.Record) string {
+	token := unknownAppSubjectToken
+	if rec.AppID != "" {
+		token = events.SanitizeSubjectName(rec.AppID)
+	}
+	return s.subjectPrefix + "." + token
+}
+
+// drain dispatches any records still buffered at shutdown using a fresh
+// context, since the run context may already be cancelled.
+func (s *AuditService) drain() {
+	for {
+		select {
+		case rec := <-s.records:
+			s.dispatch(context.Background(), &rec)
+		default:
+			return
+		}
+	}
+}
+
+// dispatch publishes a single record to NATS and, if configured, persists it.
+// Failures are logged; audit delivery is best-effort and never fails ingestion.
+func (s *AuditService) dispatch(ctx context.Context, rec *domain.Record) {
+	ctx, cancel := context.WithTimeout(ctx, s.publishTimeout)
+	defer cancel()
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		s.logger.Error("failed to marshal audit record", "request_id", rec.RequestID, "error", err)
+		return
+	}
+
+	subject := s.Subject(rec)
+	if _, err := s.publisher.Publish(ctx, subject, data); err != nil {
+		s.logger.Error("failed to publish audit record",
+			"request_id", rec.RequestID,
+			"subject", subject,
+			"error", err,
+		)
+	}
+
+	if s.store != nil {
+		if err := s.store.Insert(ctx, rec); err != nil {
+			s.logger.Error("failed to persist audit record",
+				"request_id", rec.RequestID,
+				"error", err,
+			)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/SebastienMelki/causality/internal/audit/internal/domain"
+)
+
+// mockPublisher records published messages.
+type mockPublisher struct {
+	mu       sync.Mutex
+	subjects []string
+	payloads [][]byte
+	err      error
+}
+
+func (m *mockPublisher) Publish(_ context.Context, subject string, data []byte, _ ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.subjects = append(m.subjects, subject)
+	m.payloads = append(m.payloads, data)
+	return &jetstream.PubAck{}, nil
+}
+
+// mockStore records inserted audit records.
+type mockStore struct {
+	mu      sync.Mutex
+	records []domain.Record
+}
+
+func (m *mockStore) Insert(_ context.Context, rec *domain.Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, *rec)
+	return nil
+}
+
+func TestAuditService_PublishesAndPersists(t *testing.T) {
+	pub := &mockPublisher{}
+	store := &mockStore{}
+	svc := NewAuditService(pub, store, "audit.ingest", 10, time.Second, nil)
+	svc.Start(context.Background())
+
+	svc.Record(domain.Record{RequestID: "req-1", AppID: "My.App", AcceptedCount: 3})
+	svc.Record(domain.Record{RequestID: "req-2", StatusCode: 401})
+	svc.Stop()
+
+	if len(pub.subjects) != 2 {
+		t.Fatalf("published %d records, want 2", len(pub.subjects))
+	}
+	if pub.subjects[0] != "audit.ingest.my_app" {
+		t.Errorf("subject[0] = %q, want audit.ingest.my_app", pub.subjects[0])
+	}
+	if pub.subjects[1] != "audit.ingest.unknown" {
+		t.Errorf("subject[1] = %q, want audit.ingest.unknown", pub.subjects[1])
+	}
+
+	var decoded domain.Record
+	if err := json.Unmarshal(pub.payloads[0], &decoded); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if decoded.RequestID != "req-1" || decoded.AcceptedCount != 3 {
+		t.Errorf("decoded record = %+v, want request_id req-1 with 3 accepted", decoded)
+	}
+
+	if len(store.records) != 2 {
+		t.Errorf("persisted %d records, want 2", len(store.records))
+	}
+}
+
+func TestAuditService_PublishFailureStillPersists(t *testing.T) {
+	pub := &mockPublisher{err: errors.New("nats down")}
+	store := &mockStore{}
+	svc := NewAuditService(pub, store, "audit.ingest", 10, time.Second, nil)
+	svc.Start(context.Background())
+
+	svc.Record(domain.Record{RequestID: "req-1"})
+	svc.Stop()
+
+	if len(store.records) != 1 {
+		t.Errorf("persisted %d records, want 1", len(store.records))
+	}
+}
+
+func TestAuditService_DropsWhenBufferFull(t *testing.T) {
+	pub := &mockPublisher{}
+	// Not started: nothing drains the buffer.
+	svc := NewAuditService(pub, nil, "audit.ingest", 1, time.Second, nil)
+
+	svc.Record(domain.Record{RequestID: "req-1"})
+	svc.Record(domain.Record{RequestID: "req-2"})
+
+	if got := len(svc.records); got != 1 {
+		t.Errorf("buffered %d records, want 1", got)
+	}
+}
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS ingest_audit_log (
+    id              BIGSERIAL PRIMARY KEY,
+    request_id      TEXT NOT NULL,
+    api_key_id      TEXT NOT NULL DEFAULT '',
+    app_id          TEXT NOT NULL DEFAULT '',
+    method          TEXT NOT NULL,
+    path            TEXT NOT NULL,
+    client_ip       TEXT NOT NULL DEFAULT '',
+    user_agent      TEXT NOT NULL DEFAULT '',
+    status_code     INTEGER NOT NULL,
+    decision        TEXT NOT NULL,
+    event_count     INTEGER NOT NULL DEFAULT 0,
+    accepted_count  INTEGER NOT NULL DEFAULT 0,
+    rejected_count  INTEGER NOT NULL DEFAULT 0,
+    duplicate_count INTEGER NOT NULL DEFAULT 0,
+    reasons         JSONB NOT NULL DEFAULT '[]',
+    duration_ms     BIGINT NOT NULL DEFAULT 0,
+    received_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Billing reconciliation: per-app usage over time
+CREATE INDEX IF NOT EXISTS idx_ingest_audit_log_app_received ON ingest_audit_log(app_id, received_at);
+
+-- Abuse investigation: per-key activity over time
+CREATE INDEX IF NOT EXISTS idx_ingest_audit_log_key_received ON ingest_audit_log(api_key_id, received_at);
+
+-- Lookup by request ID (matches X-Request-ID response header)
+CREATE INDEX IF NOT EXISTS idx_ingest_audit_log_request_id ON ingest_audit_log(request_id);
@@ -0,0 +1,4 @@
+ALTER TABLE ingest_audit_log DROP COLUMN IF EXISTS sdk_capabilities;
+ALTER TABLE ingest_audit_log DROP COLUMN IF EXISTS sdk_platform;
+ALTER TABLE ingest_audit_log DROP COLUMN IF EXISTS sdk_version;
+ALTER TABLE ingest_audit_log DROP COLUMN IF EXISTS sdk_name;
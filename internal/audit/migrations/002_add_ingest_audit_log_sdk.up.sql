@@ -0,0 +1,6 @@
+-- SDK of the request, from its X-Causality-SDK handshake header; empty when
+-- the client did not send one.
+ALTER TABLE ingest_audit_log ADD COLUMN IF NOT EXISTS sdk_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE ingest_audit_log ADD COLUMN IF NOT EXISTS sdk_version TEXT NOT NULL DEFAULT '';
+ALTER TABLE ingest_audit_log ADD COLUMN IF NOT EXISTS sdk_platform TEXT NOT NULL DEFAULT '';
+ALTER TABLE ingest_audit_log ADD COLUMN IF NOT EXISTS sdk_capabilities JSONB NOT NULL DEFAULT '[]';
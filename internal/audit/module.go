@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/audit/internal/repo"
+	"github.com/SebastienMelki/causality/internal/audit/internal/service"
+	"github.com/SebastienMelki/causality/internal/audit/migrations"
+	"github.com/SebastienMelki/causality/internal/db"
+)
+
+// Migrations are the audit module's database migrations.
+var Migrations = db.Source{Name: "audit", FS: migrations.FS}
+
+// Config holds the audit module configuration.
+//
+// Environment variable overrides:
+//   - AUDIT_ENABLED:          emit audit records (default: true)
+//   - AUDIT_SUBJECT_PREFIX:   NATS subject prefix; records go to "{prefix}.{app_id}" (default: audit.ingest)
+//   - AUDIT_POSTGRES_ENABLED: also persist records to the ingest_audit_log table (default: false)
+//   - AUDIT_BUFFER_SIZE:      in-memory record buffer before records are dropped (default: 10000)
+//   - AUDIT_PUBLISH_TIMEOUT:  per-record publish/insert timeout (default: 5s)
+type Config struct {
+	Enabled         bool          `env:"AUDIT_ENABLED"          envDefault:"true"`
+	SubjectPrefix   string        `env:"AUDIT_SUBJECT_PREFIX"   envDefault:"audit.ingest"`
+	PostgresEnabled bool          `env:"AUDIT_POSTGRES_ENABLED" envDefault:"false"`
+	BufferSize      int           `env:"AUDIT_BUFFER_SIZE"      envDefault:"10000"`
+	PublishTimeout  time.Duration `env:"AUDIT_PUBLISH_TIMEOUT"  envDefault:"5s"`
+}
+
+// Module is the audit module facade. It wraps the audit service and exposes
+// a Recorder for the HTTP gateway.
+type Module struct {
+	svc *service.AuditService
+}
+
+// New creates a new audit Module. The publisher is typically the JetStream
+// context; db is only used when cfg.PostgresEnabled is set and may be nil
+// otherwise.
+func New(publisher Publisher, db *sql.DB, cfg Config, logger *slog.Logger) *Module {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger = logger.With("module", "audit")
+
+	var store service.RecordStore
+	if cfg.PostgresEnabled && db != nil {
+		store = repo.NewRecordRepository(db)
+	}
+
+	return &Module{
+		svc: service.NewAuditService(publisher, store, cfg.SubjectPrefix, cfg.BufferSize, cfg.PublishTimeout, logger),
+	}
+}
+
+// Start begins the background dispatch goroutine.
+func (m *Module) Start(ctx context.Context) {
+	m.svc.Start(ctx)
+}
+
+// Stop flushes buffered records and stops the dispatch goroutine.
+func (m *Module) Stop() {
+	m.svc.Stop()
+}
+
+// Record enqueues an audit record for asynchronous delivery. It never blocks.
+func (m *Module) Record(rec Record) {
+	m.svc.Record(rec)
+}
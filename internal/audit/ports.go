@@ -0,0 +1,58 @@
+// Package audit provides the per-request ingestion audit log. Every request to
+// the ingestion endpoints produces one audit record (API key ID, app_id, event
+// counts, decision and reasons, request ID) that is published to a dedicated
+// NATS subject and optionally persisted to PostgreSQL for abuse investigation
+// and billing reconciliation.
+package audit
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/SebastienMelki/causality/internal/audit/internal/domain"
+)
+
+// Record is a single per-request ingestion audit entry.
+type Record = domain.Record
+
+// Decision summarizes the outcome of an ingestion request.
+type Decision = domain.Decision
+
+// Decision values recorded on audit records.
+const (
+	DecisionAccepted = domain.DecisionAccepted
+	DecisionPartial  = domain.DecisionPartial
+	DecisionRejected = domain.DecisionRejected
+)
+
+// Recorder accepts audit records. Implementations must be safe for concurrent
+// use and must not block the request path.
+type Recorder interface {
+	// Record enqueues an audit record for asynchronous delivery.
+	Record(rec Record)
+}
+
+// Publisher defines the port for publishing audit records to NATS. It is
+// satisfied by jetstream.JetStream.
+type Publisher interface {
+	Publish(ctx context.Context, subject string, data []byte, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+}
+
+// Store defines the port for audit record persistence.
+type Store interface {
+	// Insert appends an audit record.
+	Insert(ctx context.Context, rec *Record) error
+}
+
+// DecisionFor derives the request decision from the HTTP status code and the
+// per-event accepted/rejected counts.
+func DecisionFor(statusCode, accepted, rejected int) Decision {
+	return domain.DecisionFor(statusCode, accepted, rejected)
+}
+
+// StatusReason returns a stable decision reason for a request rejected at the
+// HTTP layer (e.g., "unauthorized", "rate_limited"). Empty for 2xx codes.
+func StatusReason(statusCode int) string {
+	return domain.StatusReason(statusCode)
+}
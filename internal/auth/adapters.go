@@ -1,12 +1,18 @@
 package auth
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/SebastienMelki/causality/internal/auth/internal/domain"
+	"github.com/SebastienMelki/causality/internal/observability"
 )
 
 // skipAuthPaths lists URL path prefixes that bypass API key authentication.
@@ -15,12 +21,25 @@ var skipAuthPaths = []string{
 	"/health",
 	"/ready",
 	"/metrics",
+	"/debug/",
 	"/api/admin/",
 }
 
-// authMiddleware returns HTTP middleware that validates the X-API-Key header.
-// On success it injects the authenticated app_id into the request context.
-// On failure it returns 401 Unauthorized with a JSON error body.
+// Signed request headers. The signature is computed over the timestamp and
+// the raw request body (see domain.ComputeSignature).
+const (
+	// TimestampHeader carries the request time in Unix seconds.
+	TimestampHeader = "X-Causality-Timestamp"
+
+	// SignatureHeader carries "sha256=" + base64 HMAC-SHA256 of
+	// "{timestamp}.{body}" keyed by the API key's signing secret.
+	SignatureHeader = "X-Causality-Signature"
+)
+
+// authMiddleware returns HTTP middleware that validates the X-API-Key header,
+// and the request signature for keys with a signing secret. On success it
+// injects the authenticated app_id into the request context. On failure it
+// returns 401 Unauthorized with a JSON error body.
 func (m *Module) authMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -61,13 +80,107 @@ func (m *Module) authMiddleware() func(http.Handler) http.Handler {
 				return
 			}
 
-			// Inject app_id into context for downstream handlers
+			if key.SigningSecret != "" {
+				if msg := m.verifySignedRequest(r, key.SigningSecret); msg != "" {
+					m.logger.Warn("rejected signed request",
+						"key_id", key.ID,
+						"app_id", key.AppID,
+						"reason", msg,
+					)
+					writeAuthError(w, msg)
+					return
+				}
+			}
+
+			// Inject app_id, key ID, and event scope into context for downstream handlers
 			ctx := context.WithValue(r.Context(), AppIDContextKey, key.AppID)
+			ctx = context.WithValue(ctx, KeyIDContextKey, key.ID)
+			if len(key.AllowedEventTypes) > 0 {
+				ctx = context.WithValue(ctx, EventScopeContextKey, key.AllowedEventTypes)
+			}
+			ctx = observability.WithLogAttrs(ctx, observability.LogKeyAppID, key.AppID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// verifySignedRequest checks the timestamp and signature headers of a request
+// authenticated by a signing key. It returns an error message, or "" if the
+// request is valid. The body is read and restored for downstream handlers;
+// its size is already bounded by the gateway's body size limit.
+func (m *Module) verifySignedRequest(r *http.Request, secret string) string {
+	timestamp := r.Header.Get(TimestampHeader)
+	signature := r.Header.Get(SignatureHeader)
+	if timestamp == "" || signature == "" {
+		return "missing request signature"
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "invalid request timestamp"
+	}
+	now := time.Now()
+	if skew := now.Sub(time.Unix(unix, 0)).Abs(); skew > m.config.SignatureMaxSkew {
+		return "request timestamp outside allowed window"
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "failed to read request body"
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if !domain.VerifySignature(secret, timestamp, body, signature) {
+		return "invalid request signature"
+	}
+
+	// A signature seen within the window is a replay. Entries expire once
+	// their timestamp falls outside the window, after which the timestamp
+	// check rejects them anyway.
+	if !m.replay.add(signature, time.Unix(unix, 0).Add(m.config.SignatureMaxSkew), now) {
+		return "replayed request"
+	}
+
+	return ""
+}
+
+// replayGuard remembers recently accepted request signatures until they
+// expire. It is per process, so replays across gateway instances are only
+// bounded by the timestamp window.
+type replayGuard struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastPrune time.Time
+}
+
+// newReplayGuard creates an empty replay guard.
+func newReplayGuard() *replayGuard {
+	return &replayGuard{seen: make(map[string]time.Time)}
+}
+
+// add records a signature valid until expiresAt. It returns false if the
+// signature was already recorded and has not expired.
+func (g *replayGuard) add(signature string, expiresAt, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Prune expired entries at most once per second
+	if now.Sub(g.lastPrune) >= time.Second {
+		for sig, exp := range g.seen {
+			if now.After(exp) {
+				delete(g.seen, sig)
+			}
+		}
+		g.lastPrune = now
+	}
+
+	if exp, ok := g.seen[signature]; ok && !now.After(exp) {
+		return false
+	}
+	g.seen[signature] = expiresAt
+	return true
+}
+
 // GetAppID retrieves the authenticated app_id from the request context.
 // Returns an empty string if no app_id is present (e.g., unauthenticated request).
 func GetAppID(ctx context.Context) string {
@@ -77,6 +190,23 @@ func GetAppID(ctx context.Context) string {
 	return ""
 }
 
+// GetKeyID retrieves the ID of the authenticating API key from the request
+// context. Returns an empty string if the request was not authenticated.
+func GetKeyID(ctx context.Context) string {
+	if keyID, ok := ctx.Value(KeyIDContextKey).(string); ok {
+		return keyID
+	}
+	return ""
+}
+
+// EventAllowed reports whether the API key that authenticated the request may
+// send events of the given category and type. Requests without a restricted
+// key (including unauthenticated ones) allow all events.
+func EventAllowed(ctx context.Context, category, eventType string) bool {
+	scope, _ := ctx.Value(EventScopeContextKey).([]string)
+	return domain.EventScopeAllows(scope, category, eventType)
+}
+
 // writeAuthError writes a 401 Unauthorized JSON response.
 func writeAuthError(w http.ResponseWriter, message string) {
 	w.Header().Set("Content-Type", "application/json")
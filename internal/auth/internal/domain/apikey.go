@@ -3,11 +3,14 @@
 package domain
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
 )
 
@@ -26,6 +29,17 @@ type APIKey struct {
 	// Name is a human-readable label for the key (e.g., "Production iOS").
 	Name string
 
+	// AllowedEventTypes restricts the events the key may send. Each entry is
+	// an event category ("commerce") or a category and type
+	// ("commerce.purchase_complete"). An empty list allows all events.
+	AllowedEventTypes []string
+
+	// SigningSecret is the HMAC secret for signed requests. When set, every
+	// request authenticated by this key must carry a valid signature. Unlike
+	// the key itself it is stored as-is, since the server must recompute
+	// signatures.
+	SigningSecret string
+
 	// Revoked indicates whether this key has been revoked.
 	Revoked bool
 
@@ -36,6 +50,15 @@ type APIKey struct {
 	RevokedAt *time.Time
 }
 
+// KeyOptions holds the optional settings of a new API key.
+type KeyOptions struct {
+	// AllowedEventTypes restricts the events the key may send; nil allows all.
+	AllowedEventTypes []string
+
+	// Signed generates a signing secret and requires signed requests.
+	Signed bool
+}
+
 // hexKeyRegex matches exactly 64 lowercase hexadecimal characters.
 var hexKeyRegex = regexp.MustCompile(`^[0-9a-f]{64}$`)
 
@@ -54,6 +77,35 @@ func GenerateKey() (plaintext string, hash string, err error) {
 	return plaintext, hash, nil
 }
 
+// GenerateSigningSecret creates a new random HMAC signing secret as a
+// 64-char hex string from 32 random bytes.
+func GenerateSigningSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ComputeSignature returns the request signature for a timestamp header value
+// and raw request body: "sha256=" followed by the base64 HMAC-SHA256 of
+// "{timestamp}.{body}" keyed by the signing secret. This matches the format of
+// outgoing webhook signatures.
+func ComputeSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether signature is valid for the timestamp and
+// body, using a constant-time comparison.
+func VerifySignature(secret, timestamp string, body []byte, signature string) bool {
+	expected := ComputeSignature(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
 // HashKey computes the SHA256 hash of a plaintext API key and returns it
 // as a lowercase hex-encoded string. SHA256 is used instead of bcrypt because
 // API keys are high-entropy random strings (256 bits), making brute-force
@@ -68,3 +120,33 @@ func HashKey(plaintext string) string {
 func ValidateKeyFormat(key string) bool {
 	return hexKeyRegex.MatchString(key)
 }
+
+// scopeCategoryRegex matches an event category in an event scope entry.
+var scopeCategoryRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// ValidateEventScope checks that every entry of an allowed event types list is
+// a category or a "category.type" pair. Custom event names may contain dots,
+// so only the first dot separates the category from the type.
+func ValidateEventScope(scope []string) error {
+	for _, entry := range scope {
+		category, eventType, hasType := strings.Cut(entry, ".")
+		if !scopeCategoryRegex.MatchString(category) || (hasType && eventType == "") {
+			return fmt.Errorf("invalid event scope entry %q: want \"category\" or \"category.type\"", entry)
+		}
+	}
+	return nil
+}
+
+// EventScopeAllows reports whether an allowed event types list permits events
+// of the given category and type. An empty list allows everything.
+func EventScopeAllows(scope []string, category, eventType string) bool {
+	if len(scope) == 0 {
+		return true
+	}
+	for _, entry := range scope {
+		if entry == category || entry == category+"."+eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -157,3 +157,77 @@ func TestGenerateKey_ConcurrentSafety(t *testing.T) {
 		seen[key] = true
 	}
 }
+
+func TestValidateEventScope(t *testing.T) {
+	tests := []struct {
+		name    string
+		scope   []string
+		wantErr bool
+	}{
+		{name: "empty", scope: nil},
+		{name: "categories and types", scope: []string{"commerce", "user.login", "custom.checkout.step"}},
+		{name: "empty entry", scope: []string{""}, wantErr: true},
+		{name: "missing type", scope: []string{"commerce."}, wantErr: true},
+		{name: "uppercase category", scope: []string{"Commerce"}, wantErr: true},
+		{name: "wildcard", scope: []string{"*"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateEventScope(tt.scope); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEventScope(%v) error = %v, wantErr %v", tt.scope, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEventScopeAllows(t *testing.T) {
+	scope := []string{"commerce", "user.login"}
+
+	tests := []struct {
+		category  string
+		eventType string
+		want      bool
+	}{
+		{category: "commerce", eventType: "purchase_complete", want: true},
+		{category: "user", eventType: "login", want: true},
+		{category: "user", eventType: "logout", want: false},
+		{category: "interaction", eventType: "button_tap", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := EventScopeAllows(scope, tt.category, tt.eventType); got != tt.want {
+			t.Errorf("EventScopeAllows(%s.%s) = %v, want %v", tt.category, tt.eventType, got, tt.want)
+		}
+	}
+
+	if !EventScopeAllows(nil, "interaction", "button_tap") {
+		t.Error("empty scope should allow all events")
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	secret, err := GenerateSigningSecret()
+	if err != nil {
+		t.Fatalf("GenerateSigningSecret() returned unexpected error: %v", err)
+	}
+	if !hexPattern.MatchString(secret) {
+		t.Errorf("signing secret is not 64 hex chars: got %q", secret)
+	}
+
+	body := []byte(`{"event":{"app_id":"app"}}`)
+	signature := ComputeSignature(secret, "1700000000", body)
+
+	if !VerifySignature(secret, "1700000000", body, signature) {
+		t.Error("VerifySignature() should accept a valid signature")
+	}
+	if VerifySignature(secret, "1700000001", body, signature) {
+		t.Error("VerifySignature() should reject a different timestamp")
+	}
+	if VerifySignature(secret, "1700000000", []byte(`{}`), signature) {
+		t.Error("VerifySignature() should reject a different body")
+	}
+	if VerifySignature("other", "1700000000", body, signature) {
+		t.Error("VerifySignature() should reject a different secret")
+	}
+}
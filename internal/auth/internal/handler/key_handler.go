@@ -3,10 +3,12 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strings"
 
+	"github.com/SebastienMelki/causality/internal/auth/internal/domain"
 	"github.com/SebastienMelki/causality/internal/auth/internal/service"
 )
 
@@ -43,18 +45,23 @@ func (h *KeyHandler) RegisterRoutes(mux *http.ServeMux) {
 
 // createKeyRequest is the JSON request body for creating a new API key.
 type createKeyRequest struct {
-	AppID string `json:"app_id"`
-	Name  string `json:"name"`
+	AppID             string   `json:"app_id"`
+	Name              string   `json:"name"`
+	AllowedEventTypes []string `json:"allowed_event_types"`
+	Signed            bool     `json:"signed"`
 }
 
 // createKeyResponse is the JSON response for a newly created API key.
-// The plaintext key is only returned once at creation time.
+// The plaintext key and signing secret are only returned once at creation
+// time.
 type createKeyResponse struct {
-	ID       string `json:"id"`
-	AppID    string `json:"app_id"`
-	Name     string `json:"name"`
-	Key      string `json:"key"`
-	Messsage string `json:"message"`
+	ID                string   `json:"id"`
+	AppID             string   `json:"app_id"`
+	Name              string   `json:"name"`
+	AllowedEventTypes []string `json:"allowed_event_types,omitempty"`
+	Key               string   `json:"key"`
+	SigningSecret     string   `json:"signing_secret,omitempty"`
+	Messsage          string   `json:"message"`
 }
 
 // handleCreate handles POST /api/admin/keys - creates a new API key.
@@ -74,7 +81,16 @@ func (h *KeyHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	plaintext, key, err := h.service.CreateKey(r.Context(), req.AppID, req.Name)
+	plaintext, key, err := h.service.CreateKey(r.Context(), req.AppID, req.Name, domain.KeyOptions{
+		AllowedEventTypes: req.AllowedEventTypes,
+		Signed:            req.Signed,
+	})
+	if errors.Is(err, service.ErrInvalidEventScope) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		h.logger.Error("failed to create API key",
 			"app_id", req.AppID,
@@ -87,11 +103,13 @@ func (h *KeyHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	writeJSON(w, http.StatusCreated, createKeyResponse{
-		ID:       key.ID,
-		AppID:    key.AppID,
-		Name:     key.Name,
-		Key:      plaintext,
-		Messsage: "Store this key securely. It will not be shown again.",
+		ID:                key.ID,
+		AppID:             key.AppID,
+		Name:              key.Name,
+		AllowedEventTypes: key.AllowedEventTypes,
+		Key:               plaintext,
+		SigningSecret:     key.SigningSecret,
+		Messsage:          "Store this key securely. It will not be shown again.",
 	})
 }
 
@@ -154,22 +172,26 @@ func (h *KeyHandler) handleList(w http.ResponseWriter, r *http.Request) {
 
 	// Build response that never exposes key hashes
 	type keyItem struct {
-		ID        string  `json:"id"`
-		AppID     string  `json:"app_id"`
-		Name      string  `json:"name"`
-		Revoked   bool    `json:"revoked"`
-		CreatedAt string  `json:"created_at"`
-		RevokedAt *string `json:"revoked_at,omitempty"`
+		ID                string   `json:"id"`
+		AppID             string   `json:"app_id"`
+		Name              string   `json:"name"`
+		AllowedEventTypes []string `json:"allowed_event_types,omitempty"`
+		Signed            bool     `json:"signed"`
+		Revoked           bool     `json:"revoked"`
+		CreatedAt         string   `json:"created_at"`
+		RevokedAt         *string  `json:"revoked_at,omitempty"`
 	}
 
 	items := make([]keyItem, len(keys))
 	for i, k := range keys {
 		item := keyItem{
-			ID:        k.ID,
-			AppID:     k.AppID,
-			Name:      k.Name,
-			Revoked:   k.Revoked,
-			CreatedAt: k.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+			ID:                k.ID,
+			AppID:             k.AppID,
+			Name:              k.Name,
+			AllowedEventTypes: k.AllowedEventTypes,
+			Signed:            k.SigningSecret != "",
+			Revoked:           k.Revoked,
+			CreatedAt:         k.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		}
 		if k.RevokedAt != nil {
 			formatted := k.RevokedAt.Format("2006-01-02T15:04:05Z07:00")
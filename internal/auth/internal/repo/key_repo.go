@@ -5,8 +5,18 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/SebastienMelki/causality/internal/auth/internal/domain"
+	"github.com/SebastienMelki/causality/internal/outbox"
+)
+
+// Outbox event types of API key changes.
+const (
+	eventKeyCreated = "api_key.created"
+	eventKeyRevoked = "api_key.revoked"
 )
 
 // KeyRepository implements the KeyStore interface using PostgreSQL.
@@ -23,7 +33,7 @@ func NewKeyRepository(db *sql.DB) *KeyRepository {
 // Returns nil, nil if no matching key is found.
 func (r *KeyRepository) FindByHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
 	query := `
-		SELECT id, app_id, key_hash, name, revoked, created_at, revoked_at
+		SELECT id, app_id, key_hash, name, allowed_event_types, signing_secret, revoked, created_at, revoked_at
 		FROM api_keys
 		WHERE key_hash = $1 AND NOT revoked
 	`
@@ -34,6 +44,8 @@ func (r *KeyRepository) FindByHash(ctx context.Context, keyHash string) (*domain
 		&key.AppID,
 		&key.KeyHash,
 		&key.Name,
+		stringArray{&key.AllowedEventTypes},
+		&key.SigningSecret,
 		&key.Revoked,
 		&key.CreatedAt,
 		&key.RevokedAt,
@@ -48,48 +60,86 @@ func (r *KeyRepository) FindByHash(ctx context.Context, keyHash string) (*domain
 	return &key, nil
 }
 
-// Create inserts a new API key record into the database.
+// Create inserts a new API key record into the database, along with an
+// api_key.created outbox event in the same transaction.
 func (r *KeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
 	query := `
-		INSERT INTO api_keys (id, app_id, key_hash, name)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO api_keys (id, app_id, key_hash, name, allowed_event_types, signing_secret)
+		VALUES ($1, $2, $3, $4, $5, $6)
 	`
 
-	_, err := r.db.ExecContext(ctx, query, key.ID, key.AppID, key.KeyHash, key.Name)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, query,
+		key.ID, key.AppID, key.KeyHash, key.Name, scopeOrEmpty(key.AllowedEventTypes), key.SigningSecret,
+	)
 	if err != nil {
 		return fmt.Errorf("failed to insert api key: %w", err)
 	}
 
-	return nil
+	// The event carries metadata only, never the key hash or signing secret.
+	err = outbox.Enqueue(ctx, tx, &outbox.Event{
+		Type:       eventKeyCreated,
+		AppID:      key.AppID,
+		ResourceID: key.ID,
+		Data: map[string]any{
+			"name":                key.Name,
+			"allowed_event_types": scopeOrEmpty(key.AllowedEventTypes),
+			"signed":              key.SigningSecret != "",
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-// Revoke marks an API key as revoked by setting revoked=true and revoked_at=now().
+// Revoke marks an API key as revoked by setting revoked=true and
+// revoked_at=now(), along with an api_key.revoked outbox event in the same
+// transaction.
 func (r *KeyRepository) Revoke(ctx context.Context, id string) error {
 	query := `
 		UPDATE api_keys SET revoked = true, revoked_at = now()
 		WHERE id = $1
+		RETURNING app_id
 	`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to revoke api key: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	rows, err := result.RowsAffected()
+	var appID string
+	err = tx.QueryRowContext(ctx, query, id).Scan(&appID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("api key not found: %s", id)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to check rows affected: %w", err)
+		return fmt.Errorf("failed to revoke api key: %w", err)
 	}
-	if rows == 0 {
-		return fmt.Errorf("api key not found: %s", id)
+
+	err = outbox.Enqueue(ctx, tx, &outbox.Event{
+		Type:       eventKeyRevoked,
+		AppID:      appID,
+		ResourceID: id,
+	})
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 // ListByAppID returns all API keys for the given app, ordered by creation date descending.
 func (r *KeyRepository) ListByAppID(ctx context.Context, appID string) ([]domain.APIKey, error) {
 	query := `
-		SELECT id, app_id, key_hash, name, revoked, created_at, revoked_at
+		SELECT id, app_id, key_hash, name, allowed_event_types, signing_secret, revoked, created_at, revoked_at
 		FROM api_keys
 		WHERE app_id = $1
 		ORDER BY created_at DESC
@@ -109,6 +159,8 @@ func (r *KeyRepository) ListByAppID(ctx context.Context, appID string) ([]domain
 			&key.AppID,
 			&key.KeyHash,
 			&key.Name,
+			stringArray{&key.AllowedEventTypes},
+			&key.SigningSecret,
 			&key.Revoked,
 			&key.CreatedAt,
 			&key.RevokedAt,
@@ -124,3 +176,53 @@ func (r *KeyRepository) ListByAppID(ctx context.Context, appID string) ([]domain
 
 	return keys, nil
 }
+
+// ListAppIDs returns the distinct app IDs that have API keys, in order.
+func (r *KeyRepository) ListAppIDs(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT app_id FROM api_keys ORDER BY app_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query app ids: %w", err)
+	}
+	defer rows.Close()
+
+	var appIDs []string
+	for rows.Next() {
+		var appID string
+		if err := rows.Scan(&appID); err != nil {
+			return nil, fmt.Errorf("failed to scan app id: %w", err)
+		}
+		appIDs = append(appIDs, appID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating app ids: %w", err)
+	}
+
+	return appIDs, nil
+}
+
+// scopeOrEmpty returns scope, or an empty list for nil so the NOT NULL
+// allowed_event_types column receives '{}' rather than NULL.
+func scopeOrEmpty(scope []string) []string {
+	if scope == nil {
+		return []string{}
+	}
+	return scope
+}
+
+// typeMaps holds the pgx type maps stringArray scans with. A map caches scan
+// plans, so it is not safe for concurrent use.
+var typeMaps = sync.Pool{New: func() any { return pgtype.NewMap() }}
+
+// stringArray scans a text[] column into a string slice, which database/sql
+// cannot do on its own.
+type stringArray struct {
+	dst *[]string
+}
+
+// Scan implements sql.Scanner.
+func (a stringArray) Scan(src any) error {
+	m := typeMaps.Get().(*pgtype.Map)
+	defer typeMaps.Put(m)
+	return m.SQLScanner(a.dst).Scan(src)
+}
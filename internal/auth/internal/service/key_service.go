@@ -19,13 +19,15 @@ type KeyStore interface {
 	Create(ctx context.Context, key *domain.APIKey) error
 	Revoke(ctx context.Context, id string) error
 	ListByAppID(ctx context.Context, appID string) ([]domain.APIKey, error)
+	ListAppIDs(ctx context.Context) ([]string, error)
 }
 
 // Common errors returned by KeyService methods.
 var (
-	ErrKeyNotFound = errors.New("api key not found or revoked")
-	ErrInvalidKey  = errors.New("invalid api key format")
-	ErrEmptyAppID  = errors.New("app_id is required")
+	ErrKeyNotFound       = errors.New("api key not found or revoked")
+	ErrInvalidKey        = errors.New("invalid api key format")
+	ErrEmptyAppID        = errors.New("app_id is required")
+	ErrInvalidEventScope = errors.New("invalid allowed_event_types")
 )
 
 // KeyService provides business logic for API key management including
@@ -69,12 +71,17 @@ func (s *KeyService) ValidateKey(ctx context.Context, keyHash string) (*domain.A
 	return key, nil
 }
 
-// CreateKey generates a new API key for the given app. It returns the
-// plaintext key (to be shown once to the user) and the persisted APIKey record.
-func (s *KeyService) CreateKey(ctx context.Context, appID, name string) (plaintext string, key *domain.APIKey, err error) {
+// CreateKey generates a new API key for the given app with the given options
+// (allowed event types, signed requests). It returns the plaintext key (to be
+// shown once to the user) and the persisted APIKey record, whose
+// SigningSecret is set for signed keys.
+func (s *KeyService) CreateKey(ctx context.Context, appID, name string, opts domain.KeyOptions) (plaintext string, key *domain.APIKey, err error) {
 	if appID == "" {
 		return "", nil, ErrEmptyAppID
 	}
+	if err := domain.ValidateEventScope(opts.AllowedEventTypes); err != nil {
+		return "", nil, fmt.Errorf("%w: %w", ErrInvalidEventScope, err)
+	}
 
 	plaintext, hash, err := domain.GenerateKey()
 	if err != nil {
@@ -82,10 +89,18 @@ func (s *KeyService) CreateKey(ctx context.Context, appID, name string) (plainte
 	}
 
 	key = &domain.APIKey{
-		ID:      uuid.Must(uuid.NewV7()).String(),
-		AppID:   appID,
-		KeyHash: hash,
-		Name:    name,
+		ID:                uuid.Must(uuid.NewV7()).String(),
+		AppID:             appID,
+		KeyHash:           hash,
+		Name:              name,
+		AllowedEventTypes: opts.AllowedEventTypes,
+	}
+
+	if opts.Signed {
+		key.SigningSecret, err = domain.GenerateSigningSecret()
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to generate signing secret: %w", err)
+		}
 	}
 
 	if err := s.store.Create(ctx, key); err != nil {
@@ -96,6 +111,8 @@ func (s *KeyService) CreateKey(ctx context.Context, appID, name string) (plainte
 		"key_id", key.ID,
 		"app_id", appID,
 		"name", name,
+		"allowed_event_types", opts.AllowedEventTypes,
+		"signed", opts.Signed,
 	)
 
 	return plaintext, key, nil
@@ -124,3 +141,13 @@ func (s *KeyService) ListKeys(ctx context.Context, appID string) ([]domain.APIKe
 
 	return keys, nil
 }
+
+// ListApps returns the IDs of all apps that have API keys, revoked or not.
+func (s *KeyService) ListApps(ctx context.Context) ([]string, error) {
+	appIDs, err := s.store.ListAppIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list apps: %w", err)
+	}
+
+	return appIDs, nil
+}
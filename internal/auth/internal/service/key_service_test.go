@@ -4,6 +4,7 @@ package service
 import (
 	"context"
 	"errors"
+	"sort"
 	"testing"
 	"time"
 
@@ -60,6 +61,22 @@ func (m *mockKeyStore) Revoke(_ context.Context, id string) error {
 	return nil
 }
 
+func (m *mockKeyStore) ListAppIDs(_ context.Context) ([]string, error) {
+	if m.listErr != nil {
+		return nil, m.listErr
+	}
+	seen := make(map[string]bool)
+	var result []string
+	for _, key := range m.keys {
+		if !seen[key.AppID] {
+			seen[key.AppID] = true
+			result = append(result, key.AppID)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
 func (m *mockKeyStore) ListByAppID(_ context.Context, appID string) ([]domain.APIKey, error) {
 	if m.listErr != nil {
 		return nil, m.listErr
@@ -152,7 +169,7 @@ func TestCreateKey_Success(t *testing.T) {
 	store := newMockKeyStore()
 	svc := NewKeyService(store, nil)
 
-	plaintext, key, err := svc.CreateKey(context.Background(), "app-1", "Production Key")
+	plaintext, key, err := svc.CreateKey(context.Background(), "app-1", "Production Key", domain.KeyOptions{})
 	if err != nil {
 		t.Fatalf("CreateKey() returned unexpected error: %v", err)
 	}
@@ -189,7 +206,7 @@ func TestCreateKey_EmptyAppID(t *testing.T) {
 	store := newMockKeyStore()
 	svc := NewKeyService(store, nil)
 
-	_, _, err := svc.CreateKey(context.Background(), "", "Some Key")
+	_, _, err := svc.CreateKey(context.Background(), "", "Some Key", domain.KeyOptions{})
 	if err == nil {
 		t.Error("CreateKey() should return error for empty app_id")
 	}
@@ -203,12 +220,54 @@ func TestCreateKey_StoreError(t *testing.T) {
 	store.createErr = errors.New("failed to insert")
 	svc := NewKeyService(store, nil)
 
-	_, _, err := svc.CreateKey(context.Background(), "app-1", "Test Key")
+	_, _, err := svc.CreateKey(context.Background(), "app-1", "Test Key", domain.KeyOptions{})
 	if err == nil {
 		t.Error("CreateKey() should return error when store fails")
 	}
 }
 
+func TestCreateKey_EventScope(t *testing.T) {
+	store := newMockKeyStore()
+	svc := NewKeyService(store, nil)
+
+	_, key, err := svc.CreateKey(context.Background(), "app-1", "Server Key", domain.KeyOptions{AllowedEventTypes: []string{"commerce", "user.login"}})
+	if err != nil {
+		t.Fatalf("CreateKey() returned unexpected error: %v", err)
+	}
+	if len(key.AllowedEventTypes) != 2 {
+		t.Errorf("key.AllowedEventTypes = %v, want 2 entries", key.AllowedEventTypes)
+	}
+
+	_, _, err = svc.CreateKey(context.Background(), "app-1", "Bad Key", domain.KeyOptions{AllowedEventTypes: []string{"commerce."}})
+	if !errors.Is(err, ErrInvalidEventScope) {
+		t.Errorf("CreateKey() error = %v, want ErrInvalidEventScope", err)
+	}
+	if store.createCalls != 1 {
+		t.Errorf("store.Create() called %d times, want 1", store.createCalls)
+	}
+}
+
+func TestCreateKey_Signed(t *testing.T) {
+	store := newMockKeyStore()
+	svc := NewKeyService(store, nil)
+
+	_, key, err := svc.CreateKey(context.Background(), "app-1", "Server Key", domain.KeyOptions{Signed: true})
+	if err != nil {
+		t.Fatalf("CreateKey() returned unexpected error: %v", err)
+	}
+	if len(key.SigningSecret) != 64 {
+		t.Errorf("key.SigningSecret length = %d, want 64", len(key.SigningSecret))
+	}
+
+	_, unsigned, err := svc.CreateKey(context.Background(), "app-1", "SDK Key", domain.KeyOptions{})
+	if err != nil {
+		t.Fatalf("CreateKey() returned unexpected error: %v", err)
+	}
+	if unsigned.SigningSecret != "" {
+		t.Error("unsigned key should not have a signing secret")
+	}
+}
+
 func TestRevokeKey_Success(t *testing.T) {
 	store := newMockKeyStore()
 	svc := NewKeyService(store, nil)
@@ -308,3 +367,21 @@ func TestListKeys_StoreError(t *testing.T) {
 		t.Error("ListKeys() should return error when store fails")
 	}
 }
+
+func TestListApps(t *testing.T) {
+	store := newMockKeyStore()
+	svc := NewKeyService(store, nil)
+
+	store.keys["hash1"] = &domain.APIKey{ID: "key-1", AppID: "app-2", KeyHash: "hash1"}
+	store.keys["hash2"] = &domain.APIKey{ID: "key-2", AppID: "app-1", KeyHash: "hash2"}
+	store.keys["hash3"] = &domain.APIKey{ID: "key-3", AppID: "app-2", KeyHash: "hash3", Revoked: true}
+
+	apps, err := svc.ListApps(context.Background())
+	if err != nil {
+		t.Fatalf("ListApps() returned unexpected error: %v", err)
+	}
+
+	if len(apps) != 2 || apps[0] != "app-1" || apps[1] != "app-2" {
+		t.Errorf("ListApps() = %v, want [app-1 app-2]", apps)
+	}
+}
@@ -9,7 +9,7 @@ CREATE TABLE IF NOT EXISTS api_keys (
 );
 
 -- Partial index for fast lookup of active keys by hash
-CREATE INDEX idx_api_keys_key_hash ON api_keys(key_hash) WHERE NOT revoked;
+CREATE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys(key_hash) WHERE NOT revoked;
 
 -- Index for listing keys by app
-CREATE INDEX idx_api_keys_app_id ON api_keys(app_id);
+CREATE INDEX IF NOT EXISTS idx_api_keys_app_id ON api_keys(app_id);
@@ -0,0 +1,3 @@
+-- Restrict keys to event categories ("commerce") or category.type pairs
+-- ("commerce.purchase_complete"). An empty array allows all events.
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS allowed_event_types TEXT[] NOT NULL DEFAULT '{}';
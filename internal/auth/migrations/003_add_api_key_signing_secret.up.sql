@@ -0,0 +1,3 @@
+-- HMAC secret for signed requests; keys with a secret must sign every request.
+-- Empty means the key is not signed.
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS signing_secret TEXT NOT NULL DEFAULT '';
@@ -5,13 +5,29 @@ import (
 	"database/sql"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/SebastienMelki/causality/internal/auth/internal/domain"
 	"github.com/SebastienMelki/causality/internal/auth/internal/handler"
 	"github.com/SebastienMelki/causality/internal/auth/internal/repo"
 	"github.com/SebastienMelki/causality/internal/auth/internal/service"
+	"github.com/SebastienMelki/causality/internal/auth/migrations"
+	"github.com/SebastienMelki/causality/internal/db"
 )
 
+// Migrations are the auth module's database migrations.
+var Migrations = db.Source{Name: "auth", FS: migrations.FS}
+
+// Config holds the auth module configuration.
+//
+// Environment variable overrides:
+//   - AUTH_SIGNATURE_MAX_SKEW: how far X-Causality-Timestamp of a signed request
+//     may differ from the server clock; signatures are also rejected if seen
+//     again within this window (default: 5m)
+type Config struct {
+	SignatureMaxSkew time.Duration `env:"AUTH_SIGNATURE_MAX_SKEW" envDefault:"5m"`
+}
+
 // Module is the auth module facade. It wires together the domain, service,
 // repository, and handler layers, and exposes the public API for key management
 // and HTTP middleware.
@@ -19,15 +35,20 @@ type Module struct {
 	service *service.KeyService
 	repo    *repo.KeyRepository
 	handler *handler.KeyHandler
+	replay  *replayGuard
+	config  Config
 	logger  *slog.Logger
 }
 
 // New creates a new auth Module. It initializes the PostgreSQL repository,
 // key service, and admin handler.
-func New(db *sql.DB, logger *slog.Logger) *Module {
+func New(db *sql.DB, cfg Config, logger *slog.Logger) *Module {
 	if logger == nil {
 		logger = slog.Default()
 	}
+	if cfg.SignatureMaxSkew <= 0 {
+		cfg.SignatureMaxSkew = 5 * time.Minute
+	}
 
 	keyRepo := repo.NewKeyRepository(db)
 	keySvc := service.NewKeyService(keyRepo, logger)
@@ -37,18 +58,22 @@ func New(db *sql.DB, logger *slog.Logger) *Module {
 		service: keySvc,
 		repo:    keyRepo,
 		handler: keyHandler,
+		replay:  newReplayGuard(),
+		config:  cfg,
 		logger:  logger.With("component", "auth-module"),
 	}
 }
 
-// CreateKey generates a new API key for the given app. The returned plaintext
-// key must be shown to the user once and cannot be retrieved again.
-func (m *Module) CreateKey(ctx context.Context, appID, name string) (string, error) {
-	plaintext, _, err := m.service.CreateKey(ctx, appID, name)
+// CreateKey generates a new API key for the given app. The options restrict
+// the event categories and types the key may send and whether its requests
+// must be signed. The returned plaintext key and signing secret (empty for
+// unsigned keys) must be shown to the user once and cannot be retrieved again.
+func (m *Module) CreateKey(ctx context.Context, appID, name string, opts KeyOptions) (plaintext, signingSecret string, err error) {
+	plaintext, key, err := m.service.CreateKey(ctx, appID, name, opts)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
-	return plaintext, nil
+	return plaintext, key.SigningSecret, nil
 }
 
 // RevokeKey revokes an API key by its ID.
@@ -61,9 +86,16 @@ func (m *Module) ListKeys(ctx context.Context, appID string) ([]domain.APIKey, e
 	return m.service.ListKeys(ctx, appID)
 }
 
+// ListApps returns the IDs of all apps that have API keys.
+func (m *Module) ListApps(ctx context.Context) ([]string, error) {
+	return m.service.ListApps(ctx)
+}
+
 // AuthMiddleware returns HTTP middleware that validates API keys from the
 // X-API-Key header and injects the authenticated app_id into the request
-// context. Health, readiness, and metrics endpoints are excluded from auth.
+// context. Keys with a signing secret also require a valid request signature
+// (X-Causality-Timestamp, X-Causality-Signature). Health, readiness, and
+// metrics endpoints are excluded from auth.
 func (m *Module) AuthMiddleware() func(http.Handler) http.Handler {
 	return m.authMiddleware()
 }
@@ -9,6 +9,12 @@ import (
 	"github.com/SebastienMelki/causality/internal/auth/internal/domain"
 )
 
+// KeyOptions holds the optional settings of a new API key.
+type KeyOptions = domain.KeyOptions
+
+// APIKey is a stored API key record.
+type APIKey = domain.APIKey
+
 // KeyStore defines the port for API key persistence operations.
 type KeyStore interface {
 	// FindByHash retrieves an active (non-revoked) API key by its SHA256 hash.
@@ -22,6 +28,9 @@ type KeyStore interface {
 
 	// ListByAppID returns all API keys for a given app, ordered by creation date descending.
 	ListByAppID(ctx context.Context, appID string) ([]domain.APIKey, error)
+
+	// ListAppIDs returns the distinct app IDs that have API keys, in order.
+	ListAppIDs(ctx context.Context) ([]string, error)
 }
 
 // contextKey is an unexported type for context keys to avoid collisions.
@@ -30,3 +39,11 @@ type contextKey string
 // AppIDContextKey is the context key used to inject the authenticated app_id
 // into the request context after successful API key validation.
 const AppIDContextKey contextKey = "app_id"
+
+// KeyIDContextKey is the context key used to inject the ID of the API key that
+// authenticated the request. Used for audit logging and per-key attribution.
+const KeyIDContextKey contextKey = "key_id"
+
+// EventScopeContextKey is the context key used to inject the authenticating
+// API key's allowed event types. Absent or empty means all events are allowed.
+const EventScopeContextKey contextKey = "event_scope"
@@ -0,0 +1,342 @@
+// Package domain contains the compaction schedule model: cron expressions,
+// per-app overrides, and blackout windows during which no compaction runs.
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/SebastienMelki/causality/internal/schedule"
+)
+
+// DefaultAppID is the app ID under which the default schedule is stored in
+// PostgreSQL. It applies to every app without an override.
+const DefaultAppID = "*"
+
+// ErrInvalidSchedule is returned when a schedule fails validation.
+var ErrInvalidSchedule = errors.New("invalid compaction schedule")
+
+// Schedule is the compaction schedule as loaded from a config file or the
+// database. Zero values fall back to the module's environment configuration.
+type Schedule struct {
+	// Cron is the default cron expression (standard 5-field or @hourly style).
+	Cron string `json:"cron"`
+
+	// TargetSize is the default compacted file size in bytes.
+	TargetSize int64 `json:"target_size,omitempty"`
+
+	// MinFiles is the default minimum number of small files to compact.
+	MinFiles int `json:"min_files,omitempty"`
+
+	// Blackouts are windows during which no app is compacted.
+	Blackouts []Blackout `json:"blackouts,omitempty"`
+
+	// Apps holds per-app overrides keyed by app ID.
+	Apps map[string]AppOverride `json:"apps,omitempty"`
+}
+
+// AppOverride overrides the default schedule for a single app.
+type AppOverride struct {
+	// Cron runs this app on its own schedule instead of the default one.
+	Cron string `json:"cron,omitempty"`
+
+	// TargetSize overrides the compacted file size for this app.
+	TargetSize int64 `json:"target_size,omitempty"`
+
+	// MinFiles overrides the minimum small-file count for this app.
+	MinFiles int `json:"min_files,omitempty"`
+
+	// Blackouts are added to the default blackout windows for this app.
+	Blackouts []Blackout `json:"blackouts,omitempty"`
+
+	// Disabled excludes the app from compaction entirely.
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// Blackout is a daily time window during which compaction must not run,
+// e.g. peak query hours. End before Start wraps past midnight.
+type Blackout struct {
+	// Start is the window start as HH:MM.
+	Start string `json:"start"`
+
+	// End is the window end as HH:MM (exclusive).
+	End string `json:"end"`
+
+	// Days restricts the window to the given weekdays (mon..sun) on which
+	// it starts. Empty means every day.
+	Days []string `json:"days,omitempty"`
+
+	// Timezone is the IANA time zone of Start and End. Defaults to UTC.
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// Settings are the compaction parameters applied to one app.
+type Settings struct {
+	TargetSize int64
+	MinFiles   int
+}
+
+// Plan is a validated Schedule with parsed cron expressions and windows.
+// It is immutable and safe for concurrent use.
+type Plan struct {
+	spec      string
+	cron      cron.Schedule
+	defaults  Settings
+	blackouts []window
+	apps      map[string]appPlan
+}
+
+type appPlan struct {
+	cron      cron.Schedule
+	settings  Settings
+	blackouts []window
+	disabled  bool
+}
+
+// Due records which schedules fired in a scheduler tick.
+type Due struct {
+	// All marks every schedule as fired, e.g. for a manual run.
+	All bool
+
+	// Default is true when the default cron fired.
+	Default bool
+
+	// Apps holds apps with their own cron that fired.
+	Apps map[string]bool
+}
+
+// Any reports whether any schedule fired.
+func (d Due) Any() bool {
+	return d.All || d.Default || len(d.Apps) > 0
+}
+
+// Compile validates s and resolves zero values against defaults.
+func Compile(s *Schedule, defaults Settings) (*Plan, error) {
+	p := &Plan{
+		spec:     s.Cron,
+		defaults: mergeSettings(defaults, s.TargetSize, s.MinFiles),
+		apps:     make(map[string]appPlan, len(s.Apps)),
+	}
+
+	var err error
+	if s.Cron != "" {
+		if p.cron, err = schedule.Parse(s.Cron); err != nil {
+			return nil, fmt.Errorf("%w: cron %q: %v", ErrInvalidSchedule, s.Cron, err)
+		}
+	}
+	if p.blackouts, err = compileWindows(s.Blackouts); err != nil {
+		return nil, err
+	}
+
+	for appID, o := range s.Apps {
+		if appID == "" || appID == DefaultAppID {
+			return nil, fmt.Errorf("%w: invalid app id %q", ErrInvalidSchedule, appID)
+		}
+		ap := appPlan{
+			settings: mergeSettings(p.defaults, o.TargetSize, o.MinFiles),
+			disabled: o.Disabled,
+		}
+		if o.Cron != "" {
+			if ap.cron, err = schedule.Parse(o.Cron); err != nil {
+				return nil, fmt.Errorf("%w: app %s cron %q: %v", ErrInvalidSchedule, appID, o.Cron, err)
+			}
+		}
+		if ap.blackouts, err = compileWindows(o.Blackouts); err != nil {
+			return nil, fmt.Errorf("app %s: %w", appID, err)
+		}
+		p.apps[appID] = ap
+	}
+
+	return p, nil
+}
+
+// Next returns the earliest time after t at which any schedule fires, or the
+// zero time if no schedule has a cron expression.
+func (p *Plan) Next(t time.Time) time.Time {
+	var next time.Time
+	consider := func(c cron.Schedule) {
+		if c == nil {
+			return
+		}
+		if n := c.Next(t); next.IsZero() || n.Before(next) {
+			next = n
+		}
+	}
+
+	consider(p.cron)
+	for _, ap := range p.apps {
+		if !ap.disabled {
+			consider(ap.cron)
+		}
+	}
+	return next
+}
+
+// DueBetween reports which schedules fired in the interval (from, to].
+func (p *Plan) DueBetween(from, to time.Time) Due {
+	fired := func(c cron.Schedule) bool {
+		return c != nil && !c.Next(from).After(to)
+	}
+
+	due := Due{Default: fired(p.cron)}
+	for appID, ap := range p.apps {
+		if !ap.disabled && fired(ap.cron) {
+			if due.Apps == nil {
+				due.Apps = make(map[string]bool)
+			}
+			due.Apps[appID] = true
+		}
+	}
+	return due
+}
+
+// Select reports whether appID should be compacted at now given the schedules
+// that fired, and with which settings. Apps with their own cron only run when
+// that cron fired; all others follow the default cron.
+func (p *Plan) Select(appID string, due Due, now time.Time) (Settings, bool) {
+	ap, ok := p.apps[appID]
+	if ok && ap.disabled {
+		return Settings{}, false
+	}
+
+	switch {
+	case due.All:
+	case ok && ap.cron != nil:
+		if !due.Apps[appID] {
+			return Settings{}, false
+		}
+	case !due.Default:
+		return Settings{}, false
+	}
+
+	if inWindows(p.blackouts, now) || (ok && inWindows(ap.blackouts, now)) {
+		return Settings{}, false
+	}
+
+	if ok {
+		return ap.settings, true
+	}
+	return p.defaults, true
+}
+
+// InBlackout reports whether now falls in a default blackout window.
+func (p *Plan) InBlackout(now time.Time) bool {
+	return inWindows(p.blackouts, now)
+}
+
+// Spec returns the default cron expression.
+func (p *Plan) Spec() string {
+	return p.spec
+}
+
+// Overrides returns the number of per-app overrides.
+func (p *Plan) Overrides() int {
+	return len(p.apps)
+}
+
+func mergeSettings(base Settings, targetSize int64, minFiles int) Settings {
+	if targetSize > 0 {
+		base.TargetSize = targetSize
+	}
+	if minFiles > 0 {
+		base.MinFiles = minFiles
+	}
+	return base
+}
+
+// window is a parsed Blackout. Times are minutes since local midnight.
+type window struct {
+	start, end int
+	days       [7]bool // indexed by time.Weekday; all false means every day
+	loc        *time.Location
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func compileWindows(blackouts []Blackout) ([]window, error) {
+	windows := make([]window, 0, len(blackouts))
+	for _, b := range blackouts {
+		w := window{loc: time.UTC}
+
+		var err error
+		if w.start, err = parseClock(b.Start); err != nil {
+			return nil, err
+		}
+		if w.end, err = parseClock(b.End); err != nil {
+			return nil, err
+		}
+		if w.start == w.end {
+			return nil, fmt.Errorf("%w: blackout %s-%s is empty", ErrInvalidSchedule, b.Start, b.End)
+		}
+		if b.Timezone != "" {
+			if w.loc, err = time.LoadLocation(b.Timezone); err != nil {
+				return nil, fmt.Errorf("%w: blackout timezone %q: %v", ErrInvalidSchedule, b.Timezone, err)
+			}
+		}
+		for _, d := range b.Days {
+			name := strings.ToLower(strings.TrimSpace(d))
+			if len(name) > 3 {
+				name = name[:3]
+			}
+			wd, ok := weekdays[name]
+			if !ok {
+				return nil, fmt.Errorf("%w: blackout day %q", ErrInvalidSchedule, d)
+			}
+			w.days[wd] = true
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: blackout time %q must be HH:MM", ErrInvalidSchedule, s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func inWindows(windows []window, now time.Time) bool {
+	for _, w := range windows {
+		if w.contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w window) contains(t time.Time) bool {
+	local := t.In(w.loc)
+	minute := local.Hour()*60 + local.Minute()
+
+	if w.start < w.end {
+		return minute >= w.start && minute < w.end && w.onDay(local.Weekday())
+	}
+
+	// Window wraps midnight: the part after midnight belongs to the window
+	// that started the previous day.
+	if minute >= w.start {
+		return w.onDay(local.Weekday())
+	}
+	if minute < w.end {
+		return w.onDay((local.Weekday() + 6) % 7)
+	}
+	return false
+}
+
+func (w window) onDay(d time.Weekday) bool {
+	for _, set := range w.days {
+		if set {
+			return w.days[d]
+		}
+	}
+	return true
+}
@@ -0,0 +1,156 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var testDefaults = Settings{TargetSize: 128, MinFiles: 2}
+
+func mustCompile(t *testing.T, s *Schedule) *Plan {
+	t.Helper()
+	p, err := Compile(s, testDefaults)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	return p
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		s    Schedule
+	}{
+		{"bad cron", Schedule{Cron: "every hour"}},
+		{"bad app cron", Schedule{Apps: map[string]AppOverride{"a": {Cron: "* *"}}}},
+		{"bad blackout time", Schedule{Blackouts: []Blackout{{Start: "25:00", End: "01:00"}}}},
+		{"empty blackout", Schedule{Blackouts: []Blackout{{Start: "01:00", End: "01:00"}}}},
+		{"bad blackout day", Schedule{Blackouts: []Blackout{{Start: "01:00", End: "02:00", Days: []string{"xyz"}}}}},
+		{"bad timezone", Schedule{Blackouts: []Blackout{{Start: "01:00", End: "02:00", Timezone: "Mars/Base"}}}},
+		{"default app id", Schedule{Apps: map[string]AppOverride{DefaultAppID: {}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Compile(&tt.s, testDefaults); !errors.Is(err, ErrInvalidSchedule) {
+				t.Errorf("Compile() error = %v, want ErrInvalidSchedule", err)
+			}
+		})
+	}
+}
+
+func TestPlan_NextAndDue(t *testing.T) {
+	p := mustCompile(t, &Schedule{
+		Cron: "0 * * * *",
+		Apps: map[string]AppOverride{
+			"busy": {Cron: "*/15 * * * *"},
+			"off":  {Cron: "* * * * *", Disabled: true},
+		},
+	})
+
+	from := time.Date(2026, 3, 2, 10, 5, 0, 0, time.UTC)
+	if got, want := p.Next(from), time.Date(2026, 3, 2, 10, 15, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next() = %v, want %v (disabled apps must not fire)", got, want)
+	}
+
+	due := p.DueBetween(from, time.Date(2026, 3, 2, 10, 15, 0, 0, time.UTC))
+	if due.Default || !due.Apps["busy"] || due.Apps["off"] {
+		t.Errorf("DueBetween() at :15 = %+v, want only busy", due)
+	}
+
+	due = p.DueBetween(time.Date(2026, 3, 2, 10, 45, 0, 0, time.UTC), time.Date(2026, 3, 2, 11, 0, 0, 0, time.UTC))
+	if !due.Default || !due.Apps["busy"] {
+		t.Errorf("DueBetween() at :00 = %+v, want default and busy", due)
+	}
+}
+
+func TestPlan_NextWithoutCron(t *testing.T) {
+	p := mustCompile(t, &Schedule{})
+	if next := p.Next(time.Now()); !next.IsZero() {
+		t.Errorf("Next() = %v, want zero time", next)
+	}
+}
+
+func TestPlan_Select(t *testing.T) {
+	p := mustCompile(t, &Schedule{
+		Cron:       "@hourly",
+		TargetSize: 256,
+		Apps: map[string]AppOverride{
+			"big":  {TargetSize: 1024, MinFiles: 10},
+			"own":  {Cron: "*/15 * * * *"},
+			"skip": {Disabled: true},
+		},
+	})
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		appID  string
+		due    Due
+		want   Settings
+		wantOK bool
+	}{
+		{"default app", "other", Due{Default: true}, Settings{TargetSize: 256, MinFiles: 2}, true},
+		{"override settings", "big", Due{Default: true}, Settings{TargetSize: 1024, MinFiles: 10}, true},
+		{"default not due", "other", Due{Apps: map[string]bool{"own": true}}, Settings{}, false},
+		{"own cron ignores default", "own", Due{Default: true}, Settings{}, false},
+		{"own cron due", "own", Due{Apps: map[string]bool{"own": true}}, Settings{TargetSize: 256, MinFiles: 2}, true},
+		{"disabled", "skip", Due{Default: true}, Settings{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := p.Select(tt.appID, tt.due, now)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("Select() = %+v, %v; want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPlan_SelectBlackouts(t *testing.T) {
+	p := mustCompile(t, &Schedule{
+		Cron:      "@hourly",
+		Blackouts: []Blackout{{Start: "09:00", End: "17:00", Days: []string{"mon", "tuesday"}}},
+		Apps: map[string]AppOverride{
+			"night": {Blackouts: []Blackout{{Start: "22:00", End: "02:00", Timezone: "America/New_York"}}},
+		},
+	})
+	due := Due{Default: true}
+
+	tests := []struct {
+		name   string
+		appID  string
+		now    time.Time
+		wantOK bool
+	}{
+		{"inside default window", "a", time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC), false}, // Monday
+		{"window end exclusive", "a", time.Date(2026, 3, 2, 17, 0, 0, 0, time.UTC), true},
+		{"other weekday", "a", time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC), true}, // Wednesday
+		{"app window before midnight", "night", time.Date(2026, 3, 4, 3, 30, 0, 0, time.UTC), false},
+		{"app window after midnight", "night", time.Date(2026, 3, 4, 6, 30, 0, 0, time.UTC), false},
+		{"app window outside", "night", time.Date(2026, 3, 4, 8, 0, 0, 0, time.UTC), true},
+		{"app window not applied to others", "a", time.Date(2026, 3, 4, 3, 30, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := p.Select(tt.appID, due, tt.now); ok != tt.wantOK {
+				t.Errorf("Select(%s, %v) ok = %v, want %v", tt.appID, tt.now, ok, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestWindow_WrapRespectsStartDay(t *testing.T) {
+	p := mustCompile(t, &Schedule{
+		Blackouts: []Blackout{{Start: "23:00", End: "01:00", Days: []string{"fri"}}},
+	})
+
+	// Saturday 00:30 belongs to Friday's window.
+	if !p.InBlackout(time.Date(2026, 3, 7, 0, 30, 0, 0, time.UTC)) {
+		t.Error("InBlackout(Sat 00:30) = false, want true")
+	}
+	// Friday 00:30 belongs to Thursday's window, which is not configured.
+	if p.InBlackout(time.Date(2026, 3, 6, 0, 30, 0, 0, time.UTC)) {
+		t.Error("InBlackout(Fri 00:30) = true, want false")
+	}
+}
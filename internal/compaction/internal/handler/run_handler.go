@@ -0,0 +1,88 @@
+// Package handler provides the HTTP handler for triggering compaction runs.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+// Runner runs compaction once.
+type Runner interface {
+	RunNow(ctx context.Context) error
+}
+
+// RunHandler triggers compaction runs on demand. Runs outlive the request
+// that started them, and only one manual run is in progress at a time.
+type RunHandler struct {
+	runner Runner
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	running bool
+}
+
+// NewRunHandler creates a new RunHandler.
+func NewRunHandler(runner Runner, logger *slog.Logger) *RunHandler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &RunHandler{
+		runner: runner,
+		logger: logger.With("component", "compaction-handler"),
+	}
+}
+
+// RegisterRoutes mounts the compaction endpoint on the given ServeMux.
+//
+// Endpoints:
+//   - POST /api/admin/compaction/run - Start a compaction run
+//
+// TODO(phase-3): Protect these endpoints with session auth + RBAC.
+func (h *RunHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/admin/compaction/run", h.handleRun)
+}
+
+// handleRun handles POST /api/admin/compaction/run. It responds 202 Accepted
+// once the run has started, or 409 Conflict if a manual run is in progress.
+func (h *RunHandler) handleRun(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	if h.running {
+		h.mu.Unlock()
+		writeJSON(w, http.StatusConflict, map[string]string{
+			"error": "a compaction run is already in progress",
+		})
+		return
+	}
+	h.running = true
+	h.mu.Unlock()
+
+	ctx := context.WithoutCancel(r.Context())
+	go func() {
+		defer func() {
+			h.mu.Lock()
+			h.running = false
+			h.mu.Unlock()
+		}()
+
+		h.logger.Info("manual compaction triggered")
+		if err := h.runner.RunNow(ctx); err != nil {
+			h.logger.Error("manual compaction failed", "error", err)
+			return
+		}
+		h.logger.Info("manual compaction completed")
+	}()
+
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"status": "started",
+	})
+}
+
+// writeJSON writes a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
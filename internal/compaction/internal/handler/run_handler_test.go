@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// blockingRunner runs until released.
+type blockingRunner struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingRunner) RunNow(ctx context.Context) error {
+	r.started <- struct{}{}
+	<-r.release
+	return ctx.Err()
+}
+
+// TestRunHandler verifies a run is started once and concurrent triggers are
+// rejected until it completes.
+func TestRunHandler(t *testing.T) {
+	runner := &blockingRunner{started: make(chan struct{}, 1), release: make(chan struct{})}
+	mux := http.NewServeMux()
+	NewRunHandler(runner, nil).RegisterRoutes(mux)
+
+	trigger := func() int {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/admin/compaction/run", nil))
+		return rec.Code
+	}
+
+	if code := trigger(); code != http.StatusAccepted {
+		t.Fatalf("first trigger status = %d, want %d", code, http.StatusAccepted)
+	}
+	<-runner.started
+
+	if code := trigger(); code != http.StatusConflict {
+		t.Errorf("concurrent trigger status = %d, want %d", code, http.StatusConflict)
+	}
+
+	close(runner.release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		code := trigger()
+		if code == http.StatusAccepted {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("trigger after completion status = %d, want %d", code, http.StatusAccepted)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	<-runner.started
+}
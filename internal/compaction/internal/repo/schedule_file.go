@@ -0,0 +1,41 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/SebastienMelki/causality/internal/compaction/internal/domain"
+)
+
+// ScheduleFile loads the compaction schedule from a JSON file. The file is
+// re-read on every Load so edits are picked up by the scheduler's reload loop.
+type ScheduleFile struct {
+	path string
+}
+
+// NewScheduleFile creates a new ScheduleFile reading from path.
+func NewScheduleFile(path string) *ScheduleFile {
+	return &ScheduleFile{path: path}
+}
+
+// Load reads and decodes the schedule file. Unknown fields are rejected so
+// typos do not silently fall back to defaults.
+func (f *ScheduleFile) Load(_ context.Context) (*domain.Schedule, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule file: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var schedule domain.Schedule
+	if err := dec.Decode(&schedule); err != nil {
+		return nil, fmt.Errorf("failed to decode schedule file %s: %w", f.path, err)
+	}
+
+	return &schedule, nil
+}
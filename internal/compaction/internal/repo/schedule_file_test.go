@@ -0,0 +1,53 @@
+package repo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeSchedule(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "schedule.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write schedule: %v", err)
+	}
+	return path
+}
+
+func TestScheduleFile_Load(t *testing.T) {
+	path := writeSchedule(t, `{
+		"cron": "@hourly",
+		"blackouts": [{"start": "09:00", "end": "17:00", "days": ["mon"]}],
+		"apps": {"big": {"cron": "*/15 * * * *", "target_size": 1024}}
+	}`)
+
+	schedule, err := NewScheduleFile(path).Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if schedule.Cron != "@hourly" {
+		t.Errorf("Cron = %q, want @hourly", schedule.Cron)
+	}
+	if len(schedule.Blackouts) != 1 || schedule.Blackouts[0].Start != "09:00" {
+		t.Errorf("Blackouts = %+v", schedule.Blackouts)
+	}
+	if app := schedule.Apps["big"]; app.Cron != "*/15 * * * *" || app.TargetSize != 1024 {
+		t.Errorf("Apps[big] = %+v", app)
+	}
+}
+
+func TestScheduleFile_RejectsUnknownFields(t *testing.T) {
+	path := writeSchedule(t, `{"cron": "@hourly", "target_szie": 10}`)
+
+	if _, err := NewScheduleFile(path).Load(context.Background()); err == nil {
+		t.Error("Load() with unknown field should fail")
+	}
+}
+
+func TestScheduleFile_Missing(t *testing.T) {
+	if _, err := NewScheduleFile(filepath.Join(t.TempDir(), "missing.json")).Load(context.Background()); err == nil {
+		t.Error("Load() of missing file should fail")
+	}
+}
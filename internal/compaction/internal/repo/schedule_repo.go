@@ -0,0 +1,78 @@
+// Package repo provides the compaction schedule sources: a JSON config file
+// and a PostgreSQL table.
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/SebastienMelki/causality/internal/compaction/internal/domain"
+)
+
+// ScheduleRepository loads the compaction schedule from the
+// compaction_schedules table. The row with app_id '*' holds the default
+// schedule; every other row is a per-app override.
+type ScheduleRepository struct {
+	db *sql.DB
+}
+
+// NewScheduleRepository creates a new ScheduleRepository backed by the given database.
+func NewScheduleRepository(db *sql.DB) *ScheduleRepository {
+	return &ScheduleRepository{db: db}
+}
+
+// Load reads all schedule rows.
+func (r *ScheduleRepository) Load(ctx context.Context) (*domain.Schedule, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT app_id, cron, target_size, min_files, blackouts, enabled
+		FROM compaction_schedules
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query compaction schedules: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	schedule := &domain.Schedule{Apps: make(map[string]domain.AppOverride)}
+	for rows.Next() {
+		var (
+			appID, cron   string
+			targetSize    int64
+			minFiles      int
+			blackoutsJSON []byte
+			enabled       bool
+		)
+		if err := rows.Scan(&appID, &cron, &targetSize, &minFiles, &blackoutsJSON, &enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan compaction schedule: %w", err)
+		}
+
+		var blackouts []domain.Blackout
+		if len(blackoutsJSON) > 0 {
+			if err := json.Unmarshal(blackoutsJSON, &blackouts); err != nil {
+				return nil, fmt.Errorf("failed to decode blackouts for app %s: %w", appID, err)
+			}
+		}
+
+		if appID == domain.DefaultAppID {
+			schedule.Cron = cron
+			schedule.TargetSize = targetSize
+			schedule.MinFiles = minFiles
+			schedule.Blackouts = blackouts
+			continue
+		}
+
+		schedule.Apps[appID] = domain.AppOverride{
+			Cron:       cron,
+			TargetSize: targetSize,
+			MinFiles:   minFiles,
+			Blackouts:  blackouts,
+			Disabled:   !enabled,
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate compaction schedules: %w", err)
+	}
+
+	return schedule, nil
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+
+	"github.com/SebastienMelki/causality/internal/nats"
+	"github.com/SebastienMelki/causality/internal/observability"
+)
+
+// Alert types published by the compaction service.
+const (
+	// AlertTypeSmallFiles fires when a partition accumulates more small files
+	// than the configured threshold.
+	AlertTypeSmallFiles = "compaction_small_files"
+
+	// AlertTypeBacklog fires when partitions that need compaction remain
+	// uncompacted for longer than the configured threshold.
+	AlertTypeBacklog = "compaction_backlog"
+)
+
+// AlertPublisher publishes alert payloads to NATS JetStream.
+type AlertPublisher interface {
+	Publish(ctx context.Context, subject string, data []byte, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+}
+
+// Alert is the JSON payload published for compaction alerts. Alerts are
+// published to anomalies.{app_id}.{type} so they flow through the same
+// alerting consumer as anomaly detections.
+type Alert struct {
+	Type         string    `json:"type"`
+	AppID        string    `json:"app_id"`
+	Partition    string    `json:"partition,omitempty"`
+	SmallFiles   int       `json:"small_files,omitempty"`
+	SmallBytes   int64     `json:"small_bytes,omitempty"`
+	Threshold    float64   `json:"threshold"`
+	BacklogHours float64   `json:"backlog_hours,omitempty"`
+	Pending      int       `json:"pending_partitions,omitempty"`
+	DetectedAt   time.Time `json:"detected_at"`
+}
+
+// Alerter evaluates small-file and backlog thresholds and publishes alerts.
+// A nil *Alerter is a no-op.
+type Alerter struct {
+	publisher           AlertPublisher
+	smallFilesThreshold int
+	backlogThreshold    time.Duration
+	metrics             *observability.Metrics
+	logger              *slog.Logger
+}
+
+// NewAlerter creates a compaction alerter. A zero threshold disables the
+// corresponding alert; a nil publisher records metrics and logs only.
+func NewAlerter(
+	publisher AlertPublisher,
+	smallFilesThreshold int,
+	backlogThreshold time.Duration,
+	metrics *observability.Metrics,
+	logger *slog.Logger,
+) *Alerter {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Alerter{
+		publisher:           publisher,
+		smallFilesThreshold: smallFilesThreshold,
+		backlogThreshold:    backlogThreshold,
+		metrics:             metrics,
+		logger:              logger.With("component", "compaction-alerter"),
+	}
+}
+
+// CheckPartition records the small-file count of a partition and alerts if it
+// exceeds the threshold.
+func (a *Alerter) CheckPartition(ctx context.Context, partition string, smallFiles int, smallBytes int64) {
+	if a == nil {
+		return
+	}
+
+	if a.metrics != nil {
+		a.metrics.CompactionSmallFiles.Record(ctx, int64(smallFiles))
+	}
+
+	if a.smallFilesThreshold <= 0 || smallFiles < a.smallFilesThreshold {
+		return
+	}
+
+	a.fire(ctx, Alert{
+		Type:       AlertTypeSmallFiles,
+		AppID:      extractAppID(partition),
+		Partition:  partition,
+		SmallFiles: smallFiles,
+		SmallBytes: smallBytes,
+		Threshold:  float64(a.smallFilesThreshold),
+		DetectedAt: time.Now().UTC(),
+	})
+}
+
+// CheckBacklog records the compaction backlog age and alerts for each app
+// whose oldest pending partition ended longer ago than the threshold.
+// pending maps app_id to the end time of its oldest uncompacted partition
+// and the number of pending partitions.
+func (a *Alerter) CheckBacklog(ctx context.Context, pending map[string]PendingBacklog, now time.Time) {
+	if a == nil {
+		return
+	}
+
+	var maxAge time.Duration
+	for _, p := range pending {
+		maxAge = max(maxAge, now.Sub(p.Oldest))
+	}
+	if a.metrics != nil {
+		a.metrics.CompactionBacklogAge.Record(ctx, maxAge.Hours())
+	}
+
+	if a.backlogThreshold <= 0 {
+		return
+	}
+
+	for appID, p := range pending {
+		age := now.Sub(p.Oldest)
+		if age < a.backlogThreshold {
+			continue
+		}
+		a.fire(ctx, Alert{
+			Type:         AlertTypeBacklog,
+			AppID:        appID,
+			Threshold:    a.backlogThreshold.Hours(),
+			BacklogHours: age.Hours(),
+			Pending:      p.Partitions,
+			DetectedAt:   now.UTC(),
+		})
+	}
+}
+
+// PendingBacklog describes an app's partitions left uncompacted after a run.
+type PendingBacklog struct {
+	Oldest     time.Time
+	Partitions int
+}
+
+// fire logs, counts, and publishes an alert.
+func (a *Alerter) fire(ctx context.Context, alert Alert) {
+	a.logger.Warn("compaction alert",
+		"type", alert.Type,
+		"app_id", alert.AppID,
+		"partition", alert.Partition,
+		"small_files", alert.SmallFiles,
+		"backlog_hours", alert.BacklogHours,
+	)
+
+	if a.metrics != nil {
+		a.metrics.CompactionAlerts.Add(ctx, 1, otelmetric.WithAttributes(attribute.String("type", alert.Type)))
+	}
+
+	if a.publisher == nil {
+		return
+	}
+
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		a.logger.Error("failed to marshal compaction alert", "error", err)
+		return
+	}
+
+	appID := alert.AppID
+	if appID == "" {
+		appID = "unknown"
+	}
+	subject := nats.DerivedSubject(nats.FamilyAnomalies, appID, alert.Type)
+	if _, err := a.publisher.Publish(ctx, subject, payload); err != nil {
+		a.logger.Error("failed to publish compaction alert",
+			"subject", subject,
+			"error", err,
+		)
+	}
+}
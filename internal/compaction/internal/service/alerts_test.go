@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+// mockAlertPublisher records published alerts.
+type mockAlertPublisher struct {
+	mu       sync.Mutex
+	subjects []string
+	alerts   []Alert
+}
+
+func (m *mockAlertPublisher) Publish(_ context.Context, subject string, data []byte, _ ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var a Alert
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, err
+	}
+	m.subjects = append(m.subjects, subject)
+	m.alerts = append(m.alerts, a)
+	return &jetstream.PubAck{}, nil
+}
+
+func TestAlerter_CheckPartition(t *testing.T) {
+	pub := &mockAlertPublisher{}
+	a := NewAlerter(pub, 10, 0, nil, nil)
+	partition := "events/app_id=Demo App/year=2026/month=01/day=15/hour=10/"
+
+	a.CheckPartition(context.Background(), partition, 9, 900)
+	if len(pub.alerts) != 0 {
+		t.Fatalf("alert fired below threshold: %+v", pub.alerts)
+	}
+
+	a.CheckPartition(context.Background(), partition, 10, 1000)
+	if len(pub.alerts) != 1 {
+		t.Fatalf("expected 1 alert, got %d", len(pub.alerts))
+	}
+	if pub.subjects[0] != "anomalies.demo_app.compaction_small_files" {
+		t.Errorf("subject = %q", pub.subjects[0])
+	}
+	if got := pub.alerts[0]; got.Type != AlertTypeSmallFiles || got.SmallFiles != 10 || got.Partition != partition {
+		t.Errorf("alert = %+v", got)
+	}
+}
+
+func TestAlerter_CheckBacklog(t *testing.T) {
+	pub := &mockAlertPublisher{}
+	a := NewAlerter(pub, 0, 6*time.Hour, nil, nil)
+	now := time.Date(2026, 1, 15, 20, 0, 0, 0, time.UTC)
+
+	pending := make(map[string]PendingBacklog)
+	recordPending(warehouse.DefaultPartitionScheme(), pending, "events/app_id=old/year=2026/month=01/day=15/hour=10/")
+	recordPending(warehouse.DefaultPartitionScheme(), pending, "events/app_id=old/year=2026/month=01/day=15/hour=12/")
+	recordPending(warehouse.DefaultPartitionScheme(), pending, "events/app_id=fresh/year=2026/month=01/day=15/hour=18/")
+
+	if p := pending["old"]; p.Partitions != 2 || !p.Oldest.Equal(time.Date(2026, 1, 15, 11, 0, 0, 0, time.UTC)) {
+		t.Fatalf("pending[old] = %+v", p)
+	}
+
+	a.CheckBacklog(context.Background(), pending, now)
+	if len(pub.alerts) != 1 {
+		t.Fatalf("expected 1 backlog alert, got %d", len(pub.alerts))
+	}
+	if got := pub.alerts[0]; got.AppID != "old" || got.Type != AlertTypeBacklog || got.BacklogHours != 9 || got.Pending != 2 {
+		t.Errorf("alert = %+v", got)
+	}
+}
+
+func TestAlerter_NilIsNoop(t *testing.T) {
+	var a *Alerter
+	a.CheckPartition(context.Background(), "p", 1000, 1)
+	a.CheckBacklog(context.Background(), map[string]PendingBacklog{"x": {}}, time.Now())
+}
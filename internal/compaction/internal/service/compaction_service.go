@@ -4,8 +4,9 @@
 // The compaction service is stateless and idempotent. It uses the S3 file layout
 // as its state: on each run it lists objects in cold partitions, identifies
 // groups of small files, downloads them, merges their row groups into a single
-// compacted file, uploads the result, and deletes the originals. If no small
-// files are found, the run is a no-op.
+// compacted file (optionally deduplicated by event id and sorted by configured
+// columns), uploads the result, and deletes the originals. If no small files
+// are found, the run is a no-op.
 package service
 
 import (
@@ -24,7 +25,10 @@ import (
 	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/google/uuid"
 	"github.com/parquet-go/parquet-go"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
 
+	"github.com/SebastienMelki/causality/internal/compaction/internal/domain"
 	"github.com/SebastienMelki/causality/internal/observability"
 	"github.com/SebastienMelki/causality/internal/warehouse"
 )
@@ -45,11 +49,18 @@ type s3Object struct {
 }
 
 // CompactionService merges small Parquet files into larger ones.
-// It only operates on cold partitions (older than the current hour)
+// It only operates on cold partitions (whose hour or day has passed)
 // and is safe to re-run (idempotent).
 type CompactionService struct {
-	s3Client   *s3.Client
+	s3Client   warehouse.ObjectAPI
 	s3Config   warehouse.S3Config
+	partitions *warehouse.PartitionScheme
+	parquetCfg warehouse.ParquetConfig
+	delta      *warehouse.DeltaLog
+	alerter    *Alerter
+	discovery  DiscoveryConfig
+	sort       SortConfig
+	dedup      bool
 	targetSize int64
 	minFiles   int
 	metrics    *observability.Metrics
@@ -58,8 +69,14 @@ type CompactionService struct {
 
 // NewCompactionService creates a new compaction service.
 func NewCompactionService(
-	s3Client *s3.Client,
+	s3Client warehouse.ObjectAPI,
 	s3Config warehouse.S3Config,
+	parquetCfg warehouse.ParquetConfig,
+	delta *warehouse.DeltaLog,
+	alerter *Alerter,
+	discovery DiscoveryConfig,
+	sort SortConfig,
+	dedup bool,
 	targetSize int64,
 	minFiles int,
 	metrics *observability.Metrics,
@@ -74,10 +91,20 @@ func NewCompactionService(
 	if minFiles < 2 {
 		minFiles = DefaultMinFiles
 	}
+	if sort.MemoryRows <= 0 {
+		sort.MemoryRows = DefaultSortMemoryRows
+	}
 
 	return &CompactionService{
 		s3Client:   s3Client,
 		s3Config:   s3Config,
+		partitions: s3Config.Partitioning(),
+		parquetCfg: parquetCfg,
+		delta:      delta,
+		alerter:    alerter,
+		discovery:  discovery,
+		sort:       sort,
+		dedup:      dedup,
 		targetSize: targetSize,
 		minFiles:   minFiles,
 		metrics:    metrics,
@@ -85,31 +112,60 @@ func NewCompactionService(
 	}
 }
 
+// SelectFunc decides whether the partitions of appID are compacted in the
+// current run and with which settings. Zero settings fall back to the
+// service defaults. It is called once per partition so that a blackout
+// window starting mid-run stops further work.
+type SelectFunc func(appID string) (domain.Settings, bool)
+
 // CompactAll lists all cold partitions and compacts each one.
 // It records the CompactionRuns metric on each invocation.
 func (cs *CompactionService) CompactAll(ctx context.Context) error {
+	return cs.CompactSelected(ctx, nil)
+}
+
+// CompactSelected compacts the cold partitions of apps accepted by selectFn.
+// A nil selectFn compacts every app with the default settings.
+func (cs *CompactionService) CompactSelected(ctx context.Context, selectFn SelectFunc) error {
 	start := time.Now()
 	cs.logger.Info("starting compaction run")
 
-	partitions, err := cs.listColdPartitions(ctx)
+	snapshot, err := cs.deltaSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+
+	partitions, err := cs.discoverPartitions(ctx, snapshot)
 	if err != nil {
 		return fmt.Errorf("list cold partitions: %w", err)
 	}
 
 	cs.logger.Info("found cold partitions", "count", len(partitions))
 
-	var compacted int
+	var compacted, deferred int
+	pending := make(map[string]PendingBacklog)
 	for _, partition := range partitions {
 		if err := ctx.Err(); err != nil {
 			return err
 		}
 
-		did, compactErr := cs.CompactPartition(ctx, partition)
+		settings := cs.defaultSettings()
+		if selectFn != nil {
+			override, ok := selectFn(extractAppID(partition))
+			if !ok {
+				deferred++
+				continue
+			}
+			settings = cs.resolveSettings(override)
+		}
+
+		did, compactErr := cs.compactPartition(ctx, partition, snapshot, settings)
 		if compactErr != nil {
 			cs.logger.Error("failed to compact partition",
 				"partition", partition,
 				"error", compactErr,
 			)
+			recordPending(cs.partitions, pending, partition)
 			// Continue with other partitions; don't fail the whole run.
 			continue
 		}
@@ -118,6 +174,8 @@ func (cs *CompactionService) CompactAll(ctx context.Context) error {
 		}
 	}
 
+	cs.alerter.CheckBacklog(ctx, pending, time.Now().UTC())
+
 	duration := float64(time.Since(start).Milliseconds())
 
 	if cs.metrics != nil {
@@ -128,6 +186,7 @@ func (cs *CompactionService) CompactAll(ctx context.Context) error {
 	cs.logger.Info("compaction run complete",
 		"partitions_total", len(partitions),
 		"partitions_compacted", compacted,
+		"partitions_deferred", deferred,
 		"duration_ms", duration,
 	)
 
@@ -142,26 +201,51 @@ func (cs *CompactionService) CompactAll(ctx context.Context) error {
 //   - Delete originals ONLY after successful upload
 //   - On failure, leave originals intact
 func (cs *CompactionService) CompactPartition(ctx context.Context, partition string) (bool, error) {
+	snapshot, err := cs.deltaSnapshot(ctx)
+	if err != nil {
+		return false, err
+	}
+	return cs.compactPartition(ctx, partition, snapshot, cs.defaultSettings())
+}
+
+// compactPartition compacts a partition. When snapshot is non-nil only files
+// active in the Delta table are considered, since files removed by earlier
+// OPTIMIZE commits remain in storage until vacuumed.
+func (cs *CompactionService) compactPartition(ctx context.Context, partition string, snapshot *warehouse.DeltaSnapshot, settings domain.Settings) (bool, error) {
 	// List all objects in the partition.
 	objects, err := cs.listObjects(ctx, partition)
 	if err != nil {
 		return false, fmt.Errorf("list objects in partition %s: %w", partition, err)
 	}
 
+	if snapshot != nil {
+		active := objects[:0]
+		for _, obj := range objects {
+			if snapshot.Contains(obj.Key) {
+				active = append(active, obj)
+			}
+		}
+		objects = active
+	}
+
 	// Identify small files (smaller than target size).
 	var smallFiles []s3Object
+	var smallBytes int64
 	for _, obj := range objects {
-		if obj.Size < cs.targetSize {
+		if obj.Size < settings.TargetSize {
 			smallFiles = append(smallFiles, obj)
+			smallBytes += obj.Size
 		}
 	}
 
+	cs.alerter.CheckPartition(ctx, partition, len(smallFiles), smallBytes)
+
 	// Need at least minFiles small files to justify compaction.
-	if len(smallFiles) < cs.minFiles {
+	if len(smallFiles) < settings.MinFiles {
 		cs.logger.Debug("skipping partition, not enough small files",
 			"partition", partition,
 			"small_files", len(smallFiles),
-			"min_required", cs.minFiles,
+			"min_required", settings.MinFiles,
 		)
 		if cs.metrics != nil {
 			cs.metrics.CompactionPartitionsSkipped.Add(ctx, 1)
@@ -175,14 +259,14 @@ func (cs *CompactionService) CompactPartition(ctx context.Context, partition str
 	)
 
 	// Group small files into batches that will produce files close to targetSize.
-	batches := cs.groupIntoBatches(smallFiles)
+	batches := batchFiles(smallFiles, settings)
 
 	for batchIdx, batch := range batches {
 		if err := ctx.Err(); err != nil {
 			return false, err
 		}
 
-		if err := cs.mergeBatch(ctx, partition, batch, batchIdx); err != nil {
+		if err := cs.mergeBatch(ctx, partition, batch, batchIdx, snapshot); err != nil {
 			return false, fmt.Errorf("merge batch %d in partition %s: %w", batchIdx, partition, err)
 		}
 	}
@@ -190,14 +274,36 @@ func (cs *CompactionService) CompactPartition(ctx context.Context, partition str
 	return true, nil
 }
 
+// defaultSettings returns the service-wide target size and minimum file count.
+func (cs *CompactionService) defaultSettings() domain.Settings {
+	return domain.Settings{TargetSize: cs.targetSize, MinFiles: cs.minFiles}
+}
+
+// resolveSettings fills unset or out-of-range fields of s from the defaults.
+func (cs *CompactionService) resolveSettings(s domain.Settings) domain.Settings {
+	if s.TargetSize <= 0 {
+		s.TargetSize = cs.targetSize
+	}
+	if s.MinFiles < 2 {
+		s.MinFiles = cs.minFiles
+	}
+	return s
+}
+
 // groupIntoBatches groups small files into batches whose total size approaches targetSize.
 func (cs *CompactionService) groupIntoBatches(files []s3Object) [][]s3Object {
+	return batchFiles(files, cs.defaultSettings())
+}
+
+// batchFiles groups small files into batches whose total size
+// approaches settings.TargetSize, each holding at least settings.MinFiles files.
+func batchFiles(files []s3Object, settings domain.Settings) [][]s3Object {
 	var batches [][]s3Object
 	var currentBatch []s3Object
 	var currentSize int64
 
 	for _, f := range files {
-		if currentSize+f.Size > cs.targetSize && len(currentBatch) >= cs.minFiles {
+		if currentSize+f.Size > settings.TargetSize && len(currentBatch) >= settings.MinFiles {
 			batches = append(batches, currentBatch)
 			currentBatch = nil
 			currentSize = 0
@@ -207,16 +313,30 @@ func (cs *CompactionService) groupIntoBatches(files []s3Object) [][]s3Object {
 	}
 
 	// Add remaining files if we have enough for a batch.
-	if len(currentBatch) >= cs.minFiles {
+	if len(currentBatch) >= settings.MinFiles {
 		batches = append(batches, currentBatch)
 	}
 
 	return batches
 }
 
+// deltaSnapshot returns the current Delta table snapshot, or nil when Delta
+// output is disabled.
+func (cs *CompactionService) deltaSnapshot(ctx context.Context) (*warehouse.DeltaSnapshot, error) {
+	if cs.delta == nil {
+		return nil, nil
+	}
+	snapshot, err := cs.delta.Snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load delta snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
 // mergeBatch downloads a batch of small Parquet files, merges their row groups,
-// uploads the compacted file, and deletes the originals.
-func (cs *CompactionService) mergeBatch(ctx context.Context, partition string, batch []s3Object, batchIdx int) error {
+// uploads the compacted file, and deletes the originals (or, with Delta
+// enabled, commits an OPTIMIZE that removes them from the table).
+func (cs *CompactionService) mergeBatch(ctx context.Context, partition string, batch []s3Object, batchIdx int, snapshot *warehouse.DeltaSnapshot) error {
 	cs.logger.Debug("merging batch",
 		"partition", partition,
 		"batch", batchIdx,
@@ -226,6 +346,7 @@ func (cs *CompactionService) mergeBatch(ctx context.Context, partition string, b
 	// Step 1: Download all small files and collect their row groups.
 	var allRowGroups []parquet.RowGroup
 	var downloadedFiles []*parquet.File
+	var sources []warehouse.FileIntegrity
 
 	for _, obj := range batch {
 		data, err := cs.downloadObject(ctx, obj.Key)
@@ -243,7 +364,16 @@ func (cs *CompactionService) mergeBatch(ctx context.Context, partition string, b
 			continue
 		}
 
+		source, err := warehouse.ReadFileIntegrity(obj.Key, pf)
+		if err != nil {
+			cs.logger.Warn("ignoring invalid stream sequence metadata",
+				"key", obj.Key,
+				"error", err,
+			)
+		}
+
 		downloadedFiles = append(downloadedFiles, pf)
+		sources = append(sources, source)
 		allRowGroups = append(allRowGroups, pf.RowGroups()...)
 	}
 
@@ -255,54 +385,40 @@ func (cs *CompactionService) mergeBatch(ctx context.Context, partition string, b
 		return nil
 	}
 
-	// Step 2: Merge row groups.
-	merged, err := parquet.MergeRowGroups(allRowGroups)
+	// Step 2: Merge row groups, converting files written before a column was
+	// added (such as country and region) to the current EventRow schema,
+	// extended with the promoted property columns of any of the files.
+	schemas := make([]*parquet.Schema, len(downloadedFiles))
+	for i, pf := range downloadedFiles {
+		schemas[i] = pf.Schema()
+	}
+	schema := warehouse.EventRowSchema(warehouse.PromotedColumnsOf(schemas...))
+	merged, err := parquet.MergeRowGroups(allRowGroups, schema)
 	if err != nil {
 		return fmt.Errorf("merge row groups: %w", err)
 	}
 
-	// Step 3: Write merged data to a new Parquet file using the EventRow schema.
-	var buf bytes.Buffer
-
-	schema := parquet.SchemaOf(warehouse.EventRow{})
-	writer := parquet.NewWriter(&buf,
-		schema,
-		parquet.Compression(&parquet.Snappy),
-		parquet.CreatedBy("causality-compaction", "1.0.0", ""),
-	)
-
-	// Copy merged rows into the writer.
-	rowReader := parquet.NewRowGroupReader(merged)
-	rowBuf := make([]parquet.Row, 1000)
-	for {
-		n, readErr := rowReader.ReadRows(rowBuf)
-		if n > 0 {
-			if _, writeErr := writer.WriteRows(rowBuf[:n]); writeErr != nil {
-				return fmt.Errorf("write merged rows: %w", writeErr)
-			}
-		}
-		if readErr != nil {
-			if readErr == io.EOF {
-				break
-			}
-			return fmt.Errorf("read merged rows: %w", readErr)
-		}
-	}
-
-	if err := writer.Close(); err != nil {
-		return fmt.Errorf("close compacted writer: %w", err)
+	// Step 3: Write merged data to a new Parquet file using the EventRow
+	// schema, deduplicated by event id and sorted by the configured sort
+	// keys, carrying over the stream sequences of the sources.
+	compacted, err := cs.writeCompacted(merged, schema, sources)
+	if err != nil {
+		return err
 	}
+	compactedData := compacted.data
 
 	// Step 4: Upload the compacted file.
 	compactedKey := cs.generateCompactedKey(partition)
-	compactedData := buf.Bytes()
 
-	if _, err := cs.s3Client.PutObject(ctx, &s3.PutObjectInput{
+	input := &s3.PutObjectInput{
 		Bucket:      aws.String(cs.s3Config.Bucket),
 		Key:         aws.String(compactedKey),
 		Body:        bytes.NewReader(compactedData),
 		ContentType: aws.String("application/x-parquet"),
-	}); err != nil {
+	}
+	cs.s3Config.ApplyObjectOptions(input, extractAppID(partition))
+
+	if _, err := cs.s3Client.PutObject(ctx, input); err != nil {
 		return fmt.Errorf("upload compacted file %s: %w", compactedKey, err)
 	}
 
@@ -310,8 +426,19 @@ func (cs *CompactionService) mergeBatch(ctx context.Context, partition string, b
 		"key", compactedKey,
 		"size_bytes", len(compactedData),
 		"source_files", len(batch),
+		"rows", compacted.rows,
+		"duplicate_rows", compacted.duplicates,
 	)
 
+	// Step 5 (Delta): commit the rewrite instead of deleting originals.
+	if snapshot != nil {
+		if err := cs.commitOptimize(ctx, snapshot.Version, compactedKey, int64(len(compactedData)), compacted.rows, batch); err != nil {
+			return err
+		}
+		cs.recordCompacted(ctx, partition, len(batch), compacted)
+		return nil
+	}
+
 	// Step 5: Delete originals ONLY after successful upload.
 	if err := cs.deleteObjects(ctx, batch); err != nil {
 		// Log but don't fail: the compacted file exists, so next run
@@ -325,89 +452,257 @@ func (cs *CompactionService) mergeBatch(ctx context.Context, partition string, b
 		)
 	}
 
-	if cs.metrics != nil {
-		cs.metrics.CompactionFilesCompacted.Add(ctx, int64(len(batch)))
+	cs.recordCompacted(ctx, partition, len(batch), compacted)
+
+	return nil
+}
+
+// recordCompacted records the metrics of a committed compacted file.
+func (cs *CompactionService) recordCompacted(ctx context.Context, partition string, sourceFiles int, compacted *compactedFile) {
+	if cs.metrics == nil {
+		return
+	}
+	cs.metrics.CompactionFilesCompacted.Add(ctx, int64(sourceFiles))
+
+	appID := otelmetric.WithAttributes(attribute.String("app_id", extractAppID(partition)))
+	cs.metrics.CompactionRowsMerged.Add(ctx, compacted.rows+compacted.duplicates, appID)
+	if compacted.duplicates > 0 {
+		cs.metrics.CompactionRowsDuplicate.Add(ctx, compacted.duplicates, appID)
+	}
+}
+
+// compactedFile is a written compacted file.
+type compactedFile struct {
+	data []byte
+
+	// rows is the number of rows written.
+	rows int64
+
+	// duplicates is the number of rows dropped by deduplication.
+	duplicates int64
+}
+
+// writeCompacted writes the rows of merged to a new Parquet file with
+// schema. With deduplication enabled, rows whose event id was already
+// written are dropped. The stream sequences of sources are recorded in the
+// footer when every source has them.
+func (cs *CompactionService) writeCompacted(merged parquet.RowGroup, schema *parquet.Schema, sources []warehouse.FileIntegrity) (*compactedFile, error) {
+	var dedup *rowDeduper
+	if cs.dedup {
+		var err error
+		if dedup, err = newRowDeduper(schema); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	file := &compactedFile{}
+
+	options := []parquet.WriterOption{
+		schema,
+		parquet.Compression(&parquet.Snappy),
+		parquet.CreatedBy("causality-compaction", "1.0.0", ""),
+	}
+	options = append(options, cs.parquetCfg.StatisticsOptions()...)
+	writer := cs.sort.newCompactedWriter(&buf, options)
+
+	// Copy merged rows into the writer.
+	rowReader := parquet.NewRowGroupReader(merged)
+	rowBuf := make([]parquet.Row, 1000)
+	for {
+		n, readErr := rowReader.ReadRows(rowBuf)
+		if n > 0 {
+			rows := rowBuf[:n]
+			if dedup != nil {
+				rows = dedup.filter(rows)
+				file.duplicates += int64(n - len(rows))
+			}
+			if _, writeErr := writer.WriteRows(rows); writeErr != nil {
+				return nil, fmt.Errorf("write merged rows: %w", writeErr)
+			}
+			file.rows += int64(len(rows))
+		}
+		if readErr != nil {
+			if readErr == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("read merged rows: %w", readErr)
+		}
+	}
+
+	if stream, ranges, deduplicated, ok := sourceSequences(sources); ok {
+		writer.SetKeyValueMetadata(warehouse.MetadataStream, stream)
+		writer.SetKeyValueMetadata(warehouse.MetadataStreamSequences, ranges.String())
+		if deduplicated += file.duplicates; deduplicated > 0 {
+			writer.SetKeyValueMetadata(warehouse.MetadataDeduplicatedRows, strconv.FormatInt(deduplicated, 10))
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("close compacted writer: %w", err)
+	}
+
+	file.data = buf.Bytes()
+	return file, nil
+}
+
+// sourceSequences returns the union of the stream sequences of sources and
+// the rows they dropped as duplicates. It reports false if any source lacks
+// sequences or they come from different streams, since the compacted file
+// could then not be verified.
+func sourceSequences(sources []warehouse.FileIntegrity) (string, warehouse.SequenceRanges, int64, bool) {
+	if len(sources) == 0 {
+		return "", nil, 0, false
+	}
+
+	sets := make([]warehouse.SequenceRanges, len(sources))
+	var deduplicated int64
+	for i, source := range sources {
+		if !source.HasSequences || source.Stream != sources[0].Stream {
+			return "", nil, 0, false
+		}
+		sets[i] = source.Sequences
+		deduplicated += source.Deduplicated
+	}
+	return sources[0].Stream, warehouse.UnionSequenceRanges(sets...), deduplicated, true
+}
+
+// commitOptimize records a compaction in the Delta log. If the commit fails
+// the compacted file is deleted so it is not picked up by path-based readers.
+func (cs *CompactionService) commitOptimize(ctx context.Context, readVersion int64, compactedKey string, size, numRows int64, batch []s3Object) error {
+	removed := make([]string, len(batch))
+	for i, obj := range batch {
+		removed[i] = obj.Key
+	}
+
+	compacted := warehouse.DeltaFile{Key: compactedKey, Size: size, NumRecords: numRows}
+	if err := cs.delta.Optimize(ctx, readVersion, compacted, removed); err != nil {
+		if delErr := cs.deleteObjects(ctx, []s3Object{{Key: compactedKey}}); delErr != nil {
+			cs.logger.Error("failed to delete uncommitted compacted file",
+				"key", compactedKey,
+				"error", delErr,
+			)
+		}
+		return fmt.Errorf("commit delta optimize: %w", err)
 	}
 
 	return nil
 }
 
-// listColdPartitions returns S3 prefixes for partitions that are older than
-// the current hour. It walks the Hive-style partition tree:
-// {prefix}/app_id=X/year=Y/month=M/day=D/hour=H/
+// listColdPartitions returns S3 prefixes for partitions whose hour or day
+// has passed. It walks the Hive-style partition tree laid out by the
+// partition template, by default {prefix}/app_id=X/year=Y/month=M/day=D/hour=H/
 func (cs *CompactionService) listColdPartitions(ctx context.Context) ([]string, error) {
-	now := time.Now().UTC()
+	partitionSet := make(map[string]struct{})
+	if err := cs.listColdPartitionsUnder(ctx, cs.s3Config.Prefix+"/", time.Now().UTC(), partitionSet); err != nil {
+		return nil, err
+	}
+	return setToSlice(partitionSet), nil
+}
 
-	// List all objects with the configured prefix and find unique partition prefixes.
+// listColdPartitionsUnder LISTs all objects under prefix and adds the cold
+// partitions they belong to to partitionSet.
+func (cs *CompactionService) listColdPartitionsUnder(ctx context.Context, prefix string, now time.Time, partitionSet map[string]struct{}) error {
 	paginator := s3.NewListObjectsV2Paginator(cs.s3Client, &s3.ListObjectsV2Input{
 		Bucket: aws.String(cs.s3Config.Bucket),
-		Prefix: aws.String(cs.s3Config.Prefix + "/"),
+		Prefix: aws.String(prefix),
 	})
 
-	partitionSet := make(map[string]struct{})
-
 	for paginator.HasMorePages() {
 		page, err := paginator.NextPage(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("list objects: %w", err)
+			return fmt.Errorf("list objects: %w", err)
 		}
 
 		for _, obj := range page.Contents {
 			if obj.Key == nil {
 				continue
 			}
-			partition := extractPartitionPrefix(*obj.Key)
-			if partition == "" {
-				continue
-			}
-
-			// Check if this partition is cold (older than current hour).
-			if isColdPartition(partition, now) {
-				partitionSet[partition] = struct{}{}
-			}
+			addColdPartition(cs.partitions, partitionSet, *obj.Key, now)
 		}
 	}
 
-	partitions := make([]string, 0, len(partitionSet))
-	for p := range partitionSet {
-		partitions = append(partitions, p)
-	}
+	return nil
+}
 
-	return partitions, nil
+// addColdPartition adds the partition of key to partitionSet if the key is
+// in a cold partition.
+func addColdPartition(scheme *warehouse.PartitionScheme, partitionSet map[string]struct{}, key string, now time.Time) {
+	partition := extractPartitionPrefix(scheme, key)
+	if partition == "" {
+		return
+	}
+	if isColdPartition(scheme, partition, now) {
+		partitionSet[partition] = struct{}{}
+	}
 }
 
-// partitionRegex matches Hive-style partition paths and extracts date components.
-var partitionRegex = regexp.MustCompile(
-	`(.*?/app_id=[^/]+/year=(\d{4})/month=(\d{2})/day=(\d{2})/hour=(\d{2})/)`,
-)
+// setToSlice returns the members of set in no particular order.
+func setToSlice(set map[string]struct{}) []string {
+	out := make([]string, 0, len(set))
+	for v := range set {
+		out = append(out, v)
+	}
+	return out
+}
 
 // extractPartitionPrefix extracts the partition prefix from an S3 key.
 // For example, "events/app_id=demo/year=2026/month=01/day=15/hour=10/events_uuid.parquet"
-// returns "events/app_id=demo/year=2026/month=01/day=15/hour=10/".
-func extractPartitionPrefix(key string) string {
-	matches := partitionRegex.FindStringSubmatch(key)
+// returns "events/app_id=demo/year=2026/month=01/day=15/hour=10/" with the
+// default partition template.
+func extractPartitionPrefix(scheme *warehouse.PartitionScheme, key string) string {
+	return scheme.PartitionPrefix(key)
+}
+
+// appIDRegex extracts the app_id value from a partition path.
+var appIDRegex = regexp.MustCompile(`app_id=([^/]+)/`)
+
+// extractAppID extracts the app_id from a partition prefix.
+// For example, "events/app_id=demo/year=2026/month=01/day=15/hour=10/" returns "demo".
+func extractAppID(partition string) string {
+	matches := appIDRegex.FindStringSubmatch(partition)
 	if len(matches) < 2 {
 		return ""
 	}
 	return matches[1]
 }
 
-// isColdPartition checks whether a partition is older than the current hour.
-func isColdPartition(partition string, now time.Time) bool {
-	matches := partitionRegex.FindStringSubmatch(partition)
-	if len(matches) < 6 {
-		return false
+// recordPending adds a partition that remains uncompacted to the per-app
+// backlog, tracking the end time of the oldest such partition.
+func recordPending(scheme *warehouse.PartitionScheme, pending map[string]PendingBacklog, partition string) {
+	_, end, ok := partitionWindow(scheme, partition)
+	if !ok {
+		return
 	}
 
-	year, _ := strconv.Atoi(matches[2])
-	month, _ := strconv.Atoi(matches[3])
-	day, _ := strconv.Atoi(matches[4])
-	hour, _ := strconv.Atoi(matches[5])
+	appID := extractAppID(partition)
+	p, exists := pending[appID]
+	if !exists || end.Before(p.Oldest) {
+		p.Oldest = end
+	}
+	p.Partitions++
+	pending[appID] = p
+}
 
-	partitionTime := time.Date(year, time.Month(month), day, hour, 0, 0, 0, time.UTC)
-	currentHour := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), 0, 0, 0, time.UTC)
+// partitionWindow returns the hour or day a partition covers.
+func partitionWindow(scheme *warehouse.PartitionScheme, partition string) (start, end time.Time, ok bool) {
+	p, ok := scheme.Parse(partition)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	start, end = scheme.Window(p)
+	return start, end, true
+}
+
+// isColdPartition checks whether the hour or day a partition covers has
+// passed, so no more events are written to it.
+func isColdPartition(scheme *warehouse.PartitionScheme, partition string, now time.Time) bool {
+	_, end, ok := partitionWindow(scheme, partition)
+	if !ok {
+		return false
+	}
 
-	return partitionTime.Before(currentHour)
+	return !end.After(now)
 }
 
 // listObjects lists all objects within a given partition prefix.
@@ -44,7 +44,7 @@ func TestExtractPartitionPrefix(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result := extractPartitionPrefix(tc.key)
+			result := extractPartitionPrefix(warehouse.DefaultPartitionScheme(), tc.key)
 			if result != tc.expected {
 				t.Errorf("extractPartitionPrefix(%q) = %q, want %q", tc.key, result, tc.expected)
 			}
@@ -52,6 +52,24 @@ func TestExtractPartitionPrefix(t *testing.T) {
 	}
 }
 
+// TestExtractAppID verifies app_id extraction from partition prefixes.
+func TestExtractAppID(t *testing.T) {
+	tests := []struct {
+		partition string
+		expected  string
+	}{
+		{"events/app_id=demo/year=2026/month=01/day=15/hour=10/", "demo"},
+		{"data/nested/app_id=my-app/year=2024/month=12/day=31/hour=23/", "my-app"},
+		{"events/random/", ""},
+	}
+
+	for _, tc := range tests {
+		if got := extractAppID(tc.partition); got != tc.expected {
+			t.Errorf("extractAppID(%q) = %q, want %q", tc.partition, got, tc.expected)
+		}
+	}
+}
+
 // TestIsColdPartition verifies cold partition detection.
 func TestIsColdPartition(t *testing.T) {
 	// Fixed time: 2026-01-15 10:30:00 UTC
@@ -96,7 +114,7 @@ func TestIsColdPartition(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result := isColdPartition(tc.partition, now)
+			result := isColdPartition(warehouse.DefaultPartitionScheme(), tc.partition, now)
 			if result != tc.isCold {
 				t.Errorf("isColdPartition(%q, %v) = %v, want %v", tc.partition, now, result, tc.isCold)
 			}
@@ -192,6 +210,12 @@ func TestNewCompactionService_Defaults(t *testing.T) {
 	cs := NewCompactionService(
 		nil, // s3Client
 		warehouse.S3Config{Bucket: "test-bucket", Prefix: "events"},
+		warehouse.ParquetConfig{},
+		nil, // deltaLog
+		nil, // alerter
+		DiscoveryConfig{},
+		SortConfig{},
+		false, // dedup
 		0,   // targetSize 0 should use default
 		0,   // minFiles 0 should use default
 		nil, // metrics
@@ -215,6 +239,12 @@ func TestNewCompactionService_CustomValues(t *testing.T) {
 	cs := NewCompactionService(
 		nil,
 		warehouse.S3Config{Bucket: "test-bucket", Prefix: "events"},
+		warehouse.ParquetConfig{},
+		nil,
+		nil,
+		DiscoveryConfig{},
+		SortConfig{},
+		false, // dedup
 		customTargetSize,
 		customMinFiles,
 		nil,
@@ -268,7 +298,7 @@ func TestS3Object(t *testing.T) {
 	}
 }
 
-// TestPartitionRegex verifies the partition regex matches correctly.
+// TestPartitionRegex verifies the default partition scheme matches correctly.
 func TestPartitionRegex(t *testing.T) {
 	tests := []struct {
 		key         string
@@ -283,9 +313,9 @@ func TestPartitionRegex(t *testing.T) {
 	}
 
 	for _, tc := range tests {
-		matched := partitionRegex.MatchString(tc.key)
+		matched := extractPartitionPrefix(warehouse.DefaultPartitionScheme(), tc.key) != ""
 		if matched != tc.shouldMatch {
-			t.Errorf("partitionRegex.MatchString(%q) = %v, want %v", tc.key, matched, tc.shouldMatch)
+			t.Errorf("match(%q) = %v, want %v", tc.key, matched, tc.shouldMatch)
 		}
 	}
 }
@@ -457,7 +487,7 @@ func TestDeleteObjects_Batching(t *testing.T) {
 
 // TestNewCompactionService_NilLogger verifies default logger is used.
 func TestNewCompactionService_NilLogger(t *testing.T) {
-	cs := NewCompactionService(nil, warehouse.S3Config{}, 0, 0, nil, nil)
+	cs := NewCompactionService(nil, warehouse.S3Config{}, warehouse.ParquetConfig{}, nil, nil, DiscoveryConfig{}, SortConfig{}, false, 0, 0, nil, nil)
 
 	if cs.logger == nil {
 		t.Error("Logger should not be nil after NewCompactionService")
@@ -466,7 +496,7 @@ func TestNewCompactionService_NilLogger(t *testing.T) {
 
 // TestNewCompactionService_NilMetrics verifies service works without metrics.
 func TestNewCompactionService_NilMetrics(t *testing.T) {
-	cs := NewCompactionService(nil, warehouse.S3Config{}, 0, 0, nil, nil)
+	cs := NewCompactionService(nil, warehouse.S3Config{}, warehouse.ParquetConfig{}, nil, nil, DiscoveryConfig{}, SortConfig{}, false, 0, 0, nil, nil)
 
 	if cs.metrics != nil {
 		t.Error("Metrics should be nil when not provided")
@@ -476,14 +506,14 @@ func TestNewCompactionService_NilMetrics(t *testing.T) {
 // TestNewCompactionService_MinFilesEnforcement verifies minFiles minimum is 2.
 func TestNewCompactionService_MinFilesEnforcement(t *testing.T) {
 	// minFiles < 2 should be set to DefaultMinFiles (2)
-	cs := NewCompactionService(nil, warehouse.S3Config{}, 0, 1, nil, nil)
+	cs := NewCompactionService(nil, warehouse.S3Config{}, warehouse.ParquetConfig{}, nil, nil, DiscoveryConfig{}, SortConfig{}, false, 0, 1, nil, nil)
 
 	if cs.minFiles != DefaultMinFiles {
 		t.Errorf("minFiles = %d, want %d (minimum enforced)", cs.minFiles, DefaultMinFiles)
 	}
 
 	// minFiles = 0 should also use default
-	cs2 := NewCompactionService(nil, warehouse.S3Config{}, 0, 0, nil, nil)
+	cs2 := NewCompactionService(nil, warehouse.S3Config{}, warehouse.ParquetConfig{}, nil, nil, DiscoveryConfig{}, SortConfig{}, false, 0, 0, nil, nil)
 	if cs2.minFiles != DefaultMinFiles {
 		t.Errorf("minFiles = %d, want %d for zero value", cs2.minFiles, DefaultMinFiles)
 	}
@@ -570,7 +600,7 @@ func TestIsColdPartition_EdgeCases(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result := isColdPartition(tc.partition, tc.now)
+			result := isColdPartition(warehouse.DefaultPartitionScheme(), tc.partition, tc.now)
 			if result != tc.expected {
 				t.Errorf("isColdPartition(%q, %v) = %v, want %v",
 					tc.partition, tc.now, result, tc.expected)
@@ -605,7 +635,7 @@ func TestExtractPartitionPrefix_VariousFormats(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result := extractPartitionPrefix(tc.key)
+			result := extractPartitionPrefix(warehouse.DefaultPartitionScheme(), tc.key)
 			if result != tc.expected {
 				t.Errorf("extractPartitionPrefix(%q) = %q, want %q", tc.key, result, tc.expected)
 			}
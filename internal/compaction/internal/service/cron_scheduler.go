@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/compaction/internal/domain"
+)
+
+// ScheduleSource loads the current compaction schedule.
+type ScheduleSource interface {
+	Load(ctx context.Context) (*domain.Schedule, error)
+}
+
+// StaticSchedule is a ScheduleSource that always returns the same schedule.
+type StaticSchedule domain.Schedule
+
+// Load returns a copy of the static schedule.
+func (s StaticSchedule) Load(_ context.Context) (*domain.Schedule, error) {
+	schedule := domain.Schedule(s)
+	return &schedule, nil
+}
+
+// CronScheduler runs compaction on cron expressions with per-app overrides
+// and blackout windows. The schedule is re-loaded from its source on every
+// reload interval; an invalid schedule is logged and the previous one kept.
+type CronScheduler struct {
+	svc      *CompactionService
+	source   ScheduleSource
+	defaults domain.Settings
+	reload   time.Duration
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	schedule *domain.Schedule
+	plan     *domain.Plan
+	stopCh   chan struct{}
+	running  bool
+}
+
+// NewCronScheduler creates a new cron-driven compaction scheduler. defaults
+// are the settings used when the schedule leaves them unset.
+func NewCronScheduler(
+	svc *CompactionService,
+	source ScheduleSource,
+	defaults domain.Settings,
+	reload time.Duration,
+	logger *slog.Logger,
+) *CronScheduler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if reload <= 0 {
+		reload = 30 * time.Second
+	}
+
+	return &CronScheduler{
+		svc:      svc,
+		source:   source,
+		defaults: defaults,
+		reload:   reload,
+		logger:   logger.With("component", "compaction-cron-scheduler"),
+	}
+}
+
+// Start loads the schedule and begins the scheduling loop in a background
+// goroutine. It fails if the initial schedule cannot be loaded or is invalid.
+func (s *CronScheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		s.logger.Warn("scheduler already running")
+		return nil
+	}
+
+	schedule, plan, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
+	s.schedule, s.plan = schedule, plan
+
+	s.stopCh = make(chan struct{})
+	s.running = true
+
+	go s.run(ctx, s.stopCh)
+
+	s.logger.Info("compaction cron scheduler started",
+		"cron", plan.Spec(),
+		"app_overrides", plan.Overrides(),
+		"reload_interval", s.reload,
+	)
+	return nil
+}
+
+// Stop signals the scheduler to stop.
+func (s *CronScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+
+	close(s.stopCh)
+	s.running = false
+	s.logger.Info("compaction cron scheduler stopped")
+}
+
+// RunNow triggers an immediate compaction run of every app with its
+// configured settings, ignoring cron expressions but honoring blackouts and
+// disabled apps.
+func (s *CronScheduler) RunNow(ctx context.Context) error {
+	plan := s.currentPlan()
+	if plan == nil {
+		return s.svc.CompactAll(ctx)
+	}
+
+	due := domain.Due{All: true}
+	return s.svc.CompactSelected(ctx, func(appID string) (domain.Settings, bool) {
+		return plan.Select(appID, due, time.Now())
+	})
+}
+
+// run is the main scheduler loop. It sleeps until the next cron fire time
+// across all schedules, waking on each reload tick to pick up changes.
+func (s *CronScheduler) run(ctx context.Context, stopCh <-chan struct{}) {
+	reloadTicker := time.NewTicker(s.reload)
+	defer reloadTicker.Stop()
+
+	// Timers created under Go 1.23+ semantics never deliver stale values
+	// after Stop or Reset, so one timer is reused across iterations.
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+
+	last := time.Now()
+	for {
+		plan := s.currentPlan()
+		if next := plan.Next(last); next.IsZero() {
+			timer.Stop()
+		} else {
+			timer.Reset(time.Until(next))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-stopCh:
+			return
+		case <-reloadTicker.C:
+			s.reloadPlan(ctx)
+		case now := <-timer.C:
+			s.tick(ctx, plan, plan.DueBetween(last, now), now)
+			last = now
+		}
+	}
+}
+
+// tick runs compaction for the schedules that fired at now.
+func (s *CronScheduler) tick(ctx context.Context, plan *domain.Plan, due domain.Due, now time.Time) {
+	if !due.Any() {
+		return
+	}
+	if plan.InBlackout(now) {
+		s.logger.Info("scheduled compaction skipped, inside blackout window")
+		return
+	}
+
+	s.logger.Info("scheduled compaction triggered",
+		"default", due.Default,
+		"apps", len(due.Apps),
+	)
+	err := s.svc.CompactSelected(ctx, func(appID string) (domain.Settings, bool) {
+		return plan.Select(appID, due, time.Now())
+	})
+	if err != nil {
+		s.logger.Error("scheduled compaction failed", "error", err)
+	}
+}
+
+// reloadPlan re-reads the schedule and swaps in the new plan when it changed.
+func (s *CronScheduler) reloadPlan(ctx context.Context) {
+	schedule, plan, err := s.load(ctx)
+	if err != nil {
+		s.logger.Error("failed to reload compaction schedule, keeping previous", "error", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if reflect.DeepEqual(schedule, s.schedule) {
+		return
+	}
+	s.schedule, s.plan = schedule, plan
+
+	s.logger.Info("compaction schedule reloaded",
+		"cron", plan.Spec(),
+		"app_overrides", plan.Overrides(),
+	)
+}
+
+// load fetches and compiles the schedule.
+func (s *CronScheduler) load(ctx context.Context) (*domain.Schedule, *domain.Plan, error) {
+	schedule, err := s.source.Load(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load compaction schedule: %w", err)
+	}
+	plan, err := domain.Compile(schedule, s.defaults)
+	if err != nil {
+		return nil, nil, err
+	}
+	return schedule, plan, nil
+}
+
+// currentPlan returns the active plan.
+func (s *CronScheduler) currentPlan() *domain.Plan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.plan
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/SebastienMelki/causality/internal/compaction/internal/domain"
+)
+
+// mutableSource is a ScheduleSource whose schedule can be swapped by tests.
+type mutableSource struct {
+	schedule domain.Schedule
+	err      error
+}
+
+func (m *mutableSource) Load(_ context.Context) (*domain.Schedule, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	s := m.schedule
+	return &s, nil
+}
+
+func TestCronScheduler_StartRejectsInvalidSchedule(t *testing.T) {
+	source := &mutableSource{schedule: domain.Schedule{Cron: "not a cron"}}
+	s := NewCronScheduler(nil, source, domain.Settings{}, 0, nil)
+
+	if err := s.Start(context.Background()); !errors.Is(err, domain.ErrInvalidSchedule) {
+		t.Fatalf("Start() error = %v, want ErrInvalidSchedule", err)
+	}
+	if s.running {
+		t.Error("scheduler should not be running after a failed start")
+	}
+}
+
+func TestCronScheduler_ReloadPlan(t *testing.T) {
+	ctx := context.Background()
+	source := &mutableSource{schedule: domain.Schedule{Cron: "@hourly"}}
+	s := NewCronScheduler(nil, source, domain.Settings{}, 0, nil)
+
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer s.Stop()
+
+	source.schedule = domain.Schedule{Cron: "*/5 * * * *"}
+	s.reloadPlan(ctx)
+	if got := s.currentPlan().Spec(); got != "*/5 * * * *" {
+		t.Errorf("after reload Spec() = %q, want */5 * * * *", got)
+	}
+
+	// Invalid schedules and load errors keep the previous plan.
+	source.schedule = domain.Schedule{Cron: "bogus"}
+	s.reloadPlan(ctx)
+	source.err = errors.New("database unavailable")
+	s.reloadPlan(ctx)
+	if got := s.currentPlan().Spec(); got != "*/5 * * * *" {
+		t.Errorf("after failed reloads Spec() = %q, want previous */5 * * * *", got)
+	}
+}
+
+func TestCompactionService_ResolveSettings(t *testing.T) {
+	cs := &CompactionService{targetSize: 100, minFiles: 2}
+
+	got := cs.resolveSettings(domain.Settings{TargetSize: 500})
+	if got.TargetSize != 500 || got.MinFiles != 2 {
+		t.Errorf("resolveSettings() = %+v, want {500 2}", got)
+	}
+
+	// A per-app override changes how files are batched.
+	files := []s3Object{{Key: "a", Size: 60}, {Key: "b", Size: 60}, {Key: "c", Size: 60}, {Key: "d", Size: 60}}
+	if n := len(batchFiles(files, cs.defaultSettings())); n != 2 {
+		t.Errorf("default batches = %d, want 2", n)
+	}
+	if n := len(batchFiles(files, got)); n != 1 {
+		t.Errorf("override batches = %d, want 1", n)
+	}
+}
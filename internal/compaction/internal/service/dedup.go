@@ -0,0 +1,59 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// rowDeduper drops rows whose event id was already seen, keeping the first
+// row of each id. Redelivered events are written again by the warehouse
+// sink, so the same event can appear in several source files.
+type rowDeduper struct {
+	column int
+	seen   map[string]struct{}
+	kept   []parquet.Row
+}
+
+// newRowDeduper creates a deduper for rows of schema, keyed by its id column.
+func newRowDeduper(schema *parquet.Schema) (*rowDeduper, error) {
+	leaf, ok := schema.Lookup("id")
+	if !ok {
+		return nil, errors.New("schema has no id column")
+	}
+	return &rowDeduper{
+		column: leaf.ColumnIndex,
+		seen:   make(map[string]struct{}),
+	}, nil
+}
+
+// filter returns the rows whose id was not seen before. The result is only
+// valid until the next call; rows is left unchanged so that the caller can
+// keep reading into it.
+func (d *rowDeduper) filter(rows []parquet.Row) []parquet.Row {
+	d.kept = d.kept[:0]
+	for _, row := range rows {
+		id, ok := d.rowID(row)
+		if ok {
+			if _, dup := d.seen[id]; dup {
+				continue
+			}
+			d.seen[id] = struct{}{}
+		}
+		d.kept = append(d.kept, row)
+	}
+	return d.kept
+}
+
+// rowID returns the id value of row. Rows without one are never dropped.
+func (d *rowDeduper) rowID(row parquet.Row) (string, bool) {
+	for _, v := range row {
+		if v.Column() == d.column {
+			if v.IsNull() {
+				return "", false
+			}
+			return string(v.ByteArray()), true
+		}
+	}
+	return "", false
+}
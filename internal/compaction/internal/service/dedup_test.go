@@ -0,0 +1,99 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+// TestWriteCompacted_Dedup verifies rows redelivered into several source
+// files are written once, keeping the first, and counted as duplicates in
+// the footer next to the carried-over stream sequences.
+func TestWriteCompacted_Dedup(t *testing.T) {
+	cs := NewCompactionService(nil, warehouse.S3Config{}, warehouse.ParquetConfig{}, nil, nil, DiscoveryConfig{},
+		SortConfig{}, true, 0, 0, nil, nil)
+
+	writer := warehouse.NewParquetWriter(warehouse.ParquetConfig{})
+	var rowGroups []parquet.RowGroup
+	var sources []warehouse.FileIntegrity
+	for file := 0; file < 3; file++ {
+		rows := make([]warehouse.EventRow, 1500)
+		seqs := make([]uint64, len(rows))
+		for i := range rows {
+			// Retried publishes are stored at new sequences.
+			seqs[i] = uint64(file*1500 + i + 1)
+
+			// Files overlap by 500 events; each copy records its file.
+			id := file*1000 + i
+			rows[i] = warehouse.EventRow{
+				ID:          fmt.Sprintf("evt-%d", id),
+				AppID:       "demo",
+				DeviceID:    fmt.Sprintf("dev-%d", file),
+				PayloadJSON: "{}",
+			}
+		}
+		ranges := warehouse.NewSequenceRanges(seqs)
+		data, err := writer.WithOptions(warehouse.SequenceMetadata("CAUSALITY_EVENTS", ranges, 0)...).Write(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pf, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		source, err := warehouse.ReadFileIntegrity(fmt.Sprintf("file-%d", file), pf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rowGroups = append(rowGroups, pf.RowGroups()...)
+		sources = append(sources, source)
+	}
+
+	schema := warehouse.EventRowSchema(nil)
+	merged, err := parquet.MergeRowGroups(rowGroups, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compacted, err := cs.writeCompacted(merged, schema, sources)
+	if err != nil {
+		t.Fatalf("writeCompacted() error = %v", err)
+	}
+	if compacted.rows != 3500 || compacted.duplicates != 1000 {
+		t.Errorf("rows = %d, duplicates = %d, want 3500 and 1000", compacted.rows, compacted.duplicates)
+	}
+
+	got, err := parquet.Read[warehouse.EventRow](bytes.NewReader(compacted.data), int64(len(compacted.data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool, len(got))
+	for _, row := range got {
+		if seen[row.ID] {
+			t.Fatalf("event %s written twice", row.ID)
+		}
+		seen[row.ID] = true
+		if row.ID == "evt-1200" && row.DeviceID != "dev-0" {
+			t.Errorf("evt-1200 kept from %s, want the first copy from dev-0", row.DeviceID)
+		}
+	}
+	if len(got) != 3500 {
+		t.Errorf("read %d rows, want 3500", len(got))
+	}
+
+	pf, err := parquet.OpenFile(bytes.NewReader(compacted.data), int64(len(compacted.data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := warehouse.ReadFileIntegrity("compacted", pf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Stream != "CAUSALITY_EVENTS" || info.Sequences.String() != "1-4500" || info.Deduplicated != 1000 {
+		t.Errorf("footer stream = %q, sequences = %q, deduplicated = %d, want CAUSALITY_EVENTS, 1-4500 and 1000",
+			info.Stream, info.Sequences, info.Deduplicated)
+	}
+}
@@ -0,0 +1,348 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+// Partition discovery modes.
+const (
+	// DiscoveryList LISTs the whole event prefix on every run.
+	DiscoveryList = "list"
+
+	// DiscoveryInventory reads the latest S3 Inventory report and LISTs only
+	// the days written since it was generated.
+	DiscoveryInventory = "inventory"
+
+	// DiscoveryDelta derives partitions from the Delta Lake snapshot.
+	DiscoveryDelta = "delta"
+)
+
+// DefaultInventoryMaxAge is the oldest inventory report that is still used.
+const DefaultInventoryMaxAge = 48 * time.Hour
+
+// DiscoveryConfig configures how cold partitions are discovered.
+type DiscoveryConfig struct {
+	// Mode is one of DiscoveryList, DiscoveryInventory, or DiscoveryDelta.
+	// Empty means DiscoveryList.
+	Mode string
+
+	// InventoryBucket is the S3 Inventory destination bucket. Defaults to
+	// the event bucket.
+	InventoryBucket string
+
+	// InventoryPrefix is the inventory configuration prefix holding the
+	// dated report folders, i.e. {destination-prefix}/{source-bucket}/{config-id}.
+	InventoryPrefix string
+
+	// InventoryMaxAge is the oldest report that is used before falling back
+	// to a full LIST.
+	InventoryMaxAge time.Duration
+}
+
+// errInventoryUnavailable is returned when no usable inventory report exists.
+var errInventoryUnavailable = errors.New("s3 inventory unavailable")
+
+// discoverPartitions returns the cold partitions to consider in a run using
+// the configured discovery mode, falling back to a full LIST when the
+// inventory or Delta snapshot cannot be used.
+func (cs *CompactionService) discoverPartitions(ctx context.Context, snapshot *warehouse.DeltaSnapshot) ([]string, error) {
+	now := time.Now().UTC()
+
+	switch cs.discovery.Mode {
+	case DiscoveryDelta:
+		if snapshot != nil {
+			partitionSet := make(map[string]struct{})
+			for _, key := range snapshot.Keys() {
+				addColdPartition(cs.partitions, partitionSet, key, now)
+			}
+			cs.logger.Debug("discovered partitions from delta snapshot", "version", snapshot.Version)
+			return setToSlice(partitionSet), nil
+		}
+		cs.logger.Warn("delta partition discovery requires DELTA_ENABLED, falling back to LIST")
+
+	case DiscoveryInventory:
+		partitions, err := cs.inventoryPartitions(ctx, now)
+		if err == nil {
+			return partitions, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		cs.logger.Warn("inventory partition discovery failed, falling back to LIST", "error", err)
+	}
+
+	return cs.listColdPartitions(ctx)
+}
+
+// inventoryPartitions reads the latest S3 Inventory report and adds the
+// partitions written since it was generated by LISTing, per app in the
+// report, only the days after the report date. Apps first seen after the
+// report are picked up once they appear in a later report.
+func (cs *CompactionService) inventoryPartitions(ctx context.Context, now time.Time) ([]string, error) {
+	manifestKey, generated, err := cs.latestInventoryManifest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if maxAge := cs.inventoryMaxAge(); now.Sub(generated) > maxAge {
+		return nil, fmt.Errorf("%w: latest report %s is older than %s", errInventoryUnavailable, manifestKey, maxAge)
+	}
+
+	body, err := cs.getInventoryObject(ctx, manifestKey)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := parseInventoryManifest(body)
+	_ = body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := cs.s3Config.Prefix + "/"
+	partitionSet := make(map[string]struct{})
+	apps := make(map[string]struct{})
+	for _, file := range manifest.Files {
+		if err := cs.readInventoryFile(ctx, file.Key, manifest.keyColumn, prefix, now, partitionSet, apps); err != nil {
+			return nil, err
+		}
+	}
+
+	// The report only covers objects that existed when it was generated.
+	// Templates with segments before the date list the whole app once.
+	fromInventory := len(partitionSet)
+	for app := range apps {
+		listed := make(map[string]struct{})
+		for day := truncateDay(generated); !day.After(now); day = day.AddDate(0, 0, 1) {
+			dayPrefix := prefix + cs.partitions.DayPrefix(app, day)
+			if _, ok := listed[dayPrefix]; ok {
+				continue
+			}
+			listed[dayPrefix] = struct{}{}
+			if err := cs.listColdPartitionsUnder(ctx, dayPrefix, now, partitionSet); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	cs.logger.Info("discovered partitions from s3 inventory",
+		"manifest", manifestKey,
+		"generated", generated,
+		"partitions_inventory", fromInventory,
+		"partitions_total", len(partitionSet),
+	)
+
+	return setToSlice(partitionSet), nil
+}
+
+// inventoryFolderRegex matches the dated report folders of an inventory
+// configuration, e.g. "2026-10-17T01-00Z/".
+var inventoryFolderRegex = regexp.MustCompile(`(\d{4}-\d{2}-\d{2}T\d{2}-\d{2}Z)/$`)
+
+// inventoryFolderLayout is the time layout of inventory report folders.
+const inventoryFolderLayout = "2006-01-02T15-04Z"
+
+// latestInventoryManifest returns the manifest key of the newest inventory
+// report and the time it was generated.
+func (cs *CompactionService) latestInventoryManifest(ctx context.Context) (string, time.Time, error) {
+	if cs.discovery.InventoryPrefix == "" {
+		return "", time.Time{}, fmt.Errorf("%w: no inventory prefix configured", errInventoryUnavailable)
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(cs.s3Client, &s3.ListObjectsV2Input{
+		Bucket:    aws.String(cs.inventoryBucket()),
+		Prefix:    aws.String(strings.TrimSuffix(cs.discovery.InventoryPrefix, "/") + "/"),
+		Delimiter: aws.String("/"),
+	})
+
+	var folders []string
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return "", time.Time{}, fmt.Errorf("list inventory reports: %w", err)
+		}
+		for _, cp := range page.CommonPrefixes {
+			if cp.Prefix != nil {
+				folders = append(folders, *cp.Prefix)
+			}
+		}
+	}
+
+	folder, generated, ok := latestInventoryFolder(folders)
+	if !ok {
+		return "", time.Time{}, fmt.Errorf("%w: no reports under %s", errInventoryUnavailable, cs.discovery.InventoryPrefix)
+	}
+	return folder + "manifest.json", generated, nil
+}
+
+// latestInventoryFolder picks the newest dated report folder.
+func latestInventoryFolder(folders []string) (string, time.Time, bool) {
+	sort.Strings(folders)
+	for i := len(folders) - 1; i >= 0; i-- {
+		m := inventoryFolderRegex.FindStringSubmatch(folders[i])
+		if m == nil {
+			continue
+		}
+		generated, err := time.Parse(inventoryFolderLayout, m[1])
+		if err != nil {
+			continue
+		}
+		return folders[i], generated, true
+	}
+	return "", time.Time{}, false
+}
+
+// inventoryManifest is the subset of an S3 Inventory manifest.json used for
+// partition discovery.
+type inventoryManifest struct {
+	FileFormat string `json:"fileFormat"`
+	FileSchema string `json:"fileSchema"`
+	Files      []struct {
+		Key string `json:"key"`
+	} `json:"files"`
+
+	// keyColumn is the index of the Key column in each CSV record.
+	keyColumn int
+}
+
+// parseInventoryManifest decodes a manifest and locates the Key column.
+// Only CSV reports are supported.
+func parseInventoryManifest(r io.Reader) (*inventoryManifest, error) {
+	var m inventoryManifest
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("decode inventory manifest: %w", err)
+	}
+	if !strings.EqualFold(m.FileFormat, "CSV") {
+		return nil, fmt.Errorf("%w: unsupported report format %q (only CSV)", errInventoryUnavailable, m.FileFormat)
+	}
+
+	m.keyColumn = -1
+	for i, field := range strings.Split(m.FileSchema, ",") {
+		if strings.TrimSpace(field) == "Key" {
+			m.keyColumn = i
+			break
+		}
+	}
+	if m.keyColumn < 0 {
+		return nil, fmt.Errorf("%w: report schema %q has no Key column", errInventoryUnavailable, m.FileSchema)
+	}
+
+	return &m, nil
+}
+
+// readInventoryFile streams one gzip-compressed CSV report file and records
+// the cold partitions and app IDs of the keys under prefix.
+func (cs *CompactionService) readInventoryFile(
+	ctx context.Context,
+	key string,
+	keyColumn int,
+	prefix string,
+	now time.Time,
+	partitionSet, apps map[string]struct{},
+) error {
+	body, err := cs.getInventoryObject(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = body.Close() }()
+
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return fmt.Errorf("open inventory file %s: %w", key, err)
+	}
+	defer func() { _ = gz.Close() }()
+
+	if err := scanInventoryCSV(gz, keyColumn, prefix, cs.partitions, now, partitionSet, apps); err != nil {
+		return fmt.Errorf("read inventory file %s: %w", key, err)
+	}
+	return nil
+}
+
+// scanInventoryCSV reads inventory records and records the cold partitions
+// and app IDs of the keys under prefix. Keys in inventory reports are
+// URL-encoded.
+func scanInventoryCSV(
+	r io.Reader,
+	keyColumn int,
+	prefix string,
+	scheme *warehouse.PartitionScheme,
+	now time.Time,
+	partitionSet, apps map[string]struct{},
+) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if keyColumn >= len(record) {
+			continue
+		}
+
+		key, err := url.QueryUnescape(record[keyColumn])
+		if err != nil || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		partition := extractPartitionPrefix(scheme, key)
+		if partition == "" {
+			continue
+		}
+		apps[extractAppID(partition)] = struct{}{}
+		if isColdPartition(scheme, partition, now) {
+			partitionSet[partition] = struct{}{}
+		}
+	}
+}
+
+// getInventoryObject opens an object in the inventory bucket.
+func (cs *CompactionService) getInventoryObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := cs.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(cs.inventoryBucket()),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get inventory object %s: %w", key, err)
+	}
+	return out.Body, nil
+}
+
+func (cs *CompactionService) inventoryBucket() string {
+	if cs.discovery.InventoryBucket != "" {
+		return cs.discovery.InventoryBucket
+	}
+	return cs.s3Config.Bucket
+}
+
+func (cs *CompactionService) inventoryMaxAge() time.Duration {
+	if cs.discovery.InventoryMaxAge > 0 {
+		return cs.discovery.InventoryMaxAge
+	}
+	return DefaultInventoryMaxAge
+}
+
+// truncateDay returns midnight UTC of t's day.
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
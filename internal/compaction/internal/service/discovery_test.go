@@ -0,0 +1,111 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+func TestLatestInventoryFolder(t *testing.T) {
+	folders := []string{
+		"inv/causality-events/daily/2026-10-16T01-00Z/",
+		"inv/causality-events/daily/hive/",
+		"inv/causality-events/daily/2026-10-17T01-00Z/",
+		"inv/causality-events/daily/data/",
+	}
+
+	folder, generated, ok := latestInventoryFolder(folders)
+	if !ok {
+		t.Fatal("latestInventoryFolder() found no folder")
+	}
+	if folder != "inv/causality-events/daily/2026-10-17T01-00Z/" {
+		t.Errorf("folder = %q", folder)
+	}
+	if want := time.Date(2026, 10, 17, 1, 0, 0, 0, time.UTC); !generated.Equal(want) {
+		t.Errorf("generated = %v, want %v", generated, want)
+	}
+
+	if _, _, ok := latestInventoryFolder([]string{"inv/hive/", "inv/data/"}); ok {
+		t.Error("latestInventoryFolder() without dated folders should report none")
+	}
+}
+
+func TestParseInventoryManifest(t *testing.T) {
+	m, err := parseInventoryManifest(strings.NewReader(`{
+		"sourceBucket": "causality-events",
+		"fileFormat": "CSV",
+		"fileSchema": "Bucket, Key, Size, LastModifiedDate",
+		"files": [{"key": "inv/data/a.csv.gz", "size": 10}, {"key": "inv/data/b.csv.gz", "size": 12}]
+	}`))
+	if err != nil {
+		t.Fatalf("parseInventoryManifest() error = %v", err)
+	}
+	if m.keyColumn != 1 {
+		t.Errorf("keyColumn = %d, want 1", m.keyColumn)
+	}
+	if len(m.Files) != 2 || m.Files[1].Key != "inv/data/b.csv.gz" {
+		t.Errorf("Files = %+v", m.Files)
+	}
+}
+
+func TestParseInventoryManifest_Unsupported(t *testing.T) {
+	tests := []string{
+		`{"fileFormat": "Parquet", "fileSchema": "message s3.inventory {}", "files": []}`,
+		`{"fileFormat": "CSV", "fileSchema": "Bucket, Size", "files": []}`,
+	}
+	for _, manifest := range tests {
+		if _, err := parseInventoryManifest(strings.NewReader(manifest)); !errors.Is(err, errInventoryUnavailable) {
+			t.Errorf("parseInventoryManifest(%s) error = %v, want errInventoryUnavailable", manifest, err)
+		}
+	}
+}
+
+func TestScanInventoryCSV(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 30, 0, 0, time.UTC)
+	csvData := strings.Join([]string{
+		`"causality-events","events/app_id=demo/year=2026/month=10/day=17/hour=10/events_a.parquet","100"`,
+		`"causality-events","events/app_id=demo/year=2026/month=10/day=17/hour=10/events_b.parquet","100"`,
+		`"causality-events","events/app_id=my%20app/year=2026/month=10/day=16/hour=23/events_c.parquet","100"`,
+		// Current hour: not cold, but the app is still recorded.
+		`"causality-events","events/app_id=hot/year=2026/month=10/day=17/hour=12/events_d.parquet","100"`,
+		// Outside the event prefix.
+		`"causality-events","_delta_log/00000000000000000000.json","100"`,
+		`"causality-events","other/app_id=x/year=2026/month=10/day=17/hour=10/events_e.parquet","100"`,
+	}, "\n")
+
+	partitionSet := make(map[string]struct{})
+	apps := make(map[string]struct{})
+	if err := scanInventoryCSV(strings.NewReader(csvData), 1, "events/", warehouse.DefaultPartitionScheme(), now, partitionSet, apps); err != nil {
+		t.Fatalf("scanInventoryCSV() error = %v", err)
+	}
+
+	partitions := setToSlice(partitionSet)
+	sort.Strings(partitions)
+	want := []string{
+		"events/app_id=demo/year=2026/month=10/day=17/hour=10/",
+		"events/app_id=my app/year=2026/month=10/day=16/hour=23/",
+	}
+	if strings.Join(partitions, ",") != strings.Join(want, ",") {
+		t.Errorf("partitions = %v, want %v", partitions, want)
+	}
+
+	for _, app := range []string{"demo", "my app", "hot"} {
+		if _, ok := apps[app]; !ok {
+			t.Errorf("apps missing %q: %v", app, apps)
+		}
+	}
+	if len(apps) != 3 {
+		t.Errorf("apps = %v, want 3", apps)
+	}
+}
+
+func TestTruncateDay(t *testing.T) {
+	got := truncateDay(time.Date(2026, 10, 17, 23, 59, 0, 0, time.FixedZone("X", 3600)))
+	if want := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("truncateDay() = %v, want %v", got, want)
+	}
+}
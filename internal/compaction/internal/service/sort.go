@@ -0,0 +1,80 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+// DefaultSortMemoryRows is the number of rows sorted in memory before a
+// sorted run is spilled to disk.
+const DefaultSortMemoryRows int64 = 100_000
+
+// ErrInvalidSortKey is returned for sort keys that are not event columns.
+var ErrInvalidSortKey = errors.New("invalid compaction sort key")
+
+// SortConfig configures the row order of compacted files.
+type SortConfig struct {
+	// Keys are the columns rows are sorted by, ascending, in order of
+	// precedence (e.g. timestamp_ms, device_id). Empty keeps the order of
+	// the source files.
+	Keys []string
+
+	// MemoryRows bounds the rows sorted in memory. Larger batches are
+	// sorted in runs of MemoryRows rows, spilled to temporary files, and
+	// merged while writing the compacted file.
+	MemoryRows int64
+
+	// TempDir holds the spilled runs. Empty uses the OS temp directory.
+	TempDir string
+}
+
+// ValidateSortKeys checks every sort key names a column of the event schema.
+func ValidateSortKeys(keys []string) error {
+	schema := warehouse.EventRowSchema(nil)
+	for _, key := range keys {
+		if _, ok := schema.Lookup(strings.Split(key, ".")...); !ok {
+			return fmt.Errorf("%w: %q", ErrInvalidSortKey, key)
+		}
+	}
+	return nil
+}
+
+// compactedWriter writes the rows of a compacted file.
+type compactedWriter interface {
+	parquet.RowWriter
+	SetKeyValueMetadata(key, value string)
+	Close() error
+}
+
+// newCompactedWriter returns a writer producing a compacted file with the
+// given options. With sort keys configured, rows are sorted with a
+// memory-bounded external sort and the sort order is recorded in the file's
+// sorting_columns metadata.
+func (c SortConfig) newCompactedWriter(output io.Writer, options []parquet.WriterOption) compactedWriter {
+	if len(c.Keys) == 0 {
+		return parquet.NewWriter(output, options...)
+	}
+
+	columns := make([]parquet.SortingColumn, len(c.Keys))
+	for i, key := range c.Keys {
+		columns[i] = parquet.Ascending(strings.Split(key, ".")...)
+	}
+
+	tempDir := c.TempDir
+	if tempDir == "" {
+		tempDir = os.TempDir()
+	}
+
+	options = append(options, parquet.SortingWriterConfig(
+		parquet.SortingColumns(columns...),
+		parquet.SortingBuffers(parquet.NewFileBufferPool(tempDir, "causality-compaction-*")),
+	))
+	return parquet.NewSortingWriter[any](output, c.MemoryRows, options...)
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+func TestValidateSortKeys(t *testing.T) {
+	if err := ValidateSortKeys([]string{"timestamp_ms", "device_id"}); err != nil {
+		t.Errorf("ValidateSortKeys() error = %v", err)
+	}
+	if err := ValidateSortKeys([]string{"timestamp"}); !errors.Is(err, ErrInvalidSortKey) {
+		t.Errorf("ValidateSortKeys(unknown) error = %v, want ErrInvalidSortKey", err)
+	}
+}
+
+// TestWriteCompacted_Sorted verifies rows are sorted by the sort keys when
+// the batch exceeds the in-memory bound and sorted runs are spilled.
+func TestWriteCompacted_Sorted(t *testing.T) {
+	tempDir := t.TempDir()
+	cs := NewCompactionService(nil, warehouse.S3Config{}, warehouse.ParquetConfig{}, nil, nil, DiscoveryConfig{},
+		SortConfig{Keys: []string{"timestamp_ms", "device_id"}, MemoryRows: 7, TempDir: tempDir}, false, 0, 0, nil, nil)
+
+	rows := make([]warehouse.EventRow, 100)
+	for i := range rows {
+		rows[i] = warehouse.EventRow{
+			ID:          fmt.Sprintf("evt-%d", i),
+			AppID:       "demo",
+			DeviceID:    fmt.Sprintf("dev-%d", (i*31)%5),
+			TimestampMS: int64((i * 37) % 20),
+			PayloadJSON: "{}",
+		}
+	}
+	data, err := warehouse.NewParquetWriter(warehouse.ParquetConfig{}).Write(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := warehouse.EventRowSchema(nil)
+	merged, err := parquet.MergeRowGroups(source.RowGroups(), schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compacted, err := cs.writeCompacted(merged, schema, nil)
+	if err != nil {
+		t.Fatalf("writeCompacted() error = %v", err)
+	}
+
+	got, err := parquet.Read[warehouse.EventRow](bytes.NewReader(compacted.data), int64(len(compacted.data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(rows) {
+		t.Fatalf("compacted %d rows, want %d", len(got), len(rows))
+	}
+	for i := 1; i < len(got); i++ {
+		prev, cur := got[i-1], got[i]
+		if prev.TimestampMS > cur.TimestampMS || (prev.TimestampMS == cur.TimestampMS && prev.DeviceID > cur.DeviceID) {
+			t.Fatalf("row %d (%d, %s) sorts before row %d (%d, %s)",
+				i, cur.TimestampMS, cur.DeviceID, i-1, prev.TimestampMS, prev.DeviceID)
+		}
+	}
+
+	if entries, _ := os.ReadDir(tempDir); len(entries) != 0 {
+		t.Errorf("%d spilled runs left in the temp directory", len(entries))
+	}
+}
@@ -0,0 +1,12 @@
+-- Compaction schedules. The row with app_id '*' is the default schedule;
+-- other rows override it for a single app. Zero/empty values fall back to
+-- the default row, then to the COMPACTION_* environment configuration.
+CREATE TABLE IF NOT EXISTS compaction_schedules (
+    app_id      TEXT PRIMARY KEY,
+    cron        TEXT NOT NULL DEFAULT '',
+    target_size BIGINT NOT NULL DEFAULT 0,
+    min_files   INTEGER NOT NULL DEFAULT 0,
+    blackouts   JSONB NOT NULL DEFAULT '[]',
+    enabled     BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
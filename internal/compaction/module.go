@@ -6,36 +6,73 @@
 // Over time, this degrades Trino query performance because it must open many
 // small files. The compaction module periodically merges these small files into
 // larger ones (target 128-256 MB), which significantly reduces the number of
-// files Trino must scan.
+// files Trino must scan. With COMPACTION_SORT_KEYS set, compacted rows are
+// sorted (e.g. by timestamp_ms and device_id) so row group statistics prune
+// range scans and rows sharing a key are adjacent.
 //
 // # Safety
 //
 //   - Only cold partitions (older than the current hour) are compacted.
 //   - Original files are deleted ONLY after the compacted file is successfully uploaded.
+//   - With Delta Lake enabled, originals are never deleted: an OPTIMIZE-style
+//     commit removes them from the table and a Delta VACUUM reclaims them.
 //   - The service is stateless and idempotent: S3 file layout IS the state.
 //   - If compaction fails partway, originals remain intact for the next run.
+//
+// # Scheduling
+//
+// By default compaction runs every COMPACTION_SCHEDULE. Setting COMPACTION_CRON
+// or COMPACTION_SCHEDULE_SOURCE switches to cron scheduling, where a schedule
+// loaded from a JSON file or the compaction_schedules table may add per-app
+// cron expressions, target sizes, and blackout windows. The schedule is
+// re-loaded every COMPACTION_SCHEDULE_RELOAD_INTERVAL.
 package compaction
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net/http"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-
+	"github.com/SebastienMelki/causality/internal/compaction/internal/domain"
+	"github.com/SebastienMelki/causality/internal/compaction/internal/handler"
+	"github.com/SebastienMelki/causality/internal/compaction/internal/repo"
 	"github.com/SebastienMelki/causality/internal/compaction/internal/service"
+	"github.com/SebastienMelki/causality/internal/compaction/migrations"
+	"github.com/SebastienMelki/causality/internal/db"
 	"github.com/SebastienMelki/causality/internal/observability"
 	"github.com/SebastienMelki/causality/internal/warehouse"
 )
 
+// Migrations are the compaction module's database migrations.
+var Migrations = db.Source{Name: "compaction", FS: migrations.FS}
+
 // Config holds configuration for the compaction module.
 type Config struct {
 	// Enabled controls whether compaction is active.
 	Enabled bool `env:"COMPACTION_ENABLED" envDefault:"true"`
 
-	// Schedule is the interval between compaction runs.
+	// Schedule is the interval between compaction runs. Ignored when cron
+	// scheduling is enabled via Cron or ScheduleSource.
 	Schedule time.Duration `env:"COMPACTION_SCHEDULE" envDefault:"1h"`
 
+	// Cron is the default cron expression (e.g. "15 * * * *" or "@hourly").
+	// A cron expression in the schedule source takes precedence.
+	Cron string `env:"COMPACTION_CRON"`
+
+	// ScheduleSource selects where per-app schedules are loaded from:
+	// empty (environment only), "file", or "postgres".
+	ScheduleSource string `env:"COMPACTION_SCHEDULE_SOURCE"`
+
+	// ScheduleFile is the JSON schedule file used with the "file" source.
+	ScheduleFile string `env:"COMPACTION_SCHEDULE_FILE"`
+
+	// ScheduleReloadInterval is how often the schedule source is re-read.
+	ScheduleReloadInterval time.Duration `env:"COMPACTION_SCHEDULE_RELOAD_INTERVAL" envDefault:"30s"`
+
 	// TargetSize is the target file size for compacted files in bytes.
 	// Default: 128 MB (134217728 bytes).
 	TargetSize int64 `env:"COMPACTION_TARGET_SIZE" envDefault:"134217728"`
@@ -43,29 +80,104 @@ type Config struct {
 	// MinFiles is the minimum number of small files in a partition
 	// required to trigger compaction.
 	MinFiles int `env:"COMPACTION_MIN_FILES" envDefault:"2"`
+
+	// PartitionDiscovery selects how cold partitions are found: "list"
+	// (LIST the whole prefix), "inventory" (latest S3 Inventory report plus
+	// a LIST of the days since), or "delta" (the Delta Lake snapshot).
+	// Inventory and delta fall back to LIST when unusable.
+	PartitionDiscovery string `env:"COMPACTION_PARTITION_DISCOVERY" envDefault:"list"`
+
+	// InventoryBucket is the S3 Inventory destination bucket (default: the event bucket).
+	InventoryBucket string `env:"COMPACTION_INVENTORY_BUCKET"`
+
+	// InventoryPrefix is the inventory configuration prefix containing the
+	// dated report folders: {destination-prefix}/{source-bucket}/{config-id}.
+	InventoryPrefix string `env:"COMPACTION_INVENTORY_PREFIX"`
+
+	// InventoryMaxAge is the oldest inventory report that is still used.
+	InventoryMaxAge time.Duration `env:"COMPACTION_INVENTORY_MAX_AGE" envDefault:"48h"`
+
+	// SortKeys are the columns compacted files are sorted by, ascending, so
+	// range scans and dedup by key read fewer row groups. Empty keeps the
+	// order of the source files.
+	SortKeys []string `env:"COMPACTION_SORT_KEYS" envSeparator:","`
+
+	// SortMemoryRows bounds the rows sorted in memory; larger batches are
+	// sorted in runs spilled to SortTempDir and merged.
+	SortMemoryRows int64 `env:"COMPACTION_SORT_MEMORY_ROWS" envDefault:"100000"`
+
+	// SortTempDir holds spilled sort runs (default: the OS temp directory).
+	SortTempDir string `env:"COMPACTION_SORT_TEMP_DIR"`
+
+	// Dedup drops rows whose event id already appears in the files merged
+	// into the same compacted file, keeping the first. Duplicates are counted
+	// by the compaction.rows.duplicate metric.
+	Dedup bool `env:"COMPACTION_DEDUP" envDefault:"false"`
+
+	// AlertSmallFiles is the number of small files in a single partition
+	// that triggers a small-file alert. Zero disables the alert.
+	AlertSmallFiles int `env:"COMPACTION_ALERT_SMALL_FILES" envDefault:"500"`
+
+	// AlertBacklog is how long a partition may remain uncompacted after it
+	// goes cold before a backlog alert fires. Zero disables the alert.
+	AlertBacklog time.Duration `env:"COMPACTION_ALERT_BACKLOG" envDefault:"6h"`
+}
+
+// Schedule sources.
+const (
+	ScheduleSourceFile     = "file"
+	ScheduleSourcePostgres = "postgres"
+)
+
+// ErrInvalidScheduleSource is returned by Start when the schedule source is
+// unknown or missing its file path or database.
+var ErrInvalidScheduleSource = errors.New("invalid compaction schedule source")
+
+// ErrInvalidSortKey is returned by Start when a sort key is not an event
+// column.
+var ErrInvalidSortKey = service.ErrInvalidSortKey
+
+// CronEnabled reports whether cron scheduling replaces the fixed interval.
+func (c Config) CronEnabled() bool {
+	return c.Cron != "" || c.ScheduleSource != ""
 }
 
+// AlertPublisher publishes compaction alerts to NATS JetStream.
+type AlertPublisher = service.AlertPublisher
+
 // Module is the compaction module facade.
 // It wraps the compaction service and scheduler, providing a clean public API
 // with Start/Stop lifecycle and manual RunNow trigger.
 type Module struct {
-	svc       *service.CompactionService
-	scheduler *service.Scheduler
-	config    Config
-	logger    *slog.Logger
+	svc           *service.CompactionService
+	scheduler     *service.Scheduler
+	cronScheduler *service.CronScheduler
+	sourceErr     error
+	sortErr       error
+	config        Config
+	logger        *slog.Logger
 }
 
 // New creates a new compaction module.
 //
 // Parameters:
-//   - s3Client: the raw AWS S3 client for listing, downloading, uploading, and deleting files
+//   - s3Client: the object API (AWS S3 client or FSStore) for listing, downloading, uploading, and deleting files
 //   - s3Config: S3 configuration (bucket, prefix, etc.)
+//   - parquetConfig: Parquet writer configuration (statistics and page index settings)
+//   - deltaLog: Delta Lake transaction log; when non-nil, compaction commits
+//     OPTIMIZE-style add/remove actions instead of deleting source files
+//   - alerts: publisher for small-file and backlog alerts (may be nil to only log and record metrics)
+//   - db: database holding compaction_schedules (only required for the "postgres" schedule source)
 //   - cfg: compaction module configuration
 //   - metrics: observability metrics (may be nil for no-op)
 //   - logger: structured logger
 func New(
-	s3Client *s3.Client,
+	s3Client warehouse.ObjectAPI,
 	s3Config warehouse.S3Config,
+	parquetConfig warehouse.ParquetConfig,
+	deltaLog *warehouse.DeltaLog,
+	alerts AlertPublisher,
+	db *sql.DB,
 	cfg Config,
 	metrics *observability.Metrics,
 	logger *slog.Logger,
@@ -77,20 +189,54 @@ func New(
 	compactionSvc := service.NewCompactionService(
 		s3Client,
 		s3Config,
+		parquetConfig,
+		deltaLog,
+		service.NewAlerter(alerts, cfg.AlertSmallFiles, cfg.AlertBacklog, metrics, logger),
+		service.DiscoveryConfig{
+			Mode:            cfg.PartitionDiscovery,
+			InventoryBucket: cfg.InventoryBucket,
+			InventoryPrefix: cfg.InventoryPrefix,
+			InventoryMaxAge: cfg.InventoryMaxAge,
+		},
+		service.SortConfig{
+			Keys:       cfg.SortKeys,
+			MemoryRows: cfg.SortMemoryRows,
+			TempDir:    cfg.SortTempDir,
+		},
+		cfg.Dedup,
 		cfg.TargetSize,
 		cfg.MinFiles,
 		metrics,
 		logger,
 	)
 
-	scheduler := service.NewScheduler(compactionSvc, cfg.Schedule, logger)
-
-	return &Module{
+	m := &Module{
 		svc:       compactionSvc,
-		scheduler: scheduler,
+		scheduler: service.NewScheduler(compactionSvc, cfg.Schedule, logger),
 		config:    cfg,
 		logger:    logger.With("component", "compaction-module"),
 	}
+
+	// Reported by Start so that an unknown sort key fails startup.
+	m.sortErr = service.ValidateSortKeys(cfg.SortKeys)
+
+	if cfg.CronEnabled() {
+		source, err := scheduleSource(cfg, db)
+		if err != nil {
+			// Reported by Start so that a misconfigured schedule fails startup.
+			m.sourceErr = err
+		} else {
+			m.cronScheduler = service.NewCronScheduler(
+				compactionSvc,
+				source,
+				domain.Settings{TargetSize: cfg.TargetSize, MinFiles: cfg.MinFiles},
+				cfg.ScheduleReloadInterval,
+				logger,
+			)
+		}
+	}
+
+	return m
 }
 
 // Start begins the scheduled compaction process.
@@ -103,10 +249,25 @@ func (m *Module) Start(ctx context.Context) error {
 
 	m.logger.Info("starting compaction module",
 		"schedule", m.config.Schedule,
+		"cron", m.config.Cron,
+		"schedule_source", m.config.ScheduleSource,
+		"partition_discovery", m.config.PartitionDiscovery,
 		"target_size", m.config.TargetSize,
 		"min_files", m.config.MinFiles,
+		"sort_keys", m.config.SortKeys,
+		"dedup", m.config.Dedup,
 	)
 
+	if m.sortErr != nil {
+		return m.sortErr
+	}
+	if m.sourceErr != nil {
+		return m.sourceErr
+	}
+	if m.cronScheduler != nil {
+		return m.cronScheduler.Start(ctx)
+	}
+
 	m.scheduler.Start(ctx)
 	return nil
 }
@@ -114,10 +275,66 @@ func (m *Module) Start(ctx context.Context) error {
 // Stop stops the compaction scheduler.
 func (m *Module) Stop() {
 	m.logger.Info("stopping compaction module")
+	if m.cronScheduler != nil {
+		m.cronScheduler.Stop()
+		return
+	}
 	m.scheduler.Stop()
 }
 
+// scheduleSource builds the configured schedule source. The environment
+// cron expression is used when the source leaves the default cron unset.
+func scheduleSource(cfg Config, db *sql.DB) (service.ScheduleSource, error) {
+	var source service.ScheduleSource
+	switch cfg.ScheduleSource {
+	case "":
+		return service.StaticSchedule{Cron: cfg.Cron}, nil
+	case ScheduleSourceFile:
+		if cfg.ScheduleFile == "" {
+			return nil, fmt.Errorf("%w: COMPACTION_SCHEDULE_FILE is required", ErrInvalidScheduleSource)
+		}
+		source = repo.NewScheduleFile(cfg.ScheduleFile)
+	case ScheduleSourcePostgres:
+		if db == nil {
+			return nil, fmt.Errorf("%w: no database configured", ErrInvalidScheduleSource)
+		}
+		source = repo.NewScheduleRepository(db)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidScheduleSource, cfg.ScheduleSource)
+	}
+
+	return defaultCron{source: source, cron: cfg.Cron}, nil
+}
+
+// defaultCron fills in the default cron expression of a loaded schedule.
+type defaultCron struct {
+	source service.ScheduleSource
+	cron   string
+}
+
+// Load loads the schedule from the wrapped source.
+func (d defaultCron) Load(ctx context.Context) (*domain.Schedule, error) {
+	schedule, err := d.source.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.Cron == "" {
+		schedule.Cron = d.cron
+	}
+	return schedule, nil
+}
+
+// RegisterRoutes mounts the compaction admin endpoint onto the given ServeMux:
+//   - POST /api/admin/compaction/run - Start a compaction run (see RunNow)
+func (m *Module) RegisterRoutes(mux *http.ServeMux) {
+	handler.NewRunHandler(m, m.logger).RegisterRoutes(mux)
+}
+
 // RunNow triggers an immediate compaction run outside the scheduled interval.
+// With cron scheduling, per-app settings, blackouts, and disabled apps apply.
 func (m *Module) RunNow(ctx context.Context) error {
+	if m.cronScheduler != nil {
+		return m.cronScheduler.RunNow(ctx)
+	}
 	return m.svc.CompactAll(ctx)
 }
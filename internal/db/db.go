@@ -0,0 +1,176 @@
+// Package db provides the PostgreSQL connection pool shared by every service,
+// a pgx pool exposed to repositories as a *sql.DB, with health checks and the
+// golang-migrate runner that applies each module's embedded migrations at
+// startup.
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// ErrConnection indicates the database could not be reached.
+var ErrConnection = errors.New("database connection error")
+
+// healthCheckTimeout bounds HealthCheck when ctx has no earlier deadline.
+const healthCheckTimeout = 2 * time.Second
+
+// Config holds PostgreSQL connection and pool configuration. Services embed
+// it with a prefix, e.g. DATABASE_HOST.
+type Config struct {
+	// Host is the PostgreSQL host
+	Host string `env:"HOST" envDefault:"localhost"`
+
+	// Port is the PostgreSQL port
+	Port int `env:"PORT" envDefault:"5432"`
+
+	// User is the database user
+	User string `env:"USER" envDefault:"hive"`
+
+	// Password is the database password
+	Password string `env:"PASSWORD" envDefault:"hive"`
+
+	// Name is the database name
+	Name string `env:"NAME" envDefault:"causality_server"`
+
+	// SSLMode is the SSL mode (disable, require, verify-ca, verify-full)
+	SSLMode string `env:"SSL_MODE" envDefault:"disable"`
+
+	// MaxOpenConns is the maximum number of open connections
+	MaxOpenConns int `env:"MAX_OPEN_CONNS" envDefault:"25"`
+
+	// MinIdleConns is the number of idle connections the pool keeps open
+	// to serve bursts without connecting
+	MinIdleConns int `env:"MIN_IDLE_CONNS" envDefault:"2"`
+
+	// ConnMaxLifetime is the maximum connection lifetime
+	ConnMaxLifetime time.Duration `env:"CONN_MAX_LIFETIME" envDefault:"5m"`
+
+	// ConnMaxIdleTime is how long a connection may stay idle before it is
+	// closed
+	ConnMaxIdleTime time.Duration `env:"CONN_MAX_IDLE_TIME" envDefault:"1m"`
+
+	// Migrate applies pending migrations at startup
+	Migrate bool `env:"MIGRATE" envDefault:"true"`
+}
+
+// DSN returns the PostgreSQL connection string.
+func (c Config) DSN() string {
+	return fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		c.Host, c.Port, c.User, c.Password, c.Name, c.SSLMode,
+	)
+}
+
+// Pool is a pgx pool of PostgreSQL connections. It embeds a *sql.DB over
+// the pool for module repositories, which share its connections.
+type Pool struct {
+	*sql.DB
+	pgx    *pgxpool.Pool
+	config Config
+	logger *slog.Logger
+}
+
+// Open opens a connection pool configured by cfg and verifies that the
+// database is reachable.
+func Open(ctx context.Context, cfg Config, logger *slog.Logger) (*Pool, error) {
+	pool, err := Connect(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pool.PingContext(ctx); err != nil {
+		_ = pool.Close()
+		return nil, fmt.Errorf("%w: %w", ErrConnection, err)
+	}
+
+	pool.logger.Info("connected to database",
+		"host", cfg.Host,
+		"port", cfg.Port,
+		"max_open_conns", cfg.MaxOpenConns,
+	)
+	return pool, nil
+}
+
+// Connect creates a connection pool configured by cfg without connecting;
+// connections are opened on first use.
+func Connect(cfg Config, logger *slog.Logger) (*Pool, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger = logger.With("component", "db", "database", cfg.Name)
+
+	poolCfg, err := pgxpool.ParseConfig(cfg.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("invalid database configuration: %w", err)
+	}
+	if cfg.MaxOpenConns > 0 {
+		poolCfg.MaxConns = int32(cfg.MaxOpenConns)
+	}
+	poolCfg.MinIdleConns = int32(min(cfg.MinIdleConns, int(poolCfg.MaxConns)))
+	if cfg.ConnMaxLifetime > 0 {
+		poolCfg.MaxConnLifetime = cfg.ConnMaxLifetime
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = cfg.ConnMaxIdleTime
+	}
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	return &Pool{
+		DB:     stdlib.OpenDBFromPool(pool),
+		pgx:    pool,
+		config: cfg,
+		logger: logger,
+	}, nil
+}
+
+// Close closes the pool and its connections.
+func (p *Pool) Close() error {
+	err := p.DB.Close()
+	p.pgx.Close()
+	return err
+}
+
+// Pgx returns the underlying pgx pool, for features database/sql does not
+// expose such as LISTEN.
+func (p *Pool) Pgx() *pgxpool.Pool {
+	return p.pgx
+}
+
+// Config returns the configuration the pool was opened with.
+func (p *Pool) Config() Config {
+	return p.config
+}
+
+// HealthCheck reports whether the database answers a ping within two
+// seconds, or ctx's deadline if earlier.
+func (p *Pool) HealthCheck(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	if err := p.PingContext(ctx); err != nil {
+		return fmt.Errorf("%w: %w", ErrConnection, err)
+	}
+	return nil
+}
+
+// Migrate applies the pending migrations of sources, in order, unless the
+// pool's configuration disables migrations.
+func (p *Pool) Migrate(ctx context.Context, sources ...Source) error {
+	if !p.config.Migrate {
+		p.logger.Info("database migrations disabled")
+		return nil
+	}
+	return Migrate(ctx, p.pgx, p.logger, sources...)
+}
@@ -0,0 +1,222 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	pgxmigrate "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// ErrInvalidMigration indicates a migration file name that is not
+// {version}_{title}.up.sql or .down.sql, or a version used twice.
+var ErrInvalidMigration = errors.New("invalid migration")
+
+// ErrDirtyMigration indicates a migration that failed part way, recorded as
+// dirty by golang-migrate. The schema must be checked and the version forced
+// by hand (migrate force) before migrations run again.
+var ErrDirtyMigration = errors.New("dirty migration")
+
+// migrationLockID is the advisory lock key held while migrating, so replicas
+// starting together apply each migration once. It is shared by every
+// service, since several of them migrate the same database.
+const migrationLockID int64 = 0x6361757361 // "causa"
+
+// migrationsTablePrefix prefixes the golang-migrate version table of each
+// source, e.g. schema_migrations_auth.
+const migrationsTablePrefix = "schema_migrations_"
+
+// Source is a module's migrations, in golang-migrate's file layout:
+// {version}_{title}.up.sql applies version, {version}_{title}.down.sql
+// reverts it. Versions are applied in numeric order and recorded in the
+// source's own schema_migrations_{Name} table, so modules sharing a database
+// number their migrations independently.
+type Source struct {
+	// Name identifies the module, e.g. "auth".
+	Name string
+
+	// FS holds the migration files at its root, usually an embed.FS.
+	FS fs.FS
+}
+
+// migration is a parsed up migration.
+type migration struct {
+	version int64
+	name    string
+}
+
+// parseMigrations returns the up migrations of fsys ordered by version.
+// golang-migrate skips files it cannot parse, so a misnamed migration is
+// rejected here rather than silently never applied.
+func parseMigrations(fsys fs.FS) ([]migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int64]string)
+	var migrations []migration
+	for _, name := range names {
+		base, ok := strings.CutSuffix(name, ".up.sql")
+		if !ok {
+			if strings.HasSuffix(name, ".down.sql") {
+				continue
+			}
+			return nil, fmt.Errorf("%w: %s: not an .up.sql or .down.sql file", ErrInvalidMigration, name)
+		}
+
+		prefix, _, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("%w: %s: missing version prefix", ErrInvalidMigration, name)
+		}
+		if other, dup := seen[version]; dup {
+			return nil, fmt.Errorf("%w: %s and %s share version %d", ErrInvalidMigration, other, name, version)
+		}
+		seen[version] = name
+
+		migrations = append(migrations, migration{version: version, name: name})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
+
+// Migrate applies the pending migrations of sources to the database of pool
+// with golang-migrate, in order. It holds a PostgreSQL advisory lock for the
+// duration, so concurrent callers wait for each other. The pool needs a
+// connection for the lock and one for golang-migrate.
+//
+// A migration that fails leaves its version dirty, and Migrate returns
+// ErrDirtyMigration until an operator checks the schema and forces the
+// version.
+func Migrate(ctx context.Context, pool *pgxpool.Pool, logger *slog.Logger, sources ...Source) error {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	// Advisory locks belong to the session, so lock and unlock on one
+	// connection
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get migration connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID); err != nil {
+			logger.Warn("failed to release migration lock", "error", err)
+		}
+	}()
+
+	for _, src := range sources {
+		applied, err := migrateSource(ctx, pool, src)
+		if err != nil {
+			return fmt.Errorf("failed to migrate %s: %w", src.Name, err)
+		}
+		if applied > 0 {
+			logger.Info("applied migrations", "source", src.Name, "count", applied)
+		}
+	}
+	return nil
+}
+
+// migrateSource applies the pending migrations of src and returns how many
+// were applied.
+func migrateSource(ctx context.Context, pool *pgxpool.Pool, src Source) (int, error) {
+	migrations, err := parseMigrations(src.FS)
+	if err != nil {
+		return 0, err
+	}
+
+	files, err := iofs.New(src.FS, ".")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	// The driver closes the *sql.DB it is given; closing a *sql.DB opened
+	// from the pool leaves the pool open.
+	sqlDB := stdlib.OpenDBFromPool(pool)
+	driver, err := pgxmigrate.WithInstance(sqlDB, &pgxmigrate.Config{
+		MigrationsTable: migrationsTablePrefix + src.Name,
+	})
+	if err != nil {
+		_ = sqlDB.Close()
+		_ = files.Close()
+		return 0, fmt.Errorf("failed to open migration driver: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", files, "pgx5", driver)
+	if err != nil {
+		_ = driver.Close()
+		_ = files.Close()
+		return 0, err
+	}
+	defer func() { _, _ = m.Close() }()
+
+	// golang-migrate takes no context; stop between migrations on cancel
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			m.GracefulStop <- true
+		case <-done:
+		}
+	}()
+
+	before, err := currentVersion(m)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	after, _, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return 0, err
+	}
+
+	count := 0
+	for _, mig := range migrations {
+		if mig.version > before && mig.version <= int64(after) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// currentVersion returns the applied version of m, 0 when none is, or
+// ErrDirtyMigration when the version is dirty.
+func currentVersion(m *migrate.Migrate) (int64, error) {
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migration version: %w", err)
+	}
+	if dirty {
+		return 0, fmt.Errorf("%w: version %d", ErrDirtyMigration, version)
+	}
+	return int64(version), nil
+}
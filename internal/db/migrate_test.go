@@ -0,0 +1,76 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"010_add_index.up.sql":      {Data: []byte("CREATE INDEX ...")},
+		"010_add_index.down.sql":    {Data: []byte("DROP INDEX ...")},
+		"002_add_column.up.sql":     {Data: []byte("ALTER TABLE ...")},
+		"002_add_column.down.sql":   {Data: []byte("ALTER TABLE ...")},
+		"001_create_table.up.sql":   {Data: []byte("CREATE TABLE ...")},
+		"001_create_table.down.sql": {Data: []byte("DROP TABLE ...")},
+		"migrations.go":             {Data: []byte("package migrations")},
+	}
+
+	migrations, err := parseMigrations(fsys)
+	if err != nil {
+		t.Fatalf("parseMigrations() = %v", err)
+	}
+
+	want := []migration{
+		{version: 1, name: "001_create_table.up.sql"},
+		{version: 2, name: "002_add_column.up.sql"},
+		{version: 10, name: "010_add_index.up.sql"},
+	}
+	if len(migrations) != len(want) {
+		t.Fatalf("migrations = %+v, want %+v", migrations, want)
+	}
+	for i := range want {
+		if migrations[i] != want[i] {
+			t.Errorf("migrations[%d] = %+v, want %+v", i, migrations[i], want[i])
+		}
+	}
+}
+
+func TestParseMigrations_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"001_create_table.up.sql": {},
+				"1_create_other.up.sql":   {},
+			},
+		},
+		{
+			name: "missing version",
+			fsys: fstest.MapFS{"create_table.up.sql": {}},
+		},
+		{
+			name: "unknown direction",
+			fsys: fstest.MapFS{"001_create_table.sql": {}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseMigrations(tt.fsys); !errors.Is(err, ErrInvalidMigration) {
+				t.Errorf("parseMigrations() = %v, want ErrInvalidMigration", err)
+			}
+		})
+	}
+}
+
+func TestConfigDSN(t *testing.T) {
+	cfg := Config{Host: "db", Port: 5433, User: "u", Password: "p", Name: "causality_server", SSLMode: "require"}
+	want := "host=db port=5433 user=u password=p dbname=causality_server sslmode=require"
+	if got := cfg.DSN(); got != want {
+		t.Errorf("DSN() = %q, want %q", got, want)
+	}
+}
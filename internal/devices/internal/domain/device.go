@@ -0,0 +1,194 @@
+// Package domain contains the core types for the device registry.
+package domain
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrDeviceNotFound is returned when a device is not in the registry.
+var ErrDeviceNotFound = errors.New("device not found")
+
+// Risk score weights of the share of a device's events sent from an
+// emulator and from a jailbroken or rooted device.
+const (
+	EmulatorWeight  = 0.6
+	JailbreakWeight = 0.4
+)
+
+// Observation is the registry-relevant content of a single event.
+type Observation struct {
+	AppID       string
+	DeviceID    string
+	At          time.Time
+	Platform    string
+	OSVersion   string
+	AppVersion  string
+	DeviceModel string
+	Jailbroken  bool
+	Emulator    bool
+}
+
+// DeviceKey identifies one device of one app.
+type DeviceKey struct {
+	AppID    string
+	DeviceID string
+}
+
+// Device is a device registry record. Event counts are exponentially
+// decayed to LastSeenAt, so old events weigh less than recent ones and the
+// risk score follows the device's recent behaviour.
+type Device struct {
+	AppID       string `json:"app_id"`
+	DeviceID    string `json:"device_id"`
+	Platform    string `json:"platform"`
+	OSVersion   string `json:"os_version"`
+	AppVersion  string `json:"app_version"`
+	DeviceModel string `json:"device_model"`
+
+	// Jailbroken and Emulator are the flags of the most recent event.
+	Jailbroken bool `json:"jailbroken"`
+	Emulator   bool `json:"emulator"`
+
+	// EverJailbroken and EverEmulator are set once any event had the flag.
+	EverJailbroken bool `json:"ever_jailbroken"`
+	EverEmulator   bool `json:"ever_emulator"`
+
+	// Events, JailbrokenEvents and EmulatorEvents are decayed event counts.
+	Events           float64 `json:"events"`
+	JailbrokenEvents float64 `json:"jailbroken_events"`
+	EmulatorEvents   float64 `json:"emulator_events"`
+
+	// RiskScore is between 0 and 1; see ComputeRiskScore.
+	RiskScore float64 `json:"risk_score"`
+
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+// FromObservation returns the record of a device seen once.
+func FromObservation(o Observation) Device {
+	d := Device{
+		AppID:          o.AppID,
+		DeviceID:       o.DeviceID,
+		Platform:       o.Platform,
+		OSVersion:      o.OSVersion,
+		AppVersion:     o.AppVersion,
+		DeviceModel:    o.DeviceModel,
+		Jailbroken:     o.Jailbroken,
+		Emulator:       o.Emulator,
+		EverJailbroken: o.Jailbroken,
+		EverEmulator:   o.Emulator,
+		Events:         1,
+		FirstSeenAt:    o.At,
+		LastSeenAt:     o.At,
+	}
+	if o.Jailbroken {
+		d.JailbrokenEvents = 1
+	}
+	if o.Emulator {
+		d.EmulatorEvents = 1
+	}
+	d.RiskScore = ComputeRiskScore(d)
+	return d
+}
+
+// ComputeRiskScore returns the weighted share of a device's decayed events
+// that were sent from an emulator or a jailbroken device, between 0 and 1.
+func ComputeRiskScore(d Device) float64 {
+	if d.Events <= 0 {
+		return 0
+	}
+	score := (EmulatorWeight*d.EmulatorEvents + JailbreakWeight*d.JailbrokenEvents) / d.Events
+	return math.Min(math.Max(score, 0), 1)
+}
+
+// Merge merges o, another record of the same device, into d. Both sides'
+// counts are decayed to the later LastSeenAt with the given half-life (no
+// decay if halfLife is not positive), attributes come from the more
+// recently seen side, and the risk score is recomputed.
+func (d *Device) Merge(o Device, halfLife time.Duration) {
+	if d.DeviceID == "" {
+		*d = o
+		d.RiskScore = ComputeRiskScore(*d)
+		return
+	}
+
+	latest := d.LastSeenAt
+	if o.LastSeenAt.After(latest) {
+		latest = o.LastSeenAt
+	}
+	fd := decayFactor(latest.Sub(d.LastSeenAt), halfLife)
+	fo := decayFactor(latest.Sub(o.LastSeenAt), halfLife)
+
+	if !o.LastSeenAt.Before(d.LastSeenAt) {
+		d.Platform = o.Platform
+		d.OSVersion = o.OSVersion
+		d.AppVersion = o.AppVersion
+		d.DeviceModel = o.DeviceModel
+		d.Jailbroken = o.Jailbroken
+		d.Emulator = o.Emulator
+	}
+	d.EverJailbroken = d.EverJailbroken || o.EverJailbroken
+	d.EverEmulator = d.EverEmulator || o.EverEmulator
+
+	d.Events = d.Events*fd + o.Events*fo
+	d.JailbrokenEvents = d.JailbrokenEvents*fd + o.JailbrokenEvents*fo
+	d.EmulatorEvents = d.EmulatorEvents*fd + o.EmulatorEvents*fo
+
+	if o.FirstSeenAt.Before(d.FirstSeenAt) {
+		d.FirstSeenAt = o.FirstSeenAt
+	}
+	d.LastSeenAt = latest
+	d.RiskScore = ComputeRiskScore(*d)
+}
+
+// decayFactor returns the weight of a count that is age old.
+func decayFactor(age, halfLife time.Duration) float64 {
+	if halfLife <= 0 || age <= 0 {
+		return 1
+	}
+	return math.Pow(0.5, age.Seconds()/halfLife.Seconds())
+}
+
+// Aggregator accumulates device records in memory between flushes. It is
+// safe for concurrent use.
+type Aggregator struct {
+	mu       sync.Mutex
+	halfLife time.Duration
+	devices  map[DeviceKey]*Device
+}
+
+// NewAggregator creates an empty Aggregator decaying counts with halfLife.
+func NewAggregator(halfLife time.Duration) *Aggregator {
+	return &Aggregator{halfLife: halfLife, devices: make(map[DeviceKey]*Device)}
+}
+
+// Add records an observation.
+func (a *Aggregator) Add(o Observation) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := DeviceKey{AppID: o.AppID, DeviceID: o.DeviceID}
+	d, ok := a.devices[key]
+	if !ok {
+		d = &Device{}
+		a.devices[key] = d
+	}
+	d.Merge(FromObservation(o), a.halfLife)
+}
+
+// Drain returns the accumulated records and resets the aggregator.
+func (a *Aggregator) Drain() map[DeviceKey]Device {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	drained := make(map[DeviceKey]Device, len(a.devices))
+	for key, d := range a.devices {
+		drained[key] = *d
+	}
+	a.devices = make(map[DeviceKey]*Device)
+	return drained
+}
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestComputeRiskScore(t *testing.T) {
+	tests := []struct {
+		name string
+		d    Device
+		want float64
+	}{
+		{"no events", Device{}, 0},
+		{"clean", Device{Events: 10}, 0},
+		{"always emulator", Device{Events: 4, EmulatorEvents: 4}, EmulatorWeight},
+		{"always both", Device{Events: 4, EmulatorEvents: 4, JailbrokenEvents: 4}, 1},
+		{"half jailbroken", Device{Events: 10, JailbrokenEvents: 5}, JailbreakWeight / 2},
+	}
+	for _, tt := range tests {
+		if got := ComputeRiskScore(tt.d); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: ComputeRiskScore() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDevice_MergeDecays(t *testing.T) {
+	t0 := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	halfLife := 24 * time.Hour
+
+	d := FromObservation(Observation{AppID: "app", DeviceID: "d1", At: t0, Emulator: true, AppVersion: "1.0"})
+	d.Merge(FromObservation(Observation{AppID: "app", DeviceID: "d1", At: t0.Add(halfLife), AppVersion: "1.1"}), halfLife)
+
+	// The emulator event is one half-life old: weight 0.5 of 1.5 events.
+	if math.Abs(d.Events-1.5) > 1e-9 || math.Abs(d.EmulatorEvents-0.5) > 1e-9 {
+		t.Errorf("counts = %v events, %v emulator, want 1.5 and 0.5", d.Events, d.EmulatorEvents)
+	}
+	if want := EmulatorWeight / 3; math.Abs(d.RiskScore-want) > 1e-9 {
+		t.Errorf("RiskScore = %v, want %v", d.RiskScore, want)
+	}
+	if d.Emulator || !d.EverEmulator || d.AppVersion != "1.1" {
+		t.Errorf("flags = emulator %v ever %v app %q, want latest clean event on 1.1", d.Emulator, d.EverEmulator, d.AppVersion)
+	}
+	if !d.FirstSeenAt.Equal(t0) || !d.LastSeenAt.Equal(t0.Add(halfLife)) {
+		t.Errorf("seen = %v..%v", d.FirstSeenAt, d.LastSeenAt)
+	}
+
+	// Merging an older record keeps the newer attributes.
+	d.Merge(FromObservation(Observation{AppID: "app", DeviceID: "d1", At: t0.Add(-halfLife), AppVersion: "0.9"}), halfLife)
+	if d.AppVersion != "1.1" || !d.FirstSeenAt.Equal(t0.Add(-halfLife)) {
+		t.Errorf("after older merge: app %q first seen %v", d.AppVersion, d.FirstSeenAt)
+	}
+}
+
+func TestAggregator_AddAndDrain(t *testing.T) {
+	agg := NewAggregator(0)
+	at := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	agg.Add(Observation{AppID: "app", DeviceID: "d1", At: at, Jailbroken: true})
+	agg.Add(Observation{AppID: "app", DeviceID: "d1", At: at.Add(time.Minute)})
+	agg.Add(Observation{AppID: "app", DeviceID: "d2", At: at})
+
+	devices := agg.Drain()
+	if len(devices) != 2 {
+		t.Fatalf("got %d devices, want 2", len(devices))
+	}
+	d1 := devices[DeviceKey{AppID: "app", DeviceID: "d1"}]
+	if d1.Events != 2 || d1.JailbrokenEvents != 1 || d1.RiskScore != JailbreakWeight/2 {
+		t.Errorf("d1 = %+v, want 2 events, 1 jailbroken", d1)
+	}
+
+	if again := agg.Drain(); len(again) != 0 {
+		t.Errorf("Drain after Drain returned %d devices, want 0", len(again))
+	}
+}
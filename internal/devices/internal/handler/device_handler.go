@@ -0,0 +1,138 @@
+// Package handler provides HTTP handlers for the device registry lookup API.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/SebastienMelki/causality/internal/devices/internal/domain"
+)
+
+// Listing limits for GET /api/admin/devices/{app_id}.
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// DeviceReader reads device registry records.
+type DeviceReader interface {
+	Get(ctx context.Context, appID, deviceID string) (*domain.Device, error)
+	ListRisky(ctx context.Context, appID string, minScore float64, limit int) ([]domain.Device, error)
+}
+
+// DeviceHandler handles HTTP requests for device lookup.
+type DeviceHandler struct {
+	store  DeviceReader
+	logger *slog.Logger
+}
+
+// NewDeviceHandler creates a new DeviceHandler.
+func NewDeviceHandler(store DeviceReader, logger *slog.Logger) *DeviceHandler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &DeviceHandler{
+		store:  store,
+		logger: logger.With("component", "device-handler"),
+	}
+}
+
+// RegisterRoutes mounts the device lookup endpoints on the given ServeMux.
+//
+// Endpoints:
+//   - GET /api/admin/devices/{app_id}/{device_id}
+//   - GET /api/admin/devices/{app_id}?min_risk=&limit=
+//
+// TODO(phase-3): Protect these endpoints with session auth + RBAC.
+func (h *DeviceHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/admin/devices/{app_id}/{device_id}", h.handleGet)
+	mux.HandleFunc("GET /api/admin/devices/{app_id}", h.handleListRisky)
+}
+
+// handleGet handles GET /api/admin/devices/{app_id}/{device_id} - returns a
+// device's registry record.
+func (h *DeviceHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	appID := r.PathValue("app_id")
+	deviceID := r.PathValue("device_id")
+
+	device, err := h.store.Get(r.Context(), appID, deviceID)
+	if err != nil {
+		if errors.Is(err, domain.ErrDeviceNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{
+				"error": "device not found",
+			})
+			return
+		}
+		h.logger.Error("failed to get device",
+			"app_id", appID,
+			"device_id", deviceID,
+			"error", err,
+		)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to get device",
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, device)
+}
+
+// handleListRisky handles GET /api/admin/devices/{app_id} - lists an app's
+// devices with a risk score of at least min_risk (default 0), riskiest
+// first.
+func (h *DeviceHandler) handleListRisky(w http.ResponseWriter, r *http.Request) {
+	appID := r.PathValue("app_id")
+	q := r.URL.Query()
+
+	minRisk := 0.0
+	if v := q.Get("min_risk"); v != "" {
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "min_risk must be a number between 0 and 1",
+			})
+			return
+		}
+		minRisk = parsed
+	}
+
+	limit := defaultListLimit
+	if v := q.Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 || parsed > maxListLimit {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "limit must be between 1 and 1000",
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	devices, err := h.store.ListRisky(r.Context(), appID, minRisk, limit)
+	if err != nil {
+		h.logger.Error("failed to list devices",
+			"app_id", appID,
+			"error", err,
+		)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to list devices",
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"devices": devices,
+		"count":   len(devices),
+	})
+}
+
+// writeJSON writes a JSON response with the given status code and body.
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
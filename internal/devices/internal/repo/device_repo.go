@@ -0,0 +1,211 @@
+// Package repo provides the PostgreSQL implementation of the devices Store port.
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/devices/internal/domain"
+)
+
+// deviceColumns is the column list shared by the device queries, in the
+// order scanDevice reads it.
+const deviceColumns = `
+	app_id, device_id, platform, os_version, app_version, device_model,
+	jailbroken, emulator, ever_jailbroken, ever_emulator,
+	events, jailbroken_events, emulator_events, risk_score,
+	first_seen_at, last_seen_at`
+
+// DeviceRepository implements the Store interface using PostgreSQL.
+type DeviceRepository struct {
+	db *sql.DB
+}
+
+// NewDeviceRepository creates a new DeviceRepository backed by the given database.
+func NewDeviceRepository(db *sql.DB) *DeviceRepository {
+	return &DeviceRepository{db: db}
+}
+
+// MergeDevices atomically merges device records into device_registry. Each
+// stored row is locked and merged with domain.Device.Merge, so the decayed
+// counts and risk score are computed in one place.
+func (r *DeviceRepository) MergeDevices(ctx context.Context, devices map[domain.DeviceKey]domain.Device, halfLife time.Duration) error {
+	if len(devices) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	selectStmt, err := tx.PrepareContext(ctx, `
+		SELECT `+deviceColumns+`
+		FROM device_registry
+		WHERE app_id = $1 AND device_id = $2
+		FOR UPDATE
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare device select: %w", err)
+	}
+	defer func() { _ = selectStmt.Close() }()
+
+	upsertStmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO device_registry (`+deviceColumns+`, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, NOW())
+		ON CONFLICT (app_id, device_id) DO UPDATE
+		SET platform          = EXCLUDED.platform,
+		    os_version        = EXCLUDED.os_version,
+		    app_version       = EXCLUDED.app_version,
+		    device_model      = EXCLUDED.device_model,
+		    jailbroken        = EXCLUDED.jailbroken,
+		    emulator          = EXCLUDED.emulator,
+		    ever_jailbroken   = EXCLUDED.ever_jailbroken,
+		    ever_emulator     = EXCLUDED.ever_emulator,
+		    events            = EXCLUDED.events,
+		    jailbroken_events = EXCLUDED.jailbroken_events,
+		    emulator_events   = EXCLUDED.emulator_events,
+		    risk_score        = EXCLUDED.risk_score,
+		    first_seen_at     = EXCLUDED.first_seen_at,
+		    last_seen_at      = EXCLUDED.last_seen_at,
+		    updated_at        = NOW()
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare device upsert: %w", err)
+	}
+	defer func() { _ = upsertStmt.Close() }()
+
+	// Lock rows in key order so concurrent batches cannot deadlock.
+	keys := make([]domain.DeviceKey, 0, len(devices))
+	for key := range devices {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].AppID != keys[j].AppID {
+			return keys[i].AppID < keys[j].AppID
+		}
+		return keys[i].DeviceID < keys[j].DeviceID
+	})
+
+	for _, key := range keys {
+		d := devices[key]
+		stored, err := scanDevice(selectStmt.QueryRowContext(ctx, key.AppID, key.DeviceID))
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+		case err != nil:
+			return fmt.Errorf("failed to get device %s: %w", key.DeviceID, err)
+		default:
+			stored.Merge(d, halfLife)
+			d = *stored
+		}
+
+		if _, err := upsertStmt.ExecContext(ctx,
+			key.AppID,
+			key.DeviceID,
+			d.Platform,
+			d.OSVersion,
+			d.AppVersion,
+			d.DeviceModel,
+			d.Jailbroken,
+			d.Emulator,
+			d.EverJailbroken,
+			d.EverEmulator,
+			d.Events,
+			d.JailbrokenEvents,
+			d.EmulatorEvents,
+			d.RiskScore,
+			d.FirstSeenAt,
+			d.LastSeenAt,
+		); err != nil {
+			return fmt.Errorf("failed to upsert device %s: %w", key.DeviceID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit devices: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns a device's registry record, or domain.ErrDeviceNotFound.
+func (r *DeviceRepository) Get(ctx context.Context, appID, deviceID string) (*domain.Device, error) {
+	d, err := scanDevice(r.db.QueryRowContext(ctx, `
+		SELECT `+deviceColumns+`
+		FROM device_registry
+		WHERE app_id = $1 AND device_id = $2
+	`, appID, deviceID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrDeviceNotFound
+		}
+		return nil, fmt.Errorf("failed to get device: %w", err)
+	}
+	return d, nil
+}
+
+// ListRisky returns an app's devices with a risk score of at least minScore,
+// riskiest first.
+func (r *DeviceRepository) ListRisky(ctx context.Context, appID string, minScore float64, limit int) ([]domain.Device, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+deviceColumns+`
+		FROM device_registry
+		WHERE app_id = $1 AND risk_score >= $2
+		ORDER BY risk_score DESC, last_seen_at DESC
+		LIMIT $3
+	`, appID, minScore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+	defer rows.Close()
+
+	devices := []domain.Device{}
+	for rows.Next() {
+		d, err := scanDevice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan device: %w", err)
+		}
+		devices = append(devices, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate devices: %w", err)
+	}
+
+	return devices, nil
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanDevice scans a row selected with deviceColumns.
+func scanDevice(s scanner) (*domain.Device, error) {
+	var d domain.Device
+	if err := s.Scan(
+		&d.AppID,
+		&d.DeviceID,
+		&d.Platform,
+		&d.OSVersion,
+		&d.AppVersion,
+		&d.DeviceModel,
+		&d.Jailbroken,
+		&d.Emulator,
+		&d.EverJailbroken,
+		&d.EverEmulator,
+		&d.Events,
+		&d.JailbrokenEvents,
+		&d.EmulatorEvents,
+		&d.RiskScore,
+		&d.FirstSeenAt,
+		&d.LastSeenAt,
+	); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
@@ -0,0 +1,254 @@
+// Package service implements the device registry sink: a JetStream consumer
+// that merges each event's device context into per-device records with
+// rolling emulator and jailbreak risk scores.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/SebastienMelki/causality/internal/devices/internal/domain"
+	"github.com/SebastienMelki/causality/internal/events"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// DeviceStore defines the persistence interface needed by the extractor.
+// This mirrors the devices.Store port to avoid import cycles.
+type DeviceStore interface {
+	MergeDevices(ctx context.Context, devices map[domain.DeviceKey]domain.Device, halfLife time.Duration) error
+}
+
+// maxClockSkew bounds how far a client timestamp may lead the server's
+// receive time before the receive time is used instead.
+const maxClockSkew = 5 * time.Minute
+
+// Extractor consumes events from the event stream and maintains the device
+// registry. Each fetched batch is aggregated in memory, persisted in one
+// transaction, and only then acked (at-least-once: a crash between commit
+// and ack can count a single batch twice).
+type Extractor struct {
+	js             jetstream.JetStream
+	store          DeviceStore
+	streamName     string
+	consumerName   string
+	fetchBatchSize int
+	fetchMaxWait   time.Duration
+	halfLife       time.Duration
+	logger         *slog.Logger
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewExtractor creates a new device extractor for the given durable consumer.
+// Event counts are decayed with halfLife.
+func NewExtractor(
+	js jetstream.JetStream,
+	store DeviceStore,
+	streamName string,
+	consumerName string,
+	fetchBatchSize int,
+	fetchMaxWait time.Duration,
+	halfLife time.Duration,
+	logger *slog.Logger,
+) *Extractor {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if fetchBatchSize < 1 {
+		fetchBatchSize = 500
+	}
+	if fetchMaxWait <= 0 {
+		fetchMaxWait = 5 * time.Second
+	}
+
+	return &Extractor{
+		js:             js,
+		store:          store,
+		streamName:     streamName,
+		consumerName:   consumerName,
+		fetchBatchSize: fetchBatchSize,
+		fetchMaxWait:   fetchMaxWait,
+		halfLife:       halfLife,
+		logger:         logger.With("component", "device-extractor"),
+		stopCh:         make(chan struct{}),
+		doneCh:         make(chan struct{}),
+	}
+}
+
+// Start looks up the durable consumer and begins the fetch loop.
+func (e *Extractor) Start(ctx context.Context) error {
+	stream, err := e.js.Stream(ctx, e.streamName)
+	if err != nil {
+		return fmt.Errorf("failed to get stream: %w", err)
+	}
+
+	consumer, err := stream.Consumer(ctx, e.consumerName)
+	if err != nil {
+		return fmt.Errorf("failed to get consumer: %w", err)
+	}
+
+	e.logger.Info("starting device extractor",
+		"stream", e.streamName,
+		"consumer", e.consumerName,
+		"fetch_batch_size", e.fetchBatchSize,
+	)
+
+	go e.run(ctx, consumer)
+	return nil
+}
+
+// Stop signals the fetch loop to stop and waits for the in-flight batch.
+func (e *Extractor) Stop() {
+	e.stopOnce.Do(func() {
+		close(e.stopCh)
+	})
+	<-e.doneCh
+}
+
+// run is the main fetch loop.
+func (e *Extractor) run(ctx context.Context, consumer jetstream.Consumer) {
+	defer close(e.doneCh)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.stopCh:
+			return
+		default:
+		}
+
+		msgs, err := consumer.Fetch(e.fetchBatchSize, jetstream.FetchMaxWait(e.fetchMaxWait))
+		if err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) {
+				e.logger.Error("failed to fetch messages", "error", err)
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+					return
+				case <-e.stopCh:
+					return
+				}
+			}
+			continue
+		}
+
+		var batch []jetstream.Msg
+		for msg := range msgs.Messages() {
+			batch = append(batch, msg)
+		}
+		if err := msgs.Error(); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			e.logger.Warn("fetch completed with error", "error", err)
+		}
+
+		e.processBatch(ctx, batch)
+	}
+}
+
+// processBatch aggregates a fetched batch, persists it, and acks on success.
+// Unparseable messages are terminated so they are not redelivered.
+func (e *Extractor) processBatch(ctx context.Context, batch []jetstream.Msg) {
+	if len(batch) == 0 {
+		return
+	}
+
+	agg := domain.NewAggregator(e.halfLife)
+	counted := make([]jetstream.Msg, 0, len(batch))
+
+	for _, msg := range batch {
+		receivedAt := time.Now()
+		if meta, err := msg.Metadata(); err == nil {
+			receivedAt = meta.Timestamp
+		}
+
+		obs, err := observationFromMessage(msg.Data(), receivedAt)
+		if err != nil {
+			e.logger.Warn("terminating unparseable message",
+				"subject", msg.Subject(),
+				"error", err,
+			)
+			_ = msg.Term()
+			continue
+		}
+
+		agg.Add(obs)
+		counted = append(counted, msg)
+	}
+
+	if err := e.store.MergeDevices(ctx, agg.Drain(), e.halfLife); err != nil {
+		e.logger.Error("failed to persist devices, will redeliver",
+			"messages", len(counted),
+			"error", err,
+		)
+		for _, msg := range counted {
+			_ = msg.Nak()
+		}
+		return
+	}
+
+	for _, msg := range counted {
+		if err := msg.Ack(); err != nil {
+			e.logger.Warn("failed to ack message", "subject", msg.Subject(), "error", err)
+		}
+	}
+
+	e.logger.Debug("device batch recorded", "messages", len(counted))
+}
+
+// observationFromMessage extracts the device context of a serialized
+// EventEnvelope. The client timestamp is used unless it is missing or ahead
+// of receivedAt by more than maxClockSkew.
+func observationFromMessage(data []byte, receivedAt time.Time) (domain.Observation, error) {
+	var event pb.EventEnvelope
+	if err := events.Decode(data, &event); err != nil {
+		return domain.Observation{}, fmt.Errorf("unmarshal event: %w", err)
+	}
+	if event.GetAppId() == "" {
+		return domain.Observation{}, errors.New("event has no app_id")
+	}
+	if event.GetDeviceId() == "" {
+		return domain.Observation{}, errors.New("event has no device_id")
+	}
+
+	at := receivedAt
+	if ms := event.GetTimestampMs(); ms > 0 {
+		if ts := time.UnixMilli(ms); !ts.After(receivedAt.Add(maxClockSkew)) {
+			at = ts
+		}
+	}
+
+	dc := event.GetDeviceContext()
+	return domain.Observation{
+		AppID:       event.GetAppId(),
+		DeviceID:    event.GetDeviceId(),
+		At:          at.UTC(),
+		Platform:    platformOf(dc.GetPlatform()),
+		OSVersion:   dc.GetOsVersion(),
+		AppVersion:  dc.GetAppVersion(),
+		DeviceModel: dc.GetDeviceModel(),
+		Jailbroken:  dc.GetIsJailbroken(),
+		Emulator:    dc.GetIsEmulator(),
+	}, nil
+}
+
+// platformOf maps a protobuf platform to its registry name.
+func platformOf(p pb.Platform) string {
+	switch p {
+	case pb.Platform_PLATFORM_IOS:
+		return "ios"
+	case pb.Platform_PLATFORM_ANDROID:
+		return "android"
+	case pb.Platform_PLATFORM_WEB:
+		return "web"
+	default:
+		return "other"
+	}
+}
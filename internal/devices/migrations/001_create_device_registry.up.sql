@@ -0,0 +1,23 @@
+-- Per-device records with rolling emulator/jailbreak risk scores (device registry)
+CREATE TABLE IF NOT EXISTS device_registry (
+    app_id VARCHAR(255) NOT NULL,
+    device_id VARCHAR(255) NOT NULL,
+    platform VARCHAR(50) NOT NULL DEFAULT '',
+    os_version VARCHAR(100) NOT NULL DEFAULT '',
+    app_version VARCHAR(100) NOT NULL DEFAULT '',
+    device_model VARCHAR(255) NOT NULL DEFAULT '',
+    jailbroken BOOLEAN NOT NULL DEFAULT FALSE,
+    emulator BOOLEAN NOT NULL DEFAULT FALSE,
+    ever_jailbroken BOOLEAN NOT NULL DEFAULT FALSE,
+    ever_emulator BOOLEAN NOT NULL DEFAULT FALSE,
+    events DOUBLE PRECISION NOT NULL DEFAULT 0,
+    jailbroken_events DOUBLE PRECISION NOT NULL DEFAULT 0,
+    emulator_events DOUBLE PRECISION NOT NULL DEFAULT 0,
+    risk_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    first_seen_at TIMESTAMPTZ NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (app_id, device_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_registry_risk ON device_registry(app_id, risk_score DESC);
@@ -0,0 +1,100 @@
+package devices
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/SebastienMelki/causality/internal/db"
+	"github.com/SebastienMelki/causality/internal/devices/internal/handler"
+	"github.com/SebastienMelki/causality/internal/devices/internal/repo"
+	"github.com/SebastienMelki/causality/internal/devices/internal/service"
+	"github.com/SebastienMelki/causality/internal/devices/migrations"
+)
+
+// Migrations are the devices module's database migrations.
+var Migrations = db.Source{Name: "devices", FS: migrations.FS}
+
+// Config holds the devices module configuration.
+type Config struct {
+	// Enabled controls whether the device registry is maintained.
+	Enabled bool `env:"DEVICES_ENABLED" envDefault:"false"`
+
+	// ConsumerName is the durable JetStream consumer used for extraction.
+	ConsumerName string `env:"DEVICES_CONSUMER_NAME" envDefault:"device-registry"`
+
+	// FilterSubject selects which stream subjects feed the registry.
+	FilterSubject string `env:"DEVICES_FILTER_SUBJECT" envDefault:"events.>"`
+
+	// FetchBatchSize is the number of messages aggregated per transaction.
+	FetchBatchSize int `env:"DEVICES_FETCH_BATCH_SIZE" envDefault:"500"`
+
+	// FetchMaxWait bounds how long a fetch waits for a full batch.
+	FetchMaxWait time.Duration `env:"DEVICES_FETCH_MAX_WAIT" envDefault:"5s"`
+
+	// RiskHalfLife is the age at which an event counts half as much towards
+	// a device's risk score.
+	RiskHalfLife time.Duration `env:"DEVICES_RISK_HALF_LIFE" envDefault:"168h"`
+}
+
+// Module is the devices module facade. It wires the PostgreSQL repository,
+// stream extractor, and lookup API.
+type Module struct {
+	repo      *repo.DeviceRepository
+	extractor *service.Extractor
+	handler   *handler.DeviceHandler
+	config    Config
+	logger    *slog.Logger
+}
+
+// New creates a new devices Module consuming from streamName.
+func New(db *sql.DB, js jetstream.JetStream, streamName string, cfg Config, logger *slog.Logger) *Module {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	deviceRepo := repo.NewDeviceRepository(db)
+
+	return &Module{
+		repo: deviceRepo,
+		extractor: service.NewExtractor(
+			js,
+			deviceRepo,
+			streamName,
+			cfg.ConsumerName,
+			cfg.FetchBatchSize,
+			cfg.FetchMaxWait,
+			cfg.RiskHalfLife,
+			logger,
+		),
+		handler: handler.NewDeviceHandler(deviceRepo, logger),
+		config:  cfg,
+		logger:  logger.With("component", "devices-module"),
+	}
+}
+
+// Start begins device extraction.
+func (m *Module) Start(ctx context.Context) error {
+	return m.extractor.Start(ctx)
+}
+
+// Stop stops device extraction.
+func (m *Module) Stop() {
+	m.extractor.Stop()
+}
+
+// Lookup returns a device's registry record, or ErrDeviceNotFound.
+func (m *Module) Lookup(ctx context.Context, appID, deviceID string) (*Device, error) {
+	return m.repo.Get(ctx, appID, deviceID)
+}
+
+// RegisterRoutes mounts the device lookup endpoints onto the given ServeMux:
+//   - GET /api/admin/devices/{app_id}/{device_id} - Get a device's record
+//   - GET /api/admin/devices/{app_id}?min_risk=&limit= - List risky devices
+func (m *Module) RegisterRoutes(mux *http.ServeMux) {
+	m.handler.RegisterRoutes(mux)
+}
@@ -0,0 +1,38 @@
+// Package devices provides the device registry. It consumes events from the
+// JetStream event stream and keeps one record per device in PostgreSQL with
+// the device's latest platform, OS, app version and model, its jailbreak and
+// emulator flags, and a rolling risk score: the weighted share of its
+// recent events (exponentially decayed) sent from an emulator or a
+// jailbroken device. Records are served by a lookup API and read by the rule
+// engine for conditions with the "device" source.
+package devices
+
+import (
+	"context"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/devices/internal/domain"
+)
+
+// Device is a device registry record.
+type Device = domain.Device
+
+// DeviceKey identifies one device of one app.
+type DeviceKey = domain.DeviceKey
+
+// ErrDeviceNotFound is returned when a device is not in the registry.
+var ErrDeviceNotFound = domain.ErrDeviceNotFound
+
+// Store defines the port for device registry persistence.
+type Store interface {
+	// MergeDevices atomically merges accumulated device records, decaying
+	// event counts with halfLife.
+	MergeDevices(ctx context.Context, devices map[DeviceKey]Device, halfLife time.Duration) error
+
+	// Get returns a device's record, or ErrDeviceNotFound.
+	Get(ctx context.Context, appID, deviceID string) (*Device, error)
+
+	// ListRisky returns an app's devices with a risk score of at least
+	// minScore, riskiest first.
+	ListRisky(ctx context.Context, appID string, minScore float64, limit int) ([]Device, error)
+}
@@ -0,0 +1,247 @@
+// Package domain contains the core types for notification digests.
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Notification kinds, named after the derived subject family they are
+// consumed from.
+const (
+	KindAnomaly  = "anomaly"
+	KindReaction = "reaction"
+)
+
+// SeverityCritical is the severity of notifications sent immediately
+// instead of being digested.
+const SeverityCritical = "critical"
+
+// maxGroupSamples is the number of notifications kept per digest group.
+const maxGroupSamples = 3
+
+// Notification is an anomaly alert or rule match notification.
+type Notification struct {
+	Kind     string
+	AppID    string
+	Name     string
+	Severity string
+	At       time.Time
+	Details  json.RawMessage
+}
+
+// Critical reports whether n bypasses the digest.
+func (n Notification) Critical() bool {
+	return n.Severity == SeverityCritical
+}
+
+// Title returns the one-line subject of an immediately sent notification.
+func (n Notification) Title() string {
+	return fmt.Sprintf("[%s] %s %s on app %s", n.Severity, n.Kind, n.Name, n.AppID)
+}
+
+// Text renders n as a plain-text message.
+func (n Notification) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s at %s", n.Title(), n.At.UTC().Format(time.RFC3339))
+	if len(n.Details) > 0 {
+		fmt.Fprintf(&b, "\n%s", n.Details)
+	}
+	return b.String()
+}
+
+// Group summarizes the notifications of one kind and name in a digest.
+type Group struct {
+	Kind    string         `json:"kind"`
+	Name    string         `json:"name"`
+	Count   int            `json:"count"`
+	FirstAt time.Time      `json:"first_at"`
+	LastAt  time.Time      `json:"last_at"`
+	Samples []Notification `json:"-"`
+}
+
+// Digest summarizes an app's notifications over one window.
+type Digest struct {
+	AppID  string    `json:"app_id"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Total  int       `json:"total"`
+	Groups []*Group  `json:"groups"`
+}
+
+// add records n in the digest's group for its kind and name.
+func (d *Digest) add(n Notification) {
+	d.Total++
+	for _, g := range d.Groups {
+		if g.Kind == n.Kind && g.Name == n.Name {
+			g.Count++
+			if n.At.Before(g.FirstAt) {
+				g.FirstAt = n.At
+			}
+			if n.At.After(g.LastAt) {
+				g.LastAt = n.At
+			}
+			if len(g.Samples) < maxGroupSamples {
+				g.Samples = append(g.Samples, n)
+			}
+			return
+		}
+	}
+	d.Groups = append(d.Groups, &Group{
+		Kind:    n.Kind,
+		Name:    n.Name,
+		Count:   1,
+		FirstAt: n.At,
+		LastAt:  n.At,
+		Samples: []Notification{n},
+	})
+}
+
+// merge adds the notifications summarized by other into d.
+func (d *Digest) merge(other *Digest) {
+	if other.From.Before(d.From) {
+		d.From = other.From
+	}
+	d.Total += other.Total
+	for _, og := range other.Groups {
+		merged := false
+		for _, g := range d.Groups {
+			if g.Kind != og.Kind || g.Name != og.Name {
+				continue
+			}
+			g.Count += og.Count
+			if og.FirstAt.Before(g.FirstAt) {
+				g.FirstAt = og.FirstAt
+			}
+			if og.LastAt.After(g.LastAt) {
+				g.LastAt = og.LastAt
+			}
+			for _, s := range og.Samples {
+				if len(g.Samples) < maxGroupSamples {
+					g.Samples = append(g.Samples, s)
+				}
+			}
+			merged = true
+			break
+		}
+		if !merged {
+			d.Groups = append(d.Groups, og)
+		}
+	}
+}
+
+// Title returns the one-line subject of the digest.
+func (d *Digest) Title() string {
+	noun := "notifications"
+	if d.Total == 1 {
+		noun = "notification"
+	}
+	return fmt.Sprintf("Causality digest for app %s: %d %s", d.AppID, d.Total, noun)
+}
+
+// Text renders the digest as a plain-text message, busiest groups first.
+func (d *Digest) Text() string {
+	groups := make([]*Group, len(d.Groups))
+	copy(groups, d.Groups)
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Count > groups[j].Count })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s between %s and %s\n", d.Title(),
+		d.From.UTC().Format(time.RFC3339), d.To.UTC().Format(time.RFC3339))
+	for _, g := range groups {
+		fmt.Fprintf(&b, "\n• %s %s: %d (first %s, last %s)", g.Kind, g.Name, g.Count,
+			g.FirstAt.UTC().Format(time.TimeOnly), g.LastAt.UTC().Format(time.TimeOnly))
+		if len(g.Samples) > 0 && len(g.Samples[len(g.Samples)-1].Details) > 0 {
+			fmt.Fprintf(&b, "\n    latest: %s", g.Samples[len(g.Samples)-1].Details)
+		}
+	}
+	return b.String()
+}
+
+// Aggregator collects each app's notifications until its window elapses.
+// A window opens with the app's first notification after the previous
+// digest. It is not safe for concurrent use.
+type Aggregator struct {
+	window     time.Duration
+	appWindows map[string]time.Duration
+	pending    map[string]*Digest
+}
+
+// NewAggregator creates an Aggregator digesting over window, or over the
+// window appWindows sets for an app.
+func NewAggregator(window time.Duration, appWindows map[string]time.Duration) *Aggregator {
+	return &Aggregator{
+		window:     window,
+		appWindows: appWindows,
+		pending:    make(map[string]*Digest),
+	}
+}
+
+// Window returns the digest window of appID.
+func (a *Aggregator) Window(appID string) time.Duration {
+	if w, ok := a.appWindows[appID]; ok && w > 0 {
+		return w
+	}
+	return a.window
+}
+
+// Add records n, received at now, in its app's pending digest.
+func (a *Aggregator) Add(n Notification, now time.Time) {
+	d, ok := a.pending[n.AppID]
+	if !ok {
+		d = &Digest{AppID: n.AppID, From: now}
+		a.pending[n.AppID] = d
+	}
+	d.add(n)
+}
+
+// Due removes and returns the pending digests whose window has elapsed at
+// now, ordered by app.
+func (a *Aggregator) Due(now time.Time) []*Digest {
+	var due []*Digest
+	for appID, d := range a.pending {
+		if now.Sub(d.From) < a.Window(appID) {
+			continue
+		}
+		d.To = now
+		due = append(due, d)
+		delete(a.pending, appID)
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].AppID < due[j].AppID })
+	return due
+}
+
+// Drain removes and returns all pending digests, ordered by app.
+func (a *Aggregator) Drain(now time.Time) []*Digest {
+	digests := make([]*Digest, 0, len(a.pending))
+	for appID, d := range a.pending {
+		d.To = now
+		digests = append(digests, d)
+		delete(a.pending, appID)
+	}
+	sort.Slice(digests, func(i, j int) bool { return digests[i].AppID < digests[j].AppID })
+	return digests
+}
+
+// Restore returns a digest that could not be sent to the pending digests,
+// merging it with notifications received since.
+func (a *Aggregator) Restore(d *Digest) {
+	pending, ok := a.pending[d.AppID]
+	if !ok {
+		a.pending[d.AppID] = d
+		return
+	}
+	pending.merge(d)
+}
+
+// Pending returns the number of notifications awaiting a digest.
+func (a *Aggregator) Pending() int {
+	n := 0
+	for _, d := range a.pending {
+		n += d.Total
+	}
+	return n
+}
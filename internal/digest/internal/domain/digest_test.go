@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAggregator_Windows(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	agg := NewAggregator(15*time.Minute, map[string]time.Duration{"fast": time.Minute})
+
+	agg.Add(Notification{Kind: KindAnomaly, AppID: "shop", Name: "error-spike", At: start}, start)
+	agg.Add(Notification{Kind: KindAnomaly, AppID: "shop", Name: "error-spike", At: start.Add(time.Minute)}, start.Add(time.Minute))
+	agg.Add(Notification{Kind: KindReaction, AppID: "shop", Name: "checkout-failed", At: start.Add(2 * time.Minute)}, start.Add(2*time.Minute))
+	agg.Add(Notification{Kind: KindAnomaly, AppID: "fast", Name: "latency", At: start}, start)
+
+	due := agg.Due(start.Add(5 * time.Minute))
+	if len(due) != 1 || due[0].AppID != "fast" {
+		t.Fatalf("due after 5m = %+v, want only the app with a 1m window", due)
+	}
+
+	due = agg.Due(start.Add(15 * time.Minute))
+	if len(due) != 1 || due[0].AppID != "shop" {
+		t.Fatalf("due after 15m = %+v, want shop", due)
+	}
+	shop := due[0]
+	if shop.Total != 3 || len(shop.Groups) != 2 {
+		t.Fatalf("shop digest total = %d, groups = %d, want 3 and 2", shop.Total, len(shop.Groups))
+	}
+	if g := shop.Groups[0]; g.Count != 2 || !g.LastAt.Equal(start.Add(time.Minute)) {
+		t.Errorf("error-spike group = %+v, want 2 notifications", g)
+	}
+	if agg.Pending() != 0 {
+		t.Errorf("pending = %d after flushing, want 0", agg.Pending())
+	}
+}
+
+func TestAggregator_Restore(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	agg := NewAggregator(time.Minute, nil)
+
+	agg.Add(Notification{Kind: KindAnomaly, AppID: "shop", Name: "error-spike", At: start}, start)
+	failed := agg.Due(start.Add(time.Minute))[0]
+
+	later := start.Add(90 * time.Second)
+	agg.Add(Notification{Kind: KindAnomaly, AppID: "shop", Name: "error-spike", At: later}, later)
+	agg.Restore(failed)
+
+	due := agg.Due(start.Add(2 * time.Minute))
+	if len(due) != 1 {
+		t.Fatalf("due = %d digests, want the restored digest", len(due))
+	}
+	if d := due[0]; d.Total != 2 || !d.From.Equal(start) || len(d.Groups) != 1 || d.Groups[0].Count != 2 {
+		t.Errorf("restored digest = %+v, want both notifications from %v", d, start)
+	}
+}
+
+func TestDigest_Text(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	agg := NewAggregator(time.Minute, nil)
+	agg.Add(Notification{Kind: KindReaction, AppID: "shop", Name: "checkout-failed", At: start}, start)
+	for i := range 3 {
+		at := start.Add(time.Duration(i) * time.Second)
+		agg.Add(Notification{Kind: KindAnomaly, AppID: "shop", Name: "error-spike", At: at, Details: []byte(`{"rate":12}`)}, at)
+	}
+
+	text := agg.Drain(start.Add(time.Minute))[0].Text()
+	if !strings.HasPrefix(text, "Causality digest for app shop: 4 notifications") {
+		t.Errorf("text = %q, want the digest title first", text)
+	}
+	spike, failed := strings.Index(text, "anomaly error-spike: 3"), strings.Index(text, "reaction checkout-failed: 1")
+	if spike < 0 || failed < 0 || spike > failed {
+		t.Errorf("text = %q, want groups busiest first", text)
+	}
+	if !strings.Contains(text, `latest: {"rate":12}`) {
+		t.Errorf("text = %q, want the latest details", text)
+	}
+}
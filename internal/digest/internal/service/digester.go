@@ -0,0 +1,329 @@
+// Package service implements the notification digester: a JetStream
+// consumer that aggregates anomaly and rule match notifications per app and
+// sends them as periodic digests, and the Slack and email senders.
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/SebastienMelki/causality/internal/digest/internal/domain"
+	"github.com/SebastienMelki/causality/internal/nats"
+)
+
+// flushCheckInterval is how often pending digests are checked for an
+// elapsed window.
+const flushCheckInterval = 10 * time.Second
+
+// Digester consumes notifications from the derived stream. Critical
+// notifications are sent as they arrive and acked once a channel accepted
+// them. Others are acked on receipt and held in memory until their app's
+// window elapses, so pending digests are lost if the process crashes; they
+// are sent on a graceful stop.
+type Digester struct {
+	js             jetstream.JetStream
+	streamName     string
+	consumerName   string
+	fetchBatchSize int
+	fetchMaxWait   time.Duration
+	senders        []Sender
+	logger         *slog.Logger
+
+	mu  sync.Mutex
+	agg *domain.Aggregator
+	now func() time.Time
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewDigester creates a digester for the given durable consumer, sending
+// through senders.
+func NewDigester(
+	js jetstream.JetStream,
+	streamName string,
+	consumerName string,
+	fetchBatchSize int,
+	fetchMaxWait time.Duration,
+	agg *domain.Aggregator,
+	senders []Sender,
+	logger *slog.Logger,
+) *Digester {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if fetchBatchSize < 1 {
+		fetchBatchSize = 100
+	}
+	if fetchMaxWait <= 0 {
+		fetchMaxWait = 5 * time.Second
+	}
+
+	return &Digester{
+		js:             js,
+		streamName:     streamName,
+		consumerName:   consumerName,
+		fetchBatchSize: fetchBatchSize,
+		fetchMaxWait:   fetchMaxWait,
+		senders:        senders,
+		logger:         logger.With("component", "notification-digest"),
+		agg:            agg,
+		now:            time.Now,
+		stopCh:         make(chan struct{}),
+	}
+}
+
+// Start looks up the durable consumer and begins the fetch and flush loops.
+func (d *Digester) Start(ctx context.Context) error {
+	stream, err := d.js.Stream(ctx, d.streamName)
+	if err != nil {
+		return fmt.Errorf("failed to get stream: %w", err)
+	}
+
+	consumer, err := stream.Consumer(ctx, d.consumerName)
+	if err != nil {
+		return fmt.Errorf("failed to get consumer: %w", err)
+	}
+
+	d.logger.Info("starting notification digest",
+		"stream", d.streamName,
+		"consumer", d.consumerName,
+		"senders", len(d.senders),
+	)
+
+	d.wg.Add(2)
+	go d.run(ctx, consumer)
+	go d.flushLoop(ctx)
+	return nil
+}
+
+// Stop stops the loops and sends all pending digests, waiting up to timeout.
+func (d *Digester) Stop(timeout time.Duration) {
+	d.stopOnce.Do(func() {
+		close(d.stopCh)
+	})
+	d.wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	d.mu.Lock()
+	pending := d.agg.Drain(d.now())
+	d.mu.Unlock()
+	for _, digest := range pending {
+		if err := d.sendDigest(ctx, digest); err != nil {
+			d.logger.Error("dropping pending digest on shutdown",
+				"app_id", digest.AppID,
+				"notifications", digest.Total,
+				"error", err,
+			)
+		}
+	}
+}
+
+// run is the main fetch loop.
+func (d *Digester) run(ctx context.Context, consumer jetstream.Consumer) {
+	defer d.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.stopCh:
+			return
+		default:
+		}
+
+		msgs, err := consumer.Fetch(d.fetchBatchSize, jetstream.FetchMaxWait(d.fetchMaxWait))
+		if err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) {
+				d.logger.Error("failed to fetch messages", "error", err)
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+					return
+				case <-d.stopCh:
+					return
+				}
+			}
+			continue
+		}
+
+		for msg := range msgs.Messages() {
+			d.handleMessage(ctx, msg)
+		}
+		if err := msgs.Error(); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			d.logger.Warn("fetch completed with error", "error", err)
+		}
+	}
+}
+
+// handleMessage digests or immediately sends one notification.
+// Unparseable messages are terminated so they are not redelivered.
+func (d *Digester) handleMessage(ctx context.Context, msg jetstream.Msg) {
+	receivedAt := d.now()
+	if meta, err := msg.Metadata(); err == nil {
+		receivedAt = meta.Timestamp
+	}
+
+	n, err := ParseNotification(msg.Subject(), msg.Data(), receivedAt)
+	if err != nil {
+		d.logger.Warn("terminating unparseable notification",
+			"subject", msg.Subject(),
+			"error", err,
+		)
+		_ = msg.Term()
+		return
+	}
+
+	if n.Critical() {
+		if err := d.send(ctx, n.Title(), n.Text()); err != nil {
+			d.logger.Error("failed to send critical notification, will redeliver",
+				"app_id", n.AppID,
+				"name", n.Name,
+				"error", err,
+			)
+			_ = msg.Nak()
+			return
+		}
+		_ = msg.Ack()
+		return
+	}
+
+	d.mu.Lock()
+	d.agg.Add(n, d.now())
+	d.mu.Unlock()
+	if err := msg.Ack(); err != nil {
+		d.logger.Warn("failed to ack notification", "subject", msg.Subject(), "error", err)
+	}
+}
+
+// flushLoop periodically sends the digests whose window has elapsed.
+func (d *Digester) flushLoop(ctx context.Context) {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(flushCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.stopCh:
+			return
+		case <-ticker.C:
+			d.Flush(ctx)
+		}
+	}
+}
+
+// Flush sends the digests whose window has elapsed. A digest no channel
+// accepted is kept and retried on the next flush.
+func (d *Digester) Flush(ctx context.Context) {
+	d.mu.Lock()
+	due := d.agg.Due(d.now())
+	d.mu.Unlock()
+
+	for _, digest := range due {
+		if err := d.sendDigest(ctx, digest); err != nil {
+			d.logger.Error("failed to send digest, will retry",
+				"app_id", digest.AppID,
+				"notifications", digest.Total,
+				"error", err,
+			)
+			d.mu.Lock()
+			d.agg.Restore(digest)
+			d.mu.Unlock()
+		}
+	}
+}
+
+// sendDigest sends one app's digest.
+func (d *Digester) sendDigest(ctx context.Context, digest *domain.Digest) error {
+	if err := d.send(ctx, digest.Title(), digest.Text()); err != nil {
+		return err
+	}
+	d.logger.Info("digest sent",
+		"app_id", digest.AppID,
+		"notifications", digest.Total,
+		"groups", len(digest.Groups),
+	)
+	return nil
+}
+
+// send delivers a message through every sender. It fails only if no sender
+// accepted it, so a message is not repeated on channels that received it.
+func (d *Digester) send(ctx context.Context, subject, body string) error {
+	var errs []error
+	for _, s := range d.senders {
+		if err := s.Send(ctx, subject, body); err != nil {
+			d.logger.Warn("notification channel failed", "channel", s.Name(), "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name(), err))
+		}
+	}
+	if len(errs) == len(d.senders) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// notificationPayload is the subset of anomaly and rule match payloads used
+// in digests.
+type notificationPayload struct {
+	AnomalyConfigName string          `json:"anomaly_config_name"`
+	RuleName          string          `json:"rule_name"`
+	Severity          string          `json:"severity"`
+	Details           json.RawMessage `json:"details"`
+	EventID           string          `json:"event_id"`
+}
+
+// ParseNotification builds a notification from a derived stream message.
+// Anomalies are named after their config and rule matches after their rule,
+// falling back to the subject's name token. Only anomaly payloads carry a
+// severity; rule matches are always digested.
+func ParseNotification(subject string, data []byte, at time.Time) (domain.Notification, error) {
+	info, ok := nats.ParseDerivedSubject(subject)
+	if !ok {
+		return domain.Notification{}, fmt.Errorf("subject %q is not a derived subject", subject)
+	}
+
+	var payload notificationPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return domain.Notification{}, fmt.Errorf("unmarshal payload: %w", err)
+	}
+
+	n := domain.Notification{
+		AppID:    info.AppID,
+		Name:     info.Name,
+		Severity: payload.Severity,
+		At:       at,
+	}
+	switch info.Family {
+	case nats.FamilyAnomalies:
+		n.Kind = domain.KindAnomaly
+		if payload.AnomalyConfigName != "" {
+			n.Name = payload.AnomalyConfigName
+		}
+		n.Details = payload.Details
+	case nats.FamilyReactions:
+		n.Kind = domain.KindReaction
+		n.Severity = ""
+		if payload.RuleName != "" {
+			n.Name = payload.RuleName
+		}
+		if payload.EventID != "" {
+			n.Details, _ = json.Marshal(map[string]string{"event_id": payload.EventID})
+		}
+	default:
+		return domain.Notification{}, fmt.Errorf("subject family %q is not digested", info.Family)
+	}
+	return n, nil
+}
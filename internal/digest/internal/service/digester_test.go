@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/digest/internal/domain"
+)
+
+// fakeSender records messages, failing while err is set.
+type fakeSender struct {
+	name     string
+	err      error
+	subjects []string
+}
+
+func (f *fakeSender) Name() string { return f.name }
+
+func (f *fakeSender) Send(_ context.Context, subject, _ string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.subjects = append(f.subjects, subject)
+	return nil
+}
+
+func TestParseNotification(t *testing.T) {
+	at := time.Now()
+	tests := []struct {
+		name         string
+		subject      string
+		payload      string
+		wantKind     string
+		wantName     string
+		wantCritical bool
+		wantErr      bool
+	}{
+		{
+			name:         "critical anomaly",
+			subject:      "anomalies.shop.error_spike",
+			payload:      `{"anomaly_config_name":"error-spike","severity":"critical","details":{"rate":12}}`,
+			wantKind:     domain.KindAnomaly,
+			wantName:     "error-spike",
+			wantCritical: true,
+		},
+		{
+			name:     "compaction alert",
+			subject:  "anomalies.shop.compaction_failed",
+			payload:  `{"reason":"timeout"}`,
+			wantKind: domain.KindAnomaly,
+			wantName: "compaction_failed",
+		},
+		{
+			name:     "rule match ignores severity",
+			subject:  "reactions.shop.checkout",
+			payload:  `{"rule_name":"checkout-failed","severity":"critical","event_id":"e1"}`,
+			wantKind: domain.KindReaction,
+			wantName: "checkout-failed",
+		},
+		{name: "session", subject: "sessions.shop.started", payload: `{}`, wantErr: true},
+		{name: "not derived", subject: "events.shop.screen.view", payload: `{}`, wantErr: true},
+		{name: "invalid payload", subject: "anomalies.shop.x", payload: `not json`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := ParseNotification(tt.subject, []byte(tt.payload), at)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseNotification() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if n.Kind != tt.wantKind || n.Name != tt.wantName || n.AppID != "shop" || n.Critical() != tt.wantCritical {
+				t.Errorf("ParseNotification() = %+v, want %s %s critical=%v", n, tt.wantKind, tt.wantName, tt.wantCritical)
+			}
+		})
+	}
+}
+
+func TestDigester_Flush(t *testing.T) {
+	now := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	slack := &fakeSender{name: "slack", err: errors.New("down")}
+	email := &fakeSender{name: "email", err: errors.New("down")}
+	d := NewDigester(nil, "CAUSALITY_DERIVED", "notification-digest", 0, 0,
+		domain.NewAggregator(time.Minute, nil), []Sender{slack, email}, nil)
+	d.now = func() time.Time { return now }
+
+	d.agg.Add(domain.Notification{Kind: domain.KindAnomaly, AppID: "shop", Name: "error-spike", At: now}, now)
+	now = now.Add(time.Minute)
+
+	d.Flush(context.Background())
+	if d.agg.Pending() != 1 {
+		t.Fatalf("pending = %d after every channel failed, want the digest kept", d.agg.Pending())
+	}
+
+	email.err = nil
+	d.Flush(context.Background())
+	if d.agg.Pending() != 0 || len(email.subjects) != 1 {
+		t.Fatalf("pending = %d, emails = %d, want the digest sent once a channel accepts it", d.agg.Pending(), len(email.subjects))
+	}
+	if !strings.Contains(email.subjects[0], "app shop: 1 notification") {
+		t.Errorf("subject = %q", email.subjects[0])
+	}
+}
+
+func TestSlackSender(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &got)
+	}))
+	defer srv.Close()
+
+	if err := NewSlackSender(srv.URL, srv.Client()).Send(context.Background(), "Digest", "• anomaly x: 2"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got["text"] != "*Digest*\n• anomaly x: 2" {
+		t.Errorf("text = %q", got["text"])
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "no_service", http.StatusNotFound)
+	}))
+	defer failing.Close()
+	if err := NewSlackSender(failing.URL, failing.Client()).Send(context.Background(), "Digest", ""); err == nil {
+		t.Error("Send() to a failing webhook succeeded")
+	}
+}
+
+func TestEmailSender(t *testing.T) {
+	s := NewEmailSender("smtp.example.com:587", "user", "secret", "alerts@example.com", []string{"a@example.com", "b@example.com"}, time.Second)
+	var sent []byte
+	s.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		if addr != "smtp.example.com:587" || a == nil || len(to) != 2 {
+			t.Errorf("sendMail(%q, %v, %v)", addr, a, to)
+		}
+		sent = msg
+		return nil
+	}
+
+	if err := s.Send(context.Background(), "Digest\r\nBcc: x@example.com", "line 1\nline 2"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	msg := string(sent)
+	if !strings.Contains(msg, "Subject: Digest  Bcc: x@example.com\r\n") {
+		t.Errorf("message = %q, want line breaks stripped from the subject", msg)
+	}
+	if !strings.Contains(msg, "To: a@example.com, b@example.com\r\n") || !strings.HasSuffix(msg, "\r\n\r\nline 1\r\nline 2\r\n") {
+		t.Errorf("message = %q", msg)
+	}
+}
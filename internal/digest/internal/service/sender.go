@@ -0,0 +1,139 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Sender delivers a message to one notification channel.
+type Sender interface {
+	// Name identifies the channel in logs.
+	Name() string
+
+	// Send delivers a message with the given subject and plain-text body.
+	Send(ctx context.Context, subject, body string) error
+}
+
+// SlackSender posts messages to a Slack incoming webhook.
+type SlackSender struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewSlackSender creates a sender posting to the Slack incoming webhook at
+// webhookURL.
+func NewSlackSender(webhookURL string, client *http.Client) *SlackSender {
+	return &SlackSender{webhookURL: webhookURL, client: client}
+}
+
+// Name returns "slack".
+func (s *SlackSender) Name() string { return "slack" }
+
+// Send posts the subject in bold followed by the body.
+func (s *SlackSender) Send(ctx context.Context, subject, body string) error {
+	payload, err := json.Marshal(map[string]string{
+		"text": "*" + subject + "*\n" + body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal Slack message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create Slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("slack returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// EmailSender sends messages as plain-text email through an SMTP server.
+type EmailSender struct {
+	addr     string
+	auth     smtp.Auth
+	from     string
+	to       []string
+	timeout  time.Duration
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewEmailSender creates a sender mailing from from to the to addresses
+// through the SMTP server at addr (host:port). PLAIN authentication is used
+// when username is set.
+func NewEmailSender(addr, username, password, from string, to []string, timeout time.Duration) *EmailSender {
+	var auth smtp.Auth
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &EmailSender{
+		addr:     addr,
+		auth:     auth,
+		from:     from,
+		to:       to,
+		timeout:  timeout,
+		sendMail: smtp.SendMail,
+	}
+}
+
+// Name returns "email".
+func (s *EmailSender) Name() string { return "email" }
+
+// Send mails the message. net/smtp does not take a context, so the send is
+// abandoned, not interrupted, when ctx or the sender's timeout ends first.
+func (s *EmailSender) Send(ctx context.Context, subject, body string) error {
+	msg := buildEmail(s.from, s.to, subject, body)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.sendMail(s.addr, s.auth, s.from, s.to, msg) }()
+
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return fmt.Errorf("failed to send email: %w", err)
+		}
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("failed to send email: timed out after %s", s.timeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// buildEmail formats a plain-text RFC 5322 message. Line breaks in the
+// subject are replaced so it cannot inject headers.
+func buildEmail(from string, to []string, subject, body string) []byte {
+	subject = strings.NewReplacer("\r", " ", "\n", " ").Replace(subject)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
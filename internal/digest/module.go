@@ -0,0 +1,143 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/SebastienMelki/causality/internal/digest/internal/domain"
+	"github.com/SebastienMelki/causality/internal/digest/internal/service"
+	"github.com/SebastienMelki/causality/internal/nats"
+)
+
+// Config holds the digest module configuration.
+type Config struct {
+	// Enabled controls whether notifications are digested and sent.
+	Enabled bool `env:"DIGEST_ENABLED" envDefault:"false"`
+
+	// ConsumerName is the durable consumer on the derived stream.
+	ConsumerName string `env:"DIGEST_CONSUMER_NAME" envDefault:"notification-digest"`
+
+	// FetchBatchSize is the number of notifications fetched at once.
+	FetchBatchSize int `env:"DIGEST_FETCH_BATCH_SIZE" envDefault:"100"`
+
+	// FetchMaxWait bounds how long a fetch waits for a full batch.
+	FetchMaxWait time.Duration `env:"DIGEST_FETCH_MAX_WAIT" envDefault:"5s"`
+
+	// Window is how long an app's notifications are collected before its
+	// digest is sent. The window opens with the first notification.
+	Window time.Duration `env:"DIGEST_WINDOW" envDefault:"15m"`
+
+	// AppWindows overrides Window per app (app_id:duration,...).
+	AppWindows map[string]time.Duration `env:"DIGEST_APP_WINDOWS"`
+
+	// SendTimeout bounds each Slack request or email.
+	SendTimeout time.Duration `env:"DIGEST_SEND_TIMEOUT" envDefault:"10s"`
+
+	// SlackWebhookURL is a Slack incoming webhook. Slack is disabled when
+	// empty.
+	SlackWebhookURL string `env:"DIGEST_SLACK_WEBHOOK_URL"`
+
+	// SMTPAddr is the SMTP server (host:port) sending email digests.
+	SMTPAddr string `env:"DIGEST_SMTP_ADDR" envDefault:"localhost:25"`
+
+	// SMTPUsername and SMTPPassword authenticate to the SMTP server with
+	// PLAIN auth when the username is set.
+	SMTPUsername string `env:"DIGEST_SMTP_USERNAME"`
+	SMTPPassword string `env:"DIGEST_SMTP_PASSWORD"`
+
+	// EmailFrom is the sender address of email digests.
+	EmailFrom string `env:"DIGEST_EMAIL_FROM" envDefault:"causality@localhost"`
+
+	// EmailTo lists the recipients of email digests. Email is disabled when
+	// empty.
+	EmailTo []string `env:"DIGEST_EMAIL_TO" envSeparator:","`
+}
+
+// ConsumerConfig returns the derived stream consumer the module reads from.
+func (c Config) ConsumerConfig() nats.ConsumerConfig {
+	return nats.ConsumerConfig{
+		Name:           c.ConsumerName,
+		FilterSubjects: []string{nats.FamilyAnomalies + ".>", nats.FamilyReactions + ".>"},
+		AckWait:        30 * time.Second,
+		MaxAckPending:  1000,
+		MaxDeliver:     5,
+	}
+}
+
+// Module is the digest module facade. It wires the derived stream digester
+// and the configured Slack and email senders.
+type Module struct {
+	digester *service.Digester
+	config   Config
+	logger   *slog.Logger
+}
+
+// New creates a new digest Module consuming from the derived stream
+// streamName. It fails with ErrNoChannels when neither Slack nor email is
+// configured.
+func New(js jetstream.JetStream, streamName string, cfg Config, logger *slog.Logger) (*Module, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.SendTimeout <= 0 {
+		cfg.SendTimeout = 10 * time.Second
+	}
+	if cfg.Window <= 0 {
+		return nil, fmt.Errorf("invalid digest window %s", cfg.Window)
+	}
+
+	var senders []service.Sender
+	if cfg.SlackWebhookURL != "" {
+		senders = append(senders, service.NewSlackSender(cfg.SlackWebhookURL, &http.Client{Timeout: cfg.SendTimeout}))
+	}
+	if len(cfg.EmailTo) > 0 {
+		senders = append(senders, service.NewEmailSender(
+			cfg.SMTPAddr,
+			cfg.SMTPUsername,
+			cfg.SMTPPassword,
+			cfg.EmailFrom,
+			cfg.EmailTo,
+			cfg.SendTimeout,
+		))
+	}
+	if len(senders) == 0 {
+		return nil, ErrNoChannels
+	}
+
+	return &Module{
+		digester: service.NewDigester(
+			js,
+			streamName,
+			cfg.ConsumerName,
+			cfg.FetchBatchSize,
+			cfg.FetchMaxWait,
+			domain.NewAggregator(cfg.Window, cfg.AppWindows),
+			senders,
+			logger,
+		),
+		config: cfg,
+		logger: logger.With("component", "digest-module"),
+	}, nil
+}
+
+// Start begins consuming notifications.
+func (m *Module) Start(ctx context.Context) error {
+	m.logger.Info("notification digest configured",
+		"window", m.config.Window,
+		"app_windows", len(m.config.AppWindows),
+		"slack", m.config.SlackWebhookURL != "",
+		"email", len(m.config.EmailTo) > 0,
+	)
+	return m.digester.Start(ctx)
+}
+
+// Stop stops consuming and sends the pending digests, waiting up to
+// timeout.
+func (m *Module) Stop(timeout time.Duration) {
+	m.digester.Stop(timeout)
+}
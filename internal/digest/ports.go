@@ -0,0 +1,23 @@
+// Package digest provides notification digests. It consumes anomaly alerts
+// and rule match notifications from the derived stream (anomalies.> and
+// reactions.>), aggregates them per app over a configurable window and
+// sends one summarized digest per app and window to Slack and/or email.
+// Alerts of anomaly configs with severity critical skip the digest and are
+// sent as they arrive.
+package digest
+
+import (
+	"errors"
+
+	"github.com/SebastienMelki/causality/internal/digest/internal/domain"
+)
+
+// Notification is an anomaly alert or rule match notification.
+type Notification = domain.Notification
+
+// Digest summarizes an app's notifications over one window.
+type Digest = domain.Digest
+
+// ErrNoChannels indicates digests are enabled without a Slack webhook or
+// email recipients to send them to.
+var ErrNoChannels = errors.New("notification digest needs a Slack webhook URL or email recipients")
@@ -153,11 +153,11 @@ func (s *DLQService) handleAdvisory(ctx context.Context) nats.MsgHandler {
 				headers[k] = v
 			}
 		}
-		headers.Set("X-DLQ-Original-Subject", rawMsg.Subject)
-		headers.Set("X-DLQ-Original-Stream", advisory.Stream)
-		headers.Set("X-DLQ-Original-Consumer", advisory.Consumer)
-		headers.Set("X-DLQ-Original-Sequence", fmt.Sprintf("%d", advisory.StreamSeq))
-		headers.Set("X-DLQ-Deliveries", fmt.Sprintf("%d", advisory.Deliveries))
+		headers.Set(headerOriginalSubject, rawMsg.Subject)
+		headers.Set(headerOriginalStream, advisory.Stream)
+		headers.Set(headerOriginalConsumer, advisory.Consumer)
+		headers.Set(headerOriginalSequence, fmt.Sprintf("%d", advisory.StreamSeq))
+		headers.Set(headerDeliveries, fmt.Sprintf("%d", advisory.Deliveries))
 
 		pubMsg := &nats.Msg{
 			Subject: dlqSubject,
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// DLQ message headers set when a message is moved to the DLQ.
+const (
+	headerOriginalSubject  = "X-DLQ-Original-Subject"
+	headerOriginalStream   = "X-DLQ-Original-Stream"
+	headerOriginalConsumer = "X-DLQ-Original-Consumer"
+	headerOriginalSequence = "X-DLQ-Original-Sequence"
+	headerDeliveries       = "X-DLQ-Deliveries"
+	headerPrefix           = "X-DLQ-"
+)
+
+// ErrNotDLQMessage is returned when replaying a message that lacks the
+// original subject header.
+var ErrNotDLQMessage = errors.New("message has no original subject")
+
+// Message describes a message held in the DLQ stream.
+type Message struct {
+	// Sequence is the message's sequence in the DLQ stream.
+	Sequence uint64 `json:"sequence"`
+
+	// Subject is the original subject the message was published to.
+	Subject string `json:"subject"`
+
+	// Consumer is the consumer that exhausted its delivery attempts.
+	Consumer string `json:"consumer"`
+
+	// OriginalSequence is the message's sequence in the main stream.
+	OriginalSequence uint64 `json:"original_sequence"`
+
+	// Deliveries is the number of delivery attempts before dead-lettering.
+	Deliveries uint64 `json:"deliveries"`
+
+	// Size is the payload size in bytes.
+	Size int `json:"size"`
+
+	// Time is when the message was moved to the DLQ.
+	Time time.Time `json:"time"`
+}
+
+// ListMessages returns up to limit messages from the DLQ stream, oldest
+// first.
+func (s *DLQService) ListMessages(ctx context.Context, dlqStreamName string, limit int) ([]Message, error) {
+	stream, err := s.js.Stream(ctx, dlqStreamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DLQ stream: %w", err)
+	}
+
+	info, err := stream.Info(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DLQ stream info: %w", err)
+	}
+
+	var messages []Message
+	for seq := info.State.FirstSeq; seq <= info.State.LastSeq && len(messages) < limit; seq++ {
+		raw, err := stream.GetMsg(ctx, seq)
+		if errors.Is(err, jetstream.ErrMsgNotFound) {
+			// Deleted after replay
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get DLQ message %d: %w", seq, err)
+		}
+		messages = append(messages, messageFromRaw(raw))
+	}
+
+	return messages, nil
+}
+
+// Replay republishes a DLQ message to its original subject, where the
+// consumers pick it up again, and removes it from the DLQ stream.
+func (s *DLQService) Replay(ctx context.Context, dlqStreamName string, seq uint64) error {
+	stream, err := s.js.Stream(ctx, dlqStreamName)
+	if err != nil {
+		return fmt.Errorf("failed to get DLQ stream: %w", err)
+	}
+
+	raw, err := stream.GetMsg(ctx, seq)
+	if err != nil {
+		return fmt.Errorf("failed to get DLQ message %d: %w", seq, err)
+	}
+
+	subject := raw.Header.Get(headerOriginalSubject)
+	if subject == "" {
+		return fmt.Errorf("%w: DLQ message %d", ErrNotDLQMessage, seq)
+	}
+
+	// Restore the original headers. The message ID is dropped so that the
+	// stream's duplicate window does not discard the replay.
+	headers := nats.Header{}
+	for k, v := range raw.Header {
+		if strings.HasPrefix(k, headerPrefix) || k == jetstream.MsgIDHeader {
+			continue
+		}
+		headers[k] = v
+	}
+
+	if _, err := s.js.PublishMsg(ctx, &nats.Msg{Subject: subject, Data: raw.Data, Header: headers}); err != nil {
+		return fmt.Errorf("failed to republish DLQ message %d: %w", seq, err)
+	}
+
+	if err := stream.DeleteMsg(ctx, seq); err != nil {
+		return fmt.Errorf("failed to delete replayed DLQ message %d: %w", seq, err)
+	}
+
+	s.logger.Info("replayed DLQ message",
+		"dlq_seq", seq,
+		"subject", subject,
+	)
+	return nil
+}
+
+// messageFromRaw describes a raw DLQ stream message.
+func messageFromRaw(raw *jetstream.RawStreamMsg) Message {
+	originalSeq, _ := strconv.ParseUint(raw.Header.Get(headerOriginalSequence), 10, 64)
+	deliveries, _ := strconv.ParseUint(raw.Header.Get(headerDeliveries), 10, 64)
+	return Message{
+		Sequence:         raw.Sequence,
+		Subject:          raw.Header.Get(headerOriginalSubject),
+		Consumer:         raw.Header.Get(headerOriginalConsumer),
+		OriginalSequence: originalSeq,
+		Deliveries:       deliveries,
+		Size:             len(raw.Data),
+		Time:             raw.Time,
+	}
+}
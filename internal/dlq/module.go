@@ -82,6 +82,24 @@ func (m *Module) GetDLQCount(ctx context.Context) (int64, error) {
 	return m.service.GetDLQCount(ctx, m.dlqStreamName)
 }
 
+// Message describes a message held in the DLQ stream.
+type Message = service.Message
+
+// ErrNotDLQMessage is returned when replaying a message that lacks the
+// original subject header.
+var ErrNotDLQMessage = service.ErrNotDLQMessage
+
+// ListMessages returns up to limit messages from the DLQ stream, oldest first.
+func (m *Module) ListMessages(ctx context.Context, limit int) ([]Message, error) {
+	return m.service.ListMessages(ctx, m.dlqStreamName, limit)
+}
+
+// Replay republishes the DLQ message with the given DLQ stream sequence to
+// its original subject and removes it from the DLQ stream.
+func (m *Module) Replay(ctx context.Context, seq uint64) error {
+	return m.service.Replay(ctx, m.dlqStreamName, seq)
+}
+
 // AlertThreshold returns the configured alert threshold.
 func (m *Module) AlertThreshold() int64 {
 	return m.config.AlertThreshold
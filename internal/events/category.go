@@ -15,6 +15,7 @@ const (
 	CategoryInteraction = "interaction"
 	CategoryCommerce    = "commerce"
 	CategorySystem      = "system"
+	CategoryExperiment  = "experiment"
 	CategoryCustom      = "custom"
 	CategoryUnknown     = "unknown"
 
@@ -89,6 +90,12 @@ func GetCategoryAndType(event *pb.EventEnvelope) (category, eventType string) {
 		return CategorySystem, "memory_warning"
 	case *pb.EventEnvelope_BatteryChange:
 		return CategorySystem, "battery_change"
+	case *pb.EventEnvelope_PushTokenUpdate:
+		return CategorySystem, "push_token_update"
+
+	// Experiment events.
+	case *pb.EventEnvelope_ExperimentExposure:
+		return CategoryExperiment, "exposure"
 
 	// Custom events.
 	case *pb.EventEnvelope_CustomEvent:
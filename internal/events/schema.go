@@ -0,0 +1,78 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// Envelope schema versions.
+const (
+	// SchemaVersionLegacy is the envelope produced by clients predating schema
+	// versioning, which leave schema_version unset.
+	SchemaVersionLegacy uint32 = 1
+
+	// CurrentSchemaVersion is the envelope version consumers process.
+	CurrentSchemaVersion uint32 = 2
+)
+
+// ErrUnsupportedSchemaVersion is returned for envelopes newer than
+// CurrentSchemaVersion.
+var ErrUnsupportedSchemaVersion = errors.New("unsupported schema version")
+
+// Translator upgrades an envelope in place from one schema version to the
+// next.
+type Translator func(event *pb.EventEnvelope)
+
+// translators maps a schema version to the translator upgrading it to the
+// following version. Every version below CurrentSchemaVersion must have one.
+var translators = map[uint32]Translator{
+	// Version 2 only introduced schema_version itself.
+	SchemaVersionLegacy: func(*pb.EventEnvelope) {},
+}
+
+// SchemaVersion returns the schema version of an envelope, treating an unset
+// version as SchemaVersionLegacy.
+func SchemaVersion(event *pb.EventEnvelope) uint32 {
+	if v := event.GetSchemaVersion(); v != 0 {
+		return v
+	}
+	return SchemaVersionLegacy
+}
+
+// CheckSchemaVersion returns an error wrapping ErrUnsupportedSchemaVersion if
+// the envelope is newer than CurrentSchemaVersion.
+func CheckSchemaVersion(event *pb.EventEnvelope) error {
+	if v := SchemaVersion(event); v > CurrentSchemaVersion {
+		return fmt.Errorf("%w: %d, supported %d-%d", ErrUnsupportedSchemaVersion, v, SchemaVersionLegacy, CurrentSchemaVersion)
+	}
+	return nil
+}
+
+// Upgrade translates an envelope in place to CurrentSchemaVersion. Envelopes
+// newer than CurrentSchemaVersion are left unchanged; fields this build does
+// not know are preserved by protobuf as unknown fields.
+func Upgrade(event *pb.EventEnvelope) {
+	v := SchemaVersion(event)
+	if v > CurrentSchemaVersion {
+		return
+	}
+	for ; v < CurrentSchemaVersion; v++ {
+		translators[v](event)
+	}
+	event.SchemaVersion = CurrentSchemaVersion
+}
+
+// Decode unmarshals a serialized envelope and upgrades it to
+// CurrentSchemaVersion. Consumers use it instead of proto.Unmarshal so that
+// events from older clients are processed in the current shape.
+func Decode(data []byte, event *pb.EventEnvelope) error {
+	if err := proto.Unmarshal(data, event); err != nil {
+		return err
+	}
+	Upgrade(event)
+	return nil
+}
@@ -0,0 +1,64 @@
+package events
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// TestTranslators_Complete verifies every version below the current one can
+// be upgraded.
+func TestTranslators_Complete(t *testing.T) {
+	for v := SchemaVersionLegacy; v < CurrentSchemaVersion; v++ {
+		if translators[v] == nil {
+			t.Errorf("no translator from schema version %d", v)
+		}
+	}
+}
+
+// TestDecode_UpgradesLegacy verifies an envelope without schema_version is
+// decoded as the current version with its content intact.
+func TestDecode_UpgradesLegacy(t *testing.T) {
+	legacy := &pb.EventEnvelope{
+		Id:          "evt-1",
+		AppId:       "demo",
+		TimestampMs: 1700000000000,
+		Payload:     &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}},
+	}
+	data, err := proto.Marshal(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var event pb.EventEnvelope
+	if err := Decode(data, &event); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if event.GetSchemaVersion() != CurrentSchemaVersion {
+		t.Errorf("SchemaVersion = %d, want %d", event.GetSchemaVersion(), CurrentSchemaVersion)
+	}
+
+	legacy.SchemaVersion = CurrentSchemaVersion
+	if !proto.Equal(&event, legacy) {
+		t.Errorf("Decode() = %v, want %v", &event, legacy)
+	}
+}
+
+// TestUpgrade_Newer verifies envelopes from newer clients are left unchanged
+// and rejected by CheckSchemaVersion.
+func TestUpgrade_Newer(t *testing.T) {
+	event := &pb.EventEnvelope{SchemaVersion: CurrentSchemaVersion + 1}
+	Upgrade(event)
+	if event.GetSchemaVersion() != CurrentSchemaVersion+1 {
+		t.Errorf("SchemaVersion = %d, want %d", event.GetSchemaVersion(), CurrentSchemaVersion+1)
+	}
+	if err := CheckSchemaVersion(event); !errors.Is(err, ErrUnsupportedSchemaVersion) {
+		t.Errorf("CheckSchemaVersion() error = %v, want %v", err, ErrUnsupportedSchemaVersion)
+	}
+	if err := CheckSchemaVersion(&pb.EventEnvelope{}); err != nil {
+		t.Errorf("CheckSchemaVersion(legacy) error = %v, want nil", err)
+	}
+}
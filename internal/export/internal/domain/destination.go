@@ -0,0 +1,203 @@
+// Package domain contains the export destination model: the external
+// warehouses partitions are loaded into, their schema mapping, and per-app
+// export progress.
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Destination types.
+const (
+	TypeBigQuery  = "bigquery"
+	TypeSnowflake = "snowflake"
+)
+
+// ErrInvalidDestination is returned when a destination fails validation.
+var ErrInvalidDestination = errors.New("invalid export destination")
+
+// identifier matches the unquoted SQL identifiers accepted in destination
+// tables and column mappings, optionally qualified with dots.
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*(\.[A-Za-z_][A-Za-z0-9_$]*)*$`)
+
+// Destinations is the export destinations file.
+type Destinations struct {
+	Destinations []Destination `json:"destinations"`
+}
+
+// Destination is an external warehouse the event lake is exported to.
+type Destination struct {
+	// Name identifies the destination in watermarks, logs and load job IDs.
+	Name string `json:"name"`
+
+	// Type is TypeBigQuery or TypeSnowflake.
+	Type string `json:"type"`
+
+	// Apps restricts the export to these app IDs. Empty exports every app.
+	Apps []string `json:"apps,omitempty"`
+
+	// Columns maps event columns to destination columns. Unmapped columns
+	// are not exported. Empty exports every column under its own name.
+	Columns []ColumnMapping `json:"columns,omitempty"`
+
+	// BigQuery holds the settings of TypeBigQuery destinations.
+	BigQuery *BigQueryConfig `json:"bigquery,omitempty"`
+
+	// Snowflake holds the settings of TypeSnowflake destinations.
+	Snowflake *SnowflakeConfig `json:"snowflake,omitempty"`
+}
+
+// ColumnMapping exports the event column Source as the destination column
+// Target.
+type ColumnMapping struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// BigQueryConfig holds the settings of a BigQuery destination. Partitions
+// are loaded with load jobs uploading newline-delimited JSON.
+type BigQueryConfig struct {
+	// Project, Dataset and Table name the destination table.
+	Project string `json:"project"`
+	Dataset string `json:"dataset"`
+	Table   string `json:"table"`
+
+	// Location is the dataset location (e.g. "US", "europe-west1").
+	Location string `json:"location,omitempty"`
+
+	// CredentialsFile is a service account JSON key with BigQuery job and
+	// table write permissions.
+	CredentialsFile string `json:"credentials_file"`
+
+	// APIURL overrides the BigQuery API base URL.
+	APIURL string `json:"api_url,omitempty"`
+}
+
+// SnowflakeConfig holds the settings of a Snowflake destination. Partitions
+// are loaded with COPY INTO from an external stage over the event bucket,
+// through the SQL API with key-pair authentication.
+type SnowflakeConfig struct {
+	// Account is the account identifier (e.g. "myorg-myaccount").
+	Account string `json:"account"`
+
+	// User is the user whose public key is registered for key-pair
+	// authentication.
+	User string `json:"user"`
+
+	// PrivateKeyFile is the user's unencrypted PKCS#8 PEM private key.
+	PrivateKeyFile string `json:"private_key_file"`
+
+	// Role and Warehouse run the COPY statements.
+	Role      string `json:"role,omitempty"`
+	Warehouse string `json:"warehouse"`
+
+	// Database, Schema and Table name the destination table.
+	Database string `json:"database"`
+	Schema   string `json:"schema"`
+	Table    string `json:"table"`
+
+	// Stage is the external stage whose URL points at the event bucket.
+	Stage string `json:"stage"`
+
+	// StagePrefix is the key prefix the stage URL points at within the
+	// bucket, removed from file keys. Empty if it is the bucket root.
+	StagePrefix string `json:"stage_prefix,omitempty"`
+
+	// APIURL overrides the SQL API base URL
+	// (https://{account}.snowflakecomputing.com).
+	APIURL string `json:"api_url,omitempty"`
+}
+
+// Validate checks a destination's name, type settings and column mapping.
+func (d *Destination) Validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidDestination)
+	}
+
+	switch d.Type {
+	case TypeBigQuery:
+		if err := d.BigQuery.validate(); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidDestination, d.Name, err)
+		}
+	case TypeSnowflake:
+		if err := d.Snowflake.validate(); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidDestination, d.Name, err)
+		}
+	default:
+		return fmt.Errorf("%w: %s: unknown type %q", ErrInvalidDestination, d.Name, d.Type)
+	}
+
+	targets := make(map[string]bool, len(d.Columns))
+	for _, c := range d.Columns {
+		if !identifier.MatchString(c.Source) || !identifier.MatchString(c.Target) {
+			return fmt.Errorf("%w: %s: invalid column mapping %q -> %q", ErrInvalidDestination, d.Name, c.Source, c.Target)
+		}
+		if targets[c.Target] {
+			return fmt.Errorf("%w: %s: column %q is mapped twice", ErrInvalidDestination, d.Name, c.Target)
+		}
+		targets[c.Target] = true
+	}
+
+	return nil
+}
+
+// Includes reports whether the destination exports appID.
+func (d *Destination) Includes(appID string) bool {
+	if len(d.Apps) == 0 {
+		return true
+	}
+	for _, app := range d.Apps {
+		if app == appID {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the settings of a BigQuery destination.
+func (c *BigQueryConfig) validate() error {
+	if c == nil {
+		return errors.New("bigquery settings are required")
+	}
+	if c.Project == "" || c.Dataset == "" || c.Table == "" {
+		return errors.New("bigquery project, dataset and table are required")
+	}
+	if c.CredentialsFile == "" {
+		return errors.New("bigquery credentials_file is required")
+	}
+	return nil
+}
+
+// validate checks the settings of a Snowflake destination.
+func (c *SnowflakeConfig) validate() error {
+	if c == nil {
+		return errors.New("snowflake settings are required")
+	}
+	if c.Account == "" || c.User == "" || c.PrivateKeyFile == "" {
+		return errors.New("snowflake account, user and private_key_file are required")
+	}
+	if c.Warehouse == "" || c.Database == "" || c.Schema == "" {
+		return errors.New("snowflake warehouse, database and schema are required")
+	}
+	if !identifier.MatchString(c.Table) {
+		return fmt.Errorf("snowflake table %q is not an identifier", c.Table)
+	}
+	if !identifier.MatchString(c.Stage) {
+		return fmt.Errorf("snowflake stage %q is not an identifier", c.Stage)
+	}
+	return nil
+}
+
+// Watermark is the export progress of one app to one destination: every
+// partition window before ExportedUntil is loaded.
+type Watermark struct {
+	ExportedUntil time.Time
+
+	// Attempts counts the failed loads of the window starting at
+	// ExportedUntil, and LastError is the error of the latest one.
+	Attempts  int
+	LastError string
+}
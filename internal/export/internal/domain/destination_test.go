@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDestination_Validate(t *testing.T) {
+	bigQuery := func() Destination {
+		return Destination{
+			Name: "bq",
+			Type: TypeBigQuery,
+			BigQuery: &BigQueryConfig{
+				Project:         "p",
+				Dataset:         "analytics",
+				Table:           "events",
+				CredentialsFile: "/secrets/bq.json",
+			},
+		}
+	}
+	snowflake := func() Destination {
+		return Destination{
+			Name: "sf",
+			Type: TypeSnowflake,
+			Snowflake: &SnowflakeConfig{
+				Account:        "org-acct",
+				User:           "LOADER",
+				PrivateKeyFile: "/secrets/sf.pem",
+				Warehouse:      "LOAD_WH",
+				Database:       "ANALYTICS",
+				Schema:         "PUBLIC",
+				Table:          "EVENTS",
+				Stage:          "RAW.CAUSALITY_LAKE",
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		dest    func() Destination
+		wantErr bool
+	}{
+		{"bigquery", bigQuery, false},
+		{"snowflake", snowflake, false},
+		{"mapped columns", func() Destination {
+			d := bigQuery()
+			d.Columns = []ColumnMapping{{Source: "event_type", Target: "event_name"}, {Source: "app_id", Target: "app_id"}}
+			return d
+		}, false},
+		{"no name", func() Destination { d := bigQuery(); d.Name = ""; return d }, true},
+		{"unknown type", func() Destination { d := bigQuery(); d.Type = "redshift"; return d }, true},
+		{"missing settings", func() Destination { d := bigQuery(); d.BigQuery = nil; return d }, true},
+		{"no credentials", func() Destination { d := bigQuery(); d.BigQuery.CredentialsFile = ""; return d }, true},
+		{"stage injection", func() Destination { d := snowflake(); d.Snowflake.Stage = "s; DROP TABLE x"; return d }, true},
+		{"invalid target", func() Destination {
+			d := snowflake()
+			d.Columns = []ColumnMapping{{Source: "id", Target: "id\" --"}}
+			return d
+		}, true},
+		{"target mapped twice", func() Destination {
+			d := bigQuery()
+			d.Columns = []ColumnMapping{{Source: "id", Target: "event_id"}, {Source: "correlation_id", Target: "event_id"}}
+			return d
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := tt.dest()
+			err := d.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidDestination) {
+				t.Errorf("Validate() error = %v, want ErrInvalidDestination", err)
+			}
+		})
+	}
+}
+
+func TestDestination_Includes(t *testing.T) {
+	all := Destination{}
+	if !all.Includes("any") {
+		t.Error("destination without apps should include every app")
+	}
+
+	some := Destination{Apps: []string{"a", "b"}}
+	if !some.Includes("b") || some.Includes("c") {
+		t.Error("destination with apps should include only those apps")
+	}
+}
@@ -0,0 +1,42 @@
+package repo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/SebastienMelki/causality/internal/export/internal/domain"
+)
+
+// LoadDestinations reads and validates the export destinations file.
+// Unknown fields are rejected so typos do not silently fall back to
+// defaults, and destination names must be unique.
+func LoadDestinations(path string) ([]domain.Destination, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read destinations file: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var file domain.Destinations
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to decode destinations file %s: %w", path, err)
+	}
+
+	names := make(map[string]bool, len(file.Destinations))
+	for i := range file.Destinations {
+		dest := &file.Destinations[i]
+		if err := dest.Validate(); err != nil {
+			return nil, err
+		}
+		if names[dest.Name] {
+			return nil, fmt.Errorf("%w: duplicate name %q", domain.ErrInvalidDestination, dest.Name)
+		}
+		names[dest.Name] = true
+	}
+
+	return file.Destinations, nil
+}
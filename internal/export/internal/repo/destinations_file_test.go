@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SebastienMelki/causality/internal/export/internal/domain"
+)
+
+func writeDestinations(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "destinations.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write destinations: %v", err)
+	}
+	return path
+}
+
+func TestLoadDestinations(t *testing.T) {
+	path := writeDestinations(t, `{"destinations": [
+		{"name": "bq", "type": "bigquery", "apps": ["app"],
+		 "columns": [{"source": "id", "target": "event_id"}],
+		 "bigquery": {"project": "p", "dataset": "d", "table": "events", "credentials_file": "/secrets/bq.json"}}
+	]}`)
+
+	dests, err := LoadDestinations(path)
+	if err != nil {
+		t.Fatalf("LoadDestinations() error = %v", err)
+	}
+	if len(dests) != 1 || dests[0].Name != "bq" || dests[0].BigQuery.Table != "events" {
+		t.Fatalf("LoadDestinations() = %+v", dests)
+	}
+	if len(dests[0].Columns) != 1 || dests[0].Columns[0].Target != "event_id" {
+		t.Errorf("Columns = %+v", dests[0].Columns)
+	}
+}
+
+func TestLoadDestinations_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unknown field", `{"destinations": [{"name": "bq", "type": "bigquery", "tabel": "x"}]}`},
+		{"duplicate name", `{"destinations": [
+			{"name": "bq", "type": "bigquery", "bigquery": {"project": "p", "dataset": "d", "table": "t", "credentials_file": "c"}},
+			{"name": "bq", "type": "bigquery", "bigquery": {"project": "p", "dataset": "d", "table": "u", "credentials_file": "c"}}
+		]}`},
+		{"missing settings", `{"destinations": [{"name": "sf", "type": "snowflake"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadDestinations(writeDestinations(t, tt.content)); err == nil {
+				t.Error("LoadDestinations() error = nil")
+			}
+		})
+	}
+
+	_, err := LoadDestinations(writeDestinations(t, `{"destinations": [{"name": "sf", "type": "snowflake"}]}`))
+	if !errors.Is(err, domain.ErrInvalidDestination) {
+		t.Errorf("LoadDestinations() error = %v, want ErrInvalidDestination", err)
+	}
+}
@@ -0,0 +1,83 @@
+// Package repo provides the PostgreSQL implementation of the export Store
+// port and the destinations file loader.
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/export/internal/domain"
+)
+
+// maxErrorLength bounds the stored last error of a watermark.
+const maxErrorLength = 1024
+
+// WatermarkRepository implements the Store interface using PostgreSQL.
+type WatermarkRepository struct {
+	db *sql.DB
+}
+
+// NewWatermarkRepository creates a new WatermarkRepository backed by the given database.
+func NewWatermarkRepository(db *sql.DB) *WatermarkRepository {
+	return &WatermarkRepository{db: db}
+}
+
+// Watermarks returns, per app, the export progress to a destination.
+func (r *WatermarkRepository) Watermarks(ctx context.Context, destination string) (map[string]domain.Watermark, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT app_id, exported_until, attempts, last_error
+		FROM export_watermarks
+		WHERE destination = $1
+	`, destination)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query export watermarks: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	watermarks := make(map[string]domain.Watermark)
+	for rows.Next() {
+		var appID string
+		var w domain.Watermark
+		if err := rows.Scan(&appID, &w.ExportedUntil, &w.Attempts, &w.LastError); err != nil {
+			return nil, fmt.Errorf("failed to scan export watermark: %w", err)
+		}
+		watermarks[appID] = w
+	}
+
+	return watermarks, rows.Err()
+}
+
+// Advance moves an app's watermark for a destination to until and clears
+// its failed attempts.
+func (r *WatermarkRepository) Advance(ctx context.Context, destination, appID string, until time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO export_watermarks (destination, app_id, exported_until)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (destination, app_id) DO UPDATE
+		SET exported_until = EXCLUDED.exported_until, attempts = 0, last_error = '', updated_at = NOW()
+	`, destination, appID, until); err != nil {
+		return fmt.Errorf("failed to advance export watermark for %s/%s: %w", destination, appID, err)
+	}
+	return nil
+}
+
+// RecordFailure counts a failed load of the window at an app's watermark
+// for a destination, which is created at window if missing.
+func (r *WatermarkRepository) RecordFailure(ctx context.Context, destination, appID string, window time.Time, loadErr error) error {
+	msg := loadErr.Error()
+	if len(msg) > maxErrorLength {
+		msg = msg[:maxErrorLength]
+	}
+
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO export_watermarks (destination, app_id, exported_until, attempts, last_error)
+		VALUES ($1, $2, $3, 1, $4)
+		ON CONFLICT (destination, app_id) DO UPDATE
+		SET attempts = export_watermarks.attempts + 1, last_error = EXCLUDED.last_error, updated_at = NOW()
+	`, destination, appID, window, msg); err != nil {
+		return fmt.Errorf("failed to record export failure for %s/%s: %w", destination, appID, err)
+	}
+	return nil
+}
@@ -0,0 +1,346 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/export/internal/domain"
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+const (
+	bigQueryAPIURL   = "https://bigquery.googleapis.com"
+	bigQueryScope    = "https://www.googleapis.com/auth/bigquery"
+	googleTokenURL   = "https://oauth2.googleapis.com/token"
+	bigQueryJobState = "DONE"
+)
+
+// invalidJobIDChars matches characters not allowed in BigQuery job IDs.
+var invalidJobIDChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// serviceAccount is the subset of a Google service account JSON key used to
+// obtain access tokens.
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// bigQueryJob is the subset of a BigQuery job resource read by the loader.
+type bigQueryJob struct {
+	Status struct {
+		State       string `json:"state"`
+		ErrorResult *struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"errorResult"`
+	} `json:"status"`
+	Statistics struct {
+		Load struct {
+			OutputRows string `json:"outputRows"`
+		} `json:"load"`
+	} `json:"statistics"`
+}
+
+// BigQueryLoader loads partition windows into a BigQuery table. The rows of
+// a window's files are converted to newline-delimited JSON with the
+// destination's column mapping and uploaded as one load job appending to
+// the table. Job IDs are derived from the destination, app, window and
+// attempt, so a load whose job was created before a crash is not appended
+// twice: the existing job is awaited instead.
+type BigQueryLoader struct {
+	name     string
+	columns  []domain.ColumnMapping
+	config   domain.BigQueryConfig
+	files    fileReader
+	client   *http.Client
+	apiURL   string
+	account  serviceAccount
+	key      *rsa.PrivateKey
+	interval time.Duration
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewBigQueryLoader creates a loader for a BigQuery destination, reading its
+// service account key. timeout bounds each API request.
+func NewBigQueryLoader(dest domain.Destination, reader *warehouse.PartitionReader, timeout time.Duration) (*BigQueryLoader, error) {
+	return newBigQueryLoader(dest, reader, timeout)
+}
+
+func newBigQueryLoader(dest domain.Destination, files fileReader, timeout time.Duration) (*BigQueryLoader, error) {
+	cfg := *dest.BigQuery
+
+	data, err := os.ReadFile(cfg.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("read bigquery credentials for %s: %w", dest.Name, err)
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("decode bigquery credentials for %s: %w", dest.Name, err)
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = googleTokenURL
+	}
+	key, err := parseRSAPrivateKey([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("bigquery credentials for %s: %w", dest.Name, err)
+	}
+
+	apiURL := cfg.APIURL
+	if apiURL == "" {
+		apiURL = bigQueryAPIURL
+	}
+
+	return &BigQueryLoader{
+		name:     dest.Name,
+		columns:  dest.Columns,
+		config:   cfg,
+		files:    files,
+		client:   &http.Client{Timeout: timeout},
+		apiURL:   strings.TrimSuffix(apiURL, "/"),
+		account:  account,
+		key:      key,
+		interval: 2 * time.Second,
+	}, nil
+}
+
+// Load uploads the rows of a window's files in one load job and waits for
+// it to finish.
+func (l *BigQueryLoader) Load(ctx context.Context, req LoadRequest) (int64, error) {
+	var body bytes.Buffer
+	var rows int64
+	for _, key := range req.Keys {
+		data, err := l.files.Download(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		n, err := writeJSONRows(&body, data, l.columns)
+		if err != nil {
+			return 0, fmt.Errorf("convert %s: %w", key, err)
+		}
+		rows += n
+	}
+	if rows == 0 {
+		return 0, nil
+	}
+
+	jobID := l.jobID(req)
+	job, err := l.insertJob(ctx, jobID, body.Bytes())
+	if err != nil {
+		return 0, err
+	}
+
+	for job.Status.State != bigQueryJobState {
+		if err := sleepContext(ctx, l.interval); err != nil {
+			return 0, err
+		}
+		if job, err = l.getJob(ctx, jobID); err != nil {
+			return 0, err
+		}
+	}
+
+	if e := job.Status.ErrorResult; e != nil {
+		return 0, fmt.Errorf("bigquery job %s failed: %s: %s", jobID, e.Reason, e.Message)
+	}
+
+	if loaded, err := strconv.ParseInt(job.Statistics.Load.OutputRows, 10, 64); err == nil {
+		return loaded, nil
+	}
+	return rows, nil
+}
+
+// jobID returns the load job ID of a request.
+func (l *BigQueryLoader) jobID(req LoadRequest) string {
+	id := fmt.Sprintf("causality_%s_%s_%s_%d", l.name, req.AppID, req.Window.UTC().Format("2006010215"), req.Attempt)
+	return invalidJobIDChars.ReplaceAllString(id, "_")
+}
+
+// insertJob creates a load job uploading data. If the job already exists,
+// it is returned instead.
+func (l *BigQueryLoader) insertJob(ctx context.Context, jobID string, data []byte) (*bigQueryJob, error) {
+	reference := map[string]string{"projectId": l.config.Project, "jobId": jobID}
+	if l.config.Location != "" {
+		reference["location"] = l.config.Location
+	}
+	metadata, err := json.Marshal(map[string]interface{}{
+		"jobReference": reference,
+		"configuration": map[string]interface{}{
+			"load": map[string]interface{}{
+				"destinationTable": map[string]string{
+					"projectId": l.config.Project,
+					"datasetId": l.config.Dataset,
+					"tableId":   l.config.Table,
+				},
+				"sourceFormat":     "NEWLINE_DELIMITED_JSON",
+				"writeDisposition": "WRITE_APPEND",
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		data        []byte
+	}{
+		{"application/json; charset=UTF-8", metadata},
+		{"application/octet-stream", data},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := pw.Write(part.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/upload/bigquery/v2/projects/%s/jobs?uploadType=multipart",
+		l.apiURL, url.PathEscape(l.config.Project))
+	job, status, err := l.do(ctx, http.MethodPost, endpoint, "multipart/related; boundary="+mw.Boundary(), &body)
+	if status == http.StatusConflict {
+		return l.getJob(ctx, jobID)
+	}
+	return job, err
+}
+
+// getJob returns a load job.
+func (l *BigQueryLoader) getJob(ctx context.Context, jobID string) (*bigQueryJob, error) {
+	endpoint := fmt.Sprintf("%s/bigquery/v2/projects/%s/jobs/%s",
+		l.apiURL, url.PathEscape(l.config.Project), url.PathEscape(jobID))
+	if l.config.Location != "" {
+		endpoint += "?location=" + url.QueryEscape(l.config.Location)
+	}
+	job, _, err := l.do(ctx, http.MethodGet, endpoint, "", nil)
+	return job, err
+}
+
+// do sends an authorized API request and decodes the job in its response.
+// The response status is returned with errors.
+func (l *BigQueryLoader) do(ctx context.Context, method, endpoint, contentType string, body io.Reader) (*bigQueryJob, int, error) {
+	token, err := l.accessToken(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("bigquery request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("read bigquery response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, resp.StatusCode, fmt.Errorf("bigquery returned status %d: %s", resp.StatusCode, truncate(data))
+	}
+
+	var job bigQueryJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("decode bigquery job: %w", err)
+	}
+	return &job, resp.StatusCode, nil
+}
+
+// accessToken returns an OAuth access token for the service account,
+// exchanging a signed assertion when the cached token is about to expire.
+func (l *BigQueryLoader) accessToken(ctx context.Context) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.token != "" && now.Before(l.tokenExpiry.Add(-time.Minute)) {
+		return l.token, nil
+	}
+
+	assertion, err := signJWT(l.key, map[string]interface{}{
+		"iss":   l.account.ClientEmail,
+		"scope": bigQueryScope,
+		"aud":   l.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned status %d: %s", resp.StatusCode, truncate(data))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(data, &token); err != nil {
+		return "", fmt.Errorf("decode token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("token endpoint returned no access token")
+	}
+
+	l.token = token.AccessToken
+	l.tokenExpiry = now.Add(time.Duration(token.ExpiresIn) * time.Second)
+	return l.token, nil
+}
+
+// truncate returns the start of an error response body.
+func truncate(data []byte) string {
+	const limit = 1024
+	if len(data) > limit {
+		data = data[:limit]
+	}
+	return string(data)
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/SebastienMelki/causality/internal/export/internal/domain"
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+// fakeFiles serves file contents by key.
+type fakeFiles map[string][]byte
+
+func (f fakeFiles) Download(_ context.Context, key string) ([]byte, error) {
+	return f[key], nil
+}
+
+// writeTestKey writes a PKCS#8 PEM private key to a temporary file.
+func writeTestKey(t *testing.T) (string, []byte) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return path, data
+}
+
+// fakeBigQuery is a BigQuery API server accepting load jobs.
+type fakeBigQuery struct {
+	mu       sync.Mutex
+	tokens   int
+	uploads  []string
+	rows     []map[string]interface{}
+	polls    int
+	conflict bool
+}
+
+func (f *fakeBigQuery) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.tokens++
+		f.mu.Unlock()
+		if err := r.ParseForm(); err != nil || r.Form.Get("assertion") == "" {
+			http.Error(w, "missing assertion", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "tok", "expires_in": 3600})
+	})
+	mux.HandleFunc("/upload/bigquery/v2/projects/proj/jobs", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			t.Errorf("content type: %v", err)
+			return
+		}
+		mr := multipart.NewReader(r.Body, params["boundary"])
+
+		var metadata struct {
+			JobReference struct {
+				JobID string `json:"jobId"`
+			} `json:"jobReference"`
+		}
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Errorf("metadata part: %v", err)
+			return
+		}
+		if err := json.NewDecoder(part).Decode(&metadata); err != nil {
+			t.Errorf("decode metadata: %v", err)
+		}
+		part, err = mr.NextPart()
+		if err != nil {
+			t.Errorf("data part: %v", err)
+			return
+		}
+		data, _ := io.ReadAll(part)
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.uploads = append(f.uploads, metadata.JobReference.JobID)
+		if f.conflict {
+			http.Error(w, "already exists", http.StatusConflict)
+			return
+		}
+		scanner := bufio.NewScanner(strings.NewReader(string(data)))
+		for scanner.Scan() {
+			var row map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+				t.Errorf("decode row: %v", err)
+			}
+			f.rows = append(f.rows, row)
+		}
+		_, _ = io.WriteString(w, `{"status":{"state":"RUNNING"}}`)
+	})
+	mux.HandleFunc("/bigquery/v2/projects/proj/jobs/", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.polls++
+		if r.URL.Query().Get("location") != "EU" {
+			t.Errorf("location = %q, want EU", r.URL.Query().Get("location"))
+		}
+		if f.polls < 2 {
+			_, _ = io.WriteString(w, `{"status":{"state":"RUNNING"}}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"status":{"state":"DONE"},"statistics":{"load":{"outputRows":"2"}}}`)
+	})
+	return mux
+}
+
+func newTestBigQueryLoader(t *testing.T, serverURL string, files fileReader, columns []domain.ColumnMapping) *BigQueryLoader {
+	t.Helper()
+	_, keyPEM := writeTestKey(t)
+	credentials, _ := json.Marshal(map[string]string{
+		"client_email": "loader@proj.iam.gserviceaccount.com",
+		"private_key":  string(keyPEM),
+		"token_uri":    serverURL + "/token",
+	})
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	if err := os.WriteFile(path, credentials, 0o600); err != nil {
+		t.Fatalf("write credentials: %v", err)
+	}
+
+	loader, err := newBigQueryLoader(domain.Destination{
+		Name:    "bq-main",
+		Type:    domain.TypeBigQuery,
+		Columns: columns,
+		BigQuery: &domain.BigQueryConfig{
+			Project:         "proj",
+			Dataset:         "analytics",
+			Table:           "events",
+			Location:        "EU",
+			CredentialsFile: path,
+			APIURL:          serverURL,
+		},
+	}, files, time.Second)
+	if err != nil {
+		t.Fatalf("newBigQueryLoader() error = %v", err)
+	}
+	loader.interval = time.Millisecond
+	return loader
+}
+
+func TestBigQueryLoader_Load(t *testing.T) {
+	data, err := warehouse.NewParquetWriter(warehouse.ParquetConfig{Compression: "snappy"}).Write([]warehouse.EventRow{
+		{ID: "1", AppID: "app", EventType: "screen_view", TimestampMS: 1000},
+		{ID: "2", AppID: "app", EventType: "purchase_complete", TimestampMS: 2000},
+	})
+	if err != nil {
+		t.Fatalf("write parquet: %v", err)
+	}
+
+	fake := &fakeBigQuery{}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	loader := newTestBigQueryLoader(t, server.URL, fakeFiles{"a.parquet": data}, []domain.ColumnMapping{
+		{Source: "id", Target: "event_id"},
+		{Source: "event_type", Target: "name"},
+	})
+
+	window := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	loaded, err := loader.Load(context.Background(), LoadRequest{
+		AppID: "my.app", Window: window, Keys: []string{"a.parquet"}, Attempt: 2,
+	})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded != 2 {
+		t.Errorf("Load() = %d, want 2", loaded)
+	}
+
+	if len(fake.uploads) != 1 || fake.uploads[0] != "causality_bq-main_my_app_2026031009_2" {
+		t.Errorf("job IDs = %v, want [causality_bq-main_my_app_2026031009_2]", fake.uploads)
+	}
+	if len(fake.rows) != 2 {
+		t.Fatalf("uploaded rows = %d, want 2", len(fake.rows))
+	}
+	row := fake.rows[1]
+	if row["event_id"] != "2" || row["name"] != "purchase_complete" || len(row) != 2 {
+		t.Errorf("row = %v, want event_id and name only", row)
+	}
+
+	// A second load reuses the cached access token.
+	if _, err := loader.Load(context.Background(), LoadRequest{AppID: "my.app", Window: window, Keys: []string{"a.parquet"}, Attempt: 3}); err != nil {
+		t.Fatalf("second Load() error = %v", err)
+	}
+	if fake.tokens != 1 {
+		t.Errorf("token requests = %d, want 1", fake.tokens)
+	}
+}
+
+func TestBigQueryLoader_LoadExistingJob(t *testing.T) {
+	data, err := warehouse.NewParquetWriter(warehouse.ParquetConfig{Compression: "snappy"}).Write([]warehouse.EventRow{
+		{ID: "1", AppID: "app", EventType: "screen_view"},
+	})
+	if err != nil {
+		t.Fatalf("write parquet: %v", err)
+	}
+
+	fake := &fakeBigQuery{conflict: true}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	loader := newTestBigQueryLoader(t, server.URL, fakeFiles{"a.parquet": data}, nil)
+	loaded, err := loader.Load(context.Background(), LoadRequest{
+		AppID: "app", Window: time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC), Keys: []string{"a.parquet"}, Attempt: 1,
+	})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded != 2 || fake.polls < 2 {
+		t.Errorf("Load() = %d after %d polls, want the existing job's 2 rows", loaded, fake.polls)
+	}
+}
+
+func TestBigQueryLoader_LoadEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := parquet.NewGenericWriter[warehouse.EventRow](&buf).Close(); err != nil {
+		t.Fatalf("write parquet: %v", err)
+	}
+	data := buf.Bytes()
+
+	fake := &fakeBigQuery{}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	loader := newTestBigQueryLoader(t, server.URL, fakeFiles{"a.parquet": data}, nil)
+	loaded, err := loader.Load(context.Background(), LoadRequest{AppID: "app", Keys: []string{"a.parquet"}, Attempt: 1})
+	if err != nil || loaded != 0 {
+		t.Fatalf("Load() = %d, %v, want 0, nil", loaded, err)
+	}
+	if len(fake.uploads) != 0 {
+		t.Errorf("uploads = %d, want none for an empty window", len(fake.uploads))
+	}
+}
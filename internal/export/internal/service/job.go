@@ -0,0 +1,296 @@
+// Package service implements the warehouse export job: it loads the
+// partitions the warehouse sink and compaction have finished writing into
+// external warehouses, one partition window per load, tracking progress per
+// destination and app.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/export/internal/domain"
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+// ExportStore defines the persistence interface needed by the export job.
+// This mirrors the export.Store port to avoid import cycles.
+type ExportStore interface {
+	Watermarks(ctx context.Context, destination string) (map[string]domain.Watermark, error)
+	Advance(ctx context.Context, destination, appID string, until time.Time) error
+	RecordFailure(ctx context.Context, destination, appID string, window time.Time, loadErr error) error
+}
+
+// partitionLister is the subset of warehouse.PartitionReader used by Job.
+type partitionLister interface {
+	Window() time.Duration
+	ListApps(ctx context.Context) ([]string, error)
+	ListWindow(ctx context.Context, appID string, start time.Time) ([]string, error)
+}
+
+// fileReader downloads warehouse files for loaders that upload them.
+type fileReader interface {
+	Download(ctx context.Context, key string) ([]byte, error)
+}
+
+// LoadRequest is one partition window of one app to load.
+type LoadRequest struct {
+	AppID  string
+	Window time.Time
+	Keys   []string
+
+	// Attempt numbers the loads of this window, counting those of earlier
+	// runs, so loaders can derive idempotent job IDs from it.
+	Attempt int
+}
+
+// Loader loads partition files into one destination.
+type Loader interface {
+	// Load loads the files of a request and returns the number of rows
+	// loaded.
+	Load(ctx context.Context, req LoadRequest) (int64, error)
+}
+
+// Destination is a configured destination and its loader.
+type Destination struct {
+	Config domain.Destination
+	Loader Loader
+}
+
+// JobConfig holds the export job parameters.
+type JobConfig struct {
+	// SettleDelay is how long after a partition window ends before it is
+	// exported, giving the warehouse sink time to flush late batches and
+	// compaction time to merge the window's files.
+	SettleDelay time.Duration
+
+	// Backfill is how far back the export of an app without a watermark
+	// starts.
+	Backfill time.Duration
+
+	// MaxAttempts is the number of loads of a window per run before the
+	// app is skipped until the next run.
+	MaxAttempts int
+
+	// RetryBackoff is the wait before the second load of a window, doubled
+	// for each further load.
+	RetryBackoff time.Duration
+}
+
+// Job is the warehouse export batch job.
+type Job struct {
+	store        ExportStore
+	lister       partitionLister
+	destinations []Destination
+	config       JobConfig
+	now          func() time.Time
+	sleep        func(ctx context.Context, d time.Duration) error
+	logger       *slog.Logger
+}
+
+// NewJob creates a new export job listing the warehouse through reader.
+func NewJob(store ExportStore, reader *warehouse.PartitionReader, destinations []Destination, cfg JobConfig, logger *slog.Logger) *Job {
+	return newJob(store, reader, destinations, cfg, logger)
+}
+
+func newJob(store ExportStore, lister partitionLister, destinations []Destination, cfg JobConfig, logger *slog.Logger) *Job {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.Backfill <= 0 {
+		cfg.Backfill = 24 * time.Hour
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 30 * time.Second
+	}
+
+	return &Job{
+		store:        store,
+		lister:       lister,
+		destinations: destinations,
+		config:       cfg,
+		now:          time.Now,
+		sleep:        sleepContext,
+		logger:       logger.With("component", "warehouse-export"),
+	}
+}
+
+// Run exports every settled partition window not yet exported to each
+// destination. Failures for one app are logged and retried on the next
+// run; the first error is returned.
+func (j *Job) Run(ctx context.Context) error {
+	start := j.now().UTC()
+	window := j.lister.Window()
+	until := start.Add(-j.config.SettleDelay).Truncate(window)
+
+	apps, err := j.lister.ListApps(ctx)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, dest := range j.destinations {
+		if err := j.runDestination(ctx, dest, apps, window, until); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	j.logger.Info("warehouse export finished",
+		"destinations", len(j.destinations),
+		"apps", len(apps),
+		"exported_until", until,
+		"duration", time.Since(start),
+	)
+	return firstErr
+}
+
+// runDestination exports each app's windows before until to one
+// destination.
+func (j *Job) runDestination(ctx context.Context, dest Destination, apps []string, window time.Duration, until time.Time) error {
+	watermarks, err := j.store.Watermarks(ctx, dest.Config.Name)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, appID := range apps {
+		if !dest.Config.Includes(appID) {
+			continue
+		}
+
+		watermark, ok := watermarks[appID]
+		if !ok {
+			watermark.ExportedUntil = until.Add(-j.config.Backfill).Truncate(window)
+		}
+
+		if err := j.exportApp(ctx, dest, appID, watermark, window, until); err != nil {
+			j.logger.Warn("failed to export app",
+				"destination", dest.Config.Name,
+				"app_id", appID,
+				"error", err,
+			)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+
+		if ctx.Err() != nil {
+			return errors.Join(firstErr, ctx.Err())
+		}
+	}
+
+	return firstErr
+}
+
+// exportApp loads an app's windows from its watermark up to until, in
+// order, advancing the watermark after each loaded window. Windows without
+// files are skipped. It stops at the first window that fails to load.
+func (j *Job) exportApp(ctx context.Context, dest Destination, appID string, watermark domain.Watermark, window time.Duration, until time.Time) error {
+	from := watermark.ExportedUntil
+	attempts := watermark.Attempts
+
+	for w := from; w.Before(until); w = w.Add(window) {
+		keys, err := j.lister.ListWindow(ctx, appID, w)
+		if err != nil {
+			return j.advance(ctx, dest, appID, from, w, err)
+		}
+		if len(keys) == 0 {
+			continue
+		}
+
+		// Failures are recorded against the watermark, so it must be at w.
+		if w.After(from) {
+			if err := j.store.Advance(ctx, dest.Config.Name, appID, w); err != nil {
+				return err
+			}
+			from, attempts = w, 0
+		}
+
+		req := LoadRequest{AppID: appID, Window: w, Keys: keys}
+		rows, err := j.load(ctx, dest, req, attempts)
+		if err != nil {
+			return err
+		}
+
+		next := w.Add(window)
+		if err := j.store.Advance(ctx, dest.Config.Name, appID, next); err != nil {
+			return err
+		}
+		from, attempts = next, 0
+
+		j.logger.Info("exported partition window",
+			"destination", dest.Config.Name,
+			"app_id", appID,
+			"window", w,
+			"files", len(keys),
+			"rows", rows,
+		)
+	}
+
+	return j.advance(ctx, dest, appID, from, until, nil)
+}
+
+// advance moves an app's watermark over the empty windows between from and
+// to, then returns loadErr.
+func (j *Job) advance(ctx context.Context, dest Destination, appID string, from, to time.Time, loadErr error) error {
+	if to.After(from) {
+		if err := j.store.Advance(ctx, dest.Config.Name, appID, to); err != nil {
+			return errors.Join(loadErr, err)
+		}
+	}
+	return loadErr
+}
+
+// load loads a window up to MaxAttempts times, recording each failure.
+// attempts is the number of failed loads of the window in earlier runs.
+func (j *Job) load(ctx context.Context, dest Destination, req LoadRequest, attempts int) (int64, error) {
+	backoff := j.config.RetryBackoff
+
+	var lastErr error
+	for i := 0; i < j.config.MaxAttempts; i++ {
+		if i > 0 {
+			if err := j.sleep(ctx, backoff); err != nil {
+				return 0, errors.Join(lastErr, err)
+			}
+			backoff *= 2
+		}
+
+		req.Attempt = attempts + i + 1
+		rows, err := dest.Loader.Load(ctx, req)
+		if err == nil {
+			return rows, nil
+		}
+		lastErr = fmt.Errorf("load window %s (attempt %d): %w", req.Window.Format(time.RFC3339), req.Attempt, err)
+
+		j.logger.Warn("export load failed",
+			"destination", dest.Config.Name,
+			"app_id", req.AppID,
+			"window", req.Window,
+			"attempt", req.Attempt,
+			"error", err,
+		)
+		if err := j.store.RecordFailure(ctx, dest.Config.Name, req.AppID, req.Window, err); err != nil {
+			return 0, errors.Join(lastErr, err)
+		}
+	}
+
+	return 0, lastErr
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
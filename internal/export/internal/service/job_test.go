@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/export/internal/domain"
+)
+
+// mockStore is an in-memory ExportStore.
+type mockStore struct {
+	watermarks map[string]map[string]domain.Watermark
+}
+
+func newMockStore() *mockStore {
+	return &mockStore{watermarks: make(map[string]map[string]domain.Watermark)}
+}
+
+func (m *mockStore) Watermarks(_ context.Context, destination string) (map[string]domain.Watermark, error) {
+	out := make(map[string]domain.Watermark)
+	for app, w := range m.watermarks[destination] {
+		out[app] = w
+	}
+	return out, nil
+}
+
+func (m *mockStore) Advance(_ context.Context, destination, appID string, until time.Time) error {
+	if m.watermarks[destination] == nil {
+		m.watermarks[destination] = make(map[string]domain.Watermark)
+	}
+	m.watermarks[destination][appID] = domain.Watermark{ExportedUntil: until}
+	return nil
+}
+
+func (m *mockStore) RecordFailure(_ context.Context, destination, appID string, window time.Time, loadErr error) error {
+	if m.watermarks[destination] == nil {
+		m.watermarks[destination] = make(map[string]domain.Watermark)
+	}
+	w, ok := m.watermarks[destination][appID]
+	if !ok {
+		w.ExportedUntil = window
+	}
+	w.Attempts++
+	w.LastError = loadErr.Error()
+	m.watermarks[destination][appID] = w
+	return nil
+}
+
+// fakeLister serves partition files keyed by app and window start.
+type fakeLister struct {
+	apps  []string
+	files map[string]map[time.Time][]string
+}
+
+func (f *fakeLister) Window() time.Duration { return time.Hour }
+
+func (f *fakeLister) ListApps(context.Context) ([]string, error) { return f.apps, nil }
+
+func (f *fakeLister) ListWindow(_ context.Context, appID string, start time.Time) ([]string, error) {
+	return f.files[appID][start], nil
+}
+
+// fakeLoader records loads and fails those of windows in fail.
+type fakeLoader struct {
+	loads []LoadRequest
+	fail  map[time.Time]bool
+}
+
+func (f *fakeLoader) Load(_ context.Context, req LoadRequest) (int64, error) {
+	f.loads = append(f.loads, req)
+	if f.fail[req.Window] {
+		return 0, errors.New("quota exceeded")
+	}
+	return int64(len(req.Keys)), nil
+}
+
+func TestJob_Run(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 30, 0, 0, time.UTC)
+	h := func(hour int) time.Time { return time.Date(2026, 3, 10, hour, 0, 0, 0, time.UTC) }
+
+	lister := &fakeLister{
+		apps: []string{"app-a", "app-b"},
+		files: map[string]map[time.Time][]string{
+			"app-a": {h(7): {"a7"}, h(9): {"a9-1", "a9-2"}, h(11): {"a11"}},
+			"app-b": {h(8): {"b8"}},
+		},
+	}
+	loader := &fakeLoader{}
+	other := &fakeLoader{}
+	store := newMockStore()
+
+	job := newJob(store, lister, []Destination{
+		{Config: domain.Destination{Name: "bq"}, Loader: loader},
+		{Config: domain.Destination{Name: "sf", Apps: []string{"app-b"}}, Loader: other},
+	}, JobConfig{SettleDelay: 2 * time.Hour, Backfill: 4 * time.Hour}, nil)
+	job.now = func() time.Time { return now }
+
+	if err := job.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// Settled windows are those before 10:00; the backfill starts at 06:00.
+	var windows []time.Time
+	for _, req := range loader.loads {
+		windows = append(windows, req.Window)
+	}
+	want := []time.Time{h(7), h(9), h(8)}
+	if len(windows) != len(want) {
+		t.Fatalf("loaded windows = %v, want %v", windows, want)
+	}
+	for i := range want {
+		if !windows[i].Equal(want[i]) {
+			t.Errorf("load %d window = %v, want %v", i, windows[i], want[i])
+		}
+	}
+	if loader.loads[0].Attempt != 1 {
+		t.Errorf("first attempt = %d, want 1", loader.loads[0].Attempt)
+	}
+
+	if len(other.loads) != 1 || other.loads[0].AppID != "app-b" {
+		t.Errorf("app-filtered destination loads = %+v, want one app-b load", other.loads)
+	}
+	for _, app := range []string{"app-a", "app-b"} {
+		if got := store.watermarks["bq"][app].ExportedUntil; !got.Equal(h(10)) {
+			t.Errorf("%s watermark = %v, want %v", app, got, h(10))
+		}
+	}
+	if _, ok := store.watermarks["sf"]["app-a"]; ok {
+		t.Error("app-a has a watermark on a destination it is not exported to")
+	}
+
+	// Nothing new has settled: a second run loads nothing.
+	loader.loads = nil
+	if err := job.Run(context.Background()); err != nil {
+		t.Fatalf("second Run() error = %v", err)
+	}
+	if len(loader.loads) != 0 {
+		t.Errorf("second run loaded %d windows, want 0", len(loader.loads))
+	}
+}
+
+func TestJob_RunRetries(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 30, 0, 0, time.UTC)
+	h := func(hour int) time.Time { return time.Date(2026, 3, 10, hour, 0, 0, 0, time.UTC) }
+
+	lister := &fakeLister{
+		apps: []string{"app"},
+		files: map[string]map[time.Time][]string{
+			"app": {h(8): {"f8"}, h(9): {"f9"}},
+		},
+	}
+	loader := &fakeLoader{fail: map[time.Time]bool{h(9): true}}
+	store := newMockStore()
+	store.watermarks["bq"] = map[string]domain.Watermark{"app": {ExportedUntil: h(7)}}
+
+	var slept []time.Duration
+	job := newJob(store, lister, []Destination{
+		{Config: domain.Destination{Name: "bq"}, Loader: loader},
+	}, JobConfig{SettleDelay: 2 * time.Hour, MaxAttempts: 3, RetryBackoff: time.Second}, nil)
+	job.now = func() time.Time { return now }
+	job.sleep = func(_ context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+
+	if err := job.Run(context.Background()); err == nil {
+		t.Fatal("Run() error = nil, want the failed load")
+	}
+
+	// 08:00 loads, then 09:00 fails three times with doubling backoff.
+	if len(loader.loads) != 4 {
+		t.Fatalf("loads = %d, want 4", len(loader.loads))
+	}
+	if len(slept) != 2 || slept[0] != time.Second || slept[1] != 2*time.Second {
+		t.Errorf("backoff = %v, want [1s 2s]", slept)
+	}
+	w := store.watermarks["bq"]["app"]
+	if !w.ExportedUntil.Equal(h(9)) || w.Attempts != 3 || w.LastError == "" {
+		t.Errorf("watermark = %+v, want 09:00 with 3 failed attempts", w)
+	}
+
+	// The next run continues the attempt numbering and, once the load
+	// succeeds, moves past the window.
+	loader.loads = nil
+	loader.fail = nil
+	if err := job.Run(context.Background()); err != nil {
+		t.Fatalf("second Run() error = %v", err)
+	}
+	if len(loader.loads) != 1 || loader.loads[0].Attempt != 4 {
+		t.Fatalf("second run loads = %+v, want attempt 4 of 09:00", loader.loads)
+	}
+	w = store.watermarks["bq"]["app"]
+	if !w.ExportedUntil.Equal(h(10)) || w.Attempts != 0 {
+		t.Errorf("watermark after retry = %+v, want 10:00 with no attempts", w)
+	}
+}
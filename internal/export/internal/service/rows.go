@@ -0,0 +1,96 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/SebastienMelki/causality/internal/export/internal/domain"
+)
+
+// writeJSONRows writes the rows of a Parquet file to w as newline-delimited
+// JSON objects, with the columns renamed by mapping. Unmapped columns are
+// dropped; an empty mapping keeps every column under its own name. Null
+// values and mapped columns missing from the file are omitted. It returns
+// the number of rows written.
+func writeJSONRows(w io.Writer, data []byte, mapping []domain.ColumnMapping) (int64, error) {
+	pf, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return 0, err
+	}
+
+	// names maps leaf column indexes to destination column names.
+	names := make(map[int]string)
+	if len(mapping) == 0 {
+		for i, path := range pf.Schema().Columns() {
+			if len(path) == 1 {
+				names[i] = path[0]
+			}
+		}
+	} else {
+		for _, m := range mapping {
+			if col, ok := pf.Schema().Lookup(m.Source); ok {
+				names[col.ColumnIndex] = m.Target
+			}
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	buf := make([]parquet.Row, 256)
+	var written int64
+	for _, rg := range pf.RowGroups() {
+		rows := rg.Rows()
+		for {
+			n, err := rows.ReadRows(buf)
+			for _, row := range buf[:n] {
+				obj := make(map[string]interface{}, len(names))
+				for _, v := range row {
+					name, ok := names[v.Column()]
+					if !ok || v.IsNull() {
+						continue
+					}
+					obj[name] = jsonValue(v)
+				}
+				if err := enc.Encode(obj); err != nil {
+					_ = rows.Close()
+					return written, err
+				}
+				written++
+			}
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				_ = rows.Close()
+				return written, fmt.Errorf("read rows: %w", err)
+			}
+		}
+		if err := rows.Close(); err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
+
+// jsonValue converts a Parquet value to its JSON representation.
+func jsonValue(v parquet.Value) interface{} {
+	switch v.Kind() {
+	case parquet.Boolean:
+		return v.Boolean()
+	case parquet.Int32:
+		return v.Int32()
+	case parquet.Int64:
+		return v.Int64()
+	case parquet.Float:
+		return v.Float()
+	case parquet.Double:
+		return v.Double()
+	default:
+		return string(v.ByteArray())
+	}
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/export/internal/domain"
+)
+
+// snowflakeMaxFiles is the maximum number of files listed in one COPY
+// statement's FILES clause.
+const snowflakeMaxFiles = 1000
+
+// snowflakeResult is the subset of a SQL API response read by the loader.
+type snowflakeResult struct {
+	Message           string `json:"message"`
+	StatementHandle   string `json:"statementHandle"`
+	ResultSetMetaData struct {
+		RowType []struct {
+			Name string `json:"name"`
+		} `json:"rowType"`
+	} `json:"resultSetMetaData"`
+	Data [][]*string `json:"data"`
+}
+
+// SnowflakeLoader loads partition windows into a Snowflake table with COPY
+// INTO statements run through the SQL API. Files are read in place from an
+// external stage over the event bucket; the destination's column mapping
+// becomes the COPY transformation. Snowflake skips files it already loaded
+// into the table, so retried loads do not duplicate rows.
+type SnowflakeLoader struct {
+	columns  []domain.ColumnMapping
+	config   domain.SnowflakeConfig
+	client   *http.Client
+	apiURL   string
+	key      *rsa.PrivateKey
+	issuer   string
+	subject  string
+	interval time.Duration
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewSnowflakeLoader creates a loader for a Snowflake destination, reading
+// its private key. timeout bounds each API request.
+func NewSnowflakeLoader(dest domain.Destination, timeout time.Duration) (*SnowflakeLoader, error) {
+	cfg := *dest.Snowflake
+
+	data, err := os.ReadFile(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read snowflake private key for %s: %w", dest.Name, err)
+	}
+	key, err := parseRSAPrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("snowflake private key for %s: %w", dest.Name, err)
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("snowflake public key for %s: %w", dest.Name, err)
+	}
+	fingerprint := sha256.Sum256(publicKey)
+
+	// Tokens name the account without its region or cloud suffix.
+	account, _, _ := strings.Cut(strings.ToUpper(cfg.Account), ".")
+	qualifiedUser := account + "." + strings.ToUpper(cfg.User)
+
+	apiURL := cfg.APIURL
+	if apiURL == "" {
+		apiURL = "https://" + cfg.Account + ".snowflakecomputing.com"
+	}
+
+	return &SnowflakeLoader{
+		columns:  dest.Columns,
+		config:   cfg,
+		client:   &http.Client{Timeout: timeout},
+		apiURL:   strings.TrimSuffix(apiURL, "/"),
+		key:      key,
+		issuer:   qualifiedUser + ".SHA256:" + base64.StdEncoding.EncodeToString(fingerprint[:]),
+		subject:  qualifiedUser,
+		interval: 2 * time.Second,
+	}, nil
+}
+
+// Load copies a window's files into the table, up to snowflakeMaxFiles per
+// statement, and returns the number of rows loaded.
+func (l *SnowflakeLoader) Load(ctx context.Context, req LoadRequest) (int64, error) {
+	var loaded int64
+	for start := 0; start < len(req.Keys); start += snowflakeMaxFiles {
+		end := min(start+snowflakeMaxFiles, len(req.Keys))
+		rows, err := l.execute(ctx, l.copyStatement(req.Keys[start:end]))
+		if err != nil {
+			return loaded, err
+		}
+		loaded += rows
+	}
+	return loaded, nil
+}
+
+// copyStatement returns the COPY INTO statement loading keys.
+func (l *SnowflakeLoader) copyStatement(keys []string) string {
+	files := make([]string, len(keys))
+	for i, key := range keys {
+		path := strings.TrimPrefix(strings.TrimPrefix(key, l.config.StagePrefix), "/")
+		files[i] = "'" + strings.ReplaceAll(path, "'", "''") + "'"
+	}
+
+	var b strings.Builder
+	b.WriteString("COPY INTO " + l.config.Table)
+	if len(l.columns) == 0 {
+		b.WriteString(" FROM @" + l.config.Stage)
+	} else {
+		targets := make([]string, len(l.columns))
+		sources := make([]string, len(l.columns))
+		for i, c := range l.columns {
+			targets[i] = c.Target
+			sources[i] = `$1:"` + c.Source + `"`
+		}
+		b.WriteString(" (" + strings.Join(targets, ", ") + ")")
+		b.WriteString(" FROM (SELECT " + strings.Join(sources, ", ") + " FROM @" + l.config.Stage + ")")
+	}
+	b.WriteString(" FILES = (" + strings.Join(files, ", ") + ")")
+	b.WriteString(" FILE_FORMAT = (TYPE = PARQUET)")
+	if len(l.columns) == 0 {
+		b.WriteString(" MATCH_BY_COLUMN_NAME = CASE_INSENSITIVE")
+	}
+	return b.String()
+}
+
+// execute runs a statement, waits for it to finish, and returns the sum of
+// the rows_loaded column of its result.
+func (l *SnowflakeLoader) execute(ctx context.Context, statement string) (int64, error) {
+	body, err := json.Marshal(map[string]string{
+		"statement": statement,
+		"database":  l.config.Database,
+		"schema":    l.config.Schema,
+		"warehouse": l.config.Warehouse,
+		"role":      l.config.Role,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	result, status, err := l.do(ctx, http.MethodPost, l.apiURL+"/api/v2/statements", body)
+	for err == nil && status == http.StatusAccepted {
+		if err := sleepContext(ctx, l.interval); err != nil {
+			return 0, err
+		}
+		result, status, err = l.do(ctx, http.MethodGet,
+			l.apiURL+"/api/v2/statements/"+url.PathEscape(result.StatementHandle), nil)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	column := -1
+	for i, col := range result.ResultSetMetaData.RowType {
+		if strings.EqualFold(col.Name, "rows_loaded") {
+			column = i
+		}
+	}
+	var loaded int64
+	for _, row := range result.Data {
+		if column < 0 || column >= len(row) || row[column] == nil {
+			continue
+		}
+		n, _ := strconv.ParseInt(*row[column], 10, 64)
+		loaded += n
+	}
+	return loaded, nil
+}
+
+// do sends an authorized SQL API request and decodes its response. 200 and
+// 202 (still running) responses are returned with their status.
+func (l *SnowflakeLoader) do(ctx context.Context, method, endpoint string, body []byte) (*snowflakeResult, int, error) {
+	token, err := l.jwt()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Snowflake-Authorization-Token-Type", "KEYPAIR_JWT")
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("snowflake request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("read snowflake response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return nil, resp.StatusCode, fmt.Errorf("snowflake returned status %d: %s", resp.StatusCode, truncate(data))
+	}
+
+	var result snowflakeResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("decode snowflake response: %w", err)
+	}
+	return &result, resp.StatusCode, nil
+}
+
+// jwt returns a key-pair authentication token, signing a new one when the
+// cached token is about to expire.
+func (l *SnowflakeLoader) jwt() (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.token != "" && now.Before(l.tokenExpiry.Add(-5*time.Minute)) {
+		return l.token, nil
+	}
+
+	expiry := now.Add(time.Hour)
+	token, err := signJWT(l.key, map[string]interface{}{
+		"iss": l.issuer,
+		"sub": l.subject,
+		"iat": now.Unix(),
+		"exp": expiry.Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	l.token, l.tokenExpiry = token, expiry
+	return token, nil
+}
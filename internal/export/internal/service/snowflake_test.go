@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/export/internal/domain"
+)
+
+func newTestSnowflakeLoader(t *testing.T, serverURL string, columns []domain.ColumnMapping) *SnowflakeLoader {
+	t.Helper()
+	keyPath, _ := writeTestKey(t)
+	loader, err := NewSnowflakeLoader(domain.Destination{
+		Name:    "sf",
+		Type:    domain.TypeSnowflake,
+		Columns: columns,
+		Snowflake: &domain.SnowflakeConfig{
+			Account:        "xy12345.eu-west-1",
+			User:           "loader",
+			PrivateKeyFile: keyPath,
+			Warehouse:      "LOAD_WH",
+			Database:       "ANALYTICS",
+			Schema:         "PUBLIC",
+			Table:          "EVENTS",
+			Stage:          "causality_stage",
+			StagePrefix:    "events/",
+			APIURL:         serverURL,
+		},
+	}, time.Second)
+	if err != nil {
+		t.Fatalf("NewSnowflakeLoader() error = %v", err)
+	}
+	loader.interval = time.Millisecond
+	return loader
+}
+
+func TestSnowflakeLoader_CopyStatement(t *testing.T) {
+	keys := []string{"events/app_id=a/year=2026/f1.parquet", "events/app_id=a/year=2026/it's.parquet"}
+
+	loader := newTestSnowflakeLoader(t, "http://unused", nil)
+	want := "COPY INTO EVENTS FROM @causality_stage" +
+		" FILES = ('app_id=a/year=2026/f1.parquet', 'app_id=a/year=2026/it''s.parquet')" +
+		" FILE_FORMAT = (TYPE = PARQUET) MATCH_BY_COLUMN_NAME = CASE_INSENSITIVE"
+	if got := loader.copyStatement(keys); got != want {
+		t.Errorf("copyStatement() =\n%s\nwant\n%s", got, want)
+	}
+
+	loader = newTestSnowflakeLoader(t, "http://unused", []domain.ColumnMapping{
+		{Source: "id", Target: "EVENT_ID"},
+		{Source: "event_type", Target: "NAME"},
+	})
+	want = `COPY INTO EVENTS (EVENT_ID, NAME) FROM (SELECT $1:"id", $1:"event_type" FROM @causality_stage)` +
+		" FILES = ('app_id=a/year=2026/f1.parquet')" +
+		" FILE_FORMAT = (TYPE = PARQUET)"
+	if got := loader.copyStatement(keys[:1]); got != want {
+		t.Errorf("copyStatement() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSnowflakeLoader_Load(t *testing.T) {
+	var (
+		mu         sync.Mutex
+		statements []map[string]string
+		polls      int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Snowflake-Authorization-Token-Type") != "KEYPAIR_JWT" ||
+			!strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v2/statements":
+			var body map[string]string
+			data, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(data, &body)
+			statements = append(statements, body)
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = io.WriteString(w, `{"statementHandle":"h-1","message":"Asynchronous execution in progress."}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v2/statements/h-1":
+			polls++
+			if polls%2 == 1 {
+				w.WriteHeader(http.StatusAccepted)
+				_, _ = io.WriteString(w, `{"statementHandle":"h-1"}`)
+				return
+			}
+			_, _ = io.WriteString(w, `{
+				"resultSetMetaData": {"rowType": [{"name": "file"}, {"name": "status"}, {"name": "rows_parsed"}, {"name": "rows_loaded"}]},
+				"data": [["f1", "LOADED", "3", "3"], ["f2", "LOADED", "4", "4"]]
+			}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	loader := newTestSnowflakeLoader(t, server.URL, nil)
+
+	keys := make([]string, snowflakeMaxFiles+1)
+	for i := range keys {
+		keys[i] = "events/f.parquet"
+	}
+	loaded, err := loader.Load(context.Background(), LoadRequest{AppID: "app", Keys: keys, Attempt: 1})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// Two statements, each summing rows_loaded over two files.
+	if loaded != 14 {
+		t.Errorf("Load() = %d, want 14", loaded)
+	}
+	if len(statements) != 2 {
+		t.Fatalf("statements = %d, want 2", len(statements))
+	}
+	s := statements[0]
+	if s["database"] != "ANALYTICS" || s["schema"] != "PUBLIC" || s["warehouse"] != "LOAD_WH" {
+		t.Errorf("statement context = %v", s)
+	}
+	if !strings.HasPrefix(s["statement"], "COPY INTO EVENTS ") {
+		t.Errorf("statement = %q, want a COPY INTO", s["statement"])
+	}
+}
+
+func TestSnowflakeLoader_LoadError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = io.WriteString(w, `{"message":"Table 'EVENTS' does not exist"}`)
+	}))
+	defer server.Close()
+
+	loader := newTestSnowflakeLoader(t, server.URL, nil)
+	_, err := loader.Load(context.Background(), LoadRequest{AppID: "app", Keys: []string{"events/f.parquet"}})
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("Load() error = %v, want the statement error", err)
+	}
+}
+
+func TestSnowflakeLoader_Issuer(t *testing.T) {
+	loader := newTestSnowflakeLoader(t, "http://unused", nil)
+	if loader.subject != "XY12345.LOADER" {
+		t.Errorf("subject = %q, want XY12345.LOADER", loader.subject)
+	}
+	if !strings.HasPrefix(loader.issuer, "XY12345.LOADER.SHA256:") {
+		t.Errorf("issuer = %q, want the key fingerprint", loader.issuer)
+	}
+}
@@ -0,0 +1,11 @@
+-- Per-destination, per-app export progress: every partition window before
+-- exported_until is loaded into the destination
+CREATE TABLE IF NOT EXISTS export_watermarks (
+    destination    TEXT NOT NULL,
+    app_id         TEXT NOT NULL,
+    exported_until TIMESTAMPTZ NOT NULL,
+    attempts       INTEGER NOT NULL DEFAULT 0,
+    last_error     TEXT NOT NULL DEFAULT '',
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (destination, app_id)
+);
@@ -0,0 +1,180 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/db"
+	"github.com/SebastienMelki/causality/internal/export/internal/domain"
+	"github.com/SebastienMelki/causality/internal/export/internal/repo"
+	"github.com/SebastienMelki/causality/internal/export/internal/service"
+	"github.com/SebastienMelki/causality/internal/export/migrations"
+	"github.com/SebastienMelki/causality/internal/schedule"
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+// Migrations are the export module's database migrations.
+var Migrations = db.Source{Name: "export", FS: migrations.FS}
+
+// Config holds configuration for the export module.
+type Config struct {
+	// Enabled controls whether the export job runs.
+	Enabled bool `env:"EXPORT_ENABLED" envDefault:"false"`
+
+	// Cron is the cron expression the job runs on (e.g. "45 * * * *" or "@hourly").
+	Cron string `env:"EXPORT_CRON" envDefault:"45 * * * *"`
+
+	// DestinationsFile is the JSON file listing the export destinations.
+	DestinationsFile string `env:"EXPORT_DESTINATIONS_FILE"`
+
+	// SettleDelay is how long after a partition window ends before it is
+	// exported. It should cover the compaction interval so windows are
+	// loaded once their files are merged.
+	SettleDelay time.Duration `env:"EXPORT_SETTLE_DELAY" envDefault:"2h"`
+
+	// Backfill is how far back the export of a new destination or app starts.
+	Backfill time.Duration `env:"EXPORT_BACKFILL" envDefault:"24h"`
+
+	// MaxAttempts is the number of loads of a window per run before the app
+	// is skipped until the next run.
+	MaxAttempts int `env:"EXPORT_MAX_ATTEMPTS" envDefault:"3"`
+
+	// RetryBackoff is the wait before retrying a failed load, doubled for
+	// each further attempt.
+	RetryBackoff time.Duration `env:"EXPORT_RETRY_BACKOFF" envDefault:"30s"`
+
+	// Timeout bounds each destination API request, including uploads.
+	Timeout time.Duration `env:"EXPORT_TIMEOUT" envDefault:"10m"`
+}
+
+// ErrNoDestinations is returned by New when the export is enabled without
+// a destinations file.
+var ErrNoDestinations = errors.New("export enabled without EXPORT_DESTINATIONS_FILE")
+
+// Module is the export module facade. It wires the PostgreSQL repository,
+// warehouse reader, destination loaders, export job, and cron scheduler.
+type Module struct {
+	job       *service.Job
+	scheduler *schedule.Scheduler
+	config    Config
+	logger    *slog.Logger
+}
+
+// New creates a new export module.
+//
+// Parameters:
+//   - s3Client: the object API (AWS S3 client or FSStore) used to read warehouse partitions
+//   - s3Config: S3 configuration of the event lake (bucket, prefix)
+//   - deltaLog: Delta Lake transaction log; when non-nil, files removed by
+//     compaction but not yet vacuumed are not exported
+//   - db: database holding export_watermarks
+//   - cfg: export module configuration
+//   - logger: structured logger
+//
+// When enabled, it fails if the cron expression, the destinations file, or
+// a destination's credentials are invalid.
+func New(
+	s3Client warehouse.ObjectAPI,
+	s3Config warehouse.S3Config,
+	deltaLog *warehouse.DeltaLog,
+	db *sql.DB,
+	cfg Config,
+	logger *slog.Logger,
+) (*Module, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	m := &Module{
+		config: cfg,
+		logger: logger.With("component", "export-module"),
+	}
+	if !cfg.Enabled {
+		return m, nil
+	}
+	if cfg.DestinationsFile == "" {
+		return nil, ErrNoDestinations
+	}
+
+	configs, err := repo.LoadDestinations(cfg.DestinationsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := warehouse.NewPartitionReader(s3Client, s3Config, deltaLog)
+	destinations := make([]service.Destination, 0, len(configs))
+	for _, dest := range configs {
+		loader, err := newLoader(dest, reader, cfg.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		destinations = append(destinations, service.Destination{Config: dest, Loader: loader})
+	}
+
+	m.job = service.NewJob(
+		repo.NewWatermarkRepository(db),
+		reader,
+		destinations,
+		service.JobConfig{
+			SettleDelay:  cfg.SettleDelay,
+			Backfill:     cfg.Backfill,
+			MaxAttempts:  cfg.MaxAttempts,
+			RetryBackoff: cfg.RetryBackoff,
+		},
+		logger,
+	)
+
+	m.scheduler, err = schedule.New("warehouse export", cfg.Cron, m.job.Run, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// newLoader creates the loader of a destination's type.
+func newLoader(dest domain.Destination, reader *warehouse.PartitionReader, timeout time.Duration) (service.Loader, error) {
+	switch dest.Type {
+	case domain.TypeBigQuery:
+		return service.NewBigQueryLoader(dest, reader, timeout)
+	case domain.TypeSnowflake:
+		return service.NewSnowflakeLoader(dest, timeout)
+	default:
+		return nil, fmt.Errorf("%w: %s: unknown type %q", ErrInvalidDestination, dest.Name, dest.Type)
+	}
+}
+
+// Start begins the scheduled export job. If the job is disabled via config,
+// this is a no-op.
+func (m *Module) Start(ctx context.Context) {
+	if !m.config.Enabled {
+		m.logger.Info("warehouse export disabled, skipping start")
+		return
+	}
+
+	m.logger.Info("starting export module",
+		"cron", m.config.Cron,
+		"destinations_file", m.config.DestinationsFile,
+		"settle_delay", m.config.SettleDelay,
+	)
+	m.scheduler.Start(ctx)
+}
+
+// Stop stops the export scheduler.
+func (m *Module) Stop() {
+	if m.scheduler != nil {
+		m.scheduler.Stop()
+	}
+}
+
+// RunNow runs the export job immediately.
+func (m *Module) RunNow(ctx context.Context) error {
+	if m.job == nil {
+		return nil
+	}
+	return m.job.Run(ctx)
+}
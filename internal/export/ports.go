@@ -0,0 +1,48 @@
+// Package export provides the warehouse export job. On a cron schedule it
+// loads the event lake's settled partition windows (by default an hour old
+// or more, once compaction has merged them) into external warehouses:
+// BigQuery through load jobs and Snowflake through COPY INTO from an
+// external stage. Destinations, their app filter and column mapping are
+// configured in a JSON file. Progress is tracked per destination and app
+// in the export_watermarks table, so each window is loaded once, in order,
+// and failed loads are retried with backoff on this and later runs.
+package export
+
+import (
+	"context"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/export/internal/domain"
+)
+
+// Destination is an external warehouse the event lake is exported to.
+type Destination = domain.Destination
+
+// ColumnMapping exports an event column under a destination column name.
+type ColumnMapping = domain.ColumnMapping
+
+// Watermark is the export progress of one app to one destination.
+type Watermark = domain.Watermark
+
+// Destination types.
+const (
+	TypeBigQuery  = domain.TypeBigQuery
+	TypeSnowflake = domain.TypeSnowflake
+)
+
+// ErrInvalidDestination is returned when a destination fails validation.
+var ErrInvalidDestination = domain.ErrInvalidDestination
+
+// Store defines the port for export progress persistence.
+type Store interface {
+	// Watermarks returns, per app, the export progress to a destination.
+	Watermarks(ctx context.Context, destination string) (map[string]Watermark, error)
+
+	// Advance moves an app's watermark for a destination to until and
+	// clears its failed attempts.
+	Advance(ctx context.Context, destination, appID string, until time.Time) error
+
+	// RecordFailure counts a failed load of the window at an app's
+	// watermark for a destination.
+	RecordFailure(ctx context.Context, destination, appID string, window time.Time, loadErr error) error
+}
@@ -0,0 +1,138 @@
+// Package domain contains the core types for per-user feature extraction.
+package domain
+
+import (
+	"sync"
+	"time"
+)
+
+// Platform buckets used for the platform mix features.
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformWeb     = "web"
+	PlatformOther   = "other"
+)
+
+// Observation is the feature-relevant content of a single event.
+type Observation struct {
+	AppID    string
+	DeviceID string
+
+	// UserID is set for events that identify the user (login, signup).
+	UserID string
+
+	At       time.Time
+	Platform string
+
+	// SessionStart is true for events that begin a session (app_start).
+	SessionStart bool
+
+	// Purchase is true for completed purchases, with RevenueCents their total.
+	Purchase     bool
+	RevenueCents int64
+}
+
+// ActivityKey identifies one user's activity on one UTC day. Users are keyed
+// by device_id; UserID is attached once the device identifies a user.
+type ActivityKey struct {
+	AppID    string
+	DeviceID string
+	Day      time.Time
+}
+
+// Activity holds one user's activity accumulated for a day.
+type Activity struct {
+	UserID        string
+	Events        int64
+	Sessions      int64
+	Purchases     int64
+	RevenueCents  int64
+	IOSEvents     int64
+	AndroidEvents int64
+	WebEvents     int64
+	OtherEvents   int64
+	LastEventAt   time.Time
+}
+
+// add merges an observation into the activity.
+func (a *Activity) add(o Observation) {
+	if o.UserID != "" {
+		a.UserID = o.UserID
+	}
+	a.Events++
+	if o.SessionStart {
+		a.Sessions++
+	}
+	if o.Purchase {
+		a.Purchases++
+		a.RevenueCents += o.RevenueCents
+	}
+	switch o.Platform {
+	case PlatformIOS:
+		a.IOSEvents++
+	case PlatformAndroid:
+		a.AndroidEvents++
+	case PlatformWeb:
+		a.WebEvents++
+	default:
+		a.OtherEvents++
+	}
+	if o.At.After(a.LastEventAt) {
+		a.LastEventAt = o.At
+	}
+}
+
+// DayOf truncates t to its UTC calendar day.
+func DayOf(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// WindowStart returns the first UTC day of a window of days days ending on
+// the day containing now.
+func WindowStart(now time.Time, days int) time.Time {
+	if days < 1 {
+		days = 1
+	}
+	return DayOf(now).AddDate(0, 0, -(days - 1))
+}
+
+// Aggregator accumulates per-user daily activity in memory between flushes.
+// It is safe for concurrent use.
+type Aggregator struct {
+	mu       sync.Mutex
+	activity map[ActivityKey]*Activity
+}
+
+// NewAggregator creates an empty Aggregator.
+func NewAggregator() *Aggregator {
+	return &Aggregator{activity: make(map[ActivityKey]*Activity)}
+}
+
+// Add records an observation on the UTC day containing o.At.
+func (a *Aggregator) Add(o Observation) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := ActivityKey{AppID: o.AppID, DeviceID: o.DeviceID, Day: DayOf(o.At)}
+	act, ok := a.activity[key]
+	if !ok {
+		act = &Activity{}
+		a.activity[key] = act
+	}
+	act.add(o)
+}
+
+// Drain returns the accumulated activity and resets the aggregator.
+func (a *Aggregator) Drain() map[ActivityKey]Activity {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	drained := make(map[ActivityKey]Activity, len(a.activity))
+	for key, act := range a.activity {
+		drained[key] = *act
+	}
+	a.activity = make(map[ActivityKey]*Activity)
+	return drained
+}
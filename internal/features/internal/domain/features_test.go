@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowStart(t *testing.T) {
+	now := time.Date(2025, 3, 30, 18, 0, 0, 0, time.UTC)
+
+	if got, want := WindowStart(now, 30), time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("WindowStart(30) = %v, want %v", got, want)
+	}
+	if got, want := WindowStart(now, 0), DayOf(now); !got.Equal(want) {
+		t.Errorf("WindowStart(0) = %v, want %v", got, want)
+	}
+}
+
+func TestAggregator_AddAndDrain(t *testing.T) {
+	agg := NewAggregator()
+	day1 := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	day2 := time.Date(2025, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	agg.Add(Observation{AppID: "app", DeviceID: "d1", At: day1, Platform: PlatformIOS, SessionStart: true})
+	agg.Add(Observation{AppID: "app", DeviceID: "d1", At: day1.Add(2 * time.Hour), Platform: PlatformIOS, UserID: "u1"})
+	agg.Add(Observation{AppID: "app", DeviceID: "d1", At: day1.Add(time.Hour), Platform: PlatformWeb, Purchase: true, RevenueCents: 1299})
+	agg.Add(Observation{AppID: "app", DeviceID: "d1", At: day2, Platform: "unknown"})
+	agg.Add(Observation{AppID: "app", DeviceID: "d2", At: day1, Platform: PlatformAndroid})
+
+	activity := agg.Drain()
+	if len(activity) != 3 {
+		t.Fatalf("got %d keys, want 3", len(activity))
+	}
+
+	got := activity[ActivityKey{AppID: "app", DeviceID: "d1", Day: DayOf(day1)}]
+	want := Activity{
+		UserID:       "u1",
+		Events:       3,
+		Sessions:     1,
+		Purchases:    1,
+		RevenueCents: 1299,
+		IOSEvents:    2,
+		WebEvents:    1,
+		LastEventAt:  day1.Add(2 * time.Hour),
+	}
+	if got != want {
+		t.Errorf("d1 day1 = %+v, want %+v", got, want)
+	}
+
+	if other := activity[ActivityKey{AppID: "app", DeviceID: "d1", Day: DayOf(day2)}]; other.OtherEvents != 1 {
+		t.Errorf("d1 day2 other events = %d, want 1", other.OtherEvents)
+	}
+
+	if again := agg.Drain(); len(again) != 0 {
+		t.Errorf("Drain after Drain returned %d keys, want 0", len(again))
+	}
+}
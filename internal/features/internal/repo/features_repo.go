@@ -0,0 +1,170 @@
+// Package repo provides the PostgreSQL implementation of the features Store port.
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/features/internal/domain"
+)
+
+// FeatureRepository implements the Store interface using PostgreSQL.
+type FeatureRepository struct {
+	db *sql.DB
+}
+
+// NewFeatureRepository creates a new FeatureRepository backed by the given database.
+func NewFeatureRepository(db *sql.DB) *FeatureRepository {
+	return &FeatureRepository{db: db}
+}
+
+// AddActivity atomically merges per-user daily activity into
+// user_activity_daily. A non-empty user_id replaces the stored one.
+func (r *FeatureRepository) AddActivity(ctx context.Context, activity map[domain.ActivityKey]domain.Activity) error {
+	if len(activity) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO user_activity_daily (
+			app_id, device_id, day, user_id, events, sessions, purchases, revenue_cents,
+			ios_events, android_events, web_events, other_events, last_event_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (app_id, device_id, day) DO UPDATE
+		SET user_id        = COALESCE(NULLIF(EXCLUDED.user_id, ''), user_activity_daily.user_id),
+		    events         = user_activity_daily.events + EXCLUDED.events,
+		    sessions       = user_activity_daily.sessions + EXCLUDED.sessions,
+		    purchases      = user_activity_daily.purchases + EXCLUDED.purchases,
+		    revenue_cents  = user_activity_daily.revenue_cents + EXCLUDED.revenue_cents,
+		    ios_events     = user_activity_daily.ios_events + EXCLUDED.ios_events,
+		    android_events = user_activity_daily.android_events + EXCLUDED.android_events,
+		    web_events     = user_activity_daily.web_events + EXCLUDED.web_events,
+		    other_events   = user_activity_daily.other_events + EXCLUDED.other_events,
+		    last_event_at  = GREATEST(user_activity_daily.last_event_at, EXCLUDED.last_event_at)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare activity upsert: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for key, a := range activity {
+		if _, err := stmt.ExecContext(ctx,
+			key.AppID,
+			key.DeviceID,
+			key.Day,
+			a.UserID,
+			a.Events,
+			a.Sessions,
+			a.Purchases,
+			a.RevenueCents,
+			a.IOSEvents,
+			a.AndroidEvents,
+			a.WebEvents,
+			a.OtherEvents,
+			a.LastEventAt,
+		); err != nil {
+			return fmt.Errorf("failed to upsert activity for device %s: %w", key.DeviceID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit activity: %w", err)
+	}
+
+	return nil
+}
+
+// RefreshFeatures recomputes user_features from the last windowDays days of
+// activity, removes vectors for users with no activity in the window, and
+// prunes activity older than retentionDays. It runs in one transaction so
+// readers never observe a partially refreshed table. It returns the number
+// of feature vectors written.
+func (r *FeatureRepository) RefreshFeatures(ctx context.Context, now time.Time, windowDays, retentionDays int) (int64, error) {
+	windowStart := domain.WindowStart(now, windowDays)
+	retentionStart := domain.WindowStart(now, retentionDays)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO user_features (
+			app_id, device_id, user_id, recency_days, active_days, events, sessions,
+			purchases, monetary_cents, ios_share, android_share, web_share, other_share,
+			first_seen_day, last_seen_at, window_days, computed_at
+		)
+		SELECT
+			app_id,
+			device_id,
+			COALESCE((ARRAY_AGG(user_id ORDER BY day DESC) FILTER (WHERE user_id <> ''))[1], ''),
+			GREATEST(EXTRACT(EPOCH FROM ($1::TIMESTAMPTZ - MAX(last_event_at))) / 86400, 0),
+			COUNT(*),
+			SUM(events),
+			SUM(sessions),
+			SUM(purchases),
+			SUM(revenue_cents),
+			SUM(ios_events)::DOUBLE PRECISION / NULLIF(SUM(events), 0),
+			SUM(android_events)::DOUBLE PRECISION / NULLIF(SUM(events), 0),
+			SUM(web_events)::DOUBLE PRECISION / NULLIF(SUM(events), 0),
+			SUM(other_events)::DOUBLE PRECISION / NULLIF(SUM(events), 0),
+			MIN(day),
+			MAX(last_event_at),
+			$3::INTEGER,
+			$1::TIMESTAMPTZ
+		FROM user_activity_daily
+		WHERE day >= $2
+		GROUP BY app_id, device_id
+		HAVING SUM(events) > 0
+		ON CONFLICT (app_id, device_id) DO UPDATE
+		SET user_id        = EXCLUDED.user_id,
+		    recency_days   = EXCLUDED.recency_days,
+		    active_days    = EXCLUDED.active_days,
+		    events         = EXCLUDED.events,
+		    sessions       = EXCLUDED.sessions,
+		    purchases      = EXCLUDED.purchases,
+		    monetary_cents = EXCLUDED.monetary_cents,
+		    ios_share      = EXCLUDED.ios_share,
+		    android_share  = EXCLUDED.android_share,
+		    web_share      = EXCLUDED.web_share,
+		    other_share    = EXCLUDED.other_share,
+		    first_seen_day = EXCLUDED.first_seen_day,
+		    last_seen_at   = EXCLUDED.last_seen_at,
+		    window_days    = EXCLUDED.window_days,
+		    computed_at    = EXCLUDED.computed_at
+	`, now, windowStart, windowDays)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute user features: %w", err)
+	}
+	written, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM user_features WHERE computed_at < $1`, now,
+	); err != nil {
+		return 0, fmt.Errorf("failed to delete stale user features: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM user_activity_daily WHERE day < $1`, retentionStart,
+	); err != nil {
+		return 0, fmt.Errorf("failed to prune user activity: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit feature refresh: %w", err)
+	}
+
+	return written, nil
+}
@@ -0,0 +1,259 @@
+// Package service implements the feature sink: a JetStream consumer that
+// aggregates per-user daily activity, and a scheduled refresher that
+// recomputes rolling feature vectors from it.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/SebastienMelki/causality/internal/events"
+	"github.com/SebastienMelki/causality/internal/features/internal/domain"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// FeatureStore defines the persistence interface needed by the feature
+// services. This mirrors the features.Store port to avoid import cycles.
+type FeatureStore interface {
+	AddActivity(ctx context.Context, activity map[domain.ActivityKey]domain.Activity) error
+	RefreshFeatures(ctx context.Context, now time.Time, windowDays, retentionDays int) (int64, error)
+}
+
+// maxClockSkew bounds how far a client timestamp may lead the server's
+// receive time before the receive time is used instead.
+const maxClockSkew = 5 * time.Minute
+
+// Extractor consumes events from the event stream and maintains per-user
+// daily activity. Each fetched batch is aggregated in memory, persisted in
+// one transaction, and only then acked (at-least-once: a crash between
+// commit and ack can double count a single batch).
+type Extractor struct {
+	js             jetstream.JetStream
+	store          FeatureStore
+	streamName     string
+	consumerName   string
+	fetchBatchSize int
+	fetchMaxWait   time.Duration
+	logger         *slog.Logger
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewExtractor creates a new feature extractor for the given durable consumer.
+func NewExtractor(
+	js jetstream.JetStream,
+	store FeatureStore,
+	streamName string,
+	consumerName string,
+	fetchBatchSize int,
+	fetchMaxWait time.Duration,
+	logger *slog.Logger,
+) *Extractor {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if fetchBatchSize < 1 {
+		fetchBatchSize = 500
+	}
+	if fetchMaxWait <= 0 {
+		fetchMaxWait = 5 * time.Second
+	}
+
+	return &Extractor{
+		js:             js,
+		store:          store,
+		streamName:     streamName,
+		consumerName:   consumerName,
+		fetchBatchSize: fetchBatchSize,
+		fetchMaxWait:   fetchMaxWait,
+		logger:         logger.With("component", "feature-extractor"),
+		stopCh:         make(chan struct{}),
+		doneCh:         make(chan struct{}),
+	}
+}
+
+// Start looks up the durable consumer and begins the fetch loop.
+func (e *Extractor) Start(ctx context.Context) error {
+	stream, err := e.js.Stream(ctx, e.streamName)
+	if err != nil {
+		return fmt.Errorf("failed to get stream: %w", err)
+	}
+
+	consumer, err := stream.Consumer(ctx, e.consumerName)
+	if err != nil {
+		return fmt.Errorf("failed to get consumer: %w", err)
+	}
+
+	e.logger.Info("starting feature extractor",
+		"stream", e.streamName,
+		"consumer", e.consumerName,
+		"fetch_batch_size", e.fetchBatchSize,
+	)
+
+	go e.run(ctx, consumer)
+	return nil
+}
+
+// Stop signals the fetch loop to stop and waits for the in-flight batch.
+func (e *Extractor) Stop() {
+	e.stopOnce.Do(func() {
+		close(e.stopCh)
+	})
+	<-e.doneCh
+}
+
+// run is the main fetch loop.
+func (e *Extractor) run(ctx context.Context, consumer jetstream.Consumer) {
+	defer close(e.doneCh)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.stopCh:
+			return
+		default:
+		}
+
+		msgs, err := consumer.Fetch(e.fetchBatchSize, jetstream.FetchMaxWait(e.fetchMaxWait))
+		if err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) {
+				e.logger.Error("failed to fetch messages", "error", err)
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+					return
+				case <-e.stopCh:
+					return
+				}
+			}
+			continue
+		}
+
+		var batch []jetstream.Msg
+		for msg := range msgs.Messages() {
+			batch = append(batch, msg)
+		}
+		if err := msgs.Error(); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			e.logger.Warn("fetch completed with error", "error", err)
+		}
+
+		e.processBatch(ctx, batch)
+	}
+}
+
+// processBatch aggregates a fetched batch, persists it, and acks on success.
+// Unparseable messages are terminated so they are not redelivered.
+func (e *Extractor) processBatch(ctx context.Context, batch []jetstream.Msg) {
+	if len(batch) == 0 {
+		return
+	}
+
+	agg := domain.NewAggregator()
+	counted := make([]jetstream.Msg, 0, len(batch))
+
+	for _, msg := range batch {
+		receivedAt := time.Now()
+		if meta, err := msg.Metadata(); err == nil {
+			receivedAt = meta.Timestamp
+		}
+
+		obs, err := observationFromMessage(msg.Data(), receivedAt)
+		if err != nil {
+			e.logger.Warn("terminating unparseable message",
+				"subject", msg.Subject(),
+				"error", err,
+			)
+			_ = msg.Term()
+			continue
+		}
+
+		agg.Add(obs)
+		counted = append(counted, msg)
+	}
+
+	if err := e.store.AddActivity(ctx, agg.Drain()); err != nil {
+		e.logger.Error("failed to persist user activity, will redeliver",
+			"messages", len(counted),
+			"error", err,
+		)
+		for _, msg := range counted {
+			_ = msg.Nak()
+		}
+		return
+	}
+
+	for _, msg := range counted {
+		if err := msg.Ack(); err != nil {
+			e.logger.Warn("failed to ack message", "subject", msg.Subject(), "error", err)
+		}
+	}
+
+	e.logger.Debug("feature batch recorded", "messages", len(counted))
+}
+
+// observationFromMessage extracts the feature-relevant fields from a
+// serialized EventEnvelope. The client timestamp is used unless it is
+// missing or ahead of receivedAt by more than maxClockSkew.
+func observationFromMessage(data []byte, receivedAt time.Time) (domain.Observation, error) {
+	var event pb.EventEnvelope
+	if err := events.Decode(data, &event); err != nil {
+		return domain.Observation{}, fmt.Errorf("unmarshal event: %w", err)
+	}
+	if event.GetAppId() == "" {
+		return domain.Observation{}, errors.New("event has no app_id")
+	}
+	if event.GetDeviceId() == "" {
+		return domain.Observation{}, errors.New("event has no device_id")
+	}
+
+	at := receivedAt
+	if ms := event.GetTimestampMs(); ms > 0 {
+		if ts := time.UnixMilli(ms); !ts.After(receivedAt.Add(maxClockSkew)) {
+			at = ts
+		}
+	}
+
+	obs := domain.Observation{
+		AppID:    event.GetAppId(),
+		DeviceID: event.GetDeviceId(),
+		At:       at.UTC(),
+		Platform: platformOf(event.GetDeviceContext().GetPlatform()),
+	}
+
+	switch {
+	case event.GetUserLogin() != nil:
+		obs.UserID = event.GetUserLogin().GetUserId()
+	case event.GetUserSignup() != nil:
+		obs.UserID = event.GetUserSignup().GetUserId()
+	case event.GetAppStart() != nil:
+		obs.SessionStart = true
+	case event.GetPurchaseComplete() != nil:
+		obs.Purchase = true
+		obs.RevenueCents = event.GetPurchaseComplete().GetTotalCents()
+	}
+
+	return obs, nil
+}
+
+// platformOf maps a protobuf platform to its feature bucket.
+func platformOf(p pb.Platform) string {
+	switch p {
+	case pb.Platform_PLATFORM_IOS:
+		return domain.PlatformIOS
+	case pb.Platform_PLATFORM_ANDROID:
+		return domain.PlatformAndroid
+	case pb.Platform_PLATFORM_WEB:
+		return domain.PlatformWeb
+	default:
+		return domain.PlatformOther
+	}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/SebastienMelki/causality/internal/features/internal/domain"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+func marshalEvent(t *testing.T, event *pb.EventEnvelope) []byte {
+	t.Helper()
+	data, err := proto.Marshal(event)
+	if err != nil {
+		t.Fatalf("marshal event: %v", err)
+	}
+	return data
+}
+
+func TestObservationFromMessage(t *testing.T) {
+	receivedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	sentAt := receivedAt.Add(-time.Minute)
+
+	tests := []struct {
+		name  string
+		event *pb.EventEnvelope
+		want  domain.Observation
+	}{
+		{
+			name: "purchase",
+			event: &pb.EventEnvelope{
+				AppId:         "app",
+				DeviceId:      "d1",
+				TimestampMs:   sentAt.UnixMilli(),
+				DeviceContext: &pb.DeviceContext{Platform: pb.Platform_PLATFORM_ANDROID},
+				Payload: &pb.EventEnvelope_PurchaseComplete{
+					PurchaseComplete: &pb.PurchaseComplete{TotalCents: 4999},
+				},
+			},
+			want: domain.Observation{
+				AppID: "app", DeviceID: "d1", At: sentAt,
+				Platform: domain.PlatformAndroid, Purchase: true, RevenueCents: 4999,
+			},
+		},
+		{
+			name: "login identifies user",
+			event: &pb.EventEnvelope{
+				AppId:         "app",
+				DeviceId:      "d1",
+				TimestampMs:   sentAt.UnixMilli(),
+				DeviceContext: &pb.DeviceContext{Platform: pb.Platform_PLATFORM_IOS},
+				Payload: &pb.EventEnvelope_UserLogin{
+					UserLogin: &pb.UserLogin{UserId: "u1"},
+				},
+			},
+			want: domain.Observation{
+				AppID: "app", DeviceID: "d1", UserID: "u1", At: sentAt,
+				Platform: domain.PlatformIOS,
+			},
+		},
+		{
+			name: "app start in the future uses receive time",
+			event: &pb.EventEnvelope{
+				AppId:       "app",
+				DeviceId:    "d1",
+				TimestampMs: receivedAt.Add(time.Hour).UnixMilli(),
+				Payload:     &pb.EventEnvelope_AppStart{AppStart: &pb.AppStart{}},
+			},
+			want: domain.Observation{
+				AppID: "app", DeviceID: "d1", At: receivedAt,
+				Platform: domain.PlatformOther, SessionStart: true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := observationFromMessage(marshalEvent(t, tt.event), receivedAt)
+			if err != nil {
+				t.Fatalf("observationFromMessage() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("observationFromMessage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObservationFromMessage_RequiresIDs(t *testing.T) {
+	data := marshalEvent(t, &pb.EventEnvelope{AppId: "app"})
+	if _, err := observationFromMessage(data, time.Now()); err == nil {
+		t.Error("expected error for event without device_id")
+	}
+	if _, err := observationFromMessage([]byte("not protobuf"), time.Now()); err == nil {
+		t.Error("expected error for unparseable message")
+	}
+}
+
+// mockStore is an in-memory FeatureStore for testing.
+type mockStore struct {
+	now                       time.Time
+	windowDays, retentionDays int
+}
+
+func (m *mockStore) AddActivity(context.Context, map[domain.ActivityKey]domain.Activity) error {
+	return nil
+}
+
+func (m *mockStore) RefreshFeatures(_ context.Context, now time.Time, windowDays, retentionDays int) (int64, error) {
+	m.now, m.windowDays, m.retentionDays = now, windowDays, retentionDays
+	return 1, nil
+}
+
+func TestRefresher_RefreshOnce(t *testing.T) {
+	store := &mockStore{}
+	r := NewRefresher(store, time.Hour, 30, 7, nil)
+	fixed := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return fixed }
+
+	if err := r.RefreshOnce(context.Background()); err != nil {
+		t.Fatalf("RefreshOnce() error = %v", err)
+	}
+	if !store.now.Equal(fixed) || store.windowDays != 30 {
+		t.Errorf("got now=%v window=%d, want %v and 30", store.now, store.windowDays, fixed)
+	}
+	if store.retentionDays != 30 {
+		t.Errorf("retention = %d, want raised to window (30)", store.retentionDays)
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Refresher periodically recomputes the rolling per-user feature vectors
+// from daily activity and prunes activity past retention.
+type Refresher struct {
+	store         FeatureStore
+	interval      time.Duration
+	windowDays    int
+	retentionDays int
+	now           func() time.Time
+	logger        *slog.Logger
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewRefresher creates a new feature refresher. Retention is raised to the
+// window length if it is shorter, so the window is always fully populated.
+func NewRefresher(
+	store FeatureStore,
+	interval time.Duration,
+	windowDays int,
+	retentionDays int,
+	logger *slog.Logger,
+) *Refresher {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	if windowDays < 1 {
+		windowDays = 30
+	}
+	if retentionDays < windowDays {
+		retentionDays = windowDays
+	}
+
+	return &Refresher{
+		store:         store,
+		interval:      interval,
+		windowDays:    windowDays,
+		retentionDays: retentionDays,
+		now:           time.Now,
+		logger:        logger.With("component", "feature-refresher"),
+		stopCh:        make(chan struct{}),
+		doneCh:        make(chan struct{}),
+	}
+}
+
+// Start runs an initial refresh and then begins the periodic refresh loop
+// in a background goroutine.
+func (r *Refresher) Start(ctx context.Context) {
+	r.logger.Info("starting feature refresher",
+		"interval", r.interval,
+		"window_days", r.windowDays,
+		"retention_days", r.retentionDays,
+	)
+
+	go func() {
+		defer close(r.doneCh)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			if err := r.RefreshOnce(ctx); err != nil {
+				r.logger.Error("feature refresh failed", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop signals the refresh loop to stop and waits for it to exit.
+func (r *Refresher) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopCh)
+	})
+	<-r.doneCh
+}
+
+// RefreshOnce recomputes all feature vectors.
+func (r *Refresher) RefreshOnce(ctx context.Context) error {
+	start := r.now()
+	written, err := r.store.RefreshFeatures(ctx, start.UTC(), r.windowDays, r.retentionDays)
+	if err != nil {
+		return err
+	}
+
+	r.logger.Info("user features refreshed",
+		"vectors", written,
+		"duration", time.Since(start),
+	)
+	return nil
+}
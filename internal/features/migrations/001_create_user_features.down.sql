@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_features;
+DROP TABLE IF EXISTS user_activity_daily;
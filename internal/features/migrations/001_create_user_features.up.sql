@@ -0,0 +1,42 @@
+-- Per-user daily activity, aggregated from the event stream (feature-sink)
+CREATE TABLE IF NOT EXISTS user_activity_daily (
+    app_id         TEXT NOT NULL,
+    device_id      TEXT NOT NULL,
+    day            DATE NOT NULL,
+    user_id        TEXT NOT NULL DEFAULT '',
+    events         BIGINT NOT NULL DEFAULT 0,
+    sessions       BIGINT NOT NULL DEFAULT 0,
+    purchases      BIGINT NOT NULL DEFAULT 0,
+    revenue_cents  BIGINT NOT NULL DEFAULT 0,
+    ios_events     BIGINT NOT NULL DEFAULT 0,
+    android_events BIGINT NOT NULL DEFAULT 0,
+    web_events     BIGINT NOT NULL DEFAULT 0,
+    other_events   BIGINT NOT NULL DEFAULT 0,
+    last_event_at  TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (app_id, device_id, day)
+);
+
+-- Retention pruning and window scans by day
+CREATE INDEX IF NOT EXISTS idx_user_activity_daily_day ON user_activity_daily(day);
+
+-- Rolling per-user feature vectors, recomputed on a schedule
+CREATE TABLE IF NOT EXISTS user_features (
+    app_id         TEXT NOT NULL,
+    device_id      TEXT NOT NULL,
+    user_id        TEXT NOT NULL DEFAULT '',
+    recency_days   DOUBLE PRECISION NOT NULL,
+    active_days    BIGINT NOT NULL,
+    events         BIGINT NOT NULL,
+    sessions       BIGINT NOT NULL,
+    purchases      BIGINT NOT NULL,
+    monetary_cents BIGINT NOT NULL,
+    ios_share      DOUBLE PRECISION NOT NULL,
+    android_share  DOUBLE PRECISION NOT NULL,
+    web_share      DOUBLE PRECISION NOT NULL,
+    other_share    DOUBLE PRECISION NOT NULL,
+    first_seen_day DATE NOT NULL,
+    last_seen_at   TIMESTAMPTZ NOT NULL,
+    window_days    INTEGER NOT NULL,
+    computed_at    TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (app_id, device_id)
+);
@@ -0,0 +1,97 @@
+package features
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/SebastienMelki/causality/internal/db"
+	"github.com/SebastienMelki/causality/internal/features/internal/repo"
+	"github.com/SebastienMelki/causality/internal/features/internal/service"
+	"github.com/SebastienMelki/causality/internal/features/migrations"
+)
+
+// Migrations are the features module's database migrations.
+var Migrations = db.Source{Name: "features", FS: migrations.FS}
+
+// Config holds the features module configuration.
+type Config struct {
+	// ConsumerName is the durable JetStream consumer used for extraction.
+	ConsumerName string `env:"FEATURES_CONSUMER_NAME" envDefault:"feature-sink"`
+
+	// FilterSubject selects which stream subjects feed the features.
+	FilterSubject string `env:"FEATURES_FILTER_SUBJECT" envDefault:"events.>"`
+
+	// FetchBatchSize is the number of messages aggregated per transaction.
+	FetchBatchSize int `env:"FEATURES_FETCH_BATCH_SIZE" envDefault:"500"`
+
+	// FetchMaxWait bounds how long a fetch waits for a full batch.
+	FetchMaxWait time.Duration `env:"FEATURES_FETCH_MAX_WAIT" envDefault:"5s"`
+
+	// RefreshInterval is how often feature vectors are recomputed.
+	RefreshInterval time.Duration `env:"FEATURES_REFRESH_INTERVAL" envDefault:"1h"`
+
+	// WindowDays is the rolling window, in days, that features cover.
+	WindowDays int `env:"FEATURES_WINDOW_DAYS" envDefault:"30"`
+
+	// RetentionDays is how long daily activity is kept. Values shorter than
+	// WindowDays are raised to it.
+	RetentionDays int `env:"FEATURES_RETENTION_DAYS" envDefault:"90"`
+}
+
+// Module is the features module facade. It wires the PostgreSQL repository,
+// stream extractor, and scheduled feature refresher.
+type Module struct {
+	extractor *service.Extractor
+	refresher *service.Refresher
+	config    Config
+	logger    *slog.Logger
+}
+
+// New creates a new features Module consuming from streamName.
+func New(db *sql.DB, js jetstream.JetStream, streamName string, cfg Config, logger *slog.Logger) *Module {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	featureRepo := repo.NewFeatureRepository(db)
+
+	return &Module{
+		extractor: service.NewExtractor(
+			js,
+			featureRepo,
+			streamName,
+			cfg.ConsumerName,
+			cfg.FetchBatchSize,
+			cfg.FetchMaxWait,
+			logger,
+		),
+		refresher: service.NewRefresher(
+			featureRepo,
+			cfg.RefreshInterval,
+			cfg.WindowDays,
+			cfg.RetentionDays,
+			logger,
+		),
+		config: cfg,
+		logger: logger.With("component", "features-module"),
+	}
+}
+
+// Start begins extraction and the feature refresh loop.
+func (m *Module) Start(ctx context.Context) error {
+	if err := m.extractor.Start(ctx); err != nil {
+		return err
+	}
+	m.refresher.Start(ctx)
+	return nil
+}
+
+// Stop stops extraction and the feature refresh loop.
+func (m *Module) Stop() {
+	m.extractor.Stop()
+	m.refresher.Stop()
+}
@@ -0,0 +1,30 @@
+// Package features provides the ML feature sink. It consumes events from
+// the JetStream event stream, maintains per-user daily activity in
+// PostgreSQL, and on a schedule recomputes rolling per-user feature vectors
+// (recency, frequency, monetary value, session counts, and platform mix)
+// into the user_features table.
+package features
+
+import (
+	"context"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/features/internal/domain"
+)
+
+// Activity is one user's activity accumulated for a day.
+type Activity = domain.Activity
+
+// ActivityKey identifies one user's activity on one UTC day.
+type ActivityKey = domain.ActivityKey
+
+// Store defines the port for feature persistence.
+type Store interface {
+	// AddActivity atomically merges per-user daily activity.
+	AddActivity(ctx context.Context, activity map[ActivityKey]Activity) error
+
+	// RefreshFeatures recomputes feature vectors over the last windowDays
+	// days and prunes activity older than retentionDays. It returns the
+	// number of vectors written.
+	RefreshFeatures(ctx context.Context, now time.Time, windowDays, retentionDays int) (int64, error)
+}
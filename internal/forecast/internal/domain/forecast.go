@@ -0,0 +1,204 @@
+// Package domain contains the forecasting model used to learn expected
+// hourly event counts: a linear trend plus hour-of-day or hour-of-week
+// seasonality, with per-season residual spread giving the expected range.
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+// Seasonal periods, in hours.
+const (
+	DailyPeriod  = 24
+	WeeklyPeriod = 7 * 24
+)
+
+// MinHistory is the number of hourly observations required to fit a model.
+const MinHistory = 2 * DailyPeriod
+
+// SeriesKey identifies one forecast series.
+type SeriesKey struct {
+	AppID         string
+	EventCategory string
+	EventType     string
+}
+
+// HourlyCount is the number of events of one series in one UTC hour.
+type HourlyCount struct {
+	SeriesKey
+	Hour  time.Time
+	Count int64
+}
+
+// Baseline is the expected range of a series' event count for one hour.
+type Baseline struct {
+	SeriesKey
+	Hour     time.Time
+	Expected float64
+	Lower    float64
+	Upper    float64
+}
+
+// Model is a fitted additive forecast: trend + seasonal, with the residual
+// standard deviation of each season used for the expected range.
+type Model struct {
+	start     time.Time
+	intercept float64
+	slope     float64
+	period    int
+	seasonal  []float64
+	sigma     []float64
+}
+
+// Fit fits a model to a dense hourly series whose first value is the count
+// for the hour starting at start. Weekly seasonality is used once two full
+// weeks are available, daily otherwise. It returns false if the series is
+// shorter than MinHistory.
+func Fit(start time.Time, counts []float64) (*Model, bool) {
+	n := len(counts)
+	if n < MinHistory {
+		return nil, false
+	}
+
+	period := DailyPeriod
+	if n >= 2*WeeklyPeriod {
+		period = WeeklyPeriod
+	}
+
+	m := &Model{
+		start:    start.UTC().Truncate(time.Hour),
+		period:   period,
+		seasonal: make([]float64, period),
+		sigma:    make([]float64, period),
+	}
+	m.intercept, m.slope = linearFit(counts)
+
+	// Seasonal component: mean detrended value per season, centered so the
+	// trend alone carries the level.
+	sums := make([]float64, period)
+	seen := make([]float64, period)
+	for i, c := range counts {
+		s := m.season(i)
+		sums[s] += c - m.trend(i)
+		seen[s]++
+	}
+	var mean float64
+	for s := range sums {
+		if seen[s] > 0 {
+			m.seasonal[s] = sums[s] / seen[s]
+		}
+		mean += m.seasonal[s]
+	}
+	mean /= float64(period)
+	for s := range m.seasonal {
+		m.seasonal[s] -= mean
+	}
+
+	// Residual spread per season.
+	sq := make([]float64, period)
+	for i, c := range counts {
+		r := c - m.trend(i) - m.seasonal[m.season(i)]
+		sq[m.season(i)] += r * r
+	}
+	for s := range sq {
+		if seen[s] > 1 {
+			m.sigma[s] = math.Sqrt(sq[s] / (seen[s] - 1))
+		}
+	}
+
+	return m, true
+}
+
+// Period returns the seasonal period of the model, in hours.
+func (m *Model) Period() int {
+	return m.period
+}
+
+// Predict returns the expected count for the hour starting at hour and its
+// range of width standard deviations either side. The spread is at least
+// the Poisson noise of the expected count, so sparse series are not flagged
+// on every stray event.
+func (m *Model) Predict(hour time.Time, width float64) (expected, lower, upper float64) {
+	i := int(hour.UTC().Truncate(time.Hour).Sub(m.start) / time.Hour)
+	s := m.season(i)
+
+	expected = math.Max(m.trend(i)+m.seasonal[s], 0)
+	spread := math.Max(m.sigma[s], math.Sqrt(math.Max(expected, 1)))
+	lower = math.Max(expected-width*spread, 0)
+	upper = expected + width*spread
+	return expected, lower, upper
+}
+
+// Forecast returns baselines for hours consecutive hours starting at from.
+func (m *Model) Forecast(key SeriesKey, from time.Time, hours int, width float64) []Baseline {
+	from = from.UTC().Truncate(time.Hour)
+	baselines := make([]Baseline, 0, hours)
+	for h := 0; h < hours; h++ {
+		hour := from.Add(time.Duration(h) * time.Hour)
+		expected, lower, upper := m.Predict(hour, width)
+		baselines = append(baselines, Baseline{
+			SeriesKey: key,
+			Hour:      hour,
+			Expected:  expected,
+			Lower:     lower,
+			Upper:     upper,
+		})
+	}
+	return baselines
+}
+
+// trend returns the trend value at index i.
+func (m *Model) trend(i int) float64 {
+	return m.intercept + m.slope*float64(i)
+}
+
+// season returns the season of index i, measured from the model start so
+// that the same hour of day (or week) always maps to the same season.
+func (m *Model) season(i int) int {
+	hourOfWeek := int(m.start.Weekday())*DailyPeriod + m.start.Hour() + i
+	s := hourOfWeek % m.period
+	if s < 0 {
+		s += m.period
+	}
+	return s
+}
+
+// linearFit returns the least-squares intercept and slope of y over its index.
+func linearFit(y []float64) (intercept, slope float64) {
+	n := float64(len(y))
+	var sx, sy, sxx, sxy float64
+	for i, v := range y {
+		x := float64(i)
+		sx += x
+		sy += v
+		sxx += x * x
+		sxy += x * v
+	}
+	den := n*sxx - sx*sx
+	if den == 0 {
+		return sy / n, 0
+	}
+	slope = (n*sxy - sx*sy) / den
+	intercept = (sy - slope*sx) / n
+	return intercept, slope
+}
+
+// Dense returns the hourly counts of one series between from (inclusive) and
+// to (exclusive), with hours missing from counts filled with zero.
+func Dense(counts map[time.Time]int64, from, to time.Time) []float64 {
+	from = from.UTC().Truncate(time.Hour)
+	to = to.UTC().Truncate(time.Hour)
+	if !to.After(from) {
+		return nil
+	}
+
+	series := make([]float64, int(to.Sub(from)/time.Hour))
+	for hour, c := range counts {
+		i := int(hour.UTC().Truncate(time.Hour).Sub(from) / time.Hour)
+		if i >= 0 && i < len(series) {
+			series[i] = float64(c)
+		}
+	}
+	return series
+}
@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// monday is a Monday midnight UTC, so hour-of-week seasons start at zero.
+var monday = time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+
+func TestFit_TooShort(t *testing.T) {
+	if _, ok := Fit(monday, make([]float64, MinHistory-1)); ok {
+		t.Error("Fit() succeeded on a series shorter than MinHistory")
+	}
+}
+
+func TestFit_DailySeasonality(t *testing.T) {
+	// Three days of 100 events per hour, 400 at 12:00.
+	counts := make([]float64, 3*DailyPeriod)
+	for i := range counts {
+		counts[i] = 100
+		if i%DailyPeriod == 12 {
+			counts[i] = 400
+		}
+	}
+
+	m, ok := Fit(monday, counts)
+	if !ok {
+		t.Fatal("Fit() failed")
+	}
+	if m.Period() != DailyPeriod {
+		t.Errorf("period = %d, want %d", m.Period(), DailyPeriod)
+	}
+
+	// The day after the history ends.
+	next := monday.Add(3 * 24 * time.Hour)
+	if expected, _, _ := m.Predict(next.Add(12*time.Hour), 3); math.Abs(expected-400) > 1 {
+		t.Errorf("expected at noon = %v, want 400", expected)
+	}
+	expected, lower, upper := m.Predict(next.Add(3*time.Hour), 3)
+	if math.Abs(expected-100) > 1 {
+		t.Errorf("expected at 03:00 = %v, want 100", expected)
+	}
+	// No residual noise: the range is the Poisson floor, 3*sqrt(100) = 30.
+	if math.Abs(lower-70) > 1 || math.Abs(upper-130) > 1 {
+		t.Errorf("range at 03:00 = [%v, %v], want [70, 130]", lower, upper)
+	}
+}
+
+func TestFit_WeeklySeasonalityAndTrend(t *testing.T) {
+	// Two weeks with weekends at half volume and slow growth.
+	counts := make([]float64, 2*WeeklyPeriod)
+	for i := range counts {
+		level := 1000 + float64(i)
+		if i%WeeklyPeriod >= 5*DailyPeriod {
+			level /= 2
+		}
+		counts[i] = level
+	}
+
+	m, ok := Fit(monday, counts)
+	if !ok {
+		t.Fatal("Fit() failed")
+	}
+	if m.Period() != WeeklyPeriod {
+		t.Fatalf("period = %d, want %d", m.Period(), WeeklyPeriod)
+	}
+
+	weekday, _, _ := m.Predict(monday.Add(2*7*24*time.Hour+10*time.Hour), 3)
+	saturday, _, _ := m.Predict(monday.Add(2*7*24*time.Hour+5*24*time.Hour+10*time.Hour), 3)
+	if weekday <= 1000+float64(2*WeeklyPeriod)-300 {
+		t.Errorf("weekday forecast %v does not continue the trend", weekday)
+	}
+	if saturday >= weekday*0.75 {
+		t.Errorf("saturday forecast %v not well below weekday %v", saturday, weekday)
+	}
+}
+
+func TestModel_ForecastNeverNegative(t *testing.T) {
+	// A steeply declining series would extrapolate below zero.
+	counts := make([]float64, MinHistory)
+	for i := range counts {
+		counts[i] = float64(MinHistory - i)
+	}
+
+	m, _ := Fit(monday, counts)
+	for _, b := range m.Forecast(SeriesKey{AppID: "app"}, monday.Add(time.Duration(2*MinHistory)*time.Hour), 24, 3) {
+		if b.Expected < 0 || b.Lower < 0 || b.Upper < b.Expected {
+			t.Fatalf("invalid baseline %+v", b)
+		}
+	}
+}
+
+func TestDense(t *testing.T) {
+	counts := map[time.Time]int64{
+		monday:                    5,
+		monday.Add(2 * time.Hour): 7,
+		monday.Add(-time.Hour):    9, // before the range
+	}
+
+	got := Dense(counts, monday, monday.Add(3*time.Hour))
+	want := []float64{5, 0, 7}
+	if len(got) != len(want) {
+		t.Fatalf("len = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Package repo provides the PostgreSQL implementation of the forecast Store port.
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/forecast/internal/domain"
+)
+
+// ForecastRepository implements the Store interface using PostgreSQL.
+type ForecastRepository struct {
+	db *sql.DB
+}
+
+// NewForecastRepository creates a new ForecastRepository backed by the given database.
+func NewForecastRepository(db *sql.DB) *ForecastRepository {
+	return &ForecastRepository{db: db}
+}
+
+// Watermarks returns, per app, the hour up to which warehouse data has been
+// counted.
+func (r *ForecastRepository) Watermarks(ctx context.Context) (map[string]time.Time, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT app_id, counted_until FROM anomaly_forecast_watermarks`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query forecast watermarks: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	watermarks := make(map[string]time.Time)
+	for rows.Next() {
+		var appID string
+		var until time.Time
+		if err := rows.Scan(&appID, &until); err != nil {
+			return nil, fmt.Errorf("failed to scan forecast watermark: %w", err)
+		}
+		watermarks[appID] = until
+	}
+
+	return watermarks, rows.Err()
+}
+
+// AddHourlyCounts stores the counts of an app's hours before until and
+// advances its watermark, in one transaction.
+func (r *ForecastRepository) AddHourlyCounts(ctx context.Context, appID string, until time.Time, counts []domain.HourlyCount) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if len(counts) > 0 {
+		stmt, err := tx.PrepareContext(ctx, `
+			INSERT INTO anomaly_hourly_counts (app_id, event_category, event_type, hour, event_count)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (app_id, event_category, event_type, hour) DO UPDATE
+			SET event_count = EXCLUDED.event_count
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare hourly count upsert: %w", err)
+		}
+		defer func() { _ = stmt.Close() }()
+
+		for _, c := range counts {
+			if _, err := stmt.ExecContext(ctx, c.AppID, c.EventCategory, c.EventType, c.Hour, c.Count); err != nil {
+				return fmt.Errorf("failed to upsert hourly count for app %s: %w", c.AppID, err)
+			}
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO anomaly_forecast_watermarks (app_id, counted_until)
+		VALUES ($1, $2)
+		ON CONFLICT (app_id) DO UPDATE
+		SET counted_until = EXCLUDED.counted_until, updated_at = NOW()
+	`, appID, until); err != nil {
+		return fmt.Errorf("failed to advance forecast watermark for app %s: %w", appID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit hourly counts: %w", err)
+	}
+
+	return nil
+}
+
+// HourlyCounts returns all hourly counts at or after since.
+func (r *ForecastRepository) HourlyCounts(ctx context.Context, since time.Time) ([]domain.HourlyCount, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT app_id, event_category, event_type, hour, event_count
+		FROM anomaly_hourly_counts
+		WHERE hour >= $1
+		ORDER BY app_id, event_category, event_type, hour
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query hourly counts: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var counts []domain.HourlyCount
+	for rows.Next() {
+		var c domain.HourlyCount
+		if err := rows.Scan(&c.AppID, &c.EventCategory, &c.EventType, &c.Hour, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan hourly count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+
+	return counts, rows.Err()
+}
+
+// ReplaceBaselines stores freshly computed baselines and removes baselines
+// older than before, or from an earlier run, in one transaction, so series
+// that can no longer be fitted stop alerting.
+func (r *ForecastRepository) ReplaceBaselines(ctx context.Context, baselines []domain.Baseline, computedAt, before time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if len(baselines) > 0 {
+		stmt, err := tx.PrepareContext(ctx, `
+			INSERT INTO anomaly_baselines (app_id, event_category, event_type, hour, expected, lower_bound, upper_bound, computed_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (app_id, event_category, event_type, hour) DO UPDATE
+			SET expected = EXCLUDED.expected,
+			    lower_bound = EXCLUDED.lower_bound,
+			    upper_bound = EXCLUDED.upper_bound,
+			    computed_at = EXCLUDED.computed_at
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare baseline upsert: %w", err)
+		}
+		defer func() { _ = stmt.Close() }()
+
+		for _, b := range baselines {
+			if _, err := stmt.ExecContext(ctx,
+				b.AppID,
+				b.EventCategory,
+				b.EventType,
+				b.Hour,
+				b.Expected,
+				b.Lower,
+				b.Upper,
+				computedAt,
+			); err != nil {
+				return fmt.Errorf("failed to upsert baseline for app %s: %w", b.AppID, err)
+			}
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM anomaly_baselines WHERE hour < $1 OR computed_at < $2`, before, computedAt,
+	); err != nil {
+		return fmt.Errorf("failed to delete stale baselines: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit baselines: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteHourlyCountsBefore removes hourly counts older than before.
+func (r *ForecastRepository) DeleteHourlyCountsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM anomaly_hourly_counts WHERE hour < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old hourly counts: %w", err)
+	}
+	return result.RowsAffected()
+}
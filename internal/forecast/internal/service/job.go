@@ -0,0 +1,224 @@
+// Package service implements the anomaly forecast job: it counts hourly
+// events per app and event type from the warehouse, fits a seasonal forecast
+// per series, and stores expected-range baselines for the anomaly detector.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/forecast/internal/domain"
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+// ForecastStore defines the persistence interface needed by the forecast
+// job. This mirrors the forecast.Store port to avoid import cycles.
+type ForecastStore interface {
+	Watermarks(ctx context.Context) (map[string]time.Time, error)
+	AddHourlyCounts(ctx context.Context, appID string, until time.Time, counts []domain.HourlyCount) error
+	HourlyCounts(ctx context.Context, since time.Time) ([]domain.HourlyCount, error)
+	ReplaceBaselines(ctx context.Context, baselines []domain.Baseline, computedAt, before time.Time) error
+	DeleteHourlyCountsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// hourCounter counts the events of an hour in the warehouse.
+type hourCounter interface {
+	ListApps(ctx context.Context) ([]string, error)
+	CountHour(ctx context.Context, appID string, hour time.Time) (map[eventTypeKey]int64, error)
+}
+
+// JobConfig holds the forecast job parameters.
+type JobConfig struct {
+	// Lookback is the history used to fit each series.
+	Lookback time.Duration
+
+	// Horizon is how far ahead baselines are written.
+	Horizon time.Duration
+
+	// SettleDelay is how long after an hour ends before its partition is
+	// counted, giving the warehouse sink time to flush late batches.
+	SettleDelay time.Duration
+
+	// IntervalWidth is the number of standard deviations either side of the
+	// expected count that is considered normal.
+	IntervalWidth float64
+
+	// CommitHours is the number of hours counted per app between commits,
+	// so a long backfill keeps its progress if interrupted.
+	CommitHours int
+}
+
+// Job is the anomaly forecast batch job.
+type Job struct {
+	store   ForecastStore
+	counter hourCounter
+	config  JobConfig
+	now     func() time.Time
+	logger  *slog.Logger
+}
+
+// NewJob creates a new forecast job reading the warehouse through reader.
+func NewJob(store ForecastStore, reader *warehouse.PartitionReader, cfg JobConfig, logger *slog.Logger) *Job {
+	return newJob(store, warehouseCounter{reader}, cfg, logger)
+}
+
+func newJob(store ForecastStore, counter hourCounter, cfg JobConfig, logger *slog.Logger) *Job {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.Lookback <= 0 {
+		cfg.Lookback = 28 * 24 * time.Hour
+	}
+	if cfg.Horizon <= 0 {
+		cfg.Horizon = 48 * time.Hour
+	}
+	if cfg.IntervalWidth <= 0 {
+		cfg.IntervalWidth = 3
+	}
+	if cfg.CommitHours < 1 {
+		cfg.CommitHours = 24
+	}
+
+	return &Job{
+		store:   store,
+		counter: counter,
+		config:  cfg,
+		now:     time.Now,
+		logger:  logger.With("component", "anomaly-forecast"),
+	}
+}
+
+// Run counts every settled hour not yet counted, refits all series, and
+// replaces the stored baselines. Counting failures for one app are logged
+// and retried on the next run; the first error is returned.
+func (j *Job) Run(ctx context.Context) error {
+	start := j.now().UTC()
+	until := start.Add(-j.config.SettleDelay).Truncate(time.Hour)
+	since := until.Add(-j.config.Lookback).Truncate(time.Hour)
+
+	countErr := j.countHours(ctx, since, until)
+
+	counts, err := j.store.HourlyCounts(ctx, since)
+	if err != nil {
+		return errors.Join(countErr, err)
+	}
+
+	baselines, fitted := j.fit(counts, since, until)
+	if err := j.store.ReplaceBaselines(ctx, baselines, start, start.Truncate(time.Hour)); err != nil {
+		return errors.Join(countErr, err)
+	}
+
+	if deleted, err := j.store.DeleteHourlyCountsBefore(ctx, since); err != nil {
+		j.logger.Warn("failed to prune hourly counts", "error", err)
+	} else if deleted > 0 {
+		j.logger.Debug("pruned hourly counts", "count", deleted)
+	}
+
+	j.logger.Info("anomaly baselines refreshed",
+		"series", fitted,
+		"baselines", len(baselines),
+		"counted_until", until,
+		"duration", time.Since(start),
+	)
+	return countErr
+}
+
+// countHours counts each app's uncounted hours between since and until.
+func (j *Job) countHours(ctx context.Context, since, until time.Time) error {
+	apps, err := j.counter.ListApps(ctx)
+	if err != nil {
+		return err
+	}
+	watermarks, err := j.store.Watermarks(ctx)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, appID := range apps {
+		from := since
+		if w, ok := watermarks[appID]; ok && w.After(from) {
+			from = w
+		}
+		if err := j.countApp(ctx, appID, from, until); err != nil {
+			j.logger.Error("failed to count warehouse hours", "app_id", appID, "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// countApp counts one app's hours in [from, until), committing every
+// CommitHours hours.
+func (j *Job) countApp(ctx context.Context, appID string, from, until time.Time) error {
+	var pending []domain.HourlyCount
+	hours := 0
+	for hour := from; hour.Before(until); hour = hour.Add(time.Hour) {
+		byType, err := j.counter.CountHour(ctx, appID, hour)
+		if err != nil {
+			return fmt.Errorf("count hour %s: %w", hour.Format(time.RFC3339), err)
+		}
+		for key, n := range byType {
+			pending = append(pending, domain.HourlyCount{
+				SeriesKey: domain.SeriesKey{AppID: appID, EventCategory: key.category, EventType: key.eventType},
+				Hour:      hour,
+				Count:     n,
+			})
+		}
+
+		hours++
+		next := hour.Add(time.Hour)
+		if hours%j.config.CommitHours == 0 || !next.Before(until) {
+			if err := j.store.AddHourlyCounts(ctx, appID, next, pending); err != nil {
+				return err
+			}
+			pending = pending[:0]
+		}
+	}
+
+	if hours > 0 {
+		j.logger.Debug("counted warehouse hours", "app_id", appID, "hours", hours)
+	}
+	return nil
+}
+
+// fit fits every series with counts between since and until and returns
+// baselines from until over the horizon. Each series starts at its first
+// counted hour; hours without events afterwards count as zero.
+func (j *Job) fit(counts []domain.HourlyCount, since, until time.Time) ([]domain.Baseline, int) {
+	series := make(map[domain.SeriesKey]map[time.Time]int64)
+	first := make(map[domain.SeriesKey]time.Time)
+	for _, c := range counts {
+		hour := c.Hour.UTC()
+		if hour.Before(since) || !hour.Before(until) {
+			continue
+		}
+		if series[c.SeriesKey] == nil {
+			series[c.SeriesKey] = make(map[time.Time]int64)
+		}
+		series[c.SeriesKey][hour] = c.Count
+		if f, ok := first[c.SeriesKey]; !ok || hour.Before(f) {
+			first[c.SeriesKey] = hour
+		}
+	}
+
+	horizon := int(j.config.Horizon / time.Hour)
+	var baselines []domain.Baseline
+	fitted := 0
+	for key, hourly := range series {
+		model, ok := domain.Fit(first[key], domain.Dense(hourly, first[key], until))
+		if !ok {
+			continue
+		}
+		baselines = append(baselines, model.Forecast(key, until, horizon, j.config.IntervalWidth)...)
+		fitted++
+	}
+
+	return baselines, fitted
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/forecast/internal/domain"
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+// mockStore is an in-memory ForecastStore for testing.
+type mockStore struct {
+	watermarks map[string]time.Time
+	counts     []domain.HourlyCount
+	baselines  []domain.Baseline
+	commits    int
+}
+
+func (m *mockStore) Watermarks(context.Context) (map[string]time.Time, error) {
+	return m.watermarks, nil
+}
+
+func (m *mockStore) AddHourlyCounts(_ context.Context, appID string, until time.Time, counts []domain.HourlyCount) error {
+	m.counts = append(m.counts, counts...)
+	m.watermarks[appID] = until
+	m.commits++
+	return nil
+}
+
+func (m *mockStore) HourlyCounts(_ context.Context, since time.Time) ([]domain.HourlyCount, error) {
+	var out []domain.HourlyCount
+	for _, c := range m.counts {
+		if !c.Hour.Before(since) {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockStore) ReplaceBaselines(_ context.Context, baselines []domain.Baseline, _, _ time.Time) error {
+	m.baselines = baselines
+	return nil
+}
+
+func (m *mockStore) DeleteHourlyCountsBefore(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+// fakeCounter reports a constant count per hour and records counted hours.
+type fakeCounter struct {
+	counted []time.Time
+}
+
+func (f *fakeCounter) ListApps(context.Context) ([]string, error) {
+	return []string{"app"}, nil
+}
+
+func (f *fakeCounter) CountHour(_ context.Context, _ string, hour time.Time) (map[eventTypeKey]int64, error) {
+	f.counted = append(f.counted, hour)
+	return map[eventTypeKey]int64{{category: "commerce", eventType: "purchase_complete"}: 50}, nil
+}
+
+func TestJob_Run(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 5, 0, 0, time.UTC)
+	store := &mockStore{watermarks: map[string]time.Time{}}
+	counter := &fakeCounter{}
+
+	job := newJob(store, counter, JobConfig{
+		Lookback:    3 * 24 * time.Hour,
+		Horizon:     6 * time.Hour,
+		SettleDelay: 15 * time.Minute,
+		CommitHours: 24,
+	}, nil)
+	job.now = func() time.Time { return now }
+
+	if err := job.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// 12:05 minus the settle delay is 11:50, so hours up to 11:00 are counted.
+	until := time.Date(2025, 3, 10, 11, 0, 0, 0, time.UTC)
+	if len(counter.counted) != 72 || !counter.counted[71].Equal(until.Add(-time.Hour)) {
+		t.Fatalf("counted %d hours ending %v, want 72 ending %v", len(counter.counted), counter.counted[len(counter.counted)-1], until.Add(-time.Hour))
+	}
+	if store.commits != 3 || !store.watermarks["app"].Equal(until) {
+		t.Errorf("commits = %d, watermark = %v; want 3 commits up to %v", store.commits, store.watermarks["app"], until)
+	}
+
+	if len(store.baselines) != 6 {
+		t.Fatalf("baselines = %d, want 6", len(store.baselines))
+	}
+	first := store.baselines[0]
+	if !first.Hour.Equal(until) || first.EventType != "purchase_complete" || first.Expected < 49 || first.Expected > 51 {
+		t.Errorf("first baseline = %+v, want ~50 expected at %v", first, until)
+	}
+
+	// A second run in the same hour has nothing new to count.
+	counter.counted = nil
+	if err := job.Run(context.Background()); err != nil {
+		t.Fatalf("second Run() error = %v", err)
+	}
+	if len(counter.counted) != 0 {
+		t.Errorf("second run counted %d hours, want 0", len(counter.counted))
+	}
+}
+
+func TestCountEventTypes(t *testing.T) {
+	rows := []warehouse.EventRow{
+		{ID: "1", AppID: "app", EventCategory: "screen", EventType: "screen_view"},
+		{ID: "2", AppID: "app", EventCategory: "screen", EventType: "screen_view"},
+		{ID: "3", AppID: "app", EventCategory: "commerce", EventType: "purchase_complete"},
+	}
+	data, err := warehouse.NewParquetWriter(warehouse.ParquetConfig{Compression: "snappy"}).Write(rows)
+	if err != nil {
+		t.Fatalf("write parquet: %v", err)
+	}
+
+	counts := make(map[eventTypeKey]int64)
+	if err := countEventTypes(data, time.Time{}, counts); err != nil {
+		t.Fatalf("countEventTypes() error = %v", err)
+	}
+
+	if got := counts[eventTypeKey{category: "screen", eventType: "screen_view"}]; got != 2 {
+		t.Errorf("screen_view = %d, want 2", got)
+	}
+	if got := counts[eventTypeKey{category: "commerce", eventType: "purchase_complete"}]; got != 1 {
+		t.Errorf("purchase_complete = %d, want 1", got)
+	}
+}
+
+func TestCountEventTypes_HourFilter(t *testing.T) {
+	hour := time.Date(2026, 1, 5, 7, 0, 0, 0, time.UTC)
+	rows := []warehouse.EventRow{
+		{ID: "1", EventCategory: "screen", EventType: "screen_view", TimestampMS: hour.Add(-time.Minute).UnixMilli()},
+		{ID: "2", EventCategory: "screen", EventType: "screen_view", TimestampMS: hour.UnixMilli()},
+		{ID: "3", EventCategory: "screen", EventType: "screen_view", TimestampMS: hour.Add(59 * time.Minute).UnixMilli()},
+		{ID: "4", EventCategory: "screen", EventType: "screen_view", TimestampMS: hour.Add(time.Hour).UnixMilli()},
+	}
+	data, err := warehouse.NewParquetWriter(warehouse.ParquetConfig{Compression: "snappy"}).Write(rows)
+	if err != nil {
+		t.Fatalf("write parquet: %v", err)
+	}
+
+	counts := make(map[eventTypeKey]int64)
+	if err := countEventTypes(data, hour, counts); err != nil {
+		t.Fatalf("countEventTypes() error = %v", err)
+	}
+	if got := counts[eventTypeKey{category: "screen", eventType: "screen_view"}]; got != 2 {
+		t.Errorf("screen_view = %d, want 2", got)
+	}
+}
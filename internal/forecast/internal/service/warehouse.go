@@ -0,0 +1,173 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+// errMissingColumn is returned for Parquet files without event type columns.
+var errMissingColumn = errors.New("parquet file has no event_category/event_type column")
+
+// errMissingTimestamp is returned when rows must be filtered by hour but the
+// Parquet file has no timestamp column.
+var errMissingTimestamp = errors.New("parquet file has no timestamp_ms column")
+
+// eventTypeKey identifies an event type within one app.
+type eventTypeKey struct {
+	category  string
+	eventType string
+}
+
+// warehouseCounter counts events per event type in the warehouse's Parquet
+// partitions. Only the event_category and event_type columns are decoded,
+// plus timestamp_ms when partitions span a day.
+type warehouseCounter struct {
+	*warehouse.PartitionReader
+}
+
+// CountHour returns the number of events per event type in an app's
+// partitions for the hour starting at hour.
+func (w warehouseCounter) CountHour(ctx context.Context, appID string, hour time.Time) (map[eventTypeKey]int64, error) {
+	keys, err := w.ListCovering(ctx, appID, hour)
+	if err != nil {
+		return nil, err
+	}
+
+	// Partitions spanning a day also hold other hours
+	var filter time.Time
+	if w.Window() > time.Hour {
+		filter = hour
+	}
+
+	counts := make(map[eventTypeKey]int64)
+	for _, key := range keys {
+		data, err := w.Download(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if err := countEventTypes(data, filter, counts); err != nil {
+			return nil, fmt.Errorf("count %s: %w", key, err)
+		}
+	}
+
+	return counts, nil
+}
+
+// countEventTypes adds the number of rows per event type in a Parquet file
+// to counts. If hour is not zero, only rows with a timestamp in that hour
+// are counted.
+func countEventTypes(data []byte, hour time.Time, counts map[eventTypeKey]int64) error {
+	pf, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	categoryCol, ok := pf.Schema().Lookup("event_category")
+	if !ok {
+		return errMissingColumn
+	}
+	typeCol, ok := pf.Schema().Lookup("event_type")
+	if !ok {
+		return errMissingColumn
+	}
+	timestampCol, ok := pf.Schema().Lookup("timestamp_ms")
+	if !ok && !hour.IsZero() {
+		return errMissingTimestamp
+	}
+	from, to := hour.UnixMilli(), hour.Add(time.Hour).UnixMilli()
+
+	for _, rg := range pf.RowGroups() {
+		chunks := rg.ColumnChunks()
+		categories, err := readStrings(chunks[categoryCol.ColumnIndex])
+		if err != nil {
+			return err
+		}
+		types, err := readStrings(chunks[typeCol.ColumnIndex])
+		if err != nil {
+			return err
+		}
+		if len(categories) != len(types) {
+			return fmt.Errorf("column length mismatch: %d categories, %d types", len(categories), len(types))
+		}
+
+		var timestamps []int64
+		if !hour.IsZero() {
+			timestamps, err = readInt64s(chunks[timestampCol.ColumnIndex])
+			if err != nil {
+				return err
+			}
+			if len(timestamps) != len(types) {
+				return fmt.Errorf("column length mismatch: %d timestamps, %d types", len(timestamps), len(types))
+			}
+		}
+
+		for i := range types {
+			if timestamps != nil && (timestamps[i] < from || timestamps[i] >= to) {
+				continue
+			}
+			counts[eventTypeKey{category: categories[i], eventType: types[i]}]++
+		}
+	}
+
+	return nil
+}
+
+// readStrings decodes every value of a string column chunk.
+func readStrings(chunk parquet.ColumnChunk) ([]string, error) {
+	var out []string
+	err := readValues(chunk, func(v parquet.Value) {
+		out = append(out, v.String())
+	})
+	return out, err
+}
+
+// readInt64s decodes every value of an int64 column chunk.
+func readInt64s(chunk parquet.ColumnChunk) ([]int64, error) {
+	var out []int64
+	err := readValues(chunk, func(v parquet.Value) {
+		out = append(out, v.Int64())
+	})
+	return out, err
+}
+
+// readValues calls fn with every value of a column chunk. Values are only
+// valid during the call.
+func readValues(chunk parquet.ColumnChunk, fn func(parquet.Value)) error {
+	pages := chunk.Pages()
+	defer pages.Close()
+
+	buf := make([]parquet.Value, 1024)
+	for {
+		page, err := pages.ReadPage()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		values := page.Values()
+		for {
+			n, err := values.ReadValues(buf)
+			for _, v := range buf[:n] {
+				fn(v)
+			}
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				parquet.Release(page)
+				return err
+			}
+		}
+		parquet.Release(page)
+	}
+}
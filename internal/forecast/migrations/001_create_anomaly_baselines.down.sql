@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS anomaly_baselines;
+DROP TABLE IF EXISTS anomaly_forecast_watermarks;
+DROP TABLE IF EXISTS anomaly_hourly_counts;
@@ -0,0 +1,35 @@
+-- Hourly event counts per app and event type, aggregated from the warehouse
+-- by the anomaly forecast job
+CREATE TABLE IF NOT EXISTS anomaly_hourly_counts (
+    app_id VARCHAR(255) NOT NULL,
+    event_category VARCHAR(100) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    hour TIMESTAMPTZ NOT NULL,
+    event_count BIGINT NOT NULL,
+    PRIMARY KEY (app_id, event_category, event_type, hour)
+);
+
+CREATE INDEX IF NOT EXISTS idx_anomaly_hourly_counts_hour ON anomaly_hourly_counts(hour);
+
+-- Per-app aggregation progress: every hour before counted_until is counted
+CREATE TABLE IF NOT EXISTS anomaly_forecast_watermarks (
+    app_id VARCHAR(255) PRIMARY KEY,
+    counted_until TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Learned expected ranges per app, event type, and hour, used by the
+-- forecast detection type
+CREATE TABLE IF NOT EXISTS anomaly_baselines (
+    app_id VARCHAR(255) NOT NULL,
+    event_category VARCHAR(100) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    hour TIMESTAMPTZ NOT NULL,
+    expected DOUBLE PRECISION NOT NULL,
+    lower_bound DOUBLE PRECISION NOT NULL,
+    upper_bound DOUBLE PRECISION NOT NULL,
+    computed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (app_id, event_category, event_type, hour)
+);
+
+CREATE INDEX IF NOT EXISTS idx_anomaly_baselines_hour ON anomaly_baselines(hour);
@@ -0,0 +1,127 @@
+package forecast
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/db"
+	"github.com/SebastienMelki/causality/internal/forecast/internal/repo"
+	"github.com/SebastienMelki/causality/internal/forecast/internal/service"
+	"github.com/SebastienMelki/causality/internal/forecast/migrations"
+	"github.com/SebastienMelki/causality/internal/schedule"
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+// Migrations are the forecast module's database migrations.
+var Migrations = db.Source{Name: "forecast", FS: migrations.FS}
+
+// Config holds configuration for the forecast module.
+type Config struct {
+	// Enabled controls whether the forecast job runs.
+	Enabled bool `env:"FORECAST_ENABLED" envDefault:"false"`
+
+	// Cron is the cron expression the job runs on (e.g. "20 * * * *" or "@hourly").
+	Cron string `env:"FORECAST_CRON" envDefault:"20 * * * *"`
+
+	// Lookback is the history each series is fitted on. Two weeks or more
+	// enables hour-of-week seasonality.
+	Lookback time.Duration `env:"FORECAST_LOOKBACK" envDefault:"672h"`
+
+	// Horizon is how far ahead baselines are written. It must cover the
+	// time until the next successful run.
+	Horizon time.Duration `env:"FORECAST_HORIZON" envDefault:"48h"`
+
+	// SettleDelay is how long after an hour ends before it is counted.
+	SettleDelay time.Duration `env:"FORECAST_SETTLE_DELAY" envDefault:"15m"`
+
+	// IntervalWidth is the number of standard deviations either side of the
+	// expected count treated as normal.
+	IntervalWidth float64 `env:"FORECAST_INTERVAL_WIDTH" envDefault:"3"`
+}
+
+// Module is the forecast module facade. It wires the PostgreSQL repository,
+// warehouse reader, forecast job, and cron scheduler.
+type Module struct {
+	job       *service.Job
+	scheduler *schedule.Scheduler
+	config    Config
+	logger    *slog.Logger
+}
+
+// New creates a new forecast module.
+//
+// Parameters:
+//   - s3Client: the object API (AWS S3 client or FSStore) used to read warehouse partitions
+//   - s3Config: S3 configuration of the event lake (bucket, prefix)
+//   - deltaLog: Delta Lake transaction log; when non-nil, files removed by
+//     compaction but not yet vacuumed are not counted
+//   - db: database holding the anomaly tables
+//   - cfg: forecast module configuration
+//   - logger: structured logger
+//
+// It fails if the cron expression is invalid.
+func New(
+	s3Client warehouse.ObjectAPI,
+	s3Config warehouse.S3Config,
+	deltaLog *warehouse.DeltaLog,
+	db *sql.DB,
+	cfg Config,
+	logger *slog.Logger,
+) (*Module, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	job := service.NewJob(
+		repo.NewForecastRepository(db),
+		warehouse.NewPartitionReader(s3Client, s3Config, deltaLog),
+		service.JobConfig{
+			Lookback:      cfg.Lookback,
+			Horizon:       cfg.Horizon,
+			SettleDelay:   cfg.SettleDelay,
+			IntervalWidth: cfg.IntervalWidth,
+		},
+		logger,
+	)
+
+	scheduler, err := schedule.New("anomaly forecast", cfg.Cron, job.Run, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Module{
+		job:       job,
+		scheduler: scheduler,
+		config:    cfg,
+		logger:    logger.With("component", "forecast-module"),
+	}, nil
+}
+
+// Start begins the scheduled forecast job. If the job is disabled via
+// config, this is a no-op.
+func (m *Module) Start(ctx context.Context) {
+	if !m.config.Enabled {
+		m.logger.Info("anomaly forecast disabled, skipping start")
+		return
+	}
+
+	m.logger.Info("starting forecast module",
+		"cron", m.config.Cron,
+		"lookback", m.config.Lookback,
+		"horizon", m.config.Horizon,
+		"interval_width", m.config.IntervalWidth,
+	)
+	m.scheduler.Start(ctx)
+}
+
+// Stop stops the forecast scheduler.
+func (m *Module) Stop() {
+	m.scheduler.Stop()
+}
+
+// RunNow runs the forecast job immediately.
+func (m *Module) RunNow(ctx context.Context) error {
+	return m.job.Run(ctx)
+}
@@ -0,0 +1,41 @@
+// Package forecast provides the anomaly forecast job. On a cron schedule it
+// counts hourly events per app and event type from the warehouse's Parquet
+// partitions, fits a seasonal forecast (linear trend plus hour-of-day or
+// hour-of-week seasonality) per series, and writes expected-range baselines
+// to the anomaly_baselines table. Anomaly configs with the "forecast"
+// detection type alert when an hour's event count exceeds its baseline.
+package forecast
+
+import (
+	"context"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/forecast/internal/domain"
+)
+
+// Baseline is the expected range of a series' event count for one hour.
+type Baseline = domain.Baseline
+
+// HourlyCount is the number of events of one series in one UTC hour.
+type HourlyCount = domain.HourlyCount
+
+// Store defines the port for forecast persistence.
+type Store interface {
+	// Watermarks returns, per app, the hour up to which warehouse data has
+	// been counted.
+	Watermarks(ctx context.Context) (map[string]time.Time, error)
+
+	// AddHourlyCounts stores an app's hourly counts and advances its
+	// watermark to until.
+	AddHourlyCounts(ctx context.Context, appID string, until time.Time, counts []HourlyCount) error
+
+	// HourlyCounts returns all hourly counts at or after since.
+	HourlyCounts(ctx context.Context, since time.Time) ([]HourlyCount, error)
+
+	// ReplaceBaselines stores baselines computed at computedAt and deletes
+	// earlier ones and those for hours before before.
+	ReplaceBaselines(ctx context.Context, baselines []Baseline, computedAt, before time.Time) error
+
+	// DeleteHourlyCountsBefore removes hourly counts older than before.
+	DeleteHourlyCountsBefore(ctx context.Context, before time.Time) (int64, error)
+}
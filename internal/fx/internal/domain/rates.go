@@ -0,0 +1,159 @@
+// Package domain contains the foreign exchange rate types and the currency
+// conversion logic of the fx module.
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNoRates is returned when no exchange rates have been loaded or fetched.
+var ErrNoRates = errors.New("no exchange rates available")
+
+// Rates are the exchange rates of one day, normalized to the US dollar.
+type Rates struct {
+	// Date is the UTC day the rates were published for.
+	Date time.Time `json:"date"`
+
+	// UnitsPerUSD maps ISO 4217 currency codes to the units of that currency
+	// one US dollar buys. USD itself is always 1.
+	UnitsPerUSD map[string]float64 `json:"units_per_usd"`
+
+	// FetchedAt is when the rates were fetched.
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// NormalizeRates converts rates quoted against base (units of each currency
+// per unit of base) into Rates against the US dollar. The quotes must
+// include USD unless base is USD.
+func NormalizeRates(date time.Time, base string, quotes map[string]float64, fetchedAt time.Time) (Rates, error) {
+	base = strings.ToUpper(base)
+
+	all := make(map[string]float64, len(quotes)+1)
+	for currency, rate := range quotes {
+		if rate > 0 {
+			all[strings.ToUpper(currency)] = rate
+		}
+	}
+	all[base] = 1
+
+	usd, ok := all["USD"]
+	if !ok {
+		return Rates{}, fmt.Errorf("rates quoted in %s do not include USD", base)
+	}
+
+	rates := Rates{
+		Date:        Day(date),
+		UnitsPerUSD: make(map[string]float64, len(all)),
+		FetchedAt:   fetchedAt,
+	}
+	for currency, rate := range all {
+		rates.UnitsPerUSD[currency] = rate / usd
+	}
+	rates.UnitsPerUSD["USD"] = 1
+
+	return rates, nil
+}
+
+// Day truncates t to its UTC day.
+func Day(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// minorUnitExponents lists the ISO 4217 currencies whose minor unit is not a
+// hundredth. Every other currency has two decimals.
+var minorUnitExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0,
+	"XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// MinorToMajor converts an amount in a currency's minor unit (such as cents)
+// to its major unit.
+func MinorToMajor(currency string, minorUnits int64) float64 {
+	exp, ok := minorUnitExponents[currency]
+	if !ok {
+		exp = 2
+	}
+	return float64(minorUnits) / math.Pow10(exp)
+}
+
+// Table holds the rates of several days, oldest first. A Table is immutable
+// once built.
+type Table struct {
+	days []Rates
+}
+
+// NewTable builds a Table from rates in any order. Later entries replace
+// earlier ones for the same day.
+func NewTable(days []Rates) *Table {
+	byDay := make(map[time.Time]Rates, len(days))
+	for _, r := range days {
+		byDay[Day(r.Date)] = r
+	}
+
+	t := &Table{days: make([]Rates, 0, len(byDay))}
+	for _, r := range byDay {
+		t.days = append(t.days, r)
+	}
+	sort.Slice(t.days, func(i, j int) bool { return t.days[i].Date.Before(t.days[j].Date) })
+
+	return t
+}
+
+// With returns a new Table with r added, replacing any rates for its day.
+func (t *Table) With(r Rates) *Table {
+	days := make([]Rates, 0, len(t.days)+1)
+	days = append(days, t.days...)
+	return NewTable(append(days, r))
+}
+
+// Latest returns the most recent rates, or false if the table is empty.
+func (t *Table) Latest() (Rates, bool) {
+	if len(t.days) == 0 {
+		return Rates{}, false
+	}
+	return t.days[len(t.days)-1], true
+}
+
+// ratesAt returns the rates in effect at the given time: those of the latest
+// day on or before it, or of the oldest day when at predates the table.
+func (t *Table) ratesAt(at time.Time) (Rates, bool) {
+	if len(t.days) == 0 {
+		return Rates{}, false
+	}
+	day := Day(at)
+	i := sort.Search(len(t.days), func(i int) bool { return t.days[i].Date.After(day) })
+	if i == 0 {
+		return t.days[0], true
+	}
+	return t.days[i-1], true
+}
+
+// ToUSD converts an amount in a currency's minor unit to US dollars, rounded
+// to the cent, with the rates in effect at the given time. It returns false
+// for empty or unknown currencies and when the table is empty.
+func (t *Table) ToUSD(currency string, minorUnits int64, at time.Time) (float64, bool) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return 0, false
+	}
+
+	rates, ok := t.ratesAt(at)
+	if !ok {
+		return 0, false
+	}
+	rate, ok := rates.UnitsPerUSD[currency]
+	if !ok || rate <= 0 {
+		return 0, false
+	}
+
+	usd := MinorToMajor(currency, minorUnits) / rate
+	return math.Round(usd*100) / 100, true
+}
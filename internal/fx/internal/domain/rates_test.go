@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNormalizeRates(t *testing.T) {
+	date := time.Date(2026, 3, 10, 16, 0, 0, 0, time.UTC)
+
+	rates, err := NormalizeRates(date, "eur", map[string]float64{"USD": 1.25, "GBP": 0.85}, date)
+	if err != nil {
+		t.Fatalf("NormalizeRates() error = %v", err)
+	}
+	want := map[string]float64{"USD": 1, "EUR": 0.8, "GBP": 0.68}
+	for currency, rate := range want {
+		if math.Abs(rates.UnitsPerUSD[currency]-rate) > 1e-9 {
+			t.Errorf("UnitsPerUSD[%s] = %v, want %v", currency, rates.UnitsPerUSD[currency], rate)
+		}
+	}
+	if !rates.Date.Equal(Day(date)) {
+		t.Errorf("Date = %v, want %v", rates.Date, Day(date))
+	}
+
+	if _, err := NormalizeRates(date, "EUR", map[string]float64{"GBP": 0.85}, date); err == nil {
+		t.Error("NormalizeRates() without USD succeeded, want error")
+	}
+}
+
+func TestTable_ToUSD(t *testing.T) {
+	day1 := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	table := NewTable([]Rates{
+		{Date: day2, UnitsPerUSD: map[string]float64{"USD": 1, "EUR": 0.5, "JPY": 150, "KWD": 0.3}},
+		{Date: day1, UnitsPerUSD: map[string]float64{"USD": 1, "EUR": 0.8}},
+	})
+
+	tests := []struct {
+		name       string
+		currency   string
+		minorUnits int64
+		at         time.Time
+		want       float64
+		wantOK     bool
+	}{
+		{"usd", "USD", 1999, day2, 19.99, true},
+		{"latest day", "eur", 1000, day2.Add(5 * time.Hour), 20, true},
+		{"previous day", "EUR", 1000, day1.Add(23 * time.Hour), 12.5, true},
+		{"before history", "EUR", 1000, day1.AddDate(0, -1, 0), 12.5, true},
+		{"after history", "EUR", 1000, day2.AddDate(0, 0, 3), 20, true},
+		{"zero decimals", "JPY", 1500, day2, 10, true},
+		{"three decimals", "KWD", 3000, day2, 10, true},
+		{"missing on day", "JPY", 1500, day1, 0, false},
+		{"unknown", "XXX", 100, day2, 0, false},
+		{"empty", "", 100, day2, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := table.ToUSD(tt.currency, tt.minorUnits, tt.at)
+		if ok != tt.wantOK || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: ToUSD() = %v, %v, want %v, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	if _, ok := NewTable(nil).ToUSD("USD", 100, day1); ok {
+		t.Error("ToUSD() on empty table succeeded")
+	}
+}
+
+func TestTable_With(t *testing.T) {
+	day := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+	table := NewTable([]Rates{{Date: day, UnitsPerUSD: map[string]float64{"USD": 1, "EUR": 0.8}}})
+
+	updated := table.With(Rates{Date: day.Add(time.Hour), UnitsPerUSD: map[string]float64{"USD": 1, "EUR": 0.5}})
+	if got, _ := updated.ToUSD("EUR", 100, day); got != 2 {
+		t.Errorf("updated ToUSD() = %v, want 2", got)
+	}
+	if got, _ := table.ToUSD("EUR", 100, day); got != 1.25 {
+		t.Errorf("original ToUSD() = %v, want 1.25 (tables are immutable)", got)
+	}
+}
@@ -0,0 +1,113 @@
+// Package repo provides the PostgreSQL implementation of the fx Store port.
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/fx/internal/domain"
+)
+
+// RatesRepository implements the Store interface using PostgreSQL.
+type RatesRepository struct {
+	db *sql.DB
+}
+
+// NewRatesRepository creates a new RatesRepository backed by the given database.
+func NewRatesRepository(db *sql.DB) *RatesRepository {
+	return &RatesRepository{db: db}
+}
+
+// SaveRates upserts a day's rates into fx_rates in one transaction.
+func (r *RatesRepository) SaveRates(ctx context.Context, rates domain.Rates) error {
+	if len(rates.UnitsPerUSD) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO fx_rates (rate_date, currency, units_per_usd, fetched_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (rate_date, currency) DO UPDATE
+		SET units_per_usd = EXCLUDED.units_per_usd,
+		    fetched_at    = EXCLUDED.fetched_at
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare rate upsert: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	// Write currencies in a fixed order so concurrent writers cannot deadlock.
+	currencies := make([]string, 0, len(rates.UnitsPerUSD))
+	for currency := range rates.UnitsPerUSD {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	for _, currency := range currencies {
+		if _, err := stmt.ExecContext(ctx,
+			rates.Date,
+			currency,
+			rates.UnitsPerUSD[currency],
+			rates.FetchedAt,
+		); err != nil {
+			return fmt.Errorf("failed to upsert rate %s: %w", currency, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rates: %w", err)
+	}
+
+	return nil
+}
+
+// RatesSince returns the rates of every day on or after since, oldest first.
+func (r *RatesRepository) RatesSince(ctx context.Context, since time.Time) ([]domain.Rates, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT rate_date, currency, units_per_usd, fetched_at
+		FROM fx_rates
+		WHERE rate_date >= $1
+		ORDER BY rate_date, currency
+	`, domain.Day(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rates: %w", err)
+	}
+	defer rows.Close()
+
+	var days []domain.Rates
+	for rows.Next() {
+		var (
+			date      time.Time
+			currency  string
+			rate      float64
+			fetchedAt time.Time
+		)
+		if err := rows.Scan(&date, &currency, &rate, &fetchedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan rate: %w", err)
+		}
+
+		date = domain.Day(date)
+		if n := len(days); n == 0 || !days[n-1].Date.Equal(date) {
+			days = append(days, domain.Rates{Date: date, UnitsPerUSD: map[string]float64{}})
+		}
+		day := &days[len(days)-1]
+		day.UnitsPerUSD[currency] = rate
+		if fetchedAt.After(day.FetchedAt) {
+			day.FetchedAt = fetchedAt
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rates: %w", err)
+	}
+
+	return days, nil
+}
@@ -0,0 +1,250 @@
+// Package service implements the fx rate refresher: it fetches daily exchange
+// rates from an HTTP rates API on a cron schedule, stores them, and keeps an
+// in-memory rate table for currency conversion.
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/SebastienMelki/causality/internal/fx/internal/domain"
+	"github.com/SebastienMelki/causality/internal/schedule"
+)
+
+// staleAfter is the age of the newest stored rates after which Start fetches
+// immediately instead of waiting for the first cron fire.
+const staleAfter = 24 * time.Hour
+
+// RatesStore defines the persistence interface needed by the refresher.
+// This mirrors the fx.Store port to avoid import cycles.
+type RatesStore interface {
+	SaveRates(ctx context.Context, rates domain.Rates) error
+	RatesSince(ctx context.Context, since time.Time) ([]domain.Rates, error)
+}
+
+// ratesResponse is the rates API response: rates quoted against base, as
+// served by Frankfurter (api.frankfurter.app) and compatible APIs. Date is
+// optional and defaults to the day of the fetch.
+type ratesResponse struct {
+	Base  string             `json:"base"`
+	Date  string             `json:"date"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// Refresher fetches and stores daily exchange rates and converts amounts to
+// US dollars with them.
+type Refresher struct {
+	store   RatesStore
+	client  *http.Client
+	url     string
+	spec    string
+	cron    cron.Schedule
+	history time.Duration
+	now     func() time.Time
+	logger  *slog.Logger
+
+	table atomic.Pointer[domain.Table]
+
+	mu      sync.Mutex
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+	running bool
+}
+
+// NewRefresher creates a refresher fetching url on the cron expression spec
+// and keeping history worth of rates in memory.
+func NewRefresher(
+	store RatesStore,
+	url string,
+	spec string,
+	history time.Duration,
+	timeout time.Duration,
+	logger *slog.Logger,
+) (*Refresher, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	cronSchedule, err := schedule.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("parse fx cron %q: %w", spec, err)
+	}
+
+	r := &Refresher{
+		store:   store,
+		client:  &http.Client{Timeout: timeout},
+		url:     url,
+		spec:    spec,
+		cron:    cronSchedule,
+		history: history,
+		now:     time.Now,
+		logger:  logger.With("component", "fx-refresher"),
+	}
+	r.table.Store(domain.NewTable(nil))
+	return r, nil
+}
+
+// Start loads the stored rates and begins the refresh loop in a background
+// goroutine. If the newest stored rates are missing or stale, they are
+// fetched right away.
+func (r *Refresher) Start(ctx context.Context) error {
+	if err := r.Load(ctx); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running {
+		r.logger.Warn("refresher already running")
+		return nil
+	}
+
+	r.stopCh = make(chan struct{})
+	r.doneCh = make(chan struct{})
+	r.running = true
+
+	latest, ok := r.table.Load().Latest()
+	fetchNow := !ok || r.now().Sub(latest.FetchedAt) > staleAfter
+
+	go r.run(ctx, fetchNow, r.stopCh, r.doneCh)
+
+	r.logger.Info("fx refresher started", "cron", r.spec, "fetch_now", fetchNow)
+	return nil
+}
+
+// Stop signals the refresh loop to stop and waits for a running fetch to
+// finish.
+func (r *Refresher) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.running {
+		return
+	}
+
+	close(r.stopCh)
+	<-r.doneCh
+	r.running = false
+}
+
+// run fetches on each cron fire time, and once immediately if fetchNow.
+func (r *Refresher) run(ctx context.Context, fetchNow bool, stopCh <-chan struct{}, doneCh chan<- struct{}) {
+	defer close(doneCh)
+
+	if fetchNow {
+		if err := r.Refresh(ctx); err != nil {
+			r.logger.Error("fx rate refresh failed", "error", err)
+		}
+	}
+
+	for {
+		timer := time.NewTimer(time.Until(r.cron.Next(r.now())))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-stopCh:
+			timer.Stop()
+			return
+		case <-timer.C:
+			if err := r.Refresh(ctx); err != nil {
+				r.logger.Error("fx rate refresh failed", "error", err)
+			}
+		}
+	}
+}
+
+// Load replaces the in-memory rate table with the stored rates of the
+// configured history.
+func (r *Refresher) Load(ctx context.Context) error {
+	days, err := r.store.RatesSince(ctx, r.now().Add(-r.history))
+	if err != nil {
+		return fmt.Errorf("failed to load fx rates: %w", err)
+	}
+	r.table.Store(domain.NewTable(days))
+	return nil
+}
+
+// Refresh fetches the current rates, stores them, and adds them to the
+// in-memory table.
+func (r *Refresher) Refresh(ctx context.Context) error {
+	rates, err := r.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := r.store.SaveRates(ctx, rates); err != nil {
+		return err
+	}
+	r.table.Store(r.table.Load().With(rates))
+
+	r.logger.Info("fx rates refreshed",
+		"date", rates.Date.Format(time.DateOnly),
+		"currencies", len(rates.UnitsPerUSD),
+	)
+	return nil
+}
+
+// fetch gets the current rates from the rates API.
+func (r *Refresher) fetch(ctx context.Context) (domain.Rates, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return domain.Rates{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return domain.Rates{}, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return domain.Rates{}, fmt.Errorf("rates API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var body ratesResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return domain.Rates{}, fmt.Errorf("failed to decode rates: %w", err)
+	}
+	if body.Base == "" || len(body.Rates) == 0 {
+		return domain.Rates{}, fmt.Errorf("rates response has no base or rates")
+	}
+
+	now := r.now().UTC()
+	date := now
+	if body.Date != "" {
+		if date, err = time.Parse(time.DateOnly, body.Date); err != nil {
+			return domain.Rates{}, fmt.Errorf("invalid rates date %q: %w", body.Date, err)
+		}
+	}
+
+	return domain.NormalizeRates(date, body.Base, body.Rates, now)
+}
+
+// Latest returns the most recent rates, or domain.ErrNoRates.
+func (r *Refresher) Latest() (domain.Rates, error) {
+	rates, ok := r.table.Load().Latest()
+	if !ok {
+		return domain.Rates{}, domain.ErrNoRates
+	}
+	return rates, nil
+}
+
+// ToUSD converts an amount in a currency's minor unit to US dollars with the
+// rates in effect at the given time.
+func (r *Refresher) ToUSD(currency string, minorUnits int64, at time.Time) (float64, bool) {
+	return r.table.Load().ToUSD(currency, minorUnits, at)
+}
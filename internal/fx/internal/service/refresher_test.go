@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/fx/internal/domain"
+)
+
+// fakeStore is an in-memory RatesStore.
+type fakeStore struct {
+	saved []domain.Rates
+}
+
+func (s *fakeStore) SaveRates(_ context.Context, rates domain.Rates) error {
+	s.saved = append(s.saved, rates)
+	return nil
+}
+
+func (s *fakeStore) RatesSince(_ context.Context, since time.Time) ([]domain.Rates, error) {
+	var days []domain.Rates
+	for _, r := range s.saved {
+		if !r.Date.Before(domain.Day(since)) {
+			days = append(days, r)
+		}
+	}
+	return days, nil
+}
+
+func TestRefresher_Refresh(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"amount":1.0,"base":"EUR","date":"2026-03-10","rates":{"USD":1.25,"GBP":0.85}}`))
+	}))
+	defer srv.Close()
+
+	store := &fakeStore{}
+	r, err := NewRefresher(store, srv.URL, "@daily", 30*24*time.Hour, time.Second, nil)
+	if err != nil {
+		t.Fatalf("NewRefresher() error = %v", err)
+	}
+	r.now = func() time.Time { return time.Date(2026, 3, 11, 9, 0, 0, 0, time.UTC) }
+
+	if _, ok := r.ToUSD("EUR", 100, r.now()); ok {
+		t.Error("ToUSD() before refresh succeeded")
+	}
+
+	if err := r.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if len(store.saved) != 1 || store.saved[0].Date.Format(time.DateOnly) != "2026-03-10" {
+		t.Fatalf("saved = %+v, want the 2026-03-10 rates", store.saved)
+	}
+	if got, ok := r.ToUSD("EUR", 1000, r.now()); !ok || got != 12.5 {
+		t.Errorf("ToUSD(EUR) = %v, %v, want 12.5", got, ok)
+	}
+
+	// A fresh refresher loads the stored rates.
+	loaded, _ := NewRefresher(store, srv.URL, "@daily", 30*24*time.Hour, time.Second, nil)
+	loaded.now = r.now
+	if err := loaded.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got, ok := loaded.ToUSD("GBP", 850, r.now()); !ok || got != 12.5 {
+		t.Errorf("loaded ToUSD(GBP) = %v, %v, want 12.5", got, ok)
+	}
+}
+
+func TestRefresher_RefreshRejectsBadResponses(t *testing.T) {
+	tests := map[string]string{
+		"no usd":   `{"base":"EUR","rates":{"GBP":0.85}}`,
+		"no rates": `{"base":"USD"}`,
+		"bad date": `{"base":"USD","date":"10/03/2026","rates":{"EUR":0.8}}`,
+		"not json": `<html>`,
+	}
+	for name, body := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(body))
+		}))
+		store := &fakeStore{}
+		r, _ := NewRefresher(store, srv.URL, "@daily", time.Hour, time.Second, nil)
+		if err := r.Refresh(context.Background()); err == nil {
+			t.Errorf("%s: Refresh() succeeded, want error", name)
+		}
+		if len(store.saved) != 0 {
+			t.Errorf("%s: saved %d rates, want none", name, len(store.saved))
+		}
+		srv.Close()
+	}
+}
@@ -0,0 +1,8 @@
+-- Daily foreign exchange rates used to normalize purchase amounts to USD
+CREATE TABLE IF NOT EXISTS fx_rates (
+    rate_date DATE NOT NULL,
+    currency CHAR(3) NOT NULL,
+    units_per_usd DOUBLE PRECISION NOT NULL,
+    fetched_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (rate_date, currency)
+);
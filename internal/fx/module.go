@@ -0,0 +1,93 @@
+package fx
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/db"
+	"github.com/SebastienMelki/causality/internal/fx/internal/repo"
+	"github.com/SebastienMelki/causality/internal/fx/internal/service"
+	"github.com/SebastienMelki/causality/internal/fx/migrations"
+)
+
+// Migrations are the fx module's database migrations.
+var Migrations = db.Source{Name: "fx", FS: migrations.FS}
+
+// Config holds the fx module configuration.
+type Config struct {
+	// Enabled controls whether purchase amounts are normalized to USD.
+	Enabled bool `env:"FX_ENABLED" envDefault:"false"`
+
+	// RatesURL is the rates API endpoint. It must return JSON with a base
+	// currency and rates quoted against it, including USD.
+	RatesURL string `env:"FX_RATES_URL" envDefault:"https://api.frankfurter.app/latest?from=USD"`
+
+	// Cron is the cron expression rates are fetched on. The default runs
+	// after the ECB's daily reference rates are published.
+	Cron string `env:"FX_CRON" envDefault:"30 16 * * *"`
+
+	// History is how far back stored rates are loaded, bounding how late an
+	// event can arrive and still be converted with its own day's rates.
+	History time.Duration `env:"FX_HISTORY" envDefault:"2160h"`
+
+	// Timeout is the HTTP timeout for rates API requests.
+	Timeout time.Duration `env:"FX_TIMEOUT" envDefault:"10s"`
+}
+
+// Module is the fx module facade. It wires the PostgreSQL repository and the
+// rate refresher.
+type Module struct {
+	refresher *service.Refresher
+	config    Config
+	logger    *slog.Logger
+}
+
+// New creates a new fx Module. It fails if the cron expression is invalid.
+func New(db *sql.DB, cfg Config, logger *slog.Logger) (*Module, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	refresher, err := service.NewRefresher(
+		repo.NewRatesRepository(db),
+		cfg.RatesURL,
+		cfg.Cron,
+		cfg.History,
+		cfg.Timeout,
+		logger,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Module{
+		refresher: refresher,
+		config:    cfg,
+		logger:    logger.With("component", "fx-module"),
+	}, nil
+}
+
+// Start loads the stored rates and begins the scheduled rate refresh.
+func (m *Module) Start(ctx context.Context) error {
+	return m.refresher.Start(ctx)
+}
+
+// Stop stops the rate refresh.
+func (m *Module) Stop() {
+	m.refresher.Stop()
+}
+
+// Latest returns the most recent rates, or ErrNoRates.
+func (m *Module) Latest() (Rates, error) {
+	return m.refresher.Latest()
+}
+
+// ToUSD converts an amount in a currency's minor unit (such as a
+// purchase's total_cents) to US dollars, rounded to the cent, with the rates
+// in effect at the given time. It returns false for unknown currencies and
+// before any rates are available.
+func (m *Module) ToUSD(currency string, minorUnits int64, at time.Time) (float64, bool) {
+	return m.refresher.ToUSD(currency, minorUnits, at)
+}
@@ -0,0 +1,30 @@
+// Package fx provides currency normalization for revenue events. It fetches
+// daily foreign exchange rates from an HTTP rates API, stores them in
+// PostgreSQL, and converts purchase amounts to US dollars with the rates of
+// the purchase's day. The reaction engine and the warehouse sink use it to
+// attach amount_usd to purchase_complete events, so revenue rules, anomaly
+// thresholds and warehouse queries work across multi-currency apps.
+package fx
+
+import (
+	"context"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/fx/internal/domain"
+)
+
+// Rates are the exchange rates of one day, normalized to the US dollar.
+type Rates = domain.Rates
+
+// ErrNoRates is returned when no exchange rates have been loaded or fetched.
+var ErrNoRates = domain.ErrNoRates
+
+// Store defines the port for exchange rate persistence.
+type Store interface {
+	// SaveRates stores a day's rates, replacing any stored for that day.
+	SaveRates(ctx context.Context, rates Rates) error
+
+	// RatesSince returns the rates of every day on or after since, oldest
+	// first.
+	RatesSince(ctx context.Context, since time.Time) ([]Rates, error)
+}
@@ -0,0 +1,421 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/auth"
+)
+
+// abuseStateKey is the context key for the per-request abuse tracking state.
+const abuseStateKey ContextKey = "abuse_state"
+
+// Ban subject prefixes. A subject is "ip:{address}" or "key:{api key ID}".
+const (
+	banSubjectIP  = "ip:"
+	banSubjectKey = "key:"
+)
+
+// Ban is an active temporary ban of a client IP or API key.
+type Ban struct {
+	// Subject is "ip:{address}" or "key:{api key ID}".
+	Subject string `json:"subject"`
+
+	// Reason describes why the ban was imposed.
+	Reason string `json:"reason"`
+
+	// Requests and Errors are the counts in the window that triggered the ban.
+	Requests int `json:"requests"`
+	Errors   int `json:"errors"`
+
+	// BannedAt and ExpiresAt bound the ban.
+	BannedAt  time.Time `json:"banned_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// abuseWindow counts requests and client errors of one subject in the
+// current window.
+type abuseWindow struct {
+	start    time.Time
+	requests int
+	errors   int
+}
+
+// AbuseGuard enforces CIDR allow/deny lists and temporarily bans client IPs
+// and API keys whose client error rate exceeds a threshold. State is kept in
+// memory per gateway instance. It is safe for concurrent use.
+type AbuseGuard struct {
+	config AbuseConfig
+	allow  []netip.Prefix
+	deny   []netip.Prefix
+	now    func() time.Time
+	logger *slog.Logger
+
+	mu        sync.Mutex
+	windows   map[string]*abuseWindow
+	bans      map[string]*Ban
+	lastPrune time.Time
+}
+
+// NewAbuseGuard creates an abuse guard. It fails if a CIDR is invalid.
+func NewAbuseGuard(cfg AbuseConfig, logger *slog.Logger) (*AbuseGuard, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	allow, err := parseCIDRs(cfg.AllowCIDRs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ABUSE_ALLOW_CIDRS: %w", err)
+	}
+	deny, err := parseCIDRs(cfg.DenyCIDRs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ABUSE_DENY_CIDRS: %w", err)
+	}
+
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.BanDuration <= 0 {
+		cfg.BanDuration = 15 * time.Minute
+	}
+
+	return &AbuseGuard{
+		config:  cfg,
+		allow:   allow,
+		deny:    deny,
+		now:     time.Now,
+		logger:  logger.With("component", "abuse-guard"),
+		windows: make(map[string]*abuseWindow),
+		bans:    make(map[string]*Ban),
+	}, nil
+}
+
+// parseCIDRs parses CIDRs or bare addresses (treated as single-host prefixes).
+func parseCIDRs(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if !strings.Contains(v, "/") {
+			addr, err := netip.ParseAddr(v)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// ipAllowed reports whether the address passes the deny and allow lists. An
+// empty allow list allows every address not denied.
+func (g *AbuseGuard) ipAllowed(addr netip.Addr) bool {
+	for _, p := range g.deny {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	if len(g.allow) == 0 {
+		return true
+	}
+	for _, p := range g.allow {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// banned returns the active ban of a subject, or nil.
+func (g *AbuseGuard) banned(subject string) *Ban {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ban, ok := g.bans[subject]
+	if !ok {
+		return nil
+	}
+	if g.now().After(ban.ExpiresAt) {
+		delete(g.bans, subject)
+		return nil
+	}
+	return ban
+}
+
+// record counts a completed request of a subject and bans the subject once
+// its client error rate in the window reaches the threshold.
+func (g *AbuseGuard) record(subject string, statusCode int) {
+	if !g.config.BanEnabled || g.config.ErrorRateThreshold <= 0 {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	g.pruneLocked(now)
+
+	w, ok := g.windows[subject]
+	if !ok || now.Sub(w.start) >= g.config.Window {
+		w = &abuseWindow{start: now}
+		g.windows[subject] = w
+	}
+	w.requests++
+	if isClientError(statusCode) {
+		w.errors++
+	}
+
+	if w.requests < g.config.MinRequests {
+		return
+	}
+	rate := float64(w.errors) / float64(w.requests)
+	if rate < g.config.ErrorRateThreshold {
+		return
+	}
+	if _, already := g.bans[subject]; already {
+		return
+	}
+
+	ban := &Ban{
+		Subject:   subject,
+		Reason:    fmt.Sprintf("client error rate %.0f%% over %s", rate*100, g.config.Window),
+		Requests:  w.requests,
+		Errors:    w.errors,
+		BannedAt:  now,
+		ExpiresAt: now.Add(g.config.BanDuration),
+	}
+	g.bans[subject] = ban
+	delete(g.windows, subject)
+
+	g.logger.Warn("temporarily banned client",
+		"subject", subject,
+		"requests", ban.Requests,
+		"errors", ban.Errors,
+		"expires_at", ban.ExpiresAt,
+	)
+}
+
+// isClientError reports whether a status counts towards the error rate.
+// 429 is excluded: rate-limited clients are already being throttled. So is
+// 426: outdated SDKs keep sending until their apps are upgraded.
+func isClientError(statusCode int) bool {
+	return statusCode >= 400 && statusCode < 500 &&
+		statusCode != http.StatusTooManyRequests && statusCode != http.StatusUpgradeRequired
+}
+
+// pruneLocked drops expired windows and bans at most once per window.
+// Caller must hold g.mu.
+func (g *AbuseGuard) pruneLocked(now time.Time) {
+	if now.Sub(g.lastPrune) < g.config.Window {
+		return
+	}
+	for subject, w := range g.windows {
+		if now.Sub(w.start) >= g.config.Window {
+			delete(g.windows, subject)
+		}
+	}
+	for subject, ban := range g.bans {
+		if now.After(ban.ExpiresAt) {
+			delete(g.bans, subject)
+		}
+	}
+	g.lastPrune = now
+}
+
+// Bans returns the active bans ordered by expiry.
+func (g *AbuseGuard) Bans() []Ban {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	bans := make([]Ban, 0, len(g.bans))
+	for _, ban := range g.bans {
+		if !now.After(ban.ExpiresAt) {
+			bans = append(bans, *ban)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].ExpiresAt.Before(bans[j].ExpiresAt) })
+	return bans
+}
+
+// Unban lifts the ban of a subject and resets its error window. It reports
+// whether a ban was lifted.
+func (g *AbuseGuard) Unban(subject string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	_, ok := g.bans[subject]
+	delete(g.bans, subject)
+	delete(g.windows, subject)
+	return ok
+}
+
+// clientIP returns the client address of a request, from the first
+// X-Forwarded-For entry when trusted and present, else from RemoteAddr.
+func (g *AbuseGuard) clientIP(r *http.Request) (netip.Addr, bool) {
+	if g.config.TrustForwardedFor {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			first, _, _ := strings.Cut(xff, ",")
+			if addr, err := netip.ParseAddr(strings.TrimSpace(first)); err == nil {
+				return addr.Unmap(), true
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// abuseState carries the authenticated key ID from AbuseIdentity back to
+// AbuseProtection, which records the response status once it is known.
+type abuseState struct {
+	keyID string
+}
+
+// probePaths are the health and metrics endpoints polled by load balancers,
+// orchestrators and Prometheus, which usually sit outside the client CIDRs.
+var probePaths = map[string]bool{
+	"/health":  true,
+	"/ready":   true,
+	"/metrics": true,
+}
+
+// AbuseProtection rejects requests from denied or banned client IPs with 403
+// Forbidden and records each response status towards the client IP's (and,
+// via AbuseIdentity, the API key's) error rate. It must run outside the auth
+// middleware so authentication failures are counted. The allow and deny
+// lists apply to every path but the health and metrics probes; admin paths
+// are exempt from bans and are not counted, so a ban can always be lifted
+// from an allowed address.
+func AbuseProtection(guard *AbuseGuard) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if probePaths[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			addr, ok := guard.clientIP(r)
+			if ok && !guard.ipAllowed(addr) {
+				writeForbidden(w, "client address not allowed")
+				return
+			}
+
+			if strings.HasPrefix(r.URL.Path, "/api/admin/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ipSubject := ""
+			if ok {
+				ipSubject = banSubjectIP + addr.String()
+				if guard.banned(ipSubject) != nil {
+					writeForbidden(w, "client temporarily banned")
+					return
+				}
+			}
+
+			state := &abuseState{}
+			ctx := context.WithValue(r.Context(), abuseStateKey, state)
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+			if ipSubject != "" {
+				guard.record(ipSubject, wrapped.statusCode)
+			}
+			if state.keyID != "" {
+				guard.record(banSubjectKey+state.keyID, wrapped.statusCode)
+			}
+		})
+	}
+}
+
+// AbuseIdentity rejects requests authenticated by a banned API key with 403
+// Forbidden and passes the key ID to AbuseProtection. It must run after the
+// auth middleware.
+func AbuseIdentity(guard *AbuseGuard) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keyID := auth.GetKeyID(r.Context())
+			if keyID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if state, ok := r.Context().Value(abuseStateKey).(*abuseState); ok {
+				state.keyID = keyID
+			}
+			if guard.banned(banSubjectKey+keyID) != nil {
+				writeForbidden(w, "API key temporarily banned")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeForbidden writes a 403 Forbidden JSON response.
+func writeForbidden(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"error": message,
+	})
+}
+
+// RegisterRoutes mounts the abuse admin endpoints on the given ServeMux.
+//
+// Endpoints:
+//   - GET    /api/admin/abuse/bans           - List active bans
+//   - DELETE /api/admin/abuse/bans/{subject} - Lift a ban (e.g. "ip:203.0.113.7")
+func (g *AbuseGuard) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/admin/abuse/bans", g.handleListBans)
+	mux.HandleFunc("DELETE /api/admin/abuse/bans/{subject}", g.handleUnban)
+}
+
+// handleListBans handles GET /api/admin/abuse/bans.
+func (g *AbuseGuard) handleListBans(w http.ResponseWriter, _ *http.Request) {
+	bans := g.Bans()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"bans":  bans,
+		"count": len(bans),
+	})
+}
+
+// handleUnban handles DELETE /api/admin/abuse/bans/{subject}.
+func (g *AbuseGuard) handleUnban(w http.ResponseWriter, r *http.Request) {
+	subject := r.PathValue("subject")
+	w.Header().Set("Content-Type", "application/json")
+	if !g.Unban(subject) {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "ban not found"})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "unbanned", "subject": subject})
+}
@@ -0,0 +1,280 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/auth"
+)
+
+// newTestAbuseGuard creates a guard with a small ban threshold.
+func newTestAbuseGuard(t *testing.T, cfg AbuseConfig) *AbuseGuard {
+	t.Helper()
+	cfg.BanEnabled = true
+	cfg.ErrorRateThreshold = 0.9
+	cfg.MinRequests = 5
+	cfg.Window = time.Minute
+	cfg.BanDuration = 10 * time.Minute
+
+	guard, err := NewAbuseGuard(cfg, nil)
+	if err != nil {
+		t.Fatalf("NewAbuseGuard() error = %v", err)
+	}
+	return guard
+}
+
+// statusHandler responds with a fixed status code.
+func statusHandler(code int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(code)
+	})
+}
+
+// TestAbuseProtection_CIDRLists verifies deny lists take precedence and a
+// non-empty allow list rejects everything else.
+func TestAbuseProtection_CIDRLists(t *testing.T) {
+	guard := newTestAbuseGuard(t, AbuseConfig{
+		AllowCIDRs: []string{"10.0.0.0/8", "192.0.2.10"},
+		DenyCIDRs:  []string{"10.1.0.0/16"},
+	})
+	handler := AbuseProtection(guard)(statusHandler(http.StatusOK))
+
+	tests := []struct {
+		remoteAddr string
+		want       int
+	}{
+		{remoteAddr: "10.2.3.4:5000", want: http.StatusOK},
+		{remoteAddr: "192.0.2.10:5000", want: http.StatusOK},
+		{remoteAddr: "10.1.2.3:5000", want: http.StatusForbidden},
+		{remoteAddr: "203.0.113.7:5000", want: http.StatusForbidden},
+	}
+
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/v1/events/ingest", nil)
+		req.RemoteAddr = tc.remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tc.want {
+			t.Errorf("%s: got status %d, want %d", tc.remoteAddr, rec.Code, tc.want)
+		}
+	}
+}
+
+// TestAbuseProtection_ProbePaths verifies health and metrics probes from
+// outside the allow list are served, while other paths are rejected.
+func TestAbuseProtection_ProbePaths(t *testing.T) {
+	guard := newTestAbuseGuard(t, AbuseConfig{
+		AllowCIDRs: []string{"10.0.0.0/8"},
+		DenyCIDRs:  []string{"198.51.100.0/24"},
+	})
+	handler := AbuseProtection(guard)(statusHandler(http.StatusOK))
+
+	tests := []struct {
+		path       string
+		remoteAddr string
+		want       int
+	}{
+		{path: "/health", remoteAddr: "203.0.113.7:5000", want: http.StatusOK},
+		{path: "/ready", remoteAddr: "203.0.113.7:5000", want: http.StatusOK},
+		{path: "/metrics", remoteAddr: "198.51.100.9:5000", want: http.StatusOK},
+		{path: "/v1/events/ingest", remoteAddr: "203.0.113.7:5000", want: http.StatusForbidden},
+		{path: "/health/extra", remoteAddr: "203.0.113.7:5000", want: http.StatusForbidden},
+	}
+
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.RemoteAddr = tc.remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tc.want {
+			t.Errorf("%s from %s: got status %d, want %d", tc.path, tc.remoteAddr, rec.Code, tc.want)
+		}
+	}
+}
+
+// TestAbuseProtection_AdminPaths verifies the CIDR lists also protect the
+// admin APIs, while admin requests are neither banned nor counted.
+func TestAbuseProtection_AdminPaths(t *testing.T) {
+	guard := newTestAbuseGuard(t, AbuseConfig{DenyCIDRs: []string{"198.51.100.0/24"}})
+	handler := AbuseProtection(guard)(statusHandler(http.StatusBadRequest))
+
+	send := func(path, remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := send("/api/admin/abuse/bans", "198.51.100.9:5000"); code != http.StatusForbidden {
+		t.Errorf("denied address on admin path: got status %d, want %d", code, http.StatusForbidden)
+	}
+
+	for i := range 10 {
+		if code := send("/api/admin/keys", "203.0.113.7:5000"); code != http.StatusBadRequest {
+			t.Fatalf("admin request %d: got status %d, want %d", i, code, http.StatusBadRequest)
+		}
+	}
+	if bans := guard.Bans(); len(bans) != 0 {
+		t.Fatalf("Bans() = %+v, want none for admin requests", bans)
+	}
+
+	// A banned address can still reach the admin APIs to lift its ban
+	for range 5 {
+		send("/v1/events/ingest", "203.0.113.7:5000")
+	}
+	if code := send("/v1/events/ingest", "203.0.113.7:5000"); code != http.StatusForbidden {
+		t.Fatalf("after ban: got status %d, want %d", code, http.StatusForbidden)
+	}
+	if code := send("/api/admin/abuse/bans", "203.0.113.7:5000"); code != http.StatusBadRequest {
+		t.Errorf("banned address on admin path: got status %d, want %d", code, http.StatusBadRequest)
+	}
+}
+
+// TestNewAbuseGuard_InvalidCIDR verifies invalid CIDRs are rejected at startup.
+func TestNewAbuseGuard_InvalidCIDR(t *testing.T) {
+	if _, err := NewAbuseGuard(AbuseConfig{DenyCIDRs: []string{"10.0.0.0/33"}}, nil); err == nil {
+		t.Error("NewAbuseGuard() should reject an invalid CIDR")
+	}
+}
+
+// TestAbuseProtection_BansIPOnErrorRate verifies a client IP is banned once
+// its 4xx rate reaches the threshold, and that the ban can be lifted.
+func TestAbuseProtection_BansIPOnErrorRate(t *testing.T) {
+	guard := newTestAbuseGuard(t, AbuseConfig{})
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	guard.now = func() time.Time { return now }
+
+	badHandler := AbuseProtection(guard)(statusHandler(http.StatusBadRequest))
+	goodHandler := AbuseProtection(guard)(statusHandler(http.StatusOK))
+
+	send := func(h http.Handler) int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/events/ingest", nil)
+		req.RemoteAddr = "203.0.113.7:5000"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := range 5 {
+		if code := send(badHandler); code != http.StatusBadRequest {
+			t.Fatalf("request %d: got status %d, want %d", i, code, http.StatusBadRequest)
+		}
+	}
+	if code := send(goodHandler); code != http.StatusForbidden {
+		t.Fatalf("after ban: got status %d, want %d", code, http.StatusForbidden)
+	}
+
+	bans := guard.Bans()
+	if len(bans) != 1 || bans[0].Subject != "ip:203.0.113.7" || bans[0].Errors != 5 {
+		t.Fatalf("Bans() = %+v, want one ban of ip:203.0.113.7 with 5 errors", bans)
+	}
+
+	// The ban expires after BanDuration
+	now = now.Add(11 * time.Minute)
+	if code := send(goodHandler); code != http.StatusOK {
+		t.Errorf("after expiry: got status %d, want %d", code, http.StatusOK)
+	}
+}
+
+// TestAbuseProtection_RateLimitedNotCounted verifies 429 and 426 responses
+// do not count towards bans.
+func TestAbuseProtection_RateLimitedNotCounted(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusUpgradeRequired} {
+		guard := newTestAbuseGuard(t, AbuseConfig{})
+		handler := AbuseProtection(guard)(statusHandler(status))
+
+		for range 10 {
+			req := httptest.NewRequest(http.MethodPost, "/v1/events/ingest", nil)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		if bans := guard.Bans(); len(bans) != 0 {
+			t.Errorf("status %d: Bans() = %+v, want none", status, bans)
+		}
+	}
+}
+
+// TestAbuseIdentity_BansKey verifies an API key is banned on its own error
+// rate, independently of the client IP.
+func TestAbuseIdentity_BansKey(t *testing.T) {
+	guard := newTestAbuseGuard(t, AbuseConfig{})
+
+	// Simulate the auth middleware between AbuseProtection and AbuseIdentity
+	withKey := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), auth.KeyIDContextKey, "key-1")
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+	chain := func(code int) http.Handler {
+		return Chain(statusHandler(code), AbuseProtection(guard), withKey, AbuseIdentity(guard))
+	}
+
+	for i := range 5 {
+		req := httptest.NewRequest(http.MethodPost, "/v1/events/ingest", nil)
+		req.RemoteAddr = fmt.Sprintf("198.51.100.%d:5000", i+1)
+		chain(http.StatusBadRequest).ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/events/ingest", nil)
+	req.RemoteAddr = "198.51.100.9:5000"
+	rec := httptest.NewRecorder()
+	chain(http.StatusOK).ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("banned key: got status %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	if guard.banned("key:key-1") == nil {
+		t.Error("key-1 should be banned")
+	}
+	if guard.banned("ip:198.51.100.1") != nil {
+		t.Error("individual IPs should not be banned")
+	}
+}
+
+// TestAbuseGuard_AdminRoutes verifies bans can be listed and lifted.
+func TestAbuseGuard_AdminRoutes(t *testing.T) {
+	guard := newTestAbuseGuard(t, AbuseConfig{})
+	for range 5 {
+		guard.record("ip:203.0.113.7", http.StatusUnauthorized)
+	}
+
+	mux := http.NewServeMux()
+	guard.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/admin/abuse/bans", nil))
+	var list struct {
+		Bans  []Ban `json:"bans"`
+		Count int   `json:"count"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("decode list response: %v", err)
+	}
+	if list.Count != 1 || list.Bans[0].Subject != "ip:203.0.113.7" {
+		t.Fatalf("list = %+v, want one ban of ip:203.0.113.7", list)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/admin/abuse/bans/ip:203.0.113.7", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("unban: got status %d, want %d", rec.Code, http.StatusOK)
+	}
+	if len(guard.Bans()) != 0 {
+		t.Error("ban should be lifted")
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/admin/abuse/bans/ip:203.0.113.7", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("second unban: got status %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
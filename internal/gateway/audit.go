@@ -0,0 +1,197 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/audit"
+	"github.com/SebastienMelki/causality/internal/auth"
+)
+
+// auditReasonPublishFailed is the decision reason recorded when an event
+// passed validation but could not be published to NATS.
+const auditReasonPublishFailed = "publish_failed"
+
+// auditReasonPublishTimeout is the decision reason recorded when an event
+// was not published within the publish timeout.
+const auditReasonPublishTimeout = "publish_timeout"
+
+// auditStateKey is the context key for the per-request audit state.
+const auditStateKey ContextKey = "audit_state"
+
+// AuditRecorder receives one audit record per ingestion request.
+// Implementations must be safe for concurrent use and must not block.
+type AuditRecorder interface {
+	// Record enqueues an audit record for asynchronous delivery.
+	Record(rec audit.Record)
+}
+
+// auditState accumulates audit details while a request flows through the
+// middleware chain and the event service. It is stored in the request context
+// by the Audit middleware; all methods are safe to call on a nil receiver so
+// the event service works unchanged when auditing is disabled.
+type auditState struct {
+	mu         sync.Mutex
+	keyID      string
+	appID      string
+	eventCount int
+	accepted   int
+	rejected   int
+	duplicates int
+	reasons    []string
+	sdk        SDKInfo
+}
+
+// auditFromContext returns the audit state for the request, or nil if the
+// request is not being audited.
+func auditFromContext(ctx context.Context) *auditState {
+	state, _ := ctx.Value(auditStateKey).(*auditState)
+	return state
+}
+
+// setIdentity records the authenticated API key and app.
+func (a *auditState) setIdentity(keyID, appID string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.keyID = keyID
+	a.appID = appID
+}
+
+// setSDK records the SDK handshake of the request.
+func (a *auditState) setSDK(info SDKInfo) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sdk = info
+}
+
+// setEventCount records the number of events submitted in the request.
+func (a *auditState) setEventCount(n int) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.eventCount = n
+}
+
+// accept records an accepted event. Duplicates are accepted but counted separately.
+func (a *auditState) accept(duplicate bool) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.accepted++
+	if duplicate {
+		a.duplicates++
+	}
+}
+
+// reject records a rejected event with its decision reason.
+func (a *auditState) reject(reason string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rejected++
+	a.addReasonLocked(reason)
+}
+
+// fail records a request-level failure reason without counting an event.
+func (a *auditState) fail(reason string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.addReasonLocked(reason)
+}
+
+// addReasonLocked appends a reason if not already present. Caller must hold a.mu.
+func (a *auditState) addReasonLocked(reason string) {
+	for _, r := range a.reasons {
+		if r == reason {
+			return
+		}
+	}
+	a.reasons = append(a.reasons, reason)
+}
+
+// Audit emits one audit record per ingestion request to the given recorder.
+// It must run inside RequestID (so the request ID is available) and outside
+// the auth and rate-limit middleware (so their rejections are captured).
+// Non-ingestion paths pass through without being audited.
+func Audit(recorder AuditRecorder) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isIngestPath(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			state := &auditState{}
+			ctx := context.WithValue(r.Context(), auditStateKey, state)
+
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+			recorder.Record(buildAuditRecord(r, state, wrapped.statusCode, start))
+		})
+	}
+}
+
+// AuditIdentity copies the authenticated API key ID and app_id into the audit
+// state. It must run after the auth middleware.
+func AuditIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		auditFromContext(ctx).setIdentity(auth.GetKeyID(ctx), auth.GetAppID(ctx))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// buildAuditRecord assembles the final audit record for a completed request.
+func buildAuditRecord(r *http.Request, state *auditState, statusCode int, start time.Time) audit.Record {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	reasons := append([]string(nil), state.reasons...)
+	if len(reasons) == 0 {
+		if reason := audit.StatusReason(statusCode); reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+
+	return audit.Record{
+		RequestID:       GetRequestID(r.Context()),
+		KeyID:           state.keyID,
+		AppID:           state.appID,
+		Method:          r.Method,
+		Path:            r.URL.Path,
+		ClientIP:        r.RemoteAddr,
+		UserAgent:       r.UserAgent(),
+		SDKName:         state.sdk.Name,
+		SDKVersion:      state.sdk.Version,
+		SDKPlatform:     state.sdk.Platform,
+		SDKCapabilities: state.sdk.Capabilities,
+		StatusCode:      statusCode,
+		Decision:        audit.DecisionFor(statusCode, state.accepted, state.rejected),
+		EventCount:      state.eventCount,
+		AcceptedCount:   state.accepted,
+		RejectedCount:   state.rejected,
+		DuplicateCount:  state.duplicates,
+		Reasons:         reasons,
+		DurationMs:      time.Since(start).Milliseconds(),
+		ReceivedAt:      start.UTC(),
+	}
+}
@@ -0,0 +1,122 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/audit"
+	"github.com/SebastienMelki/causality/internal/auth"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// mockAuditRecorder collects audit records for assertions.
+type mockAuditRecorder struct {
+	mu      sync.Mutex
+	records []audit.Record
+}
+
+func (m *mockAuditRecorder) Record(rec audit.Record) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, rec)
+}
+
+// fakeAuth injects a fixed key ID and app ID, standing in for the auth middleware.
+func fakeAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), auth.AppIDContextKey, "app-1")
+		ctx = context.WithValue(ctx, auth.KeyIDContextKey, "key-1")
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func TestAudit_RecordsBatchOutcome(t *testing.T) {
+	recorder := &mockAuditRecorder{}
+	svc := NewEventServiceWithPublisher(newMockPublisher(), nil, 0, nil)
+
+	handler := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = svc.IngestEventBatch(r.Context(), &pb.IngestEventBatchRequest{
+			Events: []*pb.EventEnvelope{
+				{
+					AppId:       "app-1",
+					TimestampMs: time.Now().UnixMilli(),
+					Payload: &pb.EventEnvelope_ScreenView{
+						ScreenView: &pb.ScreenView{ScreenName: "home"},
+					},
+				},
+				nil,
+				{AppId: "app-1"},
+			},
+		})
+		w.WriteHeader(http.StatusOK)
+	}), RequestID, Audit(recorder), fakeAuth, AuditIdentity)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/events/batch", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(recorder.records) != 1 {
+		t.Fatalf("got %d audit records, want 1", len(recorder.records))
+	}
+	rec := recorder.records[0]
+
+	if rec.RequestID != "req-123" {
+		t.Errorf("RequestID = %q, want req-123", rec.RequestID)
+	}
+	if rec.KeyID != "key-1" || rec.AppID != "app-1" {
+		t.Errorf("identity = (%q, %q), want (key-1, app-1)", rec.KeyID, rec.AppID)
+	}
+	if rec.EventCount != 3 || rec.AcceptedCount != 1 || rec.RejectedCount != 2 {
+		t.Errorf("counts = %d/%d/%d, want 3/1/2", rec.EventCount, rec.AcceptedCount, rec.RejectedCount)
+	}
+	if rec.Decision != audit.DecisionPartial {
+		t.Errorf("Decision = %q, want %q", rec.Decision, audit.DecisionPartial)
+	}
+	if len(rec.Reasons) != 2 {
+		t.Errorf("Reasons = %v, want 2 distinct reasons", rec.Reasons)
+	}
+}
+
+func TestAudit_RecordsMiddlewareRejection(t *testing.T) {
+	recorder := &mockAuditRecorder{}
+
+	reject := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		})
+	}
+	handler := Chain(http.NotFoundHandler(), RequestID, Audit(recorder), reject)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/events/ingest", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(recorder.records) != 1 {
+		t.Fatalf("got %d audit records, want 1", len(recorder.records))
+	}
+	rec := recorder.records[0]
+
+	if rec.StatusCode != http.StatusUnauthorized {
+		t.Errorf("StatusCode = %d, want 401", rec.StatusCode)
+	}
+	if rec.Decision != audit.DecisionRejected {
+		t.Errorf("Decision = %q, want rejected", rec.Decision)
+	}
+	if len(rec.Reasons) != 1 || rec.Reasons[0] != "unauthorized" {
+		t.Errorf("Reasons = %v, want [unauthorized]", rec.Reasons)
+	}
+}
+
+func TestAudit_SkipsNonIngestionPaths(t *testing.T) {
+	recorder := &mockAuditRecorder{}
+	handler := Chain(http.NotFoundHandler(), RequestID, Audit(recorder))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if len(recorder.records) != 0 {
+		t.Errorf("got %d audit records for /health, want 0", len(recorder.records))
+	}
+}
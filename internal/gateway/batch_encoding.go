@@ -0,0 +1,172 @@
+package gateway
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/SebastienMelki/causality/internal/ingest"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// Request formats the ingestion endpoints advertise. Besides the
+// sebuf-generated JSON and protobuf bindings they accept
+// ingest.DelimitedBatchContentType bodies.
+const (
+	// acceptPostValue advertises the request bodies the ingestion endpoints
+	// accept (Accept-Post, W3C LDP) so clients can upgrade from JSON.
+	acceptPostValue = "application/json, application/x-protobuf, " + ingest.DelimitedBatchContentType
+
+	// acceptEncodingValue advertises the request content codings the
+	// ingestion endpoints accept (RFC 7694).
+	acceptEncodingValue = "gzip"
+)
+
+// BatchDecoding decodes compressed and length-delimited ingestion bodies
+// before they reach the generated handlers:
+//
+//   - Content-Encoding: gzip bodies are decompressed, capped at maxDecompressed bytes.
+//   - ingest.DelimitedBatchContentType bodies on the batch endpoints are re-encoded as
+//     a binary IngestEventBatchRequest (application/x-protobuf).
+//
+// Every ingestion response advertises the accepted formats via Accept-Post
+// and Accept-Encoding. Unsupported codings get 415 so clients can fall back
+// to plain JSON.
+func BatchDecoding(maxDecompressed int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isIngestPath(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Accept-Post", acceptPostValue)
+			w.Header().Set("Accept-Encoding", acceptEncodingValue)
+
+			if r.Method != http.MethodPost {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if err := decodeBody(w, r, maxDecompressed); err != nil {
+				auditFromContext(r.Context()).fail(err.Error())
+				http.Error(w, err.Error(), decodeErrorStatus(err))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// batchPath is the v1 batch ingestion endpoint.
+const batchPath = "/v1/events/batch"
+
+// decodeBody applies the content coding and, for delimited batches, rewrites
+// the body into a binary IngestEventBatchRequest.
+func decodeBody(w http.ResponseWriter, r *http.Request, maxDecompressed int64) error {
+	if err := decodeContentEncoding(w, r, maxDecompressed); err != nil {
+		return err
+	}
+
+	if mediaType(r.Header.Get("Content-Type")) != ingest.DelimitedBatchContentType {
+		return nil
+	}
+	if !isBatchPath(r.URL.Path) {
+		return fmt.Errorf("%w: %s is only accepted on the batch endpoints", ingest.ErrUnsupportedBatchEncoding, ingest.DelimitedBatchContentType)
+	}
+	return rewriteDelimitedBatch(r)
+}
+
+// decodeErrorStatus maps a decoding error to its HTTP status. Unsupported
+// formats get 415 so that clients fall back to JSON.
+func decodeErrorStatus(err error) int {
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxErr):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ingest.ErrUnsupportedBatchEncoding):
+		return http.StatusUnsupportedMediaType
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// decodeContentEncoding replaces a gzip-encoded body with its decompressed
+// stream. Identity and absent codings pass through.
+func decodeContentEncoding(w http.ResponseWriter, r *http.Request, maxDecompressed int64) error {
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "", "identity":
+		return nil
+	case "gzip", "x-gzip":
+	default:
+		return fmt.Errorf("%w: content encoding %q", ingest.ErrUnsupportedBatchEncoding, encoding)
+	}
+
+	gz, err := gzip.NewReader(r.Body)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ingest.ErrInvalidBatchBody, err)
+	}
+
+	r.Body = &gzipBody{
+		Reader: http.MaxBytesReader(w, gz, maxDecompressed),
+		gz:     gz,
+		raw:    r.Body,
+	}
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	r.ContentLength = -1
+	return nil
+}
+
+// gzipBody is a decompressed request body that closes both the gzip reader
+// and the underlying body.
+type gzipBody struct {
+	io.Reader
+	gz  *gzip.Reader
+	raw io.ReadCloser
+}
+
+func (b *gzipBody) Close() error {
+	_ = b.gz.Close()
+	return b.raw.Close()
+}
+
+// rewriteDelimitedBatch decodes length-delimited EventEnvelopes from the body
+// and replaces it with the equivalent binary IngestEventBatchRequest.
+func rewriteDelimitedBatch(r *http.Request) error {
+	events, err := ingest.ReadDelimitedEvents(r.Body)
+	_ = r.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	body, err := proto.Marshal(&pb.IngestEventBatchRequest{Events: events})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ingest.ErrInvalidBatchBody, err)
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	r.Header.Set("Content-Type", pb.ProtoContentType)
+	return nil
+}
+
+// mediaType returns the media type of a Content-Type header without parameters.
+func mediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.TrimSpace(strings.ToLower(contentType))
+	}
+	return mt
+}
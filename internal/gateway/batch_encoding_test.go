@@ -0,0 +1,219 @@
+package gateway
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/SebastienMelki/causality/internal/ingest"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// newBatchDecodingHandler returns the generated event handlers behind the
+// BatchDecoding middleware, publishing into pub.
+func newBatchDecodingHandler(t *testing.T, pub *mockPublisher, maxDecompressed int64) http.Handler {
+	t.Helper()
+	mux := http.NewServeMux()
+	svc := NewEventServiceWithPublisher(pub, nil, 0, nil)
+	if err := pb.RegisterEventServiceServer(svc, pb.WithMux(mux)); err != nil {
+		t.Fatalf("register event service: %v", err)
+	}
+	return Chain(mux, BatchDecoding(maxDecompressed))
+}
+
+func testEnvelopes(n int) []*pb.EventEnvelope {
+	events := make([]*pb.EventEnvelope, n)
+	for i := range events {
+		events[i] = &pb.EventEnvelope{
+			AppId:       "test-app",
+			DeviceId:    "device-1",
+			TimestampMs: time.Now().UnixMilli(),
+			Payload:     &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}},
+		}
+	}
+	return events
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func delimitedBatch(t *testing.T, events []*pb.EventEnvelope) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	for _, e := range events {
+		if _, err := protodelim.MarshalTo(&buf, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestBatchDecoding_GzipDelimitedBatch(t *testing.T) {
+	pub := newMockPublisher()
+	handler := newBatchDecodingHandler(t, pub, 1<<20)
+
+	body := gzipBytes(t, delimitedBatch(t, testEnvelopes(3)))
+	req := httptest.NewRequest(http.MethodPost, "/v1/events/batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", ingest.DelimitedBatchContentType)
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-protobuf" {
+		t.Errorf("response Content-Type = %q, want application/x-protobuf", ct)
+	}
+
+	var resp pb.IngestEventBatchResponse
+	if err := proto.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if resp.AcceptedCount != 3 || len(pub.publishedEvents) != 3 {
+		t.Errorf("accepted = %d, published = %d, want 3", resp.AcceptedCount, len(pub.publishedEvents))
+	}
+}
+
+func TestBatchDecoding_GzipJSON(t *testing.T) {
+	pub := newMockPublisher()
+	handler := newBatchDecodingHandler(t, pub, 1<<20)
+
+	jsonBody := fmt.Sprintf(`{"event":{"appId":"test-app","deviceId":"d1","timestampMs":"%d","screenView":{"screenName":"home"}}}`,
+		time.Now().UnixMilli())
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/events/ingest", bytes.NewReader(gzipBytes(t, []byte(jsonBody))))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if len(pub.publishedEvents) != 1 {
+		t.Errorf("published = %d, want 1", len(pub.publishedEvents))
+	}
+}
+
+func TestBatchDecoding_AdvertisesFormats(t *testing.T) {
+	handler := newBatchDecodingHandler(t, newMockPublisher(), 1<<20)
+
+	body := `{"events":[]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/events/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Accept-Post"); !strings.Contains(got, ingest.DelimitedBatchContentType) {
+		t.Errorf("Accept-Post = %q, want it to list %s", got, ingest.DelimitedBatchContentType)
+	}
+	if got := rec.Header().Get("Accept-Encoding"); got != "gzip" {
+		t.Errorf("Accept-Encoding = %q, want gzip", got)
+	}
+}
+
+func TestBatchDecoding_Rejections(t *testing.T) {
+	tests := []struct {
+		name            string
+		path            string
+		contentType     string
+		contentEncoding string
+		body            []byte
+		maxDecompressed int64
+		wantStatus      int
+	}{
+		{
+			name:            "unsupported encoding",
+			path:            "/v1/events/batch",
+			contentType:     ingest.DelimitedBatchContentType,
+			contentEncoding: "br",
+			body:            []byte("x"),
+			wantStatus:      http.StatusUnsupportedMediaType,
+		},
+		{
+			name:            "not gzip",
+			path:            "/v1/events/batch",
+			contentType:     ingest.DelimitedBatchContentType,
+			contentEncoding: "gzip",
+			body:            []byte("not gzip"),
+			wantStatus:      http.StatusBadRequest,
+		},
+		{
+			name:        "delimited on single-event endpoint",
+			path:        "/v1/events/ingest",
+			contentType: ingest.DelimitedBatchContentType,
+			body:        []byte{},
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "truncated frame",
+			path:        "/v1/events/batch",
+			contentType: ingest.DelimitedBatchContentType,
+			body:        []byte{0x10, 0x01},
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:            "decompression bomb",
+			path:            "/v1/events/batch",
+			contentType:     ingest.DelimitedBatchContentType,
+			contentEncoding: "gzip",
+			body:            nil, // filled below
+			maxDecompressed: 64,
+			wantStatus:      http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := tt.body
+			if body == nil {
+				body = gzipBytes(t, delimitedBatch(t, testEnvelopes(10)))
+			}
+			maxDecompressed := tt.maxDecompressed
+			if maxDecompressed == 0 {
+				maxDecompressed = 1 << 20
+			}
+
+			pub := newMockPublisher()
+			handler := newBatchDecodingHandler(t, pub, maxDecompressed)
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewReader(body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.contentEncoding != "" {
+				req.Header.Set("Content-Encoding", tt.contentEncoding)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if len(pub.publishedEvents) != 0 {
+				t.Errorf("published %d events, want 0", len(pub.publishedEvents))
+			}
+		})
+	}
+}
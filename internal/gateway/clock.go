@@ -0,0 +1,61 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Clock sync headers. SDKs send their local send time in ClientTimeHeader and
+// read the gateway receive time from ServerTimeHeader on the response to
+// estimate the offset of the device clock from server time. Both are unix
+// milliseconds.
+const (
+	ClientTimeHeader = "Causality-Client-Time"
+	ServerTimeHeader = "Causality-Server-Time"
+)
+
+// clockSampleKey is the context key for the request's clock sample.
+const clockSampleKey ContextKey = "clock_sample"
+
+// clockSample is the receive time of a request and, if the client sent a
+// valid ClientTimeHeader, its device send time.
+type clockSample struct {
+	received time.Time
+	sent     time.Time
+}
+
+// clockSampleFromContext returns the request's clock sample, and false
+// outside ClockSync.
+func clockSampleFromContext(ctx context.Context) (clockSample, bool) {
+	sample, ok := ctx.Value(clockSampleKey).(clockSample)
+	return sample, ok
+}
+
+// ClockSync sets ServerTimeHeader on every response to the time the request
+// was received, and echoes a valid ClientTimeHeader back so clients can match
+// the sample to the request that produced it. Both times are kept in the
+// request context for the data-quality clock skew distribution.
+func ClockSync(next http.Handler) http.Handler {
+	return clockSync(time.Now)(next)
+}
+
+// clockSync is ClockSync with an injectable clock for tests.
+func clockSync(now func() time.Time) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sample := clockSample{received: now()}
+			w.Header().Set(ServerTimeHeader, strconv.FormatInt(sample.received.UnixMilli(), 10))
+			if sent := r.Header.Get(ClientTimeHeader); sent != "" {
+				if ms, err := strconv.ParseInt(sent, 10, 64); err == nil {
+					w.Header().Set(ClientTimeHeader, sent)
+					if ms > 0 {
+						sample.sent = time.UnixMilli(ms)
+					}
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clockSampleKey, sample)))
+		})
+	}
+}
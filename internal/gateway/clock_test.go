@@ -0,0 +1,51 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClockSync(t *testing.T) {
+	received := time.UnixMilli(1_700_000_000_123)
+	var sample clockSample
+	handler := clockSync(func() time.Time { return received })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sample, _ = clockSampleFromContext(r.Context())
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	tests := []struct {
+		name       string
+		clientTime string
+		wantEcho   string
+		wantSent   time.Time
+	}{
+		{"no client time", "", "", time.Time{}},
+		{"client time echoed", "1700000002500", "1700000002500", time.UnixMilli(1_700_000_002_500)},
+		{"invalid client time dropped", "<script>", "", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/events/ingest", nil)
+			if tt.clientTime != "" {
+				req.Header.Set(ClientTimeHeader, tt.clientTime)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusAccepted {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusAccepted)
+			}
+			if got := rec.Header().Get(ServerTimeHeader); got != "1700000000123" {
+				t.Errorf("%s = %q, want 1700000000123", ServerTimeHeader, got)
+			}
+			if got := rec.Header().Get(ClientTimeHeader); got != tt.wantEcho {
+				t.Errorf("%s = %q, want %q", ClientTimeHeader, got, tt.wantEcho)
+			}
+			if !sample.received.Equal(received) || !sample.sent.Equal(tt.wantSent) {
+				t.Errorf("clock sample = %+v, want received %v, sent %v", sample, received, tt.wantSent)
+			}
+		})
+	}
+}
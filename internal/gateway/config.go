@@ -3,6 +3,8 @@ package gateway
 
 import (
 	"time"
+
+	"github.com/SebastienMelki/causality/internal/ingest"
 )
 
 // Config holds HTTP gateway configuration.
@@ -28,12 +30,36 @@ type Config struct {
 	// Rate limiting configuration
 	RateLimit RateLimitConfig `envPrefix:"RATE_LIMIT_"`
 
+	// Abuse protection configuration (IP allow/deny lists, temporary bans)
+	Abuse AbuseConfig `envPrefix:"ABUSE_"`
+
+	// Per-key request/response capture for debugging SDK integrations
+	DebugCapture DebugCaptureConfig `envPrefix:"DEBUG_CAPTURE_"`
+
 	// MaxBodySize is the maximum request body size in bytes (default: 5 MB)
 	MaxBodySize int64 `env:"MAX_BODY_SIZE" envDefault:"5242880"`
 
+	// MaxDecompressedBodySize is the maximum size of a gzip-encoded request
+	// body after decompression (default: 20 MB)
+	MaxDecompressedBodySize int64 `env:"MAX_DECOMPRESSED_BODY_SIZE" envDefault:"20971520"`
+
 	// MaxBatchEvents is the maximum number of events in a single batch request
 	MaxBatchEvents int `env:"MAX_BATCH_EVENTS" envDefault:"1000"`
 
+	// PublishTimeout bounds the time spent publishing a request's events to
+	// NATS; events not published in time are reported as publish_timeout
+	// (0 disables the deadline)
+	PublishTimeout time.Duration `env:"PUBLISH_TIMEOUT" envDefault:"2s"`
+
+	// Synthetic JetStream publish probe feeding readiness
+	PublishProbe PublishProbeConfig `envPrefix:"PUBLISH_PROBE_"`
+
+	// Per-event size limits
+	EventLimits ingest.LimitsConfig `envPrefix:"EVENT_LIMIT_"`
+
+	// Ingestion from edge collectors over NATS core
+	NATSIngest NATSIngestConfig `envPrefix:"NATS_INGEST_"`
+
 	// Shutdown timeout for graceful shutdown
 	ShutdownTimeout time.Duration `env:"HTTP_SHUTDOWN_TIMEOUT" envDefault:"30s"`
 }
@@ -47,10 +73,10 @@ type CORSConfig struct {
 	AllowedMethods []string `env:"ALLOWED_METHODS" envDefault:"GET,POST,PUT,DELETE,OPTIONS"`
 
 	// AllowedHeaders is a list of allowed headers
-	AllowedHeaders []string `env:"ALLOWED_HEADERS" envDefault:"Accept,Authorization,Content-Type,X-Request-ID,X-Correlation-ID,X-API-Key"`
+	AllowedHeaders []string `env:"ALLOWED_HEADERS" envDefault:"Accept,Authorization,Content-Type,X-Request-ID,X-Correlation-ID,X-API-Key,Causality-Client-Time,X-Causality-SDK"`
 
 	// ExposedHeaders is a list of headers exposed to the client
-	ExposedHeaders []string `env:"EXPOSED_HEADERS" envDefault:"X-Request-ID"`
+	ExposedHeaders []string `env:"EXPOSED_HEADERS" envDefault:"X-Request-ID,Causality-Server-Time,Causality-Client-Time,Causality-Min-SDK-Version"`
 
 	// AllowCredentials indicates whether cookies are allowed
 	AllowCredentials bool `env:"ALLOW_CREDENTIALS" envDefault:"false"`
@@ -75,4 +101,113 @@ type RateLimitConfig struct {
 
 	// PerKeyBurst is the per-API-key burst size
 	PerKeyBurst int `env:"PER_KEY_BURST" envDefault:"2000"`
+
+	// RedisAddr is the Redis address (host:port) holding per-key token buckets
+	// shared by all gateway replicas; empty keeps limits in process
+	RedisAddr string `env:"REDIS_ADDR"`
+
+	// RedisUsername is the Redis ACL user; empty authenticates with the password only
+	RedisUsername string `env:"REDIS_USERNAME"`
+
+	// RedisPassword is the Redis AUTH password
+	RedisPassword string `env:"REDIS_PASSWORD"`
+
+	// RedisTLS connects to Redis over TLS
+	RedisTLS bool `env:"REDIS_TLS" envDefault:"false"`
+
+	// RedisDB is the Redis database number
+	RedisDB int `env:"REDIS_DB" envDefault:"0"`
+
+	// RedisTimeout bounds each Redis call; on timeout or error the local limiter is used
+	RedisTimeout time.Duration `env:"REDIS_TIMEOUT" envDefault:"50ms"`
+
+	// RedisPoolSize is the maximum number of Redis connections
+	RedisPoolSize int `env:"REDIS_POOL_SIZE" envDefault:"16"`
+
+	// RedisKeyPrefix is prepended to the app_id to form the Redis key
+	RedisKeyPrefix string `env:"REDIS_KEY_PREFIX" envDefault:"causality:ratelimit:"`
+}
+
+// NATSIngestConfig holds settings for ingesting events published over NATS
+// core by edge collectors that cannot use HTTP.
+type NATSIngestConfig struct {
+	// Enabled subscribes the gateway to Subject
+	Enabled bool `env:"ENABLED" envDefault:"false"`
+
+	// Subject is the subject collectors publish batches to; it must not
+	// overlap the subjects of the event streams
+	Subject string `env:"SUBJECT" envDefault:"ingest.raw.>"`
+
+	// QueueGroup is shared by the gateway replicas so each message is
+	// ingested once
+	QueueGroup string `env:"QUEUE_GROUP" envDefault:"causality-gateway"`
+
+	// Workers is the number of messages ingested concurrently per gateway
+	Workers int `env:"WORKERS" envDefault:"4"`
+}
+
+// PublishProbeConfig holds synthetic publish probe configuration.
+type PublishProbeConfig struct {
+	// Enabled enables the probe; when acks stall, /ready fails and ingestion
+	// is rejected with 503
+	Enabled bool `env:"ENABLED" envDefault:"true"`
+
+	// Subject is the subject probes are published to, captured by the probe stream
+	Subject string `env:"SUBJECT" envDefault:"probe.gateway"`
+
+	// Interval is the time between probes
+	Interval time.Duration `env:"INTERVAL" envDefault:"5s"`
+
+	// Timeout bounds how long a probe waits for its ack
+	Timeout time.Duration `env:"TIMEOUT" envDefault:"2s"`
+
+	// StallThreshold is how long publishes may go unacked before the gateway
+	// stops accepting ingestion
+	StallThreshold time.Duration `env:"STALL_THRESHOLD" envDefault:"15s"`
+}
+
+// AbuseConfig holds IP filtering and automatic ban configuration.
+type AbuseConfig struct {
+	// AllowCIDRs restricts clients to these CIDRs or addresses; empty allows all
+	AllowCIDRs []string `env:"ALLOW_CIDRS"`
+
+	// DenyCIDRs rejects clients in these CIDRs or addresses (checked first)
+	DenyCIDRs []string `env:"DENY_CIDRS"`
+
+	// TrustForwardedFor takes the client IP from the first X-Forwarded-For
+	// entry; only enable behind a proxy that sets it
+	TrustForwardedFor bool `env:"TRUST_FORWARDED_FOR" envDefault:"false"`
+
+	// BanEnabled enables temporary bans of IPs and API keys with high client error rates
+	BanEnabled bool `env:"BAN_ENABLED" envDefault:"true"`
+
+	// ErrorRateThreshold is the fraction of 4xx responses (excluding 429) in a
+	// window that triggers a ban
+	ErrorRateThreshold float64 `env:"ERROR_RATE_THRESHOLD" envDefault:"0.9"`
+
+	// MinRequests is the number of requests in a window before a ban is considered
+	MinRequests int `env:"MIN_REQUESTS" envDefault:"20"`
+
+	// Window is the error rate measurement window
+	Window time.Duration `env:"WINDOW" envDefault:"1m"`
+
+	// BanDuration is how long a ban lasts
+	BanDuration time.Duration `env:"BAN_DURATION" envDefault:"15m"`
+}
+
+// DebugCaptureConfig holds per-key debug capture configuration. Capture is
+// enabled per API key through the admin API.
+type DebugCaptureConfig struct {
+	// MaxEntries is the default and maximum number of requests kept per key
+	MaxEntries int `env:"MAX_ENTRIES" envDefault:"50"`
+
+	// MaxBodyBytes is the number of request and response body bytes kept per entry
+	MaxBodyBytes int `env:"MAX_BODY_BYTES" envDefault:"65536"`
+
+	// MaxDuration is the default and maximum time a key is captured
+	MaxDuration time.Duration `env:"MAX_DURATION" envDefault:"1h"`
+
+	// RedactFields are JSON field names whose values are redacted anywhere in
+	// captured bodies, matched ignoring case, underscores and dashes
+	RedactFields []string `env:"REDACT_FIELDS" envDefault:"user_id,email,phone,phone_number,ip,ip_address,first_name,last_name,address,password,token,push_token"`
 }
@@ -0,0 +1,516 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/SebastienMelki/causality/internal/auth"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// debugCaptureKey is the context key for the per-request debug capture.
+const debugCaptureKey ContextKey = "debug_capture"
+
+// redactedValue replaces redacted header and body values.
+const redactedValue = "[REDACTED]"
+
+// debugCaptureHeaders are the request headers recorded in captures. Headers
+// carrying credentials are recorded as redacted.
+var debugCaptureHeaders = []string{
+	"Content-Type",
+	"Content-Encoding",
+	"Content-Length",
+	"User-Agent",
+	"X-API-Key",
+	"X-Causality-Timestamp",
+	"X-Causality-Signature",
+}
+
+// debugCaptureSecretHeaders are recorded as redacted.
+var debugCaptureSecretHeaders = map[string]bool{
+	"X-Api-Key":             true,
+	"X-Causality-Signature": true,
+}
+
+// CapturedRequest is a request of an API key under debug capture, with the
+// gateway's response.
+type CapturedRequest struct {
+	Time      time.Time         `json:"time"`
+	RequestID string            `json:"request_id"`
+	Method    string            `json:"method"`
+	Path      string            `json:"path"`
+	Headers   map[string]string `json:"headers"`
+
+	// RequestBody is the decoded body (decompressed; protobuf rendered as
+	// JSON) with sensitive fields redacted. It is empty if the request was
+	// rejected before its body was decoded, e.g. by rate limiting.
+	RequestBody          string `json:"request_body"`
+	RequestBodyTruncated bool   `json:"request_body_truncated,omitempty"`
+
+	// Status and ResponseBody are the gateway's decision.
+	Status                int    `json:"status"`
+	ResponseBody          string `json:"response_body"`
+	ResponseBodyTruncated bool   `json:"response_body_truncated,omitempty"`
+
+	DurationMS int64 `json:"duration_ms"`
+}
+
+// DebugCaptureSession is the debug capture of one API key.
+type DebugCaptureSession struct {
+	KeyID      string    `json:"key_id"`
+	MaxEntries int       `json:"max_entries"`
+	EnabledAt  time.Time `json:"enabled_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Captured   int       `json:"captured"`
+
+	// Entries are the last MaxEntries requests, oldest first.
+	Entries []CapturedRequest `json:"entries,omitempty"`
+}
+
+// captureSession is a DebugCaptureSession with its ring buffer.
+type captureSession struct {
+	maxEntries int
+	enabledAt  time.Time
+	expiresAt  time.Time
+	captured   int
+	ring       []CapturedRequest
+	next       int
+}
+
+// add appends an entry, overwriting the oldest once the buffer is full.
+func (s *captureSession) add(entry CapturedRequest) {
+	s.captured++
+	if len(s.ring) < s.maxEntries {
+		s.ring = append(s.ring, entry)
+		return
+	}
+	s.ring[s.next] = entry
+	s.next = (s.next + 1) % s.maxEntries
+}
+
+// entries returns the buffered entries, oldest first.
+func (s *captureSession) entries() []CapturedRequest {
+	out := make([]CapturedRequest, 0, len(s.ring))
+	out = append(out, s.ring[s.next:]...)
+	return append(out, s.ring[:s.next]...)
+}
+
+// DebugCapture records the request and response bodies of API keys an
+// operator enabled capture for, in a per-key ring buffer, to diagnose SDK
+// integration bugs. Captures are kept in memory per gateway instance and
+// stop at their expiry. It is safe for concurrent use.
+type DebugCapture struct {
+	config DebugCaptureConfig
+	redact map[string]bool
+	now    func() time.Time
+
+	mu       sync.Mutex
+	sessions map[string]*captureSession
+}
+
+// NewDebugCapture creates a debug capture with no keys enabled.
+func NewDebugCapture(cfg DebugCaptureConfig) *DebugCapture {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 50
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = 64 << 10
+	}
+	if cfg.MaxDuration <= 0 {
+		cfg.MaxDuration = time.Hour
+	}
+
+	redact := make(map[string]bool, len(cfg.RedactFields))
+	for _, field := range cfg.RedactFields {
+		redact[normalizeFieldName(field)] = true
+	}
+
+	return &DebugCapture{
+		config:   cfg,
+		redact:   redact,
+		now:      time.Now,
+		sessions: make(map[string]*captureSession),
+	}
+}
+
+// Enable starts capturing the requests of keyID into a buffer of maxEntries
+// (the configured default if zero) for duration (capped at the configured
+// maximum). Enabling an enabled key restarts its capture.
+func (d *DebugCapture) Enable(keyID string, maxEntries int, duration time.Duration) DebugCaptureSession {
+	if maxEntries <= 0 || maxEntries > d.config.MaxEntries {
+		maxEntries = d.config.MaxEntries
+	}
+	if duration <= 0 || duration > d.config.MaxDuration {
+		duration = d.config.MaxDuration
+	}
+
+	now := d.now()
+	session := &captureSession{
+		maxEntries: maxEntries,
+		enabledAt:  now,
+		expiresAt:  now.Add(duration),
+	}
+
+	d.mu.Lock()
+	d.sessions[keyID] = session
+	d.mu.Unlock()
+
+	return session.snapshot(keyID, false)
+}
+
+// Disable stops capturing keyID and drops its buffer. It reports whether
+// capture was enabled.
+func (d *DebugCapture) Disable(keyID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.sessions[keyID]
+	delete(d.sessions, keyID)
+	return ok
+}
+
+// Session returns the capture of keyID with its entries. Expired captures
+// remain readable until disabled.
+func (d *DebugCapture) Session(keyID string) (DebugCaptureSession, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	session, ok := d.sessions[keyID]
+	if !ok {
+		return DebugCaptureSession{}, false
+	}
+	return session.snapshot(keyID, true), true
+}
+
+// Sessions returns all captures without their entries, sorted by key ID.
+func (d *DebugCapture) Sessions() []DebugCaptureSession {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]DebugCaptureSession, 0, len(d.sessions))
+	for keyID, session := range d.sessions {
+		out = append(out, session.snapshot(keyID, false))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].KeyID < out[j].KeyID })
+	return out
+}
+
+// snapshot copies the session, with its entries if requested.
+func (s *captureSession) snapshot(keyID string, withEntries bool) DebugCaptureSession {
+	out := DebugCaptureSession{
+		KeyID:      keyID,
+		MaxEntries: s.maxEntries,
+		EnabledAt:  s.enabledAt,
+		ExpiresAt:  s.expiresAt,
+		Captured:   s.captured,
+	}
+	if withEntries {
+		out.Entries = s.entries()
+	}
+	return out
+}
+
+// active reports whether keyID is being captured.
+func (d *DebugCapture) active(keyID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	session, ok := d.sessions[keyID]
+	return ok && d.now().Before(session.expiresAt)
+}
+
+// record adds a captured request to the buffer of keyID, unless its capture
+// was disabled or expired meanwhile.
+func (d *DebugCapture) record(keyID string, entry CapturedRequest) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if session, ok := d.sessions[keyID]; ok && entry.Time.Before(session.expiresAt) {
+		session.add(entry)
+	}
+}
+
+// debugCaptureState collects a captured request while it flows through the
+// middleware chain.
+type debugCaptureState struct {
+	body          string
+	bodyTruncated bool
+}
+
+// captureWriter keeps the status and the first bytes of a response.
+type captureWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+	limit      int
+	truncated  bool
+}
+
+func (cw *captureWriter) WriteHeader(code int) {
+	cw.statusCode = code
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *captureWriter) Write(b []byte) (int, error) {
+	if room := cw.limit - cw.body.Len(); room > 0 {
+		cw.body.Write(b[:min(room, len(b))])
+	}
+	if cw.body.Len()+len(b) > cw.limit {
+		cw.truncated = true
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// DebugCaptureMiddleware records the requests and responses of API keys under
+// capture. It must run after the auth middleware, so the key is known, and
+// before rate limiting, so rejections are captured too. DebugCaptureBody
+// adds the decoded request body.
+func DebugCaptureMiddleware(capture *DebugCapture) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keyID := auth.GetKeyID(r.Context())
+			if keyID == "" || !capture.active(keyID) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := capture.now()
+			state := &debugCaptureState{}
+			ctx := context.WithValue(r.Context(), debugCaptureKey, state)
+			wrapped := &captureWriter{ResponseWriter: w, statusCode: http.StatusOK, limit: capture.config.MaxBodyBytes}
+			next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+			capture.record(keyID, CapturedRequest{
+				Time:                  start,
+				RequestID:             GetRequestID(ctx),
+				Method:                r.Method,
+				Path:                  r.URL.Path,
+				Headers:               capturedHeaders(r.Header),
+				RequestBody:           state.body,
+				RequestBodyTruncated:  state.bodyTruncated,
+				Status:                wrapped.statusCode,
+				ResponseBody:          capture.redactBody(wrapped.body.Bytes(), "application/json", nil),
+				ResponseBodyTruncated: wrapped.truncated,
+				DurationMS:            capture.now().Sub(start).Milliseconds(),
+			})
+		})
+	}
+}
+
+// DebugCaptureBody records the request body of captured requests. It must
+// run after BatchDecoding, so compressed and length-delimited bodies are
+// recorded decoded, and before UnknownFields, so the body is recorded as the
+// client sent it.
+func DebugCaptureBody(capture *DebugCapture) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			state, ok := r.Context().Value(debugCaptureKey).(*debugCaptureState)
+			if !ok || r.Body == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			_ = r.Body.Close()
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			if err != nil {
+				// Let the handler report the read error.
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			state.body = capture.redactBody(body, r.Header.Get("Content-Type"), ingestRequests[r.URL.Path])
+			if len(state.body) > capture.config.MaxBodyBytes {
+				state.body = state.body[:capture.config.MaxBodyBytes]
+				state.bodyTruncated = true
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// capturedHeaders returns the recorded request headers, redacting
+// credentials.
+func capturedHeaders(header http.Header) map[string]string {
+	out := make(map[string]string)
+	for _, name := range debugCaptureHeaders {
+		value := header.Get(name)
+		if value == "" {
+			continue
+		}
+		if debugCaptureSecretHeaders[http.CanonicalHeaderKey(name)] {
+			value = redactedValue
+		}
+		out[name] = value
+	}
+	return out
+}
+
+// redactBody renders a body for a capture with the configured fields
+// redacted. Protobuf bodies of a known request message are rendered as JSON.
+// Bodies that are not valid JSON, such as malformed or truncated ones, are
+// redacted textually.
+func (d *DebugCapture) redactBody(body []byte, contentType string, md protoreflect.MessageDescriptor) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	if mediaType(contentType) == pb.ProtoContentType && md != nil {
+		msg := dynamicpb.NewMessage(md)
+		if err := proto.Unmarshal(body, msg); err == nil {
+			if rendered, err := protojson.Marshal(msg); err == nil {
+				body = rendered
+			}
+		}
+	}
+
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return d.redactOpaque(body)
+	}
+	redacted, err := json.Marshal(d.redactValue(doc))
+	if err != nil {
+		return d.redactOpaque(body)
+	}
+	return string(redacted)
+}
+
+// jsonMemberPattern matches a JSON object member with a scalar value.
+var jsonMemberPattern = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"(\s*:\s*)("(?:[^"\\]|\\.)*"?|[^\s,"\[\]{}]+)`)
+
+// redactOpaque redacts the scalar values of redacted fields in a body that
+// could not be parsed as JSON.
+func (d *DebugCapture) redactOpaque(body []byte) string {
+	return jsonMemberPattern.ReplaceAllStringFunc(string(body), func(member string) string {
+		parts := jsonMemberPattern.FindStringSubmatch(member)
+		if !d.redact[normalizeFieldName(parts[1])] {
+			return member
+		}
+		return `"` + parts[1] + `"` + parts[2] + `"` + redactedValue + `"`
+	})
+}
+
+// redactValue replaces the values of redacted fields anywhere in a decoded
+// JSON document.
+func (d *DebugCapture) redactValue(v any) any {
+	switch value := v.(type) {
+	case map[string]any:
+		for key, child := range value {
+			if d.redact[normalizeFieldName(key)] {
+				value[key] = redactedValue
+				continue
+			}
+			value[key] = d.redactValue(child)
+		}
+	case []any:
+		for i, child := range value {
+			value[i] = d.redactValue(child)
+		}
+	}
+	return v
+}
+
+// normalizeFieldName lowercases a field name and drops separators, so
+// user_id, userId and user-id match.
+func normalizeFieldName(name string) string {
+	name = strings.ToLower(name)
+	return strings.NewReplacer("_", "", "-", "").Replace(name)
+}
+
+// RegisterRoutes mounts the debug capture admin endpoints on the given
+// ServeMux.
+//
+// Endpoints:
+//   - GET    /api/admin/debug-capture          - List captures (without entries)
+//   - PUT    /api/admin/debug-capture/{key_id} - Start capturing a key
+//   - GET    /api/admin/debug-capture/{key_id} - Read a key's captured requests
+//   - DELETE /api/admin/debug-capture/{key_id} - Stop capturing a key and drop its buffer
+func (d *DebugCapture) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/admin/debug-capture", d.handleList)
+	mux.HandleFunc("PUT /api/admin/debug-capture/{key_id}", d.handleEnable)
+	mux.HandleFunc("GET /api/admin/debug-capture/{key_id}", d.handleGet)
+	mux.HandleFunc("DELETE /api/admin/debug-capture/{key_id}", d.handleDisable)
+}
+
+// enableCaptureRequest is the optional body of PUT
+// /api/admin/debug-capture/{key_id}.
+type enableCaptureRequest struct {
+	// MaxEntries is the ring buffer size (default and cap:
+	// DEBUG_CAPTURE_MAX_ENTRIES).
+	MaxEntries int `json:"max_entries"`
+
+	// Duration is how long to capture, e.g. "15m" (default and cap:
+	// DEBUG_CAPTURE_MAX_DURATION).
+	Duration string `json:"duration"`
+}
+
+// handleList handles GET /api/admin/debug-capture.
+func (d *DebugCapture) handleList(w http.ResponseWriter, _ *http.Request) {
+	sessions := d.Sessions()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"captures": sessions,
+		"count":    len(sessions),
+	})
+}
+
+// handleEnable handles PUT /api/admin/debug-capture/{key_id}.
+func (d *DebugCapture) handleEnable(w http.ResponseWriter, r *http.Request) {
+	var req enableCaptureRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			writeBadRequest(w, "invalid request body")
+			return
+		}
+	}
+
+	var duration time.Duration
+	if req.Duration != "" {
+		var err error
+		if duration, err = time.ParseDuration(req.Duration); err != nil || duration <= 0 {
+			writeBadRequest(w, "duration must be a positive Go duration such as 15m")
+			return
+		}
+	}
+	if req.MaxEntries < 0 {
+		writeBadRequest(w, "max_entries must not be negative")
+		return
+	}
+
+	session := d.Enable(r.PathValue("key_id"), req.MaxEntries, duration)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(session)
+}
+
+// handleGet handles GET /api/admin/debug-capture/{key_id}.
+func (d *DebugCapture) handleGet(w http.ResponseWriter, r *http.Request) {
+	session, ok := d.Session(r.PathValue("key_id"))
+	w.Header().Set("Content-Type", "application/json")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "capture not found"})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(session)
+}
+
+// handleDisable handles DELETE /api/admin/debug-capture/{key_id}.
+func (d *DebugCapture) handleDisable(w http.ResponseWriter, r *http.Request) {
+	keyID := r.PathValue("key_id")
+	w.Header().Set("Content-Type", "application/json")
+	if !d.Disable(keyID) {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "capture not found"})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "disabled", "key_id": keyID})
+}
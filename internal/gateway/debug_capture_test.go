@@ -0,0 +1,235 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/SebastienMelki/causality/internal/auth"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+func newTestDebugCapture() *DebugCapture {
+	return NewDebugCapture(DebugCaptureConfig{
+		MaxEntries:   3,
+		MaxBodyBytes: 1024,
+		MaxDuration:  time.Hour,
+		RedactFields: []string{"user_id", "email"},
+	})
+}
+
+// captureChain simulates the middleware chain around the capture
+// middlewares, authenticating requests as keyID.
+func captureChain(capture *DebugCapture, keyID string, handler http.Handler) http.Handler {
+	withKey := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), auth.KeyIDContextKey, keyID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+	return Chain(handler, withKey, DebugCaptureMiddleware(capture), DebugCaptureBody(capture))
+}
+
+// TestDebugCapture_RecordsEnabledKeys verifies only enabled keys are
+// captured, with redacted headers and bodies, and the passed-on body intact.
+func TestDebugCapture_RecordsEnabledKeys(t *testing.T) {
+	capture := newTestDebugCapture()
+	capture.Enable("key-1", 0, 0)
+
+	var received string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"user_id":"u-1","status":"ok"}`))
+	})
+
+	body := `{"event":{"appId":"app","user_id":"u-1","customEvent":{"stringParams":{"Email":"a@example.com","plan":"pro"}}}}`
+	for _, keyID := range []string{"key-1", "key-2"} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/events/ingest", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", "secret")
+		captureChain(capture, keyID, handler).ServeHTTP(httptest.NewRecorder(), req)
+		if received != body {
+			t.Errorf("%s: handler received %q, want the original body", keyID, received)
+		}
+	}
+
+	if _, ok := capture.Session("key-2"); ok {
+		t.Error("key-2 should not be captured")
+	}
+	session, ok := capture.Session("key-1")
+	if !ok || len(session.Entries) != 1 {
+		t.Fatalf("key-1 session = %+v, want one entry", session)
+	}
+
+	entry := session.Entries[0]
+	if entry.Status != http.StatusAccepted || entry.Path != "/v1/events/ingest" {
+		t.Errorf("entry = %+v, want a 202 on /v1/events/ingest", entry)
+	}
+	if entry.Headers["X-API-Key"] != redactedValue {
+		t.Errorf("X-API-Key header = %q, want it redacted", entry.Headers["X-API-Key"])
+	}
+	for _, secret := range []string{"u-1", "a@example.com"} {
+		if strings.Contains(entry.RequestBody, secret) || strings.Contains(entry.ResponseBody, secret) {
+			t.Errorf("entry bodies contain %q: %s / %s", secret, entry.RequestBody, entry.ResponseBody)
+		}
+	}
+	if !strings.Contains(entry.RequestBody, `"plan":"pro"`) {
+		t.Errorf("request body = %s, want unredacted fields kept", entry.RequestBody)
+	}
+}
+
+// TestDebugCapture_ProtobufBody verifies protobuf request bodies are
+// captured as redacted JSON.
+func TestDebugCapture_ProtobufBody(t *testing.T) {
+	capture := newTestDebugCapture()
+	capture.Enable("key-1", 0, 0)
+
+	body, err := proto.Marshal(&pb.IngestEventRequest{Event: &pb.EventEnvelope{
+		AppId: "app",
+		Payload: &pb.EventEnvelope_CustomEvent{CustomEvent: &pb.CustomEvent{
+			EventName:    "signup",
+			StringParams: map[string]string{"email": "a@example.com"},
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/v2/events/ingest", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", pb.ProtoContentType)
+	captureChain(capture, "key-1", statusHandler(http.StatusOK)).ServeHTTP(httptest.NewRecorder(), req)
+
+	session, _ := capture.Session("key-1")
+	got := session.Entries[0].RequestBody
+	if !strings.Contains(got, `"appId":"app"`) || strings.Contains(got, "a@example.com") {
+		t.Errorf("request body = %s, want redacted JSON", got)
+	}
+}
+
+// TestDebugCapture_RingBuffer verifies only the last entries are kept,
+// oldest first.
+func TestDebugCapture_RingBuffer(t *testing.T) {
+	capture := newTestDebugCapture()
+	capture.Enable("key-1", 0, 0)
+
+	for i := range 5 {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/v1/events/ingest?n=%d", i), nil)
+		req = req.WithContext(context.WithValue(req.Context(), RequestIDKey, fmt.Sprint(i)))
+		captureChain(capture, "key-1", statusHandler(http.StatusOK)).ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	session, _ := capture.Session("key-1")
+	if session.Captured != 5 || len(session.Entries) != 3 {
+		t.Fatalf("captured = %d, entries = %d, want 5 and 3", session.Captured, len(session.Entries))
+	}
+	for i, entry := range session.Entries {
+		if want := fmt.Sprint(i + 2); entry.RequestID != want {
+			t.Errorf("entry %d request ID = %q, want %q", i, entry.RequestID, want)
+		}
+	}
+}
+
+// TestDebugCapture_Expiry verifies capture stops when it expires.
+func TestDebugCapture_Expiry(t *testing.T) {
+	capture := newTestDebugCapture()
+	now := time.Now()
+	capture.now = func() time.Time { return now }
+	capture.Enable("key-1", 0, 2*time.Hour)
+
+	session, _ := capture.Session("key-1")
+	if want := now.Add(time.Hour); !session.ExpiresAt.Equal(want) {
+		t.Errorf("expires at = %v, want capped at %v", session.ExpiresAt, want)
+	}
+
+	now = now.Add(time.Hour)
+	req := httptest.NewRequest(http.MethodPost, "/v1/events/ingest", nil)
+	captureChain(capture, "key-1", statusHandler(http.StatusOK)).ServeHTTP(httptest.NewRecorder(), req)
+	if session, _ := capture.Session("key-1"); session.Captured != 0 {
+		t.Errorf("captured = %d after expiry, want 0", session.Captured)
+	}
+}
+
+// TestRedactOpaque verifies bodies that are not valid JSON are redacted
+// textually.
+func TestRedactOpaque(t *testing.T) {
+	capture := newTestDebugCapture()
+	got := capture.redactBody([]byte(`{"events":[{"userId":"u-1","n":1},{"email": "a@example.com","pl`), "", nil)
+	want := `{"events":[{"userId":"[REDACTED]","n":1},{"email": "[REDACTED]","pl`
+	if got != want {
+		t.Errorf("redactBody() = %s, want %s", got, want)
+	}
+}
+
+// TestDebugCapture_AdminRoutes verifies captures can be enabled, read, listed
+// and disabled.
+func TestDebugCapture_AdminRoutes(t *testing.T) {
+	capture := newTestDebugCapture()
+	mux := http.NewServeMux()
+	capture.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/admin/debug-capture/key-1",
+		strings.NewReader(`{"max_entries":2,"duration":"15m"}`)))
+	var session DebugCaptureSession
+	if err := json.NewDecoder(rec.Body).Decode(&session); err != nil {
+		t.Fatalf("decode enable response: %v", err)
+	}
+	if rec.Code != http.StatusOK || session.MaxEntries != 2 || session.ExpiresAt.Sub(session.EnabledAt) != 15*time.Minute {
+		t.Fatalf("enable: status %d, session %+v", rec.Code, session)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/admin/debug-capture/key-1",
+		strings.NewReader(`{"duration":"soon"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid duration: got status %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/events/ingest", nil)
+	captureChain(capture, "key-1", statusHandler(http.StatusTooManyRequests)).ServeHTTP(httptest.NewRecorder(), req)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/admin/debug-capture/key-1", nil))
+	session = DebugCaptureSession{}
+	if err := json.NewDecoder(rec.Body).Decode(&session); err != nil {
+		t.Fatalf("decode get response: %v", err)
+	}
+	if len(session.Entries) != 1 || session.Entries[0].Status != http.StatusTooManyRequests {
+		t.Errorf("entries = %+v, want the rate limited request", session.Entries)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/admin/debug-capture", nil))
+	var list struct {
+		Captures []DebugCaptureSession `json:"captures"`
+		Count    int                   `json:"count"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("decode list response: %v", err)
+	}
+	if list.Count != 1 || list.Captures[0].KeyID != "key-1" || list.Captures[0].Entries != nil {
+		t.Errorf("list = %+v, want key-1 without entries", list)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/admin/debug-capture/key-1", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("disable: got status %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/admin/debug-capture/key-1", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("get after disable: got status %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
@@ -2,14 +2,18 @@ package gateway
 
 import "errors"
 
-// Sentinel errors for the gateway package.
+// Sentinel errors for the gateway package. Ingestion errors shared with
+// other ingestion paths are in the ingest package.
 var (
-	ErrEventRequired   = errors.New("event is required")
-	ErrAtLeastOneEvent = errors.New("at least one event is required")
-
-	// Validation errors
-	ErrAppIDRequired    = errors.New("app_id is required")
-	ErrEventTypeRequired = errors.New("event_type is required (payload must not be empty)")
-	ErrTimestampRequired = errors.New("timestamp_ms is required and must be > 0")
-	ErrBatchTooLarge     = errors.New("batch exceeds maximum event count")
+	// Validation errors of the v2 API
+	ErrTimestampInFuture = errors.New("timestamp_ms is too far in the future")
+	ErrInvalidEvent      = errors.New("invalid event")
+
+	// ErrEventTypeNotAllowed means the authenticating API key is restricted
+	// to other event categories or types. It is returned as 403 Forbidden.
+	ErrEventTypeNotAllowed = errors.New("event type not allowed for this API key")
+
+	// ErrPublishStalled means the publish probe has not been acked within
+	// its stall threshold. The gateway then reports not ready.
+	ErrPublishStalled = errors.New("JetStream publish acks stalled")
 )
@@ -0,0 +1,133 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// experimentBuckets is the number of hash buckets devices are spread over.
+// Variant weights are resolved to bucket ranges, so a device keeps its
+// variant as long as the experiment's weights do not change.
+const experimentBuckets = 10000
+
+// defaultExperimentVariants is used when an assignment request names no
+// variants: an even split between control and treatment.
+var defaultExperimentVariants = []ExperimentVariant{
+	{Name: "control", Weight: 1},
+	{Name: "treatment", Weight: 1},
+}
+
+// ExperimentVariant is a variant of an experiment and its share of devices.
+type ExperimentVariant struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+}
+
+// AssignRequest is the body of POST /v1/experiments/assign.
+type AssignRequest struct {
+	ExperimentID string              `json:"experiment_id"`
+	DeviceID     string              `json:"device_id"`
+	Variants     []ExperimentVariant `json:"variants,omitempty"`
+}
+
+// AssignResponse is the response of POST /v1/experiments/assign.
+type AssignResponse struct {
+	ExperimentID string `json:"experiment_id"`
+	DeviceID     string `json:"device_id"`
+	Variant      string `json:"variant"`
+	Bucket       int    `json:"bucket"`
+}
+
+// experimentBucket returns the bucket of a device in an experiment: the
+// first 8 bytes of SHA-256("{experiment_id}:{device_id}") modulo
+// experimentBuckets. It is deterministic, so the lake can recompute
+// assignments when joining exposures with outcomes.
+func experimentBucket(experimentID, deviceID string) int {
+	sum := sha256.Sum256([]byte(experimentID + ":" + deviceID))
+	return int(binary.BigEndian.Uint64(sum[:8]) % experimentBuckets)
+}
+
+// assignVariant returns the variant a bucket falls into. Variants cover
+// consecutive bucket ranges proportional to their weights, in order.
+func assignVariant(bucket int, variants []ExperimentVariant) string {
+	total := 0
+	for _, v := range variants {
+		total += v.Weight
+	}
+	cumulative := 0
+	for _, v := range variants {
+		cumulative += v.Weight
+		if bucket*total < cumulative*experimentBuckets {
+			return v.Name
+		}
+	}
+	return variants[len(variants)-1].Name
+}
+
+// validateAssignRequest checks an assignment request names an experiment, a
+// device, and uniquely named variants with positive weights.
+func validateAssignRequest(req *AssignRequest) error {
+	if strings.TrimSpace(req.ExperimentID) == "" {
+		return errors.New("experiment_id is required")
+	}
+	if strings.TrimSpace(req.DeviceID) == "" {
+		return errors.New("device_id is required")
+	}
+	seen := make(map[string]bool, len(req.Variants))
+	for _, v := range req.Variants {
+		if v.Name == "" {
+			return errors.New("variant name is required")
+		}
+		if v.Weight <= 0 {
+			return errors.New("variant weight must be positive")
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("duplicate variant %q", v.Name)
+		}
+		seen[v.Name] = true
+	}
+	return nil
+}
+
+// writeBadRequest writes a 400 Bad Request JSON response.
+func writeBadRequest(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"error": message,
+	})
+}
+
+// handleAssign handles POST /v1/experiments/assign. It is stateless: clients
+// send the experiment's variants with every request and track the returned
+// variant as an experiment_exposure event when the user sees it.
+func handleAssign(w http.ResponseWriter, r *http.Request) {
+	var req AssignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, "invalid request body")
+		return
+	}
+	if err := validateAssignRequest(&req); err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+
+	variants := req.Variants
+	if len(variants) == 0 {
+		variants = defaultExperimentVariants
+	}
+	bucket := experimentBucket(req.ExperimentID, req.DeviceID)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(AssignResponse{
+		ExperimentID: req.ExperimentID,
+		DeviceID:     req.DeviceID,
+		Variant:      assignVariant(bucket, variants),
+		Bucket:       bucket,
+	})
+}
@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAssignVariant_Weights(t *testing.T) {
+	variants := []ExperimentVariant{{Name: "a", Weight: 1}, {Name: "b", Weight: 3}}
+	tests := []struct {
+		bucket int
+		want   string
+	}{
+		{0, "a"},
+		{2499, "a"},
+		{2500, "b"},
+		{experimentBuckets - 1, "b"},
+	}
+	for _, tt := range tests {
+		if got := assignVariant(tt.bucket, variants); got != tt.want {
+			t.Errorf("assignVariant(%d) = %q, want %q", tt.bucket, got, tt.want)
+		}
+	}
+}
+
+func TestExperimentBucket_DeterministicAndSpread(t *testing.T) {
+	if experimentBucket("exp", "dev-1") != experimentBucket("exp", "dev-1") {
+		t.Fatal("experimentBucket() is not deterministic")
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		bucket := experimentBucket("checkout-v2", fmt.Sprintf("dev-%d", i))
+		counts[assignVariant(bucket, defaultExperimentVariants)]++
+	}
+	for _, name := range []string{"control", "treatment"} {
+		if counts[name] < 4700 || counts[name] > 5300 {
+			t.Errorf("%s got %d of 10000 devices, want about half", name, counts[name])
+		}
+	}
+}
+
+func TestHandleAssign(t *testing.T) {
+	body := `{"experiment_id":"checkout-v2","device_id":"dev-1","variants":[{"name":"control","weight":50},{"name":"treatment","weight":50}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/experiments/assign", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handleAssign(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var resp AssignResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	wantBucket := experimentBucket("checkout-v2", "dev-1")
+	if resp.Bucket != wantBucket || resp.Variant != assignVariant(wantBucket, defaultExperimentVariants) {
+		t.Errorf("response = %+v, want bucket %d", resp, wantBucket)
+	}
+
+	invalid := []string{
+		`not json`,
+		`{"device_id":"dev-1"}`,
+		`{"experiment_id":"exp"}`,
+		`{"experiment_id":"exp","device_id":"dev-1","variants":[{"name":"a","weight":0}]}`,
+		`{"experiment_id":"exp","device_id":"dev-1","variants":[{"name":"a","weight":1},{"name":"a","weight":1}]}`,
+	}
+	for _, body := range invalid {
+		rec := httptest.NewRecorder()
+		handleAssign(rec, httptest.NewRequest(http.MethodPost, "/v1/experiments/assign", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("body %s: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
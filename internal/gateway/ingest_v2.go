@@ -0,0 +1,302 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"buf.build/go/protovalidate"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/SebastienMelki/causality/internal/events"
+	"github.com/SebastienMelki/causality/internal/ingest"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// maxClockSkew bounds how far in the future a v2 event's client timestamp
+// may be. Events from clients with a badly set clock are rejected instead of
+// landing in a future partition.
+const maxClockSkew = time.Hour
+
+// maxBatchEventsV2 is the batch size limit of the batch request's max_items
+// constraint, which v2 checks without validating the events as a whole.
+const maxBatchEventsV2 = 1000
+
+// Error codes of v2 structured errors.
+const (
+	ErrorCodeInvalidBody           = "invalid_body"
+	ErrorCodeEventRequired         = "event_required"
+	ErrorCodeEventsRequired        = "events_required"
+	ErrorCodeBatchTooLarge         = "batch_too_large"
+	ErrorCodeInvalidField          = "invalid_field"
+	ErrorCodeAppIDRequired         = "app_id_required"
+	ErrorCodePayloadRequired       = "payload_required"
+	ErrorCodeTimestampRequired     = "timestamp_required"
+	ErrorCodeTimestampInFuture     = "timestamp_in_future"
+	ErrorCodeEventTypeNotAllowed   = "event_type_not_allowed"
+	ErrorCodeUnsupportedSchema     = "unsupported_schema_version"
+	ErrorCodeSDKVersionUnsupported = "sdk_version_unsupported"
+	ErrorCodePublishTimeout        = "publish_timeout"
+	ErrorCodePublishFailed         = "publish_failed"
+	ErrorCodeInternal              = "internal"
+)
+
+// APIError is a v2 structured error.
+type APIError struct {
+	// Code identifies the error; see the ErrorCode constants. Event limit
+	// rejections use their limit code (e.g. event_too_large).
+	Code string `json:"code"`
+
+	// Message is a human-readable description.
+	Message string `json:"message"`
+
+	// Field is the envelope field at fault, if any.
+	Field string `json:"field,omitempty"`
+
+	// Retryable reports whether sending the same request again may succeed.
+	Retryable bool `json:"retryable"`
+}
+
+// ErrorResponseV2 is the body of v2 error responses.
+type ErrorResponseV2 struct {
+	Error APIError `json:"error"`
+}
+
+// IngestResponseV2 is the response of POST /v2/events/ingest.
+type IngestResponseV2 struct {
+	EventID string `json:"event_id"`
+	Status  string `json:"status"`
+
+	// Deduplicated reports the event repeated an idempotency key seen
+	// within the dedup window and was not published again.
+	Deduplicated bool `json:"deduplicated"`
+}
+
+// EventResultV2 is the result of one event of a v2 batch.
+type EventResultV2 struct {
+	Index        int       `json:"index"`
+	EventID      string    `json:"event_id,omitempty"`
+	Status       string    `json:"status"`
+	Deduplicated bool      `json:"deduplicated"`
+	Error        *APIError `json:"error,omitempty"`
+}
+
+// BatchResponseV2 is the response of POST /v2/events/batch.
+type BatchResponseV2 struct {
+	AcceptedCount     int             `json:"accepted_count"`
+	RejectedCount     int             `json:"rejected_count"`
+	DeduplicatedCount int             `json:"deduplicated_count"`
+	Results           []EventResultV2 `json:"results"`
+}
+
+// registerV2 registers the v2 ingestion handlers. They take the same request
+// bodies as v1 (JSON or application/x-protobuf, and delimited batches) and
+// always respond with JSON.
+func registerV2(mux *http.ServeMux, service *EventService) error {
+	mux.HandleFunc("POST "+ingestPrefix(APIVersion2)+ingestEndpoint, service.handleIngestV2)
+	mux.HandleFunc("POST "+ingestPrefix(APIVersion2)+batchEndpoint, service.handleBatchV2)
+	return nil
+}
+
+// handleIngestV2 handles POST /v2/events/ingest.
+func (s *EventService) handleIngestV2(w http.ResponseWriter, r *http.Request) {
+	var req pb.IngestEventRequest
+	if err := decodeIngestRequest(r, &req); err != nil {
+		auditFromContext(r.Context()).fail(err.Error())
+		writeAPIError(w, http.StatusBadRequest, APIError{Code: ErrorCodeInvalidBody, Message: err.Error()})
+		return
+	}
+
+	resp, err := s.IngestEvent(r.Context(), &req)
+	if err != nil {
+		status, apiErr := apiErrorOf(err)
+		if apiErr.Code == ErrorCodePublishTimeout {
+			w.Header().Set("Retry-After", "1")
+		}
+		writeAPIError(w, status, apiErr)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, IngestResponseV2{
+		EventID:      resp.GetEventId(),
+		Status:       resp.GetStatus(),
+		Deduplicated: resp.GetStatus() == ingest.StatusDeduplicated,
+	})
+}
+
+// handleBatchV2 handles POST /v2/events/batch. Rejected events carry a
+// structured error; the batch itself is only rejected for an invalid body,
+// an empty batch or too many events.
+func (s *EventService) handleBatchV2(w http.ResponseWriter, r *http.Request) {
+	var req pb.IngestEventBatchRequest
+	if err := decodeIngestRequest(r, &req); err != nil {
+		auditFromContext(r.Context()).fail(err.Error())
+		writeAPIError(w, http.StatusBadRequest, APIError{Code: ErrorCodeInvalidBody, Message: err.Error()})
+		return
+	}
+
+	if len(req.GetEvents()) > maxBatchEventsV2 {
+		auditFromContext(r.Context()).fail(ingest.ErrBatchTooLarge.Error())
+		status, apiErr := apiErrorOf(ingest.ErrBatchTooLarge)
+		writeAPIError(w, status, apiErr)
+		return
+	}
+
+	resp, errs, err := s.ingestBatch(r.Context(), &req)
+	if err != nil {
+		status, apiErr := apiErrorOf(err)
+		writeAPIError(w, status, apiErr)
+		return
+	}
+
+	out := BatchResponseV2{
+		AcceptedCount: int(resp.GetAcceptedCount()),
+		RejectedCount: int(resp.GetRejectedCount()),
+		Results:       make([]EventResultV2, len(resp.GetResults())),
+	}
+	for i, result := range resp.GetResults() {
+		out.Results[i] = EventResultV2{
+			Index:        int(result.GetIndex()),
+			EventID:      result.GetEventId(),
+			Status:       result.GetStatus(),
+			Deduplicated: result.GetStatus() == ingest.StatusDeduplicated,
+		}
+		if out.Results[i].Deduplicated {
+			out.DeduplicatedCount++
+		}
+		if errs[i] != nil {
+			_, apiErr := apiErrorOf(errs[i])
+			out.Results[i].Error = &apiErr
+		}
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
+
+// decodeIngestRequest decodes a JSON or binary protobuf request body into msg.
+func decodeIngestRequest(r *http.Request, msg proto.Message) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("could not read request body: %w", err)
+	}
+
+	if mediaType(r.Header.Get("Content-Type")) == pb.ProtoContentType {
+		if err := proto.Unmarshal(body, msg); err != nil {
+			return fmt.Errorf("could not unmarshal binary request: %w", err)
+		}
+		return nil
+	}
+	if err := protojson.Unmarshal(body, msg); err != nil {
+		return fmt.Errorf("could not unmarshal request JSON: %w", err)
+	}
+	return nil
+}
+
+// fieldViolation is an event field violating the envelope's constraints.
+type fieldViolation struct {
+	field       string
+	description string
+}
+
+func (e *fieldViolation) Error() string {
+	return fmt.Sprintf("%s: %s: %s", ErrInvalidEvent, e.field, e.description)
+}
+
+func (e *fieldViolation) Unwrap() error {
+	return ErrInvalidEvent
+}
+
+// validateEventV2 applies the v2 envelope checks. v1 checks the field
+// constraints of the whole request before ingesting, so one invalid event
+// rejects its batch; v2 checks them per event and rejects only that event.
+// v2 also rejects client timestamps more than maxClockSkew in the future.
+func validateEventV2(event *pb.EventEnvelope, now time.Time) error {
+	if err := pb.ValidateMessage(event); err != nil {
+		violation := &fieldViolation{field: "unknown", description: err.Error()}
+		var valErr *protovalidate.ValidationError
+		if errors.As(err, &valErr) && len(valErr.Violations) > 0 {
+			first := valErr.Violations[0].Proto
+			var path []string
+			for _, element := range first.GetField().GetElements() {
+				path = append(path, element.GetFieldName())
+			}
+			if len(path) > 0 {
+				violation.field = strings.Join(path, ".")
+			}
+			violation.description = first.GetMessage()
+		}
+		return violation
+	}
+
+	if ts := time.UnixMilli(event.GetTimestampMs()); ts.After(now.Add(maxClockSkew)) {
+		return fmt.Errorf("%w: %s is more than %s ahead of the server", ErrTimestampInFuture, ts.UTC().Format(time.RFC3339), maxClockSkew)
+	}
+	return nil
+}
+
+// apiErrorOf maps an event service error to its HTTP status and structured
+// error.
+func apiErrorOf(err error) (int, APIError) {
+	apiErr := APIError{Message: err.Error()}
+	status := http.StatusBadRequest
+	var violation *fieldViolation
+
+	switch {
+	case errors.As(err, &violation):
+		apiErr.Code, apiErr.Field = ErrorCodeInvalidField, violation.field
+	case errors.Is(err, ingest.ErrEventRequired):
+		apiErr.Code, apiErr.Field = ErrorCodeEventRequired, "event"
+	case errors.Is(err, ingest.ErrAtLeastOneEvent):
+		apiErr.Code, apiErr.Field = ErrorCodeEventsRequired, "events"
+	case errors.Is(err, ingest.ErrBatchTooLarge):
+		apiErr.Code, apiErr.Field = ErrorCodeBatchTooLarge, "events"
+	case errors.Is(err, ingest.ErrAppIDRequired):
+		apiErr.Code, apiErr.Field = ErrorCodeAppIDRequired, "app_id"
+	case errors.Is(err, ingest.ErrEventTypeRequired):
+		apiErr.Code, apiErr.Field = ErrorCodePayloadRequired, "payload"
+	case errors.Is(err, ingest.ErrTimestampRequired):
+		apiErr.Code, apiErr.Field = ErrorCodeTimestampRequired, "timestamp_ms"
+	case errors.Is(err, ErrTimestampInFuture):
+		apiErr.Code, apiErr.Field = ErrorCodeTimestampInFuture, "timestamp_ms"
+	case errors.Is(err, events.ErrUnsupportedSchemaVersion):
+		apiErr.Code, apiErr.Field = ErrorCodeUnsupportedSchema, "schema_version"
+	case errors.Is(err, ErrEventTypeNotAllowed):
+		apiErr.Code, apiErr.Field = ErrorCodeEventTypeNotAllowed, "payload"
+		status = http.StatusForbidden
+	case errors.Is(err, ingest.ErrEventTooLarge), errors.Is(err, ingest.ErrTooManyProperties), errors.Is(err, ingest.ErrPropertyTooDeep):
+		// Limit error messages start with their limit code.
+		apiErr.Code, _, _ = strings.Cut(err.Error(), ":")
+		if errors.Is(err, ingest.ErrEventTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		} else {
+			apiErr.Field = "payload"
+		}
+	case errors.Is(err, ingest.ErrPublishTimeout):
+		apiErr.Code, apiErr.Retryable = ErrorCodePublishTimeout, true
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, ingest.ErrPublishFailed):
+		apiErr.Code, apiErr.Retryable = ErrorCodePublishFailed, true
+		status = http.StatusInternalServerError
+	default:
+		apiErr.Code = ErrorCodeInternal
+		status = http.StatusInternalServerError
+	}
+	return status, apiErr
+}
+
+// writeAPIError writes a v2 structured error response.
+func writeAPIError(w http.ResponseWriter, status int, apiErr APIError) {
+	writeJSON(w, status, ErrorResponseV2{Error: apiErr})
+}
+
+// writeJSON writes v as a JSON response with status.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
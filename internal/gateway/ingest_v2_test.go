@@ -0,0 +1,183 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/ingest"
+)
+
+// newVersionedMux returns a mux serving every API version with service.
+func newVersionedMux(t *testing.T, service *EventService) *http.ServeMux {
+	t.Helper()
+	mux := http.NewServeMux()
+	if err := registerVersions(mux, service); err != nil {
+		t.Fatalf("registerVersions() error = %v", err)
+	}
+	return mux
+}
+
+func postJSON(mux http.Handler, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func screenView(timestampMs int64, idempotencyKey string) string {
+	return fmt.Sprintf(`{"appId":"app","deviceId":"dev","timestampMs":"%d","idempotencyKey":%q,"screenView":{"screenName":"home"}}`,
+		timestampMs, idempotencyKey)
+}
+
+// TestIngestPathVersion verifies ingestion paths resolve to their version
+// and endpoint.
+func TestIngestPathVersion(t *testing.T) {
+	tests := []struct {
+		path     string
+		version  string
+		endpoint string
+		ok       bool
+	}{
+		{"/v1/events/ingest", APIVersion1, ingestEndpoint, true},
+		{"/v2/events/batch", APIVersion2, batchEndpoint, true},
+		{"/v3/events/ingest", "", "", false},
+		{"/v1/experiments/assign", "", "", false},
+	}
+	for _, tt := range tests {
+		version, endpoint, ok := ingestPathVersion(tt.path)
+		if version != tt.version || endpoint != tt.endpoint || ok != tt.ok {
+			t.Errorf("ingestPathVersion(%q) = %q, %q, %v, want %q, %q, %v",
+				tt.path, version, endpoint, ok, tt.version, tt.endpoint, tt.ok)
+		}
+	}
+	if !isBatchPath("/v2/events/batch") || isBatchPath("/v2/events/ingest") {
+		t.Error("isBatchPath() does not match the batch endpoints only")
+	}
+}
+
+// TestVersionedRouting verifies both versions are served, each tagged with
+// its version, and that v1 keeps its response format.
+func TestVersionedRouting(t *testing.T) {
+	service := NewEventServiceWithPublisher(newMockPublisher(), nil, 0, nil)
+	mux := newVersionedMux(t, service)
+	now := time.Now().UnixMilli()
+
+	v1 := postJSON(mux, "/v1/events/ingest", `{"event":`+screenView(now, "k1")+`}`)
+	if v1.Code != http.StatusOK || v1.Header().Get(APIVersionHeader) != APIVersion1 {
+		t.Fatalf("v1 status = %d, version = %q: %s", v1.Code, v1.Header().Get(APIVersionHeader), v1.Body)
+	}
+	if !strings.Contains(v1.Body.String(), `"eventId"`) {
+		t.Errorf("v1 body = %s, want the sebuf response", v1.Body)
+	}
+
+	v2 := postJSON(mux, "/v2/events/ingest", `{"event":`+screenView(now, "k2")+`}`)
+	if v2.Code != http.StatusOK || v2.Header().Get(APIVersionHeader) != APIVersion2 {
+		t.Fatalf("v2 status = %d, version = %q: %s", v2.Code, v2.Header().Get(APIVersionHeader), v2.Body)
+	}
+	var resp IngestResponseV2
+	if err := json.Unmarshal(v2.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.EventID == "" || resp.Status != ingest.StatusAccepted || resp.Deduplicated {
+		t.Errorf("v2 response = %+v, want accepted", resp)
+	}
+}
+
+// TestIngestV2_FutureTimestamp verifies v2 rejects timestamps too far ahead
+// with a structured error, while v1 keeps accepting them.
+func TestIngestV2_FutureTimestamp(t *testing.T) {
+	service := NewEventServiceWithPublisher(newMockPublisher(), nil, 0, nil)
+	mux := newVersionedMux(t, service)
+	future := time.Now().Add(2 * maxClockSkew).UnixMilli()
+
+	if rec := postJSON(mux, "/v1/events/ingest", `{"event":`+screenView(future, "k1")+`}`); rec.Code != http.StatusOK {
+		t.Errorf("v1 status = %d, want 200 (v1 behavior is frozen)", rec.Code)
+	}
+
+	rec := postJSON(mux, "/v2/events/ingest", `{"event":`+screenView(future, "k2")+`}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("v2 status = %d, want 400", rec.Code)
+	}
+	var resp ErrorResponseV2
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error.Code != ErrorCodeTimestampInFuture || resp.Error.Field != "timestamp_ms" || resp.Error.Retryable {
+		t.Errorf("v2 error = %+v, want timestamp_in_future on timestamp_ms", resp.Error)
+	}
+}
+
+// TestBatchV2_StructuredResults verifies per-event structured errors and
+// dedup status in v2 batch responses.
+func TestBatchV2_StructuredResults(t *testing.T) {
+	publisher := newMockPublisher()
+	publisher.failOnIndex[2] = errors.New("nats down")
+	dedup := newMockDedupChecker()
+	dedup.markAsDuplicate("dup")
+	service := NewEventServiceWithPublisher(publisher, dedup, 0, nil)
+	mux := newVersionedMux(t, service)
+	now := time.Now().UnixMilli()
+
+	body := `{"events":[` + strings.Join([]string{
+		screenView(now, "a"),
+		screenView(now, "dup"),
+		`{"appId":"app","deviceId":"dev","screenView":{"screenName":"home"}}`,
+		screenView(now, "b"),
+		screenView(now, "c"),
+		`{"appId":"app","timestampMs":"1","screenView":{"screenName":"home"}}`,
+	}, ",") + `]}`
+	rec := postJSON(mux, "/v2/events/batch", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+
+	var resp BatchResponseV2
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.AcceptedCount != 3 || resp.RejectedCount != 3 || resp.DeduplicatedCount != 1 {
+		t.Errorf("counts = %d accepted, %d rejected, %d deduplicated, want 3, 3, 1",
+			resp.AcceptedCount, resp.RejectedCount, resp.DeduplicatedCount)
+	}
+
+	wantCodes := []string{"", "", ErrorCodeTimestampRequired, "", ErrorCodePublishFailed, ErrorCodeInvalidField}
+	for i, result := range resp.Results {
+		code := ""
+		if result.Error != nil {
+			code = result.Error.Code
+		}
+		if code != wantCodes[i] {
+			t.Errorf("result %d error code = %q, want %q", i, code, wantCodes[i])
+		}
+	}
+	if !resp.Results[1].Deduplicated || resp.Results[1].Status != ingest.StatusDeduplicated {
+		t.Errorf("result 1 = %+v, want deduplicated", resp.Results[1])
+	}
+	if resp.Results[4].Error == nil || !resp.Results[4].Error.Retryable {
+		t.Errorf("result 4 error = %+v, want retryable", resp.Results[4].Error)
+	}
+	if resp.Results[5].Error == nil || resp.Results[5].Error.Field != "device_id" {
+		t.Errorf("result 5 error = %+v, want a device_id violation", resp.Results[5].Error)
+	}
+}
+
+// TestBatchV2_InvalidBody verifies malformed bodies get a structured error.
+func TestBatchV2_InvalidBody(t *testing.T) {
+	mux := newVersionedMux(t, NewEventServiceWithPublisher(newMockPublisher(), nil, 0, nil))
+
+	rec := postJSON(mux, "/v2/events/batch", `{"events":`)
+	var resp ErrorResponseV2
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusBadRequest || resp.Error.Code != ErrorCodeInvalidBody {
+		t.Errorf("status = %d, error = %+v, want 400 invalid_body", rec.Code, resp.Error)
+	}
+}
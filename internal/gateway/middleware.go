@@ -14,6 +14,7 @@ import (
 	"golang.org/x/time/rate"
 
 	"github.com/SebastienMelki/causality/internal/auth"
+	"github.com/SebastienMelki/causality/internal/observability"
 )
 
 // ContextKey is a type for context keys.
@@ -35,7 +36,9 @@ func Chain(h http.Handler, middlewares ...Middleware) http.Handler {
 	return h
 }
 
-// RequestID adds a unique request ID to each request.
+// RequestID adds a unique request ID to each request. The ID and any W3C
+// trace context on the request are attached to the request context for
+// scoped logging (see observability.Logger).
 func RequestID(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		requestID := r.Header.Get("X-Request-ID")
@@ -48,6 +51,8 @@ func RequestID(next http.Handler) http.Handler {
 
 		// Add to context
 		ctx := context.WithValue(r.Context(), RequestIDKey, requestID)
+		ctx = observability.ExtractTraceContext(ctx, r.Header)
+		ctx = observability.WithLogAttrs(ctx, observability.LogKeyRequestID, requestID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -76,7 +81,6 @@ func Logging(logger *slog.Logger) Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
-			requestID := GetRequestID(r.Context())
 
 			// Wrap response writer to capture status
 			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
@@ -85,8 +89,7 @@ func Logging(logger *slog.Logger) Middleware {
 
 			duration := time.Since(start)
 
-			logger.Info("http request",
-				"request_id", requestID,
+			observability.Logger(r.Context(), logger).Info("http request",
 				"method", r.Method,
 				"path", r.URL.Path,
 				"status", wrapped.statusCode,
@@ -104,11 +107,9 @@ func Recovery(logger *slog.Logger) Middleware {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if err := recover(); err != nil {
-					requestID := GetRequestID(r.Context())
 					stack := debug.Stack()
 
-					logger.Error("panic recovered",
-						"request_id", requestID,
+					observability.Logger(r.Context(), logger).Error("panic recovered",
 						"error", err,
 						"stack", string(stack),
 					)
@@ -189,9 +190,40 @@ func RateLimit(cfg RateLimitConfig) Middleware {
 	}
 }
 
+// KeyLimiter decides whether a request for a rate limit key is allowed.
+// Implementations must be safe for concurrent use.
+type KeyLimiter interface {
+	// Allow reports whether one more request for key is allowed now.
+	Allow(ctx context.Context, key string) bool
+}
+
+// LocalKeyLimiter is an in-process KeyLimiter with one token bucket per key.
+// With several gateway replicas, each replica enforces the limit separately.
+type LocalKeyLimiter struct {
+	rps      float64
+	burst    int
+	limiters sync.Map // map[string]*rate.Limiter
+}
+
+// NewLocalKeyLimiter creates an in-process limiter allowing rps requests per
+// second per key with the given burst.
+func NewLocalKeyLimiter(rps float64, burst int) *LocalKeyLimiter {
+	return &LocalKeyLimiter{rps: rps, burst: burst}
+}
+
+// Allow reports whether one more request for key is allowed now.
+func (l *LocalKeyLimiter) Allow(_ context.Context, key string) bool {
+	val, ok := l.limiters.Load(key)
+	if !ok {
+		val, _ = l.limiters.LoadOrStore(key, rate.NewLimiter(rate.Limit(l.rps), l.burst))
+	}
+	return val.(*rate.Limiter).Allow()
+}
+
 // PerKeyRateLimit implements per-API-key rate limiting using token bucket
 // algorithm. It reads the authenticated app_id from the request context
-// (set by the auth middleware) and maintains a separate rate limiter per key.
+// (set by the auth middleware) and maintains a separate rate limiter per key
+// in process.
 //
 // Requests without an app_id in context (e.g., unauthenticated or health
 // endpoints) pass through without rate limiting.
@@ -202,8 +234,13 @@ func PerKeyRateLimit(cfg RateLimitConfig) Middleware {
 		}
 	}
 
-	var limiters sync.Map // map[string]*rate.Limiter
+	return PerKeyRateLimitWith(NewLocalKeyLimiter(cfg.PerKeyRPS, cfg.PerKeyBurst))
+}
 
+// PerKeyRateLimitWith implements per-API-key rate limiting with the given
+// limiter, e.g. a RedisKeyLimiter shared by all gateway replicas. Requests
+// without an app_id in context pass through.
+func PerKeyRateLimitWith(limiter KeyLimiter) Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			appID := auth.GetAppID(r.Context())
@@ -215,13 +252,7 @@ func PerKeyRateLimit(cfg RateLimitConfig) Middleware {
 				return
 			}
 
-			// Load or create per-key limiter
-			val, _ := limiters.LoadOrStore(appID,
-				rate.NewLimiter(rate.Limit(cfg.PerKeyRPS), cfg.PerKeyBurst),
-			)
-			limiter := val.(*rate.Limiter)
-
-			if !limiter.Allow() {
+			if !limiter.Allow(r.Context(), appID) {
 				http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
 				return
 			}
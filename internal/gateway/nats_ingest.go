@@ -0,0 +1,171 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	natsgo "github.com/nats-io/nats.go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/SebastienMelki/causality/internal/ingest"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// NATS ingestion message headers.
+const (
+	// NATSIngestContentTypeHeader selects the encoding of an ingestion
+	// message: application/json (the default), application/x-protobuf or
+	// ingest.DelimitedBatchContentType. Replies use JSON unless it is protobuf.
+	NATSIngestContentTypeHeader = "Content-Type"
+
+	// NATSIngestErrorHeader carries the error of a message rejected as a
+	// whole, in a reply without a body.
+	NATSIngestErrorHeader = "Causality-Error"
+)
+
+// ingestConn is the subset of *natsgo.Conn used by NATSIngest.
+type ingestConn interface {
+	QueueSubscribe(subject, queue string, cb natsgo.MsgHandler) (*natsgo.Subscription, error)
+	PublishMsg(msg *natsgo.Msg) error
+}
+
+// NATSIngest ingests events that edge collectors publish over NATS core
+// instead of HTTP. Each message on the ingest subject is an
+// IngestEventBatchRequest and goes through the same validation, event
+// limits, enrichment and dedup as the batch endpoint before its events are
+// published to the main stream. Messages sent as requests get the
+// IngestEventBatchResponse as reply, so collectors can retry rejected
+// events; published messages are fire and forget.
+//
+// Messages carry no API key: access is controlled by the NATS permissions
+// on the ingest subject, and per-key rate limits and event scopes do not
+// apply.
+type NATSIngest struct {
+	conn    ingestConn
+	service *EventService
+	probe   *PublishProbe
+	config  NATSIngestConfig
+	logger  *slog.Logger
+
+	subs []*natsgo.Subscription
+}
+
+// NewNATSIngest creates a NATS ingestion listener on conn. While probe
+// reports stalled publishes, messages are rejected with ErrPublishStalled.
+func NewNATSIngest(conn *natsgo.Conn, service *EventService, probe *PublishProbe, cfg NATSIngestConfig, logger *slog.Logger) *NATSIngest {
+	return newNATSIngest(conn, service, probe, cfg, logger)
+}
+
+func newNATSIngest(conn ingestConn, service *EventService, probe *PublishProbe, cfg NATSIngestConfig, logger *slog.Logger) *NATSIngest {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.Subject == "" {
+		cfg.Subject = "ingest.raw.>"
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+
+	return &NATSIngest{
+		conn:    conn,
+		service: service,
+		probe:   probe,
+		config:  cfg,
+		logger:  logger.With("component", "nats-ingest"),
+	}
+}
+
+// Start subscribes Workers times to the ingest subject in the queue group,
+// so messages are processed concurrently and each is ingested by one
+// gateway replica.
+func (n *NATSIngest) Start() error {
+	for range n.config.Workers {
+		sub, err := n.conn.QueueSubscribe(n.config.Subject, n.config.QueueGroup, n.handle)
+		if err != nil {
+			n.Stop()
+			return fmt.Errorf("failed to subscribe to %s: %w", n.config.Subject, err)
+		}
+		n.subs = append(n.subs, sub)
+	}
+
+	n.logger.Info("NATS ingestion started",
+		"subject", n.config.Subject,
+		"queue_group", n.config.QueueGroup,
+		"workers", n.config.Workers,
+	)
+	return nil
+}
+
+// Stop drains the subscriptions: messages already received are still
+// ingested.
+func (n *NATSIngest) Stop() {
+	for _, sub := range n.subs {
+		if err := sub.Drain(); err != nil {
+			n.logger.Warn("failed to drain ingest subscription", "error", err)
+		}
+	}
+	n.subs = nil
+}
+
+// handle ingests a message and replies to it when it is a request.
+func (n *NATSIngest) handle(msg *natsgo.Msg) {
+	reply := n.ingest(context.Background(), msg)
+	if msg.Reply == "" {
+		return
+	}
+
+	reply.Subject = msg.Reply
+	if err := n.conn.PublishMsg(reply); err != nil {
+		n.logger.Warn("failed to reply to ingest message",
+			"subject", msg.Subject,
+			"error", err,
+		)
+	}
+}
+
+// ingest decodes and ingests a message, returning its reply.
+func (n *NATSIngest) ingest(ctx context.Context, msg *natsgo.Msg) *natsgo.Msg {
+	contentType := mediaType(msg.Header.Get(NATSIngestContentTypeHeader))
+
+	if err := n.probe.Err(); err != nil {
+		return n.reject(msg, err)
+	}
+
+	req, err := ingest.DecodeBatch(contentType, msg.Data)
+	if err != nil {
+		return n.reject(msg, err)
+	}
+
+	resp, _, err := n.service.ingestBatch(ctx, req)
+	if err != nil {
+		return n.reject(msg, err)
+	}
+
+	reply := natsgo.NewMsg("")
+	if contentType == pb.ProtoContentType {
+		reply.Data, err = proto.Marshal(resp)
+		reply.Header.Set(NATSIngestContentTypeHeader, pb.ProtoContentType)
+	} else {
+		reply.Data, err = protojson.Marshal(resp)
+		reply.Header.Set(NATSIngestContentTypeHeader, "application/json")
+	}
+	if err != nil {
+		return n.reject(msg, fmt.Errorf("failed to encode response: %w", err))
+	}
+	return reply
+}
+
+// reject logs a message rejected as a whole and returns its error reply.
+func (n *NATSIngest) reject(msg *natsgo.Msg, err error) *natsgo.Msg {
+	n.logger.Warn("ingest message rejected",
+		"subject", msg.Subject,
+		"error", err,
+	)
+
+	reply := natsgo.NewMsg("")
+	reply.Header.Set(NATSIngestErrorHeader, err.Error())
+	return reply
+}
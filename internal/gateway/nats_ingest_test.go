@@ -0,0 +1,219 @@
+package gateway
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	natsgo "github.com/nats-io/nats.go"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/SebastienMelki/causality/internal/ingest"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// fakeIngestConn records the replies published by NATSIngest.
+type fakeIngestConn struct {
+	replies []*natsgo.Msg
+}
+
+func (f *fakeIngestConn) QueueSubscribe(_, _ string, _ natsgo.MsgHandler) (*natsgo.Subscription, error) {
+	return &natsgo.Subscription{}, nil
+}
+
+func (f *fakeIngestConn) PublishMsg(msg *natsgo.Msg) error {
+	f.replies = append(f.replies, msg)
+	return nil
+}
+
+func ingestTestEvent(appID, idempotencyKey string) *pb.EventEnvelope {
+	return &pb.EventEnvelope{
+		AppId:          appID,
+		TimestampMs:    time.Now().UnixMilli(),
+		IdempotencyKey: idempotencyKey,
+		Payload: &pb.EventEnvelope_ScreenView{
+			ScreenView: &pb.ScreenView{ScreenName: "home"},
+		},
+	}
+}
+
+func newTestNATSIngest(pub *mockPublisher, probe *PublishProbe) (*NATSIngest, *fakeIngestConn) {
+	conn := &fakeIngestConn{}
+	svc := NewEventServiceWithPublisher(pub, newMockDedupChecker(), 0, nil)
+	return newNATSIngest(conn, svc, probe, NATSIngestConfig{}, nil), conn
+}
+
+func ingestRequestMsg(t *testing.T, contentType string, data []byte) *natsgo.Msg {
+	t.Helper()
+	msg := natsgo.NewMsg("ingest.raw.edge-1")
+	msg.Reply = "_INBOX.reply"
+	msg.Data = data
+	if contentType != "" {
+		msg.Header.Set(NATSIngestContentTypeHeader, contentType)
+	}
+	return msg
+}
+
+func TestNATSIngest_JSON(t *testing.T) {
+	pub := newMockPublisher()
+	natsIngest, conn := newTestNATSIngest(pub, nil)
+
+	data, err := protojson.Marshal(&pb.IngestEventBatchRequest{Events: []*pb.EventEnvelope{
+		ingestTestEvent("app-1", "key-1"),
+		ingestTestEvent("", "key-2"),
+		ingestTestEvent("app-1", "key-1"),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	natsIngest.handle(ingestRequestMsg(t, "", data))
+
+	if len(pub.publishedEvents) != 1 {
+		t.Fatalf("published %d events, want 1", len(pub.publishedEvents))
+	}
+	if pub.publishedEvents[0].GetId() == "" {
+		t.Error("published event was not enriched with an ID")
+	}
+	if len(conn.replies) != 1 {
+		t.Fatalf("replies = %d, want 1", len(conn.replies))
+	}
+	reply := conn.replies[0]
+	if reply.Subject != "_INBOX.reply" {
+		t.Errorf("reply subject = %q", reply.Subject)
+	}
+
+	resp := &pb.IngestEventBatchResponse{}
+	if err := protojson.Unmarshal(reply.Data, resp); err != nil {
+		t.Fatalf("decode reply: %v", err)
+	}
+	if resp.GetAcceptedCount() != 2 || resp.GetRejectedCount() != 1 {
+		t.Errorf("accepted = %d, rejected = %d, want 2 and 1", resp.GetAcceptedCount(), resp.GetRejectedCount())
+	}
+	statuses := []string{ingest.StatusAccepted, ingest.StatusRejected, ingest.StatusDeduplicated}
+	for i, result := range resp.GetResults() {
+		if result.GetStatus() != statuses[i] {
+			t.Errorf("result %d status = %q, want %q", i, result.GetStatus(), statuses[i])
+		}
+	}
+}
+
+func TestNATSIngest_Encodings(t *testing.T) {
+	var delimited bytes.Buffer
+	for _, key := range []string{"key-1", "key-2"} {
+		if _, err := protodelim.MarshalTo(&delimited, ingestTestEvent("app-1", key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	binary, err := proto.Marshal(&pb.IngestEventBatchRequest{Events: []*pb.EventEnvelope{
+		ingestTestEvent("app-1", "key-1"),
+		ingestTestEvent("app-1", "key-2"),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		data        []byte
+		replyType   string
+	}{
+		{"protobuf", pb.ProtoContentType, binary, pb.ProtoContentType},
+		{"delimited", ingest.DelimitedBatchContentType, delimited.Bytes(), "application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub := newMockPublisher()
+			natsIngest, conn := newTestNATSIngest(pub, nil)
+			natsIngest.handle(ingestRequestMsg(t, tt.contentType, tt.data))
+
+			if len(pub.publishedEvents) != 2 {
+				t.Fatalf("published %d events, want 2", len(pub.publishedEvents))
+			}
+			reply := conn.replies[0]
+			if got := reply.Header.Get(NATSIngestContentTypeHeader); got != tt.replyType {
+				t.Errorf("reply content type = %q, want %q", got, tt.replyType)
+			}
+
+			resp := &pb.IngestEventBatchResponse{}
+			if tt.replyType == pb.ProtoContentType {
+				err = proto.Unmarshal(reply.Data, resp)
+			} else {
+				err = protojson.Unmarshal(reply.Data, resp)
+			}
+			if err != nil {
+				t.Fatalf("decode reply: %v", err)
+			}
+			if resp.GetAcceptedCount() != 2 {
+				t.Errorf("accepted = %d, want 2", resp.GetAcceptedCount())
+			}
+		})
+	}
+}
+
+func TestNATSIngest_Rejected(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	stalled := newPublishProbe(&fakeProbePublisher{}, PublishProbeConfig{Interval: 5 * time.Second, StallThreshold: 15 * time.Second}, nil)
+	stalled.now = func() time.Time { return now }
+	stalled.mu.Lock()
+	stalled.lastAck, stalled.started = now.Add(-time.Minute), true
+	stalled.mu.Unlock()
+
+	valid, err := protojson.Marshal(&pb.IngestEventBatchRequest{Events: []*pb.EventEnvelope{ingestTestEvent("app-1", "key-1")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		probe       *PublishProbe
+		contentType string
+		data        []byte
+		wantErr     string
+	}{
+		{"invalid body", nil, "", []byte("{not json"), ingest.ErrInvalidBatchBody.Error()},
+		{"unsupported encoding", nil, "text/csv", valid, ingest.ErrUnsupportedBatchEncoding.Error()},
+		{"empty batch", nil, "", []byte("{}"), ingest.ErrAtLeastOneEvent.Error()},
+		{"publish stalled", stalled, "", valid, ErrPublishStalled.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub := newMockPublisher()
+			natsIngest, conn := newTestNATSIngest(pub, tt.probe)
+			natsIngest.handle(ingestRequestMsg(t, tt.contentType, tt.data))
+
+			if len(pub.publishedEvents) != 0 {
+				t.Errorf("published %d events, want none", len(pub.publishedEvents))
+			}
+			if len(conn.replies) != 1 {
+				t.Fatalf("replies = %d, want 1", len(conn.replies))
+			}
+			if got := conn.replies[0].Header.Get(NATSIngestErrorHeader); !strings.HasPrefix(got, tt.wantErr) {
+				t.Errorf("error header = %q, want prefix %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNATSIngest_NoReply(t *testing.T) {
+	pub := newMockPublisher()
+	natsIngest, conn := newTestNATSIngest(pub, nil)
+
+	data, err := protojson.Marshal(&pb.IngestEventBatchRequest{Events: []*pb.EventEnvelope{ingestTestEvent("app-1", "key-1")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := ingestRequestMsg(t, "", data)
+	msg.Reply = ""
+	natsIngest.handle(msg)
+
+	if len(pub.publishedEvents) != 1 {
+		t.Errorf("published %d events, want 1", len(pub.publishedEvents))
+	}
+	if len(conn.replies) != 0 {
+		t.Errorf("replies = %d, want none for a published message", len(conn.replies))
+	}
+}
@@ -0,0 +1,203 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// probePublisher is the subset of jetstream.JetStream used by PublishProbe.
+type probePublisher interface {
+	Publish(ctx context.Context, subject string, data []byte, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+}
+
+// PublishProbe publishes a synthetic message to JetStream every interval and
+// tracks when a publish was last acked. Once acks stall beyond the stall
+// threshold, /ready fails and ingestion is rejected with 503, so load
+// balancers and SDKs route around the gateway instead of events being
+// accepted while JetStream cannot store them.
+//
+// A nil *PublishProbe is always healthy.
+type PublishProbe struct {
+	publisher probePublisher
+	config    PublishProbeConfig
+	logger    *slog.Logger
+	now       func() time.Time
+
+	mu      sync.Mutex
+	lastAck time.Time
+	lastErr error
+	stalled bool
+	started bool
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// NewPublishProbe creates a publish probe publishing to js.
+func NewPublishProbe(js jetstream.JetStream, cfg PublishProbeConfig, logger *slog.Logger) *PublishProbe {
+	return newPublishProbe(js, cfg, logger)
+}
+
+func newPublishProbe(publisher probePublisher, cfg PublishProbeConfig, logger *slog.Logger) *PublishProbe {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 2 * time.Second
+	}
+	if cfg.StallThreshold <= 0 {
+		cfg.StallThreshold = 15 * time.Second
+	}
+
+	return &PublishProbe{
+		publisher: publisher,
+		config:    cfg,
+		logger:    logger.With("component", "publish-probe"),
+		now:       time.Now,
+		stopCh:    make(chan struct{}),
+		doneCh:    make(chan struct{}),
+	}
+}
+
+// Start probes immediately and then every interval until Stop. The stall
+// threshold is measured from Start, so a slow first ack does not fail
+// readiness.
+func (p *PublishProbe) Start() {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	p.lastAck = p.now()
+	p.started = true
+	p.mu.Unlock()
+
+	go p.run()
+
+	p.logger.Info("publish probe started",
+		"subject", p.config.Subject,
+		"interval", p.config.Interval,
+		"stall_threshold", p.config.StallThreshold,
+	)
+}
+
+// Stop stops probing. It does nothing if the probe was never started.
+func (p *PublishProbe) Stop() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	started := p.started
+	p.mu.Unlock()
+	if !started {
+		return
+	}
+	close(p.stopCh)
+	<-p.doneCh
+}
+
+// run probes every interval.
+func (p *PublishProbe) run() {
+	defer close(p.doneCh)
+
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		p.probe()
+		select {
+		case <-p.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe publishes one probe message and records the outcome.
+func (p *PublishProbe) probe() {
+	ctx, cancel := context.WithTimeout(context.Background(), p.config.Timeout)
+	defer cancel()
+
+	sent := p.now()
+	_, err := p.publisher.Publish(ctx, p.config.Subject, []byte(sent.UTC().Format(time.RFC3339Nano)))
+	p.record(err)
+}
+
+// record updates the probe state with the outcome of a probe, logging
+// transitions into and out of a stall.
+func (p *PublishProbe) record(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	if err == nil {
+		p.lastAck = now
+		p.lastErr = nil
+		if p.stalled {
+			p.stalled = false
+			p.logger.Info("JetStream publish acks recovered")
+		}
+		return
+	}
+
+	p.lastErr = err
+	p.logger.Warn("publish probe failed", "error", err)
+	if !p.stalled && now.Sub(p.lastAck) > p.config.StallThreshold {
+		p.stalled = true
+		p.logger.Error("JetStream publish acks stalled, rejecting ingestion",
+			"since", p.lastAck,
+			"error", err,
+		)
+	}
+}
+
+// Err returns ErrPublishStalled, wrapped with the time since the last ack
+// and the last probe error, once no probe has been acked within the stall
+// threshold, and nil otherwise, including before Start.
+func (p *PublishProbe) Err() error {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	since := p.now().Sub(p.lastAck)
+	if !p.started || since <= p.config.StallThreshold {
+		return nil
+	}
+	if p.lastErr == nil {
+		return fmt.Errorf("%w: no ack for %s", ErrPublishStalled, since.Round(time.Second))
+	}
+	return fmt.Errorf("%w: no ack for %s: %w", ErrPublishStalled, since.Round(time.Second), p.lastErr)
+}
+
+// PublishHealth rejects ingestion requests with 503 Service Unavailable
+// while the probe reports stalled JetStream acks. Retry-After is set to the
+// probe interval, after which the next probe may have succeeded.
+func PublishHealth(probe *PublishProbe) Middleware {
+	return func(next http.Handler) http.Handler {
+		if probe == nil {
+			return next
+		}
+		retryAfter := strconv.Itoa(max(1, int(probe.config.Interval.Round(time.Second)/time.Second)))
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isIngestPath(r.URL.Path) && probe.Err() != nil {
+				w.Header().Set("Retry-After", retryAfter)
+				http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
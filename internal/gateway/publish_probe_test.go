@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// fakeProbePublisher fails publishes while err is set.
+type fakeProbePublisher struct {
+	err      error
+	subjects []string
+}
+
+func (f *fakeProbePublisher) Publish(_ context.Context, subject string, _ []byte, _ ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	f.subjects = append(f.subjects, subject)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &jetstream.PubAck{Stream: "CAUSALITY_PROBE"}, nil
+}
+
+func TestPublishProbe_StallAndRecovery(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	publisher := &fakeProbePublisher{}
+	probe := newPublishProbe(publisher, PublishProbeConfig{
+		Subject:        "probe.gateway",
+		Interval:       time.Second,
+		StallThreshold: 10 * time.Second,
+	}, nil)
+	probe.now = func() time.Time { return now }
+
+	if err := probe.Err(); err != nil {
+		t.Fatalf("Err before Start = %v, want nil", err)
+	}
+	probe.mu.Lock()
+	probe.lastAck, probe.started = now, true
+	probe.mu.Unlock()
+
+	probe.probe()
+	if len(publisher.subjects) != 1 || publisher.subjects[0] != "probe.gateway" {
+		t.Errorf("published subjects = %v, want [probe.gateway]", publisher.subjects)
+	}
+
+	// Failures within the stall threshold keep the gateway ready.
+	publisher.err = context.DeadlineExceeded
+	now = now.Add(5 * time.Second)
+	probe.probe()
+	if err := probe.Err(); err != nil {
+		t.Errorf("Err within threshold = %v, want nil", err)
+	}
+
+	now = now.Add(6 * time.Second)
+	probe.probe()
+	err := probe.Err()
+	if !errors.Is(err, ErrPublishStalled) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Err after threshold = %v, want ErrPublishStalled wrapping the probe error", err)
+	}
+
+	publisher.err = nil
+	probe.probe()
+	if err := probe.Err(); err != nil {
+		t.Errorf("Err after recovery = %v, want nil", err)
+	}
+}
+
+func TestPublishHealth(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	probe := newPublishProbe(&fakeProbePublisher{}, PublishProbeConfig{Interval: 5 * time.Second, StallThreshold: 15 * time.Second}, nil)
+	probe.now = func() time.Time { return now }
+	probe.mu.Lock()
+	probe.lastAck, probe.started = now.Add(-time.Minute), true
+	probe.mu.Unlock()
+
+	handler := PublishHealth(probe)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/v1/events/batch", http.StatusServiceUnavailable},
+		{"/v2/events/ingest", http.StatusServiceUnavailable},
+		{"/health", http.StatusOK},
+		{"/api/admin/keys", http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.path, rec.Code, tt.want)
+		}
+		if tt.want == http.StatusServiceUnavailable && rec.Header().Get("Retry-After") != "5" {
+			t.Errorf("%s: Retry-After = %q, want 5", tt.path, rec.Header().Get("Retry-After"))
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/auth"
+	"github.com/SebastienMelki/causality/internal/quality"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// QualityRecorder receives the ingestion outcome of every event for the
+// per-app data-quality metrics. Implementations must be safe for concurrent
+// use and must not block.
+type QualityRecorder interface {
+	// Observe counts the ingestion of one event.
+	Observe(obs quality.Observation)
+}
+
+// observeQuality reports an event's outcome to the data-quality recorder,
+// with the rejection code of err for rejected events. Events are counted
+// under the authenticated app, so unauthenticated app IDs cannot add series;
+// without auth the event's own app ID is used. Publish failures are not
+// reported: they say nothing about the quality of the client's data.
+func (s *EventService) observeQuality(ctx context.Context, event *pb.EventEnvelope, outcome quality.Outcome, err error) {
+	if s.quality == nil {
+		return
+	}
+	appID := auth.GetAppID(ctx)
+	if appID == "" {
+		appID = event.GetAppId()
+	}
+	if appID == "" {
+		return
+	}
+
+	obs := quality.Observation{
+		AppID:      appID,
+		Outcome:    outcome,
+		ReceivedAt: time.Now(),
+		SDK:        sdkOf(ctx, event),
+	}
+	if err != nil {
+		_, apiErr := apiErrorOf(err)
+		obs.Reason = apiErr.Code
+	}
+	if ms := event.GetTimestampMs(); ms > 0 {
+		obs.EventTime = time.UnixMilli(ms)
+	}
+	if ms := event.GetDeviceContext().GetClockSkewMs(); ms != 0 {
+		obs.ReportedSkew = time.Duration(ms) * time.Millisecond
+	}
+	if sample, ok := clockSampleFromContext(ctx); ok {
+		obs.ReceivedAt, obs.SentAt = sample.received, sample.sent
+	}
+	s.quality.Observe(obs)
+}
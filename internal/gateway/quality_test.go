@@ -0,0 +1,90 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/auth"
+	"github.com/SebastienMelki/causality/internal/quality"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// fakeQualityRecorder records observations.
+type fakeQualityRecorder struct {
+	mu  sync.Mutex
+	obs []quality.Observation
+}
+
+func (f *fakeQualityRecorder) Observe(obs quality.Observation) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.obs = append(f.obs, obs)
+}
+
+func TestIngestEventBatch_ObservesQuality(t *testing.T) {
+	pub := newMockPublisher()
+	dedup := newMockDedupChecker()
+	dedup.markAsDuplicate("dup")
+	svc := NewEventServiceWithPublisher(pub, dedup, 0, nil)
+	recorder := &fakeQualityRecorder{}
+	svc.quality = recorder
+
+	received := time.Now()
+	sent := received.Add(-2 * time.Minute)
+	ctx := context.WithValue(context.Background(), clockSampleKey, clockSample{received: received, sent: sent})
+	screen := &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}}
+
+	req := &pb.IngestEventBatchRequest{
+		Events: []*pb.EventEnvelope{
+			{AppId: "app", TimestampMs: sent.UnixMilli(), Payload: screen},
+			{AppId: "app", IdempotencyKey: "dup", TimestampMs: sent.UnixMilli(), Payload: screen},
+			{AppId: "app", Payload: screen},
+		},
+	}
+	if _, err := svc.IngestEventBatch(ctx, req); err != nil {
+		t.Fatalf("IngestEventBatch() error = %v", err)
+	}
+
+	want := []struct {
+		outcome quality.Outcome
+		reason  string
+	}{
+		{quality.OutcomeAccepted, ""},
+		{quality.OutcomeDeduplicated, ""},
+		{quality.OutcomeRejected, ErrorCodeTimestampRequired},
+	}
+	if len(recorder.obs) != len(want) {
+		t.Fatalf("observations = %+v, want %d", recorder.obs, len(want))
+	}
+	for i, w := range want {
+		obs := recorder.obs[i]
+		if obs.AppID != "app" || obs.Outcome != w.outcome || obs.Reason != w.reason {
+			t.Errorf("observation %d = %+v, want %s %q", i, obs, w.outcome, w.reason)
+		}
+		if !obs.ReceivedAt.Equal(received) || !obs.SentAt.Equal(sent) {
+			t.Errorf("observation %d times = %v, %v, want the clock sample", i, obs.ReceivedAt, obs.SentAt)
+		}
+	}
+	if !recorder.obs[0].EventTime.Equal(time.UnixMilli(sent.UnixMilli())) {
+		t.Errorf("event time = %v, want %v", recorder.obs[0].EventTime, sent)
+	}
+}
+
+func TestIngestEvent_ObservesQualityUnderAuthenticatedApp(t *testing.T) {
+	pub := newMockPublisher()
+	svc := NewEventServiceWithPublisher(pub, nil, 0, nil)
+	recorder := &fakeQualityRecorder{}
+	svc.quality = recorder
+
+	_, err := svc.IngestEvent(context.WithValue(context.Background(), auth.AppIDContextKey, "key-app"), &pb.IngestEventRequest{
+		Event: &pb.EventEnvelope{AppId: "other-app", TimestampMs: time.Now().UnixMilli()},
+	})
+	if err == nil {
+		t.Fatal("IngestEvent() error = nil, want missing payload")
+	}
+	if len(recorder.obs) != 1 || recorder.obs[0].AppID != "key-app" || recorder.obs[0].Reason != ErrorCodePayloadRequired {
+		t.Errorf("observations = %+v, want one payload_required rejection of key-app", recorder.obs)
+	}
+}
@@ -0,0 +1,147 @@
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript atomically refills and takes one token from the bucket
+// stored in hash KEYS[1]. ARGV: rate (tokens/s), burst, now (Unix ms).
+// Returns 1 if a token was taken, 0 otherwise. Time is passed by the caller
+// and never moves backwards, so replicas with slightly skewed clocks only
+// delay refills.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+  tokens = burst
+  ts = now
+end
+if now > ts then
+  tokens = math.min(burst, tokens + (now - ts) * rate / 1000)
+  ts = now
+end
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(ts))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return allowed
+`)
+
+// fallbackLogInterval limits how often Redis failures are logged.
+const fallbackLogInterval = 30 * time.Second
+
+// RedisKeyLimiter is a KeyLimiter whose token buckets live in Redis, so the
+// per-key limit is shared by all gateway replicas. When Redis is unreachable
+// or errors, it falls back to an in-process limiter so ingestion keeps
+// working (with per-replica limits) until Redis recovers.
+type RedisKeyLimiter struct {
+	client   *redis.Client
+	timeout  time.Duration
+	fallback KeyLimiter
+	rps      float64
+	burst    int
+	prefix   string
+	now      func() time.Time
+	logger   *slog.Logger
+
+	lastLogged atomic.Int64 // Unix nanoseconds of the last fallback log
+}
+
+// NewRedisKeyLimiter creates a Redis-backed limiter from the rate limit
+// configuration. Connections are opened lazily, so an unavailable Redis at
+// startup only triggers the fallback. PerKeyRPS must be positive: the
+// bucket's expiry is the time it takes to refill.
+func NewRedisKeyLimiter(cfg RateLimitConfig, logger *slog.Logger) (*RedisKeyLimiter, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.PerKeyRPS <= 0 {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_PER_KEY_RPS %v: must be positive with Redis rate limiting", cfg.PerKeyRPS)
+	}
+
+	timeout := cfg.RedisTimeout
+	if timeout <= 0 {
+		timeout = 50 * time.Millisecond
+	}
+
+	opts := &redis.Options{
+		Addr:         cfg.RedisAddr,
+		Username:     cfg.RedisUsername,
+		Password:     cfg.RedisPassword,
+		DB:           cfg.RedisDB,
+		PoolSize:     cfg.RedisPoolSize,
+		DialTimeout:  timeout,
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout,
+		PoolTimeout:  timeout,
+		// Retrying would exceed the timeout; failed calls use the fallback.
+		MaxRetries:            -1,
+		ContextTimeoutEnabled: true,
+	}
+	if cfg.RedisTLS {
+		opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	return &RedisKeyLimiter{
+		client:   redis.NewClient(opts),
+		timeout:  timeout,
+		fallback: NewLocalKeyLimiter(cfg.PerKeyRPS, cfg.PerKeyBurst),
+		rps:      cfg.PerKeyRPS,
+		burst:    cfg.PerKeyBurst,
+		prefix:   cfg.RedisKeyPrefix,
+		now:      time.Now,
+		logger:   logger.With("component", "redis-rate-limiter"),
+	}, nil
+}
+
+// Allow reports whether one more request for key is allowed now.
+func (l *RedisKeyLimiter) Allow(ctx context.Context, key string) bool {
+	allowed, err := l.allowRedis(ctx, key)
+	if err != nil {
+		l.logFallback(err)
+		return l.fallback.Allow(ctx, key)
+	}
+	return allowed
+}
+
+// allowRedis runs the token bucket script within the Redis timeout. The
+// script is run by digest and loaded on first use.
+func (l *RedisKeyLimiter) allowRedis(ctx context.Context, key string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	n, err := tokenBucketScript.Run(ctx, l.client, []string{l.prefix + key}, l.rps, l.burst, l.now().UnixMilli()).Int64()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// logFallback logs a Redis failure at most once per fallbackLogInterval.
+func (l *RedisKeyLimiter) logFallback(err error) {
+	now := time.Now().UnixNano()
+	last := l.lastLogged.Load()
+	if now-last < int64(fallbackLogInterval) || !l.lastLogged.CompareAndSwap(last, now) {
+		return
+	}
+	l.logger.Warn("redis rate limiter unavailable, using local limits", "error", err)
+}
+
+// Close closes the Redis connections.
+func (l *RedisKeyLimiter) Close() {
+	_ = l.client.Close()
+}
@@ -0,0 +1,326 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// fakeRedis is a RESP2 server that answers the token bucket script with a
+// fixed per-key budget. EVALSHA fails with NOSCRIPT until EVAL has been used,
+// like a fresh Redis. With closeAfterReply set, it drops each connection
+// once it has answered a script call.
+type fakeRedis struct {
+	ln              net.Listener
+	mu              sync.Mutex
+	budget          map[string]int
+	loaded          bool
+	commands        []string
+	conns           int
+	closeAfterReply bool
+}
+
+func newFakeRedis(t *testing.T, budget int) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	f := &fakeRedis{ln: ln, budget: map[string]int{}}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.conns++
+			f.mu.Unlock()
+			go f.serve(conn, budget)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) serve(conn net.Conn, budget int) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil || len(args) == 0 {
+			return
+		}
+		args[0] = strings.ToUpper(args[0])
+
+		f.mu.Lock()
+		var out string
+		closeConn := false
+		switch {
+		case args[0] == "HELLO":
+			// Like Redis before 6, so the client falls back to RESP2.
+			out = "-ERR unknown command 'HELLO'\r\n"
+		case args[0] == "CLIENT":
+			out = "+OK\r\n"
+		case args[0] == "EVALSHA" && !f.loaded:
+			f.commands = append(f.commands, args[0])
+			out = "-NOSCRIPT No matching script.\r\n"
+		case args[0] == "EVALSHA" || args[0] == "EVAL":
+			f.commands = append(f.commands, args[0])
+			f.loaded = true
+			closeConn = f.closeAfterReply
+			key := args[3]
+			if _, ok := f.budget[key]; !ok {
+				f.budget[key] = budget
+			}
+			if f.budget[key] > 0 {
+				f.budget[key]--
+				out = ":1\r\n"
+			} else {
+				out = ":0\r\n"
+			}
+		default:
+			out = "+OK\r\n"
+		}
+		f.mu.Unlock()
+
+		if _, err := conn.Write([]byte(out)); err != nil || closeConn {
+			return
+		}
+	}
+}
+
+// readCommand reads one command sent as a RESP array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	n, err := readLength(r, '*')
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		size, err := readLength(r, '$')
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+// readLength reads a RESP header line of the given kind.
+func readLength(r *bufio.Reader, kind byte) (int, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+	if len(line) < 3 || line[0] != kind {
+		return 0, fmt.Errorf("unexpected RESP line %q", line)
+	}
+	return strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n"))
+}
+
+func newTestRedisLimiter(t *testing.T, cfg RateLimitConfig) *RedisKeyLimiter {
+	t.Helper()
+	limiter, err := NewRedisKeyLimiter(cfg, nil)
+	if err != nil {
+		t.Fatalf("NewRedisKeyLimiter() error = %v", err)
+	}
+	return limiter
+}
+
+// TestRedisKeyLimiter_SharedBudget verifies limits are enforced by Redis and
+// shared between limiters (i.e. gateway replicas).
+func TestRedisKeyLimiter_SharedBudget(t *testing.T) {
+	redis := newFakeRedis(t, 3)
+	cfg := RateLimitConfig{
+		Enabled:        true,
+		PerKeyRPS:      1000,
+		PerKeyBurst:    1000,
+		RedisAddr:      redis.ln.Addr().String(),
+		RedisTimeout:   time.Second,
+		RedisKeyPrefix: "rl:",
+	}
+	replicaA := newTestRedisLimiter(t, cfg)
+	replicaB := newTestRedisLimiter(t, cfg)
+	defer replicaA.Close()
+	defer replicaB.Close()
+
+	ctx := context.Background()
+	results := []bool{
+		replicaA.Allow(ctx, "app"),
+		replicaB.Allow(ctx, "app"),
+		replicaA.Allow(ctx, "app"),
+		replicaB.Allow(ctx, "app"),
+	}
+	want := []bool{true, true, true, false}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Errorf("request %d: Allow() = %v, want %v", i, results[i], want[i])
+		}
+	}
+
+	if !replicaA.Allow(ctx, "other-app") {
+		t.Error("other-app should have its own budget")
+	}
+
+	redis.mu.Lock()
+	defer redis.mu.Unlock()
+	if redis.commands[0] != "EVALSHA" || redis.commands[1] != "EVAL" || redis.commands[2] != "EVALSHA" {
+		t.Errorf("commands = %v, want EVALSHA, EVAL (after NOSCRIPT), then EVALSHA", redis.commands)
+	}
+}
+
+// TestRedisKeyLimiter_FallsBackToLocal verifies requests are limited in
+// process when Redis is unreachable.
+func TestRedisKeyLimiter_FallsBackToLocal(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	limiter := newTestRedisLimiter(t, RateLimitConfig{
+		Enabled:      true,
+		PerKeyRPS:    1,
+		PerKeyBurst:  2,
+		RedisAddr:    addr,
+		RedisTimeout: 100 * time.Millisecond,
+	})
+	defer limiter.Close()
+
+	ctx := context.Background()
+	if !limiter.Allow(ctx, "app") || !limiter.Allow(ctx, "app") {
+		t.Fatal("fallback should allow the local burst")
+	}
+	if limiter.Allow(ctx, "app") {
+		t.Error("fallback should enforce the local limit")
+	}
+}
+
+// TestNewRedisKeyLimiter_InvalidRate verifies a rate the token bucket script
+// cannot divide by is rejected at startup.
+func TestNewRedisKeyLimiter_InvalidRate(t *testing.T) {
+	for _, rps := range []float64{0, -1} {
+		if _, err := NewRedisKeyLimiter(RateLimitConfig{PerKeyRPS: rps, PerKeyBurst: 10, RedisAddr: "127.0.0.1:6379"}, nil); err == nil {
+			t.Errorf("NewRedisKeyLimiter(PerKeyRPS: %v) should fail", rps)
+		}
+	}
+}
+
+// TestRedisKeyLimiter_Reconnects verifies connections closed by Redis are
+// replaced rather than triggering the fallback.
+func TestRedisKeyLimiter_Reconnects(t *testing.T) {
+	redis := newFakeRedis(t, 2)
+	redis.closeAfterReply = true
+	limiter := newTestRedisLimiter(t, RateLimitConfig{
+		Enabled:      true,
+		PerKeyRPS:    1000,
+		PerKeyBurst:  1000,
+		RedisAddr:    redis.ln.Addr().String(),
+		RedisTimeout: time.Second,
+	})
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for i := range 2 {
+		if !limiter.Allow(ctx, "app") {
+			t.Fatalf("request %d should be allowed by Redis", i)
+		}
+		// Let the client see the closed connection before reusing it.
+		time.Sleep(10 * time.Millisecond)
+	}
+	// The local fallback would allow its burst of 1000.
+	if limiter.Allow(ctx, "app") {
+		t.Error("third request should be limited by Redis, not the fallback")
+	}
+
+	redis.mu.Lock()
+	defer redis.mu.Unlock()
+	if redis.conns < 3 {
+		t.Errorf("connections = %d, want a new one per request", redis.conns)
+	}
+}
+
+// TestTokenBucketScript_Redis runs the token bucket script on an in-process
+// Redis, checking refills over time and the bucket's expiry.
+func TestTokenBucketScript_Redis(t *testing.T) {
+	server := miniredis.RunT(t)
+	limiter := newTestRedisLimiter(t, RateLimitConfig{
+		Enabled:        true,
+		PerKeyRPS:      2,
+		PerKeyBurst:    3,
+		RedisAddr:      server.Addr(),
+		RedisTimeout:   time.Second,
+		RedisKeyPrefix: "rl:",
+	})
+	defer limiter.Close()
+
+	// The script only sees the time passed by the limiter; the local
+	// fallback would refill in real time and fail the checks below.
+	now := time.UnixMilli(1_700_000_000_000)
+	limiter.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	allowed := func(n int) int {
+		count := 0
+		for range n {
+			if limiter.Allow(ctx, "app") {
+				count++
+			}
+		}
+		return count
+	}
+
+	if got := allowed(4); got != 3 {
+		t.Fatalf("allowed %d of 4 requests on a new bucket, want the burst of 3", got)
+	}
+	if tokens := server.HGet("rl:app", "tokens"); tokens != "0" {
+		t.Errorf("tokens = %q, want 0 after draining the burst", tokens)
+	}
+
+	// 2 tokens/s: 400ms refills 0.8 tokens, 1s two
+	now = now.Add(400 * time.Millisecond)
+	if got := allowed(1); got != 0 {
+		t.Error("request allowed before a whole token was refilled")
+	}
+	now = now.Add(600 * time.Millisecond)
+	if got := allowed(3); got != 2 {
+		t.Errorf("allowed %d of 3 requests after 1s, want 2", got)
+	}
+
+	// Refills are capped at the burst
+	now = now.Add(time.Minute)
+	if got := allowed(4); got != 3 {
+		t.Errorf("allowed %d of 4 requests after a minute, want the burst of 3", got)
+	}
+
+	// The bucket expires once it would be full again: burst/rate plus 1s
+	if ttl := server.TTL("rl:app"); ttl != 2500*time.Millisecond {
+		t.Errorf("TTL = %v, want 2.5s", ttl)
+	}
+	server.FastForward(2500 * time.Millisecond)
+	if server.Exists("rl:app") {
+		t.Fatal("bucket should expire")
+	}
+
+	// Without its state, the bucket starts full even though the limiter's
+	// clock has not moved
+	if got := allowed(4); got != 3 {
+		t.Errorf("allowed %d of 4 requests after expiry, want the burst of 3", got)
+	}
+}
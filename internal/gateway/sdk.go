@@ -0,0 +1,161 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/SebastienMelki/causality/internal/quality"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// SDKHeader is the SDK handshake header. SDKs send it on every request as
+// semicolon-separated key=value pairs:
+//
+//	X-Causality-SDK: name=causality-mobile; version=1.4.0; platform=android; capabilities=clock_sync,gzip_batch
+//
+// name and version are required; platform and capabilities are optional,
+// and unknown keys are ignored so SDKs can report more over time.
+const SDKHeader = "X-Causality-SDK"
+
+// maxSDKValueLen bounds each value of the SDK header, which ends up in
+// audit records and data-quality buckets.
+const maxSDKValueLen = 64
+
+// sdkInfoKey is the context key for the request's SDK handshake.
+const sdkInfoKey ContextKey = "sdk_info"
+
+// SDKInfo is the SDK handshake of a request.
+type SDKInfo struct {
+	Name     string
+	Version  string
+	Platform string
+
+	// Capabilities are the optional protocol features the SDK uses, such
+	// as "clock_sync" or "gzip_batch".
+	Capabilities []string
+}
+
+// ParseSDKHeader parses the value of SDKHeader. It reports false if the
+// value lacks a name or version or has a value that is not a token of at
+// most 64 letters, digits, '.', '_', '+' or '-'. Platforms are lowercased.
+func ParseSDKHeader(value string) (SDKInfo, bool) {
+	var info SDKInfo
+	for _, pair := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		key, val = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(val)
+		switch key {
+		case "name":
+			info.Name = val
+		case "version":
+			info.Version = val
+		case "platform":
+			info.Platform = strings.ToLower(val)
+		case "capabilities":
+			for _, c := range strings.Split(val, ",") {
+				if c = strings.TrimSpace(c); c != "" {
+					info.Capabilities = append(info.Capabilities, c)
+				}
+			}
+		}
+	}
+
+	if info.Name == "" || info.Version == "" {
+		return SDKInfo{}, false
+	}
+	for _, v := range append([]string{info.Name, info.Version, info.Platform}, info.Capabilities...) {
+		if !isSDKToken(v) {
+			return SDKInfo{}, false
+		}
+	}
+	return info, true
+}
+
+// isSDKToken reports whether v is empty or a valid SDK header value.
+func isSDKToken(v string) bool {
+	if len(v) > maxSDKValueLen {
+		return false
+	}
+	for _, r := range v {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("._+-", r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// SDKFromContext returns the request's SDK handshake, and false if the
+// client sent no valid SDKHeader.
+func SDKFromContext(ctx context.Context) (SDKInfo, bool) {
+	info, ok := ctx.Value(sdkInfoKey).(SDKInfo)
+	return info, ok
+}
+
+// SDKHandshake parses SDKHeader into the request context, for the event
+// device context and data-quality SDK distribution, and records it in the
+// audit state. It must run inside Audit. Invalid headers are ignored rather
+// than rejected, so a malformed header never costs events.
+func SDKHandshake(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, ok := ParseSDKHeader(r.Header.Get(SDKHeader))
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := r.Context()
+		auditFromContext(ctx).setSDK(info)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, sdkInfoKey, info)))
+	})
+}
+
+// sdkPlatforms maps SDK header platforms to device context platforms.
+var sdkPlatforms = map[string]pb.Platform{
+	"ios":     pb.Platform_PLATFORM_IOS,
+	"android": pb.Platform_PLATFORM_ANDROID,
+	"web":     pb.Platform_PLATFORM_WEB,
+}
+
+// enrichSDK records the request's SDK handshake in the event's device
+// context. The SDK name is always set from the handshake; the version and
+// platform only where the event has none, since the SDK reports them
+// itself.
+func enrichSDK(ctx context.Context, event *pb.EventEnvelope) {
+	info, ok := SDKFromContext(ctx)
+	if !ok {
+		return
+	}
+	if event.DeviceContext == nil {
+		event.DeviceContext = &pb.DeviceContext{}
+	}
+	dc := event.DeviceContext
+	dc.SdkName = info.Name
+	if dc.GetSdkVersion() == "" {
+		dc.SdkVersion = info.Version
+	}
+	if dc.GetPlatform() == pb.Platform_PLATFORM_UNSPECIFIED {
+		dc.Platform = sdkPlatforms[info.Platform]
+	}
+}
+
+// sdkOf returns the SDK that sent an event for the data-quality SDK
+// distribution: the request's handshake or, from SDKs predating it, the
+// version and platform in the event's device context.
+func sdkOf(ctx context.Context, event *pb.EventEnvelope) quality.SDK {
+	if info, ok := SDKFromContext(ctx); ok {
+		return quality.SDK{Name: info.Name, Version: info.Version, Platform: info.Platform}
+	}
+	dc := event.GetDeviceContext()
+	sdk := quality.SDK{Version: dc.GetSdkVersion()}
+	for name, platform := range sdkPlatforms {
+		if dc.GetPlatform() == platform {
+			sdk.Platform = name
+		}
+	}
+	if !isSDKToken(sdk.Version) {
+		sdk.Version = ""
+	}
+	return sdk
+}
@@ -0,0 +1,87 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/SebastienMelki/causality/internal/auth"
+	"github.com/SebastienMelki/causality/internal/sdkpolicy"
+)
+
+// MinSDKVersionHeader carries the minimum SDK version of the app on
+// responses to requests from older SDKs, whether the request was rejected
+// or only flagged, so the SDK can prompt an upgrade.
+const MinSDKVersionHeader = "Causality-Min-SDK-Version"
+
+// auditReasonSDKOutdated is the decision reason recorded for requests from
+// SDKs older than the app's minimum version that were only flagged.
+const auditReasonSDKOutdated = "sdk_outdated"
+
+// SDKPolicy checks the SDK of a request against its app's minimum SDK
+// version. Implementations must be safe for concurrent use and must not
+// block.
+type SDKPolicy interface {
+	// Check returns the verdict of an app's policy for an SDK on its
+	// version.
+	Check(appID, sdkName, version string) sdkpolicy.Verdict
+}
+
+// SDKVersionPolicy enforces per-app minimum SDK versions on ingestion
+// requests. Requests from older SDKs get MinSDKVersionHeader and are either
+// rejected with 426 Upgrade Required and the sdk_version_unsupported error
+// code, or passed on when the policy only flags them. It must run after the
+// auth middleware, for the app, and inside SDKHandshake, for the SDK;
+// requests without a handshake are not checked, since their SDK is unknown.
+func SDKVersionPolicy(policy SDKPolicy) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			info, ok := SDKFromContext(ctx)
+			appID := auth.GetAppID(ctx)
+			if !ok || appID == "" || !isIngestPath(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			verdict := policy.Check(appID, info.Name, info.Version)
+			if !verdict.Outdated {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set(MinSDKVersionHeader, verdict.MinVersion)
+			if !verdict.Rejected() {
+				auditFromContext(ctx).fail(auditReasonSDKOutdated)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			auditFromContext(ctx).fail(ErrorCodeSDKVersionUnsupported)
+			writeSDKVersionUnsupported(w, r, info, verdict)
+		})
+	}
+}
+
+// writeSDKVersionUnsupported writes the 426 Upgrade Required response to a
+// request from an SDK older than the app's minimum version: a v2 structured
+// error, or for v1 the usual error message with the error code and minimum
+// version alongside.
+func writeSDKVersionUnsupported(w http.ResponseWriter, r *http.Request, info SDKInfo, verdict sdkpolicy.Verdict) {
+	message := verdict.Message
+	if message == "" {
+		message = fmt.Sprintf("%s %s is no longer supported; upgrade to %s or later", info.Name, info.Version, verdict.MinVersion)
+	}
+
+	if version, _, _ := ingestPathVersion(r.URL.Path); version == APIVersion2 {
+		writeAPIError(w, http.StatusUpgradeRequired, APIError{
+			Code:    ErrorCodeSDKVersionUnsupported,
+			Message: message,
+		})
+		return
+	}
+	writeJSON(w, http.StatusUpgradeRequired, map[string]string{
+		"error":       message,
+		"code":        ErrorCodeSDKVersionUnsupported,
+		"min_version": verdict.MinVersion,
+	})
+}
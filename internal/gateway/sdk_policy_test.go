@@ -0,0 +1,131 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/SebastienMelki/causality/internal/sdkpolicy"
+)
+
+// fakeSDKPolicy requires causality-mobile 1.4.0 on app-1, with action.
+type fakeSDKPolicy struct {
+	action sdkpolicy.Action
+}
+
+func (p fakeSDKPolicy) Check(appID, sdkName, version string) sdkpolicy.Verdict {
+	if appID != "app-1" || sdkName != "causality-mobile" || version >= "1.4.0" {
+		return sdkpolicy.Verdict{}
+	}
+	return sdkpolicy.Verdict{Outdated: true, Action: p.action, MinVersion: "1.4.0"}
+}
+
+func TestSDKVersionPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		action     sdkpolicy.Action
+		path       string
+		sdk        string
+		wantStatus int
+		wantHeader string
+		wantReason string
+	}{
+		{
+			name:       "current SDK",
+			action:     sdkpolicy.ActionReject,
+			path:       "/v1/events/batch",
+			sdk:        "name=causality-mobile; version=1.4.0",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "no handshake",
+			action:     sdkpolicy.ActionReject,
+			path:       "/v1/events/batch",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "outdated SDK rejected",
+			action:     sdkpolicy.ActionReject,
+			path:       "/v1/events/batch",
+			sdk:        "name=causality-mobile; version=1.3.9",
+			wantStatus: http.StatusUpgradeRequired,
+			wantHeader: "1.4.0",
+			wantReason: ErrorCodeSDKVersionUnsupported,
+		},
+		{
+			name:       "outdated SDK flagged",
+			action:     sdkpolicy.ActionFlag,
+			path:       "/v2/events/batch",
+			sdk:        "name=causality-mobile; version=1.3.9",
+			wantStatus: http.StatusOK,
+			wantHeader: "1.4.0",
+			wantReason: auditReasonSDKOutdated,
+		},
+		{
+			name:       "non-ingestion path",
+			action:     sdkpolicy.ActionReject,
+			path:       "/v1/experiments/assign",
+			sdk:        "name=causality-mobile; version=1.3.9",
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &mockAuditRecorder{}
+			handler := Chain(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}), Audit(recorder), SDKHandshake, fakeAuth, SDKVersionPolicy(fakeSDKPolicy{action: tt.action}))
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			if tt.sdk != "" {
+				req.Header.Set(SDKHeader, tt.sdk)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get(MinSDKVersionHeader); got != tt.wantHeader {
+				t.Errorf("%s = %q, want %q", MinSDKVersionHeader, got, tt.wantHeader)
+			}
+			if tt.wantReason != "" {
+				if len(recorder.records) != 1 || !slices.Contains(recorder.records[0].Reasons, tt.wantReason) {
+					t.Errorf("audit records = %+v, want reason %q", recorder.records, tt.wantReason)
+				}
+			}
+		})
+	}
+}
+
+func TestSDKVersionPolicy_ErrorBodies(t *testing.T) {
+	handler := Chain(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), SDKHandshake, fakeAuth, SDKVersionPolicy(fakeSDKPolicy{action: sdkpolicy.ActionReject}))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set(SDKHeader, "name=causality-mobile; version=1.2.0")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	var v1 map[string]string
+	if err := json.NewDecoder(serve("/v1/events/batch").Body).Decode(&v1); err != nil {
+		t.Fatalf("decode v1 body: %v", err)
+	}
+	if v1["code"] != ErrorCodeSDKVersionUnsupported || v1["min_version"] != "1.4.0" || v1["error"] == "" {
+		t.Errorf("v1 body = %v, want the error code and minimum version", v1)
+	}
+
+	var v2 ErrorResponseV2
+	if err := json.NewDecoder(serve("/v2/events/batch").Body).Decode(&v2); err != nil {
+		t.Fatalf("decode v2 body: %v", err)
+	}
+	if v2.Error.Code != ErrorCodeSDKVersionUnsupported || v2.Error.Retryable {
+		t.Errorf("v2 error = %+v, want non-retryable %s", v2.Error, ErrorCodeSDKVersionUnsupported)
+	}
+}
@@ -0,0 +1,120 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+func TestParseSDKHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   SDKInfo
+		wantOK bool
+	}{
+		{
+			name:   "full handshake",
+			header: "name=causality-mobile; version=1.4.0; platform=Android; capabilities=clock_sync, gzip_batch",
+			want:   SDKInfo{Name: "causality-mobile", Version: "1.4.0", Platform: "android", Capabilities: []string{"clock_sync", "gzip_batch"}},
+			wantOK: true,
+		},
+		{
+			name:   "unknown keys ignored",
+			header: "name=causality-go;version=0.3.0-rc.1;runtime=go1.24",
+			want:   SDKInfo{Name: "causality-go", Version: "0.3.0-rc.1"},
+			wantOK: true,
+		},
+		{name: "missing version", header: "name=causality-go"},
+		{name: "invalid characters", header: "name=causality/go; version=1.0.0"},
+		{name: "empty", header: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseSDKHeader(tt.header)
+			if ok != tt.wantOK {
+				t.Fatalf("ParseSDKHeader(%q) ok = %v, want %v", tt.header, ok, tt.wantOK)
+			}
+			if got.Name != tt.want.Name || got.Version != tt.want.Version || got.Platform != tt.want.Platform ||
+				!slices.Equal(got.Capabilities, tt.want.Capabilities) {
+				t.Errorf("ParseSDKHeader(%q) = %+v, want %+v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSDKHandshake_RecordsSDK(t *testing.T) {
+	pub := newMockPublisher()
+	svc := NewEventServiceWithPublisher(pub, nil, 0, nil)
+	qualityRecorder := &fakeQualityRecorder{}
+	svc.quality = qualityRecorder
+	auditRecorder := &mockAuditRecorder{}
+
+	handler := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		screen := &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}}
+		_, _ = svc.IngestEventBatch(r.Context(), &pb.IngestEventBatchRequest{
+			Events: []*pb.EventEnvelope{
+				{AppId: "app-1", TimestampMs: time.Now().UnixMilli(), Payload: screen},
+				{
+					AppId:         "app-1",
+					TimestampMs:   time.Now().UnixMilli(),
+					DeviceContext: &pb.DeviceContext{Platform: pb.Platform_PLATFORM_IOS, SdkVersion: "1.3.9"},
+					Payload:       screen,
+				},
+			},
+		})
+		w.WriteHeader(http.StatusOK)
+	}), RequestID, Audit(auditRecorder), SDKHandshake)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/events/batch", nil)
+	req.Header.Set(SDKHeader, "name=causality-mobile; version=1.4.0; platform=android; capabilities=clock_sync")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(pub.publishedEvents) != 2 {
+		t.Fatalf("published %d events, want 2", len(pub.publishedEvents))
+	}
+	// Events without device context get the handshake's version and
+	// platform; those reporting their own keep them.
+	dc := pub.publishedEvents[0].GetDeviceContext()
+	if dc.GetSdkName() != "causality-mobile" || dc.GetSdkVersion() != "1.4.0" || dc.GetPlatform() != pb.Platform_PLATFORM_ANDROID {
+		t.Errorf("device context = %+v, want causality-mobile 1.4.0 on android", dc)
+	}
+	dc = pub.publishedEvents[1].GetDeviceContext()
+	if dc.GetSdkName() != "causality-mobile" || dc.GetSdkVersion() != "1.3.9" || dc.GetPlatform() != pb.Platform_PLATFORM_IOS {
+		t.Errorf("device context = %+v, want causality-mobile 1.3.9 on ios", dc)
+	}
+
+	if len(auditRecorder.records) != 1 {
+		t.Fatalf("got %d audit records, want 1", len(auditRecorder.records))
+	}
+	rec := auditRecorder.records[0]
+	if rec.SDKName != "causality-mobile" || rec.SDKVersion != "1.4.0" || rec.SDKPlatform != "android" ||
+		!slices.Equal(rec.SDKCapabilities, []string{"clock_sync"}) {
+		t.Errorf("audit SDK = %q %q %q %v, want the handshake", rec.SDKName, rec.SDKVersion, rec.SDKPlatform, rec.SDKCapabilities)
+	}
+
+	if len(qualityRecorder.obs) != 2 {
+		t.Fatalf("got %d quality observations, want 2", len(qualityRecorder.obs))
+	}
+	for i, obs := range qualityRecorder.obs {
+		if obs.SDK.Label() != "causality-mobile/1.4.0/android" {
+			t.Errorf("observation %d SDK = %q, want the handshake", i, obs.SDK.Label())
+		}
+	}
+}
+
+func TestSDKOf_WithoutHandshake(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/v1/events/ingest", nil)
+	event := &pb.EventEnvelope{DeviceContext: &pb.DeviceContext{Platform: pb.Platform_PLATFORM_WEB, SdkVersion: "0.9.1"}}
+
+	if got := sdkOf(req.Context(), event).Label(); got != "unknown/0.9.1/web" {
+		t.Errorf("sdkOf() = %q, want unknown/0.9.1/web", got)
+	}
+	if got := sdkOf(req.Context(), &pb.EventEnvelope{}).Label(); got != "unknown/unknown/unknown" {
+		t.Errorf("sdkOf() without device context = %q, want unknown/unknown/unknown", got)
+	}
+}
@@ -7,11 +7,15 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"sync/atomic"
 	"time"
 
+	sebufhttp "github.com/SebastienMelki/sebuf/http"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/SebastienMelki/causality/internal/ingest"
 	"github.com/SebastienMelki/causality/internal/nats"
 	"github.com/SebastienMelki/causality/internal/observability"
-	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
 )
 
 // ServerOpts holds optional dependencies for the HTTP gateway server.
@@ -29,11 +33,36 @@ type ServerOpts struct {
 	Metrics *observability.Metrics
 
 	// Dedup provides deduplication checking. If nil, dedup is disabled.
-	Dedup DedupChecker
+	Dedup ingest.DedupChecker
 
 	// AdminRouteRegistrar registers admin API routes (e.g., key management)
 	// onto the mux. If nil, no admin routes are mounted.
 	AdminRouteRegistrar func(mux *http.ServeMux)
+
+	// DebugRouteRegistrar registers runtime diagnostics routes (pprof,
+	// expvar) onto the mux. If nil, no diagnostics routes are mounted.
+	DebugRouteRegistrar func(mux *http.ServeMux)
+
+	// Audit receives one audit record per ingestion request. If nil,
+	// ingestion auditing is disabled.
+	Audit AuditRecorder
+
+	// Quality receives the ingestion outcome of every event for the
+	// data-quality metrics. If nil, they are not recorded.
+	Quality QualityRecorder
+
+	// SDKPolicy enforces per-app minimum SDK versions. If nil, every SDK
+	// version is accepted.
+	SDKPolicy SDKPolicy
+
+	// Database is checked by /ready. If nil, readiness does not depend on
+	// the database.
+	Database HealthChecker
+}
+
+// HealthChecker reports whether a dependency is usable.
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
 }
 
 // Server is the HTTP gateway server.
@@ -42,7 +71,15 @@ type Server struct {
 	config       Config
 	eventService *EventService
 	natsClient   *nats.Client
+	redisLimiter *RedisKeyLimiter
+	publishProbe *PublishProbe
+	natsIngest   *NATSIngest
+	database     HealthChecker
 	logger       *slog.Logger
+
+	// natsConnected is cleared while the NATS connection is down, so /ready
+	// fails fast during a broker rollout instead of waiting on a health check.
+	natsConnected atomic.Bool
 }
 
 // NewServer creates a new HTTP gateway server with the given options.
@@ -54,22 +91,52 @@ func NewServer(cfg Config, natsClient *nats.Client, publisher *nats.Publisher, l
 		opts = &ServerOpts{}
 	}
 
-	eventService := NewEventService(publisher, opts.Dedup, cfg.MaxBatchEvents, logger)
+	limiter, err := ingest.NewLimiter(cfg.EventLimits)
+	if err != nil {
+		return nil, err
+	}
+	eventService := newEventService(ingest.Config{
+		MaxBatchEvents: cfg.MaxBatchEvents,
+		PublishTimeout: cfg.PublishTimeout,
+	}, publisher, logger, ingest.Options{
+		Dedup:   opts.Dedup,
+		Limits:  limiter,
+		Metrics: opts.Metrics,
+	})
+	eventService.quality = opts.Quality
 
 	server := &Server{
 		config:       cfg,
 		eventService: eventService,
 		natsClient:   natsClient,
+		database:     opts.Database,
 		logger:       logger.With("component", "http-server"),
 	}
+	server.natsConnected.Store(true)
+	natsClient.OnConnectionChange(server.natsConnected.Store)
+
+	// Synthetic publishes to the probe stream, feeding readiness
+	if cfg.PublishProbe.Enabled {
+		server.publishProbe = NewPublishProbe(natsClient.JetStream(), cfg.PublishProbe, logger)
+	}
+
+	// Ingestion from edge collectors over NATS core, through the same
+	// event service as HTTP
+	if cfg.NATSIngest.Enabled {
+		server.natsIngest = NewNATSIngest(natsClient.Conn(), eventService, server.publishProbe, cfg.NATSIngest, logger)
+	}
 
 	mux := http.NewServeMux()
 
-	// Register sebuf-generated HTTP handlers for EventService
-	if err := pb.RegisterEventServiceServer(eventService, pb.WithMux(mux)); err != nil {
+	// Register the ingestion handlers of every API version: sebuf-generated
+	// for v1, hand-written for v2
+	if err := registerVersions(mux, eventService); err != nil {
 		return nil, fmt.Errorf("failed to register event service: %w", err)
 	}
 
+	// Experiment variant assignment (not generated by sebuf)
+	mux.HandleFunc("POST /v1/experiments/assign", handleAssign)
+
 	// Health endpoints (not generated by sebuf)
 	mux.HandleFunc("GET /health", server.handleHealth)
 	mux.HandleFunc("GET /ready", server.handleReady)
@@ -79,27 +146,64 @@ func NewServer(cfg Config, natsClient *nats.Client, publisher *nats.Publisher, l
 		mux.Handle("GET /metrics", opts.MetricsHandler)
 	}
 
+	// RED metrics summary (JSON)
+	if opts.Metrics != nil {
+		mux.Handle("GET /debug/metrics-summary", opts.Metrics.SummaryHandler())
+	}
+
+	// Runtime diagnostics
+	if opts.DebugRouteRegistrar != nil {
+		opts.DebugRouteRegistrar(mux)
+	}
+
 	// Admin routes (API key management)
 	if opts.AdminRouteRegistrar != nil {
 		opts.AdminRouteRegistrar(mux)
 	}
 
+	// Abuse protection (IP allow/deny lists, temporary bans) and its admin routes
+	abuseGuard, err := NewAbuseGuard(cfg.Abuse, logger)
+	if err != nil {
+		return nil, err
+	}
+	abuseGuard.RegisterRoutes(mux)
+
+	// Per-key debug capture and its admin routes
+	debugCapture := NewDebugCapture(cfg.DebugCapture)
+	debugCapture.RegisterRoutes(mux)
+
 	// Build middleware chain.
-	// Order (outermost first): RequestID -> Logging -> Recovery -> HTTPMetrics ->
-	// CORS -> BodySizeLimit -> Auth -> PerKeyRateLimit -> ContentType
-	middlewares := []Middleware{
-		RequestID,
+	// Order (outermost first): RequestID -> ClockSync -> Audit ->
+	// SDKHandshake -> Logging -> Recovery -> HTTPMetrics -> AbuseProtection ->
+	// CORS -> PublishHealth -> BodySizeLimit -> Auth -> AuditIdentity ->
+	// AbuseIdentity -> DebugCapture -> SDKVersionPolicy -> PerKeyRateLimit ->
+	// BatchDecoding -> DebugCaptureBody -> UnknownFields -> ContentType
+	middlewares := []Middleware{RequestID, ClockSync}
+
+	// Ingestion audit log (outside auth/rate limiting to capture rejections)
+	if opts.Audit != nil {
+		middlewares = append(middlewares, Audit(opts.Audit))
+	}
+
+	// SDK handshake header (inside Audit, so the audit record carries it)
+	middlewares = append(middlewares,
+		SDKHandshake,
 		Logging(server.logger),
 		Recovery(server.logger),
-	}
+	)
 
 	// OTel HTTP metrics (if available)
 	if opts.Metrics != nil {
 		middlewares = append(middlewares, observability.HTTPMetrics(opts.Metrics))
 	}
 
+	// IP filtering and bans (outside auth, so auth failures count towards bans)
+	middlewares = append(middlewares, AbuseProtection(abuseGuard))
+
+	// Reject ingestion while JetStream acks stall (before reading the body)
 	middlewares = append(middlewares,
 		CORS(server.config.CORS),
+		PublishHealth(server.publishProbe),
 		BodySizeLimit(server.config.MaxBodySize),
 	)
 
@@ -108,8 +212,47 @@ func NewServer(cfg Config, natsClient *nats.Client, publisher *nats.Publisher, l
 		middlewares = append(middlewares, opts.AuthMiddleware)
 	}
 
-	// Per-key rate limiting (after auth, so app_id is in context)
-	middlewares = append(middlewares, PerKeyRateLimit(server.config.RateLimit))
+	// Capture authenticated identity for the audit record
+	if opts.Audit != nil {
+		middlewares = append(middlewares, AuditIdentity)
+	}
+
+	// Per-key bans (after auth, so the key ID is in context)
+	middlewares = append(middlewares, AbuseIdentity(abuseGuard))
+
+	// Debug capture of enabled keys (after auth, so the key ID is in context,
+	// and before rate limiting, so rejections are captured)
+	middlewares = append(middlewares, DebugCaptureMiddleware(debugCapture))
+
+	// Minimum SDK versions (after auth, so the app is in context, and before
+	// rate limiting, so rejected SDKs do not use up the key's quota)
+	if opts.SDKPolicy != nil {
+		middlewares = append(middlewares, SDKVersionPolicy(opts.SDKPolicy))
+	}
+
+	// Per-key rate limiting (after auth, so app_id is in context), shared
+	// across replicas through Redis when configured
+	if cfg.RateLimit.Enabled && cfg.RateLimit.RedisAddr != "" {
+		redisLimiter, err := NewRedisKeyLimiter(cfg.RateLimit, logger)
+		if err != nil {
+			return nil, err
+		}
+		server.redisLimiter = redisLimiter
+		middlewares = append(middlewares, PerKeyRateLimitWith(server.redisLimiter))
+	} else {
+		middlewares = append(middlewares, PerKeyRateLimit(server.config.RateLimit))
+	}
+
+	// Compressed and length-delimited batch bodies (after rate limiting, so
+	// rejected requests are never decompressed)
+	middlewares = append(middlewares, BatchDecoding(server.config.MaxDecompressedBodySize))
+
+	// Decoded request bodies of captured requests
+	middlewares = append(middlewares, DebugCaptureBody(debugCapture))
+
+	// Fields newer clients send that the event schema does not declare (after
+	// decoding, so compressed JSON bodies are covered too)
+	middlewares = append(middlewares, UnknownFields(opts.Metrics, logger))
 
 	// Content type
 	middlewares = append(middlewares, ContentType)
@@ -131,6 +274,12 @@ func NewServer(cfg Config, natsClient *nats.Client, publisher *nats.Publisher, l
 // Start starts the HTTP server.
 func (s *Server) Start() error {
 	s.logger.Info("starting HTTP server", "addr", s.config.Addr)
+	s.publishProbe.Start()
+	if s.natsIngest != nil {
+		if err := s.natsIngest.Start(); err != nil {
+			return err
+		}
+	}
 	if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return fmt.Errorf("server error: %w", err)
 	}
@@ -142,7 +291,37 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("shutting down HTTP server")
 	ctx, cancel := context.WithTimeout(ctx, s.config.ShutdownTimeout)
 	defer cancel()
-	return s.httpServer.Shutdown(ctx)
+	err := s.httpServer.Shutdown(ctx)
+	if s.natsIngest != nil {
+		s.natsIngest.Stop()
+	}
+	s.publishProbe.Stop()
+	if s.redisLimiter != nil {
+		s.redisLimiter.Close()
+	}
+	return err
+}
+
+// handleServiceError maps event service errors to HTTP status codes. Events
+// outside the API key's allowed event types get 403 Forbidden so clients can
+// tell them apart from malformed requests, events over the size limit get
+// 413 Request Entity Too Large, and publish timeouts get 503 Service
+// Unavailable with Retry-After; other errors use the default mapping.
+func handleServiceError(w http.ResponseWriter, _ *http.Request, err error) proto.Message {
+	if errors.Is(err, ingest.ErrPublishTimeout) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return &sebufhttp.Error{Message: err.Error()}
+	}
+	if errors.Is(err, ErrEventTypeNotAllowed) {
+		w.WriteHeader(http.StatusForbidden)
+		return &sebufhttp.Error{Message: err.Error()}
+	}
+	if errors.Is(err, ingest.ErrEventTooLarge) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return &sebufhttp.Error{Message: err.Error()}
+	}
+	return nil
 }
 
 // handleHealth handles GET /health.
@@ -159,7 +338,17 @@ func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
 // handleReady handles GET /ready.
 func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	if err := s.natsClient.HealthCheck(r.Context()); err != nil {
+	err := nats.ErrNotConnected
+	if s.natsConnected.Load() {
+		err = s.natsClient.HealthCheck(r.Context())
+	}
+	if err == nil {
+		err = s.publishProbe.Err()
+	}
+	if err == nil && s.database != nil {
+		err = s.database.HealthCheck(r.Context())
+	}
+	if err != nil {
 		w.WriteHeader(http.StatusServiceUnavailable)
 		if encErr := json.NewEncoder(w).Encode(map[string]string{
 			"status": "not_ready",
@@ -2,225 +2,132 @@ package gateway
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"time"
 
-	"github.com/google/uuid"
-
+	"github.com/SebastienMelki/causality/internal/auth"
+	"github.com/SebastienMelki/causality/internal/events"
+	"github.com/SebastienMelki/causality/internal/ingest"
 	"github.com/SebastienMelki/causality/internal/nats"
+	"github.com/SebastienMelki/causality/internal/quality"
 	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
 )
 
-// DedupChecker checks whether an idempotency key has been seen before.
-// Implementations must be safe for concurrent use.
-type DedupChecker interface {
-	// IsDuplicate returns true if the given key was already seen within
-	// the dedup window. An empty key always returns false.
-	IsDuplicate(key string) bool
-}
-
-// EventPublisher abstracts the NATS publisher for testing.
-type EventPublisher interface {
-	// PublishEvent publishes an event to the message queue.
-	PublishEvent(ctx context.Context, event *pb.EventEnvelope) error
-}
-
-// EventService implements the event ingestion business logic.
+// EventService implements the event ingestion endpoints on top of the
+// shared ingest service, adding what depends on the request: the API
+// version's checks, the API key's allowed events, the SDK handshake, the
+// audit record and the data-quality outcomes.
 // This service is used by HTTP handlers (sebuf-generated or manual).
 type EventService struct {
-	publisher      EventPublisher
-	dedup          DedupChecker
-	maxBatchEvents int
-	logger         *slog.Logger
+	ingest *ingest.Service
+
+	// quality receives event outcomes when set
+	quality QualityRecorder
 }
 
 // NewEventService creates a new event service. The dedup parameter is optional;
 // pass nil to disable deduplication. The maxBatchEvents parameter controls the
 // maximum number of events in a batch (0 means no limit).
-func NewEventService(publisher *nats.Publisher, dedup DedupChecker, maxBatchEvents int, logger *slog.Logger) *EventService {
+func NewEventService(publisher *nats.Publisher, dedup ingest.DedupChecker, maxBatchEvents int, logger *slog.Logger) *EventService {
 	return NewEventServiceWithPublisher(publisher, dedup, maxBatchEvents, logger)
 }
 
 // NewEventServiceWithPublisher creates a new event service with a custom publisher.
 // This allows dependency injection for testing. The publisher parameter can be any
-// implementation of EventPublisher (including *nats.Publisher).
-func NewEventServiceWithPublisher(publisher EventPublisher, dedup DedupChecker, maxBatchEvents int, logger *slog.Logger) *EventService {
-	if logger == nil {
-		logger = slog.Default()
-	}
-	return &EventService{
-		publisher:      publisher,
-		dedup:          dedup,
-		maxBatchEvents: maxBatchEvents,
-		logger:         logger.With("component", "event-service"),
-	}
+// implementation of ingest.Publisher (including *nats.Publisher).
+func NewEventServiceWithPublisher(publisher ingest.Publisher, dedup ingest.DedupChecker, maxBatchEvents int, logger *slog.Logger) *EventService {
+	return newEventService(ingest.Config{MaxBatchEvents: maxBatchEvents}, publisher, logger, ingest.Options{Dedup: dedup})
+}
+
+// newEventService creates the event service of an ingest service with cfg
+// and opts, adding the request's checks and enrichment.
+func newEventService(cfg ingest.Config, publisher ingest.Publisher, logger *slog.Logger, opts ingest.Options) *EventService {
+	opts.Validate = validateRequest
+	opts.Enrich = enrichSDK
+	return &EventService{ingest: ingest.NewService(cfg, publisher, logger, &opts)}
 }
 
 // IngestEvent handles single event ingestion.
 func (s *EventService) IngestEvent(ctx context.Context, req *pb.IngestEventRequest) (*pb.IngestEventResponse, error) {
-	if req.GetEvent() == nil {
-		return nil, ErrEventRequired
-	}
-
-	event := req.GetEvent()
+	audited := auditFromContext(ctx)
 
-	// Validate required fields
-	if err := s.validateEvent(event); err != nil {
+	resp, err := s.ingest.IngestEvent(ctx, req.GetEvent())
+	if errors.Is(err, ingest.ErrEventRequired) {
+		audited.fail(err.Error())
 		return nil, err
 	}
 
-	// Enrich envelope with server-generated values
-	s.enrichEnvelope(event)
-
-	// Check for duplicate (after enrich so idempotency_key is set)
-	if s.dedup != nil && s.dedup.IsDuplicate(event.GetIdempotencyKey()) {
-		s.logger.Debug("duplicate event silently dropped",
-			"event_id", event.GetId(),
-			"idempotency_key", event.GetIdempotencyKey(),
-		)
-		// Return success to client (silently drop)
-		return &pb.IngestEventResponse{
-			EventId: event.GetId(),
-			Status:  "accepted",
-		}, nil
-	}
-
-	// Publish to NATS
-	if err := s.publisher.PublishEvent(ctx, event); err != nil {
-		s.logger.Error("failed to publish event",
-			"event_id", event.GetId(),
-			"error", err,
-		)
-		return nil, fmt.Errorf("failed to publish event: %w", err)
+	audited.setEventCount(1)
+	if err != nil {
+		s.recordOutcome(ctx, req.GetEvent(), "", err.Error(), err)
+		return nil, err
 	}
-
-	s.logger.Debug("event ingested",
-		"event_id", event.GetId(),
-		"app_id", event.GetAppId(),
-	)
-
-	return &pb.IngestEventResponse{
-		EventId: event.GetId(),
-		Status:  "accepted",
-	}, nil
+	s.recordOutcome(ctx, req.GetEvent(), resp.GetStatus(), "", nil)
+	return resp, nil
 }
 
 // IngestEventBatch handles batch event ingestion.
 func (s *EventService) IngestEventBatch(ctx context.Context, req *pb.IngestEventBatchRequest) (*pb.IngestEventBatchResponse, error) {
-	if len(req.GetEvents()) == 0 {
-		return nil, ErrAtLeastOneEvent
-	}
+	resp, _, err := s.ingestBatch(ctx, req)
+	return resp, err
+}
 
-	// Check batch size limit
-	if s.maxBatchEvents > 0 && len(req.GetEvents()) > s.maxBatchEvents {
-		return nil, ErrBatchTooLarge
+// ingestBatch ingests the events of a batch. Alongside the response it
+// returns the error each rejected event was rejected with, by index, so
+// that API versions can report errors in their own format.
+func (s *EventService) ingestBatch(ctx context.Context, req *pb.IngestEventBatchRequest) (*pb.IngestEventBatchResponse, []error, error) {
+	audited := auditFromContext(ctx)
+	if n := len(req.GetEvents()); n > 0 {
+		audited.setEventCount(n)
 	}
 
-	results := make([]*pb.EventResult, len(req.GetEvents()))
-	acceptedCount := int32(0)
-	rejectedCount := int32(0)
-
-	for i, event := range req.GetEvents() {
-		result := &pb.EventResult{
-			Index: int32(i), //nolint:gosec // Index is bounded by batch size which is well under int32 max.
-		}
-
-		// Validate: nil event
-		if event == nil {
-			result.Status = "rejected"
-			result.Error = "event is nil"
-			rejectedCount++
-			results[i] = result
-			continue
-		}
-
-		// Validate required fields; skip invalid events
-		if err := s.validateEvent(event); err != nil {
-			result.Status = "rejected"
-			result.Error = err.Error()
-			rejectedCount++
-			results[i] = result
-			continue
-		}
-
-		// Enrich
-		s.enrichEnvelope(event)
-
-		// Dedup check
-		if s.dedup != nil && s.dedup.IsDuplicate(event.GetIdempotencyKey()) {
-			// Silently drop duplicates but report as accepted
-			result.EventId = event.GetId()
-			result.Status = "accepted"
-			acceptedCount++
-			results[i] = result
-			s.logger.Debug("duplicate event in batch silently dropped",
-				"index", i,
-				"idempotency_key", event.GetIdempotencyKey(),
-			)
-			continue
-		}
-
-		// Publish to NATS
-		if err := s.publisher.PublishEvent(ctx, event); err != nil {
-			result.Status = "rejected"
-			result.Error = err.Error()
-			rejectedCount++
-			s.logger.Warn("failed to publish event in batch",
-				"index", i,
-				"event_id", event.GetId(),
-				"error", err,
-			)
-		} else {
-			result.EventId = event.GetId()
-			result.Status = "accepted"
-			acceptedCount++
-		}
-
-		results[i] = result
+	resp, errs, err := s.ingest.IngestBatch(ctx, req)
+	if err != nil {
+		audited.fail(err.Error())
+		return nil, nil, err
 	}
 
-	s.logger.Info("batch ingestion complete",
-		"total", len(req.GetEvents()),
-		"accepted", acceptedCount,
-		"rejected", rejectedCount,
-	)
-
-	return &pb.IngestEventBatchResponse{
-		AcceptedCount: acceptedCount,
-		RejectedCount: rejectedCount,
-		Results:       results,
-	}, nil
-}
-
-// validateEvent checks that an event has all required fields.
-func (s *EventService) validateEvent(event *pb.EventEnvelope) error {
-	if event.GetAppId() == "" {
-		return ErrAppIDRequired
-	}
-	if event.GetPayload() == nil {
-		return ErrEventTypeRequired
+	for i, result := range resp.GetResults() {
+		s.recordOutcome(ctx, req.GetEvents()[i], result.GetStatus(), result.GetError(), errs[i])
 	}
-	if event.GetTimestampMs() <= 0 {
-		return ErrTimestampRequired
-	}
-	return nil
+	return resp, errs, nil
 }
 
-// enrichEnvelope adds server-generated values to the event envelope.
-func (s *EventService) enrichEnvelope(event *pb.EventEnvelope) {
-	// Generate UUID v7 if not provided (time-sortable)
-	if event.GetId() == "" {
-		event.Id = uuid.Must(uuid.NewV7()).String()
+// recordOutcome adds an event's ingestion outcome to the request's audit
+// record, rejected with reason when err is set, and reports it to the
+// data-quality recorder.
+func (s *EventService) recordOutcome(ctx context.Context, event *pb.EventEnvelope, status, reason string, err error) {
+	audited := auditFromContext(ctx)
+	switch {
+	case errors.Is(err, ingest.ErrPublishTimeout):
+		audited.reject(auditReasonPublishTimeout)
+	case errors.Is(err, ingest.ErrPublishFailed):
+		audited.reject(auditReasonPublishFailed)
+	case err != nil:
+		audited.reject(reason)
+		s.observeQuality(ctx, event, quality.OutcomeRejected, err)
+	case status == ingest.StatusDeduplicated:
+		audited.accept(true)
+		s.observeQuality(ctx, event, quality.OutcomeDeduplicated, nil)
+	default:
+		audited.accept(false)
+		s.observeQuality(ctx, event, quality.OutcomeAccepted, nil)
 	}
+}
 
-	// Set timestamp if not provided
-	if event.GetTimestampMs() == 0 {
-		event.TimestampMs = time.Now().UnixMilli()
+// validateRequest checks what depends on the request: the v2 API's
+// stricter timestamp check, and that the event's category and type are
+// within the authenticating API key's allowed events.
+func validateRequest(ctx context.Context, event *pb.EventEnvelope) error {
+	if APIVersionFromContext(ctx) == APIVersion2 {
+		if err := validateEventV2(event, time.Now()); err != nil {
+			return err
+		}
 	}
-
-	// Generate idempotency key if not provided
-	if event.GetIdempotencyKey() == "" {
-		event.IdempotencyKey = uuid.New().String()
+	if category, eventType := events.GetCategoryAndType(event); !auth.EventAllowed(ctx, category, eventType) {
+		return fmt.Errorf("%w: %s.%s", ErrEventTypeNotAllowed, category, eventType)
 	}
+	return nil
 }
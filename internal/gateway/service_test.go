@@ -6,6 +6,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/SebastienMelki/causality/internal/auth"
+	"github.com/SebastienMelki/causality/internal/ingest"
 	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
 )
 
@@ -323,8 +325,8 @@ func TestIngestEvent_MissingAppID_ReturnsError(t *testing.T) {
 	if err == nil {
 		t.Error("IngestEvent() should return error for missing app_id")
 	}
-	if !errors.Is(err, ErrAppIDRequired) {
-		t.Errorf("IngestEvent() error = %v, want ErrAppIDRequired", err)
+	if !errors.Is(err, ingest.ErrAppIDRequired) {
+		t.Errorf("IngestEvent() error = %v, want ingest.ErrAppIDRequired", err)
 	}
 }
 
@@ -344,8 +346,8 @@ func TestIngestEvent_MissingEventType_ReturnsError(t *testing.T) {
 	if err == nil {
 		t.Error("IngestEvent() should return error for missing event_type (payload)")
 	}
-	if !errors.Is(err, ErrEventTypeRequired) {
-		t.Errorf("IngestEvent() error = %v, want ErrEventTypeRequired", err)
+	if !errors.Is(err, ingest.ErrEventTypeRequired) {
+		t.Errorf("IngestEvent() error = %v, want ingest.ErrEventTypeRequired", err)
 	}
 }
 
@@ -367,8 +369,8 @@ func TestIngestEvent_MissingTimestamp_ReturnsError(t *testing.T) {
 	if err == nil {
 		t.Error("IngestEvent() should return error for missing timestamp")
 	}
-	if !errors.Is(err, ErrTimestampRequired) {
-		t.Errorf("IngestEvent() error = %v, want ErrTimestampRequired", err)
+	if !errors.Is(err, ingest.ErrTimestampRequired) {
+		t.Errorf("IngestEvent() error = %v, want ingest.ErrTimestampRequired", err)
 	}
 }
 
@@ -396,9 +398,9 @@ func TestIngestEvent_DuplicateDropped(t *testing.T) {
 		t.Fatalf("IngestEvent() returned unexpected error: %v", err)
 	}
 
-	// Should return success (silently drop)
-	if resp.Status != "accepted" {
-		t.Errorf("Response status = %q, want %q", resp.Status, "accepted")
+	// Should return success, flagged as deduplicated
+	if resp.Status != ingest.StatusDeduplicated {
+		t.Errorf("Response status = %q, want %q", resp.Status, ingest.StatusDeduplicated)
 	}
 
 	// Publisher should not have been called
@@ -407,27 +409,6 @@ func TestIngestEvent_DuplicateDropped(t *testing.T) {
 	}
 }
 
-// TestIngestEvent_IdempotencyKeyGenerated verifies that events without idempotency_key get one assigned.
-func TestIngestEvent_IdempotencyKeyGenerated(t *testing.T) {
-	svc := NewEventService(nil, nil, 0, nil)
-
-	event := &pb.EventEnvelope{
-		AppId:          "test-app",
-		TimestampMs:    time.Now().UnixMilli(),
-		IdempotencyKey: "", // Empty - should be generated
-		Payload: &pb.EventEnvelope_ScreenView{
-			ScreenView: &pb.ScreenView{ScreenName: "home"},
-		},
-	}
-
-	// Test enrichment directly
-	svc.enrichEnvelope(event)
-
-	if event.IdempotencyKey == "" {
-		t.Error("enrichEnvelope() should generate idempotency_key when empty")
-	}
-}
-
 // TestIngestEventBatch_AllInvalid verifies batch with only invalid events.
 // Note: Testing mixed valid/invalid batches requires a mock publisher which the
 // current architecture doesn't support (Publisher is a concrete struct).
@@ -491,64 +472,50 @@ func TestIngestEventBatch_AllInvalid(t *testing.T) {
 	}
 }
 
-// TestValidateEvent verifies the event validation logic.
-func TestValidateEvent(t *testing.T) {
-	svc := NewEventService(nil, nil, 0, nil)
+// TestValidateRequest_EventScope verifies that events outside the API key's
+// allowed event types are rejected with ErrEventTypeNotAllowed.
+func TestValidateRequest_EventScope(t *testing.T) {
+	ctx := context.WithValue(context.Background(), auth.EventScopeContextKey, []string{"commerce", "user.login"})
 
 	tests := []struct {
 		name    string
-		event   *pb.EventEnvelope
+		payload func(*pb.EventEnvelope)
 		wantErr error
 	}{
 		{
-			name: "valid event",
-			event: &pb.EventEnvelope{
-				AppId:       "test-app",
-				TimestampMs: time.Now().UnixMilli(),
-				Payload: &pb.EventEnvelope_ScreenView{
-					ScreenView: &pb.ScreenView{ScreenName: "home"},
-				},
+			name: "allowed category",
+			payload: func(e *pb.EventEnvelope) {
+				e.Payload = &pb.EventEnvelope_PurchaseComplete{PurchaseComplete: &pb.PurchaseComplete{OrderId: "o-1"}}
 			},
-			wantErr: nil,
 		},
 		{
-			name: "missing app_id",
-			event: &pb.EventEnvelope{
-				AppId:       "",
-				TimestampMs: time.Now().UnixMilli(),
-				Payload: &pb.EventEnvelope_ScreenView{
-					ScreenView: &pb.ScreenView{ScreenName: "home"},
-				},
+			name: "allowed type",
+			payload: func(e *pb.EventEnvelope) {
+				e.Payload = &pb.EventEnvelope_UserLogin{UserLogin: &pb.UserLogin{UserId: "u-1"}}
 			},
-			wantErr: ErrAppIDRequired,
 		},
 		{
-			name: "missing payload",
-			event: &pb.EventEnvelope{
-				AppId:       "test-app",
-				TimestampMs: time.Now().UnixMilli(),
-				Payload:     nil,
+			name: "other type in category",
+			payload: func(e *pb.EventEnvelope) {
+				e.Payload = &pb.EventEnvelope_UserLogout{UserLogout: &pb.UserLogout{UserId: "u-1"}}
 			},
-			wantErr: ErrEventTypeRequired,
+			wantErr: ErrEventTypeNotAllowed,
 		},
 		{
-			name: "missing timestamp",
-			event: &pb.EventEnvelope{
-				AppId:       "test-app",
-				TimestampMs: 0,
-				Payload: &pb.EventEnvelope_ScreenView{
-					ScreenView: &pb.ScreenView{ScreenName: "home"},
-				},
+			name: "other category",
+			payload: func(e *pb.EventEnvelope) {
+				e.Payload = &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}}
 			},
-			wantErr: ErrTimestampRequired,
+			wantErr: ErrEventTypeNotAllowed,
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			err := svc.validateEvent(tc.event)
-			if !errors.Is(err, tc.wantErr) {
-				t.Errorf("validateEvent() error = %v, wantErr %v", err, tc.wantErr)
+			event := &pb.EventEnvelope{AppId: "test-app", TimestampMs: time.Now().UnixMilli()}
+			tc.payload(event)
+			if err := validateRequest(ctx, event); !errors.Is(err, tc.wantErr) {
+				t.Errorf("validateRequest() error = %v, wantErr %v", err, tc.wantErr)
 			}
 		})
 	}
@@ -570,8 +537,8 @@ func TestIngestEventBatch_BatchTooLarge(t *testing.T) {
 	if err == nil {
 		t.Error("IngestEventBatch() should return error for batch exceeding max size")
 	}
-	if !errors.Is(err, ErrBatchTooLarge) {
-		t.Errorf("IngestEventBatch() error = %v, want ErrBatchTooLarge", err)
+	if !errors.Is(err, ingest.ErrBatchTooLarge) {
+		t.Errorf("IngestEventBatch() error = %v, want ingest.ErrBatchTooLarge", err)
 	}
 }
 
@@ -587,8 +554,8 @@ func TestIngestEventBatch_EmptyBatch(t *testing.T) {
 	if err == nil {
 		t.Error("IngestEventBatch() should return error for empty batch")
 	}
-	if !errors.Is(err, ErrAtLeastOneEvent) {
-		t.Errorf("IngestEventBatch() error = %v, want ErrAtLeastOneEvent", err)
+	if !errors.Is(err, ingest.ErrAtLeastOneEvent) {
+		t.Errorf("IngestEventBatch() error = %v, want ingest.ErrAtLeastOneEvent", err)
 	}
 }
 
@@ -693,9 +660,9 @@ func TestIngestEvent_WithDedup_DuplicateSkipsPublish(t *testing.T) {
 		t.Fatalf("IngestEvent() returned unexpected error: %v", err)
 	}
 
-	// Should return success (silently dropped)
-	if resp.Status != "accepted" {
-		t.Errorf("Response status = %q, want %q", resp.Status, "accepted")
+	// Should return success, flagged as deduplicated
+	if resp.Status != ingest.StatusDeduplicated {
+		t.Errorf("Response status = %q, want %q", resp.Status, ingest.StatusDeduplicated)
 	}
 
 	// Verify event was NOT published (it's a duplicate)
@@ -851,6 +818,14 @@ func TestIngestEventBatch_WithDedup_FiltersDuplicates(t *testing.T) {
 	if pub.publishedEvents[0].GetIdempotencyKey() != "unique-key-1" {
 		t.Errorf("Published event idempotency_key = %q, want unique-key-1", pub.publishedEvents[0].GetIdempotencyKey())
 	}
+
+	// Per-event results distinguish stored from deduplicated events
+	wantStatuses := []string{ingest.StatusDeduplicated, ingest.StatusAccepted, ingest.StatusDeduplicated}
+	for i, want := range wantStatuses {
+		if got := resp.Results[i].GetStatus(); got != want {
+			t.Errorf("Results[%d].Status = %q, want %q", i, got, want)
+		}
+	}
 }
 
 // TestIngestEventBatch_PublishError_ReturnsRejected verifies publish failures in batch.
@@ -914,6 +889,76 @@ func TestIngestEventBatch_PublishError_ReturnsRejected(t *testing.T) {
 }
 
 
+// blockingPublisher blocks each publish until its context is done.
+type blockingPublisher struct{}
+
+func (blockingPublisher) PublishEvent(ctx context.Context, _ *pb.EventEnvelope) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// TestIngestEvent_PublishTimeout_ReturnsErrPublishTimeout verifies a publish
+// outlasting the deadline is reported as a timeout.
+func TestIngestEvent_PublishTimeout_ReturnsErrPublishTimeout(t *testing.T) {
+	svc := newEventService(ingest.Config{PublishTimeout: 20 * time.Millisecond}, blockingPublisher{}, nil, ingest.Options{})
+
+	req := &pb.IngestEventRequest{
+		Event: &pb.EventEnvelope{
+			AppId:       "test-app",
+			TimestampMs: time.Now().UnixMilli(),
+			Payload:     &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}},
+		},
+	}
+
+	_, err := svc.IngestEvent(context.Background(), req)
+	if !errors.Is(err, ingest.ErrPublishTimeout) {
+		t.Fatalf("IngestEvent() error = %v, want ingest.ErrPublishTimeout", err)
+	}
+}
+
+// TestIngestEventBatch_PublishTimeout_SkipsDedup verifies events past the
+// deadline are reported as publish_timeout without recording their
+// idempotency keys, so a retry is not dropped as a duplicate.
+func TestIngestEventBatch_PublishTimeout_SkipsDedup(t *testing.T) {
+	dedup := newMockDedupChecker()
+	svc := newEventService(ingest.Config{PublishTimeout: 20 * time.Millisecond}, blockingPublisher{}, nil, ingest.Options{Dedup: dedup})
+
+	req := &pb.IngestEventBatchRequest{
+		Events: []*pb.EventEnvelope{
+			{
+				AppId:          "test-app",
+				TimestampMs:    time.Now().UnixMilli(),
+				IdempotencyKey: "key-1",
+				Payload:        &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}},
+			},
+			{
+				AppId:          "test-app",
+				TimestampMs:    time.Now().UnixMilli(),
+				IdempotencyKey: "key-2",
+				Payload:        &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "profile"}},
+			},
+		},
+	}
+
+	resp, err := svc.IngestEventBatch(context.Background(), req)
+	if err != nil {
+		t.Fatalf("IngestEventBatch() returned unexpected error: %v", err)
+	}
+	if resp.AcceptedCount != 0 || resp.RejectedCount != 2 {
+		t.Errorf("counts = %d accepted, %d rejected, want 0 and 2", resp.AcceptedCount, resp.RejectedCount)
+	}
+	for i, r := range resp.Results {
+		if r.Status != ingest.StatusPublishTimeout {
+			t.Errorf("Results[%d].Status = %q, want %q", i, r.Status, ingest.StatusPublishTimeout)
+		}
+	}
+
+	// The second event never reached the dedup check
+	if dedup.duplicateKeys["key-2"] {
+		t.Error("idempotency key of an unattempted event was recorded")
+	}
+}
+
 // TestIngestEventBatch_AllValid_AllPublished verifies all valid events are published.
 func TestIngestEventBatch_AllValid_AllPublished(t *testing.T) {
 	pub := newMockPublisher()
@@ -966,46 +1011,3 @@ func TestIngestEventBatch_AllValid_AllPublished(t *testing.T) {
 		}
 	}
 }
-
-// TestEnrichEnvelope_GeneratesIdempotencyKey verifies idempotency key generation.
-func TestEnrichEnvelope_GeneratesIdempotencyKey(t *testing.T) {
-	svc := NewEventServiceWithPublisher(nil, nil, 0, nil)
-
-	event := &pb.EventEnvelope{
-		AppId:          "test-app",
-		TimestampMs:    time.Now().UnixMilli(),
-		IdempotencyKey: "", // Empty - should be generated
-		Payload:        &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}},
-	}
-
-	svc.enrichEnvelope(event)
-
-	if event.IdempotencyKey == "" {
-		t.Error("enrichEnvelope() should generate idempotency_key when empty")
-	}
-
-	// Verify it's a valid UUID format (36 chars with dashes)
-	if len(event.IdempotencyKey) != 36 {
-		t.Errorf("Generated idempotency_key length = %d, want 36 (UUID format)", len(event.IdempotencyKey))
-	}
-}
-
-// TestEnrichEnvelope_PreservesExistingIdempotencyKey verifies existing keys are preserved.
-func TestEnrichEnvelope_PreservesExistingIdempotencyKey(t *testing.T) {
-	svc := NewEventServiceWithPublisher(nil, nil, 0, nil)
-
-	existingKey := "user-provided-key-123"
-	event := &pb.EventEnvelope{
-		AppId:          "test-app",
-		TimestampMs:    time.Now().UnixMilli(),
-		IdempotencyKey: existingKey,
-		Payload:        &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}},
-	}
-
-	svc.enrichEnvelope(event)
-
-	if event.IdempotencyKey != existingKey {
-		t.Errorf("enrichEnvelope() should preserve existing idempotency_key, got %q, want %q",
-			event.IdempotencyKey, existingKey)
-	}
-}
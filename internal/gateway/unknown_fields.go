@@ -0,0 +1,232 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/SebastienMelki/causality/internal/observability"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// ingestRequests maps the ingestion endpoints to their request messages.
+var ingestRequests = ingestRequestDescriptors()
+
+// envelopeDescriptor is the message whose unknown fields are kept in extras.
+var envelopeDescriptor = (&pb.EventEnvelope{}).ProtoReflect().Descriptor()
+
+// Values of the field attribute of the gateway.events.unknown_fields metric
+// for fields not nested in a declared envelope field.
+const (
+	unknownFieldEnvelope = "envelope"
+	unknownFieldRequest  = "request"
+)
+
+// UnknownFields captures JSON fields the event schema does not declare
+// before the generated handlers reject the request for them. Fields inside an
+// event are moved into the envelope's extras map, keyed by their path within
+// the envelope, with their raw JSON value; fields outside any event are
+// dropped. Each field is counted by gateway.events.unknown_fields under the
+// envelope field it was nested in (e.g. "button_tap"), so a client sending
+// fields the gateway predates shows up in metrics instead of losing data.
+//
+// Binary protobuf bodies pass through: proto.Unmarshal already keeps
+// unknown fields.
+func UnknownFields(metrics *observability.Metrics, logger *slog.Logger) Middleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger = logger.With("component", "unknown-fields")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			md, ok := ingestRequests[r.URL.Path]
+			if !ok || r.Method != http.MethodPost || !isJSONBody(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			r.Body.Close()
+			if err != nil {
+				auditFromContext(r.Context()).fail(err.Error())
+				http.Error(w, err.Error(), decodeErrorStatus(err))
+				return
+			}
+
+			rewritten, fields := captureUnknownFields(body, md)
+			if len(fields) > 0 {
+				body = rewritten
+				recordUnknownFields(r, metrics, logger, fields)
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+			r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isJSONBody reports whether the generated handlers decode r's body as JSON,
+// which they do for every content type but binary protobuf.
+func isJSONBody(r *http.Request) bool {
+	switch mediaType(r.Header.Get("Content-Type")) {
+	case pb.BinaryContentType, pb.ProtoContentType:
+		return false
+	default:
+		return true
+	}
+}
+
+// unknownField is a JSON field not declared by the schema.
+type unknownField struct {
+	// path is the field's dotted path within its envelope, or within the
+	// request when it is outside any envelope.
+	path string
+	// parent is the envelope field the unknown field is nested in, or
+	// unknownFieldEnvelope / unknownFieldRequest at the top level.
+	parent string
+}
+
+// captureUnknownFields strips the fields md does not declare from a JSON
+// request body and returns the rewritten body with the fields it found.
+// Bodies that are not a JSON object are returned unchanged, for the generated
+// handlers to reject.
+func captureUnknownFields(body []byte, md protoreflect.MessageDescriptor) ([]byte, []unknownField) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil || obj == nil {
+		return body, nil
+	}
+
+	var fields []unknownField
+	stripUnknown(obj, md, "", nil, &fields)
+	if len(fields) == 0 {
+		return body, nil
+	}
+
+	rewritten, err := json.Marshal(obj)
+	if err != nil {
+		return body, nil
+	}
+	return rewritten, fields
+}
+
+// stripUnknown removes the fields of obj that md does not declare, recursing
+// into message fields. Fields removed from an event are recorded in its
+// extras map; outside an event, extras is nil and they are only reported.
+func stripUnknown(obj map[string]interface{}, md protoreflect.MessageDescriptor, path string, extras map[string]interface{}, fields *[]unknownField) {
+	if md.FullName() == envelopeDescriptor.FullName() {
+		extras, _ = obj["extras"].(map[string]interface{})
+		if extras == nil {
+			extras = make(map[string]interface{})
+		}
+		path = ""
+		defer func() {
+			if len(extras) > 0 {
+				obj["extras"] = extras
+			}
+		}()
+	}
+
+	for key, value := range obj {
+		fd := fieldByJSONKey(md, key)
+		if fd != nil {
+			stripNested(value, fd, path+key+".", extras, fields)
+			continue
+		}
+
+		field := unknownField{path: path + key, parent: unknownFieldRequest}
+		if extras != nil {
+			field.parent = envelopeField(field.path)
+			if _, exists := extras[field.path]; !exists {
+				raw, _ := json.Marshal(value)
+				extras[field.path] = string(raw)
+			}
+		}
+		*fields = append(*fields, field)
+		delete(obj, key)
+	}
+}
+
+// stripNested recurses into the messages held by a declared field.
+func stripNested(value interface{}, fd protoreflect.FieldDescriptor, path string, extras map[string]interface{}, fields *[]unknownField) {
+	switch {
+	case fd.IsMap():
+		md := fd.MapValue().Message()
+		entries, _ := value.(map[string]interface{})
+		if md == nil || isWellKnown(md) {
+			return
+		}
+		for key, entry := range entries {
+			if nested, ok := entry.(map[string]interface{}); ok {
+				stripUnknown(nested, md, path+key+".", extras, fields)
+			}
+		}
+	case fd.Message() == nil || isWellKnown(fd.Message()):
+	case fd.IsList():
+		items, _ := value.([]interface{})
+		for i, item := range items {
+			if nested, ok := item.(map[string]interface{}); ok {
+				stripUnknown(nested, fd.Message(), path+strconv.Itoa(i)+".", extras, fields)
+			}
+		}
+	default:
+		if nested, ok := value.(map[string]interface{}); ok {
+			stripUnknown(nested, fd.Message(), path, extras, fields)
+		}
+	}
+}
+
+// fieldByJSONKey returns the field of md a protojson key names, either by its
+// JSON name or its proto name.
+func fieldByJSONKey(md protoreflect.MessageDescriptor, key string) protoreflect.FieldDescriptor {
+	if fd := md.Fields().ByJSONName(key); fd != nil {
+		return fd
+	}
+	return md.Fields().ByName(protoreflect.Name(key))
+}
+
+// isWellKnown reports whether md is a well-known type with a special JSON
+// mapping, whose keys are not fields.
+func isWellKnown(md protoreflect.MessageDescriptor) bool {
+	return md.ParentFile().Package() == "google.protobuf"
+}
+
+// envelopeField returns the proto name of the envelope field a path within
+// an envelope is nested in, or unknownFieldEnvelope for a top-level field.
+func envelopeField(path string) string {
+	key, _, nested := strings.Cut(path, ".")
+	fd := fieldByJSONKey(envelopeDescriptor, key)
+	if !nested || fd == nil {
+		return unknownFieldEnvelope
+	}
+	return string(fd.Name())
+}
+
+// recordUnknownFields counts the captured fields and logs their paths.
+func recordUnknownFields(r *http.Request, metrics *observability.Metrics, logger *slog.Logger, fields []unknownField) {
+	paths := make([]string, len(fields))
+	for i, f := range fields {
+		paths[i] = f.path
+		if metrics != nil {
+			metrics.EventsUnknownFields.Add(r.Context(), 1, otelmetric.WithAttributes(attribute.String("field", f.parent)))
+		}
+	}
+	sort.Strings(paths)
+	logger.Debug("captured unknown fields",
+		"path", r.URL.Path,
+		"fields", paths,
+	)
+}
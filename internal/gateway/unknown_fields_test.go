@@ -0,0 +1,131 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// newUnknownFieldsHandler returns the generated event handlers behind the
+// UnknownFields middleware, publishing into pub.
+func newUnknownFieldsHandler(t *testing.T, pub *mockPublisher) http.Handler {
+	t.Helper()
+	mux := http.NewServeMux()
+	svc := NewEventServiceWithPublisher(pub, nil, 0, nil)
+	if err := pb.RegisterEventServiceServer(svc, pb.WithMux(mux)); err != nil {
+		t.Fatalf("register event service: %v", err)
+	}
+	return Chain(mux, UnknownFields(nil, nil))
+}
+
+func TestUnknownFields_CapturedIntoExtras(t *testing.T) {
+	pub := newMockPublisher()
+	handler := newUnknownFieldsHandler(t, pub)
+
+	now := time.Now().UnixMilli()
+	body := fmt.Sprintf(`{"sentAt":"2026-01-01","events":[
+		{"appId":"test-app","deviceId":"d1","timestampMs":"%d","sessionColor":"blue",
+		 "buttonTap":{"buttonId":"pay","button_name":"Pay now","coordinates":{"x":1,"y":2,"pressure":0.5}}},
+		{"appId":"test-app","deviceId":"d1","timestampMs":"%d","screenView":{"screenName":"home"}}
+	]}`, now, now)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/events/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if len(pub.publishedEvents) != 2 {
+		t.Fatalf("published = %d, want 2", len(pub.publishedEvents))
+	}
+
+	want := map[string]string{
+		"sessionColor":                   `"blue"`,
+		"buttonTap.button_name":          `"Pay now"`,
+		"buttonTap.coordinates.pressure": `0.5`,
+	}
+	tap := pub.publishedEvents[0]
+	if !reflect.DeepEqual(tap.GetExtras(), want) {
+		t.Errorf("extras = %v, want %v", tap.GetExtras(), want)
+	}
+	if got := tap.GetButtonTap().GetButtonId(); got != "pay" {
+		t.Errorf("button_id = %q, want pay", got)
+	}
+	if extras := pub.publishedEvents[1].GetExtras(); len(extras) != 0 {
+		t.Errorf("extras of event without unknown fields = %v, want none", extras)
+	}
+}
+
+func TestUnknownFields_KnownFieldsUnchanged(t *testing.T) {
+	body := []byte(`{"event":{"app_id":"a","deviceId":"d","customEvent":{"eventName":"x","stringParams":{"k":"v"}}}}`)
+	got, fields := captureUnknownFields(body, ingestRequests["/v1/events/ingest"])
+	if len(fields) != 0 {
+		t.Errorf("fields = %v, want none", fields)
+	}
+	if string(got) != string(body) {
+		t.Errorf("body = %s, want it unchanged", got)
+	}
+}
+
+func TestCaptureUnknownFields(t *testing.T) {
+	body := []byte(`{"source":"web","events":[{
+		"appId":"a","deviceId":"d","extras":{"build":"\"42\""},"build":"43",
+		"appCrash":{"breadcrumbs":[{"eventType":"screen_view","screen":"home"}]}
+	}]}`)
+
+	got, fields := captureUnknownFields(body, ingestRequests[batchPath])
+
+	var parents []string
+	for _, f := range fields {
+		parents = append(parents, f.path+"="+f.parent)
+	}
+	sort.Strings(parents)
+	wantParents := []string{
+		"appCrash.breadcrumbs.0.screen=app_crash",
+		"build=envelope",
+		"source=request",
+	}
+	if !reflect.DeepEqual(parents, wantParents) {
+		t.Errorf("fields = %v, want %v", parents, wantParents)
+	}
+
+	var req struct {
+		Source *string `json:"source"`
+		Events []struct {
+			Extras map[string]string `json:"extras"`
+		} `json:"events"`
+	}
+	if err := json.Unmarshal(got, &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Source != nil {
+		t.Error("request-level unknown field was not dropped")
+	}
+	wantExtras := map[string]string{
+		// Extras the client already set win over captured fields.
+		"build":                         `"42"`,
+		"appCrash.breadcrumbs.0.screen": `"home"`,
+	}
+	if !reflect.DeepEqual(req.Events[0].Extras, wantExtras) {
+		t.Errorf("extras = %v, want %v", req.Events[0].Extras, wantExtras)
+	}
+}
+
+func TestCaptureUnknownFields_InvalidJSON(t *testing.T) {
+	body := []byte(`{"events":`)
+	got, fields := captureUnknownFields(body, ingestRequests[batchPath])
+	if len(fields) != 0 || string(got) != string(body) {
+		t.Errorf("captureUnknownFields() = %s, %v, want the body unchanged", got, fields)
+	}
+}
@@ -0,0 +1,129 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// Ingestion API versions. Each version serves its endpoints under
+// /<version>/events/. A version's behavior is frozen once SDKs ship against
+// it; envelope and response changes go into a new version.
+const (
+	// APIVersion1 is the sebuf-generated API used by existing SDKs.
+	APIVersion1 = "v1"
+
+	// APIVersion2 requires plausible client timestamps and returns
+	// structured errors and per-event dedup status (see ingest_v2.go).
+	APIVersion2 = "v2"
+)
+
+// APIVersionHeader is set on every ingestion response to the API version
+// that served it.
+const APIVersionHeader = "Causality-API-Version"
+
+// Ingestion endpoints of every version, relative to its prefix.
+const (
+	ingestEndpoint = "ingest"
+	batchEndpoint  = "batch"
+)
+
+// apiVersionKey is the context key for the API version of a request.
+const apiVersionKey ContextKey = "api_version"
+
+// apiVersion is an ingestion API version.
+type apiVersion struct {
+	name string
+
+	// register registers the version's ingestion handlers on mux under
+	// ingestPrefix(name).
+	register func(mux *http.ServeMux, service *EventService) error
+}
+
+// apiVersions returns the served ingestion API versions.
+func apiVersions() []apiVersion {
+	return []apiVersion{
+		{name: APIVersion1, register: registerV1},
+		{name: APIVersion2, register: registerV2},
+	}
+}
+
+// registerV1 registers the sebuf-generated v1 handlers.
+func registerV1(mux *http.ServeMux, service *EventService) error {
+	return pb.RegisterEventServiceServer(service, pb.WithMux(mux), pb.WithErrorHandler(handleServiceError))
+}
+
+// registerVersions mounts the ingestion handlers of every API version on mux.
+// Each version gets its own mux, so a version's routes cannot shadow
+// another's, and its requests carry the version in their context.
+func registerVersions(mux *http.ServeMux, service *EventService) error {
+	for _, version := range apiVersions() {
+		versionMux := http.NewServeMux()
+		if err := version.register(versionMux, service); err != nil {
+			return err
+		}
+		mux.Handle(ingestPrefix(version.name), withAPIVersion(version.name, versionMux))
+	}
+	return nil
+}
+
+// withAPIVersion tags requests and responses with the API version.
+func withAPIVersion(version string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(APIVersionHeader, version)
+		ctx := context.WithValue(r.Context(), apiVersionKey, version)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// APIVersionFromContext returns the ingestion API version serving the
+// request, or an empty string outside the ingestion endpoints.
+func APIVersionFromContext(ctx context.Context) string {
+	version, _ := ctx.Value(apiVersionKey).(string)
+	return version
+}
+
+// ingestPrefix returns the path prefix of a version's ingestion endpoints.
+func ingestPrefix(version string) string {
+	return "/" + version + "/events/"
+}
+
+// ingestPathVersion returns the API version and endpoint of an ingestion
+// path, reporting false for other paths.
+func ingestPathVersion(path string) (version, endpoint string, ok bool) {
+	for _, v := range apiVersions() {
+		if endpoint, found := strings.CutPrefix(path, ingestPrefix(v.name)); found {
+			return v.name, endpoint, true
+		}
+	}
+	return "", "", false
+}
+
+// isIngestPath reports whether path is under the ingestion endpoints of any
+// API version.
+func isIngestPath(path string) bool {
+	_, _, ok := ingestPathVersion(path)
+	return ok
+}
+
+// isBatchPath reports whether path is the batch endpoint of any API version.
+func isBatchPath(path string) bool {
+	_, endpoint, ok := ingestPathVersion(path)
+	return ok && endpoint == batchEndpoint
+}
+
+// ingestRequestDescriptors maps the ingestion endpoints of every API version
+// to their request messages. All versions share the request messages; they
+// differ in validation and responses.
+func ingestRequestDescriptors() map[string]protoreflect.MessageDescriptor {
+	descriptors := make(map[string]protoreflect.MessageDescriptor)
+	for _, v := range apiVersions() {
+		descriptors[ingestPrefix(v.name)+ingestEndpoint] = (&pb.IngestEventRequest{}).ProtoReflect().Descriptor()
+		descriptors[ingestPrefix(v.name)+batchEndpoint] = (&pb.IngestEventBatchRequest{}).ProtoReflect().Descriptor()
+	}
+	return descriptors
+}
@@ -0,0 +1,178 @@
+// Package geo resolves the coarse location of a device - its ISO 3166-1
+// alpha-2 country code and continental region - from the IANA timezone and
+// locale in its device context. Events carry no client IP, so no IP
+// geolocation database is involved.
+//
+// The timezone is preferred because it reflects where the device is, while
+// the locale reflects the user's language settings. Timezones map to
+// countries through the tzdb zone.tab table, which lists one country per
+// zone.
+package geo
+
+import (
+	_ "embed"
+	"strings"
+)
+
+// Regions returned by Resolve.
+const (
+	RegionAfrica       = "Africa"
+	RegionAntarctica   = "Antarctica"
+	RegionAsia         = "Asia"
+	RegionEurope       = "Europe"
+	RegionNorthAmerica = "North America"
+	RegionOceania      = "Oceania"
+	RegionSouthAmerica = "South America"
+)
+
+//go:embed zone.tab
+var zoneTab string
+
+// zoneCountries maps IANA timezones to country codes, and countryRegions maps
+// every country code in zone.tab to its region.
+var zoneCountries, countryRegions = parseZoneTab(zoneTab)
+
+// zoneAliases maps deprecated timezone names that devices still report to
+// their country, since zone.tab lists canonical names only.
+var zoneAliases = map[string]string{
+	"America/Buenos_Aires": "AR",
+	"America/Godthab":      "GL",
+	"Asia/Calcutta":        "IN",
+	"Asia/Katmandu":        "NP",
+	"Asia/Rangoon":         "MM",
+	"Asia/Saigon":          "VN",
+	"Europe/Kiev":          "UA",
+	"Pacific/Truk":         "FM",
+}
+
+// zonePrefixAliases maps deprecated timezone prefixes to their country.
+var zonePrefixAliases = map[string]string{
+	"US/":     "US",
+	"Canada/": "CA",
+	"Brazil/": "BR",
+	"Mexico/": "MX",
+	"Chile/":  "CL",
+}
+
+// southAmerica lists the countries whose America/ zones are in South America.
+var southAmerica = map[string]bool{
+	"AR": true, "BO": true, "BR": true, "CL": true, "CO": true, "EC": true, "FK": true,
+	"GF": true, "GY": true, "PE": true, "PY": true, "SR": true, "UY": true, "VE": true,
+}
+
+// islandRegions assigns Atlantic/ and Indian/ zone countries to the region
+// they are usually reported with.
+var islandRegions = map[string]string{
+	"BM": RegionNorthAmerica,
+	"CV": RegionAfrica,
+	"SH": RegionAfrica,
+	"FK": RegionSouthAmerica,
+	"GS": RegionSouthAmerica,
+	"FO": RegionEurope,
+	"IS": RegionEurope,
+	"CC": RegionOceania,
+	"CX": RegionOceania,
+	"IO": RegionAsia,
+	"MV": RegionAsia,
+	"KM": RegionAfrica,
+	"MG": RegionAfrica,
+	"MU": RegionAfrica,
+	"RE": RegionAfrica,
+	"SC": RegionAfrica,
+	"YT": RegionAfrica,
+	"TF": RegionAntarctica,
+}
+
+// Resolve returns the country code and region for a device's locale (such as
+// "en_US" or "pt-BR") and IANA timezone (such as "Europe/Paris"). The
+// timezone wins when both resolve; empty strings are returned when neither
+// does.
+func Resolve(locale, timezone string) (country, region string) {
+	country = CountryFromTimezone(timezone)
+	if country == "" {
+		country = CountryFromLocale(locale)
+	}
+	return country, countryRegions[country]
+}
+
+// CountryFromTimezone returns the country of an IANA timezone, or "" for
+// unknown and country-less zones such as "UTC".
+func CountryFromTimezone(timezone string) string {
+	if country, ok := zoneCountries[timezone]; ok {
+		return country
+	}
+	if country, ok := zoneAliases[timezone]; ok {
+		return country
+	}
+	for prefix, country := range zonePrefixAliases {
+		if strings.HasPrefix(timezone, prefix) {
+			return country
+		}
+	}
+	return ""
+}
+
+// CountryFromLocale returns the country of a locale's region subtag, or ""
+// when the locale has no known country. Both "_" and "-" separators are
+// accepted, and script subtags are skipped ("zh_Hant_TW" is TW).
+func CountryFromLocale(locale string) string {
+	parts := strings.FieldsFunc(locale, func(r rune) bool { return r == '_' || r == '-' })
+	for _, part := range parts[min(1, len(parts)):] {
+		if len(part) != 2 {
+			continue
+		}
+		country := strings.ToUpper(part)
+		if _, ok := countryRegions[country]; ok {
+			return country
+		}
+	}
+	return ""
+}
+
+// parseZoneTab parses the embedded zone.tab (country code and timezone,
+// tab-separated). A country's region comes from the area of the first zone
+// listed for it.
+func parseZoneTab(data string) (zones, regions map[string]string) {
+	zones = make(map[string]string)
+	regions = make(map[string]string)
+
+	for _, line := range strings.Split(data, "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		country, zone, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		zones[zone] = country
+		if _, ok := regions[country]; !ok {
+			area, _, _ := strings.Cut(zone, "/")
+			regions[country] = areaRegion(area, country)
+		}
+	}
+
+	return zones, regions
+}
+
+// areaRegion maps a timezone area (the part before the first "/") and its
+// country to a region.
+func areaRegion(area, country string) string {
+	switch area {
+	case "Africa":
+		return RegionAfrica
+	case "Antarctica":
+		return RegionAntarctica
+	case "Asia":
+		return RegionAsia
+	case "Europe", "Arctic":
+		return RegionEurope
+	case "Australia", "Pacific":
+		return RegionOceania
+	case "America":
+		if southAmerica[country] {
+			return RegionSouthAmerica
+		}
+		return RegionNorthAmerica
+	}
+	return islandRegions[country]
+}
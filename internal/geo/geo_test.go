@@ -0,0 +1,41 @@
+package geo
+
+import "testing"
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		locale, timezone string
+		country, region  string
+	}{
+		{"en_US", "Europe/Paris", "FR", RegionEurope},
+		{"en_US", "America/New_York", "US", RegionNorthAmerica},
+		{"pt-BR", "America/Sao_Paulo", "BR", RegionSouthAmerica},
+		{"en_AU", "Australia/Sydney", "AU", RegionOceania},
+		{"ja_JP", "Asia/Tokyo", "JP", RegionAsia},
+		{"en_IN", "Asia/Calcutta", "IN", RegionAsia},
+		{"en_US", "US/Pacific", "US", RegionNorthAmerica},
+		{"is_IS", "Atlantic/Reykjavik", "IS", RegionEurope},
+		{"fr_RE", "Indian/Reunion", "RE", RegionAfrica},
+		// Locale fallback for unknown or country-less timezones.
+		{"de_DE", "UTC", "DE", RegionEurope},
+		{"zh_Hant_TW", "", "TW", RegionAsia},
+		{"es-419", "", "", ""},
+		{"en", "Etc/GMT+3", "", ""},
+		{"", "", "", ""},
+	}
+	for _, tt := range tests {
+		country, region := Resolve(tt.locale, tt.timezone)
+		if country != tt.country || region != tt.region {
+			t.Errorf("Resolve(%q, %q) = %q, %q, want %q, %q",
+				tt.locale, tt.timezone, country, region, tt.country, tt.region)
+		}
+	}
+}
+
+func TestEveryCountryHasRegion(t *testing.T) {
+	for country, region := range countryRegions {
+		if region == "" {
+			t.Errorf("country %s has no region", country)
+		}
+	}
+}
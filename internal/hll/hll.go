@@ -0,0 +1,157 @@
+// Package hll implements HyperLogLog sketches counting distinct device and
+// user IDs. Sketches are mergeable and have a compact binary encoding, so
+// counts over long windows are computed from stored sketches of short ones.
+package hll
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// Precision is the number of hash bits selecting a register. 2^12 registers give a standard error of about 1.6%. Sketches
+// can only be merged with sketches of the same precision, so changing it
+// invalidates stored sketches.
+const Precision = 12
+
+const (
+	sketchRegisters = 1 << Precision
+	sketchVersion   = 1
+	sketchDense     = 0
+	sketchSparse    = 1
+)
+
+// ErrInvalidSketch is returned when decoding malformed sketch bytes.
+var ErrInvalidSketch = errors.New("invalid HyperLogLog sketch")
+
+// Sketch is a HyperLogLog sketch estimating the number of distinct IDs
+// added. Sketches of different windows merge into the sketch of their
+// union, so daily distinct counts are computed from hourly sketches.
+type Sketch struct {
+	registers [sketchRegisters]uint8
+}
+
+// New returns an empty sketch.
+func New() *Sketch {
+	return &Sketch{}
+}
+
+// Add records an ID.
+func (s *Sketch) Add(id string) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(id))
+	x := mix64(h.Sum64())
+
+	index := x >> (64 - Precision)
+	// The guard bit bounds the rank when the remaining bits are all zero.
+	rank := uint8(bits.LeadingZeros64(x<<Precision|1<<(Precision-1)) + 1)
+	if rank > s.registers[index] {
+		s.registers[index] = rank
+	}
+}
+
+// Merge adds the IDs of other to s.
+func (s *Sketch) Merge(other *Sketch) {
+	for i, r := range other.registers {
+		if r > s.registers[i] {
+			s.registers[i] = r
+		}
+	}
+}
+
+// Estimate returns the estimated number of distinct IDs added.
+func (s *Sketch) Estimate() int64 {
+	const m = float64(sketchRegisters)
+	alpha := 0.7213 / (1 + 1.079/m)
+
+	var sum float64
+	zeros := 0
+	for _, r := range s.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	estimate := alpha * m * m / sum
+	// Linear counting is more accurate while many registers are empty.
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int64(math.Round(estimate))
+}
+
+// MarshalBinary encodes the sketch. Sketches of few IDs are stored as their
+// non-zero registers, so sketches of small groups stay small.
+func (s *Sketch) MarshalBinary() ([]byte, error) {
+	nonZero := 0
+	for _, r := range s.registers {
+		if r != 0 {
+			nonZero++
+		}
+	}
+
+	if 3*nonZero >= sketchRegisters {
+		out := make([]byte, 0, 3+sketchRegisters)
+		out = append(out, sketchVersion, Precision, sketchDense)
+		return append(out, s.registers[:]...), nil
+	}
+
+	out := make([]byte, 0, 3+3*nonZero)
+	out = append(out, sketchVersion, Precision, sketchSparse)
+	for i, r := range s.registers {
+		if r != 0 {
+			out = binary.BigEndian.AppendUint16(out, uint16(i))
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// UnmarshalBinary decodes a sketch encoded by MarshalBinary.
+func (s *Sketch) UnmarshalBinary(data []byte) error {
+	if len(data) < 3 || data[0] != sketchVersion {
+		return ErrInvalidSketch
+	}
+	if data[1] != Precision {
+		return fmt.Errorf("%w: precision %d, want %d", ErrInvalidSketch, data[1], Precision)
+	}
+
+	s.registers = [sketchRegisters]uint8{}
+	body := data[3:]
+	switch data[2] {
+	case sketchDense:
+		if len(body) != sketchRegisters {
+			return ErrInvalidSketch
+		}
+		copy(s.registers[:], body)
+	case sketchSparse:
+		if len(body)%3 != 0 {
+			return ErrInvalidSketch
+		}
+		for i := 0; i < len(body); i += 3 {
+			index := binary.BigEndian.Uint16(body[i:])
+			if int(index) >= sketchRegisters {
+				return ErrInvalidSketch
+			}
+			s.registers[index] = body[i+2]
+		}
+	default:
+		return ErrInvalidSketch
+	}
+	return nil
+}
+
+// mix64 is the MurmurHash3 finalizer, spreading FNV's weak low-entropy
+// output over all 64 bits.
+func mix64(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb3fe1a85ec53
+	x ^= x >> 33
+	return x
+}
@@ -0,0 +1,89 @@
+package hll
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestSketch_Estimate(t *testing.T) {
+	for _, n := range []int{0, 1, 100, 5000, 200000} {
+		s := New()
+		for i := 0; i < n; i++ {
+			s.Add(fmt.Sprintf("device-%d", i))
+			// Repeats do not count.
+			s.Add(fmt.Sprintf("device-%d", i))
+		}
+
+		got := s.Estimate()
+		if n == 0 {
+			if got != 0 {
+				t.Errorf("Estimate() of empty sketch = %d, want 0", got)
+			}
+			continue
+		}
+		if relErr := math.Abs(float64(got)-float64(n)) / float64(n); relErr > 0.05 {
+			t.Errorf("Estimate() of %d devices = %d, error %.1f%%", n, got, relErr*100)
+		}
+	}
+}
+
+func TestSketch_Merge(t *testing.T) {
+	a, b, union := New(), New(), New()
+	for i := 0; i < 3000; i++ {
+		id := fmt.Sprintf("device-%d", i)
+		if i < 2000 {
+			a.Add(id)
+		}
+		if i >= 1000 {
+			b.Add(id)
+		}
+		union.Add(id)
+	}
+
+	a.Merge(b)
+	if a.Estimate() != union.Estimate() {
+		t.Errorf("merged Estimate() = %d, want %d", a.Estimate(), union.Estimate())
+	}
+}
+
+func TestSketch_MarshalBinary(t *testing.T) {
+	for _, n := range []int{0, 10, 50000} {
+		s := New()
+		for i := 0; i < n; i++ {
+			s.Add(fmt.Sprintf("device-%d", i))
+		}
+
+		data, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary() error = %v", err)
+		}
+		if n == 10 && len(data) > 3+3*10 {
+			t.Errorf("sketch of 10 devices is %d bytes, want sparse encoding", len(data))
+		}
+
+		decoded := New()
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Fatalf("UnmarshalBinary() error = %v", err)
+		}
+		if *decoded != *s {
+			t.Errorf("round trip of %d devices changed the sketch", n)
+		}
+	}
+}
+
+func TestSketch_UnmarshalInvalid(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"empty":     nil,
+		"version":   {2, Precision, sketchSparse},
+		"precision": {sketchVersion, 14, sketchSparse},
+		"truncated": {sketchVersion, Precision, sketchSparse, 0, 1},
+		"index":     {sketchVersion, Precision, sketchSparse, 0xff, 0xff, 1},
+		"dense":     {sketchVersion, Precision, sketchDense, 1, 2, 3},
+	} {
+		if err := New().UnmarshalBinary(data); !errors.Is(err, ErrInvalidSketch) {
+			t.Errorf("%s: UnmarshalBinary() error = %v, want ErrInvalidSketch", name, err)
+		}
+	}
+}
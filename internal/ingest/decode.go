@@ -0,0 +1,70 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// DelimitedBatchContentType is a stream of varint length-prefixed
+// EventEnvelope messages. It lets the SDK append events to the body without
+// building an IngestEventBatchRequest in memory.
+const DelimitedBatchContentType = "application/x-causality-batch"
+
+// DecodeBatch decodes an IngestEventBatchRequest in the encoding named by
+// contentType: JSON (also when empty), binary protobuf or
+// DelimitedBatchContentType.
+func DecodeBatch(contentType string, data []byte) (*pb.IngestEventBatchRequest, error) {
+	req := &pb.IngestEventBatchRequest{}
+	switch contentType {
+	case "", "application/json":
+		if err := protojson.Unmarshal(data, req); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBatchBody, err)
+		}
+	case pb.ProtoContentType:
+		if err := proto.Unmarshal(data, req); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBatchBody, err)
+		}
+	case DelimitedBatchContentType:
+		events, err := ReadDelimitedEvents(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		req.Events = events
+	default:
+		return nil, fmt.Errorf("%w: content type %q", ErrUnsupportedBatchEncoding, contentType)
+	}
+	return req, nil
+}
+
+// ReadDelimitedEvents reads varint length-prefixed EventEnvelopes until EOF.
+// An *http.MaxBytesError from body is returned as is.
+func ReadDelimitedEvents(body io.Reader) ([]*pb.EventEnvelope, error) {
+	reader := bufio.NewReader(body)
+
+	var events []*pb.EventEnvelope
+	for {
+		event := &pb.EventEnvelope{}
+		err := protodelim.UnmarshalFrom(reader, event)
+		if errors.Is(err, io.EOF) {
+			return events, nil
+		}
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: event %d: %v", ErrInvalidBatchBody, len(events), err)
+		}
+		events = append(events, event)
+	}
+}
@@ -0,0 +1,35 @@
+package ingest
+
+import "errors"
+
+// Sentinel errors for event ingestion.
+var (
+	ErrEventRequired   = errors.New("event is required")
+	ErrAtLeastOneEvent = errors.New("at least one event is required")
+
+	// Validation errors
+	ErrAppIDRequired     = errors.New("app_id is required")
+	ErrEventTypeRequired = errors.New("event_type is required (payload must not be empty)")
+	ErrTimestampRequired = errors.New("timestamp_ms is required and must be > 0")
+	ErrBatchTooLarge     = errors.New("batch exceeds maximum event count")
+
+	// ErrPublishTimeout means the event could not be published within the
+	// publish timeout. Callers should retry it.
+	ErrPublishTimeout = errors.New("publish timed out")
+
+	// ErrPublishFailed means NATS rejected or failed to store the event.
+	ErrPublishFailed = errors.New("failed to publish event")
+)
+
+// Event limit errors (see Limiter). Messages start with the rejection code.
+var (
+	ErrEventTooLarge     = errors.New(LimitCodeEventTooLarge + ": event exceeds maximum size")
+	ErrTooManyProperties = errors.New(LimitCodeTooManyProperties + ": custom event has too many properties")
+	ErrPropertyTooDeep   = errors.New(LimitCodePropertyTooDeep + ": custom event property is nested too deep")
+)
+
+// Batch decoding errors.
+var (
+	ErrUnsupportedBatchEncoding = errors.New("unsupported batch encoding")
+	ErrInvalidBatchBody         = errors.New("invalid batch body")
+)
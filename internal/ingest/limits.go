@@ -0,0 +1,169 @@
+package ingest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// Event limit rejection codes. They prefix the rejection error message and
+// are the code attribute of the gateway.events.limited metric.
+const (
+	LimitCodeEventTooLarge     = "event_too_large"
+	LimitCodeTooManyProperties = "too_many_properties"
+	LimitCodePropertyTooDeep   = "property_too_deep"
+)
+
+// LimitsConfig holds per-event limits. Zero disables a limit.
+type LimitsConfig struct {
+	// MaxBytes is the maximum serialized size of an event in bytes
+	MaxBytes int `env:"MAX_BYTES" envDefault:"65536"`
+
+	// MaxProperties is the maximum number of custom_event parameters
+	MaxProperties int `env:"MAX_PROPERTIES" envDefault:"256"`
+
+	// MaxPropertyDepth is the maximum number of dot-separated segments in a
+	// custom_event parameter key
+	MaxPropertyDepth int `env:"MAX_PROPERTY_DEPTH" envDefault:"8"`
+
+	// AppsFile is a JSON file of per-app overrides keyed by app ID, read at
+	// startup, e.g. {"app-1": {"max_bytes": 131072}}
+	AppsFile string `env:"APPS_FILE"`
+}
+
+// Limits bounds the size of a single event. Zero means no limit.
+type Limits struct {
+	// MaxBytes is the maximum serialized (protobuf) size of the event envelope.
+	MaxBytes int `json:"max_bytes,omitempty"`
+
+	// MaxProperties is the maximum number of custom_event parameters across
+	// all parameter types.
+	MaxProperties int `json:"max_properties,omitempty"`
+
+	// MaxPropertyDepth is the maximum nesting of custom_event parameter keys,
+	// counted as dot-separated path segments ("cart.item.sku" is 3 deep).
+	MaxPropertyDepth int `json:"max_property_depth,omitempty"`
+}
+
+// merge returns l with the non-zero fields of override applied.
+func (l Limits) merge(override Limits) Limits {
+	if override.MaxBytes != 0 {
+		l.MaxBytes = override.MaxBytes
+	}
+	if override.MaxProperties != 0 {
+		l.MaxProperties = override.MaxProperties
+	}
+	if override.MaxPropertyDepth != 0 {
+		l.MaxPropertyDepth = override.MaxPropertyDepth
+	}
+	return l
+}
+
+// Limiter enforces per-event limits, with optional per-app overrides.
+type Limiter struct {
+	defaults Limits
+	apps     map[string]Limits
+}
+
+// NewLimiter creates a limiter from configuration. Per-app overrides
+// are read once from cfg.AppsFile, a JSON object keyed by app ID whose
+// values set any of max_bytes, max_properties and max_property_depth.
+func NewLimiter(cfg LimitsConfig) (*Limiter, error) {
+	limiter := &Limiter{
+		defaults: Limits{
+			MaxBytes:         cfg.MaxBytes,
+			MaxProperties:    cfg.MaxProperties,
+			MaxPropertyDepth: cfg.MaxPropertyDepth,
+		},
+	}
+	if cfg.AppsFile == "" {
+		return limiter, nil
+	}
+
+	data, err := os.ReadFile(cfg.AppsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event limits file: %w", err)
+	}
+
+	// Unknown fields are rejected so typos do not silently fall back to defaults
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&limiter.apps); err != nil {
+		return nil, fmt.Errorf("failed to decode event limits file %s: %w", cfg.AppsFile, err)
+	}
+
+	return limiter, nil
+}
+
+// Limits returns the limits applied to events of appID.
+func (l *Limiter) Limits(appID string) Limits {
+	if override, ok := l.apps[appID]; ok {
+		return l.defaults.merge(override)
+	}
+	return l.defaults
+}
+
+// Check returns an error wrapping ErrEventTooLarge, ErrTooManyProperties or
+// ErrPropertyTooDeep if the event exceeds its app's limits, and the matching
+// rejection code.
+func (l *Limiter) Check(event *pb.EventEnvelope) (string, error) {
+	limits := l.Limits(event.GetAppId())
+
+	if limits.MaxBytes > 0 {
+		if size := proto.Size(event); size > limits.MaxBytes {
+			return LimitCodeEventTooLarge, fmt.Errorf("%w: %d bytes, limit %d", ErrEventTooLarge, size, limits.MaxBytes)
+		}
+	}
+
+	custom := event.GetCustomEvent()
+	if custom == nil {
+		return "", nil
+	}
+
+	if limits.MaxProperties > 0 {
+		count := len(custom.GetStringParams()) + len(custom.GetIntParams()) +
+			len(custom.GetFloatParams()) + len(custom.GetBoolParams())
+		if count > limits.MaxProperties {
+			return LimitCodeTooManyProperties, fmt.Errorf("%w: %d properties, limit %d", ErrTooManyProperties, count, limits.MaxProperties)
+		}
+	}
+
+	if limits.MaxPropertyDepth > 0 {
+		if key, depth := deepestKey(custom); depth > limits.MaxPropertyDepth {
+			return LimitCodePropertyTooDeep, fmt.Errorf("%w: %q is %d deep, limit %d", ErrPropertyTooDeep, key, depth, limits.MaxPropertyDepth)
+		}
+	}
+
+	return "", nil
+}
+
+// deepestKey returns the custom_event parameter key with the most
+// dot-separated segments, and its depth.
+func deepestKey(custom *pb.CustomEvent) (string, int) {
+	var deepest string
+	maxDepth := 0
+	visit := func(key string) {
+		if depth := strings.Count(key, ".") + 1; depth > maxDepth {
+			deepest, maxDepth = key, depth
+		}
+	}
+	for key := range custom.GetStringParams() {
+		visit(key)
+	}
+	for key := range custom.GetIntParams() {
+		visit(key)
+	}
+	for key := range custom.GetFloatParams() {
+		visit(key)
+	}
+	for key := range custom.GetBoolParams() {
+		visit(key)
+	}
+	return deepest, maxDepth
+}
@@ -0,0 +1,127 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// customEvent returns a custom event of appID with the given string params.
+func customEvent(appID string, params map[string]string) *pb.EventEnvelope {
+	return &pb.EventEnvelope{
+		AppId:       appID,
+		TimestampMs: time.Now().UnixMilli(),
+		Payload: &pb.EventEnvelope_CustomEvent{CustomEvent: &pb.CustomEvent{
+			EventName:    "custom",
+			StringParams: params,
+		}},
+	}
+}
+
+// TestLimiter_Check verifies each limit and its rejection code.
+func TestLimiter_Check(t *testing.T) {
+	limiter, err := NewLimiter(LimitsConfig{MaxBytes: 1024, MaxProperties: 3, MaxPropertyDepth: 2})
+	if err != nil {
+		t.Fatalf("NewLimiter() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		event    *pb.EventEnvelope
+		wantCode string
+		wantErr  error
+	}{
+		{
+			name:  "within limits",
+			event: customEvent("app", map[string]string{"a": "1", "cart.sku": "2"}),
+		},
+		{
+			name:     "too large",
+			event:    customEvent("app", map[string]string{"a": strings.Repeat("x", 2048)}),
+			wantCode: LimitCodeEventTooLarge,
+			wantErr:  ErrEventTooLarge,
+		},
+		{
+			name:     "too many properties",
+			event:    customEvent("app", map[string]string{"a": "1", "b": "2", "c": "3", "d": "4"}),
+			wantCode: LimitCodeTooManyProperties,
+			wantErr:  ErrTooManyProperties,
+		},
+		{
+			name:     "too deep",
+			event:    customEvent("app", map[string]string{"cart.item.sku": "1"}),
+			wantCode: LimitCodePropertyTooDeep,
+			wantErr:  ErrPropertyTooDeep,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := limiter.Check(tc.event)
+			if !errors.Is(err, tc.wantErr) || code != tc.wantCode {
+				t.Errorf("Check() = %q, %v, want %q, %v", code, err, tc.wantCode, tc.wantErr)
+			}
+			if err != nil && !strings.HasPrefix(err.Error(), tc.wantCode+":") {
+				t.Errorf("error %q should start with its code", err)
+			}
+		})
+	}
+}
+
+// TestLimiter_AppOverrides verifies per-app overrides replace only the
+// limits they set.
+func TestLimiter_AppOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	if err := os.WriteFile(path, []byte(`{"big-app": {"max_properties": 10}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	limiter, err := NewLimiter(LimitsConfig{MaxBytes: 1024, MaxProperties: 2, AppsFile: path})
+	if err != nil {
+		t.Fatalf("NewLimiter() error = %v", err)
+	}
+
+	if got := limiter.Limits("big-app"); got.MaxProperties != 10 || got.MaxBytes != 1024 {
+		t.Errorf("Limits(big-app) = %+v, want max_properties 10 and default max_bytes", got)
+	}
+
+	params := map[string]string{"a": "1", "b": "2", "c": "3"}
+	if _, err := limiter.Check(customEvent("big-app", params)); err != nil {
+		t.Errorf("Check(big-app) error = %v, want nil", err)
+	}
+	if _, err := limiter.Check(customEvent("other-app", params)); !errors.Is(err, ErrTooManyProperties) {
+		t.Errorf("Check(other-app) error = %v, want %v", err, ErrTooManyProperties)
+	}
+}
+
+// TestNewLimiter_InvalidFile verifies unknown override fields are rejected.
+func TestNewLimiter_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	if err := os.WriteFile(path, []byte(`{"app": {"max_byte": 10}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewLimiter(LimitsConfig{AppsFile: path}); err == nil {
+		t.Error("NewLimiter() should reject unknown fields")
+	}
+}
+
+// TestValidateEvent_Limits verifies validateEvent enforces event limits.
+func TestValidateEvent_Limits(t *testing.T) {
+	limiter, err := NewLimiter(LimitsConfig{MaxProperties: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewService(Config{}, nil, nil, &Options{Limits: limiter})
+
+	event := customEvent("app", map[string]string{"a": "1", "b": "2"})
+	if err := svc.validateEvent(context.Background(), event); !errors.Is(err, ErrTooManyProperties) {
+		t.Errorf("validateEvent() error = %v, want %v", err, ErrTooManyProperties)
+	}
+}
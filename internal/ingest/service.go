@@ -0,0 +1,400 @@
+// Package ingest implements event ingestion shared by the gateway and the
+// other ingestion paths: batch decoding, validation, per-event limits,
+// enrichment, deduplication and publishing.
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+
+	"github.com/SebastienMelki/causality/internal/events"
+	"github.com/SebastienMelki/causality/internal/observability"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// Event ingestion statuses reported in IngestEventResponse.Status and
+// EventResult.Status.
+const (
+	// StatusAccepted means the event was published.
+	StatusAccepted = "accepted"
+
+	// StatusDeduplicated means the event repeats an idempotency key seen
+	// within the dedup window and was dropped. It counts as accepted so
+	// clients treat it as delivered and do not retry.
+	StatusDeduplicated = "deduplicated"
+
+	// StatusRejected means the event failed validation or publishing.
+	StatusRejected = "rejected"
+
+	// StatusPublishTimeout means the event was valid but could not be
+	// published before the request's publish deadline. It counts as rejected;
+	// clients should retry it.
+	StatusPublishTimeout = "publish_timeout"
+)
+
+// DedupChecker checks whether an idempotency key has been seen before.
+// Implementations must be safe for concurrent use.
+type DedupChecker interface {
+	// IsDuplicate returns true if the given key was already seen within
+	// the dedup window. An empty key always returns false.
+	IsDuplicate(key string) bool
+}
+
+// Publisher abstracts the NATS publisher for testing.
+type Publisher interface {
+	// PublishEvent publishes an event to the message queue.
+	PublishEvent(ctx context.Context, event *pb.EventEnvelope) error
+}
+
+// Config holds the settings of a Service.
+type Config struct {
+	// MaxBatchEvents is the maximum number of events in a batch (0 means
+	// no limit).
+	MaxBatchEvents int
+
+	// PublishTimeout bounds publishing per request; zero means no deadline.
+	PublishTimeout time.Duration
+}
+
+// Options holds the optional dependencies of a Service.
+type Options struct {
+	// Dedup drops events whose idempotency key was already seen.
+	Dedup DedupChecker
+
+	// Limits enforces per-event limits.
+	Limits *Limiter
+
+	// Metrics counts limited events and publish timeouts.
+	Metrics *observability.Metrics
+
+	// Validate adds checks of the ingestion path, run after the required
+	// fields are checked and before the schema version and limits.
+	Validate func(ctx context.Context, event *pb.EventEnvelope) error
+
+	// Enrich adds values of the ingestion path, run after the
+	// server-generated values are set.
+	Enrich func(ctx context.Context, event *pb.EventEnvelope)
+}
+
+// Service validates, enriches, deduplicates and publishes events.
+type Service struct {
+	publisher      Publisher
+	maxBatchEvents int
+	publishTimeout time.Duration
+	opts           Options
+	logger         *slog.Logger
+}
+
+// NewService creates an ingest service publishing with publisher. opts may
+// be nil.
+func NewService(cfg Config, publisher Publisher, logger *slog.Logger, opts *Options) *Service {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if opts == nil {
+		opts = &Options{}
+	}
+	return &Service{
+		publisher:      publisher,
+		maxBatchEvents: cfg.MaxBatchEvents,
+		publishTimeout: cfg.PublishTimeout,
+		opts:           *opts,
+		logger:         logger.With("component", "event-service"),
+	}
+}
+
+// IngestEvent ingests a single event.
+func (s *Service) IngestEvent(ctx context.Context, event *pb.EventEnvelope) (*pb.IngestEventResponse, error) {
+	if event == nil {
+		return nil, ErrEventRequired
+	}
+
+	// Validate required fields
+	if err := s.validateEvent(ctx, event); err != nil {
+		return nil, err
+	}
+
+	// Enrich envelope with server-generated values
+	s.enrichEvent(ctx, event)
+	ctx = withEventLogAttrs(ctx, event)
+	logger := observability.Logger(ctx, s.logger)
+
+	// Check for duplicate (after enrich so idempotency_key is set)
+	if s.opts.Dedup != nil && s.opts.Dedup.IsDuplicate(event.GetIdempotencyKey()) {
+		logger.Debug("duplicate event silently dropped",
+			"idempotency_key", event.GetIdempotencyKey(),
+		)
+		// Report success to client without publishing
+		return &pb.IngestEventResponse{
+			EventId: event.GetId(),
+			Status:  StatusDeduplicated,
+		}, nil
+	}
+
+	// Publish to NATS
+	publishCtx, cancel := s.publishContext(ctx, time.Now())
+	defer cancel()
+	if err := s.publisher.PublishEvent(publishCtx, event); err != nil {
+		if publishTimedOut(ctx, publishCtx) {
+			logger.Warn("event publish timed out", "timeout", s.publishTimeout)
+			s.recordPublishTimeouts(ctx, 1)
+			return nil, fmt.Errorf("%w after %s: %w", ErrPublishTimeout, s.publishTimeout, err)
+		}
+		logger.Error("failed to publish event", "error", err)
+		return nil, fmt.Errorf("%w: %w", ErrPublishFailed, err)
+	}
+
+	logger.Debug("event ingested")
+
+	return &pb.IngestEventResponse{
+		EventId: event.GetId(),
+		Status:  StatusAccepted,
+	}, nil
+}
+
+// IngestBatch ingests the events of a batch. Alongside the response it
+// returns the error each rejected event was rejected with, by index, so
+// that callers can tell publish failures (ErrPublishFailed,
+// ErrPublishTimeout), worth retrying, from invalid events, and report
+// errors in their own format.
+func (s *Service) IngestBatch(ctx context.Context, req *pb.IngestEventBatchRequest) (*pb.IngestEventBatchResponse, []error, error) {
+	if len(req.GetEvents()) == 0 {
+		return nil, nil, ErrAtLeastOneEvent
+	}
+
+	// Check batch size limit
+	if s.maxBatchEvents > 0 && len(req.GetEvents()) > s.maxBatchEvents {
+		return nil, nil, ErrBatchTooLarge
+	}
+
+	results := make([]*pb.EventResult, len(req.GetEvents()))
+	errs := make([]error, len(req.GetEvents()))
+	acceptedCount := int32(0)
+	rejectedCount := int32(0)
+	deduplicatedCount := 0
+	timedOutCount := 0
+
+	// All publishes in the batch share one deadline, so a slow NATS cannot
+	// hold the request open for longer than the publish timeout.
+	publishStart := time.Now()
+
+	for i, event := range req.GetEvents() {
+		result := &pb.EventResult{
+			Index: int32(i), //nolint:gosec // Index is bounded by batch size which is well under int32 max.
+		}
+
+		// Validate: nil event
+		if event == nil {
+			result.Status = StatusRejected
+			result.Error = "event is nil"
+			errs[i] = ErrEventRequired
+			rejectedCount++
+			results[i] = result
+			continue
+		}
+
+		// Validate required fields; skip invalid events
+		if err := s.validateEvent(ctx, event); err != nil {
+			result.Status = StatusRejected
+			result.Error = err.Error()
+			errs[i] = err
+			rejectedCount++
+			results[i] = result
+			continue
+		}
+
+		// Enrich
+		s.enrichEvent(ctx, event)
+		eventCtx := withEventLogAttrs(ctx, event)
+		logger := observability.Logger(eventCtx, s.logger)
+
+		// Once the publish deadline has passed, report the remaining events
+		// as timed out without reaching the dedup check, which would record
+		// their idempotency keys and drop the client's retry as a duplicate.
+		publishCtx, cancel := s.publishContext(eventCtx, publishStart)
+		if publishTimedOut(eventCtx, publishCtx) {
+			cancel()
+			setPublishTimeout(result)
+			errs[i] = ErrPublishTimeout
+			rejectedCount++
+			timedOutCount++
+			results[i] = result
+			continue
+		}
+
+		// Dedup check
+		if s.opts.Dedup != nil && s.opts.Dedup.IsDuplicate(event.GetIdempotencyKey()) {
+			cancel()
+			// Drop duplicates; they count as accepted so clients don't retry
+			result.EventId = event.GetId()
+			result.Status = StatusDeduplicated
+			acceptedCount++
+			deduplicatedCount++
+			results[i] = result
+			logger.Debug("duplicate event in batch silently dropped",
+				"index", i,
+				"idempotency_key", event.GetIdempotencyKey(),
+			)
+			continue
+		}
+
+		// Publish to NATS. An event still in flight at the deadline may have
+		// been stored; a retry of it is then correctly deduplicated.
+		err := s.publisher.PublishEvent(publishCtx, event)
+		timedOut := err != nil && publishTimedOut(eventCtx, publishCtx)
+		cancel()
+
+		switch {
+		case timedOut:
+			setPublishTimeout(result)
+			errs[i] = ErrPublishTimeout
+			rejectedCount++
+			timedOutCount++
+		case err != nil:
+			result.Status = StatusRejected
+			result.Error = err.Error()
+			errs[i] = fmt.Errorf("%w: %w", ErrPublishFailed, err)
+			rejectedCount++
+			logger.Warn("failed to publish event in batch",
+				"index", i,
+				"error", err,
+			)
+		default:
+			result.EventId = event.GetId()
+			result.Status = StatusAccepted
+			acceptedCount++
+		}
+
+		results[i] = result
+	}
+
+	if timedOutCount > 0 {
+		s.recordPublishTimeouts(ctx, timedOutCount)
+		observability.Logger(ctx, s.logger).Warn("batch publish timed out",
+			"timeout", s.publishTimeout,
+			"timed_out", timedOutCount,
+		)
+	}
+
+	observability.Logger(ctx, s.logger).Info("batch ingestion complete",
+		"total", len(req.GetEvents()),
+		"accepted", acceptedCount,
+		"deduplicated", deduplicatedCount,
+		"rejected", rejectedCount,
+		"publish_timeout", timedOutCount,
+	)
+
+	return &pb.IngestEventBatchResponse{
+		AcceptedCount: acceptedCount,
+		RejectedCount: rejectedCount,
+		Results:       results,
+	}, errs, nil
+}
+
+// validateEvent checks that an event has all required fields, passes the
+// ingestion path's checks, has a supported schema version and is within its
+// app's event limits.
+func (s *Service) validateEvent(ctx context.Context, event *pb.EventEnvelope) error {
+	if event.GetAppId() == "" {
+		return ErrAppIDRequired
+	}
+	if event.GetPayload() == nil {
+		return ErrEventTypeRequired
+	}
+	if event.GetTimestampMs() <= 0 {
+		return ErrTimestampRequired
+	}
+	if s.opts.Validate != nil {
+		if err := s.opts.Validate(ctx, event); err != nil {
+			return err
+		}
+	}
+	if err := events.CheckSchemaVersion(event); err != nil {
+		return err
+	}
+	if s.opts.Limits != nil {
+		if code, err := s.opts.Limits.Check(event); err != nil {
+			if s.opts.Metrics != nil {
+				s.opts.Metrics.EventsLimited.Add(ctx, 1, otelmetric.WithAttributes(attribute.String("code", code)))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// enrichEvent adds the server-generated values and those of the ingestion
+// path to an event.
+func (s *Service) enrichEvent(ctx context.Context, event *pb.EventEnvelope) {
+	enrichEnvelope(event)
+	if s.opts.Enrich != nil {
+		s.opts.Enrich(ctx, event)
+	}
+}
+
+// publishContext returns the context for publishing an event of a request
+// whose publishing started at start. It carries the publish deadline when a
+// publish timeout is configured.
+func (s *Service) publishContext(ctx context.Context, start time.Time) (context.Context, context.CancelFunc) {
+	if s.publishTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, start.Add(s.publishTimeout))
+}
+
+// setPublishTimeout marks a batch result as not published in time.
+func setPublishTimeout(result *pb.EventResult) {
+	result.Status = StatusPublishTimeout
+	result.Error = ErrPublishTimeout.Error()
+}
+
+// publishTimedOut reports whether a publish failed because the publish
+// deadline passed, rather than the client going away or NATS failing.
+func publishTimedOut(parent, publishCtx context.Context) bool {
+	return parent.Err() == nil && errors.Is(publishCtx.Err(), context.DeadlineExceeded)
+}
+
+// recordPublishTimeouts counts events not published within the deadline.
+func (s *Service) recordPublishTimeouts(ctx context.Context, n int) {
+	if s.opts.Metrics != nil {
+		s.opts.Metrics.EventsPublishTimeout.Add(ctx, int64(n))
+	}
+}
+
+// withEventLogAttrs attaches the event's ID and app to ctx for scoped logging.
+func withEventLogAttrs(ctx context.Context, event *pb.EventEnvelope) context.Context {
+	return observability.WithLogAttrs(ctx,
+		observability.LogKeyEventID, event.GetId(),
+		observability.LogKeyAppID, event.GetAppId(),
+	)
+}
+
+// enrichEnvelope adds server-generated values to the event envelope.
+func enrichEnvelope(event *pb.EventEnvelope) {
+	// Generate UUID v7 if not provided (time-sortable)
+	if event.GetId() == "" {
+		event.Id = uuid.Must(uuid.NewV7()).String()
+	}
+
+	// Set timestamp if not provided
+	if event.GetTimestampMs() == 0 {
+		event.TimestampMs = time.Now().UnixMilli()
+	}
+
+	// Generate idempotency key if not provided
+	if event.GetIdempotencyKey() == "" {
+		event.IdempotencyKey = uuid.New().String()
+	}
+
+	// Stamp the schema version of clients predating schema versioning
+	if event.GetSchemaVersion() == 0 {
+		event.SchemaVersion = events.SchemaVersionLegacy
+	}
+}
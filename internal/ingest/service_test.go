@@ -0,0 +1,270 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/events"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// fakePublisher records published events and fails every publish with err
+// when set.
+type fakePublisher struct {
+	published []*pb.EventEnvelope
+	err       error
+}
+
+func (f *fakePublisher) PublishEvent(_ context.Context, event *pb.EventEnvelope) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.published = append(f.published, event)
+	return nil
+}
+
+// screenView returns a valid screen view event of appID.
+func screenView(appID string) *pb.EventEnvelope {
+	return &pb.EventEnvelope{
+		AppId:       appID,
+		TimestampMs: time.Now().UnixMilli(),
+		Payload:     &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}},
+	}
+}
+
+// TestService_IngestBatch_Errors verifies the error of each rejected event
+// tells invalid events from publish failures.
+func TestService_IngestBatch_Errors(t *testing.T) {
+	publisher := &fakePublisher{err: errors.New("nats down")}
+	svc := NewService(Config{}, publisher, nil, nil)
+
+	req := &pb.IngestEventBatchRequest{Events: []*pb.EventEnvelope{screenView(""), screenView("app-1")}}
+	resp, errs, err := svc.IngestBatch(context.Background(), req)
+	if err != nil {
+		t.Fatalf("IngestBatch() error = %v", err)
+	}
+	if resp.GetRejectedCount() != 2 {
+		t.Errorf("RejectedCount = %d, want 2", resp.GetRejectedCount())
+	}
+	if !errors.Is(errs[0], ErrAppIDRequired) {
+		t.Errorf("errs[0] = %v, want %v", errs[0], ErrAppIDRequired)
+	}
+	if !errors.Is(errs[1], ErrPublishFailed) {
+		t.Errorf("errs[1] = %v, want %v", errs[1], ErrPublishFailed)
+	}
+}
+
+// TestService_IngestBatch_BatchErrors verifies batches rejected as a whole.
+func TestService_IngestBatch_BatchErrors(t *testing.T) {
+	svc := NewService(Config{MaxBatchEvents: 1}, &fakePublisher{}, nil, nil)
+
+	tests := []struct {
+		name    string
+		events  []*pb.EventEnvelope
+		wantErr error
+	}{
+		{"empty", nil, ErrAtLeastOneEvent},
+		{"too large", []*pb.EventEnvelope{screenView("app-1"), screenView("app-1")}, ErrBatchTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := svc.IngestBatch(context.Background(), &pb.IngestEventBatchRequest{Events: tt.events})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("IngestBatch() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestService_Hooks verifies the ingestion path's checks run after the
+// required fields are checked and its enrichment after the
+// server-generated values are set.
+func TestService_Hooks(t *testing.T) {
+	errBlocked := errors.New("blocked")
+	var validated []string
+	publisher := &fakePublisher{}
+	svc := NewService(Config{}, publisher, nil, &Options{
+		Validate: func(_ context.Context, event *pb.EventEnvelope) error {
+			validated = append(validated, event.GetAppId())
+			if event.GetAppId() == "blocked" {
+				return errBlocked
+			}
+			return nil
+		},
+		Enrich: func(_ context.Context, event *pb.EventEnvelope) {
+			if event.GetId() == "" {
+				t.Error("Enrich called before the event ID was set")
+			}
+			event.DeviceId = "enriched"
+		},
+	})
+
+	req := &pb.IngestEventBatchRequest{Events: []*pb.EventEnvelope{screenView(""), screenView("blocked"), screenView("app-1")}}
+	_, errs, err := svc.IngestBatch(context.Background(), req)
+	if err != nil {
+		t.Fatalf("IngestBatch() error = %v", err)
+	}
+	if !errors.Is(errs[0], ErrAppIDRequired) || !errors.Is(errs[1], errBlocked) || errs[2] != nil {
+		t.Errorf("errs = %v, want [ErrAppIDRequired, blocked, nil]", errs)
+	}
+	if len(validated) != 2 {
+		t.Errorf("Validate called for %v, want the two events with an app ID", validated)
+	}
+	if len(publisher.published) != 1 || publisher.published[0].GetDeviceId() != "enriched" {
+		t.Errorf("published %v, want one enriched event", publisher.published)
+	}
+}
+
+// TestEnrichEnvelope_IdempotencyKeyGenerated verifies that events without idempotency_key get one assigned.
+func TestEnrichEnvelope_IdempotencyKeyGenerated(t *testing.T) {
+	event := &pb.EventEnvelope{
+		AppId:          "test-app",
+		TimestampMs:    time.Now().UnixMilli(),
+		IdempotencyKey: "", // Empty - should be generated
+		Payload: &pb.EventEnvelope_ScreenView{
+			ScreenView: &pb.ScreenView{ScreenName: "home"},
+		},
+	}
+
+	// Test enrichment directly
+	enrichEnvelope(event)
+
+	if event.IdempotencyKey == "" {
+		t.Error("enrichEnvelope() should generate idempotency_key when empty")
+	}
+}
+
+// TestValidateEvent verifies the event validation logic.
+func TestValidateEvent(t *testing.T) {
+	svc := NewService(Config{}, nil, nil, nil)
+
+	tests := []struct {
+		name    string
+		event   *pb.EventEnvelope
+		wantErr error
+	}{
+		{
+			name: "valid event",
+			event: &pb.EventEnvelope{
+				AppId:       "test-app",
+				TimestampMs: time.Now().UnixMilli(),
+				Payload: &pb.EventEnvelope_ScreenView{
+					ScreenView: &pb.ScreenView{ScreenName: "home"},
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "missing app_id",
+			event: &pb.EventEnvelope{
+				AppId:       "",
+				TimestampMs: time.Now().UnixMilli(),
+				Payload: &pb.EventEnvelope_ScreenView{
+					ScreenView: &pb.ScreenView{ScreenName: "home"},
+				},
+			},
+			wantErr: ErrAppIDRequired,
+		},
+		{
+			name: "missing payload",
+			event: &pb.EventEnvelope{
+				AppId:       "test-app",
+				TimestampMs: time.Now().UnixMilli(),
+				Payload:     nil,
+			},
+			wantErr: ErrEventTypeRequired,
+		},
+		{
+			name: "missing timestamp",
+			event: &pb.EventEnvelope{
+				AppId:       "test-app",
+				TimestampMs: 0,
+				Payload: &pb.EventEnvelope_ScreenView{
+					ScreenView: &pb.ScreenView{ScreenName: "home"},
+				},
+			},
+			wantErr: ErrTimestampRequired,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := svc.validateEvent(context.Background(), tc.event)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("validateEvent() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// TestEnrichEnvelope_GeneratesIdempotencyKey verifies idempotency key generation.
+func TestEnrichEnvelope_GeneratesIdempotencyKey(t *testing.T) {
+	event := &pb.EventEnvelope{
+		AppId:          "test-app",
+		TimestampMs:    time.Now().UnixMilli(),
+		IdempotencyKey: "", // Empty - should be generated
+		Payload:        &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}},
+	}
+
+	enrichEnvelope(event)
+
+	if event.IdempotencyKey == "" {
+		t.Error("enrichEnvelope() should generate idempotency_key when empty")
+	}
+
+	// Verify it's a valid UUID format (36 chars with dashes)
+	if len(event.IdempotencyKey) != 36 {
+		t.Errorf("Generated idempotency_key length = %d, want 36 (UUID format)", len(event.IdempotencyKey))
+	}
+}
+
+// TestEnrichEnvelope_PreservesExistingIdempotencyKey verifies existing keys are preserved.
+func TestEnrichEnvelope_PreservesExistingIdempotencyKey(t *testing.T) {
+	existingKey := "user-provided-key-123"
+	event := &pb.EventEnvelope{
+		AppId:          "test-app",
+		TimestampMs:    time.Now().UnixMilli(),
+		IdempotencyKey: existingKey,
+		Payload:        &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}},
+	}
+
+	enrichEnvelope(event)
+
+	if event.IdempotencyKey != existingKey {
+		t.Errorf("enrichEnvelope() should preserve existing idempotency_key, got %q, want %q",
+			event.IdempotencyKey, existingKey)
+	}
+}
+
+// TestEnrichEnvelope_StampsSchemaVersion verifies envelopes without a schema
+// version are stamped as legacy and versioned envelopes are preserved.
+func TestEnrichEnvelope_StampsSchemaVersion(t *testing.T) {
+	for _, tc := range []struct{ sent, want uint32 }{
+		{sent: 0, want: events.SchemaVersionLegacy},
+		{sent: events.CurrentSchemaVersion, want: events.CurrentSchemaVersion},
+	} {
+		event := &pb.EventEnvelope{SchemaVersion: tc.sent}
+		enrichEnvelope(event)
+		if event.SchemaVersion != tc.want {
+			t.Errorf("enrichEnvelope() schema_version %d = %d, want %d", tc.sent, event.SchemaVersion, tc.want)
+		}
+	}
+}
+
+// TestValidateEvent_UnsupportedSchemaVersion verifies envelopes newer than
+// the gateway supports are rejected.
+func TestValidateEvent_UnsupportedSchemaVersion(t *testing.T) {
+	svc := NewService(Config{}, nil, nil, nil)
+
+	event := &pb.EventEnvelope{
+		AppId:         "test-app",
+		TimestampMs:   time.Now().UnixMilli(),
+		SchemaVersion: events.CurrentSchemaVersion + 1,
+		Payload:       &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}},
+	}
+	if err := svc.validateEvent(context.Background(), event); !errors.Is(err, events.ErrUnsupportedSchemaVersion) {
+		t.Errorf("validateEvent() error = %v, want %v", err, events.ErrUnsupportedSchemaVersion)
+	}
+}
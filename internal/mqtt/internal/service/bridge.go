@@ -0,0 +1,192 @@
+// Package service implements the MQTT bridge: mapping MQTT messages to
+// event batches for the shared ingest service.
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/SebastienMelki/causality/internal/ingest"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// Payload formats of MQTT messages.
+const (
+	// FormatJSON payloads are a JSON EventEnvelope, or an
+	// IngestEventBatchRequest when they have an "events" field.
+	FormatJSON = "json"
+
+	// FormatProtobuf payloads are a binary IngestEventBatchRequest.
+	FormatProtobuf = "protobuf"
+
+	// FormatDelimited payloads are length-delimited binary EventEnvelopes.
+	FormatDelimited = "delimited"
+)
+
+// ErrUnknownFormat is returned for an unsupported payload format.
+var ErrUnknownFormat = errors.New("unknown MQTT payload format")
+
+// Ingester validates, enriches, deduplicates and publishes event batches.
+// It is satisfied by *ingest.Service.
+type Ingester interface {
+	IngestBatch(ctx context.Context, req *pb.IngestEventBatchRequest) (*pb.IngestEventBatchResponse, []error, error)
+}
+
+// TopicMapping fills event fields from topic levels. Levels are 1-based;
+// 0 disables a mapping.
+type TopicMapping struct {
+	AppIDLevel    int
+	DeviceIDLevel int
+}
+
+// Bridge ingests the events of MQTT messages.
+type Bridge struct {
+	ingester    Ingester
+	contentType string
+	mapping     TopicMapping
+	logger      *slog.Logger
+}
+
+// NewBridge creates a bridge decoding payloads in format.
+func NewBridge(ingester Ingester, format string, mapping TopicMapping, logger *slog.Logger) (*Bridge, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	var contentType string
+	switch format {
+	case FormatJSON, "":
+		contentType = "application/json"
+	case FormatProtobuf:
+		contentType = pb.ProtoContentType
+	case FormatDelimited:
+		contentType = ingest.DelimitedBatchContentType
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+
+	return &Bridge{
+		ingester:    ingester,
+		contentType: contentType,
+		mapping:     mapping,
+		logger:      logger.With("component", "mqtt-bridge"),
+	}, nil
+}
+
+// Handle ingests the events of a message. Messages that cannot be decoded
+// or are rejected as a whole are logged and dropped, since redelivering
+// them cannot succeed. It returns an error only when events failed to
+// publish, so the message is not acknowledged and the broker redelivers it;
+// events already accepted are then deduplicated by their idempotency key.
+func (b *Bridge) Handle(ctx context.Context, msg Message) error {
+	req, err := b.decode(msg.Payload)
+	if err != nil {
+		b.logger.Warn("dropping undecodable MQTT message",
+			"topic", msg.Topic,
+			"error", err,
+		)
+		return nil
+	}
+	b.applyTopic(msg.Topic, req)
+
+	resp, errs, err := b.ingester.IngestBatch(ctx, req)
+	if err != nil {
+		b.logger.Warn("MQTT message rejected",
+			"topic", msg.Topic,
+			"error", err,
+		)
+		return nil
+	}
+
+	var publishErr error
+	for i, eventErr := range errs {
+		switch {
+		case eventErr == nil:
+		case errors.Is(eventErr, ingest.ErrPublishFailed), errors.Is(eventErr, ingest.ErrPublishTimeout):
+			publishErr = eventErr
+		default:
+			b.logger.Warn("MQTT event rejected",
+				"topic", msg.Topic,
+				"index", i,
+				"error", eventErr,
+			)
+		}
+	}
+	if publishErr != nil {
+		return publishErr
+	}
+
+	b.logger.Debug("MQTT message ingested",
+		"topic", msg.Topic,
+		"accepted", resp.GetAcceptedCount(),
+		"rejected", resp.GetRejectedCount(),
+	)
+	return nil
+}
+
+// decode decodes a payload into a batch.
+func (b *Bridge) decode(payload []byte) (*pb.IngestEventBatchRequest, error) {
+	if b.contentType != "application/json" || isBatch(payload) {
+		return ingest.DecodeBatch(b.contentType, payload)
+	}
+
+	event := &pb.EventEnvelope{}
+	if err := protojson.Unmarshal(payload, event); err != nil {
+		return nil, fmt.Errorf("%w: %v", ingest.ErrInvalidBatchBody, err)
+	}
+	return &pb.IngestEventBatchRequest{Events: []*pb.EventEnvelope{event}}, nil
+}
+
+// isBatch reports whether a JSON payload is an object with an "events"
+// field rather than a single event.
+func isBatch(payload []byte) bool {
+	if !bytes.HasPrefix(bytes.TrimSpace(payload), []byte("{")) {
+		return false
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return false
+	}
+	_, ok := fields["events"]
+	return ok
+}
+
+// applyTopic fills the app and device IDs that events leave empty from the
+// levels of topic, so devices can publish to per-app and per-device topics
+// without repeating them in every event.
+func (b *Bridge) applyTopic(topic string, req *pb.IngestEventBatchRequest) {
+	levels := strings.Split(topic, "/")
+	appID := topicLevel(levels, b.mapping.AppIDLevel)
+	deviceID := topicLevel(levels, b.mapping.DeviceIDLevel)
+	if appID == "" && deviceID == "" {
+		return
+	}
+
+	for _, event := range req.GetEvents() {
+		if event == nil {
+			continue
+		}
+		if event.GetAppId() == "" {
+			event.AppId = appID
+		}
+		if event.GetDeviceId() == "" {
+			event.DeviceId = deviceID
+		}
+	}
+}
+
+// topicLevel returns the 1-based level of a topic, or "" when it is
+// disabled or out of range.
+func topicLevel(levels []string, level int) string {
+	if level <= 0 || level > len(levels) {
+		return ""
+	}
+	return levels[level-1]
+}
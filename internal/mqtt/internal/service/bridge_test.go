@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/SebastienMelki/causality/internal/ingest"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// fakeIngester records ingested batches and fails events as configured.
+type fakeIngester struct {
+	batches  []*pb.IngestEventBatchRequest
+	eventErr error
+	batchErr error
+}
+
+func (f *fakeIngester) IngestBatch(_ context.Context, req *pb.IngestEventBatchRequest) (*pb.IngestEventBatchResponse, []error, error) {
+	f.batches = append(f.batches, req)
+	if f.batchErr != nil {
+		return nil, nil, f.batchErr
+	}
+	errs := make([]error, len(req.GetEvents()))
+	for i := range errs {
+		errs[i] = f.eventErr
+	}
+	return &pb.IngestEventBatchResponse{}, errs, nil
+}
+
+func testEvent(appID string) *pb.EventEnvelope {
+	return &pb.EventEnvelope{
+		AppId:       appID,
+		TimestampMs: 1740830400000,
+		Payload: &pb.EventEnvelope_ScreenView{
+			ScreenView: &pb.ScreenView{ScreenName: "home"},
+		},
+	}
+}
+
+func newTestBridge(t *testing.T, ingester Ingester, format string, mapping TopicMapping) *Bridge {
+	t.Helper()
+	bridge, err := NewBridge(ingester, format, mapping, nil)
+	if err != nil {
+		t.Fatalf("NewBridge() error = %v", err)
+	}
+	return bridge
+}
+
+func TestBridge_Payloads(t *testing.T) {
+	single, err := protojson.Marshal(testEvent("app-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := &pb.IngestEventBatchRequest{Events: []*pb.EventEnvelope{testEvent("app-1"), testEvent("app-1")}}
+	jsonBatch, err := protojson.Marshal(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binaryBatch, err := proto.Marshal(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		format  string
+		payload []byte
+		want    int
+	}{
+		{"json event", FormatJSON, single, 1},
+		{"json batch", FormatJSON, jsonBatch, 2},
+		{"protobuf batch", FormatProtobuf, binaryBatch, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ingester := &fakeIngester{}
+			bridge := newTestBridge(t, ingester, tt.format, TopicMapping{})
+			if err := bridge.Handle(context.Background(), Message{Topic: "causality/app-1/events", Payload: tt.payload}); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if len(ingester.batches) != 1 || len(ingester.batches[0].GetEvents()) != tt.want {
+				t.Fatalf("ingested %v, want one batch of %d events", ingester.batches, tt.want)
+			}
+		})
+	}
+}
+
+func TestBridge_TopicMapping(t *testing.T) {
+	withApp := testEvent("app-own")
+	withApp.DeviceId = "device-own"
+	payload, err := protojson.Marshal(&pb.IngestEventBatchRequest{Events: []*pb.EventEnvelope{testEvent(""), withApp}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ingester := &fakeIngester{}
+	bridge := newTestBridge(t, ingester, FormatJSON, TopicMapping{AppIDLevel: 2, DeviceIDLevel: 3})
+	if err := bridge.Handle(context.Background(), Message{Topic: "causality/app-1/device-9/events", Payload: payload}); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	events := ingester.batches[0].GetEvents()
+	if events[0].GetAppId() != "app-1" || events[0].GetDeviceId() != "device-9" {
+		t.Errorf("mapped event = %s/%s, want app-1/device-9", events[0].GetAppId(), events[0].GetDeviceId())
+	}
+	if events[1].GetAppId() != "app-own" || events[1].GetDeviceId() != "device-own" {
+		t.Errorf("event with IDs = %s/%s, want them kept", events[1].GetAppId(), events[1].GetDeviceId())
+	}
+}
+
+func TestBridge_Errors(t *testing.T) {
+	valid, err := protojson.Marshal(testEvent("app-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		ingester *fakeIngester
+		payload  []byte
+		wantErr  error
+	}{
+		{"undecodable payload dropped", &fakeIngester{}, []byte("{not json"), nil},
+		{"rejected batch dropped", &fakeIngester{batchErr: ingest.ErrBatchTooLarge}, valid, nil},
+		{"invalid event dropped", &fakeIngester{eventErr: ingest.ErrAppIDRequired}, valid, nil},
+		{"publish failure redelivered", &fakeIngester{eventErr: ingest.ErrPublishFailed}, valid, ingest.ErrPublishFailed},
+		{"publish timeout redelivered", &fakeIngester{eventErr: ingest.ErrPublishTimeout}, valid, ingest.ErrPublishTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bridge := newTestBridge(t, tt.ingester, FormatJSON, TopicMapping{})
+			err := bridge.Handle(context.Background(), Message{Topic: "causality/app-1/events", Payload: tt.payload})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("Handle() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewBridge_UnknownFormat(t *testing.T) {
+	if _, err := NewBridge(&fakeIngester{}, "xml", TopicMapping{}, nil); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("NewBridge() error = %v, want ErrUnknownFormat", err)
+	}
+}
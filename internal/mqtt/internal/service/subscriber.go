@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// errSubscriptionRefused is returned when the broker refuses a topic filter.
+var errSubscriptionRefused = errors.New("MQTT subscription refused")
+
+// subscribeFailure is the SUBACK return code of a refused topic filter.
+const subscribeFailure = 0x80
+
+// SubscriberOptions configures the broker connection of a Subscriber.
+type SubscriberOptions struct {
+	// Client holds the paho connection options. The subscriber sets the
+	// message and connection-lost handlers and disables automatic
+	// acknowledgements and reconnections.
+	Client *paho.ClientOptions
+
+	// Topics are the topic filters subscribed to, at QoS.
+	Topics []string
+	QoS    byte
+
+	// MinBackoff and MaxBackoff bound the exponential backoff between
+	// connection attempts.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Message is an MQTT message received on a subscription.
+type Message struct {
+	Topic   string
+	Payload []byte
+}
+
+// Subscriber keeps a subscription to the broker open, reconnecting with
+// exponential backoff, and passes its messages to the bridge. QoS 1
+// messages are acknowledged once the bridge has handled them; after a
+// handler error the connection is dropped without acknowledging later
+// messages, so the broker redelivers them on the next session.
+type Subscriber struct {
+	bridge *Bridge
+	opts   SubscriberOptions
+	logger *slog.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewSubscriber creates a subscriber connecting with opts.
+func NewSubscriber(bridge *Bridge, opts SubscriberOptions, logger *slog.Logger) *Subscriber {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = time.Second
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = opts.MinBackoff
+	}
+
+	return &Subscriber{
+		bridge: bridge,
+		opts:   opts,
+		logger: logger.With("component", "mqtt-subscriber"),
+	}
+}
+
+// Start begins the connection loop in the background.
+func (s *Subscriber) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(ctx)
+	}()
+}
+
+// Stop disconnects and waits for the message being handled, if any.
+func (s *Subscriber) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+// run connects and serves until ctx is done. The backoff resets once a
+// connection has been established.
+func (s *Subscriber) run(ctx context.Context) {
+	backoff := s.opts.MinBackoff
+	for {
+		connected, err := s.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff = s.opts.MinBackoff
+		}
+
+		s.logger.Warn("MQTT connection lost, reconnecting",
+			"broker", s.broker(),
+			"error", err,
+			"backoff", backoff,
+		)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff = min(backoff*2, s.opts.MaxBackoff)
+	}
+}
+
+// session connects, subscribes and passes messages to the bridge until ctx
+// is done, the connection is lost or the bridge fails. It reports whether
+// the subscription was established, and always returns a non-nil error.
+func (s *Subscriber) session(ctx context.Context) (bool, error) {
+	// done carries the first reason to end the session
+	done := make(chan error, 1)
+	end := func(err error) {
+		select {
+		case done <- err:
+		default:
+		}
+	}
+
+	// Messages are handled one at a time under mu; once closed is set,
+	// later messages are left unacknowledged for redelivery
+	var (
+		mu     sync.Mutex
+		closed bool
+	)
+	handle := func(_ paho.Client, m paho.Message) {
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		msg := Message{Topic: m.Topic(), Payload: m.Payload()}
+		if err := s.bridge.Handle(ctx, msg); err != nil {
+			closed = true
+			end(fmt.Errorf("failed to handle message on %s: %w", msg.Topic, err))
+			return
+		}
+		m.Ack()
+	}
+
+	opts := *s.opts.Client
+	opts.SetAutoReconnect(false).
+		SetConnectRetry(false).
+		SetAutoAckDisabled(true).
+		SetOrderMatters(true).
+		SetDefaultPublishHandler(handle).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			end(err)
+		})
+	client := paho.NewClient(&opts)
+	defer func() {
+		mu.Lock()
+		closed = true
+		mu.Unlock()
+		if client.IsConnectionOpen() {
+			client.Disconnect(250)
+		}
+	}()
+
+	if err := s.wait(ctx, client.Connect()); err != nil {
+		return false, fmt.Errorf("failed to connect: %w", err)
+	}
+
+	// Messages queued in a persistent session may arrive before the SUBACK;
+	// the default handler receives them as well
+	filters := make(map[string]byte, len(s.opts.Topics))
+	for _, topic := range s.opts.Topics {
+		filters[topic] = s.opts.QoS
+	}
+	token := client.SubscribeMultiple(filters, nil)
+	if err := s.wait(ctx, token); err != nil {
+		return false, fmt.Errorf("failed to subscribe: %w", err)
+	}
+	for topic, code := range token.(*paho.SubscribeToken).Result() {
+		if code == subscribeFailure {
+			return false, fmt.Errorf("%w: %s", errSubscriptionRefused, topic)
+		}
+	}
+
+	s.logger.Info("connected to MQTT broker",
+		"broker", s.broker(),
+		"topics", s.opts.Topics,
+	)
+
+	select {
+	case err := <-done:
+		return true, err
+	case <-ctx.Done():
+		return true, ctx.Err()
+	}
+}
+
+// wait waits for a token to complete, up to the connect timeout.
+func (s *Subscriber) wait(ctx context.Context, token paho.Token) error {
+	timeout := s.opts.Client.ConnectTimeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-timer.C:
+		return context.DeadlineExceeded
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// broker returns the broker address for logs.
+func (s *Subscriber) broker() string {
+	if len(s.opts.Client.Servers) == 0 {
+		return ""
+	}
+	return s.opts.Client.Servers[0].String()
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	server "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/SebastienMelki/causality/internal/ingest"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// flakyIngester fails publishing the first failures batches, then accepts.
+type flakyIngester struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+	accepted chan *pb.IngestEventBatchRequest
+}
+
+func (f *flakyIngester) IngestBatch(_ context.Context, req *pb.IngestEventBatchRequest) (*pb.IngestEventBatchResponse, []error, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+
+	errs := make([]error, len(req.GetEvents()))
+	if f.calls <= f.failures {
+		for i := range errs {
+			errs[i] = ingest.ErrPublishFailed
+		}
+		return &pb.IngestEventBatchResponse{}, errs, nil
+	}
+	f.accepted <- req
+	return &pb.IngestEventBatchResponse{AcceptedCount: int32(len(errs))}, errs, nil
+}
+
+// startBroker starts an in-process MQTT broker and returns it with its
+// address.
+func startBroker(t *testing.T) (*server.Server, string) {
+	t.Helper()
+	broker := server.New(&server.Options{
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err := broker.AddHook(new(auth.AllowHook), nil); err != nil {
+		t.Fatal(err)
+	}
+	tcp := listeners.NewTCP(listeners.Config{ID: "test", Address: "127.0.0.1:0"})
+	if err := broker.AddListener(tcp); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.Serve(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = broker.Close() })
+	return broker, "tcp://" + tcp.Address()
+}
+
+// publish publishes a payload at QoS 1 with a separate client.
+func publish(t *testing.T, broker, topic string, payload []byte) {
+	t.Helper()
+	client := paho.NewClient(paho.NewClientOptions().AddBroker(broker).SetClientID("test-device"))
+	if token := client.Connect(); !token.WaitTimeout(5*time.Second) || token.Error() != nil {
+		t.Fatalf("Connect() error = %v", token.Error())
+	}
+	defer client.Disconnect(100)
+	if token := client.Publish(topic, 1, false, payload); !token.WaitTimeout(5*time.Second) || token.Error() != nil {
+		t.Fatalf("Publish() error = %v", token.Error())
+	}
+}
+
+func TestSubscriber_RedeliversAfterPublishFailure(t *testing.T) {
+	srv, broker := startBroker(t)
+	ingester := &flakyIngester{failures: 1, accepted: make(chan *pb.IngestEventBatchRequest, 1)}
+	bridge := newTestBridge(t, ingester, FormatJSON, TopicMapping{AppIDLevel: 2})
+
+	subscriber := NewSubscriber(bridge, SubscriberOptions{
+		Client: paho.NewClientOptions().
+			AddBroker(broker).
+			SetClientID("test-bridge").
+			SetCleanSession(false).
+			SetConnectTimeout(5 * time.Second),
+		Topics:     []string{"causality/+/events"},
+		QoS:        1,
+		MinBackoff: 10 * time.Millisecond,
+	}, nil)
+	subscriber.Start(context.Background())
+	defer subscriber.Stop()
+
+	// Wait for the subscription before publishing
+	deadline := time.Now().Add(5 * time.Second)
+	for len(srv.Topics.Subscribers("causality/app-1/events").Subscriptions) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("subscriber did not subscribe")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	payload, err := protojson.Marshal(testEvent(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	publish(t, broker, "causality/app-1/events", payload)
+
+	select {
+	case req := <-ingester.accepted:
+		if got := req.GetEvents()[0].GetAppId(); got != "app-1" {
+			t.Errorf("app ID = %q, want app-1", got)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("message was not redelivered after the publish failure")
+	}
+
+	ingester.mu.Lock()
+	defer ingester.mu.Unlock()
+	if ingester.calls != 2 {
+		t.Errorf("ingested %d times, want 2", ingester.calls)
+	}
+}
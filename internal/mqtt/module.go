@@ -0,0 +1,212 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/SebastienMelki/causality/internal/mqtt/internal/service"
+)
+
+// Config holds the MQTT bridge configuration.
+type Config struct {
+	// BrokerURL is the broker address. The tcp:// and mqtt:// schemes
+	// connect in plain text, ssl://, tls:// and mqtts:// over TLS.
+	BrokerURL string `env:"MQTT_BROKER_URL" envDefault:"tcp://localhost:1883"`
+
+	// ClientID identifies the bridge's session on the broker.
+	ClientID string `env:"MQTT_CLIENT_ID" envDefault:"causality-mqtt-bridge"`
+
+	// Username and Password authenticate the bridge, when set.
+	Username string `env:"MQTT_USERNAME"`
+	Password string `env:"MQTT_PASSWORD"`
+
+	// Topics are the topic filters subscribed to. Use shared subscriptions
+	// ($share/<group>/<filter>) to spread messages over bridge replicas.
+	Topics []string `env:"MQTT_TOPICS" envDefault:"causality/+/events"`
+
+	// QoS is the subscription QoS: 0 or 1. With QoS 1 a message is only
+	// acknowledged once its events are published to NATS.
+	QoS int `env:"MQTT_QOS" envDefault:"1"`
+
+	// CleanSession discards the broker session on connect. Without it the
+	// broker queues QoS 1 messages while the bridge is disconnected.
+	CleanSession bool `env:"MQTT_CLEAN_SESSION" envDefault:"false"`
+
+	// KeepAlive is the MQTT keep-alive interval.
+	KeepAlive time.Duration `env:"MQTT_KEEP_ALIVE" envDefault:"30s"`
+
+	// ConnectTimeout bounds connecting and subscribing.
+	ConnectTimeout time.Duration `env:"MQTT_CONNECT_TIMEOUT" envDefault:"10s"`
+
+	// ReconnectMinBackoff and ReconnectMaxBackoff bound the exponential
+	// backoff between reconnection attempts.
+	ReconnectMinBackoff time.Duration `env:"MQTT_RECONNECT_MIN_BACKOFF" envDefault:"1s"`
+	ReconnectMaxBackoff time.Duration `env:"MQTT_RECONNECT_MAX_BACKOFF" envDefault:"30s"`
+
+	// TLS configures TLS connections.
+	TLS TLSConfig `envPrefix:"MQTT_TLS_"`
+
+	// PayloadFormat is the encoding of message payloads: json, protobuf or
+	// delimited.
+	PayloadFormat string `env:"MQTT_PAYLOAD_FORMAT" envDefault:"json"`
+
+	// TopicAppIDLevel is the 1-based topic level that sets the app ID of
+	// events without one; 0 disables it. The default matches
+	// causality/<app_id>/events.
+	TopicAppIDLevel int `env:"MQTT_TOPIC_APP_ID_LEVEL" envDefault:"2"`
+
+	// TopicDeviceIDLevel is the 1-based topic level that sets the device ID
+	// of events without one; 0 disables it.
+	TopicDeviceIDLevel int `env:"MQTT_TOPIC_DEVICE_ID_LEVEL" envDefault:"0"`
+}
+
+// TLSConfig holds the TLS settings of the broker connection.
+type TLSConfig struct {
+	// CAFile verifies the broker against this CA bundle instead of the
+	// system roots.
+	CAFile string `env:"CA_FILE"`
+
+	// CertFile and KeyFile authenticate the bridge with a client
+	// certificate.
+	CertFile string `env:"CERT_FILE"`
+	KeyFile  string `env:"KEY_FILE"`
+
+	// ServerName overrides the name the broker certificate is verified
+	// against.
+	ServerName string `env:"SERVER_NAME"`
+}
+
+// ErrInvalidConfig is returned by New for an invalid configuration.
+var ErrInvalidConfig = errors.New("invalid MQTT configuration")
+
+// Module is the MQTT bridge module facade. It wires the broker subscription
+// to the bridge ingesting its messages.
+type Module struct {
+	subscriber *service.Subscriber
+	logger     *slog.Logger
+}
+
+// New creates a new MQTT bridge Module ingesting through ingester.
+func New(cfg Config, ingester Ingester, logger *slog.Logger) (*Module, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	opts, err := clientOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	bridge, err := service.NewBridge(ingester, cfg.PayloadFormat, service.TopicMapping{
+		AppIDLevel:    cfg.TopicAppIDLevel,
+		DeviceIDLevel: cfg.TopicDeviceIDLevel,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	return &Module{
+		subscriber: service.NewSubscriber(bridge, service.SubscriberOptions{
+			Client:     opts,
+			Topics:     cfg.Topics,
+			QoS:        byte(cfg.QoS),
+			MinBackoff: cfg.ReconnectMinBackoff,
+			MaxBackoff: cfg.ReconnectMaxBackoff,
+		}, logger),
+		logger: logger.With("component", "mqtt-module"),
+	}, nil
+}
+
+// Start connects to the broker in the background, reconnecting until Stop.
+func (m *Module) Start(ctx context.Context) {
+	m.subscriber.Start(ctx)
+}
+
+// Stop disconnects from the broker once the message being ingested, if
+// any, is done.
+func (m *Module) Stop() {
+	m.subscriber.Stop()
+}
+
+// clientOptions builds the paho connection options of cfg.
+func clientOptions(cfg Config) (*paho.ClientOptions, error) {
+	if len(cfg.Topics) == 0 {
+		return nil, fmt.Errorf("%w: no topics", ErrInvalidConfig)
+	}
+	if cfg.QoS != 0 && cfg.QoS != 1 {
+		return nil, fmt.Errorf("%w: QoS %d, want 0 or 1", ErrInvalidConfig, cfg.QoS)
+	}
+
+	broker, err := url.Parse(cfg.BrokerURL)
+	if err != nil || broker.Host == "" {
+		return nil, fmt.Errorf("%w: broker URL %q", ErrInvalidConfig, cfg.BrokerURL)
+	}
+
+	opts := paho.NewClientOptions().
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetCleanSession(cfg.CleanSession).
+		SetKeepAlive(cfg.KeepAlive).
+		SetConnectTimeout(cfg.ConnectTimeout)
+
+	scheme, port := "tcp", "1883"
+	switch broker.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		scheme, port = "ssl", "8883"
+		config, err := tlsConfig(cfg.TLS, broker.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(config)
+	default:
+		return nil, fmt.Errorf("%w: broker URL scheme %q", ErrInvalidConfig, broker.Scheme)
+	}
+	if broker.Port() != "" {
+		port = broker.Port()
+	}
+	opts.AddBroker(scheme + "://" + net.JoinHostPort(broker.Hostname(), port))
+	return opts, nil
+}
+
+// tlsConfig builds the TLS configuration of a broker connection to host.
+func tlsConfig(cfg TLSConfig, host string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: host,
+	}
+	if cfg.ServerName != "" {
+		config.ServerName = cfg.ServerName
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read MQTT CA file: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%w: no certificates in CA file %s", ErrInvalidConfig, cfg.CAFile)
+		}
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load MQTT client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
@@ -0,0 +1,48 @@
+package mqtt
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClientOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		qos     int
+		broker  string
+		tls     bool
+		wantErr bool
+	}{
+		{"plain default port", "tcp://broker", 1, "tcp://broker:1883", false, false},
+		{"plain explicit port", "mqtt://broker:1884", 0, "tcp://broker:1884", false, false},
+		{"tls default port", "mqtts://broker", 1, "ssl://broker:8883", true, false},
+		{"tls explicit port", "ssl://broker:9883", 1, "ssl://broker:9883", true, false},
+		{"unknown scheme", "ws://broker", 1, "", false, true},
+		{"missing host", "broker:1883", 1, "", false, true},
+		{"qos 2", "tcp://broker", 2, "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := clientOptions(Config{BrokerURL: tt.url, QoS: tt.qos, Topics: []string{"causality/+/events"}})
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidConfig) {
+					t.Errorf("clientOptions() error = %v, want ErrInvalidConfig", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("clientOptions() error = %v", err)
+			}
+			if len(opts.Servers) != 1 || opts.Servers[0].String() != tt.broker {
+				t.Errorf("Servers = %v, want %s", opts.Servers, tt.broker)
+			}
+			if (opts.TLSConfig != nil) != tt.tls {
+				t.Errorf("TLS = %v, want %v", opts.TLSConfig != nil, tt.tls)
+			}
+			if tt.tls && opts.TLSConfig.ServerName != "broker" {
+				t.Errorf("ServerName = %q, want broker", opts.TLSConfig.ServerName)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+// Package mqtt provides the MQTT ingestion bridge for IoT-style devices that
+// speak MQTT rather than HTTP. It subscribes to configurable topics on an
+// MQTT broker, maps message payloads to EventEnvelopes and ingests them
+// through the ingest service shared with the gateway, so events get the
+// same validation, event limits, enrichment and dedup as the HTTP endpoints
+// before they are published to NATS.
+package mqtt
+
+import "github.com/SebastienMelki/causality/internal/mqtt/internal/service"
+
+// Ingester defines the port for ingesting event batches. It is satisfied by
+// *ingest.Service.
+type Ingester = service.Ingester
+
+// Payload formats of MQTT messages.
+const (
+	// FormatJSON payloads are a JSON EventEnvelope, or an
+	// IngestEventBatchRequest when they have an "events" field.
+	FormatJSON = service.FormatJSON
+
+	// FormatProtobuf payloads are a binary IngestEventBatchRequest.
+	FormatProtobuf = service.FormatProtobuf
+
+	// FormatDelimited payloads are length-delimited binary EventEnvelopes.
+	FormatDelimited = service.FormatDelimited
+)
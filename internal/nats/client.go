@@ -4,18 +4,26 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
 )
 
+// ConnectionHandler is called when the client loses or regains its
+// connection to NATS.
+type ConnectionHandler func(connected bool)
+
 // Client wraps NATS connection and JetStream context.
 type Client struct {
 	conn   *nats.Conn
 	js     jetstream.JetStream
 	config Config
 	logger *slog.Logger
+
+	mu        sync.Mutex
+	onConnect []ConnectionHandler
 }
 
 // NewClient creates a new NATS client with the given configuration.
@@ -26,21 +34,30 @@ func NewClient(_ context.Context, cfg Config, logger *slog.Logger) (*Client, err
 
 	logger = logger.With("component", "nats-client")
 
+	client := &Client{
+		config: cfg,
+		logger: logger,
+	}
+
 	opts := []nats.Option{
 		nats.Name(cfg.Name),
 		nats.MaxReconnects(cfg.MaxReconnects),
 		nats.ReconnectWait(cfg.ReconnectWait),
+		nats.ReconnectBufSize(cfg.ReconnectBufSize),
 		nats.Timeout(cfg.Timeout),
 		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
 			if err != nil {
 				logger.Warn("disconnected from NATS", "error", err)
 			}
+			client.notifyConnection(false)
 		}),
 		nats.ReconnectHandler(func(nc *nats.Conn) {
 			logger.Info("reconnected to NATS", "url", nc.ConnectedUrl())
+			client.notifyConnection(true)
 		}),
 		nats.ClosedHandler(func(_ *nats.Conn) {
 			logger.Info("NATS connection closed")
+			client.notifyConnection(false)
 		}),
 		nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) {
 			logger.Error("NATS error", "error", err)
@@ -52,18 +69,19 @@ func NewClient(_ context.Context, cfg Config, logger *slog.Logger) (*Client, err
 		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
 	}
 
-	js, err := jetstream.New(conn)
+	var jsOpts []jetstream.JetStreamOpt
+	if cfg.PublishAsyncMaxPending > 0 {
+		jsOpts = append(jsOpts, jetstream.WithPublishAsyncMaxPending(cfg.PublishAsyncMaxPending))
+	}
+
+	js, err := jetstream.New(conn, jsOpts...)
 	if err != nil {
 		conn.Close()
 		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
 	}
 
-	client := &Client{
-		conn:   conn,
-		js:     js,
-		config: cfg,
-		logger: logger,
-	}
+	client.conn = conn
+	client.js = js
 
 	logger.Info("connected to NATS",
 		"url", conn.ConnectedUrl(),
@@ -73,6 +91,27 @@ func NewClient(_ context.Context, cfg Config, logger *slog.Logger) (*Client, err
 	return client, nil
 }
 
+// OnConnectionChange registers fn to be called when the connection is lost
+// (false) or re-established (true), e.g. to flip service readiness during a
+// broker rollout. Handlers run on the NATS callback goroutine and must not
+// block.
+func (c *Client) OnConnectionChange(fn ConnectionHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onConnect = append(c.onConnect, fn)
+}
+
+// notifyConnection calls the registered connection handlers.
+func (c *Client) notifyConnection(connected bool) {
+	c.mu.Lock()
+	handlers := append([]ConnectionHandler(nil), c.onConnect...)
+	c.mu.Unlock()
+
+	for _, fn := range handlers {
+		fn(connected)
+	}
+}
+
 // JetStream returns the JetStream context.
 func (c *Client) JetStream() jetstream.JetStream {
 	return c.js
@@ -0,0 +1,24 @@
+package nats
+
+import "testing"
+
+func TestClient_OnConnectionChange(t *testing.T) {
+	c := &Client{}
+
+	var got []bool
+	c.OnConnectionChange(func(connected bool) { got = append(got, connected) })
+	c.OnConnectionChange(func(connected bool) { got = append(got, connected) })
+
+	c.notifyConnection(false)
+	c.notifyConnection(true)
+
+	want := []bool{false, false, true, true}
+	if len(got) != len(want) {
+		t.Fatalf("handler calls = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("handler call %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
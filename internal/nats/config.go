@@ -14,11 +14,30 @@ type Config struct {
 	Name string `env:"NATS_CLIENT_NAME" envDefault:"causality-server"`
 
 	// MaxReconnects is the maximum number of reconnection attempts
+	// (-1 retries forever)
 	MaxReconnects int `env:"NATS_MAX_RECONNECTS" envDefault:"60"`
 
 	// ReconnectWait is the time to wait between reconnection attempts
 	ReconnectWait time.Duration `env:"NATS_RECONNECT_WAIT" envDefault:"2s"`
 
+	// ReconnectBufSize is the number of bytes of outgoing messages held
+	// pending while reconnecting; publishes beyond it fail immediately
+	// (-1 disables buffering)
+	ReconnectBufSize int `env:"NATS_RECONNECT_BUF_SIZE" envDefault:"8388608"` // 8MB
+
+	// PublishAsyncMaxPending is the number of asynchronous JetStream
+	// publishes awaiting an ack before further ones block
+	PublishAsyncMaxPending int `env:"NATS_PUBLISH_ASYNC_MAX_PENDING" envDefault:"4000"`
+
+	// PublishDurability is the default confirmation of event publishes:
+	// fire (asynchronous, not awaited), ack (stream ack) or double (stream
+	// ack and read-back)
+	PublishDurability string `env:"NATS_PUBLISH_DURABILITY" envDefault:"ack"`
+
+	// AppPublishDurability overrides PublishDurability per app
+	// (app_id:level,...)
+	AppPublishDurability map[string]string `env:"NATS_APP_PUBLISH_DURABILITY"`
+
 	// Timeout is the connection timeout
 	Timeout time.Duration `env:"NATS_TIMEOUT" envDefault:"5s"`
 
@@ -26,13 +45,37 @@ type Config struct {
 	Stream StreamConfig `envPrefix:"NATS_STREAM_"`
 }
 
+// EmbeddedConfig configures the in-process NATS server used for
+// single-binary evaluation deployments.
+type EmbeddedConfig struct {
+	// Enabled starts an in-process JetStream server and points the client
+	// at it, instead of connecting to NATS_URL.
+	Enabled bool `env:"EMBEDDED_NATS" envDefault:"false"`
+
+	// Host is the address the embedded server listens on. Other services
+	// (warehouse sink, reaction engine) connect to it as their NATS_URL.
+	Host string `env:"EMBEDDED_NATS_HOST" envDefault:"127.0.0.1"`
+
+	// Port is the client port of the embedded server.
+	Port int `env:"EMBEDDED_NATS_PORT" envDefault:"4222"`
+
+	// StoreDir is the JetStream storage directory.
+	StoreDir string `env:"EMBEDDED_NATS_STORE_DIR" envDefault:"./data/nats"`
+
+	// ReadyTimeout bounds how long to wait for the server to accept
+	// connections.
+	ReadyTimeout time.Duration `env:"EMBEDDED_NATS_READY_TIMEOUT" envDefault:"10s"`
+}
+
 // StreamConfig holds JetStream stream configuration.
 type StreamConfig struct {
 	// Name is the stream name
 	Name string `env:"NAME" envDefault:"CAUSALITY_EVENTS"`
 
-	// Subjects are the subjects to capture
-	Subjects []string `env:"SUBJECTS" envDefault:"events.>,requests.>,responses.>,anomalies.>"`
+	// Subjects are the subjects to capture. Subjects in a derived family
+	// (reactions, anomalies, sessions) belong to the derived stream and are
+	// ignored here.
+	Subjects []string `env:"SUBJECTS" envDefault:"events.>,requests.>,responses.>"`
 
 	// MaxAge is the maximum age of messages in the stream
 	MaxAge time.Duration `env:"MAX_AGE" envDefault:"168h"` // 7 days
@@ -51,6 +94,36 @@ type StreamConfig struct {
 
 	// DLQMaxAge is the maximum retention age for DLQ messages (default 30 days)
 	DLQMaxAge time.Duration `env:"DLQ_MAX_AGE" envDefault:"720h"`
+
+	// DerivedStreamName is the name of the stream holding derived events
+	// (reactions.>, anomalies.>, sessions.>)
+	DerivedStreamName string `env:"DERIVED_STREAM_NAME" envDefault:"CAUSALITY_DERIVED"`
+
+	// DerivedMaxAge is the maximum retention age for derived events (default 30 days)
+	DerivedMaxAge time.Duration `env:"DERIVED_MAX_AGE" envDefault:"720h"`
+
+	// AuditStreamName is the name of the ingestion audit log stream
+	AuditStreamName string `env:"AUDIT_STREAM_NAME" envDefault:"CAUSALITY_AUDIT"`
+
+	// AuditMaxAge is the maximum retention age for audit records (default 90 days)
+	AuditMaxAge time.Duration `env:"AUDIT_MAX_AGE" envDefault:"2160h"`
+
+	// PriorityEnabled routes purchase and crash events to the priority
+	// stream, which the main stream sources (requires NATS 2.10)
+	PriorityEnabled bool `env:"PRIORITY_ENABLED" envDefault:"true"`
+
+	// PriorityStreamName is the name of the stream holding priority events
+	// (priority.>)
+	PriorityStreamName string `env:"PRIORITY_STREAM_NAME" envDefault:"CAUSALITY_PRIORITY"`
+
+	// ProbeStreamName is the name of the stream holding the gateway's
+	// synthetic publish probes (probe.>)
+	ProbeStreamName string `env:"PROBE_STREAM_NAME" envDefault:"CAUSALITY_PROBE"`
+
+	// DeliveryStreamName is the name of the work queue stream holding
+	// webhook delivery IDs for the reaction engine's dispatcher
+	// (deliveries.>)
+	DeliveryStreamName string `env:"DELIVERY_STREAM_NAME" envDefault:"CAUSALITY_DELIVERIES"`
 }
 
 // ConsumerConfig holds JetStream consumer configuration.
@@ -61,6 +134,10 @@ type ConsumerConfig struct {
 	// FilterSubject is the subject filter for the consumer
 	FilterSubject string
 
+	// FilterSubjects filters the consumer on several subjects. It is used
+	// instead of FilterSubject when set.
+	FilterSubjects []string
+
 	// AckWait is the time to wait for acknowledgment
 	AckWait time.Duration
 
@@ -69,6 +146,11 @@ type ConsumerConfig struct {
 
 	// MaxDeliver is the maximum number of delivery attempts
 	MaxDeliver int
+
+	// StartTime, when set, makes a new consumer start at the first message
+	// stored at or after it instead of the start of the stream. The start
+	// position of an existing consumer cannot change and is kept.
+	StartTime *time.Time
 }
 
 // DefaultConsumerConfigs returns the default consumer configurations.
@@ -88,9 +170,16 @@ func DefaultConsumerConfigs() []ConsumerConfig {
 			MaxAckPending: 1000,
 			MaxDeliver:    3,
 		},
+	}
+}
+
+// DerivedConsumerConfigs returns the default consumer configurations for the
+// derived stream.
+func DerivedConsumerConfigs() []ConsumerConfig {
+	return []ConsumerConfig{
 		{
 			Name:          "alerting",
-			FilterSubject: "anomalies.>",
+			FilterSubject: FamilyAnomalies + ".>",
 			AckWait:       5 * time.Second,
 			MaxAckPending: 100,
 			MaxDeliver:    3,
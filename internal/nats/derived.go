@@ -0,0 +1,102 @@
+package nats
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/SebastienMelki/causality/internal/events"
+)
+
+// Derived subject families. Events derived from ingested events are
+// published as {family}.{app_id}.{name} and stored in the derived stream,
+// separate from the ingested events:
+//
+//	reactions.{app_id}.{name}  payloads published by rule actions
+//	anomalies.{app_id}.{name}  anomaly detections (name is the anomaly config)
+//	                           and compaction alerts (name is the alert type)
+//	sessions.{app_id}.{name}   session lifecycle events
+//
+// The app_id and name tokens are sanitized with events.SanitizeSubjectName,
+// so they never contain dots.
+const (
+	FamilyReactions = "reactions"
+	FamilyAnomalies = "anomalies"
+	FamilySessions  = "sessions"
+)
+
+// DerivedFamilies returns the derived subject families.
+func DerivedFamilies() []string {
+	return []string{FamilyReactions, FamilyAnomalies, FamilySessions}
+}
+
+// derivedSubjects returns the subjects captured by the derived stream.
+func derivedSubjects() []string {
+	families := DerivedFamilies()
+	subjects := make([]string, len(families))
+	for i, family := range families {
+		subjects[i] = family + ".>"
+	}
+	return subjects
+}
+
+// DerivedSubject returns the subject for a derived event.
+func DerivedSubject(family, appID, name string) string {
+	return family + "." + events.SanitizeSubjectName(appID) + "." + events.SanitizeSubjectName(name)
+}
+
+// DerivedSubjectInfo describes a derived subject holding messages.
+type DerivedSubjectInfo struct {
+	Subject  string `json:"subject"`
+	Family   string `json:"family"`
+	AppID    string `json:"app_id"`
+	Name     string `json:"name"`
+	Messages uint64 `json:"messages"`
+}
+
+// ParseDerivedSubject splits a subject following the derived taxonomy into
+// its tokens. It reports false for any other subject.
+func ParseDerivedSubject(subject string) (DerivedSubjectInfo, bool) {
+	tokens := strings.Split(subject, ".")
+	if len(tokens) != 3 || !IsDerivedFamily(tokens[0]) || tokens[1] == "" || tokens[2] == "" {
+		return DerivedSubjectInfo{}, false
+	}
+	return DerivedSubjectInfo{
+		Subject: subject,
+		Family:  tokens[0],
+		AppID:   tokens[1],
+		Name:    tokens[2],
+	}, true
+}
+
+// IsDerivedFamily reports whether family is a derived subject family.
+func IsDerivedFamily(family string) bool {
+	for _, f := range DerivedFamilies() {
+		if f == family {
+			return true
+		}
+	}
+	return false
+}
+
+// isDerivedFilter reports whether a stream subject filter falls in a derived
+// family, e.g. "anomalies.>" or "reactions.*.checkout".
+func isDerivedFilter(subject string) bool {
+	family, _, _ := strings.Cut(subject, ".")
+	return IsDerivedFamily(family)
+}
+
+// derivedSubjectInfos builds the catalog entries for per-subject message
+// counts, sorted by subject. Subjects outside the taxonomy are skipped.
+func derivedSubjectInfos(counts map[string]uint64) []DerivedSubjectInfo {
+	infos := make([]DerivedSubjectInfo, 0, len(counts))
+	for subject, n := range counts {
+		info, ok := ParseDerivedSubject(subject)
+		if !ok {
+			continue
+		}
+		info.Messages = n
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Subject < infos[j].Subject })
+	return infos
+}
@@ -0,0 +1,58 @@
+package nats
+
+import "testing"
+
+func TestDerivedSubject(t *testing.T) {
+	got := DerivedSubject(FamilyAnomalies, "Demo App", "checkout.drop")
+	if got != "anomalies.demo_app.checkout_drop" {
+		t.Errorf("DerivedSubject() = %q", got)
+	}
+
+	info, ok := ParseDerivedSubject(got)
+	if !ok || info.Family != FamilyAnomalies || info.AppID != "demo_app" || info.Name != "checkout_drop" {
+		t.Errorf("ParseDerivedSubject(%q) = %+v, %v", got, info, ok)
+	}
+}
+
+func TestParseDerivedSubject_RejectsOtherSubjects(t *testing.T) {
+	for _, subject := range []string{
+		"events.app.screen.view",
+		"alerts.app.x",
+		"reactions.app",
+		"reactions.app.x.y",
+		"sessions..x",
+	} {
+		if _, ok := ParseDerivedSubject(subject); ok {
+			t.Errorf("ParseDerivedSubject(%q) ok, want rejected", subject)
+		}
+	}
+}
+
+func TestDerivedSubjectInfos(t *testing.T) {
+	infos := derivedSubjectInfos(map[string]uint64{
+		"reactions.app.vip":      3,
+		"anomalies.app.spike":    1,
+		"anomalies.app.extra.x":  9,
+		"sessions.other.started": 2,
+	})
+	want := []string{"anomalies.app.spike", "reactions.app.vip", "sessions.other.started"}
+	if len(infos) != len(want) {
+		t.Fatalf("derivedSubjectInfos() = %+v", infos)
+	}
+	for i, subject := range want {
+		if infos[i].Subject != subject {
+			t.Errorf("infos[%d].Subject = %q, want %q", i, infos[i].Subject, subject)
+		}
+	}
+	if infos[1].Messages != 3 {
+		t.Errorf("messages = %d, want 3", infos[1].Messages)
+	}
+}
+
+func TestStreamManager_EventSubjectsDropsDerived(t *testing.T) {
+	m := NewStreamManager(nil, StreamConfig{Subjects: []string{"events.>", "anomalies.>", "requests.>", "priority.>"}}, nil)
+	got := m.eventSubjects()
+	if len(got) != 2 || got[0] != "events.>" || got[1] != "requests.>" {
+		t.Errorf("eventSubjects() = %v", got)
+	}
+}
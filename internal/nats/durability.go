@@ -0,0 +1,117 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Durability is how strongly a publish is confirmed before PublishEvent
+// returns.
+type Durability string
+
+const (
+	// DurabilityFire publishes asynchronously without waiting for the
+	// stream's ack. It is the cheapest level, for telemetry that can
+	// tolerate loss: failures to store the event are not reported.
+	DurabilityFire Durability = "fire"
+
+	// DurabilityAck waits for the stream's ack, which JetStream sends once
+	// the event is stored by a quorum of the stream's replicas. It is the
+	// default.
+	DurabilityAck Durability = "ack"
+
+	// DurabilityDouble waits for the ack, requires it to come from the
+	// stream capturing the event's subject (the priority stream for
+	// priority events), and then reads the event back by its sequence,
+	// confirming it is retrievable before the publish returns.
+	DurabilityDouble Durability = "double"
+)
+
+// ParseDurability parses a durability level name.
+func ParseDurability(s string) (Durability, error) {
+	switch d := Durability(s); d {
+	case DurabilityFire, DurabilityAck, DurabilityDouble:
+		return d, nil
+	default:
+		return "", fmt.Errorf("%w: %q (want fire, ack or double)", ErrInvalidDurability, s)
+	}
+}
+
+// SetDurability sets the default publish durability and per-app overrides
+// keyed by app ID, from their level names. An empty default keeps
+// DurabilityAck.
+func (p *Publisher) SetDurability(defaultLevel string, appLevels map[string]string) error {
+	durability := DurabilityAck
+	if defaultLevel != "" {
+		d, err := ParseDurability(defaultLevel)
+		if err != nil {
+			return err
+		}
+		durability = d
+	}
+
+	apps := make(map[string]Durability, len(appLevels))
+	for appID, level := range appLevels {
+		d, err := ParseDurability(level)
+		if err != nil {
+			return fmt.Errorf("app %s: %w", appID, err)
+		}
+		apps[appID] = d
+	}
+
+	p.durability = durability
+	p.appDurability = apps
+	return nil
+}
+
+// durabilityFor returns the publish durability of an app's events.
+func (p *Publisher) durabilityFor(appID string) Durability {
+	if d, ok := p.appDurability[appID]; ok {
+		return d
+	}
+	if p.durability == "" {
+		return DurabilityAck
+	}
+	return p.durability
+}
+
+// publish publishes msg with the given durability, returning the stream's
+// ack, or nil for DurabilityFire.
+func (p *Publisher) publish(ctx context.Context, msg *nats.Msg, durability Durability) (*jetstream.PubAck, error) {
+	switch durability {
+	case DurabilityFire:
+		if _, err := p.js.PublishMsgAsync(msg); err != nil {
+			return nil, err
+		}
+		return nil, nil
+
+	case DurabilityDouble:
+		stream := p.streamFor(msg.Subject)
+		ack, err := p.js.PublishMsg(ctx, msg, jetstream.WithExpectStream(stream))
+		if err != nil {
+			return nil, err
+		}
+		if err := p.confirmStored(ctx, stream, ack); err != nil {
+			return nil, err
+		}
+		return ack, nil
+
+	default:
+		return p.js.PublishMsg(ctx, msg)
+	}
+}
+
+// confirmStored reads the acked message back from the named stream.
+func (p *Publisher) confirmStored(ctx context.Context, streamName string, ack *jetstream.PubAck) error {
+	stream, err := p.js.Stream(ctx, streamName)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPublishUnconfirmed, err)
+	}
+	if _, err := stream.GetMsg(ctx, ack.Sequence); err != nil {
+		return fmt.Errorf("%w: sequence %d: %w", ErrPublishUnconfirmed, ack.Sequence, err)
+	}
+	return nil
+}
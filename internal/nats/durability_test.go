@@ -0,0 +1,139 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// fakeJetStream records publishes by method. Methods it does not override
+// panic through the nil embedded interface.
+type fakeJetStream struct {
+	jetstream.JetStream
+
+	sync, async []string
+	headers     []nats.Header
+	opts        int
+	stored      bool
+
+	// streams are the streams read back by name.
+	streams []string
+}
+
+func (f *fakeJetStream) PublishMsg(_ context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	f.sync = append(f.sync, msg.Subject)
+	f.headers = append(f.headers, msg.Header)
+	f.opts += len(opts)
+	return &jetstream.PubAck{Stream: "CAUSALITY_EVENTS", Sequence: uint64(len(f.sync))}, nil
+}
+
+func (f *fakeJetStream) PublishMsgAsync(msg *nats.Msg, _ ...jetstream.PublishOpt) (jetstream.PubAckFuture, error) {
+	f.async = append(f.async, msg.Subject)
+	return nil, nil
+}
+
+func (f *fakeJetStream) Stream(_ context.Context, name string) (jetstream.Stream, error) {
+	f.streams = append(f.streams, name)
+	return &fakeStream{stored: f.stored}, nil
+}
+
+// fakeStream reports every sequence as stored or missing.
+type fakeStream struct {
+	jetstream.Stream
+	stored bool
+}
+
+func (f *fakeStream) GetMsg(_ context.Context, seq uint64, _ ...jetstream.GetMsgOpt) (*jetstream.RawStreamMsg, error) {
+	if !f.stored {
+		return nil, jetstream.ErrMsgNotFound
+	}
+	return &jetstream.RawStreamMsg{Sequence: seq}, nil
+}
+
+func TestPublisher_Durability(t *testing.T) {
+	js := &fakeJetStream{stored: true}
+	p := NewPublisher(js, "CAUSALITY_EVENTS", nil)
+	if err := p.SetDurability("fire", map[string]string{"payments": "double", "checkout": "ack"}); err != nil {
+		t.Fatalf("SetDurability: %v", err)
+	}
+
+	event := func(appID string) *pb.EventEnvelope {
+		return &pb.EventEnvelope{AppId: appID, Payload: &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}}}
+	}
+	for _, appID := range []string{"ui", "checkout", "payments"} {
+		if err := p.PublishEvent(context.Background(), event(appID)); err != nil {
+			t.Fatalf("PublishEvent(%s): %v", appID, err)
+		}
+	}
+
+	if len(js.async) != 1 || js.async[0] != "events.ui.screen.view" {
+		t.Errorf("async publishes = %v, want only the ui event", js.async)
+	}
+	if len(js.sync) != 2 || js.opts != 1 {
+		t.Errorf("sync publishes = %v with %d options, want checkout and payments with 1 option", js.sync, js.opts)
+	}
+
+	// A double-ack publish fails when the event cannot be read back.
+	js.stored = false
+	err := p.PublishEvent(context.Background(), event("payments"))
+	if !errors.Is(err, ErrPublishUnconfirmed) {
+		t.Errorf("unconfirmed publish error = %v, want ErrPublishUnconfirmed", err)
+	}
+}
+
+func TestPublisher_DurabilityDoublePriorityEvent(t *testing.T) {
+	js := &fakeJetStream{stored: true}
+	p := NewPublisher(js, "CAUSALITY_EVENTS", nil)
+	p.SetPriorityRouting(true)
+	if err := p.SetDurability("ack", map[string]string{"payments": "double"}); err != nil {
+		t.Fatalf("SetDurability: %v", err)
+	}
+
+	purchase := &pb.EventEnvelope{
+		AppId:   "payments",
+		Payload: &pb.EventEnvelope_PurchaseComplete{PurchaseComplete: &pb.PurchaseComplete{}},
+	}
+	screen := &pb.EventEnvelope{
+		AppId:   "payments",
+		Payload: &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}},
+	}
+	for _, event := range []*pb.EventEnvelope{purchase, screen} {
+		if err := p.PublishEvent(context.Background(), event); err != nil {
+			t.Fatalf("PublishEvent: %v", err)
+		}
+	}
+
+	if len(js.sync) != 2 || js.sync[0] != "priority.payments.commerce.purchase_complete" {
+		t.Fatalf("sync publishes = %v, want the purchase on its priority subject first", js.sync)
+	}
+	want := []string{DefaultPriorityStreamName, "CAUSALITY_EVENTS"}
+	if len(js.streams) != 2 || js.streams[0] != want[0] || js.streams[1] != want[1] {
+		t.Errorf("streams read back = %v, want %v", js.streams, want)
+	}
+
+	p.SetPriorityStreamName("PRIORITY")
+	if err := p.PublishEvent(context.Background(), purchase); err != nil {
+		t.Fatalf("PublishEvent: %v", err)
+	}
+	if got := js.streams[len(js.streams)-1]; got != "PRIORITY" {
+		t.Errorf("stream read back = %s, want the configured priority stream", got)
+	}
+}
+
+func TestPublisher_SetDurabilityRejectsUnknownLevels(t *testing.T) {
+	p := NewPublisher(&fakeJetStream{}, "CAUSALITY_EVENTS", nil)
+	if err := p.SetDurability("quorum", nil); !errors.Is(err, ErrInvalidDurability) {
+		t.Errorf("default error = %v, want ErrInvalidDurability", err)
+	}
+	if err := p.SetDurability("", map[string]string{"app": "fast"}); !errors.Is(err, ErrInvalidDurability) {
+		t.Errorf("app error = %v, want ErrInvalidDurability", err)
+	}
+	if got := p.durabilityFor("app"); got != DurabilityAck {
+		t.Errorf("durability after failed SetDurability = %s, want ack", got)
+	}
+}
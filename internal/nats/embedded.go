@@ -0,0 +1,76 @@
+//go:build embeddednats
+
+package nats
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+// EmbeddedServer is an in-process NATS server with JetStream enabled. It lets
+// evaluators run the whole pipeline without a separate NATS deployment.
+type EmbeddedServer struct {
+	srv    *server.Server
+	logger *slog.Logger
+}
+
+// StartEmbeddedServer starts an in-process NATS server and waits until it
+// accepts connections.
+func StartEmbeddedServer(cfg EmbeddedConfig, logger *slog.Logger) (*EmbeddedServer, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger = logger.With("component", "embedded-nats")
+
+	srv, err := server.NewServer(&server.Options{
+		ServerName: "causality-embedded",
+		Host:       cfg.Host,
+		Port:       cfg.Port,
+		JetStream:  true,
+		StoreDir:   cfg.StoreDir,
+		NoSigs:     true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedded NATS server: %w", err)
+	}
+	srv.SetLoggerV2(serverLogger{logger}, false, false, false)
+
+	go srv.Start()
+	if !srv.ReadyForConnections(cfg.ReadyTimeout) {
+		srv.Shutdown()
+		return nil, fmt.Errorf("%w after %s", ErrEmbeddedNATSNotReady, cfg.ReadyTimeout)
+	}
+
+	logger.Info("embedded NATS server started",
+		"url", srv.ClientURL(),
+		"store_dir", cfg.StoreDir,
+	)
+	return &EmbeddedServer{srv: srv, logger: logger}, nil
+}
+
+// ClientURL returns the URL clients use to connect to the server.
+func (s *EmbeddedServer) ClientURL() string {
+	return s.srv.ClientURL()
+}
+
+// Shutdown stops the server and waits for it to exit. Clients should be
+// drained first.
+func (s *EmbeddedServer) Shutdown() {
+	s.srv.Shutdown()
+	s.srv.WaitForShutdown()
+	s.logger.Info("embedded NATS server stopped")
+}
+
+// serverLogger adapts slog to the nats-server logger interface.
+type serverLogger struct {
+	logger *slog.Logger
+}
+
+func (l serverLogger) Noticef(format string, v ...any) { l.logger.Info(fmt.Sprintf(format, v...)) }
+func (l serverLogger) Warnf(format string, v ...any)   { l.logger.Warn(fmt.Sprintf(format, v...)) }
+func (l serverLogger) Fatalf(format string, v ...any)  { l.logger.Error(fmt.Sprintf(format, v...)) }
+func (l serverLogger) Errorf(format string, v ...any)  { l.logger.Error(fmt.Sprintf(format, v...)) }
+func (l serverLogger) Debugf(format string, v ...any)  { l.logger.Debug(fmt.Sprintf(format, v...)) }
+func (l serverLogger) Tracef(format string, v ...any)  { l.logger.Debug(fmt.Sprintf(format, v...)) }
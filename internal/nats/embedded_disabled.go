@@ -0,0 +1,23 @@
+//go:build !embeddednats
+
+package nats
+
+import "log/slog"
+
+// EmbeddedServer is an in-process NATS server. This build does not include
+// it; build with -tags embeddednats to enable EMBEDDED_NATS.
+type EmbeddedServer struct{}
+
+// StartEmbeddedServer returns ErrEmbeddedNATSUnavailable: the NATS server is
+// only compiled in with the embeddednats build tag.
+func StartEmbeddedServer(_ EmbeddedConfig, _ *slog.Logger) (*EmbeddedServer, error) {
+	return nil, ErrEmbeddedNATSUnavailable
+}
+
+// ClientURL returns the URL clients use to connect to the server.
+func (s *EmbeddedServer) ClientURL() string {
+	return ""
+}
+
+// Shutdown stops the server.
+func (s *EmbeddedServer) Shutdown() {}
@@ -0,0 +1,112 @@
+//go:build embeddednats
+
+package nats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+func TestEmbeddedServer_JetStream(t *testing.T) {
+	embedded, err := StartEmbeddedServer(EmbeddedConfig{
+		Host:         "127.0.0.1",
+		Port:         -1, // random port
+		StoreDir:     t.TempDir(),
+		ReadyTimeout: 10 * time.Second,
+	}, nil)
+	if err != nil {
+		t.Fatalf("StartEmbeddedServer: %v", err)
+	}
+	defer embedded.Shutdown()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, Config{
+		URL:     embedded.ClientURL(),
+		Name:    "embedded-test",
+		Timeout: 5 * time.Second,
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer client.Close()
+
+	streamCfg := StreamConfig{
+		Name:     "CAUSALITY_EVENTS",
+		Subjects: []string{"events.>"},
+		MaxAge:   time.Hour,
+		MaxBytes: 1 << 20,
+		Replicas: 1,
+		Storage:  "file",
+	}
+	if _, err := NewStreamManager(client.JetStream(), streamCfg, nil).EnsureStream(ctx); err != nil {
+		t.Fatalf("EnsureStream: %v", err)
+	}
+
+	if _, err := client.JetStream().Publish(ctx, "events.app.screen.view", []byte("x")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+}
+
+func TestEmbeddedServer_DoublePublishPriorityEvent(t *testing.T) {
+	embedded, err := StartEmbeddedServer(EmbeddedConfig{
+		Host:         "127.0.0.1",
+		Port:         -1, // random port
+		StoreDir:     t.TempDir(),
+		ReadyTimeout: 10 * time.Second,
+	}, nil)
+	if err != nil {
+		t.Fatalf("StartEmbeddedServer: %v", err)
+	}
+	defer embedded.Shutdown()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, Config{
+		URL:     embedded.ClientURL(),
+		Name:    "embedded-test",
+		Timeout: 5 * time.Second,
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer client.Close()
+
+	streamCfg := StreamConfig{
+		Name:               "CAUSALITY_EVENTS",
+		Subjects:           []string{"events.>"},
+		MaxAge:             time.Hour,
+		MaxBytes:           1 << 20,
+		Replicas:           1,
+		Storage:            "file",
+		PriorityEnabled:    true,
+		PriorityStreamName: "CAUSALITY_PRIORITY",
+	}
+	if _, err := NewStreamManager(client.JetStream(), streamCfg, nil).EnsureStream(ctx); err != nil {
+		t.Fatalf("EnsureStream: %v", err)
+	}
+
+	publisher := NewPublisher(client.JetStream(), streamCfg.Name, nil)
+	publisher.SetPriorityRouting(true)
+	publisher.SetPriorityStreamName(streamCfg.PriorityStreamName)
+	if err := publisher.SetDurability("ack", map[string]string{"payments": "double"}); err != nil {
+		t.Fatalf("SetDurability: %v", err)
+	}
+
+	purchase := &pb.EventEnvelope{
+		AppId:   "payments",
+		Payload: &pb.EventEnvelope_PurchaseComplete{PurchaseComplete: &pb.PurchaseComplete{}},
+	}
+	if err := publisher.PublishEvent(ctx, purchase); err != nil {
+		t.Fatalf("PublishEvent(purchase): %v", err)
+	}
+
+	screen := &pb.EventEnvelope{
+		AppId:   "payments",
+		Payload: &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}},
+	}
+	if err := publisher.PublishEvent(ctx, screen); err != nil {
+		t.Fatalf("PublishEvent(screen): %v", err)
+	}
+}
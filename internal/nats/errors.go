@@ -6,4 +6,9 @@ import "errors"
 var (
 	ErrNotConnected     = errors.New("NATS is not connected")
 	ErrPartialPublish   = errors.New("failed to publish some events")
+	ErrInvalidSampleRate = errors.New("invalid sample rate")
+	ErrEmbeddedNATSUnavailable = errors.New("embedded NATS server not compiled in (build with -tags embeddednats)")
+	ErrEmbeddedNATSNotReady = errors.New("embedded NATS server not ready for connections")
+	ErrInvalidDurability = errors.New("invalid publish durability")
+	ErrPublishUnconfirmed = errors.New("published event could not be read back")
 )
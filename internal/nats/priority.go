@@ -0,0 +1,72 @@
+package nats
+
+import (
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/SebastienMelki/causality/internal/events"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// Priority events (purchases and crashes) are published as
+// priority.{app_id}.{category}.{type} and stored in the priority stream, so a
+// backlog of UI events in the main stream cannot delay the consumers of
+// revenue and stability signals. The main stream sources the priority stream
+// with its subjects mapped back to events.{app_id}.{category}.{type}, so
+// consumers of the main stream still see every event.
+const (
+	// PrioritySubjectPrefix is the first subject token of priority events.
+	PrioritySubjectPrefix = "priority"
+
+	// PrioritySubjects is the subject filter of the priority stream.
+	PrioritySubjects = PrioritySubjectPrefix + ".>"
+
+	// DefaultPriorityStreamName is the default name of the priority stream,
+	// matching StreamConfig.PriorityStreamName's default.
+	DefaultPriorityStreamName = "CAUSALITY_PRIORITY"
+
+	// PriorityHeader marks priority events, including their copies sourced
+	// into the main stream. Services consuming the priority stream skip
+	// marked messages on the main stream so they handle each event once.
+	PriorityHeader = "Causality-Priority"
+)
+
+// priorityEventTypes are the category.type pairs routed to the priority
+// stream.
+var priorityEventTypes = map[string]bool{
+	events.CategoryCommerce + ".purchase_complete": true,
+	events.CategoryCommerce + ".purchase_failed":   true,
+	events.CategorySystem + ".app_crash":           true,
+}
+
+// IsPriorityEvent reports whether event belongs in the priority stream.
+func IsPriorityEvent(event *pb.EventEnvelope) bool {
+	category, eventType := events.GetCategoryAndType(event)
+	return priorityEventTypes[category+"."+eventType]
+}
+
+// isPrioritySubject reports whether subject is a priority event subject.
+func isPrioritySubject(subject string) bool {
+	return strings.HasPrefix(subject, PrioritySubjectPrefix+".")
+}
+
+// IsPriorityMsg reports whether a message's headers mark a priority event.
+func IsPriorityMsg(h nats.Header) bool {
+	return h.Get(PriorityHeader) != ""
+}
+
+// PriorityConsumerConfigs returns the default consumer configurations for the
+// priority stream.
+func PriorityConsumerConfigs() []ConsumerConfig {
+	return []ConsumerConfig{
+		{
+			Name:          "analysis-engine-priority",
+			FilterSubject: PrioritySubjects,
+			AckWait:       10 * time.Second,
+			MaxAckPending: 1000,
+			MaxDeliver:    3,
+		},
+	}
+}
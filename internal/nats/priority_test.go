@@ -0,0 +1,57 @@
+package nats
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+func TestPublisher_PriorityRouting(t *testing.T) {
+	purchase := &pb.EventEnvelope{
+		AppId:   "shop",
+		Payload: &pb.EventEnvelope_PurchaseComplete{PurchaseComplete: &pb.PurchaseComplete{}},
+	}
+	crash := &pb.EventEnvelope{
+		AppId:   "shop",
+		Payload: &pb.EventEnvelope_AppCrash{AppCrash: &pb.AppCrash{}},
+	}
+	view := &pb.EventEnvelope{
+		AppId:   "shop",
+		Payload: &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}},
+	}
+
+	if !IsPriorityEvent(purchase) || !IsPriorityEvent(crash) || IsPriorityEvent(view) {
+		t.Fatalf("IsPriorityEvent: purchase=%v crash=%v view=%v, want true, true, false",
+			IsPriorityEvent(purchase), IsPriorityEvent(crash), IsPriorityEvent(view))
+	}
+
+	js := &fakeJetStream{}
+	p := NewPublisher(js, "CAUSALITY_EVENTS", nil)
+
+	// Without priority routing, priority events keep their events subject.
+	if got := p.deriveSubject(purchase); got != "events.shop.commerce.purchase_complete" {
+		t.Errorf("subject without routing = %q, want events.shop.commerce.purchase_complete", got)
+	}
+
+	p.SetPriorityRouting(true)
+	for _, event := range []*pb.EventEnvelope{purchase, crash, view} {
+		if err := p.PublishEvent(context.Background(), event); err != nil {
+			t.Fatalf("PublishEvent: %v", err)
+		}
+	}
+
+	want := []string{
+		"priority.shop.commerce.purchase_complete",
+		"priority.shop.system.app_crash",
+		"events.shop.screen.view",
+	}
+	for i, subject := range want {
+		if js.sync[i] != subject {
+			t.Errorf("publish %d subject = %q, want %q", i, js.sync[i], subject)
+		}
+		if marked := IsPriorityMsg(js.headers[i]); marked != (i < 2) {
+			t.Errorf("publish %d priority header = %v, want %v", i, marked, i < 2)
+		}
+	}
+}
@@ -4,20 +4,35 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"strings"
 
+	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/SebastienMelki/causality/internal/events"
+	"github.com/SebastienMelki/causality/internal/observability"
 	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
 )
 
-// Publisher handles publishing events to NATS JetStream.
+// Publisher handles publishing events to NATS JetStream. Events are
+// published with DurabilityAck unless SetDurability sets another default or
+// overrides it for the event's app.
 type Publisher struct {
 	js         jetstream.JetStream
 	streamName string
 	logger     *slog.Logger
+
+	// durability is the default publish durability, and appDurability
+	// overrides it per app ID.
+	durability    Durability
+	appDurability map[string]Durability
+
+	// priority routes priority events to the priority stream, named
+	// priorityStreamName.
+	priority           bool
+	priorityStreamName string
 }
 
 // NewPublisher creates a new event publisher.
@@ -29,10 +44,35 @@ func NewPublisher(js jetstream.JetStream, streamName string, logger *slog.Logger
 		js:         js,
 		streamName: streamName,
 		logger:     logger.With("component", "publisher"),
+
+		priorityStreamName: DefaultPriorityStreamName,
 	}
 }
 
+// SetPriorityRouting enables publishing priority events (see
+// IsPriorityEvent) to priority subjects, captured by the priority stream.
+func (p *Publisher) SetPriorityRouting(enabled bool) {
+	p.priority = enabled
+}
+
+// SetPriorityStreamName sets the name of the priority stream, which
+// DurabilityDouble publishes of priority events expect to store them.
+// It defaults to DefaultPriorityStreamName.
+func (p *Publisher) SetPriorityStreamName(name string) {
+	p.priorityStreamName = name
+}
+
+// streamFor returns the name of the stream capturing subject.
+func (p *Publisher) streamFor(subject string) string {
+	if isPrioritySubject(subject) {
+		return p.priorityStreamName
+	}
+	return p.streamName
+}
+
 // PublishEvent publishes a single event to the appropriate NATS subject.
+// The trace context carried by ctx, if any, is propagated in the message
+// headers so consumers can attach trace exemplars to their metrics.
 func (p *Publisher) PublishEvent(ctx context.Context, event *pb.EventEnvelope) error {
 	subject := p.deriveSubject(event)
 
@@ -41,14 +81,29 @@ func (p *Publisher) PublishEvent(ctx context.Context, event *pb.EventEnvelope) e
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	ack, err := p.js.Publish(ctx, subject, data)
+	msg := &nats.Msg{Subject: subject, Data: data, Header: nats.Header{}}
+	observability.InjectTraceContext(ctx, http.Header(msg.Header))
+	if isPrioritySubject(subject) {
+		msg.Header.Set(PriorityHeader, "true")
+	}
+
+	durability := p.durabilityFor(event.GetAppId())
+	ack, err := p.publish(ctx, msg, durability)
 	if err != nil {
 		return fmt.Errorf("failed to publish event: %w", err)
 	}
 
+	if ack == nil {
+		p.logger.Debug("event published without ack",
+			"event_id", event.GetId(),
+			"subject", subject,
+		)
+		return nil
+	}
 	p.logger.Debug("event published",
 		"event_id", event.GetId(),
 		"subject", subject,
+		"durability", durability,
 		"stream", ack.Stream,
 		"sequence", ack.Sequence,
 	)
@@ -89,7 +144,12 @@ func (p *Publisher) PublishAsync(_ context.Context, event *pb.EventEnvelope) (je
 		return nil, fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	future, err := p.js.PublishAsync(subject, data)
+	msg := &nats.Msg{Subject: subject, Data: data}
+	if isPrioritySubject(subject) {
+		msg.Header = nats.Header{PriorityHeader: []string{"true"}}
+	}
+
+	future, err := p.js.PublishMsgAsync(msg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to publish event async: %w", err)
 	}
@@ -98,7 +158,8 @@ func (p *Publisher) PublishAsync(_ context.Context, event *pb.EventEnvelope) (je
 }
 
 // deriveSubject derives the NATS subject from the event envelope.
-// Format: {kind}.{app_id}.{category}.{type}.
+// Format: {kind}.{app_id}.{category}.{type}, where kind is "priority" for
+// priority events with priority routing enabled and "events" otherwise.
 func (p *Publisher) deriveSubject(event *pb.EventEnvelope) string {
 	category, eventType := events.GetCategoryAndType(event)
 
@@ -110,7 +171,12 @@ func (p *Publisher) deriveSubject(event *pb.EventEnvelope) string {
 		eventType = events.SanitizeSubjectName(eventType)
 	}
 
-	return fmt.Sprintf("events.%s.%s.%s", appID, category, eventType)
+	kind := "events"
+	if p.priority && IsPriorityEvent(event) {
+		kind = PrioritySubjectPrefix
+	}
+
+	return fmt.Sprintf("%s.%s.%s.%s", kind, appID, category, eventType)
 }
 
 // DeriveSubjectForTest exposes subject derivation for testing.
@@ -0,0 +1,132 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync/atomic"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Sampler deterministically selects a fraction of messages by hashing their
+// payload. The same message is always either in or out of the sample, so
+// consumers sampling at the same rate see the same events and redeliveries
+// are sampled consistently.
+type Sampler struct {
+	rate      float64
+	threshold uint64
+}
+
+// NewSampler creates a sampler keeping the given fraction of messages.
+// Rate must be in (0, 1].
+func NewSampler(rate float64) (*Sampler, error) {
+	if math.IsNaN(rate) || rate <= 0 || rate > 1 {
+		return nil, fmt.Errorf("%w: %v (want 0 < rate <= 1)", ErrInvalidSampleRate, rate)
+	}
+
+	threshold := uint64(math.MaxUint64)
+	if rate < 1 {
+		threshold = uint64(rate * math.MaxUint64)
+	}
+
+	return &Sampler{rate: rate, threshold: threshold}, nil
+}
+
+// Rate returns the fraction of messages kept.
+func (s *Sampler) Rate() float64 {
+	return s.rate
+}
+
+// Keep reports whether a message with the given payload is in the sample.
+func (s *Sampler) Keep(data []byte) bool {
+	if s.threshold == math.MaxUint64 {
+		return true
+	}
+	h := fnv.New64a()
+	_, _ = h.Write(data)
+	return mix64(h.Sum64()) < s.threshold
+}
+
+// mix64 is the MurmurHash3 64-bit finalizer. FNV-1a alone leaves the high
+// bits poorly mixed for similar payloads, which skews threshold sampling.
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// SampledConsumerConfig holds configuration for a sampled consumer.
+type SampledConsumerConfig struct {
+	ConsumerConfig
+
+	// Rate is the fraction of messages delivered to the handler (e.g. 0.01
+	// for 1% of traffic).
+	Rate float64
+}
+
+// SampledConsumer is a durable JetStream consumer that passes only a
+// deterministic sample of messages to its handler. Messages outside the
+// sample are acknowledged without being handled.
+type SampledConsumer struct {
+	consumer jetstream.Consumer
+	sampler  *Sampler
+
+	kept    atomic.Uint64
+	skipped atomic.Uint64
+}
+
+// EnsureSampledConsumer creates or updates a durable consumer on the stream
+// and wraps it with a sampler, so experimental or expensive consumers can
+// process a fraction of traffic without their own sampling logic.
+func (m *StreamManager) EnsureSampledConsumer(ctx context.Context, stream jetstream.Stream, cfg SampledConsumerConfig) (*SampledConsumer, error) {
+	sampler, err := NewSampler(cfg.Rate)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.ensureConsumer(ctx, stream, cfg.ConsumerConfig); err != nil {
+		return nil, fmt.Errorf("failed to ensure consumer %s: %w", cfg.Name, err)
+	}
+
+	consumer, err := stream.Consumer(ctx, cfg.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consumer %s: %w", cfg.Name, err)
+	}
+
+	m.logger.Info("sampled consumer ready",
+		"name", cfg.Name,
+		"filter", cfg.FilterSubject,
+		"rate", cfg.Rate,
+	)
+
+	return &SampledConsumer{consumer: consumer, sampler: sampler}, nil
+}
+
+// Consume starts delivering sampled messages to handler. Messages outside
+// the sample are acknowledged immediately.
+func (c *SampledConsumer) Consume(handler jetstream.MessageHandler, opts ...jetstream.PullConsumeOpt) (jetstream.ConsumeContext, error) {
+	return c.consumer.Consume(c.wrap(handler), opts...)
+}
+
+// Stats returns the number of messages handled and skipped since creation.
+func (c *SampledConsumer) Stats() (kept, skipped uint64) {
+	return c.kept.Load(), c.skipped.Load()
+}
+
+// wrap returns a handler that forwards only sampled messages.
+func (c *SampledConsumer) wrap(handler jetstream.MessageHandler) jetstream.MessageHandler {
+	return func(msg jetstream.Msg) {
+		if !c.sampler.Keep(msg.Data()) {
+			c.skipped.Add(1)
+			_ = msg.Ack()
+			return
+		}
+		c.kept.Add(1)
+		handler(msg)
+	}
+}
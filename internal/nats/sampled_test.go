@@ -0,0 +1,100 @@
+package nats
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func TestNewSampler_InvalidRate(t *testing.T) {
+	for _, rate := range []float64{0, -0.1, 1.5, math.NaN()} {
+		if _, err := NewSampler(rate); !errors.Is(err, ErrInvalidSampleRate) {
+			t.Errorf("NewSampler(%v) error = %v, want ErrInvalidSampleRate", rate, err)
+		}
+	}
+}
+
+func TestSampler_Rate(t *testing.T) {
+	sampler, err := NewSampler(0.01)
+	if err != nil {
+		t.Fatalf("NewSampler: %v", err)
+	}
+
+	const n = 100000
+	kept := 0
+	for i := range n {
+		if sampler.Keep([]byte("event-" + strconv.Itoa(i))) {
+			kept++
+		}
+	}
+
+	// 1% of 100k is 1000; allow generous slack for hash distribution.
+	if kept < 800 || kept > 1200 {
+		t.Errorf("kept %d of %d, want about 1000", kept, n)
+	}
+}
+
+func TestSampler_Deterministic(t *testing.T) {
+	a, _ := NewSampler(0.1)
+	b, _ := NewSampler(0.1)
+	for i := range 1000 {
+		data := []byte("event-" + strconv.Itoa(i))
+		if a.Keep(data) != b.Keep(data) || a.Keep(data) != a.Keep(data) {
+			t.Fatalf("sampling of %q is not deterministic", data)
+		}
+	}
+}
+
+func TestSampler_FullRateKeepsEverything(t *testing.T) {
+	sampler, _ := NewSampler(1)
+	for i := range 1000 {
+		if !sampler.Keep([]byte(strconv.Itoa(i))) {
+			t.Fatalf("rate 1 dropped message %d", i)
+		}
+	}
+}
+
+// fakeMsg is a jetstream.Msg that records acks.
+type fakeMsg struct {
+	jetstream.Msg
+	data  []byte
+	acked bool
+}
+
+func (m *fakeMsg) Data() []byte { return m.data }
+
+func (m *fakeMsg) Ack() error {
+	m.acked = true
+	return nil
+}
+
+func TestSampledConsumer_AcksSkippedMessages(t *testing.T) {
+	sampler, _ := NewSampler(0.5)
+	c := &SampledConsumer{sampler: sampler}
+
+	handled := 0
+	handler := c.wrap(func(msg jetstream.Msg) { handled++ })
+
+	var msgs []*fakeMsg
+	for i := range 200 {
+		msg := &fakeMsg{data: []byte("event-" + strconv.Itoa(i))}
+		msgs = append(msgs, msg)
+		handler(msg)
+	}
+
+	kept, skipped := c.Stats()
+	if kept+skipped != 200 || int(kept) != handled {
+		t.Fatalf("stats: kept=%d skipped=%d handled=%d, want kept == handled and total 200", kept, skipped, handled)
+	}
+	if kept == 0 || skipped == 0 {
+		t.Fatalf("stats: kept=%d skipped=%d, want both non-zero at rate 0.5", kept, skipped)
+	}
+	for _, msg := range msgs {
+		if msg.acked == sampler.Keep(msg.data) {
+			t.Errorf("message %q: acked=%v, want only skipped messages acked by the sampler", msg.data, msg.acked)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package nats
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// ScalingConfig configures lag-based auto-scaling of a consumer's fetch
+// workers. When disabled, the consumer runs a fixed number of workers.
+type ScalingConfig struct {
+	// Enabled turns on auto-scaling between MinWorkers and MaxWorkers.
+	Enabled bool `env:"ENABLED" envDefault:"false"`
+
+	// MinWorkers is the lowest worker count.
+	MinWorkers int `env:"MIN_WORKERS" envDefault:"1"`
+
+	// MaxWorkers is the highest worker count.
+	MaxWorkers int `env:"MAX_WORKERS" envDefault:"8"`
+
+	// Interval is how often the consumer's pending count is checked.
+	Interval time.Duration `env:"INTERVAL" envDefault:"30s"`
+
+	// ScaleUpPending is the number of pending messages per worker above
+	// which workers are added, one per ScaleUpPending pending messages.
+	ScaleUpPending uint64 `env:"SCALE_UP_PENDING" envDefault:"10000"`
+
+	// ScaleDownPending is the number of pending messages per worker below
+	// which one worker is removed.
+	ScaleDownPending uint64 `env:"SCALE_DOWN_PENDING" envDefault:"1000"`
+}
+
+// bounds returns the worker count bounds, with the fixed count used when
+// scaling is disabled.
+func (c ScalingConfig) bounds(fixed int) (lo, hi int) {
+	fixed = max(fixed, 1)
+	if !c.Enabled {
+		return fixed, fixed
+	}
+	lo = max(c.MinWorkers, 1)
+	hi = max(c.MaxWorkers, lo)
+	return lo, hi
+}
+
+// MaxWorkerCount returns the most workers a consumer configured with cfg and
+// the fixed worker count runs at once.
+func MaxWorkerCount(cfg ScalingConfig, fixed int) int {
+	_, hi := cfg.bounds(fixed)
+	return hi
+}
+
+// desired returns the worker count for the given pending count. Workers are
+// added at once to match the backlog, and removed one at a time so that a
+// brief lull does not collapse the pool.
+func (c ScalingConfig) desired(current int, pending uint64, lo, hi int) int {
+	target := current
+	switch {
+	case c.ScaleUpPending > 0 && pending > c.ScaleUpPending*uint64(current):
+		target = int((pending + c.ScaleUpPending - 1) / c.ScaleUpPending)
+	case pending < c.ScaleDownPending*uint64(current):
+		target = current - 1
+	}
+	return min(max(target, lo), hi)
+}
+
+// WorkerFunc runs one fetch worker until ctx is done or stop is closed.
+// Worker ids are dense: with n workers running, ids are 0 through n-1.
+type WorkerFunc func(ctx context.Context, id int, stop <-chan struct{})
+
+// PendingFunc returns the number of messages waiting for a consumer.
+type PendingFunc func(ctx context.Context) (uint64, error)
+
+// ConsumerPending returns a PendingFunc reading the pending count of a
+// JetStream consumer.
+func ConsumerPending(consumer jetstream.Consumer) PendingFunc {
+	return func(ctx context.Context) (uint64, error) {
+		info, err := consumer.Info(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return info.NumPending, nil
+	}
+}
+
+// WorkerScaler runs a pool of fetch workers and, when scaling is enabled,
+// periodically resizes it within its bounds based on the consumer's pending
+// count. Workers are removed newest first.
+type WorkerScaler struct {
+	config  ScalingConfig
+	lo, hi  int
+	run     WorkerFunc
+	pending PendingFunc
+	logger  *slog.Logger
+
+	mu      sync.Mutex
+	stops   []chan struct{}
+	stopped bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewWorkerScaler creates a scaler running fixed workers, or between
+// cfg.MinWorkers and cfg.MaxWorkers when cfg.Enabled is set.
+func NewWorkerScaler(cfg ScalingConfig, fixed int, run WorkerFunc, pending PendingFunc, logger *slog.Logger) *WorkerScaler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	lo, hi := cfg.bounds(fixed)
+	return &WorkerScaler{
+		config:  cfg,
+		lo:      lo,
+		hi:      hi,
+		run:     run,
+		pending: pending,
+		logger:  logger.With("component", "worker-scaler"),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start starts the initial workers and, when scaling is enabled, the scaling
+// loop. Workers run until ctx is done or Stop is called.
+func (s *WorkerScaler) Start(ctx context.Context, initial int) {
+	s.resize(ctx, min(max(initial, s.lo), s.hi))
+
+	if s.config.Enabled && s.config.Interval > 0 && s.pending != nil {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.scaleLoop(ctx)
+		}()
+	}
+}
+
+// Workers returns the number of running workers.
+func (s *WorkerScaler) Workers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.stops)
+}
+
+// Stop stops all workers and the scaling loop.
+func (s *WorkerScaler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return
+	}
+	for _, stop := range s.stops {
+		close(stop)
+	}
+	s.stops = nil
+	s.stopped = true
+	close(s.done)
+}
+
+// Wait blocks until all workers and the scaling loop have returned.
+func (s *WorkerScaler) Wait() {
+	s.wg.Wait()
+}
+
+// scaleLoop checks the pending count every interval and resizes the pool.
+func (s *WorkerScaler) scaleLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.scale(ctx)
+		}
+	}
+}
+
+// scale resizes the pool for the current pending count.
+func (s *WorkerScaler) scale(ctx context.Context) {
+	pending, err := s.pending(ctx)
+	if err != nil {
+		s.logger.Warn("failed to read consumer pending count", "error", err)
+		return
+	}
+
+	current := s.Workers()
+	target := s.config.desired(current, pending, s.lo, s.hi)
+	if target != current {
+		s.logger.Info("scaling consumer workers",
+			"from", current,
+			"to", target,
+			"pending", pending,
+		)
+		s.resize(ctx, target)
+	}
+}
+
+// resize starts or stops workers until n are running. It does nothing once
+// the pool has been stopped.
+func (s *WorkerScaler) resize(ctx context.Context, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return
+	}
+
+	for len(s.stops) < n {
+		stop := make(chan struct{})
+		id := len(s.stops)
+		s.stops = append(s.stops, stop)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.run(ctx, id, stop)
+		}()
+	}
+	for len(s.stops) > n {
+		last := len(s.stops) - 1
+		close(s.stops[last])
+		s.stops = s.stops[:last]
+	}
+}
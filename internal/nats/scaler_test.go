@@ -0,0 +1,95 @@
+package nats
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScalingConfig_Desired(t *testing.T) {
+	cfg := ScalingConfig{Enabled: true, ScaleUpPending: 1000, ScaleDownPending: 100}
+
+	tests := []struct {
+		name    string
+		current int
+		pending uint64
+		want    int
+	}{
+		{"steady", 2, 1500, 2},
+		{"scale up to backlog", 1, 3500, 4},
+		{"scale up capped", 2, 100000, 6},
+		{"scale down one", 4, 300, 3},
+		{"scale down floored", 2, 0, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.desired(tt.current, tt.pending, 2, 6); got != tt.want {
+				t.Errorf("desired(%d, %d) = %d, want %d", tt.current, tt.pending, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScalingConfig_BoundsDisabled(t *testing.T) {
+	cfg := ScalingConfig{MinWorkers: 2, MaxWorkers: 8}
+	if lo, hi := cfg.bounds(3); lo != 3 || hi != 3 {
+		t.Errorf("bounds() = %d, %d, want the fixed count 3", lo, hi)
+	}
+	if got := MaxWorkerCount(cfg, 0); got != 1 {
+		t.Errorf("MaxWorkerCount() = %d, want 1", got)
+	}
+}
+
+func TestWorkerScaler_ScalesWithPending(t *testing.T) {
+	var pending atomic.Uint64
+	var running atomic.Int64
+	run := func(ctx context.Context, _ int, stop <-chan struct{}) {
+		running.Add(1)
+		defer running.Add(-1)
+		select {
+		case <-ctx.Done():
+		case <-stop:
+		}
+	}
+
+	cfg := ScalingConfig{
+		Enabled:          true,
+		MinWorkers:       1,
+		MaxWorkers:       4,
+		Interval:         5 * time.Millisecond,
+		ScaleUpPending:   100,
+		ScaleDownPending: 10,
+	}
+	s := NewWorkerScaler(cfg, 1, run, func(context.Context) (uint64, error) {
+		return pending.Load(), nil
+	}, nil)
+
+	s.Start(context.Background(), 1)
+	waitWorkers(t, s, &running, 1)
+
+	pending.Store(1000)
+	waitWorkers(t, s, &running, 4)
+
+	pending.Store(0)
+	waitWorkers(t, s, &running, 1)
+
+	s.Stop()
+	s.Wait()
+	if n := running.Load(); n != 0 {
+		t.Errorf("running workers after Stop = %d, want 0", n)
+	}
+}
+
+// waitWorkers waits until the scaler and its workers settle at want.
+func waitWorkers(t *testing.T, s *WorkerScaler, running *atomic.Int64, want int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if s.Workers() == want && running.Load() == int64(want) {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("workers = %d (running %d), want %d", s.Workers(), running.Load(), want)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/nats-io/nats.go/jetstream"
 )
@@ -29,15 +30,33 @@ func NewStreamManager(js jetstream.JetStream, cfg StreamConfig, logger *slog.Log
 }
 
 // EnsureStream creates or updates the stream with the configured settings.
+// With priority routing enabled, it first creates or updates the priority
+// stream, which the main stream sources.
 func (m *StreamManager) EnsureStream(ctx context.Context) (jetstream.Stream, error) {
 	storage := jetstream.FileStorage
 	if strings.ToLower(m.config.Storage) == "memory" {
 		storage = jetstream.MemoryStorage
 	}
 
+	subjects := m.eventSubjects()
+
+	var sources []*jetstream.StreamSource
+	if m.config.PriorityEnabled {
+		if err := m.ensurePriorityStream(ctx, storage); err != nil {
+			return nil, err
+		}
+		sources = []*jetstream.StreamSource{{
+			Name: m.config.PriorityStreamName,
+			SubjectTransforms: []jetstream.SubjectTransformConfig{{
+				Source:      PrioritySubjects,
+				Destination: "events.>",
+			}},
+		}}
+	}
+
 	streamCfg := jetstream.StreamConfig{
 		Name:        m.config.Name,
-		Subjects:    m.config.Subjects,
+		Subjects:    subjects,
 		Storage:     storage,
 		MaxAge:      m.config.MaxAge,
 		MaxBytes:    m.config.MaxBytes,
@@ -45,6 +64,7 @@ func (m *StreamManager) EnsureStream(ctx context.Context) (jetstream.Stream, err
 		Retention:   jetstream.LimitsPolicy,
 		Discard:     jetstream.DiscardOld,
 		AllowDirect: true,
+		Sources:     sources,
 	}
 
 	// Try to get existing stream first
@@ -61,7 +81,7 @@ func (m *StreamManager) EnsureStream(ctx context.Context) (jetstream.Stream, err
 	}
 
 	// Stream doesn't exist, create it
-	m.logger.Info("creating new stream", "name", m.config.Name, "subjects", m.config.Subjects)
+	m.logger.Info("creating new stream", "name", m.config.Name, "subjects", subjects)
 	stream, err := m.js.CreateStream(ctx, streamCfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create stream: %w", err)
@@ -77,6 +97,31 @@ func (m *StreamManager) EnsureStream(ctx context.Context) (jetstream.Stream, err
 	return stream, nil
 }
 
+// eventSubjects returns the configured subjects of the main stream, without
+// those in a derived family or priority subjects, which the derived and
+// priority streams capture instead.
+func (m *StreamManager) eventSubjects() []string {
+	subjects := make([]string, 0, len(m.config.Subjects))
+	for _, subject := range m.config.Subjects {
+		if isDerivedFilter(subject) {
+			m.logger.Warn("ignoring derived subject in main stream subjects; it is captured by the derived stream",
+				"subject", subject,
+				"derived_stream", m.config.DerivedStreamName,
+			)
+			continue
+		}
+		if first, _, _ := strings.Cut(subject, "."); first == PrioritySubjectPrefix {
+			m.logger.Warn("ignoring priority subject in main stream subjects; it is captured by the priority stream",
+				"subject", subject,
+				"priority_stream", m.config.PriorityStreamName,
+			)
+			continue
+		}
+		subjects = append(subjects, subject)
+	}
+	return subjects
+}
+
 // EnsureConsumers creates the default consumers for the stream.
 func (m *StreamManager) EnsureConsumers(ctx context.Context, stream jetstream.Stream, configs []ConsumerConfig) error {
 	for _, cfg := range configs {
@@ -97,10 +142,24 @@ func (m *StreamManager) ensureConsumer(ctx context.Context, stream jetstream.Str
 		MaxDeliver:    cfg.MaxDeliver,
 		DeliverPolicy: jetstream.DeliverAllPolicy,
 	}
+	if len(cfg.FilterSubjects) > 0 {
+		consumerCfg.FilterSubject = ""
+		consumerCfg.FilterSubjects = cfg.FilterSubjects
+	}
+	if cfg.StartTime != nil {
+		consumerCfg.DeliverPolicy = jetstream.DeliverByStartTimePolicy
+		consumerCfg.OptStartTime = cfg.StartTime
+	}
 
 	// Try to get existing consumer first
-	_, err := stream.Consumer(ctx, cfg.Name)
+	existing, err := stream.Consumer(ctx, cfg.Name)
 	if err == nil {
+		if cfg.StartTime != nil {
+			existingCfg := existing.CachedInfo().Config
+			consumerCfg.DeliverPolicy = existingCfg.DeliverPolicy
+			consumerCfg.OptStartTime = existingCfg.OptStartTime
+		}
+
 		// Consumer exists, update it
 		m.logger.Info("updating existing consumer", "name", cfg.Name)
 		_, err = stream.UpdateConsumer(ctx, consumerCfg)
@@ -114,6 +173,7 @@ func (m *StreamManager) ensureConsumer(ctx context.Context, stream jetstream.Str
 	m.logger.Info("creating new consumer",
 		"name", cfg.Name,
 		"filter", cfg.FilterSubject,
+		"filters", cfg.FilterSubjects,
 	)
 	_, err = stream.CreateConsumer(ctx, consumerCfg)
 	if err != nil {
@@ -170,6 +230,260 @@ func (m *StreamManager) EnsureDLQStream(ctx context.Context) (jetstream.Stream,
 	return stream, nil
 }
 
+// EnsureAuditStream creates or updates the ingestion audit log stream.
+// The audit stream captures per-request audit records published to "audit.>"
+// subjects by the HTTP gateway, and admin change events relayed from the
+// outbox to "audit.admin.>". It is kept separate from the main event stream
+// so that event consumers never see audit records.
+func (m *StreamManager) EnsureAuditStream(ctx context.Context) (jetstream.Stream, error) {
+	auditCfg := jetstream.StreamConfig{
+		Name:        m.config.AuditStreamName,
+		Subjects:    []string{"audit.>"},
+		Storage:     jetstream.FileStorage,
+		MaxAge:      m.config.AuditMaxAge,
+		Retention:   jetstream.LimitsPolicy,
+		Discard:     jetstream.DiscardOld,
+		AllowDirect: true,
+	}
+
+	// Try to get existing stream first
+	_, err := m.js.Stream(ctx, m.config.AuditStreamName)
+	if err == nil {
+		// Stream exists, update it
+		m.logger.Info("updating existing audit stream", "name", m.config.AuditStreamName)
+		stream, updateErr := m.js.UpdateStream(ctx, auditCfg)
+		if updateErr != nil {
+			return nil, fmt.Errorf("failed to update audit stream: %w", updateErr)
+		}
+		m.logger.Info("audit stream updated", "name", m.config.AuditStreamName)
+		return stream, nil
+	}
+
+	// Stream doesn't exist, create it
+	m.logger.Info("creating new audit stream",
+		"name", m.config.AuditStreamName,
+		"subjects", auditCfg.Subjects,
+		"max_age", m.config.AuditMaxAge,
+	)
+	stream, err := m.js.CreateStream(ctx, auditCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit stream: %w", err)
+	}
+
+	m.logger.Info("audit stream created",
+		"name", m.config.AuditStreamName,
+		"max_age", m.config.AuditMaxAge,
+	)
+
+	return stream, nil
+}
+
+// ensurePriorityStream creates or updates the priority stream, with the
+// retention, storage and replicas of the main stream.
+func (m *StreamManager) ensurePriorityStream(ctx context.Context, storage jetstream.StorageType) error {
+	priorityCfg := jetstream.StreamConfig{
+		Name:        m.config.PriorityStreamName,
+		Subjects:    []string{PrioritySubjects},
+		Storage:     storage,
+		MaxAge:      m.config.MaxAge,
+		MaxBytes:    m.config.MaxBytes,
+		Replicas:    m.config.Replicas,
+		Retention:   jetstream.LimitsPolicy,
+		Discard:     jetstream.DiscardOld,
+		AllowDirect: true,
+	}
+
+	// Try to get existing stream first
+	_, err := m.js.Stream(ctx, m.config.PriorityStreamName)
+	if err == nil {
+		// Stream exists, update it
+		if _, err := m.js.UpdateStream(ctx, priorityCfg); err != nil {
+			return fmt.Errorf("failed to update priority stream: %w", err)
+		}
+		m.logger.Info("priority stream updated", "name", m.config.PriorityStreamName)
+		return nil
+	}
+
+	// Stream doesn't exist, create it
+	if _, err := m.js.CreateStream(ctx, priorityCfg); err != nil {
+		return fmt.Errorf("failed to create priority stream: %w", err)
+	}
+	m.logger.Info("priority stream created",
+		"name", m.config.PriorityStreamName,
+		"subjects", priorityCfg.Subjects,
+	)
+	return nil
+}
+
+// PriorityStream returns the priority stream created by EnsureStream.
+func (m *StreamManager) PriorityStream(ctx context.Context) (jetstream.Stream, error) {
+	stream, err := m.js.Stream(ctx, m.config.PriorityStreamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get priority stream: %w", err)
+	}
+	return stream, nil
+}
+
+// ProbeSubjects is the subject filter of the probe stream. The gateway
+// publishes synthetic messages to it to check that JetStream acks publishes.
+const ProbeSubjects = "probe.>"
+
+// EnsureProbeStream creates or updates the probe stream. It uses the storage
+// and replicas of the main stream, so a probe is acked only when an event
+// could be, and keeps just the latest message per subject. Like the audit
+// stream, it is separate so that event consumers never see probes.
+func (m *StreamManager) EnsureProbeStream(ctx context.Context) (jetstream.Stream, error) {
+	storage := jetstream.FileStorage
+	if strings.ToLower(m.config.Storage) == "memory" {
+		storage = jetstream.MemoryStorage
+	}
+
+	probeCfg := jetstream.StreamConfig{
+		Name:              m.config.ProbeStreamName,
+		Subjects:          []string{ProbeSubjects},
+		Storage:           storage,
+		Replicas:          m.config.Replicas,
+		MaxMsgsPerSubject: 1,
+		MaxAge:            time.Hour,
+		Retention:         jetstream.LimitsPolicy,
+		Discard:           jetstream.DiscardOld,
+	}
+
+	// Try to get existing stream first
+	_, err := m.js.Stream(ctx, m.config.ProbeStreamName)
+	if err == nil {
+		// Stream exists, update it
+		stream, updateErr := m.js.UpdateStream(ctx, probeCfg)
+		if updateErr != nil {
+			return nil, fmt.Errorf("failed to update probe stream: %w", updateErr)
+		}
+		m.logger.Info("probe stream updated", "name", m.config.ProbeStreamName)
+		return stream, nil
+	}
+
+	// Stream doesn't exist, create it
+	stream, err := m.js.CreateStream(ctx, probeCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create probe stream: %w", err)
+	}
+
+	m.logger.Info("probe stream created",
+		"name", m.config.ProbeStreamName,
+		"storage", m.config.Storage,
+	)
+
+	return stream, nil
+}
+
+// DeliverySubjects is the subject filter of the delivery stream. The
+// reaction engine publishes the ID of each webhook delivery it creates to
+// deliveries.{webhook_id}.
+const DeliverySubjects = "deliveries.>"
+
+// EnsureDeliveryStream creates or updates the webhook delivery work queue
+// stream. Each message is removed once a dispatcher worker acks it; the
+// deliveries themselves stay in PostgreSQL, so the stream only holds IDs and
+// inherits the main stream's replicas.
+func (m *StreamManager) EnsureDeliveryStream(ctx context.Context) (jetstream.Stream, error) {
+	deliveryCfg := jetstream.StreamConfig{
+		Name:      m.config.DeliveryStreamName,
+		Subjects:  []string{DeliverySubjects},
+		Storage:   jetstream.FileStorage,
+		Replicas:  m.config.Replicas,
+		Retention: jetstream.WorkQueuePolicy,
+		Discard:   jetstream.DiscardOld,
+	}
+
+	// Try to get existing stream first
+	_, err := m.js.Stream(ctx, m.config.DeliveryStreamName)
+	if err == nil {
+		// Stream exists, update it
+		stream, updateErr := m.js.UpdateStream(ctx, deliveryCfg)
+		if updateErr != nil {
+			return nil, fmt.Errorf("failed to update delivery stream: %w", updateErr)
+		}
+		m.logger.Info("delivery stream updated", "name", m.config.DeliveryStreamName)
+		return stream, nil
+	}
+
+	// Stream doesn't exist, create it
+	stream, err := m.js.CreateStream(ctx, deliveryCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create delivery stream: %w", err)
+	}
+
+	m.logger.Info("delivery stream created",
+		"name", m.config.DeliveryStreamName,
+		"subjects", deliveryCfg.Subjects,
+	)
+
+	return stream, nil
+}
+
+// EnsureDerivedStream creates or updates the derived event stream, which
+// captures every derived subject family (see DerivedFamilies) with its own
+// retention. Call it after EnsureStream, so that the main stream has
+// released any derived subjects it captured before.
+func (m *StreamManager) EnsureDerivedStream(ctx context.Context) (jetstream.Stream, error) {
+	derivedCfg := jetstream.StreamConfig{
+		Name:        m.config.DerivedStreamName,
+		Subjects:    derivedSubjects(),
+		Storage:     jetstream.FileStorage,
+		MaxAge:      m.config.DerivedMaxAge,
+		Replicas:    m.config.Replicas,
+		Retention:   jetstream.LimitsPolicy,
+		Discard:     jetstream.DiscardOld,
+		AllowDirect: true,
+	}
+
+	// Try to get existing stream first
+	_, err := m.js.Stream(ctx, m.config.DerivedStreamName)
+	if err == nil {
+		// Stream exists, update it
+		m.logger.Info("updating existing derived stream", "name", m.config.DerivedStreamName)
+		stream, updateErr := m.js.UpdateStream(ctx, derivedCfg)
+		if updateErr != nil {
+			return nil, fmt.Errorf("failed to update derived stream: %w", updateErr)
+		}
+		m.logger.Info("derived stream updated", "name", m.config.DerivedStreamName)
+		return stream, nil
+	}
+
+	// Stream doesn't exist, create it
+	m.logger.Info("creating new derived stream",
+		"name", m.config.DerivedStreamName,
+		"subjects", derivedCfg.Subjects,
+		"max_age", m.config.DerivedMaxAge,
+	)
+	stream, err := m.js.CreateStream(ctx, derivedCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create derived stream: %w", err)
+	}
+
+	m.logger.Info("derived stream created",
+		"name", m.config.DerivedStreamName,
+		"max_age", m.config.DerivedMaxAge,
+	)
+
+	return stream, nil
+}
+
+// DerivedSubjects returns the derived subjects currently holding messages in
+// the derived stream, with their message counts, sorted by subject.
+func (m *StreamManager) DerivedSubjects(ctx context.Context) ([]DerivedSubjectInfo, error) {
+	stream, err := m.js.Stream(ctx, m.config.DerivedStreamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get derived stream: %w", err)
+	}
+
+	info, err := stream.Info(ctx, jetstream.WithSubjectFilter(">"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get derived stream info: %w", err)
+	}
+
+	return derivedSubjectInfos(info.State.Subjects), nil
+}
+
 // GetStreamInfo returns information about the stream.
 func (m *StreamManager) GetStreamInfo(ctx context.Context) (*jetstream.StreamInfo, error) {
 	stream, err := m.js.Stream(ctx, m.config.Name)
@@ -0,0 +1,81 @@
+package observability
+
+import (
+	"context"
+	"expvar"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/signal"
+	runtimepprof "runtime/pprof"
+	"syscall"
+)
+
+// DebugConfig gates the runtime diagnostics endpoints. All are disabled by
+// default; only enable them on listeners that are not publicly reachable.
+type DebugConfig struct {
+	// PprofEnabled serves net/http/pprof under /debug/pprof/.
+	PprofEnabled bool `env:"DEBUG_PPROF_ENABLED" envDefault:"false"`
+
+	// ExpvarEnabled serves expvar variables (including runtime.MemStats) at
+	// /debug/vars.
+	ExpvarEnabled bool `env:"DEBUG_EXPVAR_ENABLED" envDefault:"false"`
+
+	// GoroutineDumpOnSIGQUIT writes all goroutine stacks to stderr on
+	// SIGQUIT and keeps the process running, instead of Go's default of
+	// dumping and exiting.
+	GoroutineDumpOnSIGQUIT bool `env:"DEBUG_SIGQUIT_GOROUTINE_DUMP" envDefault:"false"`
+}
+
+// RegisterDebugRoutes mounts the enabled diagnostics endpoints onto mux.
+func RegisterDebugRoutes(mux *http.ServeMux, cfg DebugConfig) {
+	if cfg.PprofEnabled {
+		mux.HandleFunc("GET /debug/pprof/", pprof.Index)
+		mux.HandleFunc("GET /debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("GET /debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("GET /debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("POST /debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
+	}
+	if cfg.ExpvarEnabled {
+		mux.Handle("GET /debug/vars", expvar.Handler())
+	}
+}
+
+// DumpGoroutinesOnSignal installs the SIGQUIT goroutine dump handler if
+// enabled. The handler is removed, restoring the default SIGQUIT behavior,
+// when ctx is canceled.
+func DumpGoroutinesOnSignal(ctx context.Context, cfg DebugConfig, logger *slog.Logger) {
+	if !cfg.GoroutineDumpOnSIGQUIT {
+		return
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGQUIT)
+
+	go func() {
+		defer signal.Stop(sigCh)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigCh:
+				logger.Info("SIGQUIT received, dumping goroutines to stderr")
+				if err := writeGoroutineDump(os.Stderr); err != nil {
+					logger.Error("failed to dump goroutines", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// writeGoroutineDump writes the stacks of all goroutines in the same format
+// as an unrecovered panic.
+func writeGoroutineDump(w io.Writer) error {
+	return runtimepprof.Lookup("goroutine").WriteTo(w, 2)
+}
@@ -0,0 +1,57 @@
+package observability
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestRegisterDebugRoutes verifies diagnostics endpoints are only mounted
+// when enabled.
+func TestRegisterDebugRoutes(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        DebugConfig
+		wantPprof  int
+		wantExpvar int
+	}{
+		{name: "disabled", cfg: DebugConfig{}, wantPprof: http.StatusNotFound, wantExpvar: http.StatusNotFound},
+		{name: "pprof only", cfg: DebugConfig{PprofEnabled: true}, wantPprof: http.StatusOK, wantExpvar: http.StatusNotFound},
+		{name: "both", cfg: DebugConfig{PprofEnabled: true, ExpvarEnabled: true}, wantPprof: http.StatusOK, wantExpvar: http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			RegisterDebugRoutes(mux, tc.cfg)
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil))
+			if rec.Code != tc.wantPprof {
+				t.Errorf("pprof: got status %d, want %d", rec.Code, tc.wantPprof)
+			}
+
+			rec = httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+			if rec.Code != tc.wantExpvar {
+				t.Errorf("expvar: got status %d, want %d", rec.Code, tc.wantExpvar)
+			}
+			if tc.wantExpvar == http.StatusOK && !strings.Contains(rec.Body.String(), "memstats") {
+				t.Error("expvar output should include memstats")
+			}
+		})
+	}
+}
+
+// TestWriteGoroutineDump verifies the dump includes goroutine stacks.
+func TestWriteGoroutineDump(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeGoroutineDump(&buf); err != nil {
+		t.Fatalf("writeGoroutineDump() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "TestWriteGoroutineDump") {
+		t.Error("dump should include the calling goroutine's stack")
+	}
+}
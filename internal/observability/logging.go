@@ -0,0 +1,86 @@
+package observability
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Log attribute keys for request- and event-scoped context. Use these rather
+// than ad-hoc strings so log queries work the same across services.
+const (
+	LogKeyRequestID = "request_id"
+	LogKeyAppID     = "app_id"
+	LogKeyEventID   = "event_id"
+	LogKeyConsumer  = "consumer"
+	LogKeyTraceID   = "trace_id"
+	LogKeySpanID    = "span_id"
+)
+
+// logAttrsKey is the context key for the accumulated log attributes.
+type logAttrsKey struct{}
+
+// WithLogAttrs returns a context carrying args (slog key-value pairs or
+// slog.Attr values) in addition to any attributes already attached by outer
+// layers. A key attached again replaces the earlier value.
+func WithLogAttrs(ctx context.Context, args ...any) context.Context {
+	if len(args) == 0 {
+		return ctx
+	}
+
+	existing := logAttrsFromContext(ctx)
+	added := slog.Group("", args...).Value.Group()
+
+	merged := make([]slog.Attr, 0, len(existing)+len(added))
+	for _, attr := range existing {
+		if !hasKey(added, attr.Key) {
+			merged = append(merged, attr)
+		}
+	}
+	merged = append(merged, added...)
+
+	return context.WithValue(ctx, logAttrsKey{}, merged)
+}
+
+// Logger returns base enriched with the attributes attached to ctx by
+// WithLogAttrs, plus the trace and span IDs of the span context carried by
+// ctx (see ExtractTraceContext) for log-trace correlation.
+func Logger(ctx context.Context, base *slog.Logger) *slog.Logger {
+	if base == nil {
+		base = slog.Default()
+	}
+
+	attrs := logAttrsFromContext(ctx)
+	args := make([]any, 0, len(attrs)+2)
+	for _, attr := range attrs {
+		args = append(args, attr)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		args = append(args,
+			slog.String(LogKeyTraceID, sc.TraceID().String()),
+			slog.String(LogKeySpanID, sc.SpanID().String()),
+		)
+	}
+
+	if len(args) == 0 {
+		return base
+	}
+	return base.With(args...)
+}
+
+// logAttrsFromContext returns the attributes attached to ctx, if any.
+func logAttrsFromContext(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(logAttrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// hasKey reports whether attrs contains an attribute named key.
+func hasKey(attrs []slog.Attr, key string) bool {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package observability
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"testing"
+)
+
+// TestLogger_ContextAttrs verifies attributes accumulate across layers, later
+// values replace earlier ones, and trace IDs are added for correlation.
+func TestLogger_ContextAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	base := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := ExtractTraceContext(context.Background(), header)
+	ctx = WithLogAttrs(ctx, LogKeyRequestID, "req-1", LogKeyAppID, "from-key")
+	ctx = WithLogAttrs(ctx, LogKeyEventID, "evt-1", slog.String(LogKeyAppID, "from-event"))
+
+	Logger(ctx, base).Info("event ingested")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("decode log record: %v", err)
+	}
+	want := map[string]string{
+		LogKeyRequestID: "req-1",
+		LogKeyAppID:     "from-event",
+		LogKeyEventID:   "evt-1",
+		LogKeyTraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+		LogKeySpanID:    "00f067aa0ba902b7",
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("%s = %v, want %q", key, record[key], value)
+		}
+	}
+}
+
+// TestLogger_NoAttrs verifies the base logger is returned unchanged when the
+// context carries nothing.
+func TestLogger_NoAttrs(t *testing.T) {
+	base := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	if got := Logger(context.Background(), base); got != base {
+		t.Error("Logger() should return the base logger for a bare context")
+	}
+}
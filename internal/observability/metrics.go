@@ -1,6 +1,11 @@
 package observability
 
 import (
+	"context"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 	otelmetric "go.opentelemetry.io/otel/metric"
 )
 
@@ -19,6 +24,10 @@ type Metrics struct {
 	NATSFlushLatency      otelmetric.Float64Histogram
 	NATSAckLatency        otelmetric.Float64Histogram
 
+	// Consumer metrics
+	ConsumerProcessDuration otelmetric.Float64Histogram
+	ConsumerErrors          otelmetric.Int64Counter
+
 	// S3 / storage metrics
 	S3FilesWritten otelmetric.Int64Counter
 	S3FileSize     otelmetric.Int64Histogram
@@ -26,6 +35,11 @@ type Metrics struct {
 	// Deduplication metrics
 	DedupDropped otelmetric.Int64Counter
 
+	// Gateway metrics
+	EventsLimited        otelmetric.Int64Counter
+	EventsPublishTimeout otelmetric.Int64Counter
+	EventsUnknownFields  otelmetric.Int64Counter
+
 	// Dead-letter queue metrics
 	DLQDepth otelmetric.Int64UpDownCounter
 
@@ -34,12 +48,26 @@ type Metrics struct {
 	CompactionFilesCompacted    otelmetric.Int64Counter
 	CompactionPartitionsSkipped otelmetric.Int64Counter
 	CompactionDuration          otelmetric.Float64Histogram
+	CompactionSmallFiles        otelmetric.Int64Histogram
+	CompactionBacklogAge        otelmetric.Float64Gauge
+	CompactionAlerts            otelmetric.Int64Counter
+	CompactionRowsMerged        otelmetric.Int64Counter
+	CompactionRowsDuplicate     otelmetric.Int64Counter
 
 	// Reaction engine metrics
 	RulesEvaluated otelmetric.Int64Counter
 	AlertsFired    otelmetric.Int64Counter
 	WebhookSuccess otelmetric.Int64Counter
 	WebhookFailure otelmetric.Int64Counter
+
+	// Webhook delivery SLO metrics (see RecordWebhookAttempt)
+	WebhookAttemptDuration  otelmetric.Float64Histogram
+	WebhookDeliveryAge      otelmetric.Float64Histogram
+	WebhookDeliveryAttempts otelmetric.Int64Histogram
+	WebhookDeliveries       otelmetric.Int64Counter
+
+	// red backs the JSON metrics summary (see SummaryHandler).
+	red *redTracker
 }
 
 // NewMetrics creates all metric instruments from the given Meter.
@@ -110,6 +138,24 @@ func NewMetrics(meter otelmetric.Meter) (*Metrics, error) {
 		return nil, err
 	}
 
+	// Consumer metrics
+	m.ConsumerProcessDuration, err = meter.Float64Histogram(
+		"consumer.process.duration",
+		otelmetric.WithUnit("ms"),
+		otelmetric.WithDescription("Per-message consumer processing duration in milliseconds"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.ConsumerErrors, err = meter.Int64Counter(
+		"consumer.errors",
+		otelmetric.WithDescription("Messages that consumers failed to process"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	// S3 / storage metrics
 	m.S3FilesWritten, err = meter.Int64Counter(
 		"s3.files.written",
@@ -137,6 +183,29 @@ func NewMetrics(meter otelmetric.Meter) (*Metrics, error) {
 		return nil, err
 	}
 
+	// Gateway metrics
+	m.EventsLimited, err = meter.Int64Counter(
+		"gateway.events.limited",
+		otelmetric.WithDescription("Events rejected for exceeding per-event limits, by code"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	m.EventsPublishTimeout, err = meter.Int64Counter(
+		"gateway.events.publish_timeout",
+		otelmetric.WithDescription("Events not published to NATS within the publish timeout"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	m.EventsUnknownFields, err = meter.Int64Counter(
+		"gateway.events.unknown_fields",
+		otelmetric.WithDescription("JSON fields not declared by the event schema, by enclosing field"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	// Dead-letter queue metrics
 	m.DLQDepth, err = meter.Int64UpDownCounter(
 		"dlq.depth",
@@ -180,6 +249,47 @@ func NewMetrics(meter otelmetric.Meter) (*Metrics, error) {
 		return nil, err
 	}
 
+	m.CompactionSmallFiles, err = meter.Int64Histogram(
+		"compaction.partition.small_files",
+		otelmetric.WithDescription("Small files found per partition during compaction"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.CompactionBacklogAge, err = meter.Float64Gauge(
+		"compaction.backlog.age",
+		otelmetric.WithUnit("h"),
+		otelmetric.WithDescription("Age in hours of the oldest partition left uncompacted after a run"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.CompactionAlerts, err = meter.Int64Counter(
+		"compaction.alerts",
+		otelmetric.WithDescription("Compaction alerts fired, by type"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.CompactionRowsMerged, err = meter.Int64Counter(
+		"compaction.rows.merged",
+		otelmetric.WithDescription("Rows read from source files during compaction, by app"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.CompactionRowsDuplicate, err = meter.Int64Counter(
+		"compaction.rows.duplicate",
+		otelmetric.WithDescription("Rows dropped by compaction deduplication because their event id was already seen, by app"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	// Reaction engine metrics
 	m.RulesEvaluated, err = meter.Int64Counter(
 		"rules.evaluated",
@@ -213,5 +323,126 @@ func NewMetrics(meter otelmetric.Meter) (*Metrics, error) {
 		return nil, err
 	}
 
+	m.WebhookAttemptDuration, err = meter.Float64Histogram(
+		"webhook.attempt.duration",
+		otelmetric.WithUnit("ms"),
+		otelmetric.WithDescription("Webhook request latency in milliseconds, by outcome and status class"),
+		otelmetric.WithExplicitBucketBoundaries(5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.WebhookDeliveryAge, err = meter.Float64Histogram(
+		"webhook.delivery.age",
+		otelmetric.WithUnit("s"),
+		otelmetric.WithDescription("Time from a webhook delivery's creation to its successful attempt, in seconds"),
+		otelmetric.WithExplicitBucketBoundaries(1, 2, 5, 10, 30, 60, 120, 300, 900, 1800, 3600),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.WebhookDeliveryAttempts, err = meter.Int64Histogram(
+		"webhook.delivery.attempts",
+		otelmetric.WithDescription("Attempts made by webhook deliveries that were delivered or dead-lettered, by status"),
+		otelmetric.WithExplicitBucketBoundaries(1, 2, 3, 4, 5, 10, 20),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.WebhookDeliveries, err = meter.Int64Counter(
+		"webhook.deliveries",
+		otelmetric.WithDescription("Webhook delivery attempts by resulting status (delivered, retrying, dead_letter)"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.red = newREDTracker()
+
 	return &m, nil
 }
+
+// RecordConsumed records that a consumer processed messages messages in
+// duration, failed of which could not be processed. The processing duration
+// is recorded per message; pass a context carrying the producer's trace
+// (see ExtractTraceContext) to attach a trace exemplar.
+func (m *Metrics) RecordConsumed(ctx context.Context, consumer string, messages, failed int, duration time.Duration) {
+	if messages <= 0 {
+		return
+	}
+
+	attrs := otelmetric.WithAttributes(attribute.String("consumer", consumer))
+	perMessage := float64(duration) / float64(time.Millisecond) / float64(messages)
+	for range messages {
+		m.ConsumerProcessDuration.Record(ctx, perMessage, attrs)
+	}
+	if failed > 0 {
+		m.ConsumerErrors.Add(ctx, int64(failed), attrs)
+	}
+
+	if m.red != nil {
+		m.red.record(redKindConsumer, consumer, int64(messages), int64(failed), duration)
+	}
+}
+
+// Webhook delivery statuses recorded by RecordWebhookDelivered and
+// RecordWebhookFailed.
+const (
+	WebhookStatusDelivered  = "delivered"
+	WebhookStatusRetrying   = "retrying"
+	WebhookStatusDeadLetter = "dead_letter"
+)
+
+// RecordWebhookAttempt records the latency of one webhook request, which
+// carries deliveries deliveries when batched. statusCode is 0 when no
+// response was received.
+func (m *Metrics) RecordWebhookAttempt(ctx context.Context, statusCode int, failed bool, deliveries int, duration time.Duration) {
+	outcome := "success"
+	counter := m.WebhookSuccess
+	if failed {
+		outcome = "failure"
+		counter = m.WebhookFailure
+	}
+
+	m.WebhookAttemptDuration.Record(ctx, float64(duration)/float64(time.Millisecond), otelmetric.WithAttributes(
+		attribute.String("outcome", outcome),
+		attribute.String("status_class", statusClass(statusCode)),
+	))
+	counter.Add(ctx, int64(deliveries))
+}
+
+// RecordWebhookDelivered records a delivery that succeeded on its attempts-th
+// attempt, age after it was created. Its age distribution tracks SLOs such
+// as "99% of webhooks delivered within 60s".
+func (m *Metrics) RecordWebhookDelivered(ctx context.Context, age time.Duration, attempts int) {
+	attrs := otelmetric.WithAttributes(attribute.String("status", WebhookStatusDelivered))
+	m.WebhookDeliveryAge.Record(ctx, age.Seconds())
+	m.WebhookDeliveryAttempts.Record(ctx, int64(attempts), attrs)
+	m.WebhookDeliveries.Add(ctx, 1, attrs)
+}
+
+// RecordWebhookFailed records a failed attempt of a delivery, its
+// attempts-th, after which it is retried or, when deadLettered, given up.
+func (m *Metrics) RecordWebhookFailed(ctx context.Context, deadLettered bool, attempts int) {
+	status := WebhookStatusRetrying
+	if deadLettered {
+		status = WebhookStatusDeadLetter
+	}
+	attrs := otelmetric.WithAttributes(attribute.String("status", status))
+	if deadLettered {
+		m.WebhookDeliveryAttempts.Record(ctx, int64(attempts), attrs)
+	}
+	m.WebhookDeliveries.Add(ctx, 1, attrs)
+}
+
+// statusClass returns the class of an HTTP status code, such as "2xx", or
+// "error" when no response was received.
+func statusClass(statusCode int) string {
+	if statusCode < 100 || statusCode > 599 {
+		return "error"
+	}
+	return strconv.Itoa(statusCode/100) + "xx"
+}
@@ -23,6 +23,9 @@ func (w *statusResponseWriter) WriteHeader(code int) {
 // HTTPMetrics returns HTTP middleware that records request metrics.
 // It measures request duration, counts total requests, and counts error
 // responses (status >= 400). Metrics are tagged with method, path, and status.
+// A W3C traceparent header on the request is extracted into the request
+// context, so sampled requests attach trace exemplars to the duration
+// histogram and downstream publishers can propagate the trace.
 //
 // Usage:
 //
@@ -31,6 +34,8 @@ func HTTPMetrics(metrics *Metrics) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
+			ctx := ExtractTraceContext(r.Context(), r.Header)
+			r = r.WithContext(ctx)
 
 			wrapped := &statusResponseWriter{
 				ResponseWriter: w,
@@ -39,7 +44,8 @@ func HTTPMetrics(metrics *Metrics) func(http.Handler) http.Handler {
 
 			next.ServeHTTP(wrapped, r)
 
-			duration := float64(time.Since(start).Milliseconds())
+			elapsed := time.Since(start)
+			duration := float64(elapsed.Milliseconds())
 			status := wrapped.statusCode
 
 			attrs := otelmetric.WithAttributes(
@@ -48,11 +54,17 @@ func HTTPMetrics(metrics *Metrics) func(http.Handler) http.Handler {
 				attribute.String("status", strconv.Itoa(status)),
 			)
 
-			metrics.HTTPRequestDuration.Record(r.Context(), duration, attrs)
-			metrics.HTTPRequestTotal.Add(r.Context(), 1, attrs)
+			metrics.HTTPRequestDuration.Record(ctx, duration, attrs)
+			metrics.HTTPRequestTotal.Add(ctx, 1, attrs)
 
+			var failed int64
 			if status >= 400 {
-				metrics.HTTPRequestErrors.Add(r.Context(), 1, attrs)
+				metrics.HTTPRequestErrors.Add(ctx, 1, attrs)
+				failed = 1
+			}
+
+			if metrics.red != nil {
+				metrics.red.record(redKindHTTP, r.Method+" "+r.URL.Path, 1, failed, elapsed)
 			}
 		})
 	}
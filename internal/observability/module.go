@@ -6,9 +6,10 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/prometheus"
+	otelprom "go.opentelemetry.io/otel/exporters/prometheus"
 	otelmetric "go.opentelemetry.io/otel/metric"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 )
@@ -24,7 +25,7 @@ type Module struct {
 // as the metric reader, creates a MeterProvider, and sets it as the global
 // OTel MeterProvider. The serviceName is used as the meter scope name.
 func New(serviceName string) (*Module, error) {
-	exporter, err := prometheus.New()
+	exporter, err := otelprom.New()
 	if err != nil {
 		return nil, err
 	}
@@ -49,10 +50,15 @@ func (m *Module) Shutdown(ctx context.Context) error {
 	return m.provider.Shutdown(ctx)
 }
 
-// MetricsHandler returns an http.Handler that serves Prometheus metrics
-// in the standard exposition format. Mount this at "/metrics".
+// MetricsHandler returns an http.Handler that serves Prometheus metrics. It
+// negotiates the OpenMetrics format when the scraper asks for it, which is
+// required for histogram trace exemplars to be exposed. Mount this at
+// "/metrics".
 func (m *Module) MetricsHandler() http.Handler {
-	return promhttp.Handler()
+	return promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}),
+	)
 }
 
 // Meter returns the OTel Meter for creating metric instruments.
@@ -0,0 +1,222 @@
+package observability
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RED summary settings.
+const (
+	// redWindowSeconds is the length of the rolling window, in one-second
+	// buckets, that rates and latency percentiles are computed over.
+	redWindowSeconds = 60
+
+	// maxREDSeries caps the number of tracked series per kind. Further
+	// series are folded into redOverflowSeries.
+	maxREDSeries = 200
+
+	redOverflowSeries = "other"
+)
+
+// redLatencyBounds are the latency bucket upper bounds in milliseconds. They
+// match the OTel SDK's default histogram boundaries so the summary agrees
+// with the exported histograms.
+var redLatencyBounds = []float64{0, 5, 10, 25, 50, 75, 100, 250, 500, 750, 1000, 2500, 5000, 7500, 10000}
+
+// Series kinds in the RED summary.
+const (
+	redKindHTTP     = "http"
+	redKindConsumer = "consumer"
+)
+
+// REDSeries is the rate/errors/duration summary of one HTTP route or
+// consumer. Rates and percentiles cover the rolling window; totals cover the
+// process lifetime. Percentiles are bucket upper bounds in milliseconds.
+type REDSeries struct {
+	Name          string  `json:"name"`
+	RatePerSecond float64 `json:"rate_per_second"`
+	ErrorRate     float64 `json:"error_rate"`
+	Requests      int64   `json:"requests"`
+	Errors        int64   `json:"errors"`
+	P50Ms         float64 `json:"p50_ms"`
+	P95Ms         float64 `json:"p95_ms"`
+	P99Ms         float64 `json:"p99_ms"`
+	TotalRequests int64   `json:"total_requests"`
+	TotalErrors   int64   `json:"total_errors"`
+}
+
+// REDSummary is the response of the metrics summary endpoint.
+type REDSummary struct {
+	WindowSeconds int         `json:"window_seconds"`
+	UptimeSeconds int64       `json:"uptime_seconds"`
+	HTTP          []REDSeries `json:"http"`
+	Consumers     []REDSeries `json:"consumers"`
+}
+
+// redBucket aggregates one second of observations.
+type redBucket struct {
+	second  int64
+	count   int64
+	errors  int64
+	latency []int64 // per redLatencyBounds bucket, plus overflow
+}
+
+// redSeries is a ring of one-second buckets plus lifetime totals.
+type redSeries struct {
+	buckets     [redWindowSeconds]redBucket
+	totalCount  int64
+	totalErrors int64
+}
+
+// redTracker keeps in-process RED numbers for the metrics summary endpoint.
+// The exported OTel instruments remain the source of truth for dashboards;
+// this is a quick view for operators and smoke tests.
+type redTracker struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	started time.Time
+	series  map[string]map[string]*redSeries // kind -> name -> series
+}
+
+// newREDTracker creates an empty tracker.
+func newREDTracker() *redTracker {
+	return &redTracker{
+		now:     time.Now,
+		started: time.Now(),
+		series: map[string]map[string]*redSeries{
+			redKindHTTP:     {},
+			redKindConsumer: {},
+		},
+	}
+}
+
+// record adds count observations, errors of which failed, that took duration
+// in total.
+func (t *redTracker) record(kind, name string, count, errors int64, duration time.Duration) {
+	if count <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	byName := t.series[kind]
+	s, ok := byName[name]
+	if !ok {
+		if len(byName) >= maxREDSeries {
+			name = redOverflowSeries
+			s = byName[name]
+		}
+		if s == nil {
+			s = &redSeries{}
+			byName[name] = s
+		}
+	}
+
+	second := t.now().Unix()
+	b := &s.buckets[second%redWindowSeconds]
+	if b.second != second || b.latency == nil {
+		*b = redBucket{second: second, latency: make([]int64, len(redLatencyBounds)+1)}
+	}
+
+	ms := float64(duration) / float64(time.Millisecond) / float64(count)
+	b.latency[sort.SearchFloat64s(redLatencyBounds, ms)] += count
+	b.count += count
+	b.errors += errors
+	s.totalCount += count
+	s.totalErrors += errors
+}
+
+// summary computes the current RED numbers.
+func (t *redTracker) summary() REDSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	return REDSummary{
+		WindowSeconds: redWindowSeconds,
+		UptimeSeconds: int64(now.Sub(t.started).Seconds()),
+		HTTP:          t.summarizeKind(redKindHTTP, now.Unix()),
+		Consumers:     t.summarizeKind(redKindConsumer, now.Unix()),
+	}
+}
+
+// summarizeKind summarizes all series of a kind, sorted by name.
+func (t *redTracker) summarizeKind(kind string, nowSecond int64) []REDSeries {
+	out := make([]REDSeries, 0, len(t.series[kind]))
+	for name, s := range t.series[kind] {
+		out = append(out, s.summarize(name, nowSecond))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// summarize aggregates the buckets within the window ending at nowSecond.
+func (s *redSeries) summarize(name string, nowSecond int64) REDSeries {
+	latency := make([]int64, len(redLatencyBounds)+1)
+	result := REDSeries{
+		Name:          name,
+		TotalRequests: s.totalCount,
+		TotalErrors:   s.totalErrors,
+	}
+
+	for i := range s.buckets {
+		b := &s.buckets[i]
+		if b.latency == nil || nowSecond-b.second >= redWindowSeconds {
+			continue
+		}
+		result.Requests += b.count
+		result.Errors += b.errors
+		for j, n := range b.latency {
+			latency[j] += n
+		}
+	}
+
+	if result.Requests > 0 {
+		result.RatePerSecond = float64(result.Requests) / redWindowSeconds
+		result.ErrorRate = float64(result.Errors) / float64(result.Requests)
+		result.P50Ms = latencyQuantile(latency, result.Requests, 0.50)
+		result.P95Ms = latencyQuantile(latency, result.Requests, 0.95)
+		result.P99Ms = latencyQuantile(latency, result.Requests, 0.99)
+	}
+	return result
+}
+
+// latencyQuantile returns the upper bound of the bucket holding quantile q.
+// Observations above the largest bound report that bound.
+func latencyQuantile(latency []int64, total int64, q float64) float64 {
+	rank := int64(q * float64(total))
+	if rank >= total {
+		rank = total - 1
+	}
+
+	var seen int64
+	for i, n := range latency {
+		seen += n
+		if seen > rank {
+			if i >= len(redLatencyBounds) {
+				break
+			}
+			return redLatencyBounds[i]
+		}
+	}
+	return redLatencyBounds[len(redLatencyBounds)-1]
+}
+
+// SummaryHandler returns an http.Handler that serves the current RED (rate,
+// errors, duration) numbers per HTTP route and consumer as JSON. Mount this
+// at "/debug/metrics-summary".
+func (m *Metrics) SummaryHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		summary := REDSummary{WindowSeconds: redWindowSeconds, HTTP: []REDSeries{}, Consumers: []REDSeries{}}
+		if m.red != nil {
+			summary = m.red.summary()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(summary)
+	})
+}
@@ -0,0 +1,118 @@
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// newTestMetrics creates metrics backed by a manual reader.
+func newTestMetrics(t *testing.T) (*Metrics, *sdkmetric.ManualReader) {
+	t.Helper()
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	m, err := NewMetrics(provider.Meter("test"))
+	if err != nil {
+		t.Fatalf("NewMetrics() error = %v", err)
+	}
+	return m, reader
+}
+
+// TestREDTracker_Summary verifies rates, error rates, and percentiles over
+// the rolling window, and that old buckets drop out.
+func TestREDTracker_Summary(t *testing.T) {
+	tracker := newREDTracker()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	for i := range 100 {
+		duration := 3 * time.Millisecond
+		if i >= 90 {
+			duration = 400 * time.Millisecond
+		}
+		var failed int64
+		if i%10 == 0 {
+			failed = 1
+		}
+		tracker.record(redKindHTTP, "POST /v1/events/ingest", 1, failed, duration)
+	}
+	tracker.record(redKindConsumer, "warehouse", 30, 0, 30*time.Millisecond)
+
+	summary := tracker.summary()
+	if len(summary.HTTP) != 1 || len(summary.Consumers) != 1 {
+		t.Fatalf("summary = %+v, want one HTTP and one consumer series", summary)
+	}
+
+	got := summary.HTTP[0]
+	if got.Requests != 100 || got.Errors != 10 || got.ErrorRate != 0.1 {
+		t.Errorf("requests/errors/rate = %d/%d/%v, want 100/10/0.1", got.Requests, got.Errors, got.ErrorRate)
+	}
+	if got.P50Ms != 5 || got.P95Ms != 500 || got.P99Ms != 500 {
+		t.Errorf("p50/p95/p99 = %v/%v/%v, want 5/500/500", got.P50Ms, got.P95Ms, got.P99Ms)
+	}
+
+	consumer := summary.Consumers[0]
+	if consumer.Requests != 30 || consumer.P50Ms != 5 {
+		t.Errorf("consumer = %+v, want 30 messages at p50 5ms", consumer)
+	}
+
+	// Outside the window only lifetime totals remain
+	now = now.Add(2 * time.Minute)
+	got = tracker.summary().HTTP[0]
+	if got.Requests != 0 || got.RatePerSecond != 0 || got.TotalRequests != 100 || got.TotalErrors != 10 {
+		t.Errorf("after window = %+v, want no recent requests and lifetime totals 100/10", got)
+	}
+}
+
+// TestHTTPMetrics_ExemplarsAndSummary verifies sampled requests attach trace
+// exemplars and show up in the JSON summary.
+func TestHTTPMetrics_ExemplarsAndSummary(t *testing.T) {
+	metrics, reader := newTestMetrics(t)
+	handler := HTTPMetrics(metrics)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/events/ingest", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	var exemplarTrace string
+	for _, sm := range rm.ScopeMetrics {
+		for _, metric := range sm.Metrics {
+			hist, ok := metric.Data.(metricdata.Histogram[float64])
+			if !ok || metric.Name != "http.request.duration" {
+				continue
+			}
+			for _, dp := range hist.DataPoints {
+				for _, ex := range dp.Exemplars {
+					exemplarTrace = string(ex.TraceID)
+				}
+			}
+		}
+	}
+	if exemplarTrace == "" {
+		t.Error("http.request.duration should carry a trace exemplar")
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.SummaryHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/metrics-summary", nil))
+	var summary REDSummary
+	if err := json.NewDecoder(rec.Body).Decode(&summary); err != nil {
+		t.Fatalf("decode summary: %v", err)
+	}
+	if len(summary.HTTP) != 1 || summary.HTTP[0].Name != "POST /v1/events/ingest" || summary.HTTP[0].Requests != 1 {
+		t.Errorf("summary.HTTP = %+v, want one POST /v1/events/ingest request", summary.HTTP)
+	}
+}
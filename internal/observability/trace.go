@@ -0,0 +1,26 @@
+package observability
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// traceContext propagates W3C trace context (the traceparent and tracestate
+// headers). Causality does not create spans itself; it carries the caller's
+// trace so metric exemplars link back to it.
+var traceContext = propagation.TraceContext{}
+
+// ExtractTraceContext returns ctx carrying the remote span context found in
+// header, if any. Measurements recorded with the returned context attach a
+// trace exemplar when the remote span is sampled.
+func ExtractTraceContext(ctx context.Context, header http.Header) context.Context {
+	return traceContext.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// InjectTraceContext writes the span context carried by ctx, if any, into
+// header so downstream consumers can continue the trace.
+func InjectTraceContext(ctx context.Context, header http.Header) {
+	traceContext.Inject(ctx, propagation.HeaderCarrier(header))
+}
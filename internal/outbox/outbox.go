@@ -0,0 +1,88 @@
+// Package outbox implements the transactional outbox for admin changes that
+// must emit events, such as API keys being created or rules being updated.
+// Writers insert the event into the outbox table in the same PostgreSQL
+// transaction as the change, so the event exists if and only if the change
+// was committed. A Relay publishes pending events to NATS JetStream with the
+// event ID as message ID, so JetStream drops events republished after a
+// relay failure, and marks them published.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/SebastienMelki/causality/internal/db"
+	"github.com/SebastienMelki/causality/internal/outbox/migrations"
+)
+
+// Migrations are the outbox table's database migrations, applied by every
+// service whose database holds an outbox.
+var Migrations = db.Source{Name: "outbox", FS: migrations.FS}
+
+// SubjectPrefix is the NATS subject prefix of admin events. Events are
+// published to "{prefix}.{type}", e.g. "audit.admin.api_key.created", which
+// the ingestion audit stream captures.
+const SubjectPrefix = "audit.admin"
+
+// Event is an admin change. It is published as JSON.
+type Event struct {
+	// ID identifies the event; it is the JetStream message ID.
+	ID string `json:"id"`
+
+	// Type is the change, as "{resource}.{action}" (e.g. "rule.updated").
+	Type string `json:"type"`
+
+	// AppID is the app of the changed resource, if it belongs to one.
+	AppID string `json:"app_id,omitempty"`
+
+	// ResourceID identifies the changed resource.
+	ResourceID string `json:"resource_id"`
+
+	// Actor is who made the change, if known.
+	Actor string `json:"actor,omitempty"`
+
+	// Data holds resource-specific details of the change.
+	Data any `json:"data,omitempty"`
+
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Subject returns the NATS subject the event is published to.
+func (e *Event) Subject() string {
+	return SubjectPrefix + "." + e.Type
+}
+
+// Execer executes statements. It is satisfied by *sql.Tx.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Enqueue adds e to the outbox within tx, setting its ID and OccurredAt if
+// unset. The event is published once tx commits.
+func Enqueue(ctx context.Context, tx Execer, e *Event) error {
+	if e.ID == "" {
+		e.ID = uuid.Must(uuid.NewV7()).String()
+	}
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = time.Now().UTC()
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox event: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO outbox (event_id, subject, payload)
+		VALUES ($1, $2, $3)
+	`, e.ID, e.Subject(), payload)
+	if err != nil {
+		return fmt.Errorf("failed to insert outbox event %s: %w", e.Type, err)
+	}
+	return nil
+}
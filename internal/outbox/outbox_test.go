@@ -0,0 +1,111 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// fakeExecer records the arguments of executed statements.
+type fakeExecer struct {
+	args [][]any
+}
+
+func (e *fakeExecer) ExecContext(_ context.Context, _ string, args ...any) (sql.Result, error) {
+	e.args = append(e.args, args)
+	return nil, nil
+}
+
+func TestEnqueue(t *testing.T) {
+	tx := &fakeExecer{}
+	e := &Event{Type: "api_key.created", AppID: "app-1", ResourceID: "key-1", Data: map[string]any{"name": "ios"}}
+	if err := Enqueue(context.Background(), tx, e); err != nil {
+		t.Fatalf("Enqueue() = %v", err)
+	}
+
+	if e.ID == "" || e.OccurredAt.IsZero() {
+		t.Fatalf("event = %+v, want ID and OccurredAt set", e)
+	}
+	if len(tx.args) != 1 {
+		t.Fatalf("executed %d statements, want 1", len(tx.args))
+	}
+	args := tx.args[0]
+	if args[0] != e.ID || args[1] != "audit.admin.api_key.created" {
+		t.Errorf("args = %v, want the event ID and subject", args[:2])
+	}
+
+	var decoded Event
+	if err := json.Unmarshal(args[2].([]byte), &decoded); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if decoded.ID != e.ID || decoded.Type != e.Type || decoded.ResourceID != "key-1" || decoded.AppID != "app-1" {
+		t.Errorf("payload = %+v, want the event", decoded)
+	}
+}
+
+func TestEnqueue_KeepsID(t *testing.T) {
+	tx := &fakeExecer{}
+	e := &Event{ID: "evt-1", Type: "rule.deleted", ResourceID: "rule-1"}
+	if err := Enqueue(context.Background(), tx, e); err != nil {
+		t.Fatalf("Enqueue() = %v", err)
+	}
+	if e.ID != "evt-1" {
+		t.Errorf("ID = %q, want evt-1", e.ID)
+	}
+}
+
+// fakePublisher records published messages, failing on failOn subjects.
+type fakePublisher struct {
+	msgs   []*nats.Msg
+	failOn string
+}
+
+func (p *fakePublisher) PublishMsg(_ context.Context, msg *nats.Msg, _ ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	if msg.Subject == p.failOn {
+		return nil, errors.New("nats: timeout")
+	}
+	p.msgs = append(p.msgs, msg)
+	return &jetstream.PubAck{}, nil
+}
+
+func TestRelayPublish(t *testing.T) {
+	msgs := []message{
+		{id: 1, eventID: "evt-1", subject: "audit.admin.rule.created", payload: []byte(`{}`)},
+		{id: 2, eventID: "evt-2", subject: "audit.admin.rule.updated", payload: []byte(`{}`)},
+		{id: 3, eventID: "evt-3", subject: "audit.admin.rule.deleted", payload: []byte(`{}`)},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	pub := &fakePublisher{}
+	r := NewRelay(nil, pub, Config{}, logger)
+	ids, err := r.publish(context.Background(), msgs)
+	if err != nil {
+		t.Fatalf("publish() = %v", err)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[2] != 3 {
+		t.Errorf("ids = %v, want [1 2 3]", ids)
+	}
+	for i, m := range pub.msgs {
+		if got := m.Header.Get(jetstream.MsgIDHeader); got != msgs[i].eventID {
+			t.Errorf("message %d ID = %q, want %q", i, got, msgs[i].eventID)
+		}
+	}
+
+	// Events after a failure stay pending so they never overtake it.
+	pub = &fakePublisher{failOn: "audit.admin.rule.updated"}
+	r = NewRelay(nil, pub, Config{}, logger)
+	ids, err = r.publish(context.Background(), msgs)
+	if err == nil {
+		t.Fatal("publish() succeeded, want the publish error")
+	}
+	if len(ids) != 1 || ids[0] != 1 {
+		t.Errorf("ids = %v, want [1]", ids)
+	}
+}
@@ -0,0 +1,231 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Config holds the outbox relay configuration.
+//
+// Environment variable overrides:
+//   - OUTBOX_RELAY_ENABLED:    publish outbox events to NATS (default: true)
+//   - OUTBOX_RELAY_INTERVAL:   how often pending events are polled (default: 1s)
+//   - OUTBOX_RELAY_BATCH_SIZE: events published per transaction (default: 100)
+//   - OUTBOX_RETENTION:        how long published events are kept (default: 168h)
+type Config struct {
+	Enabled   bool          `env:"OUTBOX_RELAY_ENABLED"    envDefault:"true"`
+	Interval  time.Duration `env:"OUTBOX_RELAY_INTERVAL"   envDefault:"1s"`
+	BatchSize int           `env:"OUTBOX_RELAY_BATCH_SIZE" envDefault:"100"`
+	Retention time.Duration `env:"OUTBOX_RETENTION"        envDefault:"168h"`
+}
+
+// Publisher is the subset of the JetStream API the relay publishes with.
+type Publisher interface {
+	PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+}
+
+// message is a pending outbox row.
+type message struct {
+	id      int64
+	eventID string
+	subject string
+	payload []byte
+}
+
+// Relay publishes pending outbox events in insertion order. Each batch is
+// claimed with FOR UPDATE SKIP LOCKED, so replicas relaying the same outbox
+// never publish an event concurrently, and marked published in the same
+// transaction once JetStream has acknowledged it. An event acknowledged but
+// not marked, because the relay stopped in between, is published again with
+// the same message ID and dropped by JetStream's duplicate window; consumers
+// that must be exactly-once beyond the window deduplicate by event ID.
+type Relay struct {
+	db        *sql.DB
+	publisher Publisher
+	config    Config
+	logger    *slog.Logger
+
+	// lastPrune is when published events past the retention were last
+	// deleted
+	lastPrune time.Time
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewRelay creates a relay publishing the outbox in db with publisher.
+func NewRelay(db *sql.DB, publisher Publisher, cfg Config, logger *slog.Logger) *Relay {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+
+	return &Relay{
+		db:        db,
+		publisher: publisher,
+		config:    cfg,
+		logger:    logger.With("component", "outbox-relay"),
+		stopCh:    make(chan struct{}),
+		doneCh:    make(chan struct{}),
+	}
+}
+
+// Start begins relaying in a background goroutine until ctx is cancelled
+// or Stop is called.
+func (r *Relay) Start(ctx context.Context) {
+	go func() {
+		defer close(r.doneCh)
+		ticker := time.NewTicker(r.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.stopCh:
+				return
+			case <-ticker.C:
+				if _, err := r.RelayPending(ctx); err != nil {
+					r.logger.Warn("failed to relay outbox events", "error", err)
+				}
+				r.prune(ctx)
+			}
+		}
+	}()
+}
+
+// Stop signals the relay to stop and waits for it to exit.
+func (r *Relay) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopCh)
+	})
+	<-r.doneCh
+}
+
+// RelayPending publishes pending events batch by batch until none are left
+// or publishing fails. It returns the number of events published.
+func (r *Relay) RelayPending(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		claimed, published, err := r.relayBatch(ctx)
+		total += published
+		if err != nil || claimed < r.config.BatchSize {
+			return total, err
+		}
+	}
+}
+
+// relayBatch claims a batch of pending events, publishes them and marks the
+// published ones. It returns the number claimed and published.
+func (r *Relay) relayBatch(ctx context.Context) (claimed, published int, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	msgs, err := claimPending(ctx, tx, r.config.BatchSize)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(msgs) == 0 {
+		return 0, 0, nil
+	}
+
+	ids, pubErr := r.publish(ctx, msgs)
+	if len(ids) > 0 {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE outbox SET published_at = now() WHERE id = ANY($1)
+		`, ids); err != nil {
+			return len(msgs), 0, fmt.Errorf("failed to mark outbox events published: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return len(msgs), 0, fmt.Errorf("failed to commit outbox batch: %w", err)
+		}
+	}
+	return len(msgs), len(ids), pubErr
+}
+
+// claimPending locks up to limit pending events within tx, oldest first,
+// skipping events locked by other relays.
+func claimPending(ctx context.Context, tx *sql.Tx, limit int) ([]message, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, event_id, subject, payload
+		FROM outbox
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var msgs []message
+	for rows.Next() {
+		var m message
+		if err := rows.Scan(&m.id, &m.eventID, &m.subject, &m.payload); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		msgs = append(msgs, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate outbox: %w", err)
+	}
+	return msgs, nil
+}
+
+// publish publishes msgs in order with their event IDs as message IDs,
+// stopping at the first failure so later events never overtake it. It
+// returns the outbox IDs of the published events.
+func (r *Relay) publish(ctx context.Context, msgs []message) ([]int64, error) {
+	ids := make([]int64, 0, len(msgs))
+	for _, m := range msgs {
+		ack, err := r.publisher.PublishMsg(ctx, &nats.Msg{
+			Subject: m.subject,
+			Data:    m.payload,
+			Header:  nats.Header{jetstream.MsgIDHeader: []string{m.eventID}},
+		})
+		if err != nil {
+			return ids, fmt.Errorf("failed to publish outbox event %s: %w", m.eventID, err)
+		}
+		if ack != nil && ack.Duplicate {
+			r.logger.Debug("outbox event already published", "event_id", m.eventID)
+		}
+		ids = append(ids, m.id)
+	}
+	return ids, nil
+}
+
+// prune deletes published events past the retention, at most once an hour.
+func (r *Relay) prune(ctx context.Context) {
+	if r.config.Retention <= 0 || time.Since(r.lastPrune) < time.Hour {
+		return
+	}
+	r.lastPrune = time.Now()
+
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM outbox WHERE published_at < $1
+	`, time.Now().Add(-r.config.Retention))
+	if err != nil {
+		r.logger.Warn("failed to prune outbox", "error", err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		r.logger.Info("pruned outbox", "events", n)
+	}
+}
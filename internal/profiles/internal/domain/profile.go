@@ -0,0 +1,173 @@
+// Package domain contains the core types for user profiles.
+package domain
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrProfileNotFound is returned when no profile exists for a user.
+var ErrProfileNotFound = errors.New("profile not found")
+
+// Trait keys derived from identity events and the device context.
+const (
+	TraitSignupMethod   = "signup_method"
+	TraitReferralSource = "referral_source"
+	TraitLoginMethod    = "login_method"
+	TraitUpdatedFields  = "last_updated_fields"
+	TraitLocale         = "locale"
+	TraitTimezone       = "timezone"
+)
+
+// Update is the profile-relevant content of a single identity event.
+type Update struct {
+	AppID  string
+	UserID string
+	At     time.Time
+
+	// Signup and Login mark the identity event that produced the update.
+	Signup bool
+	Login  bool
+
+	// Traits are set on the profile; later updates overwrite earlier ones.
+	Traits map[string]string
+
+	// Device is the device the event was sent from, if it has a device_id.
+	Device *Device
+}
+
+// ProfileKey identifies one user of one app.
+type ProfileKey struct {
+	AppID  string
+	UserID string
+}
+
+// Device is a device a user has been seen on.
+type Device struct {
+	DeviceID    string    `json:"device_id"`
+	Platform    string    `json:"platform"`
+	OSVersion   string    `json:"os_version,omitempty"`
+	AppVersion  string    `json:"app_version,omitempty"`
+	DeviceModel string    `json:"device_model,omitempty"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+// Profile is a user's profile: traits, activity bounds, and devices.
+type Profile struct {
+	AppID       string            `json:"app_id"`
+	UserID      string            `json:"user_id"`
+	Traits      map[string]string `json:"traits"`
+	FirstSeenAt time.Time         `json:"first_seen_at"`
+	LastSeenAt  time.Time         `json:"last_seen_at"`
+	SignedUpAt  *time.Time        `json:"signed_up_at,omitempty"`
+	LastLoginAt *time.Time        `json:"last_login_at,omitempty"`
+	Devices     []Device          `json:"devices"`
+}
+
+// pending accumulates updates for one profile between flushes. Trait and
+// device attribute times are tracked so out-of-order updates in a batch
+// resolve to the latest value.
+type pending struct {
+	profile Profile
+	traitAt map[string]time.Time
+	devices map[string]*Device
+}
+
+// add merges an update into the pending profile.
+func (p *pending) add(u Update) {
+	at := u.At
+	if p.profile.FirstSeenAt.IsZero() || at.Before(p.profile.FirstSeenAt) {
+		p.profile.FirstSeenAt = at
+	}
+	if at.After(p.profile.LastSeenAt) {
+		p.profile.LastSeenAt = at
+	}
+	if u.Signup && (p.profile.SignedUpAt == nil || at.Before(*p.profile.SignedUpAt)) {
+		p.profile.SignedUpAt = &at
+	}
+	if u.Login && (p.profile.LastLoginAt == nil || at.After(*p.profile.LastLoginAt)) {
+		p.profile.LastLoginAt = &at
+	}
+
+	for k, v := range u.Traits {
+		if prev, ok := p.traitAt[k]; ok && prev.After(at) {
+			continue
+		}
+		p.profile.Traits[k] = v
+		p.traitAt[k] = at
+	}
+
+	if u.Device == nil || u.Device.DeviceID == "" {
+		return
+	}
+	d, ok := p.devices[u.Device.DeviceID]
+	if !ok {
+		d = &Device{DeviceID: u.Device.DeviceID, FirstSeenAt: at}
+		p.devices[u.Device.DeviceID] = d
+	}
+	if at.Before(d.FirstSeenAt) {
+		d.FirstSeenAt = at
+	}
+	if !at.Before(d.LastSeenAt) {
+		d.LastSeenAt = at
+		d.Platform = u.Device.Platform
+		d.OSVersion = u.Device.OSVersion
+		d.AppVersion = u.Device.AppVersion
+		d.DeviceModel = u.Device.DeviceModel
+	}
+}
+
+// Aggregator accumulates profile updates in memory between flushes. It is
+// safe for concurrent use.
+type Aggregator struct {
+	mu       sync.Mutex
+	profiles map[ProfileKey]*pending
+}
+
+// NewAggregator creates an empty Aggregator.
+func NewAggregator() *Aggregator {
+	return &Aggregator{profiles: make(map[ProfileKey]*pending)}
+}
+
+// Add records an update on the profile of u.AppID and u.UserID.
+func (a *Aggregator) Add(u Update) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := ProfileKey{AppID: u.AppID, UserID: u.UserID}
+	p, ok := a.profiles[key]
+	if !ok {
+		p = &pending{
+			profile: Profile{AppID: u.AppID, UserID: u.UserID, Traits: make(map[string]string)},
+			traitAt: make(map[string]time.Time),
+			devices: make(map[string]*Device),
+		}
+		a.profiles[key] = p
+	}
+	p.add(u)
+}
+
+// Drain returns the accumulated profiles, with devices ordered by
+// device_id, and resets the aggregator.
+func (a *Aggregator) Drain() map[ProfileKey]Profile {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	drained := make(map[ProfileKey]Profile, len(a.profiles))
+	for key, p := range a.profiles {
+		profile := p.profile
+		profile.Devices = make([]Device, 0, len(p.devices))
+		for _, d := range p.devices {
+			profile.Devices = append(profile.Devices, *d)
+		}
+		sort.Slice(profile.Devices, func(i, j int) bool {
+			return profile.Devices[i].DeviceID < profile.Devices[j].DeviceID
+		})
+		drained[key] = profile
+	}
+	a.profiles = make(map[ProfileKey]*pending)
+	return drained
+}
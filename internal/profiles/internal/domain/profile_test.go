@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAggregator_AddAndDrain(t *testing.T) {
+	agg := NewAggregator()
+	t0 := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+	t2 := t0.Add(2 * time.Hour)
+
+	// Added out of order: the later login must win the login_method trait
+	// and the device attributes.
+	agg.Add(Update{
+		AppID: "app", UserID: "u1", At: t2, Login: true,
+		Traits: map[string]string{TraitLoginMethod: "google"},
+		Device: &Device{DeviceID: "d1", Platform: "ios", AppVersion: "2.0.0"},
+	})
+	agg.Add(Update{
+		AppID: "app", UserID: "u1", At: t0, Signup: true,
+		Traits: map[string]string{TraitSignupMethod: "email", TraitLoginMethod: "email"},
+		Device: &Device{DeviceID: "d1", Platform: "ios", AppVersion: "1.0.0"},
+	})
+	agg.Add(Update{
+		AppID: "app", UserID: "u1", At: t1, Login: true,
+		Traits: map[string]string{TraitLoginMethod: "password"},
+		Device: &Device{DeviceID: "d0", Platform: "web"},
+	})
+	agg.Add(Update{AppID: "app", UserID: "u2", At: t1})
+
+	profiles := agg.Drain()
+	if len(profiles) != 2 {
+		t.Fatalf("got %d profiles, want 2", len(profiles))
+	}
+
+	got := profiles[ProfileKey{AppID: "app", UserID: "u1"}]
+	if !got.FirstSeenAt.Equal(t0) || !got.LastSeenAt.Equal(t2) {
+		t.Errorf("seen = %v..%v, want %v..%v", got.FirstSeenAt, got.LastSeenAt, t0, t2)
+	}
+	if got.SignedUpAt == nil || !got.SignedUpAt.Equal(t0) {
+		t.Errorf("SignedUpAt = %v, want %v", got.SignedUpAt, t0)
+	}
+	if got.LastLoginAt == nil || !got.LastLoginAt.Equal(t2) {
+		t.Errorf("LastLoginAt = %v, want %v", got.LastLoginAt, t2)
+	}
+	if got.Traits[TraitLoginMethod] != "google" || got.Traits[TraitSignupMethod] != "email" {
+		t.Errorf("Traits = %v, want login_method=google signup_method=email", got.Traits)
+	}
+
+	if len(got.Devices) != 2 || got.Devices[0].DeviceID != "d0" || got.Devices[1].DeviceID != "d1" {
+		t.Fatalf("Devices = %+v, want d0 and d1", got.Devices)
+	}
+	d1 := got.Devices[1]
+	if d1.AppVersion != "2.0.0" || !d1.FirstSeenAt.Equal(t0) || !d1.LastSeenAt.Equal(t2) {
+		t.Errorf("d1 = %+v, want app 2.0.0 seen %v..%v", d1, t0, t2)
+	}
+
+	if u2 := profiles[ProfileKey{AppID: "app", UserID: "u2"}]; u2.SignedUpAt != nil || len(u2.Devices) != 0 {
+		t.Errorf("u2 = %+v, want no signup and no devices", u2)
+	}
+
+	if again := agg.Drain(); len(again) != 0 {
+		t.Errorf("Drain after Drain returned %d profiles, want 0", len(again))
+	}
+}
@@ -0,0 +1,113 @@
+// Package handler provides HTTP handlers for the profile lookup API.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/SebastienMelki/causality/internal/profiles/internal/domain"
+)
+
+// ProfileReader reads user profiles.
+type ProfileReader interface {
+	Get(ctx context.Context, appID, userID string) (*domain.Profile, error)
+	FindByDevice(ctx context.Context, appID, deviceID string) ([]domain.Profile, error)
+}
+
+// ProfileHandler handles HTTP requests for profile lookup.
+type ProfileHandler struct {
+	store  ProfileReader
+	logger *slog.Logger
+}
+
+// NewProfileHandler creates a new ProfileHandler.
+func NewProfileHandler(store ProfileReader, logger *slog.Logger) *ProfileHandler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &ProfileHandler{
+		store:  store,
+		logger: logger.With("component", "profile-handler"),
+	}
+}
+
+// RegisterRoutes mounts the profile lookup endpoints on the given ServeMux.
+//
+// Endpoints:
+//   - GET /api/admin/profiles/{app_id}/{user_id}
+//   - GET /api/admin/profiles/{app_id}?device_id=
+//
+// TODO(phase-3): Protect these endpoints with session auth + RBAC.
+func (h *ProfileHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/admin/profiles/{app_id}/{user_id}", h.handleGet)
+	mux.HandleFunc("GET /api/admin/profiles/{app_id}", h.handleFindByDevice)
+}
+
+// handleGet handles GET /api/admin/profiles/{app_id}/{user_id} - returns a
+// user's profile.
+func (h *ProfileHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	appID := r.PathValue("app_id")
+	userID := r.PathValue("user_id")
+
+	profile, err := h.store.Get(r.Context(), appID, userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrProfileNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{
+				"error": "profile not found",
+			})
+			return
+		}
+		h.logger.Error("failed to get profile",
+			"app_id", appID,
+			"user_id", userID,
+			"error", err,
+		)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to get profile",
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, profile)
+}
+
+// handleFindByDevice handles GET /api/admin/profiles/{app_id}?device_id= -
+// returns the profiles of every user seen on a device.
+func (h *ProfileHandler) handleFindByDevice(w http.ResponseWriter, r *http.Request) {
+	appID := r.PathValue("app_id")
+	deviceID := r.URL.Query().Get("device_id")
+	if deviceID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "device_id query parameter is required",
+		})
+		return
+	}
+
+	profiles, err := h.store.FindByDevice(r.Context(), appID, deviceID)
+	if err != nil {
+		h.logger.Error("failed to find profiles by device",
+			"app_id", appID,
+			"device_id", deviceID,
+			"error", err,
+		)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to find profiles",
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"profiles": profiles,
+		"count":    len(profiles),
+	})
+}
+
+// writeJSON writes a JSON response with the given status code and body.
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,224 @@
+// Package repo provides the PostgreSQL implementation of the profiles Store port.
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/SebastienMelki/causality/internal/profiles/internal/domain"
+)
+
+// ProfileRepository implements the Store interface using PostgreSQL.
+type ProfileRepository struct {
+	db *sql.DB
+}
+
+// NewProfileRepository creates a new ProfileRepository backed by the given database.
+func NewProfileRepository(db *sql.DB) *ProfileRepository {
+	return &ProfileRepository{db: db}
+}
+
+// MergeProfiles atomically merges profiles into user_profiles and their
+// devices into user_profile_devices. Traits and device attributes from the
+// more recently seen side win; first-seen and signup times keep the
+// earliest value, last-seen and login times the latest.
+func (r *ProfileRepository) MergeProfiles(ctx context.Context, profiles map[domain.ProfileKey]domain.Profile) error {
+	if len(profiles) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	profileStmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO user_profiles AS p (
+			app_id, user_id, traits, first_seen_at, last_seen_at, signed_up_at, last_login_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (app_id, user_id) DO UPDATE
+		SET traits        = CASE WHEN EXCLUDED.last_seen_at >= p.last_seen_at
+		                         THEN p.traits || EXCLUDED.traits
+		                         ELSE EXCLUDED.traits || p.traits END,
+		    first_seen_at = LEAST(p.first_seen_at, EXCLUDED.first_seen_at),
+		    last_seen_at  = GREATEST(p.last_seen_at, EXCLUDED.last_seen_at),
+		    signed_up_at  = LEAST(p.signed_up_at, EXCLUDED.signed_up_at),
+		    last_login_at = GREATEST(p.last_login_at, EXCLUDED.last_login_at),
+		    updated_at    = NOW()
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare profile upsert: %w", err)
+	}
+	defer func() { _ = profileStmt.Close() }()
+
+	deviceStmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO user_profile_devices AS d (
+			app_id, user_id, device_id, platform, os_version, app_version, device_model,
+			first_seen_at, last_seen_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (app_id, user_id, device_id) DO UPDATE
+		SET platform      = CASE WHEN EXCLUDED.last_seen_at >= d.last_seen_at THEN EXCLUDED.platform ELSE d.platform END,
+		    os_version    = CASE WHEN EXCLUDED.last_seen_at >= d.last_seen_at THEN EXCLUDED.os_version ELSE d.os_version END,
+		    app_version   = CASE WHEN EXCLUDED.last_seen_at >= d.last_seen_at THEN EXCLUDED.app_version ELSE d.app_version END,
+		    device_model  = CASE WHEN EXCLUDED.last_seen_at >= d.last_seen_at THEN EXCLUDED.device_model ELSE d.device_model END,
+		    first_seen_at = LEAST(d.first_seen_at, EXCLUDED.first_seen_at),
+		    last_seen_at  = GREATEST(d.last_seen_at, EXCLUDED.last_seen_at)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare device upsert: %w", err)
+	}
+	defer func() { _ = deviceStmt.Close() }()
+
+	for key, p := range profiles {
+		traits, err := json.Marshal(p.Traits)
+		if err != nil {
+			return fmt.Errorf("failed to marshal traits for user %s: %w", key.UserID, err)
+		}
+
+		if _, err := profileStmt.ExecContext(ctx,
+			key.AppID,
+			key.UserID,
+			traits,
+			p.FirstSeenAt,
+			p.LastSeenAt,
+			p.SignedUpAt,
+			p.LastLoginAt,
+		); err != nil {
+			return fmt.Errorf("failed to upsert profile for user %s: %w", key.UserID, err)
+		}
+
+		for _, d := range p.Devices {
+			if _, err := deviceStmt.ExecContext(ctx,
+				key.AppID,
+				key.UserID,
+				d.DeviceID,
+				d.Platform,
+				d.OSVersion,
+				d.AppVersion,
+				d.DeviceModel,
+				d.FirstSeenAt,
+				d.LastSeenAt,
+			); err != nil {
+				return fmt.Errorf("failed to upsert device %s for user %s: %w", d.DeviceID, key.UserID, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit profiles: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns the profile of a user with its devices, most recently seen
+// first. It returns domain.ErrProfileNotFound if the user has no profile.
+func (r *ProfileRepository) Get(ctx context.Context, appID, userID string) (*domain.Profile, error) {
+	var (
+		p           = domain.Profile{AppID: appID, UserID: userID}
+		traits      []byte
+		signedUpAt  sql.NullTime
+		lastLoginAt sql.NullTime
+	)
+	err := r.db.QueryRowContext(ctx, `
+		SELECT traits, first_seen_at, last_seen_at, signed_up_at, last_login_at
+		FROM user_profiles
+		WHERE app_id = $1 AND user_id = $2
+	`, appID, userID).Scan(&traits, &p.FirstSeenAt, &p.LastSeenAt, &signedUpAt, &lastLoginAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrProfileNotFound
+		}
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+
+	if err := json.Unmarshal(traits, &p.Traits); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal traits: %w", err)
+	}
+	if signedUpAt.Valid {
+		p.SignedUpAt = &signedUpAt.Time
+	}
+	if lastLoginAt.Valid {
+		p.LastLoginAt = &lastLoginAt.Time
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT device_id, platform, os_version, app_version, device_model, first_seen_at, last_seen_at
+		FROM user_profile_devices
+		WHERE app_id = $1 AND user_id = $2
+		ORDER BY last_seen_at DESC
+	`, appID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+	defer rows.Close()
+
+	p.Devices = []domain.Device{}
+	for rows.Next() {
+		var d domain.Device
+		if err := rows.Scan(
+			&d.DeviceID,
+			&d.Platform,
+			&d.OSVersion,
+			&d.AppVersion,
+			&d.DeviceModel,
+			&d.FirstSeenAt,
+			&d.LastSeenAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan device: %w", err)
+		}
+		p.Devices = append(p.Devices, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate devices: %w", err)
+	}
+
+	return &p, nil
+}
+
+// FindByDevice returns the profiles of every user seen on a device, most
+// recently seen on it first.
+func (r *ProfileRepository) FindByDevice(ctx context.Context, appID, deviceID string) ([]domain.Profile, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT user_id
+		FROM user_profile_devices
+		WHERE app_id = $1 AND device_id = $2
+		ORDER BY last_seen_at DESC
+	`, appID, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find users by device: %w", err)
+	}
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("failed to scan user id: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, fmt.Errorf("failed to iterate users: %w", err)
+	}
+	_ = rows.Close()
+
+	profiles := make([]domain.Profile, 0, len(userIDs))
+	for _, userID := range userIDs {
+		p, err := r.Get(ctx, appID, userID)
+		if err != nil {
+			if errors.Is(err, domain.ErrProfileNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		profiles = append(profiles, *p)
+	}
+
+	return profiles, nil
+}
@@ -0,0 +1,290 @@
+// Package service implements the profile sink: a JetStream consumer that
+// merges identity events into per-user profiles.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/SebastienMelki/causality/internal/events"
+	"github.com/SebastienMelki/causality/internal/profiles/internal/domain"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// ProfileStore defines the persistence interface needed by the extractor.
+// This mirrors the profiles.Store port to avoid import cycles.
+type ProfileStore interface {
+	MergeProfiles(ctx context.Context, profiles map[domain.ProfileKey]domain.Profile) error
+}
+
+// maxClockSkew bounds how far a client timestamp may lead the server's
+// receive time before the receive time is used instead.
+const maxClockSkew = 5 * time.Minute
+
+// Extractor consumes identity events from the event stream and maintains
+// user profiles. Each fetched batch is aggregated in memory, persisted in one
+// transaction, and only then acked. Profile merges are idempotent, so a
+// redelivered batch does not change the result.
+type Extractor struct {
+	js             jetstream.JetStream
+	store          ProfileStore
+	streamName     string
+	consumerName   string
+	fetchBatchSize int
+	fetchMaxWait   time.Duration
+	logger         *slog.Logger
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewExtractor creates a new profile extractor for the given durable consumer.
+func NewExtractor(
+	js jetstream.JetStream,
+	store ProfileStore,
+	streamName string,
+	consumerName string,
+	fetchBatchSize int,
+	fetchMaxWait time.Duration,
+	logger *slog.Logger,
+) *Extractor {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if fetchBatchSize < 1 {
+		fetchBatchSize = 500
+	}
+	if fetchMaxWait <= 0 {
+		fetchMaxWait = 5 * time.Second
+	}
+
+	return &Extractor{
+		js:             js,
+		store:          store,
+		streamName:     streamName,
+		consumerName:   consumerName,
+		fetchBatchSize: fetchBatchSize,
+		fetchMaxWait:   fetchMaxWait,
+		logger:         logger.With("component", "profile-extractor"),
+		stopCh:         make(chan struct{}),
+		doneCh:         make(chan struct{}),
+	}
+}
+
+// Start looks up the durable consumer and begins the fetch loop.
+func (e *Extractor) Start(ctx context.Context) error {
+	stream, err := e.js.Stream(ctx, e.streamName)
+	if err != nil {
+		return fmt.Errorf("failed to get stream: %w", err)
+	}
+
+	consumer, err := stream.Consumer(ctx, e.consumerName)
+	if err != nil {
+		return fmt.Errorf("failed to get consumer: %w", err)
+	}
+
+	e.logger.Info("starting profile extractor",
+		"stream", e.streamName,
+		"consumer", e.consumerName,
+		"fetch_batch_size", e.fetchBatchSize,
+	)
+
+	go e.run(ctx, consumer)
+	return nil
+}
+
+// Stop signals the fetch loop to stop and waits for the in-flight batch.
+func (e *Extractor) Stop() {
+	e.stopOnce.Do(func() {
+		close(e.stopCh)
+	})
+	<-e.doneCh
+}
+
+// run is the main fetch loop.
+func (e *Extractor) run(ctx context.Context, consumer jetstream.Consumer) {
+	defer close(e.doneCh)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.stopCh:
+			return
+		default:
+		}
+
+		msgs, err := consumer.Fetch(e.fetchBatchSize, jetstream.FetchMaxWait(e.fetchMaxWait))
+		if err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) {
+				e.logger.Error("failed to fetch messages", "error", err)
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+					return
+				case <-e.stopCh:
+					return
+				}
+			}
+			continue
+		}
+
+		var batch []jetstream.Msg
+		for msg := range msgs.Messages() {
+			batch = append(batch, msg)
+		}
+		if err := msgs.Error(); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			e.logger.Warn("fetch completed with error", "error", err)
+		}
+
+		e.processBatch(ctx, batch)
+	}
+}
+
+// processBatch aggregates a fetched batch, persists it, and acks on success.
+// Events that carry no identity are acked without being recorded, and
+// unparseable messages are terminated so they are not redelivered.
+func (e *Extractor) processBatch(ctx context.Context, batch []jetstream.Msg) {
+	if len(batch) == 0 {
+		return
+	}
+
+	agg := domain.NewAggregator()
+	counted := make([]jetstream.Msg, 0, len(batch))
+
+	for _, msg := range batch {
+		receivedAt := time.Now()
+		if meta, err := msg.Metadata(); err == nil {
+			receivedAt = meta.Timestamp
+		}
+
+		update, ok, err := updateFromMessage(msg.Data(), receivedAt)
+		if err != nil {
+			e.logger.Warn("terminating unparseable message",
+				"subject", msg.Subject(),
+				"error", err,
+			)
+			_ = msg.Term()
+			continue
+		}
+
+		if ok {
+			agg.Add(update)
+		}
+		counted = append(counted, msg)
+	}
+
+	if err := e.store.MergeProfiles(ctx, agg.Drain()); err != nil {
+		e.logger.Error("failed to persist profiles, will redeliver",
+			"messages", len(counted),
+			"error", err,
+		)
+		for _, msg := range counted {
+			_ = msg.Nak()
+		}
+		return
+	}
+
+	for _, msg := range counted {
+		if err := msg.Ack(); err != nil {
+			e.logger.Warn("failed to ack message", "subject", msg.Subject(), "error", err)
+		}
+	}
+
+	e.logger.Debug("profile batch recorded", "messages", len(counted))
+}
+
+// updateFromMessage extracts a profile update from a serialized
+// EventEnvelope. It reports false for events that are not user_login,
+// user_signup or user_profile_update, or that carry no user_id. The client
+// timestamp is used unless it is missing or ahead of receivedAt by more than
+// maxClockSkew.
+func updateFromMessage(data []byte, receivedAt time.Time) (domain.Update, bool, error) {
+	var event pb.EventEnvelope
+	if err := events.Decode(data, &event); err != nil {
+		return domain.Update{}, false, fmt.Errorf("unmarshal event: %w", err)
+	}
+	if event.GetAppId() == "" {
+		return domain.Update{}, false, errors.New("event has no app_id")
+	}
+
+	traits := make(map[string]string)
+	update := domain.Update{AppID: event.GetAppId()}
+
+	switch {
+	case event.GetUserLogin() != nil:
+		login := event.GetUserLogin()
+		update.UserID = login.GetUserId()
+		update.Login = true
+		setTrait(traits, domain.TraitLoginMethod, login.GetMethod())
+	case event.GetUserSignup() != nil:
+		signup := event.GetUserSignup()
+		update.UserID = signup.GetUserId()
+		update.Signup = true
+		setTrait(traits, domain.TraitSignupMethod, signup.GetMethod())
+		setTrait(traits, domain.TraitReferralSource, signup.GetReferralSource())
+	case event.GetUserProfileUpdate() != nil:
+		profileUpdate := event.GetUserProfileUpdate()
+		update.UserID = profileUpdate.GetUserId()
+		setTrait(traits, domain.TraitUpdatedFields, strings.Join(profileUpdate.GetFieldsUpdated(), ","))
+	default:
+		return domain.Update{}, false, nil
+	}
+	if update.UserID == "" {
+		return domain.Update{}, false, nil
+	}
+
+	at := receivedAt
+	if ms := event.GetTimestampMs(); ms > 0 {
+		if ts := time.UnixMilli(ms); !ts.After(receivedAt.Add(maxClockSkew)) {
+			at = ts
+		}
+	}
+	update.At = at.UTC()
+
+	dc := event.GetDeviceContext()
+	setTrait(traits, domain.TraitLocale, dc.GetLocale())
+	setTrait(traits, domain.TraitTimezone, dc.GetTimezone())
+	update.Traits = traits
+
+	if event.GetDeviceId() != "" {
+		update.Device = &domain.Device{
+			DeviceID:    event.GetDeviceId(),
+			Platform:    platformOf(dc.GetPlatform()),
+			OSVersion:   dc.GetOsVersion(),
+			AppVersion:  dc.GetAppVersion(),
+			DeviceModel: dc.GetDeviceModel(),
+		}
+	}
+
+	return update, true, nil
+}
+
+// setTrait sets a trait unless the value is empty.
+func setTrait(traits map[string]string, key, value string) {
+	if value != "" {
+		traits[key] = value
+	}
+}
+
+// platformOf maps a protobuf platform to its profile device platform.
+func platformOf(p pb.Platform) string {
+	switch p {
+	case pb.Platform_PLATFORM_IOS:
+		return "ios"
+	case pb.Platform_PLATFORM_ANDROID:
+		return "android"
+	case pb.Platform_PLATFORM_WEB:
+		return "web"
+	default:
+		return "other"
+	}
+}
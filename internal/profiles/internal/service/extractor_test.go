@@ -0,0 +1,130 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/SebastienMelki/causality/internal/profiles/internal/domain"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+func marshalEvent(t *testing.T, event *pb.EventEnvelope) []byte {
+	t.Helper()
+	data, err := proto.Marshal(event)
+	if err != nil {
+		t.Fatalf("marshal event: %v", err)
+	}
+	return data
+}
+
+func TestUpdateFromMessage(t *testing.T) {
+	receivedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	sentAt := receivedAt.Add(-time.Minute)
+
+	tests := []struct {
+		name  string
+		event *pb.EventEnvelope
+		want  domain.Update
+	}{
+		{
+			name: "signup with device",
+			event: &pb.EventEnvelope{
+				AppId:       "app",
+				DeviceId:    "d1",
+				TimestampMs: sentAt.UnixMilli(),
+				DeviceContext: &pb.DeviceContext{
+					Platform:    pb.Platform_PLATFORM_IOS,
+					OsVersion:   "18.1",
+					AppVersion:  "2.3.0",
+					DeviceModel: "iPhone16,1",
+					Locale:      "en_US",
+				},
+				Payload: &pb.EventEnvelope_UserSignup{
+					UserSignup: &pb.UserSignup{UserId: "u1", Method: "email", ReferralSource: "ad"},
+				},
+			},
+			want: domain.Update{
+				AppID: "app", UserID: "u1", At: sentAt, Signup: true,
+				Traits: map[string]string{
+					domain.TraitSignupMethod:   "email",
+					domain.TraitReferralSource: "ad",
+					domain.TraitLocale:         "en_US",
+				},
+				Device: &domain.Device{
+					DeviceID: "d1", Platform: "ios", OSVersion: "18.1",
+					AppVersion: "2.3.0", DeviceModel: "iPhone16,1",
+				},
+			},
+		},
+		{
+			name: "login in the future uses receive time",
+			event: &pb.EventEnvelope{
+				AppId:       "app",
+				TimestampMs: receivedAt.Add(time.Hour).UnixMilli(),
+				Payload: &pb.EventEnvelope_UserLogin{
+					UserLogin: &pb.UserLogin{UserId: "u1", Method: "google"},
+				},
+			},
+			want: domain.Update{
+				AppID: "app", UserID: "u1", At: receivedAt, Login: true,
+				Traits: map[string]string{domain.TraitLoginMethod: "google"},
+			},
+		},
+		{
+			name: "profile update",
+			event: &pb.EventEnvelope{
+				AppId:       "app",
+				TimestampMs: sentAt.UnixMilli(),
+				Payload: &pb.EventEnvelope_UserProfileUpdate{
+					UserProfileUpdate: &pb.UserProfileUpdate{UserId: "u1", FieldsUpdated: []string{"email", "name"}},
+				},
+			},
+			want: domain.Update{
+				AppID: "app", UserID: "u1", At: sentAt,
+				Traits: map[string]string{domain.TraitUpdatedFields: "email,name"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := updateFromMessage(marshalEvent(t, tt.event), receivedAt)
+			if err != nil {
+				t.Fatalf("updateFromMessage() error = %v", err)
+			}
+			if !ok {
+				t.Fatal("updateFromMessage() ok = false, want true")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("updateFromMessage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateFromMessage_Skips(t *testing.T) {
+	for name, event := range map[string]*pb.EventEnvelope{
+		"non-identity event": {
+			AppId:   "app",
+			Payload: &pb.EventEnvelope_AppStart{AppStart: &pb.AppStart{}},
+		},
+		"login without user id": {
+			AppId:   "app",
+			Payload: &pb.EventEnvelope_UserLogin{UserLogin: &pb.UserLogin{Method: "email"}},
+		},
+	} {
+		if _, ok, err := updateFromMessage(marshalEvent(t, event), time.Now()); err != nil || ok {
+			t.Errorf("%s: ok = %v, err = %v, want skipped without error", name, ok, err)
+		}
+	}
+
+	if _, _, err := updateFromMessage(marshalEvent(t, &pb.EventEnvelope{}), time.Now()); err == nil {
+		t.Error("expected error for event without app_id")
+	}
+	if _, _, err := updateFromMessage([]byte("not protobuf"), time.Now()); err == nil {
+		t.Error("expected error for unparseable message")
+	}
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_profile_devices;
+DROP TABLE IF EXISTS user_profiles;
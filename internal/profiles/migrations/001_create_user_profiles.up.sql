@@ -0,0 +1,30 @@
+-- Per-user profiles, maintained from identity events (profile-sink)
+CREATE TABLE IF NOT EXISTS user_profiles (
+    app_id        TEXT NOT NULL,
+    user_id       TEXT NOT NULL,
+    traits        JSONB NOT NULL DEFAULT '{}',
+    first_seen_at TIMESTAMPTZ NOT NULL,
+    last_seen_at  TIMESTAMPTZ NOT NULL,
+    signed_up_at  TIMESTAMPTZ,
+    last_login_at TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (app_id, user_id)
+);
+
+-- Devices each user has been seen on
+CREATE TABLE IF NOT EXISTS user_profile_devices (
+    app_id        TEXT NOT NULL,
+    user_id       TEXT NOT NULL,
+    device_id     TEXT NOT NULL,
+    platform      TEXT NOT NULL DEFAULT '',
+    os_version    TEXT NOT NULL DEFAULT '',
+    app_version   TEXT NOT NULL DEFAULT '',
+    device_model  TEXT NOT NULL DEFAULT '',
+    first_seen_at TIMESTAMPTZ NOT NULL,
+    last_seen_at  TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (app_id, user_id, device_id),
+    FOREIGN KEY (app_id, user_id) REFERENCES user_profiles(app_id, user_id) ON DELETE CASCADE
+);
+
+-- Profile lookup by device
+CREATE INDEX IF NOT EXISTS idx_user_profile_devices_device ON user_profile_devices(app_id, device_id);
@@ -0,0 +1,120 @@
+package profiles
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/SebastienMelki/causality/internal/db"
+	"github.com/SebastienMelki/causality/internal/profiles/internal/handler"
+	"github.com/SebastienMelki/causality/internal/profiles/internal/repo"
+	"github.com/SebastienMelki/causality/internal/profiles/internal/service"
+	"github.com/SebastienMelki/causality/internal/profiles/migrations"
+)
+
+// Migrations are the profiles module's database migrations.
+var Migrations = db.Source{Name: "profiles", FS: migrations.FS}
+
+// Config holds the profiles module configuration.
+type Config struct {
+	// ConsumerName is the durable JetStream consumer used for extraction.
+	ConsumerName string `env:"PROFILES_CONSUMER_NAME" envDefault:"profile-sink"`
+
+	// FilterSubject selects which stream subjects feed the profiles. Only
+	// user_login, user_signup and user_profile_update events are recorded.
+	FilterSubject string `env:"PROFILES_FILTER_SUBJECT" envDefault:"events.*.user.>"`
+
+	// FetchBatchSize is the number of messages aggregated per transaction.
+	FetchBatchSize int `env:"PROFILES_FETCH_BATCH_SIZE" envDefault:"500"`
+
+	// FetchMaxWait bounds how long a fetch waits for a full batch.
+	FetchMaxWait time.Duration `env:"PROFILES_FETCH_MAX_WAIT" envDefault:"5s"`
+}
+
+// Module is the profiles module facade. It wires the PostgreSQL repository,
+// stream extractor, and lookup API.
+type Module struct {
+	repo      *repo.ProfileRepository
+	extractor *service.Extractor
+	handler   *handler.ProfileHandler
+	config    Config
+	logger    *slog.Logger
+}
+
+// New creates a new profiles Module consuming from streamName.
+func New(db *sql.DB, js jetstream.JetStream, streamName string, cfg Config, logger *slog.Logger) *Module {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	profileRepo := repo.NewProfileRepository(db)
+
+	return &Module{
+		repo: profileRepo,
+		extractor: service.NewExtractor(
+			js,
+			profileRepo,
+			streamName,
+			cfg.ConsumerName,
+			cfg.FetchBatchSize,
+			cfg.FetchMaxWait,
+			logger,
+		),
+		handler: handler.NewProfileHandler(profileRepo, logger),
+		config:  cfg,
+		logger:  logger.With("component", "profiles-module"),
+	}
+}
+
+// Start begins profile extraction.
+func (m *Module) Start(ctx context.Context) error {
+	return m.extractor.Start(ctx)
+}
+
+// Stop stops profile extraction.
+func (m *Module) Stop() {
+	m.extractor.Stop()
+}
+
+// Get returns a user's profile, or ErrProfileNotFound.
+func (m *Module) Get(ctx context.Context, appID, userID string) (*Profile, error) {
+	return m.repo.Get(ctx, appID, userID)
+}
+
+// FindByDevice returns the profiles of every user seen on a device.
+func (m *Module) FindByDevice(ctx context.Context, appID, deviceID string) ([]Profile, error) {
+	return m.repo.FindByDevice(ctx, appID, deviceID)
+}
+
+// RegisterRoutes mounts the profile lookup endpoints onto the given ServeMux:
+//   - GET /api/admin/profiles/{app_id}/{user_id} - Get a user's profile
+//   - GET /api/admin/profiles/{app_id}?device_id= - Find profiles by device
+func (m *Module) RegisterRoutes(mux *http.ServeMux) {
+	m.handler.RegisterRoutes(mux)
+}
+
+// Reader serves profile lookups without extracting profiles, for services
+// reading the profiles maintained by profile-sink.
+type Reader struct {
+	repo *repo.ProfileRepository
+}
+
+// NewReader creates a Reader of the profiles in db.
+func NewReader(db *sql.DB) *Reader {
+	return &Reader{repo: repo.NewProfileRepository(db)}
+}
+
+// Get returns a user's profile, or ErrProfileNotFound.
+func (r *Reader) Get(ctx context.Context, appID, userID string) (*Profile, error) {
+	return r.repo.Get(ctx, appID, userID)
+}
+
+// FindByDevice returns the profiles of every user seen on a device, most
+// recently seen on it first.
+func (r *Reader) FindByDevice(ctx context.Context, appID, deviceID string) ([]Profile, error) {
+	return r.repo.FindByDevice(ctx, appID, deviceID)
+}
@@ -0,0 +1,38 @@
+// Package profiles provides the user profile store. It consumes identity
+// events (user_login, user_signup, user_profile_update) from the JetStream
+// event stream and maintains one profile per user in PostgreSQL: traits,
+// first and last seen times, signup and last login times, and the devices
+// the user has been seen on. Profiles are served by a lookup API, so
+// consumers no longer need to join login events against the warehouse to
+// resolve a user.
+package profiles
+
+import (
+	"context"
+
+	"github.com/SebastienMelki/causality/internal/profiles/internal/domain"
+)
+
+// Profile is a user's profile: traits, activity bounds, and devices.
+type Profile = domain.Profile
+
+// Device is a device a user has been seen on.
+type Device = domain.Device
+
+// ProfileKey identifies one user of one app.
+type ProfileKey = domain.ProfileKey
+
+// ErrProfileNotFound is returned when no profile exists for a user.
+var ErrProfileNotFound = domain.ErrProfileNotFound
+
+// Store defines the port for profile persistence.
+type Store interface {
+	// MergeProfiles atomically merges accumulated profile updates.
+	MergeProfiles(ctx context.Context, profiles map[ProfileKey]Profile) error
+
+	// Get returns a user's profile, or ErrProfileNotFound.
+	Get(ctx context.Context, appID, userID string) (*Profile, error)
+
+	// FindByDevice returns the profiles of every user seen on a device.
+	FindByDevice(ctx context.Context, appID, deviceID string) ([]Profile, error)
+}
@@ -0,0 +1,69 @@
+// Package domain contains the core types for the push token registry.
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Push providers a token can belong to.
+const (
+	ProviderFCM  = "fcm"
+	ProviderAPNs = "apns"
+)
+
+var (
+	// ErrTokenNotFound is returned when a device has no push token.
+	ErrTokenNotFound = errors.New("push token not found")
+
+	// ErrProviderNotConfigured is returned when sending to a token whose
+	// provider has no credentials.
+	ErrProviderNotConfigured = errors.New("push provider not configured")
+
+	// ErrInvalidToken is returned by a provider that rejected a token as
+	// unregistered or malformed; the token is then removed from the registry.
+	ErrInvalidToken = errors.New("push token rejected by provider")
+)
+
+// ValidProvider reports whether provider is a supported push provider.
+func ValidProvider(provider string) bool {
+	return provider == ProviderFCM || provider == ProviderAPNs
+}
+
+// TokenKey identifies the push token of one device of one app.
+type TokenKey struct {
+	AppID    string
+	DeviceID string
+}
+
+// Token is a device's push token. An empty Token in an update removes the
+// device's registration.
+type Token struct {
+	AppID     string    `json:"app_id"`
+	DeviceID  string    `json:"device_id"`
+	Provider  string    `json:"provider"`
+	Token     string    `json:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Latest keeps the most recent update of each device, so a batch replaying
+// a device's token history stores only its final token.
+func Latest(updates []Token) map[TokenKey]Token {
+	latest := make(map[TokenKey]Token, len(updates))
+	for _, u := range updates {
+		key := TokenKey{AppID: u.AppID, DeviceID: u.DeviceID}
+		if prev, ok := latest[key]; ok && prev.UpdatedAt.After(u.UpdatedAt) {
+			continue
+		}
+		latest[key] = u
+	}
+	return latest
+}
+
+// Notification is a push notification, rendered by the rule that sends it.
+type Notification struct {
+	Title string
+	Body  string
+	// Data is delivered to the app alongside the notification.
+	Data map[string]string
+}
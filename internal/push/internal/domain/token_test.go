@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatest(t *testing.T) {
+	t0 := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	latest := Latest([]Token{
+		{AppID: "app", DeviceID: "d1", Provider: ProviderFCM, Token: "new", UpdatedAt: t0.Add(time.Minute)},
+		{AppID: "app", DeviceID: "d1", Provider: ProviderFCM, Token: "old", UpdatedAt: t0},
+		{AppID: "app", DeviceID: "d2", Provider: ProviderAPNs, Token: "a", UpdatedAt: t0},
+		{AppID: "other", DeviceID: "d1", Provider: ProviderAPNs, Token: "b", UpdatedAt: t0},
+	})
+
+	if len(latest) != 3 {
+		t.Fatalf("len(Latest()) = %d, want 3", len(latest))
+	}
+	if got := latest[TokenKey{AppID: "app", DeviceID: "d1"}].Token; got != "new" {
+		t.Errorf("app/d1 token = %q, want %q", got, "new")
+	}
+	if got := latest[TokenKey{AppID: "other", DeviceID: "d1"}].Token; got != "b" {
+		t.Errorf("other/d1 token = %q, want %q", got, "b")
+	}
+}
+
+func TestValidProvider(t *testing.T) {
+	for provider, want := range map[string]bool{"fcm": true, "apns": true, "gcm": false, "": false} {
+		if got := ValidProvider(provider); got != want {
+			t.Errorf("ValidProvider(%q) = %v, want %v", provider, got, want)
+		}
+	}
+}
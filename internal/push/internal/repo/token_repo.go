@@ -0,0 +1,104 @@
+// Package repo provides the PostgreSQL implementation of the push Store port.
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/SebastienMelki/causality/internal/push/internal/domain"
+)
+
+// TokenRepository implements the Store interface using PostgreSQL.
+type TokenRepository struct {
+	db *sql.DB
+}
+
+// NewTokenRepository creates a new TokenRepository backed by the given database.
+func NewTokenRepository(db *sql.DB) *TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+// ApplyUpdates stores token updates in one transaction. An update older than
+// the stored token is ignored, so redelivered events cannot restore a
+// replaced token; an update with an empty token removes the registration.
+func (r *TokenRepository) ApplyUpdates(ctx context.Context, updates map[domain.TokenKey]domain.Token) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	upsertStmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO push_tokens (app_id, device_id, provider, token, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (app_id, device_id) DO UPDATE
+		SET provider = EXCLUDED.provider, token = EXCLUDED.token, updated_at = EXCLUDED.updated_at
+		WHERE push_tokens.updated_at <= EXCLUDED.updated_at
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare token upsert: %w", err)
+	}
+	defer func() { _ = upsertStmt.Close() }()
+
+	deleteStmt, err := tx.PrepareContext(ctx, `
+		DELETE FROM push_tokens
+		WHERE app_id = $1 AND device_id = $2 AND updated_at <= $3
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare token delete: %w", err)
+	}
+	defer func() { _ = deleteStmt.Close() }()
+
+	for key, t := range updates {
+		if t.Token == "" {
+			_, err = deleteStmt.ExecContext(ctx, key.AppID, key.DeviceID, t.UpdatedAt)
+		} else {
+			_, err = upsertStmt.ExecContext(ctx, key.AppID, key.DeviceID, t.Provider, t.Token, t.UpdatedAt)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to store push token of device %s: %w", key.DeviceID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit push tokens: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns a device's push token, or ErrTokenNotFound.
+func (r *TokenRepository) Get(ctx context.Context, appID, deviceID string) (*domain.Token, error) {
+	t := &domain.Token{AppID: appID, DeviceID: deviceID}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT provider, token, updated_at
+		FROM push_tokens
+		WHERE app_id = $1 AND device_id = $2
+	`, appID, deviceID).Scan(&t.Provider, &t.Token, &t.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get push token: %w", err)
+	}
+	return t, nil
+}
+
+// Delete removes a device's registration if it still holds token, so a
+// token refreshed since it was rejected is kept.
+func (r *TokenRepository) Delete(ctx context.Context, appID, deviceID, token string) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM push_tokens
+		WHERE app_id = $1 AND device_id = $2 AND token = $3
+	`, appID, deviceID, token)
+	if err != nil {
+		return fmt.Errorf("failed to delete push token: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/push/internal/domain"
+)
+
+const (
+	// apnsProductionEndpoint and apnsSandboxEndpoint are the APNs provider
+	// API hosts.
+	apnsProductionEndpoint = "https://api.push.apple.com"
+	apnsSandboxEndpoint    = "https://api.sandbox.push.apple.com"
+
+	// apnsTokenLifetime is how long a provider token is reused. APNs rejects
+	// tokens older than an hour and throttles providers refreshing them more
+	// often than every 20 minutes.
+	apnsTokenLifetime = 50 * time.Minute
+)
+
+// apnsInvalidTokenReasons are the APNs error reasons of tokens that will
+// never be deliverable again.
+var apnsInvalidTokenReasons = map[string]bool{
+	"BadDeviceToken":         true,
+	"Unregistered":           true,
+	"DeviceTokenNotForTopic": true,
+}
+
+// APNsSender sends notifications through the APNs provider API over HTTP/2,
+// authenticating with a token signing key (.p8).
+type APNsSender struct {
+	client   *http.Client
+	endpoint string
+	keyID    string
+	teamID   string
+	topic    string
+	key      *ecdsa.PrivateKey
+
+	mu       sync.Mutex
+	jwt      string
+	issuedAt time.Time
+}
+
+// NewAPNsSender creates an APNs sender from a token signing key's PEM
+// contents. topic is the app's bundle id; production selects the production
+// or the sandbox environment.
+func NewAPNsSender(keyPEM []byte, keyID, teamID, topic string, production bool, client *http.Client) (*APNsSender, error) {
+	if keyID == "" || teamID == "" || topic == "" {
+		return nil, errors.New("APNs needs a key id, team id and topic")
+	}
+
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("failed to parse APNs key: no PEM block found")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse APNs key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("failed to parse APNs key: not an ECDSA private key")
+	}
+
+	endpoint := apnsSandboxEndpoint
+	if production {
+		endpoint = apnsProductionEndpoint
+	}
+
+	return &APNsSender{
+		client:   client,
+		endpoint: endpoint,
+		keyID:    keyID,
+		teamID:   teamID,
+		topic:    topic,
+		key:      key,
+	}, nil
+}
+
+// Send delivers n to an APNs device token as an alert. Data entries are
+// added to the payload beside aps. It returns domain.ErrInvalidToken when
+// APNs reports the token unregistered or malformed.
+func (s *APNsSender) Send(ctx context.Context, token string, n domain.Notification) error {
+	jwt, err := s.token()
+	if err != nil {
+		return err
+	}
+
+	payload := make(map[string]interface{}, len(n.Data)+1)
+	for k, v := range n.Data {
+		payload[k] = v
+	}
+	payload["aps"] = map[string]interface{}{
+		"alert": map[string]string{
+			"title": n.Title,
+			"body":  n.Body,
+		},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal APNs payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		s.endpoint+"/3/device/"+url.PathEscape(token), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create APNs request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "bearer "+jwt)
+	req.Header.Set("apns-topic", s.topic)
+	req.Header.Set("apns-push-type", "alert")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("APNs request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var apnsErr struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.Unmarshal(respBody, &apnsErr)
+	if resp.StatusCode == http.StatusGone || apnsInvalidTokenReasons[apnsErr.Reason] {
+		return fmt.Errorf("%w: %s", domain.ErrInvalidToken, apnsErr.Reason)
+	}
+	if apnsErr.Reason == "ExpiredProviderToken" {
+		s.mu.Lock()
+		s.jwt = ""
+		s.mu.Unlock()
+	}
+	return fmt.Errorf("APNs returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+}
+
+// token returns the cached provider token, signing a new one when it is
+// older than apnsTokenLifetime.
+func (s *APNsSender) token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.jwt != "" && time.Since(s.issuedAt) < apnsTokenLifetime {
+		return s.jwt, nil
+	}
+
+	now := time.Now()
+	jwt, err := signJWT(map[string]string{"alg": "ES256", "kid": s.keyID}, map[string]interface{}{
+		"iss": s.teamID,
+		"iat": now.Unix(),
+	}, func(digest []byte) ([]byte, error) {
+		r, sigS, err := ecdsa.Sign(rand.Reader, s.key, digest)
+		if err != nil {
+			return nil, err
+		}
+		// JWS encodes ES256 signatures as the fixed size r || s.
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		sigS.FillBytes(sig[32:])
+		return sig, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	s.jwt = jwt
+	s.issuedAt = now
+	return s.jwt, nil
+}
@@ -0,0 +1,237 @@
+// Package service implements the push token registry sink, a JetStream
+// consumer storing the tokens reported by push_token_update events, and the
+// FCM and APNs senders.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/SebastienMelki/causality/internal/events"
+	"github.com/SebastienMelki/causality/internal/push/internal/domain"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// TokenStore defines the persistence interface needed by the extractor.
+// This mirrors the push.Store port to avoid import cycles.
+type TokenStore interface {
+	ApplyUpdates(ctx context.Context, updates map[domain.TokenKey]domain.Token) error
+}
+
+// errNotTokenUpdate marks events that are not push_token_update events,
+// which the extractor acks without storing.
+var errNotTokenUpdate = errors.New("not a push_token_update event")
+
+// Extractor consumes push_token_update events and keeps the latest token of
+// each device. Each fetched batch is stored in one transaction and only then
+// acked.
+type Extractor struct {
+	js             jetstream.JetStream
+	store          TokenStore
+	streamName     string
+	consumerName   string
+	fetchBatchSize int
+	fetchMaxWait   time.Duration
+	logger         *slog.Logger
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewExtractor creates a new push token extractor for the given durable
+// consumer.
+func NewExtractor(
+	js jetstream.JetStream,
+	store TokenStore,
+	streamName string,
+	consumerName string,
+	fetchBatchSize int,
+	fetchMaxWait time.Duration,
+	logger *slog.Logger,
+) *Extractor {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if fetchBatchSize < 1 {
+		fetchBatchSize = 100
+	}
+	if fetchMaxWait <= 0 {
+		fetchMaxWait = 5 * time.Second
+	}
+
+	return &Extractor{
+		js:             js,
+		store:          store,
+		streamName:     streamName,
+		consumerName:   consumerName,
+		fetchBatchSize: fetchBatchSize,
+		fetchMaxWait:   fetchMaxWait,
+		logger:         logger.With("component", "push-token-extractor"),
+		stopCh:         make(chan struct{}),
+		doneCh:         make(chan struct{}),
+	}
+}
+
+// Start looks up the durable consumer and begins the fetch loop.
+func (e *Extractor) Start(ctx context.Context) error {
+	stream, err := e.js.Stream(ctx, e.streamName)
+	if err != nil {
+		return fmt.Errorf("failed to get stream: %w", err)
+	}
+
+	consumer, err := stream.Consumer(ctx, e.consumerName)
+	if err != nil {
+		return fmt.Errorf("failed to get consumer: %w", err)
+	}
+
+	e.logger.Info("starting push token extractor",
+		"stream", e.streamName,
+		"consumer", e.consumerName,
+		"fetch_batch_size", e.fetchBatchSize,
+	)
+
+	go e.run(ctx, consumer)
+	return nil
+}
+
+// Stop signals the fetch loop to stop and waits for the in-flight batch.
+func (e *Extractor) Stop() {
+	e.stopOnce.Do(func() {
+		close(e.stopCh)
+	})
+	<-e.doneCh
+}
+
+// run is the main fetch loop.
+func (e *Extractor) run(ctx context.Context, consumer jetstream.Consumer) {
+	defer close(e.doneCh)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.stopCh:
+			return
+		default:
+		}
+
+		msgs, err := consumer.Fetch(e.fetchBatchSize, jetstream.FetchMaxWait(e.fetchMaxWait))
+		if err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) {
+				e.logger.Error("failed to fetch messages", "error", err)
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+					return
+				case <-e.stopCh:
+					return
+				}
+			}
+			continue
+		}
+
+		var batch []jetstream.Msg
+		for msg := range msgs.Messages() {
+			batch = append(batch, msg)
+		}
+		if err := msgs.Error(); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			e.logger.Warn("fetch completed with error", "error", err)
+		}
+
+		e.processBatch(ctx, batch)
+	}
+}
+
+// processBatch stores the token updates of a fetched batch and acks on
+// success. Unparseable messages are terminated so they are not redelivered;
+// other events are acked without being stored.
+func (e *Extractor) processBatch(ctx context.Context, batch []jetstream.Msg) {
+	if len(batch) == 0 {
+		return
+	}
+
+	updates := make([]domain.Token, 0, len(batch))
+	counted := make([]jetstream.Msg, 0, len(batch))
+
+	for _, msg := range batch {
+		receivedAt := time.Now()
+		if meta, err := msg.Metadata(); err == nil {
+			receivedAt = meta.Timestamp
+		}
+
+		update, err := tokenFromMessage(msg.Data(), receivedAt)
+		if errors.Is(err, errNotTokenUpdate) {
+			counted = append(counted, msg)
+			continue
+		}
+		if err != nil {
+			e.logger.Warn("terminating unparseable message",
+				"subject", msg.Subject(),
+				"error", err,
+			)
+			_ = msg.Term()
+			continue
+		}
+
+		updates = append(updates, update)
+		counted = append(counted, msg)
+	}
+
+	if err := e.store.ApplyUpdates(ctx, domain.Latest(updates)); err != nil {
+		e.logger.Error("failed to persist push tokens, will redeliver",
+			"messages", len(counted),
+			"error", err,
+		)
+		for _, msg := range counted {
+			_ = msg.Nak()
+		}
+		return
+	}
+
+	for _, msg := range counted {
+		if err := msg.Ack(); err != nil {
+			e.logger.Warn("failed to ack message", "subject", msg.Subject(), "error", err)
+		}
+	}
+
+	e.logger.Debug("push token batch recorded", "messages", len(counted), "updates", len(updates))
+}
+
+// tokenFromMessage extracts the token update of a serialized EventEnvelope.
+// Updates are ordered by receive time rather than the client timestamp, so a
+// device with a wrong clock cannot pin an old token.
+func tokenFromMessage(data []byte, receivedAt time.Time) (domain.Token, error) {
+	var event pb.EventEnvelope
+	if err := events.Decode(data, &event); err != nil {
+		return domain.Token{}, fmt.Errorf("unmarshal event: %w", err)
+	}
+
+	update := event.GetPushTokenUpdate()
+	if update == nil {
+		return domain.Token{}, errNotTokenUpdate
+	}
+	if event.GetAppId() == "" {
+		return domain.Token{}, errors.New("event has no app_id")
+	}
+	if event.GetDeviceId() == "" {
+		return domain.Token{}, errors.New("event has no device_id")
+	}
+	if update.GetToken() != "" && !domain.ValidProvider(update.GetProvider()) {
+		return domain.Token{}, fmt.Errorf("unknown push provider %q", update.GetProvider())
+	}
+
+	return domain.Token{
+		AppID:     event.GetAppId(),
+		DeviceID:  event.GetDeviceId(),
+		Provider:  update.GetProvider(),
+		Token:     update.GetToken(),
+		UpdatedAt: receivedAt.UTC(),
+	}, nil
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/SebastienMelki/causality/internal/push/internal/domain"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// fakeTokenStore records the updates applied, failing with err when set.
+type fakeTokenStore struct {
+	applied []map[domain.TokenKey]domain.Token
+	err     error
+}
+
+func (f *fakeTokenStore) ApplyUpdates(_ context.Context, updates map[domain.TokenKey]domain.Token) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.applied = append(f.applied, updates)
+	return nil
+}
+
+// fakeMsg is a jetstream.Msg received at a fixed time that records acks,
+// naks and terminations.
+type fakeMsg struct {
+	jetstream.Msg
+	data   []byte
+	meta   jetstream.MsgMetadata
+	acked  bool
+	nakked bool
+	termed bool
+}
+
+func (m *fakeMsg) Data() []byte                              { return m.data }
+func (m *fakeMsg) Subject() string                           { return "events.app.system.push_token_update" }
+func (m *fakeMsg) Metadata() (*jetstream.MsgMetadata, error) { return &m.meta, nil }
+
+func (m *fakeMsg) Ack() error {
+	m.acked = true
+	return nil
+}
+
+func (m *fakeMsg) Nak() error {
+	m.nakked = true
+	return nil
+}
+
+func (m *fakeMsg) Term() error {
+	m.termed = true
+	return nil
+}
+
+// tokenUpdateMsg returns a message carrying a push_token_update event of
+// device, received at receivedAt.
+func tokenUpdateMsg(t *testing.T, device, token, provider string, receivedAt time.Time) *fakeMsg {
+	t.Helper()
+	data, err := proto.Marshal(&pb.EventEnvelope{
+		AppId:    "app",
+		DeviceId: device,
+		Payload: &pb.EventEnvelope_PushTokenUpdate{
+			PushTokenUpdate: &pb.PushTokenUpdate{Token: token, Provider: provider},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fakeMsg{data: data, meta: jetstream.MsgMetadata{Timestamp: receivedAt}}
+}
+
+func TestTokenFromMessage(t *testing.T) {
+	receivedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	marshal := func(event *pb.EventEnvelope) []byte {
+		data, err := proto.Marshal(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	update := func(token, provider string) *pb.EventEnvelope {
+		return &pb.EventEnvelope{
+			AppId:    "app",
+			DeviceId: "d1",
+			Payload: &pb.EventEnvelope_PushTokenUpdate{
+				PushTokenUpdate: &pb.PushTokenUpdate{Token: token, Provider: provider},
+			},
+		}
+	}
+
+	got, err := tokenFromMessage(marshal(update("tok-1", "fcm")), receivedAt)
+	if err != nil {
+		t.Fatalf("tokenFromMessage() error = %v", err)
+	}
+	if got.AppID != "app" || got.DeviceID != "d1" || got.Token != "tok-1" || got.Provider != "fcm" || !got.UpdatedAt.Equal(receivedAt) {
+		t.Errorf("tokenFromMessage() = %+v", got)
+	}
+
+	if got, err := tokenFromMessage(marshal(update("", "")), receivedAt); err != nil || got.Token != "" {
+		t.Errorf("unregister: tokenFromMessage() = %+v, %v, want empty token", got, err)
+	}
+	if _, err := tokenFromMessage(marshal(update("tok-1", "gcm")), receivedAt); err == nil {
+		t.Error("unknown provider: expected error")
+	}
+
+	other := &pb.EventEnvelope{
+		AppId:   "app",
+		Payload: &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}},
+	}
+	if _, err := tokenFromMessage(marshal(other), receivedAt); !errors.Is(err, errNotTokenUpdate) {
+		t.Errorf("screen view: error = %v, want errNotTokenUpdate", err)
+	}
+}
+
+// TestExtractor_ProcessBatch verifies a batch stores the latest token of
+// each device, acks what it consumed and terminates unparseable messages.
+func TestExtractor_ProcessBatch(t *testing.T) {
+	t0 := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	screenView, err := proto.Marshal(&pb.EventEnvelope{
+		AppId:   "app",
+		Payload: &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newer := tokenUpdateMsg(t, "d1", "tok-new", domain.ProviderFCM, t0.Add(time.Minute))
+	older := tokenUpdateMsg(t, "d1", "tok-old", domain.ProviderFCM, t0)
+	unregister := tokenUpdateMsg(t, "d2", "", "", t0)
+	other := &fakeMsg{data: screenView}
+	badProvider := tokenUpdateMsg(t, "d3", "tok", "gcm", t0)
+	garbage := &fakeMsg{data: []byte("not a protobuf")}
+
+	store := &fakeTokenStore{}
+	e := NewExtractor(nil, store, "CAUSALITY_EVENTS", "push-tokens", 0, 0, nil)
+	e.processBatch(context.Background(), []jetstream.Msg{newer, older, unregister, other, badProvider, garbage})
+
+	if len(store.applied) != 1 {
+		t.Fatalf("ApplyUpdates called %d times, want 1", len(store.applied))
+	}
+	updates := store.applied[0]
+	if len(updates) != 2 {
+		t.Fatalf("updates = %+v, want d1 and d2", updates)
+	}
+	if got := updates[domain.TokenKey{AppID: "app", DeviceID: "d1"}]; got.Token != "tok-new" || !got.UpdatedAt.Equal(t0.Add(time.Minute)) {
+		t.Errorf("d1 update = %+v, want the token received last", got)
+	}
+	if got, ok := updates[domain.TokenKey{AppID: "app", DeviceID: "d2"}]; !ok || got.Token != "" {
+		t.Errorf("d2 update = %+v, want an unregistration", got)
+	}
+
+	for name, msg := range map[string]*fakeMsg{"newer": newer, "older": older, "unregister": unregister, "screen view": other} {
+		if !msg.acked || msg.termed {
+			t.Errorf("%s: acked=%v termed=%v, want acked", name, msg.acked, msg.termed)
+		}
+	}
+	for name, msg := range map[string]*fakeMsg{"unknown provider": badProvider, "garbage": garbage} {
+		if !msg.termed || msg.acked {
+			t.Errorf("%s: acked=%v termed=%v, want terminated", name, msg.acked, msg.termed)
+		}
+	}
+}
+
+// TestExtractor_ProcessBatchStoreError verifies a batch the store rejects
+// is redelivered rather than acked.
+func TestExtractor_ProcessBatchStoreError(t *testing.T) {
+	msg := tokenUpdateMsg(t, "d1", "tok", domain.ProviderAPNs, time.Now())
+	garbage := &fakeMsg{data: []byte("not a protobuf")}
+
+	e := NewExtractor(nil, &fakeTokenStore{err: errors.New("connection refused")}, "CAUSALITY_EVENTS", "push-tokens", 0, 0, nil)
+	e.processBatch(context.Background(), []jetstream.Msg{msg, garbage})
+
+	if msg.acked || !msg.nakked {
+		t.Errorf("update: acked=%v nakked=%v, want nakked", msg.acked, msg.nakked)
+	}
+	if !garbage.termed {
+		t.Error("garbage should be terminated even when the store fails")
+	}
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/SebastienMelki/causality/internal/push/internal/domain"
+)
+
+func TestFCMSender_Send(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tokenRequests atomic.Int32
+	var message map[string]map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests.Add(1)
+		_ = r.ParseForm()
+		if r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			t.Errorf("grant_type = %q", r.Form.Get("grant_type"))
+		}
+		parts := strings.Split(r.Form.Get("assertion"), ".")
+		if len(parts) != 3 {
+			t.Fatalf("assertion has %d parts, want 3", len(parts))
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+			t.Errorf("assertion signature: %v", err)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "access-1", "expires_in": 3600})
+	})
+	mux.HandleFunc("POST /v1/projects/proj-1/messages:send", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer access-1" {
+			t.Errorf("Authorization = %q", got)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&message)
+		if message["message"]["token"] == "gone" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"error":{"status":"NOT_FOUND","details":[{"errorCode":"UNREGISTERED"}]}}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"name":"projects/proj-1/messages/1"}`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: mustPKCS8(t, key)})
+	credentials, _ := json.Marshal(serviceAccount{
+		ProjectID:   "proj-1",
+		ClientEmail: "push@proj-1.iam.gserviceaccount.com",
+		PrivateKey:  string(keyPEM),
+		TokenURI:    srv.URL + "/token",
+	})
+
+	sender, err := NewFCMSender(credentials, "", srv.Client())
+	if err != nil {
+		t.Fatalf("NewFCMSender() error = %v", err)
+	}
+	sender.endpoint = srv.URL
+
+	n := domain.Notification{Title: "Your cart", Body: "2 items left", Data: map[string]string{"cart_id": "c1"}}
+	if err := sender.Send(context.Background(), "tok-1", n); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	notification := message["message"]["notification"].(map[string]interface{})
+	if notification["title"] != "Your cart" || notification["body"] != "2 items left" {
+		t.Errorf("notification = %v", notification)
+	}
+	if data := message["message"]["data"].(map[string]interface{}); data["cart_id"] != "c1" {
+		t.Errorf("data = %v", data)
+	}
+
+	if err := sender.Send(context.Background(), "gone", n); !errors.Is(err, domain.ErrInvalidToken) {
+		t.Errorf("Send(unregistered) error = %v, want ErrInvalidToken", err)
+	}
+	if got := tokenRequests.Load(); got != 1 {
+		t.Errorf("token requests = %d, want 1 (access token reused)", got)
+	}
+}
+
+func TestAPNsSender_Send(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var payload map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("apns-topic"); got != "com.example.app" {
+			t.Errorf("apns-topic = %q", got)
+		}
+		jwt := strings.TrimPrefix(r.Header.Get("Authorization"), "bearer ")
+		parts := strings.Split(jwt, ".")
+		if len(parts) != 3 {
+			t.Fatalf("provider token has %d parts, want 3", len(parts))
+		}
+		header, _ := base64.RawURLEncoding.DecodeString(parts[0])
+		if !strings.Contains(string(header), `"kid":"KEY123"`) {
+			t.Errorf("header = %s, want kid KEY123", header)
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if len(sig) != 64 || !ecdsa.Verify(&key.PublicKey, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+			t.Error("provider token signature does not verify")
+		}
+
+		if r.URL.Path == "/3/device/bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"reason":"BadDeviceToken"}`)
+			return
+		}
+		if r.URL.Path != "/3/device/tok-1" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer srv.Close()
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: mustPKCS8(t, key)})
+	sender, err := NewAPNsSender(keyPEM, "KEY123", "TEAM123", "com.example.app", false, srv.Client())
+	if err != nil {
+		t.Fatalf("NewAPNsSender() error = %v", err)
+	}
+	sender.endpoint = srv.URL
+
+	n := domain.Notification{Title: "Your cart", Body: "2 items left", Data: map[string]string{"cart_id": "c1"}}
+	if err := sender.Send(context.Background(), "tok-1", n); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	alert := payload["aps"].(map[string]interface{})["alert"].(map[string]interface{})
+	if alert["title"] != "Your cart" || alert["body"] != "2 items left" {
+		t.Errorf("alert = %v", alert)
+	}
+	if payload["cart_id"] != "c1" {
+		t.Errorf("payload = %v, want cart_id beside aps", payload)
+	}
+
+	if err := sender.Send(context.Background(), "bad", n); !errors.Is(err, domain.ErrInvalidToken) {
+		t.Errorf("Send(bad token) error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func mustPKCS8(t *testing.T, key interface{}) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
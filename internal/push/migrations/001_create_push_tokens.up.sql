@@ -0,0 +1,9 @@
+-- Latest push notification token of each device (push token registry)
+CREATE TABLE IF NOT EXISTS push_tokens (
+    app_id VARCHAR(255) NOT NULL,
+    device_id VARCHAR(255) NOT NULL,
+    provider VARCHAR(20) NOT NULL,
+    token TEXT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (app_id, device_id)
+);
@@ -0,0 +1,9 @@
+// Package migrations embeds the push module's SQL migrations.
+package migrations
+
+import "embed"
+
+// FS holds the up and down migration files.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,186 @@
+package push
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/SebastienMelki/causality/internal/db"
+	"github.com/SebastienMelki/causality/internal/push/internal/repo"
+	"github.com/SebastienMelki/causality/internal/push/internal/service"
+	"github.com/SebastienMelki/causality/internal/push/migrations"
+)
+
+// Migrations are the push module's database migrations.
+var Migrations = db.Source{Name: "push", FS: migrations.FS}
+
+// Config holds the push module configuration.
+type Config struct {
+	// Enabled controls whether push tokens are registered and the
+	// push_notification rule action sends notifications.
+	Enabled bool `env:"PUSH_ENABLED" envDefault:"false"`
+
+	// ConsumerName is the durable JetStream consumer used for registration.
+	ConsumerName string `env:"PUSH_CONSUMER_NAME" envDefault:"push-tokens"`
+
+	// FilterSubject selects which stream subjects feed the registry.
+	FilterSubject string `env:"PUSH_FILTER_SUBJECT" envDefault:"events.*.system.push_token_update"`
+
+	// FetchBatchSize is the number of messages stored per transaction.
+	FetchBatchSize int `env:"PUSH_FETCH_BATCH_SIZE" envDefault:"100"`
+
+	// FetchMaxWait bounds how long a fetch waits for a full batch.
+	FetchMaxWait time.Duration `env:"PUSH_FETCH_MAX_WAIT" envDefault:"5s"`
+
+	// SendTimeout bounds each notification request, including obtaining
+	// provider credentials.
+	SendTimeout time.Duration `env:"PUSH_SEND_TIMEOUT" envDefault:"10s"`
+
+	// FCMCredentialsFile is the path of a Firebase service account key
+	// file. FCM is disabled when empty.
+	FCMCredentialsFile string `env:"PUSH_FCM_CREDENTIALS_FILE"`
+
+	// FCMProjectID overrides the service account's Firebase project.
+	FCMProjectID string `env:"PUSH_FCM_PROJECT_ID"`
+
+	// APNsKeyFile is the path of an APNs token signing key (.p8). APNs is
+	// disabled when empty.
+	APNsKeyFile string `env:"PUSH_APNS_KEY_FILE"`
+
+	// APNsKeyID is the id of the APNs signing key.
+	APNsKeyID string `env:"PUSH_APNS_KEY_ID"`
+
+	// APNsTeamID is the Apple developer team id owning the key.
+	APNsTeamID string `env:"PUSH_APNS_TEAM_ID"`
+
+	// APNsTopic is the app's bundle id.
+	APNsTopic string `env:"PUSH_APNS_TOPIC"`
+
+	// APNsProduction sends through the production APNs environment instead
+	// of the sandbox.
+	APNsProduction bool `env:"PUSH_APNS_PRODUCTION" envDefault:"true"`
+}
+
+// Module is the push module facade. It wires the PostgreSQL repository, the
+// token extractor, and the configured provider senders.
+type Module struct {
+	repo      *repo.TokenRepository
+	extractor *service.Extractor
+	senders   map[string]service.Sender
+	config    Config
+	logger    *slog.Logger
+}
+
+// New creates a new push Module consuming from streamName. It fails if a
+// configured provider's credentials cannot be read.
+func New(db *sql.DB, js jetstream.JetStream, streamName string, cfg Config, logger *slog.Logger) (*Module, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.SendTimeout <= 0 {
+		cfg.SendTimeout = 10 * time.Second
+	}
+
+	client := &http.Client{Timeout: cfg.SendTimeout}
+	senders := make(map[string]service.Sender)
+
+	if cfg.FCMCredentialsFile != "" {
+		credentials, err := os.ReadFile(cfg.FCMCredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read FCM credentials: %w", err)
+		}
+		sender, err := service.NewFCMSender(credentials, cfg.FCMProjectID, client)
+		if err != nil {
+			return nil, err
+		}
+		senders[ProviderFCM] = sender
+	}
+
+	if cfg.APNsKeyFile != "" {
+		key, err := os.ReadFile(cfg.APNsKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read APNs key: %w", err)
+		}
+		sender, err := service.NewAPNsSender(key, cfg.APNsKeyID, cfg.APNsTeamID, cfg.APNsTopic, cfg.APNsProduction, client)
+		if err != nil {
+			return nil, err
+		}
+		senders[ProviderAPNs] = sender
+	}
+
+	tokenRepo := repo.NewTokenRepository(db)
+
+	return &Module{
+		repo: tokenRepo,
+		extractor: service.NewExtractor(
+			js,
+			tokenRepo,
+			streamName,
+			cfg.ConsumerName,
+			cfg.FetchBatchSize,
+			cfg.FetchMaxWait,
+			logger,
+		),
+		senders: senders,
+		config:  cfg,
+		logger:  logger.With("component", "push-module"),
+	}, nil
+}
+
+// Start begins push token registration.
+func (m *Module) Start(ctx context.Context) error {
+	m.logger.Info("push providers configured",
+		"fcm", m.senders[ProviderFCM] != nil,
+		"apns", m.senders[ProviderAPNs] != nil,
+	)
+	return m.extractor.Start(ctx)
+}
+
+// Stop stops push token registration.
+func (m *Module) Stop() {
+	m.extractor.Stop()
+}
+
+// Lookup returns a device's push token, or ErrTokenNotFound.
+func (m *Module) Lookup(ctx context.Context, appID, deviceID string) (*Token, error) {
+	return m.repo.Get(ctx, appID, deviceID)
+}
+
+// Send delivers n to a device through the provider of its push token. It
+// returns ErrTokenNotFound for devices without a token and
+// ErrProviderNotConfigured when the token's provider has no credentials.
+// Tokens the provider rejects are removed, and ErrInvalidToken is returned.
+func (m *Module) Send(ctx context.Context, appID, deviceID string, n Notification) error {
+	token, err := m.repo.Get(ctx, appID, deviceID)
+	if err != nil {
+		return err
+	}
+
+	sender, ok := m.senders[token.Provider]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrProviderNotConfigured, token.Provider)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.config.SendTimeout)
+	defer cancel()
+
+	err = sender.Send(ctx, token.Token, n)
+	if errors.Is(err, ErrInvalidToken) {
+		m.logger.Info("removing rejected push token",
+			"app_id", appID,
+			"device_id", deviceID,
+			"provider", token.Provider,
+		)
+		if delErr := m.repo.Delete(ctx, appID, deviceID, token.Token); delErr != nil {
+			m.logger.Warn("failed to remove rejected push token", "device_id", deviceID, "error", delErr)
+		}
+	}
+	return err
+}
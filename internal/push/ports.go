@@ -0,0 +1,53 @@
+// Package push provides the push token registry and notification sending.
+// It consumes push_token_update events, which the SDKs send when an app
+// registers for remote notifications, from the JetStream event stream and
+// keeps each device's latest token in PostgreSQL. The rule engine's
+// push_notification action sends through it via FCM or APNs; tokens a
+// provider rejects as unregistered are removed.
+package push
+
+import (
+	"context"
+
+	"github.com/SebastienMelki/causality/internal/push/internal/domain"
+)
+
+// Token is a device's push token.
+type Token = domain.Token
+
+// TokenKey identifies the push token of one device of one app.
+type TokenKey = domain.TokenKey
+
+// Notification is a push notification.
+type Notification = domain.Notification
+
+// Push providers a token can belong to.
+const (
+	ProviderFCM  = domain.ProviderFCM
+	ProviderAPNs = domain.ProviderAPNs
+)
+
+var (
+	// ErrTokenNotFound is returned when a device has no push token.
+	ErrTokenNotFound = domain.ErrTokenNotFound
+
+	// ErrProviderNotConfigured is returned when sending to a token whose
+	// provider has no credentials.
+	ErrProviderNotConfigured = domain.ErrProviderNotConfigured
+
+	// ErrInvalidToken is returned when a provider rejected a token.
+	ErrInvalidToken = domain.ErrInvalidToken
+)
+
+// Store defines the port for push token persistence.
+type Store interface {
+	// ApplyUpdates stores the latest token update of each device. Updates
+	// older than the stored token are ignored; empty tokens unregister.
+	ApplyUpdates(ctx context.Context, updates map[TokenKey]Token) error
+
+	// Get returns a device's push token, or ErrTokenNotFound.
+	Get(ctx context.Context, appID, deviceID string) (*Token, error)
+
+	// Delete removes a device's registration if it still holds token.
+	Delete(ctx context.Context, appID, deviceID, token string) error
+}
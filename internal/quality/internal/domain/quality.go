@@ -0,0 +1,446 @@
+// Package domain contains the core types of the data-quality time series.
+package domain
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcome is what the gateway did with an event.
+type Outcome string
+
+// Outcome values of observations.
+const (
+	// OutcomeAccepted means the event was published.
+	OutcomeAccepted Outcome = "accepted"
+
+	// OutcomeDeduplicated means the event repeated an idempotency key and
+	// was dropped.
+	OutcomeDeduplicated Outcome = "deduplicated"
+
+	// OutcomeRejected means the event failed validation.
+	OutcomeRejected Outcome = "rejected"
+)
+
+// Bucket dimensions of the hourly distributions.
+const (
+	// DimensionRejection counts rejected events by rejection code.
+	DimensionRejection = "rejection"
+
+	// DimensionClockSkew counts events by the offset of the sending device's
+	// clock from server time (see SkewBucket).
+	DimensionClockSkew = "clock_skew"
+
+	// DimensionSDK counts events by the SDK that sent them (see SDK.Label).
+	DimensionSDK = "sdk"
+)
+
+// unknownSDK stands for the parts of an SDK its client did not report.
+const unknownSDK = "unknown"
+
+// SDK identifies the client library that sent an event.
+type SDK struct {
+	Name     string
+	Version  string
+	Platform string
+}
+
+// Label returns the SDK's bucket label, "name/version/platform", with
+// unreported parts as "unknown".
+func (s SDK) Label() string {
+	parts := []string{s.Name, s.Version, s.Platform}
+	for i, p := range parts {
+		if p == "" {
+			parts[i] = unknownSDK
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// ParseSDKLabel returns the SDK of a bucket label. Labels with too few
+// parts leave the missing parts empty.
+func ParseSDKLabel(label string) SDK {
+	parts := strings.SplitN(label, "/", 3)
+	parts = append(parts, "", "")
+	return SDK{Name: parts[0], Version: parts[1], Platform: parts[2]}
+}
+
+// skewBuckets are the upper bounds of the clock skew buckets, with their
+// labels. Negative skews are device clocks behind the server.
+var skewBuckets = []struct {
+	upper time.Duration
+	label string
+}{
+	{-time.Hour, "behind_1h+"},
+	{-5 * time.Minute, "behind_5m-1h"},
+	{-time.Minute, "behind_1m-5m"},
+	{-5 * time.Second, "behind_5s-1m"},
+	{5 * time.Second, "in_sync"},
+	{time.Minute, "ahead_5s-1m"},
+	{5 * time.Minute, "ahead_1m-5m"},
+	{time.Hour, "ahead_5m-1h"},
+}
+
+// skewOverflow is the label of skews of an hour or more ahead.
+const skewOverflow = "ahead_1h+"
+
+// SkewBucket returns the label of the clock skew bucket of skew, the
+// device clock minus server time. Skews within 5 seconds, about a request's
+// round trip, are in sync.
+func SkewBucket(skew time.Duration) string {
+	for _, b := range skewBuckets {
+		if skew < b.upper {
+			return b.label
+		}
+	}
+	return skewOverflow
+}
+
+// Observation is the ingestion of one event.
+type Observation struct {
+	AppID   string
+	Outcome Outcome
+
+	// Reason is the rejection code of a rejected event.
+	Reason string
+
+	// ReceivedAt is when the gateway received the request.
+	ReceivedAt time.Time
+
+	// EventTime is the event's client timestamp; zero if it has none.
+	EventTime time.Time
+
+	// SentAt is the device time the request was sent at, from the clock
+	// sync header; zero if the client did not send it.
+	SentAt time.Time
+
+	// ReportedSkew is the device clock offset an SDK corrected EventTime
+	// by, from device_context.clock_skew_ms; zero if not reported.
+	ReportedSkew time.Duration
+
+	// SDK is the client library that sent the event, from the SDK
+	// handshake header or, for older SDKs, the event's device context.
+	SDK SDK
+}
+
+// Key identifies the counters of one app and UTC hour.
+type Key struct {
+	AppID string
+	Hour  time.Time
+}
+
+// HourOf truncates t to its UTC hour.
+func HourOf(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Hour)
+}
+
+// Counts holds the data-quality counters of an app over a window.
+// Accepted excludes deduplicated events.
+type Counts struct {
+	Accepted     int64 `json:"accepted"`
+	Deduplicated int64 `json:"deduplicated"`
+	Rejected     int64 `json:"rejected"`
+
+	// Late counts accepted events sent more than the late threshold after
+	// their timestamp.
+	Late int64 `json:"late"`
+
+	// Rejections counts rejected events by rejection code.
+	Rejections map[string]int64 `json:"rejections,omitempty"`
+
+	// ClockSkew counts events from clients sending the clock sync header,
+	// or reporting their skew, by skew bucket.
+	ClockSkew map[string]int64 `json:"clock_skew,omitempty"`
+
+	// SDKs counts received events by SDK label.
+	SDKs map[string]int64 `json:"sdks,omitempty"`
+}
+
+// Received returns the number of events received.
+func (c *Counts) Received() int64 {
+	return c.Accepted + c.Deduplicated + c.Rejected
+}
+
+// Add adds the counters of other.
+func (c *Counts) Add(other *Counts) {
+	c.Accepted += other.Accepted
+	c.Deduplicated += other.Deduplicated
+	c.Rejected += other.Rejected
+	c.Late += other.Late
+	for reason, n := range other.Rejections {
+		c.AddBucket(DimensionRejection, reason, n)
+	}
+	for bucket, n := range other.ClockSkew {
+		c.AddBucket(DimensionClockSkew, bucket, n)
+	}
+	for label, n := range other.SDKs {
+		c.AddBucket(DimensionSDK, label, n)
+	}
+}
+
+// AddBucket adds n to a bucket of a distribution. Unknown dimensions are
+// ignored.
+func (c *Counts) AddBucket(dimension, bucket string, n int64) {
+	switch dimension {
+	case DimensionRejection:
+		if c.Rejections == nil {
+			c.Rejections = make(map[string]int64)
+		}
+		c.Rejections[bucket] += n
+	case DimensionClockSkew:
+		if c.ClockSkew == nil {
+			c.ClockSkew = make(map[string]int64)
+		}
+		c.ClockSkew[bucket] += n
+	case DimensionSDK:
+		if c.SDKs == nil {
+			c.SDKs = make(map[string]int64)
+		}
+		c.SDKs[bucket] += n
+	}
+}
+
+// observe counts one observation.
+func (c *Counts) observe(obs Observation, lateThreshold time.Duration) {
+	switch obs.Outcome {
+	case OutcomeAccepted:
+		c.Accepted++
+		// Lateness is measured in the clock of the event's timestamp: the
+		// device's send time when known, unless the SDK already corrected
+		// the timestamp to server time, so that skew does not count as delay.
+		sent := obs.ReceivedAt
+		if !obs.SentAt.IsZero() && obs.ReportedSkew == 0 {
+			sent = obs.SentAt
+		}
+		if !obs.EventTime.IsZero() && sent.Sub(obs.EventTime) > lateThreshold {
+			c.Late++
+		}
+	case OutcomeDeduplicated:
+		c.Deduplicated++
+	case OutcomeRejected:
+		c.Rejected++
+		reason := obs.Reason
+		if reason == "" {
+			reason = "unknown"
+		}
+		c.AddBucket(DimensionRejection, reason, 1)
+	}
+
+	switch {
+	case !obs.SentAt.IsZero():
+		c.AddBucket(DimensionClockSkew, SkewBucket(obs.SentAt.Sub(obs.ReceivedAt)), 1)
+	case obs.ReportedSkew != 0:
+		c.AddBucket(DimensionClockSkew, SkewBucket(obs.ReportedSkew), 1)
+	}
+
+	c.AddBucket(DimensionSDK, obs.SDK.Label(), 1)
+}
+
+// Hourly is the stored counters of one app and hour.
+type Hourly struct {
+	Hour time.Time `json:"start"`
+	Counts
+}
+
+// Report is the data-quality report of an app over a window: its totals
+// and rates, and the series of counters by hour or day.
+type Report struct {
+	AppID       string    `json:"app_id"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Granularity string    `json:"granularity"`
+	Totals      Counts    `json:"totals"`
+
+	// DuplicateRate and RejectionRate are fractions of received events;
+	// LateRate is a fraction of accepted events.
+	DuplicateRate float64 `json:"duplicate_rate"`
+	RejectionRate float64 `json:"rejection_rate"`
+	LateRate      float64 `json:"late_rate"`
+
+	Series []Hourly `json:"series"`
+}
+
+// Report granularities.
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+)
+
+// NewReport builds the report of an app over [from, to) from its hourly
+// counters. With GranularityDay, the series sums the hours of each UTC day.
+func NewReport(appID string, from, to time.Time, granularity string, hours []Hourly) Report {
+	r := Report{
+		AppID:       appID,
+		From:        from,
+		To:          to,
+		Granularity: granularity,
+		Series:      []Hourly{},
+	}
+
+	points := make(map[time.Time]*Hourly)
+	for i := range hours {
+		r.Totals.Add(&hours[i].Counts)
+
+		start := hours[i].Hour.UTC()
+		if granularity == GranularityDay {
+			start = start.Truncate(24 * time.Hour)
+		}
+		p, ok := points[start]
+		if !ok {
+			p = &Hourly{Hour: start}
+			points[start] = p
+		}
+		p.Add(&hours[i].Counts)
+	}
+	for _, p := range points {
+		r.Series = append(r.Series, *p)
+	}
+	sort.Slice(r.Series, func(i, j int) bool { return r.Series[i].Hour.Before(r.Series[j].Hour) })
+
+	if received := r.Totals.Received(); received > 0 {
+		r.DuplicateRate = float64(r.Totals.Deduplicated) / float64(received)
+		r.RejectionRate = float64(r.Totals.Rejected) / float64(received)
+	}
+	if r.Totals.Accepted > 0 {
+		r.LateRate = float64(r.Totals.Late) / float64(r.Totals.Accepted)
+	}
+	return r
+}
+
+// SDKVersion is the traffic of one SDK version on one platform.
+type SDKVersion struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Platform string `json:"platform"`
+	Events   int64  `json:"events"`
+
+	// Share is the fraction of the report's events sent by the version.
+	Share float64 `json:"share"`
+
+	// FirstSeen and LastSeen are the first and last hours in the window
+	// with events from the version.
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// SDKReport is the SDK version distribution of an app's events over a
+// window, for deciding when an SDK version can be deprecated.
+type SDKReport struct {
+	AppID string    `json:"app_id"`
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+
+	// Events counts the events with a recorded SDK; hours recorded before
+	// SDKs were counted are not included.
+	Events int64 `json:"events"`
+
+	// Versions are sorted by events, most first.
+	Versions []SDKVersion `json:"versions"`
+}
+
+// NewSDKReport builds the SDK version distribution of an app over
+// [from, to) from its hourly counters.
+func NewSDKReport(appID string, from, to time.Time, hours []Hourly) SDKReport {
+	r := SDKReport{AppID: appID, From: from, To: to, Versions: []SDKVersion{}}
+
+	versions := make(map[string]*SDKVersion)
+	for _, h := range hours {
+		hour := h.Hour.UTC()
+		for label, n := range h.SDKs {
+			v, ok := versions[label]
+			if !ok {
+				sdk := ParseSDKLabel(label)
+				v = &SDKVersion{Name: sdk.Name, Version: sdk.Version, Platform: sdk.Platform, FirstSeen: hour, LastSeen: hour}
+				versions[label] = v
+			}
+			v.Events += n
+			if hour.Before(v.FirstSeen) {
+				v.FirstSeen = hour
+			}
+			if hour.After(v.LastSeen) {
+				v.LastSeen = hour
+			}
+			r.Events += n
+		}
+	}
+
+	for _, v := range versions {
+		if r.Events > 0 {
+			v.Share = float64(v.Events) / float64(r.Events)
+		}
+		r.Versions = append(r.Versions, *v)
+	}
+	sort.Slice(r.Versions, func(i, j int) bool {
+		a, b := r.Versions[i], r.Versions[j]
+		if a.Events != b.Events {
+			return a.Events > b.Events
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.Platform < b.Platform
+	})
+	return r
+}
+
+// Aggregator accumulates observations into hourly counters in memory
+// between flushes. It is safe for concurrent use.
+type Aggregator struct {
+	mu            sync.Mutex
+	lateThreshold time.Duration
+	counts        map[Key]*Counts
+}
+
+// NewAggregator creates an empty Aggregator counting accepted events sent
+// more than lateThreshold after their timestamp as late.
+func NewAggregator(lateThreshold time.Duration) *Aggregator {
+	return &Aggregator{
+		lateThreshold: lateThreshold,
+		counts:        make(map[Key]*Counts),
+	}
+}
+
+// Observe counts an observation in the hour it was received.
+func (a *Aggregator) Observe(obs Observation) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := Key{AppID: obs.AppID, Hour: HourOf(obs.ReceivedAt)}
+	c, ok := a.counts[key]
+	if !ok {
+		c = &Counts{}
+		a.counts[key] = c
+	}
+	c.observe(obs, a.lateThreshold)
+}
+
+// Drain returns the accumulated counters and resets the aggregator.
+func (a *Aggregator) Drain() map[Key]*Counts {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	counts := a.counts
+	a.counts = make(map[Key]*Counts)
+	return counts
+}
+
+// Restore adds back counters drained but not stored, so that a failed
+// flush is retried by the next one.
+func (a *Aggregator) Restore(counts map[Key]*Counts) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for key, src := range counts {
+		c, ok := a.counts[key]
+		if !ok {
+			a.counts[key] = src
+			continue
+		}
+		c.Add(src)
+	}
+}
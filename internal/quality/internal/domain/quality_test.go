@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSkewBucket(t *testing.T) {
+	tests := []struct {
+		skew time.Duration
+		want string
+	}{
+		{-2 * time.Hour, "behind_1h+"},
+		{-10 * time.Minute, "behind_5m-1h"},
+		{-30 * time.Second, "behind_5s-1m"},
+		{-5 * time.Second, "in_sync"},
+		{0, "in_sync"},
+		{4 * time.Second, "in_sync"},
+		{2 * time.Minute, "ahead_1m-5m"},
+		{time.Hour, "ahead_1h+"},
+	}
+	for _, tt := range tests {
+		if got := SkewBucket(tt.skew); got != tt.want {
+			t.Errorf("SkewBucket(%v) = %q, want %q", tt.skew, got, tt.want)
+		}
+	}
+}
+
+func TestAggregator_ObserveAndDrain(t *testing.T) {
+	agg := NewAggregator(time.Hour)
+	received := time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC)
+
+	// An event buffered for 2h on a device whose clock is 3h behind was
+	// sent 2h after its timestamp: late, and skewed behind.
+	agg.Observe(Observation{
+		AppID:      "app",
+		Outcome:    OutcomeAccepted,
+		ReceivedAt: received,
+		EventTime:  received.Add(-5 * time.Hour),
+		SentAt:     received.Add(-3 * time.Hour),
+	})
+	// An event on time by its device's clock, though 3h behind the server.
+	agg.Observe(Observation{
+		AppID:      "app",
+		Outcome:    OutcomeAccepted,
+		ReceivedAt: received,
+		EventTime:  received.Add(-3 * time.Hour),
+		SentAt:     received.Add(-3 * time.Hour),
+	})
+	// A timestamp the SDK corrected to server time is measured at receipt,
+	// and its reported skew counted.
+	agg.Observe(Observation{
+		AppID:        "app",
+		Outcome:      OutcomeAccepted,
+		ReceivedAt:   received,
+		EventTime:    received.Add(-10 * time.Minute),
+		ReportedSkew: 30 * time.Second,
+	})
+	// Without the clock sync header, lateness is measured at receipt.
+	agg.Observe(Observation{AppID: "app", Outcome: OutcomeAccepted, ReceivedAt: received, EventTime: received.Add(-2 * time.Hour)})
+	agg.Observe(Observation{AppID: "app", Outcome: OutcomeDeduplicated, ReceivedAt: received, SDK: SDK{Name: "causality-mobile", Version: "1.2.0", Platform: "ios"}})
+	agg.Observe(Observation{AppID: "app", Outcome: OutcomeRejected, Reason: "timestamp_required", ReceivedAt: received, SDK: SDK{Version: "1.0.0"}})
+	agg.Observe(Observation{AppID: "app", Outcome: OutcomeRejected, ReceivedAt: received.Add(time.Hour)})
+
+	counts := agg.Drain()
+	if len(counts) != 2 {
+		t.Fatalf("got %d keys, want 2", len(counts))
+	}
+
+	got := counts[Key{AppID: "app", Hour: HourOf(received)}]
+	if got.Accepted != 4 || got.Deduplicated != 1 || got.Rejected != 1 || got.Late != 2 {
+		t.Errorf("counts = %+v, want 4 accepted, 1 deduplicated, 1 rejected, 2 late", got)
+	}
+	if got.Rejections["timestamp_required"] != 1 {
+		t.Errorf("rejections = %v, want timestamp_required: 1", got.Rejections)
+	}
+	if got.ClockSkew["behind_1h+"] != 2 || got.ClockSkew["ahead_5s-1m"] != 1 || len(got.ClockSkew) != 2 {
+		t.Errorf("clock skew = %v, want behind_1h+: 2, ahead_5s-1m: 1", got.ClockSkew)
+	}
+
+	if got.SDKs["causality-mobile/1.2.0/ios"] != 1 || got.SDKs["unknown/1.0.0/unknown"] != 1 || got.SDKs["unknown/unknown/unknown"] != 4 {
+		t.Errorf("sdks = %v, want one 1.2.0 on ios, one 1.0.0 and 4 unreported", got.SDKs)
+	}
+
+	next := counts[Key{AppID: "app", Hour: HourOf(received.Add(time.Hour))}]
+	if next.Rejections["unknown"] != 1 {
+		t.Errorf("rejections without a reason = %v, want unknown: 1", next.Rejections)
+	}
+
+	// Restored counters are merged with new observations.
+	agg.Observe(Observation{AppID: "app", Outcome: OutcomeDeduplicated, ReceivedAt: received})
+	agg.Restore(counts)
+	if again := agg.Drain()[Key{AppID: "app", Hour: HourOf(received)}]; again.Deduplicated != 2 || again.Accepted != 4 {
+		t.Errorf("restored counts = %+v, want 4 accepted, 2 deduplicated", again)
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	hours := []Hourly{
+		{Hour: day.Add(22 * time.Hour), Counts: Counts{Accepted: 6, Rejected: 2, Late: 3, Rejections: map[string]int64{"invalid_field": 2}}},
+		{Hour: day.Add(23 * time.Hour), Counts: Counts{Accepted: 2, Deduplicated: 2}},
+		{Hour: day.Add(25 * time.Hour), Counts: Counts{Accepted: 0, Rejected: 4, Rejections: map[string]int64{"invalid_field": 4}}},
+	}
+
+	r := NewReport("app", day, day.Add(48*time.Hour), GranularityDay, hours)
+	if r.Totals.Received() != 16 || r.Totals.Rejections["invalid_field"] != 6 {
+		t.Errorf("totals = %+v, want 16 received, 6 invalid_field", r.Totals)
+	}
+	if r.DuplicateRate != 0.125 || r.RejectionRate != 0.375 || r.LateRate != 0.375 {
+		t.Errorf("rates = %v, %v, %v, want 0.125, 0.375, 0.375", r.DuplicateRate, r.RejectionRate, r.LateRate)
+	}
+	if len(r.Series) != 2 || !r.Series[0].Hour.Equal(day) || r.Series[0].Accepted != 8 || r.Series[1].Rejected != 4 {
+		t.Errorf("series = %+v, want the two days", r.Series)
+	}
+
+	if hourly := NewReport("app", day, day.Add(48*time.Hour), GranularityHour, hours); len(hourly.Series) != 3 {
+		t.Errorf("hourly series has %d points, want 3", len(hourly.Series))
+	}
+}
+
+func TestNewSDKReport(t *testing.T) {
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	hours := []Hourly{
+		{Hour: day.Add(time.Hour), Counts: Counts{Accepted: 2, SDKs: map[string]int64{"causality-mobile/1.0.0/android": 2}}},
+		{Hour: day.Add(5 * time.Hour), Counts: Counts{Accepted: 8, SDKs: map[string]int64{"causality-mobile/1.0.0/android": 1, "causality-mobile/1.1.0/android": 6, "causality-go/0.3.0/go": 1}}},
+		// Counted before SDKs were recorded.
+		{Hour: day, Counts: Counts{Accepted: 5}},
+	}
+
+	r := NewSDKReport("app", day, day.Add(24*time.Hour), hours)
+	if r.Events != 10 || len(r.Versions) != 3 {
+		t.Fatalf("report = %+v, want 10 events from 3 versions", r)
+	}
+
+	top := r.Versions[0]
+	if top.Name != "causality-mobile" || top.Version != "1.1.0" || top.Platform != "android" || top.Share != 0.6 {
+		t.Errorf("top version = %+v, want causality-mobile 1.1.0 on android with 0.6 share", top)
+	}
+	old := r.Versions[1]
+	if old.Version != "1.0.0" || old.Events != 3 || !old.FirstSeen.Equal(day.Add(time.Hour)) || !old.LastSeen.Equal(day.Add(5*time.Hour)) {
+		t.Errorf("1.0.0 = %+v, want 3 events seen from 01:00 to 05:00", old)
+	}
+	if r.Versions[2].Name != "causality-go" {
+		t.Errorf("last version = %+v, want causality-go", r.Versions[2])
+	}
+}
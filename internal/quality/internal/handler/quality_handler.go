@@ -0,0 +1,170 @@
+// Package handler provides HTTP handlers for the data-quality API.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/quality/internal/domain"
+)
+
+// maxReportWindow bounds the window of a data-quality report.
+const maxReportWindow = 92 * 24 * time.Hour
+
+// defaultReportWindows are the report windows without from, ending with the
+// current hour, by granularity.
+var defaultReportWindows = map[string]time.Duration{
+	domain.GranularityHour: 24 * time.Hour,
+	domain.GranularityDay:  30 * 24 * time.Hour,
+}
+
+// QualityReader reads hourly data-quality counters.
+type QualityReader interface {
+	List(ctx context.Context, appID string, from, to time.Time) ([]domain.Hourly, error)
+}
+
+// QualityHandler handles HTTP requests for data-quality reports.
+type QualityHandler struct {
+	store  QualityReader
+	now    func() time.Time
+	logger *slog.Logger
+}
+
+// NewQualityHandler creates a new QualityHandler.
+func NewQualityHandler(store QualityReader, logger *slog.Logger) *QualityHandler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &QualityHandler{
+		store:  store,
+		now:    time.Now,
+		logger: logger.With("component", "quality-handler"),
+	}
+}
+
+// RegisterRoutes mounts the data-quality endpoints on the given ServeMux.
+//
+// Endpoints:
+//   - GET /api/admin/data-quality/{app_id}?from=RFC3339&to=RFC3339&granularity=hour|day
+//   - GET /api/admin/data-quality/{app_id}/sdks?from=RFC3339&to=RFC3339
+//
+// TODO(phase-3): Protect these endpoints with session auth + RBAC.
+func (h *QualityHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/admin/data-quality/{app_id}", h.handleReport)
+	mux.HandleFunc("GET /api/admin/data-quality/{app_id}/sdks", h.handleSDKReport)
+}
+
+// handleReport handles GET /api/admin/data-quality/{app_id} - returns an
+// app's acceptance, duplicate, rejection and late-event counters with their
+// rates, and its clock skew distribution, over a window of whole hours. The
+// window defaults to the last day by hour, or the last 30 days by day,
+// ending with the current hour.
+func (h *QualityHandler) handleReport(w http.ResponseWriter, r *http.Request) {
+	appID := r.PathValue("app_id")
+
+	granularity := r.URL.Query().Get("granularity")
+	if granularity == "" {
+		granularity = domain.GranularityHour
+	}
+	window, ok := defaultReportWindows[granularity]
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "granularity must be hour or day",
+		})
+		return
+	}
+
+	from, to, ok := h.reportWindow(w, r, window)
+	if !ok {
+		return
+	}
+	hours, ok := h.list(w, r, appID, from, to)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, domain.NewReport(appID, from, to, granularity, hours))
+}
+
+// handleSDKReport handles GET /api/admin/data-quality/{app_id}/sdks -
+// returns the distribution of an app's events by SDK name, version and
+// platform over a window of whole hours, by default the last 30 days
+// ending with the current hour.
+func (h *QualityHandler) handleSDKReport(w http.ResponseWriter, r *http.Request) {
+	appID := r.PathValue("app_id")
+
+	from, to, ok := h.reportWindow(w, r, defaultReportWindows[domain.GranularityDay])
+	if !ok {
+		return
+	}
+	hours, ok := h.list(w, r, appID, from, to)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, domain.NewSDKReport(appID, from, to, hours))
+}
+
+// reportWindow returns the hours [from, to) of a report from the from and
+// to query parameters, defaulting to window ending with the current hour.
+// It writes a 400 response and returns false for an invalid window.
+func (h *QualityHandler) reportWindow(w http.ResponseWriter, r *http.Request, window time.Duration) (from, to time.Time, ok bool) {
+	q := r.URL.Query()
+
+	to = domain.HourOf(h.now()).Add(time.Hour)
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "to must be an RFC 3339 timestamp",
+			})
+			return from, to, false
+		}
+		to = domain.HourOf(t.Add(time.Hour - time.Nanosecond))
+	}
+	from = to.Add(-window)
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "from must be an RFC 3339 timestamp",
+			})
+			return from, to, false
+		}
+		from = domain.HourOf(t)
+	}
+	if !to.After(from) || to.Sub(from) > maxReportWindow {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "from must be before to, at most 92 days apart",
+		})
+		return from, to, false
+	}
+	return from, to, true
+}
+
+// list returns an app's hourly counters in [from, to). It writes a 500
+// response and returns false if they cannot be read.
+func (h *QualityHandler) list(w http.ResponseWriter, r *http.Request, appID string, from, to time.Time) ([]domain.Hourly, bool) {
+	hours, err := h.store.List(r.Context(), appID, from, to)
+	if err != nil {
+		h.logger.Error("failed to list data quality",
+			"app_id", appID,
+			"error", err,
+		)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to read data quality",
+		})
+		return nil, false
+	}
+	return hours, true
+}
+
+// writeJSON writes a JSON response with the given status code and body.
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,155 @@
+// Package repo provides the PostgreSQL implementation of the data-quality
+// Store port.
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/quality/internal/domain"
+)
+
+// QualityRepository implements the Store interface using PostgreSQL.
+type QualityRepository struct {
+	db *sql.DB
+}
+
+// NewQualityRepository creates a new QualityRepository backed by the given
+// database.
+func NewQualityRepository(db *sql.DB) *QualityRepository {
+	return &QualityRepository{db: db}
+}
+
+// AddCounts atomically increments the hourly counters and distribution
+// buckets for every key in counts.
+func (r *QualityRepository) AddCounts(ctx context.Context, counts map[domain.Key]*domain.Counts) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	hourly, err := tx.PrepareContext(ctx, `
+		INSERT INTO data_quality_hourly (app_id, hour, accepted, deduplicated, rejected, late)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (app_id, hour) DO UPDATE
+		SET accepted     = data_quality_hourly.accepted + EXCLUDED.accepted,
+		    deduplicated = data_quality_hourly.deduplicated + EXCLUDED.deduplicated,
+		    rejected     = data_quality_hourly.rejected + EXCLUDED.rejected,
+		    late         = data_quality_hourly.late + EXCLUDED.late,
+		    updated_at   = now()
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare data-quality upsert: %w", err)
+	}
+	defer func() { _ = hourly.Close() }()
+
+	buckets, err := tx.PrepareContext(ctx, `
+		INSERT INTO data_quality_buckets (app_id, hour, dimension, bucket, count)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (app_id, hour, dimension, bucket) DO UPDATE
+		SET count = data_quality_buckets.count + EXCLUDED.count
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare data-quality bucket upsert: %w", err)
+	}
+	defer func() { _ = buckets.Close() }()
+
+	for key, c := range counts {
+		if _, err := hourly.ExecContext(ctx, key.AppID, key.Hour, c.Accepted, c.Deduplicated, c.Rejected, c.Late); err != nil {
+			return fmt.Errorf("failed to upsert data quality for app %s: %w", key.AppID, err)
+		}
+		for dimension, dist := range map[string]map[string]int64{
+			domain.DimensionRejection: c.Rejections,
+			domain.DimensionClockSkew: c.ClockSkew,
+			domain.DimensionSDK:       c.SDKs,
+		} {
+			for bucket, n := range dist {
+				if _, err := buckets.ExecContext(ctx, key.AppID, key.Hour, dimension, bucket, n); err != nil {
+					return fmt.Errorf("failed to upsert %s buckets for app %s: %w", dimension, key.AppID, err)
+				}
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit data-quality counts: %w", err)
+	}
+
+	return nil
+}
+
+// List returns an app's hourly counters for the hours in [from, to),
+// ordered by hour.
+func (r *QualityRepository) List(ctx context.Context, appID string, from, to time.Time) ([]domain.Hourly, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT hour, accepted, deduplicated, rejected, late
+		FROM data_quality_hourly
+		WHERE app_id = $1 AND hour >= $2 AND hour < $3
+		ORDER BY hour
+	`, appID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query data quality: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var hours []domain.Hourly
+	index := make(map[time.Time]int)
+	for rows.Next() {
+		var h domain.Hourly
+		if err := rows.Scan(&h.Hour, &h.Accepted, &h.Deduplicated, &h.Rejected, &h.Late); err != nil {
+			return nil, fmt.Errorf("failed to scan data quality: %w", err)
+		}
+		h.Hour = h.Hour.UTC()
+		index[h.Hour] = len(hours)
+		hours = append(hours, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate data quality: %w", err)
+	}
+
+	buckets, err := r.db.QueryContext(ctx, `
+		SELECT hour, dimension, bucket, count
+		FROM data_quality_buckets
+		WHERE app_id = $1 AND hour >= $2 AND hour < $3
+	`, appID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query data-quality buckets: %w", err)
+	}
+	defer func() { _ = buckets.Close() }()
+
+	for buckets.Next() {
+		var (
+			hour              time.Time
+			dimension, bucket string
+			count             int64
+		)
+		if err := buckets.Scan(&hour, &dimension, &bucket, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan data-quality bucket: %w", err)
+		}
+		if i, ok := index[hour.UTC()]; ok {
+			hours[i].AddBucket(dimension, bucket, count)
+		}
+	}
+
+	return hours, buckets.Err()
+}
+
+// Prune deletes the counters of hours before cutoff and returns the number
+// of hourly rows deleted.
+func (r *QualityRepository) Prune(ctx context.Context, cutoff time.Time) (int64, error) {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM data_quality_buckets WHERE hour < $1`, cutoff); err != nil {
+		return 0, fmt.Errorf("failed to prune data-quality buckets: %w", err)
+	}
+	result, err := r.db.ExecContext(ctx, `DELETE FROM data_quality_hourly WHERE hour < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune data quality: %w", err)
+	}
+	return result.RowsAffected()
+}
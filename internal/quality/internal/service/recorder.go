@@ -0,0 +1,137 @@
+// Package service implements the data-quality recorder, which aggregates
+// the gateway's per-event ingestion outcomes in memory and periodically adds
+// them to the stored hourly counters.
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/quality/internal/domain"
+)
+
+// QualityStore defines the persistence interface needed by Recorder.
+// This mirrors the quality.Store port to avoid import cycles.
+type QualityStore interface {
+	AddCounts(ctx context.Context, counts map[domain.Key]*domain.Counts) error
+	Prune(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// Recorder counts observations in an in-memory aggregator, so the request
+// path never waits on PostgreSQL, and flushes them from a background
+// goroutine. Counters of a failed flush are kept for the next one.
+type Recorder struct {
+	store     QualityStore
+	agg       *domain.Aggregator
+	interval  time.Duration
+	retention time.Duration
+	logger    *slog.Logger
+
+	// lastPrune is when hours past the retention were last deleted
+	lastPrune time.Time
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewRecorder creates a recorder flushing every interval, counting accepted
+// events sent more than lateThreshold after their timestamp as late, and
+// keeping counters for retention.
+func NewRecorder(
+	store QualityStore,
+	interval time.Duration,
+	lateThreshold time.Duration,
+	retention time.Duration,
+	logger *slog.Logger,
+) *Recorder {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	return &Recorder{
+		store:     store,
+		agg:       domain.NewAggregator(lateThreshold),
+		interval:  interval,
+		retention: retention,
+		logger:    logger.With("component", "quality-recorder"),
+		stopCh:    make(chan struct{}),
+		doneCh:    make(chan struct{}),
+	}
+}
+
+// Observe counts the ingestion of one event. It never blocks on storage.
+func (r *Recorder) Observe(obs domain.Observation) {
+	r.agg.Observe(obs)
+}
+
+// Start begins the periodic flush loop in a background goroutine. The loop
+// stops when ctx is cancelled or Stop is called, flushing the counters
+// accumulated since the last flush first.
+func (r *Recorder) Start(ctx context.Context) {
+	go func() {
+		defer close(r.doneCh)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				r.flush(context.Background())
+				return
+			case <-r.stopCh:
+				r.flush(context.Background())
+				return
+			case <-ticker.C:
+				r.flush(ctx)
+				r.prune(ctx)
+			}
+		}
+	}()
+}
+
+// Stop signals the flush loop to stop and waits for the final flush.
+func (r *Recorder) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopCh)
+	})
+	<-r.doneCh
+}
+
+// flush adds the accumulated counters to the store. On failure they are
+// restored, so they are retried by the next flush.
+func (r *Recorder) flush(ctx context.Context) {
+	counts := r.agg.Drain()
+	if len(counts) == 0 {
+		return
+	}
+	if err := r.store.AddCounts(ctx, counts); err != nil {
+		r.agg.Restore(counts)
+		r.logger.Error("failed to flush data-quality counters",
+			"keys", len(counts),
+			"error", err,
+		)
+	}
+}
+
+// prune deletes hours past the retention, at most once an hour.
+func (r *Recorder) prune(ctx context.Context) {
+	if r.retention <= 0 || time.Since(r.lastPrune) < time.Hour {
+		return
+	}
+	r.lastPrune = time.Now()
+
+	deleted, err := r.store.Prune(ctx, domain.HourOf(time.Now().Add(-r.retention)))
+	if err != nil {
+		r.logger.Warn("failed to prune data-quality counters", "error", err)
+		return
+	}
+	if deleted > 0 {
+		r.logger.Info("pruned data-quality counters", "hours", deleted)
+	}
+}
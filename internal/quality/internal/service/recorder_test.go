@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/quality/internal/domain"
+)
+
+// mockStore is an in-memory QualityStore failing AddCounts while fail is set.
+type mockStore struct {
+	fail   bool
+	counts map[domain.Key]domain.Counts
+}
+
+func (m *mockStore) AddCounts(_ context.Context, counts map[domain.Key]*domain.Counts) error {
+	if m.fail {
+		return errors.New("database unavailable")
+	}
+	for key, c := range counts {
+		stored := m.counts[key]
+		stored.Add(c)
+		m.counts[key] = stored
+	}
+	return nil
+}
+
+func (m *mockStore) Prune(context.Context, time.Time) (int64, error) { return 0, nil }
+
+func TestRecorder_FlushRetriesFailures(t *testing.T) {
+	store := &mockStore{fail: true, counts: make(map[domain.Key]domain.Counts)}
+	r := NewRecorder(store, time.Hour, time.Hour, 0, nil)
+
+	now := time.Now()
+	r.Observe(domain.Observation{AppID: "app", Outcome: domain.OutcomeAccepted, ReceivedAt: now})
+	r.flush(context.Background())
+	if len(store.counts) != 0 {
+		t.Fatalf("stored %v while the store fails", store.counts)
+	}
+
+	// The failed counters are kept and stored with later ones.
+	store.fail = false
+	r.Observe(domain.Observation{AppID: "app", Outcome: domain.OutcomeAccepted, ReceivedAt: now})
+	r.flush(context.Background())
+
+	got := store.counts[domain.Key{AppID: "app", Hour: domain.HourOf(now)}]
+	if got.Accepted != 2 {
+		t.Errorf("stored accepted = %d, want 2", got.Accepted)
+	}
+}
+
+func TestRecorder_StopFlushes(t *testing.T) {
+	store := &mockStore{counts: make(map[domain.Key]domain.Counts)}
+	r := NewRecorder(store, time.Hour, time.Hour, 0, nil)
+	r.Start(context.Background())
+
+	r.Observe(domain.Observation{AppID: "app", Outcome: domain.OutcomeRejected, Reason: "invalid_field", ReceivedAt: time.Now()})
+	r.Stop()
+
+	if len(store.counts) != 1 {
+		t.Errorf("stored %d keys after Stop, want 1", len(store.counts))
+	}
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS data_quality_buckets;
+DROP TABLE IF EXISTS data_quality_hourly;
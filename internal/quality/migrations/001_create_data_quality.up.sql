@@ -0,0 +1,26 @@
+-- Per-app hourly ingestion data-quality counters (gateway)
+CREATE TABLE IF NOT EXISTS data_quality_hourly (
+    app_id       TEXT NOT NULL,
+    hour         TIMESTAMPTZ NOT NULL,
+    accepted     BIGINT NOT NULL DEFAULT 0,
+    deduplicated BIGINT NOT NULL DEFAULT 0,
+    rejected     BIGINT NOT NULL DEFAULT 0,
+    late         BIGINT NOT NULL DEFAULT 0,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (app_id, hour)
+);
+
+-- Retention pruning by hour across all apps
+CREATE INDEX IF NOT EXISTS idx_data_quality_hourly_hour ON data_quality_hourly(hour);
+
+-- Per-app hourly distributions: rejections by code, events by clock skew
+CREATE TABLE IF NOT EXISTS data_quality_buckets (
+    app_id    TEXT NOT NULL,
+    hour      TIMESTAMPTZ NOT NULL,
+    dimension TEXT NOT NULL,
+    bucket    TEXT NOT NULL,
+    count     BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (app_id, hour, dimension, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_quality_buckets_hour ON data_quality_buckets(hour);
@@ -0,0 +1,76 @@
+package quality
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/db"
+	"github.com/SebastienMelki/causality/internal/quality/internal/handler"
+	"github.com/SebastienMelki/causality/internal/quality/internal/repo"
+	"github.com/SebastienMelki/causality/internal/quality/internal/service"
+	"github.com/SebastienMelki/causality/internal/quality/migrations"
+)
+
+// Migrations are the quality module's database migrations.
+var Migrations = db.Source{Name: "quality", FS: migrations.FS}
+
+// Config holds the data-quality module configuration.
+//
+// Environment variable overrides:
+//   - QUALITY_ENABLED:        record data-quality metrics (default: true)
+//   - QUALITY_FLUSH_INTERVAL: how often counters are added to PostgreSQL (default: 10s)
+//   - QUALITY_LATE_THRESHOLD: delay after which an accepted event counts as late (default: 1h)
+//   - QUALITY_RETENTION:      how long hourly counters are kept (default: 2208h)
+type Config struct {
+	Enabled       bool          `env:"QUALITY_ENABLED"        envDefault:"true"`
+	FlushInterval time.Duration `env:"QUALITY_FLUSH_INTERVAL" envDefault:"10s"`
+	LateThreshold time.Duration `env:"QUALITY_LATE_THRESHOLD" envDefault:"1h"`
+	Retention     time.Duration `env:"QUALITY_RETENTION"      envDefault:"2208h"`
+}
+
+// Module is the data-quality module facade. It exposes the recorder the
+// HTTP gateway reports event outcomes to, and the report endpoints.
+type Module struct {
+	recorder *service.Recorder
+	handler  *handler.QualityHandler
+}
+
+// New creates a new data-quality Module storing its counters in db.
+func New(db *sql.DB, cfg Config, logger *slog.Logger) *Module {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger = logger.With("module", "quality")
+
+	qualityRepo := repo.NewQualityRepository(db)
+
+	return &Module{
+		recorder: service.NewRecorder(qualityRepo, cfg.FlushInterval, cfg.LateThreshold, cfg.Retention, logger),
+		handler:  handler.NewQualityHandler(qualityRepo, logger),
+	}
+}
+
+// Start begins the background flush loop.
+func (m *Module) Start(ctx context.Context) {
+	m.recorder.Start(ctx)
+}
+
+// Stop flushes the remaining counters and stops the flush loop.
+func (m *Module) Stop() {
+	m.recorder.Stop()
+}
+
+// Observe counts the ingestion of one event. It never blocks.
+func (m *Module) Observe(obs Observation) {
+	m.recorder.Observe(obs)
+}
+
+// RegisterRoutes mounts the data-quality endpoints onto the given ServeMux:
+//   - GET /api/admin/data-quality/{app_id}      - An app's data-quality report
+//   - GET /api/admin/data-quality/{app_id}/sdks - An app's SDK version distribution
+func (m *Module) RegisterRoutes(mux *http.ServeMux) {
+	m.handler.RegisterRoutes(mux)
+}
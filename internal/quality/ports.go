@@ -0,0 +1,59 @@
+// Package quality provides per-app ingestion data-quality metrics. The
+// gateway reports the outcome of every event (accepted, deduplicated, or
+// rejected with its rejection code), whether it arrived late, and the clock
+// skew of the sending device and the SDK version that sent it; they are
+// aggregated into hourly time series in PostgreSQL and served by admin
+// endpoints for diagnosing noisy integrations and deciding when SDK versions
+// can be deprecated.
+package quality
+
+import (
+	"context"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/quality/internal/domain"
+)
+
+// Observation is the ingestion of one event.
+type Observation = domain.Observation
+
+// Outcome is what the gateway did with an event.
+type Outcome = domain.Outcome
+
+// Outcome values of observations.
+const (
+	OutcomeAccepted     = domain.OutcomeAccepted
+	OutcomeDeduplicated = domain.OutcomeDeduplicated
+	OutcomeRejected     = domain.OutcomeRejected
+)
+
+// SDK identifies the client library that sent an event.
+type SDK = domain.SDK
+
+// Key identifies the counters of one app and UTC hour.
+type Key = domain.Key
+
+// Counts holds the data-quality counters of an app over a window.
+type Counts = domain.Counts
+
+// Hourly is the stored counters of one app and hour.
+type Hourly = domain.Hourly
+
+// Report is the data-quality report of an app over a window.
+type Report = domain.Report
+
+// SDKReport is the SDK version distribution of an app's events over a
+// window.
+type SDKReport = domain.SDKReport
+
+// Store defines the port for data-quality counter persistence.
+type Store interface {
+	// AddCounts atomically increments the hourly counters of every key.
+	AddCounts(ctx context.Context, counts map[Key]*Counts) error
+
+	// List returns an app's hourly counters for the hours in [from, to).
+	List(ctx context.Context, appID string, from, to time.Time) ([]Hourly, error)
+
+	// Prune deletes the counters of hours before cutoff.
+	Prune(ctx context.Context, cutoff time.Time) (int64, error)
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/nats-io/nats.go/jetstream"
 
 	"github.com/SebastienMelki/causality/internal/events"
+	"github.com/SebastienMelki/causality/internal/nats"
 	"github.com/SebastienMelki/causality/internal/reaction/db"
 	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
 )
@@ -36,15 +37,36 @@ type CountConfig struct {
 	MaxCount      int `json:"max_count"`
 }
 
+// ForecastConfig holds configuration for forecast-based anomaly detection,
+// which compares each hour's event count with the baseline learned by the
+// anomaly forecast job.
+type ForecastConfig struct {
+	// MinCount is the smallest hourly count that may alert, so sparse event
+	// types with tiny baselines do not alert on a handful of events.
+	MinCount int `json:"min_count"`
+}
+
+// baselineKey identifies the learned baseline of one event type and hour.
+type baselineKey struct {
+	appID     string
+	category  string
+	eventType string
+	hour      time.Time
+}
+
 // AnomalyDetector detects anomalies in events.
 type AnomalyDetector struct {
 	anomalyConfigs *db.AnomalyConfigRepository
 	js             jetstream.JetStream
 	config         AnomalyConfig
+	currency       CurrencyConverter
+	maintenance    *MaintenanceSchedule
 	logger         *slog.Logger
 
-	mu            sync.RWMutex
-	cachedConfigs []*db.AnomalyConfig
+	mu              sync.RWMutex
+	cachedConfigs   []*db.AnomalyConfig
+	cachedBaselines map[baselineKey]*db.AnomalyBaseline
+	revenue         *revenueState
 	stopCh        chan struct{}
 	doneCh        chan struct{}
 }
@@ -65,11 +87,26 @@ func NewAnomalyDetector(
 		js:             js,
 		config:         config,
 		logger:         logger.With("component", "anomaly-detector"),
+		revenue:        newRevenueState(),
 		stopCh:         make(chan struct{}),
 		doneCh:         make(chan struct{}),
 	}
 }
 
+// SetCurrencyConverter sets the converter used to add amount_usd to
+// purchase_complete events, so thresholds can be set in US dollars. Must be
+// called before Start.
+func (a *AnomalyDetector) SetCurrencyConverter(converter CurrencyConverter) {
+	a.currency = converter
+}
+
+// SetMaintenance sets the maintenance windows that suppress anomaly alerts.
+// Suppressed anomalies are recorded but neither published nor escalated.
+// Must be called before Start.
+func (a *AnomalyDetector) SetMaintenance(schedule *MaintenanceSchedule) {
+	a.maintenance = schedule
+}
+
 // Start starts the anomaly detector's background tasks.
 func (a *AnomalyDetector) Start(ctx context.Context) error {
 	// Load initial configs
@@ -80,6 +117,7 @@ func (a *AnomalyDetector) Start(ctx context.Context) error {
 	// Start background tasks
 	go a.refreshLoop(ctx)
 	go a.cleanupLoop(ctx)
+	go a.revenueLoop(ctx)
 
 	a.logger.Info("anomaly detector started",
 		"config_count", len(a.cachedConfigs),
@@ -133,7 +171,8 @@ func (a *AnomalyDetector) cleanupLoop(ctx context.Context) {
 	}
 }
 
-// cleanup removes old state and event records.
+// cleanup removes old state records. Old anomaly events are removed by
+// Retention.
 func (a *AnomalyDetector) cleanup(ctx context.Context) {
 	cutoff := time.Now().Add(-a.config.StateRetentionDuration)
 
@@ -144,29 +183,64 @@ func (a *AnomalyDetector) cleanup(ctx context.Context) {
 		a.logger.Debug("cleaned up old state", "count", stateCount)
 	}
 
-	eventCount, err := a.anomalyConfigs.CleanupOldEvents(ctx, cutoff)
+	revenueCutoff := time.Now().Add(-a.config.RevenueRetentionDuration)
+	revenueCount, err := a.anomalyConfigs.CleanupOldRevenueState(ctx, revenueCutoff)
 	if err != nil {
-		a.logger.Error("failed to cleanup old events", "error", err)
-	} else if eventCount > 0 {
-		a.logger.Debug("cleaned up old events", "count", eventCount)
+		a.logger.Error("failed to cleanup old revenue state", "error", err)
+	} else if revenueCount > 0 {
+		a.logger.Debug("cleaned up old revenue state", "count", revenueCount)
 	}
 }
 
-// refreshConfigs loads anomaly configs from the database.
+// refreshConfigs loads anomaly configs from the database, along with the
+// learned baselines around the current hour when a forecast config exists.
 func (a *AnomalyDetector) refreshConfigs(ctx context.Context) error {
 	configs, err := a.anomalyConfigs.GetEnabled(ctx)
 	if err != nil {
 		return err
 	}
 
+	var baselines map[baselineKey]*db.AnomalyBaseline
+	for _, config := range configs {
+		if config.DetectionType == db.DetectionTypeForecast {
+			baselines, err = a.loadBaselines(ctx, time.Now())
+			if err != nil {
+				return fmt.Errorf("failed to load anomaly baselines: %w", err)
+			}
+			break
+		}
+	}
+
 	a.mu.Lock()
 	a.cachedConfigs = configs
+	a.cachedBaselines = baselines
 	a.mu.Unlock()
 
-	a.logger.Debug("anomaly configs refreshed", "count", len(configs))
+	a.logger.Debug("anomaly configs refreshed", "count", len(configs), "baselines", len(baselines))
 	return nil
 }
 
+// loadBaselines loads the baselines for the hours around now, so the cache
+// stays valid across an hour boundary until the next refresh.
+func (a *AnomalyDetector) loadBaselines(ctx context.Context, now time.Time) (map[baselineKey]*db.AnomalyBaseline, error) {
+	hour := now.UTC().Truncate(time.Hour)
+	list, err := a.anomalyConfigs.GetBaselines(ctx, hour.Add(-time.Hour), hour.Add(2*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+
+	baselines := make(map[baselineKey]*db.AnomalyBaseline, len(list))
+	for _, b := range list {
+		baselines[baselineKey{
+			appID:     b.AppID,
+			category:  b.EventCategory,
+			eventType: b.EventType,
+			hour:      b.Hour.UTC(),
+		}] = b
+	}
+	return baselines, nil
+}
+
 // ProcessEvent checks an event against all matching anomaly configs.
 func (a *AnomalyDetector) ProcessEvent(ctx context.Context, event *pb.EventEnvelope) error {
 	category, eventType := events.GetCategoryAndType(event)
@@ -222,6 +296,10 @@ func (a *AnomalyDetector) evaluateConfig(ctx context.Context, config *db.Anomaly
 		return a.evaluateRate(ctx, config, event)
 	case db.DetectionTypeCount:
 		return a.evaluateCount(ctx, config, event)
+	case db.DetectionTypeForecast:
+		return a.evaluateForecast(ctx, config, event)
+	case db.DetectionTypeRevenue:
+		return a.evaluateRevenue(ctx, config, event, eventJSON)
 	default:
 		return fmt.Errorf("%w: %s", ErrInvalidDetectionType, config.DetectionType)
 	}
@@ -266,7 +344,7 @@ func (a *AnomalyDetector) evaluateThreshold(ctx context.Context, config *db.Anom
 	}
 
 	if anomalyDetected {
-		if err := a.checkCooldownAndAlert(ctx, config, event, details, eventJSON); err != nil {
+		if err := a.checkCooldownAndAlert(ctx, config, eventOrigin(event), details, eventJSON); err != nil {
 			return err
 		}
 	}
@@ -296,7 +374,7 @@ func (a *AnomalyDetector) evaluateRate(ctx context.Context, config *db.AnomalyCo
 			"max_per_minute": rc.MaxPerMinute,
 			"window":         windowKey,
 		}
-		if err := a.checkCooldownAndAlert(ctx, config, event, details, nil); err != nil {
+		if err := a.checkCooldownAndAlert(ctx, config, eventOrigin(event), details, nil); err != nil {
 			return err
 		}
 	}
@@ -329,7 +407,7 @@ func (a *AnomalyDetector) evaluateCount(ctx context.Context, config *db.AnomalyC
 			"window_seconds": cc.WindowSeconds,
 			"window_start":   windowKey,
 		}
-		if err := a.checkCooldownAndAlert(ctx, config, event, details, nil); err != nil {
+		if err := a.checkCooldownAndAlert(ctx, config, eventOrigin(event), details, nil); err != nil {
 			return err
 		}
 	}
@@ -337,9 +415,72 @@ func (a *AnomalyDetector) evaluateCount(ctx context.Context, config *db.AnomalyC
 	return nil
 }
 
-// checkCooldownAndAlert checks cooldown period and alerts if not in cooldown.
-func (a *AnomalyDetector) checkCooldownAndAlert(ctx context.Context, config *db.AnomalyConfig, event *pb.EventEnvelope, details map[string]interface{}, eventJSON map[string]interface{}) error {
+// evaluateForecast checks if the event type's count in the current hour
+// exceeds the upper bound of its learned baseline. Event types without a
+// baseline (too little history) are skipped.
+func (a *AnomalyDetector) evaluateForecast(ctx context.Context, config *db.AnomalyConfig, event *pb.EventEnvelope) error {
+	var fc ForecastConfig
+	if err := json.Unmarshal(config.Config, &fc); err != nil {
+		return fmt.Errorf("invalid forecast config: %w", err)
+	}
+
 	appID := event.AppId
+	category, eventType := events.GetCategoryAndType(event)
+	hour := time.Now().UTC().Truncate(time.Hour)
+
+	a.mu.RLock()
+	baseline := a.cachedBaselines[baselineKey{appID: appID, category: category, eventType: eventType, hour: hour}]
+	a.mu.RUnlock()
+	if baseline == nil {
+		return nil
+	}
+
+	// Hourly window per event type, since one config may cover many types
+	windowKey := fmt.Sprintf("%s|%s.%s", hour.Format("2006-01-02T15"), category, eventType)
+	count, err := a.anomalyConfigs.IncrementStateCount(ctx, config.ID, appID, windowKey)
+	if err != nil {
+		return fmt.Errorf("failed to increment state count: %w", err)
+	}
+
+	if count >= fc.MinCount && float64(count) > baseline.Upper {
+		details := map[string]interface{}{
+			"count":       count,
+			"expected":    baseline.Expected,
+			"lower_bound": baseline.Lower,
+			"upper_bound": baseline.Upper,
+			"hour":        hour.Format(time.RFC3339),
+		}
+		if err := a.checkCooldownAndAlert(ctx, config, eventOrigin(event), details, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// anomalyOrigin is what an anomaly was detected on. Event is nil for
+// anomalies detected when a window closes, such as a revenue drop.
+type anomalyOrigin struct {
+	appID     string
+	category  string
+	eventType string
+	event     *pb.EventEnvelope
+}
+
+// eventOrigin returns the origin of an anomaly detected on an event.
+func eventOrigin(event *pb.EventEnvelope) anomalyOrigin {
+	category, eventType := events.GetCategoryAndType(event)
+	return anomalyOrigin{
+		appID:     event.AppId,
+		category:  category,
+		eventType: eventType,
+		event:     event,
+	}
+}
+
+// checkCooldownAndAlert checks cooldown period and alerts if not in cooldown.
+func (a *AnomalyDetector) checkCooldownAndAlert(ctx context.Context, config *db.AnomalyConfig, origin anomalyOrigin, details map[string]interface{}, eventJSON map[string]interface{}) error {
+	appID := origin.appID
 	windowKey := time.Now().UTC().Format("2006-01-02T15:04")
 
 	// Check cooldown
@@ -364,7 +505,6 @@ func (a *AnomalyDetector) checkCooldownAndAlert(ctx context.Context, config *db.
 	}
 
 	// Record anomaly event
-	category, eventType := events.GetCategoryAndType(event)
 	detailsJSON, _ := json.Marshal(details)
 	var eventDataJSON []byte
 	if eventJSON != nil {
@@ -374,19 +514,39 @@ func (a *AnomalyDetector) checkCooldownAndAlert(ctx context.Context, config *db.
 	anomalyEvent := &db.AnomalyEvent{
 		AnomalyConfigID: config.ID,
 		AppID:           &appID,
-		EventCategory:   &category,
-		EventType:       &eventType,
+		EventCategory:   optionalString(origin.category),
+		EventType:       optionalString(origin.eventType),
 		DetectionType:   string(config.DetectionType),
 		Details:         detailsJSON,
 		EventData:       eventDataJSON,
 	}
+	window := a.maintenance.AnomalyWindow(appID)
+	if window != nil {
+		anomalyEvent.Suppressed = true
+		anomalyEvent.MaintenanceWindowID = &window.ID
+	} else if config.EscalationPolicyID != nil {
+		// The escalator notifies the policy's steps as they fall due.
+		now := time.Now()
+		anomalyEvent.EscalationPolicyID = config.EscalationPolicyID
+		anomalyEvent.NextEscalationAt = &now
+	}
 
 	if err := a.anomalyConfigs.RecordAnomalyEvent(ctx, anomalyEvent); err != nil {
 		a.logger.Error("failed to record anomaly event", "error", err)
 	}
 
+	if window != nil {
+		a.logger.Info("anomaly suppressed by maintenance window",
+			"config_id", config.ID,
+			"config_name", config.Name,
+			"app_id", appID,
+			"maintenance_window", window.Name,
+		)
+		return nil
+	}
+
 	// Publish to NATS
-	a.publishAnomaly(ctx, config, event, details)
+	a.publishAnomaly(ctx, config, origin, details)
 
 	a.logger.Warn("anomaly detected",
 		"config_id", config.ID,
@@ -400,23 +560,25 @@ func (a *AnomalyDetector) checkCooldownAndAlert(ctx context.Context, config *db.
 }
 
 // publishAnomaly publishes an anomaly alert to NATS.
-func (a *AnomalyDetector) publishAnomaly(ctx context.Context, config *db.AnomalyConfig, event *pb.EventEnvelope, details map[string]interface{}) {
-	category, eventType := events.GetCategoryAndType(event)
-	appID := event.AppId
+func (a *AnomalyDetector) publishAnomaly(ctx context.Context, config *db.AnomalyConfig, origin anomalyOrigin, details map[string]interface{}) {
+	appID := origin.appID
 
 	payload := map[string]interface{}{
 		"anomaly_config_id":   config.ID,
 		"anomaly_config_name": config.Name,
 		"detection_type":      config.DetectionType,
+		"severity":            config.Severity,
 		"app_id":              appID,
-		"event_category":      category,
-		"event_type":          eventType,
-		"event_id":            event.Id,
-		"device_id":           event.DeviceId,
-		"timestamp_ms":        event.TimestampMs,
+		"event_category":      origin.category,
+		"event_type":          origin.eventType,
 		"details":             details,
 		"detected_at":         time.Now().UTC().Format(time.RFC3339),
 	}
+	if event := origin.event; event != nil {
+		payload["event_id"] = event.Id
+		payload["device_id"] = event.DeviceId
+		payload["timestamp_ms"] = event.TimestampMs
+	}
 
 	payloadJSON, err := json.Marshal(payload)
 	if err != nil {
@@ -425,8 +587,7 @@ func (a *AnomalyDetector) publishAnomaly(ctx context.Context, config *db.Anomaly
 	}
 
 	// Publish to anomalies.{app_id}.{config_name}
-	configName := events.SanitizeSubjectName(config.Name)
-	subject := fmt.Sprintf("anomalies.%s.%s", events.SanitizeSubjectName(appID), configName)
+	subject := nats.DerivedSubject(nats.FamilyAnomalies, appID, config.Name)
 
 	if _, err := a.js.Publish(ctx, subject, payloadJSON); err != nil {
 		a.logger.Error("failed to publish anomaly",
@@ -501,9 +662,19 @@ func (a *AnomalyDetector) eventToJSON(event *pb.EventEnvelope) (map[string]inter
 	// Add other types as needed for anomaly detection
 	}
 
+	addAmountUSD(result, event, a.currency)
+
 	return result, nil
 }
 
+// optionalString returns a pointer to s, or nil if s is empty.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
 // toFloat64Value converts a value to float64.
 func toFloat64Value(v interface{}) (float64, bool) {
 	switch n := v.(type) {
@@ -0,0 +1,377 @@
+package reaction
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// anomalyConfigAdminStore is the subset of db.AnomalyConfigRepository used by
+// AnomalyConfigHandler.
+type anomalyConfigAdminStore interface {
+	List(ctx context.Context, limit, offset int) ([]*db.AnomalyConfig, error)
+	ListByAppID(ctx context.Context, appID string, limit, offset int) ([]*db.AnomalyConfig, error)
+	GetByID(ctx context.Context, id string) (*db.AnomalyConfig, error)
+	Create(ctx context.Context, config *db.AnomalyConfig) error
+	Update(ctx context.Context, config *db.AnomalyConfig) error
+	Delete(ctx context.Context, id string) error
+}
+
+// AnomalyConfigHandler serves the admin API for anomaly configs and their
+// previews against recent events.
+type AnomalyConfigHandler struct {
+	configs  anomalyConfigAdminStore
+	events   recentEventSource
+	detector *AnomalyDetector
+	config   AnomalyConfig
+	logger   *slog.Logger
+	now      func() time.Time
+}
+
+// NewAnomalyConfigHandler creates an AnomalyConfigHandler. Previews read
+// recent events from the search sink at cfg.PreviewSearchURL and are
+// unavailable if it is empty. If detector is non-nil, previews convert
+// events like it does (e.g. adding amount_usd to purchases); changes are
+// picked up on its next config refresh.
+func NewAnomalyConfigHandler(configs *db.AnomalyConfigRepository, detector *AnomalyDetector, cfg AnomalyConfig, logger *slog.Logger) *AnomalyConfigHandler {
+	var events recentEventSource
+	if cfg.PreviewSearchURL != "" {
+		events = NewSearchEventSource(cfg.PreviewSearchURL, 30*time.Second)
+	}
+	return newAnomalyConfigHandler(configs, events, detector, cfg, logger)
+}
+
+func newAnomalyConfigHandler(configs anomalyConfigAdminStore, events recentEventSource, detector *AnomalyDetector, cfg AnomalyConfig, logger *slog.Logger) *AnomalyConfigHandler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if detector == nil {
+		detector = &AnomalyDetector{}
+	}
+	return &AnomalyConfigHandler{
+		configs:  configs,
+		events:   events,
+		detector: detector,
+		config:   cfg,
+		logger:   logger.With("component", "anomaly-config-handler"),
+		now:      time.Now,
+	}
+}
+
+// RegisterRoutes mounts anomaly config admin endpoints on the given ServeMux.
+//
+// TODO(phase-3): Protect these endpoints with session auth + RBAC.
+//
+// Endpoints:
+//   - GET    /api/admin/anomaly-configs              - List configs (?app_id=&limit=&offset=)
+//   - POST   /api/admin/anomaly-configs              - Create a config
+//   - POST   /api/admin/anomaly-configs/preview      - Preview an unsaved config
+//   - GET    /api/admin/anomaly-configs/{id}         - Get a config
+//   - PUT    /api/admin/anomaly-configs/{id}         - Replace a config
+//   - DELETE /api/admin/anomaly-configs/{id}         - Delete a config
+//   - POST   /api/admin/anomaly-configs/{id}/preview - Preview a saved config
+func (h *AnomalyConfigHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/admin/anomaly-configs", h.handleList)
+	mux.HandleFunc("POST /api/admin/anomaly-configs", h.handleCreate)
+	mux.HandleFunc("POST /api/admin/anomaly-configs/preview", h.handlePreview)
+	mux.HandleFunc("GET /api/admin/anomaly-configs/{id}", h.handleGet)
+	mux.HandleFunc("PUT /api/admin/anomaly-configs/{id}", h.handleUpdate)
+	mux.HandleFunc("DELETE /api/admin/anomaly-configs/{id}", h.handleDelete)
+	mux.HandleFunc("POST /api/admin/anomaly-configs/{id}/preview", h.handlePreviewSaved)
+}
+
+// handleList handles GET /api/admin/anomaly-configs.
+func (h *AnomalyConfigHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryInt(r, "limit", defaultVersionPageSize)
+	if err != nil || limit < 1 || limit > maxVersionPageSize {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "limit must be between 1 and 500",
+		})
+		return
+	}
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "offset must be a non-negative integer",
+		})
+		return
+	}
+
+	var configs []*db.AnomalyConfig
+	if appID := r.URL.Query().Get("app_id"); appID != "" {
+		configs, err = h.configs.ListByAppID(r.Context(), appID, limit, offset)
+	} else {
+		configs, err = h.configs.List(r.Context(), limit, offset)
+	}
+	if err != nil {
+		h.logger.Error("failed to list anomaly configs", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to list anomaly configs",
+		})
+		return
+	}
+	if configs == nil {
+		configs = []*db.AnomalyConfig{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"anomaly_configs": configs,
+		"count":           len(configs),
+	})
+}
+
+// handleCreate handles POST /api/admin/anomaly-configs.
+func (h *AnomalyConfigHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	config, ok := h.decodeConfig(w, r, nil)
+	if !ok {
+		return
+	}
+
+	if err := h.configs.Create(r.Context(), config); err != nil {
+		h.logger.Error("failed to create anomaly config", "name", config.Name, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to create anomaly config",
+		})
+		return
+	}
+
+	h.logger.Info("anomaly config created",
+		"config_id", config.ID,
+		"name", config.Name,
+		"detection_type", config.DetectionType,
+	)
+	writeJSON(w, http.StatusCreated, config)
+}
+
+// handleGet handles GET /api/admin/anomaly-configs/{id}.
+func (h *AnomalyConfigHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	config, ok := h.getConfig(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, config)
+}
+
+// handleUpdate handles PUT /api/admin/anomaly-configs/{id}. The body
+// replaces the config; unset fields take the schema defaults.
+func (h *AnomalyConfigHandler) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.getConfig(w, r)
+	if !ok {
+		return
+	}
+	config, ok := h.decodeConfig(w, r, existing)
+	if !ok {
+		return
+	}
+
+	if err := h.configs.Update(r.Context(), config); err != nil {
+		if errors.Is(err, db.ErrAnomalyConfigNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+		h.logger.Error("failed to update anomaly config", "config_id", config.ID, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to update anomaly config",
+		})
+		return
+	}
+
+	h.logger.Info("anomaly config updated", "config_id", config.ID, "name", config.Name)
+
+	// Return the stored config, with its new updated_at
+	if updated, err := h.configs.GetByID(r.Context(), config.ID); err == nil {
+		config = updated
+	}
+	writeJSON(w, http.StatusOK, config)
+}
+
+// handleDelete handles DELETE /api/admin/anomaly-configs/{id}.
+func (h *AnomalyConfigHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if err := h.configs.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, db.ErrAnomalyConfigNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+		h.logger.Error("failed to delete anomaly config", "config_id", id, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to delete anomaly config",
+		})
+		return
+	}
+
+	h.logger.Info("anomaly config deleted", "config_id", id)
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status": "deleted",
+		"id":     id,
+	})
+}
+
+// handlePreview handles POST /api/admin/anomaly-configs/preview. The body is
+// a config as accepted by create; its name is optional.
+func (h *AnomalyConfigHandler) handlePreview(w http.ResponseWriter, r *http.Request) {
+	var spec AnomalyConfigSpec
+	if !decodeStrict(w, r, &spec) {
+		return
+	}
+	if spec.Name == "" {
+		spec.Name = "preview"
+	}
+	config := spec.normalize().anomalyConfig(nil)
+	if err := validateAnomalyConfig(config); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	h.preview(w, r, config)
+}
+
+// handlePreviewSaved handles POST /api/admin/anomaly-configs/{id}/preview.
+func (h *AnomalyConfigHandler) handlePreviewSaved(w http.ResponseWriter, r *http.Request) {
+	config, ok := h.getConfig(w, r)
+	if !ok {
+		return
+	}
+	h.preview(w, r, config)
+}
+
+// preview replays the last PreviewWindow of events of one app against
+// config. Configs scoped to an app preview that app; others need the app_id
+// query parameter, since the hot store is searched per app.
+func (h *AnomalyConfigHandler) preview(w http.ResponseWriter, r *http.Request, config *db.AnomalyConfig) {
+	if h.events == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{
+			"error": "previews need ANOMALY_PREVIEW_SEARCH_URL",
+		})
+		return
+	}
+
+	appID := r.URL.Query().Get("app_id")
+	switch {
+	case config.AppID != nil && appID != "" && appID != *config.AppID:
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "app_id does not match the config's app",
+		})
+		return
+	case config.AppID != nil:
+		appID = *config.AppID
+	case appID == "":
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "app_id query parameter is required for configs covering all apps",
+		})
+		return
+	}
+
+	// Check the detection type before reading events
+	if _, err := previewEvaluator(h.detector, config); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	to := h.now().UTC()
+	from := to.Add(-h.config.PreviewWindow)
+	var category, eventType string
+	if config.EventCategory != nil {
+		category = *config.EventCategory
+	}
+	if config.EventType != nil {
+		eventType = *config.EventType
+	}
+
+	recent, truncated, err := h.events.RecentEvents(r.Context(), RecentEventQuery{
+		AppID:         appID,
+		EventCategory: category,
+		EventType:     eventType,
+		Since:         from,
+		Until:         to,
+		MaxEvents:     h.config.PreviewMaxEvents,
+	})
+	if err != nil {
+		h.logger.Error("failed to read recent events for preview", "app_id", appID, "error", err)
+		writeJSON(w, http.StatusBadGateway, map[string]string{
+			"error": "failed to read recent events",
+		})
+		return
+	}
+
+	preview, err := previewAnomalyConfig(h.detector, config, appID, recent)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	preview.From, preview.To, preview.Truncated = from, to, truncated
+	if truncated && len(recent) > 0 {
+		preview.From = time.UnixMilli(recent[0].GetTimestampMs()).UTC()
+	}
+
+	writeJSON(w, http.StatusOK, preview)
+}
+
+// getConfig loads the config named by the id path value, writing the error
+// response if it cannot.
+func (h *AnomalyConfigHandler) getConfig(w http.ResponseWriter, r *http.Request) (*db.AnomalyConfig, bool) {
+	id := r.PathValue("id")
+	config, err := h.configs.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrAnomalyConfigNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{
+				"error": err.Error(),
+			})
+			return nil, false
+		}
+		h.logger.Error("failed to get anomaly config", "config_id", id, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to get anomaly config",
+		})
+		return nil, false
+	}
+	return config, true
+}
+
+// decodeConfig decodes and validates a config request body, keeping the
+// identity of existing if non-nil. It writes the error response if the body
+// is invalid.
+func (h *AnomalyConfigHandler) decodeConfig(w http.ResponseWriter, r *http.Request, existing *db.AnomalyConfig) (*db.AnomalyConfig, bool) {
+	var spec AnomalyConfigSpec
+	if !decodeStrict(w, r, &spec) {
+		return nil, false
+	}
+
+	config := spec.normalize().anomalyConfig(existing)
+	if err := validateAnomalyConfig(config); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return nil, false
+	}
+	return config, true
+}
+
+// decodeStrict decodes a JSON request body into v, rejecting unknown fields.
+// It writes the error response if the body is invalid.
+func decodeStrict(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "invalid request body: " + err.Error(),
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,351 @@
+package reaction
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+type fakeAnomalyConfigStore struct {
+	configs map[string]*db.AnomalyConfig
+	nextID  int
+}
+
+func newFakeAnomalyConfigStore() *fakeAnomalyConfigStore {
+	return &fakeAnomalyConfigStore{configs: make(map[string]*db.AnomalyConfig)}
+}
+
+func (f *fakeAnomalyConfigStore) List(_ context.Context, limit, offset int) ([]*db.AnomalyConfig, error) {
+	var out []*db.AnomalyConfig
+	for _, c := range f.configs {
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (f *fakeAnomalyConfigStore) ListByAppID(_ context.Context, appID string, limit, offset int) ([]*db.AnomalyConfig, error) {
+	var out []*db.AnomalyConfig
+	for _, c := range f.configs {
+		if c.AppID != nil && *c.AppID == appID {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeAnomalyConfigStore) GetByID(_ context.Context, id string) (*db.AnomalyConfig, error) {
+	c, ok := f.configs[id]
+	if !ok {
+		return nil, db.ErrAnomalyConfigNotFound
+	}
+	copied := *c
+	return &copied, nil
+}
+
+func (f *fakeAnomalyConfigStore) Create(_ context.Context, config *db.AnomalyConfig) error {
+	f.nextID++
+	config.ID = fmt.Sprintf("a%d", f.nextID)
+	copied := *config
+	f.configs[config.ID] = &copied
+	return nil
+}
+
+func (f *fakeAnomalyConfigStore) Update(_ context.Context, config *db.AnomalyConfig) error {
+	if _, ok := f.configs[config.ID]; !ok {
+		return db.ErrAnomalyConfigNotFound
+	}
+	copied := *config
+	f.configs[config.ID] = &copied
+	return nil
+}
+
+func (f *fakeAnomalyConfigStore) Delete(_ context.Context, id string) error {
+	if _, ok := f.configs[id]; !ok {
+		return db.ErrAnomalyConfigNotFound
+	}
+	delete(f.configs, id)
+	return nil
+}
+
+// fakeEventSource returns fixed events and records the last query.
+type fakeEventSource struct {
+	events []*pb.EventEnvelope
+	query  RecentEventQuery
+	err    error
+}
+
+func (f *fakeEventSource) RecentEvents(_ context.Context, q RecentEventQuery) ([]*pb.EventEnvelope, bool, error) {
+	f.query = q
+	return f.events, false, f.err
+}
+
+func newTestAnomalyMux(store *fakeAnomalyConfigStore, events recentEventSource) *http.ServeMux {
+	mux := http.NewServeMux()
+	h := newAnomalyConfigHandler(store, events, nil, AnomalyConfig{PreviewWindow: time.Hour, PreviewMaxEvents: 100}, nil)
+	h.RegisterRoutes(mux)
+	return mux
+}
+
+func serve(mux http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return rec
+}
+
+func TestValidateAnomalyConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		detectionType db.DetectionType
+		config        string
+		wantErr       bool
+	}{
+		{"threshold", db.DetectionTypeThreshold, `{"path":"$.purchase_complete.total_cents","max":100}`, false},
+		{"threshold without bounds", db.DetectionTypeThreshold, `{"path":"$.a"}`, true},
+		{"threshold without path", db.DetectionTypeThreshold, `{"min":1}`, true},
+		{"threshold min above max", db.DetectionTypeThreshold, `{"path":"a","min":5,"max":1}`, true},
+		{"threshold empty path segment", db.DetectionTypeThreshold, `{"path":"a..b","min":1}`, true},
+		{"rate", db.DetectionTypeRate, `{"max_per_minute":10}`, false},
+		{"rate misspelled field", db.DetectionTypeRate, `{"max_rate":10}`, true},
+		{"rate wrong type", db.DetectionTypeRate, `{"max_per_minute":"10"}`, true},
+		{"count", db.DetectionTypeCount, `{"window_seconds":60,"max_count":100}`, false},
+		{"count without window", db.DetectionTypeCount, `{"max_count":100}`, true},
+		{"forecast defaults", db.DetectionTypeForecast, ``, false},
+		{"revenue", db.DetectionTypeRevenue, `{"min_ratio":0.5}`, false},
+		{"revenue unknown field", db.DetectionTypeRevenue, `{"min_ratio":0.5,"ratio":2}`, true},
+		{"unknown type", "magic", `{}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAnomalyConfig(&db.AnomalyConfig{
+				Name:          "a",
+				DetectionType: tt.detectionType,
+				Config:        json.RawMessage(tt.config),
+				Severity:      db.SeverityWarning,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateAnomalyConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidAnomalyConfig) {
+				t.Errorf("error %v does not wrap ErrInvalidAnomalyConfig", err)
+			}
+		})
+	}
+}
+
+func TestAnomalyConfigHandler_CRUD(t *testing.T) {
+	store := newFakeAnomalyConfigStore()
+	mux := newTestAnomalyMux(store, nil)
+
+	rec := serve(mux, http.MethodPost, "/api/admin/anomaly-configs",
+		`{"name":"error-spike","app_id":"app","detection_type":"rate","config":{"max_per_minute":5}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status: got %d, want 201 (body %s)", rec.Code, rec.Body)
+	}
+	var created db.AnomalyConfig
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if created.ID == "" || created.CooldownSeconds != defaultCooldownSeconds || !created.Enabled {
+		t.Errorf("created = %+v, want an ID and the schema defaults", created)
+	}
+
+	rec = serve(mux, http.MethodPost, "/api/admin/anomaly-configs",
+		`{"name":"typo","detection_type":"rate","config":{"max_rate":5}}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "max_rate") {
+		t.Errorf("invalid create: got %d %s, want 400 naming the unknown field", rec.Code, rec.Body)
+	}
+
+	path := "/api/admin/anomaly-configs/" + created.ID
+	rec = serve(mux, http.MethodPut, path,
+		`{"name":"error-spike","app_id":"app","detection_type":"count","config":{"window_seconds":60,"max_count":50},"enabled":false}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update status: got %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	if c := store.configs[created.ID]; c.DetectionType != db.DetectionTypeCount || c.Enabled {
+		t.Errorf("stored config = %+v, want a disabled count config", c)
+	}
+
+	rec = serve(mux, http.MethodGet, "/api/admin/anomaly-configs?app_id=app", "")
+	var list struct {
+		Count int `json:"count"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if list.Count != 1 {
+		t.Errorf("list count: got %d, want 1", list.Count)
+	}
+
+	if rec := serve(mux, http.MethodDelete, path, ""); rec.Code != http.StatusOK {
+		t.Errorf("delete status: got %d, want 200", rec.Code)
+	}
+	if rec := serve(mux, http.MethodGet, path, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("get after delete status: got %d, want 404", rec.Code)
+	}
+	if rec := serve(mux, http.MethodPut, path, `{"name":"x","detection_type":"rate","config":{"max_per_minute":1}}`); rec.Code != http.StatusNotFound {
+		t.Errorf("update after delete status: got %d, want 404", rec.Code)
+	}
+}
+
+func TestAnomalyConfigHandler_Preview(t *testing.T) {
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var events []*pb.EventEnvelope
+	// Five purchases in minute 0 and three in minute 1, one second apart
+	for i, second := range []int{0, 1, 2, 3, 4, 60, 61, 62} {
+		events = append(events, &pb.EventEnvelope{
+			Id:          fmt.Sprintf("e%d", i),
+			AppId:       "app",
+			TimestampMs: base.Add(time.Duration(second) * time.Second).UnixMilli(),
+			Payload: &pb.EventEnvelope_PurchaseComplete{PurchaseComplete: &pb.PurchaseComplete{
+				TotalCents: int64(1000 * (i + 1)),
+			}},
+		})
+	}
+	source := &fakeEventSource{events: events}
+	mux := newTestAnomalyMux(newFakeAnomalyConfigStore(), source)
+
+	tests := []struct {
+		name       string
+		body       string
+		violations int
+		alerts     int
+		suppressed int
+	}{
+		// Events 3, 4 and 5 of minute 0 exceed 2 per minute; minute 1
+		// reaches 3 with its third event. A 30s cooldown suppresses the
+		// second and third violation in minute 0.
+		{"rate", `{"detection_type":"rate","config":{"max_per_minute":2},"cooldown_seconds":30}`, 4, 2, 2},
+		// Purchases above 55.00 are e5, e6 and e7.
+		{"threshold", `{"detection_type":"threshold","config":{"path":"$.purchase_complete.total_cents","max":5500},"cooldown_seconds":0}`, 3, 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(mux, http.MethodPost, "/api/admin/anomaly-configs/preview?app_id=app", tt.body)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status: got %d, want 200 (body %s)", rec.Code, rec.Body)
+			}
+			var preview AnomalyPreview
+			if err := json.NewDecoder(rec.Body).Decode(&preview); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if preview.EventsEvaluated != 8 || preview.EventsMatched != 8 {
+				t.Errorf("evaluated %d, matched %d, want 8 and 8", preview.EventsEvaluated, preview.EventsMatched)
+			}
+			if preview.Violations != tt.violations || preview.Alerts != tt.alerts || preview.SuppressedByCooldown != tt.suppressed {
+				t.Errorf("violations %d, alerts %d, suppressed %d, want %d, %d, %d",
+					preview.Violations, preview.Alerts, preview.SuppressedByCooldown, tt.violations, tt.alerts, tt.suppressed)
+			}
+			if len(preview.Samples) != tt.alerts {
+				t.Errorf("samples: got %d, want %d", len(preview.Samples), tt.alerts)
+			}
+		})
+	}
+
+	if got := source.query; got.AppID != "app" || got.Until.Sub(got.Since) != time.Hour || got.MaxEvents != 100 {
+		t.Errorf("query = %+v, want the app's last hour", got)
+	}
+}
+
+func TestAnomalyConfigHandler_PreviewErrors(t *testing.T) {
+	store := newFakeAnomalyConfigStore()
+	appID := "app"
+	store.configs["a1"] = &db.AnomalyConfig{ID: "a1", Name: "n", AppID: &appID, DetectionType: db.DetectionTypeRate, Config: json.RawMessage(`{"max_per_minute":1}`)}
+	mux := newTestAnomalyMux(store, &fakeEventSource{})
+	rate := `{"detection_type":"rate","config":{"max_per_minute":1}}`
+
+	tests := []struct {
+		name   string
+		mux    *http.ServeMux
+		target string
+		body   string
+		status int
+	}{
+		{"all apps without app_id", mux, "/api/admin/anomaly-configs/preview", rate, http.StatusBadRequest},
+		{"forecast", mux, "/api/admin/anomaly-configs/preview?app_id=app", `{"detection_type":"forecast"}`, http.StatusBadRequest},
+		{"invalid config", mux, "/api/admin/anomaly-configs/preview?app_id=app", `{"detection_type":"rate"}`, http.StatusBadRequest},
+		{"saved config", mux, "/api/admin/anomaly-configs/a1/preview", "", http.StatusOK},
+		{"saved config of another app", mux, "/api/admin/anomaly-configs/a1/preview?app_id=other", "", http.StatusBadRequest},
+		{"unknown config", mux, "/api/admin/anomaly-configs/a2/preview", "", http.StatusNotFound},
+		{"no hot store", newTestAnomalyMux(store, nil), "/api/admin/anomaly-configs/a1/preview", "", http.StatusServiceUnavailable},
+		{"hot store error", newTestAnomalyMux(store, &fakeEventSource{err: errors.New("down")}), "/api/admin/anomaly-configs/a1/preview", "", http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serve(tt.mux, http.MethodPost, tt.target, tt.body); rec.Code != tt.status {
+				t.Errorf("status: got %d, want %d (body %s)", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+}
+
+// TestSearchEventSource_Pages verifies scans page past the search API limit
+// and return events oldest first.
+func TestSearchEventSource_Pages(t *testing.T) {
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	const total = searchPageSize + 500
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/admin/search/app/events" {
+			http.NotFound(w, r)
+			return
+		}
+		until, err := time.Parse(time.RFC3339, r.URL.Query().Get("until"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Event i has timestamp base+i ms; most recent first
+		var page []map[string]any
+		for i := total - 1; i >= 0 && len(page) < searchPageSize; i-- {
+			ts := base.Add(time.Duration(i) * time.Millisecond)
+			if !ts.Before(until) {
+				continue
+			}
+			page = append(page, map[string]any{
+				"event_id":  fmt.Sprintf("e%d", i),
+				"timestamp": ts,
+				"envelope":  json.RawMessage(fmt.Sprintf(`{"id":"e%d","app_id":"app","timestamp_ms":"%d"}`, i, ts.UnixMilli())),
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"events": page, "count": len(page)})
+	}))
+	defer server.Close()
+
+	source := NewSearchEventSource(server.URL+"/", time.Second)
+	events, truncated, err := source.RecentEvents(context.Background(), RecentEventQuery{
+		AppID: "app",
+		Since: base,
+		Until: base.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("RecentEvents() error = %v", err)
+	}
+	if len(events) != total || truncated {
+		t.Fatalf("got %d events (truncated %v), want %d", len(events), truncated, total)
+	}
+	if events[0].GetId() != "e0" || events[total-1].GetId() != fmt.Sprintf("e%d", total-1) {
+		t.Errorf("events run from %s to %s, want oldest first", events[0].GetId(), events[total-1].GetId())
+	}
+
+	events, truncated, err = source.RecentEvents(context.Background(), RecentEventQuery{
+		AppID:     "app",
+		Since:     base,
+		Until:     base.Add(time.Hour),
+		MaxEvents: 10,
+	})
+	if err != nil {
+		t.Fatalf("RecentEvents() error = %v", err)
+	}
+	if len(events) != 10 || !truncated || events[9].GetId() != fmt.Sprintf("e%d", total-1) {
+		t.Errorf("got %d events (truncated %v), want the 10 most recent", len(events), truncated)
+	}
+}
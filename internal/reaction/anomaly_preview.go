@@ -0,0 +1,305 @@
+package reaction
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/SebastienMelki/causality/internal/events"
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// maxPreviewSamples is the number of would-be alerts a preview returns.
+const maxPreviewSamples = 20
+
+// searchPageSize is the page size of hot store scans, the search API's
+// maximum.
+const searchPageSize = 1000
+
+// RecentEventQuery selects an app's recent events.
+type RecentEventQuery struct {
+	AppID         string
+	EventCategory string
+	EventType     string
+	Since         time.Time
+	Until         time.Time
+
+	// MaxEvents bounds the events returned; the most recent are kept.
+	MaxEvents int
+}
+
+// recentEventSource returns an app's recent events, oldest first, and whether
+// older events matching the query were left out.
+type recentEventSource interface {
+	RecentEvents(ctx context.Context, q RecentEventQuery) ([]*pb.EventEnvelope, bool, error)
+}
+
+// SearchEventSource reads recent events from the search sink's hot store
+// through its search API.
+type SearchEventSource struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewSearchEventSource creates a SearchEventSource for the search sink at
+// baseURL (e.g. http://search-sink:8086).
+func NewSearchEventSource(baseURL string, timeout time.Duration) *SearchEventSource {
+	return &SearchEventSource{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// searchResponse is the subset of the search API response used here.
+type searchResponse struct {
+	Events []struct {
+		EventID   string          `json:"event_id"`
+		Timestamp time.Time       `json:"timestamp"`
+		Envelope  json.RawMessage `json:"envelope"`
+	} `json:"events"`
+}
+
+// RecentEvents scans the app's events in the query's time range, most recent
+// first, one page at a time. Pages are bounded by the oldest timestamp seen
+// so far, so events sharing it are fetched again and skipped.
+func (s *SearchEventSource) RecentEvents(ctx context.Context, q RecentEventQuery) ([]*pb.EventEnvelope, bool, error) {
+	var (
+		found     []*pb.EventEnvelope
+		seen      = make(map[string]bool)
+		until     = q.Until
+		unmarshal = protojson.UnmarshalOptions{DiscardUnknown: true}
+	)
+
+	for {
+		page, err := s.search(ctx, q, until)
+		if err != nil {
+			return nil, false, err
+		}
+
+		added := 0
+		for _, e := range page.Events {
+			if seen[e.EventID] {
+				continue
+			}
+			seen[e.EventID] = true
+			added++
+
+			event := &pb.EventEnvelope{}
+			if err := unmarshal.Unmarshal(e.Envelope, event); err != nil {
+				return nil, false, fmt.Errorf("failed to decode event %s: %w", e.EventID, err)
+			}
+			found = append(found, event)
+			if q.MaxEvents > 0 && len(found) >= q.MaxEvents {
+				slices.Reverse(found)
+				return found, true, nil
+			}
+		}
+
+		if len(page.Events) < searchPageSize {
+			slices.Reverse(found)
+			return found, false, nil
+		}
+		if added == 0 {
+			// A full page of events sharing one timestamp; older events
+			// cannot be paged to.
+			slices.Reverse(found)
+			return found, true, nil
+		}
+		until = page.Events[len(page.Events)-1].Timestamp.Add(time.Millisecond)
+	}
+}
+
+// search fetches one page of events before until.
+func (s *SearchEventSource) search(ctx context.Context, q RecentEventQuery, until time.Time) (*searchResponse, error) {
+	params := url.Values{}
+	params.Set("since", q.Since.UTC().Format(time.RFC3339Nano))
+	params.Set("until", until.UTC().Format(time.RFC3339Nano))
+	params.Set("limit", strconv.Itoa(searchPageSize))
+	if q.EventCategory != "" {
+		params.Set("event_category", q.EventCategory)
+	}
+	if q.EventType != "" {
+		params.Set("event_type", q.EventType)
+	}
+	endpoint := s.baseURL + "/api/admin/search/" + url.PathEscape(q.AppID) + "/events?" + params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("search request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("search API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var page searchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to decode search response: %w", err)
+	}
+	return &page, nil
+}
+
+// AnomalyPreview reports how an anomaly config would have behaved on an
+// app's recent events.
+type AnomalyPreview struct {
+	AppID string    `json:"app_id"`
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+
+	// EventsEvaluated is the number of events replayed; Truncated reports
+	// that older events in the window were skipped.
+	EventsEvaluated int  `json:"events_evaluated"`
+	Truncated       bool `json:"truncated"`
+
+	// EventsMatched is the number of events passing the config's filters.
+	EventsMatched int `json:"events_matched"`
+
+	// Violations is the number of events that broke the config's limit;
+	// Alerts of them would have alerted and SuppressedByCooldown were
+	// within the cooldown of a previous alert.
+	Violations           int `json:"violations"`
+	Alerts               int `json:"alerts"`
+	SuppressedByCooldown int `json:"suppressed_by_cooldown"`
+
+	// Samples are the first alerts.
+	Samples []AnomalyPreviewAlert `json:"samples"`
+}
+
+// AnomalyPreviewAlert is an alert a preview would have raised.
+type AnomalyPreviewAlert struct {
+	At      time.Time              `json:"at"`
+	EventID string                 `json:"event_id"`
+	Details map[string]interface{} `json:"details"`
+}
+
+// previewAnomalyConfig replays events, oldest first, against a threshold,
+// rate or count config, as the detector would have evaluated them on
+// arrival. Windows and cooldowns use the events' client timestamps. detector
+// supplies the event JSON conversion of threshold paths.
+func previewAnomalyConfig(detector *AnomalyDetector, config *db.AnomalyConfig, appID string, replayed []*pb.EventEnvelope) (*AnomalyPreview, error) {
+	violates, err := previewEvaluator(detector, config)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &AnomalyPreview{AppID: appID, EventsEvaluated: len(replayed), Samples: []AnomalyPreviewAlert{}}
+	cooldown := time.Duration(config.CooldownSeconds) * time.Second
+	var lastAlert time.Time
+
+	for _, event := range replayed {
+		category, eventType := events.GetCategoryAndType(event)
+		if !detector.matchesFilter(config, appID, category, eventType) {
+			continue
+		}
+		preview.EventsMatched++
+
+		at := time.UnixMilli(event.GetTimestampMs()).UTC()
+		details := violates(event, at)
+		if details == nil {
+			continue
+		}
+		preview.Violations++
+
+		if !lastAlert.IsZero() && at.Sub(lastAlert) < cooldown {
+			preview.SuppressedByCooldown++
+			continue
+		}
+		lastAlert = at
+		preview.Alerts++
+		if len(preview.Samples) < maxPreviewSamples {
+			preview.Samples = append(preview.Samples, AnomalyPreviewAlert{At: at, EventID: event.GetId(), Details: details})
+		}
+	}
+
+	return preview, nil
+}
+
+// previewEvaluator returns a function reporting the details of an event's
+// violation of config, or nil if it does not violate it. Stateful detection
+// types count the events passed to it.
+func previewEvaluator(detector *AnomalyDetector, config *db.AnomalyConfig) (func(*pb.EventEnvelope, time.Time) map[string]interface{}, error) {
+	switch config.DetectionType {
+	case db.DetectionTypeThreshold:
+		tc, err := parseThresholdConfig(config.Config)
+		if err != nil {
+			return nil, err
+		}
+		return func(event *pb.EventEnvelope, _ time.Time) map[string]interface{} {
+			eventJSON, err := detector.eventToJSON(event)
+			if err != nil {
+				return nil
+			}
+			value, ok := detector.extractJSONPath(eventJSON, tc.Path)
+			if !ok {
+				return nil
+			}
+			numValue, ok := toFloat64Value(value)
+			if !ok {
+				return nil
+			}
+			switch {
+			case tc.Min != nil && numValue < *tc.Min:
+				return map[string]interface{}{"value": numValue, "threshold_min": *tc.Min, "violation": "below_min"}
+			case tc.Max != nil && numValue > *tc.Max:
+				return map[string]interface{}{"value": numValue, "threshold_max": *tc.Max, "violation": "above_max"}
+			}
+			return nil
+		}, nil
+
+	case db.DetectionTypeRate:
+		rc, err := parseRateConfig(config.Config)
+		if err != nil {
+			return nil, err
+		}
+		counts := make(map[string]int)
+		return func(_ *pb.EventEnvelope, at time.Time) map[string]interface{} {
+			windowKey := at.Format("2006-01-02T15:04")
+			counts[windowKey]++
+			if counts[windowKey] <= rc.MaxPerMinute {
+				return nil
+			}
+			return map[string]interface{}{"rate": counts[windowKey], "max_per_minute": rc.MaxPerMinute, "window": windowKey}
+		}, nil
+
+	case db.DetectionTypeCount:
+		cc, err := parseCountConfig(config.Config)
+		if err != nil {
+			return nil, err
+		}
+		counts := make(map[string]int)
+		return func(_ *pb.EventEnvelope, at time.Time) map[string]interface{} {
+			windowKey := at.Truncate(time.Duration(cc.WindowSeconds) * time.Second).Format(time.RFC3339)
+			counts[windowKey]++
+			if counts[windowKey] <= cc.MaxCount {
+				return nil
+			}
+			return map[string]interface{}{
+				"count":          counts[windowKey],
+				"max_count":      cc.MaxCount,
+				"window_seconds": cc.WindowSeconds,
+				"window_start":   windowKey,
+			}
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("%w: %s (supported: threshold, rate, count)", ErrPreviewUnsupported, config.DetectionType)
+	}
+}
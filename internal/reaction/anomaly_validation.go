@@ -0,0 +1,133 @@
+package reaction
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// validateAnomalyConfig checks an anomaly config's settings and decodes its
+// type-specific config into the typed struct of its detection type. Unknown
+// config fields are rejected, so a misspelled setting cannot silently fall
+// back to its zero value.
+func validateAnomalyConfig(config *db.AnomalyConfig) error {
+	if strings.TrimSpace(config.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidAnomalyConfig)
+	}
+	if config.CooldownSeconds < 0 {
+		return fmt.Errorf("%w: cooldown_seconds must not be negative", ErrInvalidAnomalyConfig)
+	}
+	if !config.Severity.Valid() {
+		return fmt.Errorf("%w: %w: %q", ErrInvalidAnomalyConfig, ErrInvalidSeverity, config.Severity)
+	}
+
+	var err error
+	switch config.DetectionType {
+	case db.DetectionTypeThreshold:
+		_, err = parseThresholdConfig(config.Config)
+	case db.DetectionTypeRate:
+		_, err = parseRateConfig(config.Config)
+	case db.DetectionTypeCount:
+		_, err = parseCountConfig(config.Config)
+	case db.DetectionTypeForecast:
+		_, err = parseForecastConfig(config.Config)
+	case db.DetectionTypeRevenue:
+		var rc RevenueConfig
+		if err = decodeAnomalyConfig(config.Config, &rc); err == nil {
+			_, err = parseRevenueConfig(config.Config)
+		}
+	default:
+		return fmt.Errorf("%w: %w: %q", ErrInvalidAnomalyConfig, ErrInvalidDetectionType, config.DetectionType)
+	}
+	if err != nil && !errors.Is(err, ErrInvalidAnomalyConfig) {
+		err = fmt.Errorf("%w: %w", ErrInvalidAnomalyConfig, err)
+	}
+	return err
+}
+
+// parseThresholdConfig decodes and validates a threshold config.
+func parseThresholdConfig(raw json.RawMessage) (ThresholdConfig, error) {
+	var tc ThresholdConfig
+	if err := decodeAnomalyConfig(raw, &tc); err != nil {
+		return tc, err
+	}
+
+	path := strings.TrimPrefix(tc.Path, "$.")
+	if path == "" {
+		return tc, fmt.Errorf("%w: threshold config needs a path", ErrInvalidAnomalyConfig)
+	}
+	for _, part := range strings.Split(path, ".") {
+		if part == "" {
+			return tc, fmt.Errorf("%w: threshold path %q has an empty segment", ErrInvalidAnomalyConfig, tc.Path)
+		}
+	}
+
+	switch {
+	case tc.Min == nil && tc.Max == nil:
+		return tc, fmt.Errorf("%w: threshold config needs min or max", ErrInvalidAnomalyConfig)
+	case tc.Min != nil && tc.Max != nil && *tc.Min > *tc.Max:
+		return tc, fmt.Errorf("%w: threshold min must not exceed max", ErrInvalidAnomalyConfig)
+	}
+	return tc, nil
+}
+
+// parseRateConfig decodes and validates a rate config.
+func parseRateConfig(raw json.RawMessage) (RateConfig, error) {
+	var rc RateConfig
+	if err := decodeAnomalyConfig(raw, &rc); err != nil {
+		return rc, err
+	}
+	if rc.MaxPerMinute <= 0 {
+		return rc, fmt.Errorf("%w: rate config needs a positive max_per_minute", ErrInvalidAnomalyConfig)
+	}
+	return rc, nil
+}
+
+// parseCountConfig decodes and validates a count config.
+func parseCountConfig(raw json.RawMessage) (CountConfig, error) {
+	var cc CountConfig
+	if err := decodeAnomalyConfig(raw, &cc); err != nil {
+		return cc, err
+	}
+	switch {
+	case cc.WindowSeconds <= 0:
+		return cc, fmt.Errorf("%w: count config needs a positive window_seconds", ErrInvalidAnomalyConfig)
+	case cc.MaxCount <= 0:
+		return cc, fmt.Errorf("%w: count config needs a positive max_count", ErrInvalidAnomalyConfig)
+	}
+	return cc, nil
+}
+
+// parseForecastConfig decodes and validates a forecast config.
+func parseForecastConfig(raw json.RawMessage) (ForecastConfig, error) {
+	var fc ForecastConfig
+	if err := decodeAnomalyConfig(raw, &fc); err != nil {
+		return fc, err
+	}
+	if fc.MinCount < 0 {
+		return fc, fmt.Errorf("%w: forecast min_count must not be negative", ErrInvalidAnomalyConfig)
+	}
+	return fc, nil
+}
+
+// decodeAnomalyConfig strictly decodes a type-specific config into v. An
+// empty config decodes as {}.
+func decodeAnomalyConfig(raw json.RawMessage, v any) error {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		raw = json.RawMessage("{}")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAnomalyConfig, err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return fmt.Errorf("%w: trailing data after config", ErrInvalidAnomalyConfig)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package reaction
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+// Archived tables, named as in the archive's keys.
+const (
+	archiveTableAnomalyEvents = "anomaly_events"
+	archiveTableDeliveries    = "webhook_deliveries"
+)
+
+// Archive writes rows removed by Retention to Parquet files in the event
+// bucket, under prefix:
+//
+//	{prefix}/anomaly_events/year={y}/month={m}/day={d}/anomaly_events_{uuid}.parquet
+//	{prefix}/webhook_deliveries/year={y}/month={m}/day={d}/webhook_deliveries_{uuid}.parquet
+//
+// Rows are partitioned by the UTC day they were created. Each batch writes
+// new files, so a batch archived again after its deletion failed leaves
+// duplicate rows, distinguishable by id.
+type Archive struct {
+	s3Client warehouse.ObjectAPI
+	s3Config warehouse.S3Config
+	prefix   string
+}
+
+// NewArchive creates an archive under prefix in the bucket described by
+// s3Config.
+func NewArchive(s3Client warehouse.ObjectAPI, s3Config warehouse.S3Config, prefix string) *Archive {
+	return &Archive{
+		s3Client: s3Client,
+		s3Config: s3Config,
+		prefix:   strings.Trim(prefix, "/"),
+	}
+}
+
+// anomalyEventArchiveRow is the archived form of an anomaly event. Times are
+// Unix milliseconds, 0 when unset.
+type anomalyEventArchiveRow struct {
+	ID                  string `parquet:"id,snappy"`
+	AnomalyConfigID     string `parquet:"anomaly_config_id,snappy,dict"`
+	AppID               string `parquet:"app_id,snappy,dict,optional"`
+	EventCategory       string `parquet:"event_category,snappy,dict,optional"`
+	EventType           string `parquet:"event_type,snappy,dict,optional"`
+	DetectionType       string `parquet:"detection_type,snappy,dict"`
+	DetailsJSON         string `parquet:"details_json,snappy"`
+	EventDataJSON       string `parquet:"event_data_json,snappy,optional"`
+	EscalationPolicyID  string `parquet:"escalation_policy_id,snappy,dict,optional"`
+	EscalationStep      int32  `parquet:"escalation_step"`
+	AcknowledgedAtMS    int64  `parquet:"acknowledged_at_ms,optional"`
+	AcknowledgedBy      string `parquet:"acknowledged_by,snappy,optional"`
+	Suppressed          bool   `parquet:"suppressed"`
+	MaintenanceWindowID string `parquet:"maintenance_window_id,snappy,optional"`
+	CreatedAtMS         int64  `parquet:"created_at_ms"`
+}
+
+// deliveryArchiveRow is the archived form of a webhook delivery. Payloads
+// are archived as stored, so encrypted payloads stay encrypted.
+type deliveryArchiveRow struct {
+	ID              string `parquet:"id,snappy"`
+	WebhookID       string `parquet:"webhook_id,snappy,dict"`
+	RuleID          string `parquet:"rule_id,snappy,dict,optional"`
+	RuleVersion     int32  `parquet:"rule_version,optional"`
+	AnomalyConfigID string `parquet:"anomaly_config_id,snappy,dict,optional"`
+	IdempotencyKey  string `parquet:"idempotency_key,snappy,optional"`
+	PayloadJSON     string `parquet:"payload_json,snappy"`
+	Status          string `parquet:"status,snappy,dict"`
+	Attempts        int32  `parquet:"attempts"`
+	MaxAttempts     int32  `parquet:"max_attempts"`
+	LastAttemptAtMS int64  `parquet:"last_attempt_at_ms,optional"`
+	LastError       string `parquet:"last_error,snappy,optional"`
+	LastStatusCode  int32  `parquet:"last_status_code,optional"`
+	CreatedAtMS     int64  `parquet:"created_at_ms"`
+	DeliveredAtMS   int64  `parquet:"delivered_at_ms,optional"`
+}
+
+// WriteAnomalyEvents archives anomaly events.
+func (a *Archive) WriteAnomalyEvents(ctx context.Context, events []*db.AnomalyEvent) error {
+	rows := make([]anomalyEventArchiveRow, len(events))
+	for i, event := range events {
+		rows[i] = anomalyEventArchiveRow{
+			ID:                  event.ID,
+			AnomalyConfigID:     event.AnomalyConfigID,
+			AppID:               stringValue(event.AppID),
+			EventCategory:       stringValue(event.EventCategory),
+			EventType:           stringValue(event.EventType),
+			DetectionType:       event.DetectionType,
+			DetailsJSON:         string(event.Details),
+			EventDataJSON:       string(event.EventData),
+			EscalationPolicyID:  stringValue(event.EscalationPolicyID),
+			EscalationStep:      int32(event.EscalationStep),
+			AcknowledgedAtMS:    unixMilli(event.AcknowledgedAt),
+			AcknowledgedBy:      stringValue(event.AcknowledgedBy),
+			Suppressed:          event.Suppressed,
+			MaintenanceWindowID: stringValue(event.MaintenanceWindowID),
+			CreatedAtMS:         event.CreatedAt.UnixMilli(),
+		}
+	}
+	return writeArchive(ctx, a, archiveTableAnomalyEvents, rows, func(row anomalyEventArchiveRow) int64 {
+		return row.CreatedAtMS
+	})
+}
+
+// WriteDeliveries archives webhook deliveries.
+func (a *Archive) WriteDeliveries(ctx context.Context, deliveries []*db.WebhookDelivery) error {
+	rows := make([]deliveryArchiveRow, len(deliveries))
+	for i, delivery := range deliveries {
+		row := deliveryArchiveRow{
+			ID:              delivery.ID,
+			WebhookID:       delivery.WebhookID,
+			RuleID:          stringValue(delivery.RuleID),
+			AnomalyConfigID: stringValue(delivery.AnomalyConfigID),
+			IdempotencyKey:  stringValue(delivery.IdempotencyKey),
+			PayloadJSON:     string(delivery.Payload),
+			Status:          string(delivery.Status),
+			Attempts:        int32(delivery.Attempts),
+			MaxAttempts:     int32(delivery.MaxAttempts),
+			LastAttemptAtMS: unixMilli(delivery.LastAttemptAt),
+			LastError:       stringValue(delivery.LastError),
+			CreatedAtMS:     delivery.CreatedAt.UnixMilli(),
+			DeliveredAtMS:   unixMilli(delivery.DeliveredAt),
+		}
+		if delivery.RuleVersion != nil {
+			row.RuleVersion = int32(*delivery.RuleVersion)
+		}
+		if delivery.LastStatusCode != nil {
+			row.LastStatusCode = int32(*delivery.LastStatusCode)
+		}
+		rows[i] = row
+	}
+	return writeArchive(ctx, a, archiveTableDeliveries, rows, func(row deliveryArchiveRow) int64 {
+		return row.CreatedAtMS
+	})
+}
+
+// writeArchive writes rows to one Parquet file per UTC day of createdAtMS.
+func writeArchive[T any](ctx context.Context, a *Archive, table string, rows []T, createdAtMS func(T) int64) error {
+	var days []time.Time
+	byDay := make(map[time.Time][]T)
+	for _, row := range rows {
+		day := time.UnixMilli(createdAtMS(row)).UTC().Truncate(24 * time.Hour)
+		if _, ok := byDay[day]; !ok {
+			days = append(days, day)
+		}
+		byDay[day] = append(byDay[day], row)
+	}
+
+	for _, day := range days {
+		var buf bytes.Buffer
+		writer := parquet.NewGenericWriter[T](&buf,
+			parquet.Compression(&parquet.Snappy),
+			parquet.CreatedBy("causality-reaction-archive", "1.0.0", ""),
+		)
+		if _, err := writer.Write(byDay[day]); err != nil {
+			return fmt.Errorf("write %s archive rows: %w", table, err)
+		}
+		if err := writer.Close(); err != nil {
+			return fmt.Errorf("close %s archive writer: %w", table, err)
+		}
+
+		if err := a.put(ctx, a.key(table, day), buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// key returns the object key of a new archive file of table for day.
+func (a *Archive) key(table string, day time.Time) string {
+	return fmt.Sprintf("%s/%s/year=%d/month=%02d/day=%02d/%s_%s.parquet",
+		a.prefix, table, day.Year(), int(day.Month()), day.Day(), table, uuid.NewString())
+}
+
+// put stores an archive file with the bucket's encryption settings.
+func (a *Archive) put(ctx context.Context, key string, data []byte) error {
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(a.s3Config.Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/x-parquet"),
+	}
+	a.s3Config.ApplyObjectOptions(input, "")
+
+	if _, err := a.s3Client.PutObject(ctx, input); err != nil {
+		return fmt.Errorf("put object %s: %w", key, err)
+	}
+	return nil
+}
+
+// stringValue returns *s, or "" when s is nil.
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// unixMilli returns t in Unix milliseconds, or 0 when t is nil.
+func unixMilli(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+	return t.UnixMilli()
+}
@@ -0,0 +1,420 @@
+package reaction
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/SebastienMelki/causality/internal/events"
+	"github.com/SebastienMelki/causality/internal/nats"
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// ChainHeader lists, comma separated, the IDs of the rules that produced a
+// message published by a rule action, oldest first. Rule chaining uses it to
+// detect cycles and bound chain depth.
+const ChainHeader = "Causality-Chain"
+
+// ChainConfig holds rule chaining settings. Chained rules match events
+// published to the derived stream by rules (reactions.>) and anomaly
+// detection (anomalies.>): their event_category is the subject family and
+// their event_type the subject name.
+type ChainConfig struct {
+	// Enabled consumes the derived stream and evaluates chained rules.
+	Enabled bool `env:"ENABLED" envDefault:"false"`
+
+	// ConsumerName is the durable consumer on the derived stream.
+	ConsumerName string `env:"CONSUMER_NAME" envDefault:"rule-chaining"`
+
+	// MaxDepth is the number of rules a chain may pass through. A derived
+	// event produced by MaxDepth chained rules is not evaluated.
+	MaxDepth int `env:"MAX_DEPTH" envDefault:"3"`
+
+	// FetchBatchSize is the number of derived events fetched at once.
+	FetchBatchSize int `env:"FETCH_BATCH_SIZE" envDefault:"100"`
+}
+
+// ConsumerConfig returns the derived stream consumer of chained rules.
+func (c ChainConfig) ConsumerConfig() nats.ConsumerConfig {
+	return nats.ConsumerConfig{
+		Name:           c.ConsumerName,
+		FilterSubjects: []string{nats.FamilyReactions + ".>", nats.FamilyAnomalies + ".>"},
+		AckWait:        30 * time.Second,
+		MaxAckPending:  1000,
+		MaxDeliver:     5,
+	}
+}
+
+// derivedMessages is the subset of jetstream.Stream read by conditions with
+// the "derived" source.
+type derivedMessages interface {
+	GetLastMsgForSubject(ctx context.Context, subject string) (*jetstream.RawStreamMsg, error)
+}
+
+// DerivedEvent is a message of the derived stream evaluated against chained
+// rules.
+type DerivedEvent struct {
+	// Subject is {family}.{app_id}.{name}.
+	Subject string
+
+	// Sequence is the message's stream sequence, identifying the event.
+	Sequence uint64
+
+	// Timestamp is when the message was stored.
+	Timestamp time.Time
+
+	// Payload is the JSON payload, which conditions are evaluated against.
+	Payload map[string]interface{}
+
+	// Chain lists the rules that produced the event (see ChainHeader).
+	Chain []string
+}
+
+// ParseChain returns the rule IDs listed in a message's ChainHeader.
+func ParseChain(h natsgo.Header) []string {
+	value := h.Get(ChainHeader)
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// ProcessDerived evaluates a derived event against the chained rules of its
+// subject's family and executes the actions of those that match. Rules the
+// event's chain already passed through are skipped, breaking cycles.
+func (e *Engine) ProcessDerived(ctx context.Context, derived *DerivedEvent) error {
+	info, ok := nats.ParseDerivedSubject(derived.Subject)
+	if !ok {
+		return fmt.Errorf("subject %q is not a derived subject", derived.Subject)
+	}
+
+	// Payloads carry the unsanitized app ID; the subject token is a
+	// fallback
+	appID, _ := derived.Payload["app_id"].(string)
+	if appID == "" {
+		appID = info.AppID
+	}
+	deviceID, _ := derived.Payload["device_id"].(string)
+	event := &pb.EventEnvelope{
+		Id:          fmt.Sprintf("%s:%d", info.Family, derived.Sequence),
+		AppId:       appID,
+		DeviceId:    deviceID,
+		TimestampMs: derived.Timestamp.UnixMilli(),
+	}
+
+	e.mu.RLock()
+	rules := e.cachedRules
+	e.mu.RUnlock()
+
+	var chained []*db.Rule
+	for _, rule := range rules {
+		if rule.EventCategory == nil || *rule.EventCategory != info.Family {
+			continue
+		}
+		if inChain(derived.Chain, rule.ID) {
+			e.logger.Warn("skipping rule already in chain",
+				"rule_id", rule.ID,
+				"rule_name", rule.Name,
+				"subject", derived.Subject,
+				"chain", derived.Chain,
+			)
+			continue
+		}
+		chained = append(chained, rule)
+	}
+
+	data := e.newConditionData(ctx, event, derived.Payload)
+	matchedRules := e.findMatchingRules(chained, appID, info.Family, info.Name, data)
+	if len(matchedRules) == 0 {
+		return nil
+	}
+
+	e.logger.Info("chained rules matched",
+		"subject", derived.Subject,
+		"app_id", appID,
+		"chain", derived.Chain,
+		"matched_rules", len(matchedRules),
+	)
+
+	chain := derived.Chain
+	if chain == nil {
+		chain = []string{}
+	}
+	var deliveries []*db.WebhookDelivery
+	e.fireRules(ctx, matchedRules, event, derived.Payload, chain, &deliveries)
+	e.insertDeliveries(ctx, deliveries)
+	return nil
+}
+
+// inChain reports whether ruleID is in chain.
+func inChain(chain []string, ruleID string) bool {
+	for _, id := range chain {
+		if id == ruleID {
+			return true
+		}
+	}
+	return false
+}
+
+// derivedToJSON returns the last message on the derived subject
+// {family}.{app_id}.{name} as a document with its age in seconds and its
+// payload, or nil if there is no derived stream or no message. Lookup errors
+// are logged and treated as a missing message.
+func (e *Engine) derivedToJSON(ctx context.Context, appID, family, name string) map[string]interface{} {
+	if e.derived == nil || !nats.IsDerivedFamily(family) {
+		return nil
+	}
+
+	subject := nats.DerivedSubject(family, appID, name)
+	msg, err := e.derived.GetLastMsgForSubject(ctx, subject)
+	if err != nil {
+		if !errors.Is(err, jetstream.ErrMsgNotFound) {
+			e.logger.Warn("failed to read derived subject for rule conditions",
+				"subject", subject,
+				"error", err,
+			)
+		}
+		return nil
+	}
+
+	doc := map[string]interface{}{
+		"age_seconds":  time.Since(msg.Time).Seconds(),
+		"published_at": msg.Time.UTC().Format(time.RFC3339),
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(msg.Data, &payload); err == nil {
+		doc["payload"] = payload
+	}
+	return doc
+}
+
+// ChainConsumer consumes the derived stream and evaluates each event against
+// chained rules with Engine.ProcessDerived. Events whose chain passed through
+// ChainConfig.MaxDepth rules are acked without being evaluated.
+type ChainConsumer struct {
+	js         jetstream.JetStream
+	engine     *Engine
+	streamName string
+	config     ChainConfig
+	logger     *slog.Logger
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewChainConsumer creates a consumer of the derived stream for chained
+// rules.
+func NewChainConsumer(js jetstream.JetStream, engine *Engine, streamName string, cfg ChainConfig, logger *slog.Logger) *ChainConsumer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.FetchBatchSize < 1 {
+		cfg.FetchBatchSize = 100
+	}
+
+	return &ChainConsumer{
+		js:         js,
+		engine:     engine,
+		streamName: streamName,
+		config:     cfg,
+		logger:     logger.With("component", "rule-chaining"),
+		stopCh:     make(chan struct{}),
+		doneCh:     make(chan struct{}),
+	}
+}
+
+// Start looks up the durable consumer and begins fetching.
+func (c *ChainConsumer) Start(ctx context.Context) error {
+	stream, err := c.js.Stream(ctx, c.streamName)
+	if err != nil {
+		return fmt.Errorf("failed to get stream: %w", err)
+	}
+
+	consumer, err := stream.Consumer(ctx, c.config.ConsumerName)
+	if err != nil {
+		return fmt.Errorf("failed to get consumer: %w", err)
+	}
+
+	c.logger.Info("starting rule chaining consumer",
+		"stream", c.streamName,
+		"consumer", c.config.ConsumerName,
+		"max_depth", c.config.MaxDepth,
+	)
+
+	go c.run(ctx, consumer)
+	return nil
+}
+
+// Stop stops fetching and waits for the current batch to finish. Start must
+// have succeeded.
+func (c *ChainConsumer) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stopCh)
+	})
+	<-c.doneCh
+}
+
+// run is the fetch loop.
+func (c *ChainConsumer) run(ctx context.Context, consumer jetstream.Consumer) {
+	defer close(c.doneCh)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.stopCh:
+			return
+		default:
+		}
+
+		msgs, err := consumer.Fetch(c.config.FetchBatchSize, jetstream.FetchMaxWait(5*time.Second))
+		if err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) {
+				c.logger.Error("failed to fetch messages", "error", err)
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+					return
+				case <-c.stopCh:
+					return
+				}
+			}
+			continue
+		}
+
+		for msg := range msgs.Messages() {
+			c.handleMessage(ctx, msg)
+		}
+		if err := msgs.Error(); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			c.logger.Warn("fetch completed with error", "error", err)
+		}
+	}
+}
+
+// handleMessage evaluates one derived event and acks it. Messages whose
+// payload is not a JSON object are terminated so they are not redelivered.
+func (c *ChainConsumer) handleMessage(ctx context.Context, msg jetstream.Msg) {
+	derived := &DerivedEvent{
+		Subject:   msg.Subject(),
+		Timestamp: time.Now(),
+		Chain:     ParseChain(msg.Headers()),
+	}
+	if meta, err := msg.Metadata(); err == nil {
+		derived.Sequence = meta.Sequence.Stream
+		derived.Timestamp = meta.Timestamp
+	}
+
+	if len(derived.Chain) >= c.config.MaxDepth {
+		c.logger.Debug("rule chain reached max depth",
+			"subject", derived.Subject,
+			"chain", derived.Chain,
+			"max_depth", c.config.MaxDepth,
+		)
+		_ = msg.Ack()
+		return
+	}
+
+	if err := json.Unmarshal(msg.Data(), &derived.Payload); err != nil || derived.Payload == nil {
+		c.logger.Warn("terminating unparseable derived event",
+			"subject", msg.Subject(),
+			"error", err,
+		)
+		_ = msg.Term()
+		return
+	}
+
+	if err := c.engine.ProcessDerived(ctx, derived); err != nil {
+		c.logger.Warn("terminating unprocessable derived event",
+			"subject", msg.Subject(),
+			"error", err,
+		)
+		_ = msg.Term()
+		return
+	}
+	if err := msg.Ack(); err != nil {
+		c.logger.Error("failed to ACK derived event", "error", err)
+	}
+}
+
+// ruleChainCycle returns the names of rules forming a cycle, first rule
+// repeated last, if rules can trigger each other in a loop through their
+// reactions.{app_id}.{name} publish subjects, or nil otherwise. Rules
+// limited to different apps cannot trigger each other.
+func ruleChainCycle(rules []RuleSpec) []string {
+	triggers := func(from, to RuleSpec) bool {
+		if to.EventCategory == nil || *to.EventCategory != nats.FamilyReactions {
+			return false
+		}
+		if from.AppID != nil && to.AppID != nil && *from.AppID != *to.AppID {
+			return false
+		}
+		for _, subject := range from.Actions.PublishSubjects {
+			info, ok := nats.ParseDerivedSubject(strings.ReplaceAll(subject, "{app_id}", "app"))
+			if !ok || info.Family != nats.FamilyReactions {
+				continue
+			}
+			if to.EventType == nil || events.SanitizeSubjectName(*to.EventType) == info.Name {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Depth-first search, with the rules on the current path in stack
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := make([]int, len(rules))
+	var stack []int
+	var visit func(i int) []string
+	visit = func(i int) []string {
+		state[i] = onPath
+		stack = append(stack, i)
+		for j := range rules {
+			if !triggers(rules[i], rules[j]) {
+				continue
+			}
+			switch state[j] {
+			case onPath:
+				var cycle []string
+				for k := len(stack) - 1; k >= 0; k-- {
+					if stack[k] == j {
+						for _, n := range stack[k:] {
+							cycle = append(cycle, rules[n].Name)
+						}
+						break
+					}
+				}
+				return append(cycle, rules[j].Name)
+			case unvisited:
+				if cycle := visit(j); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[i] = done
+		return nil
+	}
+
+	for i := range rules {
+		if state[i] == unvisited {
+			if cycle := visit(i); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package reaction
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// publishRecorder is a jetstream.JetStream recording published messages.
+type publishRecorder struct {
+	jetstream.JetStream
+	msgs []*natsgo.Msg
+}
+
+func (p *publishRecorder) PublishMsg(_ context.Context, msg *natsgo.Msg, _ ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	p.msgs = append(p.msgs, msg)
+	return &jetstream.PubAck{}, nil
+}
+
+// lastMessages is a derivedMessages holding the last message per subject.
+type lastMessages map[string]*jetstream.RawStreamMsg
+
+func (m lastMessages) GetLastMsgForSubject(_ context.Context, subject string) (*jetstream.RawStreamMsg, error) {
+	if msg, ok := m[subject]; ok {
+		return msg, nil
+	}
+	return nil, jetstream.ErrMsgNotFound
+}
+
+func TestEngine_ProcessDerived(t *testing.T) {
+	anomalies, reactions := "anomalies", "reactions"
+	errorSpike, escalate := "error_spike", "escalate"
+
+	js := &publishRecorder{}
+	e := NewEngine(nil, nil, nil, js, EngineConfig{}, DispatcherConfig{}, nil, nil)
+	e.derived = lastMessages{
+		"reactions.shop.checkout_failed": {Time: time.Now().Add(-5 * time.Minute), Data: []byte(`{"rule_name": "checkout_failed"}`)},
+	}
+	e.cachedRules = []*db.Rule{
+		{
+			// Escalates error spikes while checkouts failed recently.
+			ID:            "escalate",
+			EventCategory: &anomalies,
+			EventType:     &errorSpike,
+			Conditions: []db.Condition{
+				{Path: "$.severity", Operator: "eq", Value: "critical"},
+				{Source: db.ConditionSourceDerived, Path: "reactions.checkout_failed.age_seconds", Operator: "lte", Value: 600},
+			},
+			Actions: db.Actions{PublishSubjects: []string{"reactions.{app_id}.escalate"}},
+		},
+		{
+			// Matches its own output, a cycle broken by the chain.
+			ID:            "loop",
+			EventCategory: &reactions,
+			EventType:     &escalate,
+			Actions:       db.Actions{PublishSubjects: []string{"reactions.{app_id}.escalate"}},
+		},
+		{
+			// Rules without a derived family never match derived events.
+			ID:      "catch-all",
+			Actions: db.Actions{PublishSubjects: []string{"reactions.{app_id}.everything"}},
+		},
+	}
+
+	ctx := context.Background()
+	anomaly := &DerivedEvent{
+		Subject:   "anomalies.shop.error_spike",
+		Sequence:  7,
+		Timestamp: time.Now(),
+		Payload:   map[string]interface{}{"app_id": "shop", "severity": "critical"},
+	}
+	if err := e.ProcessDerived(ctx, anomaly); err != nil {
+		t.Fatalf("ProcessDerived: %v", err)
+	}
+	if len(js.msgs) != 1 || js.msgs[0].Subject != "reactions.shop.escalate" {
+		t.Fatalf("published %d messages, want reactions.shop.escalate", len(js.msgs))
+	}
+	if got := ParseChain(js.msgs[0].Header); !reflect.DeepEqual(got, []string{"escalate"}) {
+		t.Errorf("chain header = %v, want [escalate]", got)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(js.msgs[0].Data, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload["event_id"] != "anomalies:7" || !reflect.DeepEqual(payload["chain"], []interface{}{"escalate"}) {
+		t.Errorf("payload event_id = %v, chain = %v, want anomalies:7 and [escalate]", payload["event_id"], payload["chain"])
+	}
+
+	// The escalation triggers the loop rule once; its own output then
+	// carries it in the chain and is skipped.
+	escalation := &DerivedEvent{
+		Subject:  "reactions.shop.escalate",
+		Sequence: 8,
+		Payload:  payload,
+		Chain:    ParseChain(js.msgs[0].Header),
+	}
+	if err := e.ProcessDerived(ctx, escalation); err != nil {
+		t.Fatalf("ProcessDerived: %v", err)
+	}
+	if len(js.msgs) != 2 {
+		t.Fatalf("published %d messages, want 2", len(js.msgs))
+	}
+	looped := &DerivedEvent{
+		Subject:  "reactions.shop.escalate",
+		Sequence: 9,
+		Payload:  payload,
+		Chain:    ParseChain(js.msgs[1].Header),
+	}
+	if !reflect.DeepEqual(looped.Chain, []string{"escalate", "loop"}) {
+		t.Errorf("chain = %v, want [escalate loop]", looped.Chain)
+	}
+	if err := e.ProcessDerived(ctx, looped); err != nil {
+		t.Fatalf("ProcessDerived: %v", err)
+	}
+	if len(js.msgs) != 2 {
+		t.Errorf("published %d messages after a cycle, want 2", len(js.msgs))
+	}
+
+	// Without a recent checkout failure the escalation does not fire.
+	e.derived = lastMessages{}
+	if err := e.ProcessDerived(ctx, anomaly); err != nil {
+		t.Fatalf("ProcessDerived: %v", err)
+	}
+	if len(js.msgs) != 2 {
+		t.Errorf("published %d messages without a recent checkout failure, want 2", len(js.msgs))
+	}
+}
+
+func TestChainConsumer_AcksAtMaxDepth(t *testing.T) {
+	c := NewChainConsumer(nil, nil, "CAUSALITY_DERIVED", ChainConfig{MaxDepth: 2}, nil)
+	msg := &fakeMsg{
+		data:    []byte(`{}`),
+		headers: natsgo.Header{ChainHeader: []string{"a,b"}},
+	}
+	c.handleMessage(context.Background(), msg)
+	if !msg.acked || msg.termed {
+		t.Errorf("acked=%v termed=%v, want acked without evaluation", msg.acked, msg.termed)
+	}
+}
+
+func TestRuleChainCycle(t *testing.T) {
+	reactions := "reactions"
+	name := func(s string) *string { return &s }
+
+	tests := []struct {
+		name  string
+		rules []RuleSpec
+		want  []string
+	}{
+		{
+			name: "chain without cycle",
+			rules: []RuleSpec{
+				{Name: "a", Actions: RuleSpecActions{PublishSubjects: []string{"reactions.{app_id}.a"}}},
+				{Name: "b", EventCategory: &reactions, EventType: name("a"), Actions: RuleSpecActions{PublishSubjects: []string{"reactions.{app_id}.b"}}},
+			},
+		},
+		{
+			name: "self loop",
+			rules: []RuleSpec{
+				{Name: "a", EventCategory: &reactions, Actions: RuleSpecActions{PublishSubjects: []string{"reactions.{app_id}.a"}}},
+			},
+			want: []string{"a", "a"},
+		},
+		{
+			name: "cycle through three rules",
+			rules: []RuleSpec{
+				{Name: "a", EventCategory: &reactions, EventType: name("c"), Actions: RuleSpecActions{PublishSubjects: []string{"reactions.{app_id}.a"}}},
+				{Name: "b", EventCategory: &reactions, EventType: name("a"), Actions: RuleSpecActions{PublishSubjects: []string{"reactions.{app_id}.b"}}},
+				{Name: "c", EventCategory: &reactions, EventType: name("b"), Actions: RuleSpecActions{PublishSubjects: []string{"reactions.{app_id}.c"}}},
+			},
+			want: []string{"a", "b", "c", "a"},
+		},
+		{
+			name: "rules of different apps",
+			rules: []RuleSpec{
+				{Name: "a", AppID: name("x"), EventCategory: &reactions, EventType: name("b"), Actions: RuleSpecActions{PublishSubjects: []string{"reactions.{app_id}.a"}}},
+				{Name: "b", AppID: name("y"), EventCategory: &reactions, EventType: name("a"), Actions: RuleSpecActions{PublishSubjects: []string{"reactions.{app_id}.b"}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ruleChainCycle(tt.rules); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ruleChainCycle = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
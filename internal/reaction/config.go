@@ -4,6 +4,7 @@ package reaction
 import (
 	"time"
 
+	"github.com/SebastienMelki/causality/internal/nats"
 	"github.com/SebastienMelki/causality/internal/reaction/db"
 )
 
@@ -21,9 +22,24 @@ type Config struct {
 	// Anomaly detection configuration
 	Anomaly AnomalyConfig `envPrefix:"ANOMALY_"`
 
+	// Maintenance window configuration
+	Maintenance MaintenanceConfig `envPrefix:"MAINTENANCE_"`
+
 	// Consumer configuration
 	Consumer ConsumerConfig `envPrefix:"CONSUMER_"`
 
+	// Rule chaining configuration
+	Chain ChainConfig `envPrefix:"CHAIN_"`
+
+	// User profile lookup configuration
+	Users UsersConfig `envPrefix:"USERS_"`
+
+	// Webhook payload encryption configuration
+	PayloadEncryption PayloadEncryptionConfig `envPrefix:"PAYLOAD_ENCRYPTION_"`
+
+	// Anomaly event and webhook delivery retention configuration
+	Retention RetentionConfig `envPrefix:"RETENTION_"`
+
 	// ShutdownTimeout is the maximum time to wait for graceful shutdown.
 	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"30s"`
 }
@@ -34,18 +50,67 @@ type ConsumerConfig struct {
 	// from NATS in parallel.
 	WorkerCount int `env:"WORKER_COUNT" envDefault:"1"`
 
+	// WorkerScaling adjusts the worker count between its bounds based on
+	// the consumer's pending count. WorkerCount is the initial count.
+	WorkerScaling nats.ScalingConfig `envPrefix:"WORKER_SCALING_"`
+
 	// FetchBatchSize is the number of messages to fetch per pull request
 	// from the NATS consumer.
 	FetchBatchSize int `env:"FETCH_BATCH_SIZE" envDefault:"100"`
+
+	// BatchProcessing processes each fetched batch at once instead of one
+	// message at a time: rules are evaluated for up to
+	// ENGINE_MAX_CONCURRENT_EVALUATIONS events concurrently and the webhook
+	// deliveries of the batch are inserted in one transaction.
+	BatchProcessing bool `env:"BATCH_PROCESSING" envDefault:"false"`
+
+	// Ordered evaluates the events of each device one at a time in stream
+	// order when BatchProcessing is on. Order holds within a batch, so
+	// ordering across batches also needs a WorkerCount of 1.
+	Ordered bool `env:"ORDERED" envDefault:"false"`
+
+	// TrackProcessed records the stream sequence of each processed message
+	// so redelivered messages that were already processed are acked without
+	// firing their rules again.
+	TrackProcessed bool `env:"TRACK_PROCESSED" envDefault:"true"`
+
+	// ProcessedRetention is how long processed stream sequences are kept.
+	// It should exceed the longest time a message can wait for redelivery.
+	ProcessedRetention time.Duration `env:"PROCESSED_RETENTION" envDefault:"24h"`
 }
 
 // EngineConfig holds rule engine settings.
 type EngineConfig struct {
-	// RuleRefreshInterval is how often to reload rules from the database
+	// RuleRefreshInterval is how often to reload rules from the database. With
+	// RuleChangeListen enabled this is only a fallback.
 	RuleRefreshInterval time.Duration `env:"RULE_REFRESH_INTERVAL" envDefault:"30s"`
 
+	// RuleChangeListen enables Postgres LISTEN/NOTIFY so rule changes are
+	// picked up immediately instead of on the next refresh interval
+	RuleChangeListen bool `env:"RULE_CHANGE_LISTEN" envDefault:"true"`
+
+	// RuleChangeDebounce is how long to wait after a change notification
+	// before refreshing, so bursts of changes cause a single reload
+	RuleChangeDebounce time.Duration `env:"RULE_CHANGE_DEBOUNCE" envDefault:"200ms"`
+
 	// MaxConcurrentEvaluations is the max number of concurrent rule evaluations
 	MaxConcurrentEvaluations int `env:"MAX_CONCURRENT_EVALUATIONS" envDefault:"100"`
+
+	// ShadowSampleRate is the fraction (0-1) of shadow rule matches whose event
+	// is stored as a sample. Every match is counted regardless.
+	ShadowSampleRate float64 `env:"SHADOW_SAMPLE_RATE" envDefault:"0.01"`
+
+	// ShadowMaxSamples is the number of newest samples kept per shadow rule
+	ShadowMaxSamples int `env:"SHADOW_MAX_SAMPLES" envDefault:"100"`
+
+	// MetricMaxSeries bounds the label value combinations of each rule
+	// metric action counter; further combinations are counted as "other"
+	MetricMaxSeries int `env:"METRIC_MAX_SERIES" envDefault:"1000"`
+
+	// HistoryRetention is how long the last occurrence of events referenced
+	// by history conditions is kept. It bounds the windows of those
+	// conditions.
+	HistoryRetention time.Duration `env:"HISTORY_RETENTION" envDefault:"24h"`
 }
 
 // DispatcherConfig holds webhook dispatcher settings.
@@ -56,6 +121,25 @@ type DispatcherConfig struct {
 	// PollInterval is how often to poll for pending deliveries
 	PollInterval time.Duration `env:"POLL_INTERVAL" envDefault:"1s"`
 
+	// Queue selects how workers find new deliveries: "postgres" polls
+	// webhook_deliveries every PollInterval; "nats" consumes the IDs of new
+	// deliveries from the JetStream delivery stream and polls Postgres only
+	// every QueueRetryInterval, for retries and deliveries that could not be
+	// enqueued. Postgres stays the record of every delivery either way.
+	Queue string `env:"QUEUE" envDefault:"postgres"`
+
+	// QueueConsumerName is the durable consumer of the delivery stream
+	QueueConsumerName string `env:"QUEUE_CONSUMER_NAME" envDefault:"webhook-dispatcher"`
+
+	// QueueRetryInterval is how often the "nats" queue polls Postgres. The
+	// poll skips deliveries due for less than this, which are left to the
+	// queue
+	QueueRetryInterval time.Duration `env:"QUEUE_RETRY_INTERVAL" envDefault:"15s"`
+
+	// QueueAckWait is how long a consumed delivery ID may stay unacked
+	// before it is redelivered; it should cover a fetched batch's requests
+	QueueAckWait time.Duration `env:"QUEUE_ACK_WAIT" envDefault:"5m"`
+
 	// BatchSize is the number of deliveries to fetch per poll
 	BatchSize int `env:"BATCH_SIZE" envDefault:"100"`
 
@@ -75,6 +159,73 @@ type DispatcherConfig struct {
 	RequestTimeout time.Duration `env:"REQUEST_TIMEOUT" envDefault:"30s"`
 }
 
+// RetentionConfig holds retention settings for anomaly events and webhook
+// deliveries.
+type RetentionConfig struct {
+	// AnomalyEvents is how long anomaly events are kept; 0 keeps them for
+	// ANOMALY_STATE_RETENTION_DURATION
+	AnomalyEvents time.Duration `env:"ANOMALY_EVENTS"`
+
+	// Deliveries is how long delivered and dead-lettered webhook deliveries
+	// are kept; 0 keeps them forever
+	Deliveries time.Duration `env:"DELIVERIES"`
+
+	// Interval is how often expired rows are removed
+	Interval time.Duration `env:"INTERVAL" envDefault:"1h"`
+
+	// BatchSize is the number of rows archived and deleted at a time
+	BatchSize int `env:"BATCH_SIZE" envDefault:"5000"`
+
+	// Archive exports expired rows to Parquet in the S3 bucket before they
+	// are deleted; rows that fail to archive are kept until the next run
+	Archive bool `env:"ARCHIVE" envDefault:"false"`
+
+	// ArchivePrefix is the key prefix of archived rows in the bucket
+	ArchivePrefix string `env:"ARCHIVE_PREFIX" envDefault:"archive"`
+}
+
+// PayloadEncryptionConfig holds at-rest encryption settings for webhook
+// delivery payloads.
+type PayloadEncryptionConfig struct {
+	// Enabled encrypts new webhook payloads before they are stored
+	Enabled bool `env:"ENABLED" envDefault:"false"`
+
+	// Provider holds the key-encryption key: kms (AWS KMS) or local (Key,
+	// for development only)
+	Provider string `env:"PROVIDER" envDefault:"kms"`
+
+	// KMS configures the AWS KMS key used when Provider is kms
+	KMS KMSConfig `envPrefix:"KMS_"`
+
+	// KeyID identifies the active local key-encryption key and is stored with each payload
+	KeyID string `env:"KEY_ID" envDefault:"default"`
+
+	// Key is the base64-encoded 256-bit key-encryption key
+	Key string `env:"KEY"`
+
+	// RetiredKeys maps previous key IDs to base64 keys kept for decryption only (id:key,...)
+	RetiredKeys map[string]string `env:"RETIRED_KEYS"`
+}
+
+// Payload key-encryption key providers.
+const (
+	PayloadKeyProviderKMS   = "kms"
+	PayloadKeyProviderLocal = "local"
+)
+
+// KMSConfig holds the AWS KMS key wrapping webhook payload data keys.
+// Credentials come from the default AWS credential chain.
+type KMSConfig struct {
+	// KeyID is the ID, ARN or alias of the symmetric KMS key
+	KeyID string `env:"KEY_ID"`
+
+	// Region is the AWS region of the key (default: from the AWS config)
+	Region string `env:"REGION"`
+
+	// Endpoint overrides the KMS endpoint, e.g. for LocalStack
+	Endpoint string `env:"ENDPOINT"`
+}
+
 // AnomalyConfig holds anomaly detection settings.
 type AnomalyConfig struct {
 	// ConfigRefreshInterval is how often to reload anomaly configs
@@ -85,6 +236,37 @@ type AnomalyConfig struct {
 
 	// StateRetentionDuration is how long to keep state records
 	StateRetentionDuration time.Duration `env:"STATE_RETENTION_DURATION" envDefault:"24h"`
+
+	// RevenueRetentionDuration is how long to keep revenue window sums. It
+	// must cover the baseline windows of every revenue config.
+	RevenueRetentionDuration time.Duration `env:"REVENUE_RETENTION_DURATION" envDefault:"840h"`
+
+	// RevenueCheckInterval is how often closed revenue windows are checked
+	// for drops below their baseline
+	RevenueCheckInterval time.Duration `env:"REVENUE_CHECK_INTERVAL" envDefault:"1m"`
+
+	// PreviewSearchURL is the base URL of the search sink, whose hot store
+	// supplies the recent events anomaly config previews replay; empty
+	// disables previews
+	PreviewSearchURL string `env:"PREVIEW_SEARCH_URL"`
+
+	// PreviewWindow is how far back previews replay events
+	PreviewWindow time.Duration `env:"PREVIEW_WINDOW" envDefault:"1h"`
+
+	// PreviewMaxEvents bounds the events a preview replays; older events in
+	// the window are skipped and the preview reports it was truncated
+	PreviewMaxEvents int `env:"PREVIEW_MAX_EVENTS" envDefault:"20000"`
+
+	// EscalationInterval is how often unacknowledged alerts are checked for
+	// due escalation steps
+	EscalationInterval time.Duration `env:"ESCALATION_INTERVAL" envDefault:"30s"`
+}
+
+// MaintenanceConfig holds maintenance window settings.
+type MaintenanceConfig struct {
+	// RefreshInterval is how often maintenance windows are reloaded; admin
+	// API changes apply immediately on the engine that served them
+	RefreshInterval time.Duration `env:"REFRESH_INTERVAL" envDefault:"30s"`
 }
 
 // BasicAuthConfig holds basic auth configuration.
@@ -5,16 +5,25 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"sync"
+	"net/http"
 	"time"
 
 	"github.com/nats-io/nats.go/jetstream"
-	"google.golang.org/protobuf/proto"
 
+	"github.com/SebastienMelki/causality/internal/events"
+	"github.com/SebastienMelki/causality/internal/nats"
 	"github.com/SebastienMelki/causality/internal/observability"
 	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
 )
 
+// ProcessedTracker records the stream sequences a consumer finished
+// processing. It is satisfied by *db.ProcessedRepository.
+type ProcessedTracker interface {
+	IsProcessed(ctx context.Context, consumer string, streamSeq uint64) (bool, error)
+	MarkProcessed(ctx context.Context, consumer string, streamSeq uint64, eventID string) error
+	DeleteOld(ctx context.Context, olderThan time.Time) (int64, error)
+}
+
 // Consumer consumes events from NATS JetStream and processes them through the reaction engine.
 type Consumer struct {
 	js           jetstream.JetStream
@@ -25,8 +34,14 @@ type Consumer struct {
 	config       ConsumerConfig
 	consumerName string
 	streamName   string
+	processed    ProcessedTracker
+
+	// skipPriority acks priority events without processing them, because a
+	// consumer of the priority stream handles them.
+	skipPriority bool
 
 	shutdownTimeout time.Duration
+	scaler          *nats.WorkerScaler
 	stopCh          chan struct{}
 	doneCh          chan struct{}
 }
@@ -66,6 +81,21 @@ func NewConsumer(
 	}
 }
 
+// SetProcessedTracker sets the store of processed stream sequences. With it,
+// redelivered messages that were already processed are acked without being
+// processed again. Must be called before Start.
+func (c *Consumer) SetProcessedTracker(tracker ProcessedTracker) {
+	c.processed = tracker
+}
+
+// SetSkipPriority makes the consumer ack priority events (see
+// nats.IsPriorityMsg) without processing them. Set it on the main stream
+// consumer when a priority stream consumer handles those events. Must be
+// called before Start.
+func (c *Consumer) SetSkipPriority(skip bool) {
+	c.skipPriority = skip
+}
+
 // Start starts consuming events from NATS with a configurable worker pool.
 func (c *Consumer) Start(ctx context.Context) error {
 	// Get stream
@@ -90,21 +120,27 @@ func (c *Consumer) Start(ctx context.Context) error {
 		"stream", c.streamName,
 		"workers", workerCount,
 		"fetch_batch_size", c.config.FetchBatchSize,
+		"batch_processing", c.config.BatchProcessing,
+		"ordered", c.config.Ordered,
 	)
 
+	ctx = observability.WithLogAttrs(ctx, observability.LogKeyConsumer, c.consumerName)
+
 	// Start worker pool
-	var wg sync.WaitGroup
-	for i := range workerCount {
-		wg.Add(1)
-		go func(id int) {
-			defer wg.Done()
-			c.workerLoop(ctx, consumer, id)
-		}(i)
+	c.scaler = nats.NewWorkerScaler(c.config.WorkerScaling, workerCount,
+		func(ctx context.Context, id int, stop <-chan struct{}) {
+			c.workerLoop(ctx, consumer, id, stop)
+		},
+		nats.ConsumerPending(consumer), c.logger)
+	c.scaler.Start(ctx, workerCount)
+
+	if c.processed != nil {
+		go c.pruneProcessed(ctx)
 	}
 
 	// Close doneCh when all workers finish
 	go func() {
-		wg.Wait()
+		c.scaler.Wait()
 		close(c.doneCh)
 	}()
 
@@ -112,8 +148,8 @@ func (c *Consumer) Start(ctx context.Context) error {
 }
 
 // workerLoop is the main loop for a single fetch worker. It pulls messages
-// from the NATS consumer and processes them.
-func (c *Consumer) workerLoop(ctx context.Context, consumer jetstream.Consumer, id int) {
+// from the NATS consumer and processes them until stop is closed.
+func (c *Consumer) workerLoop(ctx context.Context, consumer jetstream.Consumer, id int, stop <-chan struct{}) {
 	logger := c.logger.With("worker_id", id)
 	logger.Debug("worker started")
 	defer logger.Debug("worker stopped")
@@ -129,6 +165,8 @@ func (c *Consumer) workerLoop(ctx context.Context, consumer jetstream.Consumer,
 			return
 		case <-c.stopCh:
 			return
+		case <-stop:
+			return
 		default:
 			msgs, err := consumer.Fetch(fetchSize, jetstream.FetchMaxWait(5*time.Second))
 			if err != nil {
@@ -141,13 +179,23 @@ func (c *Consumer) workerLoop(ctx context.Context, consumer jetstream.Consumer,
 						return
 					case <-c.stopCh:
 						return
+					case <-stop:
+						return
 					}
 				}
 				continue
 			}
 
-			for msg := range msgs.Messages() {
-				c.processMessage(ctx, msg)
+			if c.config.BatchProcessing {
+				var batch []jetstream.Msg
+				for msg := range msgs.Messages() {
+					batch = append(batch, msg)
+				}
+				c.processBatch(ctx, batch)
+			} else {
+				for msg := range msgs.Messages() {
+					c.processMessage(ctx, msg)
+				}
 			}
 
 			if err := msgs.Error(); err != nil {
@@ -157,36 +205,148 @@ func (c *Consumer) workerLoop(ctx context.Context, consumer jetstream.Consumer,
 	}
 }
 
+// consumedMessage is a decoded message awaiting processing. ctx carries the
+// message's trace context and log attributes.
+type consumedMessage struct {
+	ctx   context.Context
+	msg   jetstream.Msg
+	event *pb.EventEnvelope
+	meta  *jetstream.MsgMetadata
+}
+
 // processMessage deserializes a single NATS message and processes it through
-// the rule engine and anomaly detector. Poison messages (unmarshal failures)
-// are terminated immediately so they are not redelivered.
+// the rule engine and anomaly detector.
 func (c *Consumer) processMessage(ctx context.Context, msg jetstream.Msg) {
+	start := time.Now()
+	failed := 0
+
+	m, poison := c.decode(ctx, msg)
+	if poison {
+		failed = 1
+	}
+	if m != nil {
+		var ruleErr error
+		if c.engine != nil {
+			ruleErr = c.engine.ProcessEvent(m.ctx, m.event)
+		}
+		if !c.finish(m, ruleErr) {
+			failed = 1
+		}
+	}
+
+	if c.metrics != nil {
+		c.metrics.RecordConsumed(ctx, "reaction", 1, failed, time.Since(start))
+	}
+}
+
+// processBatch processes a fetched batch at once: the rules of all its events
+// are evaluated concurrently by Engine.ProcessEvents, which inserts their
+// webhook deliveries together, then each message goes through the anomaly
+// detector in stream order and is acked.
+func (c *Consumer) processBatch(ctx context.Context, msgs []jetstream.Msg) {
+	if len(msgs) == 0 {
+		return
+	}
+
+	start := time.Now()
+	failed := 0
+
+	batch := make([]*consumedMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		m, poison := c.decode(ctx, msg)
+		if poison {
+			failed++
+		}
+		if m != nil {
+			batch = append(batch, m)
+		}
+	}
+
+	ruleErrs := make([]error, len(batch))
+	if c.engine != nil && len(batch) > 0 {
+		evts := make([]*pb.EventEnvelope, len(batch))
+		for i, m := range batch {
+			evts[i] = m.event
+		}
+		ruleErrs = c.engine.ProcessEvents(ctx, evts, c.config.Ordered)
+	}
+
+	for i, m := range batch {
+		if !c.finish(m, ruleErrs[i]) {
+			failed++
+		}
+	}
+
+	if c.metrics != nil {
+		c.metrics.RecordConsumed(ctx, "reaction", len(msgs), failed, time.Since(start))
+	}
+}
+
+// decode deserializes a message. It returns nil when the message needs no
+// processing: skipped priority events are acked, poison messages (unmarshal
+// failures) are terminated so they are not redelivered, reported by poison,
+// and with a processed tracker, redelivered messages whose stream sequence
+// was already processed are acked and skipped.
+func (c *Consumer) decode(ctx context.Context, msg jetstream.Msg) (m *consumedMessage, poison bool) {
+	if c.skipPriority && nats.IsPriorityMsg(msg.Headers()) {
+		if err := msg.Ack(); err != nil {
+			c.logger.Error("failed to ACK priority message", "error", err)
+		}
+		return nil, false
+	}
+
+	ctx = observability.ExtractTraceContext(ctx, http.Header(msg.Headers()))
+
 	var event pb.EventEnvelope
-	if err := proto.Unmarshal(msg.Data(), &event); err != nil {
+	if err := events.Decode(msg.Data(), &event); err != nil {
+		logger := observability.Logger(ctx, c.logger)
 		// Poison message: terminate to prevent infinite redelivery
-		c.logger.Error("poison message: unmarshal failure, terminating",
+		logger.Error("poison message: unmarshal failure, terminating",
 			"error", err,
 			"subject", msg.Subject(),
 		)
 		if termErr := msg.Term(); termErr != nil {
-			c.logger.Error("failed to terminate poison message", "error", termErr)
+			logger.Error("failed to terminate poison message", "error", termErr)
 		}
-		return
+		return nil, true
 	}
 
-	c.logger.Debug("processing event",
-		"event_id", event.Id,
-		"app_id", event.AppId,
-		"subject", msg.Subject(),
+	ctx = observability.WithLogAttrs(ctx,
+		observability.LogKeyEventID, event.Id,
+		observability.LogKeyAppID, event.AppId,
 	)
+	logger := observability.Logger(ctx, c.logger)
+
+	// Without metadata the message is processed but not tracked.
+	meta, _ := msg.Metadata()
+	if meta != nil && c.alreadyProcessed(ctx, meta) {
+		logger.Info("skipping already processed message",
+			"subject", msg.Subject(),
+			"stream_seq", meta.Sequence.Stream,
+			"num_delivered", meta.NumDelivered,
+		)
+		if err := msg.Ack(); err != nil {
+			logger.Error("failed to ACK message", "error", err)
+		}
+		return nil, false
+	}
+
+	logger.Debug("processing event", "subject", msg.Subject())
+	return &consumedMessage{ctx: ctx, msg: msg, event: &event, meta: meta}, false
+}
+
+// finish completes a message whose rules were evaluated with ruleErr: it
+// runs the anomaly detector, records the message as processed and acks it.
+// It reports whether processing succeeded.
+func (c *Consumer) finish(m *consumedMessage, ruleErr error) bool {
+	ctx := m.ctx
+	logger := observability.Logger(ctx, c.logger)
+	ok := true
 
-	// Process through rule engine
 	if c.engine != nil {
-		if err := c.engine.ProcessEvent(ctx, &event); err != nil {
-			c.logger.Error("rule engine error",
-				"event_id", event.Id,
-				"error", err,
-			)
+		if ruleErr != nil {
+			ok = false
+			logger.Error("rule engine error", "error", ruleErr)
 		}
 		// Record rules evaluated metric
 		if c.metrics != nil {
@@ -196,11 +356,9 @@ func (c *Consumer) processMessage(ctx context.Context, msg jetstream.Msg) {
 
 	// Process through anomaly detector
 	if c.anomaly != nil {
-		if err := c.anomaly.ProcessEvent(ctx, &event); err != nil {
-			c.logger.Error("anomaly detector error",
-				"event_id", event.Id,
-				"error", err,
-			)
+		if err := c.anomaly.ProcessEvent(ctx, m.event); err != nil {
+			ok = false
+			logger.Error("anomaly detector error", "error", err)
 		}
 	}
 
@@ -209,9 +367,65 @@ func (c *Consumer) processMessage(ctx context.Context, msg jetstream.Msg) {
 		c.metrics.NATSMessagesProcessed.Add(ctx, 1)
 	}
 
+	if c.processed != nil && m.meta != nil {
+		if err := c.processed.MarkProcessed(ctx, c.consumerName, m.meta.Sequence.Stream, m.event.Id); err != nil {
+			logger.Warn("failed to record processed message", "error", err)
+		}
+	}
+
 	// ACK successful processing
-	if err := msg.Ack(); err != nil {
-		c.logger.Error("failed to ACK message", "error", err)
+	if err := m.msg.Ack(); err != nil {
+		logger.Error("failed to ACK message", "error", err)
+	}
+	return ok
+}
+
+// alreadyProcessed reports whether a redelivered message was processed
+// before. First deliveries are never looked up. Lookup failures are treated
+// as not processed, so the message is processed rather than lost.
+func (c *Consumer) alreadyProcessed(ctx context.Context, meta *jetstream.MsgMetadata) bool {
+	if c.processed == nil || meta.NumDelivered <= 1 {
+		return false
+	}
+
+	processed, err := c.processed.IsProcessed(ctx, c.consumerName, meta.Sequence.Stream)
+	if err != nil {
+		observability.Logger(ctx, c.logger).Warn("failed to look up processed message",
+			"stream_seq", meta.Sequence.Stream,
+			"error", err,
+		)
+		return false
+	}
+	return processed
+}
+
+// pruneProcessed periodically deletes processed sequences older than the
+// configured retention.
+func (c *Consumer) pruneProcessed(ctx context.Context) {
+	retention := c.config.ProcessedRetention
+	if retention <= 0 {
+		retention = 24 * time.Hour
+	}
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.stopCh:
+			return
+		case <-ticker.C:
+			deleted, err := c.processed.DeleteOld(ctx, time.Now().Add(-retention))
+			if err != nil {
+				c.logger.Error("failed to prune processed messages", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				c.logger.Debug("pruned processed messages", "count", deleted)
+			}
+		}
 	}
 }
 
@@ -220,6 +434,9 @@ func (c *Consumer) processMessage(ctx context.Context, msg jetstream.Msg) {
 func (c *Consumer) Stop(ctx context.Context) error {
 	c.logger.Info("stopping reaction consumer")
 	close(c.stopCh)
+	if c.scaler != nil {
+		c.scaler.Stop()
+	}
 
 	// Create a shutdown context with the configured timeout
 	shutdownCtx, shutdownCancel := context.WithTimeout(ctx, c.shutdownTimeout)
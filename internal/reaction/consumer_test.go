@@ -0,0 +1,196 @@
+package reaction
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// fakeTracker records processed sequences in memory.
+type fakeTracker struct {
+	processed map[uint64]string
+	lookups   int
+}
+
+func (f *fakeTracker) IsProcessed(_ context.Context, _ string, streamSeq uint64) (bool, error) {
+	f.lookups++
+	_, ok := f.processed[streamSeq]
+	return ok, nil
+}
+
+func (f *fakeTracker) MarkProcessed(_ context.Context, _ string, streamSeq uint64, eventID string) error {
+	f.processed[streamSeq] = eventID
+	return nil
+}
+
+func (f *fakeTracker) DeleteOld(_ context.Context, _ time.Time) (int64, error) {
+	return 0, nil
+}
+
+// fakeMsg is a jetstream.Msg with fixed metadata that records acks and
+// terminations.
+type fakeMsg struct {
+	jetstream.Msg
+	data    []byte
+	meta    jetstream.MsgMetadata
+	headers nats.Header
+	acked   bool
+	termed  bool
+	nakked  bool
+}
+
+func (m *fakeMsg) Data() []byte                              { return m.data }
+func (m *fakeMsg) Subject() string                           { return "events.app.screen.view" }
+func (m *fakeMsg) Headers() nats.Header                      { return m.headers }
+func (m *fakeMsg) Metadata() (*jetstream.MsgMetadata, error) { return &m.meta, nil }
+
+func (m *fakeMsg) Ack() error {
+	m.acked = true
+	return nil
+}
+
+func (m *fakeMsg) Term() error {
+	m.termed = true
+	return nil
+}
+
+func (m *fakeMsg) NakWithDelay(time.Duration) error {
+	m.nakked = true
+	return nil
+}
+
+func TestConsumer_SkipsAlreadyProcessedRedeliveries(t *testing.T) {
+	tracker := &fakeTracker{processed: map[uint64]string{}}
+	c := NewConsumer(nil, nil, nil, "reaction", "CAUSALITY_EVENTS", ConsumerConfig{}, 0, nil, nil)
+	c.SetProcessedTracker(tracker)
+
+	message := func(seq, delivered uint64, eventID string) *fakeMsg {
+		data, err := proto.Marshal(&pb.EventEnvelope{Id: eventID, AppId: "app"})
+		if err != nil {
+			t.Fatalf("marshal event: %v", err)
+		}
+		meta := jetstream.MsgMetadata{NumDelivered: delivered}
+		meta.Sequence.Stream = seq
+		return &fakeMsg{data: data, meta: meta}
+	}
+
+	ctx := context.Background()
+
+	first := message(1, 1, "e1")
+	c.processMessage(ctx, first)
+	if !first.acked || tracker.processed[1] != "e1" {
+		t.Fatalf("first delivery: acked=%v processed=%v, want acked and recorded", first.acked, tracker.processed)
+	}
+	if tracker.lookups != 0 {
+		t.Errorf("first delivery looked up the tracker %d times, want 0", tracker.lookups)
+	}
+
+	// A redelivery of the processed message is acked without reprocessing.
+	tracker.processed[1] = "marker"
+	redelivered := message(1, 2, "e1")
+	c.processMessage(ctx, redelivered)
+	if !redelivered.acked || tracker.processed[1] != "marker" {
+		t.Errorf("redelivery: acked=%v processed=%v, want acked and skipped", redelivered.acked, tracker.processed)
+	}
+
+	// A redelivery of a message that was never processed is processed.
+	unprocessed := message(2, 3, "e2")
+	c.processMessage(ctx, unprocessed)
+	if !unprocessed.acked || tracker.processed[2] != "e2" {
+		t.Errorf("unprocessed redelivery: acked=%v processed=%v, want acked and recorded", unprocessed.acked, tracker.processed)
+	}
+	if tracker.lookups != 2 {
+		t.Errorf("tracker lookups: got %d, want 2", tracker.lookups)
+	}
+}
+
+func TestConsumer_SkipPriority(t *testing.T) {
+	tracker := &fakeTracker{processed: map[uint64]string{}}
+	c := NewConsumer(nil, nil, nil, "reaction", "CAUSALITY_EVENTS", ConsumerConfig{}, 0, nil, nil)
+	c.SetProcessedTracker(tracker)
+	c.SetSkipPriority(true)
+
+	message := func(seq uint64, eventID string, headers nats.Header) *fakeMsg {
+		data, err := proto.Marshal(&pb.EventEnvelope{Id: eventID, AppId: "app"})
+		if err != nil {
+			t.Fatalf("marshal event: %v", err)
+		}
+		msg := &fakeMsg{data: data, headers: headers}
+		msg.meta.NumDelivered = 1
+		msg.meta.Sequence.Stream = seq
+		return msg
+	}
+
+	ctx := context.Background()
+
+	priority := message(1, "e1", nats.Header{"Causality-Priority": []string{"true"}})
+	c.processMessage(ctx, priority)
+	if !priority.acked || tracker.processed[1] != "" {
+		t.Errorf("priority message: acked=%v processed=%v, want acked and skipped", priority.acked, tracker.processed)
+	}
+
+	regular := message(2, "e2", nil)
+	c.processMessage(ctx, regular)
+	if !regular.acked || tracker.processed[2] != "e2" {
+		t.Errorf("regular message: acked=%v processed=%v, want acked and recorded", regular.acked, tracker.processed)
+	}
+}
+
+func TestConsumer_ProcessBatch(t *testing.T) {
+	tracker := &fakeTracker{processed: map[uint64]string{2: "e2"}}
+	store := &recordingDeliveryStore{}
+	engine := NewEngine(nil, nil, nil, nil, EngineConfig{MaxConcurrentEvaluations: 2}, DispatcherConfig{}, nil, nil)
+	engine.deliveries = store
+	engine.cachedRules = []*db.Rule{{
+		ID:         "r1",
+		Conditions: []db.Condition{{Path: "$.app_id", Operator: "eq", Value: "app"}},
+		Actions:    db.Actions{Webhooks: []string{"w1"}},
+	}}
+
+	c := NewConsumer(nil, engine, nil, "reaction", "CAUSALITY_EVENTS", ConsumerConfig{BatchProcessing: true}, 0, nil, nil)
+	c.SetProcessedTracker(tracker)
+
+	var msgs []*fakeMsg
+	for seq := uint64(1); seq <= 3; seq++ {
+		data, err := proto.Marshal(&pb.EventEnvelope{Id: fmt.Sprintf("e%d", seq), AppId: "app", DeviceId: "d1"})
+		if err != nil {
+			t.Fatalf("marshal event: %v", err)
+		}
+		msg := &fakeMsg{data: data}
+		msg.meta.NumDelivered = 2
+		msg.meta.Sequence.Stream = seq
+		msgs = append(msgs, msg)
+	}
+	poison := &fakeMsg{data: []byte{0xff}}
+
+	c.processBatch(context.Background(), []jetstream.Msg{msgs[0], msgs[1], poison, msgs[2]})
+
+	for i, msg := range msgs {
+		if !msg.acked {
+			t.Errorf("message %d not acked", i+1)
+		}
+	}
+	if !poison.termed || poison.acked {
+		t.Errorf("poison message: termed=%v acked=%v, want terminated only", poison.termed, poison.acked)
+	}
+	if tracker.processed[1] != "e1" || tracker.processed[3] != "e3" {
+		t.Errorf("processed = %v, want sequences 1 and 3 recorded", tracker.processed)
+	}
+
+	// The already processed message queues nothing; the others queue one
+	// delivery each, inserted together.
+	if len(store.batches) != 1 || len(store.batches[0]) != 2 {
+		t.Fatalf("CreateBatch batches = %v, want one batch of 2 deliveries", store.batches)
+	}
+	if got := *store.batches[0][1].IdempotencyKey; got != "r1:e3" {
+		t.Errorf("second delivery key = %q, want r1:e3", got)
+	}
+}
@@ -12,6 +12,8 @@ import (
 var (
 	ErrAnomalyConfigNotFound = errors.New("anomaly config not found")
 	ErrAnomalyStateNotFound  = errors.New("anomaly state not found")
+	ErrAnomalyEventNotFound  = errors.New("anomaly event not found")
+	ErrAlreadyAcknowledged   = errors.New("anomaly event already acknowledged")
 )
 
 // DetectionType represents the type of anomaly detection.
@@ -21,22 +23,44 @@ const (
 	DetectionTypeThreshold DetectionType = "threshold"
 	DetectionTypeRate      DetectionType = "rate"
 	DetectionTypeCount     DetectionType = "count"
+	DetectionTypeForecast  DetectionType = "forecast"
+	DetectionTypeRevenue   DetectionType = "revenue"
 )
 
+// Severity is how urgent an anomaly config's alerts are.
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Valid reports whether s is a known severity.
+func (s Severity) Valid() bool {
+	switch s {
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+		return true
+	}
+	return false
+}
+
 // AnomalyConfig represents an anomaly detection configuration.
 type AnomalyConfig struct {
-	ID              string          `json:"id"`
-	Name            string          `json:"name"`
-	Description     *string         `json:"description,omitempty"`
-	AppID           *string         `json:"app_id,omitempty"`
-	EventCategory   *string         `json:"event_category,omitempty"`
-	EventType       *string         `json:"event_type,omitempty"`
-	DetectionType   DetectionType   `json:"detection_type"`
-	Config          json.RawMessage `json:"config"`
-	CooldownSeconds int             `json:"cooldown_seconds"`
-	Enabled         bool            `json:"enabled"`
-	CreatedAt       time.Time       `json:"created_at"`
-	UpdatedAt       time.Time       `json:"updated_at"`
+	ID                 string          `json:"id"`
+	Name               string          `json:"name"`
+	Description        *string         `json:"description,omitempty"`
+	AppID              *string         `json:"app_id,omitempty"`
+	EventCategory      *string         `json:"event_category,omitempty"`
+	EventType          *string         `json:"event_type,omitempty"`
+	DetectionType      DetectionType   `json:"detection_type"`
+	Config             json.RawMessage `json:"config"`
+	CooldownSeconds    int             `json:"cooldown_seconds"`
+	Severity           Severity        `json:"severity"`
+	EscalationPolicyID *string         `json:"escalation_policy_id,omitempty"`
+	Enabled            bool            `json:"enabled"`
+	CreatedAt          time.Time       `json:"created_at"`
+	UpdatedAt          time.Time       `json:"updated_at"`
 }
 
 // AnomalyEvent represents a detected anomaly.
@@ -49,7 +73,22 @@ type AnomalyEvent struct {
 	DetectionType   string          `json:"detection_type"`
 	Details         json.RawMessage `json:"details"`
 	EventData       json.RawMessage `json:"event_data,omitempty"`
-	CreatedAt       time.Time       `json:"created_at"`
+
+	// Escalation state. EscalationStep is the index of the next step of
+	// the policy to notify, due at NextEscalationAt; NextEscalationAt is
+	// nil once the event is acknowledged or every step was notified.
+	EscalationPolicyID *string    `json:"escalation_policy_id,omitempty"`
+	EscalationStep     int        `json:"escalation_step"`
+	NextEscalationAt   *time.Time `json:"next_escalation_at,omitempty"`
+	AcknowledgedAt     *time.Time `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy     *string    `json:"acknowledged_by,omitempty"`
+
+	// Suppressed events were detected during a maintenance window and
+	// were neither published nor escalated.
+	Suppressed          bool    `json:"suppressed"`
+	MaintenanceWindowID *string `json:"maintenance_window_id,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // AnomalyState represents the sliding window state for rate/count detection.
@@ -59,26 +98,41 @@ type AnomalyState struct {
 	AppID           string     `json:"app_id"`
 	WindowKey       string     `json:"window_key"`
 	EventCount      int        `json:"event_count"`
+	ValueSum        float64    `json:"value_sum"`
 	LastAlertAt     *time.Time `json:"last_alert_at,omitempty"`
 	CreatedAt       time.Time  `json:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
+// AnomalyBaseline is the learned expected range of an app's event count for
+// one event type and hour, written by the anomaly forecast job.
+type AnomalyBaseline struct {
+	AppID         string    `json:"app_id"`
+	EventCategory string    `json:"event_category"`
+	EventType     string    `json:"event_type"`
+	Hour          time.Time `json:"hour"`
+	Expected      float64   `json:"expected"`
+	Lower         float64   `json:"lower"`
+	Upper         float64   `json:"upper"`
+	ComputedAt    time.Time `json:"computed_at"`
+}
+
 // AnomalyConfigRepository provides CRUD operations for anomaly configs.
 type AnomalyConfigRepository struct {
-	db *sql.DB
+	db   *sql.DB
+	read *readRouter
 }
 
 // NewAnomalyConfigRepository creates a new anomaly config repository.
 func NewAnomalyConfigRepository(client *Client) *AnomalyConfigRepository {
-	return &AnomalyConfigRepository{db: client.DB()}
+	return &AnomalyConfigRepository{db: client.DB(), read: client.read}
 }
 
 // Create creates a new anomaly config.
 func (r *AnomalyConfigRepository) Create(ctx context.Context, config *AnomalyConfig) error {
 	query := `
-		INSERT INTO anomaly_configs (name, description, app_id, event_category, event_type, detection_type, config, cooldown_seconds, enabled)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO anomaly_configs (name, description, app_id, event_category, event_type, detection_type, config, cooldown_seconds, severity, escalation_policy_id, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING id, created_at, updated_at
 	`
 
@@ -92,6 +146,8 @@ func (r *AnomalyConfigRepository) Create(ctx context.Context, config *AnomalyCon
 		config.DetectionType,
 		config.Config,
 		config.CooldownSeconds,
+		config.Severity,
+		config.EscalationPolicyID,
 		config.Enabled,
 	).Scan(&config.ID, &config.CreatedAt, &config.UpdatedAt)
 }
@@ -99,7 +155,7 @@ func (r *AnomalyConfigRepository) Create(ctx context.Context, config *AnomalyCon
 // GetByID retrieves an anomaly config by ID.
 func (r *AnomalyConfigRepository) GetByID(ctx context.Context, id string) (*AnomalyConfig, error) {
 	query := `
-		SELECT id, name, description, app_id, event_category, event_type, detection_type, config, cooldown_seconds, enabled, created_at, updated_at
+		SELECT id, name, description, app_id, event_category, event_type, detection_type, config, cooldown_seconds, severity, escalation_policy_id, enabled, created_at, updated_at
 		FROM anomaly_configs
 		WHERE id = $1
 	`
@@ -115,6 +171,8 @@ func (r *AnomalyConfigRepository) GetByID(ctx context.Context, id string) (*Anom
 		&config.DetectionType,
 		&config.Config,
 		&config.CooldownSeconds,
+		&config.Severity,
+		&config.EscalationPolicyID,
 		&config.Enabled,
 		&config.CreatedAt,
 		&config.UpdatedAt,
@@ -129,16 +187,17 @@ func (r *AnomalyConfigRepository) GetByID(ctx context.Context, id string) (*Anom
 	return config, nil
 }
 
-// GetEnabled retrieves all enabled anomaly configs.
+// GetEnabled retrieves all enabled anomaly configs, from the read replica
+// when one is caught up.
 func (r *AnomalyConfigRepository) GetEnabled(ctx context.Context) ([]*AnomalyConfig, error) {
 	query := `
-		SELECT id, name, description, app_id, event_category, event_type, detection_type, config, cooldown_seconds, enabled, created_at, updated_at
+		SELECT id, name, description, app_id, event_category, event_type, detection_type, config, cooldown_seconds, severity, escalation_policy_id, enabled, created_at, updated_at
 		FROM anomaly_configs
 		WHERE enabled = true
 		ORDER BY name
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.read.reader().QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -150,7 +209,7 @@ func (r *AnomalyConfigRepository) GetEnabled(ctx context.Context) ([]*AnomalyCon
 // GetMatchingConfigs retrieves enabled anomaly configs that could match the given app_id, category, and type.
 func (r *AnomalyConfigRepository) GetMatchingConfigs(ctx context.Context, appID, category, eventType string) ([]*AnomalyConfig, error) {
 	query := `
-		SELECT id, name, description, app_id, event_category, event_type, detection_type, config, cooldown_seconds, enabled, created_at, updated_at
+		SELECT id, name, description, app_id, event_category, event_type, detection_type, config, cooldown_seconds, severity, escalation_policy_id, enabled, created_at, updated_at
 		FROM anomaly_configs
 		WHERE enabled = true
 		  AND (app_id IS NULL OR app_id = $1)
@@ -183,6 +242,8 @@ func (r *AnomalyConfigRepository) scanConfigs(rows *sql.Rows) ([]*AnomalyConfig,
 			&config.DetectionType,
 			&config.Config,
 			&config.CooldownSeconds,
+			&config.Severity,
+			&config.EscalationPolicyID,
 			&config.Enabled,
 			&config.CreatedAt,
 			&config.UpdatedAt,
@@ -200,8 +261,9 @@ func (r *AnomalyConfigRepository) Update(ctx context.Context, config *AnomalyCon
 	query := `
 		UPDATE anomaly_configs
 		SET name = $1, description = $2, app_id = $3, event_category = $4, event_type = $5,
-		    detection_type = $6, config = $7, cooldown_seconds = $8, enabled = $9
-		WHERE id = $10
+		    detection_type = $6, config = $7, cooldown_seconds = $8, severity = $9,
+		    escalation_policy_id = $10, enabled = $11
+		WHERE id = $12
 	`
 
 	result, err := r.db.ExecContext(
@@ -214,6 +276,8 @@ func (r *AnomalyConfigRepository) Update(ctx context.Context, config *AnomalyCon
 		config.DetectionType,
 		config.Config,
 		config.CooldownSeconds,
+		config.Severity,
+		config.EscalationPolicyID,
 		config.Enabled,
 		config.ID,
 	)
@@ -255,7 +319,7 @@ func (r *AnomalyConfigRepository) Delete(ctx context.Context, id string) error {
 // List retrieves all anomaly configs with pagination.
 func (r *AnomalyConfigRepository) List(ctx context.Context, limit, offset int) ([]*AnomalyConfig, error) {
 	query := `
-		SELECT id, name, description, app_id, event_category, event_type, detection_type, config, cooldown_seconds, enabled, created_at, updated_at
+		SELECT id, name, description, app_id, event_category, event_type, detection_type, config, cooldown_seconds, severity, escalation_policy_id, enabled, created_at, updated_at
 		FROM anomaly_configs
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2
@@ -270,11 +334,32 @@ func (r *AnomalyConfigRepository) List(ctx context.Context, limit, offset int) (
 	return r.scanConfigs(rows)
 }
 
+// ListByAppID retrieves the anomaly configs scoped to an app with
+// pagination. Configs that apply to all apps (NULL app_id) are not included.
+func (r *AnomalyConfigRepository) ListByAppID(ctx context.Context, appID string, limit, offset int) ([]*AnomalyConfig, error) {
+	query := `
+		SELECT id, name, description, app_id, event_category, event_type, detection_type, config, cooldown_seconds, severity, escalation_policy_id, enabled, created_at, updated_at
+		FROM anomaly_configs
+		WHERE app_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, appID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	return r.scanConfigs(rows)
+}
+
 // RecordAnomalyEvent records a detected anomaly event.
 func (r *AnomalyConfigRepository) RecordAnomalyEvent(ctx context.Context, event *AnomalyEvent) error {
 	query := `
-		INSERT INTO anomaly_events (anomaly_config_id, app_id, event_category, event_type, detection_type, details, event_data)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO anomaly_events (anomaly_config_id, app_id, event_category, event_type, detection_type, details, event_data,
+		                            escalation_policy_id, next_escalation_at, suppressed, maintenance_window_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING id, created_at
 	`
 
@@ -287,13 +372,17 @@ func (r *AnomalyConfigRepository) RecordAnomalyEvent(ctx context.Context, event
 		event.DetectionType,
 		event.Details,
 		event.EventData,
+		event.EscalationPolicyID,
+		event.NextEscalationAt,
+		event.Suppressed,
+		event.MaintenanceWindowID,
 	).Scan(&event.ID, &event.CreatedAt)
 }
 
 // GetAnomalyEvents retrieves anomaly events for a config with pagination.
 func (r *AnomalyConfigRepository) GetAnomalyEvents(ctx context.Context, configID string, limit, offset int) ([]*AnomalyEvent, error) {
 	query := `
-		SELECT id, anomaly_config_id, app_id, event_category, event_type, detection_type, details, event_data, created_at
+		SELECT ` + anomalyEventColumns + `
 		FROM anomaly_events
 		WHERE anomaly_config_id = $1
 		ORDER BY created_at DESC
@@ -306,28 +395,136 @@ func (r *AnomalyConfigRepository) GetAnomalyEvents(ctx context.Context, configID
 	}
 	defer func() { _ = rows.Close() }()
 
+	return scanAnomalyEvents(rows)
+}
+
+// anomalyEventColumns are the anomaly_events columns read by
+// scanAnomalyEvent.
+const anomalyEventColumns = `id, anomaly_config_id, app_id, event_category, event_type, detection_type, details, event_data,
+		       escalation_policy_id, escalation_step, next_escalation_at, acknowledged_at, acknowledged_by,
+		       suppressed, maintenance_window_id, created_at`
+
+// scanAnomalyEvent scans one anomaly event row.
+func scanAnomalyEvent(row rowScanner) (*AnomalyEvent, error) {
+	event := &AnomalyEvent{}
+	if err := row.Scan(
+		&event.ID,
+		&event.AnomalyConfigID,
+		&event.AppID,
+		&event.EventCategory,
+		&event.EventType,
+		&event.DetectionType,
+		&event.Details,
+		&event.EventData,
+		&event.EscalationPolicyID,
+		&event.EscalationStep,
+		&event.NextEscalationAt,
+		&event.AcknowledgedAt,
+		&event.AcknowledgedBy,
+		&event.Suppressed,
+		&event.MaintenanceWindowID,
+		&event.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// scanAnomalyEvents scans multiple anomaly events from rows.
+func scanAnomalyEvents(rows *sql.Rows) ([]*AnomalyEvent, error) {
 	var events []*AnomalyEvent
 	for rows.Next() {
-		event := &AnomalyEvent{}
-		if err := rows.Scan(
-			&event.ID,
-			&event.AnomalyConfigID,
-			&event.AppID,
-			&event.EventCategory,
-			&event.EventType,
-			&event.DetectionType,
-			&event.Details,
-			&event.EventData,
-			&event.CreatedAt,
-		); err != nil {
+		event, err := scanAnomalyEvent(rows)
+		if err != nil {
 			return nil, err
 		}
 		events = append(events, event)
 	}
-
 	return events, rows.Err()
 }
 
+// GetAnomalyEvent retrieves an anomaly event by ID.
+func (r *AnomalyConfigRepository) GetAnomalyEvent(ctx context.Context, id string) (*AnomalyEvent, error) {
+	query := `SELECT ` + anomalyEventColumns + ` FROM anomaly_events WHERE id = $1`
+
+	event, err := scanAnomalyEvent(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAnomalyEventNotFound
+		}
+		return nil, err
+	}
+	return event, nil
+}
+
+// AcknowledgeAnomalyEvent records that an anomaly event was acknowledged by
+// by, stopping its escalation. It returns ErrAlreadyAcknowledged, with the
+// event, if it was acknowledged before.
+func (r *AnomalyConfigRepository) AcknowledgeAnomalyEvent(ctx context.Context, id, by string) (*AnomalyEvent, error) {
+	query := `
+		UPDATE anomaly_events
+		SET acknowledged_at = NOW(), acknowledged_by = $2, next_escalation_at = NULL
+		WHERE id = $1 AND acknowledged_at IS NULL
+		RETURNING ` + anomalyEventColumns
+
+	event, err := scanAnomalyEvent(r.db.QueryRowContext(ctx, query, id, nullIfEmpty(by)))
+	if errors.Is(err, sql.ErrNoRows) {
+		event, err = r.GetAnomalyEvent(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return event, ErrAlreadyAcknowledged
+	}
+	return event, err
+}
+
+// GetDueEscalations retrieves unacknowledged anomaly events whose next
+// escalation step is due at now, oldest first.
+func (r *AnomalyConfigRepository) GetDueEscalations(ctx context.Context, now time.Time, limit int) ([]*AnomalyEvent, error) {
+	query := `
+		SELECT ` + anomalyEventColumns + `
+		FROM anomaly_events
+		WHERE next_escalation_at <= $1 AND acknowledged_at IS NULL
+		ORDER BY next_escalation_at
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	return scanAnomalyEvents(rows)
+}
+
+// AdvanceEscalation moves an unacknowledged anomaly event from escalation
+// step fromStep to step fromStep+1, due at nextAt (nil when no step is
+// left), or only reschedules it when advance is false. It reports false
+// when the event was acknowledged or moved on concurrently.
+func (r *AnomalyConfigRepository) AdvanceEscalation(ctx context.Context, id string, fromStep int, advance bool, nextAt *time.Time) (bool, error) {
+	step := fromStep
+	if advance {
+		step++
+	}
+
+	query := `
+		UPDATE anomaly_events
+		SET escalation_step = $3, next_escalation_at = $4
+		WHERE id = $1 AND escalation_step = $2 AND acknowledged_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, fromStep, step, nextAt)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
 // GetOrCreateState gets or creates an anomaly state record.
 func (r *AnomalyConfigRepository) GetOrCreateState(ctx context.Context, configID, appID, windowKey string) (*AnomalyState, error) {
 	// Try to insert, on conflict return existing
@@ -336,7 +533,7 @@ func (r *AnomalyConfigRepository) GetOrCreateState(ctx context.Context, configID
 		VALUES ($1, $2, $3, 0)
 		ON CONFLICT (anomaly_config_id, app_id, window_key)
 		DO UPDATE SET updated_at = NOW()
-		RETURNING id, anomaly_config_id, app_id, window_key, event_count, last_alert_at, created_at, updated_at
+		RETURNING id, anomaly_config_id, app_id, window_key, event_count, value_sum, last_alert_at, created_at, updated_at
 	`
 
 	state := &AnomalyState{}
@@ -346,6 +543,7 @@ func (r *AnomalyConfigRepository) GetOrCreateState(ctx context.Context, configID
 		&state.AppID,
 		&state.WindowKey,
 		&state.EventCount,
+		&state.ValueSum,
 		&state.LastAlertAt,
 		&state.CreatedAt,
 		&state.UpdatedAt,
@@ -376,12 +574,91 @@ func (r *AnomalyConfigRepository) IncrementStateCount(ctx context.Context, confi
 	return count, nil
 }
 
-// UpdateLastAlertAt updates the last alert time for a state.
+// AddStateValue adds value to a window's sum, counts the event, and returns
+// the new sum.
+func (r *AnomalyConfigRepository) AddStateValue(ctx context.Context, configID, appID, windowKey string, value float64) (float64, error) {
+	query := `
+		INSERT INTO anomaly_state (anomaly_config_id, app_id, window_key, event_count, value_sum)
+		VALUES ($1, $2, $3, 1, $4)
+		ON CONFLICT (anomaly_config_id, app_id, window_key)
+		DO UPDATE SET event_count = anomaly_state.event_count + 1,
+		              value_sum = anomaly_state.value_sum + EXCLUDED.value_sum,
+		              updated_at = NOW()
+		RETURNING value_sum
+	`
+
+	var sum float64
+	if err := r.db.QueryRowContext(ctx, query, configID, appID, windowKey, value).Scan(&sum); err != nil {
+		return 0, err
+	}
+
+	return sum, nil
+}
+
+// GetStateSums returns the value sums of an app's windows. Windows without
+// state are omitted.
+func (r *AnomalyConfigRepository) GetStateSums(ctx context.Context, configID, appID string, windowKeys []string) (map[string]float64, error) {
+	query := `
+		SELECT window_key, value_sum
+		FROM anomaly_state
+		WHERE anomaly_config_id = $1 AND app_id = $2 AND window_key = ANY($3)
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, configID, appID, windowKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sums := make(map[string]float64, len(windowKeys))
+	for rows.Next() {
+		var key string
+		var sum float64
+		if err := rows.Scan(&key, &sum); err != nil {
+			return nil, err
+		}
+		sums[key] = sum
+	}
+
+	return sums, rows.Err()
+}
+
+// GetStateApps returns the apps with state in any of the given windows.
+func (r *AnomalyConfigRepository) GetStateApps(ctx context.Context, configID string, windowKeys []string) ([]string, error) {
+	query := `
+		SELECT DISTINCT app_id
+		FROM anomaly_state
+		WHERE anomaly_config_id = $1 AND window_key = ANY($2)
+		ORDER BY app_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, configID, windowKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var apps []string
+	for rows.Next() {
+		var appID string
+		if err := rows.Scan(&appID); err != nil {
+			return nil, err
+		}
+		apps = append(apps, appID)
+	}
+
+	return apps, rows.Err()
+}
+
+// UpdateLastAlertAt records an alert for a config and app in the given
+// window, creating the window's state if needed so the cooldown applies to
+// every detection type.
 func (r *AnomalyConfigRepository) UpdateLastAlertAt(ctx context.Context, configID, appID, windowKey string) error {
 	query := `
-		UPDATE anomaly_state
-		SET last_alert_at = NOW()
-		WHERE anomaly_config_id = $1 AND app_id = $2 AND window_key = $3
+		INSERT INTO anomaly_state (anomaly_config_id, app_id, window_key, last_alert_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (anomaly_config_id, app_id, window_key)
+		DO UPDATE SET last_alert_at = NOW(), updated_at = NOW()
 	`
 
 	_, err := r.db.ExecContext(ctx, query, configID, appID, windowKey)
@@ -413,14 +690,33 @@ func (r *AnomalyConfigRepository) GetLastAlertAt(ctx context.Context, configID,
 	return lastAlertAt, nil
 }
 
-// CleanupOldState deletes old state records.
+// RevenueWindowPrefix prefixes the window keys of revenue state, which is
+// kept longer than other state to serve as the revenue baseline.
+const RevenueWindowPrefix = "revenue|"
+
+// CleanupOldState deletes old state records, except revenue windows.
 func (r *AnomalyConfigRepository) CleanupOldState(ctx context.Context, olderThan time.Time) (int64, error) {
 	query := `
 		DELETE FROM anomaly_state
-		WHERE updated_at < $1
+		WHERE updated_at < $1 AND window_key NOT LIKE $2
 	`
 
-	result, err := r.db.ExecContext(ctx, query, olderThan)
+	result, err := r.db.ExecContext(ctx, query, olderThan, RevenueWindowPrefix+"%")
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// CleanupOldRevenueState deletes old revenue window records.
+func (r *AnomalyConfigRepository) CleanupOldRevenueState(ctx context.Context, olderThan time.Time) (int64, error) {
+	query := `
+		DELETE FROM anomaly_state
+		WHERE updated_at < $1 AND window_key LIKE $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, olderThan, RevenueWindowPrefix+"%")
 	if err != nil {
 		return 0, err
 	}
@@ -442,3 +738,68 @@ func (r *AnomalyConfigRepository) CleanupOldEvents(ctx context.Context, olderTha
 
 	return result.RowsAffected()
 }
+
+// ListOldEvents retrieves up to limit anomaly events created before
+// olderThan, oldest first, for archival ahead of DeleteEvents.
+func (r *AnomalyConfigRepository) ListOldEvents(ctx context.Context, olderThan time.Time, limit int) ([]*AnomalyEvent, error) {
+	query := `
+		SELECT ` + anomalyEventColumns + `
+		FROM anomaly_events
+		WHERE created_at < $1
+		ORDER BY created_at, id
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, olderThan, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	return scanAnomalyEvents(rows)
+}
+
+// DeleteEvents deletes anomaly events by ID.
+func (r *AnomalyConfigRepository) DeleteEvents(ctx context.Context, ids []string) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM anomaly_events WHERE id = ANY($1::text[]::uuid[])`, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// GetBaselines retrieves the learned baselines for hours in [from, to).
+func (r *AnomalyConfigRepository) GetBaselines(ctx context.Context, from, to time.Time) ([]*AnomalyBaseline, error) {
+	query := `
+		SELECT app_id, event_category, event_type, hour, expected, lower_bound, upper_bound, computed_at
+		FROM anomaly_baselines
+		WHERE hour >= $1 AND hour < $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var baselines []*AnomalyBaseline
+	for rows.Next() {
+		b := &AnomalyBaseline{}
+		if err := rows.Scan(
+			&b.AppID,
+			&b.EventCategory,
+			&b.EventType,
+			&b.Hour,
+			&b.Expected,
+			&b.Lower,
+			&b.Upper,
+			&b.ComputedAt,
+		); err != nil {
+			return nil, err
+		}
+		baselines = append(baselines, b)
+	}
+
+	return baselines, rows.Err()
+}
@@ -9,11 +9,21 @@ import (
 	"log/slog"
 	"time"
 
-	_ "github.com/lib/pq" // PostgreSQL driver
+	"github.com/jackc/pgx/v5"
+
+	pgdb "github.com/SebastienMelki/causality/internal/db"
+	"github.com/SebastienMelki/causality/internal/reaction/db/migrations"
 )
 
+// RuleChangeChannel is the NOTIFY channel signalled by the trigger on the
+// rules table whenever a rule is inserted, updated, or deleted.
+const RuleChangeChannel = "rule_changes"
+
 // ErrDatabaseConnection indicates a database connection error.
-var ErrDatabaseConnection = errors.New("database connection error")
+var ErrDatabaseConnection = pgdb.ErrConnection
+
+// Migrations are the reaction engine's core schema migrations.
+var Migrations = pgdb.Source{Name: "reaction", FS: migrations.FS}
 
 // Config holds PostgreSQL connection settings.
 type Config struct {
@@ -38,16 +48,47 @@ type Config struct {
 	// MaxOpenConns is the maximum number of open connections
 	MaxOpenConns int `env:"MAX_OPEN_CONNS" envDefault:"25"`
 
-	// MaxIdleConns is the maximum number of idle connections
-	MaxIdleConns int `env:"MAX_IDLE_CONNS" envDefault:"5"`
+	// MinIdleConns is the number of idle connections the pool keeps open
+	// to serve bursts without connecting
+	MinIdleConns int `env:"MIN_IDLE_CONNS" envDefault:"2"`
 
 	// ConnMaxLifetime is the maximum connection lifetime
 	ConnMaxLifetime time.Duration `env:"CONN_MAX_LIFETIME" envDefault:"5m"`
+
+	// ConnMaxIdleTime is how long a connection may stay idle before it is
+	// closed
+	ConnMaxIdleTime time.Duration `env:"CONN_MAX_IDLE_TIME" envDefault:"1m"`
+
+	// Migrate applies pending migrations at startup
+	Migrate bool `env:"MIGRATE" envDefault:"true"`
+
+	// Replica is an optional read replica for rule and anomaly config
+	// refreshes and delivery status reads
+	Replica ReplicaConfig `envPrefix:"REPLICA_"`
+}
+
+// pool returns the shared pool configuration of c.
+func (c Config) pool() pgdb.Config {
+	return pgdb.Config{
+		Host:            c.Host,
+		Port:            c.Port,
+		User:            c.User,
+		Password:        c.Password,
+		Name:            c.Name,
+		SSLMode:         c.SSLMode,
+		MaxOpenConns:    c.MaxOpenConns,
+		MinIdleConns:    c.MinIdleConns,
+		ConnMaxLifetime: c.ConnMaxLifetime,
+		ConnMaxIdleTime: c.ConnMaxIdleTime,
+		Migrate:         c.Migrate,
+	}
 }
 
 // Client provides database access for the reaction engine.
 type Client struct {
+	pool   *pgdb.Pool
 	db     *sql.DB
+	read   *readRouter
 	logger *slog.Logger
 }
 
@@ -58,42 +99,30 @@ func NewClient(ctx context.Context, cfg Config, logger *slog.Logger) (*Client, e
 	}
 	logger = logger.With("component", "reaction-db")
 
-	dsn := fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name, cfg.SSLMode,
-	)
-
-	db, err := sql.Open("postgres", dsn)
+	pool, err := pgdb.Open(ctx, cfg.pool(), logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
 	}
 
-	// Configure connection pool
-	db.SetMaxOpenConns(cfg.MaxOpenConns)
-	db.SetMaxIdleConns(cfg.MaxIdleConns)
-	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
-
-	// Verify connection
-	if err := db.PingContext(ctx); err != nil {
-		_ = db.Close()
-		return nil, fmt.Errorf("%w: %w", ErrDatabaseConnection, err)
+	read := newReadRouter(pool.DB, logger)
+	if cfg.Replica.Host != "" {
+		if err := read.openReplica(cfg); err != nil {
+			_ = pool.Close()
+			return nil, err
+		}
 	}
 
-	logger.Info("connected to database",
-		"host", cfg.Host,
-		"port", cfg.Port,
-		"database", cfg.Name,
-	)
-
 	return &Client{
-		db:     db,
+		pool:   pool,
+		db:     pool.DB,
+		read:   read,
 		logger: logger,
 	}, nil
 }
 
-// Close closes the database connection.
+// Close closes the database connections.
 func (c *Client) Close() error {
-	return c.db.Close()
+	return errors.Join(c.read.close(), c.pool.Close())
 }
 
 // DB returns the underlying database connection for use by repository structs.
@@ -106,7 +135,98 @@ func (c *Client) Ping(ctx context.Context) error {
 	return c.db.PingContext(ctx)
 }
 
+// HealthCheck reports whether the database answers a ping in time.
+func (c *Client) HealthCheck(ctx context.Context) error {
+	return c.pool.HealthCheck(ctx)
+}
+
+// Migrate applies the pending migrations of sources, in order, unless the
+// configuration disables migrations.
+func (c *Client) Migrate(ctx context.Context, sources ...pgdb.Source) error {
+	return c.pool.Migrate(ctx, sources...)
+}
+
 // BeginTx starts a new transaction.
 func (c *Client) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
 	return c.db.BeginTx(ctx, opts)
 }
+
+// ListenRuleChanges opens a dedicated LISTEN connection on RuleChangeChannel.
+// The returned channel receives a value after rules change and after the
+// listener reconnects (when notifications may have been missed). Rapid
+// changes are coalesced. Each notification also pins reads to the primary
+// for a while, so the refresh it triggers sees the change even when a read
+// replica has not replayed it yet. The listener is closed when ctx is
+// cancelled.
+func (c *Client) ListenRuleChanges(ctx context.Context) (<-chan struct{}, error) {
+	conn, err := c.listen(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(chan struct{}, 1)
+	notify := func() {
+		c.read.pinPrimary()
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+
+	go func() {
+		backoff := listenMinBackoff
+		for {
+			_, err := conn.WaitForNotification(ctx)
+			if err == nil {
+				notify()
+				continue
+			}
+			_ = conn.Close(context.Background())
+			if ctx.Err() != nil {
+				return
+			}
+			c.logger.Warn("rule change listener disconnected", "error", err)
+
+			// Reconnect with backoff; a refresh follows since notifications
+			// may have been missed
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+				conn, err = c.listen(ctx)
+				if err == nil {
+					break
+				}
+				c.logger.Warn("rule change listener connection attempt failed", "error", err)
+				backoff = min(backoff*2, listenMaxBackoff)
+			}
+			backoff = listenMinBackoff
+			c.logger.Info("rule change listener reconnected")
+			notify()
+		}
+	}()
+
+	return changes, nil
+}
+
+// Reconnection backoff bounds of the rule change listener.
+const (
+	listenMinBackoff = time.Second
+	listenMaxBackoff = time.Minute
+)
+
+// listen opens a connection outside the pool, with the pool's settings, and
+// listens on RuleChangeChannel.
+func (c *Client) listen(ctx context.Context) (*pgx.Conn, error) {
+	conn, err := pgx.ConnectConfig(ctx, c.pool.Pgx().Config().ConnConfig.Copy())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect rule change listener: %w", err)
+	}
+	if _, err := conn.Exec(ctx, "LISTEN "+RuleChangeChannel); err != nil {
+		_ = conn.Close(context.Background())
+		return nil, fmt.Errorf("failed to listen on %s: %w", RuleChangeChannel, err)
+	}
+	return conn, nil
+}
@@ -10,7 +10,8 @@ import (
 
 // Sentinel errors for deliveries.
 var (
-	ErrDeliveryNotFound = errors.New("delivery not found")
+	ErrDeliveryNotFound  = errors.New("delivery not found")
+	ErrDuplicateDelivery = errors.New("delivery already exists for idempotency key")
 )
 
 // DeliveryStatus represents the status of a webhook delivery.
@@ -24,12 +25,17 @@ const (
 	DeliveryStatusDeadLetter DeliveryStatus = "dead_letter"
 )
 
-// WebhookDelivery represents a webhook delivery attempt.
+// WebhookDelivery represents a webhook delivery attempt. IdempotencyKey
+// identifies the side effect it performs: at most one delivery per webhook
+// exists for a key, so a redelivered event does not queue it twice.
+// Deliveries without a key are not deduplicated.
 type WebhookDelivery struct {
 	ID              string          `json:"id"`
 	WebhookID       string          `json:"webhook_id"`
 	RuleID          *string         `json:"rule_id,omitempty"`
+	RuleVersion     *int            `json:"rule_version,omitempty"`
 	AnomalyConfigID *string         `json:"anomaly_config_id,omitempty"`
+	IdempotencyKey  *string         `json:"idempotency_key,omitempty"`
 	Payload         json.RawMessage `json:"payload"`
 	Status          DeliveryStatus  `json:"status"`
 	Attempts        int             `json:"attempts"`
@@ -42,91 +48,139 @@ type WebhookDelivery struct {
 	DeliveredAt     *time.Time      `json:"delivered_at,omitempty"`
 }
 
-// DeliveryRepository provides CRUD operations for webhook deliveries.
+// DeliveryRepository provides CRUD operations for webhook deliveries. Status
+// reads (GetByID, List, GetDeadLettered, GetStats) use the read replica when
+// one is caught up; the dispatcher's claim and update queries always use the
+// primary.
 type DeliveryRepository struct {
-	db *sql.DB
+	db   *sql.DB
+	read *readRouter
 }
 
 // NewDeliveryRepository creates a new delivery repository.
 func NewDeliveryRepository(client *Client) *DeliveryRepository {
-	return &DeliveryRepository{db: client.DB()}
+	return &DeliveryRepository{db: client.DB(), read: client.read}
 }
 
-// Create creates a new delivery.
+// insertDeliveryQuery inserts a delivery unless one already exists for its
+// webhook and idempotency key, in which case no row is returned.
+const insertDeliveryQuery = `
+	INSERT INTO webhook_deliveries (webhook_id, rule_id, rule_version, anomaly_config_id, idempotency_key, payload, status, max_attempts, next_attempt_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	ON CONFLICT (webhook_id, idempotency_key) WHERE idempotency_key IS NOT NULL DO NOTHING
+	RETURNING id, created_at
+`
+
+// Create creates a new delivery. It returns ErrDuplicateDelivery if a
+// delivery with the same webhook and idempotency key exists.
 func (r *DeliveryRepository) Create(ctx context.Context, delivery *WebhookDelivery) error {
-	query := `
-		INSERT INTO webhook_deliveries (webhook_id, rule_id, anomaly_config_id, payload, status, max_attempts, next_attempt_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, created_at
-	`
-
-	return r.db.QueryRowContext(
-		ctx, query,
+	err := r.db.QueryRowContext(
+		ctx, insertDeliveryQuery,
 		delivery.WebhookID,
 		delivery.RuleID,
+		delivery.RuleVersion,
 		delivery.AnomalyConfigID,
+		delivery.IdempotencyKey,
 		delivery.Payload,
 		delivery.Status,
 		delivery.MaxAttempts,
 		delivery.NextAttemptAt,
 	).Scan(&delivery.ID, &delivery.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrDuplicateDelivery
+	}
+	return err
 }
 
-// CreateBatch creates multiple deliveries in a single transaction.
-func (r *DeliveryRepository) CreateBatch(ctx context.Context, deliveries []*WebhookDelivery) error {
+// CreateBatch creates multiple deliveries in a single transaction and
+// returns how many were created. Deliveries whose webhook and idempotency
+// key already have one are skipped and keep an empty ID.
+func (r *DeliveryRepository) CreateBatch(ctx context.Context, deliveries []*WebhookDelivery) (int, error) {
 	if len(deliveries) == 0 {
-		return nil
+		return 0, nil
 	}
 
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	stmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO webhook_deliveries (webhook_id, rule_id, anomaly_config_id, payload, status, max_attempts, next_attempt_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, created_at
-	`)
+	stmt, err := tx.PrepareContext(ctx, insertDeliveryQuery)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer func() { _ = stmt.Close() }()
 
+	created := 0
 	for _, delivery := range deliveries {
 		err := stmt.QueryRowContext(
 			ctx,
 			delivery.WebhookID,
 			delivery.RuleID,
+			delivery.RuleVersion,
 			delivery.AnomalyConfigID,
+			delivery.IdempotencyKey,
 			delivery.Payload,
 			delivery.Status,
 			delivery.MaxAttempts,
 			delivery.NextAttemptAt,
 		).Scan(&delivery.ID, &delivery.CreatedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
 		if err != nil {
-			return err
+			return 0, err
 		}
+		created++
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return created, nil
 }
 
-// GetPending retrieves pending deliveries ready for processing.
-func (r *DeliveryRepository) GetPending(ctx context.Context, limit int) ([]*WebhookDelivery, error) {
+// GetPending retrieves pending deliveries ready for processing that have
+// been due for at least overdue. A non-zero overdue leaves recently due
+// deliveries to the delivery queue's consumers.
+func (r *DeliveryRepository) GetPending(ctx context.Context, overdue time.Duration, limit int) ([]*WebhookDelivery, error) {
 	query := `
-		SELECT id, webhook_id, rule_id, anomaly_config_id, payload, status, attempts, max_attempts,
+		SELECT id, webhook_id, rule_id, rule_version, anomaly_config_id, idempotency_key, payload, status, attempts, max_attempts,
 		       next_attempt_at, last_attempt_at, last_error, last_status_code, created_at, delivered_at
 		FROM webhook_deliveries
 		WHERE status IN ('pending', 'in_progress')
+		  AND next_attempt_at <= NOW() - make_interval(secs => $1)
+		ORDER BY next_attempt_at ASC
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, overdue.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	return r.scanDeliveries(rows)
+}
+
+// GetPendingByIDs retrieves the deliveries among ids that are ready for
+// processing, for IDs consumed from the delivery queue. It reads the primary,
+// since the deliveries were usually created moments ago.
+func (r *DeliveryRepository) GetPendingByIDs(ctx context.Context, ids []string) ([]*WebhookDelivery, error) {
+	query := `
+		SELECT id, webhook_id, rule_id, rule_version, anomaly_config_id, idempotency_key, payload, status, attempts, max_attempts,
+		       next_attempt_at, last_attempt_at, last_error, last_status_code, created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE id = ANY($1::text[]::uuid[])
+		  AND status IN ('pending', 'in_progress')
 		  AND next_attempt_at <= NOW()
 		ORDER BY next_attempt_at ASC
-		LIMIT $1
 		FOR UPDATE SKIP LOCKED
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, limit)
+	rows, err := r.db.QueryContext(ctx, query, ids)
 	if err != nil {
 		return nil, err
 	}
@@ -138,18 +192,20 @@ func (r *DeliveryRepository) GetPending(ctx context.Context, limit int) ([]*Webh
 // GetByID retrieves a delivery by ID.
 func (r *DeliveryRepository) GetByID(ctx context.Context, id string) (*WebhookDelivery, error) {
 	query := `
-		SELECT id, webhook_id, rule_id, anomaly_config_id, payload, status, attempts, max_attempts,
+		SELECT id, webhook_id, rule_id, rule_version, anomaly_config_id, idempotency_key, payload, status, attempts, max_attempts,
 		       next_attempt_at, last_attempt_at, last_error, last_status_code, created_at, delivered_at
 		FROM webhook_deliveries
 		WHERE id = $1
 	`
 
 	delivery := &WebhookDelivery{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := r.read.reader().QueryRowContext(ctx, query, id).Scan(
 		&delivery.ID,
 		&delivery.WebhookID,
 		&delivery.RuleID,
+		&delivery.RuleVersion,
 		&delivery.AnomalyConfigID,
+		&delivery.IdempotencyKey,
 		&delivery.Payload,
 		&delivery.Status,
 		&delivery.Attempts,
@@ -180,7 +236,9 @@ func (r *DeliveryRepository) scanDeliveries(rows *sql.Rows) ([]*WebhookDelivery,
 			&delivery.ID,
 			&delivery.WebhookID,
 			&delivery.RuleID,
+			&delivery.RuleVersion,
 			&delivery.AnomalyConfigID,
+			&delivery.IdempotencyKey,
 			&delivery.Payload,
 			&delivery.Status,
 			&delivery.Attempts,
@@ -282,7 +340,7 @@ func (r *DeliveryRepository) MarkFailed(ctx context.Context, id string, statusCo
 // GetDeadLettered retrieves dead-lettered deliveries for review.
 func (r *DeliveryRepository) GetDeadLettered(ctx context.Context, limit, offset int) ([]*WebhookDelivery, error) {
 	query := `
-		SELECT id, webhook_id, rule_id, anomaly_config_id, payload, status, attempts, max_attempts,
+		SELECT id, webhook_id, rule_id, rule_version, anomaly_config_id, idempotency_key, payload, status, attempts, max_attempts,
 		       next_attempt_at, last_attempt_at, last_error, last_status_code, created_at, delivered_at
 		FROM webhook_deliveries
 		WHERE status = 'dead_letter'
@@ -290,7 +348,7 @@ func (r *DeliveryRepository) GetDeadLettered(ctx context.Context, limit, offset
 		LIMIT $1 OFFSET $2
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	rows, err := r.read.reader().QueryContext(ctx, query, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -299,6 +357,53 @@ func (r *DeliveryRepository) GetDeadLettered(ctx context.Context, limit, offset
 	return r.scanDeliveries(rows)
 }
 
+// DeliveryFilter narrows a delivery listing. Empty fields match everything.
+type DeliveryFilter struct {
+	WebhookID       string
+	RuleID          string
+	AnomalyConfigID string
+	Status          DeliveryStatus
+}
+
+// List retrieves deliveries matching the filter, newest first, with
+// pagination.
+func (r *DeliveryRepository) List(ctx context.Context, filter DeliveryFilter, limit, offset int) ([]*WebhookDelivery, error) {
+	query := `
+		SELECT id, webhook_id, rule_id, rule_version, anomaly_config_id, idempotency_key, payload, status, attempts, max_attempts,
+		       next_attempt_at, last_attempt_at, last_error, last_status_code, created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE ($1::uuid IS NULL OR webhook_id = $1)
+		  AND ($2::uuid IS NULL OR rule_id = $2)
+		  AND ($3::uuid IS NULL OR anomaly_config_id = $3)
+		  AND ($4::text IS NULL OR status = $4)
+		ORDER BY created_at DESC
+		LIMIT $5 OFFSET $6
+	`
+
+	rows, err := r.read.reader().QueryContext(ctx, query,
+		nullIfEmpty(filter.WebhookID),
+		nullIfEmpty(filter.RuleID),
+		nullIfEmpty(filter.AnomalyConfigID),
+		nullIfEmpty(string(filter.Status)),
+		limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	return r.scanDeliveries(rows)
+}
+
+// nullIfEmpty returns nil for an empty string so optional filters bind as
+// SQL NULL.
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
 // Retry resets a dead-lettered delivery for retry.
 func (r *DeliveryRepository) Retry(ctx context.Context, id string) error {
 	query := `
@@ -339,6 +444,38 @@ func (r *DeliveryRepository) DeleteOld(ctx context.Context, olderThan time.Time)
 	return result.RowsAffected()
 }
 
+// ListOld retrieves up to limit delivered or dead-lettered deliveries created
+// before olderThan, oldest first, for archival ahead of DeleteByIDs.
+func (r *DeliveryRepository) ListOld(ctx context.Context, olderThan time.Time, limit int) ([]*WebhookDelivery, error) {
+	query := `
+		SELECT id, webhook_id, rule_id, rule_version, anomaly_config_id, idempotency_key, payload, status, attempts, max_attempts,
+		       next_attempt_at, last_attempt_at, last_error, last_status_code, created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE status IN ('delivered', 'dead_letter')
+		  AND created_at < $1
+		ORDER BY created_at, id
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, olderThan, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	return r.scanDeliveries(rows)
+}
+
+// DeleteByIDs deletes deliveries by ID.
+func (r *DeliveryRepository) DeleteByIDs(ctx context.Context, ids []string) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE id = ANY($1::text[]::uuid[])`, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
 // GetStats retrieves delivery statistics.
 func (r *DeliveryRepository) GetStats(ctx context.Context) (map[string]int64, error) {
 	query := `
@@ -347,7 +484,7 @@ func (r *DeliveryRepository) GetStats(ctx context.Context) (map[string]int64, er
 		GROUP BY status
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.read.reader().QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Sentinel errors for escalation policies.
+var (
+	ErrEscalationPolicyNotFound = errors.New("escalation policy not found")
+)
+
+// EscalationStep notifies webhooks once an alert has been unacknowledged
+// for AfterMinutes.
+type EscalationStep struct {
+	AfterMinutes int      `json:"after_minutes"`
+	Webhooks     []string `json:"webhooks"`
+}
+
+// EscalationPolicy is an ordered list of escalation steps. The steps of an
+// alert stop when it is acknowledged.
+type EscalationPolicy struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	Description *string          `json:"description,omitempty"`
+	Steps       []EscalationStep `json:"steps"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// EscalationPolicyRepository provides CRUD operations for escalation
+// policies.
+type EscalationPolicyRepository struct {
+	db *sql.DB
+}
+
+// NewEscalationPolicyRepository creates a new escalation policy repository.
+func NewEscalationPolicyRepository(client *Client) *EscalationPolicyRepository {
+	return &EscalationPolicyRepository{db: client.DB()}
+}
+
+// Create creates a new escalation policy.
+func (r *EscalationPolicyRepository) Create(ctx context.Context, policy *EscalationPolicy) error {
+	stepsJSON, err := json.Marshal(policy.Steps)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO escalation_policies (name, description, steps)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at
+	`
+
+	return r.db.QueryRowContext(ctx, query, policy.Name, policy.Description, stepsJSON).
+		Scan(&policy.ID, &policy.CreatedAt, &policy.UpdatedAt)
+}
+
+// GetByID retrieves an escalation policy by ID.
+func (r *EscalationPolicyRepository) GetByID(ctx context.Context, id string) (*EscalationPolicy, error) {
+	query := `
+		SELECT id, name, description, steps, created_at, updated_at
+		FROM escalation_policies
+		WHERE id = $1
+	`
+
+	policy, err := scanEscalationPolicy(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrEscalationPolicyNotFound
+		}
+		return nil, err
+	}
+	return policy, nil
+}
+
+// List retrieves escalation policies with pagination.
+func (r *EscalationPolicyRepository) List(ctx context.Context, limit, offset int) ([]*EscalationPolicy, error) {
+	query := `
+		SELECT id, name, description, steps, created_at, updated_at
+		FROM escalation_policies
+		ORDER BY name
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var policies []*EscalationPolicy
+	for rows.Next() {
+		policy, err := scanEscalationPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	return policies, rows.Err()
+}
+
+// Update updates an escalation policy.
+func (r *EscalationPolicyRepository) Update(ctx context.Context, policy *EscalationPolicy) error {
+	stepsJSON, err := json.Marshal(policy.Steps)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE escalation_policies
+		SET name = $1, description = $2, steps = $3
+		WHERE id = $4
+		RETURNING updated_at
+	`
+
+	err = r.db.QueryRowContext(ctx, query, policy.Name, policy.Description, stepsJSON, policy.ID).
+		Scan(&policy.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrEscalationPolicyNotFound
+	}
+	return err
+}
+
+// Delete deletes an escalation policy by ID. Anomaly configs using it stop
+// escalating.
+func (r *EscalationPolicyRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM escalation_policies WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrEscalationPolicyNotFound
+	}
+	return nil
+}
+
+// scanEscalationPolicy scans one escalation policy row.
+func scanEscalationPolicy(row rowScanner) (*EscalationPolicy, error) {
+	policy := &EscalationPolicy{}
+	var stepsJSON []byte
+	if err := row.Scan(
+		&policy.ID,
+		&policy.Name,
+		&policy.Description,
+		&stepsJSON,
+		&policy.CreatedAt,
+		&policy.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(stepsJSON, &policy.Steps); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// HistoryRepository records when each device last sent the events that
+// history rule conditions reference. It keeps one row per app, device and
+// event, so its size is bounded by the devices active within the retention.
+type HistoryRepository struct {
+	db *sql.DB
+}
+
+// NewHistoryRepository creates a new event history repository.
+func NewHistoryRepository(client *Client) *HistoryRepository {
+	return &HistoryRepository{db: client.DB()}
+}
+
+// Record records that a device sent the event eventName, {category}.{type},
+// at seenAt. Earlier occurrences recorded out of order do not move the last
+// occurrence back.
+func (r *HistoryRepository) Record(ctx context.Context, appID, deviceID, eventName string, seenAt time.Time) error {
+	query := `
+		INSERT INTO event_history (app_id, device_id, event_name, last_seen_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (app_id, device_id, event_name)
+		DO UPDATE SET last_seen_at = GREATEST(event_history.last_seen_at, EXCLUDED.last_seen_at)
+	`
+
+	_, err := r.db.ExecContext(ctx, query, appID, deviceID, eventName, seenAt)
+	return err
+}
+
+// LastSeen returns when a device last sent each of the given events. Events
+// the device never sent, or not within the retention, are omitted.
+func (r *HistoryRepository) LastSeen(ctx context.Context, appID, deviceID string, eventNames []string) (map[string]time.Time, error) {
+	query := `
+		SELECT event_name, last_seen_at
+		FROM event_history
+		WHERE app_id = $1 AND device_id = $2 AND event_name = ANY($3)
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, appID, deviceID, eventNames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seen := make(map[string]time.Time, len(eventNames))
+	for rows.Next() {
+		var name string
+		var at time.Time
+		if err := rows.Scan(&name, &at); err != nil {
+			return nil, err
+		}
+		seen[name] = at
+	}
+
+	return seen, rows.Err()
+}
+
+// DeleteOld deletes occurrences last seen before olderThan.
+func (r *HistoryRepository) DeleteOld(ctx context.Context, olderThan time.Time) (int64, error) {
+	query := `
+		DELETE FROM event_history
+		WHERE last_seen_at < $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, olderThan)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
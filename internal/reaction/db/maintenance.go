@@ -0,0 +1,288 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Sentinel errors for maintenance windows.
+var (
+	ErrMaintenanceWindowNotFound = errors.New("maintenance window not found")
+)
+
+// MaintenanceWindow is a period during which rule actions and anomaly alerts
+// of one app, or of all apps when AppID is nil, are suppressed. Suppressed
+// rule matches and anomalies are still recorded.
+type MaintenanceWindow struct {
+	ID                string    `json:"id"`
+	Name              string    `json:"name"`
+	Description       *string   `json:"description,omitempty"`
+	AppID             *string   `json:"app_id,omitempty"`
+	StartsAt          time.Time `json:"starts_at"`
+	EndsAt            time.Time `json:"ends_at"`
+	SuppressRules     bool      `json:"suppress_rules"`
+	SuppressAnomalies bool      `json:"suppress_anomalies"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// Covers reports whether the window applies to appID at t.
+func (w *MaintenanceWindow) Covers(appID string, t time.Time) bool {
+	if w.AppID != nil && *w.AppID != appID {
+		return false
+	}
+	return !t.Before(w.StartsAt) && t.Before(w.EndsAt)
+}
+
+// SuppressedRuleMatch is a rule match whose actions a maintenance window
+// suppressed.
+type SuppressedRuleMatch struct {
+	ID                  string    `json:"id"`
+	RuleID              string    `json:"rule_id"`
+	RuleVersion         int       `json:"rule_version"`
+	MaintenanceWindowID *string   `json:"maintenance_window_id,omitempty"`
+	EventID             string    `json:"event_id"`
+	AppID               string    `json:"app_id"`
+	MatchedAt           time.Time `json:"matched_at"`
+}
+
+// MaintenanceWindowRepository provides CRUD operations for maintenance
+// windows and reads the matches and anomalies they suppressed.
+type MaintenanceWindowRepository struct {
+	db *sql.DB
+}
+
+// NewMaintenanceWindowRepository creates a new maintenance window repository.
+func NewMaintenanceWindowRepository(client *Client) *MaintenanceWindowRepository {
+	return &MaintenanceWindowRepository{db: client.DB()}
+}
+
+// maintenanceWindowColumns are the maintenance_windows columns read by
+// scanMaintenanceWindow.
+const maintenanceWindowColumns = `id, name, description, app_id, starts_at, ends_at, suppress_rules, suppress_anomalies, created_at, updated_at`
+
+// Create creates a new maintenance window.
+func (r *MaintenanceWindowRepository) Create(ctx context.Context, window *MaintenanceWindow) error {
+	query := `
+		INSERT INTO maintenance_windows (name, description, app_id, starts_at, ends_at, suppress_rules, suppress_anomalies)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at
+	`
+
+	return r.db.QueryRowContext(
+		ctx, query,
+		window.Name,
+		window.Description,
+		window.AppID,
+		window.StartsAt,
+		window.EndsAt,
+		window.SuppressRules,
+		window.SuppressAnomalies,
+	).Scan(&window.ID, &window.CreatedAt, &window.UpdatedAt)
+}
+
+// GetByID retrieves a maintenance window by ID.
+func (r *MaintenanceWindowRepository) GetByID(ctx context.Context, id string) (*MaintenanceWindow, error) {
+	query := `SELECT ` + maintenanceWindowColumns + ` FROM maintenance_windows WHERE id = $1`
+
+	window, err := scanMaintenanceWindow(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMaintenanceWindowNotFound
+		}
+		return nil, err
+	}
+	return window, nil
+}
+
+// List retrieves maintenance windows with pagination, latest start first.
+func (r *MaintenanceWindowRepository) List(ctx context.Context, limit, offset int) ([]*MaintenanceWindow, error) {
+	query := `
+		SELECT ` + maintenanceWindowColumns + `
+		FROM maintenance_windows
+		ORDER BY starts_at DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	return scanMaintenanceWindows(rows)
+}
+
+// ListUnfinished retrieves the active and upcoming maintenance windows: those
+// ending after now.
+func (r *MaintenanceWindowRepository) ListUnfinished(ctx context.Context, now time.Time) ([]*MaintenanceWindow, error) {
+	query := `
+		SELECT ` + maintenanceWindowColumns + `
+		FROM maintenance_windows
+		WHERE ends_at > $1
+		ORDER BY starts_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	return scanMaintenanceWindows(rows)
+}
+
+// Update updates a maintenance window.
+func (r *MaintenanceWindowRepository) Update(ctx context.Context, window *MaintenanceWindow) error {
+	query := `
+		UPDATE maintenance_windows
+		SET name = $1, description = $2, app_id = $3, starts_at = $4, ends_at = $5,
+		    suppress_rules = $6, suppress_anomalies = $7
+		WHERE id = $8
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx, query,
+		window.Name,
+		window.Description,
+		window.AppID,
+		window.StartsAt,
+		window.EndsAt,
+		window.SuppressRules,
+		window.SuppressAnomalies,
+		window.ID,
+	).Scan(&window.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrMaintenanceWindowNotFound
+	}
+	return err
+}
+
+// Delete deletes a maintenance window by ID. The matches and anomalies it
+// suppressed are kept.
+func (r *MaintenanceWindowRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM maintenance_windows WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrMaintenanceWindowNotFound
+	}
+	return nil
+}
+
+// RecordSuppressedMatch records a rule match suppressed by a maintenance
+// window.
+func (r *MaintenanceWindowRepository) RecordSuppressedMatch(ctx context.Context, match *SuppressedRuleMatch) error {
+	query := `
+		INSERT INTO rule_suppressed_matches (rule_id, rule_version, maintenance_window_id, event_id, app_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, matched_at
+	`
+
+	return r.db.QueryRowContext(
+		ctx, query,
+		match.RuleID,
+		match.RuleVersion,
+		match.MaintenanceWindowID,
+		match.EventID,
+		match.AppID,
+	).Scan(&match.ID, &match.MatchedAt)
+}
+
+// ListSuppressedMatches retrieves the rule matches a maintenance window
+// suppressed, newest first.
+func (r *MaintenanceWindowRepository) ListSuppressedMatches(ctx context.Context, windowID string, limit int) ([]*SuppressedRuleMatch, error) {
+	query := `
+		SELECT id, rule_id, rule_version, maintenance_window_id, event_id, app_id, matched_at
+		FROM rule_suppressed_matches
+		WHERE maintenance_window_id = $1
+		ORDER BY matched_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, windowID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var matches []*SuppressedRuleMatch
+	for rows.Next() {
+		match := &SuppressedRuleMatch{}
+		if err := rows.Scan(
+			&match.ID,
+			&match.RuleID,
+			&match.RuleVersion,
+			&match.MaintenanceWindowID,
+			&match.EventID,
+			&match.AppID,
+			&match.MatchedAt,
+		); err != nil {
+			return nil, err
+		}
+		matches = append(matches, match)
+	}
+	return matches, rows.Err()
+}
+
+// ListSuppressedAnomalies retrieves the anomaly events a maintenance window
+// suppressed, newest first.
+func (r *MaintenanceWindowRepository) ListSuppressedAnomalies(ctx context.Context, windowID string, limit int) ([]*AnomalyEvent, error) {
+	query := `
+		SELECT ` + anomalyEventColumns + `
+		FROM anomaly_events
+		WHERE maintenance_window_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, windowID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	return scanAnomalyEvents(rows)
+}
+
+// scanMaintenanceWindow scans one maintenance window row.
+func scanMaintenanceWindow(row rowScanner) (*MaintenanceWindow, error) {
+	window := &MaintenanceWindow{}
+	if err := row.Scan(
+		&window.ID,
+		&window.Name,
+		&window.Description,
+		&window.AppID,
+		&window.StartsAt,
+		&window.EndsAt,
+		&window.SuppressRules,
+		&window.SuppressAnomalies,
+		&window.CreatedAt,
+		&window.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return window, nil
+}
+
+// scanMaintenanceWindows scans maintenance window rows.
+func scanMaintenanceWindows(rows *sql.Rows) ([]*MaintenanceWindow, error) {
+	var windows []*MaintenanceWindow
+	for rows.Next() {
+		window, err := scanMaintenanceWindow(rows)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+	return windows, rows.Err()
+}
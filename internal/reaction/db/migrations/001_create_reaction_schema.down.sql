@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS anomaly_state;
+DROP TABLE IF EXISTS anomaly_events;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS anomaly_configs;
+DROP TABLE IF EXISTS rules;
+DROP TABLE IF EXISTS webhooks;
+DROP FUNCTION IF EXISTS update_updated_at_column();
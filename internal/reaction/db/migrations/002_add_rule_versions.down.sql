@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS rule_versions;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS rule_version;
+ALTER TABLE rules DROP COLUMN IF EXISTS version;
@@ -0,0 +1,16 @@
+-- Rule versioning: the current version of each rule, its immutable history,
+-- and the version that fired each delivery.
+ALTER TABLE rules ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1; -- Current rule_versions.version
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS rule_version INTEGER; -- Version of the rule that fired
+
+-- Rule versions table: immutable history of every rule create/update/rollback
+CREATE TABLE IF NOT EXISTS rule_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    rule_id UUID NOT NULL REFERENCES rules(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    snapshot JSONB NOT NULL, -- Full rule definition at this version
+    diff JSONB NOT NULL DEFAULT '[]', -- [{"field":"priority","old":1,"new":5}]
+    author VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(rule_id, version)
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS rule_shadow_samples;
+DROP TABLE IF EXISTS rule_shadow_stats;
+ALTER TABLE rules DROP COLUMN IF EXISTS shadow;
@@ -0,0 +1,23 @@
+-- Shadow rules record would-have-fired matches without executing actions.
+ALTER TABLE rules ADD COLUMN IF NOT EXISTS shadow BOOLEAN NOT NULL DEFAULT false;
+
+-- Rule shadow stats table: would-have-fired match counters for shadow rules
+CREATE TABLE IF NOT EXISTS rule_shadow_stats (
+    rule_id UUID PRIMARY KEY REFERENCES rules(id) ON DELETE CASCADE,
+    match_count BIGINT NOT NULL DEFAULT 0,
+    first_matched_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_matched_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Rule shadow samples table: sampled events matched by shadow rules
+CREATE TABLE IF NOT EXISTS rule_shadow_samples (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    rule_id UUID NOT NULL REFERENCES rules(id) ON DELETE CASCADE,
+    rule_version INTEGER NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    app_id VARCHAR(255) NOT NULL,
+    event_data JSONB NOT NULL,
+    matched_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_rule_shadow_samples_rule_matched ON rule_shadow_samples(rule_id, matched_at DESC);
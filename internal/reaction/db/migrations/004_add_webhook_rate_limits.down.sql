@@ -0,0 +1,2 @@
+ALTER TABLE webhooks DROP COLUMN IF EXISTS batch_size;
+ALTER TABLE webhooks DROP COLUMN IF EXISTS max_rps;
@@ -0,0 +1,3 @@
+-- Per-webhook rate limiting and batched deliveries.
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS max_rps DOUBLE PRECISION NOT NULL DEFAULT 0; -- Max requests per second to this endpoint, 0 = unlimited
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS batch_size INTEGER NOT NULL DEFAULT 0; -- >1 coalesces up to N pending deliveries into one array payload
@@ -0,0 +1 @@
+DROP FUNCTION IF EXISTS notify_rule_change() CASCADE;
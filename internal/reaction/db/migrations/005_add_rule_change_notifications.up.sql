@@ -0,0 +1,12 @@
+-- Notify the reaction engine of rule changes so it refreshes its rule cache
+CREATE OR REPLACE FUNCTION notify_rule_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('rule_changes', COALESCE(NEW.id, OLD.id)::text);
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE OR REPLACE TRIGGER notify_rules_changed
+    AFTER INSERT OR UPDATE OR DELETE ON rules
+    FOR EACH ROW EXECUTE FUNCTION notify_rule_change();
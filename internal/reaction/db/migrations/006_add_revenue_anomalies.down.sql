@@ -0,0 +1 @@
+ALTER TABLE anomaly_state DROP COLUMN IF EXISTS value_sum;
@@ -0,0 +1,2 @@
+-- Revenue anomaly detection sums event values per window.
+ALTER TABLE anomaly_state ADD COLUMN IF NOT EXISTS value_sum DOUBLE PRECISION NOT NULL DEFAULT 0; -- Summed values for revenue windows
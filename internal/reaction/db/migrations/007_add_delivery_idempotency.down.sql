@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS processed_messages;
+DROP INDEX IF EXISTS idx_webhook_deliveries_idempotency_key;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS idempotency_key;
@@ -0,0 +1,18 @@
+-- One delivery per webhook and idempotency key, and the stream sequences each
+-- consumer finished, so redelivered messages do not fire their side effects
+-- again.
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS idempotency_key TEXT; -- "{rule_id}:{event_id}" for rule deliveries
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_idempotency_key ON webhook_deliveries(webhook_id, idempotency_key) WHERE idempotency_key IS NOT NULL;
+
+-- Processed messages table: stream sequences each consumer finished, so
+-- redelivered messages are skipped instead of firing their side effects again
+CREATE TABLE IF NOT EXISTS processed_messages (
+    consumer VARCHAR(255) NOT NULL,
+    stream_seq BIGINT NOT NULL,
+    event_id VARCHAR(255),
+    processed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (consumer, stream_seq)
+);
+
+CREATE INDEX IF NOT EXISTS idx_processed_messages_processed_at ON processed_messages(processed_at);
@@ -0,0 +1 @@
+ALTER TABLE anomaly_configs DROP COLUMN IF EXISTS severity;
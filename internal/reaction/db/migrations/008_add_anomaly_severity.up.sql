@@ -0,0 +1,3 @@
+-- Alert severity: info, warning or critical. Critical alerts bypass
+-- notification digests.
+ALTER TABLE anomaly_configs ADD COLUMN IF NOT EXISTS severity VARCHAR(20) NOT NULL DEFAULT 'warning';
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_anomaly_events_next_escalation;
+ALTER TABLE anomaly_events DROP COLUMN IF EXISTS acknowledged_by;
+ALTER TABLE anomaly_events DROP COLUMN IF EXISTS acknowledged_at;
+ALTER TABLE anomaly_events DROP COLUMN IF EXISTS next_escalation_at;
+ALTER TABLE anomaly_events DROP COLUMN IF EXISTS escalation_step;
+ALTER TABLE anomaly_events DROP COLUMN IF EXISTS escalation_policy_id;
+ALTER TABLE anomaly_configs DROP COLUMN IF EXISTS escalation_policy_id;
+DROP TABLE IF EXISTS escalation_policies;
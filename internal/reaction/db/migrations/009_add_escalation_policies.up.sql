@@ -0,0 +1,24 @@
+-- Escalation policies: who to notify about an unacknowledged anomaly, and when
+CREATE TABLE IF NOT EXISTS escalation_policies (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT,
+    steps JSONB NOT NULL DEFAULT '[]', -- [{"after_minutes":0,"webhooks":["uuid"]},{"after_minutes":15,"webhooks":["uuid"]}]
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE OR REPLACE TRIGGER update_escalation_policies_updated_at
+    BEFORE UPDATE ON escalation_policies
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- NULL means alerts of the config are not escalated
+ALTER TABLE anomaly_configs ADD COLUMN IF NOT EXISTS escalation_policy_id UUID REFERENCES escalation_policies(id) ON DELETE SET NULL;
+
+ALTER TABLE anomaly_events ADD COLUMN IF NOT EXISTS escalation_policy_id UUID REFERENCES escalation_policies(id) ON DELETE SET NULL;
+ALTER TABLE anomaly_events ADD COLUMN IF NOT EXISTS escalation_step INTEGER NOT NULL DEFAULT 0; -- Index of the next escalation step to notify
+ALTER TABLE anomaly_events ADD COLUMN IF NOT EXISTS next_escalation_at TIMESTAMPTZ; -- NULL once acknowledged or all steps are notified
+ALTER TABLE anomaly_events ADD COLUMN IF NOT EXISTS acknowledged_at TIMESTAMPTZ;
+ALTER TABLE anomaly_events ADD COLUMN IF NOT EXISTS acknowledged_by VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_anomaly_events_next_escalation ON anomaly_events(next_escalation_at) WHERE next_escalation_at IS NOT NULL;
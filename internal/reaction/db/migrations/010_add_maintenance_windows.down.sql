@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_anomaly_events_maintenance_window;
+ALTER TABLE anomaly_events DROP COLUMN IF EXISTS maintenance_window_id;
+ALTER TABLE anomaly_events DROP COLUMN IF EXISTS suppressed;
+DROP TABLE IF EXISTS rule_suppressed_matches;
+DROP TABLE IF EXISTS maintenance_windows;
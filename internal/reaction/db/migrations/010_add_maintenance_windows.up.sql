@@ -0,0 +1,42 @@
+-- Maintenance windows table: periods during which rule actions and anomaly
+-- alerts are suppressed, for one app or all apps
+CREATE TABLE IF NOT EXISTS maintenance_windows (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    app_id VARCHAR(255), -- NULL applies to all apps
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    suppress_rules BOOLEAN NOT NULL DEFAULT true,
+    suppress_anomalies BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_maintenance_windows_ends_at ON maintenance_windows(ends_at);
+
+CREATE OR REPLACE TRIGGER update_maintenance_windows_updated_at
+    BEFORE UPDATE ON maintenance_windows
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Rule suppressed matches table: rule matches whose actions a maintenance
+-- window suppressed
+CREATE TABLE IF NOT EXISTS rule_suppressed_matches (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    rule_id UUID NOT NULL REFERENCES rules(id) ON DELETE CASCADE,
+    rule_version INTEGER NOT NULL,
+    maintenance_window_id UUID REFERENCES maintenance_windows(id) ON DELETE SET NULL,
+    event_id VARCHAR(255) NOT NULL,
+    app_id VARCHAR(255) NOT NULL,
+    matched_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_rule_suppressed_matches_window ON rule_suppressed_matches(maintenance_window_id, matched_at DESC);
+CREATE INDEX IF NOT EXISTS idx_rule_suppressed_matches_rule ON rule_suppressed_matches(rule_id, matched_at DESC);
+
+-- Anomalies detected during a maintenance window are recorded, not published
+ALTER TABLE anomaly_events ADD COLUMN IF NOT EXISTS suppressed BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE anomaly_events ADD COLUMN IF NOT EXISTS maintenance_window_id UUID REFERENCES maintenance_windows(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_anomaly_events_maintenance_window ON anomaly_events(maintenance_window_id) WHERE maintenance_window_id IS NOT NULL;
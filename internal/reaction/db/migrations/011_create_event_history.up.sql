@@ -0,0 +1,10 @@
+-- Last time each device sent the events referenced by history rule conditions
+CREATE TABLE IF NOT EXISTS event_history (
+    app_id VARCHAR(255) NOT NULL,
+    device_id VARCHAR(255) NOT NULL,
+    event_name VARCHAR(255) NOT NULL, -- {category}.{type}, e.g. "commerce.purchase_complete"
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (app_id, device_id, event_name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_history_last_seen ON event_history(last_seen_at);
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// ProcessedRepository records the stream sequences each consumer finished
+// processing, so redelivered messages can be recognized and skipped.
+type ProcessedRepository struct {
+	db *sql.DB
+}
+
+// NewProcessedRepository creates a new processed message repository.
+func NewProcessedRepository(client *Client) *ProcessedRepository {
+	return &ProcessedRepository{db: client.DB()}
+}
+
+// MarkProcessed records that consumer finished the message at streamSeq.
+// Recording a sequence twice is not an error.
+func (r *ProcessedRepository) MarkProcessed(ctx context.Context, consumer string, streamSeq uint64, eventID string) error {
+	query := `
+		INSERT INTO processed_messages (consumer, stream_seq, event_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (consumer, stream_seq) DO NOTHING
+	`
+
+	_, err := r.db.ExecContext(ctx, query, consumer, int64(streamSeq), nullIfEmpty(eventID))
+	return err
+}
+
+// IsProcessed reports whether consumer finished the message at streamSeq.
+func (r *ProcessedRepository) IsProcessed(ctx context.Context, consumer string, streamSeq uint64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM processed_messages WHERE consumer = $1 AND stream_seq = $2
+		)
+	`
+
+	var processed bool
+	err := r.db.QueryRowContext(ctx, query, consumer, int64(streamSeq)).Scan(&processed)
+	return processed, err
+}
+
+// DeleteOld deletes sequences processed before olderThan.
+func (r *ProcessedRepository) DeleteOld(ctx context.Context, olderThan time.Time) (int64, error) {
+	query := `
+		DELETE FROM processed_messages
+		WHERE processed_at < $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, olderThan)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pgdb "github.com/SebastienMelki/causality/internal/db"
+)
+
+// ReplicaConfig holds the settings of an optional read replica. Credentials,
+// database name, SSL mode and pool sizes are those of the primary.
+type ReplicaConfig struct {
+	// Host is the replica host; empty disables the replica
+	Host string `env:"HOST"`
+
+	// Port is the replica port
+	Port int `env:"PORT" envDefault:"5432"`
+
+	// MaxLag is the replication lag beyond which reads fail back to the
+	// primary until the replica catches up
+	MaxLag time.Duration `env:"MAX_LAG" envDefault:"5s"`
+
+	// LagCheckInterval is how often the replication lag is measured
+	LagCheckInterval time.Duration `env:"LAG_CHECK_INTERVAL" envDefault:"2s"`
+}
+
+// replicaLagQuery measures how far the replica's replay is behind the
+// primary. A replica that has replayed everything it received is not
+// lagging, however old its last replayed transaction, so an idle primary
+// does not look like lag.
+const replicaLagQuery = `
+	SELECT CASE
+		WHEN NOT pg_is_in_recovery() THEN 0
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	END
+`
+
+// readRouter picks the database read-only repository methods query: the
+// replica while its lag is within MaxLag, otherwise the primary. Reads also
+// go to the primary for MaxLag after a write or rule change notification, so
+// a refresh following a change sees it even on a lagging replica. Without a
+// replica every read goes to the primary.
+type readRouter struct {
+	primary *sql.DB
+	replica *pgdb.Pool
+	maxLag  time.Duration
+	logger  *slog.Logger
+
+	// healthy is set while the replica's last lag check was within maxLag
+	healthy atomic.Bool
+
+	// pinnedUntil is the Unix nano time until which reads go to the primary
+	pinnedUntil atomic.Int64
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// newReadRouter creates a router reading from primary only.
+func newReadRouter(primary *sql.DB, logger *slog.Logger) *readRouter {
+	return &readRouter{
+		primary: primary,
+		logger:  logger,
+		stopCh:  make(chan struct{}),
+		doneCh:  make(chan struct{}),
+	}
+}
+
+// openReplica opens the replica pool of cfg and starts measuring its lag.
+// The replica is connected to in the background, so an unreachable replica
+// does not prevent startup; reads use the primary until it is reachable and
+// caught up.
+func (r *readRouter) openReplica(cfg Config) error {
+	replicaCfg := cfg.pool()
+	replicaCfg.Host = cfg.Replica.Host
+	replicaCfg.Port = cfg.Replica.Port
+
+	replica, err := pgdb.Connect(replicaCfg, r.logger)
+	if err != nil {
+		return fmt.Errorf("failed to open replica: %w", err)
+	}
+
+	r.replica = replica
+	r.maxLag = cfg.Replica.MaxLag
+
+	interval := cfg.Replica.LagCheckInterval
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	go r.monitor(interval)
+
+	r.logger.Info("read replica configured",
+		"host", cfg.Replica.Host,
+		"port", cfg.Replica.Port,
+		"max_lag", r.maxLag,
+	)
+	return nil
+}
+
+// reader returns the database for a read-only query.
+func (r *readRouter) reader() *sql.DB {
+	if r.replica == nil || !r.healthy.Load() || time.Now().UnixNano() < r.pinnedUntil.Load() {
+		return r.primary
+	}
+	return r.replica.DB
+}
+
+// pinPrimary sends reads to the primary for maxLag, the longest a healthy
+// replica may take to replay a change just committed.
+func (r *readRouter) pinPrimary() {
+	if r.replica == nil {
+		return
+	}
+	r.pinnedUntil.Store(time.Now().Add(r.maxLag).UnixNano())
+}
+
+// monitor measures the replica's lag every interval until close.
+func (r *readRouter) monitor(interval time.Duration) {
+	defer close(r.doneCh)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	r.checkLag()
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+			r.checkLag()
+		}
+	}
+}
+
+// checkLag measures the replica's lag and records whether it is usable.
+func (r *readRouter) checkLag() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var seconds float64
+	err := r.replica.QueryRowContext(ctx, replicaLagQuery).Scan(&seconds)
+	r.setLag(time.Duration(seconds*float64(time.Second)), err)
+}
+
+// setLag records a lag measurement, logging when reads move between the
+// replica and the primary.
+func (r *readRouter) setLag(lag time.Duration, err error) {
+	healthy := err == nil && lag <= r.maxLag
+	if r.healthy.Swap(healthy) == healthy {
+		return
+	}
+
+	switch {
+	case healthy:
+		r.logger.Info("read replica caught up, reading from replica", "lag", lag)
+	case err != nil:
+		r.logger.Warn("read replica unavailable, reading from primary", "error", err)
+	default:
+		r.logger.Warn("read replica lagging, reading from primary", "lag", lag, "max_lag", r.maxLag)
+	}
+}
+
+// close stops the lag monitor and closes the replica pool.
+func (r *readRouter) close() error {
+	if r.replica == nil {
+		return nil
+	}
+	r.stopOnce.Do(func() {
+		close(r.stopCh)
+	})
+	<-r.doneCh
+	return r.replica.Close()
+}
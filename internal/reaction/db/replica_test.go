@@ -0,0 +1,65 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	pgdb "github.com/SebastienMelki/causality/internal/db"
+)
+
+func TestReadRouter(t *testing.T) {
+	primary, replica := &sql.DB{}, &pgdb.Pool{DB: &sql.DB{}}
+	r := newReadRouter(primary, slog.Default())
+
+	if got := r.reader(); got != primary {
+		t.Fatal("reader() without replica should be the primary")
+	}
+
+	r.replica = replica
+	r.maxLag = time.Second
+
+	if got := r.reader(); got != primary {
+		t.Error("reader() before the first lag check should be the primary")
+	}
+
+	r.setLag(500*time.Millisecond, nil)
+	if got := r.reader(); got != replica.DB {
+		t.Error("reader() within max lag should be the replica")
+	}
+
+	r.setLag(2*time.Second, nil)
+	if got := r.reader(); got != primary {
+		t.Error("reader() beyond max lag should be the primary")
+	}
+
+	r.setLag(0, nil)
+	if got := r.reader(); got != replica.DB {
+		t.Error("reader() after catching up should be the replica")
+	}
+
+	r.setLag(0, errors.New("connection refused"))
+	if got := r.reader(); got != primary {
+		t.Error("reader() with an unreachable replica should be the primary")
+	}
+}
+
+func TestReadRouter_PinPrimary(t *testing.T) {
+	primary, replica := &sql.DB{}, &pgdb.Pool{DB: &sql.DB{}}
+	r := newReadRouter(primary, slog.Default())
+	r.replica = replica
+	r.maxLag = time.Hour
+	r.setLag(0, nil)
+
+	r.pinPrimary()
+	if got := r.reader(); got != primary {
+		t.Error("reader() after pinPrimary should be the primary")
+	}
+
+	r.pinnedUntil.Store(time.Now().Add(-time.Second).UnixNano())
+	if got := r.reader(); got != replica.DB {
+		t.Error("reader() after the pin expires should be the replica")
+	}
+}
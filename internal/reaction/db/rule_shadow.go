@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ShadowStats counts would-have-fired matches of a shadow rule.
+type ShadowStats struct {
+	RuleID         string     `json:"rule_id"`
+	MatchCount     int64      `json:"match_count"`
+	FirstMatchedAt *time.Time `json:"first_matched_at,omitempty"`
+	LastMatchedAt  *time.Time `json:"last_matched_at,omitempty"`
+}
+
+// ShadowSample is a sampled event matched by a shadow rule.
+type ShadowSample struct {
+	ID          string          `json:"id"`
+	RuleID      string          `json:"rule_id"`
+	RuleVersion int             `json:"rule_version"`
+	EventID     string          `json:"event_id"`
+	AppID       string          `json:"app_id"`
+	EventData   json.RawMessage `json:"event_data"`
+	MatchedAt   time.Time       `json:"matched_at"`
+}
+
+// RecordShadowMatch increments a shadow rule's match counter. If sample is
+// non-nil it is stored too, keeping only the newest maxSamples per rule.
+func (r *RuleRepository) RecordShadowMatch(ctx context.Context, ruleID string, sample *ShadowSample, maxSamples int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO rule_shadow_stats (rule_id, match_count)
+		VALUES ($1, 1)
+		ON CONFLICT (rule_id) DO UPDATE
+		SET match_count = rule_shadow_stats.match_count + 1, last_matched_at = NOW()
+	`, ruleID)
+	if err != nil {
+		return err
+	}
+
+	if sample != nil {
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO rule_shadow_samples (rule_id, rule_version, event_id, app_id, event_data)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, matched_at
+		`,
+			ruleID,
+			sample.RuleVersion,
+			sample.EventID,
+			sample.AppID,
+			sample.EventData,
+		).Scan(&sample.ID, &sample.MatchedAt)
+		if err != nil {
+			return err
+		}
+		sample.RuleID = ruleID
+
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM rule_shadow_samples
+			WHERE rule_id = $1
+			  AND id NOT IN (
+			      SELECT id FROM rule_shadow_samples
+			      WHERE rule_id = $1
+			      ORDER BY matched_at DESC
+			      LIMIT $2
+			  )
+		`, ruleID, maxSamples)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetShadowStats retrieves a rule's shadow match counter. A rule that has
+// never matched in shadow mode returns zero counts.
+func (r *RuleRepository) GetShadowStats(ctx context.Context, ruleID string) (*ShadowStats, error) {
+	query := `
+		SELECT match_count, first_matched_at, last_matched_at
+		FROM rule_shadow_stats
+		WHERE rule_id = $1
+	`
+
+	stats := &ShadowStats{RuleID: ruleID}
+	err := r.db.QueryRowContext(ctx, query, ruleID).Scan(
+		&stats.MatchCount,
+		&stats.FirstMatchedAt,
+		&stats.LastMatchedAt,
+	)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// ListShadowSamples retrieves a rule's sampled shadow matches, newest first.
+func (r *RuleRepository) ListShadowSamples(ctx context.Context, ruleID string, limit int) ([]*ShadowSample, error) {
+	query := `
+		SELECT id, rule_id, rule_version, event_id, app_id, event_data, matched_at
+		FROM rule_shadow_samples
+		WHERE rule_id = $1
+		ORDER BY matched_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, ruleID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var samples []*ShadowSample
+	for rows.Next() {
+		sample := &ShadowSample{}
+		if err := rows.Scan(
+			&sample.ID,
+			&sample.RuleID,
+			&sample.RuleVersion,
+			&sample.EventID,
+			&sample.AppID,
+			&sample.EventData,
+			&sample.MatchedAt,
+		); err != nil {
+			return nil, err
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, rows.Err()
+}
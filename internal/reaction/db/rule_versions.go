@@ -0,0 +1,255 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Sentinel errors for rule versions.
+var (
+	ErrRuleVersionNotFound = errors.New("rule version not found")
+)
+
+// RuleDefinition is the versioned part of a rule: everything that affects
+// matching and actions, without identity or timestamps.
+type RuleDefinition struct {
+	Name          string      `json:"name"`
+	Description   *string     `json:"description"`
+	AppID         *string     `json:"app_id"`
+	EventCategory *string     `json:"event_category"`
+	EventType     *string     `json:"event_type"`
+	Conditions    []Condition `json:"conditions"`
+	Actions       Actions     `json:"actions"`
+	Priority      int         `json:"priority"`
+	Enabled       bool        `json:"enabled"`
+	Shadow        bool        `json:"shadow"`
+}
+
+// ruleDefinitionFields lists RuleDefinition JSON fields in diff order.
+var ruleDefinitionFields = []string{
+	"name", "description", "app_id", "event_category", "event_type",
+	"conditions", "actions", "priority", "enabled", "shadow",
+}
+
+// Definition returns the versioned part of the rule.
+func (r *Rule) Definition() RuleDefinition {
+	return RuleDefinition{
+		Name:          r.Name,
+		Description:   r.Description,
+		AppID:         r.AppID,
+		EventCategory: r.EventCategory,
+		EventType:     r.EventType,
+		Conditions:    r.Conditions,
+		Actions:       r.Actions,
+		Priority:      r.Priority,
+		Enabled:       r.Enabled,
+		Shadow:        r.Shadow,
+	}
+}
+
+// apply copies the definition onto rule, keeping its identity and timestamps.
+func (d RuleDefinition) apply(rule *Rule) {
+	rule.Name = d.Name
+	rule.Description = d.Description
+	rule.AppID = d.AppID
+	rule.EventCategory = d.EventCategory
+	rule.EventType = d.EventType
+	rule.Conditions = d.Conditions
+	rule.Actions = d.Actions
+	rule.Priority = d.Priority
+	rule.Enabled = d.Enabled
+	rule.Shadow = d.Shadow
+}
+
+// RuleChange describes a single field changed between two rule versions.
+type RuleChange struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old"`
+	New   json.RawMessage `json:"new"`
+}
+
+// RuleVersion is an immutable snapshot of a rule definition.
+type RuleVersion struct {
+	ID        string         `json:"id"`
+	RuleID    string         `json:"rule_id"`
+	Version   int            `json:"version"`
+	Snapshot  RuleDefinition `json:"snapshot"`
+	Diff      []RuleChange   `json:"diff"`
+	Author    string         `json:"author"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// DiffRules returns the definition fields that differ between before and
+// after, in a stable field order.
+func DiffRules(before, after *Rule) ([]RuleChange, error) {
+	beforeFields, err := definitionFields(before.Definition())
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := definitionFields(after.Definition())
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []RuleChange{}
+	for _, field := range ruleDefinitionFields {
+		if !bytes.Equal(beforeFields[field], afterFields[field]) {
+			changes = append(changes, RuleChange{
+				Field: field,
+				Old:   beforeFields[field],
+				New:   afterFields[field],
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+// definitionFields marshals a definition into its JSON fields.
+func definitionFields(def RuleDefinition) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(def)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// insertRuleVersion records rule's current definition as rule.Version.
+func insertRuleVersion(ctx context.Context, tx *sql.Tx, rule *Rule, changes []RuleChange, author string) error {
+	snapshotJSON, err := json.Marshal(rule.Definition())
+	if err != nil {
+		return err
+	}
+
+	if changes == nil {
+		changes = []RuleChange{}
+	}
+	diffJSON, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO rule_versions (rule_id, version, snapshot, diff, author)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err = tx.ExecContext(ctx, query, rule.ID, rule.Version, snapshotJSON, diffJSON, author)
+	return err
+}
+
+// ListVersions retrieves a rule's versions, newest first, with pagination.
+func (r *RuleRepository) ListVersions(ctx context.Context, ruleID string, limit, offset int) ([]*RuleVersion, error) {
+	query := `
+		SELECT id, rule_id, version, snapshot, diff, author, created_at
+		FROM rule_versions
+		WHERE rule_id = $1
+		ORDER BY version DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, ruleID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var versions []*RuleVersion
+	for rows.Next() {
+		version, err := scanRuleVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, rows.Err()
+}
+
+// GetVersion retrieves a single version of a rule.
+func (r *RuleRepository) GetVersion(ctx context.Context, ruleID string, version int) (*RuleVersion, error) {
+	query := `
+		SELECT id, rule_id, version, snapshot, diff, author, created_at
+		FROM rule_versions
+		WHERE rule_id = $1 AND version = $2
+	`
+
+	v, err := scanRuleVersion(r.db.QueryRowContext(ctx, query, ruleID, version))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRuleVersionNotFound
+		}
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// Rollback restores the definition of a prior version. The rollback is
+// itself recorded as a new version, so history is never rewritten.
+func (r *RuleRepository) Rollback(ctx context.Context, ruleID string, version int, author string) (*Rule, error) {
+	target, err := r.GetVersion(ctx, ruleID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rule := &Rule{ID: ruleID}
+	target.Snapshot.apply(rule)
+	if err := updateRuleTx(ctx, tx, rule, author, eventRuleRolledBack); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanRuleVersion scans a single rule version.
+func scanRuleVersion(row rowScanner) (*RuleVersion, error) {
+	v := &RuleVersion{}
+	var snapshotJSON, diffJSON []byte
+
+	if err := row.Scan(
+		&v.ID,
+		&v.RuleID,
+		&v.Version,
+		&snapshotJSON,
+		&diffJSON,
+		&v.Author,
+		&v.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(snapshotJSON, &v.Snapshot); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(diffJSON, &v.Diff); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
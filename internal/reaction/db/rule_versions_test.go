@@ -0,0 +1,53 @@
+package db
+
+import (
+	"testing"
+)
+
+func TestDiffRules(t *testing.T) {
+	desc := "checkout alerts"
+	before := &Rule{
+		ID:         "r1",
+		Name:       "checkout",
+		Conditions: []Condition{{Path: "$.amount", Operator: "gt", Value: 100}},
+		Priority:   1,
+		Enabled:    true,
+		Version:    1,
+	}
+	after := *before
+	after.Description = &desc
+	after.Priority = 5
+	after.Version = 2
+
+	changes, err := DiffRules(before, &after)
+	if err != nil {
+		t.Fatalf("DiffRules: %v", err)
+	}
+
+	want := []struct{ field, old, new string }{
+		{"description", "null", `"checkout alerts"`},
+		{"priority", "1", "5"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("changes: got %d (%+v), want %d", len(changes), changes, len(want))
+	}
+	for i, w := range want {
+		c := changes[i]
+		if c.Field != w.field || string(c.Old) != w.old || string(c.New) != w.new {
+			t.Errorf("change %d: got %s %s -> %s, want %s %s -> %s",
+				i, c.Field, c.Old, c.New, w.field, w.old, w.new)
+		}
+	}
+}
+
+func TestDiffRules_NoChanges(t *testing.T) {
+	rule := &Rule{Name: "signup", Enabled: true}
+
+	changes, err := DiffRules(rule, rule)
+	if err != nil {
+		t.Fatalf("DiffRules: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("changes: got %+v, want none", changes)
+	}
+}
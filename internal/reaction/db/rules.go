@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"errors"
 	"time"
+
+	"github.com/SebastienMelki/causality/internal/outbox"
 )
 
 // Sentinel errors for rules.
@@ -13,8 +15,39 @@ var (
 	ErrRuleNotFound = errors.New("rule not found")
 )
 
+// Condition sources select the document a condition's path is read from.
+const (
+	// ConditionSourceEvent reads the path from the event (the default).
+	ConditionSourceEvent = "event"
+
+	// ConditionSourceDevice reads the path from the device registry record
+	// of the event's device (e.g. "risk_score", "emulator").
+	ConditionSourceDevice = "device"
+
+	// ConditionSourceDerived reads the path from the last message on a
+	// derived subject of the event's app. The path starts with the
+	// subject's family and name, followed by "age_seconds" or
+	// "payload.{field}", e.g. "reactions.checkout_failed.age_seconds".
+	ConditionSourceDerived = "derived"
+
+	// ConditionSourceHistory checks when the event's device last sent
+	// another event. The path is the event's {category}.{type}, the
+	// operator "within" or "not_within", and the value a duration such as
+	// "30m", e.g. {"source": "history", "path": "commerce.purchase_complete",
+	// "operator": "not_within", "value": "30m"}.
+	ConditionSourceHistory = "history"
+
+	// ConditionSourceUser reads the path from the profile of the user most
+	// recently seen on the event's device (e.g. "traits.plan",
+	// "signed_up_at").
+	ConditionSourceUser = "user"
+)
+
 // Condition represents a single condition in a rule.
 type Condition struct {
+	// Source is ConditionSourceEvent (or empty), ConditionSourceDevice,
+	// ConditionSourceDerived, ConditionSourceHistory or ConditionSourceUser.
+	Source   string      `json:"source,omitempty"`
 	Path     string      `json:"path"`
 	Operator string      `json:"operator"`
 	Value    interface{} `json:"value"`
@@ -22,8 +55,30 @@ type Condition struct {
 
 // Actions represents the actions to take when a rule matches.
 type Actions struct {
-	Webhooks        []string `json:"webhooks"`
-	PublishSubjects []string `json:"publish_subjects"`
+	Webhooks        []string       `json:"webhooks"`
+	PublishSubjects []string       `json:"publish_subjects"`
+	Metrics         []MetricAction `json:"metrics,omitempty"`
+	// PushNotification, when set, sends a push notification to the event's
+	// device.
+	PushNotification *PushNotificationAction `json:"push_notification,omitempty"`
+}
+
+// MetricAction increments the counter Name when a rule matches. Labels maps
+// label names to JSONPath expressions into the event, e.g.
+// {"platform": "$.device_context.platform"}.
+type MetricAction struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// PushNotificationAction sends a push notification to the device of the
+// matched event. Title, Body and Data values are templates in which
+// {{$.path}} placeholders are replaced with event fields, e.g.
+// "{{$.custom_event.int_params.item_count}} items are waiting in your cart".
+type PushNotificationAction struct {
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	Data  map[string]string `json:"data,omitempty"`
 }
 
 // Rule represents a rule definition for event matching.
@@ -38,22 +93,35 @@ type Rule struct {
 	Actions       Actions     `json:"actions"`
 	Priority      int         `json:"priority"`
 	Enabled       bool        `json:"enabled"`
+	Shadow        bool        `json:"shadow"`
+	Version       int         `json:"version"`
 	CreatedAt     time.Time   `json:"created_at"`
 	UpdatedAt     time.Time   `json:"updated_at"`
 }
 
-// RuleRepository provides CRUD operations for rules.
+// Outbox event types of rule changes.
+const (
+	eventRuleCreated    = "rule.created"
+	eventRuleUpdated    = "rule.updated"
+	eventRuleRolledBack = "rule.rolled_back"
+	eventRuleDeleted    = "rule.deleted"
+)
+
+// RuleRepository provides CRUD operations for rules. Every create, update,
+// and rollback records an immutable row in rule_versions, and every change
+// enqueues an outbox event in the same transaction.
 type RuleRepository struct {
-	db *sql.DB
+	db   *sql.DB
+	read *readRouter
 }
 
 // NewRuleRepository creates a new rule repository.
 func NewRuleRepository(client *Client) *RuleRepository {
-	return &RuleRepository{db: client.DB()}
+	return &RuleRepository{db: client.DB(), read: client.read}
 }
 
-// Create creates a new rule.
-func (r *RuleRepository) Create(ctx context.Context, rule *Rule) error {
+// Create creates a new rule as version 1, attributed to author.
+func (r *RuleRepository) Create(ctx context.Context, rule *Rule, author string) error {
 	conditionsJSON, err := json.Marshal(rule.Conditions)
 	if err != nil {
 		return err
@@ -64,13 +132,19 @@ func (r *RuleRepository) Create(ctx context.Context, rule *Rule) error {
 		return err
 	}
 
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
 	query := `
-		INSERT INTO rules (name, description, app_id, event_category, event_type, conditions, actions, priority, enabled)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-		RETURNING id, created_at, updated_at
+		INSERT INTO rules (name, description, app_id, event_category, event_type, conditions, actions, priority, enabled, shadow)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, version, created_at, updated_at
 	`
 
-	return r.db.QueryRowContext(
+	err = tx.QueryRowContext(
 		ctx, query,
 		rule.Name,
 		rule.Description,
@@ -81,13 +155,27 @@ func (r *RuleRepository) Create(ctx context.Context, rule *Rule) error {
 		actionsJSON,
 		rule.Priority,
 		rule.Enabled,
-	).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+		rule.Shadow,
+	).Scan(&rule.ID, &rule.Version, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := insertRuleVersion(ctx, tx, rule, nil, author); err != nil {
+		return err
+	}
+
+	if err := enqueueRuleEvent(ctx, tx, eventRuleCreated, rule, author, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // GetByID retrieves a rule by ID.
 func (r *RuleRepository) GetByID(ctx context.Context, id string) (*Rule, error) {
 	query := `
-		SELECT id, name, description, app_id, event_category, event_type, conditions, actions, priority, enabled, created_at, updated_at
+		SELECT id, name, description, app_id, event_category, event_type, conditions, actions, priority, enabled, shadow, version, created_at, updated_at
 		FROM rules
 		WHERE id = $1
 	`
@@ -106,6 +194,8 @@ func (r *RuleRepository) GetByID(ctx context.Context, id string) (*Rule, error)
 		&actionsJSON,
 		&rule.Priority,
 		&rule.Enabled,
+		&rule.Shadow,
+		&rule.Version,
 		&rule.CreatedAt,
 		&rule.UpdatedAt,
 	)
@@ -126,29 +216,30 @@ func (r *RuleRepository) GetByID(ctx context.Context, id string) (*Rule, error)
 	return rule, nil
 }
 
-// GetEnabled retrieves all enabled rules ordered by priority.
+// GetEnabled retrieves all enabled rules ordered by priority, from the read
+// replica when one is caught up.
 func (r *RuleRepository) GetEnabled(ctx context.Context) ([]*Rule, error) {
 	query := `
-		SELECT id, name, description, app_id, event_category, event_type, conditions, actions, priority, enabled, created_at, updated_at
+		SELECT id, name, description, app_id, event_category, event_type, conditions, actions, priority, enabled, shadow, version, created_at, updated_at
 		FROM rules
 		WHERE enabled = true
 		ORDER BY priority DESC, name
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.read.reader().QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer func() { _ = rows.Close() }()
 
-	return r.scanRules(rows)
+	return scanRules(rows)
 }
 
 // GetMatchingRules retrieves enabled rules that could match the given app_id, category, and type.
 // Rules match if their filter is NULL (matches all) or equals the given value.
 func (r *RuleRepository) GetMatchingRules(ctx context.Context, appID, category, eventType string) ([]*Rule, error) {
 	query := `
-		SELECT id, name, description, app_id, event_category, event_type, conditions, actions, priority, enabled, created_at, updated_at
+		SELECT id, name, description, app_id, event_category, event_type, conditions, actions, priority, enabled, shadow, version, created_at, updated_at
 		FROM rules
 		WHERE enabled = true
 		  AND (app_id IS NULL OR app_id = $1)
@@ -163,11 +254,11 @@ func (r *RuleRepository) GetMatchingRules(ctx context.Context, appID, category,
 	}
 	defer func() { _ = rows.Close() }()
 
-	return r.scanRules(rows)
+	return scanRules(rows)
 }
 
 // scanRules scans multiple rules from rows.
-func (r *RuleRepository) scanRules(rows *sql.Rows) ([]*Rule, error) {
+func scanRules(rows *sql.Rows) ([]*Rule, error) {
 	var rules []*Rule
 	for rows.Next() {
 		rule := &Rule{}
@@ -184,6 +275,8 @@ func (r *RuleRepository) scanRules(rows *sql.Rows) ([]*Rule, error) {
 			&actionsJSON,
 			&rule.Priority,
 			&rule.Enabled,
+			&rule.Shadow,
+			&rule.Version,
 			&rule.CreatedAt,
 			&rule.UpdatedAt,
 		); err != nil {
@@ -203,8 +296,30 @@ func (r *RuleRepository) scanRules(rows *sql.Rows) ([]*Rule, error) {
 	return rules, rows.Err()
 }
 
-// Update updates a rule.
-func (r *RuleRepository) Update(ctx context.Context, rule *Rule) error {
+// Update updates a rule, bumping its version and recording the change
+// attributed to author.
+func (r *RuleRepository) Update(ctx context.Context, rule *Rule, author string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := updateRuleTx(ctx, tx, rule, author, eventRuleUpdated); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// updateRuleTx applies rule within tx, records a new version diffed against
+// the stored definition, and enqueues an outbox event of eventType.
+func updateRuleTx(ctx context.Context, tx *sql.Tx, rule *Rule, author, eventType string) error {
+	current, err := getRuleForUpdate(ctx, tx, rule.ID)
+	if err != nil {
+		return err
+	}
+
 	conditionsJSON, err := json.Marshal(rule.Conditions)
 	if err != nil {
 		return err
@@ -218,11 +333,12 @@ func (r *RuleRepository) Update(ctx context.Context, rule *Rule) error {
 	query := `
 		UPDATE rules
 		SET name = $1, description = $2, app_id = $3, event_category = $4, event_type = $5,
-		    conditions = $6, actions = $7, priority = $8, enabled = $9
-		WHERE id = $10
+		    conditions = $6, actions = $7, priority = $8, enabled = $9, shadow = $10, version = version + 1
+		WHERE id = $11
+		RETURNING version, created_at, updated_at
 	`
 
-	result, err := r.db.ExecContext(
+	err = tx.QueryRowContext(
 		ctx, query,
 		rule.Name,
 		rule.Description,
@@ -233,47 +349,114 @@ func (r *RuleRepository) Update(ctx context.Context, rule *Rule) error {
 		actionsJSON,
 		rule.Priority,
 		rule.Enabled,
+		rule.Shadow,
 		rule.ID,
-	)
+	).Scan(&rule.Version, &rule.CreatedAt, &rule.UpdatedAt)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRuleNotFound
+		}
 		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	changes, err := DiffRules(current, rule)
 	if err != nil {
 		return err
 	}
-	if rowsAffected == 0 {
-		return ErrRuleNotFound
+
+	if err := insertRuleVersion(ctx, tx, rule, changes, author); err != nil {
+		return err
+	}
+
+	return enqueueRuleEvent(ctx, tx, eventType, rule, author, changes)
+}
+
+// getRuleForUpdate loads a rule within tx, locking its row until the
+// transaction ends.
+func getRuleForUpdate(ctx context.Context, tx *sql.Tx, id string) (*Rule, error) {
+	query := `
+		SELECT id, name, description, app_id, event_category, event_type, conditions, actions, priority, enabled, shadow, version, created_at, updated_at
+		FROM rules
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	rows, err := tx.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	rules, err := scanRules(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, ErrRuleNotFound
 	}
 
-	return nil
+	return rules[0], nil
 }
 
-// Delete deletes a rule by ID.
+// Delete deletes a rule by ID, enqueueing a rule.deleted outbox event in the
+// same transaction.
 func (r *RuleRepository) Delete(ctx context.Context, id string) error {
-	query := `DELETE FROM rules WHERE id = $1`
+	query := `DELETE FROM rules WHERE id = $1 RETURNING name, app_id, version`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	rows, err := result.RowsAffected()
+	rule := &Rule{ID: id}
+	err = tx.QueryRowContext(ctx, query, id).Scan(&rule.Name, &rule.AppID, &rule.Version)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRuleNotFound
+		}
 		return err
 	}
-	if rows == 0 {
-		return ErrRuleNotFound
+
+	if err := enqueueRuleEvent(ctx, tx, eventRuleDeleted, rule, "", nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ruleEvent is the data of rule outbox events.
+type ruleEvent struct {
+	Name    string       `json:"name"`
+	Version int          `json:"version"`
+	Changes []RuleChange `json:"changes,omitempty"`
+}
+
+// enqueueRuleEvent enqueues an outbox event of eventType for rule within tx,
+// attributed to author.
+func enqueueRuleEvent(ctx context.Context, tx *sql.Tx, eventType string, rule *Rule, author string, changes []RuleChange) error {
+	var appID string
+	if rule.AppID != nil {
+		appID = *rule.AppID
 	}
 
-	return nil
+	return outbox.Enqueue(ctx, tx, &outbox.Event{
+		Type:       eventType,
+		AppID:      appID,
+		ResourceID: rule.ID,
+		Actor:      author,
+		Data: ruleEvent{
+			Name:    rule.Name,
+			Version: rule.Version,
+			Changes: changes,
+		},
+	})
 }
 
 // List retrieves all rules with pagination.
 func (r *RuleRepository) List(ctx context.Context, limit, offset int) ([]*Rule, error) {
 	query := `
-		SELECT id, name, description, app_id, event_category, event_type, conditions, actions, priority, enabled, created_at, updated_at
+		SELECT id, name, description, app_id, event_category, event_type, conditions, actions, priority, enabled, shadow, version, created_at, updated_at
 		FROM rules
 		ORDER BY priority DESC, created_at DESC
 		LIMIT $1 OFFSET $2
@@ -285,5 +468,25 @@ func (r *RuleRepository) List(ctx context.Context, limit, offset int) ([]*Rule,
 	}
 	defer func() { _ = rows.Close() }()
 
-	return r.scanRules(rows)
+	return scanRules(rows)
+}
+
+// ListByAppID retrieves the rules scoped to an app with pagination. Rules
+// that apply to all apps (NULL app_id) are not included.
+func (r *RuleRepository) ListByAppID(ctx context.Context, appID string, limit, offset int) ([]*Rule, error) {
+	query := `
+		SELECT id, name, description, app_id, event_category, event_type, conditions, actions, priority, enabled, shadow, version, created_at, updated_at
+		FROM rules
+		WHERE app_id = $1
+		ORDER BY priority DESC, created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, appID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	return scanRules(rows)
 }
@@ -23,6 +23,8 @@ type Webhook struct {
 	Headers    map[string]string `json:"headers"`
 	Enabled    bool              `json:"enabled"`
 	TimeoutMs  int               `json:"timeout_ms"`
+	MaxRPS     float64           `json:"max_rps"`    // 0 means unlimited
+	BatchSize  int               `json:"batch_size"` // >1 coalesces deliveries into array payloads
 	CreatedAt  time.Time         `json:"created_at"`
 	UpdatedAt  time.Time         `json:"updated_at"`
 }
@@ -45,8 +47,8 @@ func (r *WebhookRepository) Create(ctx context.Context, webhook *Webhook) error
 	}
 
 	query := `
-		INSERT INTO webhooks (name, url, auth_type, auth_config, headers, enabled, timeout_ms)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO webhooks (name, url, auth_type, auth_config, headers, enabled, timeout_ms, max_rps, batch_size)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id, created_at, updated_at
 	`
 
@@ -59,13 +61,15 @@ func (r *WebhookRepository) Create(ctx context.Context, webhook *Webhook) error
 		headersJSON,
 		webhook.Enabled,
 		webhook.TimeoutMs,
+		webhook.MaxRPS,
+		webhook.BatchSize,
 	).Scan(&webhook.ID, &webhook.CreatedAt, &webhook.UpdatedAt)
 }
 
 // GetByID retrieves a webhook by ID.
 func (r *WebhookRepository) GetByID(ctx context.Context, id string) (*Webhook, error) {
 	query := `
-		SELECT id, name, url, auth_type, auth_config, headers, enabled, timeout_ms, created_at, updated_at
+		SELECT id, name, url, auth_type, auth_config, headers, enabled, timeout_ms, max_rps, batch_size, created_at, updated_at
 		FROM webhooks
 		WHERE id = $1
 	`
@@ -82,6 +86,8 @@ func (r *WebhookRepository) GetByID(ctx context.Context, id string) (*Webhook, e
 		&headersJSON,
 		&webhook.Enabled,
 		&webhook.TimeoutMs,
+		&webhook.MaxRPS,
+		&webhook.BatchSize,
 		&webhook.CreatedAt,
 		&webhook.UpdatedAt,
 	)
@@ -102,7 +108,7 @@ func (r *WebhookRepository) GetByID(ctx context.Context, id string) (*Webhook, e
 // GetEnabled retrieves all enabled webhooks.
 func (r *WebhookRepository) GetEnabled(ctx context.Context) ([]*Webhook, error) {
 	query := `
-		SELECT id, name, url, auth_type, auth_config, headers, enabled, timeout_ms, created_at, updated_at
+		SELECT id, name, url, auth_type, auth_config, headers, enabled, timeout_ms, max_rps, batch_size, created_at, updated_at
 		FROM webhooks
 		WHERE enabled = true
 		ORDER BY name
@@ -128,6 +134,8 @@ func (r *WebhookRepository) GetEnabled(ctx context.Context) ([]*Webhook, error)
 			&headersJSON,
 			&webhook.Enabled,
 			&webhook.TimeoutMs,
+			&webhook.MaxRPS,
+			&webhook.BatchSize,
 			&webhook.CreatedAt,
 			&webhook.UpdatedAt,
 		); err != nil {
@@ -151,9 +159,9 @@ func (r *WebhookRepository) GetByIDs(ctx context.Context, ids []string) ([]*Webh
 	}
 
 	query := `
-		SELECT id, name, url, auth_type, auth_config, headers, enabled, timeout_ms, created_at, updated_at
+		SELECT id, name, url, auth_type, auth_config, headers, enabled, timeout_ms, max_rps, batch_size, created_at, updated_at
 		FROM webhooks
-		WHERE id = ANY($1)
+		WHERE id = ANY($1::text[]::uuid[])
 	`
 
 	rows, err := r.db.QueryContext(ctx, query, ids)
@@ -176,6 +184,8 @@ func (r *WebhookRepository) GetByIDs(ctx context.Context, ids []string) ([]*Webh
 			&headersJSON,
 			&webhook.Enabled,
 			&webhook.TimeoutMs,
+			&webhook.MaxRPS,
+			&webhook.BatchSize,
 			&webhook.CreatedAt,
 			&webhook.UpdatedAt,
 		); err != nil {
@@ -201,8 +211,9 @@ func (r *WebhookRepository) Update(ctx context.Context, webhook *Webhook) error
 
 	query := `
 		UPDATE webhooks
-		SET name = $1, url = $2, auth_type = $3, auth_config = $4, headers = $5, enabled = $6, timeout_ms = $7
-		WHERE id = $8
+		SET name = $1, url = $2, auth_type = $3, auth_config = $4, headers = $5, enabled = $6, timeout_ms = $7,
+		    max_rps = $8, batch_size = $9
+		WHERE id = $10
 		RETURNING updated_at
 	`
 
@@ -215,6 +226,8 @@ func (r *WebhookRepository) Update(ctx context.Context, webhook *Webhook) error
 		headersJSON,
 		webhook.Enabled,
 		webhook.TimeoutMs,
+		webhook.MaxRPS,
+		webhook.BatchSize,
 		webhook.ID,
 	)
 	if err != nil {
@@ -255,7 +268,7 @@ func (r *WebhookRepository) Delete(ctx context.Context, id string) error {
 // List retrieves all webhooks with pagination.
 func (r *WebhookRepository) List(ctx context.Context, limit, offset int) ([]*Webhook, error) {
 	query := `
-		SELECT id, name, url, auth_type, auth_config, headers, enabled, timeout_ms, created_at, updated_at
+		SELECT id, name, url, auth_type, auth_config, headers, enabled, timeout_ms, max_rps, batch_size, created_at, updated_at
 		FROM webhooks
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2
@@ -281,6 +294,8 @@ func (r *WebhookRepository) List(ctx context.Context, limit, offset int) ([]*Web
 			&headersJSON,
 			&webhook.Enabled,
 			&webhook.TimeoutMs,
+			&webhook.MaxRPS,
+			&webhook.BatchSize,
 			&webhook.CreatedAt,
 			&webhook.UpdatedAt,
 		); err != nil {
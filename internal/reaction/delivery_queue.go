@@ -0,0 +1,156 @@
+package reaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/SebastienMelki/causality/internal/nats"
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// Delivery queue modes of DispatcherConfig.Queue.
+const (
+	DeliveryQueuePostgres = "postgres"
+	DeliveryQueueNATS     = "nats"
+)
+
+// ConsumerConfig returns the delivery stream consumer shared by the
+// dispatcher workers of every engine. Redeliveries are bounded because the
+// Postgres retry poll picks up any delivery the queue gives up on.
+func (c DispatcherConfig) ConsumerConfig() nats.ConsumerConfig {
+	return nats.ConsumerConfig{
+		Name:          c.QueueConsumerName,
+		FilterSubject: nats.DeliverySubjects,
+		AckWait:       c.QueueAckWait,
+		MaxAckPending: 10000,
+		MaxDeliver:    5,
+	}
+}
+
+// deliveryPublisher is the subset of jetstream.JetStream used by
+// DeliveryQueue.
+type deliveryPublisher interface {
+	PublishMsg(ctx context.Context, msg *natsgo.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+}
+
+// DeliveryQueue enqueues the IDs of newly created webhook deliveries to the
+// delivery stream, where dispatcher workers consume them instead of waiting
+// for the next Postgres poll.
+type DeliveryQueue struct {
+	js     deliveryPublisher
+	logger *slog.Logger
+}
+
+// NewDeliveryQueue creates a delivery queue publishing to js.
+func NewDeliveryQueue(js jetstream.JetStream, logger *slog.Logger) *DeliveryQueue {
+	return newDeliveryQueue(js, logger)
+}
+
+func newDeliveryQueue(js deliveryPublisher, logger *slog.Logger) *DeliveryQueue {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &DeliveryQueue{
+		js:     js,
+		logger: logger.With("component", "delivery-queue"),
+	}
+}
+
+// SetupDeliveryQueue ensures the delivery stream and the dispatcher's
+// consumer of it when cfg selects the "nats" queue, and returns the queue
+// new deliveries are enqueued to and the consumer the dispatcher reads. Both
+// are nil with the "postgres" queue.
+func SetupDeliveryQueue(
+	ctx context.Context,
+	js jetstream.JetStream,
+	streams *nats.StreamManager,
+	cfg DispatcherConfig,
+	logger *slog.Logger,
+) (*DeliveryQueue, jetstream.Consumer, error) {
+	switch cfg.Queue {
+	case DeliveryQueuePostgres, "":
+		return nil, nil, nil
+	case DeliveryQueueNATS:
+	default:
+		return nil, nil, fmt.Errorf("unknown dispatcher queue %q (want %q or %q)", cfg.Queue, DeliveryQueuePostgres, DeliveryQueueNATS)
+	}
+
+	stream, err := streams.EnsureDeliveryStream(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := streams.EnsureConsumers(ctx, stream, []nats.ConsumerConfig{cfg.ConsumerConfig()}); err != nil {
+		return nil, nil, err
+	}
+	consumer, err := stream.Consumer(ctx, cfg.QueueConsumerName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get delivery consumer: %w", err)
+	}
+
+	return NewDeliveryQueue(js, logger), consumer, nil
+}
+
+// deliverySubject returns the delivery stream subject of a delivery.
+func deliverySubject(delivery *db.WebhookDelivery) string {
+	return "deliveries." + delivery.WebhookID
+}
+
+// Enqueue publishes the ID of each created delivery, skipping those
+// CreateBatch left without an ID as duplicates. The ID is the message ID, so
+// JetStream drops a repeated publish within its duplicate window. A delivery
+// that fails to publish stays pending in Postgres for the retry poll; the
+// returned error joins every failure.
+func (q *DeliveryQueue) Enqueue(ctx context.Context, deliveries []*db.WebhookDelivery) error {
+	var errs []error
+	for _, delivery := range deliveries {
+		if delivery.ID == "" {
+			continue
+		}
+
+		msg := &natsgo.Msg{
+			Subject: deliverySubject(delivery),
+			Data:    []byte(delivery.ID),
+			Header:  natsgo.Header{},
+		}
+		msg.Header.Set(natsgo.MsgIdHdr, delivery.ID)
+
+		if _, err := q.js.PublishMsg(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("delivery %s: %w", delivery.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// enqueueDeliveries enqueues created deliveries when queue is set, logging
+// failures: the retry poll delivers them later.
+func enqueueDeliveries(ctx context.Context, queue *DeliveryQueue, deliveries []*db.WebhookDelivery, logger *slog.Logger) {
+	if queue == nil {
+		return
+	}
+	if err := queue.Enqueue(ctx, deliveries); err != nil {
+		logger.Warn("failed to enqueue webhook deliveries, leaving them to the retry poll",
+			"error", err,
+		)
+	}
+}
+
+// queuedMessage is a delivery stream message and the delivery it names.
+type queuedMessage struct {
+	msg jetstream.Msg
+	id  string
+}
+
+// settle acks a consumed message, or naks it for redelivery after delay when
+// its delivery was deferred by the webhook's rate limit.
+func (m queuedMessage) settle(deferred bool, delay time.Duration) error {
+	if deferred {
+		return m.msg.NakWithDelay(delay)
+	}
+	return m.msg.Ack()
+}
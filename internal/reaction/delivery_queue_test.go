@@ -0,0 +1,97 @@
+package reaction
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// fakeDeliveryPublisher records published delivery stream messages.
+type fakeDeliveryPublisher struct {
+	msgs []*natsgo.Msg
+}
+
+func (f *fakeDeliveryPublisher) PublishMsg(_ context.Context, msg *natsgo.Msg, _ ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	f.msgs = append(f.msgs, msg)
+	return &jetstream.PubAck{}, nil
+}
+
+func TestDeliveryQueue_Enqueue(t *testing.T) {
+	publisher := &fakeDeliveryPublisher{}
+	queue := newDeliveryQueue(publisher, nil)
+
+	deliveries := []*db.WebhookDelivery{
+		{ID: "0190a8f0-0000-7000-8000-000000000001", WebhookID: "w1"},
+		{WebhookID: "w1"}, // duplicate skipped by CreateBatch
+		{ID: "0190a8f0-0000-7000-8000-000000000002", WebhookID: "w2"},
+	}
+	if err := queue.Enqueue(context.Background(), deliveries); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	if len(publisher.msgs) != 2 {
+		t.Fatalf("published %d messages, want 2", len(publisher.msgs))
+	}
+	msg := publisher.msgs[1]
+	if msg.Subject != "deliveries.w2" {
+		t.Errorf("subject: got %q, want deliveries.w2", msg.Subject)
+	}
+	if string(msg.Data) != deliveries[2].ID || msg.Header.Get(natsgo.MsgIdHdr) != deliveries[2].ID {
+		t.Errorf("got data %q, msg id %q; want delivery ID in both", msg.Data, msg.Header.Get(natsgo.MsgIdHdr))
+	}
+}
+
+func TestDispatcher_ProcessQueued(t *testing.T) {
+	limited, limitedRequests := newWebhookServer(t, http.StatusOK)
+	open, openRequests := newWebhookServer(t, http.StatusOK)
+
+	pending := append(testDeliveries("w1", 2), testDeliveries("w2", 1)...)
+	pending[0].ID = "0190a8f0-0000-7000-8000-000000000001"
+	pending[1].ID = "0190a8f0-0000-7000-8000-000000000002"
+	pending[2].ID = "0190a8f0-0000-7000-8000-000000000003"
+	store := &fakeDeliveryStore{pending: pending}
+	d := newTestDispatcher(store, fakeWebhookStore{
+		"w1": {ID: "w1", URL: limited.URL, Enabled: true, MaxRPS: 1},
+		"w2": {ID: "w2", URL: open.URL, Enabled: true},
+	})
+
+	msgs := []*fakeMsg{
+		{data: []byte(pending[0].ID)},
+		{data: []byte(pending[1].ID)},
+		{data: []byte(pending[2].ID)},
+		{data: []byte("0190a8f0-0000-7000-8000-0000000000ff")}, // no longer pending
+		{data: []byte("not-a-delivery")},
+	}
+	batch := make([]jetstream.Msg, len(msgs))
+	for i, msg := range msgs {
+		batch[i] = msg
+	}
+
+	d.processQueued(context.Background(), batch)
+
+	if got := len(limitedRequests()); got != 1 {
+		t.Errorf("rate-limited webhook requests: got %d, want 1", got)
+	}
+	if got := len(openRequests()); got != 1 {
+		t.Errorf("unlimited webhook requests: got %d, want 1", got)
+	}
+
+	want := []struct{ acked, nakked, termed bool }{
+		{acked: true},
+		{nakked: true}, // deferred by the rate limit
+		{acked: true},
+		{acked: true},
+		{termed: true},
+	}
+	for i, w := range want {
+		if msgs[i].acked != w.acked || msgs[i].nakked != w.nakked || msgs[i].termed != w.termed {
+			t.Errorf("message %d: got acked=%v nakked=%v termed=%v, want %+v",
+				i, msgs[i].acked, msgs[i].nakked, msgs[i].termed, w)
+		}
+	}
+}
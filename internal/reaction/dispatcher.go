@@ -7,23 +7,58 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go/jetstream"
+	"golang.org/x/time/rate"
+
+	"github.com/SebastienMelki/causality/internal/observability"
 	"github.com/SebastienMelki/causality/internal/reaction/db"
 )
 
-// Dispatcher handles webhook delivery with retries.
+// deliveryStore is the subset of db.DeliveryRepository used by Dispatcher.
+type deliveryStore interface {
+	GetPending(ctx context.Context, overdue time.Duration, limit int) ([]*db.WebhookDelivery, error)
+	GetPendingByIDs(ctx context.Context, ids []string) ([]*db.WebhookDelivery, error)
+	MarkInProgress(ctx context.Context, id string) error
+	MarkDelivered(ctx context.Context, id string, statusCode int) error
+	MarkFailed(ctx context.Context, id string, statusCode *int, errMsg string, nextAttemptAt time.Time) error
+}
+
+// webhookStore is the subset of db.WebhookRepository used by Dispatcher.
+type webhookStore interface {
+	GetByID(ctx context.Context, id string) (*db.Webhook, error)
+}
+
+// Dispatcher handles webhook delivery with retries, per-endpoint rate
+// limiting, and optional batching. Workers poll Postgres for pending
+// deliveries, or consume them from the delivery stream when a queue is set.
 type Dispatcher struct {
-	deliveries *db.DeliveryRepository
-	webhooks   *db.WebhookRepository
+	deliveries deliveryStore
+	webhooks   webhookStore
 	config     DispatcherConfig
+	cipher     *PayloadCipher
 	logger     *slog.Logger
 	httpClient *http.Client
+	metrics    *observability.Metrics
+
+	// queue, when set, supplies new deliveries; Postgres is then polled
+	// every pollInterval for deliveries due for at least pollOverdue
+	queue        jetstream.Consumer
+	pollInterval time.Duration
+	pollOverdue  time.Duration
+
+	limitersMu sync.Mutex
+	limiters   map[string]*rate.Limiter
 
 	stopCh chan struct{}
 	doneCh chan struct{}
@@ -34,6 +69,17 @@ func NewDispatcher(
 	deliveries *db.DeliveryRepository,
 	webhooks *db.WebhookRepository,
 	config DispatcherConfig,
+	cipher *PayloadCipher,
+	logger *slog.Logger,
+) *Dispatcher {
+	return newDispatcher(deliveries, webhooks, config, cipher, logger)
+}
+
+func newDispatcher(
+	deliveries deliveryStore,
+	webhooks webhookStore,
+	config DispatcherConfig,
+	cipher *PayloadCipher,
 	logger *slog.Logger,
 ) *Dispatcher {
 	if logger == nil {
@@ -44,15 +90,36 @@ func NewDispatcher(
 		deliveries: deliveries,
 		webhooks:   webhooks,
 		config:     config,
+		cipher:     cipher,
 		logger:     logger.With("component", "reaction-dispatcher"),
 		httpClient: &http.Client{
 			Timeout: config.RequestTimeout,
 		},
-		stopCh: make(chan struct{}),
-		doneCh: make(chan struct{}),
+		limiters:     make(map[string]*rate.Limiter),
+		pollInterval: config.PollInterval,
+		stopCh:       make(chan struct{}),
+		doneCh:       make(chan struct{}),
 	}
 }
 
+// SetMetrics sets the metrics recording webhook attempt latency, delivery
+// age at success, attempts per delivery and per-status counts. Without it
+// nothing is recorded. Call it before Start.
+func (d *Dispatcher) SetMetrics(metrics *observability.Metrics) {
+	d.metrics = metrics
+}
+
+// SetQueue makes the workers consume new deliveries from the delivery stream
+// consumer instead of polling Postgres for them. A single worker still polls
+// Postgres every QueueRetryInterval for retries, rate-limited deliveries the
+// queue gave up on and deliveries that could not be enqueued. Call it before
+// Start.
+func (d *Dispatcher) SetQueue(consumer jetstream.Consumer) {
+	d.queue = consumer
+	d.pollInterval = d.config.QueueRetryInterval
+	d.pollOverdue = d.config.QueueRetryInterval
+}
+
 // Start starts the dispatcher workers.
 func (d *Dispatcher) Start(ctx context.Context) {
 	var wg sync.WaitGroup
@@ -62,11 +129,28 @@ func (d *Dispatcher) Start(ctx context.Context) {
 		wg.Add(1)
 		go func(workerID int) {
 			defer wg.Done()
-			d.worker(ctx, workerID)
+			if d.queue != nil {
+				d.queueWorker(ctx, workerID)
+			} else {
+				d.worker(ctx, workerID)
+			}
 		}(i)
 	}
 
-	d.logger.Info("dispatcher started", "workers", d.config.Workers)
+	// With a queue, one worker polls Postgres for retries
+	if d.queue != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.worker(ctx, d.config.Workers)
+		}()
+	}
+
+	d.logger.Info("dispatcher started",
+		"workers", d.config.Workers,
+		"queue", d.queue != nil,
+		"poll_interval", d.pollInterval,
+	)
 
 	// Wait for stop signal then wait for workers
 	go func() {
@@ -87,7 +171,7 @@ func (d *Dispatcher) Stop() {
 func (d *Dispatcher) worker(ctx context.Context, workerID int) {
 	d.logger.Debug("worker started", "worker_id", workerID)
 
-	ticker := time.NewTicker(d.config.PollInterval)
+	ticker := time.NewTicker(d.pollInterval)
 	defer ticker.Stop()
 
 	for {
@@ -102,71 +186,338 @@ func (d *Dispatcher) worker(ctx context.Context, workerID int) {
 	}
 }
 
-// processDeliveries fetches and processes pending deliveries.
+// processDeliveries fetches pending deliveries and processes them grouped
+// by webhook.
 func (d *Dispatcher) processDeliveries(ctx context.Context) {
-	deliveries, err := d.deliveries.GetPending(ctx, d.config.BatchSize)
+	deliveries, err := d.deliveries.GetPending(ctx, d.pollOverdue, d.config.BatchSize)
 	if err != nil {
 		d.logger.Error("failed to get pending deliveries", "error", err)
 		return
 	}
 
-	for _, delivery := range deliveries {
-		if err := d.processDelivery(ctx, delivery); err != nil {
-			d.logger.Error("failed to process delivery",
-				"delivery_id", delivery.ID,
-				"error", err,
-			)
+	for _, group := range groupByWebhook(deliveries) {
+		d.processWebhookDeliveries(ctx, group)
+	}
+}
+
+// queueWorker is a delivery worker consuming the delivery stream.
+func (d *Dispatcher) queueWorker(ctx context.Context, workerID int) {
+	d.logger.Debug("queue worker started", "worker_id", workerID)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.stopCh:
+			return
+		default:
+		}
+
+		msgs, err := d.queue.Fetch(d.config.BatchSize, jetstream.FetchMaxWait(5*time.Second))
+		if err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) {
+				d.logger.Error("failed to fetch queued deliveries", "error", err)
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+					return
+				case <-d.stopCh:
+					return
+				}
+			}
+			continue
+		}
+
+		var batch []jetstream.Msg
+		for msg := range msgs.Messages() {
+			batch = append(batch, msg)
+		}
+		if err := msgs.Error(); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			d.logger.Warn("fetch completed with error", "error", err)
+		}
+
+		if len(batch) > 0 {
+			d.processQueued(ctx, batch)
 		}
 	}
 }
 
-// processDelivery processes a single delivery.
-func (d *Dispatcher) processDelivery(ctx context.Context, delivery *db.WebhookDelivery) error {
-	// Mark as in progress
-	if err := d.deliveries.MarkInProgress(ctx, delivery.ID); err != nil {
-		return fmt.Errorf("failed to mark in progress: %w", err)
+// processQueued processes the deliveries named by a batch of delivery stream
+// messages, grouped by webhook, then acks the messages. Messages naming a
+// delivery that is no longer pending, or not yet due, are acked without
+// effect: Postgres holds the delivery's state and the retry poll finds it
+// when it is due again. Messages of deliveries deferred by a rate limit are
+// redelivered after PollInterval.
+func (d *Dispatcher) processQueued(ctx context.Context, msgs []jetstream.Msg) {
+	queued := make([]queuedMessage, 0, len(msgs))
+	ids := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		id := string(msg.Data())
+		if _, err := uuid.Parse(id); err != nil {
+			d.logger.Warn("terminating queued delivery with invalid ID",
+				"subject", msg.Subject(),
+				"id", id,
+			)
+			_ = msg.Term()
+			continue
+		}
+		queued = append(queued, queuedMessage{msg: msg, id: id})
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	deliveries, err := d.deliveries.GetPendingByIDs(ctx, ids)
+	if err != nil {
+		d.logger.Error("failed to get queued deliveries", "error", err)
+		for _, m := range queued {
+			_ = m.msg.Nak()
+		}
+		return
+	}
+
+	deferred := make(map[string]bool)
+	for _, group := range groupByWebhook(deliveries) {
+		for _, delivery := range d.processWebhookDeliveries(ctx, group) {
+			deferred[delivery.ID] = true
+		}
 	}
 
-	// Get webhook config
-	webhook, err := d.webhooks.GetByID(ctx, delivery.WebhookID)
+	for _, m := range queued {
+		if err := m.settle(deferred[m.id], d.config.PollInterval); err != nil {
+			d.logger.Error("failed to ACK queued delivery", "delivery_id", m.id, "error", err)
+		}
+	}
+}
+
+// processWebhookDeliveries processes pending deliveries for a single webhook.
+// Deliveries are sent in chunks of the webhook's batch size (one at a time
+// when batching is off). Chunks over the webhook's rate limit are left
+// pending for a later poll and returned.
+func (d *Dispatcher) processWebhookDeliveries(ctx context.Context, deliveries []*db.WebhookDelivery) []*db.WebhookDelivery {
+	webhook, err := d.webhooks.GetByID(ctx, deliveries[0].WebhookID)
 	if err != nil {
-		errMsg := fmt.Sprintf("webhook not found: %v", err)
-		nextAttempt := d.calculateNextAttempt(delivery.Attempts)
-		return d.deliveries.MarkFailed(ctx, delivery.ID, nil, errMsg, nextAttempt)
+		d.failAll(ctx, deliveries, fmt.Sprintf("webhook not found: %v", err))
+		return nil
 	}
 
 	if !webhook.Enabled {
-		errMsg := "webhook is disabled"
-		nextAttempt := d.calculateNextAttempt(delivery.Attempts)
-		return d.deliveries.MarkFailed(ctx, delivery.ID, nil, errMsg, nextAttempt)
+		d.failAll(ctx, deliveries, "webhook is disabled")
+		return nil
+	}
+
+	limiter := d.limiterFor(webhook)
+	chunks := chunkDeliveries(deliveries, webhook.BatchSize)
+	for i, chunk := range chunks {
+		if limiter != nil && !limiter.Allow() {
+			var deferred []*db.WebhookDelivery
+			for _, rest := range chunks[i:] {
+				deferred = append(deferred, rest...)
+			}
+			d.logger.Debug("webhook rate limited, deferring deliveries",
+				"webhook_id", webhook.ID,
+				"max_rps", webhook.MaxRPS,
+				"deferred", len(deferred),
+			)
+			return deferred
+		}
+
+		d.processChunk(ctx, webhook, chunk)
+	}
+	return nil
+}
+
+// processChunk delivers a chunk of deliveries in a single request. In
+// batching mode the payload is a JSON array of the individual payloads.
+func (d *Dispatcher) processChunk(ctx context.Context, webhook *db.Webhook, chunk []*db.WebhookDelivery) {
+	ready := make([]*db.WebhookDelivery, 0, len(chunk))
+	payloads := make([][]byte, 0, len(chunk))
+
+	for _, delivery := range chunk {
+		// Mark as in progress
+		if err := d.deliveries.MarkInProgress(ctx, delivery.ID); err != nil {
+			d.logger.Error("failed to process delivery",
+				"delivery_id", delivery.ID,
+				"error", fmt.Errorf("failed to mark in progress: %w", err),
+			)
+			continue
+		}
+
+		// Decrypt payload (plaintext payloads pass through unchanged)
+		payload, err := d.cipher.Open(ctx, delivery.Payload)
+		if err != nil {
+			d.fail(ctx, delivery, nil, fmt.Sprintf("failed to decrypt payload: %v", err))
+			continue
+		}
+
+		ready = append(ready, delivery)
+		payloads = append(payloads, payload)
+	}
+
+	if len(ready) == 0 {
+		return
+	}
+
+	body, batchSize, idempotencyKey := payloads[0], 0, ""
+	if webhook.BatchSize > 1 {
+		body, batchSize = batchPayload(payloads), len(payloads)
+	} else if ready[0].IdempotencyKey != nil {
+		idempotencyKey = *ready[0].IdempotencyKey
 	}
 
 	// Deliver webhook
-	statusCode, err := d.deliver(ctx, webhook, delivery.Payload)
+	start := time.Now()
+	statusCode, err := d.deliver(ctx, webhook, body, batchSize, idempotencyKey)
+	if d.metrics != nil {
+		code := 0
+		if statusCode != nil {
+			code = *statusCode
+		}
+		d.metrics.RecordWebhookAttempt(ctx, code, err != nil, len(ready), time.Since(start))
+	}
 	if err != nil {
-		errMsg := err.Error()
-		nextAttempt := d.calculateNextAttempt(delivery.Attempts)
-		d.logger.Warn("delivery failed",
+		for _, delivery := range ready {
+			d.logger.Warn("delivery failed",
+				"delivery_id", delivery.ID,
+				"webhook_id", webhook.ID,
+				"attempt", delivery.Attempts+1,
+				"error", err.Error(),
+			)
+			d.fail(ctx, delivery, statusCode, err.Error())
+		}
+		return
+	}
+
+	// Success
+	for _, delivery := range ready {
+		d.logger.Info("delivery successful",
 			"delivery_id", delivery.ID,
 			"webhook_id", webhook.ID,
-			"attempt", delivery.Attempts+1,
-			"error", errMsg,
-			"next_attempt", nextAttempt,
+			"status_code", *statusCode,
+			"batch_size", batchSize,
 		)
-		return d.deliveries.MarkFailed(ctx, delivery.ID, statusCode, errMsg, nextAttempt)
+		if err := d.deliveries.MarkDelivered(ctx, delivery.ID, *statusCode); err != nil {
+			d.logger.Error("failed to process delivery",
+				"delivery_id", delivery.ID,
+				"error", err,
+			)
+			continue
+		}
+		if d.metrics != nil {
+			d.metrics.RecordWebhookDelivered(ctx, time.Since(delivery.CreatedAt), delivery.Attempts+1)
+		}
 	}
+}
 
-	// Success
-	d.logger.Info("delivery successful",
-		"delivery_id", delivery.ID,
-		"webhook_id", webhook.ID,
-		"status_code", *statusCode,
-	)
-	return d.deliveries.MarkDelivered(ctx, delivery.ID, *statusCode)
+// fail records a failed attempt and schedules a retry with backoff.
+func (d *Dispatcher) fail(ctx context.Context, delivery *db.WebhookDelivery, statusCode *int, errMsg string) {
+	nextAttempt := d.calculateNextAttempt(delivery.Attempts)
+	if err := d.deliveries.MarkFailed(ctx, delivery.ID, statusCode, errMsg, nextAttempt); err != nil {
+		d.logger.Error("failed to process delivery",
+			"delivery_id", delivery.ID,
+			"error", err,
+		)
+		return
+	}
+	if d.metrics != nil {
+		// MarkFailed dead-letters the delivery on its last attempt
+		attempts := delivery.Attempts + 1
+		d.metrics.RecordWebhookFailed(ctx, attempts >= delivery.MaxAttempts, attempts)
+	}
+}
+
+// failAll marks every delivery as in progress and then failed with errMsg.
+func (d *Dispatcher) failAll(ctx context.Context, deliveries []*db.WebhookDelivery, errMsg string) {
+	for _, delivery := range deliveries {
+		if err := d.deliveries.MarkInProgress(ctx, delivery.ID); err != nil {
+			d.logger.Error("failed to process delivery",
+				"delivery_id", delivery.ID,
+				"error", fmt.Errorf("failed to mark in progress: %w", err),
+			)
+			continue
+		}
+		d.fail(ctx, delivery, nil, errMsg)
+	}
+}
+
+// limiterFor returns the rate limiter for a webhook, or nil if the webhook
+// is unlimited. Limiters are shared by all workers and follow changes to
+// the webhook's max RPS.
+func (d *Dispatcher) limiterFor(webhook *db.Webhook) *rate.Limiter {
+	d.limitersMu.Lock()
+	defer d.limitersMu.Unlock()
+
+	if webhook.MaxRPS <= 0 {
+		delete(d.limiters, webhook.ID)
+		return nil
+	}
+
+	limit := rate.Limit(webhook.MaxRPS)
+	burst := max(1, int(math.Ceil(webhook.MaxRPS)))
+
+	limiter, ok := d.limiters[webhook.ID]
+	if !ok {
+		limiter = rate.NewLimiter(limit, burst)
+		d.limiters[webhook.ID] = limiter
+	} else if limiter.Limit() != limit {
+		limiter.SetLimit(limit)
+		limiter.SetBurst(burst)
+	}
+
+	return limiter
 }
 
-// deliver makes the HTTP request to the webhook endpoint.
-func (d *Dispatcher) deliver(ctx context.Context, webhook *db.Webhook, payload []byte) (*int, error) {
+// groupByWebhook groups deliveries by webhook ID, preserving the order in
+// which each webhook first appears.
+func groupByWebhook(deliveries []*db.WebhookDelivery) [][]*db.WebhookDelivery {
+	index := make(map[string]int)
+	var groups [][]*db.WebhookDelivery
+	for _, delivery := range deliveries {
+		i, ok := index[delivery.WebhookID]
+		if !ok {
+			i = len(groups)
+			index[delivery.WebhookID] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], delivery)
+	}
+	return groups
+}
+
+// chunkDeliveries splits deliveries into chunks of up to size. A size of 1
+// or less yields one delivery per chunk.
+func chunkDeliveries(deliveries []*db.WebhookDelivery, size int) [][]*db.WebhookDelivery {
+	size = max(size, 1)
+	chunks := make([][]*db.WebhookDelivery, 0, (len(deliveries)+size-1)/size)
+	for start := 0; start < len(deliveries); start += size {
+		end := min(start+size, len(deliveries))
+		chunks = append(chunks, deliveries[start:end])
+	}
+	return chunks
+}
+
+// batchPayload joins JSON payloads into a single JSON array.
+func batchPayload(payloads [][]byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	buf.Write(bytes.Join(payloads, []byte(",")))
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
+
+// batchSizeHeader carries the number of deliveries in a batched payload.
+const batchSizeHeader = "X-Batch-Size"
+
+// idempotencyKeyHeader carries the idempotency key of a single delivery, so
+// receivers can discard retries they already handled. Batched payloads carry
+// the key of each delivery in its idempotency_key field instead.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// deliver makes the HTTP request to the webhook endpoint. A non-zero
+// batchSize marks the payload as a batch of that many deliveries; a non-empty
+// idempotencyKey is sent in the Idempotency-Key header.
+func (d *Dispatcher) deliver(ctx context.Context, webhook *db.Webhook, payload []byte, batchSize int, idempotencyKey string) (*int, error) {
 	// Create request
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
 	if err != nil {
@@ -175,6 +526,12 @@ func (d *Dispatcher) deliver(ctx context.Context, webhook *db.Webhook, payload [
 
 	// Set content type
 	req.Header.Set("Content-Type", "application/json")
+	if batchSize > 0 {
+		req.Header.Set(batchSizeHeader, strconv.Itoa(batchSize))
+	}
+	if idempotencyKey != "" {
+		req.Header.Set(idempotencyKeyHeader, idempotencyKey)
+	}
 
 	// Add custom headers
 	for key, value := range webhook.Headers {
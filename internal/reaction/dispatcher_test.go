@@ -0,0 +1,321 @@
+package reaction
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/SebastienMelki/causality/internal/observability"
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// fakeDeliveryStore records delivery state transitions in memory.
+type fakeDeliveryStore struct {
+	mu        sync.Mutex
+	pending   []*db.WebhookDelivery
+	delivered []string
+	failed    []string
+}
+
+func (f *fakeDeliveryStore) GetPending(_ context.Context, overdue time.Duration, limit int) ([]*db.WebhookDelivery, error) {
+	return f.pending, nil
+}
+
+func (f *fakeDeliveryStore) GetPendingByIDs(_ context.Context, ids []string) ([]*db.WebhookDelivery, error) {
+	var deliveries []*db.WebhookDelivery
+	for _, delivery := range f.pending {
+		for _, id := range ids {
+			if delivery.ID == id {
+				deliveries = append(deliveries, delivery)
+			}
+		}
+	}
+	return deliveries, nil
+}
+
+func (f *fakeDeliveryStore) MarkInProgress(_ context.Context, id string) error {
+	return nil
+}
+
+func (f *fakeDeliveryStore) MarkDelivered(_ context.Context, id string, statusCode int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.delivered = append(f.delivered, id)
+	return nil
+}
+
+func (f *fakeDeliveryStore) MarkFailed(_ context.Context, id string, statusCode *int, errMsg string, nextAttemptAt time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failed = append(f.failed, id)
+	return nil
+}
+
+type fakeWebhookStore map[string]*db.Webhook
+
+func (f fakeWebhookStore) GetByID(_ context.Context, id string) (*db.Webhook, error) {
+	webhook, ok := f[id]
+	if !ok {
+		return nil, db.ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// webhookRequest is a request received by the test webhook server.
+type webhookRequest struct {
+	body           string
+	batchSize      string
+	idempotencyKey string
+}
+
+func newWebhookServer(t *testing.T, status int) (*httptest.Server, func() []webhookRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []webhookRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, webhookRequest{
+			body:           string(body),
+			batchSize:      r.Header.Get(batchSizeHeader),
+			idempotencyKey: r.Header.Get(idempotencyKeyHeader),
+		})
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []webhookRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]webhookRequest(nil), requests...)
+	}
+}
+
+func testDeliveries(webhookID string, n int) []*db.WebhookDelivery {
+	deliveries := make([]*db.WebhookDelivery, n)
+	for i := range deliveries {
+		key := "rule-1:event-" + strconv.Itoa(i)
+		deliveries[i] = &db.WebhookDelivery{
+			ID:             webhookID + "-d" + strconv.Itoa(i),
+			WebhookID:      webhookID,
+			Payload:        json.RawMessage(`{"n":` + strconv.Itoa(i) + `}`),
+			IdempotencyKey: &key,
+		}
+	}
+	return deliveries
+}
+
+func newTestDispatcher(deliveries *fakeDeliveryStore, webhooks fakeWebhookStore) *Dispatcher {
+	return newDispatcher(deliveries, webhooks, DispatcherConfig{
+		BatchSize:         100,
+		InitialBackoff:    time.Second,
+		MaxBackoff:        time.Minute,
+		BackoffMultiplier: 2,
+		RequestTimeout:    5 * time.Second,
+	}, nil, nil)
+}
+
+func TestDispatcher_Unbatched(t *testing.T) {
+	server, requests := newWebhookServer(t, http.StatusOK)
+	store := &fakeDeliveryStore{pending: testDeliveries("w1", 2)}
+	d := newTestDispatcher(store, fakeWebhookStore{
+		"w1": {ID: "w1", URL: server.URL, Enabled: true},
+	})
+
+	d.processDeliveries(context.Background())
+
+	got := requests()
+	if len(got) != 2 {
+		t.Fatalf("requests: got %d, want 2", len(got))
+	}
+	if got[0].body != `{"n":0}` || got[0].batchSize != "" {
+		t.Errorf("first request: got body %s, batch header %q; want single payload without header", got[0].body, got[0].batchSize)
+	}
+	if got[1].idempotencyKey != "rule-1:event-1" {
+		t.Errorf("second request: got idempotency key %q, want rule-1:event-1", got[1].idempotencyKey)
+	}
+	if len(store.delivered) != 2 {
+		t.Errorf("delivered: got %v, want 2 deliveries", store.delivered)
+	}
+}
+
+func TestDispatcher_Batching(t *testing.T) {
+	server, requests := newWebhookServer(t, http.StatusOK)
+	store := &fakeDeliveryStore{pending: testDeliveries("w1", 3)}
+	d := newTestDispatcher(store, fakeWebhookStore{
+		"w1": {ID: "w1", URL: server.URL, Enabled: true, BatchSize: 2},
+	})
+
+	d.processDeliveries(context.Background())
+
+	got := requests()
+	if len(got) != 2 {
+		t.Fatalf("requests: got %d, want 2", len(got))
+	}
+	if got[0].body != `[{"n":0},{"n":1}]` || got[0].batchSize != "2" {
+		t.Errorf("first batch: got body %s, batch header %q", got[0].body, got[0].batchSize)
+	}
+	if got[0].idempotencyKey != "" {
+		t.Errorf("first batch: got idempotency key %q, want none", got[0].idempotencyKey)
+	}
+	if got[1].body != `[{"n":2}]` || got[1].batchSize != "1" {
+		t.Errorf("second batch: got body %s, batch header %q", got[1].body, got[1].batchSize)
+	}
+	if len(store.delivered) != 3 {
+		t.Errorf("delivered: got %v, want 3 deliveries", store.delivered)
+	}
+}
+
+func TestDispatcher_BatchFailureFailsEveryDelivery(t *testing.T) {
+	server, _ := newWebhookServer(t, http.StatusServiceUnavailable)
+	store := &fakeDeliveryStore{pending: testDeliveries("w1", 2)}
+	d := newTestDispatcher(store, fakeWebhookStore{
+		"w1": {ID: "w1", URL: server.URL, Enabled: true, BatchSize: 10},
+	})
+
+	d.processDeliveries(context.Background())
+
+	if len(store.failed) != 2 || len(store.delivered) != 0 {
+		t.Errorf("got failed=%v delivered=%v, want both failed", store.failed, store.delivered)
+	}
+}
+
+func TestDispatcher_RateLimitDefersDeliveries(t *testing.T) {
+	limited, limitedRequests := newWebhookServer(t, http.StatusOK)
+	open, openRequests := newWebhookServer(t, http.StatusOK)
+
+	pending := append(testDeliveries("w1", 3), testDeliveries("w2", 2)...)
+	store := &fakeDeliveryStore{pending: pending}
+	d := newTestDispatcher(store, fakeWebhookStore{
+		"w1": {ID: "w1", URL: limited.URL, Enabled: true, MaxRPS: 1},
+		"w2": {ID: "w2", URL: open.URL, Enabled: true},
+	})
+
+	d.processDeliveries(context.Background())
+
+	if got := len(limitedRequests()); got != 1 {
+		t.Errorf("rate-limited webhook requests: got %d, want 1", got)
+	}
+	if got := len(openRequests()); got != 2 {
+		t.Errorf("unlimited webhook requests: got %d, want 2", got)
+	}
+	if len(store.delivered) != 3 || len(store.failed) != 0 {
+		t.Errorf("got delivered=%v failed=%v, want 3 delivered and deferred deliveries untouched",
+			store.delivered, store.failed)
+	}
+}
+
+func TestDispatcher_LimiterFollowsMaxRPS(t *testing.T) {
+	d := newTestDispatcher(&fakeDeliveryStore{}, fakeWebhookStore{})
+	webhook := &db.Webhook{ID: "w1", MaxRPS: 5}
+
+	limiter := d.limiterFor(webhook)
+	if limiter == nil || limiter.Burst() != 5 {
+		t.Fatalf("limiter: got %v, want burst 5", limiter)
+	}
+
+	webhook.MaxRPS = 0.5
+	if got := d.limiterFor(webhook); got != limiter || got.Burst() != 1 {
+		t.Errorf("updated limiter: got %p burst %d, want same limiter with burst 1", got, got.Burst())
+	}
+
+	webhook.MaxRPS = 0
+	if got := d.limiterFor(webhook); got != nil {
+		t.Errorf("unlimited webhook: got limiter %v, want nil", got)
+	}
+}
+
+func TestDispatcher_Metrics(t *testing.T) {
+	ok, _ := newWebhookServer(t, http.StatusOK)
+	down, _ := newWebhookServer(t, http.StatusServiceUnavailable)
+
+	delivered := testDeliveries("w1", 2)
+	for _, delivery := range delivered {
+		delivery.CreatedAt = time.Now().Add(-10 * time.Second)
+		delivery.MaxAttempts = 5
+	}
+	delivered[1].Attempts = 2
+	failing := testDeliveries("w2", 2)
+	failing[0].MaxAttempts = 5
+	failing[1].Attempts, failing[1].MaxAttempts = 4, 5
+
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	metrics, err := observability.NewMetrics(provider.Meter("test"))
+	if err != nil {
+		t.Fatalf("NewMetrics: %v", err)
+	}
+
+	store := &fakeDeliveryStore{pending: append(delivered, failing...)}
+	d := newTestDispatcher(store, fakeWebhookStore{
+		"w1": {ID: "w1", URL: ok.URL, Enabled: true},
+		"w2": {ID: "w2", URL: down.URL, Enabled: true},
+	})
+	d.SetMetrics(metrics)
+
+	d.processDeliveries(context.Background())
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	statuses := make(map[string]int64)
+	attemptCounts := make(map[string]uint64)
+	var ages metricdata.HistogramDataPoint[float64]
+	var requests uint64
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				if m.Name != "webhook.deliveries" {
+					continue
+				}
+				for _, dp := range data.DataPoints {
+					status, _ := dp.Attributes.Value("status")
+					statuses[status.AsString()] = dp.Value
+				}
+			case metricdata.Histogram[int64]:
+				for _, dp := range data.DataPoints {
+					status, _ := dp.Attributes.Value("status")
+					attemptCounts[status.AsString()] = dp.Count
+				}
+			case metricdata.Histogram[float64]:
+				switch m.Name {
+				case "webhook.delivery.age":
+					ages = data.DataPoints[0]
+				case "webhook.attempt.duration":
+					for _, dp := range data.DataPoints {
+						requests += dp.Count
+					}
+				}
+			}
+		}
+	}
+
+	want := map[string]int64{"delivered": 2, "retrying": 1, "dead_letter": 1}
+	for status, n := range want {
+		if statuses[status] != n {
+			t.Errorf("webhook.deliveries{status=%s}: got %d, want %d", status, statuses[status], n)
+		}
+	}
+	if attemptCounts["delivered"] != 2 || attemptCounts["dead_letter"] != 1 || attemptCounts["retrying"] != 0 {
+		t.Errorf("webhook.delivery.attempts counts: got %v, want 2 delivered and 1 dead-lettered", attemptCounts)
+	}
+	if minAge, _ := ages.Min.Value(); ages.Count != 2 || minAge < 10 {
+		t.Errorf("webhook.delivery.age: got count %d min %v, want 2 deliveries of at least 10s", ages.Count, minAge)
+	}
+	if requests != 4 {
+		t.Errorf("webhook.attempt.duration: got %d requests, want 4", requests)
+	}
+}
@@ -3,33 +3,78 @@ package reaction
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math/rand"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	natsgo "github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
+	otelmetric "go.opentelemetry.io/otel/metric"
 
+	"github.com/SebastienMelki/causality/internal/devices"
 	"github.com/SebastienMelki/causality/internal/events"
 	"github.com/SebastienMelki/causality/internal/reaction/db"
 	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
 )
 
+// ruleStore is the subset of db.RuleRepository used by Engine.
+type ruleStore interface {
+	GetEnabled(ctx context.Context) ([]*db.Rule, error)
+	RecordShadowMatch(ctx context.Context, ruleID string, sample *db.ShadowSample, maxSamples int) error
+}
+
+// deliveryCreator is the subset of db.DeliveryRepository used by Engine and
+// Escalator.
+type deliveryCreator interface {
+	CreateBatch(ctx context.Context, deliveries []*db.WebhookDelivery) (int, error)
+}
+
+// DeviceLookup reads device registry records for conditions with the
+// "device" source. It is satisfied by *devices.Module.
+type DeviceLookup interface {
+	Lookup(ctx context.Context, appID, deviceID string) (*devices.Device, error)
+}
+
+// CurrencyConverter converts purchase amounts to US dollars. It is satisfied
+// by *fx.Module.
+type CurrencyConverter interface {
+	ToUSD(currency string, minorUnits int64, at time.Time) (float64, bool)
+}
+
 // Engine evaluates events against rules and triggers actions.
 type Engine struct {
-	rules         *db.RuleRepository
+	rules         ruleStore
 	webhooks      *db.WebhookRepository
-	deliveries    *db.DeliveryRepository
+	deliveries    deliveryCreator
+	deliveryQueue *DeliveryQueue
 	js            jetstream.JetStream
 	config        EngineConfig
 	dispatcherCfg DispatcherConfig
+	payloadCipher *PayloadCipher
+	devices       DeviceLookup
+	currency      CurrencyConverter
+	counters      *ruleCounters
+	push          PushSender
+	maintenance   *MaintenanceSchedule
+	derived       derivedMessages
+	history       historyStore
+	users         UserLookup
+	userCache     *userCache
 	logger        *slog.Logger
 
 	mu          sync.RWMutex
 	cachedRules []*db.Rule
+	// historyEvents are the events referenced by the history conditions of
+	// the cached rules.
+	historyEvents map[string]bool
+
+	ruleChanges <-chan struct{}
 	stopCh      chan struct{}
 	doneCh      chan struct{}
 }
@@ -42,6 +87,7 @@ func NewEngine(
 	js jetstream.JetStream,
 	config EngineConfig,
 	dispatcherCfg DispatcherConfig,
+	payloadCipher *PayloadCipher,
 	logger *slog.Logger,
 ) *Engine {
 	if logger == nil {
@@ -55,12 +101,67 @@ func NewEngine(
 		js:            js,
 		config:        config,
 		dispatcherCfg: dispatcherCfg,
+		payloadCipher: payloadCipher,
 		logger:        logger.With("component", "reaction-engine"),
 		stopCh:        make(chan struct{}),
 		doneCh:        make(chan struct{}),
 	}
 }
 
+// SetRuleChanges sets a channel signalled when rules change in the database
+// (see db.Client.ListenRuleChanges). The engine then refreshes its cache
+// shortly after each change, and the refresh interval becomes a fallback.
+// Must be called before Start.
+func (e *Engine) SetRuleChanges(changes <-chan struct{}) {
+	e.ruleChanges = changes
+}
+
+// SetDeviceLookup sets the device registry read by conditions with the
+// "device" source. Without it those conditions see no device record, so
+// only "not_exists" matches. Must be called before Start.
+func (e *Engine) SetDeviceLookup(lookup DeviceLookup) {
+	e.devices = lookup
+}
+
+// SetCurrencyConverter sets the converter used to add amount_usd to
+// purchase_complete events, so rules can compare revenue across currencies.
+// Must be called before Start.
+func (e *Engine) SetCurrencyConverter(converter CurrencyConverter) {
+	e.currency = converter
+}
+
+// SetMeter sets the meter that creates the counters of rule metric actions.
+// Without it metric actions are ignored. Must be called before Start.
+func (e *Engine) SetMeter(meter otelmetric.Meter) {
+	e.counters = newRuleCounters(meter, e.config.MetricMaxSeries, e.logger)
+}
+
+// SetPushSender sets the sender of push_notification actions. Without it
+// those actions are ignored. Must be called before Start.
+func (e *Engine) SetPushSender(sender PushSender) {
+	e.push = sender
+}
+
+// SetDeliveryQueue sets the queue the IDs of new webhook deliveries are
+// enqueued to, so dispatcher workers consuming it send them without waiting
+// for a Postgres poll. Must be called before Start.
+func (e *Engine) SetDeliveryQueue(queue *DeliveryQueue) {
+	e.deliveryQueue = queue
+}
+
+// SetMaintenance sets the maintenance windows that suppress rule actions.
+// Suppressed matches are recorded instead. Must be called before Start.
+func (e *Engine) SetMaintenance(schedule *MaintenanceSchedule) {
+	e.maintenance = schedule
+}
+
+// SetDerivedStream sets the derived stream read by conditions with the
+// "derived" source. Without it those conditions see no derived message, so
+// only "not_exists" matches. Must be called before Start.
+func (e *Engine) SetDerivedStream(stream jetstream.Stream) {
+	e.derived = stream
+}
+
 // Start starts the engine's background tasks (rule refresh).
 func (e *Engine) Start(ctx context.Context) error {
 	// Load initial rules
@@ -71,9 +172,14 @@ func (e *Engine) Start(ctx context.Context) error {
 	// Start background rule refresh
 	go e.refreshLoop(ctx)
 
+	if e.history != nil {
+		go e.pruneHistory(ctx)
+	}
+
 	e.logger.Info("rule engine started",
 		"rule_count", len(e.cachedRules),
 		"refresh_interval", e.config.RuleRefreshInterval,
+		"change_notifications", e.ruleChanges != nil,
 	)
 
 	return nil
@@ -85,19 +191,31 @@ func (e *Engine) Stop() {
 	<-e.doneCh
 }
 
-// refreshLoop periodically refreshes rules from the database.
+// refreshLoop refreshes rules from the database periodically and, if a
+// change channel is set, shortly after each change notification.
 func (e *Engine) refreshLoop(ctx context.Context) {
 	defer close(e.doneCh)
 
 	ticker := time.NewTicker(e.config.RuleRefreshInterval)
 	defer ticker.Stop()
 
+	// debounce is armed by a change notification and fires the refresh.
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+	defer debounce.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-e.stopCh:
 			return
+		case <-e.ruleChanges:
+			debounce.Reset(e.config.RuleChangeDebounce)
+		case <-debounce.C:
+			if err := e.refreshRules(ctx); err != nil {
+				e.logger.Error("failed to refresh rules after change", "error", err)
+			}
 		case <-ticker.C:
 			if err := e.refreshRules(ctx); err != nil {
 				e.logger.Error("failed to refresh rules", "error", err)
@@ -113,21 +231,103 @@ func (e *Engine) refreshRules(ctx context.Context) error {
 		return err
 	}
 
+	historyEvents := historyEventNames(rules)
+
 	e.mu.Lock()
 	e.cachedRules = rules
+	e.historyEvents = historyEvents
 	e.mu.Unlock()
 
 	e.logger.Debug("rules refreshed", "count", len(rules))
 	return nil
 }
 
+// RefreshRules reloads the rule cache immediately, e.g. after an admin
+// rollback, instead of waiting for the next refresh interval.
+func (e *Engine) RefreshRules(ctx context.Context) error {
+	return e.refreshRules(ctx)
+}
+
 // ProcessEvent evaluates an event against all matching rules.
 func (e *Engine) ProcessEvent(ctx context.Context, event *pb.EventEnvelope) error {
+	var deliveries []*db.WebhookDelivery
+	if err := e.processEvent(ctx, event, &deliveries); err != nil {
+		return err
+	}
+	e.insertDeliveries(ctx, deliveries)
+	return nil
+}
+
+// ProcessEvents evaluates a batch of events, up to MaxConcurrentEvaluations
+// at a time, and inserts the webhook deliveries of the whole batch in one
+// transaction. With ordered set, the events of each device are evaluated one
+// at a time in batch order, so their actions run in that order. The returned
+// slice holds the error of each event, nil on success.
+func (e *Engine) ProcessEvents(ctx context.Context, batch []*pb.EventEnvelope, ordered bool) []error {
+	errs := make([]error, len(batch))
+	queued := make([][]*db.WebhookDelivery, len(batch))
+
+	limit := e.config.MaxConcurrentEvaluations
+	if limit < 1 {
+		limit = 1
+	}
+	sem := make(chan struct{}, limit)
+
+	var wg sync.WaitGroup
+	for _, group := range evaluationGroups(batch, ordered) {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			for _, i := range group {
+				errs[i] = e.processEvent(ctx, batch[i], &queued[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	var deliveries []*db.WebhookDelivery
+	for _, q := range queued {
+		deliveries = append(deliveries, q...)
+	}
+	e.insertDeliveries(ctx, deliveries)
+
+	return errs
+}
+
+// evaluationGroups splits a batch into groups of event indexes evaluated one
+// after another. Unordered, every event is its own group; ordered, the events
+// of a device share a group in batch order. Events without a device id are
+// never grouped.
+func evaluationGroups(batch []*pb.EventEnvelope, ordered bool) [][]int {
+	groups := make([][]int, 0, len(batch))
+	byDevice := make(map[string]int)
+	for i, event := range batch {
+		if ordered && event.DeviceId != "" {
+			if g, ok := byDevice[event.DeviceId]; ok {
+				groups[g] = append(groups[g], i)
+				continue
+			}
+			byDevice[event.DeviceId] = len(groups)
+		}
+		groups = append(groups, []int{i})
+	}
+	return groups
+}
+
+// processEvent evaluates an event against all matching rules and executes
+// their actions. Webhook deliveries are appended to deliveries instead of
+// being inserted, so callers can insert those of many events together.
+func (e *Engine) processEvent(ctx context.Context, event *pb.EventEnvelope, deliveries *[]*db.WebhookDelivery) error {
 	category, eventType := events.GetCategoryAndType(event)
 	appID := event.AppId
 
 	e.mu.RLock()
 	rules := e.cachedRules
+	historyEvents := e.historyEvents
 	e.mu.RUnlock()
 
 	// Convert event to JSON for condition evaluation
@@ -136,7 +336,12 @@ func (e *Engine) ProcessEvent(ctx context.Context, event *pb.EventEnvelope) erro
 		return fmt.Errorf("failed to convert event to JSON: %w", err)
 	}
 
-	matchedRules := e.findMatchingRules(rules, appID, category, eventType, eventJSON)
+	data := e.newConditionData(ctx, event, eventJSON)
+	matchedRules := e.findMatchingRules(rules, appID, category, eventType, data)
+
+	// Recorded after evaluation, so history conditions of the event's own
+	// rules see the device's previous occurrence
+	e.recordHistory(ctx, event, category, eventType, historyEvents)
 
 	if len(matchedRules) == 0 {
 		e.logger.Debug("no rules matched",
@@ -155,22 +360,242 @@ func (e *Engine) ProcessEvent(ctx context.Context, event *pb.EventEnvelope) erro
 		"matched_rules", len(matchedRules),
 	)
 
-	// Execute actions for each matched rule
+	e.fireRules(ctx, matchedRules, event, eventJSON, nil, deliveries)
+	return nil
+}
+
+// fireRules executes the actions of matched rules. Shadow rules and rules of
+// apps in maintenance only record the match. chain lists the rules that
+// produced a derived event, and is nil for ingested events.
+func (e *Engine) fireRules(ctx context.Context, matchedRules []*db.Rule, event *pb.EventEnvelope, eventJSON map[string]interface{}, chain []string, deliveries *[]*db.WebhookDelivery) {
+	window := e.maintenance.RuleWindow(event.AppId)
 	for _, rule := range matchedRules {
-		if err := e.executeActions(ctx, rule, event, eventJSON); err != nil {
+		if rule.Shadow {
+			e.recordShadowMatch(ctx, rule, event, eventJSON)
+			continue
+		}
+		if window != nil {
+			e.recordSuppressedMatch(ctx, rule, event, window)
+			continue
+		}
+
+		if err := e.executeActions(ctx, rule, event, eventJSON, chain, deliveries); err != nil {
 			e.logger.Error("failed to execute rule actions",
 				"rule_id", rule.ID,
 				"rule_name", rule.Name,
+				"rule_version", rule.Version,
 				"error", err,
 			)
 		}
 	}
+}
 
-	return nil
+// newConditionData returns the documents an event's rule conditions are
+// evaluated against.
+func (e *Engine) newConditionData(ctx context.Context, event *pb.EventEnvelope, eventJSON map[string]interface{}) *conditionData {
+	return &conditionData{
+		event:      eventJSON,
+		at:         eventTime(event),
+		loadDevice: func() map[string]interface{} { return e.deviceToJSON(ctx, event) },
+		loadUser:   func() map[string]interface{} { return e.userToJSON(ctx, event) },
+		loadDerived: func(family, name string) map[string]interface{} {
+			return e.derivedToJSON(ctx, event.AppId, family, name)
+		},
+		loadHistory: func() map[string]time.Time {
+			e.mu.RLock()
+			names := e.historyEvents
+			e.mu.RUnlock()
+			return e.lastSeen(ctx, event, names)
+		},
+	}
+}
+
+// recordShadowMatch records that a shadow rule would have fired, storing the
+// event as a sample with probability ShadowSampleRate.
+func (e *Engine) recordShadowMatch(ctx context.Context, rule *db.Rule, event *pb.EventEnvelope, eventJSON map[string]interface{}) {
+	var sample *db.ShadowSample
+	if e.shouldSampleShadow() {
+		eventData, err := json.Marshal(eventJSON)
+		if err != nil {
+			e.logger.Warn("failed to marshal shadow sample", "rule_id", rule.ID, "error", err)
+		} else {
+			sample = &db.ShadowSample{
+				RuleVersion: rule.Version,
+				EventID:     event.Id,
+				AppID:       event.AppId,
+				EventData:   eventData,
+			}
+		}
+	}
+
+	if err := e.rules.RecordShadowMatch(ctx, rule.ID, sample, e.config.ShadowMaxSamples); err != nil {
+		e.logger.Error("failed to record shadow match",
+			"rule_id", rule.ID,
+			"error", err,
+		)
+		return
+	}
+
+	e.logger.Debug("shadow rule matched",
+		"rule_id", rule.ID,
+		"rule_name", rule.Name,
+		"rule_version", rule.Version,
+		"event_id", event.Id,
+		"sampled", sample != nil,
+	)
+}
+
+// recordSuppressedMatch records that a maintenance window suppressed a
+// rule's actions.
+func (e *Engine) recordSuppressedMatch(ctx context.Context, rule *db.Rule, event *pb.EventEnvelope, window *db.MaintenanceWindow) {
+	match := &db.SuppressedRuleMatch{
+		RuleID:              rule.ID,
+		RuleVersion:         rule.Version,
+		MaintenanceWindowID: &window.ID,
+		EventID:             event.Id,
+		AppID:               event.AppId,
+	}
+	if err := e.maintenance.RecordSuppressedMatch(ctx, match); err != nil {
+		e.logger.Error("failed to record suppressed match",
+			"rule_id", rule.ID,
+			"maintenance_window_id", window.ID,
+			"error", err,
+		)
+		return
+	}
+
+	e.logger.Debug("rule actions suppressed by maintenance window",
+		"rule_id", rule.ID,
+		"rule_name", rule.Name,
+		"maintenance_window", window.Name,
+		"event_id", event.Id,
+	)
+}
+
+// shouldSampleShadow reports whether a shadow match should be stored as a sample.
+func (e *Engine) shouldSampleShadow() bool {
+	if e.config.ShadowSampleRate <= 0 || e.config.ShadowMaxSamples <= 0 {
+		return false
+	}
+	return rand.Float64() < e.config.ShadowSampleRate //nolint:gosec // sampling, not security
+}
+
+// conditionData holds the documents rule conditions are evaluated against.
+// The device, user, derived and history documents are loaded on first use,
+// so events whose rules only have event conditions never query the device
+// registry, the profile store, the derived stream or the event history.
+type conditionData struct {
+	event       map[string]interface{}
+	at          time.Time
+	loadDevice  func() map[string]interface{}
+	loadUser    func() map[string]interface{}
+	loadDerived func(family, name string) map[string]interface{}
+	loadHistory func() map[string]time.Time
+
+	device       map[string]interface{}
+	deviceLoaded bool
+
+	user       map[string]interface{}
+	userLoaded bool
+
+	history       map[string]time.Time
+	historyLoaded bool
+
+	// derived maps family to name to the document of the last message on
+	// the subject, nil if there is none.
+	derived map[string]interface{}
+}
+
+// source returns the document for a condition's source, or nil if the
+// source is unknown or has no document for this event.
+func (d *conditionData) source(cond db.Condition) map[string]interface{} {
+	switch cond.Source {
+	case "", db.ConditionSourceEvent:
+		return d.event
+	case db.ConditionSourceDevice:
+		if !d.deviceLoaded {
+			d.deviceLoaded = true
+			if d.loadDevice != nil {
+				d.device = d.loadDevice()
+			}
+		}
+		return d.device
+	case db.ConditionSourceUser:
+		if !d.userLoaded {
+			d.userLoaded = true
+			if d.loadUser != nil {
+				d.user = d.loadUser()
+			}
+		}
+		return d.user
+	case db.ConditionSourceDerived:
+		return d.derivedSource(cond.Path)
+	default:
+		return nil
+	}
+}
+
+// lastSeen returns when the event's device last sent the events referenced
+// by history conditions, loading them on first use.
+func (d *conditionData) lastSeen() map[string]time.Time {
+	if !d.historyLoaded {
+		d.historyLoaded = true
+		if d.loadHistory != nil {
+			d.history = d.loadHistory()
+		}
+	}
+	return d.history
+}
+
+// derivedSource returns a document holding the last message on the derived
+// subject named by the first two tokens of path, {family}.{name}, loading
+// it on first use.
+func (d *conditionData) derivedSource(path string) map[string]interface{} {
+	tokens := strings.SplitN(strings.TrimPrefix(path, "$."), ".", 3)
+	if len(tokens) < 3 || d.loadDerived == nil {
+		return nil
+	}
+	family, name := tokens[0], tokens[1]
+
+	if d.derived == nil {
+		d.derived = make(map[string]interface{})
+	}
+	names, ok := d.derived[family].(map[string]interface{})
+	if !ok {
+		names = make(map[string]interface{})
+		d.derived[family] = names
+	}
+	if _, loaded := names[name]; !loaded {
+		names[name] = d.loadDerived(family, name)
+	}
+	return d.derived
+}
+
+// deviceToJSON returns the device registry record of the event's device as
+// a JSON map, or nil if there is no registry or no record. Lookup errors
+// are logged and treated as a missing record.
+func (e *Engine) deviceToJSON(ctx context.Context, event *pb.EventEnvelope) map[string]interface{} {
+	if e.devices == nil || event.DeviceId == "" {
+		return nil
+	}
+
+	device, err := e.devices.Lookup(ctx, event.AppId, event.DeviceId)
+	if err != nil {
+		if !errors.Is(err, devices.ErrDeviceNotFound) {
+			e.logger.Warn("failed to look up device for rule conditions",
+				"app_id", event.AppId,
+				"device_id", event.DeviceId,
+				"error", err,
+			)
+		}
+		return nil
+	}
+
+	return structToMap(device)
 }
 
 // findMatchingRules finds rules that match the event.
-func (e *Engine) findMatchingRules(rules []*db.Rule, appID, category, eventType string, eventJSON map[string]interface{}) []*db.Rule {
+func (e *Engine) findMatchingRules(rules []*db.Rule, appID, category, eventType string, data *conditionData) []*db.Rule {
 	var matched []*db.Rule
 
 	for _, rule := range rules {
@@ -178,7 +603,7 @@ func (e *Engine) findMatchingRules(rules []*db.Rule, appID, category, eventType
 			continue
 		}
 
-		if !e.evaluateConditions(rule.Conditions, eventJSON) {
+		if !e.evaluateConditions(rule.Conditions, data) {
 			continue
 		}
 
@@ -202,14 +627,22 @@ func (e *Engine) matchesFilter(rule *db.Rule, appID, category, eventType string)
 	return true
 }
 
-// evaluateConditions evaluates all conditions against the event.
-func (e *Engine) evaluateConditions(conditions []db.Condition, eventJSON map[string]interface{}) bool {
+// evaluateConditions evaluates all conditions against the event and, for
+// conditions with other sources, its device record, derived messages or the
+// device's recent events.
+func (e *Engine) evaluateConditions(conditions []db.Condition, data *conditionData) bool {
 	if len(conditions) == 0 {
 		return true
 	}
 
 	for _, cond := range conditions {
-		if !e.evaluateCondition(cond, eventJSON) {
+		if cond.Source == db.ConditionSourceHistory {
+			if !e.evaluateHistoryCondition(cond, data) {
+				return false
+			}
+			continue
+		}
+		if !e.evaluateCondition(cond, data.source(cond)) {
 			return false
 		}
 	}
@@ -217,10 +650,11 @@ func (e *Engine) evaluateConditions(conditions []db.Condition, eventJSON map[str
 	return true
 }
 
-// evaluateCondition evaluates a single condition.
-func (e *Engine) evaluateCondition(cond db.Condition, eventJSON map[string]interface{}) bool {
+// evaluateCondition evaluates a single condition against the document of
+// its source.
+func (e *Engine) evaluateCondition(cond db.Condition, doc map[string]interface{}) bool {
 	// Extract value at path
-	value, exists := e.extractJSONPath(eventJSON, cond.Path)
+	value, exists := e.extractJSONPath(doc, cond.Path)
 
 	switch cond.Operator {
 	case "exists":
@@ -390,12 +824,15 @@ func toFloat64(v interface{}) (float64, bool) {
 	}
 }
 
-// executeActions executes the actions for a matched rule.
-func (e *Engine) executeActions(ctx context.Context, rule *db.Rule, event *pb.EventEnvelope, eventJSON map[string]interface{}) error {
+// executeActions executes the actions for a matched rule. Its webhook
+// deliveries are appended to deliveries. chain lists the rules that produced
+// a derived event; the rule is appended to it on the messages it publishes.
+func (e *Engine) executeActions(ctx context.Context, rule *db.Rule, event *pb.EventEnvelope, eventJSON map[string]interface{}, chain []string, deliveries *[]*db.WebhookDelivery) error {
 	// Create payload for webhooks
 	payload := map[string]interface{}{
 		"rule_id":        rule.ID,
 		"rule_name":      rule.Name,
+		"rule_version":   rule.Version,
 		"event_id":       event.Id,
 		"app_id":         event.AppId,
 		"device_id":      event.DeviceId,
@@ -404,6 +841,14 @@ func (e *Engine) executeActions(ctx context.Context, rule *db.Rule, event *pb.Ev
 		"event":          eventJSON,
 		"triggered_at":   time.Now().UTC().Format(time.RFC3339),
 	}
+	idempotencyKey := deliveryIdempotencyKey(rule.ID, event.Id)
+	if idempotencyKey != "" {
+		payload["idempotency_key"] = idempotencyKey
+	}
+	published := append(chain[:len(chain):len(chain)], rule.ID)
+	if chain != nil {
+		payload["chain"] = published
+	}
 
 	payloadJSON, err := json.Marshal(payload)
 	if err != nil {
@@ -412,47 +857,119 @@ func (e *Engine) executeActions(ctx context.Context, rule *db.Rule, event *pb.Ev
 
 	// Queue webhook deliveries
 	if len(rule.Actions.Webhooks) > 0 {
-		if err := e.queueWebhooks(ctx, rule, payloadJSON); err != nil {
+		queued, err := e.webhookDeliveries(ctx, rule, idempotencyKey, payloadJSON)
+		if err != nil {
 			e.logger.Error("failed to queue webhooks",
 				"rule_id", rule.ID,
 				"error", err,
 			)
 		}
+		*deliveries = append(*deliveries, queued...)
 	}
 
 	// Publish to NATS subjects
 	if len(rule.Actions.PublishSubjects) > 0 {
-		e.publishToSubjects(ctx, rule.Actions.PublishSubjects, event.AppId, payloadJSON)
+		e.publishToSubjects(ctx, rule.Actions.PublishSubjects, event.AppId, payloadJSON, published)
+	}
+
+	// Increment counters
+	if e.counters != nil {
+		lookup := func(path string) (interface{}, bool) { return e.extractJSONPath(eventJSON, path) }
+		for _, action := range rule.Actions.Metrics {
+			if err := e.counters.increment(ctx, action, lookup); err != nil {
+				e.logger.Warn("failed to increment rule metric",
+					"rule_id", rule.ID,
+					"metric", action.Name,
+					"error", err,
+				)
+			}
+		}
+	}
+
+	// Send push notification
+	if e.push != nil && rule.Actions.PushNotification != nil {
+		e.sendPushNotification(ctx, rule, event.AppId, event.DeviceId, eventJSON)
 	}
 
 	return nil
 }
 
-// queueWebhooks creates delivery records for the specified webhooks.
-func (e *Engine) queueWebhooks(ctx context.Context, rule *db.Rule, payload []byte) error {
+// deliveryIdempotencyKey returns the idempotency key of the deliveries a
+// rule queues for an event, or "" for events without an id, whose deliveries
+// cannot be deduplicated.
+func deliveryIdempotencyKey(ruleID, eventID string) string {
+	if eventID == "" {
+		return ""
+	}
+	return ruleID + ":" + eventID
+}
+
+// webhookDeliveries builds the delivery records of a rule's webhooks.
+func (e *Engine) webhookDeliveries(ctx context.Context, rule *db.Rule, idempotencyKey string, payload []byte) ([]*db.WebhookDelivery, error) {
 	var deliveries []*db.WebhookDelivery
 
+	var key *string
+	if idempotencyKey != "" {
+		key = &idempotencyKey
+	}
+
+	stored, err := e.payloadCipher.Seal(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt payload: %w", err)
+	}
+
 	for _, webhookID := range rule.Actions.Webhooks {
 		delivery := &db.WebhookDelivery{
-			WebhookID:     webhookID,
-			RuleID:        &rule.ID,
-			Payload:       payload,
-			Status:        db.DeliveryStatusPending,
-			MaxAttempts:   e.dispatcherCfg.MaxAttempts,
-			NextAttemptAt: time.Now(),
+			WebhookID:      webhookID,
+			RuleID:         &rule.ID,
+			RuleVersion:    &rule.Version,
+			IdempotencyKey: key,
+			Payload:        stored,
+			Status:         db.DeliveryStatusPending,
+			MaxAttempts:    e.dispatcherCfg.MaxAttempts,
+			NextAttemptAt:  time.Now(),
 		}
 		deliveries = append(deliveries, delivery)
 	}
 
-	return e.deliveries.CreateBatch(ctx, deliveries)
+	return deliveries, nil
+}
+
+// insertDeliveries inserts queued webhook deliveries in one transaction.
+// Deliveries whose webhook already has one with the same idempotency key,
+// because the event was redelivered after its rule fired, are skipped.
+func (e *Engine) insertDeliveries(ctx context.Context, deliveries []*db.WebhookDelivery) {
+	if len(deliveries) == 0 {
+		return
+	}
+
+	created, err := e.deliveries.CreateBatch(ctx, deliveries)
+	if err != nil {
+		e.logger.Error("failed to queue webhooks",
+			"deliveries", len(deliveries),
+			"error", err,
+		)
+		return
+	}
+	if skipped := len(deliveries) - created; skipped > 0 {
+		e.logger.Info("skipped duplicate webhook deliveries",
+			"deliveries", len(deliveries),
+			"skipped", skipped,
+		)
+	}
+
+	enqueueDeliveries(ctx, e.deliveryQueue, deliveries, e.logger)
 }
 
 // publishToSubjects publishes to NATS subjects with template substitution.
-func (e *Engine) publishToSubjects(ctx context.Context, subjects []string, appID string, payload []byte) {
+// The chain of rules that produced the payload is set in the ChainHeader.
+func (e *Engine) publishToSubjects(ctx context.Context, subjects []string, appID string, payload []byte, chain []string) {
 	for _, subjectTemplate := range subjects {
 		subject := strings.ReplaceAll(subjectTemplate, "{app_id}", events.SanitizeSubjectName(appID))
 
-		if _, err := e.js.Publish(ctx, subject, payload); err != nil {
+		msg := &natsgo.Msg{Subject: subject, Data: payload, Header: natsgo.Header{}}
+		msg.Header.Set(ChainHeader, strings.Join(chain, ","))
+		if _, err := e.js.PublishMsg(ctx, msg); err != nil {
 			e.logger.Error("failed to publish to subject",
 				"subject", subject,
 				"error", err,
@@ -493,6 +1010,8 @@ func (e *Engine) eventToJSON(event *pb.EventEnvelope) (map[string]interface{}, e
 			"is_jailbroken": dc.IsJailbroken,
 			"is_emulator":   dc.IsEmulator,
 			"sdk_version":   dc.SdkVersion,
+			"clock_skew_ms": dc.ClockSkewMs,
+			"sdk_name":      dc.SdkName,
 		}
 	}
 
@@ -554,13 +1073,36 @@ func (e *Engine) eventToJSON(event *pb.EventEnvelope) (map[string]interface{}, e
 		result["memory_warning"] = structToMap(p.MemoryWarning)
 	case *pb.EventEnvelope_BatteryChange:
 		result["battery_change"] = structToMap(p.BatteryChange)
+	case *pb.EventEnvelope_ExperimentExposure:
+		result["experiment_exposure"] = structToMap(p.ExperimentExposure)
 	case *pb.EventEnvelope_CustomEvent:
 		result["custom_event"] = structToMap(p.CustomEvent)
 	}
 
+	addAmountUSD(result, event, e.currency)
+
 	return result, nil
 }
 
+// addAmountUSD sets purchase_complete.amount_usd in an event's JSON map to
+// the purchase total converted to US dollars, if converter is set and knows
+// the currency.
+func addAmountUSD(result map[string]interface{}, event *pb.EventEnvelope, converter CurrencyConverter) {
+	purchase := event.GetPurchaseComplete()
+	if converter == nil || purchase == nil {
+		return
+	}
+	fields, ok := result["purchase_complete"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	at := time.UnixMilli(event.GetTimestampMs())
+	if usd, ok := converter.ToUSD(purchase.GetCurrency(), purchase.GetTotalCents(), at); ok {
+		fields["amount_usd"] = usd
+	}
+}
+
 // structToMap converts a protobuf struct to a map via JSON marshaling.
 func structToMap(v interface{}) map[string]interface{} {
 	if v == nil {
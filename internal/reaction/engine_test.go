@@ -0,0 +1,267 @@
+package reaction
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/devices"
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// countingRuleStore counts rule cache refreshes.
+type countingRuleStore struct {
+	refreshes atomic.Int32
+}
+
+func (s *countingRuleStore) GetEnabled(_ context.Context) ([]*db.Rule, error) {
+	s.refreshes.Add(1)
+	return nil, nil
+}
+
+func (s *countingRuleStore) RecordShadowMatch(_ context.Context, ruleID string, sample *db.ShadowSample, maxSamples int) error {
+	return nil
+}
+
+func TestEngine_RefreshesOnRuleChange(t *testing.T) {
+	store := &countingRuleStore{}
+	e := NewEngine(nil, nil, nil, nil, EngineConfig{
+		RuleRefreshInterval: time.Hour,
+		RuleChangeDebounce:  10 * time.Millisecond,
+	}, DispatcherConfig{}, nil, nil)
+	e.rules = store
+
+	changes := make(chan struct{}, 1)
+	e.SetRuleChanges(changes)
+
+	if err := e.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer e.Stop()
+
+	if got := store.refreshes.Load(); got != 1 {
+		t.Fatalf("refreshes after start: got %d, want 1", got)
+	}
+
+	// A burst of notifications triggers a single debounced refresh.
+	for range 3 {
+		changes <- struct{}{}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for store.refreshes.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if got := store.refreshes.Load(); got != 2 {
+		t.Errorf("refreshes after change burst: got %d, want 2", got)
+	}
+}
+
+// fakeDeviceLookup serves device records from a map keyed by device ID.
+type fakeDeviceLookup struct {
+	devices map[string]*devices.Device
+}
+
+func (f *fakeDeviceLookup) Lookup(_ context.Context, _, deviceID string) (*devices.Device, error) {
+	d, ok := f.devices[deviceID]
+	if !ok {
+		return nil, devices.ErrDeviceNotFound
+	}
+	return d, nil
+}
+
+func TestEngine_DeviceConditionSource(t *testing.T) {
+	lookup := &fakeDeviceLookup{devices: map[string]*devices.Device{
+		"risky": {DeviceID: "risky", RiskScore: 0.8, Emulator: true},
+		"clean": {DeviceID: "clean", RiskScore: 0},
+	}}
+	e := NewEngine(nil, nil, nil, nil, EngineConfig{}, DispatcherConfig{}, nil, nil)
+	e.SetDeviceLookup(lookup)
+
+	rule := &db.Rule{ID: "r1", Conditions: []db.Condition{
+		{Path: "$.app_id", Operator: "eq", Value: "app"},
+		{Source: db.ConditionSourceDevice, Path: "risk_score", Operator: "gte", Value: 0.5},
+	}}
+	eventRule := &db.Rule{ID: "r2", Conditions: []db.Condition{
+		{Path: "$.app_id", Operator: "eq", Value: "other"},
+	}}
+
+	tests := []struct {
+		deviceID string
+		want     int
+	}{
+		{"risky", 1},
+		{"clean", 0},
+		{"unknown", 0},
+	}
+	for _, tt := range tests {
+		event := &pb.EventEnvelope{AppId: "app", DeviceId: tt.deviceID}
+		eventJSON, err := e.eventToJSON(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := &conditionData{
+			event:      eventJSON,
+			loadDevice: func() map[string]interface{} { return e.deviceToJSON(context.Background(), event) },
+		}
+		if got := e.findMatchingRules([]*db.Rule{rule, eventRule}, "app", "", "", data); len(got) != tt.want {
+			t.Errorf("device %s: matched %d rules, want %d", tt.deviceID, len(got), tt.want)
+		}
+	}
+
+	// Rules without device conditions never query the registry.
+	eventJSON, _ := e.eventToJSON(&pb.EventEnvelope{AppId: "app", DeviceId: "risky"})
+	data := &conditionData{
+		event:      eventJSON,
+		loadDevice: func() map[string]interface{} { t.Error("device loaded for event-only rule"); return nil },
+	}
+	e.findMatchingRules([]*db.Rule{eventRule}, "app", "", "", data)
+}
+
+// fakeConverter converts with fixed units-per-USD rates.
+type fakeConverter map[string]float64
+
+func (c fakeConverter) ToUSD(currency string, minorUnits int64, _ time.Time) (float64, bool) {
+	rate, ok := c[currency]
+	if !ok {
+		return 0, false
+	}
+	return float64(minorUnits) / 100 / rate, true
+}
+
+func TestEngine_AmountUSD(t *testing.T) {
+	e := NewEngine(nil, nil, nil, nil, EngineConfig{}, DispatcherConfig{}, nil, nil)
+	e.SetCurrencyConverter(fakeConverter{"EUR": 0.5})
+
+	rule := &db.Rule{ID: "r1", Conditions: []db.Condition{
+		{Path: "$.purchase_complete.amount_usd", Operator: "gte", Value: 100},
+	}}
+
+	tests := []struct {
+		currency string
+		cents    int64
+		want     int
+	}{
+		{"EUR", 6000, 1},
+		{"EUR", 4000, 0},
+		{"GBP", 100000, 0},
+	}
+	for _, tt := range tests {
+		event := &pb.EventEnvelope{
+			AppId: "app",
+			Payload: &pb.EventEnvelope_PurchaseComplete{
+				PurchaseComplete: &pb.PurchaseComplete{OrderId: "o1", TotalCents: tt.cents, Currency: tt.currency},
+			},
+		}
+		eventJSON, err := e.eventToJSON(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := e.findMatchingRules([]*db.Rule{rule}, "app", "", "", &conditionData{event: eventJSON}); len(got) != tt.want {
+			t.Errorf("%d %s: matched %d rules, want %d", tt.cents, tt.currency, len(got), tt.want)
+		}
+	}
+
+	detector := NewAnomalyDetector(nil, nil, AnomalyConfig{}, nil)
+	detector.SetCurrencyConverter(fakeConverter{"EUR": 0.5})
+	anomalyJSON, _ := detector.eventToJSON(&pb.EventEnvelope{
+		Payload: &pb.EventEnvelope_PurchaseComplete{
+			PurchaseComplete: &pb.PurchaseComplete{TotalCents: 1000, Currency: "EUR"},
+		},
+	})
+	if got, _ := detector.extractJSONPath(anomalyJSON, "$.purchase_complete.amount_usd"); got != 20.0 {
+		t.Errorf("anomaly amount_usd = %v, want 20", got)
+	}
+}
+
+func TestDeliveryIdempotencyKey(t *testing.T) {
+	if got := deliveryIdempotencyKey("rule-1", "event-1"); got != "rule-1:event-1" {
+		t.Errorf("deliveryIdempotencyKey() = %q, want rule-1:event-1", got)
+	}
+	if got := deliveryIdempotencyKey("rule-1", ""); got != "" {
+		t.Errorf("deliveryIdempotencyKey() without event id = %q, want empty", got)
+	}
+}
+
+// recordingDeliveryStore records the deliveries of each CreateBatch call.
+type recordingDeliveryStore struct {
+	mu      sync.Mutex
+	batches [][]*db.WebhookDelivery
+}
+
+func (s *recordingDeliveryStore) CreateBatch(_ context.Context, deliveries []*db.WebhookDelivery) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, deliveries)
+	return len(deliveries), nil
+}
+
+func TestEvaluationGroups(t *testing.T) {
+	batch := []*pb.EventEnvelope{
+		{DeviceId: "a"},
+		{DeviceId: "b"},
+		{DeviceId: "a"},
+		{},
+		{},
+		{DeviceId: "b"},
+	}
+
+	unordered := [][]int{{0}, {1}, {2}, {3}, {4}, {5}}
+	if got := evaluationGroups(batch, false); !reflect.DeepEqual(got, unordered) {
+		t.Errorf("unordered groups = %v, want %v", got, unordered)
+	}
+
+	ordered := [][]int{{0, 2}, {1, 5}, {3}, {4}}
+	if got := evaluationGroups(batch, true); !reflect.DeepEqual(got, ordered) {
+		t.Errorf("ordered groups = %v, want %v", got, ordered)
+	}
+}
+
+// TestEngine_ProcessEventsInsertsDeliveriesTogether verifies the deliveries
+// of a batch are inserted in one call, in batch order.
+func TestEngine_ProcessEventsInsertsDeliveriesTogether(t *testing.T) {
+	for _, ordered := range []bool{false, true} {
+		store := &recordingDeliveryStore{}
+		e := NewEngine(nil, nil, nil, nil, EngineConfig{MaxConcurrentEvaluations: 4}, DispatcherConfig{}, nil, nil)
+		e.deliveries = store
+		e.cachedRules = []*db.Rule{{
+			ID:         "r1",
+			Conditions: []db.Condition{{Path: "$.app_id", Operator: "eq", Value: "app"}},
+			Actions:    db.Actions{Webhooks: []string{"w1", "w2"}},
+		}}
+
+		var batch []*pb.EventEnvelope
+		for _, id := range []string{"e1", "e2", "e3", "e4", "e5"} {
+			batch = append(batch, &pb.EventEnvelope{Id: id, AppId: "app", DeviceId: "d" + id[1:]})
+		}
+		batch = append(batch, &pb.EventEnvelope{Id: "other", AppId: "other"})
+
+		errs := e.ProcessEvents(context.Background(), batch, ordered)
+		for i, err := range errs {
+			if err != nil {
+				t.Errorf("ordered=%v: event %d error = %v", ordered, i, err)
+			}
+		}
+
+		if len(store.batches) != 1 {
+			t.Fatalf("ordered=%v: CreateBatch called %d times, want 1", ordered, len(store.batches))
+		}
+		var keys []string
+		for _, d := range store.batches[0] {
+			keys = append(keys, d.WebhookID+"/"+*d.IdempotencyKey)
+		}
+		want := []string{
+			"w1/r1:e1", "w2/r1:e1", "w1/r1:e2", "w2/r1:e2", "w1/r1:e3",
+			"w2/r1:e3", "w1/r1:e4", "w2/r1:e4", "w1/r1:e5", "w2/r1:e5",
+		}
+		if !reflect.DeepEqual(keys, want) {
+			t.Errorf("ordered=%v: deliveries = %v, want %v", ordered, keys, want)
+		}
+	}
+}
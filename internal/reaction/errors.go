@@ -31,6 +31,27 @@ var (
 	// ErrInvalidDetectionType indicates an unknown detection type.
 	ErrInvalidDetectionType = errors.New("invalid detection type")
 
+	// ErrInvalidSeverity indicates an unknown anomaly severity.
+	ErrInvalidSeverity = errors.New("invalid severity")
+
+	// ErrInvalidRevenueConfig indicates a revenue anomaly config is invalid.
+	ErrInvalidRevenueConfig = errors.New("invalid revenue config")
+
+	// ErrInvalidAnomalyConfig indicates an anomaly config fails validation.
+	ErrInvalidAnomalyConfig = errors.New("invalid anomaly config")
+
+	// ErrInvalidEscalationPolicy indicates an escalation policy fails
+	// validation.
+	ErrInvalidEscalationPolicy = errors.New("invalid escalation policy")
+
+	// ErrInvalidMaintenanceWindow indicates a maintenance window fails
+	// validation.
+	ErrInvalidMaintenanceWindow = errors.New("invalid maintenance window")
+
+	// ErrPreviewUnsupported indicates an anomaly config's detection type
+	// cannot be previewed.
+	ErrPreviewUnsupported = errors.New("detection type cannot be previewed")
+
 	// ErrDeliveryMaxAttemptsReached indicates max delivery attempts reached.
 	ErrDeliveryMaxAttemptsReached = errors.New("max delivery attempts reached")
 
@@ -54,4 +75,13 @@ var (
 
 	// ErrAnomalyStateNotFound indicates no anomaly state was found.
 	ErrAnomalyStateNotFound = errors.New("anomaly state not found")
+
+	// ErrInvalidEncryptionKey indicates a payload encryption key is missing or malformed.
+	ErrInvalidEncryptionKey = errors.New("invalid payload encryption key")
+
+	// ErrPayloadKeyUnavailable indicates the key needed to decrypt a payload is not configured.
+	ErrPayloadKeyUnavailable = errors.New("payload encryption key unavailable")
+
+	// ErrPayloadDecryption indicates an encrypted payload could not be decrypted.
+	ErrPayloadDecryption = errors.New("payload decryption failed")
 )
@@ -0,0 +1,281 @@
+package reaction
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// escalationBatchSize is the number of due escalations handled per check.
+const escalationBatchSize = 100
+
+// escalationEventStore is the subset of db.AnomalyConfigRepository used by
+// Escalator.
+type escalationEventStore interface {
+	GetByID(ctx context.Context, id string) (*db.AnomalyConfig, error)
+	GetDueEscalations(ctx context.Context, now time.Time, limit int) ([]*db.AnomalyEvent, error)
+	AdvanceEscalation(ctx context.Context, id string, fromStep int, advance bool, nextAt *time.Time) (bool, error)
+}
+
+// escalationPolicyStore is the subset of db.EscalationPolicyRepository used
+// by Escalator.
+type escalationPolicyStore interface {
+	GetByID(ctx context.Context, id string) (*db.EscalationPolicy, error)
+}
+
+// Escalator notifies the steps of the escalation policies of unacknowledged
+// anomaly alerts as they fall due. Each step queues a webhook delivery per
+// webhook of the step, keyed by alert and step so a step is queued once even
+// if several engines escalate the same alert.
+type Escalator struct {
+	events      escalationEventStore
+	policies    escalationPolicyStore
+	deliveries  deliveryCreator
+	queue       *DeliveryQueue
+	cipher      *PayloadCipher
+	interval    time.Duration
+	maxAttempts int
+	logger      *slog.Logger
+	now         func() time.Time
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// NewEscalator creates a new escalator.
+func NewEscalator(
+	events *db.AnomalyConfigRepository,
+	policies *db.EscalationPolicyRepository,
+	deliveries *db.DeliveryRepository,
+	config AnomalyConfig,
+	dispatcherConfig DispatcherConfig,
+	cipher *PayloadCipher,
+	logger *slog.Logger,
+) *Escalator {
+	return newEscalator(events, policies, deliveries, config.EscalationInterval, dispatcherConfig.MaxAttempts, cipher, logger)
+}
+
+func newEscalator(
+	events escalationEventStore,
+	policies escalationPolicyStore,
+	deliveries deliveryCreator,
+	interval time.Duration,
+	maxAttempts int,
+	cipher *PayloadCipher,
+	logger *slog.Logger,
+) *Escalator {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	return &Escalator{
+		events:      events,
+		policies:    policies,
+		deliveries:  deliveries,
+		cipher:      cipher,
+		interval:    interval,
+		maxAttempts: maxAttempts,
+		logger:      logger.With("component", "anomaly-escalator"),
+		now:         time.Now,
+		stopCh:      make(chan struct{}),
+		doneCh:      make(chan struct{}),
+	}
+}
+
+// SetDeliveryQueue sets the queue the IDs of escalation deliveries are
+// enqueued to. Must be called before Start.
+func (e *Escalator) SetDeliveryQueue(queue *DeliveryQueue) {
+	e.queue = queue
+}
+
+// Start starts checking for due escalations.
+func (e *Escalator) Start(ctx context.Context) {
+	go e.run(ctx)
+	e.logger.Info("anomaly escalator started", "interval", e.interval)
+}
+
+// Stop stops the escalator.
+func (e *Escalator) Stop() {
+	close(e.stopCh)
+	<-e.doneCh
+	e.logger.Info("anomaly escalator stopped")
+}
+
+// run checks for due escalations every interval.
+func (e *Escalator) run(ctx context.Context) {
+	defer close(e.doneCh)
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.stopCh:
+			return
+		case <-ticker.C:
+			e.Escalate(ctx)
+		}
+	}
+}
+
+// Escalate notifies the due escalation steps of unacknowledged alerts.
+func (e *Escalator) Escalate(ctx context.Context) {
+	now := e.now()
+	due, err := e.events.GetDueEscalations(ctx, now, escalationBatchSize)
+	if err != nil {
+		e.logger.Error("failed to get due escalations", "error", err)
+		return
+	}
+
+	policies := make(map[string]*db.EscalationPolicy)
+	configs := make(map[string]*db.AnomalyConfig)
+	for _, event := range due {
+		if err := e.escalate(ctx, event, now, policies, configs); err != nil {
+			e.logger.Error("failed to escalate anomaly",
+				"anomaly_event_id", event.ID,
+				"step", event.EscalationStep,
+				"error", err,
+			)
+		}
+	}
+}
+
+// escalate notifies an alert's current step if it is due and schedules the
+// next one. policies and configs cache lookups within one check.
+func (e *Escalator) escalate(ctx context.Context, event *db.AnomalyEvent, now time.Time, policies map[string]*db.EscalationPolicy, configs map[string]*db.AnomalyConfig) error {
+	var policy *db.EscalationPolicy
+	if event.EscalationPolicyID != nil {
+		var err error
+		policy, err = e.policy(ctx, *event.EscalationPolicyID, policies)
+		if err != nil && !errors.Is(err, db.ErrEscalationPolicyNotFound) {
+			return err
+		}
+	}
+
+	step := event.EscalationStep
+	if policy == nil || step >= len(policy.Steps) {
+		// The policy was deleted or shortened: nothing is left to notify.
+		_, err := e.events.AdvanceEscalation(ctx, event.ID, step, false, nil)
+		return err
+	}
+
+	dueAt := event.CreatedAt.Add(time.Duration(policy.Steps[step].AfterMinutes) * time.Minute)
+	if dueAt.After(now) {
+		_, err := e.events.AdvanceEscalation(ctx, event.ID, step, false, &dueAt)
+		return err
+	}
+
+	config, err := e.config(ctx, event.AnomalyConfigID, configs)
+	if err != nil {
+		return err
+	}
+	if err := e.notify(ctx, event, config, policy, step); err != nil {
+		return err
+	}
+
+	var nextAt *time.Time
+	if next := step + 1; next < len(policy.Steps) {
+		at := event.CreatedAt.Add(time.Duration(policy.Steps[next].AfterMinutes) * time.Minute)
+		nextAt = &at
+	}
+	advanced, err := e.events.AdvanceEscalation(ctx, event.ID, step, true, nextAt)
+	if err != nil {
+		return err
+	}
+	if advanced {
+		e.logger.Info("anomaly escalated",
+			"anomaly_event_id", event.ID,
+			"anomaly_config_id", event.AnomalyConfigID,
+			"policy", policy.Name,
+			"step", step,
+			"webhooks", len(policy.Steps[step].Webhooks),
+		)
+	}
+	return nil
+}
+
+// notify queues the deliveries of one escalation step.
+func (e *Escalator) notify(ctx context.Context, event *db.AnomalyEvent, config *db.AnomalyConfig, policy *db.EscalationPolicy, step int) error {
+	payload := map[string]interface{}{
+		"type":                   "anomaly_escalation",
+		"anomaly_event_id":       event.ID,
+		"anomaly_config_id":      event.AnomalyConfigID,
+		"anomaly_config_name":    config.Name,
+		"severity":               config.Severity,
+		"app_id":                 event.AppID,
+		"event_category":         event.EventCategory,
+		"event_type":             event.EventType,
+		"detection_type":         event.DetectionType,
+		"details":                event.Details,
+		"detected_at":            event.CreatedAt.UTC().Format(time.RFC3339),
+		"escalation_policy_id":   policy.ID,
+		"escalation_policy_name": policy.Name,
+		"escalation_step":        step,
+	}
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	stored, err := e.cipher.Seal(ctx, payloadJSON)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt payload: %w", err)
+	}
+
+	key := fmt.Sprintf("escalation:%s:%d", event.ID, step)
+	webhooks := policy.Steps[step].Webhooks
+	deliveries := make([]*db.WebhookDelivery, 0, len(webhooks))
+	for _, webhookID := range webhooks {
+		deliveries = append(deliveries, &db.WebhookDelivery{
+			WebhookID:       webhookID,
+			AnomalyConfigID: &event.AnomalyConfigID,
+			IdempotencyKey:  &key,
+			Payload:         stored,
+			Status:          db.DeliveryStatusPending,
+			MaxAttempts:     e.maxAttempts,
+			NextAttemptAt:   e.now(),
+		})
+	}
+
+	if _, err := e.deliveries.CreateBatch(ctx, deliveries); err != nil {
+		return fmt.Errorf("failed to queue escalation deliveries: %w", err)
+	}
+
+	enqueueDeliveries(ctx, e.queue, deliveries, e.logger)
+	return nil
+}
+
+// policy returns an escalation policy, cached in policies.
+func (e *Escalator) policy(ctx context.Context, id string, policies map[string]*db.EscalationPolicy) (*db.EscalationPolicy, error) {
+	if policy, ok := policies[id]; ok {
+		return policy, nil
+	}
+	policy, err := e.policies.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	policies[id] = policy
+	return policy, nil
+}
+
+// config returns an anomaly config, cached in configs.
+func (e *Escalator) config(ctx context.Context, id string, configs map[string]*db.AnomalyConfig) (*db.AnomalyConfig, error) {
+	if config, ok := configs[id]; ok {
+		return config, nil
+	}
+	config, err := e.events.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	configs[id] = config
+	return config, nil
+}
@@ -0,0 +1,377 @@
+package reaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// escalationPolicyAdminStore is the subset of db.EscalationPolicyRepository
+// used by EscalationHandler.
+type escalationPolicyAdminStore interface {
+	List(ctx context.Context, limit, offset int) ([]*db.EscalationPolicy, error)
+	GetByID(ctx context.Context, id string) (*db.EscalationPolicy, error)
+	Create(ctx context.Context, policy *db.EscalationPolicy) error
+	Update(ctx context.Context, policy *db.EscalationPolicy) error
+	Delete(ctx context.Context, id string) error
+}
+
+// anomalyEventAdminStore is the subset of db.AnomalyConfigRepository used
+// by EscalationHandler.
+type anomalyEventAdminStore interface {
+	GetAnomalyEvent(ctx context.Context, id string) (*db.AnomalyEvent, error)
+	AcknowledgeAnomalyEvent(ctx context.Context, id, by string) (*db.AnomalyEvent, error)
+}
+
+// webhookLookup is the subset of db.WebhookRepository used to check the
+// webhooks of escalation steps.
+type webhookLookup interface {
+	GetByIDs(ctx context.Context, ids []string) ([]*db.Webhook, error)
+}
+
+// EscalationPolicySpec is the request body of escalation policy create and
+// update.
+type EscalationPolicySpec struct {
+	Name        string              `json:"name"`
+	Description *string             `json:"description,omitempty"`
+	Steps       []db.EscalationStep `json:"steps"`
+}
+
+// EscalationHandler serves the admin API for escalation policies and the
+// acknowledgement of anomaly alerts.
+type EscalationHandler struct {
+	policies escalationPolicyAdminStore
+	events   anomalyEventAdminStore
+	webhooks webhookLookup
+	logger   *slog.Logger
+}
+
+// NewEscalationHandler creates an EscalationHandler.
+func NewEscalationHandler(policies *db.EscalationPolicyRepository, events *db.AnomalyConfigRepository, webhooks *db.WebhookRepository, logger *slog.Logger) *EscalationHandler {
+	return newEscalationHandler(policies, events, webhooks, logger)
+}
+
+func newEscalationHandler(policies escalationPolicyAdminStore, events anomalyEventAdminStore, webhooks webhookLookup, logger *slog.Logger) *EscalationHandler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &EscalationHandler{
+		policies: policies,
+		events:   events,
+		webhooks: webhooks,
+		logger:   logger.With("component", "escalation-handler"),
+	}
+}
+
+// RegisterRoutes mounts escalation admin endpoints on the given ServeMux.
+//
+// TODO(phase-3): Protect these endpoints with session auth + RBAC.
+//
+// Endpoints:
+//   - GET    /api/admin/escalation-policies          - List policies (?limit=&offset=)
+//   - POST   /api/admin/escalation-policies          - Create a policy
+//   - GET    /api/admin/escalation-policies/{id}     - Get a policy
+//   - PUT    /api/admin/escalation-policies/{id}     - Replace a policy
+//   - DELETE /api/admin/escalation-policies/{id}     - Delete a policy
+//   - GET    /api/admin/anomaly-events/{id}          - Get an alert and its escalation state
+//   - POST   /api/admin/anomaly-events/{id}/acknowledge - Acknowledge an alert, stopping its escalation
+func (h *EscalationHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/admin/escalation-policies", h.handleList)
+	mux.HandleFunc("POST /api/admin/escalation-policies", h.handleCreate)
+	mux.HandleFunc("GET /api/admin/escalation-policies/{id}", h.handleGet)
+	mux.HandleFunc("PUT /api/admin/escalation-policies/{id}", h.handleUpdate)
+	mux.HandleFunc("DELETE /api/admin/escalation-policies/{id}", h.handleDelete)
+	mux.HandleFunc("GET /api/admin/anomaly-events/{id}", h.handleGetEvent)
+	mux.HandleFunc("POST /api/admin/anomaly-events/{id}/acknowledge", h.handleAcknowledge)
+}
+
+// handleList handles GET /api/admin/escalation-policies.
+func (h *EscalationHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryInt(r, "limit", defaultVersionPageSize)
+	if err != nil || limit < 1 || limit > maxVersionPageSize {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "limit must be between 1 and 500",
+		})
+		return
+	}
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "offset must be a non-negative integer",
+		})
+		return
+	}
+
+	policies, err := h.policies.List(r.Context(), limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list escalation policies", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to list escalation policies",
+		})
+		return
+	}
+	if policies == nil {
+		policies = []*db.EscalationPolicy{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"escalation_policies": policies,
+		"count":               len(policies),
+	})
+}
+
+// handleCreate handles POST /api/admin/escalation-policies.
+func (h *EscalationHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	policy, ok := h.decodePolicy(w, r, &db.EscalationPolicy{})
+	if !ok {
+		return
+	}
+
+	if err := h.policies.Create(r.Context(), policy); err != nil {
+		h.logger.Error("failed to create escalation policy", "name", policy.Name, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to create escalation policy",
+		})
+		return
+	}
+
+	h.logger.Info("escalation policy created", "policy_id", policy.ID, "name", policy.Name, "steps", len(policy.Steps))
+	writeJSON(w, http.StatusCreated, policy)
+}
+
+// handleGet handles GET /api/admin/escalation-policies/{id}.
+func (h *EscalationHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	policy, ok := h.getPolicy(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, policy)
+}
+
+// handleUpdate handles PUT /api/admin/escalation-policies/{id}. Unacknowledged
+// alerts continue with the new steps from the step they reached.
+func (h *EscalationHandler) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.getPolicy(w, r)
+	if !ok {
+		return
+	}
+	policy, ok := h.decodePolicy(w, r, existing)
+	if !ok {
+		return
+	}
+
+	if err := h.policies.Update(r.Context(), policy); err != nil {
+		if errors.Is(err, db.ErrEscalationPolicyNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+		h.logger.Error("failed to update escalation policy", "policy_id", policy.ID, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to update escalation policy",
+		})
+		return
+	}
+
+	h.logger.Info("escalation policy updated", "policy_id", policy.ID, "name", policy.Name)
+	writeJSON(w, http.StatusOK, policy)
+}
+
+// handleDelete handles DELETE /api/admin/escalation-policies/{id}. Anomaly
+// configs and alerts using the policy stop escalating.
+func (h *EscalationHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if err := h.policies.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, db.ErrEscalationPolicyNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+		h.logger.Error("failed to delete escalation policy", "policy_id", id, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to delete escalation policy",
+		})
+		return
+	}
+
+	h.logger.Info("escalation policy deleted", "policy_id", id)
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status": "deleted",
+		"id":     id,
+	})
+}
+
+// handleGetEvent handles GET /api/admin/anomaly-events/{id}.
+func (h *EscalationHandler) handleGetEvent(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	event, err := h.events.GetAnomalyEvent(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrAnomalyEventNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+		h.logger.Error("failed to get anomaly event", "anomaly_event_id", id, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to get anomaly event",
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, event)
+}
+
+// acknowledgeRequest is the optional body of an acknowledgement.
+type acknowledgeRequest struct {
+	By string `json:"by"`
+}
+
+// handleAcknowledge handles POST /api/admin/anomaly-events/{id}/acknowledge.
+// Acknowledging an acknowledged alert returns 409 with the alert.
+func (h *EscalationHandler) handleAcknowledge(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var req acknowledgeRequest
+	if r.ContentLength != 0 && !decodeStrict(w, r, &req) {
+		return
+	}
+
+	event, err := h.events.AcknowledgeAnomalyEvent(r.Context(), id, strings.TrimSpace(req.By))
+	switch {
+	case errors.Is(err, db.ErrAlreadyAcknowledged):
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":         err.Error(),
+			"anomaly_event": event,
+		})
+		return
+	case errors.Is(err, db.ErrAnomalyEventNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	case err != nil:
+		h.logger.Error("failed to acknowledge anomaly event", "anomaly_event_id", id, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to acknowledge anomaly event",
+		})
+		return
+	}
+
+	h.logger.Info("anomaly event acknowledged",
+		"anomaly_event_id", id,
+		"by", req.By,
+		"escalation_step", event.EscalationStep,
+	)
+	writeJSON(w, http.StatusOK, event)
+}
+
+// getPolicy loads the policy named by the id path value, writing the error
+// response if it cannot.
+func (h *EscalationHandler) getPolicy(w http.ResponseWriter, r *http.Request) (*db.EscalationPolicy, bool) {
+	id := r.PathValue("id")
+	policy, err := h.policies.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrEscalationPolicyNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{
+				"error": err.Error(),
+			})
+			return nil, false
+		}
+		h.logger.Error("failed to get escalation policy", "policy_id", id, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to get escalation policy",
+		})
+		return nil, false
+	}
+	return policy, true
+}
+
+// decodePolicy decodes and validates a policy request body into policy. It
+// writes the error response if the body is invalid.
+func (h *EscalationHandler) decodePolicy(w http.ResponseWriter, r *http.Request, policy *db.EscalationPolicy) (*db.EscalationPolicy, bool) {
+	var spec EscalationPolicySpec
+	if !decodeStrict(w, r, &spec) {
+		return nil, false
+	}
+	if err := validateEscalationPolicy(spec); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return nil, false
+	}
+	if err := h.checkWebhooks(r.Context(), spec.Steps); err != nil {
+		status := http.StatusBadRequest
+		if !errors.Is(err, ErrInvalidEscalationPolicy) {
+			h.logger.Error("failed to look up escalation webhooks", "error", err)
+			status = http.StatusInternalServerError
+		}
+		writeJSON(w, status, map[string]string{
+			"error": err.Error(),
+		})
+		return nil, false
+	}
+
+	policy.Name = spec.Name
+	policy.Description = spec.Description
+	policy.Steps = spec.Steps
+	return policy, true
+}
+
+// checkWebhooks checks that the webhooks of every step exist.
+func (h *EscalationHandler) checkWebhooks(ctx context.Context, steps []db.EscalationStep) error {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, step := range steps {
+		for _, id := range step.Webhooks {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	webhooks, err := h.webhooks.GetByIDs(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to look up webhooks: %w", err)
+	}
+	for _, webhook := range webhooks {
+		delete(seen, webhook.ID)
+	}
+	for _, id := range ids {
+		if seen[id] {
+			return fmt.Errorf("%w: %w: %s", ErrInvalidEscalationPolicy, ErrWebhookNotFound, id)
+		}
+	}
+	return nil
+}
+
+// validateEscalationPolicy checks a policy's name and steps. Steps must be
+// ordered by their delay and each notify at least one webhook.
+func validateEscalationPolicy(spec EscalationPolicySpec) error {
+	if strings.TrimSpace(spec.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidEscalationPolicy)
+	}
+	if len(spec.Steps) == 0 {
+		return fmt.Errorf("%w: at least one step is required", ErrInvalidEscalationPolicy)
+	}
+	for i, step := range spec.Steps {
+		switch {
+		case step.AfterMinutes < 0:
+			return fmt.Errorf("%w: step %d: after_minutes must not be negative", ErrInvalidEscalationPolicy, i)
+		case i > 0 && step.AfterMinutes < spec.Steps[i-1].AfterMinutes:
+			return fmt.Errorf("%w: step %d: after_minutes must not be less than the previous step's", ErrInvalidEscalationPolicy, i)
+		case len(step.Webhooks) == 0:
+			return fmt.Errorf("%w: step %d: at least one webhook is required", ErrInvalidEscalationPolicy, i)
+		}
+	}
+	return nil
+}
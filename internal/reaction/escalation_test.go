@@ -0,0 +1,341 @@
+package reaction
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// fakeEscalationStore holds anomaly events and configs for the escalator and
+// the acknowledgement endpoints.
+type fakeEscalationStore struct {
+	configs map[string]*db.AnomalyConfig
+	events  map[string]*db.AnomalyEvent
+}
+
+func (f *fakeEscalationStore) GetByID(_ context.Context, id string) (*db.AnomalyConfig, error) {
+	c, ok := f.configs[id]
+	if !ok {
+		return nil, db.ErrAnomalyConfigNotFound
+	}
+	return c, nil
+}
+
+func (f *fakeEscalationStore) GetDueEscalations(_ context.Context, now time.Time, _ int) ([]*db.AnomalyEvent, error) {
+	var out []*db.AnomalyEvent
+	for _, e := range f.events {
+		if e.AcknowledgedAt == nil && e.NextEscalationAt != nil && !e.NextEscalationAt.After(now) {
+			copied := *e
+			out = append(out, &copied)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeEscalationStore) AdvanceEscalation(_ context.Context, id string, fromStep int, advance bool, nextAt *time.Time) (bool, error) {
+	e, ok := f.events[id]
+	if !ok || e.AcknowledgedAt != nil || e.EscalationStep != fromStep {
+		return false, nil
+	}
+	if advance {
+		e.EscalationStep++
+	}
+	e.NextEscalationAt = nextAt
+	return true, nil
+}
+
+func (f *fakeEscalationStore) GetAnomalyEvent(_ context.Context, id string) (*db.AnomalyEvent, error) {
+	e, ok := f.events[id]
+	if !ok {
+		return nil, db.ErrAnomalyEventNotFound
+	}
+	return e, nil
+}
+
+func (f *fakeEscalationStore) AcknowledgeAnomalyEvent(_ context.Context, id, by string) (*db.AnomalyEvent, error) {
+	e, ok := f.events[id]
+	if !ok {
+		return nil, db.ErrAnomalyEventNotFound
+	}
+	if e.AcknowledgedAt != nil {
+		return e, db.ErrAlreadyAcknowledged
+	}
+	now := time.Now()
+	e.AcknowledgedAt = &now
+	if by != "" {
+		e.AcknowledgedBy = &by
+	}
+	e.NextEscalationAt = nil
+	return e, nil
+}
+
+type fakeEscalationPolicyStore struct {
+	policies map[string]*db.EscalationPolicy
+}
+
+func (f *fakeEscalationPolicyStore) List(_ context.Context, _, _ int) ([]*db.EscalationPolicy, error) {
+	var out []*db.EscalationPolicy
+	for _, p := range f.policies {
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func (f *fakeEscalationPolicyStore) GetByID(_ context.Context, id string) (*db.EscalationPolicy, error) {
+	p, ok := f.policies[id]
+	if !ok {
+		return nil, db.ErrEscalationPolicyNotFound
+	}
+	copied := *p
+	return &copied, nil
+}
+
+func (f *fakeEscalationPolicyStore) Create(_ context.Context, policy *db.EscalationPolicy) error {
+	policy.ID = "p-new"
+	copied := *policy
+	f.policies[policy.ID] = &copied
+	return nil
+}
+
+func (f *fakeEscalationPolicyStore) Update(_ context.Context, policy *db.EscalationPolicy) error {
+	if _, ok := f.policies[policy.ID]; !ok {
+		return db.ErrEscalationPolicyNotFound
+	}
+	copied := *policy
+	f.policies[policy.ID] = &copied
+	return nil
+}
+
+func (f *fakeEscalationPolicyStore) Delete(_ context.Context, id string) error {
+	if _, ok := f.policies[id]; !ok {
+		return db.ErrEscalationPolicyNotFound
+	}
+	delete(f.policies, id)
+	return nil
+}
+
+// fakeWebhookLookup knows a fixed set of webhook IDs.
+type fakeWebhookLookup map[string]bool
+
+func (f fakeWebhookLookup) GetByIDs(_ context.Context, ids []string) ([]*db.Webhook, error) {
+	var out []*db.Webhook
+	for _, id := range ids {
+		if f[id] {
+			out = append(out, &db.Webhook{ID: id})
+		}
+	}
+	return out, nil
+}
+
+func newEscalationFixture(created time.Time) (*fakeEscalationStore, *fakeEscalationPolicyStore) {
+	policyID := "p1"
+	events := &fakeEscalationStore{
+		configs: map[string]*db.AnomalyConfig{
+			"a1": {ID: "a1", Name: "checkout spike", Severity: db.SeverityCritical, EscalationPolicyID: &policyID},
+		},
+		events: map[string]*db.AnomalyEvent{
+			"e1": {
+				ID:                 "e1",
+				AnomalyConfigID:    "a1",
+				DetectionType:      string(db.DetectionTypeRate),
+				Details:            json.RawMessage(`{}`),
+				EscalationPolicyID: &policyID,
+				NextEscalationAt:   &created,
+				CreatedAt:          created,
+			},
+		},
+	}
+	policies := &fakeEscalationPolicyStore{policies: map[string]*db.EscalationPolicy{
+		"p1": {
+			ID:   "p1",
+			Name: "on-call",
+			Steps: []db.EscalationStep{
+				{AfterMinutes: 0, Webhooks: []string{"w1"}},
+				{AfterMinutes: 15, Webhooks: []string{"w2", "w3"}},
+			},
+		},
+	}}
+	return events, policies
+}
+
+func TestEscalatorNotifiesDueSteps(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	events, policies := newEscalationFixture(created)
+	deliveries := &recordingDeliveryStore{}
+	e := newEscalator(events, policies, deliveries, time.Minute, 5, nil, nil)
+
+	now := created.Add(time.Minute)
+	e.now = func() time.Time { return now }
+	e.Escalate(context.Background())
+
+	if len(deliveries.batches) != 1 || len(deliveries.batches[0]) != 1 {
+		t.Fatalf("batches = %v, want one delivery for step 0", deliveries.batches)
+	}
+	d := deliveries.batches[0][0]
+	if d.WebhookID != "w1" || d.IdempotencyKey == nil || *d.IdempotencyKey != "escalation:e1:0" || d.MaxAttempts != 5 {
+		t.Errorf("delivery = %+v, want w1 keyed escalation:e1:0", d)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(d.Payload, &payload); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	if payload["type"] != "anomaly_escalation" || payload["severity"] != "critical" || payload["escalation_step"] != float64(0) {
+		t.Errorf("payload = %v", payload)
+	}
+
+	event := events.events["e1"]
+	wantNext := created.Add(15 * time.Minute)
+	if event.EscalationStep != 1 || event.NextEscalationAt == nil || !event.NextEscalationAt.Equal(wantNext) {
+		t.Fatalf("after step 0: step = %d, next = %v, want 1 at %v", event.EscalationStep, event.NextEscalationAt, wantNext)
+	}
+
+	// Step 1 is not yet due.
+	e.Escalate(context.Background())
+	if len(deliveries.batches) != 1 {
+		t.Fatalf("step 1 notified before it was due")
+	}
+
+	now = created.Add(20 * time.Minute)
+	e.Escalate(context.Background())
+	if len(deliveries.batches) != 2 || len(deliveries.batches[1]) != 2 {
+		t.Fatalf("batches = %v, want step 1 to notify two webhooks", deliveries.batches)
+	}
+	if event.EscalationStep != 2 || event.NextEscalationAt != nil {
+		t.Errorf("after last step: step = %d, next = %v, want 2 and nil", event.EscalationStep, event.NextEscalationAt)
+	}
+}
+
+func TestEscalatorSkipsAcknowledgedAlerts(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	events, policies := newEscalationFixture(created)
+	deliveries := &recordingDeliveryStore{}
+	e := newEscalator(events, policies, deliveries, time.Minute, 5, nil, nil)
+	e.now = func() time.Time { return created.Add(time.Hour) }
+
+	if _, err := events.AcknowledgeAnomalyEvent(context.Background(), "e1", "alice"); err != nil {
+		t.Fatalf("acknowledge: %v", err)
+	}
+	e.Escalate(context.Background())
+	if len(deliveries.batches) != 0 {
+		t.Errorf("acknowledged alert escalated: %v", deliveries.batches)
+	}
+}
+
+func TestEscalatorReschedulesStepsNotYetDue(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	events, policies := newEscalationFixture(created)
+	// The policy was changed to delay its first step after the alert.
+	policies.policies["p1"].Steps[0].AfterMinutes = 10
+	deliveries := &recordingDeliveryStore{}
+	e := newEscalator(events, policies, deliveries, time.Minute, 5, nil, nil)
+	e.now = func() time.Time { return created.Add(time.Minute) }
+
+	e.Escalate(context.Background())
+	event := events.events["e1"]
+	want := created.Add(10 * time.Minute)
+	if len(deliveries.batches) != 0 || event.EscalationStep != 0 || event.NextEscalationAt == nil || !event.NextEscalationAt.Equal(want) {
+		t.Errorf("step = %d, next = %v, batches = %d, want step 0 rescheduled at %v", event.EscalationStep, event.NextEscalationAt, len(deliveries.batches), want)
+	}
+}
+
+func TestEscalatorClearsDeletedPolicy(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	events, policies := newEscalationFixture(created)
+	delete(policies.policies, "p1")
+	deliveries := &recordingDeliveryStore{}
+	e := newEscalator(events, policies, deliveries, time.Minute, 5, nil, nil)
+	e.now = func() time.Time { return created.Add(time.Minute) }
+
+	e.Escalate(context.Background())
+	if len(deliveries.batches) != 0 || events.events["e1"].NextEscalationAt != nil {
+		t.Errorf("alert of a deleted policy still scheduled")
+	}
+}
+
+func newTestEscalationMux(events *fakeEscalationStore, policies *fakeEscalationPolicyStore) *http.ServeMux {
+	mux := http.NewServeMux()
+	h := newEscalationHandler(policies, events, fakeWebhookLookup{"w1": true, "w2": true}, nil)
+	h.RegisterRoutes(mux)
+	return mux
+}
+
+func TestEscalationHandlerValidatesPolicies(t *testing.T) {
+	events, policies := newEscalationFixture(time.Now())
+	mux := newTestEscalationMux(events, policies)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"valid", `{"name":"night","steps":[{"after_minutes":0,"webhooks":["w1"]},{"after_minutes":30,"webhooks":["w2"]}]}`, http.StatusCreated},
+		{"missing name", `{"steps":[{"after_minutes":0,"webhooks":["w1"]}]}`, http.StatusBadRequest},
+		{"no steps", `{"name":"night","steps":[]}`, http.StatusBadRequest},
+		{"negative delay", `{"name":"night","steps":[{"after_minutes":-1,"webhooks":["w1"]}]}`, http.StatusBadRequest},
+		{"decreasing delay", `{"name":"night","steps":[{"after_minutes":30,"webhooks":["w1"]},{"after_minutes":10,"webhooks":["w2"]}]}`, http.StatusBadRequest},
+		{"step without webhooks", `{"name":"night","steps":[{"after_minutes":0,"webhooks":[]}]}`, http.StatusBadRequest},
+		{"unknown webhook", `{"name":"night","steps":[{"after_minutes":0,"webhooks":["w9"]}]}`, http.StatusBadRequest},
+		{"unknown field", `{"name":"night","stepz":[]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(mux, http.MethodPost, "/api/admin/escalation-policies", tt.body)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestEscalationHandlerUpdateAndDelete(t *testing.T) {
+	events, policies := newEscalationFixture(time.Now())
+	mux := newTestEscalationMux(events, policies)
+
+	rec := serve(mux, http.MethodPut, "/api/admin/escalation-policies/p1", `{"name":"renamed","steps":[{"after_minutes":5,"webhooks":["w2"]}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := policies.policies["p1"]; got.Name != "renamed" || len(got.Steps) != 1 {
+		t.Errorf("updated policy = %+v", got)
+	}
+
+	if rec := serve(mux, http.MethodPut, "/api/admin/escalation-policies/missing", `{"name":"x","steps":[{"after_minutes":0,"webhooks":["w1"]}]}`); rec.Code != http.StatusNotFound {
+		t.Errorf("update missing status = %d, want 404", rec.Code)
+	}
+	if rec := serve(mux, http.MethodDelete, "/api/admin/escalation-policies/p1", ""); rec.Code != http.StatusOK {
+		t.Errorf("delete status = %d, want 200", rec.Code)
+	}
+	if rec := serve(mux, http.MethodGet, "/api/admin/escalation-policies/p1", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("get deleted status = %d, want 404", rec.Code)
+	}
+}
+
+func TestEscalationHandlerAcknowledge(t *testing.T) {
+	events, policies := newEscalationFixture(time.Now())
+	mux := newTestEscalationMux(events, policies)
+
+	rec := serve(mux, http.MethodPost, "/api/admin/anomaly-events/e1/acknowledge", `{"by":"alice"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("acknowledge status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var event db.AnomalyEvent
+	if err := json.Unmarshal(rec.Body.Bytes(), &event); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if event.AcknowledgedAt == nil || event.AcknowledgedBy == nil || *event.AcknowledgedBy != "alice" || event.NextEscalationAt != nil {
+		t.Errorf("acknowledged event = %+v", event)
+	}
+
+	rec = serve(mux, http.MethodPost, "/api/admin/anomaly-events/e1/acknowledge", "")
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "anomaly_event") {
+		t.Errorf("second acknowledge status = %d, body = %s, want 409 with the event", rec.Code, rec.Body.String())
+	}
+	if rec := serve(mux, http.MethodPost, "/api/admin/anomaly-events/missing/acknowledge", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("acknowledge missing status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,187 @@
+package reaction
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// historyStore is the subset of db.HistoryRepository used by Engine.
+type historyStore interface {
+	Record(ctx context.Context, appID, deviceID, eventName string, seenAt time.Time) error
+	LastSeen(ctx context.Context, appID, deviceID string, eventNames []string) (map[string]time.Time, error)
+	DeleteOld(ctx context.Context, olderThan time.Time) (int64, error)
+}
+
+// SetHistory sets the store of recent events read by conditions with the
+// "history" source. The engine records when each device last sent the
+// events those conditions reference, and prunes occurrences older than
+// EngineConfig.HistoryRetention. Without it history conditions never see a
+// recent event, so only "not_within" matches. Must be called before Start.
+func (e *Engine) SetHistory(history *db.HistoryRepository) {
+	e.history = history
+}
+
+// historyEventNames returns the events referenced by the history conditions
+// of rules.
+func historyEventNames(rules []*db.Rule) map[string]bool {
+	names := make(map[string]bool)
+	for _, rule := range rules {
+		for _, cond := range rule.Conditions {
+			if cond.Source == db.ConditionSourceHistory {
+				names[strings.TrimPrefix(cond.Path, "$.")] = true
+			}
+		}
+	}
+	return names
+}
+
+// recordHistory records that the event's device sent it, if history
+// conditions reference the event. Failures are logged: the event's own
+// rules were already evaluated.
+func (e *Engine) recordHistory(ctx context.Context, event *pb.EventEnvelope, category, eventType string, names map[string]bool) {
+	name := category + "." + eventType
+	if e.history == nil || event.DeviceId == "" || !names[name] {
+		return
+	}
+
+	if err := e.history.Record(ctx, event.AppId, event.DeviceId, name, eventTime(event)); err != nil {
+		e.logger.Warn("failed to record event history",
+			"app_id", event.AppId,
+			"device_id", event.DeviceId,
+			"event", name,
+			"error", err,
+		)
+	}
+}
+
+// lastSeen returns when the event's device last sent each of the events
+// referenced by history conditions. Lookup errors are logged and treated as
+// no recent events.
+func (e *Engine) lastSeen(ctx context.Context, event *pb.EventEnvelope, names map[string]bool) map[string]time.Time {
+	if e.history == nil || event.DeviceId == "" || len(names) == 0 {
+		return nil
+	}
+
+	list := make([]string, 0, len(names))
+	for name := range names {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+
+	seen, err := e.history.LastSeen(ctx, event.AppId, event.DeviceId, list)
+	if err != nil {
+		e.logger.Warn("failed to read event history for rule conditions",
+			"app_id", event.AppId,
+			"device_id", event.DeviceId,
+			"error", err,
+		)
+		return nil
+	}
+	return seen
+}
+
+// evaluateHistoryCondition evaluates a condition with the "history" source:
+// "within" matches if the device sent the event named by the path within the
+// condition's window before the evaluated event, and "not_within" if it did
+// not.
+func (e *Engine) evaluateHistoryCondition(cond db.Condition, data *conditionData) bool {
+	window, err := historyWindow(cond.Value)
+	if err != nil {
+		return false
+	}
+
+	last, ok := data.lastSeen()[strings.TrimPrefix(cond.Path, "$.")]
+	within := ok && data.at.Sub(last) <= window
+
+	switch cond.Operator {
+	case "within":
+		return within
+	case "not_within":
+		return !within
+	default:
+		return false
+	}
+}
+
+// validateHistoryCondition checks a condition with the "history" source.
+func validateHistoryCondition(cond db.Condition) error {
+	category, eventType, ok := strings.Cut(strings.TrimPrefix(cond.Path, "$."), ".")
+	if !ok || category == "" || eventType == "" || strings.Contains(eventType, ".") {
+		return fmt.Errorf("%w: history path %q is not {category}.{type}", ErrInvalidCondition, cond.Path)
+	}
+	if cond.Operator != "within" && cond.Operator != "not_within" {
+		return fmt.Errorf("%w: history operator %q (want within or not_within)", ErrInvalidOperator, cond.Operator)
+	}
+	if _, err := historyWindow(cond.Value); err != nil {
+		return err
+	}
+	return nil
+}
+
+// historyWindow parses the window of a history condition: a duration string
+// such as "30m", or a number of seconds.
+func historyWindow(value interface{}) (time.Duration, error) {
+	var window time.Duration
+	switch v := value.(type) {
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("%w: history window %q: %w", ErrInvalidCondition, v, err)
+		}
+		window = d
+	default:
+		seconds, ok := toFloat64(v)
+		if !ok {
+			return 0, fmt.Errorf("%w: history window %v is not a duration", ErrInvalidCondition, value)
+		}
+		window = time.Duration(seconds * float64(time.Second))
+	}
+	if window <= 0 {
+		return 0, fmt.Errorf("%w: history window %v is not positive", ErrInvalidCondition, value)
+	}
+	return window, nil
+}
+
+// eventTime returns when an event happened, or now if it has no timestamp.
+func eventTime(event *pb.EventEnvelope) time.Time {
+	if event.TimestampMs == 0 {
+		return time.Now()
+	}
+	return time.UnixMilli(event.TimestampMs)
+}
+
+// pruneHistory periodically deletes event occurrences older than the
+// history retention.
+func (e *Engine) pruneHistory(ctx context.Context) {
+	retention := e.config.HistoryRetention
+	if retention <= 0 {
+		retention = 24 * time.Hour
+	}
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.stopCh:
+			return
+		case <-ticker.C:
+			deleted, err := e.history.DeleteOld(ctx, time.Now().Add(-retention))
+			if err != nil {
+				e.logger.Error("failed to prune event history", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				e.logger.Debug("pruned event history", "count", deleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package reaction
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// memHistory is an in-memory historyStore.
+type memHistory map[string]time.Time
+
+func (m memHistory) Record(_ context.Context, appID, deviceID, eventName string, seenAt time.Time) error {
+	key := appID + "/" + deviceID + "/" + eventName
+	if seenAt.After(m[key]) {
+		m[key] = seenAt
+	}
+	return nil
+}
+
+func (m memHistory) LastSeen(_ context.Context, appID, deviceID string, eventNames []string) (map[string]time.Time, error) {
+	seen := make(map[string]time.Time)
+	for _, name := range eventNames {
+		if at, ok := m[appID+"/"+deviceID+"/"+name]; ok {
+			seen[name] = at
+		}
+	}
+	return seen, nil
+}
+
+func (m memHistory) DeleteOld(_ context.Context, _ time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestEngine_HistoryConditions(t *testing.T) {
+	store := &recordingDeliveryStore{}
+	history := memHistory{}
+	e := NewEngine(nil, nil, nil, nil, EngineConfig{}, DispatcherConfig{}, nil, nil)
+	e.deliveries = store
+	e.history = history
+
+	// Abandoned checkout: the app went to the background after a checkout
+	// started without a purchase completing.
+	system, background := "system", "app_background"
+	e.cachedRules = []*db.Rule{{
+		ID:            "abandoned-checkout",
+		EventCategory: &system,
+		EventType:     &background,
+		Conditions: []db.Condition{
+			{Source: db.ConditionSourceHistory, Path: "commerce.checkout_start", Operator: "within", Value: "30m"},
+			{Source: db.ConditionSourceHistory, Path: "commerce.purchase_complete", Operator: "not_within", Value: 1800},
+		},
+		Actions: db.Actions{Webhooks: []string{"w1"}},
+	}}
+	e.historyEvents = historyEventNames(e.cachedRules)
+
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) int64 { return start.Add(time.Duration(minutes) * time.Minute).UnixMilli() }
+	send := func(id string, minutes int, event *pb.EventEnvelope) {
+		event.Id, event.AppId, event.DeviceId, event.TimestampMs = id, "shop", "d1", at(minutes)
+		if err := e.ProcessEvent(context.Background(), event); err != nil {
+			t.Fatalf("ProcessEvent(%s): %v", id, err)
+		}
+	}
+	checkout := func() *pb.EventEnvelope {
+		return &pb.EventEnvelope{Payload: &pb.EventEnvelope_CheckoutStart{CheckoutStart: &pb.CheckoutStart{}}}
+	}
+	purchase := func() *pb.EventEnvelope {
+		return &pb.EventEnvelope{Payload: &pb.EventEnvelope_PurchaseComplete{PurchaseComplete: &pb.PurchaseComplete{}}}
+	}
+	backgrounded := func() *pb.EventEnvelope {
+		return &pb.EventEnvelope{Payload: &pb.EventEnvelope_AppBackground{AppBackground: &pb.AppBackground{}}}
+	}
+
+	send("bg0", 0, backgrounded())
+	send("c1", 1, checkout())
+	send("bg1", 10, backgrounded())
+	send("p1", 12, purchase())
+	send("bg2", 15, backgrounded())
+	send("c2", 60, checkout())
+	send("bg3", 100, backgrounded())
+
+	var fired []string
+	for _, batch := range store.batches {
+		for _, d := range batch {
+			fired = append(fired, *d.IdempotencyKey)
+		}
+	}
+	if len(fired) != 1 || fired[0] != "abandoned-checkout:bg1" {
+		t.Errorf("fired = %v, want only abandoned-checkout:bg1", fired)
+	}
+
+	// Only events referenced by history conditions are recorded.
+	if len(history) != 2 {
+		t.Errorf("recorded %d events, want checkout_start and purchase_complete", len(history))
+	}
+}
+
+func TestValidateHistoryCondition(t *testing.T) {
+	tests := []struct {
+		cond  db.Condition
+		valid bool
+	}{
+		{db.Condition{Path: "commerce.purchase_complete", Operator: "within", Value: "30m"}, true},
+		{db.Condition{Path: "$.commerce.purchase_complete", Operator: "not_within", Value: 600.0}, true},
+		{db.Condition{Path: "purchase_complete", Operator: "within", Value: "30m"}, false},
+		{db.Condition{Path: "commerce.purchase_complete", Operator: "eq", Value: "30m"}, false},
+		{db.Condition{Path: "commerce.purchase_complete", Operator: "within", Value: "soon"}, false},
+		{db.Condition{Path: "commerce.purchase_complete", Operator: "within", Value: -5.0}, false},
+	}
+	for _, tt := range tests {
+		if err := validateHistoryCondition(tt.cond); (err == nil) != tt.valid {
+			t.Errorf("validateHistoryCondition(%+v) = %v, want valid %v", tt.cond, err, tt.valid)
+		}
+	}
+}
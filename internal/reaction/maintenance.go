@@ -0,0 +1,148 @@
+package reaction
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// maintenanceWindowStore is the subset of db.MaintenanceWindowRepository
+// used by MaintenanceSchedule.
+type maintenanceWindowStore interface {
+	ListUnfinished(ctx context.Context, now time.Time) ([]*db.MaintenanceWindow, error)
+	RecordSuppressedMatch(ctx context.Context, match *db.SuppressedRuleMatch) error
+}
+
+// MaintenanceSchedule caches the active and upcoming maintenance windows so
+// the engine and anomaly detector can check them per event without querying
+// the database. Windows apply by processing time, not event time.
+//
+// A nil *MaintenanceSchedule suppresses nothing.
+type MaintenanceSchedule struct {
+	store    maintenanceWindowStore
+	interval time.Duration
+	logger   *slog.Logger
+	now      func() time.Time
+
+	mu      sync.RWMutex
+	windows []*db.MaintenanceWindow
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// NewMaintenanceSchedule creates a new maintenance schedule.
+func NewMaintenanceSchedule(windows *db.MaintenanceWindowRepository, config MaintenanceConfig, logger *slog.Logger) *MaintenanceSchedule {
+	return newMaintenanceSchedule(windows, config.RefreshInterval, logger)
+}
+
+func newMaintenanceSchedule(store maintenanceWindowStore, interval time.Duration, logger *slog.Logger) *MaintenanceSchedule {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	return &MaintenanceSchedule{
+		store:    store,
+		interval: interval,
+		logger:   logger.With("component", "maintenance-schedule"),
+		now:      time.Now,
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// Start loads the maintenance windows and refreshes them every interval.
+func (s *MaintenanceSchedule) Start(ctx context.Context) error {
+	if err := s.Refresh(ctx); err != nil {
+		return fmt.Errorf("failed to load maintenance windows: %w", err)
+	}
+
+	go s.run(ctx)
+
+	s.logger.Info("maintenance schedule started", "refresh_interval", s.interval)
+	return nil
+}
+
+// Stop stops refreshing the maintenance windows.
+func (s *MaintenanceSchedule) Stop() {
+	close(s.stopCh)
+	<-s.doneCh
+}
+
+// run refreshes the maintenance windows every interval.
+func (s *MaintenanceSchedule) run(ctx context.Context) {
+	defer close(s.doneCh)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil {
+				s.logger.Error("failed to refresh maintenance windows", "error", err)
+			}
+		}
+	}
+}
+
+// Refresh reloads the maintenance windows immediately, e.g. after an admin
+// change, instead of waiting for the next refresh interval.
+func (s *MaintenanceSchedule) Refresh(ctx context.Context) error {
+	windows, err := s.store.ListUnfinished(ctx, s.now())
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.windows = windows
+	s.mu.Unlock()
+
+	s.logger.Debug("maintenance windows refreshed", "count", len(windows))
+	return nil
+}
+
+// RuleWindow returns the active window suppressing the rule actions of
+// appID, or nil if there is none.
+func (s *MaintenanceSchedule) RuleWindow(appID string) *db.MaintenanceWindow {
+	return s.active(appID, func(w *db.MaintenanceWindow) bool { return w.SuppressRules })
+}
+
+// AnomalyWindow returns the active window suppressing the anomaly alerts of
+// appID, or nil if there is none.
+func (s *MaintenanceSchedule) AnomalyWindow(appID string) *db.MaintenanceWindow {
+	return s.active(appID, func(w *db.MaintenanceWindow) bool { return w.SuppressAnomalies })
+}
+
+// active returns the first active window covering appID that suppresses.
+func (s *MaintenanceSchedule) active(appID string, suppresses func(*db.MaintenanceWindow) bool) *db.MaintenanceWindow {
+	if s == nil {
+		return nil
+	}
+	now := s.now()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, w := range s.windows {
+		if suppresses(w) && w.Covers(appID, now) {
+			return w
+		}
+	}
+	return nil
+}
+
+// RecordSuppressedMatch records a rule match suppressed by a window.
+func (s *MaintenanceSchedule) RecordSuppressedMatch(ctx context.Context, match *db.SuppressedRuleMatch) error {
+	return s.store.RecordSuppressedMatch(ctx, match)
+}
@@ -0,0 +1,332 @@
+package reaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// maintenanceAdminStore is the subset of db.MaintenanceWindowRepository used
+// by MaintenanceHandler.
+type maintenanceAdminStore interface {
+	List(ctx context.Context, limit, offset int) ([]*db.MaintenanceWindow, error)
+	GetByID(ctx context.Context, id string) (*db.MaintenanceWindow, error)
+	Create(ctx context.Context, window *db.MaintenanceWindow) error
+	Update(ctx context.Context, window *db.MaintenanceWindow) error
+	Delete(ctx context.Context, id string) error
+	ListSuppressedMatches(ctx context.Context, windowID string, limit int) ([]*db.SuppressedRuleMatch, error)
+	ListSuppressedAnomalies(ctx context.Context, windowID string, limit int) ([]*db.AnomalyEvent, error)
+}
+
+// maintenanceRefresher reloads cached maintenance windows. It is satisfied
+// by *MaintenanceSchedule.
+type maintenanceRefresher interface {
+	Refresh(ctx context.Context) error
+}
+
+// MaintenanceWindowSpec is the request body of maintenance window create and
+// update. Omitted suppress flags default to true.
+type MaintenanceWindowSpec struct {
+	Name              string    `json:"name"`
+	Description       *string   `json:"description,omitempty"`
+	AppID             *string   `json:"app_id,omitempty"`
+	StartsAt          time.Time `json:"starts_at"`
+	EndsAt            time.Time `json:"ends_at"`
+	SuppressRules     *bool     `json:"suppress_rules,omitempty"`
+	SuppressAnomalies *bool     `json:"suppress_anomalies,omitempty"`
+}
+
+// MaintenanceHandler serves the maintenance window admin API.
+type MaintenanceHandler struct {
+	store    maintenanceAdminStore
+	schedule maintenanceRefresher
+	logger   *slog.Logger
+}
+
+// NewMaintenanceHandler creates a MaintenanceHandler. Changes are applied to
+// schedule immediately.
+func NewMaintenanceHandler(store *db.MaintenanceWindowRepository, schedule *MaintenanceSchedule, logger *slog.Logger) *MaintenanceHandler {
+	return newMaintenanceHandler(store, schedule, logger)
+}
+
+func newMaintenanceHandler(store maintenanceAdminStore, schedule maintenanceRefresher, logger *slog.Logger) *MaintenanceHandler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &MaintenanceHandler{
+		store:    store,
+		schedule: schedule,
+		logger:   logger.With("component", "maintenance-handler"),
+	}
+}
+
+// RegisterRoutes mounts maintenance window admin endpoints on the given
+// ServeMux.
+//
+// TODO(phase-3): Protect these endpoints with session auth + RBAC.
+//
+// Endpoints:
+//   - GET    /api/admin/maintenance-windows                 - List windows (?limit=&offset=)
+//   - POST   /api/admin/maintenance-windows                 - Create a window
+//   - GET    /api/admin/maintenance-windows/{id}            - Get a window
+//   - PUT    /api/admin/maintenance-windows/{id}            - Replace a window
+//   - DELETE /api/admin/maintenance-windows/{id}            - Delete a window, ending it early
+//   - GET    /api/admin/maintenance-windows/{id}/suppressed - Rule matches and anomalies it suppressed (?limit=)
+func (h *MaintenanceHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/admin/maintenance-windows", h.handleList)
+	mux.HandleFunc("POST /api/admin/maintenance-windows", h.handleCreate)
+	mux.HandleFunc("GET /api/admin/maintenance-windows/{id}", h.handleGet)
+	mux.HandleFunc("PUT /api/admin/maintenance-windows/{id}", h.handleUpdate)
+	mux.HandleFunc("DELETE /api/admin/maintenance-windows/{id}", h.handleDelete)
+	mux.HandleFunc("GET /api/admin/maintenance-windows/{id}/suppressed", h.handleSuppressed)
+}
+
+// handleList handles GET /api/admin/maintenance-windows.
+func (h *MaintenanceHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryInt(r, "limit", defaultVersionPageSize)
+	if err != nil || limit < 1 || limit > maxVersionPageSize {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "limit must be between 1 and 500",
+		})
+		return
+	}
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "offset must be a non-negative integer",
+		})
+		return
+	}
+
+	windows, err := h.store.List(r.Context(), limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list maintenance windows", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to list maintenance windows",
+		})
+		return
+	}
+	if windows == nil {
+		windows = []*db.MaintenanceWindow{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"maintenance_windows": windows,
+		"count":               len(windows),
+	})
+}
+
+// handleCreate handles POST /api/admin/maintenance-windows.
+func (h *MaintenanceHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	window, ok := decodeMaintenanceWindow(w, r, &db.MaintenanceWindow{})
+	if !ok {
+		return
+	}
+
+	if err := h.store.Create(r.Context(), window); err != nil {
+		h.logger.Error("failed to create maintenance window", "name", window.Name, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to create maintenance window",
+		})
+		return
+	}
+
+	h.logger.Info("maintenance window created",
+		"maintenance_window_id", window.ID,
+		"name", window.Name,
+		"starts_at", window.StartsAt,
+		"ends_at", window.EndsAt,
+	)
+	h.refresh(r.Context())
+	writeJSON(w, http.StatusCreated, window)
+}
+
+// handleGet handles GET /api/admin/maintenance-windows/{id}.
+func (h *MaintenanceHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	window, ok := h.getWindow(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, window)
+}
+
+// handleUpdate handles PUT /api/admin/maintenance-windows/{id}.
+func (h *MaintenanceHandler) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.getWindow(w, r)
+	if !ok {
+		return
+	}
+	window, ok := decodeMaintenanceWindow(w, r, existing)
+	if !ok {
+		return
+	}
+
+	if err := h.store.Update(r.Context(), window); err != nil {
+		if errors.Is(err, db.ErrMaintenanceWindowNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+		h.logger.Error("failed to update maintenance window", "maintenance_window_id", window.ID, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to update maintenance window",
+		})
+		return
+	}
+
+	h.logger.Info("maintenance window updated", "maintenance_window_id", window.ID, "name", window.Name)
+	h.refresh(r.Context())
+	writeJSON(w, http.StatusOK, window)
+}
+
+// handleDelete handles DELETE /api/admin/maintenance-windows/{id}.
+func (h *MaintenanceHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if err := h.store.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, db.ErrMaintenanceWindowNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+		h.logger.Error("failed to delete maintenance window", "maintenance_window_id", id, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to delete maintenance window",
+		})
+		return
+	}
+
+	h.logger.Info("maintenance window deleted", "maintenance_window_id", id)
+	h.refresh(r.Context())
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status": "deleted",
+		"id":     id,
+	})
+}
+
+// handleSuppressed handles GET /api/admin/maintenance-windows/{id}/suppressed.
+func (h *MaintenanceHandler) handleSuppressed(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryInt(r, "limit", defaultVersionPageSize)
+	if err != nil || limit < 1 || limit > maxVersionPageSize {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "limit must be between 1 and 500",
+		})
+		return
+	}
+	window, ok := h.getWindow(w, r)
+	if !ok {
+		return
+	}
+
+	matches, err := h.store.ListSuppressedMatches(r.Context(), window.ID, limit)
+	if err != nil {
+		h.logger.Error("failed to list suppressed matches", "maintenance_window_id", window.ID, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to list suppressed matches",
+		})
+		return
+	}
+	anomalies, err := h.store.ListSuppressedAnomalies(r.Context(), window.ID, limit)
+	if err != nil {
+		h.logger.Error("failed to list suppressed anomalies", "maintenance_window_id", window.ID, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to list suppressed anomalies",
+		})
+		return
+	}
+	if matches == nil {
+		matches = []*db.SuppressedRuleMatch{}
+	}
+	if anomalies == nil {
+		anomalies = []*db.AnomalyEvent{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"maintenance_window": window,
+		"rule_matches":       matches,
+		"anomaly_events":     anomalies,
+	})
+}
+
+// getWindow loads the window named by the id path value, writing the error
+// response if it cannot.
+func (h *MaintenanceHandler) getWindow(w http.ResponseWriter, r *http.Request) (*db.MaintenanceWindow, bool) {
+	id := r.PathValue("id")
+	window, err := h.store.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrMaintenanceWindowNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{
+				"error": err.Error(),
+			})
+			return nil, false
+		}
+		h.logger.Error("failed to get maintenance window", "maintenance_window_id", id, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to get maintenance window",
+		})
+		return nil, false
+	}
+	return window, true
+}
+
+// refresh applies a change to this engine's schedule. Other engines pick it
+// up on their next refresh.
+func (h *MaintenanceHandler) refresh(ctx context.Context) {
+	if h.schedule == nil {
+		return
+	}
+	if err := h.schedule.Refresh(ctx); err != nil {
+		h.logger.Error("failed to refresh maintenance windows", "error", err)
+	}
+}
+
+// decodeMaintenanceWindow decodes and validates a window request body into
+// window. It writes the error response if the body is invalid.
+func decodeMaintenanceWindow(w http.ResponseWriter, r *http.Request, window *db.MaintenanceWindow) (*db.MaintenanceWindow, bool) {
+	var spec MaintenanceWindowSpec
+	if !decodeStrict(w, r, &spec) {
+		return nil, false
+	}
+	if spec.AppID != nil && strings.TrimSpace(*spec.AppID) == "" {
+		spec.AppID = nil
+	}
+	if err := validateMaintenanceWindow(spec); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return nil, false
+	}
+
+	window.Name = spec.Name
+	window.Description = spec.Description
+	window.AppID = spec.AppID
+	window.StartsAt = spec.StartsAt
+	window.EndsAt = spec.EndsAt
+	window.SuppressRules = spec.SuppressRules == nil || *spec.SuppressRules
+	window.SuppressAnomalies = spec.SuppressAnomalies == nil || *spec.SuppressAnomalies
+	return window, true
+}
+
+// validateMaintenanceWindow checks a window's name, period and that it
+// suppresses something.
+func validateMaintenanceWindow(spec MaintenanceWindowSpec) error {
+	switch {
+	case strings.TrimSpace(spec.Name) == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidMaintenanceWindow)
+	case spec.StartsAt.IsZero() || spec.EndsAt.IsZero():
+		return fmt.Errorf("%w: starts_at and ends_at are required", ErrInvalidMaintenanceWindow)
+	case !spec.EndsAt.After(spec.StartsAt):
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidMaintenanceWindow)
+	case spec.SuppressRules != nil && !*spec.SuppressRules && spec.SuppressAnomalies != nil && !*spec.SuppressAnomalies:
+		return fmt.Errorf("%w: a window must suppress rules, anomalies or both", ErrInvalidMaintenanceWindow)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package reaction
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// fakeMaintenanceStore holds maintenance windows and records suppressed
+// matches.
+type fakeMaintenanceStore struct {
+	windows    map[string]*db.MaintenanceWindow
+	suppressed []*db.SuppressedRuleMatch
+	refreshes  int
+}
+
+func (f *fakeMaintenanceStore) ListUnfinished(_ context.Context, now time.Time) ([]*db.MaintenanceWindow, error) {
+	f.refreshes++
+	var out []*db.MaintenanceWindow
+	for _, w := range f.windows {
+		if w.EndsAt.After(now) {
+			out = append(out, w)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeMaintenanceStore) RecordSuppressedMatch(_ context.Context, match *db.SuppressedRuleMatch) error {
+	f.suppressed = append(f.suppressed, match)
+	return nil
+}
+
+func (f *fakeMaintenanceStore) List(_ context.Context, _, _ int) ([]*db.MaintenanceWindow, error) {
+	var out []*db.MaintenanceWindow
+	for _, w := range f.windows {
+		out = append(out, w)
+	}
+	return out, nil
+}
+
+func (f *fakeMaintenanceStore) GetByID(_ context.Context, id string) (*db.MaintenanceWindow, error) {
+	w, ok := f.windows[id]
+	if !ok {
+		return nil, db.ErrMaintenanceWindowNotFound
+	}
+	copied := *w
+	return &copied, nil
+}
+
+func (f *fakeMaintenanceStore) Create(_ context.Context, window *db.MaintenanceWindow) error {
+	window.ID = "m-new"
+	copied := *window
+	f.windows[window.ID] = &copied
+	return nil
+}
+
+func (f *fakeMaintenanceStore) Update(_ context.Context, window *db.MaintenanceWindow) error {
+	if _, ok := f.windows[window.ID]; !ok {
+		return db.ErrMaintenanceWindowNotFound
+	}
+	copied := *window
+	f.windows[window.ID] = &copied
+	return nil
+}
+
+func (f *fakeMaintenanceStore) Delete(_ context.Context, id string) error {
+	if _, ok := f.windows[id]; !ok {
+		return db.ErrMaintenanceWindowNotFound
+	}
+	delete(f.windows, id)
+	return nil
+}
+
+func (f *fakeMaintenanceStore) ListSuppressedMatches(_ context.Context, windowID string, _ int) ([]*db.SuppressedRuleMatch, error) {
+	var out []*db.SuppressedRuleMatch
+	for _, m := range f.suppressed {
+		if m.MaintenanceWindowID != nil && *m.MaintenanceWindowID == windowID {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeMaintenanceStore) ListSuppressedAnomalies(_ context.Context, _ string, _ int) ([]*db.AnomalyEvent, error) {
+	return nil, nil
+}
+
+func TestMaintenanceSchedule_ActiveWindows(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	app := "load-test-app"
+	store := &fakeMaintenanceStore{windows: map[string]*db.MaintenanceWindow{
+		"app": {
+			ID: "app", Name: "load test", AppID: &app,
+			StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour),
+			SuppressRules: true,
+		},
+		"global": {
+			ID: "global", Name: "db migration",
+			StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour),
+			SuppressRules: true, SuppressAnomalies: true,
+		},
+		"past": {
+			ID: "past", Name: "last week",
+			StartsAt: now.Add(-48 * time.Hour), EndsAt: now.Add(-47 * time.Hour),
+			SuppressRules: true, SuppressAnomalies: true,
+		},
+	}}
+	s := newMaintenanceSchedule(store, time.Minute, nil)
+	s.now = func() time.Time { return now }
+	if err := s.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	if w := s.RuleWindow(app); w == nil || w.ID != "app" {
+		t.Errorf("RuleWindow(%s) = %v, want app", app, w)
+	}
+	if w := s.AnomalyWindow(app); w != nil {
+		t.Errorf("AnomalyWindow(%s) = %s, want nil: the window only suppresses rules", app, w.ID)
+	}
+	if w := s.RuleWindow("other"); w != nil {
+		t.Errorf("RuleWindow(other) = %s, want nil", w.ID)
+	}
+
+	// The global window starts an hour later and covers every app.
+	now = now.Add(90 * time.Minute)
+	if w := s.AnomalyWindow("other"); w == nil || w.ID != "global" {
+		t.Errorf("AnomalyWindow(other) after start = %v, want global", w)
+	}
+
+	var nilSchedule *MaintenanceSchedule
+	if w := nilSchedule.RuleWindow(app); w != nil {
+		t.Errorf("nil schedule RuleWindow = %v, want nil", w)
+	}
+}
+
+func TestEngine_MaintenanceSuppressesActions(t *testing.T) {
+	now := time.Now()
+	store := &fakeMaintenanceStore{windows: map[string]*db.MaintenanceWindow{
+		"m1": {ID: "m1", Name: "load test", StartsAt: now.Add(-time.Minute), EndsAt: now.Add(time.Hour), SuppressRules: true},
+	}}
+	schedule := newMaintenanceSchedule(store, time.Minute, nil)
+	if err := schedule.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	deliveries := &recordingDeliveryStore{}
+	e := NewEngine(nil, nil, nil, nil, EngineConfig{MaxConcurrentEvaluations: 1}, DispatcherConfig{}, nil, nil)
+	e.deliveries = deliveries
+	e.SetMaintenance(schedule)
+	e.cachedRules = []*db.Rule{{
+		ID:         "r1",
+		Version:    3,
+		Conditions: []db.Condition{{Path: "$.app_id", Operator: "eq", Value: "app"}},
+		Actions:    db.Actions{Webhooks: []string{"w1"}},
+	}}
+
+	if err := e.ProcessEvent(context.Background(), &pb.EventEnvelope{Id: "e1", AppId: "app"}); err != nil {
+		t.Fatalf("ProcessEvent: %v", err)
+	}
+	if len(deliveries.batches) != 0 {
+		t.Errorf("deliveries queued during maintenance: %v", deliveries.batches)
+	}
+	if len(store.suppressed) != 1 {
+		t.Fatalf("suppressed matches = %d, want 1", len(store.suppressed))
+	}
+	m := store.suppressed[0]
+	if m.RuleID != "r1" || m.RuleVersion != 3 || m.EventID != "e1" || m.AppID != "app" || *m.MaintenanceWindowID != "m1" {
+		t.Errorf("suppressed match = %+v", m)
+	}
+}
+
+func TestMaintenanceHandler(t *testing.T) {
+	store := &fakeMaintenanceStore{windows: map[string]*db.MaintenanceWindow{}}
+	schedule := newMaintenanceSchedule(store, time.Minute, nil)
+	mux := http.NewServeMux()
+	newMaintenanceHandler(store, schedule, nil).RegisterRoutes(mux)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"missing name", `{"starts_at":"2025-03-01T10:00:00Z","ends_at":"2025-03-01T12:00:00Z"}`, http.StatusBadRequest},
+		{"missing period", `{"name":"load test"}`, http.StatusBadRequest},
+		{"ends before start", `{"name":"load test","starts_at":"2025-03-01T12:00:00Z","ends_at":"2025-03-01T10:00:00Z"}`, http.StatusBadRequest},
+		{"suppresses nothing", `{"name":"load test","starts_at":"2025-03-01T10:00:00Z","ends_at":"2025-03-01T12:00:00Z","suppress_rules":false,"suppress_anomalies":false}`, http.StatusBadRequest},
+		{"unknown field", `{"name":"load test","app":"x"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serve(mux, http.MethodPost, "/api/admin/maintenance-windows", tt.body); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	rec := serve(mux, http.MethodPost, "/api/admin/maintenance-windows",
+		`{"name":"load test","app_id":"app","starts_at":"2025-03-01T10:00:00Z","ends_at":"2099-03-01T12:00:00Z","suppress_anomalies":false}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var created db.MaintenanceWindow
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !created.SuppressRules || created.SuppressAnomalies || created.AppID == nil || *created.AppID != "app" {
+		t.Errorf("created window = %+v, want rules suppressed for app", created)
+	}
+	if store.refreshes != 1 || schedule.RuleWindow("app") == nil {
+		t.Errorf("schedule not refreshed after create")
+	}
+
+	if rec := serve(mux, http.MethodGet, "/api/admin/maintenance-windows/m-new/suppressed", ""); rec.Code != http.StatusOK {
+		t.Errorf("suppressed status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve(mux, http.MethodDelete, "/api/admin/maintenance-windows/m-new", ""); rec.Code != http.StatusOK {
+		t.Errorf("delete status = %d, want 200", rec.Code)
+	}
+	if schedule.RuleWindow("app") != nil {
+		t.Errorf("deleted window still suppresses")
+	}
+	if rec := serve(mux, http.MethodPut, "/api/admin/maintenance-windows/m-new", `{"name":"x","starts_at":"2025-03-01T10:00:00Z","ends_at":"2025-03-01T12:00:00Z"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("update deleted status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,171 @@
+package reaction
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// Metric action limits.
+const (
+	// maxMetricLabels bounds the labels of a metric action.
+	maxMetricLabels = 5
+	// maxMetricLabelValueLen bounds the length of a label value; longer
+	// values are truncated.
+	maxMetricLabelValueLen = 64
+	// overflowLabelValue replaces every label value of increments beyond a
+	// counter's series limit.
+	overflowLabelValue = "other"
+)
+
+// metricNamePattern is the form of metric action counter and label names,
+// valid Prometheus names once prefixed.
+var metricNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// validateMetricAction checks a metric action's counter name and labels.
+func validateMetricAction(action db.MetricAction) error {
+	if !metricNamePattern.MatchString(action.Name) {
+		return fmt.Errorf("metric name %q must match %s", action.Name, metricNamePattern)
+	}
+	if len(action.Labels) > maxMetricLabels {
+		return fmt.Errorf("metric %q has %d labels, at most %d are allowed", action.Name, len(action.Labels), maxMetricLabels)
+	}
+	for label, path := range action.Labels {
+		if !metricNamePattern.MatchString(label) {
+			return fmt.Errorf("metric %q label %q must match %s", action.Name, label, metricNamePattern)
+		}
+		if !strings.HasPrefix(path, "$.") {
+			return fmt.Errorf("metric %q label %q path %q must start with $.", action.Name, label, path)
+		}
+	}
+	return nil
+}
+
+// ruleCounters increments the counters of metric actions, exported as
+// rule_metric_{name}_total. Counters are created on first use and keep the
+// label names they were created with; increments with other label names are
+// dropped, since Prometheus rejects a metric whose series disagree on them.
+// Each counter has at most maxSeries label value combinations: increments
+// beyond them are counted with every label set to "other", so a high
+// cardinality event field cannot flood Prometheus with series.
+type ruleCounters struct {
+	meter     otelmetric.Meter
+	maxSeries int
+	logger    *slog.Logger
+
+	mu       sync.Mutex
+	counters map[string]*ruleCounter
+}
+
+// ruleCounter is a counter with its label names and known series.
+type ruleCounter struct {
+	counter otelmetric.Int64Counter
+	labels  []string
+	series  map[string]struct{}
+}
+
+// newRuleCounters creates the metric action counters of meter.
+func newRuleCounters(meter otelmetric.Meter, maxSeries int, logger *slog.Logger) *ruleCounters {
+	if maxSeries < 1 {
+		maxSeries = 1000
+	}
+	return &ruleCounters{
+		meter:     meter,
+		maxSeries: maxSeries,
+		logger:    logger,
+		counters:  make(map[string]*ruleCounter),
+	}
+}
+
+// increment adds one to the counter of action, with the label values read
+// from the event by lookup. Missing fields yield empty label values.
+func (c *ruleCounters) increment(ctx context.Context, action db.MetricAction, lookup func(path string) (interface{}, bool)) error {
+	labels := make([]string, 0, len(action.Labels))
+	for label := range action.Labels {
+		labels = append(labels, label)
+	}
+	slices.Sort(labels)
+
+	values := make([]string, len(labels))
+	for i, label := range labels {
+		if v, ok := lookup(action.Labels[label]); ok && v != nil {
+			values[i] = truncateLabelValue(fmt.Sprint(v))
+		}
+	}
+
+	counter, err := c.counter(action.Name, labels)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	key := strings.Join(values, "\x00")
+	if _, ok := counter.series[key]; !ok {
+		if len(counter.series) >= c.maxSeries {
+			for i := range values {
+				values[i] = overflowLabelValue
+			}
+		} else {
+			counter.series[key] = struct{}{}
+		}
+	}
+	c.mu.Unlock()
+
+	attrs := make([]attribute.KeyValue, len(labels))
+	for i, label := range labels {
+		attrs[i] = attribute.String(label, values[i])
+	}
+	counter.counter.Add(ctx, 1, otelmetric.WithAttributes(attrs...))
+	return nil
+}
+
+// counter returns the counter name, creating it with labels on first use.
+func (c *ruleCounters) counter(name string, labels []string) (*ruleCounter, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if counter, ok := c.counters[name]; ok {
+		if !slices.Equal(counter.labels, labels) {
+			return nil, fmt.Errorf("metric %q has labels %v, not %v", name, counter.labels, labels)
+		}
+		return counter, nil
+	}
+
+	instrument, err := c.meter.Int64Counter(
+		"rule.metric."+name,
+		otelmetric.WithDescription("Rule matches counted by the "+name+" metric action"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metric %q: %w", name, err)
+	}
+
+	counter := &ruleCounter{
+		counter: instrument,
+		labels:  labels,
+		series:  make(map[string]struct{}),
+	}
+	c.counters[name] = counter
+	return counter, nil
+}
+
+// truncateLabelValue shortens a label value to maxMetricLabelValueLen bytes
+// without splitting a UTF-8 sequence.
+func truncateLabelValue(v string) string {
+	if len(v) <= maxMetricLabelValueLen {
+		return v
+	}
+	cut := maxMetricLabelValueLen
+	for cut > 0 && v[cut]&0xC0 == 0x80 {
+		cut--
+	}
+	return v[:cut]
+}
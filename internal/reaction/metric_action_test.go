@@ -0,0 +1,110 @@
+package reaction
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// collectCounter returns the data points of the rule metric name, keyed by
+// their attributes rendered as "k=v,k=v".
+func collectCounter(t *testing.T, reader *sdkmetric.ManualReader, name string) map[string]int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+
+	points := make(map[string]int64)
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name != "rule.metric."+name {
+				continue
+			}
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok {
+				t.Fatalf("metric %s: got %T, want an int64 sum", m.Name, m.Data)
+			}
+			for _, dp := range sum.DataPoints {
+				var attrs []string
+				for _, kv := range dp.Attributes.ToSlice() {
+					attrs = append(attrs, string(kv.Key)+"="+kv.Value.Emit())
+				}
+				points[strings.Join(attrs, ",")] = dp.Value
+			}
+		}
+	}
+	return points
+}
+
+func TestEngine_MetricAction(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+	e := NewEngine(nil, nil, nil, nil, EngineConfig{MetricMaxSeries: 2}, DispatcherConfig{}, nil, nil)
+	e.SetMeter(meter)
+	e.cachedRules = []*db.Rule{{
+		ID:         "r1",
+		Conditions: []db.Condition{{Path: "$.app_id", Operator: "eq", Value: "app"}},
+		Actions: db.Actions{Metrics: []db.MetricAction{{
+			Name:   "screens",
+			Labels: map[string]string{"screen": "$.screen_view.screen_name", "missing": "$.nope"},
+		}}},
+	}}
+
+	for _, screen := range []string{"home", "home", "cart", "checkout", "settings"} {
+		event := &pb.EventEnvelope{
+			Id:      "e-" + screen,
+			AppId:   "app",
+			Payload: &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: screen}},
+		}
+		if err := e.ProcessEvent(context.Background(), event); err != nil {
+			t.Fatalf("ProcessEvent: %v", err)
+		}
+	}
+
+	// The first two screens fill the series limit; later ones count as other.
+	want := map[string]int64{
+		"missing=,screen=home":       2,
+		"missing=,screen=cart":       1,
+		"missing=other,screen=other": 2,
+	}
+	got := collectCounter(t, reader, "screens")
+	if len(got) != len(want) {
+		t.Fatalf("series = %v, want %v", got, want)
+	}
+	for series, n := range want {
+		if got[series] != n {
+			t.Errorf("series %s = %d, want %d (all: %v)", series, got[series], n, got)
+		}
+	}
+}
+
+func TestRuleCounters_RejectsOtherLabels(t *testing.T) {
+	meter := sdkmetric.NewMeterProvider().Meter("test")
+	c := newRuleCounters(meter, 10, nil)
+	lookup := func(string) (interface{}, bool) { return "v", true }
+
+	if err := c.increment(context.Background(), db.MetricAction{Name: "m", Labels: map[string]string{"a": "$.a"}}, lookup); err != nil {
+		t.Fatalf("increment: %v", err)
+	}
+	if err := c.increment(context.Background(), db.MetricAction{Name: "m", Labels: map[string]string{"b": "$.b"}}, lookup); err == nil {
+		t.Error("increment with other label names: got nil error")
+	}
+}
+
+func TestTruncateLabelValue(t *testing.T) {
+	long := strings.Repeat("a", maxMetricLabelValueLen-1) + "é"
+	if got := truncateLabelValue(long); got != strings.Repeat("a", maxMetricLabelValueLen-1) {
+		t.Errorf("truncateLabelValue() = %q, want the multi-byte rune dropped", got)
+	}
+	if got := truncateLabelValue("short"); got != "short" {
+		t.Errorf("truncateLabelValue(short) = %q", got)
+	}
+}
@@ -0,0 +1,298 @@
+package reaction
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// payloadEncryptionAlgorithm identifies the envelope format stored in
+// webhook_deliveries.payload when encryption is enabled.
+const payloadEncryptionAlgorithm = "aes-256-gcm"
+
+// dataKeySize is the size of the per-payload data encryption key (AES-256).
+const dataKeySize = 32
+
+// KeyWrapper wraps and unwraps per-payload data keys with a key-encryption
+// key. KMSKeyWrapper calls AWS KMS; LocalKeyWrapper holds the key in memory
+// and is meant for development.
+type KeyWrapper interface {
+	// KeyID returns the identifier of the key used for new payloads.
+	KeyID() string
+
+	// WrapKey encrypts a data key with the active key-encryption key.
+	WrapKey(ctx context.Context, dataKey []byte) ([]byte, error)
+
+	// UnwrapKey decrypts a data key that was wrapped with the given key ID.
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// encryptedPayload is the JSON envelope stored in place of the plaintext
+// payload. It is valid JSON so the existing JSONB column needs no change.
+type encryptedPayload struct {
+	Algorithm  string `json:"enc"`
+	KeyID      string `json:"kid"`
+	WrappedKey string `json:"wrapped_key"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// PayloadCipher performs envelope encryption of webhook delivery payloads.
+// Each payload is encrypted with a fresh AES-256-GCM data key, which is itself
+// wrapped by the configured KeyWrapper and stored alongside the ciphertext.
+// A nil *PayloadCipher passes plaintext payloads through unchanged.
+type PayloadCipher struct {
+	wrapper KeyWrapper
+	seal    bool
+}
+
+// NewPayloadCipher creates a payload cipher. When seal is false the cipher
+// only decrypts, which allows encryption to be turned off while previously
+// encrypted deliveries are still pending.
+func NewPayloadCipher(wrapper KeyWrapper, seal bool) *PayloadCipher {
+	return &PayloadCipher{wrapper: wrapper, seal: seal}
+}
+
+// NewPayloadCipherFromConfig builds a payload cipher backed by the
+// configured key provider: AWS KMS, or a local key-encryption key for
+// development. It returns nil when no key is configured and encryption is
+// disabled.
+func NewPayloadCipherFromConfig(ctx context.Context, cfg PayloadEncryptionConfig) (*PayloadCipher, error) {
+	var (
+		wrapper KeyWrapper
+		err     error
+	)
+
+	switch cfg.Provider {
+	case PayloadKeyProviderKMS, "":
+		if cfg.KMS.KeyID == "" {
+			if cfg.Enabled {
+				return nil, fmt.Errorf("%w: KMS key ID is required when payload encryption is enabled", ErrInvalidEncryptionKey)
+			}
+			return nil, nil
+		}
+		wrapper, err = NewKMSKeyWrapperFromConfig(ctx, cfg.KMS)
+	case PayloadKeyProviderLocal:
+		if cfg.Key == "" {
+			if cfg.Enabled {
+				return nil, fmt.Errorf("%w: key is required when payload encryption is enabled", ErrInvalidEncryptionKey)
+			}
+			return nil, nil
+		}
+		wrapper, err = NewLocalKeyWrapper(cfg.KeyID, cfg.Key, cfg.RetiredKeys)
+	default:
+		return nil, fmt.Errorf("%w: unknown key provider %q", ErrInvalidEncryptionKey, cfg.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPayloadCipher(wrapper, cfg.Enabled), nil
+}
+
+// Seal encrypts a payload for storage. If the cipher is nil or sealing is
+// disabled, the payload is returned unchanged.
+func (c *PayloadCipher) Seal(ctx context.Context, payload []byte) ([]byte, error) {
+	if c == nil || !c.seal {
+		return payload, nil
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	wrapped, err := c.wrapper.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	keyID := c.wrapper.KeyID()
+	envelope := encryptedPayload{
+		Algorithm:  payloadEncryptionAlgorithm,
+		KeyID:      keyID,
+		WrappedKey: base64.StdEncoding.EncodeToString(wrapped),
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, payload, []byte(keyID))),
+	}
+
+	return json.Marshal(envelope)
+}
+
+// Open returns the plaintext payload. Payloads that were stored before
+// encryption was enabled are returned unchanged.
+func (c *PayloadCipher) Open(ctx context.Context, payload []byte) ([]byte, error) {
+	envelope, ok := parseEncryptedPayload(payload)
+	if !ok {
+		return payload, nil
+	}
+
+	if c == nil {
+		return nil, ErrPayloadKeyUnavailable
+	}
+	if envelope.Algorithm != payloadEncryptionAlgorithm {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrPayloadDecryption, envelope.Algorithm)
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(envelope.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid wrapped key: %v", ErrPayloadDecryption, err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(envelope.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid nonce: %v", ErrPayloadDecryption, err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(envelope.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid ciphertext: %v", ErrPayloadDecryption, err)
+	}
+
+	dataKey, err := c.wrapper.UnwrapKey(ctx, envelope.KeyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("%w: invalid nonce size", ErrPayloadDecryption)
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(envelope.KeyID))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPayloadDecryption, err)
+	}
+
+	return plaintext, nil
+}
+
+// parseEncryptedPayload reports whether payload is an encryption envelope.
+func parseEncryptedPayload(payload []byte) (*encryptedPayload, bool) {
+	var envelope encryptedPayload
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return nil, false
+	}
+	if envelope.Algorithm == "" || envelope.Ciphertext == "" {
+		return nil, false
+	}
+	return &envelope, true
+}
+
+// LocalKeyWrapper wraps data keys with AES-256-GCM key-encryption keys held
+// in memory, for development; deployments use KMSKeyWrapper. Retired keys
+// remain available for unwrapping so that the active key can be rotated
+// without breaking pending deliveries.
+type LocalKeyWrapper struct {
+	activeID string
+	keys     map[string]cipher.AEAD
+}
+
+// NewLocalKeyWrapper creates a key wrapper from base64-encoded 256-bit keys.
+func NewLocalKeyWrapper(keyID, key string, retired map[string]string) (*LocalKeyWrapper, error) {
+	if keyID == "" {
+		return nil, fmt.Errorf("%w: key ID is required", ErrInvalidEncryptionKey)
+	}
+
+	w := &LocalKeyWrapper{
+		activeID: keyID,
+		keys:     make(map[string]cipher.AEAD, len(retired)+1),
+	}
+
+	for id, encoded := range retired {
+		if err := w.addKey(id, encoded); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.addKey(keyID, key); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// addKey decodes and registers a key-encryption key.
+func (w *LocalKeyWrapper) addKey(id, encoded string) error {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("%w: key %q is not valid base64", ErrInvalidEncryptionKey, id)
+	}
+	if len(raw) != dataKeySize {
+		return fmt.Errorf("%w: key %q must be %d bytes, got %d", ErrInvalidEncryptionKey, id, dataKeySize, len(raw))
+	}
+
+	gcm, err := newGCM(raw)
+	if err != nil {
+		return err
+	}
+	w.keys[id] = gcm
+	return nil
+}
+
+// KeyID returns the identifier of the active key-encryption key.
+func (w *LocalKeyWrapper) KeyID() string {
+	return w.activeID
+}
+
+// WrapKey encrypts a data key with the active key-encryption key. The
+// returned value is the nonce followed by the ciphertext.
+func (w *LocalKeyWrapper) WrapKey(_ context.Context, dataKey []byte) ([]byte, error) {
+	gcm := w.keys[w.activeID]
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return gcm.Seal(nonce, nonce, dataKey, []byte(w.activeID)), nil
+}
+
+// UnwrapKey decrypts a data key wrapped with the given key ID.
+func (w *LocalKeyWrapper) UnwrapKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	gcm, ok := w.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrPayloadKeyUnavailable, keyID)
+	}
+
+	if len(wrapped) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%w: wrapped key too short", ErrPayloadDecryption)
+	}
+
+	nonce, ciphertext := wrapped[:gcm.NonceSize()], wrapped[gcm.NonceSize():]
+	dataKey, err := gcm.Open(nil, nonce, ciphertext, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to unwrap data key: %v", ErrPayloadDecryption, err)
+	}
+
+	return dataKey, nil
+}
+
+// newGCM creates an AES-GCM AEAD for the given key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return gcm, nil
+}
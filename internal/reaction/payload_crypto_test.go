@@ -0,0 +1,128 @@
+package reaction
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func testKey(t *testing.T) string {
+	t.Helper()
+	key := make([]byte, dataKeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("rand.Read() error = %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func TestPayloadCipher_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	wrapper, err := NewLocalKeyWrapper("k1", testKey(t), nil)
+	if err != nil {
+		t.Fatalf("NewLocalKeyWrapper() error = %v", err)
+	}
+	c := NewPayloadCipher(wrapper, true)
+
+	plaintext := []byte(`{"app_id":"app","event":{"screen_name":"home"}}`)
+	sealed, err := c.Seal(ctx, plaintext)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if bytes.Contains(sealed, []byte("screen_name")) {
+		t.Fatalf("sealed payload contains plaintext: %s", sealed)
+	}
+
+	opened, err := c.Open(ctx, sealed)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("Open() = %s, want %s", opened, plaintext)
+	}
+}
+
+func TestPayloadCipher_PlaintextPassthrough(t *testing.T) {
+	ctx := context.Background()
+	plaintext := []byte(`{"app_id":"app"}`)
+
+	var nilCipher *PayloadCipher
+	sealed, err := nilCipher.Seal(ctx, plaintext)
+	if err != nil || !bytes.Equal(sealed, plaintext) {
+		t.Fatalf("nil Seal() = %s, %v; want passthrough", sealed, err)
+	}
+
+	opened, err := nilCipher.Open(ctx, plaintext)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Fatalf("nil Open() = %s, %v; want passthrough", opened, err)
+	}
+}
+
+func TestPayloadCipher_KeyRotation(t *testing.T) {
+	ctx := context.Background()
+	oldKey := testKey(t)
+
+	oldWrapper, err := NewLocalKeyWrapper("old", oldKey, nil)
+	if err != nil {
+		t.Fatalf("NewLocalKeyWrapper() error = %v", err)
+	}
+	sealed, err := NewPayloadCipher(oldWrapper, true).Seal(ctx, []byte(`{"a":1}`))
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	newWrapper, err := NewLocalKeyWrapper("new", testKey(t), map[string]string{"old": oldKey})
+	if err != nil {
+		t.Fatalf("NewLocalKeyWrapper() error = %v", err)
+	}
+	if _, err := NewPayloadCipher(newWrapper, true).Open(ctx, sealed); err != nil {
+		t.Fatalf("Open() with retired key error = %v", err)
+	}
+
+	unknown, err := NewLocalKeyWrapper("new", testKey(t), nil)
+	if err != nil {
+		t.Fatalf("NewLocalKeyWrapper() error = %v", err)
+	}
+	if _, err := NewPayloadCipher(unknown, true).Open(ctx, sealed); !errors.Is(err, ErrPayloadKeyUnavailable) {
+		t.Errorf("Open() without key error = %v, want ErrPayloadKeyUnavailable", err)
+	}
+
+	var nilCipher *PayloadCipher
+	if _, err := nilCipher.Open(ctx, sealed); !errors.Is(err, ErrPayloadKeyUnavailable) {
+		t.Errorf("nil Open() error = %v, want ErrPayloadKeyUnavailable", err)
+	}
+}
+
+func TestNewPayloadCipherFromConfig(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := NewPayloadCipherFromConfig(ctx, PayloadEncryptionConfig{Provider: PayloadKeyProviderKMS})
+	if err != nil || c != nil {
+		t.Errorf("disabled config = %v, %v; want nil, nil", c, err)
+	}
+
+	if _, err := NewPayloadCipherFromConfig(ctx, PayloadEncryptionConfig{Enabled: true, Provider: PayloadKeyProviderKMS}); !errors.Is(err, ErrInvalidEncryptionKey) {
+		t.Errorf("missing KMS key error = %v, want ErrInvalidEncryptionKey", err)
+	}
+
+	local := PayloadEncryptionConfig{Enabled: true, Provider: PayloadKeyProviderLocal, KeyID: "k"}
+	if _, err := NewPayloadCipherFromConfig(ctx, local); !errors.Is(err, ErrInvalidEncryptionKey) {
+		t.Errorf("missing key error = %v, want ErrInvalidEncryptionKey", err)
+	}
+
+	local.Key = "c2hvcnQ="
+	if _, err := NewPayloadCipherFromConfig(ctx, local); !errors.Is(err, ErrInvalidEncryptionKey) {
+		t.Errorf("short key error = %v, want ErrInvalidEncryptionKey", err)
+	}
+
+	local.Key = testKey(t)
+	if c, err := NewPayloadCipherFromConfig(ctx, local); err != nil || c == nil {
+		t.Errorf("local config = %v, %v; want cipher", c, err)
+	}
+
+	if _, err := NewPayloadCipherFromConfig(ctx, PayloadEncryptionConfig{Provider: "vault"}); !errors.Is(err, ErrInvalidEncryptionKey) {
+		t.Errorf("unknown provider error = %v, want ErrInvalidEncryptionKey", err)
+	}
+}
@@ -0,0 +1,94 @@
+package reaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// kmsContextKey is the encryption context key binding a wrapped data key to
+// the key ID stored in the payload envelope.
+const kmsContextKey = "causality:payload_kid"
+
+// KMSAPI is the subset of the AWS KMS client used by KMSKeyWrapper.
+type KMSAPI interface {
+	Encrypt(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error)
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// KMSKeyWrapper wraps data keys with an AWS KMS symmetric key, so the
+// key-encryption key never leaves KMS. Data keys wrapped with a previous
+// key remain decryptable as long as the service may use that key: KMS
+// finds the key from the ciphertext, so rotating keys needs no retired
+// key list.
+type KMSKeyWrapper struct {
+	client KMSAPI
+	keyID  string
+}
+
+// NewKMSKeyWrapper creates a key wrapper encrypting data keys with the
+// given KMS key ID, ARN or alias.
+func NewKMSKeyWrapper(client KMSAPI, keyID string) (*KMSKeyWrapper, error) {
+	if keyID == "" {
+		return nil, fmt.Errorf("%w: KMS key ID is required", ErrInvalidEncryptionKey)
+	}
+	return &KMSKeyWrapper{client: client, keyID: keyID}, nil
+}
+
+// NewKMSKeyWrapperFromConfig creates a KMS key wrapper with a client using
+// the default AWS credential chain.
+func NewKMSKeyWrapperFromConfig(ctx context.Context, cfg KMSConfig) (*KMSKeyWrapper, error) {
+	awsCfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(cfg.Region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	client := kms.NewFromConfig(awsCfg, func(o *kms.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+	})
+
+	return NewKMSKeyWrapper(client, cfg.KeyID)
+}
+
+// KeyID returns the KMS key used for new payloads.
+func (w *KMSKeyWrapper) KeyID() string {
+	return w.keyID
+}
+
+// WrapKey encrypts a data key with the KMS key. The returned value is the
+// KMS ciphertext blob.
+func (w *KMSKeyWrapper) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	out, err := w.client.Encrypt(ctx, &kms.EncryptInput{
+		KeyId:             aws.String(w.keyID),
+		Plaintext:         dataKey,
+		EncryptionContext: map[string]string{kmsContextKey: w.keyID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt data key with KMS: %w", err)
+	}
+	return out.CiphertextBlob, nil
+}
+
+// UnwrapKey decrypts a data key wrapped with the given key ID.
+func (w *KMSKeyWrapper) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	out, err := w.client.Decrypt(ctx, &kms.DecryptInput{
+		CiphertextBlob:    wrapped,
+		EncryptionContext: map[string]string{kmsContextKey: keyID},
+	})
+	if err != nil {
+		var invalid *types.InvalidCiphertextException
+		var incorrect *types.IncorrectKeyException
+		if errors.As(err, &invalid) || errors.As(err, &incorrect) {
+			return nil, fmt.Errorf("%w: failed to unwrap data key: %v", ErrPayloadDecryption, err)
+		}
+		return nil, fmt.Errorf("failed to decrypt data key with KMS: %w", err)
+	}
+	return out.Plaintext, nil
+}
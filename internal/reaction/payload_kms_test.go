@@ -0,0 +1,103 @@
+package reaction
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// fakeKMS stands in for KMS: a ciphertext blob is the key ID followed by
+// the plaintext, and Decrypt requires the encryption context it was
+// encrypted with.
+type fakeKMS struct {
+	blobs map[string]fakeKMSBlob
+	err   error
+}
+
+type fakeKMSBlob struct {
+	keyID     string
+	context   map[string]string
+	plaintext []byte
+}
+
+func (f *fakeKMS) Encrypt(_ context.Context, in *kms.EncryptInput, _ ...func(*kms.Options)) (*kms.EncryptOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.blobs == nil {
+		f.blobs = make(map[string]fakeKMSBlob)
+	}
+	blob := append([]byte(aws.ToString(in.KeyId)+":"), in.Plaintext...)
+	f.blobs[string(blob)] = fakeKMSBlob{keyID: aws.ToString(in.KeyId), context: in.EncryptionContext, plaintext: in.Plaintext}
+	return &kms.EncryptOutput{CiphertextBlob: blob, KeyId: in.KeyId}, nil
+}
+
+func (f *fakeKMS) Decrypt(_ context.Context, in *kms.DecryptInput, _ ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	blob, ok := f.blobs[string(in.CiphertextBlob)]
+	if !ok || !maps.Equal(blob.context, in.EncryptionContext) {
+		return nil, &types.InvalidCiphertextException{Message: aws.String("invalid ciphertext")}
+	}
+	return &kms.DecryptOutput{Plaintext: blob.plaintext, KeyId: aws.String(blob.keyID)}, nil
+}
+
+func TestKMSKeyWrapper_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeKMS{}
+	wrapper, err := NewKMSKeyWrapper(client, "alias/causality-webhooks")
+	if err != nil {
+		t.Fatalf("NewKMSKeyWrapper() error = %v", err)
+	}
+	c := NewPayloadCipher(wrapper, true)
+
+	plaintext := []byte(`{"event":"purchase"}`)
+	sealed, err := c.Seal(ctx, plaintext)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	envelope, ok := parseEncryptedPayload(sealed)
+	if !ok || envelope.KeyID != "alias/causality-webhooks" {
+		t.Fatalf("envelope = %+v, want kid alias/causality-webhooks", envelope)
+	}
+
+	opened, err := c.Open(ctx, sealed)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("Open() = %s, want %s", opened, plaintext)
+	}
+
+	// The encryption context binds the wrapped key to the envelope's key ID
+	if _, err := wrapper.UnwrapKey(ctx, "alias/other", []byte("alias/causality-webhooks:x")); !errors.Is(err, ErrPayloadDecryption) {
+		t.Errorf("UnwrapKey() with another key ID error = %v, want ErrPayloadDecryption", err)
+	}
+}
+
+func TestKMSKeyWrapper_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := NewKMSKeyWrapper(&fakeKMS{}, ""); !errors.Is(err, ErrInvalidEncryptionKey) {
+		t.Errorf("NewKMSKeyWrapper() without key error = %v, want ErrInvalidEncryptionKey", err)
+	}
+
+	unavailable := errors.New("connection refused")
+	wrapper, err := NewKMSKeyWrapper(&fakeKMS{err: unavailable}, "key")
+	if err != nil {
+		t.Fatalf("NewKMSKeyWrapper() error = %v", err)
+	}
+	if _, err := NewPayloadCipher(wrapper, true).Seal(ctx, []byte(`{}`)); !errors.Is(err, unavailable) {
+		t.Errorf("Seal() error = %v, want %v", err, unavailable)
+	}
+	if _, err := wrapper.UnwrapKey(ctx, "key", []byte("blob")); !errors.Is(err, unavailable) || errors.Is(err, ErrPayloadDecryption) {
+		t.Errorf("UnwrapKey() error = %v, want %v", err, unavailable)
+	}
+}
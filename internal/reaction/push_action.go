@@ -0,0 +1,98 @@
+package reaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/SebastienMelki/causality/internal/push"
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// PushSender sends push notifications to devices for push_notification
+// actions. It is satisfied by *push.Module.
+type PushSender interface {
+	Send(ctx context.Context, appID, deviceID string, n push.Notification) error
+}
+
+// templatePlaceholder matches the {{$.path}} placeholders of push
+// notification templates.
+var templatePlaceholder = regexp.MustCompile(`\{\{\s*(\$\.[A-Za-z0-9_.]+)\s*\}\}`)
+
+// validatePushAction checks a push notification action has a title and a
+// body.
+func validatePushAction(action *db.PushNotificationAction) error {
+	if strings.TrimSpace(action.Title) == "" {
+		return errors.New("push notification title is required")
+	}
+	if strings.TrimSpace(action.Body) == "" {
+		return errors.New("push notification body is required")
+	}
+	return nil
+}
+
+// renderPushNotification fills the templates of action with event fields
+// read by lookup.
+func renderPushNotification(action *db.PushNotificationAction, lookup func(path string) (interface{}, bool)) push.Notification {
+	n := push.Notification{
+		Title: renderTemplate(action.Title, lookup),
+		Body:  renderTemplate(action.Body, lookup),
+	}
+	if len(action.Data) > 0 {
+		n.Data = make(map[string]string, len(action.Data))
+		for k, v := range action.Data {
+			n.Data[k] = renderTemplate(v, lookup)
+		}
+	}
+	return n
+}
+
+// renderTemplate replaces each {{$.path}} placeholder of tmpl with the event
+// field at path. Missing and non-scalar fields render as "".
+func renderTemplate(tmpl string, lookup func(path string) (interface{}, bool)) string {
+	return templatePlaceholder.ReplaceAllStringFunc(tmpl, func(match string) string {
+		path := templatePlaceholder.FindStringSubmatch(match)[1]
+		v, ok := lookup(path)
+		if !ok {
+			return ""
+		}
+		switch v := v.(type) {
+		case string:
+			return v
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		case bool, int, int32, int64, uint32, uint64, float32:
+			return fmt.Sprint(v)
+		default:
+			return ""
+		}
+	})
+}
+
+// sendPushNotification sends a rule's push notification to the device of
+// the matched event. Devices without a registered token are skipped.
+func (e *Engine) sendPushNotification(ctx context.Context, rule *db.Rule, appID, deviceID string, eventJSON map[string]interface{}) {
+	if deviceID == "" {
+		return
+	}
+
+	lookup := func(path string) (interface{}, bool) { return e.extractJSONPath(eventJSON, path) }
+	n := renderPushNotification(rule.Actions.PushNotification, lookup)
+
+	err := e.push.Send(ctx, appID, deviceID, n)
+	switch {
+	case err == nil:
+		e.logger.Debug("push notification sent", "rule_id", rule.ID, "device_id", deviceID)
+	case errors.Is(err, push.ErrTokenNotFound):
+		e.logger.Debug("no push token for device", "rule_id", rule.ID, "device_id", deviceID)
+	default:
+		e.logger.Warn("failed to send push notification",
+			"rule_id", rule.ID,
+			"device_id", deviceID,
+			"error", err,
+		)
+	}
+}
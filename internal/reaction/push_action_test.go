@@ -0,0 +1,93 @@
+package reaction
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SebastienMelki/causality/internal/push"
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// sentPush is a notification recorded by fakePushSender.
+type sentPush struct {
+	appID, deviceID string
+	n               push.Notification
+}
+
+type fakePushSender struct {
+	sent []sentPush
+}
+
+func (f *fakePushSender) Send(_ context.Context, appID, deviceID string, n push.Notification) error {
+	f.sent = append(f.sent, sentPush{appID: appID, deviceID: deviceID, n: n})
+	return nil
+}
+
+func TestRenderTemplate(t *testing.T) {
+	fields := map[string]interface{}{
+		"screen": map[string]interface{}{"name": "cart", "items": float64(3), "total": 12.5},
+	}
+	lookup := func(path string) (interface{}, bool) {
+		e := &Engine{}
+		return e.extractJSONPath(fields, path)
+	}
+
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{"Back to your {{$.screen.name}}?", "Back to your cart?"},
+		{"{{ $.screen.items }} items, ${{$.screen.total}}", "3 items, $12.5"},
+		{"missing: '{{$.screen.nope}}'", "missing: ''"},
+		{"object: '{{$.screen}}'", "object: ''"},
+		{"no placeholders", "no placeholders"},
+	}
+	for _, tt := range tests {
+		if got := renderTemplate(tt.tmpl, lookup); got != tt.want {
+			t.Errorf("renderTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+}
+
+func TestEngine_PushNotificationAction(t *testing.T) {
+	sender := &fakePushSender{}
+	e := NewEngine(nil, nil, nil, nil, EngineConfig{}, DispatcherConfig{}, nil, nil)
+	e.SetPushSender(sender)
+	e.cachedRules = []*db.Rule{{
+		ID:         "r1",
+		Conditions: []db.Condition{{Path: "$.app_id", Operator: "eq", Value: "app"}},
+		Actions: db.Actions{PushNotification: &db.PushNotificationAction{
+			Title: "Still thinking it over?",
+			Body:  "You left {{$.screen_view.screen_name}} open",
+			Data:  map[string]string{"screen": "{{$.screen_view.screen_name}}"},
+		}},
+	}}
+
+	for _, deviceID := range []string{"d1", ""} {
+		event := &pb.EventEnvelope{
+			Id:       "e-" + deviceID,
+			AppId:    "app",
+			DeviceId: deviceID,
+			Payload:  &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "cart"}},
+		}
+		if err := e.ProcessEvent(context.Background(), event); err != nil {
+			t.Fatalf("ProcessEvent: %v", err)
+		}
+	}
+
+	// Events without a device have no one to notify.
+	if len(sender.sent) != 1 {
+		t.Fatalf("sent %d notifications, want 1", len(sender.sent))
+	}
+	got := sender.sent[0]
+	if got.appID != "app" || got.deviceID != "d1" {
+		t.Errorf("sent to %s/%s, want app/d1", got.appID, got.deviceID)
+	}
+	if got.n.Title != "Still thinking it over?" || got.n.Body != "You left cart open" {
+		t.Errorf("notification = %+v", got.n)
+	}
+	if got.n.Data["screen"] != "cart" {
+		t.Errorf("data = %v, want screen=cart", got.n.Data)
+	}
+}
@@ -0,0 +1,128 @@
+package reaction
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// resourceSyncer is the subset of ResourceSyncer used by ResourceHandler.
+type resourceSyncer interface {
+	Sync(ctx context.Context, spec ResourceSpec, opts SyncOptions) (*SyncResult, error)
+}
+
+// ResourceHandler serves the admin API for declarative resource sync.
+type ResourceHandler struct {
+	syncer resourceSyncer
+	engine *Engine
+	logger *slog.Logger
+}
+
+// NewResourceHandler creates a ResourceHandler. If engine is non-nil, its
+// rule cache is refreshed after an apply so rule changes take effect at once;
+// anomaly configs are picked up on the detector's next config refresh.
+func NewResourceHandler(syncer *ResourceSyncer, engine *Engine, logger *slog.Logger) *ResourceHandler {
+	return newResourceHandler(syncer, engine, logger)
+}
+
+func newResourceHandler(syncer resourceSyncer, engine *Engine, logger *slog.Logger) *ResourceHandler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &ResourceHandler{
+		syncer: syncer,
+		engine: engine,
+		logger: logger.With("component", "resource-handler"),
+	}
+}
+
+// RegisterRoutes mounts resource sync endpoints on the given ServeMux.
+//
+// TODO(phase-3): Protect these endpoints with session auth + RBAC.
+//
+// Endpoints:
+//   - POST /api/admin/resources/apply - Reconcile rules, webhooks and anomaly configs with a spec
+func (h *ResourceHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/admin/resources/apply", h.handleApply)
+}
+
+// applyRequest is the JSON request body for applying a resource spec.
+type applyRequest struct {
+	Spec   ResourceSpec `json:"spec"`
+	DryRun bool         `json:"dry_run"`
+	Prune  bool         `json:"prune"`
+	Author string       `json:"author"`
+}
+
+// handleApply handles POST /api/admin/resources/apply.
+func (h *ResourceHandler) handleApply(w http.ResponseWriter, r *http.Request) {
+	var req applyRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "invalid request body: " + err.Error(),
+		})
+		return
+	}
+	if req.Author == "" && !req.DryRun {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "author is required",
+		})
+		return
+	}
+
+	result, err := h.syncer.Sync(r.Context(), req.Spec, SyncOptions{
+		DryRun: req.DryRun,
+		Prune:  req.Prune,
+		Author: req.Author,
+	})
+	if result != nil && !req.DryRun && len(result.Changes) > 0 {
+		h.logger.Info("resources applied",
+			"changes", len(result.Changes),
+			"prune", req.Prune,
+			"author", req.Author,
+		)
+		h.refreshRules(r.Context(), result)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidResourceSpec):
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		case errors.Is(err, ErrDuplicateResourceName):
+			writeJSON(w, http.StatusConflict, map[string]string{
+				"error": err.Error(),
+			})
+		default:
+			h.logger.Error("failed to apply resources", "error", err)
+			// Changes applied before the failure remain; report them
+			body := map[string]interface{}{"error": "failed to apply resources: " + err.Error()}
+			if result != nil {
+				body["changes"] = result.Changes
+			}
+			writeJSON(w, http.StatusInternalServerError, body)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// refreshRules refreshes the engine's rule cache if the result changed rules.
+func (h *ResourceHandler) refreshRules(ctx context.Context, result *SyncResult) {
+	if h.engine == nil {
+		return
+	}
+	for _, change := range result.Changes {
+		if change.Kind == ResourceRule {
+			if err := h.engine.RefreshRules(ctx); err != nil {
+				h.logger.Warn("failed to refresh rules after apply", "error", err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package reaction
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// retentionEventStore is the subset of db.AnomalyConfigRepository used by
+// Retention.
+type retentionEventStore interface {
+	ListOldEvents(ctx context.Context, olderThan time.Time, limit int) ([]*db.AnomalyEvent, error)
+	DeleteEvents(ctx context.Context, ids []string) (int64, error)
+}
+
+// retentionDeliveryStore is the subset of db.DeliveryRepository used by
+// Retention.
+type retentionDeliveryStore interface {
+	ListOld(ctx context.Context, olderThan time.Time, limit int) ([]*db.WebhookDelivery, error)
+	DeleteByIDs(ctx context.Context, ids []string) (int64, error)
+}
+
+// archiver is the subset of Archive used by Retention.
+type archiver interface {
+	WriteAnomalyEvents(ctx context.Context, events []*db.AnomalyEvent) error
+	WriteDeliveries(ctx context.Context, deliveries []*db.WebhookDelivery) error
+}
+
+// Retention periodically deletes anomaly events and finished webhook
+// deliveries older than their retention, a batch at a time. With an archive
+// set, each batch is archived first and only deleted once archived, so a
+// failing archive delays deletion instead of losing rows.
+type Retention struct {
+	events            retentionEventStore
+	deliveries        retentionDeliveryStore
+	archive           archiver
+	eventRetention    time.Duration
+	deliveryRetention time.Duration
+	interval          time.Duration
+	batchSize         int
+	logger            *slog.Logger
+	now               func() time.Time
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// NewRetention creates a retention job. Anomaly events without a retention
+// of their own are kept for the anomaly state retention.
+func NewRetention(
+	events *db.AnomalyConfigRepository,
+	deliveries *db.DeliveryRepository,
+	config RetentionConfig,
+	anomalyConfig AnomalyConfig,
+	logger *slog.Logger,
+) *Retention {
+	eventRetention := config.AnomalyEvents
+	if eventRetention <= 0 {
+		eventRetention = anomalyConfig.StateRetentionDuration
+	}
+	return newRetention(events, deliveries, eventRetention, config.Deliveries, config.Interval, config.BatchSize, logger)
+}
+
+func newRetention(
+	events retentionEventStore,
+	deliveries retentionDeliveryStore,
+	eventRetention time.Duration,
+	deliveryRetention time.Duration,
+	interval time.Duration,
+	batchSize int,
+	logger *slog.Logger,
+) *Retention {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	if batchSize <= 0 {
+		batchSize = 5000
+	}
+
+	return &Retention{
+		events:            events,
+		deliveries:        deliveries,
+		eventRetention:    eventRetention,
+		deliveryRetention: deliveryRetention,
+		interval:          interval,
+		batchSize:         batchSize,
+		logger:            logger.With("component", "retention"),
+		now:               time.Now,
+		stopCh:            make(chan struct{}),
+		doneCh:            make(chan struct{}),
+	}
+}
+
+// SetArchive sets the archive expired rows are written to before they are
+// deleted. Must be called before Start.
+func (r *Retention) SetArchive(archive *Archive) {
+	if archive != nil {
+		r.archive = archive
+	}
+}
+
+// Start starts removing expired rows every interval.
+func (r *Retention) Start(ctx context.Context) {
+	go r.run(ctx)
+	r.logger.Info("retention started",
+		"anomaly_events", r.eventRetention,
+		"deliveries", r.deliveryRetention,
+		"interval", r.interval,
+		"archive", r.archive != nil,
+	)
+}
+
+// Stop stops the retention job.
+func (r *Retention) Stop() {
+	close(r.stopCh)
+	<-r.doneCh
+	r.logger.Info("retention stopped")
+}
+
+// run removes expired rows every interval.
+func (r *Retention) run(ctx context.Context) {
+	defer close(r.doneCh)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+			r.Run(ctx)
+		}
+	}
+}
+
+// Run removes the anomaly events and webhook deliveries past their
+// retention. A retention of 0 keeps the rows.
+func (r *Retention) Run(ctx context.Context) {
+	if r.eventRetention > 0 {
+		deleted, err := r.expire(ctx, r.now().Add(-r.eventRetention), r.expireEvents)
+		r.logResult("anomaly_events", deleted, err)
+	}
+	if r.deliveryRetention > 0 {
+		deleted, err := r.expire(ctx, r.now().Add(-r.deliveryRetention), r.expireDeliveries)
+		r.logResult("webhook_deliveries", deleted, err)
+	}
+}
+
+// expire calls batch until it handles fewer than batchSize rows or fails,
+// returning the number of rows deleted.
+func (r *Retention) expire(ctx context.Context, cutoff time.Time, batch func(context.Context, time.Time) (int, int64, error)) (int64, error) {
+	var total int64
+	for ctx.Err() == nil {
+		listed, deleted, err := batch(ctx, cutoff)
+		total += deleted
+		if err != nil {
+			return total, err
+		}
+		if listed < r.batchSize {
+			break
+		}
+	}
+	return total, nil
+}
+
+// expireEvents archives and deletes a batch of anomaly events created before
+// cutoff, returning the number listed and deleted.
+func (r *Retention) expireEvents(ctx context.Context, cutoff time.Time) (int, int64, error) {
+	events, err := r.events.ListOldEvents(ctx, cutoff, r.batchSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("list: %w", err)
+	}
+	if len(events) == 0 {
+		return 0, 0, nil
+	}
+
+	if r.archive != nil {
+		if err := r.archive.WriteAnomalyEvents(ctx, events); err != nil {
+			return len(events), 0, fmt.Errorf("archive: %w", err)
+		}
+	}
+
+	ids := make([]string, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+	deleted, err := r.events.DeleteEvents(ctx, ids)
+	if err != nil {
+		return len(events), 0, fmt.Errorf("delete: %w", err)
+	}
+	return len(events), deleted, nil
+}
+
+// expireDeliveries archives and deletes a batch of finished webhook
+// deliveries created before cutoff, returning the number listed and deleted.
+func (r *Retention) expireDeliveries(ctx context.Context, cutoff time.Time) (int, int64, error) {
+	deliveries, err := r.deliveries.ListOld(ctx, cutoff, r.batchSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("list: %w", err)
+	}
+	if len(deliveries) == 0 {
+		return 0, 0, nil
+	}
+
+	if r.archive != nil {
+		if err := r.archive.WriteDeliveries(ctx, deliveries); err != nil {
+			return len(deliveries), 0, fmt.Errorf("archive: %w", err)
+		}
+	}
+
+	ids := make([]string, len(deliveries))
+	for i, delivery := range deliveries {
+		ids[i] = delivery.ID
+	}
+	deleted, err := r.deliveries.DeleteByIDs(ctx, ids)
+	if err != nil {
+		return len(deliveries), 0, fmt.Errorf("delete: %w", err)
+	}
+	return len(deliveries), deleted, nil
+}
+
+// logResult logs the outcome of expiring a table.
+func (r *Retention) logResult(table string, deleted int64, err error) {
+	if err != nil {
+		r.logger.Error("failed to expire old rows",
+			"table", table,
+			"deleted", deleted,
+			"error", err,
+		)
+		return
+	}
+	if deleted > 0 {
+		r.logger.Debug("expired old rows", "table", table, "count", deleted)
+	}
+}
@@ -0,0 +1,273 @@
+package reaction
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+// fakeRetentionStore holds anomaly events and deliveries, oldest first.
+type fakeRetentionStore struct {
+	events     []*db.AnomalyEvent
+	deliveries []*db.WebhookDelivery
+}
+
+func (f *fakeRetentionStore) ListOldEvents(_ context.Context, olderThan time.Time, limit int) ([]*db.AnomalyEvent, error) {
+	var out []*db.AnomalyEvent
+	for _, e := range f.events {
+		if e.CreatedAt.Before(olderThan) && len(out) < limit {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeRetentionStore) DeleteEvents(_ context.Context, ids []string) (int64, error) {
+	remove := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+	}
+	var kept []*db.AnomalyEvent
+	for _, e := range f.events {
+		if !remove[e.ID] {
+			kept = append(kept, e)
+		}
+	}
+	deleted := int64(len(f.events) - len(kept))
+	f.events = kept
+	return deleted, nil
+}
+
+func (f *fakeRetentionStore) ListOld(_ context.Context, olderThan time.Time, limit int) ([]*db.WebhookDelivery, error) {
+	var out []*db.WebhookDelivery
+	for _, d := range f.deliveries {
+		finished := d.Status == db.DeliveryStatusDelivered || d.Status == db.DeliveryStatusDeadLetter
+		if finished && d.CreatedAt.Before(olderThan) && len(out) < limit {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeRetentionStore) DeleteByIDs(_ context.Context, ids []string) (int64, error) {
+	remove := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+	}
+	var kept []*db.WebhookDelivery
+	for _, d := range f.deliveries {
+		if !remove[d.ID] {
+			kept = append(kept, d)
+		}
+	}
+	deleted := int64(len(f.deliveries) - len(kept))
+	f.deliveries = kept
+	return deleted, nil
+}
+
+// fakeArchiver records archived rows, failing while err is set.
+type fakeArchiver struct {
+	events     []string
+	deliveries []string
+	err        error
+}
+
+func (f *fakeArchiver) WriteAnomalyEvents(_ context.Context, events []*db.AnomalyEvent) error {
+	if f.err != nil {
+		return f.err
+	}
+	for _, e := range events {
+		f.events = append(f.events, e.ID)
+	}
+	return nil
+}
+
+func (f *fakeArchiver) WriteDeliveries(_ context.Context, deliveries []*db.WebhookDelivery) error {
+	if f.err != nil {
+		return f.err
+	}
+	for _, d := range deliveries {
+		f.deliveries = append(f.deliveries, d.ID)
+	}
+	return nil
+}
+
+func newRetentionStore(now time.Time) *fakeRetentionStore {
+	store := &fakeRetentionStore{}
+	for i, age := range []time.Duration{72 * time.Hour, 60 * time.Hour, 48 * time.Hour, time.Hour} {
+		store.events = append(store.events, &db.AnomalyEvent{
+			ID:        "event-" + string(rune('a'+i)),
+			CreatedAt: now.Add(-age),
+		})
+	}
+	store.deliveries = []*db.WebhookDelivery{
+		{ID: "delivery-a", Status: db.DeliveryStatusDelivered, CreatedAt: now.Add(-72 * time.Hour)},
+		{ID: "delivery-b", Status: db.DeliveryStatusDeadLetter, CreatedAt: now.Add(-60 * time.Hour)},
+		{ID: "delivery-c", Status: db.DeliveryStatusPending, CreatedAt: now.Add(-60 * time.Hour)},
+		{ID: "delivery-d", Status: db.DeliveryStatusDelivered, CreatedAt: now.Add(-time.Hour)},
+	}
+	return store
+}
+
+func eventIDs(events []*db.AnomalyEvent) []string {
+	ids := make([]string, len(events))
+	for i, e := range events {
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+func deliveryIDs(deliveries []*db.WebhookDelivery) []string {
+	ids := make([]string, len(deliveries))
+	for i, d := range deliveries {
+		ids[i] = d.ID
+	}
+	return ids
+}
+
+func TestRetention_Run(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	store := newRetentionStore(now)
+	archive := &fakeArchiver{}
+
+	// A batch size of 2 expires the three old events over two batches.
+	r := newRetention(store, store, 24*time.Hour, 24*time.Hour, time.Hour, 2, nil)
+	r.archive = archive
+	r.now = func() time.Time { return now }
+	r.Run(context.Background())
+
+	if got := strings.Join(eventIDs(store.events), ","); got != "event-d" {
+		t.Errorf("remaining events = %s, want event-d", got)
+	}
+	if got := strings.Join(archive.events, ","); got != "event-a,event-b,event-c" {
+		t.Errorf("archived events = %s, want event-a,event-b,event-c", got)
+	}
+	if got := strings.Join(deliveryIDs(store.deliveries), ","); got != "delivery-c,delivery-d" {
+		t.Errorf("remaining deliveries = %s, want the pending and recent deliveries", got)
+	}
+	if got := strings.Join(archive.deliveries, ","); got != "delivery-a,delivery-b" {
+		t.Errorf("archived deliveries = %s, want delivery-a,delivery-b", got)
+	}
+}
+
+func TestRetention_ArchiveFailureKeepsRows(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	store := newRetentionStore(now)
+	archive := &fakeArchiver{err: errors.New("bucket unavailable")}
+
+	r := newRetention(store, store, 24*time.Hour, 24*time.Hour, time.Hour, 100, nil)
+	r.archive = archive
+	r.now = func() time.Time { return now }
+	r.Run(context.Background())
+
+	if len(store.events) != 4 || len(store.deliveries) != 4 {
+		t.Fatalf("rows deleted despite failed archive: %d events, %d deliveries", len(store.events), len(store.deliveries))
+	}
+
+	archive.err = nil
+	r.Run(context.Background())
+	if len(store.events) != 1 || len(store.deliveries) != 2 {
+		t.Errorf("after recovery: %d events, %d deliveries, want 1 and 2", len(store.events), len(store.deliveries))
+	}
+}
+
+func TestRetention_ZeroKeepsDeliveries(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	store := newRetentionStore(now)
+
+	r := newRetention(store, store, 24*time.Hour, 0, time.Hour, 100, nil)
+	r.now = func() time.Time { return now }
+	r.Run(context.Background())
+
+	if len(store.events) != 1 {
+		t.Errorf("remaining events = %d, want 1", len(store.events))
+	}
+	if len(store.deliveries) != 4 {
+		t.Errorf("remaining deliveries = %d, want all 4", len(store.deliveries))
+	}
+}
+
+func TestArchive_WriteDeliveries(t *testing.T) {
+	ctx := context.Background()
+	client, err := warehouse.NewFSStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFSStore() error = %v", err)
+	}
+	if _, err := client.CreateBucket(ctx, &s3.CreateBucketInput{Bucket: aws.String("lake")}); err != nil {
+		t.Fatalf("CreateBucket() error = %v", err)
+	}
+	archive := NewArchive(client, warehouse.S3Config{Bucket: "lake"}, "/archive/")
+
+	ruleID := "rule-1"
+	status := 503
+	deliveredAt := time.Date(2026, 3, 9, 10, 0, 5, 0, time.UTC)
+	deliveries := []*db.WebhookDelivery{
+		{
+			ID: "delivery-a", WebhookID: "hook-1", RuleID: &ruleID, Payload: []byte(`{"a":1}`),
+			Status: db.DeliveryStatusDelivered, Attempts: 1, MaxAttempts: 5,
+			CreatedAt: time.Date(2026, 3, 9, 10, 0, 0, 0, time.UTC), DeliveredAt: &deliveredAt,
+		},
+		{
+			ID: "delivery-b", WebhookID: "hook-1", Payload: []byte(`{"b":2}`),
+			Status: db.DeliveryStatusDeadLetter, Attempts: 5, MaxAttempts: 5, LastStatusCode: &status,
+			CreatedAt: time.Date(2026, 3, 10, 23, 59, 0, 0, time.UTC),
+		},
+	}
+	if err := archive.WriteDeliveries(ctx, deliveries); err != nil {
+		t.Fatalf("WriteDeliveries() error = %v", err)
+	}
+
+	list, err := client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String("lake"),
+		Prefix: aws.String("archive/webhook_deliveries/"),
+	})
+	if err != nil {
+		t.Fatalf("ListObjectsV2() error = %v", err)
+	}
+	var keys []string
+	for _, obj := range list.Contents {
+		keys = append(keys, aws.ToString(obj.Key))
+	}
+	sort.Strings(keys)
+	if len(keys) != 2 {
+		t.Fatalf("archived %d files, want one per day: %v", len(keys), keys)
+	}
+	if !strings.HasPrefix(keys[0], "archive/webhook_deliveries/year=2026/month=03/day=09/webhook_deliveries_") ||
+		!strings.HasPrefix(keys[1], "archive/webhook_deliveries/year=2026/month=03/day=10/webhook_deliveries_") {
+		t.Errorf("keys = %v, want day partitions 09 and 10", keys)
+	}
+
+	obj, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String("lake"), Key: aws.String(keys[1])})
+	if err != nil {
+		t.Fatalf("GetObject() error = %v", err)
+	}
+	data, err := io.ReadAll(obj.Body)
+	_ = obj.Body.Close()
+	if err != nil {
+		t.Fatalf("read object: %v", err)
+	}
+	rows, err := parquet.Read[deliveryArchiveRow](bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("parquet.Read() error = %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("rows = %d, want 1", len(rows))
+	}
+	row := rows[0]
+	if row.ID != "delivery-b" || row.Status != "dead_letter" || row.PayloadJSON != `{"b":2}` ||
+		row.LastStatusCode != 503 || row.Attempts != 5 || row.DeliveredAtMS != 0 {
+		t.Errorf("row = %+v", row)
+	}
+}
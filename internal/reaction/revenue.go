@@ -0,0 +1,465 @@
+package reaction
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// Revenue baseline types.
+const (
+	// RevenueBaselineTrailing compares a window with the windows right
+	// before it.
+	RevenueBaselineTrailing = "trailing"
+
+	// RevenueBaselineSeasonal compares a window with the same window in
+	// previous periods, such as the same hour on previous weekdays.
+	RevenueBaselineSeasonal = "seasonal"
+
+	// RevenueBaselineFixed compares a window with a configured amount.
+	RevenueBaselineFixed = "fixed"
+)
+
+// Revenue config defaults.
+const (
+	defaultRevenuePath             = "$.purchase_complete.amount_usd"
+	defaultRevenueWindowSeconds    = 3600
+	defaultTrailingBaselineWindows = 24
+	defaultSeasonalBaselineWindows = 4
+	defaultRevenuePeriodSeconds    = 7 * 24 * 3600
+)
+
+// RevenueConfig holds configuration for revenue anomaly detection, which
+// sums a value (normalized revenue by default) per app over fixed windows and
+// compares each window's sum with a baseline. Spikes alert as soon as the
+// running sum exceeds the baseline; drops alert when the window closes.
+type RevenueConfig struct {
+	// Path is the summed value. It defaults to the purchase amount in US
+	// dollars, which needs FX_ENABLED.
+	Path string `json:"path"`
+
+	// WindowSeconds is the window size. Windows are aligned to the Unix
+	// epoch, so hourly windows start on the hour.
+	WindowSeconds int `json:"window_seconds"`
+
+	// Baseline is the baseline type: trailing (default), seasonal or fixed.
+	Baseline string `json:"baseline"`
+
+	// BaselineWindows is how many windows the baseline averages: the
+	// preceding windows for trailing baselines (default 24) or the same
+	// window in preceding periods for seasonal baselines (default 4).
+	BaselineWindows int `json:"baseline_windows"`
+
+	// PeriodSeconds is the seasonal period, a multiple of the window
+	// (default one week).
+	PeriodSeconds int `json:"period_seconds"`
+
+	// Expected is the per-window revenue of a fixed baseline.
+	Expected float64 `json:"expected"`
+
+	// MinRatio alerts when a closed window's revenue is below this fraction
+	// of the baseline.
+	MinRatio *float64 `json:"min_ratio"`
+
+	// MaxRatio alerts when a window's revenue exceeds this multiple of the
+	// baseline.
+	MaxRatio *float64 `json:"max_ratio"`
+
+	// MinBaseline is the smallest baseline that may alert, so apps with
+	// little revenue do not alert on a handful of purchases.
+	MinBaseline float64 `json:"min_baseline"`
+}
+
+// parseRevenueConfig decodes a revenue config, applying defaults, and checks
+// it.
+func parseRevenueConfig(raw json.RawMessage) (RevenueConfig, error) {
+	var rc RevenueConfig
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &rc); err != nil {
+			return RevenueConfig{}, fmt.Errorf("%w: %w", ErrInvalidRevenueConfig, err)
+		}
+	}
+
+	if rc.Path == "" {
+		rc.Path = defaultRevenuePath
+	}
+	if rc.WindowSeconds == 0 {
+		rc.WindowSeconds = defaultRevenueWindowSeconds
+	}
+	if rc.Baseline == "" {
+		rc.Baseline = RevenueBaselineTrailing
+	}
+
+	switch rc.Baseline {
+	case RevenueBaselineTrailing:
+		if rc.BaselineWindows == 0 {
+			rc.BaselineWindows = defaultTrailingBaselineWindows
+		}
+	case RevenueBaselineSeasonal:
+		if rc.BaselineWindows == 0 {
+			rc.BaselineWindows = defaultSeasonalBaselineWindows
+		}
+		if rc.PeriodSeconds == 0 {
+			rc.PeriodSeconds = defaultRevenuePeriodSeconds
+		}
+	case RevenueBaselineFixed:
+		if rc.Expected <= 0 {
+			return RevenueConfig{}, fmt.Errorf("%w: fixed baseline needs a positive expected", ErrInvalidRevenueConfig)
+		}
+	default:
+		return RevenueConfig{}, fmt.Errorf("%w: unknown baseline %q", ErrInvalidRevenueConfig, rc.Baseline)
+	}
+
+	switch {
+	case rc.WindowSeconds < 0:
+		return RevenueConfig{}, fmt.Errorf("%w: window_seconds must be positive", ErrInvalidRevenueConfig)
+	case rc.BaselineWindows < 0:
+		return RevenueConfig{}, fmt.Errorf("%w: baseline_windows must be positive", ErrInvalidRevenueConfig)
+	case rc.Baseline == RevenueBaselineSeasonal &&
+		(rc.PeriodSeconds <= rc.WindowSeconds || rc.PeriodSeconds%rc.WindowSeconds != 0):
+		return RevenueConfig{}, fmt.Errorf("%w: period_seconds must be a multiple of window_seconds", ErrInvalidRevenueConfig)
+	case rc.MinRatio == nil && rc.MaxRatio == nil:
+		return RevenueConfig{}, fmt.Errorf("%w: needs min_ratio or max_ratio", ErrInvalidRevenueConfig)
+	case rc.MinRatio != nil && (*rc.MinRatio <= 0 || *rc.MinRatio >= 1):
+		return RevenueConfig{}, fmt.Errorf("%w: min_ratio must be between 0 and 1", ErrInvalidRevenueConfig)
+	case rc.MaxRatio != nil && *rc.MaxRatio <= 1:
+		return RevenueConfig{}, fmt.Errorf("%w: max_ratio must be greater than 1", ErrInvalidRevenueConfig)
+	}
+
+	return rc, nil
+}
+
+// window returns the window size.
+func (rc RevenueConfig) window() time.Duration {
+	return time.Duration(rc.WindowSeconds) * time.Second
+}
+
+// windowStart returns the start of the window containing t.
+func (rc RevenueConfig) windowStart(t time.Time) time.Time {
+	return t.UTC().Truncate(rc.window())
+}
+
+// baselineStarts returns the starts of the windows the baseline of the
+// window at start averages, most recent first. Fixed baselines have none.
+func (rc RevenueConfig) baselineStarts(start time.Time) []time.Time {
+	var step time.Duration
+	switch rc.Baseline {
+	case RevenueBaselineTrailing:
+		step = rc.window()
+	case RevenueBaselineSeasonal:
+		step = time.Duration(rc.PeriodSeconds) * time.Second
+	default:
+		return nil
+	}
+
+	starts := make([]time.Time, rc.BaselineWindows)
+	for i := range starts {
+		starts[i] = start.Add(-time.Duration(i+1) * step)
+	}
+	return starts
+}
+
+// baseline returns the baseline of the window at start from the window sums
+// by key. Windows without a sum count as no revenue, but the baseline is only
+// known once at least half of them have one, so new configs and apps do not
+// alert against a partial history. It returns false when the baseline is
+// unknown or below MinBaseline.
+func (rc RevenueConfig) baseline(start time.Time, sums map[string]float64) (float64, bool) {
+	value := rc.Expected
+	if rc.Baseline != RevenueBaselineFixed {
+		starts := rc.baselineStarts(start)
+		var total float64
+		var present int
+		for _, s := range starts {
+			if sum, ok := sums[revenueWindowKey(s)]; ok {
+				total += sum
+				present++
+			}
+		}
+		if present == 0 || present*2 < len(starts) {
+			return 0, false
+		}
+		value = total / float64(len(starts))
+	}
+
+	if value <= 0 || value < rc.MinBaseline {
+		return 0, false
+	}
+	return value, true
+}
+
+// revenueWindowKey returns the anomaly state key of the revenue window at
+// start.
+func revenueWindowKey(start time.Time) string {
+	return db.RevenueWindowPrefix + start.UTC().Format(time.RFC3339)
+}
+
+// revenueDetails returns the alert details of a revenue anomaly.
+func revenueDetails(rc RevenueConfig, start time.Time, revenue, baseline float64, violation string) map[string]interface{} {
+	details := map[string]interface{}{
+		"revenue":        revenue,
+		"baseline":       baseline,
+		"baseline_type":  rc.Baseline,
+		"ratio":          revenue / baseline,
+		"window_start":   start.Format(time.RFC3339),
+		"window_seconds": rc.WindowSeconds,
+		"violation":      violation,
+	}
+	if rc.MinRatio != nil {
+		details["min_ratio"] = *rc.MinRatio
+	}
+	if rc.MaxRatio != nil {
+		details["max_ratio"] = *rc.MaxRatio
+	}
+	return details
+}
+
+// revenueBaselineKey identifies the baseline of one config, app and window.
+type revenueBaselineKey struct {
+	configID string
+	appID    string
+	start    time.Time
+}
+
+// revenueBaseline is a cached baseline. Unknown baselines are cached too, so
+// their window sums are not reloaded on every event.
+type revenueBaseline struct {
+	value float64
+	ok    bool
+	end   time.Time
+}
+
+// revenueState holds the revenue detection state kept in memory: the
+// baselines of open windows and the last window checked for drops per config.
+type revenueState struct {
+	mu        sync.Mutex
+	baselines map[revenueBaselineKey]revenueBaseline
+	checked   map[string]time.Time
+}
+
+func newRevenueState() *revenueState {
+	return &revenueState{
+		baselines: make(map[revenueBaselineKey]revenueBaseline),
+		checked:   make(map[string]time.Time),
+	}
+}
+
+// markChecked records that the window at start of a config is checked for
+// drops. It returns false if it already was.
+func (s *revenueState) markChecked(configID string, start time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if last, ok := s.checked[configID]; ok && !start.After(last) {
+		return false
+	}
+	s.checked[configID] = start
+	return true
+}
+
+// prune drops the cached baselines of windows that closed before now.
+func (s *revenueState) prune(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, b := range s.baselines {
+		if !b.end.After(now) {
+			delete(s.baselines, key)
+		}
+	}
+}
+
+// evaluateRevenue adds the event's value to its app's current window and
+// alerts if the window's sum exceeds the baseline by MaxRatio.
+func (a *AnomalyDetector) evaluateRevenue(ctx context.Context, config *db.AnomalyConfig, event *pb.EventEnvelope, eventJSON map[string]interface{}) error {
+	rc, err := parseRevenueConfig(config.Config)
+	if err != nil {
+		return err
+	}
+
+	value, exists := a.extractJSONPath(eventJSON, rc.Path)
+	if !exists {
+		return nil // Path doesn't exist (or no FX rates yet), skip
+	}
+	amount, ok := toFloat64Value(value)
+	if !ok {
+		return nil
+	}
+
+	appID := event.AppId
+	start := rc.windowStart(time.Now())
+
+	sum, err := a.anomalyConfigs.AddStateValue(ctx, config.ID, appID, revenueWindowKey(start), amount)
+	if err != nil {
+		return fmt.Errorf("failed to add revenue: %w", err)
+	}
+
+	if rc.MaxRatio == nil {
+		return nil
+	}
+
+	baseline, ok, err := a.revenueBaseline(ctx, config.ID, rc, appID, start)
+	if err != nil {
+		return err
+	}
+	if ok && sum > baseline**rc.MaxRatio {
+		details := revenueDetails(rc, start, sum, baseline, "above_max_ratio")
+		if err := a.checkCooldownAndAlert(ctx, config, eventOrigin(event), details, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revenueBaseline returns the baseline of an app's window at start, loading
+// the baseline window sums on first use.
+func (a *AnomalyDetector) revenueBaseline(ctx context.Context, configID string, rc RevenueConfig, appID string, start time.Time) (float64, bool, error) {
+	if rc.Baseline == RevenueBaselineFixed {
+		value, ok := rc.baseline(start, nil)
+		return value, ok, nil
+	}
+
+	key := revenueBaselineKey{configID: configID, appID: appID, start: start}
+
+	a.revenue.mu.Lock()
+	cached, found := a.revenue.baselines[key]
+	a.revenue.mu.Unlock()
+	if found {
+		return cached.value, cached.ok, nil
+	}
+
+	sums, err := a.anomalyConfigs.GetStateSums(ctx, configID, appID, revenueWindowKeys(rc.baselineStarts(start)))
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get revenue baseline: %w", err)
+	}
+	value, ok := rc.baseline(start, sums)
+
+	a.revenue.mu.Lock()
+	a.revenue.baselines[key] = revenueBaseline{value: value, ok: ok, end: start.Add(rc.window())}
+	a.revenue.mu.Unlock()
+
+	return value, ok, nil
+}
+
+// revenueLoop periodically checks closed revenue windows for drops.
+func (a *AnomalyDetector) revenueLoop(ctx context.Context) {
+	ticker := time.NewTicker(a.config.RevenueCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.stopCh:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			a.checkRevenueDrops(ctx, now)
+			a.revenue.prune(now)
+		}
+	}
+}
+
+// checkRevenueDrops checks the most recently closed window of each revenue
+// config with a MinRatio, once per window.
+func (a *AnomalyDetector) checkRevenueDrops(ctx context.Context, now time.Time) {
+	a.mu.RLock()
+	configs := a.cachedConfigs
+	a.mu.RUnlock()
+
+	for _, config := range configs {
+		if config.DetectionType != db.DetectionTypeRevenue {
+			continue
+		}
+
+		rc, err := parseRevenueConfig(config.Config)
+		if err != nil {
+			a.logger.Error("invalid revenue config", "config_id", config.ID, "error", err)
+			continue
+		}
+		if rc.MinRatio == nil {
+			continue
+		}
+
+		closed := rc.windowStart(now).Add(-rc.window())
+		if !a.revenue.markChecked(config.ID, closed) {
+			continue
+		}
+
+		if err := a.checkRevenueDrop(ctx, config, rc, closed); err != nil {
+			a.logger.Error("failed to check revenue window",
+				"config_id", config.ID,
+				"config_name", config.Name,
+				"window_start", closed,
+				"error", err,
+			)
+		}
+	}
+}
+
+// checkRevenueDrop alerts for each app whose revenue in the closed window at
+// start fell below the baseline by MinRatio. Apps are those with revenue in
+// the window or its baseline windows, so a drop to zero is caught too.
+func (a *AnomalyDetector) checkRevenueDrop(ctx context.Context, config *db.AnomalyConfig, rc RevenueConfig, start time.Time) error {
+	windowKey := revenueWindowKey(start)
+	keys := append([]string{windowKey}, revenueWindowKeys(rc.baselineStarts(start))...)
+
+	var apps []string
+	if config.AppID != nil {
+		apps = []string{*config.AppID}
+	} else {
+		// Fixed baselines have no baseline windows; find apps that had
+		// revenue in the window before instead.
+		appKeys := keys
+		if rc.Baseline == RevenueBaselineFixed {
+			appKeys = append(appKeys, revenueWindowKey(start.Add(-rc.window())))
+		}
+		var err error
+		if apps, err = a.anomalyConfigs.GetStateApps(ctx, config.ID, appKeys); err != nil {
+			return fmt.Errorf("failed to get revenue apps: %w", err)
+		}
+	}
+
+	for _, appID := range apps {
+		sums, err := a.anomalyConfigs.GetStateSums(ctx, config.ID, appID, keys)
+		if err != nil {
+			return fmt.Errorf("failed to get revenue sums: %w", err)
+		}
+
+		baseline, ok := rc.baseline(start, sums)
+		revenue := sums[windowKey]
+		if !ok || revenue >= baseline**rc.MinRatio {
+			continue
+		}
+
+		origin := anomalyOrigin{appID: appID}
+		if config.EventCategory != nil {
+			origin.category = *config.EventCategory
+		}
+		if config.EventType != nil {
+			origin.eventType = *config.EventType
+		}
+
+		details := revenueDetails(rc, start, revenue, baseline, "below_min_ratio")
+		if err := a.checkCooldownAndAlert(ctx, config, origin, details, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revenueWindowKeys returns the state keys of the windows at starts.
+func revenueWindowKeys(starts []time.Time) []string {
+	keys := make([]string, len(starts))
+	for i, start := range starts {
+		keys[i] = revenueWindowKey(start)
+	}
+	return keys
+}
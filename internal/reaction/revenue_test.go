@@ -0,0 +1,110 @@
+package reaction
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRevenueConfig(t *testing.T) {
+	rc, err := parseRevenueConfig(json.RawMessage(`{"min_ratio":0.5}`))
+	if err != nil {
+		t.Fatalf("parseRevenueConfig() error = %v", err)
+	}
+	if rc.Path != defaultRevenuePath || rc.WindowSeconds != 3600 ||
+		rc.Baseline != RevenueBaselineTrailing || rc.BaselineWindows != 24 {
+		t.Errorf("defaults = %+v", rc)
+	}
+
+	seasonal, err := parseRevenueConfig(json.RawMessage(`{"baseline":"seasonal","max_ratio":3}`))
+	if err != nil {
+		t.Fatalf("parseRevenueConfig(seasonal) error = %v", err)
+	}
+	if seasonal.BaselineWindows != 4 || seasonal.PeriodSeconds != 7*24*3600 {
+		t.Errorf("seasonal defaults = %+v", seasonal)
+	}
+
+	invalid := map[string]string{
+		"no ratio":          `{}`,
+		"min ratio above 1": `{"min_ratio":1.5}`,
+		"max ratio below 1": `{"max_ratio":0.5}`,
+		"unknown baseline":  `{"baseline":"median","min_ratio":0.5}`,
+		"fixed no expected": `{"baseline":"fixed","min_ratio":0.5}`,
+		"unaligned period":  `{"baseline":"seasonal","period_seconds":5000,"min_ratio":0.5}`,
+		"not json":          `[`,
+	}
+	for name, raw := range invalid {
+		if _, err := parseRevenueConfig(json.RawMessage(raw)); !errors.Is(err, ErrInvalidRevenueConfig) {
+			t.Errorf("%s: error = %v, want ErrInvalidRevenueConfig", name, err)
+		}
+	}
+}
+
+func TestRevenueConfig_BaselineStarts(t *testing.T) {
+	start := time.Date(2026, 3, 10, 14, 0, 0, 0, time.UTC)
+
+	trailing := RevenueConfig{Baseline: RevenueBaselineTrailing, WindowSeconds: 3600, BaselineWindows: 2}
+	got := trailing.baselineStarts(start)
+	if len(got) != 2 || !got[0].Equal(start.Add(-time.Hour)) || !got[1].Equal(start.Add(-2*time.Hour)) {
+		t.Errorf("trailing baselineStarts() = %v", got)
+	}
+
+	seasonal := RevenueConfig{Baseline: RevenueBaselineSeasonal, WindowSeconds: 3600, BaselineWindows: 2, PeriodSeconds: 86400}
+	got = seasonal.baselineStarts(start)
+	if len(got) != 2 || !got[0].Equal(start.AddDate(0, 0, -1)) || !got[1].Equal(start.AddDate(0, 0, -2)) {
+		t.Errorf("seasonal baselineStarts() = %v", got)
+	}
+
+	if ws := trailing.windowStart(start.Add(42 * time.Minute)); !ws.Equal(start) {
+		t.Errorf("windowStart() = %v, want %v", ws, start)
+	}
+}
+
+func TestRevenueConfig_Baseline(t *testing.T) {
+	start := time.Date(2026, 3, 10, 14, 0, 0, 0, time.UTC)
+	rc := RevenueConfig{Baseline: RevenueBaselineTrailing, WindowSeconds: 3600, BaselineWindows: 4}
+	key := func(hoursAgo int) string {
+		return revenueWindowKey(start.Add(-time.Duration(hoursAgo) * time.Hour))
+	}
+
+	// Missing windows count as no revenue once half the windows are known.
+	sums := map[string]float64{key(1): 100, key(2): 300}
+	if got, ok := rc.baseline(start, sums); !ok || got != 100 {
+		t.Errorf("baseline() = %v, %v, want 100", got, ok)
+	}
+
+	// Too little history.
+	if _, ok := rc.baseline(start, map[string]float64{key(1): 100}); ok {
+		t.Error("baseline() with one of four windows is known")
+	}
+
+	// Below the minimum baseline.
+	rc.MinBaseline = 500
+	if _, ok := rc.baseline(start, sums); ok {
+		t.Error("baseline() below min_baseline is known")
+	}
+
+	fixed := RevenueConfig{Baseline: RevenueBaselineFixed, Expected: 250}
+	if got, ok := fixed.baseline(start, nil); !ok || got != 250 {
+		t.Errorf("fixed baseline() = %v, %v, want 250", got, ok)
+	}
+}
+
+func TestRevenueState_MarkChecked(t *testing.T) {
+	s := newRevenueState()
+	start := time.Date(2026, 3, 10, 14, 0, 0, 0, time.UTC)
+
+	if !s.markChecked("c1", start) {
+		t.Error("first markChecked() = false")
+	}
+	if s.markChecked("c1", start) {
+		t.Error("repeated markChecked() = true")
+	}
+	if !s.markChecked("c1", start.Add(time.Hour)) {
+		t.Error("markChecked() for the next window = false")
+	}
+	if !s.markChecked("c2", start) {
+		t.Error("markChecked() for another config = false")
+	}
+}
@@ -0,0 +1,221 @@
+package reaction
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// Default and maximum page sizes for the rule version history and shadow
+// sample endpoints.
+const (
+	defaultVersionPageSize = 50
+	maxVersionPageSize     = 500
+)
+
+// ruleAdminStore is the subset of db.RuleRepository used by RuleHandler.
+type ruleAdminStore interface {
+	ListVersions(ctx context.Context, ruleID string, limit, offset int) ([]*db.RuleVersion, error)
+	Rollback(ctx context.Context, ruleID string, version int, author string) (*db.Rule, error)
+	GetShadowStats(ctx context.Context, ruleID string) (*db.ShadowStats, error)
+	ListShadowSamples(ctx context.Context, ruleID string, limit int) ([]*db.ShadowSample, error)
+}
+
+// RuleHandler serves the admin API for rule version history, rollback, and
+// shadow-mode results.
+type RuleHandler struct {
+	rules  ruleAdminStore
+	engine *Engine
+	logger *slog.Logger
+}
+
+// NewRuleHandler creates a RuleHandler. If engine is non-nil, its rule cache
+// is refreshed after a rollback so the restored version takes effect at once.
+func NewRuleHandler(rules *db.RuleRepository, engine *Engine, logger *slog.Logger) *RuleHandler {
+	return newRuleHandler(rules, engine, logger)
+}
+
+func newRuleHandler(rules ruleAdminStore, engine *Engine, logger *slog.Logger) *RuleHandler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &RuleHandler{
+		rules:  rules,
+		engine: engine,
+		logger: logger.With("component", "rule-handler"),
+	}
+}
+
+// RegisterRoutes mounts rule admin endpoints on the given ServeMux.
+//
+// Endpoints:
+//   - GET  /api/admin/rules/{id}/versions  - List versions, newest first
+//   - POST /api/admin/rules/{id}/rollback  - Restore a prior version
+//   - GET  /api/admin/rules/{id}/shadow    - Shadow match count and samples
+func (h *RuleHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/admin/rules/{id}/versions", h.handleListVersions)
+	mux.HandleFunc("POST /api/admin/rules/{id}/rollback", h.handleRollback)
+	mux.HandleFunc("GET /api/admin/rules/{id}/shadow", h.handleShadow)
+}
+
+// rollbackRequest is the JSON request body for rolling back a rule.
+type rollbackRequest struct {
+	Version int    `json:"version"`
+	Author  string `json:"author"`
+}
+
+// handleListVersions handles GET /api/admin/rules/{id}/versions.
+func (h *RuleHandler) handleListVersions(w http.ResponseWriter, r *http.Request) {
+	ruleID := r.PathValue("id")
+
+	limit, err := queryInt(r, "limit", defaultVersionPageSize)
+	if err != nil || limit < 1 || limit > maxVersionPageSize {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "limit must be between 1 and 500",
+		})
+		return
+	}
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "offset must be a non-negative integer",
+		})
+		return
+	}
+
+	versions, err := h.rules.ListVersions(r.Context(), ruleID, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list rule versions", "rule_id", ruleID, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to list rule versions",
+		})
+		return
+	}
+	if versions == nil {
+		versions = []*db.RuleVersion{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"versions": versions,
+		"count":    len(versions),
+	})
+}
+
+// handleRollback handles POST /api/admin/rules/{id}/rollback.
+func (h *RuleHandler) handleRollback(w http.ResponseWriter, r *http.Request) {
+	ruleID := r.PathValue("id")
+
+	var req rollbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "invalid request body",
+		})
+		return
+	}
+	if req.Version < 1 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "version is required",
+		})
+		return
+	}
+	if req.Author == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "author is required",
+		})
+		return
+	}
+
+	rule, err := h.rules.Rollback(r.Context(), ruleID, req.Version, req.Author)
+	if err != nil {
+		if errors.Is(err, db.ErrRuleNotFound) || errors.Is(err, db.ErrRuleVersionNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+		h.logger.Error("failed to roll back rule",
+			"rule_id", ruleID,
+			"version", req.Version,
+			"error", err,
+		)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to roll back rule",
+		})
+		return
+	}
+
+	h.logger.Info("rule rolled back",
+		"rule_id", ruleID,
+		"restored_version", req.Version,
+		"new_version", rule.Version,
+		"author", req.Author,
+	)
+
+	if h.engine != nil {
+		if err := h.engine.RefreshRules(r.Context()); err != nil {
+			h.logger.Warn("failed to refresh rules after rollback", "error", err)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, rule)
+}
+
+// handleShadow handles GET /api/admin/rules/{id}/shadow.
+func (h *RuleHandler) handleShadow(w http.ResponseWriter, r *http.Request) {
+	ruleID := r.PathValue("id")
+
+	limit, err := queryInt(r, "limit", defaultVersionPageSize)
+	if err != nil || limit < 1 || limit > maxVersionPageSize {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "limit must be between 1 and 500",
+		})
+		return
+	}
+
+	stats, err := h.rules.GetShadowStats(r.Context(), ruleID)
+	if err != nil {
+		h.logger.Error("failed to get shadow stats", "rule_id", ruleID, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to get shadow stats",
+		})
+		return
+	}
+
+	samples, err := h.rules.ListShadowSamples(r.Context(), ruleID, limit)
+	if err != nil {
+		h.logger.Error("failed to list shadow samples", "rule_id", ruleID, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to list shadow samples",
+		})
+		return
+	}
+	if samples == nil {
+		samples = []*db.ShadowSample{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"stats":   stats,
+		"samples": samples,
+	})
+}
+
+// queryInt parses an optional integer query parameter.
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
+
+// writeJSON writes a JSON response with the given status code and body.
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
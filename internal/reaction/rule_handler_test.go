@@ -0,0 +1,159 @@
+package reaction
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+type fakeRuleAdminStore struct {
+	versions    []*db.RuleVersion
+	rolledBack  int
+	rollbackBy  string
+	rollbackErr error
+	shadowStats *db.ShadowStats
+	samples     []*db.ShadowSample
+}
+
+func (f *fakeRuleAdminStore) ListVersions(_ context.Context, ruleID string, limit, offset int) ([]*db.RuleVersion, error) {
+	return f.versions, nil
+}
+
+func (f *fakeRuleAdminStore) Rollback(_ context.Context, ruleID string, version int, author string) (*db.Rule, error) {
+	if f.rollbackErr != nil {
+		return nil, f.rollbackErr
+	}
+	f.rolledBack = version
+	f.rollbackBy = author
+	return &db.Rule{ID: ruleID, Version: 4}, nil
+}
+
+func (f *fakeRuleAdminStore) GetShadowStats(_ context.Context, ruleID string) (*db.ShadowStats, error) {
+	return f.shadowStats, nil
+}
+
+func (f *fakeRuleAdminStore) ListShadowSamples(_ context.Context, ruleID string, limit int) ([]*db.ShadowSample, error) {
+	return f.samples, nil
+}
+
+func newTestRuleMux(store *fakeRuleAdminStore) *http.ServeMux {
+	mux := http.NewServeMux()
+	newRuleHandler(store, nil, nil).RegisterRoutes(mux)
+	return mux
+}
+
+func TestRuleHandler_Rollback(t *testing.T) {
+	store := &fakeRuleAdminStore{}
+	mux := newTestRuleMux(store)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/rules/r1/rollback",
+		strings.NewReader(`{"version":2,"author":"alice"}`))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	if store.rolledBack != 2 || store.rollbackBy != "alice" {
+		t.Errorf("rollback: got version %d by %q, want 2 by alice", store.rolledBack, store.rollbackBy)
+	}
+
+	var rule db.Rule
+	if err := json.NewDecoder(rec.Body).Decode(&rule); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rule.Version != 4 {
+		t.Errorf("version: got %d, want 4", rule.Version)
+	}
+}
+
+func TestRuleHandler_RollbackErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		err    error
+		status int
+	}{
+		{name: "missing version", body: `{"author":"alice"}`, status: http.StatusBadRequest},
+		{name: "missing author", body: `{"version":1}`, status: http.StatusBadRequest},
+		{name: "invalid body", body: `{`, status: http.StatusBadRequest},
+		{name: "unknown version", body: `{"version":9,"author":"alice"}`, err: db.ErrRuleVersionNotFound, status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := newTestRuleMux(&fakeRuleAdminStore{rollbackErr: tt.err})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/admin/rules/r1/rollback", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("status: got %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestRuleHandler_ListVersions(t *testing.T) {
+	store := &fakeRuleAdminStore{versions: []*db.RuleVersion{
+		{RuleID: "r1", Version: 2, Author: "bob"},
+		{RuleID: "r1", Version: 1, Author: "alice"},
+	}}
+	mux := newTestRuleMux(store)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/admin/rules/r1/versions", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want 200", rec.Code)
+	}
+
+	var resp struct {
+		Count int `json:"count"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Count != 2 {
+		t.Errorf("count: got %d, want 2", resp.Count)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/admin/rules/r1/versions?limit=0", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("limit=0 status: got %d, want 400", rec.Code)
+	}
+}
+
+func TestRuleHandler_Shadow(t *testing.T) {
+	store := &fakeRuleAdminStore{
+		shadowStats: &db.ShadowStats{RuleID: "r1", MatchCount: 42},
+		samples:     []*db.ShadowSample{{RuleID: "r1", EventID: "e1", EventData: []byte(`{"id":"e1"}`)}},
+	}
+	mux := newTestRuleMux(store)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/admin/rules/r1/shadow", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want 200", rec.Code)
+	}
+
+	var resp struct {
+		Stats   db.ShadowStats     `json:"stats"`
+		Samples []*db.ShadowSample `json:"samples"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Stats.MatchCount != 42 {
+		t.Errorf("match_count: got %d, want 42", resp.Stats.MatchCount)
+	}
+	if len(resp.Samples) != 1 || resp.Samples[0].EventID != "e1" {
+		t.Errorf("samples: got %+v, want one sample for e1", resp.Samples)
+	}
+}
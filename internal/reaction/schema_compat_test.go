@@ -0,0 +1,79 @@
+package reaction
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/SebastienMelki/causality/internal/events"
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// TestSchemaCompat_LegacyEnvelope verifies an envelope from an SDK predating
+// schema versioning matches the same rules and anomaly thresholds as one at
+// the current version.
+func TestSchemaCompat_LegacyEnvelope(t *testing.T) {
+	newEvent := func(version uint32) *pb.EventEnvelope {
+		return &pb.EventEnvelope{
+			Id:            "evt-1",
+			AppId:         "demo",
+			TimestampMs:   1700000000000,
+			SchemaVersion: version,
+			Payload: &pb.EventEnvelope_PurchaseComplete{PurchaseComplete: &pb.PurchaseComplete{
+				OrderId:    "order-1",
+				TotalCents: 125000,
+				Currency:   "USD",
+			}},
+		}
+	}
+
+	appID, category, eventType := "demo", events.CategoryCommerce, "purchase_complete"
+	rule := &db.Rule{
+		ID:            "big-purchase",
+		AppID:         &appID,
+		EventCategory: &category,
+		EventType:     &eventType,
+		Conditions: []db.Condition{
+			{Path: "$.purchase_complete.total_cents", Operator: "gt", Value: 100000},
+			{Path: "$.purchase_complete.currency", Operator: "eq", Value: "USD"},
+		},
+	}
+	anomalyConfig := &db.AnomalyConfig{AppID: &appID, EventCategory: &category, EventType: &eventType}
+
+	engine := NewEngine(nil, nil, nil, nil, EngineConfig{}, DispatcherConfig{}, nil, nil)
+	detector := NewAnomalyDetector(nil, nil, AnomalyConfig{}, nil)
+
+	for _, version := range []uint32{0, events.SchemaVersionLegacy, events.CurrentSchemaVersion} {
+		data, err := proto.Marshal(newEvent(version))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var event pb.EventEnvelope
+		if err := events.Decode(data, &event); err != nil {
+			t.Fatalf("version %d: Decode() error = %v", version, err)
+		}
+
+		gotCategory, gotType := events.GetCategoryAndType(&event)
+		eventJSON, err := engine.eventToJSON(&event)
+		if err != nil {
+			t.Fatalf("version %d: eventToJSON() error = %v", version, err)
+		}
+		if matched := engine.findMatchingRules([]*db.Rule{rule}, event.GetAppId(), gotCategory, gotType, &conditionData{event: eventJSON}); len(matched) != 1 {
+			t.Errorf("version %d: rule matched %d times, want 1", version, len(matched))
+		}
+
+		if !detector.matchesFilter(anomalyConfig, event.GetAppId(), gotCategory, gotType) {
+			t.Errorf("version %d: anomaly config does not match", version)
+		}
+		anomalyJSON, err := detector.eventToJSON(&event)
+		if err != nil {
+			t.Fatalf("version %d: anomaly eventToJSON() error = %v", version, err)
+		}
+		value, ok := detector.extractJSONPath(anomalyJSON, "$.purchase_complete.total_cents")
+		if n, isNum := toFloat64Value(value); !ok || !isNum || n != 125000 {
+			t.Errorf("version %d: threshold value = %v, want 125000", version, value)
+		}
+	}
+}
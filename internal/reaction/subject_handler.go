@@ -0,0 +1,81 @@
+package reaction
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/SebastienMelki/causality/internal/nats"
+)
+
+// derivedSubjectLister is the subset of nats.StreamManager used by
+// SubjectHandler.
+type derivedSubjectLister interface {
+	DerivedSubjects(ctx context.Context) ([]nats.DerivedSubjectInfo, error)
+}
+
+// SubjectHandler serves the admin catalog of derived event subjects.
+type SubjectHandler struct {
+	streams derivedSubjectLister
+	logger  *slog.Logger
+}
+
+// NewSubjectHandler creates a SubjectHandler reading the derived stream
+// through streams.
+func NewSubjectHandler(streams *nats.StreamManager, logger *slog.Logger) *SubjectHandler {
+	return newSubjectHandler(streams, logger)
+}
+
+func newSubjectHandler(streams derivedSubjectLister, logger *slog.Logger) *SubjectHandler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SubjectHandler{
+		streams: streams,
+		logger:  logger.With("component", "subject-handler"),
+	}
+}
+
+// RegisterRoutes mounts the subject catalog endpoint on the given ServeMux.
+//
+// Endpoints:
+//   - GET /api/admin/subjects - Derived subject families and the subjects
+//     currently holding messages, optionally filtered by ?family= and ?app_id=
+func (h *SubjectHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/admin/subjects", h.handleList)
+}
+
+// handleList handles GET /api/admin/subjects.
+func (h *SubjectHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	family := r.URL.Query().Get("family")
+	appID := r.URL.Query().Get("app_id")
+
+	if family != "" && !nats.IsDerivedFamily(family) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "unknown subject family",
+		})
+		return
+	}
+
+	subjects, err := h.streams.DerivedSubjects(r.Context())
+	if err != nil {
+		h.logger.Error("failed to list derived subjects", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to list derived subjects",
+		})
+		return
+	}
+
+	active := make([]nats.DerivedSubjectInfo, 0, len(subjects))
+	for _, s := range subjects {
+		if (family == "" || s.Family == family) && (appID == "" || s.AppID == appID) {
+			active = append(active, s)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"families": nats.DerivedFamilies(),
+		"subjects": active,
+		"count":    len(active),
+	})
+}
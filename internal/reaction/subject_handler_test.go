@@ -0,0 +1,52 @@
+package reaction
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SebastienMelki/causality/internal/nats"
+)
+
+type fakeDerivedSubjects []nats.DerivedSubjectInfo
+
+func (f fakeDerivedSubjects) DerivedSubjects(context.Context) ([]nats.DerivedSubjectInfo, error) {
+	return f, nil
+}
+
+func TestSubjectHandler_List(t *testing.T) {
+	mux := http.NewServeMux()
+	newSubjectHandler(fakeDerivedSubjects{
+		{Subject: "anomalies.app1.spike", Family: "anomalies", AppID: "app1", Name: "spike", Messages: 2},
+		{Subject: "reactions.app1.vip", Family: "reactions", AppID: "app1", Name: "vip", Messages: 5},
+		{Subject: "reactions.app2.vip", Family: "reactions", AppID: "app2", Name: "vip", Messages: 1},
+	}, nil).RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/admin/subjects?family=reactions&app_id=app1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+
+	var body struct {
+		Families []string                  `json:"families"`
+		Subjects []nats.DerivedSubjectInfo `json:"subjects"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(body.Families) != 3 {
+		t.Errorf("families: got %v", body.Families)
+	}
+	if len(body.Subjects) != 1 || body.Subjects[0].Subject != "reactions.app1.vip" || body.Subjects[0].Messages != 5 {
+		t.Errorf("subjects: got %+v", body.Subjects)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/admin/subjects?family=alerts", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown family status: got %d, want 400", rec.Code)
+	}
+}
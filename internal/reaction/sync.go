@@ -0,0 +1,855 @@
+package reaction
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/SebastienMelki/causality/internal/nats"
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// Resource kinds reported in a sync result.
+const (
+	ResourceWebhook       = "webhook"
+	ResourceRule          = "rule"
+	ResourceAnomalyConfig = "anomaly_config"
+)
+
+// Sync actions reported in a sync result.
+const (
+	SyncCreate = "create"
+	SyncUpdate = "update"
+	SyncDelete = "delete"
+)
+
+// Defaults applied to fields a resource spec leaves unset. They match the
+// column defaults of the reaction engine schema.
+const (
+	defaultWebhookAuthType  = "none"
+	defaultWebhookTimeoutMs = 30000
+	defaultCooldownSeconds  = 300
+	defaultSeverity         = db.SeverityWarning
+)
+
+// syncPageSize is the page size used to load existing resources.
+const syncPageSize = 500
+
+// redacted replaces the values of secret fields in sync results.
+var redacted = json.RawMessage(`"<redacted>"`)
+
+// secretFields lists spec fields whose values may hold credentials.
+var secretFields = map[string]bool{"auth_config": true, "headers": true}
+
+// Sentinel errors for resource sync.
+var (
+	// ErrInvalidResourceSpec is returned for specs that cannot be applied.
+	ErrInvalidResourceSpec = errors.New("invalid resource spec")
+
+	// ErrDuplicateResourceName is returned when several existing resources
+	// of a kind share a name that a spec manages, so it cannot tell which
+	// one to reconcile.
+	ErrDuplicateResourceName = errors.New("duplicate resource name")
+)
+
+// ResourceSpec declares the desired rules, webhooks and anomaly configs.
+// Resources are identified by name and rules reference webhooks by name, so
+// a spec can live in version control and be applied to any environment.
+type ResourceSpec struct {
+	Webhooks       []WebhookSpec       `json:"webhooks"`
+	Rules          []RuleSpec          `json:"rules"`
+	AnomalyConfigs []AnomalyConfigSpec `json:"anomaly_configs"`
+}
+
+// WebhookSpec declares a webhook. Unset fields take the schema defaults.
+type WebhookSpec struct {
+	Name       string            `json:"name"`
+	URL        string            `json:"url"`
+	AuthType   string            `json:"auth_type,omitempty"`
+	AuthConfig json.RawMessage   `json:"auth_config,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Enabled    *bool             `json:"enabled,omitempty"`
+	TimeoutMs  int               `json:"timeout_ms,omitempty"`
+	MaxRPS     float64           `json:"max_rps,omitempty"`
+	BatchSize  int               `json:"batch_size,omitempty"`
+}
+
+// RuleSpec declares a rule. Unset fields take the schema defaults.
+type RuleSpec struct {
+	Name          string          `json:"name"`
+	Description   *string         `json:"description,omitempty"`
+	AppID         *string         `json:"app_id,omitempty"`
+	EventCategory *string         `json:"event_category,omitempty"`
+	EventType     *string         `json:"event_type,omitempty"`
+	Conditions    []db.Condition  `json:"conditions,omitempty"`
+	Actions       RuleSpecActions `json:"actions"`
+	Priority      int             `json:"priority,omitempty"`
+	Enabled       *bool           `json:"enabled,omitempty"`
+	Shadow        bool            `json:"shadow,omitempty"`
+}
+
+// RuleSpecActions declares a rule's actions, with webhooks referenced by
+// name.
+type RuleSpecActions struct {
+	Webhooks         []string                   `json:"webhooks,omitempty"`
+	PublishSubjects  []string                   `json:"publish_subjects,omitempty"`
+	Metrics          []db.MetricAction          `json:"metrics,omitempty"`
+	PushNotification *db.PushNotificationAction `json:"push_notification,omitempty"`
+}
+
+// AnomalyConfigSpec declares an anomaly config. Unset fields take the schema
+// defaults.
+type AnomalyConfigSpec struct {
+	Name            string           `json:"name"`
+	Description     *string          `json:"description,omitempty"`
+	AppID           *string          `json:"app_id,omitempty"`
+	EventCategory   *string          `json:"event_category,omitempty"`
+	EventType       *string          `json:"event_type,omitempty"`
+	DetectionType   db.DetectionType `json:"detection_type"`
+	Config          json.RawMessage  `json:"config,omitempty"`
+	CooldownSeconds *int             `json:"cooldown_seconds,omitempty"`
+	Severity        db.Severity      `json:"severity,omitempty"`
+	Enabled         *bool            `json:"enabled,omitempty"`
+
+	// EscalationPolicyID is the ID of the escalation policy of the
+	// config's alerts.
+	EscalationPolicyID *string `json:"escalation_policy_id,omitempty"`
+}
+
+// SyncOptions controls a sync.
+type SyncOptions struct {
+	// DryRun computes the changes without applying them.
+	DryRun bool
+
+	// Prune deletes existing resources that the spec does not declare.
+	// Without it they are left untouched.
+	Prune bool
+
+	// Author is recorded on the rule versions a sync creates.
+	Author string
+}
+
+// FieldChange describes one field changed by a sync. Values of secret
+// fields are redacted.
+type FieldChange struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old"`
+	New   json.RawMessage `json:"new"`
+}
+
+// ResourceChange describes one resource created, updated or deleted by a
+// sync.
+type ResourceChange struct {
+	Kind   string        `json:"kind"`
+	Name   string        `json:"name"`
+	Action string        `json:"action"`
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// SyncResult lists the changes of a sync, in the order they are applied.
+type SyncResult struct {
+	DryRun  bool             `json:"dry_run"`
+	Changes []ResourceChange `json:"changes"`
+}
+
+// syncRuleStore is the subset of db.RuleRepository used by ResourceSyncer.
+type syncRuleStore interface {
+	List(ctx context.Context, limit, offset int) ([]*db.Rule, error)
+	Create(ctx context.Context, rule *db.Rule, author string) error
+	Update(ctx context.Context, rule *db.Rule, author string) error
+	Delete(ctx context.Context, id string) error
+}
+
+// syncWebhookStore is the subset of db.WebhookRepository used by
+// ResourceSyncer.
+type syncWebhookStore interface {
+	List(ctx context.Context, limit, offset int) ([]*db.Webhook, error)
+	Create(ctx context.Context, webhook *db.Webhook) error
+	Update(ctx context.Context, webhook *db.Webhook) error
+	Delete(ctx context.Context, id string) error
+}
+
+// syncAnomalyConfigStore is the subset of db.AnomalyConfigRepository used by
+// ResourceSyncer.
+type syncAnomalyConfigStore interface {
+	List(ctx context.Context, limit, offset int) ([]*db.AnomalyConfig, error)
+	Create(ctx context.Context, config *db.AnomalyConfig) error
+	Update(ctx context.Context, config *db.AnomalyConfig) error
+	Delete(ctx context.Context, id string) error
+}
+
+// ResourceSyncer reconciles the rules, webhooks and anomaly configs in the
+// database with a declarative ResourceSpec.
+//
+// A sync is not atomic: if applying a change fails, the changes applied
+// before it remain. Applying the same spec again converges.
+type ResourceSyncer struct {
+	rules     syncRuleStore
+	webhooks  syncWebhookStore
+	anomalies syncAnomalyConfigStore
+}
+
+// NewResourceSyncer creates a ResourceSyncer.
+func NewResourceSyncer(rules *db.RuleRepository, webhooks *db.WebhookRepository, anomalies *db.AnomalyConfigRepository) *ResourceSyncer {
+	return &ResourceSyncer{rules: rules, webhooks: webhooks, anomalies: anomalies}
+}
+
+// syncPlan holds the resources to create, update and delete, per kind.
+type syncPlan struct {
+	webhooks  []syncStep[WebhookSpec, *db.Webhook]
+	anomalies []syncStep[AnomalyConfigSpec, *db.AnomalyConfig]
+	rules     []syncStep[RuleSpec, *db.Rule]
+}
+
+// syncStep is one planned change: a create (existing is nil), an update, or
+// a delete (desired is nil).
+type syncStep[S any, R any] struct {
+	change   ResourceChange
+	desired  *S
+	existing R
+}
+
+// Sync reconciles the database with spec. Changes are applied in dependency
+// order: webhooks are created and updated first so rules can reference them,
+// and deleted last. The result lists the changes applied, or that would be
+// applied with DryRun; if applying fails, it lists those applied so far.
+func (s *ResourceSyncer) Sync(ctx context.Context, spec ResourceSpec, opts SyncOptions) (*SyncResult, error) {
+	if err := validateSpec(spec); err != nil {
+		return nil, err
+	}
+
+	plan, err := s.plan(ctx, spec, opts.Prune)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{DryRun: opts.DryRun, Changes: []ResourceChange{}}
+	if opts.DryRun {
+		result.Changes = plan.changes()
+		return result, nil
+	}
+
+	err = s.apply(ctx, plan, opts.Author, func(change ResourceChange) {
+		result.Changes = append(result.Changes, change)
+	})
+	return result, err
+}
+
+// validateSpec checks that names are set and unique per kind, that rules
+// only reference declared webhooks, and that chained rules cannot trigger
+// each other in a cycle.
+func validateSpec(spec ResourceSpec) error {
+	webhooks := make(map[string]bool, len(spec.Webhooks))
+	for _, webhook := range spec.Webhooks {
+		if err := checkName(ResourceWebhook, webhook.Name, webhooks); err != nil {
+			return err
+		}
+		if webhook.URL == "" {
+			return fmt.Errorf("%w: webhook %q has no url", ErrInvalidResourceSpec, webhook.Name)
+		}
+	}
+
+	rules := make(map[string]bool, len(spec.Rules))
+	for _, rule := range spec.Rules {
+		if err := checkName(ResourceRule, rule.Name, rules); err != nil {
+			return err
+		}
+		for _, name := range rule.Actions.Webhooks {
+			if !webhooks[name] {
+				return fmt.Errorf("%w: rule %q references undeclared webhook %q", ErrInvalidResourceSpec, rule.Name, name)
+			}
+		}
+		for _, subject := range rule.Actions.PublishSubjects {
+			if !isReactionSubject(subject) {
+				return fmt.Errorf("%w: rule %q publish subject %q is not of the form reactions.{app_id}.{name}", ErrInvalidResourceSpec, rule.Name, subject)
+			}
+		}
+		for _, cond := range rule.Conditions {
+			if cond.Source != db.ConditionSourceHistory {
+				continue
+			}
+			if err := validateHistoryCondition(cond); err != nil {
+				return fmt.Errorf("%w: rule %q: %w", ErrInvalidResourceSpec, rule.Name, err)
+			}
+		}
+		for _, metric := range rule.Actions.Metrics {
+			if err := validateMetricAction(metric); err != nil {
+				return fmt.Errorf("%w: rule %q: %w", ErrInvalidResourceSpec, rule.Name, err)
+			}
+		}
+		if rule.Actions.PushNotification != nil {
+			if err := validatePushAction(rule.Actions.PushNotification); err != nil {
+				return fmt.Errorf("%w: rule %q: %w", ErrInvalidResourceSpec, rule.Name, err)
+			}
+		}
+	}
+
+	if cycle := ruleChainCycle(spec.Rules); cycle != nil {
+		return fmt.Errorf("%w: rules trigger each other in a cycle: %s", ErrInvalidResourceSpec, strings.Join(cycle, " -> "))
+	}
+
+	anomalies := make(map[string]bool, len(spec.AnomalyConfigs))
+	for _, config := range spec.AnomalyConfigs {
+		if err := checkName(ResourceAnomalyConfig, config.Name, anomalies); err != nil {
+			return err
+		}
+		if config.Severity != "" && !config.Severity.Valid() {
+			return fmt.Errorf("%w: anomaly config %q: %w: %q", ErrInvalidResourceSpec, config.Name, ErrInvalidSeverity, config.Severity)
+		}
+		switch config.DetectionType {
+		case db.DetectionTypeThreshold, db.DetectionTypeRate, db.DetectionTypeCount, db.DetectionTypeForecast:
+		case db.DetectionTypeRevenue:
+			if _, err := parseRevenueConfig(config.Config); err != nil {
+				return fmt.Errorf("%w: anomaly config %q: %w", ErrInvalidResourceSpec, config.Name, err)
+			}
+		default:
+			return fmt.Errorf("%w: anomaly config %q: %w: %q", ErrInvalidResourceSpec, config.Name, ErrInvalidDetectionType, config.DetectionType)
+		}
+	}
+
+	return nil
+}
+
+// isReactionSubject reports whether a rule publish subject template, with
+// {app_id} substituted, is a subject in the reactions family.
+func isReactionSubject(template string) bool {
+	info, ok := nats.ParseDerivedSubject(strings.ReplaceAll(template, "{app_id}", "app"))
+	return ok && info.Family == nats.FamilyReactions
+}
+
+// checkName checks that name is set and not in seen, and adds it.
+func checkName(kind, name string, seen map[string]bool) error {
+	if name == "" {
+		return fmt.Errorf("%w: %s without a name", ErrInvalidResourceSpec, kind)
+	}
+	if seen[name] {
+		return fmt.Errorf("%w: %s %q declared twice", ErrInvalidResourceSpec, kind, name)
+	}
+	seen[name] = true
+	return nil
+}
+
+// plan compares spec with the existing resources.
+func (s *ResourceSyncer) plan(ctx context.Context, spec ResourceSpec, prune bool) (*syncPlan, error) {
+	webhooks, err := listAll(ctx, s.webhooks.List)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	rules, err := listAll(ctx, s.rules.List)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rules: %w", err)
+	}
+	anomalies, err := listAll(ctx, s.anomalies.List)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list anomaly configs: %w", err)
+	}
+
+	// Existing rules reference webhooks by ID; compare them by name
+	webhookNames := make(map[string]string, len(webhooks))
+	for _, webhook := range webhooks {
+		webhookNames[webhook.ID] = webhook.Name
+	}
+
+	plan := &syncPlan{}
+	plan.webhooks, err = planKind(ResourceWebhook, spec.Webhooks, webhooks, prune,
+		func(w WebhookSpec) string { return w.Name },
+		func(w *db.Webhook) string { return w.Name },
+		func(w WebhookSpec) any { return w.normalize() },
+		func(w *db.Webhook) any { return webhookSpecOf(w) },
+	)
+	if err != nil {
+		return nil, err
+	}
+	plan.anomalies, err = planKind(ResourceAnomalyConfig, spec.AnomalyConfigs, anomalies, prune,
+		func(c AnomalyConfigSpec) string { return c.Name },
+		func(c *db.AnomalyConfig) string { return c.Name },
+		func(c AnomalyConfigSpec) any { return c.normalize() },
+		func(c *db.AnomalyConfig) any { return anomalyConfigSpecOf(c) },
+	)
+	if err != nil {
+		return nil, err
+	}
+	plan.rules, err = planKind(ResourceRule, spec.Rules, rules, prune,
+		func(r RuleSpec) string { return r.Name },
+		func(r *db.Rule) string { return r.Name },
+		func(r RuleSpec) any { return r.normalize() },
+		func(r *db.Rule) any { return ruleSpecOf(r, webhookNames) },
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// planKind plans the changes of one resource kind. Creates and updates
+// follow the spec order; deletes follow name order.
+func planKind[S any, R any](
+	kind string,
+	desired []S,
+	existing []R,
+	prune bool,
+	specName func(S) string,
+	existingName func(R) string,
+	specForm func(S) any,
+	existingForm func(R) any,
+) ([]syncStep[S, R], error) {
+	byName := make(map[string][]R, len(existing))
+	for _, resource := range existing {
+		name := existingName(resource)
+		byName[name] = append(byName[name], resource)
+	}
+
+	var steps []syncStep[S, R]
+	declared := make(map[string]bool, len(desired))
+	for i := range desired {
+		name := specName(desired[i])
+		declared[name] = true
+
+		matches := byName[name]
+		switch len(matches) {
+		case 0:
+			steps = append(steps, syncStep[S, R]{
+				change:  ResourceChange{Kind: kind, Name: name, Action: SyncCreate},
+				desired: &desired[i],
+			})
+		case 1:
+			fields, err := diffFields(existingForm(matches[0]), specForm(desired[i]))
+			if err != nil {
+				return nil, err
+			}
+			if len(fields) > 0 {
+				steps = append(steps, syncStep[S, R]{
+					change:   ResourceChange{Kind: kind, Name: name, Action: SyncUpdate, Fields: fields},
+					desired:  &desired[i],
+					existing: matches[0],
+				})
+			}
+		default:
+			return nil, fmt.Errorf("%w: %d existing %ss named %q", ErrDuplicateResourceName, len(matches), kind, name)
+		}
+	}
+
+	if prune {
+		var names []string
+		for name := range byName {
+			if !declared[name] {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, resource := range byName[name] {
+				steps = append(steps, syncStep[S, R]{
+					change:   ResourceChange{Kind: kind, Name: name, Action: SyncDelete},
+					existing: resource,
+				})
+			}
+		}
+	}
+
+	return steps, nil
+}
+
+// changes lists the planned changes in apply order.
+func (p *syncPlan) changes() []ResourceChange {
+	changes := []ResourceChange{}
+	record := func(change ResourceChange) { changes = append(changes, change) }
+	p.each(record, record, record, record)
+	return changes
+}
+
+// each calls the given functions with the planned changes in apply order:
+// webhook creates and updates, anomaly configs, rules, webhook deletes.
+func (p *syncPlan) each(webhookUpsert, anomaly, rule, webhookDelete func(ResourceChange)) {
+	for _, step := range p.webhooks {
+		if step.change.Action != SyncDelete {
+			webhookUpsert(step.change)
+		}
+	}
+	for _, step := range p.anomalies {
+		anomaly(step.change)
+	}
+	for _, step := range p.rules {
+		rule(step.change)
+	}
+	for _, step := range p.webhooks {
+		if step.change.Action == SyncDelete {
+			webhookDelete(step.change)
+		}
+	}
+}
+
+// apply applies the plan, calling applied after each change.
+func (s *ResourceSyncer) apply(ctx context.Context, plan *syncPlan, author string, applied func(ResourceChange)) error {
+	webhookIDs := make(map[string]string)
+	for _, step := range plan.webhooks {
+		if step.existing != nil {
+			webhookIDs[step.existing.Name] = step.existing.ID
+		}
+	}
+	// Unchanged webhooks are not in the plan; rules may still reference them
+	existing, err := listAll(ctx, s.webhooks.List)
+	if err != nil {
+		return fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	for _, webhook := range existing {
+		webhookIDs[webhook.Name] = webhook.ID
+	}
+
+	for _, step := range plan.webhooks {
+		if step.change.Action == SyncDelete {
+			continue
+		}
+		webhook := step.desired.normalize().webhook(step.existing)
+		if err := s.upsertWebhook(ctx, step.change.Action, webhook); err != nil {
+			return err
+		}
+		webhookIDs[webhook.Name] = webhook.ID
+		applied(step.change)
+	}
+
+	for _, step := range plan.anomalies {
+		if err := s.applyAnomalyConfig(ctx, step); err != nil {
+			return err
+		}
+		applied(step.change)
+	}
+
+	for _, step := range plan.rules {
+		if err := s.applyRule(ctx, step, webhookIDs, author); err != nil {
+			return err
+		}
+		applied(step.change)
+	}
+
+	for _, step := range plan.webhooks {
+		if step.change.Action != SyncDelete {
+			continue
+		}
+		if err := s.webhooks.Delete(ctx, step.existing.ID); err != nil {
+			return fmt.Errorf("failed to delete webhook %q: %w", step.change.Name, err)
+		}
+		applied(step.change)
+	}
+
+	return nil
+}
+
+// upsertWebhook creates or updates a webhook.
+func (s *ResourceSyncer) upsertWebhook(ctx context.Context, action string, webhook *db.Webhook) error {
+	var err error
+	if action == SyncCreate {
+		err = s.webhooks.Create(ctx, webhook)
+	} else {
+		err = s.webhooks.Update(ctx, webhook)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to %s webhook %q: %w", action, webhook.Name, err)
+	}
+	return nil
+}
+
+// applyAnomalyConfig applies one anomaly config step.
+func (s *ResourceSyncer) applyAnomalyConfig(ctx context.Context, step syncStep[AnomalyConfigSpec, *db.AnomalyConfig]) error {
+	var err error
+	switch step.change.Action {
+	case SyncCreate:
+		err = s.anomalies.Create(ctx, step.desired.normalize().anomalyConfig(nil))
+	case SyncUpdate:
+		err = s.anomalies.Update(ctx, step.desired.normalize().anomalyConfig(step.existing))
+	case SyncDelete:
+		err = s.anomalies.Delete(ctx, step.existing.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to %s anomaly config %q: %w", step.change.Action, step.change.Name, err)
+	}
+	return nil
+}
+
+// applyRule applies one rule step, resolving webhook names to IDs.
+func (s *ResourceSyncer) applyRule(ctx context.Context, step syncStep[RuleSpec, *db.Rule], webhookIDs map[string]string, author string) error {
+	var err error
+	switch step.change.Action {
+	case SyncCreate:
+		err = s.rules.Create(ctx, step.desired.normalize().rule(nil, webhookIDs), author)
+	case SyncUpdate:
+		err = s.rules.Update(ctx, step.desired.normalize().rule(step.existing, webhookIDs), author)
+	case SyncDelete:
+		err = s.rules.Delete(ctx, step.existing.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to %s rule %q: %w", step.change.Action, step.change.Name, err)
+	}
+	return nil
+}
+
+// listAll loads every page of a paginated list.
+func listAll[R any](ctx context.Context, list func(ctx context.Context, limit, offset int) ([]R, error)) ([]R, error) {
+	var all []R
+	for offset := 0; ; offset += syncPageSize {
+		page, err := list(ctx, syncPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < syncPageSize {
+			return all, nil
+		}
+	}
+}
+
+// diffFields returns the fields that differ between the JSON forms of
+// before and after, in name order. Values are compared after a JSON round
+// trip so that key order and formatting of embedded JSON do not matter.
+func diffFields(before, after any) ([]FieldChange, error) {
+	beforeFields, err := canonicalFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := canonicalFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(beforeFields)+len(afterFields))
+	for name := range beforeFields {
+		names = append(names, name)
+	}
+	for name := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	var changes []FieldChange
+	for _, name := range names {
+		old, ok := beforeFields[name]
+		if !ok {
+			old = json.RawMessage("null")
+		}
+		updated, ok := afterFields[name]
+		if !ok {
+			updated = json.RawMessage("null")
+		}
+		if bytes.Equal(old, updated) {
+			continue
+		}
+		if secretFields[name] {
+			old, updated = redacted, redacted
+		}
+		changes = append(changes, FieldChange{Field: name, Old: old, New: updated})
+	}
+	return changes, nil
+}
+
+// canonicalFields marshals v and returns its top-level fields re-encoded in
+// canonical form (sorted keys, no insignificant whitespace).
+func canonicalFields(v any) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	canonical := make(map[string]json.RawMessage, len(fields))
+	for name, value := range fields {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		canonical[name] = encoded
+	}
+	return canonical, nil
+}
+
+// emptyObject normalizes unset embedded JSON to an empty object, the schema
+// default.
+func emptyObject(raw json.RawMessage) json.RawMessage {
+	if len(bytes.TrimSpace(raw)) == 0 || bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return json.RawMessage("{}")
+	}
+	return raw
+}
+
+// enabledOrDefault returns *enabled, or true if unset.
+func enabledOrDefault(enabled *bool) *bool {
+	if enabled == nil {
+		enabled = new(bool)
+		*enabled = true
+	}
+	return enabled
+}
+
+// normalize applies the schema defaults to unset fields.
+func (w WebhookSpec) normalize() WebhookSpec {
+	if w.AuthType == "" {
+		w.AuthType = defaultWebhookAuthType
+	}
+	w.AuthConfig = emptyObject(w.AuthConfig)
+	w.Enabled = enabledOrDefault(w.Enabled)
+	if w.TimeoutMs == 0 {
+		w.TimeoutMs = defaultWebhookTimeoutMs
+	}
+	return w
+}
+
+// webhook returns the webhook w declares, keeping the identity of existing
+// if non-nil.
+func (w WebhookSpec) webhook(existing *db.Webhook) *db.Webhook {
+	webhook := &db.Webhook{}
+	if existing != nil {
+		*webhook = *existing
+	}
+	webhook.Name = w.Name
+	webhook.URL = w.URL
+	webhook.AuthType = w.AuthType
+	webhook.AuthConfig = w.AuthConfig
+	webhook.Headers = w.Headers
+	webhook.Enabled = *w.Enabled
+	webhook.TimeoutMs = w.TimeoutMs
+	webhook.MaxRPS = w.MaxRPS
+	webhook.BatchSize = w.BatchSize
+	return webhook
+}
+
+// webhookSpecOf returns the spec form of an existing webhook.
+func webhookSpecOf(w *db.Webhook) WebhookSpec {
+	enabled := w.Enabled
+	return WebhookSpec{
+		Name:       w.Name,
+		URL:        w.URL,
+		AuthType:   w.AuthType,
+		AuthConfig: emptyObject(w.AuthConfig),
+		Headers:    w.Headers,
+		Enabled:    &enabled,
+		TimeoutMs:  w.TimeoutMs,
+		MaxRPS:     w.MaxRPS,
+		BatchSize:  w.BatchSize,
+	}
+}
+
+// normalize applies the schema defaults to unset fields.
+func (r RuleSpec) normalize() RuleSpec {
+	r.Enabled = enabledOrDefault(r.Enabled)
+	return r
+}
+
+// rule returns the rule r declares, keeping the identity of existing if
+// non-nil and resolving webhook names to IDs.
+func (r RuleSpec) rule(existing *db.Rule, webhookIDs map[string]string) *db.Rule {
+	rule := &db.Rule{}
+	if existing != nil {
+		*rule = *existing
+	}
+	rule.Name = r.Name
+	rule.Description = r.Description
+	rule.AppID = r.AppID
+	rule.EventCategory = r.EventCategory
+	rule.EventType = r.EventType
+	rule.Conditions = r.Conditions
+	if rule.Conditions == nil {
+		rule.Conditions = []db.Condition{}
+	}
+	rule.Actions = db.Actions{
+		PublishSubjects:  r.Actions.PublishSubjects,
+		Metrics:          r.Actions.Metrics,
+		PushNotification: r.Actions.PushNotification,
+	}
+	for _, name := range r.Actions.Webhooks {
+		rule.Actions.Webhooks = append(rule.Actions.Webhooks, webhookIDs[name])
+	}
+	rule.Priority = r.Priority
+	rule.Enabled = *r.Enabled
+	rule.Shadow = r.Shadow
+	return rule
+}
+
+// ruleSpecOf returns the spec form of an existing rule, with webhook IDs
+// replaced by names where known.
+func ruleSpecOf(r *db.Rule, webhookNames map[string]string) RuleSpec {
+	enabled := r.Enabled
+	spec := RuleSpec{
+		Name:          r.Name,
+		Description:   r.Description,
+		AppID:         r.AppID,
+		EventCategory: r.EventCategory,
+		EventType:     r.EventType,
+		Conditions:    r.Conditions,
+		Actions:       RuleSpecActions{PublishSubjects: r.Actions.PublishSubjects, Metrics: r.Actions.Metrics, PushNotification: r.Actions.PushNotification},
+		Priority:      r.Priority,
+		Enabled:       &enabled,
+		Shadow:        r.Shadow,
+	}
+	for _, id := range r.Actions.Webhooks {
+		if name, ok := webhookNames[id]; ok {
+			id = name
+		}
+		spec.Actions.Webhooks = append(spec.Actions.Webhooks, id)
+	}
+	return spec
+}
+
+// normalize applies the schema defaults to unset fields.
+func (c AnomalyConfigSpec) normalize() AnomalyConfigSpec {
+	c.Config = emptyObject(c.Config)
+	if c.CooldownSeconds == nil {
+		cooldown := defaultCooldownSeconds
+		c.CooldownSeconds = &cooldown
+	}
+	if c.Severity == "" {
+		c.Severity = defaultSeverity
+	}
+	c.Enabled = enabledOrDefault(c.Enabled)
+	return c
+}
+
+// anomalyConfig returns the anomaly config c declares, keeping the identity
+// of existing if non-nil.
+func (c AnomalyConfigSpec) anomalyConfig(existing *db.AnomalyConfig) *db.AnomalyConfig {
+	config := &db.AnomalyConfig{}
+	if existing != nil {
+		*config = *existing
+	}
+	config.Name = c.Name
+	config.Description = c.Description
+	config.AppID = c.AppID
+	config.EventCategory = c.EventCategory
+	config.EventType = c.EventType
+	config.DetectionType = c.DetectionType
+	config.Config = c.Config
+	config.CooldownSeconds = *c.CooldownSeconds
+	config.Severity = c.Severity
+	config.EscalationPolicyID = c.EscalationPolicyID
+	config.Enabled = *c.Enabled
+	return config
+}
+
+// anomalyConfigSpecOf returns the spec form of an existing anomaly config.
+func anomalyConfigSpecOf(c *db.AnomalyConfig) AnomalyConfigSpec {
+	cooldown, enabled := c.CooldownSeconds, c.Enabled
+	return AnomalyConfigSpec{
+		Name:            c.Name,
+		Description:     c.Description,
+		AppID:           c.AppID,
+		EventCategory:   c.EventCategory,
+		EventType:       c.EventType,
+		DetectionType:   c.DetectionType,
+		Config:          emptyObject(c.Config),
+		CooldownSeconds: &cooldown,
+		Severity:        c.Severity,
+		Enabled:         &enabled,
+
+		EscalationPolicyID: c.EscalationPolicyID,
+	}
+}
@@ -0,0 +1,402 @@
+package reaction
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+)
+
+// memStore is an in-memory resource table keyed by ID.
+type memStore[R any] struct {
+	id      func(R) *string
+	items   []R
+	nextID  int
+	writes  int
+	failure error
+}
+
+func (m *memStore[R]) List(_ context.Context, limit, offset int) ([]R, error) {
+	if offset >= len(m.items) {
+		return nil, nil
+	}
+	return m.items[offset:min(offset+limit, len(m.items))], nil
+}
+
+func (m *memStore[R]) create(item R) error {
+	if m.failure != nil {
+		return m.failure
+	}
+	m.nextID++
+	*m.id(item) = fmt.Sprintf("id-%d", m.nextID)
+	m.items = append(m.items, item)
+	m.writes++
+	return nil
+}
+
+func (m *memStore[R]) update(item R) error {
+	for i := range m.items {
+		if *m.id(m.items[i]) == *m.id(item) {
+			m.items[i] = item
+			m.writes++
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func (m *memStore[R]) Delete(_ context.Context, id string) error {
+	for i := range m.items {
+		if *m.id(m.items[i]) == id {
+			m.items = append(m.items[:i], m.items[i+1:]...)
+			m.writes++
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+type memWebhookStore struct{ memStore[*db.Webhook] }
+
+func (m *memWebhookStore) Create(_ context.Context, w *db.Webhook) error { return m.create(w) }
+func (m *memWebhookStore) Update(_ context.Context, w *db.Webhook) error { return m.update(w) }
+
+type memAnomalyConfigStore struct{ memStore[*db.AnomalyConfig] }
+
+func (m *memAnomalyConfigStore) Create(_ context.Context, c *db.AnomalyConfig) error {
+	return m.create(c)
+}
+func (m *memAnomalyConfigStore) Update(_ context.Context, c *db.AnomalyConfig) error {
+	return m.update(c)
+}
+
+type memRuleStore struct {
+	memStore[*db.Rule]
+	authors []string
+}
+
+func (m *memRuleStore) Create(_ context.Context, r *db.Rule, author string) error {
+	m.authors = append(m.authors, author)
+	return m.create(r)
+}
+func (m *memRuleStore) Update(_ context.Context, r *db.Rule, author string) error {
+	m.authors = append(m.authors, author)
+	return m.update(r)
+}
+
+type syncFixture struct {
+	syncer    *ResourceSyncer
+	webhooks  *memWebhookStore
+	rules     *memRuleStore
+	anomalies *memAnomalyConfigStore
+}
+
+func newSyncFixture() *syncFixture {
+	f := &syncFixture{
+		webhooks:  &memWebhookStore{memStore[*db.Webhook]{id: func(w *db.Webhook) *string { return &w.ID }}},
+		rules:     &memRuleStore{memStore: memStore[*db.Rule]{id: func(r *db.Rule) *string { return &r.ID }}},
+		anomalies: &memAnomalyConfigStore{memStore[*db.AnomalyConfig]{id: func(c *db.AnomalyConfig) *string { return &c.ID }}},
+	}
+	f.syncer = &ResourceSyncer{rules: f.rules, webhooks: f.webhooks, anomalies: f.anomalies}
+	return f
+}
+
+func (f *syncFixture) writes() int {
+	return f.webhooks.writes + f.rules.writes + f.anomalies.writes
+}
+
+// parseSpec decodes a JSON resource spec.
+func parseSpec(t *testing.T, data string) ResourceSpec {
+	t.Helper()
+	var spec ResourceSpec
+	if err := json.Unmarshal([]byte(data), &spec); err != nil {
+		t.Fatalf("parse spec: %v", err)
+	}
+	return spec
+}
+
+const testSpec = `{
+	"webhooks": [{"name": "slack", "url": "https://hooks.example.com/slack", "auth_type": "bearer", "auth_config": {"token": "s3cret"}}],
+	"rules": [{
+		"name": "big-purchase",
+		"event_type": "purchase_complete",
+		"conditions": [{"path": "$.purchase_complete.total_cents", "operator": "gt", "value": 10000}],
+		"actions": {"webhooks": ["slack"]},
+		"priority": 10
+	}],
+	"anomaly_configs": [{"name": "error-spike", "detection_type": "rate", "config": {"max_rate": 5}}]
+}`
+
+// changeKeys renders changes as "action kind name" for comparison.
+func changeKeys(changes []ResourceChange) []string {
+	keys := make([]string, len(changes))
+	for i, c := range changes {
+		keys[i] = c.Action + " " + c.Kind + " " + c.Name
+	}
+	return keys
+}
+
+func assertChanges(t *testing.T, got []ResourceChange, want ...string) {
+	t.Helper()
+	keys := changeKeys(got)
+	if strings.Join(keys, "\n") != strings.Join(want, "\n") {
+		t.Errorf("changes:\n got %q\nwant %q", keys, want)
+	}
+}
+
+func TestResourceSyncer_CreatesInDependencyOrder(t *testing.T) {
+	f := newSyncFixture()
+
+	result, err := f.syncer.Sync(context.Background(), parseSpec(t, testSpec), SyncOptions{Author: "alice"})
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	assertChanges(t, result.Changes,
+		"create webhook slack",
+		"create anomaly_config error-spike",
+		"create rule big-purchase",
+	)
+
+	webhook := f.webhooks.items[0]
+	if webhook.AuthType != "bearer" || !webhook.Enabled || webhook.TimeoutMs != defaultWebhookTimeoutMs {
+		t.Errorf("webhook: got %+v", webhook)
+	}
+	rule := f.rules.items[0]
+	if len(rule.Actions.Webhooks) != 1 || rule.Actions.Webhooks[0] != webhook.ID {
+		t.Errorf("rule webhooks: got %v, want [%s]", rule.Actions.Webhooks, webhook.ID)
+	}
+	if !rule.Enabled || rule.Priority != 10 {
+		t.Errorf("rule: got %+v", rule)
+	}
+	if f.rules.authors[0] != "alice" {
+		t.Errorf("rule author: got %q, want alice", f.rules.authors[0])
+	}
+	if config := f.anomalies.items[0]; config.CooldownSeconds != defaultCooldownSeconds || !config.Enabled {
+		t.Errorf("anomaly config: got %+v", config)
+	}
+}
+
+func TestResourceSyncer_ReapplyIsNoop(t *testing.T) {
+	f := newSyncFixture()
+	spec := parseSpec(t, testSpec)
+	if _, err := f.syncer.Sync(context.Background(), spec, SyncOptions{Author: "alice"}); err != nil {
+		t.Fatalf("first Sync: %v", err)
+	}
+
+	// The database reformats embedded JSON; that must not count as a change
+	f.webhooks.items[0].AuthConfig = json.RawMessage(`{ "token" : "s3cret" }`)
+	f.anomalies.items[0].Config = json.RawMessage(`{"max_rate": 5.0}`)
+	writes := f.writes()
+
+	result, err := f.syncer.Sync(context.Background(), spec, SyncOptions{Author: "alice"})
+	if err != nil {
+		t.Fatalf("second Sync: %v", err)
+	}
+	assertChanges(t, result.Changes)
+	if f.writes() != writes {
+		t.Errorf("writes: got %d, want %d", f.writes(), writes)
+	}
+}
+
+func TestResourceSyncer_UpdateReportsFieldDiff(t *testing.T) {
+	f := newSyncFixture()
+	if _, err := f.syncer.Sync(context.Background(), parseSpec(t, testSpec), SyncOptions{Author: "alice"}); err != nil {
+		t.Fatalf("first Sync: %v", err)
+	}
+	ruleID := f.rules.items[0].ID
+
+	updated := strings.Replace(testSpec, `"priority": 10`, `"priority": 20, "enabled": false`, 1)
+	updated = strings.Replace(updated, `"s3cret"`, `"rotated"`, 1)
+	result, err := f.syncer.Sync(context.Background(), parseSpec(t, updated), SyncOptions{Author: "bob"})
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	assertChanges(t, result.Changes, "update webhook slack", "update rule big-purchase")
+
+	webhookFields := result.Changes[0].Fields
+	if len(webhookFields) != 1 || webhookFields[0].Field != "auth_config" ||
+		string(webhookFields[0].Old) != string(redacted) || string(webhookFields[0].New) != string(redacted) {
+		t.Errorf("webhook fields: got %+v, want redacted auth_config", webhookFields)
+	}
+
+	ruleFields := result.Changes[1].Fields
+	if len(ruleFields) != 2 || ruleFields[0].Field != "enabled" || ruleFields[1].Field != "priority" ||
+		string(ruleFields[1].Old) != "10" || string(ruleFields[1].New) != "20" {
+		t.Errorf("rule fields: got %+v", ruleFields)
+	}
+
+	rule := f.rules.items[0]
+	if rule.ID != ruleID || rule.Priority != 20 || rule.Enabled {
+		t.Errorf("rule: got %+v, want %s updated in place", rule, ruleID)
+	}
+	if got := f.rules.authors[len(f.rules.authors)-1]; got != "bob" {
+		t.Errorf("rule author: got %q, want bob", got)
+	}
+}
+
+func TestResourceSyncer_DryRunDoesNotWrite(t *testing.T) {
+	f := newSyncFixture()
+
+	result, err := f.syncer.Sync(context.Background(), parseSpec(t, testSpec), SyncOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if !result.DryRun || len(result.Changes) != 3 {
+		t.Errorf("result: got %+v, want 3 planned changes", result)
+	}
+	if f.writes() != 0 {
+		t.Errorf("writes: got %d, want 0", f.writes())
+	}
+}
+
+func TestResourceSyncer_Prune(t *testing.T) {
+	f := newSyncFixture()
+	if _, err := f.syncer.Sync(context.Background(), parseSpec(t, testSpec), SyncOptions{Author: "alice"}); err != nil {
+		t.Fatalf("first Sync: %v", err)
+	}
+	f.rules.items = append(f.rules.items, &db.Rule{ID: "manual", Name: "hand-made"})
+
+	empty := ResourceSpec{}
+
+	result, err := f.syncer.Sync(context.Background(), empty, SyncOptions{Author: "alice"})
+	if err != nil {
+		t.Fatalf("Sync without prune: %v", err)
+	}
+	assertChanges(t, result.Changes)
+
+	result, err = f.syncer.Sync(context.Background(), empty, SyncOptions{Author: "alice", Prune: true})
+	if err != nil {
+		t.Fatalf("Sync with prune: %v", err)
+	}
+	// Rules are deleted before the webhooks they reference
+	assertChanges(t, result.Changes,
+		"delete anomaly_config error-spike",
+		"delete rule big-purchase",
+		"delete rule hand-made",
+		"delete webhook slack",
+	)
+	if len(f.webhooks.items)+len(f.rules.items)+len(f.anomalies.items) != 0 {
+		t.Errorf("resources left after prune")
+	}
+}
+
+func TestResourceSyncer_RejectsInvalidSpecs(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{"unnamed webhook", `{"webhooks": [{"url": "https://example.com"}]}`},
+		{"webhook without url", `{"webhooks": [{"name": "a"}]}`},
+		{"duplicate rule", `{"rules": [{"name": "a"}, {"name": "a"}]}`},
+		{"undeclared webhook", `{"rules": [{"name": "a", "actions": {"webhooks": ["missing"]}}]}`},
+		{"publish subject outside reactions", `{"rules": [{"name": "a", "actions": {"publish_subjects": ["alerts.{app_id}.vip"]}}]}`},
+		{"invalid metric name", `{"rules": [{"name": "a", "actions": {"metrics": [{"name": "Signups-Total"}]}}]}`},
+		{"metric label without path", `{"rules": [{"name": "a", "actions": {"metrics": [{"name": "signups", "labels": {"plan": "plan"}}]}}]}`},
+		{"push notification without body", `{"rules": [{"name": "a", "actions": {"push_notification": {"title": "Hi"}}}]}`},
+		{"chained rules in a cycle", `{"rules": [
+			{"name": "a", "event_category": "reactions", "event_type": "b", "actions": {"publish_subjects": ["reactions.{app_id}.a"]}},
+			{"name": "b", "event_category": "reactions", "event_type": "a", "actions": {"publish_subjects": ["reactions.{app_id}.b"]}}
+		]}`},
+		{"history condition without a window", `{"rules": [{"name": "a", "conditions": [{"source": "history", "path": "commerce.purchase_complete", "operator": "within"}]}]}`},
+		{"unknown detection type", `{"anomaly_configs": [{"name": "a", "detection_type": "magic"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSyncFixture()
+			_, err := f.syncer.Sync(context.Background(), parseSpec(t, tt.spec), SyncOptions{Author: "alice"})
+			if !errors.Is(err, ErrInvalidResourceSpec) {
+				t.Errorf("err: got %v, want ErrInvalidResourceSpec", err)
+			}
+		})
+	}
+}
+
+func TestResourceSyncer_DuplicateExistingName(t *testing.T) {
+	f := newSyncFixture()
+	f.rules.items = []*db.Rule{{ID: "r1", Name: "dup"}, {ID: "r2", Name: "dup"}}
+
+	_, err := f.syncer.Sync(context.Background(), parseSpec(t, `{"rules": [{"name": "dup"}]}`), SyncOptions{Author: "alice"})
+	if !errors.Is(err, ErrDuplicateResourceName) {
+		t.Errorf("err: got %v, want ErrDuplicateResourceName", err)
+	}
+}
+
+func TestResourceSyncer_PartialFailureReportsApplied(t *testing.T) {
+	f := newSyncFixture()
+	f.rules.failure = errors.New("db down")
+
+	result, err := f.syncer.Sync(context.Background(), parseSpec(t, testSpec), SyncOptions{Author: "alice"})
+	if err == nil {
+		t.Fatal("Sync: got nil error")
+	}
+	assertChanges(t, result.Changes, "create webhook slack", "create anomaly_config error-spike")
+}
+
+func TestListAll_Paginates(t *testing.T) {
+	store := &memStore[*db.Rule]{id: func(r *db.Rule) *string { return &r.ID }}
+	for i := 0; i < syncPageSize+3; i++ {
+		store.items = append(store.items, &db.Rule{ID: fmt.Sprint(i)})
+	}
+
+	all, err := listAll(context.Background(), store.List)
+	if err != nil {
+		t.Fatalf("listAll: %v", err)
+	}
+	if len(all) != syncPageSize+3 {
+		t.Errorf("listAll: got %d, want %d", len(all), syncPageSize+3)
+	}
+}
+
+func TestResourceHandler_Apply(t *testing.T) {
+	f := newSyncFixture()
+	mux := http.NewServeMux()
+	newResourceHandler(f.syncer, nil, nil).RegisterRoutes(mux)
+
+	body := `{"spec":` + testSpec + `,"dry_run":true}`
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/resources/apply", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	var result SyncResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !result.DryRun || len(result.Changes) != 3 {
+		t.Errorf("result: got %+v", result)
+	}
+}
+
+func TestResourceHandler_Apply_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"unknown field", `{"spec":{"rulez":[]},"author":"alice"}`, http.StatusBadRequest},
+		{"missing author", `{"spec":{}}`, http.StatusBadRequest},
+		{"invalid spec", `{"spec":{"rules":[{"name":""}]},"author":"alice"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			newResourceHandler(newSyncFixture().syncer, nil, nil).RegisterRoutes(mux)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/admin/resources/apply", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status: got %d, want %d (body %s)", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package reaction
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/profiles"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// UsersConfig holds settings for conditions with the "user" source.
+type UsersConfig struct {
+	// Enabled resolves the user of each event's device in the profile
+	// store maintained by profile-sink
+	Enabled bool `env:"ENABLED" envDefault:"false"`
+
+	// DatabaseName is the database holding the profile store, on the same
+	// server as the reaction engine database
+	DatabaseName string `env:"DATABASE_NAME" envDefault:"causality_server"`
+
+	// CacheTTL is how long a device's resolved user profile is reused
+	// before it is looked up again
+	CacheTTL time.Duration `env:"CACHE_TTL" envDefault:"5m"`
+
+	// CacheSize bounds the number of devices whose user profile is cached
+	CacheSize int `env:"CACHE_SIZE" envDefault:"10000"`
+}
+
+// UserLookup resolves the users of a device for conditions with the "user"
+// source. It is satisfied by *profiles.Reader.
+type UserLookup interface {
+	// FindByDevice returns the profiles of every user seen on a device,
+	// most recently seen on it first.
+	FindByDevice(ctx context.Context, appID, deviceID string) ([]profiles.Profile, error)
+}
+
+// SetUserLookup sets the profile store read by conditions with the "user"
+// source. The profile of the user most recently seen on the event's device
+// is cached per device for cfg.CacheTTL. Without it those conditions see no
+// profile, so only "not_exists" matches. Must be called before Start.
+func (e *Engine) SetUserLookup(lookup UserLookup, cfg UsersConfig) {
+	e.users = lookup
+	e.userCache = newUserCache(cfg.CacheTTL, cfg.CacheSize)
+}
+
+// userToJSON returns the profile of the user most recently seen on the
+// event's device as a JSON map, or nil if there is no profile store or the
+// device has no user. Lookup errors are logged, treated as no user, and not
+// cached.
+func (e *Engine) userToJSON(ctx context.Context, event *pb.EventEnvelope) map[string]interface{} {
+	if e.users == nil || event.DeviceId == "" {
+		return nil
+	}
+
+	key := userCacheKey{appID: event.AppId, deviceID: event.DeviceId}
+	if user, ok := e.userCache.get(key); ok {
+		return user
+	}
+
+	found, err := e.users.FindByDevice(ctx, event.AppId, event.DeviceId)
+	if err != nil {
+		e.logger.Warn("failed to look up user for rule conditions",
+			"app_id", event.AppId,
+			"device_id", event.DeviceId,
+			"error", err,
+		)
+		return nil
+	}
+
+	var user map[string]interface{}
+	if len(found) > 0 {
+		user = structToMap(found[0])
+	}
+	e.userCache.put(key, user)
+	return user
+}
+
+// userCacheKey identifies one device of one app.
+type userCacheKey struct {
+	appID    string
+	deviceID string
+}
+
+// userCacheEntry is a device's resolved user profile, nil if the device has
+// no user.
+type userCacheEntry struct {
+	user    map[string]interface{}
+	expires time.Time
+}
+
+// userCache caches the resolved user profile of devices for a TTL. Cached
+// profiles are shared by concurrent evaluations and must not be modified.
+type userCache struct {
+	ttl  time.Duration
+	size int
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[userCacheKey]userCacheEntry
+}
+
+// newUserCache creates a cache of at most size devices.
+func newUserCache(ttl time.Duration, size int) *userCache {
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	if size <= 0 {
+		size = 10000
+	}
+	return &userCache{
+		ttl:     ttl,
+		size:    size,
+		now:     time.Now,
+		entries: make(map[userCacheKey]userCacheEntry),
+	}
+}
+
+// get returns a device's cached user profile and whether it was cached and
+// not expired.
+func (c *userCache) get(key userCacheKey) (map[string]interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.user, true
+}
+
+// put caches a device's user profile. When the cache is full, expired
+// entries are evicted first, then arbitrary ones.
+func (c *userCache) put(key userCacheKey, user map[string]interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		for k := range c.entries {
+			if len(c.entries) < c.size {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = userCacheEntry{user: user, expires: now.Add(c.ttl)}
+}
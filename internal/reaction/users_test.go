@@ -0,0 +1,128 @@
+package reaction
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/profiles"
+	"github.com/SebastienMelki/causality/internal/reaction/db"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// fakeUserLookup serves profiles from a map keyed by device ID and counts
+// lookups.
+type fakeUserLookup struct {
+	users   map[string][]profiles.Profile
+	err     error
+	lookups int
+}
+
+func (f *fakeUserLookup) FindByDevice(_ context.Context, _, deviceID string) ([]profiles.Profile, error) {
+	f.lookups++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.users[deviceID], nil
+}
+
+func TestEngine_UserConditionSource(t *testing.T) {
+	lookup := &fakeUserLookup{users: map[string][]profiles.Profile{
+		"shared": {
+			{AppID: "app", UserID: "u2", Traits: map[string]string{"plan": "enterprise"}},
+			{AppID: "app", UserID: "u1", Traits: map[string]string{"plan": "free"}},
+		},
+		"free": {
+			{AppID: "app", UserID: "u3", Traits: map[string]string{"plan": "free"}},
+		},
+	}}
+	e := NewEngine(nil, nil, nil, nil, EngineConfig{}, DispatcherConfig{}, nil, nil)
+	e.SetUserLookup(lookup, UsersConfig{CacheTTL: time.Minute, CacheSize: 10})
+
+	rule := &db.Rule{ID: "r1", Conditions: []db.Condition{
+		{Source: db.ConditionSourceUser, Path: "traits.plan", Operator: "eq", Value: "enterprise"},
+	}}
+
+	tests := []struct {
+		deviceID string
+		want     int
+	}{
+		{"shared", 1}, // most recently seen user
+		{"free", 0},
+		{"unknown", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		event := &pb.EventEnvelope{AppId: "app", DeviceId: tt.deviceID}
+		eventJSON, err := e.eventToJSON(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := e.newConditionData(context.Background(), event, eventJSON)
+		if got := e.findMatchingRules([]*db.Rule{rule}, "app", "", "", data); len(got) != tt.want {
+			t.Errorf("device %q: matched %d rules, want %d", tt.deviceID, len(got), tt.want)
+		}
+	}
+
+	// Resolved users, including devices without one, are cached.
+	before := lookup.lookups
+	for _, deviceID := range []string{"shared", "free", "unknown"} {
+		e.userToJSON(context.Background(), &pb.EventEnvelope{AppId: "app", DeviceId: deviceID})
+	}
+	if lookup.lookups != before {
+		t.Errorf("lookups after cached evaluations: got %d, want %d", lookup.lookups, before)
+	}
+}
+
+func TestEngine_UserLookupErrorNotCached(t *testing.T) {
+	lookup := &fakeUserLookup{err: errors.New("connection refused")}
+	e := NewEngine(nil, nil, nil, nil, EngineConfig{}, DispatcherConfig{}, nil, nil)
+	e.SetUserLookup(lookup, UsersConfig{})
+
+	event := &pb.EventEnvelope{AppId: "app", DeviceId: "d1"}
+	if user := e.userToJSON(context.Background(), event); user != nil {
+		t.Fatalf("user on lookup error: got %v, want nil", user)
+	}
+
+	lookup.err = nil
+	lookup.users = map[string][]profiles.Profile{"d1": {{AppID: "app", UserID: "u1"}}}
+	user := e.userToJSON(context.Background(), event)
+	if user["user_id"] != "u1" {
+		t.Errorf("user after lookup recovered: got %v, want u1", user["user_id"])
+	}
+	if lookup.lookups != 2 {
+		t.Errorf("lookups: got %d, want 2", lookup.lookups)
+	}
+}
+
+func TestUserCache(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := newUserCache(time.Minute, 2)
+	c.now = func() time.Time { return now }
+
+	a := userCacheKey{appID: "app", deviceID: "a"}
+	b := userCacheKey{appID: "app", deviceID: "b"}
+	d := userCacheKey{appID: "app", deviceID: "d"}
+
+	c.put(a, map[string]interface{}{"user_id": "ua"})
+	if user, ok := c.get(a); !ok || user["user_id"] != "ua" {
+		t.Fatalf("get a: got %v, %v", user, ok)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.get(a); ok {
+		t.Error("get a after TTL: got cached, want expired")
+	}
+
+	// A full cache evicts to stay within its size.
+	c.put(a, nil)
+	c.put(b, nil)
+	c.put(d, nil)
+	if len(c.entries) != 2 {
+		t.Errorf("entries: got %d, want 2", len(c.entries))
+	}
+	if _, ok := c.get(d); !ok {
+		t.Error("get d: got missing, want the newest entry cached")
+	}
+}
@@ -0,0 +1,179 @@
+package domain
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/hll"
+)
+
+// Rollup granularities.
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+)
+
+// UnknownPlatform is the platform of events without device context. Rows
+// with no platform are totals over all platforms.
+const UnknownPlatform = "unknown"
+
+// Row is one aggregate of a rollup Parquet file. Rows with an event
+// category and type count those events on one platform; rows without them
+// total all events of a platform, and the row without a platform totals all
+// events of the app, so its unique_devices approximates active devices.
+type Row struct {
+	AppID         string `parquet:"app_id,snappy,dict"`
+	Granularity   string `parquet:"granularity,snappy,dict"`
+	WindowStartMS int64  `parquet:"window_start_ms"`
+
+	// Dimensions, null in total rows
+	EventCategory string `parquet:"event_category,snappy,dict,optional"`
+	EventType     string `parquet:"event_type,snappy,dict,optional"`
+	Platform      string `parquet:"platform,snappy,dict,optional"`
+
+	// Measures
+	Events     int64   `parquet:"events"`
+	RevenueUSD float64 `parquet:"revenue_usd"`
+
+	// UniqueDevices is the estimate of DeviceSketch, a HyperLogLog sketch
+	// of the group's device IDs (see package hll)
+	UniqueDevices int64  `parquet:"unique_devices"`
+	DeviceSketch  []byte `parquet:"device_sketch,snappy"`
+}
+
+// Key identifies a rollup group. Empty fields are totals over that
+// dimension.
+type Key struct {
+	EventCategory string
+	EventType     string
+	Platform      string
+}
+
+// Aggregate holds the measures of one group.
+type Aggregate struct {
+	Events     int64
+	RevenueUSD float64
+	Devices    *hll.Sketch
+}
+
+// Rollup holds the aggregates of one app over one hour or day.
+type Rollup struct {
+	AppID       string
+	Granularity string
+	Start       time.Time
+	Groups      map[Key]*Aggregate
+}
+
+// NewRollup returns an empty rollup of the window starting at start.
+func NewRollup(appID, granularity string, start time.Time) *Rollup {
+	return &Rollup{
+		AppID:       appID,
+		Granularity: granularity,
+		Start:       start.UTC(),
+		Groups:      make(map[Key]*Aggregate),
+	}
+}
+
+// Empty reports whether the rollup counts no events.
+func (r *Rollup) Empty() bool {
+	return len(r.Groups) == 0
+}
+
+// Add counts one event in its group, its platform total and the app total.
+func (r *Rollup) Add(category, eventType, platform, deviceID string, amountUSD float64) {
+	if platform == "" {
+		platform = UnknownPlatform
+	}
+	for _, key := range []Key{
+		{EventCategory: category, EventType: eventType, Platform: platform},
+		{Platform: platform},
+		{},
+	} {
+		agg := r.group(key)
+		agg.Events++
+		agg.RevenueUSD += amountUSD
+		agg.Devices.Add(deviceID)
+	}
+}
+
+// Merge adds the aggregates of other, a rollup of a sub-window such as an
+// hour of a daily rollup.
+func (r *Rollup) Merge(other *Rollup) {
+	for key, src := range other.Groups {
+		agg := r.group(key)
+		agg.Events += src.Events
+		agg.RevenueUSD += src.RevenueUSD
+		agg.Devices.Merge(src.Devices)
+	}
+}
+
+// group returns the aggregate of key, creating it if needed.
+func (r *Rollup) group(key Key) *Aggregate {
+	agg, ok := r.Groups[key]
+	if !ok {
+		agg = &Aggregate{Devices: hll.New()}
+		r.Groups[key] = agg
+	}
+	return agg
+}
+
+// Rows returns the rollup's rows, totals first, then by dimensions.
+func (r *Rollup) Rows() ([]Row, error) {
+	rows := make([]Row, 0, len(r.Groups))
+	for key, agg := range r.Groups {
+		sketch, err := agg.Devices.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, Row{
+			AppID:         r.AppID,
+			Granularity:   r.Granularity,
+			WindowStartMS: r.Start.UnixMilli(),
+			EventCategory: key.EventCategory,
+			EventType:     key.EventType,
+			Platform:      key.Platform,
+			Events:        agg.Events,
+			RevenueUSD:    agg.RevenueUSD,
+			UniqueDevices: agg.Devices.Estimate(),
+			DeviceSketch:  sketch,
+		})
+	}
+
+	slices.SortFunc(rows, func(a, b Row) int {
+		// Empty dimensions sort first, so totals precede groups.
+		return cmp.Or(
+			cmp.Compare(a.EventCategory, b.EventCategory),
+			cmp.Compare(a.EventType, b.EventType),
+			cmp.Compare(a.Platform, b.Platform),
+		)
+	})
+	return rows, nil
+}
+
+// FromRows rebuilds a rollup from the rows of its file.
+func FromRows(appID, granularity string, start time.Time, rows []Row) (*Rollup, error) {
+	r := NewRollup(appID, granularity, start)
+	for _, row := range rows {
+		sketch := hll.New()
+		if err := sketch.UnmarshalBinary(row.DeviceSketch); err != nil {
+			return nil, fmt.Errorf("row %s/%s/%s: %w", row.EventCategory, row.EventType, row.Platform, err)
+		}
+		key := Key{EventCategory: row.EventCategory, EventType: row.EventType, Platform: row.Platform}
+		r.Groups[key] = &Aggregate{
+			Events:     row.Events,
+			RevenueUSD: row.RevenueUSD,
+			Devices:    sketch,
+		}
+	}
+	return r, nil
+}
+
+// State is the rollup progress of one app. Hourly rollups exist for every
+// hour with events before HourlyUntil, and daily rollups for every day with
+// events before DailyUntil.
+type State struct {
+	HourlyUntil time.Time `json:"hourly_until"`
+	DailyUntil  time.Time `json:"daily_until"`
+}
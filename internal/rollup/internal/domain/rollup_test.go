@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRollup_Add(t *testing.T) {
+	r := NewRollup("app", GranularityHour, time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC))
+	r.Add("screen", "screen_view", "PLATFORM_IOS", "d1", 0)
+	r.Add("screen", "screen_view", "PLATFORM_IOS", "d2", 0)
+	r.Add("commerce", "purchase_complete", "PLATFORM_IOS", "d1", 9.99)
+	r.Add("screen", "screen_view", "", "d3", 0)
+
+	tests := []struct {
+		key     Key
+		events  int64
+		revenue float64
+		devices int64
+	}{
+		{Key{"screen", "screen_view", "PLATFORM_IOS"}, 2, 0, 2},
+		{Key{"commerce", "purchase_complete", "PLATFORM_IOS"}, 1, 9.99, 1},
+		{Key{"screen", "screen_view", UnknownPlatform}, 1, 0, 1},
+		{Key{Platform: "PLATFORM_IOS"}, 3, 9.99, 2},
+		{Key{Platform: UnknownPlatform}, 1, 0, 1},
+		{Key{}, 4, 9.99, 3},
+	}
+	if len(r.Groups) != len(tests) {
+		t.Errorf("groups = %d, want %d", len(r.Groups), len(tests))
+	}
+	for _, tt := range tests {
+		agg, ok := r.Groups[tt.key]
+		if !ok {
+			t.Errorf("group %+v missing", tt.key)
+			continue
+		}
+		if agg.Events != tt.events || agg.RevenueUSD != tt.revenue || agg.Devices.Estimate() != tt.devices {
+			t.Errorf("group %+v = %d events, %v revenue, %d devices, want %d, %v, %d",
+				tt.key, agg.Events, agg.RevenueUSD, agg.Devices.Estimate(), tt.events, tt.revenue, tt.devices)
+		}
+	}
+}
+
+func TestRollup_MergeAndRows(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	h1 := NewRollup("app", GranularityHour, day)
+	h1.Add("screen", "screen_view", "web", "d1", 0)
+	h2 := NewRollup("app", GranularityHour, day.Add(time.Hour))
+	h2.Add("screen", "screen_view", "web", "d1", 0)
+	h2.Add("screen", "screen_view", "web", "d2", 0)
+
+	daily := NewRollup("app", GranularityDay, day)
+	daily.Merge(h1)
+	daily.Merge(h2)
+
+	rows, err := daily.Rows()
+	if err != nil {
+		t.Fatalf("Rows() error = %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("rows = %d, want 3", len(rows))
+	}
+	// The app total comes first; devices seen in both hours count once.
+	if rows[0].Platform != "" || rows[0].Events != 3 || rows[0].UniqueDevices != 2 {
+		t.Errorf("total row = %+v, want 3 events of 2 devices", rows[0])
+	}
+	if rows[2].EventType != "screen_view" || rows[2].Granularity != GranularityDay || rows[2].WindowStartMS != day.UnixMilli() {
+		t.Errorf("last row = %+v, want the screen_view group of the day", rows[2])
+	}
+
+	decoded, err := FromRows("app", GranularityDay, day, rows)
+	if err != nil {
+		t.Fatalf("FromRows() error = %v", err)
+	}
+	for key, agg := range daily.Groups {
+		got := decoded.Groups[key]
+		if got == nil || got.Events != agg.Events || *got.Devices != *agg.Devices {
+			t.Errorf("group %+v did not round trip", key)
+		}
+	}
+}
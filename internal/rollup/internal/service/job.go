@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/rollup/internal/domain"
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+// day is the duration of a daily rollup window.
+const day = 24 * time.Hour
+
+// eventReader is the subset of warehouse.PartitionReader the job reads
+// events with.
+type eventReader interface {
+	Window() time.Duration
+	ListApps(ctx context.Context) ([]string, error)
+	ListWindow(ctx context.Context, appID string, start time.Time) ([]string, error)
+	Download(ctx context.Context, key string) ([]byte, error)
+}
+
+// rollupStore is the subset of RollupStore the job persists rollups with.
+type rollupStore interface {
+	Read(ctx context.Context, appID, granularity string, start time.Time) (*domain.Rollup, error)
+	Write(ctx context.Context, r *domain.Rollup) error
+	State(ctx context.Context, appID string) (domain.State, bool, error)
+	SaveState(ctx context.Context, appID string, state domain.State) error
+}
+
+// JobConfig holds the rollup job parameters.
+type JobConfig struct {
+	// SettleDelay is how long after a partition window ends before it is
+	// rolled up, giving the warehouse sink time to flush late batches.
+	SettleDelay time.Duration
+
+	// Backfill is how far back the rollups of an app without progress
+	// start, rounded down to a day.
+	Backfill time.Duration
+}
+
+// Job is the rollup batch job. Each run rolls up the partition windows
+// settled since the last run into hourly rollups, then merges the hourly
+// rollups of each completed day into a daily rollup.
+type Job struct {
+	events eventReader
+	store  rollupStore
+	config JobConfig
+	now    func() time.Time
+	logger *slog.Logger
+}
+
+// NewJob creates a new rollup job reading events through reader.
+func NewJob(reader *warehouse.PartitionReader, store *RollupStore, cfg JobConfig, logger *slog.Logger) *Job {
+	return newJob(reader, store, cfg, logger)
+}
+
+func newJob(events eventReader, store rollupStore, cfg JobConfig, logger *slog.Logger) *Job {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.Backfill <= 0 {
+		cfg.Backfill = 48 * time.Hour
+	}
+
+	return &Job{
+		events: events,
+		store:  store,
+		config: cfg,
+		now:    time.Now,
+		logger: logger.With("component", "rollup"),
+	}
+}
+
+// Run rolls up every settled window not yet rolled up. Failures for one app
+// are logged and resumed on the next run; the first error is returned.
+func (j *Job) Run(ctx context.Context) error {
+	start := j.now().UTC()
+	window := j.events.Window()
+	until := start.Add(-j.config.SettleDelay).Truncate(window)
+
+	apps, err := j.events.ListApps(ctx)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, appID := range apps {
+		if err := j.rollupApp(ctx, appID, window, until); err != nil {
+			j.logger.Warn("failed to roll up app", "app_id", appID, "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		if ctx.Err() != nil {
+			return errors.Join(firstErr, ctx.Err())
+		}
+	}
+
+	j.logger.Info("rollup finished",
+		"apps", len(apps),
+		"rolled_up_until", until,
+		"duration", time.Since(start),
+	)
+	return firstErr
+}
+
+// rollupApp writes an app's hourly rollups up to until, then its daily
+// rollups up to the last day whose hours are all rolled up. Progress is
+// saved after each window, so a failed run resumes where it stopped.
+func (j *Job) rollupApp(ctx context.Context, appID string, window time.Duration, until time.Time) error {
+	state, ok, err := j.store.State(ctx, appID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		from := until.Add(-j.config.Backfill).Truncate(day)
+		state = domain.State{HourlyUntil: from, DailyUntil: from}
+	}
+
+	for w := state.HourlyUntil; w.Before(until); w = w.Add(window) {
+		hours, err := j.rollupWindow(ctx, appID, w, w.Add(window))
+		if err != nil {
+			return fmt.Errorf("roll up window %s: %w", w.Format(time.RFC3339), err)
+		}
+		state.HourlyUntil = w.Add(window)
+		if err := j.store.SaveState(ctx, appID, state); err != nil {
+			return err
+		}
+		if hours > 0 {
+			j.logger.Debug("rolled up window", "app_id", appID, "window", w, "hours", hours)
+		}
+	}
+
+	for d := state.DailyUntil; !d.Add(day).After(state.HourlyUntil); d = d.Add(day) {
+		if err := j.rollupDay(ctx, appID, d); err != nil {
+			return fmt.Errorf("roll up day %s: %w", d.Format(time.DateOnly), err)
+		}
+		state.DailyUntil = d.Add(day)
+		if err := j.store.SaveState(ctx, appID, state); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rollupWindow aggregates the events of a partition window into hourly
+// rollups and writes them. It returns the number of hours with events.
+func (j *Job) rollupWindow(ctx context.Context, appID string, from, to time.Time) (int, error) {
+	keys, err := j.events.ListWindow(ctx, appID, from)
+	if err != nil {
+		return 0, err
+	}
+
+	hours := make(map[time.Time]*domain.Rollup)
+	for _, key := range keys {
+		data, err := j.events.Download(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		if err := aggregateEvents(data, appID, from, to, hours); err != nil {
+			return 0, fmt.Errorf("aggregate %s: %w", key, err)
+		}
+	}
+
+	for _, r := range hours {
+		if err := j.store.Write(ctx, r); err != nil {
+			return 0, err
+		}
+	}
+	return len(hours), nil
+}
+
+// rollupDay merges the hourly rollups of a day into its daily rollup.
+// Days without events have no rollup.
+func (j *Job) rollupDay(ctx context.Context, appID string, start time.Time) error {
+	daily := domain.NewRollup(appID, domain.GranularityDay, start)
+	for h := start; h.Before(start.Add(day)); h = h.Add(time.Hour) {
+		hourly, err := j.store.Read(ctx, appID, domain.GranularityHour, h)
+		if err != nil {
+			return err
+		}
+		if hourly != nil {
+			daily.Merge(hourly)
+		}
+	}
+
+	if daily.Empty() {
+		return nil
+	}
+	if err := j.store.Write(ctx, daily); err != nil {
+		return err
+	}
+
+	j.logger.Info("wrote daily rollup",
+		"app_id", appID,
+		"day", start.Format(time.DateOnly),
+		"groups", len(daily.Groups),
+	)
+	return nil
+}
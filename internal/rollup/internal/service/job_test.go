@@ -0,0 +1,177 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/SebastienMelki/causality/internal/rollup/internal/domain"
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+// testLake is an event lake and rollup store in a temporary directory.
+type testLake struct {
+	t      *testing.T
+	client *warehouse.FSStore
+	config warehouse.S3Config
+	store  *RollupStore
+}
+
+func newTestLake(t *testing.T, template string) *testLake {
+	t.Helper()
+	client, err := warehouse.NewFSStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFSStore() error = %v", err)
+	}
+	if _, err := client.CreateBucket(context.Background(), &s3.CreateBucketInput{Bucket: aws.String("lake")}); err != nil {
+		t.Fatalf("CreateBucket() error = %v", err)
+	}
+	cfg := warehouse.S3Config{Bucket: "lake", Prefix: "events", PartitionTemplate: template}
+	return &testLake{t: t, client: client, config: cfg, store: NewRollupStore(client, cfg, "rollups")}
+}
+
+// putEvents writes rows as one Parquet file of the partition at key.
+func (l *testLake) putEvents(key string, rows []warehouse.EventRow) {
+	l.t.Helper()
+	data, err := warehouse.NewParquetWriter(warehouse.ParquetConfig{Compression: "snappy"}).Write(rows)
+	if err != nil {
+		l.t.Fatalf("write parquet: %v", err)
+	}
+	_, err = l.client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String("lake"),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		l.t.Fatalf("PutObject(%s) error = %v", key, err)
+	}
+}
+
+func (l *testLake) newJob(now time.Time) *Job {
+	job := NewJob(warehouse.NewPartitionReader(l.client, l.config, nil), l.store, JobConfig{
+		SettleDelay: 30 * time.Minute,
+		Backfill:    24 * time.Hour,
+	}, nil)
+	job.now = func() time.Time { return now }
+	return job
+}
+
+func event(id, device, category, eventType, platform string, ts time.Time, amount float64) warehouse.EventRow {
+	return warehouse.EventRow{
+		ID: id, AppID: "app", DeviceID: device, TimestampMS: ts.UnixMilli(),
+		EventCategory: category, EventType: eventType, Platform: platform, AmountUSD: amount,
+	}
+}
+
+func TestJob_Run(t *testing.T) {
+	ctx := context.Background()
+	lake := newTestLake(t, "")
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	lake.putEvents("events/app_id=app/year=2026/month=03/day=10/hour=09/events_a.parquet", []warehouse.EventRow{
+		event("1", "d1", "screen", "screen_view", "PLATFORM_IOS", at(9, 5), 0),
+		event("2", "d2", "screen", "screen_view", "PLATFORM_ANDROID", at(9, 10), 0),
+		event("3", "d1", "commerce", "purchase_complete", "PLATFORM_IOS", at(9, 20), 4.5),
+	})
+	lake.putEvents("events/app_id=app/year=2026/month=03/day=10/hour=23/events_b.parquet", []warehouse.EventRow{
+		event("4", "d1", "screen", "screen_view", "PLATFORM_IOS", at(23, 59), 0),
+	})
+	lake.putEvents("events/app_id=app/year=2026/month=03/day=11/hour=00/events_c.parquet", []warehouse.EventRow{
+		event("5", "d3", "screen", "screen_view", "PLATFORM_IOS", at(24, 10), 0),
+	})
+
+	// At 00:45 on the 11th, hours up to 00:00 have settled: the 10th is
+	// complete, the 11th is not.
+	if err := lake.newJob(at(24, 45)).Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	hourly, err := lake.store.Read(ctx, "app", domain.GranularityHour, at(9, 0))
+	if err != nil || hourly == nil {
+		t.Fatalf("Read(09:00) = %v, %v", hourly, err)
+	}
+	if total := hourly.Groups[domain.Key{}]; total.Events != 3 || total.RevenueUSD != 4.5 || total.Devices.Estimate() != 2 {
+		t.Errorf("09:00 total = %+v, want 3 events, 4.5 USD, 2 devices", total)
+	}
+	if ios := hourly.Groups[domain.Key{Platform: "PLATFORM_IOS"}]; ios.Events != 2 {
+		t.Errorf("09:00 iOS events = %d, want 2", ios.Events)
+	}
+	if r, _ := lake.store.Read(ctx, "app", domain.GranularityHour, at(10, 0)); r != nil {
+		t.Error("hour without events has a rollup")
+	}
+	if r, _ := lake.store.Read(ctx, "app", domain.GranularityHour, at(24, 0)); r != nil {
+		t.Error("unsettled hour was rolled up")
+	}
+
+	daily, err := lake.store.Read(ctx, "app", domain.GranularityDay, day)
+	if err != nil || daily == nil {
+		t.Fatalf("Read(daily) = %v, %v", daily, err)
+	}
+	if total := daily.Groups[domain.Key{}]; total.Events != 4 || total.Devices.Estimate() != 2 {
+		t.Errorf("daily total = %d events of %d devices, want 4 of 2", total.Events, total.Devices.Estimate())
+	}
+
+	state, ok, err := lake.store.State(ctx, "app")
+	if err != nil || !ok {
+		t.Fatalf("State() = %v, %v", ok, err)
+	}
+	if !state.HourlyUntil.Equal(at(24, 0)) || !state.DailyUntil.Equal(at(24, 0)) {
+		t.Errorf("state = %+v, want both at the 11th", state)
+	}
+
+	// The next run only rolls up the newly settled hour.
+	if err := lake.newJob(at(25, 45)).Run(ctx); err != nil {
+		t.Fatalf("second Run() error = %v", err)
+	}
+	next, err := lake.store.Read(ctx, "app", domain.GranularityHour, at(24, 0))
+	if err != nil || next == nil || next.Groups[domain.Key{}].Events != 1 {
+		t.Fatalf("Read(00:00 on the 11th) = %v, %v", next, err)
+	}
+	if state, _, _ := lake.store.State(ctx, "app"); !state.HourlyUntil.Equal(at(25, 0)) || !state.DailyUntil.Equal(at(24, 0)) {
+		t.Errorf("state after second run = %+v", state)
+	}
+}
+
+func TestJob_RunDailyPartitions(t *testing.T) {
+	ctx := context.Background()
+	lake := newTestLake(t, "app_id/date")
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	lake.putEvents("events/app_id=app/year=2026/month=03/day=10/events_a.parquet", []warehouse.EventRow{
+		event("1", "d1", "screen", "screen_view", "web", day.Add(2*time.Hour), 0),
+		event("2", "d2", "screen", "screen_view", "web", day.Add(14*time.Hour), 0),
+	})
+
+	if err := lake.newJob(day.Add(25 * time.Hour)).Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	for _, hour := range []int{2, 14} {
+		r, err := lake.store.Read(ctx, "app", domain.GranularityHour, day.Add(time.Duration(hour)*time.Hour))
+		if err != nil || r == nil || r.Groups[domain.Key{}].Events != 1 {
+			t.Errorf("Read(%02d:00) = %v, %v, want one event", hour, r, err)
+		}
+	}
+	if r, err := lake.store.Read(ctx, "app", domain.GranularityDay, day); err != nil || r == nil || r.Groups[domain.Key{}].Events != 2 {
+		t.Errorf("Read(daily) = %v, %v, want two events", r, err)
+	}
+}
+
+func TestRollupStore_Key(t *testing.T) {
+	store := NewRollupStore(nil, warehouse.S3Config{}, "/rollups/")
+	start := time.Date(2026, 3, 5, 7, 0, 0, 0, time.UTC)
+
+	if got, want := store.Key("app", domain.GranularityHour, start), "rollups/hourly/app_id=app/year=2026/month=03/day=05/hour=07/rollup.parquet"; got != want {
+		t.Errorf("hourly Key() = %q, want %q", got, want)
+	}
+	if got, want := store.Key("app", domain.GranularityDay, start), "rollups/daily/app_id=app/year=2026/month=03/day=05/rollup.parquet"; got != want {
+		t.Errorf("daily Key() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/SebastienMelki/causality/internal/rollup/internal/domain"
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+// RollupStore reads and writes rollup Parquet files and per-app progress
+// in the event bucket, under prefix:
+//
+//	{prefix}/hourly/app_id={app}/year={y}/month={m}/day={d}/hour={h}/rollup.parquet
+//	{prefix}/daily/app_id={app}/year={y}/month={m}/day={d}/rollup.parquet
+//	{prefix}/_state/app_id={app}.json
+//
+// Each window has one file, overwritten when the window is rolled up again.
+type RollupStore struct {
+	s3Client warehouse.ObjectAPI
+	s3Config warehouse.S3Config
+	prefix   string
+}
+
+// NewRollupStore creates a store for rollups under prefix in the bucket
+// described by s3Config.
+func NewRollupStore(s3Client warehouse.ObjectAPI, s3Config warehouse.S3Config, prefix string) *RollupStore {
+	return &RollupStore{
+		s3Client: s3Client,
+		s3Config: s3Config,
+		prefix:   strings.Trim(prefix, "/"),
+	}
+}
+
+// Key returns the object key of a rollup window.
+func (s *RollupStore) Key(appID, granularity string, start time.Time) string {
+	start = start.UTC()
+	date := fmt.Sprintf("year=%d/month=%02d/day=%02d/", start.Year(), int(start.Month()), start.Day())
+	if granularity == domain.GranularityDay {
+		return s.prefix + "/daily/app_id=" + appID + "/" + date + "rollup.parquet"
+	}
+	return s.prefix + "/hourly/app_id=" + appID + "/" + date + fmt.Sprintf("hour=%02d/", start.Hour()) + "rollup.parquet"
+}
+
+// Read returns a stored rollup, or nil if the window has none.
+func (s *RollupStore) Read(ctx context.Context, appID, granularity string, start time.Time) (*domain.Rollup, error) {
+	key := s.Key(appID, granularity, start)
+	data, err := s.get(ctx, key)
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	reader := parquet.NewGenericReader[domain.Row](bytes.NewReader(data))
+	defer reader.Close()
+
+	rows := make([]domain.Row, reader.NumRows())
+	if n, err := reader.Read(rows); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("read rollup %s: %w", key, err)
+	} else if n != len(rows) {
+		return nil, fmt.Errorf("read rollup %s: %d of %d rows", key, n, len(rows))
+	}
+
+	r, err := domain.FromRows(appID, granularity, start, rows)
+	if err != nil {
+		return nil, fmt.Errorf("read rollup %s: %w", key, err)
+	}
+	return r, nil
+}
+
+// Write stores a rollup, replacing the window's previous file.
+func (s *RollupStore) Write(ctx context.Context, r *domain.Rollup) error {
+	rows, err := r.Rows()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	writer := parquet.NewGenericWriter[domain.Row](&buf,
+		parquet.Compression(&parquet.Snappy),
+		parquet.CreatedBy("causality-rollup", "1.0.0", ""),
+	)
+	if _, err := writer.Write(rows); err != nil {
+		return fmt.Errorf("write rollup rows: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("close rollup writer: %w", err)
+	}
+
+	return s.put(ctx, s.Key(r.AppID, r.Granularity, r.Start), r.AppID, "application/x-parquet", buf.Bytes())
+}
+
+// State returns an app's rollup progress, and false if it has none.
+func (s *RollupStore) State(ctx context.Context, appID string) (domain.State, bool, error) {
+	key := s.stateKey(appID)
+	data, err := s.get(ctx, key)
+	if err != nil || data == nil {
+		return domain.State{}, false, err
+	}
+
+	var state domain.State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return domain.State{}, false, fmt.Errorf("decode rollup state %s: %w", key, err)
+	}
+	return state, true, nil
+}
+
+// SaveState stores an app's rollup progress.
+func (s *RollupStore) SaveState(ctx context.Context, appID string, state domain.State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return s.put(ctx, s.stateKey(appID), appID, "application/json", data)
+}
+
+// stateKey returns the object key of an app's progress.
+func (s *RollupStore) stateKey(appID string) string {
+	return s.prefix + "/_state/app_id=" + appID + ".json"
+}
+
+// get returns the contents of an object, or nil if it does not exist.
+func (s *RollupStore) get(ctx context.Context, key string) ([]byte, error) {
+	result, err := s.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.s3Config.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if warehouse.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get object %s: %w", key, err)
+	}
+	defer result.Body.Close()
+
+	data, err := io.ReadAll(result.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read object %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// put stores an object with the encryption and tags of the app's events.
+func (s *RollupStore) put(ctx context.Context, key, appID, contentType string, data []byte) error {
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(s.s3Config.Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+	}
+	s.s3Config.ApplyObjectOptions(input, appID)
+
+	if _, err := s.s3Client.PutObject(ctx, input); err != nil {
+		return fmt.Errorf("put object %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/SebastienMelki/causality/internal/rollup/internal/domain"
+)
+
+// errMissingColumn is returned for Parquet files without the columns every
+// event row has.
+var errMissingColumn = errors.New("parquet file has no device_id/timestamp_ms/event_category/event_type column")
+
+// aggregateEvents adds the rows of a Parquet file with a timestamp in
+// [from, to) to the hourly rollup of their hour in hours, creating it if
+// needed. Only the columns rolled up are decoded; files written before the
+// platform or amount_usd columns existed count as unknown platform and no
+// revenue.
+func aggregateEvents(data []byte, appID string, from, to time.Time, hours map[time.Time]*domain.Rollup) error {
+	pf, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	schema := pf.Schema()
+	required := make([]int, 0, 4)
+	for _, name := range []string{"device_id", "timestamp_ms", "event_category", "event_type"} {
+		col, ok := schema.Lookup(name)
+		if !ok {
+			return errMissingColumn
+		}
+		required = append(required, col.ColumnIndex)
+	}
+	platformCol, hasPlatform := schema.Lookup("platform")
+	amountCol, hasAmount := schema.Lookup("amount_usd")
+	fromMS, toMS := from.UnixMilli(), to.UnixMilli()
+
+	for _, rg := range pf.RowGroups() {
+		chunks := rg.ColumnChunks()
+		devices, err := readStrings(chunks[required[0]])
+		if err != nil {
+			return err
+		}
+		timestamps, err := readInt64s(chunks[required[1]])
+		if err != nil {
+			return err
+		}
+		categories, err := readStrings(chunks[required[2]])
+		if err != nil {
+			return err
+		}
+		types, err := readStrings(chunks[required[3]])
+		if err != nil {
+			return err
+		}
+		n := len(types)
+		if len(devices) != n || len(timestamps) != n || len(categories) != n {
+			return fmt.Errorf("column length mismatch in row group of %d rows", rg.NumRows())
+		}
+
+		platforms := make([]string, n)
+		if hasPlatform {
+			if platforms, err = readStrings(chunks[platformCol.ColumnIndex]); err != nil {
+				return err
+			}
+		}
+		amounts := make([]float64, n)
+		if hasAmount {
+			if amounts, err = readFloat64s(chunks[amountCol.ColumnIndex]); err != nil {
+				return err
+			}
+		}
+		if len(platforms) != n || len(amounts) != n {
+			return fmt.Errorf("column length mismatch in row group of %d rows", rg.NumRows())
+		}
+
+		for i := range types {
+			if timestamps[i] < fromMS || timestamps[i] >= toMS {
+				continue
+			}
+			hour := time.UnixMilli(timestamps[i]).UTC().Truncate(time.Hour)
+			r, ok := hours[hour]
+			if !ok {
+				r = domain.NewRollup(appID, domain.GranularityHour, hour)
+				hours[hour] = r
+			}
+			r.Add(categories[i], types[i], platforms[i], devices[i], amounts[i])
+		}
+	}
+
+	return nil
+}
+
+// readStrings decodes every value of a string column chunk. Nulls are
+// empty strings.
+func readStrings(chunk parquet.ColumnChunk) ([]string, error) {
+	var out []string
+	err := readValues(chunk, func(v parquet.Value) {
+		if v.IsNull() {
+			out = append(out, "")
+			return
+		}
+		out = append(out, v.String())
+	})
+	return out, err
+}
+
+// readInt64s decodes every value of an int64 column chunk.
+func readInt64s(chunk parquet.ColumnChunk) ([]int64, error) {
+	var out []int64
+	err := readValues(chunk, func(v parquet.Value) {
+		out = append(out, v.Int64())
+	})
+	return out, err
+}
+
+// readFloat64s decodes every value of a double column chunk. Nulls are 0.
+func readFloat64s(chunk parquet.ColumnChunk) ([]float64, error) {
+	var out []float64
+	err := readValues(chunk, func(v parquet.Value) {
+		if v.IsNull() {
+			out = append(out, 0)
+			return
+		}
+		out = append(out, v.Double())
+	})
+	return out, err
+}
+
+// readValues calls fn with every value of a column chunk. Values are only
+// valid during the call.
+func readValues(chunk parquet.ColumnChunk, fn func(parquet.Value)) error {
+	pages := chunk.Pages()
+	defer pages.Close()
+
+	buf := make([]parquet.Value, 1024)
+	for {
+		page, err := pages.ReadPage()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		values := page.Values()
+		for {
+			n, err := values.ReadValues(buf)
+			for _, v := range buf[:n] {
+				fn(v)
+			}
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				parquet.Release(page)
+				return err
+			}
+		}
+		parquet.Release(page)
+	}
+}
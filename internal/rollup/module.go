@@ -0,0 +1,113 @@
+package rollup
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/rollup/internal/service"
+	"github.com/SebastienMelki/causality/internal/schedule"
+	"github.com/SebastienMelki/causality/internal/warehouse"
+)
+
+// Config holds configuration for the rollup module.
+type Config struct {
+	// Enabled controls whether the rollup job runs.
+	Enabled bool `env:"ROLLUP_ENABLED" envDefault:"false"`
+
+	// Cron is the cron expression the job runs on (e.g. "30 * * * *" or "@hourly").
+	Cron string `env:"ROLLUP_CRON" envDefault:"30 * * * *"`
+
+	// Prefix is the key prefix rollups are written under, in the event
+	// bucket. It must not be inside the event lake prefix.
+	Prefix string `env:"ROLLUP_PREFIX" envDefault:"rollups"`
+
+	// SettleDelay is how long after a partition window ends before it is
+	// rolled up. Events arriving later are not counted.
+	SettleDelay time.Duration `env:"ROLLUP_SETTLE_DELAY" envDefault:"30m"`
+
+	// Backfill is how far back the rollups of a new app start.
+	Backfill time.Duration `env:"ROLLUP_BACKFILL" envDefault:"48h"`
+}
+
+// Module is the rollup module facade. It wires the warehouse reader, rollup
+// store, rollup job, and cron scheduler.
+type Module struct {
+	job       *service.Job
+	scheduler *schedule.Scheduler
+	config    Config
+	logger    *slog.Logger
+}
+
+// New creates a new rollup module.
+//
+// Parameters:
+//   - s3Client: the object API (AWS S3 client or FSStore) used to read
+//     warehouse partitions and write rollups
+//   - s3Config: S3 configuration of the event lake (bucket, prefix,
+//     encryption and tags, also applied to rollups)
+//   - deltaLog: Delta Lake transaction log; when non-nil, files removed by
+//     compaction but not yet vacuumed are not counted
+//   - cfg: rollup module configuration
+//   - logger: structured logger
+//
+// It fails if the cron expression is invalid.
+func New(
+	s3Client warehouse.ObjectAPI,
+	s3Config warehouse.S3Config,
+	deltaLog *warehouse.DeltaLog,
+	cfg Config,
+	logger *slog.Logger,
+) (*Module, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	job := service.NewJob(
+		warehouse.NewPartitionReader(s3Client, s3Config, deltaLog),
+		service.NewRollupStore(s3Client, s3Config, cfg.Prefix),
+		service.JobConfig{
+			SettleDelay: cfg.SettleDelay,
+			Backfill:    cfg.Backfill,
+		},
+		logger,
+	)
+
+	scheduler, err := schedule.New("rollup", cfg.Cron, job.Run, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Module{
+		job:       job,
+		scheduler: scheduler,
+		config:    cfg,
+		logger:    logger.With("component", "rollup-module"),
+	}, nil
+}
+
+// Start begins the scheduled rollup job. If the job is disabled via config,
+// this is a no-op.
+func (m *Module) Start(ctx context.Context) {
+	if !m.config.Enabled {
+		m.logger.Info("aggregate rollups disabled, skipping start")
+		return
+	}
+
+	m.logger.Info("starting rollup module",
+		"cron", m.config.Cron,
+		"prefix", m.config.Prefix,
+		"settle_delay", m.config.SettleDelay,
+	)
+	m.scheduler.Start(ctx)
+}
+
+// Stop stops the rollup scheduler.
+func (m *Module) Stop() {
+	m.scheduler.Stop()
+}
+
+// RunNow runs the rollup job immediately.
+func (m *Module) RunNow(ctx context.Context) error {
+	return m.job.Run(ctx)
+}
@@ -0,0 +1,46 @@
+// Package rollup provides the aggregate rollup job. On a cron schedule it
+// reads the event lake's settled partitions once and writes hourly rollups
+// (event counts by event type and platform, purchase revenue in US dollars
+// and HyperLogLog sketches of device IDs) as Parquet files under the rollup
+// prefix, then merges the hourly rollups of each completed day into a daily
+// rollup, so dashboards query kilobytes of aggregates instead of raw
+// events. Per-app progress is stored next to the rollups, so each window is
+// rolled up once.
+package rollup
+
+import (
+	"context"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/rollup/internal/domain"
+)
+
+// Row is one aggregate of a rollup Parquet file.
+type Row = domain.Row
+
+// Rollup holds the aggregates of one app over one hour or day.
+type Rollup = domain.Rollup
+
+// State is the rollup progress of one app.
+type State = domain.State
+
+// Rollup granularities.
+const (
+	GranularityHour = domain.GranularityHour
+	GranularityDay  = domain.GranularityDay
+)
+
+// Store defines the port for rollup persistence.
+type Store interface {
+	// Read returns a stored rollup, or nil if the window has none.
+	Read(ctx context.Context, appID, granularity string, start time.Time) (*Rollup, error)
+
+	// Write stores a rollup, replacing the window's previous one.
+	Write(ctx context.Context, r *Rollup) error
+
+	// State returns an app's rollup progress, and false if it has none.
+	State(ctx context.Context, appID string) (State, bool, error)
+
+	// SaveState stores an app's rollup progress.
+	SaveState(ctx context.Context, appID string, state State) error
+}
@@ -0,0 +1,114 @@
+// Package schedule runs batch jobs on cron expressions. All cron settings
+// accept the same syntax: standard 5-field expressions and descriptors such
+// as @hourly.
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+var parser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Parse parses a cron expression.
+func Parse(spec string) (cron.Schedule, error) {
+	return parser.Parse(spec)
+}
+
+// Scheduler runs a job on a cron expression.
+type Scheduler struct {
+	name   string
+	run    func(context.Context) error
+	spec   string
+	cron   cron.Schedule
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+	running bool
+}
+
+// New creates a scheduler calling run on the cron expression spec. name
+// identifies the job in errors and logs, e.g. "rollup".
+func New(name, spec string, run func(context.Context) error, logger *slog.Logger) (*Scheduler, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	schedule, err := Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s cron %q: %w", name, spec, err)
+	}
+
+	return &Scheduler{
+		name:   name,
+		run:    run,
+		spec:   spec,
+		cron:   schedule,
+		logger: logger.With("component", strings.ReplaceAll(name, " ", "-")+"-scheduler"),
+	}, nil
+}
+
+// Start begins the scheduling loop in a background goroutine.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		s.logger.Warn("scheduler already running")
+		return
+	}
+
+	s.stopCh = make(chan struct{})
+	s.doneCh = make(chan struct{})
+	s.running = true
+
+	go s.loop(ctx, s.stopCh, s.doneCh)
+
+	s.logger.Info(s.name+" scheduler started", "cron", s.spec)
+}
+
+// Stop signals the scheduler to stop and waits for the current run to finish.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+
+	close(s.stopCh)
+	<-s.doneCh
+	s.running = false
+	s.logger.Info(s.name + " scheduler stopped")
+}
+
+// loop sleeps until each cron fire time and runs the job.
+func (s *Scheduler) loop(ctx context.Context, stopCh <-chan struct{}, doneCh chan<- struct{}) {
+	defer close(doneCh)
+
+	for {
+		timer := time.NewTimer(time.Until(s.cron.Next(time.Now())))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-stopCh:
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.logger.Info("scheduled " + s.name + " triggered")
+			if err := s.run(ctx); err != nil {
+				s.logger.Error("scheduled "+s.name+" failed", "error", err)
+			}
+		}
+	}
+}
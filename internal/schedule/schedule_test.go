@@ -0,0 +1,57 @@
+package schedule
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	for _, spec := range []string{"0 * * * *", "15 3 * * 1-5", "@hourly", "@daily"} {
+		if _, err := Parse(spec); err != nil {
+			t.Errorf("Parse(%q) error = %v", spec, err)
+		}
+	}
+	// Seconds are not accepted
+	for _, spec := range []string{"", "0 0 * * * *", "not a cron"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) error = nil, want error", spec)
+		}
+	}
+
+	s, err := Parse("30 2 * * *")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	from := time.Date(2026, 3, 10, 14, 0, 0, 0, time.UTC)
+	if got, want := s.Next(from), time.Date(2026, 3, 11, 2, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next() = %v, want %v", got, want)
+	}
+}
+
+func TestNew_InvalidCron(t *testing.T) {
+	if _, err := New("rollup", "every hour", func(context.Context) error { return nil }, nil); err == nil {
+		t.Error("New() error = nil, want error")
+	}
+}
+
+func TestScheduler_StartStop(t *testing.T) {
+	s, err := New("rollup", "@hourly", func(context.Context) error { return nil }, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// Stop before Start is a no-op
+	s.Stop()
+
+	s.Start(context.Background())
+	s.Start(context.Background())
+	s.Stop()
+	s.Stop()
+
+	// A cancelled context ends the loop; Stop still returns
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	cancel()
+	s.Stop()
+}
@@ -0,0 +1,162 @@
+// Package domain defines the minimum SDK version policies and the version
+// ordering they are enforced with.
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Action is what the gateway does with requests from SDKs older than a
+// policy's minimum version.
+type Action string
+
+// Policy actions.
+const (
+	// ActionReject rejects the request with 426 Upgrade Required.
+	ActionReject Action = "reject"
+
+	// ActionFlag accepts the request, flagging the SDK as outdated in the
+	// response so it can prompt an upgrade.
+	ActionFlag Action = "flag"
+)
+
+// maxMessageLen bounds the message returned to outdated SDKs.
+const maxMessageLen = 256
+
+// ErrInvalidPolicy is returned when a policy fails validation.
+var ErrInvalidPolicy = errors.New("invalid SDK version policy")
+
+// ErrPolicyNotFound is returned when an app has no policy for an SDK.
+var ErrPolicyNotFound = errors.New("SDK version policy not found")
+
+// Policy is the minimum version of one SDK for one app. SDKs are identified
+// by the name of their handshake (e.g. "causality-mobile").
+type Policy struct {
+	AppID      string    `json:"app_id"`
+	SDKName    string    `json:"sdk_name"`
+	MinVersion string    `json:"min_version"`
+	Action     Action    `json:"action"`
+	Message    string    `json:"message,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Validate checks the policy's minimum version, action and message. An
+// empty action defaults to ActionReject.
+func (p *Policy) Validate() error {
+	if p.AppID == "" || p.SDKName == "" {
+		return fmt.Errorf("%w: app_id and sdk_name are required", ErrInvalidPolicy)
+	}
+	if _, ok := ParseVersion(p.MinVersion); !ok {
+		return fmt.Errorf("%w: min_version %q must be a version such as 1.4.0", ErrInvalidPolicy, p.MinVersion)
+	}
+	switch p.Action {
+	case "":
+		p.Action = ActionReject
+	case ActionReject, ActionFlag:
+	default:
+		return fmt.Errorf("%w: action must be reject or flag", ErrInvalidPolicy)
+	}
+	if len(p.Message) > maxMessageLen {
+		return fmt.Errorf("%w: message must be at most %d bytes", ErrInvalidPolicy, maxMessageLen)
+	}
+	return nil
+}
+
+// Check returns the verdict of the policy on an SDK version. Versions that
+// cannot be parsed are never outdated, since their age is unknown.
+func (p Policy) Check(version string) Verdict {
+	v, ok := ParseVersion(version)
+	if !ok {
+		return Verdict{}
+	}
+	minVersion, ok := ParseVersion(p.MinVersion)
+	if !ok || v.Compare(minVersion) >= 0 {
+		return Verdict{}
+	}
+	return Verdict{
+		Outdated:   true,
+		Action:     p.Action,
+		MinVersion: p.MinVersion,
+		Message:    p.Message,
+	}
+}
+
+// Verdict is the outcome of checking a request's SDK against its app's
+// policy. The zero Verdict allows the request.
+type Verdict struct {
+	// Outdated reports whether the SDK is older than the minimum version.
+	Outdated   bool
+	Action     Action
+	MinVersion string
+	Message    string
+}
+
+// Rejected reports whether the request must be rejected.
+func (v Verdict) Rejected() bool {
+	return v.Outdated && v.Action == ActionReject
+}
+
+// Version is a parsed SDK version: dot-separated numeric components, with an
+// optional "-" pre-release suffix. "+" build metadata is ignored.
+type Version struct {
+	Parts      []int
+	PreRelease string
+}
+
+// ParseVersion parses an SDK version such as "1.4.0", "v2.1" or
+// "0.3.0-rc.1". It reports false for anything else.
+func ParseVersion(s string) (Version, bool) {
+	s = strings.TrimPrefix(s, "v")
+	s, _, _ = strings.Cut(s, "+")
+	core, pre, _ := strings.Cut(s, "-")
+	if core == "" {
+		return Version{}, false
+	}
+
+	var v Version
+	for _, part := range strings.Split(core, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, false
+		}
+		v.Parts = append(v.Parts, n)
+	}
+	v.PreRelease = pre
+	return v, true
+}
+
+// Compare returns -1, 0 or +1 as v is older than, equal to or newer than
+// other. Missing components count as zero, so 1.4 equals 1.4.0, and a
+// pre-release is older than its release; pre-releases of the same version
+// are compared as strings.
+func (v Version) Compare(other Version) int {
+	for i := 0; i < max(len(v.Parts), len(other.Parts)); i++ {
+		a, b := 0, 0
+		if i < len(v.Parts) {
+			a = v.Parts[i]
+		}
+		if i < len(other.Parts) {
+			b = other.Parts[i]
+		}
+		if a != b {
+			if a < b {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case v.PreRelease == other.PreRelease:
+		return 0
+	case v.PreRelease == "":
+		return 1
+	case other.PreRelease == "":
+		return -1
+	}
+	return strings.Compare(v.PreRelease, other.PreRelease)
+}
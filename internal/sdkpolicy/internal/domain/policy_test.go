@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVersionCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.4.0", "1.4.0", 0},
+		{"1.4", "1.4.0", 0},
+		{"v1.4.0", "1.4.0", 0},
+		{"1.4.0+build.7", "1.4.0", 0},
+		{"1.3.9", "1.4.0", -1},
+		{"1.10.0", "1.9.2", 1},
+		{"2.0.0", "1.99.99", 1},
+		{"1.4.0-rc.1", "1.4.0", -1},
+		{"1.4.0-rc.1", "1.4.0-rc.2", -1},
+		{"1.4.0-rc.1", "1.3.9", 1},
+	}
+	for _, tt := range tests {
+		a, ok := ParseVersion(tt.a)
+		if !ok {
+			t.Fatalf("ParseVersion(%q) failed", tt.a)
+		}
+		b, ok := ParseVersion(tt.b)
+		if !ok {
+			t.Fatalf("ParseVersion(%q) failed", tt.b)
+		}
+		if got := a.Compare(b); got != tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseVersion_Invalid(t *testing.T) {
+	for _, s := range []string{"", "latest", "1..0", "1.x", "-rc.1", "1.-2"} {
+		if _, ok := ParseVersion(s); ok {
+			t.Errorf("ParseVersion(%q) succeeded, want failure", s)
+		}
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	p := Policy{AppID: "app-1", SDKName: "causality-mobile", MinVersion: "1.4.0", Action: ActionReject, Message: "please upgrade"}
+
+	v := p.Check("1.3.9")
+	if !v.Outdated || !v.Rejected() || v.MinVersion != "1.4.0" || v.Message != "please upgrade" {
+		t.Errorf("Check(1.3.9) = %+v, want rejected with the minimum version", v)
+	}
+	if v := p.Check("1.4.0"); v.Outdated {
+		t.Errorf("Check(1.4.0) = %+v, want allowed", v)
+	}
+	if v := p.Check("dev"); v.Outdated {
+		t.Errorf("Check(dev) = %+v, want unparseable versions allowed", v)
+	}
+
+	p.Action = ActionFlag
+	if v := p.Check("1.3.9"); !v.Outdated || v.Rejected() {
+		t.Errorf("flag Check(1.3.9) = %+v, want outdated but not rejected", v)
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	p := Policy{AppID: "app-1", SDKName: "causality-go", MinVersion: "0.3.0"}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	if p.Action != ActionReject {
+		t.Errorf("Action = %q, want default %q", p.Action, ActionReject)
+	}
+
+	for _, bad := range []Policy{
+		{AppID: "app-1", SDKName: "causality-go", MinVersion: "latest"},
+		{AppID: "app-1", SDKName: "causality-go", MinVersion: "0.3.0", Action: "block"},
+		{AppID: "app-1", MinVersion: "0.3.0"},
+	} {
+		if err := bad.Validate(); !errors.Is(err, ErrInvalidPolicy) {
+			t.Errorf("Validate(%+v) = %v, want ErrInvalidPolicy", bad, err)
+		}
+	}
+}
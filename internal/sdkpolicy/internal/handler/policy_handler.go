@@ -0,0 +1,185 @@
+// Package handler provides HTTP handlers for the SDK version policy admin
+// API.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/SebastienMelki/causality/internal/sdkpolicy/internal/domain"
+)
+
+// PolicyStore persists SDK version policies.
+type PolicyStore interface {
+	List(ctx context.Context, appID string) ([]domain.Policy, error)
+	Upsert(ctx context.Context, p *domain.Policy) error
+	Delete(ctx context.Context, appID, sdkName string) error
+}
+
+// PolicyRefresher reloads cached policies. It is satisfied by
+// *service.Enforcer.
+type PolicyRefresher interface {
+	Refresh(ctx context.Context) error
+}
+
+// policyRequest is the request body of PUT
+// /api/admin/sdk-policies/{app_id}/{sdk_name}.
+type policyRequest struct {
+	MinVersion string        `json:"min_version"`
+	Action     domain.Action `json:"action"`
+	Message    string        `json:"message"`
+}
+
+// PolicyHandler handles HTTP requests for SDK version policies.
+type PolicyHandler struct {
+	store    PolicyStore
+	enforcer PolicyRefresher
+	logger   *slog.Logger
+}
+
+// NewPolicyHandler creates a new PolicyHandler. Changes are applied to
+// enforcer immediately.
+func NewPolicyHandler(store PolicyStore, enforcer PolicyRefresher, logger *slog.Logger) *PolicyHandler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &PolicyHandler{
+		store:    store,
+		enforcer: enforcer,
+		logger:   logger.With("component", "sdk-policy-handler"),
+	}
+}
+
+// RegisterRoutes mounts the SDK version policy endpoints on the given
+// ServeMux.
+//
+// Endpoints:
+//   - GET    /api/admin/sdk-policies                     - List all policies
+//   - GET    /api/admin/sdk-policies/{app_id}            - List an app's policies
+//   - PUT    /api/admin/sdk-policies/{app_id}/{sdk_name} - Set an SDK's minimum version
+//   - DELETE /api/admin/sdk-policies/{app_id}/{sdk_name} - Remove an SDK's minimum version
+//
+// TODO(phase-3): Protect these endpoints with session auth + RBAC.
+func (h *PolicyHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/admin/sdk-policies", h.handleList)
+	mux.HandleFunc("GET /api/admin/sdk-policies/{app_id}", h.handleList)
+	mux.HandleFunc("PUT /api/admin/sdk-policies/{app_id}/{sdk_name}", h.handlePut)
+	mux.HandleFunc("DELETE /api/admin/sdk-policies/{app_id}/{sdk_name}", h.handleDelete)
+}
+
+// handleList handles GET /api/admin/sdk-policies and GET
+// /api/admin/sdk-policies/{app_id}.
+func (h *PolicyHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	appID := r.PathValue("app_id")
+
+	policies, err := h.store.List(r.Context(), appID)
+	if err != nil {
+		h.logger.Error("failed to list SDK version policies", "app_id", appID, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to list SDK version policies",
+		})
+		return
+	}
+	if policies == nil {
+		policies = []domain.Policy{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"policies": policies,
+		"count":    len(policies),
+	})
+}
+
+// handlePut handles PUT /api/admin/sdk-policies/{app_id}/{sdk_name} -
+// creates or replaces the minimum version of an SDK for an app. Requests
+// from older versions of the SDK are rejected, or only flagged with action
+// "flag".
+func (h *PolicyHandler) handlePut(w http.ResponseWriter, r *http.Request) {
+	var req policyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "invalid request body",
+		})
+		return
+	}
+
+	policy := &domain.Policy{
+		AppID:      r.PathValue("app_id"),
+		SDKName:    r.PathValue("sdk_name"),
+		MinVersion: req.MinVersion,
+		Action:     req.Action,
+		Message:    req.Message,
+	}
+	if err := policy.Validate(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := h.store.Upsert(r.Context(), policy); err != nil {
+		h.logger.Error("failed to save SDK version policy",
+			"app_id", policy.AppID,
+			"sdk_name", policy.SDKName,
+			"error", err,
+		)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to save SDK version policy",
+		})
+		return
+	}
+
+	h.logger.Info("SDK version policy set",
+		"app_id", policy.AppID,
+		"sdk_name", policy.SDKName,
+		"min_version", policy.MinVersion,
+		"action", policy.Action,
+	)
+	h.refresh(r.Context())
+	writeJSON(w, http.StatusOK, policy)
+}
+
+// handleDelete handles DELETE /api/admin/sdk-policies/{app_id}/{sdk_name}.
+func (h *PolicyHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	appID, sdkName := r.PathValue("app_id"), r.PathValue("sdk_name")
+
+	if err := h.store.Delete(r.Context(), appID, sdkName); err != nil {
+		if errors.Is(err, domain.ErrPolicyNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+		h.logger.Error("failed to delete SDK version policy",
+			"app_id", appID,
+			"sdk_name", sdkName,
+			"error", err,
+		)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to delete SDK version policy",
+		})
+		return
+	}
+
+	h.logger.Info("SDK version policy removed", "app_id", appID, "sdk_name", sdkName)
+	h.refresh(r.Context())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// refresh applies a change to this replica's enforcer. Other replicas pick
+// it up on their next reload.
+func (h *PolicyHandler) refresh(ctx context.Context) {
+	if err := h.enforcer.Refresh(ctx); err != nil {
+		h.logger.Warn("failed to reload SDK version policies", "error", err)
+	}
+}
+
+// writeJSON writes a JSON response with the given status code and body.
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,88 @@
+// Package repo provides the PostgreSQL implementation of the SDK version
+// policy Store port.
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/SebastienMelki/causality/internal/sdkpolicy/internal/domain"
+)
+
+// PolicyRepository implements the Store interface using PostgreSQL.
+type PolicyRepository struct {
+	db *sql.DB
+}
+
+// NewPolicyRepository creates a new PolicyRepository backed by the given
+// database.
+func NewPolicyRepository(db *sql.DB) *PolicyRepository {
+	return &PolicyRepository{db: db}
+}
+
+// List returns every policy, ordered by app and SDK. If appID is not empty,
+// only that app's policies are returned.
+func (r *PolicyRepository) List(ctx context.Context, appID string) ([]domain.Policy, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT app_id, sdk_name, min_version, action, message, updated_at
+		FROM sdk_version_policies
+		WHERE $1 = '' OR app_id = $1
+		ORDER BY app_id, sdk_name
+	`, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query SDK version policies: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var policies []domain.Policy
+	for rows.Next() {
+		var p domain.Policy
+		if err := rows.Scan(&p.AppID, &p.SDKName, &p.MinVersion, &p.Action, &p.Message, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan SDK version policy: %w", err)
+		}
+		policies = append(policies, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate SDK version policies: %w", err)
+	}
+	return policies, nil
+}
+
+// Upsert creates or replaces the policy of an app and SDK, setting its
+// UpdatedAt.
+func (r *PolicyRepository) Upsert(ctx context.Context, p *domain.Policy) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO sdk_version_policies (app_id, sdk_name, min_version, action, message)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (app_id, sdk_name) DO UPDATE
+		SET min_version = EXCLUDED.min_version,
+		    action      = EXCLUDED.action,
+		    message     = EXCLUDED.message,
+		    updated_at  = now()
+		RETURNING updated_at
+	`, p.AppID, p.SDKName, p.MinVersion, p.Action, p.Message).Scan(&p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to upsert SDK version policy: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the policy of an app and SDK. It returns
+// domain.ErrPolicyNotFound if there is none.
+func (r *PolicyRepository) Delete(ctx context.Context, appID, sdkName string) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM sdk_version_policies WHERE app_id = $1 AND sdk_name = $2
+	`, appID, sdkName)
+	if err != nil {
+		return fmt.Errorf("failed to delete SDK version policy: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if n == 0 {
+		return domain.ErrPolicyNotFound
+	}
+	return nil
+}
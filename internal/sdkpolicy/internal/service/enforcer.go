@@ -0,0 +1,125 @@
+// Package service implements the SDK version policy enforcer, which keeps
+// every policy in memory so the gateway checks requests without querying
+// PostgreSQL.
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/sdkpolicy/internal/domain"
+)
+
+// PolicyLister lists the stored policies.
+// This mirrors the sdkpolicy.Store port to avoid import cycles.
+type PolicyLister interface {
+	List(ctx context.Context, appID string) ([]domain.Policy, error)
+}
+
+// policyKey identifies the policy of one app and SDK.
+type policyKey struct {
+	appID   string
+	sdkName string
+}
+
+// Enforcer checks SDK versions against a cache of the stored policies,
+// reloaded every interval so changes made through another replica apply
+// within it. A failed reload keeps the previous policies.
+type Enforcer struct {
+	store    PolicyLister
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu       sync.RWMutex
+	policies map[policyKey]domain.Policy
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewEnforcer creates an enforcer reloading policies every interval.
+func NewEnforcer(store PolicyLister, interval time.Duration, logger *slog.Logger) *Enforcer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	return &Enforcer{
+		store:    store,
+		interval: interval,
+		logger:   logger.With("component", "sdk-policy-enforcer"),
+		policies: make(map[policyKey]domain.Policy),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// Check returns the verdict of an app's policy for an SDK on its version.
+// Apps without a policy for the SDK allow every version.
+func (e *Enforcer) Check(appID, sdkName, version string) domain.Verdict {
+	e.mu.RLock()
+	policy, ok := e.policies[policyKey{appID: appID, sdkName: sdkName}]
+	e.mu.RUnlock()
+	if !ok {
+		return domain.Verdict{}
+	}
+	return policy.Check(version)
+}
+
+// Refresh reloads the policies from the store.
+func (e *Enforcer) Refresh(ctx context.Context) error {
+	list, err := e.store.List(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	policies := make(map[policyKey]domain.Policy, len(list))
+	for _, p := range list {
+		policies[policyKey{appID: p.AppID, sdkName: p.SDKName}] = p
+	}
+
+	e.mu.Lock()
+	e.policies = policies
+	e.mu.Unlock()
+	return nil
+}
+
+// Start loads the policies, then reloads them every interval in a
+// background goroutine until ctx is cancelled or Stop is called.
+func (e *Enforcer) Start(ctx context.Context) {
+	if err := e.Refresh(ctx); err != nil {
+		e.logger.Error("failed to load SDK version policies", "error", err)
+	}
+
+	go func() {
+		defer close(e.doneCh)
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-e.stopCh:
+				return
+			case <-ticker.C:
+				if err := e.Refresh(ctx); err != nil {
+					e.logger.Warn("failed to reload SDK version policies", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop signals the reload loop to stop and waits for it to exit.
+func (e *Enforcer) Stop() {
+	e.stopOnce.Do(func() {
+		close(e.stopCh)
+	})
+	<-e.doneCh
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS sdk_version_policies;
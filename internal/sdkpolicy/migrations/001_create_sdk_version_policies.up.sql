@@ -0,0 +1,10 @@
+-- Per-app minimum SDK versions (gateway)
+CREATE TABLE IF NOT EXISTS sdk_version_policies (
+    app_id      TEXT NOT NULL,
+    sdk_name    TEXT NOT NULL,
+    min_version TEXT NOT NULL,
+    action      TEXT NOT NULL DEFAULT 'reject' CHECK (action IN ('reject', 'flag')),
+    message     TEXT NOT NULL DEFAULT '',
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (app_id, sdk_name)
+);
@@ -0,0 +1,77 @@
+package sdkpolicy
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/db"
+	"github.com/SebastienMelki/causality/internal/sdkpolicy/internal/handler"
+	"github.com/SebastienMelki/causality/internal/sdkpolicy/internal/repo"
+	"github.com/SebastienMelki/causality/internal/sdkpolicy/internal/service"
+	"github.com/SebastienMelki/causality/internal/sdkpolicy/migrations"
+)
+
+// Migrations are the sdkpolicy module's database migrations.
+var Migrations = db.Source{Name: "sdkpolicy", FS: migrations.FS}
+
+// Config holds the SDK version policy module configuration.
+//
+// Environment variable overrides:
+//   - SDK_POLICY_ENABLED:          enforce minimum SDK versions (default: true)
+//   - SDK_POLICY_REFRESH_INTERVAL: how often policies are reloaded from PostgreSQL (default: 30s)
+type Config struct {
+	Enabled         bool          `env:"SDK_POLICY_ENABLED"          envDefault:"true"`
+	RefreshInterval time.Duration `env:"SDK_POLICY_REFRESH_INTERVAL" envDefault:"30s"`
+}
+
+// Module is the SDK version policy module facade. It exposes the check the
+// HTTP gateway runs on every ingestion request, and the admin endpoints.
+type Module struct {
+	enforcer *service.Enforcer
+	handler  *handler.PolicyHandler
+}
+
+// New creates a new SDK version policy Module storing its policies in db.
+func New(db *sql.DB, cfg Config, logger *slog.Logger) *Module {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger = logger.With("module", "sdkpolicy")
+
+	policyRepo := repo.NewPolicyRepository(db)
+	enforcer := service.NewEnforcer(policyRepo, cfg.RefreshInterval, logger)
+
+	return &Module{
+		enforcer: enforcer,
+		handler:  handler.NewPolicyHandler(policyRepo, enforcer, logger),
+	}
+}
+
+// Start loads the policies and begins the background reload loop.
+func (m *Module) Start(ctx context.Context) {
+	m.enforcer.Start(ctx)
+}
+
+// Stop stops the reload loop.
+func (m *Module) Stop() {
+	m.enforcer.Stop()
+}
+
+// Check returns the verdict of an app's policy for an SDK on its version.
+// It never blocks on storage.
+func (m *Module) Check(appID, sdkName, version string) Verdict {
+	return m.enforcer.Check(appID, sdkName, version)
+}
+
+// RegisterRoutes mounts the SDK version policy endpoints onto the given
+// ServeMux:
+//   - GET    /api/admin/sdk-policies                     - List all policies
+//   - GET    /api/admin/sdk-policies/{app_id}            - List an app's policies
+//   - PUT    /api/admin/sdk-policies/{app_id}/{sdk_name} - Set an SDK's minimum version
+//   - DELETE /api/admin/sdk-policies/{app_id}/{sdk_name} - Remove an SDK's minimum version
+func (m *Module) RegisterRoutes(mux *http.ServeMux) {
+	m.handler.RegisterRoutes(mux)
+}
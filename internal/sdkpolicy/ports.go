@@ -0,0 +1,40 @@
+// Package sdkpolicy provides per-app minimum SDK versions. Admins set the
+// minimum version of an SDK for an app, and the gateway rejects requests
+// from older versions of it with a distinct error code the SDK surfaces to
+// the app, or only flags them, so a release with a wire bug can be forced
+// out of the field.
+package sdkpolicy
+
+import (
+	"context"
+
+	"github.com/SebastienMelki/causality/internal/sdkpolicy/internal/domain"
+)
+
+// Policy is the minimum version of one SDK for one app.
+type Policy = domain.Policy
+
+// Action is what the gateway does with requests from outdated SDKs.
+type Action = domain.Action
+
+// Policy actions.
+const (
+	ActionReject = domain.ActionReject
+	ActionFlag   = domain.ActionFlag
+)
+
+// Verdict is the outcome of checking a request's SDK against its app's
+// policy.
+type Verdict = domain.Verdict
+
+// Store defines the port for SDK version policy persistence.
+type Store interface {
+	// List returns every policy, or only an app's if appID is not empty.
+	List(ctx context.Context, appID string) ([]Policy, error)
+
+	// Upsert creates or replaces the policy of an app and SDK.
+	Upsert(ctx context.Context, p *Policy) error
+
+	// Delete removes the policy of an app and SDK.
+	Delete(ctx context.Context, appID, sdkName string) error
+}
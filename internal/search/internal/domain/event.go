@@ -0,0 +1,80 @@
+// Package domain contains the core types for the recent event search index.
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Query limits.
+const (
+	// DefaultLimit is the number of events returned when a query sets none.
+	DefaultLimit = 100
+	// MaxLimit bounds the number of events a query returns.
+	MaxLimit = 1000
+)
+
+// ErrEmptyQuery is returned for a query naming no device, user or event and
+// no time range.
+var ErrEmptyQuery = errors.New("query requires device_id, user_id, event_id or since")
+
+// Event is an indexed event: its identifiers and the envelope as JSON.
+type Event struct {
+	AppID          string          `json:"app_id"`
+	EventID        string          `json:"event_id"`
+	IdempotencyKey string          `json:"idempotency_key,omitempty"`
+	DeviceID       string          `json:"device_id,omitempty"`
+	UserID         string          `json:"user_id,omitempty"`
+	EventCategory  string          `json:"event_category"`
+	EventType      string          `json:"event_type"`
+	Subject        string          `json:"subject"`
+	Timestamp      time.Time       `json:"timestamp"`
+	ReceivedAt     time.Time       `json:"received_at"`
+	Envelope       json.RawMessage `json:"envelope"`
+}
+
+// Query selects indexed events of one app. At least one of DeviceID, UserID,
+// EventID and Since must be set; all set fields must match. A query setting
+// only Since (and optionally the event category and type) scans the app's
+// events in a time range, e.g. to replay them against an anomaly config.
+type Query struct {
+	AppID    string
+	DeviceID string
+	// UserID matches events carrying the user_id, and events of the devices
+	// the user was seen on within the retention window.
+	UserID string
+	// EventID matches the event id or the idempotency key.
+	EventID string
+	// EventCategory and EventType match the warehouse columns of the same
+	// names (e.g. "screen" and "view").
+	EventCategory string
+	EventType     string
+	// Since and Until bound the client timestamp; zero means unbounded.
+	Since time.Time
+	Until time.Time
+	Limit int
+}
+
+// Validate checks the query names something to look up and clamps Limit.
+func (q *Query) Validate() error {
+	if q.DeviceID == "" && q.UserID == "" && q.EventID == "" && q.Since.IsZero() {
+		return ErrEmptyQuery
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+	return nil
+}
+
+// Result is the answer to a query. OldestReceivedAt is when the oldest event
+// still indexed was received: events received before it were pruned, so an
+// empty result only proves an event was not received after it.
+type Result struct {
+	Events           []Event    `json:"events"`
+	Count            int        `json:"count"`
+	OldestReceivedAt *time.Time `json:"oldest_received_at,omitempty"`
+}
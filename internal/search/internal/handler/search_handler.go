@@ -0,0 +1,122 @@
+// Package handler provides HTTP handlers for the event search API.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/search/internal/domain"
+)
+
+// EventSearcher searches the event index.
+type EventSearcher interface {
+	Search(ctx context.Context, q domain.Query) (*domain.Result, error)
+}
+
+// SearchHandler handles HTTP requests for event search.
+type SearchHandler struct {
+	store  EventSearcher
+	logger *slog.Logger
+}
+
+// NewSearchHandler creates a new SearchHandler.
+func NewSearchHandler(store EventSearcher, logger *slog.Logger) *SearchHandler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SearchHandler{
+		store:  store,
+		logger: logger.With("component", "search-handler"),
+	}
+}
+
+// RegisterRoutes mounts the event search endpoint on the given ServeMux.
+//
+// Endpoints:
+//   - GET /api/admin/search/{app_id}/events?device_id=&user_id=&event_id=
+//     &event_category=&event_type=&since=&until=&limit=
+//
+// TODO(phase-3): Protect these endpoints with session auth + RBAC.
+func (h *SearchHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/admin/search/{app_id}/events", h.handleSearch)
+}
+
+// handleSearch handles GET /api/admin/search/{app_id}/events - returns the
+// recent events of a device, user or event id, or of the app in a time range,
+// most recent first. since and until are RFC 3339 times bounding the client
+// timestamp.
+func (h *SearchHandler) handleSearch(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	q := domain.Query{
+		AppID:         r.PathValue("app_id"),
+		DeviceID:      params.Get("device_id"),
+		UserID:        params.Get("user_id"),
+		EventID:       params.Get("event_id"),
+		EventCategory: params.Get("event_category"),
+		EventType:     params.Get("event_type"),
+	}
+
+	var err error
+	if q.Since, err = parseTime(params.Get("since")); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "since must be an RFC 3339 time",
+		})
+		return
+	}
+	if q.Until, err = parseTime(params.Get("until")); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "until must be an RFC 3339 time",
+		})
+		return
+	}
+	if limit := params.Get("limit"); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil || q.Limit < 1 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "limit must be a positive integer",
+			})
+			return
+		}
+	}
+	if err := q.Validate(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "device_id, user_id, event_id or since query parameter is required",
+		})
+		return
+	}
+
+	result, err := h.store.Search(r.Context(), q)
+	if err != nil {
+		h.logger.Error("failed to search events",
+			"app_id", q.AppID,
+			"device_id", q.DeviceID,
+			"user_id", q.UserID,
+			"event_id", q.EventID,
+			"error", err,
+		)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to search events",
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// parseTime parses an optional RFC 3339 time.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// writeJSON writes a JSON response with the given status code and body.
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,254 @@
+// Package repo provides the SQLite implementation of the search Store port.
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	// Register the pure-Go SQLite driver.
+	_ "modernc.org/sqlite"
+
+	"github.com/SebastienMelki/causality/internal/search/internal/domain"
+)
+
+// schema creates the index tables. search_device_users links devices to the
+// users seen on them, so a user lookup also finds the events a device sent
+// before or without carrying the user_id.
+const schema = `
+CREATE TABLE IF NOT EXISTS search_events (
+	app_id          TEXT    NOT NULL,
+	event_id        TEXT    NOT NULL,
+	idempotency_key TEXT    NOT NULL DEFAULT '',
+	device_id       TEXT    NOT NULL DEFAULT '',
+	user_id         TEXT    NOT NULL DEFAULT '',
+	event_category  TEXT    NOT NULL,
+	event_type      TEXT    NOT NULL,
+	subject         TEXT    NOT NULL,
+	timestamp_ms    INTEGER NOT NULL,
+	received_at_ms  INTEGER NOT NULL,
+	envelope        TEXT    NOT NULL,
+	PRIMARY KEY (app_id, event_id)
+);
+CREATE INDEX IF NOT EXISTS idx_search_events_device ON search_events (app_id, device_id, timestamp_ms);
+CREATE INDEX IF NOT EXISTS idx_search_events_user ON search_events (app_id, user_id, timestamp_ms);
+CREATE INDEX IF NOT EXISTS idx_search_events_app_time ON search_events (app_id, timestamp_ms);
+CREATE INDEX IF NOT EXISTS idx_search_events_idempotency_key ON search_events (app_id, idempotency_key);
+CREATE INDEX IF NOT EXISTS idx_search_events_received_at ON search_events (received_at_ms);
+
+CREATE TABLE IF NOT EXISTS search_device_users (
+	app_id          TEXT    NOT NULL,
+	device_id       TEXT    NOT NULL,
+	user_id         TEXT    NOT NULL,
+	last_seen_ms    INTEGER NOT NULL,
+	PRIMARY KEY (app_id, user_id, device_id)
+);
+CREATE INDEX IF NOT EXISTS idx_search_device_users_last_seen ON search_device_users (last_seen_ms);
+`
+
+// Open opens (or creates) the SQLite index at path in WAL mode, so lookups
+// do not block indexing, and creates its tables.
+func Open(ctx context.Context, path string) (*sql.DB, error) {
+	dsn := path + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(NORMAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open search index: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create search index tables: %w", err)
+	}
+	return db, nil
+}
+
+// EventRepository implements the Store interface using SQLite.
+type EventRepository struct {
+	db *sql.DB
+}
+
+// NewEventRepository creates a new EventRepository backed by the given
+// database, opened with Open.
+func NewEventRepository(db *sql.DB) *EventRepository {
+	return &EventRepository{db: db}
+}
+
+// IndexEvents stores events in one transaction. Events already indexed are
+// left unchanged, so redelivered batches are harmless.
+func (r *EventRepository) IndexEvents(ctx context.Context, events []domain.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	eventStmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO search_events (
+			app_id, event_id, idempotency_key, device_id, user_id, event_category, event_type,
+			subject, timestamp_ms, received_at_ms, envelope
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (app_id, event_id) DO NOTHING
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare event insert: %w", err)
+	}
+	defer func() { _ = eventStmt.Close() }()
+
+	userStmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO search_device_users (app_id, device_id, user_id, last_seen_ms)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (app_id, user_id, device_id) DO UPDATE
+		SET last_seen_ms = MAX(last_seen_ms, excluded.last_seen_ms)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare device user upsert: %w", err)
+	}
+	defer func() { _ = userStmt.Close() }()
+
+	for _, e := range events {
+		if _, err := eventStmt.ExecContext(ctx,
+			e.AppID,
+			e.EventID,
+			e.IdempotencyKey,
+			e.DeviceID,
+			e.UserID,
+			e.EventCategory,
+			e.EventType,
+			e.Subject,
+			e.Timestamp.UnixMilli(),
+			e.ReceivedAt.UnixMilli(),
+			string(e.Envelope),
+		); err != nil {
+			return fmt.Errorf("failed to index event %s: %w", e.EventID, err)
+		}
+
+		if e.UserID != "" && e.DeviceID != "" {
+			if _, err := userStmt.ExecContext(ctx, e.AppID, e.DeviceID, e.UserID, e.ReceivedAt.UnixMilli()); err != nil {
+				return fmt.Errorf("failed to link device %s to user %s: %w", e.DeviceID, e.UserID, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit events: %w", err)
+	}
+
+	return nil
+}
+
+// Search returns the events matching q, most recent first.
+func (r *EventRepository) Search(ctx context.Context, q domain.Query) (*domain.Result, error) {
+	var (
+		where = []string{"app_id = ?"}
+		args  = []interface{}{q.AppID}
+	)
+	if q.DeviceID != "" {
+		where = append(where, "device_id = ?")
+		args = append(args, q.DeviceID)
+	}
+	if q.UserID != "" {
+		where = append(where, `(user_id = ? OR device_id IN (
+			SELECT device_id FROM search_device_users WHERE app_id = ? AND user_id = ?
+		))`)
+		args = append(args, q.UserID, q.AppID, q.UserID)
+	}
+	if q.EventID != "" {
+		where = append(where, "(event_id = ? OR idempotency_key = ?)")
+		args = append(args, q.EventID, q.EventID)
+	}
+	if q.EventCategory != "" {
+		where = append(where, "event_category = ?")
+		args = append(args, q.EventCategory)
+	}
+	if q.EventType != "" {
+		where = append(where, "event_type = ?")
+		args = append(args, q.EventType)
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "timestamp_ms >= ?")
+		args = append(args, q.Since.UnixMilli())
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "timestamp_ms < ?")
+		args = append(args, q.Until.UnixMilli())
+	}
+	args = append(args, q.Limit)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT app_id, event_id, idempotency_key, device_id, user_id, event_category, event_type,
+		       subject, timestamp_ms, received_at_ms, envelope
+		FROM search_events
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY timestamp_ms DESC, event_id
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search events: %w", err)
+	}
+	defer rows.Close()
+
+	result := &domain.Result{Events: []domain.Event{}}
+	for rows.Next() {
+		var (
+			e            domain.Event
+			timestampMS  int64
+			receivedAtMS int64
+			envelope     string
+		)
+		if err := rows.Scan(
+			&e.AppID,
+			&e.EventID,
+			&e.IdempotencyKey,
+			&e.DeviceID,
+			&e.UserID,
+			&e.EventCategory,
+			&e.EventType,
+			&e.Subject,
+			&timestampMS,
+			&receivedAtMS,
+			&envelope,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		e.Timestamp = time.UnixMilli(timestampMS).UTC()
+		e.ReceivedAt = time.UnixMilli(receivedAtMS).UTC()
+		e.Envelope = []byte(envelope)
+		result.Events = append(result.Events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate events: %w", err)
+	}
+	result.Count = len(result.Events)
+
+	var oldest sql.NullInt64
+	if err := r.db.QueryRowContext(ctx, `SELECT MIN(received_at_ms) FROM search_events`).Scan(&oldest); err != nil {
+		return nil, fmt.Errorf("failed to get index bounds: %w", err)
+	}
+	if oldest.Valid {
+		at := time.UnixMilli(oldest.Int64).UTC()
+		result.OldestReceivedAt = &at
+	}
+
+	return result, nil
+}
+
+// Prune deletes events received, and device links last seen, before cutoff.
+// It returns the number of events deleted.
+func (r *EventRepository) Prune(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM search_events WHERE received_at_ms < ?`, cutoff.UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune events: %w", err)
+	}
+	deleted, _ := res.RowsAffected()
+
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM search_device_users WHERE last_seen_ms < ?`, cutoff.UnixMilli()); err != nil {
+		return deleted, fmt.Errorf("failed to prune device users: %w", err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,141 @@
+package repo
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/search/internal/domain"
+)
+
+func newTestRepository(t *testing.T) *EventRepository {
+	t.Helper()
+	db, err := Open(context.Background(), filepath.Join(t.TempDir(), "search.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return NewEventRepository(db)
+}
+
+func eventIDs(result *domain.Result) []string {
+	ids := make([]string, len(result.Events))
+	for i, e := range result.Events {
+		ids[i] = e.EventID
+	}
+	return ids
+}
+
+func TestEventRepository_Search(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepository(t)
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	event := func(id, device, user, eventType string, minute int) domain.Event {
+		return domain.Event{
+			AppID:          "app",
+			EventID:        id,
+			IdempotencyKey: "key-" + id,
+			DeviceID:       device,
+			UserID:         user,
+			EventCategory:  "screen",
+			EventType:      eventType,
+			Subject:        "events.app.screen.view",
+			Timestamp:      base.Add(time.Duration(minute) * time.Minute),
+			ReceivedAt:     base.Add(time.Duration(minute) * time.Minute),
+			Envelope:       []byte(`{"id":"` + id + `"}`),
+		}
+	}
+	events := []domain.Event{
+		event("e1", "d1", "", "view", 1),
+		event("e2", "d1", "u1", "login", 2),
+		event("e3", "d2", "", "view", 3),
+		event("e4", "d3", "u1", "login", 4),
+		{AppID: "other", EventID: "e5", DeviceID: "d1", EventCategory: "screen", EventType: "view", Subject: "s", Timestamp: base, ReceivedAt: base, Envelope: []byte(`{}`)},
+	}
+	if err := r.IndexEvents(ctx, events); err != nil {
+		t.Fatalf("IndexEvents() error = %v", err)
+	}
+	// Redelivered events are ignored.
+	if err := r.IndexEvents(ctx, events[:2]); err != nil {
+		t.Fatalf("IndexEvents() redelivery error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query domain.Query
+		want  []string
+	}{
+		{"device", domain.Query{DeviceID: "d1"}, []string{"e2", "e1"}},
+		{"user includes the user's devices", domain.Query{UserID: "u1"}, []string{"e4", "e2", "e1"}},
+		{"event id", domain.Query{EventID: "e3"}, []string{"e3"}},
+		{"idempotency key", domain.Query{EventID: "key-e3"}, []string{"e3"}},
+		{"event type", domain.Query{UserID: "u1", EventType: "view"}, []string{"e1"}},
+		{"time range", domain.Query{DeviceID: "d1", Since: base.Add(90 * time.Second), Until: base.Add(time.Hour)}, []string{"e2"}},
+		{"app time range", domain.Query{Since: base.Add(2 * time.Minute), EventType: "login"}, []string{"e4", "e2"}},
+		{"limit", domain.Query{UserID: "u1", Limit: 1}, []string{"e4"}},
+		{"no match", domain.Query{EventID: "missing"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.query
+			q.AppID = "app"
+			if err := q.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			result, err := r.Search(ctx, q)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if got := eventIDs(result); !reflect.DeepEqual(got, tt.want) || result.Count != len(tt.want) {
+				t.Errorf("Search() = %v (count %d), want %v", got, result.Count, tt.want)
+			}
+			if result.OldestReceivedAt == nil || !result.OldestReceivedAt.Equal(base) {
+				t.Errorf("OldestReceivedAt = %v, want %v", result.OldestReceivedAt, base)
+			}
+		})
+	}
+
+	got, err := r.Search(ctx, domain.Query{AppID: "app", EventID: "e2", Limit: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if e := got.Events[0]; e.UserID != "u1" || string(e.Envelope) != `{"id":"e2"}` || !e.Timestamp.Equal(base.Add(2*time.Minute)) {
+		t.Errorf("Search() event = %+v", e)
+	}
+}
+
+func TestEventRepository_Prune(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepository(t)
+
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	events := []domain.Event{
+		{AppID: "app", EventID: "old", DeviceID: "d1", UserID: "u1", EventType: "login", Timestamp: now.Add(-50 * time.Hour), ReceivedAt: now.Add(-50 * time.Hour), Envelope: []byte(`{}`)},
+		{AppID: "app", EventID: "new", DeviceID: "d2", EventType: "view", Timestamp: now, ReceivedAt: now, Envelope: []byte(`{}`)},
+	}
+	if err := r.IndexEvents(ctx, events); err != nil {
+		t.Fatalf("IndexEvents() error = %v", err)
+	}
+
+	deleted, err := r.Prune(ctx, now.Add(-48*time.Hour))
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Prune() deleted %d events, want 1", deleted)
+	}
+
+	result, err := r.Search(ctx, domain.Query{AppID: "app", UserID: "u1", Limit: 10})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if result.Count != 0 {
+		t.Errorf("Search() after prune = %v, want none", eventIDs(result))
+	}
+	if result.OldestReceivedAt == nil || !result.OldestReceivedAt.Equal(now) {
+		t.Errorf("OldestReceivedAt = %v, want %v", result.OldestReceivedAt, now)
+	}
+}
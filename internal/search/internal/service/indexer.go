@@ -0,0 +1,306 @@
+// Package service implements the search sink: a JetStream consumer that
+// indexes recent events and prunes them once they leave the retention window.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/SebastienMelki/causality/internal/events"
+	"github.com/SebastienMelki/causality/internal/search/internal/domain"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// EventStore defines the persistence interface needed by the indexer.
+// This mirrors the search.Store port to avoid import cycles.
+type EventStore interface {
+	IndexEvents(ctx context.Context, events []domain.Event) error
+	Prune(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// envelopeJSON renders indexed envelopes with proto field names, like the
+// warehouse payload_json column.
+var envelopeJSON = protojson.MarshalOptions{UseProtoNames: true}
+
+// Indexer consumes events from the event stream into the search index. Each
+// fetched batch is indexed in one transaction and only then acked. Events
+// received more than retention ago are pruned every pruneInterval.
+type Indexer struct {
+	js             jetstream.JetStream
+	store          EventStore
+	streamName     string
+	consumerName   string
+	fetchBatchSize int
+	fetchMaxWait   time.Duration
+	retention      time.Duration
+	pruneInterval  time.Duration
+	logger         *slog.Logger
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewIndexer creates a new event indexer for the given durable consumer.
+func NewIndexer(
+	js jetstream.JetStream,
+	store EventStore,
+	streamName string,
+	consumerName string,
+	fetchBatchSize int,
+	fetchMaxWait time.Duration,
+	retention time.Duration,
+	pruneInterval time.Duration,
+	logger *slog.Logger,
+) *Indexer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if fetchBatchSize < 1 {
+		fetchBatchSize = 500
+	}
+	if fetchMaxWait <= 0 {
+		fetchMaxWait = 5 * time.Second
+	}
+	if retention <= 0 {
+		retention = 48 * time.Hour
+	}
+	if pruneInterval <= 0 {
+		pruneInterval = 5 * time.Minute
+	}
+
+	return &Indexer{
+		js:             js,
+		store:          store,
+		streamName:     streamName,
+		consumerName:   consumerName,
+		fetchBatchSize: fetchBatchSize,
+		fetchMaxWait:   fetchMaxWait,
+		retention:      retention,
+		pruneInterval:  pruneInterval,
+		logger:         logger.With("component", "search-indexer"),
+		stopCh:         make(chan struct{}),
+	}
+}
+
+// Start looks up the durable consumer and begins the fetch and prune loops.
+func (i *Indexer) Start(ctx context.Context) error {
+	stream, err := i.js.Stream(ctx, i.streamName)
+	if err != nil {
+		return fmt.Errorf("failed to get stream: %w", err)
+	}
+
+	consumer, err := stream.Consumer(ctx, i.consumerName)
+	if err != nil {
+		return fmt.Errorf("failed to get consumer: %w", err)
+	}
+
+	i.logger.Info("starting search indexer",
+		"stream", i.streamName,
+		"consumer", i.consumerName,
+		"fetch_batch_size", i.fetchBatchSize,
+		"retention", i.retention,
+	)
+
+	i.wg.Add(2)
+	go i.run(ctx, consumer)
+	go i.pruneLoop(ctx)
+	return nil
+}
+
+// Stop signals the loops to stop and waits for the in-flight batch.
+func (i *Indexer) Stop() {
+	i.stopOnce.Do(func() {
+		close(i.stopCh)
+	})
+	i.wg.Wait()
+}
+
+// run is the main fetch loop.
+func (i *Indexer) run(ctx context.Context, consumer jetstream.Consumer) {
+	defer i.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-i.stopCh:
+			return
+		default:
+		}
+
+		msgs, err := consumer.Fetch(i.fetchBatchSize, jetstream.FetchMaxWait(i.fetchMaxWait))
+		if err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) {
+				i.logger.Error("failed to fetch messages", "error", err)
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+					return
+				case <-i.stopCh:
+					return
+				}
+			}
+			continue
+		}
+
+		var batch []jetstream.Msg
+		for msg := range msgs.Messages() {
+			batch = append(batch, msg)
+		}
+		if err := msgs.Error(); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			i.logger.Warn("fetch completed with error", "error", err)
+		}
+
+		i.processBatch(ctx, batch)
+	}
+}
+
+// processBatch indexes a fetched batch and acks on success. Unparseable
+// messages are terminated so they are not redelivered.
+func (i *Indexer) processBatch(ctx context.Context, batch []jetstream.Msg) {
+	if len(batch) == 0 {
+		return
+	}
+
+	indexed := make([]domain.Event, 0, len(batch))
+	counted := make([]jetstream.Msg, 0, len(batch))
+
+	for _, msg := range batch {
+		receivedAt := time.Now()
+		var seq uint64
+		if meta, err := msg.Metadata(); err == nil {
+			receivedAt = meta.Timestamp
+			seq = meta.Sequence.Stream
+		}
+
+		event, err := eventFromMessage(msg.Subject(), msg.Data(), receivedAt, seq)
+		if err != nil {
+			i.logger.Warn("terminating unparseable message",
+				"subject", msg.Subject(),
+				"error", err,
+			)
+			_ = msg.Term()
+			continue
+		}
+
+		indexed = append(indexed, event)
+		counted = append(counted, msg)
+	}
+
+	if err := i.store.IndexEvents(ctx, indexed); err != nil {
+		i.logger.Error("failed to index events, will redeliver",
+			"messages", len(counted),
+			"error", err,
+		)
+		for _, msg := range counted {
+			_ = msg.Nak()
+		}
+		return
+	}
+
+	for _, msg := range counted {
+		if err := msg.Ack(); err != nil {
+			i.logger.Warn("failed to ack message", "subject", msg.Subject(), "error", err)
+		}
+	}
+
+	i.logger.Debug("search batch indexed", "messages", len(counted))
+}
+
+// pruneLoop deletes events that left the retention window.
+func (i *Indexer) pruneLoop(ctx context.Context) {
+	defer i.wg.Done()
+
+	ticker := time.NewTicker(i.pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-i.stopCh:
+			return
+		case <-ticker.C:
+			cutoff := time.Now().Add(-i.retention)
+			deleted, err := i.store.Prune(ctx, cutoff)
+			if err != nil {
+				i.logger.Error("failed to prune search index", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				i.logger.Info("pruned search index", "events", deleted, "cutoff", cutoff)
+			}
+		}
+	}
+}
+
+// eventFromMessage builds the index entry of a serialized EventEnvelope.
+// Envelopes without an id are keyed by their idempotency key, or failing
+// that by their stream sequence.
+func eventFromMessage(subject string, data []byte, receivedAt time.Time, seq uint64) (domain.Event, error) {
+	var event pb.EventEnvelope
+	if err := events.Decode(data, &event); err != nil {
+		return domain.Event{}, fmt.Errorf("unmarshal event: %w", err)
+	}
+	if event.GetAppId() == "" {
+		return domain.Event{}, errors.New("event has no app_id")
+	}
+
+	envelope, err := envelopeJSON.Marshal(&event)
+	if err != nil {
+		return domain.Event{}, fmt.Errorf("marshal event: %w", err)
+	}
+
+	id := event.GetId()
+	if id == "" {
+		id = event.GetIdempotencyKey()
+	}
+	if id == "" {
+		id = fmt.Sprintf("seq-%d", seq)
+	}
+
+	timestamp := receivedAt
+	if ms := event.GetTimestampMs(); ms > 0 {
+		timestamp = time.UnixMilli(ms)
+	}
+
+	category, eventType := events.GetCategoryAndType(&event)
+	return domain.Event{
+		AppID:          event.GetAppId(),
+		EventID:        id,
+		IdempotencyKey: event.GetIdempotencyKey(),
+		DeviceID:       event.GetDeviceId(),
+		UserID:         userIDOf(&event),
+		EventCategory:  category,
+		EventType:      eventType,
+		Subject:        subject,
+		Timestamp:      timestamp.UTC(),
+		ReceivedAt:     receivedAt.UTC(),
+		Envelope:       envelope,
+	}, nil
+}
+
+// userIDOf returns the user_id field of an event's payload, which the user
+// events (login, logout, signup, profile update) carry.
+func userIDOf(event *pb.EventEnvelope) string {
+	m := event.ProtoReflect()
+	fd := m.WhichOneof(m.Descriptor().Oneofs().ByName("payload"))
+	if fd == nil || fd.Message() == nil {
+		return ""
+	}
+	payload := m.Get(fd).Message()
+	userID := payload.Descriptor().Fields().ByName("user_id")
+	if userID == nil || userID.Kind() != protoreflect.StringKind {
+		return ""
+	}
+	return payload.Get(userID).String()
+}
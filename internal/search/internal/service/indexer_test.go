@@ -0,0 +1,89 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+func marshalEvent(t *testing.T, event *pb.EventEnvelope) []byte {
+	t.Helper()
+	data, err := proto.Marshal(event)
+	if err != nil {
+		t.Fatalf("marshal event: %v", err)
+	}
+	return data
+}
+
+func TestEventFromMessage(t *testing.T) {
+	receivedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	sentAt := receivedAt.Add(-time.Minute)
+
+	data := marshalEvent(t, &pb.EventEnvelope{
+		Id:             "evt-1",
+		AppId:          "app",
+		DeviceId:       "d1",
+		IdempotencyKey: "key-1",
+		TimestampMs:    sentAt.UnixMilli(),
+		Payload: &pb.EventEnvelope_UserLogin{
+			UserLogin: &pb.UserLogin{UserId: "u1", Method: "email"},
+		},
+	})
+
+	got, err := eventFromMessage("events.app.user.login", data, receivedAt, 7)
+	if err != nil {
+		t.Fatalf("eventFromMessage() error = %v", err)
+	}
+	if got.AppID != "app" || got.EventID != "evt-1" || got.IdempotencyKey != "key-1" || got.DeviceID != "d1" {
+		t.Errorf("identifiers = %+v", got)
+	}
+	if got.UserID != "u1" {
+		t.Errorf("UserID = %q, want u1", got.UserID)
+	}
+	if got.EventCategory != "user" || got.EventType != "login" {
+		t.Errorf("category, type = %q, %q, want user, login", got.EventCategory, got.EventType)
+	}
+	if got.Subject != "events.app.user.login" || !got.Timestamp.Equal(sentAt) || !got.ReceivedAt.Equal(receivedAt) {
+		t.Errorf("subject, timestamp, received_at = %q, %v, %v", got.Subject, got.Timestamp, got.ReceivedAt)
+	}
+
+	var envelope map[string]interface{}
+	if err := json.Unmarshal(got.Envelope, &envelope); err != nil {
+		t.Fatalf("envelope is not JSON: %v", err)
+	}
+	if envelope["app_id"] != "app" || envelope["user_login"] == nil {
+		t.Errorf("envelope = %s, want proto field names", got.Envelope)
+	}
+}
+
+func TestEventFromMessage_Fallbacks(t *testing.T) {
+	receivedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	got, err := eventFromMessage("events.app.screen.view", marshalEvent(t, &pb.EventEnvelope{
+		AppId:   "app",
+		Payload: &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}},
+	}), receivedAt, 42)
+	if err != nil {
+		t.Fatalf("eventFromMessage() error = %v", err)
+	}
+	if got.EventID != "seq-42" {
+		t.Errorf("EventID = %q, want seq-42", got.EventID)
+	}
+	if got.UserID != "" {
+		t.Errorf("UserID = %q, want none", got.UserID)
+	}
+	if !got.Timestamp.Equal(receivedAt) {
+		t.Errorf("Timestamp = %v, want the receive time", got.Timestamp)
+	}
+
+	if _, err := eventFromMessage("s", marshalEvent(t, &pb.EventEnvelope{DeviceId: "d1"}), receivedAt, 1); err == nil {
+		t.Error("eventFromMessage() without app_id succeeded")
+	}
+	if _, err := eventFromMessage("s", []byte{0xff}, receivedAt, 1); err == nil {
+		t.Error("eventFromMessage() of garbage succeeded")
+	}
+}
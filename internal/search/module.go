@@ -0,0 +1,107 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/SebastienMelki/causality/internal/search/internal/handler"
+	"github.com/SebastienMelki/causality/internal/search/internal/repo"
+	"github.com/SebastienMelki/causality/internal/search/internal/service"
+)
+
+// Config holds the search module configuration.
+type Config struct {
+	// ConsumerName is the durable JetStream consumer used for indexing.
+	ConsumerName string `env:"SEARCH_CONSUMER_NAME" envDefault:"search-sink"`
+
+	// FilterSubject selects which stream subjects are indexed.
+	FilterSubject string `env:"SEARCH_FILTER_SUBJECT" envDefault:"events.>"`
+
+	// DBPath is the SQLite database file holding the index.
+	DBPath string `env:"SEARCH_DB_PATH" envDefault:"search.db"`
+
+	// Retention is how long after receipt events stay searchable.
+	Retention time.Duration `env:"SEARCH_RETENTION" envDefault:"48h"`
+
+	// PruneInterval is how often events past Retention are deleted.
+	PruneInterval time.Duration `env:"SEARCH_PRUNE_INTERVAL" envDefault:"5m"`
+
+	// FetchBatchSize is the number of messages indexed per transaction.
+	FetchBatchSize int `env:"SEARCH_FETCH_BATCH_SIZE" envDefault:"500"`
+
+	// FetchMaxWait bounds how long a fetch waits for a full batch.
+	FetchMaxWait time.Duration `env:"SEARCH_FETCH_MAX_WAIT" envDefault:"5s"`
+}
+
+// Module is the search module facade. It wires the SQLite index, stream
+// indexer, and search API.
+type Module struct {
+	repo    *repo.EventRepository
+	indexer *service.Indexer
+	handler *handler.SearchHandler
+	config  Config
+	logger  *slog.Logger
+}
+
+// Open opens (or creates) the SQLite index at path, for New.
+func Open(ctx context.Context, path string) (*sql.DB, error) {
+	return repo.Open(ctx, path)
+}
+
+// New creates a new search Module over an index opened with Open, consuming
+// from streamName.
+func New(db *sql.DB, js jetstream.JetStream, streamName string, cfg Config, logger *slog.Logger) *Module {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	eventRepo := repo.NewEventRepository(db)
+
+	return &Module{
+		repo: eventRepo,
+		indexer: service.NewIndexer(
+			js,
+			eventRepo,
+			streamName,
+			cfg.ConsumerName,
+			cfg.FetchBatchSize,
+			cfg.FetchMaxWait,
+			cfg.Retention,
+			cfg.PruneInterval,
+			logger,
+		),
+		handler: handler.NewSearchHandler(eventRepo, logger),
+		config:  cfg,
+		logger:  logger.With("component", "search-module"),
+	}
+}
+
+// Start begins indexing and pruning.
+func (m *Module) Start(ctx context.Context) error {
+	return m.indexer.Start(ctx)
+}
+
+// Stop stops indexing and pruning.
+func (m *Module) Stop() {
+	m.indexer.Stop()
+}
+
+// Search returns the indexed events matching q, most recent first.
+func (m *Module) Search(ctx context.Context, q Query) (*Result, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	return m.repo.Search(ctx, q)
+}
+
+// RegisterRoutes mounts the event search endpoint onto the given ServeMux:
+//   - GET /api/admin/search/{app_id}/events?device_id=&user_id=&event_id=&since= -
+//     Recent events of a device, user or event id, or of the app since a time
+func (m *Module) RegisterRoutes(mux *http.ServeMux) {
+	m.handler.RegisterRoutes(mux)
+}
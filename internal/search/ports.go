@@ -0,0 +1,38 @@
+// Package search provides the hot-store event search index. It consumes the
+// JetStream event stream into a SQLite database holding the last
+// SEARCH_RETENTION (24-72 hours) of events, keyed by app, event id,
+// idempotency key, device and user, and serves ad-hoc lookups over them, so
+// support can answer "did we receive event X" without querying S3.
+package search
+
+import (
+	"context"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/search/internal/domain"
+)
+
+// Event is an indexed event: its identifiers and the envelope as JSON.
+type Event = domain.Event
+
+// Query selects indexed events of one app.
+type Query = domain.Query
+
+// Result is the answer to a query.
+type Result = domain.Result
+
+// ErrEmptyQuery is returned for a query naming no device, user or event and
+// no time range.
+var ErrEmptyQuery = domain.ErrEmptyQuery
+
+// Store defines the port for the search index.
+type Store interface {
+	// IndexEvents stores events, ignoring events already indexed.
+	IndexEvents(ctx context.Context, events []Event) error
+
+	// Search returns the events matching a query, most recent first.
+	Search(ctx context.Context, q Query) (*Result, error)
+
+	// Prune deletes events received before cutoff.
+	Prune(ctx context.Context, cutoff time.Time) (int64, error)
+}
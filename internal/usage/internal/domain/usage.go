@@ -0,0 +1,172 @@
+// Package domain contains the core types for per-app usage metering.
+package domain
+
+import (
+	"sync"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/hll"
+)
+
+// Key identifies a single daily usage counter.
+type Key struct {
+	AppID string
+	Day   time.Time
+}
+
+// Counts holds the usage accumulated for a key. IngestedBytes is the encoded
+// size of the accepted events as published to the event stream; the lake
+// stores them compressed in Parquet, so it is not their storage footprint.
+type Counts struct {
+	AcceptedEvents int64
+	IngestedBytes  int64
+}
+
+// DailyUsage is the persisted usage for one app on one UTC day, with
+// IngestedBytes as in Counts.
+type DailyUsage struct {
+	AppID          string    `json:"app_id"`
+	Day            time.Time `json:"day"`
+	AcceptedEvents int64     `json:"accepted_events"`
+	IngestedBytes  int64     `json:"ingested_bytes"`
+	ReportedEvents int64     `json:"reported_events"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// PendingReport is a daily usage row whose accepted events have not all been
+// pushed to the billing provider yet.
+type PendingReport struct {
+	AppID              string
+	Day                time.Time
+	SubscriptionItemID string
+	AcceptedEvents     int64
+	ReportedEvents     int64
+}
+
+// Delta returns the number of accepted events not yet reported.
+func (p PendingReport) Delta() int64 {
+	return p.AcceptedEvents - p.ReportedEvents
+}
+
+// DayOf truncates t to its UTC calendar day.
+func DayOf(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Aggregator accumulates usage counts in memory between flushes.
+// It is safe for concurrent use.
+type Aggregator struct {
+	mu     sync.Mutex
+	counts map[Key]Counts
+}
+
+// NewAggregator creates an empty Aggregator.
+func NewAggregator() *Aggregator {
+	return &Aggregator{counts: make(map[Key]Counts)}
+}
+
+// Add records one accepted event of the given encoded size for appID on the
+// UTC day containing at.
+func (a *Aggregator) Add(appID string, at time.Time, bytes int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := Key{AppID: appID, Day: DayOf(at)}
+	c := a.counts[key]
+	c.AcceptedEvents++
+	c.IngestedBytes += bytes
+	a.counts[key] = c
+}
+
+// Drain returns the accumulated counts and resets the aggregator.
+func (a *Aggregator) Drain() map[Key]Counts {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	counts := a.counts
+	a.counts = make(map[Key]Counts)
+	return counts
+}
+
+// HourOf truncates t to its UTC hour.
+func HourOf(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Hour)
+}
+
+// SketchKey identifies the distinct device and user sketches of one app
+// and UTC hour.
+type SketchKey struct {
+	AppID string
+	Hour  time.Time
+}
+
+// Sketches holds HyperLogLog sketches of the distinct devices and users
+// seen in an app and hour, or merged over a window.
+type Sketches struct {
+	Devices *hll.Sketch
+	Users   *hll.Sketch
+}
+
+// NewSketches returns empty sketches.
+func NewSketches() Sketches {
+	return Sketches{Devices: hll.New(), Users: hll.New()}
+}
+
+// Merge adds the devices and users of other.
+func (s Sketches) Merge(other Sketches) {
+	s.Devices.Merge(other.Devices)
+	s.Users.Merge(other.Users)
+}
+
+// ActiveCounts is the estimated number of distinct devices and users of an
+// app over a window of whole hours.
+type ActiveCounts struct {
+	Window  string    `json:"window"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Devices int64     `json:"devices"`
+	Users   int64     `json:"users"`
+}
+
+// SketchAggregator accumulates distinct device and user sketches in memory
+// between flushes. It is safe for concurrent use.
+type SketchAggregator struct {
+	mu       sync.Mutex
+	sketches map[SketchKey]Sketches
+}
+
+// NewSketchAggregator creates an empty SketchAggregator.
+func NewSketchAggregator() *SketchAggregator {
+	return &SketchAggregator{sketches: make(map[SketchKey]Sketches)}
+}
+
+// Add records a device, and a user if userID is set, as active in appID
+// during the UTC hour containing at.
+func (a *SketchAggregator) Add(appID string, at time.Time, deviceID, userID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := SketchKey{AppID: appID, Hour: HourOf(at)}
+	s, ok := a.sketches[key]
+	if !ok {
+		s = NewSketches()
+		a.sketches[key] = s
+	}
+	if deviceID != "" {
+		s.Devices.Add(deviceID)
+	}
+	if userID != "" {
+		s.Users.Add(userID)
+	}
+}
+
+// Drain returns the accumulated sketches and resets the aggregator.
+func (a *SketchAggregator) Drain() map[SketchKey]Sketches {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	sketches := a.sketches
+	a.sketches = make(map[SketchKey]Sketches)
+	return sketches
+}
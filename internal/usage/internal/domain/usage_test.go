@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDayOf(t *testing.T) {
+	loc := time.FixedZone("UTC+5", 5*60*60)
+	in := time.Date(2025, 3, 2, 2, 30, 0, 0, loc) // 2025-03-01 21:30 UTC
+
+	got := DayOf(in)
+	want := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("DayOf(%v) = %v, want %v", in, got, want)
+	}
+}
+
+func TestAggregator_AddAndDrain(t *testing.T) {
+	agg := NewAggregator()
+	day1 := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	day2 := time.Date(2025, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	agg.Add("app-a", day1, 100)
+	agg.Add("app-a", day1.Add(time.Hour), 50)
+	agg.Add("app-a", day2, 10)
+	agg.Add("app-b", day1, 1)
+
+	counts := agg.Drain()
+	if len(counts) != 3 {
+		t.Fatalf("got %d keys, want 3", len(counts))
+	}
+
+	got := counts[Key{AppID: "app-a", Day: DayOf(day1)}]
+	if got.AcceptedEvents != 2 || got.IngestedBytes != 150 {
+		t.Errorf("app-a day1 = %+v, want {2 150}", got)
+	}
+
+	if again := agg.Drain(); len(again) != 0 {
+		t.Errorf("Drain after Drain returned %d keys, want 0", len(again))
+	}
+}
+
+func TestPendingReport_Delta(t *testing.T) {
+	p := PendingReport{AcceptedEvents: 120, ReportedEvents: 100}
+	if got := p.Delta(); got != 20 {
+		t.Errorf("Delta() = %d, want 20", got)
+	}
+}
+
+func TestSketchAggregator_AddAndDrain(t *testing.T) {
+	agg := NewSketchAggregator()
+	hour := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	agg.Add("app-a", hour.Add(5*time.Minute), "d1", "u1")
+	agg.Add("app-a", hour.Add(50*time.Minute), "d1", "")
+	agg.Add("app-a", hour.Add(55*time.Minute), "d2", "u1")
+	agg.Add("app-a", hour.Add(time.Hour), "d3", "")
+
+	sketches := agg.Drain()
+	if len(sketches) != 2 {
+		t.Fatalf("got %d keys, want 2", len(sketches))
+	}
+
+	got := sketches[SketchKey{AppID: "app-a", Hour: hour}]
+	if d, u := got.Devices.Estimate(), got.Users.Estimate(); d != 2 || u != 1 {
+		t.Errorf("devices, users = %d, %d, want 2, 1", d, u)
+	}
+
+	// Merging the next hour gives the devices of both.
+	got.Merge(sketches[SketchKey{AppID: "app-a", Hour: hour.Add(time.Hour)}])
+	if d := got.Devices.Estimate(); d != 3 {
+		t.Errorf("merged devices = %d, want 3", d)
+	}
+
+	if again := agg.Drain(); len(again) != 0 {
+		t.Errorf("Drain after Drain returned %d keys, want 0", len(again))
+	}
+}
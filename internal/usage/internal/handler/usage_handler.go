@@ -0,0 +1,227 @@
+// Package handler provides HTTP handlers for the usage export API.
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/usage/internal/domain"
+)
+
+// defaultExportDays is the export window when no "from" date is given.
+const defaultExportDays = 30
+
+// maxActiveWindow bounds the window of an active devices query.
+const maxActiveWindow = 92 * 24 * time.Hour
+
+// activeWindows are the rolling windows of an active devices query without
+// from and to, ending with the current hour.
+var activeWindows = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"hour", time.Hour},
+	{"day", 24 * time.Hour},
+	{"week", 7 * 24 * time.Hour},
+	{"month", 30 * 24 * time.Hour},
+}
+
+// UsageReader reads daily usage rows and active device sketches.
+type UsageReader interface {
+	List(ctx context.Context, appID string, from, to time.Time) ([]domain.DailyUsage, error)
+	ActiveSketches(ctx context.Context, appID string, from, to time.Time) (domain.Sketches, error)
+}
+
+// UsageHandler handles HTTP requests for usage export.
+type UsageHandler struct {
+	store  UsageReader
+	logger *slog.Logger
+}
+
+// NewUsageHandler creates a new UsageHandler.
+func NewUsageHandler(store UsageReader, logger *slog.Logger) *UsageHandler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &UsageHandler{
+		store:  store,
+		logger: logger.With("component", "usage-handler"),
+	}
+}
+
+// RegisterRoutes mounts the usage export endpoint on the given ServeMux.
+//
+// Endpoints:
+//   - GET /api/admin/usage?app_id=&from=YYYY-MM-DD&to=YYYY-MM-DD&format=json|csv
+//   - GET /api/admin/usage/active?app_id=&from=RFC3339&to=RFC3339
+//
+// TODO(phase-3): Protect these endpoints with session auth + RBAC.
+func (h *UsageHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/admin/usage", h.handleExport)
+	mux.HandleFunc("GET /api/admin/usage/active", h.handleActive)
+}
+
+// handleExport handles GET /api/admin/usage - exports daily usage.
+func (h *UsageHandler) handleExport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	to := domain.DayOf(time.Now())
+	if v := q.Get("to"); v != "" {
+		parsed, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "to must be a date in YYYY-MM-DD format",
+			})
+			return
+		}
+		to = parsed
+	}
+
+	from := to.AddDate(0, 0, -defaultExportDays)
+	if v := q.Get("from"); v != "" {
+		parsed, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "from must be a date in YYYY-MM-DD format",
+			})
+			return
+		}
+		from = parsed
+	}
+
+	if from.After(to) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "from must not be after to",
+		})
+		return
+	}
+
+	appID := q.Get("app_id")
+	usage, err := h.store.List(r.Context(), appID, from, to)
+	if err != nil {
+		h.logger.Error("failed to list usage",
+			"app_id", appID,
+			"error", err,
+		)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "failed to list usage",
+		})
+		return
+	}
+
+	switch q.Get("format") {
+	case "", "json":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"usage": usage,
+			"count": len(usage),
+			"from":  from.Format(time.DateOnly),
+			"to":    to.Format(time.DateOnly),
+		})
+	case "csv":
+		writeCSV(w, usage)
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "format must be json or csv",
+		})
+	}
+}
+
+// handleActive handles GET /api/admin/usage/active - estimates the distinct
+// devices and users of an app from its hourly sketches. Without from and
+// to, it returns the rolling hour, day, week and month ending with the
+// current hour; otherwise the window between them, rounded to whole hours.
+func (h *UsageHandler) handleActive(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	appID := q.Get("app_id")
+	if appID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "app_id is required",
+		})
+		return
+	}
+
+	var windows []domain.ActiveCounts
+	if q.Get("from") == "" && q.Get("to") == "" {
+		to := domain.HourOf(time.Now()).Add(time.Hour)
+		for _, aw := range activeWindows {
+			windows = append(windows, domain.ActiveCounts{Window: aw.name, From: to.Add(-aw.duration), To: to})
+		}
+	} else {
+		from, err := time.Parse(time.RFC3339, q.Get("from"))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "from must be an RFC 3339 timestamp",
+			})
+			return
+		}
+		to, err := time.Parse(time.RFC3339, q.Get("to"))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "to must be an RFC 3339 timestamp",
+			})
+			return
+		}
+		from, to = domain.HourOf(from), domain.HourOf(to.Add(time.Hour-time.Nanosecond))
+		if !to.After(from) || to.Sub(from) > maxActiveWindow {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "from must be before to, at most 92 days apart",
+			})
+			return
+		}
+		windows = append(windows, domain.ActiveCounts{Window: "custom", From: from, To: to})
+	}
+
+	for i := range windows {
+		sketches, err := h.store.ActiveSketches(r.Context(), appID, windows[i].From, windows[i].To)
+		if err != nil {
+			h.logger.Error("failed to read active sketches",
+				"app_id", appID,
+				"error", err,
+			)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{
+				"error": "failed to read active devices",
+			})
+			return
+		}
+		windows[i].Devices = sketches.Devices.Estimate()
+		windows[i].Users = sketches.Users.Estimate()
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"app_id":  appID,
+		"windows": windows,
+	})
+}
+
+// writeCSV writes usage rows as a CSV attachment.
+func writeCSV(w http.ResponseWriter, usage []domain.DailyUsage) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="usage.csv"`)
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"app_id", "day", "accepted_events", "ingested_bytes", "reported_events"})
+	for _, u := range usage {
+		_ = cw.Write([]string{
+			u.AppID,
+			u.Day.Format(time.DateOnly),
+			strconv.FormatInt(u.AcceptedEvents, 10),
+			strconv.FormatInt(u.IngestedBytes, 10),
+			strconv.FormatInt(u.ReportedEvents, 10),
+		})
+	}
+	cw.Flush()
+}
+
+// writeJSON writes a JSON response with the given status code and body.
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
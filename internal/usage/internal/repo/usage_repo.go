@@ -0,0 +1,260 @@
+// Package repo provides the PostgreSQL implementation of the usage Store port.
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/usage/internal/domain"
+)
+
+// UsageRepository implements the Store interface using PostgreSQL.
+type UsageRepository struct {
+	db *sql.DB
+}
+
+// NewUsageRepository creates a new UsageRepository backed by the given database.
+func NewUsageRepository(db *sql.DB) *UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+// AddCounts atomically increments the daily counters for every key in counts.
+func (r *UsageRepository) AddCounts(ctx context.Context, counts map[domain.Key]domain.Counts) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO usage_daily (app_id, day, accepted_events, ingested_bytes)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (app_id, day) DO UPDATE
+		SET accepted_events = usage_daily.accepted_events + EXCLUDED.accepted_events,
+		    ingested_bytes  = usage_daily.ingested_bytes + EXCLUDED.ingested_bytes,
+		    updated_at      = now()
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare usage upsert: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for key, c := range counts {
+		if _, err := stmt.ExecContext(ctx, key.AppID, key.Day, c.AcceptedEvents, c.IngestedBytes); err != nil {
+			return fmt.Errorf("failed to upsert usage for app %s: %w", key.AppID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit usage counts: %w", err)
+	}
+
+	return nil
+}
+
+// List returns daily usage rows between from and to (inclusive), ordered by
+// app and day. An empty appID returns usage for all apps.
+func (r *UsageRepository) List(ctx context.Context, appID string, from, to time.Time) ([]domain.DailyUsage, error) {
+	query := `
+		SELECT app_id, day, accepted_events, ingested_bytes, reported_events, updated_at
+		FROM usage_daily
+		WHERE day BETWEEN $1 AND $2
+		  AND ($3 = '' OR app_id = $3)
+		ORDER BY app_id, day
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, from, to, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var usage []domain.DailyUsage
+	for rows.Next() {
+		var u domain.DailyUsage
+		if err := rows.Scan(
+			&u.AppID,
+			&u.Day,
+			&u.AcceptedEvents,
+			&u.IngestedBytes,
+			&u.ReportedEvents,
+			&u.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan usage row: %w", err)
+		}
+		usage = append(usage, u)
+	}
+
+	return usage, rows.Err()
+}
+
+// PendingReports returns usage rows for apps with a billing subscription item
+// whose accepted events exceed what has already been reported.
+func (r *UsageRepository) PendingReports(ctx context.Context) ([]domain.PendingReport, error) {
+	query := `
+		SELECT u.app_id, u.day, s.subscription_item_id, u.accepted_events, u.reported_events
+		FROM usage_daily u
+		JOIN usage_stripe_items s ON s.app_id = u.app_id
+		WHERE u.accepted_events > u.reported_events
+		ORDER BY u.day, u.app_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending usage reports: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var reports []domain.PendingReport
+	for rows.Next() {
+		var p domain.PendingReport
+		if err := rows.Scan(
+			&p.AppID,
+			&p.Day,
+			&p.SubscriptionItemID,
+			&p.AcceptedEvents,
+			&p.ReportedEvents,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan pending report: %w", err)
+		}
+		reports = append(reports, p)
+	}
+
+	return reports, rows.Err()
+}
+
+// MarkReported records that accepted events up to reported have been pushed
+// to the billing provider for the given app and day.
+func (r *UsageRepository) MarkReported(ctx context.Context, appID string, day time.Time, reported int64) error {
+	query := `
+		UPDATE usage_daily
+		SET reported_events = $3
+		WHERE app_id = $1 AND day = $2 AND reported_events < $3
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, appID, day, reported); err != nil {
+		return fmt.Errorf("failed to mark usage reported: %w", err)
+	}
+	return nil
+}
+
+// MergeSketches merges hourly distinct device and user sketches into the
+// stored ones. Rows are locked while merged, so concurrent meters do not
+// lose each other's devices.
+func (r *UsageRepository) MergeSketches(ctx context.Context, sketches map[domain.SketchKey]domain.Sketches) error {
+	if len(sketches) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for key, s := range sketches {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO usage_hourly_sketches (app_id, hour, devices, users)
+			VALUES ($1, $2, '', '')
+			ON CONFLICT (app_id, hour) DO NOTHING
+		`, key.AppID, key.Hour); err != nil {
+			return fmt.Errorf("failed to create sketches for app %s: %w", key.AppID, err)
+		}
+
+		var devices, users []byte
+		if err := tx.QueryRowContext(ctx, `
+			SELECT devices, users FROM usage_hourly_sketches
+			WHERE app_id = $1 AND hour = $2
+			FOR UPDATE
+		`, key.AppID, key.Hour).Scan(&devices, &users); err != nil {
+			return fmt.Errorf("failed to lock sketches for app %s: %w", key.AppID, err)
+		}
+
+		merged, err := decodeSketches(devices, users)
+		if err != nil {
+			return fmt.Errorf("sketches for app %s at %s: %w", key.AppID, key.Hour.Format(time.RFC3339), err)
+		}
+		merged.Merge(s)
+
+		if devices, err = merged.Devices.MarshalBinary(); err != nil {
+			return err
+		}
+		if users, err = merged.Users.MarshalBinary(); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE usage_hourly_sketches
+			SET devices = $3, users = $4, updated_at = now()
+			WHERE app_id = $1 AND hour = $2
+		`, key.AppID, key.Hour, devices, users); err != nil {
+			return fmt.Errorf("failed to update sketches for app %s: %w", key.AppID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit sketches: %w", err)
+	}
+
+	return nil
+}
+
+// ActiveSketches returns an app's device and user sketches merged over the
+// hours in [from, to).
+func (r *UsageRepository) ActiveSketches(ctx context.Context, appID string, from, to time.Time) (domain.Sketches, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT devices, users FROM usage_hourly_sketches
+		WHERE app_id = $1 AND hour >= $2 AND hour < $3
+	`, appID, from, to)
+	if err != nil {
+		return domain.Sketches{}, fmt.Errorf("failed to query sketches: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	merged := domain.NewSketches()
+	for rows.Next() {
+		var devices, users []byte
+		if err := rows.Scan(&devices, &users); err != nil {
+			return domain.Sketches{}, fmt.Errorf("failed to scan sketches: %w", err)
+		}
+		s, err := decodeSketches(devices, users)
+		if err != nil {
+			return domain.Sketches{}, err
+		}
+		merged.Merge(s)
+	}
+
+	return merged, rows.Err()
+}
+
+// PruneSketches deletes sketches of hours before cutoff and returns the
+// number of rows deleted.
+func (r *UsageRepository) PruneSketches(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM usage_hourly_sketches WHERE hour < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune sketches: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// decodeSketches decodes stored sketches. Empty values, as inserted before
+// the first merge, are empty sketches.
+func decodeSketches(devices, users []byte) (domain.Sketches, error) {
+	s := domain.NewSketches()
+	if len(devices) > 0 {
+		if err := s.Devices.UnmarshalBinary(devices); err != nil {
+			return domain.Sketches{}, err
+		}
+	}
+	if len(users) > 0 {
+		if err := s.Users.UnmarshalBinary(users); err != nil {
+			return domain.Sketches{}, err
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,305 @@
+// Package service implements the usage meter: a JetStream consumer that
+// aggregates per-app daily counters, and an optional Stripe usage reporter.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/SebastienMelki/causality/internal/events"
+	"github.com/SebastienMelki/causality/internal/usage/internal/domain"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// UsageStore defines the persistence interface needed by the usage services.
+// This mirrors the usage.Store port to avoid import cycles.
+type UsageStore interface {
+	AddCounts(ctx context.Context, counts map[domain.Key]domain.Counts) error
+	List(ctx context.Context, appID string, from, to time.Time) ([]domain.DailyUsage, error)
+	PendingReports(ctx context.Context) ([]domain.PendingReport, error)
+	MarkReported(ctx context.Context, appID string, day time.Time, reported int64) error
+	MergeSketches(ctx context.Context, sketches map[domain.SketchKey]domain.Sketches) error
+	PruneSketches(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// maxClockSkew is how far ahead of the stream timestamp a client timestamp
+// may be before the stream timestamp is used instead.
+const maxClockSkew = 5 * time.Minute
+
+// pruneInterval is how often sketches past their retention are deleted.
+const pruneInterval = time.Hour
+
+// meteredEvent is the part of an event the meter records.
+type meteredEvent struct {
+	appID    string
+	deviceID string
+	userID   string
+	at       time.Time
+}
+
+// Meter consumes accepted events from the event stream and maintains per-app
+// daily accepted-event and ingested-bytes counters. Each fetched batch is
+// aggregated in memory, persisted in one transaction, and only then acked,
+// so counters are never lost (at-least-once: a crash between commit and ack
+// can double count a single batch). With sketches enabled, the distinct
+// devices and users of each app and hour are merged into stored HyperLogLog
+// sketches first; merging a redelivered batch again does not change them.
+type Meter struct {
+	js             jetstream.JetStream
+	store          UsageStore
+	streamName     string
+	consumerName   string
+	fetchBatchSize int
+	fetchMaxWait   time.Duration
+	logger         *slog.Logger
+
+	sketches        bool
+	sketchRetention time.Duration
+	lastPrune       time.Time
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewMeter creates a new usage meter for the given durable consumer.
+func NewMeter(
+	js jetstream.JetStream,
+	store UsageStore,
+	streamName string,
+	consumerName string,
+	fetchBatchSize int,
+	fetchMaxWait time.Duration,
+	logger *slog.Logger,
+) *Meter {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if fetchBatchSize < 1 {
+		fetchBatchSize = 500
+	}
+	if fetchMaxWait <= 0 {
+		fetchMaxWait = 5 * time.Second
+	}
+
+	return &Meter{
+		js:             js,
+		store:          store,
+		streamName:     streamName,
+		consumerName:   consumerName,
+		fetchBatchSize: fetchBatchSize,
+		fetchMaxWait:   fetchMaxWait,
+		logger:         logger.With("component", "usage-meter"),
+		stopCh:         make(chan struct{}),
+		doneCh:         make(chan struct{}),
+	}
+}
+
+// EnableSketches maintains hourly distinct device and user sketches, kept
+// for retention.
+func (m *Meter) EnableSketches(retention time.Duration) {
+	m.sketches = true
+	m.sketchRetention = retention
+}
+
+// Start looks up the durable consumer and begins the fetch loop.
+func (m *Meter) Start(ctx context.Context) error {
+	stream, err := m.js.Stream(ctx, m.streamName)
+	if err != nil {
+		return fmt.Errorf("failed to get stream: %w", err)
+	}
+
+	consumer, err := stream.Consumer(ctx, m.consumerName)
+	if err != nil {
+		return fmt.Errorf("failed to get consumer: %w", err)
+	}
+
+	m.logger.Info("starting usage meter",
+		"stream", m.streamName,
+		"consumer", m.consumerName,
+		"fetch_batch_size", m.fetchBatchSize,
+	)
+
+	go m.run(ctx, consumer)
+	return nil
+}
+
+// Stop signals the fetch loop to stop and waits for the in-flight batch.
+func (m *Meter) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.stopCh)
+	})
+	<-m.doneCh
+}
+
+// run is the main fetch loop.
+func (m *Meter) run(ctx context.Context, consumer jetstream.Consumer) {
+	defer close(m.doneCh)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.stopCh:
+			return
+		default:
+		}
+
+		msgs, err := consumer.Fetch(m.fetchBatchSize, jetstream.FetchMaxWait(m.fetchMaxWait))
+		if err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) {
+				m.logger.Error("failed to fetch messages", "error", err)
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+					return
+				case <-m.stopCh:
+					return
+				}
+			}
+			continue
+		}
+
+		var batch []jetstream.Msg
+		for msg := range msgs.Messages() {
+			batch = append(batch, msg)
+		}
+		if err := msgs.Error(); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			m.logger.Warn("fetch completed with error", "error", err)
+		}
+
+		m.processBatch(ctx, batch)
+		m.pruneSketches(ctx)
+	}
+}
+
+// processBatch aggregates a fetched batch, persists it, and acks on success.
+// Unparseable messages are terminated so they are not redelivered.
+func (m *Meter) processBatch(ctx context.Context, batch []jetstream.Msg) {
+	if len(batch) == 0 {
+		return
+	}
+
+	agg := domain.NewAggregator()
+	sketches := domain.NewSketchAggregator()
+	counted := make([]jetstream.Msg, 0, len(batch))
+
+	for _, msg := range batch {
+		receivedAt := time.Now()
+		if meta, err := msg.Metadata(); err == nil {
+			receivedAt = meta.Timestamp
+		}
+
+		event, err := eventFromMessage(msg.Data(), receivedAt)
+		if err != nil {
+			m.logger.Warn("terminating unparseable message",
+				"subject", msg.Subject(),
+				"error", err,
+			)
+			_ = msg.Term()
+			continue
+		}
+
+		// Bytes as published to the stream, before Parquet encoding
+		agg.Add(event.appID, receivedAt, int64(len(msg.Data())))
+		if m.sketches {
+			sketches.Add(event.appID, event.at, event.deviceID, event.userID)
+		}
+		counted = append(counted, msg)
+	}
+
+	// Sketches are merged first: if counting then fails, merging the
+	// redelivered batch again is harmless.
+	if err := m.store.MergeSketches(ctx, sketches.Drain()); err != nil {
+		m.logger.Error("failed to persist usage sketches, will redeliver",
+			"messages", len(counted),
+			"error", err,
+		)
+		for _, msg := range counted {
+			_ = msg.Nak()
+		}
+		return
+	}
+
+	if err := m.store.AddCounts(ctx, agg.Drain()); err != nil {
+		m.logger.Error("failed to persist usage counts, will redeliver",
+			"messages", len(counted),
+			"error", err,
+		)
+		for _, msg := range counted {
+			_ = msg.Nak()
+		}
+		return
+	}
+
+	for _, msg := range counted {
+		if err := msg.Ack(); err != nil {
+			m.logger.Warn("failed to ack message", "subject", msg.Subject(), "error", err)
+		}
+	}
+
+	m.logger.Debug("usage batch recorded", "messages", len(counted))
+}
+
+// pruneSketches deletes sketches past their retention, at most once per
+// pruneInterval.
+func (m *Meter) pruneSketches(ctx context.Context) {
+	if !m.sketches || m.sketchRetention <= 0 || time.Since(m.lastPrune) < pruneInterval {
+		return
+	}
+	m.lastPrune = time.Now()
+
+	deleted, err := m.store.PruneSketches(ctx, domain.HourOf(time.Now().Add(-m.sketchRetention)))
+	if err != nil {
+		m.logger.Warn("failed to prune usage sketches", "error", err)
+		return
+	}
+	if deleted > 0 {
+		m.logger.Info("pruned usage sketches", "deleted", deleted)
+	}
+}
+
+// eventFromMessage extracts the metered fields from a serialized
+// EventEnvelope. The client timestamp is used unless it is missing or ahead
+// of receivedAt by more than maxClockSkew. Users are identified by the
+// user events carrying a user_id.
+func eventFromMessage(data []byte, receivedAt time.Time) (meteredEvent, error) {
+	var event pb.EventEnvelope
+	if err := events.Decode(data, &event); err != nil {
+		return meteredEvent{}, fmt.Errorf("unmarshal event: %w", err)
+	}
+	if event.GetAppId() == "" {
+		return meteredEvent{}, errors.New("event has no app_id")
+	}
+
+	at := receivedAt
+	if ms := event.GetTimestampMs(); ms > 0 {
+		if ts := time.UnixMilli(ms); !ts.After(receivedAt.Add(maxClockSkew)) {
+			at = ts
+		}
+	}
+
+	m := meteredEvent{
+		appID:    event.GetAppId(),
+		deviceID: event.GetDeviceId(),
+		at:       at.UTC(),
+	}
+	switch {
+	case event.GetUserLogin() != nil:
+		m.userID = event.GetUserLogin().GetUserId()
+	case event.GetUserSignup() != nil:
+		m.userID = event.GetUserSignup().GetUserId()
+	case event.GetUserLogout() != nil:
+		m.userID = event.GetUserLogout().GetUserId()
+	case event.GetUserProfileUpdate() != nil:
+		m.userID = event.GetUserProfileUpdate().GetUserId()
+	}
+
+	return m, nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+func TestEventFromMessage(t *testing.T) {
+	received := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	sent := received.Add(-90 * time.Minute)
+
+	tests := []struct {
+		name     string
+		event    *pb.EventEnvelope
+		wantAt   time.Time
+		wantUser string
+	}{
+		{
+			name:   "client timestamp",
+			event:  &pb.EventEnvelope{AppId: "app", DeviceId: "d1", TimestampMs: sent.UnixMilli()},
+			wantAt: sent,
+		},
+		{
+			name:   "timestamp too far ahead",
+			event:  &pb.EventEnvelope{AppId: "app", DeviceId: "d1", TimestampMs: received.Add(time.Hour).UnixMilli()},
+			wantAt: received,
+		},
+		{
+			name: "user event",
+			event: &pb.EventEnvelope{
+				AppId:    "app",
+				DeviceId: "d1",
+				Payload:  &pb.EventEnvelope_UserLogin{UserLogin: &pb.UserLogin{UserId: "u1"}},
+			},
+			wantAt:   received,
+			wantUser: "u1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := proto.Marshal(tt.event)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := eventFromMessage(data, received)
+			if err != nil {
+				t.Fatalf("eventFromMessage() error = %v", err)
+			}
+			if got.appID != "app" || got.deviceID != "d1" {
+				t.Errorf("app, device = %q, %q, want app, d1", got.appID, got.deviceID)
+			}
+			if !got.at.Equal(tt.wantAt) {
+				t.Errorf("at = %v, want %v", got.at, tt.wantAt)
+			}
+			if got.userID != tt.wantUser {
+				t.Errorf("userID = %q, want %q", got.userID, tt.wantUser)
+			}
+		})
+	}
+
+	if _, err := eventFromMessage([]byte{0xff}, received); err == nil {
+		t.Error("eventFromMessage() of garbage error = nil")
+	}
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/usage/internal/domain"
+)
+
+// StripeReporter periodically pushes unreported accepted-event counts to
+// Stripe as metered usage records. Usage is pushed as "increment" records
+// with an idempotency key derived from the app, day, and cumulative total,
+// so retries after a crash never double bill.
+type StripeReporter struct {
+	store    UsageStore
+	client   *http.Client
+	apiURL   string
+	apiKey   string
+	interval time.Duration
+	logger   *slog.Logger
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewStripeReporter creates a new Stripe usage reporter.
+func NewStripeReporter(
+	store UsageStore,
+	apiURL string,
+	apiKey string,
+	interval time.Duration,
+	timeout time.Duration,
+	logger *slog.Logger,
+) *StripeReporter {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	return &StripeReporter{
+		store:    store,
+		client:   &http.Client{Timeout: timeout},
+		apiURL:   strings.TrimSuffix(apiURL, "/"),
+		apiKey:   apiKey,
+		interval: interval,
+		logger:   logger.With("component", "stripe-reporter"),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// Start begins the periodic push loop in a background goroutine.
+func (r *StripeReporter) Start(ctx context.Context) {
+	r.logger.Info("starting stripe usage reporter", "interval", r.interval)
+
+	go func() {
+		defer close(r.doneCh)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.stopCh:
+				return
+			case <-ticker.C:
+				if err := r.PushOnce(ctx); err != nil {
+					r.logger.Error("stripe usage push failed", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop signals the push loop to stop and waits for it to exit.
+func (r *StripeReporter) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopCh)
+	})
+	<-r.doneCh
+}
+
+// PushOnce pushes all pending usage deltas to Stripe. Individual failures
+// are logged and retried on the next run; the first error is returned.
+func (r *StripeReporter) PushOnce(ctx context.Context) error {
+	reports, err := r.store.PendingReports(ctx)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, report := range reports {
+		if err := r.push(ctx, report); err != nil {
+			r.logger.Warn("failed to push usage record",
+				"app_id", report.AppID,
+				"day", report.Day.Format(time.DateOnly),
+				"error", err,
+			)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		if err := r.store.MarkReported(ctx, report.AppID, report.Day, report.AcceptedEvents); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		r.logger.Info("pushed usage record",
+			"app_id", report.AppID,
+			"day", report.Day.Format(time.DateOnly),
+			"quantity", report.Delta(),
+		)
+	}
+
+	return firstErr
+}
+
+// push sends one usage record to the Stripe usage records API.
+func (r *StripeReporter) push(ctx context.Context, report domain.PendingReport) error {
+	form := url.Values{}
+	form.Set("quantity", strconv.FormatInt(report.Delta(), 10))
+	form.Set("timestamp", strconv.FormatInt(time.Now().Unix(), 10))
+	form.Set("action", "increment")
+
+	endpoint := fmt.Sprintf("%s/v1/subscription_items/%s/usage_records",
+		r.apiURL, url.PathEscape(report.SubscriptionItemID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth(r.apiKey, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Idempotency-Key", idempotencyKey(report))
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("stripe returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// idempotencyKey identifies a usage push by app, day, and the cumulative
+// total being reported up to.
+func idempotencyKey(report domain.PendingReport) string {
+	return fmt.Sprintf("causality-usage-%s-%s-%d",
+		report.AppID, report.Day.Format("20060102"), report.AcceptedEvents)
+}
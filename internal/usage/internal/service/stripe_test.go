@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/usage/internal/domain"
+)
+
+// mockStore is an in-memory UsageStore for testing.
+type mockStore struct {
+	pending  []domain.PendingReport
+	reported map[string]int64
+}
+
+func (m *mockStore) AddCounts(context.Context, map[domain.Key]domain.Counts) error { return nil }
+
+func (m *mockStore) List(context.Context, string, time.Time, time.Time) ([]domain.DailyUsage, error) {
+	return nil, nil
+}
+
+func (m *mockStore) MergeSketches(context.Context, map[domain.SketchKey]domain.Sketches) error {
+	return nil
+}
+
+func (m *mockStore) PruneSketches(context.Context, time.Time) (int64, error) { return 0, nil }
+
+func (m *mockStore) PendingReports(context.Context) ([]domain.PendingReport, error) {
+	return m.pending, nil
+}
+
+func (m *mockStore) MarkReported(_ context.Context, appID string, _ time.Time, reported int64) error {
+	m.reported[appID] = reported
+	return nil
+}
+
+func TestStripeReporter_PushOnce(t *testing.T) {
+	var gotPath, gotQuantity, gotIdempotency, gotUser string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotIdempotency = r.Header.Get("Idempotency-Key")
+		gotUser, _, _ = r.BasicAuth()
+		_ = r.ParseForm()
+		gotQuantity = r.PostForm.Get("quantity")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"id":"mbur_123"}`))
+	}))
+	defer srv.Close()
+
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	store := &mockStore{
+		pending: []domain.PendingReport{
+			{AppID: "app-a", Day: day, SubscriptionItemID: "si_123", AcceptedEvents: 150, ReportedEvents: 100},
+		},
+		reported: make(map[string]int64),
+	}
+
+	reporter := NewStripeReporter(store, srv.URL, "sk_test", time.Hour, time.Second, nil)
+	if err := reporter.PushOnce(context.Background()); err != nil {
+		t.Fatalf("PushOnce() error = %v", err)
+	}
+
+	if gotPath != "/v1/subscription_items/si_123/usage_records" {
+		t.Errorf("path = %q", gotPath)
+	}
+	if gotQuantity != "50" {
+		t.Errorf("quantity = %q, want 50", gotQuantity)
+	}
+	if gotUser != "sk_test" {
+		t.Errorf("basic auth user = %q, want sk_test", gotUser)
+	}
+	if gotIdempotency != "causality-usage-app-a-20250301-150" {
+		t.Errorf("idempotency key = %q", gotIdempotency)
+	}
+	if store.reported["app-a"] != 150 {
+		t.Errorf("reported = %d, want 150", store.reported["app-a"])
+	}
+}
+
+func TestStripeReporter_PushOnceFailureLeavesUnreported(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	store := &mockStore{
+		pending: []domain.PendingReport{
+			{AppID: "app-a", Day: time.Now(), SubscriptionItemID: "si_123", AcceptedEvents: 10},
+		},
+		reported: make(map[string]int64),
+	}
+
+	reporter := NewStripeReporter(store, srv.URL, "sk_test", time.Hour, time.Second, nil)
+	if err := reporter.PushOnce(context.Background()); err == nil {
+		t.Fatal("PushOnce() expected error on 500 response")
+	}
+	if _, ok := store.reported["app-a"]; ok {
+		t.Error("usage marked reported despite push failure")
+	}
+}
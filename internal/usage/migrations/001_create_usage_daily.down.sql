@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS usage_stripe_items;
+DROP TABLE IF EXISTS usage_daily;
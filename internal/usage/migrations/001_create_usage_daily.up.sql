@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS usage_daily (
+    app_id          TEXT NOT NULL,
+    day             DATE NOT NULL,
+    accepted_events BIGINT NOT NULL DEFAULT 0,
+    stored_bytes    BIGINT NOT NULL DEFAULT 0,
+    reported_events BIGINT NOT NULL DEFAULT 0,
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (app_id, day)
+);
+
+-- Export queries by date range across all apps
+CREATE INDEX IF NOT EXISTS idx_usage_daily_day ON usage_daily(day);
+
+-- Maps an app to its Stripe metered subscription item (optional)
+CREATE TABLE IF NOT EXISTS usage_stripe_items (
+    app_id               TEXT PRIMARY KEY,
+    subscription_item_id TEXT NOT NULL,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT now()
+);
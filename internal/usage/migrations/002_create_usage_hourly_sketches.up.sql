@@ -0,0 +1,14 @@
+-- HyperLogLog sketches of the distinct devices and users seen per app and
+-- hour (see package hll), merged to estimate active devices and users over
+-- any window of whole hours
+CREATE TABLE IF NOT EXISTS usage_hourly_sketches (
+    app_id     TEXT NOT NULL,
+    hour       TIMESTAMPTZ NOT NULL,
+    devices    BYTEA NOT NULL,
+    users      BYTEA NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (app_id, hour)
+);
+
+-- Retention pruning
+CREATE INDEX IF NOT EXISTS idx_usage_hourly_sketches_hour ON usage_hourly_sketches(hour);
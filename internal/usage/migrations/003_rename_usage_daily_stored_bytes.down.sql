@@ -0,0 +1,9 @@
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'usage_daily' AND column_name = 'ingested_bytes'
+    ) THEN
+        ALTER TABLE usage_daily RENAME COLUMN ingested_bytes TO stored_bytes;
+    END IF;
+END $$;
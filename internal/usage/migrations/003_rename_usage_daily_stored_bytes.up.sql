@@ -0,0 +1,12 @@
+-- The counter holds the encoded size of accepted events as published to the
+-- event stream, not their compressed size in the event lake. Databases
+-- created from the docker init script already have the new name.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'usage_daily' AND column_name = 'stored_bytes'
+    ) THEN
+        ALTER TABLE usage_daily RENAME COLUMN stored_bytes TO ingested_bytes;
+    END IF;
+END $$;
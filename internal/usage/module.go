@@ -0,0 +1,141 @@
+package usage
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/SebastienMelki/causality/internal/db"
+	"github.com/SebastienMelki/causality/internal/usage/internal/handler"
+	"github.com/SebastienMelki/causality/internal/usage/internal/repo"
+	"github.com/SebastienMelki/causality/internal/usage/internal/service"
+	"github.com/SebastienMelki/causality/internal/usage/migrations"
+)
+
+// Migrations are the usage module's database migrations.
+var Migrations = db.Source{Name: "usage", FS: migrations.FS}
+
+// Config holds the usage module configuration.
+type Config struct {
+	// ConsumerName is the durable JetStream consumer used for metering.
+	ConsumerName string `env:"USAGE_CONSUMER_NAME" envDefault:"usage-meter"`
+
+	// FilterSubject selects which stream subjects are metered.
+	FilterSubject string `env:"USAGE_FILTER_SUBJECT" envDefault:"events.>"`
+
+	// FetchBatchSize is the number of messages aggregated per transaction.
+	FetchBatchSize int `env:"USAGE_FETCH_BATCH_SIZE" envDefault:"500"`
+
+	// FetchMaxWait bounds how long a fetch waits for a full batch.
+	FetchMaxWait time.Duration `env:"USAGE_FETCH_MAX_WAIT" envDefault:"5s"`
+
+	// SketchesEnabled maintains hourly HyperLogLog sketches of each app's
+	// distinct devices and users, served by the active devices endpoint.
+	SketchesEnabled bool `env:"USAGE_SKETCHES_ENABLED" envDefault:"true"`
+
+	// SketchRetention is how long hourly sketches are kept. It bounds the
+	// windows active devices can be estimated over.
+	SketchRetention time.Duration `env:"USAGE_SKETCH_RETENTION" envDefault:"2208h"`
+
+	// Stripe configures the optional metered-usage push.
+	Stripe StripeConfig `envPrefix:"STRIPE_"`
+}
+
+// StripeConfig holds Stripe metered-usage push settings.
+type StripeConfig struct {
+	// Enabled turns on periodic usage pushes to Stripe.
+	Enabled bool `env:"ENABLED" envDefault:"false"`
+
+	// APIKey is the Stripe secret key.
+	APIKey string `env:"API_KEY"`
+
+	// APIURL is the Stripe API base URL.
+	APIURL string `env:"API_URL" envDefault:"https://api.stripe.com"`
+
+	// PushInterval is how often pending usage is pushed.
+	PushInterval time.Duration `env:"PUSH_INTERVAL" envDefault:"1h"`
+
+	// Timeout is the HTTP timeout for Stripe requests.
+	Timeout time.Duration `env:"TIMEOUT" envDefault:"10s"`
+}
+
+// Module is the usage module facade. It wires the PostgreSQL repository,
+// stream meter, export handler, and optional Stripe reporter.
+type Module struct {
+	meter    *service.Meter
+	reporter *service.StripeReporter
+	handler  *handler.UsageHandler
+	config   Config
+	logger   *slog.Logger
+}
+
+// New creates a new usage Module consuming from streamName.
+func New(db *sql.DB, js jetstream.JetStream, streamName string, cfg Config, logger *slog.Logger) *Module {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	usageRepo := repo.NewUsageRepository(db)
+
+	m := &Module{
+		meter: service.NewMeter(
+			js,
+			usageRepo,
+			streamName,
+			cfg.ConsumerName,
+			cfg.FetchBatchSize,
+			cfg.FetchMaxWait,
+			logger,
+		),
+		handler: handler.NewUsageHandler(usageRepo, logger),
+		config:  cfg,
+		logger:  logger.With("component", "usage-module"),
+	}
+
+	if cfg.SketchesEnabled {
+		m.meter.EnableSketches(cfg.SketchRetention)
+	}
+
+	if cfg.Stripe.Enabled {
+		m.reporter = service.NewStripeReporter(
+			usageRepo,
+			cfg.Stripe.APIURL,
+			cfg.Stripe.APIKey,
+			cfg.Stripe.PushInterval,
+			cfg.Stripe.Timeout,
+			logger,
+		)
+	}
+
+	return m
+}
+
+// Start begins metering and, if enabled, the Stripe push loop.
+func (m *Module) Start(ctx context.Context) error {
+	if err := m.meter.Start(ctx); err != nil {
+		return err
+	}
+	if m.reporter != nil {
+		m.reporter.Start(ctx)
+	}
+	return nil
+}
+
+// Stop stops metering and the Stripe push loop.
+func (m *Module) Stop() {
+	m.meter.Stop()
+	if m.reporter != nil {
+		m.reporter.Stop()
+	}
+}
+
+// RegisterRoutes mounts the usage endpoints onto the given ServeMux:
+//   - GET /api/admin/usage - Export daily usage as JSON or CSV
+//   - GET /api/admin/usage/active - Estimate an app's distinct devices and users
+func (m *Module) RegisterRoutes(mux *http.ServeMux) {
+	m.handler.RegisterRoutes(mux)
+}
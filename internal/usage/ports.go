@@ -0,0 +1,47 @@
+// Package usage provides billing/usage metering. It consumes accepted events
+// from the JetStream event stream, maintains per-app daily accepted-event and
+// ingested-bytes counters and hourly HyperLogLog sketches of distinct devices
+// and users in PostgreSQL, exposes export and active-device APIs, and
+// optionally pushes metered usage to Stripe.
+package usage
+
+import (
+	"context"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/usage/internal/domain"
+)
+
+// DailyUsage is the persisted usage for one app on one UTC day.
+type DailyUsage = domain.DailyUsage
+
+// ActiveCounts is the estimated number of distinct devices and users of an
+// app over a window.
+type ActiveCounts = domain.ActiveCounts
+
+// Store defines the port for usage counter persistence.
+type Store interface {
+	// AddCounts atomically increments daily counters.
+	AddCounts(ctx context.Context, counts map[domain.Key]domain.Counts) error
+
+	// List returns daily usage between from and to (inclusive). An empty
+	// appID returns usage for all apps.
+	List(ctx context.Context, appID string, from, to time.Time) ([]DailyUsage, error)
+
+	// PendingReports returns usage not yet pushed to the billing provider.
+	PendingReports(ctx context.Context) ([]domain.PendingReport, error)
+
+	// MarkReported records the cumulative count pushed for an app and day.
+	MarkReported(ctx context.Context, appID string, day time.Time, reported int64) error
+
+	// MergeSketches merges hourly distinct device and user sketches into
+	// the stored ones.
+	MergeSketches(ctx context.Context, sketches map[domain.SketchKey]domain.Sketches) error
+
+	// ActiveSketches returns an app's device and user sketches merged over
+	// the hours in [from, to).
+	ActiveSketches(ctx context.Context, appID string, from, to time.Time) (domain.Sketches, error)
+
+	// PruneSketches deletes sketches of hours before cutoff.
+	PruneSketches(ctx context.Context, cutoff time.Time) (int64, error)
+}
@@ -0,0 +1,433 @@
+package warehouse
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	arrowparquet "github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+
+	"github.com/SebastienMelki/causality/internal/events"
+	"github.com/SebastienMelki/causality/internal/geo"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// Column indexes of the Arrow event schema, in EventRow order.
+const (
+	arrowID = iota
+	arrowAppID
+	arrowDeviceID
+	arrowTimestampMS
+	arrowCorrelationID
+	arrowEventCategory
+	arrowEventType
+	arrowPlatform
+	arrowOSVersion
+	arrowAppVersion
+	arrowBuildNumber
+	arrowDeviceModel
+	arrowManufacturer
+	arrowScreenWidth
+	arrowScreenHeight
+	arrowLocale
+	arrowTimezone
+	arrowNetworkType
+	arrowCarrier
+	arrowIsJailbroken
+	arrowIsEmulator
+	arrowSDKVersion
+	arrowClockSkewMS
+	arrowSDKName
+	arrowCountry
+	arrowRegion
+	arrowAmountUSD
+	arrowExperimentID
+	arrowExperimentVariant
+	arrowPayloadJSON
+	arrowYear
+	arrowMonth
+	arrowDay
+	arrowHour
+)
+
+// arrowEventFields are the Arrow fields of the EventRow columns. Nullable
+// fields are the optional EventRow columns, which hold nulls for zero values.
+var arrowEventFields = []arrow.Field{
+	arrowID:                {Name: "id", Type: arrow.BinaryTypes.String},
+	arrowAppID:             {Name: "app_id", Type: arrow.BinaryTypes.String},
+	arrowDeviceID:          {Name: "device_id", Type: arrow.BinaryTypes.String},
+	arrowTimestampMS:       {Name: "timestamp_ms", Type: arrow.PrimitiveTypes.Int64},
+	arrowCorrelationID:     {Name: "correlation_id", Type: arrow.BinaryTypes.String, Nullable: true},
+	arrowEventCategory:     {Name: "event_category", Type: arrow.BinaryTypes.String},
+	arrowEventType:         {Name: "event_type", Type: arrow.BinaryTypes.String},
+	arrowPlatform:          {Name: "platform", Type: arrow.BinaryTypes.String, Nullable: true},
+	arrowOSVersion:         {Name: "os_version", Type: arrow.BinaryTypes.String, Nullable: true},
+	arrowAppVersion:        {Name: "app_version", Type: arrow.BinaryTypes.String, Nullable: true},
+	arrowBuildNumber:       {Name: "build_number", Type: arrow.BinaryTypes.String, Nullable: true},
+	arrowDeviceModel:       {Name: "device_model", Type: arrow.BinaryTypes.String, Nullable: true},
+	arrowManufacturer:      {Name: "manufacturer", Type: arrow.BinaryTypes.String, Nullable: true},
+	arrowScreenWidth:       {Name: "screen_width", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+	arrowScreenHeight:      {Name: "screen_height", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+	arrowLocale:            {Name: "locale", Type: arrow.BinaryTypes.String, Nullable: true},
+	arrowTimezone:          {Name: "timezone", Type: arrow.BinaryTypes.String, Nullable: true},
+	arrowNetworkType:       {Name: "network_type", Type: arrow.BinaryTypes.String, Nullable: true},
+	arrowCarrier:           {Name: "carrier", Type: arrow.BinaryTypes.String, Nullable: true},
+	arrowIsJailbroken:      {Name: "is_jailbroken", Type: arrow.FixedWidthTypes.Boolean, Nullable: true},
+	arrowIsEmulator:        {Name: "is_emulator", Type: arrow.FixedWidthTypes.Boolean, Nullable: true},
+	arrowSDKVersion:        {Name: "sdk_version", Type: arrow.BinaryTypes.String, Nullable: true},
+	arrowClockSkewMS:       {Name: "clock_skew_ms", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	arrowSDKName:           {Name: "sdk_name", Type: arrow.BinaryTypes.String, Nullable: true},
+	arrowCountry:           {Name: "country", Type: arrow.BinaryTypes.String, Nullable: true},
+	arrowRegion:            {Name: "region", Type: arrow.BinaryTypes.String, Nullable: true},
+	arrowAmountUSD:         {Name: "amount_usd", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+	arrowExperimentID:      {Name: "experiment_id", Type: arrow.BinaryTypes.String, Nullable: true},
+	arrowExperimentVariant: {Name: "experiment_variant", Type: arrow.BinaryTypes.String, Nullable: true},
+	arrowPayloadJSON:       {Name: "payload_json", Type: arrow.BinaryTypes.String},
+	arrowYear:              {Name: "year", Type: arrow.PrimitiveTypes.Int64},
+	arrowMonth:             {Name: "month", Type: arrow.PrimitiveTypes.Int64},
+	arrowDay:               {Name: "day", Type: arrow.PrimitiveTypes.Int64},
+	arrowHour:              {Name: "hour", Type: arrow.PrimitiveTypes.Int64},
+}
+
+// promotedArrowTypes maps promoted column types to their Arrow types.
+var promotedArrowTypes = map[string]arrow.DataType{
+	PromotedString: arrow.BinaryTypes.String,
+	PromotedInt64:  arrow.PrimitiveTypes.Int64,
+	PromotedDouble: arrow.PrimitiveTypes.Float64,
+	PromotedBool:   arrow.FixedWidthTypes.Boolean,
+}
+
+// ArrowSchema returns the Arrow schema of EventRowSchema(columns): the
+// EventRow columns followed by the optional promoted columns.
+func ArrowSchema(columns []PromotedColumn) *arrow.Schema {
+	fields := slices.Clone(arrowEventFields)
+	for _, c := range columns {
+		typ, ok := promotedArrowTypes[c.Type]
+		if !ok {
+			typ = promotedArrowTypes[PromotedString]
+		}
+		fields = append(fields, arrow.Field{Name: c.Column, Type: typ, Nullable: true})
+	}
+	return arrow.NewSchema(fields, nil)
+}
+
+// ArrowBatchBuilder builds Arrow record batches of events column by column,
+// with the columns of EventRowSchema. It converts EventEnvelopes without
+// going through EventRow structs and parquet-go's reflection-based
+// encoding. Records are written to Parquet by ParquetWriter.WriteArrow.
+//
+// BenchmarkParquetWriter_Arrow and BenchmarkParquetWriter_EventRow compare
+// the two paths; encoding time is on par (payload serialization dominates
+// both) while the Arrow path allocates about a third less memory.
+type ArrowBatchBuilder struct {
+	builder  *array.RecordBuilder
+	columns  []PromotedColumn
+	geo      bool
+	currency CurrencyConverter
+}
+
+// NewArrowBatchBuilder creates a builder of records with the EventRow
+// columns and the given promoted columns, allocating from mem.
+func NewArrowBatchBuilder(mem memory.Allocator, columns []PromotedColumn) *ArrowBatchBuilder {
+	return &ArrowBatchBuilder{
+		builder: array.NewRecordBuilder(mem, ArrowSchema(columns)),
+		columns: columns,
+	}
+}
+
+// SetGeo fills the country and region columns from each event's locale and
+// timezone (see EventRow.enrichGeo).
+func (b *ArrowBatchBuilder) SetGeo(enabled bool) {
+	b.geo = enabled
+}
+
+// SetCurrencyConverter fills the amount_usd column of purchase_complete
+// events using converter.
+func (b *ArrowBatchBuilder) SetCurrencyConverter(converter CurrencyConverter) {
+	b.currency = converter
+}
+
+// Append appends an event, with the values EventRowFromProto gives it. The
+// year..hour columns are taken from the event's timestamp. Events are
+// written in the order they are appended, so callers append them by
+// timestamp (see ParquetWriter.Write).
+func (b *ArrowBatchBuilder) Append(event *pb.EventEnvelope) {
+	year, month, day, hour := eventTime(event)
+	category, eventType := events.GetCategoryAndType(event)
+
+	b.str(arrowID).Append(event.GetId())
+	b.str(arrowAppID).Append(event.GetAppId())
+	b.str(arrowDeviceID).Append(event.GetDeviceId())
+	b.int64(arrowTimestampMS).Append(event.GetTimestampMs())
+	appendOptString(b.str(arrowCorrelationID), event.GetCorrelationId())
+	b.str(arrowEventCategory).Append(category)
+	b.str(arrowEventType).Append(eventType)
+
+	ctx := event.GetDeviceContext()
+	platform, networkType := "", ""
+	if ctx != nil {
+		platform, networkType = ctx.GetPlatform().String(), ctx.GetNetworkType().String()
+	}
+	appendOptString(b.str(arrowPlatform), platform)
+	appendOptString(b.str(arrowOSVersion), ctx.GetOsVersion())
+	appendOptString(b.str(arrowAppVersion), ctx.GetAppVersion())
+	appendOptString(b.str(arrowBuildNumber), ctx.GetBuildNumber())
+	appendOptString(b.str(arrowDeviceModel), ctx.GetDeviceModel())
+	appendOptString(b.str(arrowManufacturer), ctx.GetManufacturer())
+	appendOptInt32(b.int32(arrowScreenWidth), ctx.GetScreenWidth())
+	appendOptInt32(b.int32(arrowScreenHeight), ctx.GetScreenHeight())
+	appendOptString(b.str(arrowLocale), ctx.GetLocale())
+	appendOptString(b.str(arrowTimezone), ctx.GetTimezone())
+	appendOptString(b.str(arrowNetworkType), networkType)
+	appendOptString(b.str(arrowCarrier), ctx.GetCarrier())
+	appendOptBool(b.bool(arrowIsJailbroken), ctx.GetIsJailbroken())
+	appendOptBool(b.bool(arrowIsEmulator), ctx.GetIsEmulator())
+	appendOptString(b.str(arrowSDKVersion), ctx.GetSdkVersion())
+	appendOptInt64(b.int64(arrowClockSkewMS), ctx.GetClockSkewMs())
+	appendOptString(b.str(arrowSDKName), ctx.GetSdkName())
+
+	country, region := "", ""
+	if b.geo {
+		country, region = geo.Resolve(ctx.GetLocale(), ctx.GetTimezone())
+	}
+	appendOptString(b.str(arrowCountry), country)
+	appendOptString(b.str(arrowRegion), region)
+
+	amountUSD := 0.0
+	if b.currency != nil {
+		amountUSD = purchaseAmountUSD(event, b.currency)
+	}
+	appendOptFloat64(b.builder.Field(arrowAmountUSD).(*array.Float64Builder), amountUSD)
+
+	exposure := event.GetExperimentExposure()
+	appendOptString(b.str(arrowExperimentID), exposure.GetExperimentId())
+	appendOptString(b.str(arrowExperimentVariant), exposure.GetVariant())
+
+	b.str(arrowPayloadJSON).Append(serializePayload(event))
+	b.int64(arrowYear).Append(int64(year))
+	b.int64(arrowMonth).Append(int64(month))
+	b.int64(arrowDay).Append(int64(day))
+	b.int64(arrowHour).Append(int64(hour))
+
+	b.appendPromoted(event.GetCustomEvent())
+}
+
+// appendPromoted appends the promoted column values of a custom event, or
+// nulls (see appendPromoted for parquet rows).
+func (b *ArrowBatchBuilder) appendPromoted(custom *pb.CustomEvent) {
+	for i, c := range b.columns {
+		field := b.builder.Field(len(arrowEventFields) + i)
+		v, ok := promotedValue(custom, c)
+		if !ok {
+			field.AppendNull()
+			continue
+		}
+		switch fb := field.(type) {
+		case *array.Int64Builder:
+			fb.Append(v.Int64())
+		case *array.Float64Builder:
+			fb.Append(v.Double())
+		case *array.BooleanBuilder:
+			fb.Append(v.Boolean())
+		case *array.StringBuilder:
+			fb.BinaryBuilder.Append(v.ByteArray())
+		}
+	}
+}
+
+// Len returns the number of events appended since the last NewRecord.
+func (b *ArrowBatchBuilder) Len() int {
+	return b.builder.Field(arrowID).Len()
+}
+
+// NewRecord returns the appended events as a record and resets the builder.
+// The caller releases the record.
+func (b *ArrowBatchBuilder) NewRecord() arrow.Record {
+	return b.builder.NewRecord()
+}
+
+// Release releases the builder's buffers.
+func (b *ArrowBatchBuilder) Release() {
+	b.builder.Release()
+}
+
+func (b *ArrowBatchBuilder) str(i int) *array.StringBuilder {
+	return b.builder.Field(i).(*array.StringBuilder)
+}
+
+func (b *ArrowBatchBuilder) int32(i int) *array.Int32Builder {
+	return b.builder.Field(i).(*array.Int32Builder)
+}
+
+func (b *ArrowBatchBuilder) int64(i int) *array.Int64Builder {
+	return b.builder.Field(i).(*array.Int64Builder)
+}
+
+func (b *ArrowBatchBuilder) bool(i int) *array.BooleanBuilder {
+	return b.builder.Field(i).(*array.BooleanBuilder)
+}
+
+// The appendOpt functions append zero values as nulls, like parquet-go does
+// for the optional EventRow columns.
+
+func appendOptString(b *array.StringBuilder, v string) {
+	if v == "" {
+		b.AppendNull()
+		return
+	}
+	b.Append(v)
+}
+
+func appendOptInt32(b *array.Int32Builder, v int32) {
+	if v == 0 {
+		b.AppendNull()
+		return
+	}
+	b.Append(v)
+}
+
+func appendOptInt64(b *array.Int64Builder, v int64) {
+	if v == 0 {
+		b.AppendNull()
+		return
+	}
+	b.Append(v)
+}
+
+func appendOptFloat64(b *array.Float64Builder, v float64) {
+	if v == 0 {
+		b.AppendNull()
+		return
+	}
+	b.Append(v)
+}
+
+func appendOptBool(b *array.BooleanBuilder, v bool) {
+	if !v {
+		b.AppendNull()
+		return
+	}
+	b.Append(v)
+}
+
+// WriteArrow writes a record built by an ArrowBatchBuilder to Parquet with
+// the Arrow Parquet writer and returns the bytes. The file has the schema,
+// compression, dictionary encoding, statistics and footer metadata of the
+// files Write and WritePromoted produce, so readers and compaction cannot
+// tell them apart. The page index holds full min/max values; the
+// ColumnIndexSizeLimit truncation is not applied. payload_json has no
+// statistics.
+func (w *ParquetWriter) WriteArrow(rec arrow.Record) ([]byte, error) {
+	if rec.NumRows() == 0 {
+		return nil, ErrNoRowsToWrite
+	}
+
+	var buf bytes.Buffer
+	writer, err := pqarrow.NewFileWriter(rec.Schema(), &buf, w.arrowProperties(), pqarrow.DefaultWriterProps())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create arrow writer: %w", err)
+	}
+
+	if err := writer.Write(rec); err != nil {
+		return nil, fmt.Errorf("failed to write record: %w", err)
+	}
+
+	config := parquet.DefaultWriterConfig()
+	config.Apply(w.extra...)
+	keys := make([]string, 0, len(config.KeyValueMetadata))
+	for k := range config.KeyValueMetadata {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		if err := writer.AppendKeyValueMetadata(k, config.KeyValueMetadata[k]); err != nil {
+			return nil, fmt.Errorf("failed to write metadata: %w", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close writer: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// arrowProperties returns the Arrow Parquet writer properties matching
+// writerOptions.
+func (w *ParquetWriter) arrowProperties() *arrowparquet.WriterProperties {
+	props := []arrowparquet.WriterProperty{
+		arrowparquet.WithCompression(w.arrowCompression()),
+		arrowparquet.WithCreatedBy("causality-warehouse-sink version 1.0.0"),
+		arrowparquet.WithRootName(eventRowSchema.Name()),
+		// The default repeated root makes parquet-go read every column as
+		// repeated
+		arrowparquet.WithRootRepetition(arrowparquet.Repetitions.Required),
+		arrowparquet.WithStats(true),
+		arrowparquet.WithPageIndexEnabled(true),
+		// See StatisticsOptions. Disabling the page index of payload_json
+		// would leave its offset index empty, which parquet-go cannot seek
+		arrowparquet.WithStatsFor("payload_json", false),
+		arrowparquet.WithDictionaryDefault(false),
+	}
+	for _, column := range dictionaryColumns {
+		props = append(props, arrowparquet.WithDictionaryFor(column, true))
+	}
+	return arrowparquet.NewWriterProperties(props...)
+}
+
+// dictionaryColumns are the EventRow columns tagged dict.
+var dictionaryColumns = func() []string {
+	var columns []string
+	for _, field := range eventRowSchema.Fields() {
+		if enc := field.Encoding(); enc != nil && enc.Encoding() == format.RLEDictionary {
+			columns = append(columns, field.Name())
+		}
+	}
+	return columns
+}()
+
+// arrowCompression returns the Arrow compression codec based on config (see
+// getCompressionCodec).
+func (w *ParquetWriter) arrowCompression() compress.Compression {
+	switch strings.ToLower(w.config.Compression) {
+	case "gzip":
+		return compress.Codecs.Gzip
+	case "zstd":
+		return compress.Codecs.Zstd
+	case "none":
+		return compress.Codecs.Uncompressed
+	default:
+		return compress.Codecs.Snappy
+	}
+}
+
+// deltaFileFromRecord builds the Delta add-file description of a partition
+// file written from rec (see deltaFileFromRows).
+func deltaFileFromRecord(key string, size int64, rec arrow.Record) DeltaFile {
+	f := DeltaFile{Key: key, Size: size, NumRecords: rec.NumRows()}
+	if rec.NumRows() == 0 {
+		return f
+	}
+
+	ts := rec.Column(arrowTimestampMS).(*array.Int64)
+	types := rec.Column(arrowEventType).(*array.String)
+	minTS, maxTS := ts.Value(0), ts.Value(0)
+	minType, maxType := types.Value(0), types.Value(0)
+	for i := 1; i < ts.Len(); i++ {
+		minTS, maxTS = min(minTS, ts.Value(i)), max(maxTS, ts.Value(i))
+		minType, maxType = min(minType, types.Value(i)), max(maxType, types.Value(i))
+	}
+
+	// The strings point into the record's buffers, which are released
+	f.MinValues = map[string]any{"timestamp_ms": minTS, "event_type": strings.Clone(minType)}
+	f.MaxValues = map[string]any{"timestamp_ms": maxTS, "event_type": strings.Clone(maxType)}
+	return f
+}
@@ -0,0 +1,249 @@
+package warehouse
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/parquet-go/parquet-go"
+
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// arrowTestFile writes events through an ArrowBatchBuilder, in timestamp
+// order, with geo enrichment and amount_usd filled in.
+func arrowTestFile(t testing.TB, writer *ParquetWriter, events []*pb.EventEnvelope, columns []PromotedColumn) []byte {
+	t.Helper()
+	sorted := slices.Clone(events)
+	slices.SortStableFunc(sorted, func(a, b *pb.EventEnvelope) int {
+		return cmp.Compare(a.GetTimestampMs(), b.GetTimestampMs())
+	})
+
+	builder := NewArrowBatchBuilder(memory.DefaultAllocator, columns)
+	defer builder.Release()
+	builder.SetGeo(true)
+	builder.SetCurrencyConverter(fixedConverter{})
+	for _, event := range sorted {
+		builder.Append(event)
+	}
+	rec := builder.NewRecord()
+	defer rec.Release()
+
+	data, err := writer.WriteArrow(rec)
+	if err != nil {
+		t.Fatalf("WriteArrow() error = %v", err)
+	}
+	return data
+}
+
+// TestWriteArrow_MatchesEventRowPath verifies the Arrow path writes the
+// schema, rows, statistics and footer metadata of the EventRow path.
+func TestWriteArrow_MatchesEventRowPath(t *testing.T) {
+	writer := NewParquetWriter(ParquetConfig{Compression: "snappy", PageStatistics: true}).
+		WithOptions(SequenceMetadata("CAUSALITY_EVENTS", NewSequenceRanges([]uint64{1, 2, 3}), 0)...)
+	events := mixedTestEvents(200)
+
+	rows := make([]EventRow, len(events))
+	for i, event := range events {
+		year, month, day, hour := eventTime(event)
+		rows[i] = EventRowFromProto(event, year, month, day, hour)
+		rows[i].enrichGeo()
+		rows[i].AmountUSD = purchaseAmountUSD(event, fixedConverter{})
+	}
+	rowData, err := writer.Write(rows)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	arrowData := arrowTestFile(t, writer, events, nil)
+
+	rowFile, err := parquet.OpenFile(bytes.NewReader(rowData), int64(len(rowData)))
+	if err != nil {
+		t.Fatalf("OpenFile(rows) error = %v", err)
+	}
+	arrowFile, err := parquet.OpenFile(bytes.NewReader(arrowData), int64(len(arrowData)))
+	if err != nil {
+		t.Fatalf("OpenFile(arrow) error = %v", err)
+	}
+	if got, want := arrowFile.Schema().String(), rowFile.Schema().String(); got != want {
+		t.Errorf("arrow schema =\n%s\nwant\n%s", got, want)
+	}
+	for _, key := range []string{MetadataStream, MetadataStreamSequences} {
+		want, _ := rowFile.Lookup(key)
+		if got, ok := arrowFile.Lookup(key); !ok || got != want {
+			t.Errorf("metadata %s = %q, want %q", key, got, want)
+		}
+	}
+
+	wantRows, err := parquet.Read[EventRow](bytes.NewReader(rowData), int64(len(rowData)))
+	if err != nil {
+		t.Fatalf("Read(rows) error = %v", err)
+	}
+	gotRows, err := parquet.Read[EventRow](bytes.NewReader(arrowData), int64(len(arrowData)))
+	if err != nil {
+		t.Fatalf("Read(arrow) error = %v", err)
+	}
+	if !reflect.DeepEqual(gotRows, wantRows) {
+		for i := range min(len(gotRows), len(wantRows)) {
+			if gotRows[i] != wantRows[i] {
+				t.Fatalf("row %d = %+v, want %+v", i, gotRows[i], wantRows[i])
+			}
+		}
+		t.Fatalf("read %d rows, want %d", len(gotRows), len(wantRows))
+	}
+
+	stats, err := InspectParquet(bytes.NewReader(arrowData), int64(len(arrowData)))
+	if err != nil {
+		t.Fatalf("InspectParquet() error = %v", err)
+	}
+	if missing := stats.MissingStats(StatsColumns); len(missing) > 0 {
+		t.Errorf("MissingStats() = %v, want none", missing)
+	}
+
+	// Compaction merges files of both paths
+	merged, err := parquet.MergeRowGroups([]parquet.RowGroup{rowFile.RowGroups()[0], arrowFile.RowGroups()[0]}, EventRowSchema(nil))
+	if err != nil {
+		t.Fatalf("MergeRowGroups() error = %v", err)
+	}
+	if merged.NumRows() != int64(2*len(events)) {
+		t.Errorf("merged rows = %d, want %d", merged.NumRows(), 2*len(events))
+	}
+}
+
+// TestWriteArrow_Promoted verifies the promoted columns are filled like
+// WritePromoted fills them.
+func TestWriteArrow_Promoted(t *testing.T) {
+	writer := NewParquetWriter(ParquetConfig{Compression: "snappy"})
+	base := time.Date(2026, 3, 10, 14, 0, 0, 0, time.UTC).UnixMilli()
+	events := []*pb.EventEnvelope{
+		promotionTestEvent("app", base+2000, map[string]interface{}{"character": "mage", "level": int64(3), "score": 2.5}),
+		promotionTestEvent("app", base+1000, map[string]interface{}{"character": int64(7), "level": 4.0, "score": int64(9)}),
+		promotionTestEvent("app", base+3000, map[string]interface{}{"level": 4.5}),
+		{Id: "screen", AppId: "app", TimestampMs: base + 4000, Payload: &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}}},
+	}
+	columns := []PromotedColumn{
+		{Key: "character", Column: "prop_character", Type: PromotedString},
+		{Key: "level", Column: "prop_level", Type: PromotedInt64},
+		{Key: "score", Column: "prop_score", Type: PromotedDouble},
+	}
+
+	rows := make([]EventRow, len(events))
+	for i, event := range events {
+		year, month, day, hour := eventTime(event)
+		rows[i] = EventRowFromProto(event, year, month, day, hour)
+	}
+	want, err := writer.WritePromoted(rows, events, columns)
+	if err != nil {
+		t.Fatalf("WritePromoted() error = %v", err)
+	}
+	got := arrowTestFile(t, writer, events, columns)
+
+	file, err := parquet.OpenFile(bytes.NewReader(got), int64(len(got)))
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	if cols := PromotedColumnsOf(file.Schema()); !reflect.DeepEqual(cols, columns) {
+		t.Errorf("PromotedColumnsOf() = %+v, want %+v", cols, columns)
+	}
+
+	gotRows, err := parquet.Read[promotedTestRow](bytes.NewReader(got), int64(len(got)))
+	if err != nil {
+		t.Fatalf("Read(arrow) error = %v", err)
+	}
+	wantRows, err := parquet.Read[promotedTestRow](bytes.NewReader(want), int64(len(want)))
+	if err != nil {
+		t.Fatalf("Read(rows) error = %v", err)
+	}
+	if !reflect.DeepEqual(gotRows, wantRows) {
+		t.Errorf("rows = %+v, want %+v", gotRows, wantRows)
+	}
+}
+
+func TestWriteArrow_Empty(t *testing.T) {
+	builder := NewArrowBatchBuilder(memory.DefaultAllocator, nil)
+	defer builder.Release()
+	rec := builder.NewRecord()
+	defer rec.Release()
+
+	if _, err := NewParquetWriter(ParquetConfig{}).WriteArrow(rec); !errors.Is(err, ErrNoRowsToWrite) {
+		t.Errorf("WriteArrow() error = %v, want ErrNoRowsToWrite", err)
+	}
+}
+
+func TestConsumer_EncodePartitionArrow(t *testing.T) {
+	events := mixedTestEvents(50)
+	tracked := make([]trackedEvent, len(events))
+	for i, event := range events {
+		tracked[i] = trackedEvent{event: event}
+	}
+
+	encode := func(arrow bool) ([]byte, DeltaFile) {
+		t.Helper()
+		cfg := Config{Parquet: ParquetConfig{Compression: "snappy", Arrow: arrow}}
+		c := &Consumer{config: cfg, parquet: NewParquetWriter(cfg.Parquet)}
+		data, deltaFile, err := c.encodePartition(tracked)
+		if err != nil {
+			t.Fatalf("encodePartition(arrow=%v) error = %v", arrow, err)
+		}
+		return data, deltaFile("key", int64(len(data)))
+	}
+
+	rowData, rowDelta := encode(false)
+	arrowData, arrowDelta := encode(true)
+	rowDelta.Size, arrowDelta.Size = 0, 0
+	if !reflect.DeepEqual(arrowDelta, rowDelta) {
+		t.Errorf("arrow delta file = %+v, want %+v", arrowDelta, rowDelta)
+	}
+
+	wantRows, err := parquet.Read[EventRow](bytes.NewReader(rowData), int64(len(rowData)))
+	if err != nil {
+		t.Fatalf("Read(rows) error = %v", err)
+	}
+	gotRows, err := parquet.Read[EventRow](bytes.NewReader(arrowData), int64(len(arrowData)))
+	if err != nil {
+		t.Fatalf("Read(arrow) error = %v", err)
+	}
+	if !reflect.DeepEqual(gotRows, wantRows) {
+		t.Error("arrow rows differ from EventRow rows")
+	}
+}
+
+// The benchmarks compare the two ways a partition is encoded, from its
+// EventEnvelopes to Parquet bytes:
+//
+//	go test ./internal/warehouse -run '^$' -bench BenchmarkParquetWriter -benchmem
+
+const benchmarkPartitionEvents = 10000
+
+func BenchmarkParquetWriter_EventRow(b *testing.B) {
+	writer := NewParquetWriter(ParquetConfig{Compression: "snappy", PageStatistics: true, ColumnIndexSizeLimit: 64})
+	events := mixedTestEvents(benchmarkPartitionEvents)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rows := make([]EventRow, len(events))
+		for j, event := range events {
+			year, month, day, hour := eventTime(event)
+			rows[j] = EventRowFromProto(event, year, month, day, hour)
+			rows[j].enrichGeo()
+			rows[j].AmountUSD = purchaseAmountUSD(event, fixedConverter{})
+		}
+		if _, err := writer.Write(rows); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParquetWriter_Arrow(b *testing.B) {
+	writer := NewParquetWriter(ParquetConfig{Compression: "snappy", PageStatistics: true, ColumnIndexSizeLimit: 64})
+	events := mixedTestEvents(benchmarkPartitionEvents)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		arrowTestFile(b, writer, events, nil)
+	}
+}
@@ -3,6 +3,8 @@ package warehouse
 
 import (
 	"time"
+
+	"github.com/SebastienMelki/causality/internal/nats"
 )
 
 // Config holds warehouse sink configuration.
@@ -16,6 +18,15 @@ type Config struct {
 	// Parquet configuration
 	Parquet ParquetConfig `envPrefix:"PARQUET_"`
 
+	// Delta Lake transaction log configuration
+	Delta DeltaConfig `envPrefix:"DELTA_"`
+
+	// Geo enrichment configuration
+	Geo GeoConfig `envPrefix:"GEO_"`
+
+	// Custom event property column promotion configuration
+	Promotion PromotionConfig `envPrefix:"PROMOTION_"`
+
 	// ShutdownTimeout is the maximum time to wait for graceful shutdown.
 	// During shutdown, in-flight batches are flushed. If this timeout expires,
 	// remaining messages may be lost.
@@ -24,6 +35,13 @@ type Config struct {
 
 // S3Config holds S3/MinIO configuration.
 type S3Config struct {
+	// Backend selects the object store: s3 (S3/MinIO) or fs (local
+	// directory at FSRoot, for tests and local runs)
+	Backend string `env:"BACKEND" envDefault:"s3"`
+
+	// FSRoot is the directory buckets are created in when Backend is fs
+	FSRoot string `env:"FS_ROOT" envDefault:"./data/lake"`
+
 	// Endpoint is the S3 endpoint URL (e.g., "http://localhost:9000" for MinIO)
 	Endpoint string `env:"ENDPOINT" envDefault:"http://localhost:9000"`
 
@@ -44,11 +62,36 @@ type S3Config struct {
 
 	// Prefix is the key prefix for all objects
 	Prefix string `env:"PREFIX" envDefault:"events"`
+
+	// PartitionTemplate is the partition layout under Prefix, as slash
+	// separated segments: app_id, category, user_bucket, date, hour (see
+	// PartitionScheme)
+	PartitionTemplate string `env:"PARTITION_TEMPLATE" envDefault:"app_id/date/hour"`
+
+	// PartitionUserBuckets is the number of user buckets for the user_bucket
+	// segment
+	PartitionUserBuckets int `env:"PARTITION_USER_BUCKETS" envDefault:"16"`
+
+	// SSE is the server-side encryption mode for uploaded objects (none, sse-s3, sse-kms)
+	SSE string `env:"SSE" envDefault:"none"`
+
+	// SSEKMSKeyID is the KMS key ID or ARN used when SSE is sse-kms.
+	// Empty uses the bucket's default AWS-managed key.
+	SSEKMSKeyID string `env:"SSE_KMS_KEY_ID"`
+
+	// TaggingEnabled attaches object tags (app_id, retention-class) to uploads
+	TaggingEnabled bool `env:"OBJECT_TAGGING_ENABLED" envDefault:"true"`
+
+	// RetentionClass is the retention-class tag value used by lifecycle rules
+	RetentionClass string `env:"RETENTION_CLASS" envDefault:"standard"`
+
+	// ExtraTags are additional static tags applied to every object (key:value,...)
+	ExtraTags map[string]string `env:"OBJECT_TAGS"`
 }
 
 // BatchConfig holds event batching configuration.
 type BatchConfig struct {
-	// MaxEvents is the maximum number of events per batch
+	// MaxEvents is the maximum number of events per worker batch
 	MaxEvents int `env:"MAX_EVENTS" envDefault:"10000"`
 
 	// FlushInterval is the maximum time to wait before flushing a batch
@@ -58,10 +101,14 @@ type BatchConfig struct {
 	MaxConcurrentWrites int `env:"MAX_CONCURRENT_WRITES" envDefault:"4"`
 
 	// WorkerCount is the number of goroutines that fetch and process messages
-	// from NATS in parallel. Each worker fetches a batch of messages and
-	// adds them to the shared batch buffer.
+	// from NATS in parallel. Each worker fills and flushes its own batch, so
+	// up to WorkerCount * MaxEvents events are buffered.
 	WorkerCount int `env:"WORKER_COUNT" envDefault:"1"`
 
+	// WorkerScaling adjusts the worker count between its bounds based on
+	// the consumer's pending count. WorkerCount is the initial count.
+	WorkerScaling nats.ScalingConfig `envPrefix:"WORKER_SCALING_"`
+
 	// FetchBatchSize is the number of messages to fetch per pull request
 	// from the NATS consumer.
 	FetchBatchSize int `env:"FETCH_BATCH_SIZE" envDefault:"100"`
@@ -74,4 +121,59 @@ type ParquetConfig struct {
 
 	// RowGroupSize is the number of rows per row group
 	RowGroupSize int64 `env:"ROW_GROUP_SIZE" envDefault:"10000"`
+
+	// PageStatistics writes min/max statistics into each data page header
+	// in addition to the column chunk statistics and page index
+	PageStatistics bool `env:"PAGE_STATISTICS" envDefault:"true"`
+
+	// ColumnIndexSizeLimit is the maximum length of min/max values stored in
+	// the page index; longer string values are truncated
+	ColumnIndexSizeLimit int `env:"COLUMN_INDEX_SIZE_LIMIT" envDefault:"64"`
+
+	// Arrow builds partitions as Apache Arrow record batches
+	// (ArrowBatchBuilder) and writes them with the Arrow Parquet writer,
+	// instead of EventRow structs written by parquet-go
+	Arrow bool `env:"ARROW" envDefault:"false"`
+}
+
+// DeltaConfig holds Delta Lake output configuration.
+type DeltaConfig struct {
+	// Enabled writes Delta Lake transaction log commits (_delta_log) under the
+	// S3 prefix so the event lake can be mounted as a Delta table
+	Enabled bool `env:"ENABLED" envDefault:"false"`
+
+	// MaxCommitRetries is the number of versions to try when concurrent
+	// writers race for the same commit version
+	MaxCommitRetries int `env:"MAX_COMMIT_RETRIES" envDefault:"20"`
+}
+
+// GeoConfig holds geo enrichment configuration.
+type GeoConfig struct {
+	// Enabled fills the country and region columns from each event's device
+	// timezone, falling back to its locale (see package geo)
+	Enabled bool `env:"ENABLED" envDefault:"false"`
+}
+
+// PromotionConfig holds custom event property column promotion
+// configuration.
+type PromotionConfig struct {
+	// Enabled tracks the custom_event property keys of each app and writes
+	// the most frequent ones to dedicated prop_{key} columns (see
+	// ColumnPromoter)
+	Enabled bool `env:"ENABLED" envDefault:"false"`
+
+	// MaxColumns is the most keys promoted per app
+	MaxColumns int `env:"MAX_COLUMNS" envDefault:"16"`
+
+	// MinShare is the fraction of an app's custom events that must carry a
+	// key for it to be promoted
+	MinShare float64 `env:"MIN_SHARE" envDefault:"0.25"`
+
+	// MinEvents is the number of custom events of an app to observe before
+	// promoting any of its keys
+	MinEvents uint64 `env:"MIN_EVENTS" envDefault:"1000"`
+
+	// MaxTrackedKeys bounds the distinct keys counted per app; keys first
+	// seen beyond it are ignored
+	MaxTrackedKeys int `env:"MAX_TRACKED_KEYS" envDefault:"1024"`
 }
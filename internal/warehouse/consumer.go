@@ -1,16 +1,20 @@
 package warehouse
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
 	"time"
 
+	"github.com/apache/arrow-go/v18/arrow/memory"
 	"github.com/nats-io/nats.go/jetstream"
-	"google.golang.org/protobuf/proto"
 
+	"github.com/SebastienMelki/causality/internal/events"
+	"github.com/SebastienMelki/causality/internal/nats"
 	"github.com/SebastienMelki/causality/internal/observability"
 	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
 )
@@ -22,22 +26,53 @@ type trackedEvent struct {
 	msg   jetstream.Msg
 }
 
-// Consumer consumes events from NATS JetStream and writes them to S3.
+// workerBatch is the batch owned by one fetch worker. Only its worker appends
+// to it, so adding events is uncontended; the mutex guards against the flush
+// timer and the final flush swapping it out concurrently.
+type workerBatch struct {
+	id        int
+	mu        sync.Mutex
+	events    []trackedEvent
+	lastFlush time.Time
+}
+
+// newWorkerBatches creates one batch per worker the consumer may run. A
+// batch outlives its worker when the pool scales down: it is flushed by the
+// flush timer and picked up again if the worker is restarted.
+func newWorkerBatches(cfg BatchConfig) []*workerBatch {
+	workerCount := nats.MaxWorkerCount(cfg.WorkerScaling, cfg.WorkerCount)
+	batches := make([]*workerBatch, workerCount)
+	for i := range batches {
+		batches[i] = &workerBatch{
+			id:        i,
+			events:    make([]trackedEvent, 0, cfg.MaxEvents),
+			lastFlush: time.Now(),
+		}
+	}
+	return batches
+}
+
+// Consumer consumes events from NATS JetStream and writes them to S3. Each
+// worker fills and flushes its own batch, so workers never wait on each other
+// while batching and scale with WorkerCount. Metrics are recorded on shared
+// instruments without a worker attribute, giving a merged view.
 type Consumer struct {
 	js           jetstream.JetStream
 	config       Config
 	s3Client     *S3Client
+	delta        *DeltaLog
 	parquet      *ParquetWriter
+	currency     CurrencyConverter
+	promoter     *ColumnPromoter
 	logger       *slog.Logger
 	metrics      *observability.Metrics
 	consumerName string
 	streamName   string
 
-	mu        sync.Mutex
-	batch     []trackedEvent
-	lastFlush time.Time
-	stopCh    chan struct{}
-	doneCh    chan struct{}
+	batches []*workerBatch
+	scaler  *nats.WorkerScaler
+	stopCh  chan struct{}
+	doneCh  chan struct{}
 }
 
 // NewConsumer creates a new warehouse consumer.
@@ -45,6 +80,7 @@ func NewConsumer(
 	js jetstream.JetStream,
 	cfg Config,
 	s3Client *S3Client,
+	delta *DeltaLog,
 	consumerName string,
 	streamName string,
 	logger *slog.Logger,
@@ -58,18 +94,30 @@ func NewConsumer(
 		js:           js,
 		config:       cfg,
 		s3Client:     s3Client,
+		delta:        delta,
 		parquet:      NewParquetWriter(cfg.Parquet),
 		logger:       logger.With("component", "warehouse-consumer"),
 		metrics:      metrics,
 		consumerName: consumerName,
 		streamName:   streamName,
-		batch:        make([]trackedEvent, 0, cfg.Batch.MaxEvents),
-		lastFlush:    time.Now(),
+		batches:      newWorkerBatches(cfg.Batch),
 		stopCh:       make(chan struct{}),
 		doneCh:       make(chan struct{}),
 	}
 }
 
+// SetCurrencyConverter sets the converter used to fill the amount_usd column
+// of purchase_complete events. Must be called before Start.
+func (c *Consumer) SetCurrencyConverter(converter CurrencyConverter) {
+	c.currency = converter
+}
+
+// SetColumnPromoter sets the promoter deciding which custom event property
+// keys are written to dedicated columns. Must be called before Start.
+func (c *Consumer) SetColumnPromoter(promoter *ColumnPromoter) {
+	c.promoter = promoter
+}
+
 // Start starts consuming events from NATS with a configurable worker pool.
 func (c *Consumer) Start(ctx context.Context) error {
 	// Get stream and consumer
@@ -83,34 +131,30 @@ func (c *Consumer) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to get consumer: %w", err)
 	}
 
-	workerCount := c.config.Batch.WorkerCount
-	if workerCount < 1 {
-		workerCount = 1
-	}
-
 	c.logger.Info("starting warehouse consumer",
 		"consumer", c.consumerName,
 		"stream", c.streamName,
-		"workers", workerCount,
+		"workers", c.config.Batch.WorkerCount,
+		"max_workers", len(c.batches),
 		"fetch_batch_size", c.config.Batch.FetchBatchSize,
 	)
 
+	ctx = observability.WithLogAttrs(ctx, observability.LogKeyConsumer, c.consumerName)
+
 	// Start flush timer
 	go c.flushTimer(ctx)
 
 	// Start worker pool
-	var wg sync.WaitGroup
-	for i := range workerCount {
-		wg.Add(1)
-		go func(id int) {
-			defer wg.Done()
-			c.workerLoop(ctx, consumer, id)
-		}(i)
-	}
+	c.scaler = nats.NewWorkerScaler(c.config.Batch.WorkerScaling, c.config.Batch.WorkerCount,
+		func(ctx context.Context, id int, stop <-chan struct{}) {
+			c.workerLoop(ctx, consumer, c.batches[id], stop)
+		},
+		nats.ConsumerPending(consumer), c.logger)
+	c.scaler.Start(ctx, c.config.Batch.WorkerCount)
 
 	// Close doneCh when all workers finish
 	go func() {
-		wg.Wait()
+		c.scaler.Wait()
 		close(c.doneCh)
 	}()
 
@@ -118,9 +162,10 @@ func (c *Consumer) Start(ctx context.Context) error {
 }
 
 // workerLoop is the main loop for a single fetch worker. It pulls messages
-// from the NATS consumer and processes them. ACK/NAK is deferred to flush.
-func (c *Consumer) workerLoop(ctx context.Context, consumer jetstream.Consumer, id int) {
-	logger := c.logger.With("worker_id", id)
+// from the NATS consumer into the worker's own batch until stop is closed.
+// ACK/NAK is deferred to flush.
+func (c *Consumer) workerLoop(ctx context.Context, consumer jetstream.Consumer, batch *workerBatch, stop <-chan struct{}) {
+	logger := c.logger.With("worker_id", batch.id)
 	logger.Debug("worker started")
 	defer logger.Debug("worker stopped")
 
@@ -135,6 +180,8 @@ func (c *Consumer) workerLoop(ctx context.Context, consumer jetstream.Consumer,
 			return
 		case <-c.stopCh:
 			return
+		case <-stop:
+			return
 		default:
 			msgs, err := consumer.Fetch(fetchSize, jetstream.FetchMaxWait(5*time.Second))
 			if err != nil {
@@ -147,13 +194,15 @@ func (c *Consumer) workerLoop(ctx context.Context, consumer jetstream.Consumer,
 						return
 					case <-c.stopCh:
 						return
+					case <-stop:
+						return
 					}
 				}
 				continue
 			}
 
 			for msg := range msgs.Messages() {
-				c.processMessage(ctx, msg)
+				c.processMessage(ctx, batch, msg)
 			}
 
 			if err := msgs.Error(); err != nil {
@@ -163,36 +212,40 @@ func (c *Consumer) workerLoop(ctx context.Context, consumer jetstream.Consumer,
 	}
 }
 
-// processMessage deserializes a single NATS message and adds it to the batch.
-// Poison messages (unmarshal failures) are terminated immediately so they are
-// not redelivered. Valid messages are tracked and ACKed/NAKed later in flush.
-func (c *Consumer) processMessage(ctx context.Context, msg jetstream.Msg) {
+// processMessage deserializes a single NATS message and adds it to the
+// worker's batch, flushing the batch when it is full. Poison messages
+// (unmarshal failures) are terminated immediately so they are not
+// redelivered. Valid messages are tracked and ACKed/NAKed later in flush.
+func (c *Consumer) processMessage(ctx context.Context, batch *workerBatch, msg jetstream.Msg) {
+	logger := observability.Logger(ctx, c.logger)
+
 	var event pb.EventEnvelope
-	if err := proto.Unmarshal(msg.Data(), &event); err != nil {
+	if err := events.Decode(msg.Data(), &event); err != nil {
 		// Poison message: terminate to prevent infinite redelivery
-		c.logger.Error("poison message: unmarshal failure, terminating",
+		logger.Error("poison message: unmarshal failure, terminating",
 			"error", err,
 			"subject", msg.Subject(),
 		)
 		if termErr := msg.Term(); termErr != nil {
-			c.logger.Error("failed to terminate poison message", "error", termErr)
+			logger.Error("failed to terminate poison message", "error", termErr)
 		}
 		return
 	}
 
-	c.mu.Lock()
-	c.batch = append(c.batch, trackedEvent{event: &event, msg: msg})
-	shouldFlush := len(c.batch) >= c.config.Batch.MaxEvents
-	c.mu.Unlock()
+	batch.mu.Lock()
+	batch.events = append(batch.events, trackedEvent{event: &event, msg: msg})
+	shouldFlush := len(batch.events) >= c.config.Batch.MaxEvents
+	batch.mu.Unlock()
 
 	if shouldFlush {
-		if err := c.flush(ctx); err != nil {
-			c.logger.Error("failed to flush batch", "error", err)
+		if err := c.flush(ctx, batch); err != nil {
+			logger.Error("failed to flush batch", "error", err)
 		}
 	}
 }
 
-// flushTimer periodically flushes the batch based on time interval.
+// flushTimer periodically flushes worker batches that have not been flushed
+// within the interval. Each batch is checked and flushed independently.
 func (c *Consumer) flushTimer(ctx context.Context) {
 	ticker := time.NewTicker(c.config.Batch.FlushInterval)
 	defer ticker.Stop()
@@ -204,44 +257,48 @@ func (c *Consumer) flushTimer(ctx context.Context) {
 		case <-c.stopCh:
 			return
 		case <-ticker.C:
-			c.mu.Lock()
-			batchLen := len(c.batch)
-			timeSinceFlush := time.Since(c.lastFlush)
-			c.mu.Unlock()
-
-			if batchLen > 0 && timeSinceFlush >= c.config.Batch.FlushInterval {
-				c.logger.Debug("time-based flush triggered",
-					"batch_size", batchLen,
-					"interval", timeSinceFlush,
-				)
-				if err := c.flush(ctx); err != nil {
-					c.logger.Error("failed to flush batch on timer", "error", err)
+			for _, batch := range c.batches {
+				batch.mu.Lock()
+				batchLen := len(batch.events)
+				timeSinceFlush := time.Since(batch.lastFlush)
+				batch.mu.Unlock()
+
+				if batchLen > 0 && timeSinceFlush >= c.config.Batch.FlushInterval {
+					c.logger.Debug("time-based flush triggered",
+						"worker_id", batch.id,
+						"batch_size", batchLen,
+						"interval", timeSinceFlush,
+					)
+					if err := c.flush(ctx, batch); err != nil {
+						c.logger.Error("failed to flush batch on timer", "worker_id", batch.id, "error", err)
+					}
 				}
 			}
 		}
 	}
 }
 
-// flush writes the current batch to S3.
+// flush writes a worker's current batch to S3.
 // For each partition, messages are ACKed only after a successful S3 write.
 // On write failure, messages are NAKed so NATS redelivers them.
-func (c *Consumer) flush(ctx context.Context) error {
+func (c *Consumer) flush(ctx context.Context, batch *workerBatch) error {
 	flushStart := time.Now()
+	logger := observability.Logger(ctx, c.logger).With("worker_id", batch.id)
 
-	c.mu.Lock()
-	if len(c.batch) == 0 {
-		c.mu.Unlock()
+	batch.mu.Lock()
+	if len(batch.events) == 0 {
+		batch.mu.Unlock()
 		return nil
 	}
 
 	// Swap batch
-	tracked := c.batch
-	c.batch = make([]trackedEvent, 0, c.config.Batch.MaxEvents)
-	c.lastFlush = time.Now()
-	c.mu.Unlock()
+	tracked := batch.events
+	batch.events = make([]trackedEvent, 0, c.config.Batch.MaxEvents)
+	batch.lastFlush = time.Now()
+	batch.mu.Unlock()
 
 	batchSize := len(tracked)
-	c.logger.Info("flushing batch", "count", batchSize)
+	logger.Info("flushing batch", "count", batchSize)
 
 	// Record batch size metric
 	if c.metrics != nil {
@@ -253,8 +310,17 @@ func (c *Consumer) flush(ctx context.Context) error {
 
 	// Write each partition
 	for key, partitionTracked := range partitions {
-		if err := c.writePartition(ctx, key, partitionTracked); err != nil {
-			c.logger.Error("failed to write partition, NAKing messages for redelivery",
+		writeStart := time.Now()
+		err := c.writePartition(ctx, key, partitionTracked)
+		if c.metrics != nil {
+			failed := 0
+			if err != nil {
+				failed = len(partitionTracked)
+			}
+			c.metrics.RecordConsumed(ctx, "warehouse", len(partitionTracked), failed, time.Since(writeStart))
+		}
+		if err != nil {
+			logger.Error("failed to write partition, NAKing messages for redelivery",
 				"partition", key,
 				"events", len(partitionTracked),
 				"error", err,
@@ -262,7 +328,7 @@ func (c *Consumer) flush(ctx context.Context) error {
 			// NAK all messages in the failed partition so NATS redelivers them
 			for _, t := range partitionTracked {
 				if nakErr := t.msg.Nak(); nakErr != nil {
-					c.logger.Error("failed to NAK message", "error", nakErr)
+					logger.Error("failed to NAK message", "error", nakErr)
 				}
 			}
 			continue
@@ -271,7 +337,7 @@ func (c *Consumer) flush(ctx context.Context) error {
 		// Partition written successfully: ACK all messages
 		for _, t := range partitionTracked {
 			if ackErr := t.msg.Ack(); ackErr != nil {
-				c.logger.Error("failed to ACK message after successful write", "error", ackErr)
+				logger.Error("failed to ACK message after successful write", "error", ackErr)
 			}
 		}
 
@@ -282,13 +348,21 @@ func (c *Consumer) flush(ctx context.Context) error {
 		}
 	}
 
+	// Promote property keys that became frequent; new columns are written
+	// from the next flush on
+	if c.promoter != nil {
+		if err := c.promoter.Update(ctx); err != nil {
+			logger.Warn("failed to update promoted columns", "error", err)
+		}
+	}
+
 	// Record flush latency
 	if c.metrics != nil {
 		flushDuration := float64(time.Since(flushStart).Milliseconds())
 		c.metrics.NATSFlushLatency.Record(ctx, flushDuration)
 	}
 
-	c.logger.Info("batch flushed",
+	logger.Info("batch flushed",
 		"count", batchSize,
 		"partitions", len(partitions),
 		"duration_ms", time.Since(flushStart).Milliseconds(),
@@ -297,55 +371,52 @@ func (c *Consumer) flush(ctx context.Context) error {
 	return nil
 }
 
-// partitionKey represents a unique partition for events.
-type partitionKey struct {
-	AppID string
-	Year  int
-	Month int
-	Day   int
-	Hour  int
-}
-
-// groupByPartition groups tracked events by their partition key.
-func (c *Consumer) groupByPartition(tracked []trackedEvent) map[partitionKey][]trackedEvent {
-	partitions := make(map[partitionKey][]trackedEvent)
+// groupByPartition groups tracked events by their partition, following the
+// configured partition template.
+func (c *Consumer) groupByPartition(tracked []trackedEvent) map[Partition][]trackedEvent {
+	scheme := c.partitionScheme()
+	partitions := make(map[Partition][]trackedEvent)
 
 	for _, t := range tracked {
-		ts := time.UnixMilli(t.event.GetTimestampMs()).UTC()
-		key := partitionKey{
-			AppID: t.event.GetAppId(),
-			Year:  ts.Year(),
-			Month: int(ts.Month()),
-			Day:   ts.Day(),
-			Hour:  ts.Hour(),
-		}
-
+		key := scheme.PartitionOf(t.event)
 		partitions[key] = append(partitions[key], t)
 	}
 
 	return partitions
 }
 
-// writePartition writes a partition of tracked events to S3.
-func (c *Consumer) writePartition(ctx context.Context, key partitionKey, tracked []trackedEvent) error {
-	// Extract events from tracked for Parquet conversion
-	rows := make([]EventRow, len(tracked))
-	for i, t := range tracked {
-		rows[i] = EventRowFromProto(t.event, key.Year, key.Month, key.Day, key.Hour)
+// partitionScheme returns the partition scheme of the S3 client, or the one
+// configured when the consumer has no client (tests).
+func (c *Consumer) partitionScheme() *PartitionScheme {
+	if c.s3Client != nil {
+		return c.s3Client.Partitioning()
 	}
+	return c.config.S3.Partitioning()
+}
 
-	// Write to Parquet
-	data, err := c.parquet.Write(rows)
+// writePartition writes a partition of tracked events to S3.
+func (c *Consumer) writePartition(ctx context.Context, key Partition, tracked []trackedEvent) error {
+	// Convert to Parquet
+	data, deltaFile, err := c.encodePartition(tracked)
 	if err != nil {
 		return fmt.Errorf("failed to write parquet: %w", err)
 	}
 
 	// Upload to S3
-	s3Key := c.s3Client.GenerateKey(key.AppID, key.Year, key.Month, key.Day, key.Hour)
-	if err := c.s3Client.Upload(ctx, s3Key, data); err != nil {
+	s3Key := c.s3Client.GenerateKey(key)
+	if err := c.s3Client.Upload(ctx, s3Key, key.AppID, data); err != nil {
 		return fmt.Errorf("failed to upload to S3: %w", err)
 	}
 
+	// Commit the file to the Delta transaction log. If this fails the batch
+	// is redelivered and rewritten; the uploaded file stays unreferenced by
+	// the Delta table.
+	if c.delta != nil {
+		if err := c.delta.Append(ctx, deltaFile(s3Key, int64(len(data)))); err != nil {
+			return fmt.Errorf("failed to commit delta log: %w", err)
+		}
+	}
+
 	// Record file size metric
 	if c.metrics != nil {
 		c.metrics.S3FileSize.Record(ctx, int64(len(data)))
@@ -360,12 +431,137 @@ func (c *Consumer) writePartition(ctx context.Context, key partitionKey, tracked
 	return nil
 }
 
+// flushAll flushes every worker batch, returning the joined errors.
+func (c *Consumer) flushAll(ctx context.Context) error {
+	var errs []error
+	for _, batch := range c.batches {
+		if err := c.flush(ctx, batch); err != nil {
+			errs = append(errs, fmt.Errorf("worker %d: %w", batch.id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// encodePartition converts a partition's events to a Parquet file, through
+// EventRows or an Arrow record batch depending on configuration. It also
+// returns a function building the Delta add-file description once the key
+// is known.
+// The year..hour columns are taken from each event's timestamp, since a
+// partition may span a whole day. Country and region are filled in when geo
+// enrichment is enabled, amount_usd when a currency converter is set, and
+// the promoted property columns of the partition's apps when a column
+// promoter is set. The stream sequences of the messages are recorded in the
+// footer metadata for partition integrity checks.
+func (c *Consumer) encodePartition(tracked []trackedEvent) ([]byte, func(string, int64) DeltaFile, error) {
+	writer := c.parquet
+	if ranges, ok := streamSequences(tracked); ok {
+		writer = writer.WithOptions(SequenceMetadata(c.streamName, ranges, 0)...)
+	}
+
+	var events []*pb.EventEnvelope
+	var promoted []PromotedColumn
+	if c.promoter != nil {
+		events = make([]*pb.EventEnvelope, len(tracked))
+		for i, t := range tracked {
+			events[i] = t.event
+			c.promoter.Observe(t.event)
+		}
+		promoted = c.promoter.Columns(events)
+	}
+
+	if c.config.Parquet.Arrow {
+		return c.encodeArrow(writer, tracked, promoted)
+	}
+
+	rows := make([]EventRow, len(tracked))
+	for i, t := range tracked {
+		year, month, day, hour := eventTime(t.event)
+		rows[i] = EventRowFromProto(t.event, year, month, day, hour)
+		if c.config.Geo.Enabled {
+			rows[i].enrichGeo()
+		}
+		if c.currency != nil {
+			rows[i].AmountUSD = purchaseAmountUSD(t.event, c.currency)
+		}
+	}
+	var data []byte
+	var err error
+	if len(promoted) > 0 {
+		data, err = writer.WritePromoted(rows, events, promoted)
+	} else {
+		data, err = writer.Write(rows)
+	}
+	return data, func(s3Key string, size int64) DeltaFile {
+		return deltaFileFromRows(s3Key, size, rows)
+	}, err
+}
+
+// encodeArrow converts a partition's events to a Parquet file through an
+// Arrow record batch (see encodePartition).
+func (c *Consumer) encodeArrow(writer *ParquetWriter, tracked []trackedEvent, promoted []PromotedColumn) ([]byte, func(string, int64) DeltaFile, error) {
+	// Sort by timestamp, as Write does
+	sorted := make([]*pb.EventEnvelope, len(tracked))
+	for i, t := range tracked {
+		sorted[i] = t.event
+	}
+	slices.SortStableFunc(sorted, func(a, b *pb.EventEnvelope) int {
+		return cmp.Compare(a.GetTimestampMs(), b.GetTimestampMs())
+	})
+
+	builder := NewArrowBatchBuilder(memory.DefaultAllocator, promoted)
+	defer builder.Release()
+	builder.SetGeo(c.config.Geo.Enabled)
+	if c.currency != nil {
+		builder.SetCurrencyConverter(c.currency)
+	}
+	for _, event := range sorted {
+		builder.Append(event)
+	}
+
+	rec := builder.NewRecord()
+	defer rec.Release()
+
+	data, err := writer.WriteArrow(rec)
+	deltaFile := deltaFileFromRecord("", 0, rec)
+	return data, func(s3Key string, size int64) DeltaFile {
+		f := deltaFile
+		f.Key, f.Size = s3Key, size
+		return f
+	}, err
+}
+
+// streamSequences returns the stream sequences of the tracked messages. ok
+// is false when the sequence of a message is unknown.
+func streamSequences(tracked []trackedEvent) (SequenceRanges, bool) {
+	seqs := make([]uint64, len(tracked))
+	for i, t := range tracked {
+		if t.msg == nil {
+			return nil, false
+		}
+		meta, err := t.msg.Metadata()
+		if err != nil || meta.Sequence.Stream == 0 {
+			return nil, false
+		}
+		seqs[i] = meta.Sequence.Stream
+	}
+	return NewSequenceRanges(seqs), true
+}
+
+// eventTime returns the UTC year, month, day and hour of an event.
+func eventTime(event *pb.EventEnvelope) (year, month, day, hour int) {
+	ts := time.UnixMilli(event.GetTimestampMs()).UTC()
+	return ts.Year(), int(ts.Month()), ts.Day(), ts.Hour()
+}
+
 // Stop stops the consumer gracefully. It signals workers to stop, waits for
 // them to finish (up to ShutdownTimeout), and performs a final flush of any
-// remaining messages in the batch.
+// remaining messages in the worker batches.
 func (c *Consumer) Stop(ctx context.Context) error {
 	c.logger.Info("stopping warehouse consumer")
 	close(c.stopCh)
+	if c.scaler != nil {
+		c.scaler.Stop()
+	}
 
 	// Create a shutdown context with the configured timeout
 	shutdownTimeout := c.config.ShutdownTimeout
@@ -387,7 +583,7 @@ func (c *Consumer) Stop(ctx context.Context) error {
 
 	// Final flush of any remaining messages
 	c.logger.Info("performing final flush")
-	if err := c.flush(shutdownCtx); err != nil {
+	if err := c.flushAll(shutdownCtx); err != nil {
 		c.logger.Error("failed final flush, messages may be redelivered by NATS", "error", err)
 		return fmt.Errorf("final flush failed: %w", err)
 	}
@@ -395,3 +591,23 @@ func (c *Consumer) Stop(ctx context.Context) error {
 	c.logger.Info("warehouse consumer stopped")
 	return nil
 }
+
+// deltaFileFromRows builds the Delta add-file description, including
+// min/max statistics for the pruning columns, for a written partition file.
+func deltaFileFromRows(key string, size int64, rows []EventRow) DeltaFile {
+	f := DeltaFile{Key: key, Size: size, NumRecords: int64(len(rows))}
+	if len(rows) == 0 {
+		return f
+	}
+
+	minTS, maxTS := rows[0].TimestampMS, rows[0].TimestampMS
+	minType, maxType := rows[0].EventType, rows[0].EventType
+	for _, r := range rows[1:] {
+		minTS, maxTS = min(minTS, r.TimestampMS), max(maxTS, r.TimestampMS)
+		minType, maxType = min(minType, r.EventType), max(maxType, r.EventType)
+	}
+
+	f.MinValues = map[string]any{"timestamp_ms": minTS, "event_type": minType}
+	f.MaxValues = map[string]any{"timestamp_ms": maxTS, "event_type": maxType}
+	return f
+}
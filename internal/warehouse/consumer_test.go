@@ -2,16 +2,20 @@
 package warehouse
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
+	"github.com/parquet-go/parquet-go"
 	"go.opentelemetry.io/otel/metric/noop"
 	"google.golang.org/protobuf/proto"
 
@@ -23,6 +27,7 @@ import (
 type mockJetStreamMsg struct {
 	data       []byte
 	subject    string
+	seq        uint64
 	ackCalled  atomic.Bool
 	nakCalled  atomic.Bool
 	termCalled atomic.Bool
@@ -81,7 +86,7 @@ func (m *mockJetStreamMsg) DoubleAck(ctx context.Context) error {
 }
 
 func (m *mockJetStreamMsg) Metadata() (*jetstream.MsgMetadata, error) {
-	return &jetstream.MsgMetadata{}, nil
+	return &jetstream.MsgMetadata{Sequence: jetstream.SequencePair{Stream: m.seq}}, nil
 }
 
 // mockS3Client mocks S3 operations for testing.
@@ -91,13 +96,13 @@ type mockS3Client struct {
 	uploadedKey string
 }
 
-func (m *mockS3Client) Upload(_ context.Context, key string, _ []byte) error {
+func (m *mockS3Client) Upload(_ context.Context, key, _ string, _ []byte) error {
 	m.uploadCalls.Add(1)
 	m.uploadedKey = key
 	return m.uploadErr
 }
 
-func (m *mockS3Client) GenerateKey(appID string, year, month, day, hour int) string {
+func (m *mockS3Client) GenerateKey(_ Partition) string {
 	return "test-key.parquet"
 }
 
@@ -126,8 +131,7 @@ func createTestConsumer(t *testing.T) *Consumer {
 		config:       cfg,
 		parquet:      NewParquetWriter(cfg.Parquet),
 		logger:       logger,
-		batch:        make([]trackedEvent, 0, cfg.Batch.MaxEvents),
-		lastFlush:    time.Now(),
+		batches:      newWorkerBatches(cfg.Batch),
 		stopCh:       make(chan struct{}),
 		doneCh:       make(chan struct{}),
 	}
@@ -155,7 +159,7 @@ func TestProcessMessage_UnmarshalError_TermsMessage(t *testing.T) {
 	}
 
 	// Process the message
-	c.processMessage(context.Background(), msg)
+	c.processMessage(context.Background(), c.batches[0], msg)
 
 	// msg.Term() should have been called
 	if !msg.termCalled.Load() {
@@ -196,21 +200,52 @@ func TestProcessMessage_ValidEvent_AddsToBatch(t *testing.T) {
 	}
 
 	// Process the message
-	c.processMessage(context.Background(), msg)
+	c.processMessage(context.Background(), c.batches[0], msg)
 
 	// Verify event was added to batch
-	if len(c.batch) != 1 {
-		t.Errorf("Batch length = %d, want 1", len(c.batch))
+	if len(c.batches[0].events) != 1 {
+		t.Errorf("Batch length = %d, want 1", len(c.batches[0].events))
 	}
 
 	// Verify the tracked event has the message reference (compare as interface)
-	if c.batch[0].msg == nil {
+	if c.batches[0].events[0].msg == nil {
 		t.Error("Tracked event should have a message reference")
 	}
 
 	// Verify event data is correct
-	if c.batch[0].event.Id != "test-event-1" {
-		t.Errorf("Event ID = %q, want %q", c.batch[0].event.Id, "test-event-1")
+	if c.batches[0].events[0].event.Id != "test-event-1" {
+		t.Errorf("Event ID = %q, want %q", c.batches[0].events[0].event.Id, "test-event-1")
+	}
+}
+
+// TestEncodePartition_RecordsStreamSequences verifies written files record
+// the stream sequences of their events in the footer.
+func TestEncodePartition_RecordsStreamSequences(t *testing.T) {
+	c := createTestConsumer(t)
+	c.streamName = "CAUSALITY_EVENTS"
+
+	var tracked []trackedEvent
+	for _, seq := range []uint64{7, 3, 4, 5, 9} {
+		tracked = append(tracked, trackedEvent{
+			event: &pb.EventEnvelope{Id: fmt.Sprintf("evt-%d", seq), AppId: "test-app"},
+			msg:   &mockJetStreamMsg{seq: seq},
+		})
+	}
+
+	data, _, err := c.encodePartition(tracked)
+	if err != nil {
+		t.Fatalf("encodePartition() error = %v", err)
+	}
+	f, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := ReadFileIntegrity("test-key.parquet", f)
+	if err != nil {
+		t.Fatalf("ReadFileIntegrity() error = %v", err)
+	}
+	if !info.HasSequences || info.Stream != "CAUSALITY_EVENTS" || info.Sequences.String() != "3-5,7,9" || info.Rows != 5 {
+		t.Errorf("ReadFileIntegrity() = %+v, want 5 rows at CAUSALITY_EVENTS 3-5,7,9", info)
 	}
 }
 
@@ -267,7 +302,7 @@ func TestGroupByPartition(t *testing.T) {
 func TestFlush_EmptyBatch(t *testing.T) {
 	c := createTestConsumer(t)
 
-	err := c.flush(context.Background())
+	err := c.flush(context.Background(), c.batches[0])
 	if err != nil {
 		t.Errorf("flush() with empty batch should not return error: %v", err)
 	}
@@ -337,11 +372,11 @@ func TestFlush_NAKOnWriteError_Simulation(t *testing.T) {
 	}
 }
 
-// TestPartitionKey verifies partitionKey struct behavior.
+// TestPartitionKey verifies Partition struct behavior.
 func TestPartitionKey(t *testing.T) {
-	key1 := partitionKey{AppID: "app-1", Year: 2026, Month: 1, Day: 15, Hour: 10}
-	key2 := partitionKey{AppID: "app-1", Year: 2026, Month: 1, Day: 15, Hour: 10}
-	key3 := partitionKey{AppID: "app-2", Year: 2026, Month: 1, Day: 15, Hour: 10}
+	key1 := Partition{AppID: "app-1", Year: 2026, Month: 1, Day: 15, Hour: 10}
+	key2 := Partition{AppID: "app-1", Year: 2026, Month: 1, Day: 15, Hour: 10}
+	key3 := Partition{AppID: "app-2", Year: 2026, Month: 1, Day: 15, Hour: 10}
 
 	// Same partition keys should be equal (used as map keys)
 	if key1 != key2 {
@@ -393,12 +428,13 @@ func TestFlushTimer_TriggersFlush(t *testing.T) {
 		config:       cfg,
 		parquet:      NewParquetWriter(cfg.Parquet),
 		logger:       logger,
-		batch:        make([]trackedEvent, 0, cfg.Batch.MaxEvents),
-		lastFlush:    time.Now().Add(-100 * time.Millisecond), // Set past flush time
+		batches:      newWorkerBatches(cfg.Batch),
 		stopCh:       make(chan struct{}),
 		doneCh:       make(chan struct{}),
 	}
 
+	c.batches[0].lastFlush = time.Now().Add(-100 * time.Millisecond) // Set past flush time
+
 	// Note: Don't add events to batch because flush() will panic without S3Client
 	// Instead, we test that the timer ticks and checks the condition.
 	// With empty batch, flush returns early without hitting S3.
@@ -519,8 +555,7 @@ func TestStop_TimesOutWaitingForWorkers(t *testing.T) {
 		config:       cfg,
 		parquet:      NewParquetWriter(cfg.Parquet),
 		logger:       logger,
-		batch:        make([]trackedEvent, 0, cfg.Batch.MaxEvents),
-		lastFlush:    time.Now(),
+		batches:      newWorkerBatches(cfg.Batch),
 		stopCh:       make(chan struct{}),
 		doneCh:       make(chan struct{}), // Never closed - simulates stuck workers
 	}
@@ -561,8 +596,7 @@ func TestProcessMessage_BatchThreshold_ChecksShouldFlush(t *testing.T) {
 		config:       cfg,
 		parquet:      NewParquetWriter(cfg.Parquet),
 		logger:       logger,
-		batch:        make([]trackedEvent, 0, cfg.Batch.MaxEvents),
-		lastFlush:    time.Now(),
+		batches:      newWorkerBatches(cfg.Batch),
 		stopCh:       make(chan struct{}),
 		doneCh:       make(chan struct{}),
 	}
@@ -578,11 +612,11 @@ func TestProcessMessage_BatchThreshold_ChecksShouldFlush(t *testing.T) {
 	msg1 := &mockJetStreamMsg{data: data1, subject: "events.test"}
 
 	// Process first message - should add to batch
-	c.processMessage(context.Background(), msg1)
+	c.processMessage(context.Background(), c.batches[0], msg1)
 
-	c.mu.Lock()
-	batchLen1 := len(c.batch)
-	c.mu.Unlock()
+	c.batches[0].mu.Lock()
+	batchLen1 := len(c.batches[0].events)
+	c.batches[0].mu.Unlock()
 
 	if batchLen1 != 1 {
 		t.Errorf("After first message, batch len = %d, want 1", batchLen1)
@@ -590,9 +624,9 @@ func TestProcessMessage_BatchThreshold_ChecksShouldFlush(t *testing.T) {
 
 	// The shouldFlush logic checks len(batch) >= MaxEvents
 	// We verify the batch size check is working
-	c.mu.Lock()
-	shouldFlush := len(c.batch) >= c.config.Batch.MaxEvents
-	c.mu.Unlock()
+	c.batches[0].mu.Lock()
+	shouldFlush := len(c.batches[0].events) >= c.config.Batch.MaxEvents
+	c.batches[0].mu.Unlock()
 
 	if shouldFlush {
 		t.Error("shouldFlush should be false with 1 event and MaxEvents=2")
@@ -605,7 +639,7 @@ func TestFlush_WithMetrics_RecordsValues(t *testing.T) {
 	c.metrics = createTestMetrics(t)
 
 	// Empty batch - flush should return early without issues
-	err := c.flush(context.Background())
+	err := c.flush(context.Background(), c.batches[0])
 	if err != nil {
 		t.Errorf("flush() with empty batch and metrics returned error: %v", err)
 	}
@@ -622,13 +656,13 @@ func TestNewConsumer_SetsDefaults(t *testing.T) {
 		},
 	}
 
-	c := NewConsumer(nil, cfg, nil, "test-consumer", "test-stream", nil, nil)
+	c := NewConsumer(nil, cfg, nil, nil, "test-consumer", "test-stream", nil, nil)
 
 	if c.logger == nil {
 		t.Error("Consumer should have a default logger")
 	}
-	if c.batch == nil {
-		t.Error("Consumer should have initialized batch slice")
+	if len(c.batches) != 1 || c.batches[0].events == nil {
+		t.Error("Consumer should have initialized one batch per worker")
 	}
 	if c.stopCh == nil {
 		t.Error("Consumer should have initialized stopCh")
@@ -648,7 +682,7 @@ func TestNewConsumer_WithMetrics(t *testing.T) {
 	}
 
 	metrics := createTestMetrics(t)
-	c := NewConsumer(nil, cfg, nil, "test", "stream", nil, metrics)
+	c := NewConsumer(nil, cfg, nil, nil, "test", "stream", nil, metrics)
 
 	if c.metrics != metrics {
 		t.Error("Consumer should store the provided metrics")
@@ -667,7 +701,7 @@ func TestProcessMessage_TermError_LogsError(t *testing.T) {
 	}
 
 	// This should not panic even though Term() fails
-	c.processMessage(context.Background(), msg)
+	c.processMessage(context.Background(), c.batches[0], msg)
 
 	// Verify Term was attempted
 	if !msg.termCalled.Load() {
@@ -680,21 +714,21 @@ func TestFlush_SwapsBatch_EmptyDoesNotSwap(t *testing.T) {
 	c := createTestConsumer(t)
 
 	// Empty batch
-	initialLen := len(c.batch)
+	initialLen := len(c.batches[0].events)
 	if initialLen != 0 {
 		t.Fatalf("Initial batch len = %d, want 0", initialLen)
 	}
 
 	// Flush with empty batch returns early
-	err := c.flush(context.Background())
+	err := c.flush(context.Background(), c.batches[0])
 	if err != nil {
 		t.Errorf("flush() with empty batch returned error: %v", err)
 	}
 
 	// Batch should still be empty
-	c.mu.Lock()
-	afterLen := len(c.batch)
-	c.mu.Unlock()
+	c.batches[0].mu.Lock()
+	afterLen := len(c.batches[0].events)
+	c.batches[0].mu.Unlock()
 
 	if afterLen != 0 {
 		t.Errorf("After flush, batch len = %d, want 0", afterLen)
@@ -707,14 +741,14 @@ func TestFlush_EmptyBatch_DoesNotUpdateLastFlush(t *testing.T) {
 
 	// Set lastFlush to past
 	pastTime := time.Now().Add(-10 * time.Minute)
-	c.lastFlush = pastTime
+	c.batches[0].lastFlush = pastTime
 
 	// Flush with empty batch
-	_ = c.flush(context.Background())
+	_ = c.flush(context.Background(), c.batches[0])
 
-	c.mu.Lock()
-	newLastFlush := c.lastFlush
-	c.mu.Unlock()
+	c.batches[0].mu.Lock()
+	newLastFlush := c.batches[0].lastFlush
+	c.batches[0].mu.Unlock()
 
 	// lastFlush should NOT be updated for empty batch (returns early)
 	if !newLastFlush.Equal(pastTime) {
@@ -804,8 +838,7 @@ func TestWorkerLoop_ContextCancel(t *testing.T) {
 		config:       cfg,
 		parquet:      NewParquetWriter(cfg.Parquet),
 		logger:       logger,
-		batch:        make([]trackedEvent, 0, cfg.Batch.MaxEvents),
-		lastFlush:    time.Now(),
+		batches:      newWorkerBatches(cfg.Batch),
 		stopCh:       make(chan struct{}),
 		doneCh:       make(chan struct{}),
 	}
@@ -814,7 +847,7 @@ func TestWorkerLoop_ContextCancel(t *testing.T) {
 
 	done := make(chan struct{})
 	go func() {
-		c.workerLoop(ctx, consumer, 0)
+		c.workerLoop(ctx, consumer, c.batches[0], nil)
 		close(done)
 	}()
 
@@ -853,15 +886,14 @@ func TestWorkerLoop_StopChannel(t *testing.T) {
 		config:       cfg,
 		parquet:      NewParquetWriter(cfg.Parquet),
 		logger:       logger,
-		batch:        make([]trackedEvent, 0, cfg.Batch.MaxEvents),
-		lastFlush:    time.Now(),
+		batches:      newWorkerBatches(cfg.Batch),
 		stopCh:       make(chan struct{}),
 		doneCh:       make(chan struct{}),
 	}
 
 	done := make(chan struct{})
 	go func() {
-		c.workerLoop(context.Background(), consumer, 0)
+		c.workerLoop(context.Background(), consumer, c.batches[0], nil)
 		close(done)
 	}()
 
@@ -906,8 +938,7 @@ func TestWorkerLoop_FetchError(t *testing.T) {
 		config:       cfg,
 		parquet:      NewParquetWriter(cfg.Parquet),
 		logger:       logger,
-		batch:        make([]trackedEvent, 0, cfg.Batch.MaxEvents),
-		lastFlush:    time.Now(),
+		batches:      newWorkerBatches(cfg.Batch),
 		stopCh:       make(chan struct{}),
 		doneCh:       make(chan struct{}),
 	}
@@ -917,7 +948,7 @@ func TestWorkerLoop_FetchError(t *testing.T) {
 
 	done := make(chan struct{})
 	go func() {
-		c.workerLoop(ctx, consumer, 0)
+		c.workerLoop(ctx, consumer, c.batches[0], nil)
 		close(done)
 	}()
 
@@ -975,8 +1006,7 @@ func TestWorkerLoop_ProcessesMessages(t *testing.T) {
 		config:       cfg,
 		parquet:      NewParquetWriter(cfg.Parquet),
 		logger:       logger,
-		batch:        make([]trackedEvent, 0, cfg.Batch.MaxEvents),
-		lastFlush:    time.Now(),
+		batches:      newWorkerBatches(cfg.Batch),
 		stopCh:       make(chan struct{}),
 		doneCh:       make(chan struct{}),
 	}
@@ -986,7 +1016,7 @@ func TestWorkerLoop_ProcessesMessages(t *testing.T) {
 
 	done := make(chan struct{})
 	go func() {
-		c.workerLoop(ctx, consumer, 0)
+		c.workerLoop(ctx, consumer, c.batches[0], nil)
 		close(done)
 	}()
 
@@ -994,9 +1024,9 @@ func TestWorkerLoop_ProcessesMessages(t *testing.T) {
 	time.Sleep(50 * time.Millisecond)
 
 	// Check that event was added to batch
-	c.mu.Lock()
-	batchLen := len(c.batch)
-	c.mu.Unlock()
+	c.batches[0].mu.Lock()
+	batchLen := len(c.batches[0].events)
+	c.batches[0].mu.Unlock()
 
 	if batchLen != 1 {
 		t.Errorf("Batch len = %d, want 1", batchLen)
@@ -1032,8 +1062,7 @@ func TestWorkerLoop_FetchBatchSizeDefault(t *testing.T) {
 		config:       cfg,
 		parquet:      NewParquetWriter(cfg.Parquet),
 		logger:       logger,
-		batch:        make([]trackedEvent, 0, cfg.Batch.MaxEvents),
-		lastFlush:    time.Now(),
+		batches:      newWorkerBatches(cfg.Batch),
 		stopCh:       make(chan struct{}),
 		doneCh:       make(chan struct{}),
 	}
@@ -1043,7 +1072,7 @@ func TestWorkerLoop_FetchBatchSizeDefault(t *testing.T) {
 
 	done := make(chan struct{})
 	go func() {
-		c.workerLoop(ctx, consumer, 0)
+		c.workerLoop(ctx, consumer, c.batches[0], nil)
 		close(done)
 	}()
 
@@ -1087,8 +1116,7 @@ func TestWorkerLoop_MessagesIterationError(t *testing.T) {
 		config:       cfg,
 		parquet:      NewParquetWriter(cfg.Parquet),
 		logger:       logger,
-		batch:        make([]trackedEvent, 0, cfg.Batch.MaxEvents),
-		lastFlush:    time.Now(),
+		batches:      newWorkerBatches(cfg.Batch),
 		stopCh:       make(chan struct{}),
 		doneCh:       make(chan struct{}),
 	}
@@ -1098,10 +1126,89 @@ func TestWorkerLoop_MessagesIterationError(t *testing.T) {
 
 	done := make(chan struct{})
 	go func() {
-		c.workerLoop(ctx, consumer, 0)
+		c.workerLoop(ctx, consumer, c.batches[0], nil)
 		close(done)
 	}()
 
 	// Should not panic on iteration error, just log and continue
 	<-done
 }
+
+// TestProcessMessage_PerWorkerBatches verifies workers fill their own batches
+// concurrently without affecting each other.
+func TestProcessMessage_PerWorkerBatches(t *testing.T) {
+	c := createTestConsumer(t)
+	c.config.Batch.WorkerCount = 4
+	c.batches = newWorkerBatches(c.config.Batch)
+
+	data, err := proto.Marshal(&pb.EventEnvelope{
+		Id:          "worker-event",
+		AppId:       "test-app",
+		TimestampMs: time.Now().UnixMilli(),
+		Payload:     &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal event: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i, batch := range c.batches {
+		wg.Add(1)
+		go func(n int, batch *workerBatch) {
+			defer wg.Done()
+			for range n {
+				c.processMessage(context.Background(), batch, &mockJetStreamMsg{data: data, subject: "events.test"})
+			}
+		}((i+1)*10, batch)
+	}
+	wg.Wait()
+
+	for i, batch := range c.batches {
+		if got, want := len(batch.events), (i+1)*10; got != want {
+			t.Errorf("worker %d batch len = %d, want %d", i, got, want)
+		}
+	}
+}
+
+// BenchmarkProcessMessage_Workers measures batching throughput as workers are
+// added; with per-worker batches it should scale with the worker count.
+func BenchmarkProcessMessage_Workers(b *testing.B) {
+	data, err := proto.Marshal(&pb.EventEnvelope{
+		Id:          "bench-event",
+		AppId:       "bench-app",
+		TimestampMs: time.Now().UnixMilli(),
+		Payload:     &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}},
+	})
+	if err != nil {
+		b.Fatalf("Failed to marshal event: %v", err)
+	}
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			cfg := BatchConfig{MaxEvents: b.N + 1, WorkerCount: workers}
+			c := &Consumer{
+				config:  Config{Batch: cfg},
+				logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+				batches: newWorkerBatches(cfg),
+			}
+			msg := &mockJetStreamMsg{data: data, subject: "events.test"}
+
+			b.ResetTimer()
+			var wg sync.WaitGroup
+			for i, batch := range c.batches {
+				n := b.N / workers
+				if i < b.N%workers {
+					n++
+				}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for range n {
+						c.processMessage(context.Background(), batch, msg)
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
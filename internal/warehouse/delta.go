@@ -0,0 +1,662 @@
+package warehouse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/google/uuid"
+)
+
+// deltaLogDir is the transaction log directory under the table root.
+const deltaLogDir = "_delta_log"
+
+// deltaCommitRegex matches commit file names in the transaction log.
+var deltaCommitRegex = regexp.MustCompile(`/` + deltaLogDir + `/(\d{20})\.json$`)
+
+// DeltaObjectStore is the subset of the S3 API used by DeltaLog.
+// *s3.Client satisfies it.
+type DeltaObjectStore interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+// DeltaFile describes a data file added to the Delta table.
+type DeltaFile struct {
+	// Key is the full S3 object key of the Parquet file.
+	Key string
+
+	// Size is the file size in bytes.
+	Size int64
+
+	// NumRecords is the number of rows in the file.
+	NumRecords int64
+
+	// MinValues and MaxValues are optional per-column statistics.
+	MinValues map[string]any
+	MaxValues map[string]any
+}
+
+// DeltaSnapshot is an immutable view of the table's active data files at a
+// given version.
+type DeltaSnapshot struct {
+	Version int64
+	active  map[string]struct{}
+}
+
+// Contains reports whether the data file with the given S3 key is part of
+// the table at this version.
+func (s *DeltaSnapshot) Contains(key string) bool {
+	_, ok := s.active[key]
+	return ok
+}
+
+// Len returns the number of active data files.
+func (s *DeltaSnapshot) Len() int {
+	return len(s.active)
+}
+
+// Keys returns the S3 keys of the active data files in no particular order.
+func (s *DeltaSnapshot) Keys() []string {
+	keys := make([]string, 0, len(s.active))
+	for key := range s.active {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// DeltaLog writes Delta Lake transaction log commits for the event lake so
+// that the Parquet files written by the sink can be mounted as a Delta table.
+// The table root is the configured S3 prefix; commits are written with S3
+// conditional puts (If-None-Match) so concurrent writers never overwrite
+// each other's versions.
+type DeltaLog struct {
+	store  DeltaObjectStore
+	bucket string
+	root   string
+	config DeltaConfig
+	logger *slog.Logger
+
+	// partitions is the layout written by the sink; its columns are the
+	// Delta partition columns
+	partitions *PartitionScheme
+
+	mu      sync.Mutex
+	version int64
+
+	snapMu   sync.Mutex
+	snapshot *DeltaSnapshot
+}
+
+// NewDeltaLog creates a Delta transaction log writer for the table rooted at
+// the S3 prefix.
+func NewDeltaLog(store DeltaObjectStore, s3Cfg S3Config, cfg DeltaConfig, logger *slog.Logger) *DeltaLog {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.MaxCommitRetries <= 0 {
+		cfg.MaxCommitRetries = 1
+	}
+
+	return &DeltaLog{
+		store:      store,
+		bucket:     s3Cfg.Bucket,
+		root:       strings.TrimSuffix(s3Cfg.Prefix, "/"),
+		config:     cfg,
+		logger:     logger.With("component", "delta-log"),
+		partitions: s3Cfg.Partitioning(),
+		version:    -1,
+		snapshot:   &DeltaSnapshot{Version: -1, active: map[string]struct{}{}},
+	}
+}
+
+// Init discovers the latest committed version and creates the table
+// (protocol and metadata at version 0) if it does not exist yet.
+func (d *DeltaLog) Init(ctx context.Context) error {
+	latest, err := d.latestVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if latest < 0 {
+		created, createErr := d.createTable(ctx)
+		if createErr != nil {
+			return createErr
+		}
+		if created {
+			d.logger.Info("created delta table", "root", d.root)
+		}
+		latest = 0
+	}
+
+	d.mu.Lock()
+	d.version = max(d.version, latest)
+	d.mu.Unlock()
+
+	d.logger.Info("delta log initialized", "root", d.root, "version", latest)
+	return nil
+}
+
+// Append commits a blind append of newly written data files.
+func (d *DeltaLog) Append(ctx context.Context, files ...DeltaFile) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	now := time.Now().UnixMilli()
+	actions := make([]deltaAction, 0, len(files)+1)
+	actions = append(actions, deltaAction{CommitInfo: &deltaCommitInfo{
+		Timestamp:     now,
+		Operation:     "WRITE",
+		OperationArgs: map[string]string{"mode": "Append"},
+		IsBlindAppend: true,
+		EngineInfo:    "causality-warehouse-sink",
+	}})
+	for _, f := range files {
+		add, err := d.addAction(f, now, true)
+		if err != nil {
+			return err
+		}
+		actions = append(actions, deltaAction{Add: add})
+	}
+
+	_, err := d.commit(ctx, actions, -1, nil)
+	return err
+}
+
+// Optimize commits an OPTIMIZE-style rewrite: the compacted file is added and
+// the source files are logically removed, both with dataChange=false. Source
+// files are not deleted from storage; a Delta VACUUM reclaims them after the
+// retention period. readVersion is the snapshot version the source files were
+// selected from; the commit fails with ErrDeltaConcurrentRemove if any later
+// commit already removed one of them.
+func (d *DeltaLog) Optimize(ctx context.Context, readVersion int64, compacted DeltaFile, removedKeys []string) error {
+	now := time.Now().UnixMilli()
+
+	add, err := d.addAction(compacted, now, false)
+	if err != nil {
+		return err
+	}
+
+	actions := []deltaAction{
+		{CommitInfo: &deltaCommitInfo{
+			Timestamp:     now,
+			Operation:     "OPTIMIZE",
+			OperationArgs: map[string]string{"predicate": "[]", "zOrderBy": "[]"},
+			EngineInfo:    "causality-compaction",
+		}},
+		{Add: add},
+	}
+
+	removed := make(map[string]struct{}, len(removedKeys))
+	for _, key := range removedKeys {
+		path := d.relativePath(key)
+		removed[path] = struct{}{}
+		actions = append(actions, deltaAction{Remove: &deltaRemove{
+			Path:              encodeDeltaPath(path),
+			DeletionTimestamp: now,
+			DataChange:        false,
+			PartitionValues:   d.partitionValues(key),
+		}})
+	}
+
+	_, err = d.commit(ctx, actions, readVersion, removed)
+	return err
+}
+
+// Snapshot replays any commits newer than the cached snapshot and returns the
+// table's active data files.
+func (d *DeltaLog) Snapshot(ctx context.Context) (*DeltaSnapshot, error) {
+	d.snapMu.Lock()
+	defer d.snapMu.Unlock()
+
+	current := d.snapshot
+	var next map[string]struct{}
+	version := current.Version
+
+	for {
+		actions, err := d.readCommit(ctx, version+1)
+		if errors.Is(err, errDeltaCommitNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if next == nil {
+			next = make(map[string]struct{}, len(current.active))
+			for k := range current.active {
+				next[k] = struct{}{}
+			}
+		}
+		for _, a := range actions {
+			switch {
+			case a.Add != nil:
+				next[d.absoluteKey(decodeDeltaPath(a.Add.Path))] = struct{}{}
+			case a.Remove != nil:
+				delete(next, d.absoluteKey(decodeDeltaPath(a.Remove.Path)))
+			}
+		}
+		version++
+	}
+
+	if next != nil {
+		d.snapshot = &DeltaSnapshot{Version: version, active: next}
+
+		d.mu.Lock()
+		d.version = max(d.version, version)
+		d.mu.Unlock()
+	}
+
+	return d.snapshot, nil
+}
+
+// commit writes the actions as the next table version. On a version conflict
+// it advances to the following version and retries. When removed is non-empty
+// every commit after readVersion is checked for removals of the same files.
+func (d *DeltaLog) commit(ctx context.Context, actions []deltaAction, readVersion int64, removed map[string]struct{}) (int64, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, a := range actions {
+		if err := enc.Encode(a); err != nil {
+			return 0, fmt.Errorf("failed to encode delta action: %w", err)
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	checked := readVersion
+	for attempt := 0; attempt < d.config.MaxCommitRetries; attempt++ {
+		version := d.version + 1
+
+		if len(removed) > 0 {
+			for v := checked + 1; v < version; v++ {
+				if err := d.checkRemoveConflict(ctx, v, removed); err != nil {
+					return 0, err
+				}
+			}
+			checked = version - 1
+		}
+
+		_, err := d.store.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(d.bucket),
+			Key:         aws.String(d.commitKey(version)),
+			Body:        bytes.NewReader(buf.Bytes()),
+			ContentType: aws.String("application/json"),
+			IfNoneMatch: aws.String("*"),
+		})
+		if err == nil {
+			d.version = version
+			d.logger.Debug("delta commit written", "version", version, "actions", len(actions))
+			return version, nil
+		}
+		if !isConditionalWriteConflict(err) {
+			return 0, fmt.Errorf("failed to write delta commit %d: %w", version, err)
+		}
+
+		// Another writer committed this version first; retry on the next one.
+		d.version = version
+	}
+
+	return 0, fmt.Errorf("%w: gave up after %d attempts", ErrDeltaCommitConflict, d.config.MaxCommitRetries)
+}
+
+// checkRemoveConflict returns ErrDeltaConcurrentRemove if the commit at the
+// given version removed any of the given paths.
+func (d *DeltaLog) checkRemoveConflict(ctx context.Context, version int64, removed map[string]struct{}) error {
+	actions, err := d.readCommit(ctx, version)
+	if err != nil {
+		return err
+	}
+	for _, a := range actions {
+		if a.Remove == nil {
+			continue
+		}
+		if _, ok := removed[decodeDeltaPath(a.Remove.Path)]; ok {
+			return fmt.Errorf("%w: %s removed in version %d", ErrDeltaConcurrentRemove, a.Remove.Path, version)
+		}
+	}
+	return nil
+}
+
+// createTable writes version 0 with the protocol and table metadata. It
+// returns false if another writer created the table first.
+func (d *DeltaLog) createTable(ctx context.Context) (bool, error) {
+	schema, err := deltaSchemaString(d.partitions.Columns())
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now().UnixMilli()
+	actions := []deltaAction{
+		{CommitInfo: &deltaCommitInfo{Timestamp: now, Operation: "CREATE TABLE", EngineInfo: "causality-warehouse-sink"}},
+		{Protocol: &deltaProtocol{MinReaderVersion: 1, MinWriterVersion: 2}},
+		{MetaData: &deltaMetaData{
+			ID:               uuid.New().String(),
+			Format:           deltaFormat{Provider: "parquet", Options: map[string]string{}},
+			SchemaString:     schema,
+			PartitionColumns: d.partitions.Columns(),
+			Configuration:    map[string]string{},
+			CreatedTime:      now,
+		}},
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, a := range actions {
+		if err := enc.Encode(a); err != nil {
+			return false, fmt.Errorf("failed to encode delta action: %w", err)
+		}
+	}
+
+	_, err = d.store.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(d.bucket),
+		Key:         aws.String(d.commitKey(0)),
+		Body:        bytes.NewReader(buf.Bytes()),
+		ContentType: aws.String("application/json"),
+		IfNoneMatch: aws.String("*"),
+	})
+	if err == nil {
+		return true, nil
+	}
+	if isConditionalWriteConflict(err) {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to create delta table: %w", err)
+}
+
+// latestVersion lists the transaction log and returns the highest committed
+// version, or -1 if the table does not exist.
+func (d *DeltaLog) latestVersion(ctx context.Context) (int64, error) {
+	latest := int64(-1)
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(d.bucket),
+		Prefix: aws.String(d.logPrefix()),
+	}
+
+	for {
+		page, err := d.store.ListObjectsV2(ctx, input)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list delta log: %w", err)
+		}
+		for _, obj := range page.Contents {
+			if obj.Key == nil {
+				continue
+			}
+			m := deltaCommitRegex.FindStringSubmatch(*obj.Key)
+			if m == nil {
+				continue
+			}
+			if v, parseErr := strconv.ParseInt(m[1], 10, 64); parseErr == nil && v > latest {
+				latest = v
+			}
+		}
+		if page.IsTruncated == nil || !*page.IsTruncated {
+			break
+		}
+		input.ContinuationToken = page.NextContinuationToken
+	}
+
+	return latest, nil
+}
+
+// readCommit reads and parses the commit at the given version.
+func (d *DeltaLog) readCommit(ctx context.Context, version int64) ([]deltaAction, error) {
+	out, err := d.store.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(d.bucket),
+		Key:    aws.String(d.commitKey(version)),
+	})
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, errDeltaCommitNotFound
+		}
+		return nil, fmt.Errorf("failed to read delta commit %d: %w", version, err)
+	}
+	defer out.Body.Close()
+
+	var actions []deltaAction
+	scanner := bufio.NewScanner(out.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var a deltaAction
+		if err := json.Unmarshal(line, &a); err != nil {
+			return nil, fmt.Errorf("failed to parse delta commit %d: %w", version, err)
+		}
+		actions = append(actions, a)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read delta commit %d: %w", version, err)
+	}
+
+	return actions, nil
+}
+
+// addAction builds an add action for a data file.
+func (d *DeltaLog) addAction(f DeltaFile, now int64, dataChange bool) (*deltaAdd, error) {
+	stats, err := json.Marshal(deltaStats{
+		NumRecords: f.NumRecords,
+		MinValues:  f.MinValues,
+		MaxValues:  f.MaxValues,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode delta stats: %w", err)
+	}
+
+	return &deltaAdd{
+		Path:             encodeDeltaPath(d.relativePath(f.Key)),
+		PartitionValues:  d.partitionValues(f.Key),
+		Size:             f.Size,
+		ModificationTime: now,
+		DataChange:       dataChange,
+		Stats:            string(stats),
+	}, nil
+}
+
+// logPrefix returns the S3 prefix of the transaction log.
+func (d *DeltaLog) logPrefix() string {
+	if d.root == "" {
+		return deltaLogDir + "/"
+	}
+	return d.root + "/" + deltaLogDir + "/"
+}
+
+// commitKey returns the S3 key of the commit file for a version.
+func (d *DeltaLog) commitKey(version int64) string {
+	return fmt.Sprintf("%s%020d.json", d.logPrefix(), version)
+}
+
+// relativePath converts an S3 key to a path relative to the table root.
+func (d *DeltaLog) relativePath(key string) string {
+	if d.root == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, d.root+"/")
+}
+
+// absoluteKey converts a table-relative path back to an S3 key.
+func (d *DeltaLog) absoluteKey(path string) string {
+	if d.root == "" {
+		return path
+	}
+	return d.root + "/" + path
+}
+
+// encodeDeltaPath URI-encodes a relative path as required by the Delta protocol.
+func encodeDeltaPath(path string) string {
+	return (&url.URL{Path: path}).EscapedPath()
+}
+
+// decodeDeltaPath reverses encodeDeltaPath.
+func decodeDeltaPath(path string) string {
+	if decoded, err := url.PathUnescape(path); err == nil {
+		return decoded
+	}
+	return path
+}
+
+// partitionValues extracts partition values from a data file key.
+// Numeric values are normalized (no zero padding) as Delta expects.
+func (d *DeltaLog) partitionValues(key string) map[string]string {
+	p, ok := d.partitions.Parse(key)
+	if !ok {
+		return map[string]string{}
+	}
+	return d.partitions.ColumnValues(p)
+}
+
+// deltaSchemaString builds the Delta schema JSON from the EventRow parquet
+// tags. Partition columns that are not stored in the data files (user_bucket)
+// are appended.
+func deltaSchemaString(partitionColumns []string) (string, error) {
+	type field struct {
+		Name     string            `json:"name"`
+		Type     string            `json:"type"`
+		Nullable bool              `json:"nullable"`
+		Metadata map[string]string `json:"metadata"`
+	}
+
+	t := reflect.TypeOf(EventRow{})
+	fields := make([]field, 0, t.NumField())
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("parquet"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		var typ string
+		switch f.Type.Kind() {
+		case reflect.String:
+			typ = "string"
+		case reflect.Int, reflect.Int64:
+			typ = "long"
+		case reflect.Int32:
+			typ = "integer"
+		case reflect.Bool:
+			typ = "boolean"
+		case reflect.Float64:
+			typ = "double"
+		default:
+			return "", fmt.Errorf("unsupported delta column type %s for %s", f.Type, name)
+		}
+
+		fields = append(fields, field{Name: name, Type: typ, Nullable: true, Metadata: map[string]string{}})
+	}
+
+	for _, column := range partitionColumns {
+		if !slices.ContainsFunc(fields, func(f field) bool { return f.Name == column }) {
+			fields = append(fields, field{Name: column, Type: "integer", Nullable: true, Metadata: map[string]string{}})
+		}
+	}
+
+	schema, err := json.Marshal(struct {
+		Type   string  `json:"type"`
+		Fields []field `json:"fields"`
+	}{Type: "struct", Fields: fields})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode delta schema: %w", err)
+	}
+	return string(schema), nil
+}
+
+// isConditionalWriteConflict reports whether err is an S3 conditional write
+// failure (the object already exists or a concurrent write is in progress).
+func isConditionalWriteConflict(err error) bool {
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		status := respErr.HTTPStatusCode()
+		return status == http.StatusPreconditionFailed || status == http.StatusConflict
+	}
+	return false
+}
+
+// IsNotFound reports whether err indicates a missing S3 object.
+func IsNotFound(err error) bool {
+	var noSuchKey *s3types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return true
+	}
+	var respErr *awshttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotFound
+}
+
+// Delta Lake transaction log actions (protocol spec, JSON commit format).
+type deltaAction struct {
+	CommitInfo *deltaCommitInfo `json:"commitInfo,omitempty"`
+	Protocol   *deltaProtocol   `json:"protocol,omitempty"`
+	MetaData   *deltaMetaData   `json:"metaData,omitempty"`
+	Add        *deltaAdd        `json:"add,omitempty"`
+	Remove     *deltaRemove     `json:"remove,omitempty"`
+}
+
+type deltaProtocol struct {
+	MinReaderVersion int `json:"minReaderVersion"`
+	MinWriterVersion int `json:"minWriterVersion"`
+}
+
+type deltaFormat struct {
+	Provider string            `json:"provider"`
+	Options  map[string]string `json:"options"`
+}
+
+type deltaMetaData struct {
+	ID               string            `json:"id"`
+	Format           deltaFormat       `json:"format"`
+	SchemaString     string            `json:"schemaString"`
+	PartitionColumns []string          `json:"partitionColumns"`
+	Configuration    map[string]string `json:"configuration"`
+	CreatedTime      int64             `json:"createdTime"`
+}
+
+type deltaAdd struct {
+	Path             string            `json:"path"`
+	PartitionValues  map[string]string `json:"partitionValues"`
+	Size             int64             `json:"size"`
+	ModificationTime int64             `json:"modificationTime"`
+	DataChange       bool              `json:"dataChange"`
+	Stats            string            `json:"stats,omitempty"`
+}
+
+type deltaRemove struct {
+	Path              string            `json:"path"`
+	DeletionTimestamp int64             `json:"deletionTimestamp"`
+	DataChange        bool              `json:"dataChange"`
+	PartitionValues   map[string]string `json:"partitionValues,omitempty"`
+}
+
+type deltaCommitInfo struct {
+	Timestamp     int64             `json:"timestamp"`
+	Operation     string            `json:"operation"`
+	OperationArgs map[string]string `json:"operationParameters,omitempty"`
+	IsBlindAppend bool              `json:"isBlindAppend,omitempty"`
+	EngineInfo    string            `json:"engineInfo,omitempty"`
+}
+
+type deltaStats struct {
+	NumRecords int64          `json:"numRecords"`
+	MinValues  map[string]any `json:"minValues,omitempty"`
+	MaxValues  map[string]any `json:"maxValues,omitempty"`
+}
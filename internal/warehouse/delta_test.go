@@ -0,0 +1,236 @@
+package warehouse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// memObjectStore is an in-memory DeltaObjectStore that honors If-None-Match.
+type memObjectStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newMemObjectStore() *memObjectStore {
+	return &memObjectStore{objects: make(map[string][]byte)}
+}
+
+func statusError(status int) error {
+	return &awshttp.ResponseError{ResponseError: &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+		Err:      errors.New(http.StatusText(status)),
+	}}
+}
+
+func (m *memObjectStore) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.objects[*in.Key]; exists && in.IfNoneMatch != nil {
+		return nil, statusError(http.StatusPreconditionFailed)
+	}
+	data, _ := io.ReadAll(in.Body)
+	m.objects[*in.Key] = data
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (m *memObjectStore) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.objects[*in.Key]
+	if !ok {
+		return nil, &s3types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))}, nil
+}
+
+func (m *memObjectStore) ListObjectsV2(_ context.Context, in *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var keys []string
+	for k := range m.objects {
+		if strings.HasPrefix(k, *in.Prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	out := &s3.ListObjectsV2Output{}
+	for _, k := range keys {
+		out.Contents = append(out.Contents, s3types.Object{Key: &k})
+	}
+	return out, nil
+}
+
+func (m *memObjectStore) commit(t *testing.T, key string) []deltaAction {
+	t.Helper()
+	data, ok := m.objects[key]
+	if !ok {
+		t.Fatalf("commit %s not found", key)
+	}
+	var actions []deltaAction
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		var a deltaAction
+		if err := json.Unmarshal(line, &a); err != nil {
+			t.Fatalf("invalid commit line %s: %v", line, err)
+		}
+		actions = append(actions, a)
+	}
+	return actions
+}
+
+const testDataKey = "events/app_id=demo/year=2026/month=01/day=05/hour=03/events_a.parquet"
+
+func TestDeltaLog_InitAndAppend(t *testing.T) {
+	ctx := context.Background()
+	store := newMemObjectStore()
+	cfg := S3Config{Bucket: "b", Prefix: "events"}
+
+	d := NewDeltaLog(store, cfg, DeltaConfig{Enabled: true, MaxCommitRetries: 5}, nil)
+	if err := d.Init(ctx); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	v0 := store.commit(t, "events/_delta_log/00000000000000000000.json")
+	var hasProtocol, hasMeta bool
+	for _, a := range v0 {
+		hasProtocol = hasProtocol || a.Protocol != nil
+		if a.MetaData != nil {
+			hasMeta = true
+			if strings.Join(a.MetaData.PartitionColumns, ",") != "app_id,year,month,day,hour" {
+				t.Errorf("partition columns = %v", a.MetaData.PartitionColumns)
+			}
+		}
+	}
+	if !hasProtocol || !hasMeta {
+		t.Fatalf("version 0 missing protocol or metadata: %+v", v0)
+	}
+
+	if err := d.Append(ctx, DeltaFile{Key: testDataKey, Size: 42, NumRecords: 3}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	var add *deltaAdd
+	for _, a := range store.commit(t, "events/_delta_log/00000000000000000001.json") {
+		if a.Add != nil {
+			add = a.Add
+		}
+	}
+	if add == nil {
+		t.Fatal("version 1 has no add action")
+	}
+	if add.Path != "app_id=demo/year=2026/month=01/day=05/hour=03/events_a.parquet" {
+		t.Errorf("add path = %q", add.Path)
+	}
+	if add.PartitionValues["month"] != "1" || add.PartitionValues["app_id"] != "demo" {
+		t.Errorf("partition values = %v", add.PartitionValues)
+	}
+	if !add.DataChange || add.Size != 42 {
+		t.Errorf("add = %+v", add)
+	}
+
+	// A second writer must resume after the existing versions, not reinitialize.
+	other := NewDeltaLog(store, cfg, DeltaConfig{MaxCommitRetries: 5}, nil)
+	if err := other.Init(ctx); err != nil {
+		t.Fatalf("Init() second writer error = %v", err)
+	}
+	if err := other.Append(ctx, DeltaFile{Key: testDataKey, Size: 1}); err != nil {
+		t.Fatalf("Append() second writer error = %v", err)
+	}
+	store.commit(t, "events/_delta_log/00000000000000000002.json")
+}
+
+func TestDeltaLog_AppendRetriesOnVersionConflict(t *testing.T) {
+	ctx := context.Background()
+	store := newMemObjectStore()
+	cfg := S3Config{Bucket: "b", Prefix: "events"}
+
+	a := NewDeltaLog(store, cfg, DeltaConfig{MaxCommitRetries: 5}, nil)
+	b := NewDeltaLog(store, cfg, DeltaConfig{MaxCommitRetries: 5}, nil)
+	if err := a.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Append(ctx, DeltaFile{Key: testDataKey}); err != nil {
+		t.Fatal(err)
+	}
+	// b still believes version 0 is latest and must move past a's commit.
+	if err := b.Append(ctx, DeltaFile{Key: testDataKey}); err != nil {
+		t.Fatalf("Append() after conflict error = %v", err)
+	}
+	store.commit(t, "events/_delta_log/00000000000000000002.json")
+}
+
+func TestDeltaLog_OptimizeAndSnapshot(t *testing.T) {
+	ctx := context.Background()
+	store := newMemObjectStore()
+	cfg := S3Config{Bucket: "b", Prefix: "events"}
+	d := NewDeltaLog(store, cfg, DeltaConfig{MaxCommitRetries: 5}, nil)
+	if err := d.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	base := "events/app_id=demo/year=2026/month=01/day=05/hour=03/"
+	if err := d.Append(ctx, DeltaFile{Key: base + "a.parquet"}, DeltaFile{Key: base + "b.parquet"}); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := d.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if snap.Len() != 2 || !snap.Contains(base+"a.parquet") {
+		t.Fatalf("snapshot before optimize has %d files", snap.Len())
+	}
+
+	if err := d.Optimize(ctx, snap.Version, DeltaFile{Key: base + "c.parquet"}, []string{base + "a.parquet", base + "b.parquet"}); err != nil {
+		t.Fatalf("Optimize() error = %v", err)
+	}
+
+	after, err := d.Snapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Len() != 1 || !after.Contains(base+"c.parquet") {
+		t.Errorf("snapshot after optimize = %d files, want only c.parquet", after.Len())
+	}
+	if keys := after.Keys(); len(keys) != 1 || keys[0] != base+"c.parquet" {
+		t.Errorf("Keys() after optimize = %v, want only c.parquet", keys)
+	}
+	if snap.Len() != 2 {
+		t.Error("earlier snapshot must be immutable")
+	}
+
+	// A second optimize based on the stale snapshot must detect the removal.
+	err = d.Optimize(ctx, snap.Version, DeltaFile{Key: base + "d.parquet"}, []string{base + "a.parquet"})
+	if !errors.Is(err, ErrDeltaConcurrentRemove) {
+		t.Errorf("stale Optimize() error = %v, want ErrDeltaConcurrentRemove", err)
+	}
+}
+
+func TestDeltaSchemaString_PartitionOnlyColumns(t *testing.T) {
+	schema, err := deltaSchemaString([]string{"app_id", "user_bucket", "year"})
+	if err != nil {
+		t.Fatalf("deltaSchemaString() error = %v", err)
+	}
+
+	for _, name := range []string{`"name":"app_id"`, `"name":"user_bucket"`, `"name":"year"`} {
+		if n := strings.Count(schema, name); n != 1 {
+			t.Errorf("schema has %d %s fields, want 1", n, name)
+		}
+	}
+}
@@ -5,4 +5,21 @@ import "errors"
 // Sentinel errors for the warehouse package.
 var (
 	ErrNoRowsToWrite = errors.New("no rows to write")
+
+	// ErrInvalidS3Config indicates an invalid backend, encryption or tagging setting.
+	ErrInvalidS3Config = errors.New("invalid S3 configuration")
+
+	// ErrDeltaCommitConflict indicates a Delta commit lost too many version races.
+	ErrDeltaCommitConflict = errors.New("delta commit conflict")
+
+	// ErrDeltaConcurrentRemove indicates files selected for an OPTIMIZE commit
+	// were removed by a concurrent commit.
+	ErrDeltaConcurrentRemove = errors.New("delta files removed concurrently")
+
+	// ErrInvalidSequenceRanges indicates malformed stream sequence ranges in
+	// a file's footer metadata.
+	ErrInvalidSequenceRanges = errors.New("invalid stream sequence ranges")
+
+	// errDeltaCommitNotFound indicates the requested commit version does not exist.
+	errDeltaCommitNotFound = errors.New("delta commit not found")
 )
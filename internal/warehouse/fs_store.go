@@ -0,0 +1,341 @@
+package warehouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// fsTempDir holds partially written objects under the store root. Its name
+// is not a valid bucket name, so it never collides with a bucket.
+const fsTempDir = ".tmp"
+
+// defaultFSMaxKeys is the ListObjectsV2 page size when MaxKeys is unset,
+// matching S3.
+const defaultFSMaxKeys = 1000
+
+// FSStore implements ObjectAPI against a local directory, for tests and
+// local runs without MinIO. Each bucket is a directory under the root and
+// each object a file at its key, so the lake can be inspected with ordinary
+// tools.
+//
+// Writes are atomic (temporary file and rename), conditional puts with
+// If-None-Match: * fail with 412 if the object exists, listings are ordered
+// by key and paginated with continuation tokens, and missing objects are
+// reported with the same error types as S3. Server-side encryption, tagging
+// and other object metadata are ignored.
+type FSStore struct {
+	root string
+}
+
+// NewFSStore creates an FSStore rooted at root, creating the directory if
+// needed.
+func NewFSStore(root string) (*FSStore, error) {
+	if err := os.MkdirAll(filepath.Join(root, fsTempDir), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create object store directory: %w", err)
+	}
+	return &FSStore{root: root}, nil
+}
+
+// PutObject writes an object. With IfNoneMatch set it only creates the
+// object if it does not exist.
+func (f *FSStore) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	path, err := f.objectPath(in.Bucket, in.Key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create object directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Join(f.root, fsTempDir), "put-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if in.Body != nil {
+		if _, err := io.Copy(tmp, in.Body); err != nil {
+			_ = tmp.Close()
+			return nil, fmt.Errorf("failed to write object: %w", err)
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write object: %w", err)
+	}
+
+	if aws.ToString(in.IfNoneMatch) == "*" {
+		// Link fails if the target exists, making create-if-absent atomic
+		if err := os.Link(tmp.Name(), path); err != nil {
+			if errors.Is(err, fs.ErrExist) {
+				return nil, fsStatusError(http.StatusPreconditionFailed)
+			}
+			return nil, fmt.Errorf("failed to write object: %w", err)
+		}
+		return &s3.PutObjectOutput{}, nil
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, fmt.Errorf("failed to write object: %w", err)
+	}
+	return &s3.PutObjectOutput{}, nil
+}
+
+// GetObject opens an object. The caller closes the returned body.
+func (f *FSStore) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	path, err := f.objectPath(in.Bucket, in.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, &s3types.NoSuchKey{Message: aws.String("key " + aws.ToString(in.Key) + " not found")}
+		}
+		return nil, fmt.Errorf("failed to open object: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		_ = file.Close()
+		return nil, &s3types.NoSuchKey{Message: aws.String("key " + aws.ToString(in.Key) + " not found")}
+	}
+
+	return &s3.GetObjectOutput{
+		Body:          file,
+		ContentLength: aws.Int64(info.Size()),
+		LastModified:  aws.Time(info.ModTime()),
+	}, nil
+}
+
+// ListObjectsV2 lists objects in key order, honoring Prefix, Delimiter,
+// StartAfter, ContinuationToken and MaxKeys.
+func (f *FSStore) ListObjectsV2(_ context.Context, in *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	bucketDir, err := f.bucketDir(in.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	prefix := aws.ToString(in.Prefix)
+	delimiter := aws.ToString(in.Delimiter)
+	maxKeys := int(aws.ToInt32(in.MaxKeys))
+	if maxKeys <= 0 {
+		maxKeys = defaultFSMaxKeys
+	}
+	after := aws.ToString(in.StartAfter)
+	if token := aws.ToString(in.ContinuationToken); token != "" {
+		after = token
+	}
+
+	objects, err := f.walk(bucketDir, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &s3.ListObjectsV2Output{
+		Name:      in.Bucket,
+		Prefix:    in.Prefix,
+		Delimiter: in.Delimiter,
+		MaxKeys:   aws.Int32(int32(maxKeys)),
+	}
+	count := 0
+	last := ""
+	for _, obj := range objects {
+		key := aws.ToString(obj.Key)
+		if key <= after || (strings.HasSuffix(after, delimiter) && delimiter != "" && strings.HasPrefix(key, after)) {
+			continue
+		}
+
+		// Group keys sharing a prefix up to the next delimiter
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				common := key[:len(prefix)+i+len(delimiter)]
+				if common == last {
+					continue
+				}
+				if count == maxKeys {
+					out.IsTruncated = aws.Bool(true)
+					out.NextContinuationToken = aws.String(last)
+					break
+				}
+				out.CommonPrefixes = append(out.CommonPrefixes, s3types.CommonPrefix{Prefix: aws.String(common)})
+				last = common
+				count++
+				continue
+			}
+		}
+
+		if count == maxKeys {
+			out.IsTruncated = aws.Bool(true)
+			out.NextContinuationToken = aws.String(last)
+			break
+		}
+		out.Contents = append(out.Contents, obj)
+		last = key
+		count++
+	}
+	if out.IsTruncated == nil {
+		out.IsTruncated = aws.Bool(false)
+	}
+	out.KeyCount = aws.Int32(int32(count))
+	return out, nil
+}
+
+// walk returns the objects of a bucket whose keys start with prefix, sorted
+// by key. Only the directory containing the prefix is walked.
+func (f *FSStore) walk(bucketDir, prefix string) ([]s3types.Object, error) {
+	start := bucketDir
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		start = filepath.Join(bucketDir, filepath.FromSlash(prefix[:i]))
+	}
+
+	var objects []s3types.Object
+	err := filepath.WalkDir(start, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(bucketDir, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, s3types.Object{
+			Key:          aws.String(key),
+			Size:         aws.Int64(info.Size()),
+			LastModified: aws.Time(info.ModTime()),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	// Directory order differs from key order ("a-b" sorts before "a/b")
+	sort.Slice(objects, func(i, j int) bool {
+		return aws.ToString(objects[i].Key) < aws.ToString(objects[j].Key)
+	})
+	return objects, nil
+}
+
+// DeleteObjects deletes objects, ignoring missing ones as S3 does, and
+// removes directories left empty.
+func (f *FSStore) DeleteObjects(_ context.Context, in *s3.DeleteObjectsInput, _ ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	bucketDir, err := f.bucketDir(in.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &s3.DeleteObjectsOutput{}
+	if in.Delete == nil {
+		return out, nil
+	}
+	for _, obj := range in.Delete.Objects {
+		path, err := f.objectPath(in.Bucket, obj.Key)
+		if err == nil {
+			err = os.Remove(path)
+		}
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			out.Errors = append(out.Errors, s3types.Error{
+				Key:     obj.Key,
+				Code:    aws.String("InternalError"),
+				Message: aws.String(err.Error()),
+			})
+			continue
+		}
+		out.Deleted = append(out.Deleted, s3types.DeletedObject{Key: obj.Key})
+		removeEmptyDirs(filepath.Dir(path), bucketDir)
+	}
+	return out, nil
+}
+
+// HeadBucket reports whether the bucket exists.
+func (f *FSStore) HeadBucket(_ context.Context, in *s3.HeadBucketInput, _ ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	dir, err := f.bucketDir(in.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, &s3types.NotFound{Message: aws.String("bucket " + aws.ToString(in.Bucket) + " not found")}
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
+
+// CreateBucket creates the bucket directory.
+func (f *FSStore) CreateBucket(_ context.Context, in *s3.CreateBucketInput, _ ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
+	dir, err := f.bucketDir(in.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create bucket: %w", err)
+	}
+	return &s3.CreateBucketOutput{}, nil
+}
+
+// bucketDir returns the directory of a bucket.
+func (f *FSStore) bucketDir(bucket *string) (string, error) {
+	name := aws.ToString(bucket)
+	if name == "" || name == fsTempDir || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("%w: invalid bucket name %q", ErrInvalidS3Config, name)
+	}
+	return filepath.Join(f.root, name), nil
+}
+
+// objectPath returns the file path of an object, rejecting keys that would
+// escape the bucket directory.
+func (f *FSStore) objectPath(bucket, key *string) (string, error) {
+	dir, err := f.bucketDir(bucket)
+	if err != nil {
+		return "", err
+	}
+	k := aws.ToString(key)
+	if k == "" || !filepath.IsLocal(filepath.FromSlash(k)) {
+		return "", fmt.Errorf("invalid object key %q", k)
+	}
+	return filepath.Join(dir, filepath.FromSlash(k)), nil
+}
+
+// removeEmptyDirs removes dir and its parents up to (not including) stop
+// while they are empty.
+func removeEmptyDirs(dir, stop string) {
+	for dir != stop && strings.HasPrefix(dir, stop) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// fsStatusError returns an error carrying an HTTP status, as the AWS SDK
+// reports S3 error responses.
+func fsStatusError(status int) error {
+	return &awshttp.ResponseError{ResponseError: &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+		Err:      errors.New(http.StatusText(status)),
+	}}
+}
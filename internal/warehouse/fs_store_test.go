@@ -0,0 +1,265 @@
+package warehouse
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func newTestFSStore(t *testing.T) *FSStore {
+	t.Helper()
+	store, err := NewFSStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFSStore() error = %v", err)
+	}
+	if _, err := store.CreateBucket(context.Background(), &s3.CreateBucketInput{Bucket: aws.String("b")}); err != nil {
+		t.Fatalf("CreateBucket() error = %v", err)
+	}
+	return store
+}
+
+func putFS(t *testing.T, store *FSStore, key, body string) {
+	t.Helper()
+	_, err := store.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String("b"),
+		Key:    aws.String(key),
+		Body:   strings.NewReader(body),
+	})
+	if err != nil {
+		t.Fatalf("PutObject(%s) error = %v", key, err)
+	}
+}
+
+// TestFSStore_PutGet verifies objects round-trip and missing keys report
+// NoSuchKey.
+func TestFSStore_PutGet(t *testing.T) {
+	ctx := context.Background()
+	store := newTestFSStore(t)
+	putFS(t, store, "events/app_id=demo/a.parquet", "data")
+	putFS(t, store, "events/app_id=demo/a.parquet", "replaced")
+
+	out, err := store.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String("b"), Key: aws.String("events/app_id=demo/a.parquet")})
+	if err != nil {
+		t.Fatalf("GetObject() error = %v", err)
+	}
+	data, _ := io.ReadAll(out.Body)
+	_ = out.Body.Close()
+	if string(data) != "replaced" || aws.ToInt64(out.ContentLength) != int64(len("replaced")) {
+		t.Errorf("GetObject() = %q (%d bytes), want %q", data, aws.ToInt64(out.ContentLength), "replaced")
+	}
+
+	_, err = store.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String("b"), Key: aws.String("missing")})
+	if !IsNotFound(err) {
+		t.Errorf("GetObject(missing) error = %v, want not found", err)
+	}
+}
+
+// TestFSStore_ConditionalPut verifies If-None-Match: * only creates new
+// objects, failing like S3 otherwise.
+func TestFSStore_ConditionalPut(t *testing.T) {
+	ctx := context.Background()
+	store := newTestFSStore(t)
+
+	put := func(body string) error {
+		_, err := store.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String("b"),
+			Key:         aws.String("log/00.json"),
+			Body:        strings.NewReader(body),
+			IfNoneMatch: aws.String("*"),
+		})
+		return err
+	}
+	if err := put("first"); err != nil {
+		t.Fatalf("first PutObject() error = %v", err)
+	}
+	if err := put("second"); !isConditionalWriteConflict(err) {
+		t.Errorf("second PutObject() error = %v, want conditional write conflict", err)
+	}
+
+	out, err := store.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String("b"), Key: aws.String("log/00.json")})
+	if err != nil {
+		t.Fatalf("GetObject() error = %v", err)
+	}
+	data, _ := io.ReadAll(out.Body)
+	_ = out.Body.Close()
+	if string(data) != "first" {
+		t.Errorf("object = %q, want %q", data, "first")
+	}
+}
+
+// TestFSStore_List verifies key order, prefix filtering, delimiters and
+// pagination through the SDK paginator.
+func TestFSStore_List(t *testing.T) {
+	ctx := context.Background()
+	store := newTestFSStore(t)
+	keys := []string{
+		"events/app_id=a/day=01/f1.parquet",
+		"events/app_id=a/day=01/f2.parquet",
+		"events/app_id=a/day=02/f3.parquet",
+		"events/app_id=a-b/day=01/f4.parquet",
+		"events/app_id=b/day=01/f5.parquet",
+		"other/f6.parquet",
+	}
+	for _, k := range keys {
+		putFS(t, store, k, "x")
+	}
+
+	var listed []string
+	paginator := s3.NewListObjectsV2Paginator(store, &s3.ListObjectsV2Input{
+		Bucket:  aws.String("b"),
+		Prefix:  aws.String("events/"),
+		MaxKeys: aws.Int32(2),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			t.Fatalf("NextPage() error = %v", err)
+		}
+		for _, obj := range page.Contents {
+			listed = append(listed, aws.ToString(obj.Key))
+		}
+	}
+	want := []string{keys[3], keys[0], keys[1], keys[2], keys[4]}
+	if strings.Join(listed, ",") != strings.Join(want, ",") {
+		t.Errorf("listed = %v, want %v", listed, want)
+	}
+
+	var prefixes []string
+	paginator = s3.NewListObjectsV2Paginator(store, &s3.ListObjectsV2Input{
+		Bucket:    aws.String("b"),
+		Prefix:    aws.String("events/app_id=a/"),
+		Delimiter: aws.String("/"),
+		MaxKeys:   aws.Int32(1),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			t.Fatalf("NextPage() error = %v", err)
+		}
+		for _, cp := range page.CommonPrefixes {
+			prefixes = append(prefixes, aws.ToString(cp.Prefix))
+		}
+		if len(page.Contents) != 0 {
+			t.Errorf("delimited listing returned objects: %v", page.Contents)
+		}
+	}
+	wantPrefixes := []string{"events/app_id=a/day=01/", "events/app_id=a/day=02/"}
+	if strings.Join(prefixes, ",") != strings.Join(wantPrefixes, ",") {
+		t.Errorf("common prefixes = %v, want %v", prefixes, wantPrefixes)
+	}
+}
+
+// TestFSStore_DeleteObjects verifies deletes ignore missing keys and prune
+// empty directories.
+func TestFSStore_DeleteObjects(t *testing.T) {
+	ctx := context.Background()
+	store := newTestFSStore(t)
+	putFS(t, store, "events/app_id=a/day=01/f1.parquet", "x")
+	putFS(t, store, "events/app_id=a/day=02/f2.parquet", "x")
+
+	out, err := store.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String("b"),
+		Delete: &s3types.Delete{Objects: []s3types.ObjectIdentifier{
+			{Key: aws.String("events/app_id=a/day=01/f1.parquet")},
+			{Key: aws.String("events/app_id=a/day=01/missing.parquet")},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("DeleteObjects() error = %v", err)
+	}
+	if len(out.Deleted) != 2 || len(out.Errors) != 0 {
+		t.Errorf("DeleteObjects() deleted %d, errors %v", len(out.Deleted), out.Errors)
+	}
+
+	if _, err := os.Stat(filepath.Join(store.root, "b", "events", "app_id=a", "day=01")); !os.IsNotExist(err) {
+		t.Errorf("empty partition directory not removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(store.root, "b", "events", "app_id=a", "day=02", "f2.parquet")); err != nil {
+		t.Errorf("unrelated object removed: %v", err)
+	}
+}
+
+// TestFSStore_RejectsEscapingKeys verifies keys cannot address files outside
+// the bucket.
+func TestFSStore_RejectsEscapingKeys(t *testing.T) {
+	store := newTestFSStore(t)
+	for _, key := range []string{"../outside", "/abs", ""} {
+		_, err := store.PutObject(context.Background(), &s3.PutObjectInput{
+			Bucket: aws.String("b"),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader(nil),
+		})
+		if err == nil {
+			t.Errorf("PutObject(%q) succeeded", key)
+		}
+	}
+}
+
+// TestFSStore_HeadBucket verifies missing buckets are reported.
+func TestFSStore_HeadBucket(t *testing.T) {
+	ctx := context.Background()
+	store := newTestFSStore(t)
+	if _, err := store.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String("b")}); err != nil {
+		t.Errorf("HeadBucket(b) error = %v", err)
+	}
+	if _, err := store.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String("missing")}); err == nil {
+		t.Error("HeadBucket(missing) succeeded")
+	}
+}
+
+// TestNewS3Client_FSBackend verifies the fs backend uploads through FSStore
+// and the Delta log works on it.
+func TestNewS3Client_FSBackend(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	cfg := S3Config{Backend: BackendFS, FSRoot: root, Bucket: "lake", Prefix: "events"}
+
+	client, err := NewS3Client(ctx, cfg, nil)
+	if err != nil {
+		t.Fatalf("NewS3Client() error = %v", err)
+	}
+	if err := client.EnsureBucket(ctx); err != nil {
+		t.Fatalf("EnsureBucket() error = %v", err)
+	}
+
+	key := client.GenerateKey(Partition{AppID: "demo", Year: 2026, Month: 1, Day: 5, Hour: 3})
+	if err := client.Upload(ctx, key, "demo", []byte("parquet")); err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "lake", filepath.FromSlash(key))); err != nil {
+		t.Errorf("uploaded file missing: %v", err)
+	}
+
+	d := NewDeltaLog(client.RawClient(), cfg, DeltaConfig{Enabled: true, MaxCommitRetries: 5}, nil)
+	if err := d.Init(ctx); err != nil {
+		t.Fatalf("DeltaLog.Init() error = %v", err)
+	}
+	if err := d.Append(ctx, DeltaFile{Key: key, Size: 7, NumRecords: 1}); err != nil {
+		t.Fatalf("DeltaLog.Append() error = %v", err)
+	}
+	snapshot, err := d.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("DeltaLog.Snapshot() error = %v", err)
+	}
+	if !snapshot.Contains(key) {
+		t.Errorf("snapshot does not contain %s", key)
+	}
+}
+
+// TestS3Config_ValidateBackend verifies unknown backends are rejected.
+func TestS3Config_ValidateBackend(t *testing.T) {
+	if err := (S3Config{Backend: "gcs"}).Validate(); err == nil {
+		t.Error("Validate() accepted unknown backend")
+	}
+	if err := (S3Config{Backend: BackendFS}).Validate(); err == nil {
+		t.Error("Validate() accepted fs backend without FS_ROOT")
+	}
+}
@@ -0,0 +1,281 @@
+package warehouse
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Integrity issue types.
+const (
+	// IssueRowCount is a file whose row count does not match its sequences:
+	// more rows means a message was written twice, fewer means rows were lost.
+	IssueRowCount = "row_count"
+
+	// IssueOverlap is a range of sequences written to more than one file,
+	// usually a batch redelivered after its upload succeeded.
+	IssueOverlap = "overlap"
+
+	// IssueGap is a range of acknowledged sequences found in no file. Poison
+	// messages and messages moved to the DLQ leave gaps as well.
+	IssueGap = "gap"
+
+	// IssueStream is a file recording a different stream.
+	IssueStream = "stream_mismatch"
+
+	// IssueUnreadable is a file whose footer could not be read.
+	IssueUnreadable = "unreadable"
+)
+
+// FileIntegrity is the row count and stream sequence metadata of a file.
+type FileIntegrity struct {
+	Key          string         `json:"key"`
+	Rows         int64          `json:"rows"`
+	Deduplicated int64          `json:"deduplicated,omitempty"`
+	Stream       string         `json:"stream,omitempty"`
+	Sequences    SequenceRanges `json:"-"`
+
+	// HasSequences is false for files written before sequences were
+	// recorded, or compacted from such files.
+	HasSequences bool `json:"has_sequences"`
+}
+
+// ReadFileIntegrity reads the row count and sequence metadata of f.
+func ReadFileIntegrity(key string, f *parquet.File) (FileIntegrity, error) {
+	info := FileIntegrity{Key: key, Rows: f.NumRows()}
+
+	stream, hasStream := f.Lookup(MetadataStream)
+	value, hasSequences := f.Lookup(MetadataStreamSequences)
+	if !hasStream || !hasSequences {
+		return info, nil
+	}
+
+	ranges, err := ParseSequenceRanges(value)
+	if err != nil {
+		return info, err
+	}
+	if value, ok := f.Lookup(MetadataDeduplicatedRows); ok {
+		if info.Deduplicated, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return info, fmt.Errorf("invalid %s %q", MetadataDeduplicatedRows, value)
+		}
+	}
+
+	info.Stream = stream
+	info.Sequences = ranges
+	info.HasSequences = true
+	return info, nil
+}
+
+// StreamBounds describes the stream files are verified against.
+type StreamBounds struct {
+	Name string `json:"name"`
+
+	// FirstSeq and LastSeq are the oldest and newest retained sequences.
+	FirstSeq uint64 `json:"first_seq"`
+	LastSeq  uint64 `json:"last_seq"`
+
+	// AckFloor is the sequence up to which the warehouse sink acknowledged
+	// every message, so all of them must be in a file. Zero checks gaps
+	// only between the sequences found in files.
+	AckFloor uint64 `json:"ack_floor"`
+}
+
+// IntegrityIssue is a problem found by CheckIntegrity.
+type IntegrityIssue struct {
+	Type      string   `json:"type"`
+	Keys      []string `json:"keys,omitempty"`
+	First     uint64   `json:"first,omitempty"`
+	Last      uint64   `json:"last,omitempty"`
+	Rows      int64    `json:"rows,omitempty"`
+	Sequences uint64   `json:"sequences,omitempty"`
+	Detail    string   `json:"detail,omitempty"`
+
+	// InStream reports whether part of a gap is still retained by the
+	// stream and can be replayed.
+	InStream bool `json:"in_stream,omitempty"`
+}
+
+// IntegrityReport is the machine-readable result of a verification.
+type IntegrityReport struct {
+	Stream                StreamBounds     `json:"stream"`
+	Files                 int              `json:"files"`
+	FilesWithoutSequences int              `json:"files_without_sequences"`
+	Rows                  int64            `json:"rows"`
+	Sequences             uint64           `json:"sequences"`
+	FirstSeq              uint64           `json:"first_seq,omitempty"`
+	LastSeq               uint64           `json:"last_seq,omitempty"`
+	Issues                []IntegrityIssue `json:"issues"`
+	OK                    bool             `json:"ok"`
+}
+
+// CheckIntegrity checks the row count of each file against its sequences
+// and the sequences of all files against each other and stream, flagging
+// row count mismatches, sequences written to several files, and, with
+// checkGaps, acknowledged sequences missing from every file. Gaps are only
+// meaningful when files holds every file of the lake, since the events of
+// all apps share the stream.
+func CheckIntegrity(files []FileIntegrity, stream StreamBounds, checkGaps bool) *IntegrityReport {
+	report := &IntegrityReport{Stream: stream, Files: len(files), Issues: []IntegrityIssue{}}
+
+	type keyedRange struct {
+		SequenceRange
+		key string
+	}
+	var ranges []keyedRange
+	var sets []SequenceRanges
+
+	for _, f := range files {
+		report.Rows += f.Rows
+		if !f.HasSequences {
+			report.FilesWithoutSequences++
+			continue
+		}
+		if stream.Name != "" && f.Stream != stream.Name {
+			report.Issues = append(report.Issues, IntegrityIssue{
+				Type:   IssueStream,
+				Keys:   []string{f.Key},
+				Detail: fmt.Sprintf("file records stream %q", f.Stream),
+			})
+			continue
+		}
+
+		count := f.Sequences.Count()
+		if uint64(f.Rows) > count || uint64(f.Rows+f.Deduplicated) < count {
+			report.Issues = append(report.Issues, IntegrityIssue{
+				Type:      IssueRowCount,
+				Keys:      []string{f.Key},
+				Rows:      f.Rows,
+				Sequences: count,
+			})
+		}
+		for _, r := range f.Sequences {
+			ranges = append(ranges, keyedRange{SequenceRange: r, key: f.Key})
+		}
+		sets = append(sets, f.Sequences)
+	}
+
+	// Overlaps: sweep the ranges by first sequence, tracking the range
+	// reaching furthest so far.
+	slices.SortFunc(ranges, func(a, b keyedRange) int {
+		return cmp.Or(cmp.Compare(a.First, b.First), cmp.Compare(a.Last, b.Last), strings.Compare(a.key, b.key))
+	})
+	var reach *keyedRange
+	for i := range ranges {
+		r := &ranges[i]
+		if reach != nil && r.First <= reach.Last {
+			report.Issues = append(report.Issues, IntegrityIssue{
+				Type:  IssueOverlap,
+				Keys:  []string{reach.key, r.key},
+				First: r.First,
+				Last:  min(r.Last, reach.Last),
+			})
+		}
+		if reach == nil || r.Last > reach.Last {
+			reach = r
+		}
+	}
+
+	covered := UnionSequenceRanges(sets...)
+	report.Sequences = covered.Count()
+	if len(covered) > 0 {
+		report.FirstSeq = covered[0].First
+		report.LastSeq = covered[len(covered)-1].Last
+	}
+	if checkGaps {
+		report.Issues = append(report.Issues, sequenceGaps(covered, stream)...)
+	}
+
+	report.OK = len(report.Issues) == 0
+	return report
+}
+
+// sequenceGaps returns the holes between the covered ranges, and after them
+// up to the ack floor of stream.
+func sequenceGaps(covered SequenceRanges, stream StreamBounds) []IntegrityIssue {
+	var gaps []IntegrityIssue
+	addGap := func(first, last uint64) {
+		gaps = append(gaps, IntegrityIssue{
+			Type:     IssueGap,
+			First:    first,
+			Last:     last,
+			InStream: stream.FirstSeq > 0 && last >= stream.FirstSeq,
+		})
+	}
+	for i := 1; i < len(covered); i++ {
+		addGap(covered[i-1].Last+1, covered[i].First-1)
+	}
+	if n := len(covered); n > 0 && stream.AckFloor > covered[n-1].Last {
+		addGap(covered[n-1].Last+1, stream.AckFloor)
+	}
+	return gaps
+}
+
+// ScanFileIntegrity reads the integrity metadata of every Parquet file under
+// the lake prefix joined with subPrefix for which include returns true (all
+// of them if include is nil), such as the active files of a Delta snapshot.
+// Each file is downloaded in full, since object stores such as FSStore do
+// not support ranged reads. Files whose footer cannot be read are returned
+// as IssueUnreadable issues.
+func ScanFileIntegrity(ctx context.Context, client ObjectAPI, cfg S3Config, subPrefix string, include func(key string) bool) ([]FileIntegrity, []IntegrityIssue, error) {
+	prefix := strings.TrimSuffix(cfg.Prefix, "/") + "/" + strings.TrimPrefix(subPrefix, "/")
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(cfg.Bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	var files []FileIntegrity
+	var issues []IntegrityIssue
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+		}
+
+		for _, obj := range page.Contents {
+			if obj.Key == nil || !strings.HasSuffix(*obj.Key, ".parquet") || (include != nil && !include(*obj.Key)) {
+				continue
+			}
+			info, err := readObjectIntegrity(ctx, client, cfg.Bucket, *obj.Key)
+			if err != nil {
+				issues = append(issues, IntegrityIssue{Type: IssueUnreadable, Keys: []string{*obj.Key}, Detail: err.Error()})
+				continue
+			}
+			files = append(files, info)
+		}
+	}
+
+	return files, issues, nil
+}
+
+// readObjectIntegrity downloads a Parquet object and reads its integrity
+// metadata.
+func readObjectIntegrity(ctx context.Context, client ObjectAPI, bucket, key string) (FileIntegrity, error) {
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return FileIntegrity{}, fmt.Errorf("failed to get object: %w", err)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return FileIntegrity{}, fmt.Errorf("failed to read object: %w", err)
+	}
+
+	f, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return FileIntegrity{}, fmt.Errorf("failed to open parquet file: %w", err)
+	}
+	return ReadFileIntegrity(key, f)
+}
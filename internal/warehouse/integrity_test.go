@@ -0,0 +1,124 @@
+package warehouse
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSequenceRanges(t *testing.T) {
+	ranges := NewSequenceRanges([]uint64{9, 1, 2, 3, 3, 5, 6, 12})
+	if got := ranges.String(); got != "1-3,5-6,9,12" {
+		t.Errorf("String() = %q, want %q", got, "1-3,5-6,9,12")
+	}
+	if got := ranges.Count(); got != 7 {
+		t.Errorf("Count() = %d, want 7", got)
+	}
+
+	parsed, err := ParseSequenceRanges(ranges.String())
+	if err != nil {
+		t.Fatalf("ParseSequenceRanges() error = %v", err)
+	}
+	if parsed.String() != ranges.String() {
+		t.Errorf("ParseSequenceRanges() = %q, want %q", parsed, ranges)
+	}
+
+	union := UnionSequenceRanges(ranges, SequenceRanges{{First: 4, Last: 4}, {First: 10, Last: 11}})
+	if got := union.String(); got != "1-6,9-12" {
+		t.Errorf("UnionSequenceRanges() = %q, want %q", got, "1-6,9-12")
+	}
+
+	for _, invalid := range []string{"x", "5-3", "1-3,2", "4,1"} {
+		if _, err := ParseSequenceRanges(invalid); !errors.Is(err, ErrInvalidSequenceRanges) {
+			t.Errorf("ParseSequenceRanges(%q) error = %v, want ErrInvalidSequenceRanges", invalid, err)
+		}
+	}
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	file := func(key string, rows, deduplicated int64, ranges string) FileIntegrity {
+		seqs, err := ParseSequenceRanges(ranges)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return FileIntegrity{Key: key, Rows: rows, Deduplicated: deduplicated, Stream: "EVENTS", Sequences: seqs, HasSequences: true}
+	}
+	stream := StreamBounds{Name: "EVENTS", FirstSeq: 50, LastSeq: 120, AckFloor: 110}
+
+	t.Run("consistent", func(t *testing.T) {
+		report := CheckIntegrity([]FileIntegrity{
+			file("a", 40, 0, "1-40"),
+			file("b", 60, 10, "41-110"),
+			{Key: "legacy", Rows: 12},
+		}, stream, true)
+		if !report.OK || len(report.Issues) != 0 {
+			t.Fatalf("issues = %+v, want none", report.Issues)
+		}
+		if report.Sequences != 110 || report.Rows != 112 || report.FilesWithoutSequences != 1 {
+			t.Errorf("report = %+v", report)
+		}
+	})
+
+	t.Run("issues", func(t *testing.T) {
+		files := []FileIntegrity{
+			file("a", 45, 0, "1-40"),
+			file("b", 20, 0, "31-50"),
+			file("c", 10, 0, "61-70"),
+			{Key: "other", Rows: 1, Stream: "OTHER", Sequences: SequenceRanges{{First: 1, Last: 1}}, HasSequences: true},
+		}
+		report := CheckIntegrity(files, stream, true)
+		if report.OK {
+			t.Fatal("OK = true, want issues")
+		}
+
+		want := []IntegrityIssue{
+			{Type: IssueRowCount, Keys: []string{"a"}, Rows: 45, Sequences: 40},
+			{Type: IssueStream, Keys: []string{"other"}},
+			{Type: IssueOverlap, Keys: []string{"a", "b"}, First: 31, Last: 40},
+			{Type: IssueGap, First: 51, Last: 60, InStream: true},
+			{Type: IssueGap, First: 71, Last: 110, InStream: true},
+		}
+		if len(report.Issues) != len(want) {
+			t.Fatalf("issues = %+v, want %d", report.Issues, len(want))
+		}
+		for i, issue := range report.Issues {
+			if issue.Type != want[i].Type || issue.First != want[i].First || issue.Last != want[i].Last ||
+				issue.Rows != want[i].Rows || issue.Sequences != want[i].Sequences || issue.InStream != want[i].InStream ||
+				len(issue.Keys) != len(want[i].Keys) {
+				t.Errorf("issue %d = %+v, want %+v", i, issue, want[i])
+			}
+		}
+
+		if report := CheckIntegrity(files, stream, false); len(report.Issues) != 3 {
+			t.Errorf("issues without gap checks = %+v, want 3", report.Issues)
+		}
+	})
+}
+
+func TestScanFileIntegrity(t *testing.T) {
+	ctx := context.Background()
+	store := newTestFSStore(t)
+	cfg := S3Config{Bucket: "b", Prefix: "events"}
+
+	data, err := NewParquetWriter(ParquetConfig{}).
+		WithOptions(SequenceMetadata("EVENTS", SequenceRanges{{First: 1, Last: 2}}, 0)...).
+		Write([]EventRow{{ID: "evt-1", AppID: "demo"}, {ID: "evt-2", AppID: "demo"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	putFS(t, store, "events/app_id=demo/year=2026/a.parquet", string(data))
+	putFS(t, store, "events/app_id=demo/year=2026/b.parquet", "not parquet")
+	putFS(t, store, "events/app_id=other/year=2026/c.parquet", string(data))
+	putFS(t, store, "events/_delta_log/00000000000000000000.json", "{}")
+
+	files, issues, err := ScanFileIntegrity(ctx, store, cfg, "app_id=demo/", nil)
+	if err != nil {
+		t.Fatalf("ScanFileIntegrity() error = %v", err)
+	}
+	if len(files) != 1 || files[0].Rows != 2 || files[0].Sequences.String() != "1-2" {
+		t.Errorf("files = %+v, want a.parquet with sequences 1-2", files)
+	}
+	if len(issues) != 1 || issues[0].Type != IssueUnreadable || issues[0].Keys[0] != "events/app_id=demo/year=2026/b.parquet" {
+		t.Errorf("issues = %+v, want b.parquet unreadable", issues)
+	}
+}
@@ -2,14 +2,18 @@ package warehouse
 
 import (
 	"bytes"
+	"cmp"
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"slices"
+	"time"
 
 	"github.com/parquet-go/parquet-go"
 	"github.com/parquet-go/parquet-go/compress"
 
 	"github.com/SebastienMelki/causality/internal/events"
+	"github.com/SebastienMelki/causality/internal/geo"
 	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
 )
 
@@ -43,6 +47,22 @@ type EventRow struct {
 	IsJailbroken bool   `parquet:"is_jailbroken,optional"`
 	IsEmulator   bool   `parquet:"is_emulator,optional"`
 	SDKVersion   string `parquet:"sdk_version,snappy,optional"`
+	ClockSkewMS  int64  `parquet:"clock_skew_ms,optional"`
+	SDKName      string `parquet:"sdk_name,snappy,dict,optional"`
+
+	// Geo enrichment fields, resolved from Locale and Timezone when
+	// GeoConfig.Enabled is set (see enrichGeo)
+	Country string `parquet:"country,snappy,dict,optional"`
+	Region  string `parquet:"region,snappy,dict,optional"`
+
+	// Purchase total in US dollars, set on purchase_complete events when a
+	// CurrencyConverter is configured (see purchaseAmountUSD)
+	AmountUSD float64 `parquet:"amount_usd,optional"`
+
+	// Experiment and variant of experiment_exposure events, so exposures can
+	// be joined with outcomes without parsing payload_json
+	ExperimentID      string `parquet:"experiment_id,snappy,dict,optional"`
+	ExperimentVariant string `parquet:"experiment_variant,snappy,dict,optional"`
 
 	// Payload as JSON (with type discriminator for querying)
 	PayloadJSON string `parquet:"payload_json,snappy"`
@@ -88,6 +108,13 @@ func EventRowFromProto(event *pb.EventEnvelope, year, month, day, hour int) Even
 		row.IsJailbroken = ctx.GetIsJailbroken()
 		row.IsEmulator = ctx.GetIsEmulator()
 		row.SDKVersion = ctx.GetSdkVersion()
+		row.ClockSkewMS = ctx.GetClockSkewMs()
+		row.SDKName = ctx.GetSdkName()
+	}
+
+	if exposure := event.GetExperimentExposure(); exposure != nil {
+		row.ExperimentID = exposure.GetExperimentId()
+		row.ExperimentVariant = exposure.GetVariant()
 	}
 
 	// Serialize payload to JSON
@@ -96,6 +123,28 @@ func EventRowFromProto(event *pb.EventEnvelope, year, month, day, hour int) Even
 	return row
 }
 
+// CurrencyConverter converts purchase amounts to US dollars. It is satisfied
+// by *fx.Module.
+type CurrencyConverter interface {
+	ToUSD(currency string, minorUnits int64, at time.Time) (float64, bool)
+}
+
+// purchaseAmountUSD returns the total of a purchase_complete event in US
+// dollars, or 0 for other events and unknown currencies.
+func purchaseAmountUSD(event *pb.EventEnvelope, converter CurrencyConverter) float64 {
+	purchase := event.GetPurchaseComplete()
+	if purchase == nil {
+		return 0
+	}
+	usd, _ := converter.ToUSD(purchase.GetCurrency(), purchase.GetTotalCents(), time.UnixMilli(event.GetTimestampMs()))
+	return usd
+}
+
+// enrichGeo sets Country and Region from the row's locale and timezone.
+func (r *EventRow) enrichGeo() {
+	r.Country, r.Region = geo.Resolve(r.Locale, r.Timezone)
+}
+
 // serializePayload serializes the event payload to JSON.
 func serializePayload(event *pb.EventEnvelope) string {
 	if event.GetPayload() == nil {
@@ -118,9 +167,31 @@ func serializePayload(event *pb.EventEnvelope) string {
 	return "{}"
 }
 
+// StatsColumns are the columns downstream engines prune on. Their min/max
+// statistics must be present in the column chunk metadata and page index of
+// every file written by the sink and by compaction.
+var StatsColumns = []string{"timestamp_ms", "app_id", "event_type"}
+
+// StatisticsOptions returns the writer options controlling column statistics
+// and page indexes. Both the sink and compaction writers apply them so that
+// compacted files stay as prunable as the originals.
+func (c ParquetConfig) StatisticsOptions() []parquet.WriterOption {
+	opts := []parquet.WriterOption{
+		parquet.DataPageStatistics(c.PageStatistics),
+		// Bounds over raw JSON payloads are useless for pruning and only
+		// inflate the footer.
+		parquet.SkipPageBounds("payload_json"),
+	}
+	if c.ColumnIndexSizeLimit > 0 {
+		opts = append(opts, parquet.ColumnIndexSizeLimit(c.ColumnIndexSizeLimit))
+	}
+	return opts
+}
+
 // ParquetWriter handles writing events to Parquet format.
 type ParquetWriter struct {
 	config ParquetConfig
+	extra  []parquet.WriterOption
 }
 
 // NewParquetWriter creates a new Parquet writer.
@@ -130,6 +201,15 @@ func NewParquetWriter(cfg ParquetConfig) *ParquetWriter {
 	}
 }
 
+// WithOptions returns a writer that also applies options, such as footer
+// metadata, to the files it writes.
+func (w *ParquetWriter) WithOptions(options ...parquet.WriterOption) *ParquetWriter {
+	return &ParquetWriter{
+		config: w.config,
+		extra:  append(slices.Clone(w.extra), options...),
+	}
+}
+
 // Write writes a batch of event rows to Parquet format and returns the bytes.
 func (w *ParquetWriter) Write(rows []EventRow) ([]byte, error) {
 	if len(rows) == 0 {
@@ -138,17 +218,19 @@ func (w *ParquetWriter) Write(rows []EventRow) ([]byte, error) {
 
 	var buf bytes.Buffer
 
-	// Get compression codec
-	codec := w.getCompressionCodec()
+	// Sort by timestamp so page-level min/max bounds are tight and time-range
+	// predicates can skip pages. Partitions share app_id, so timestamp is the
+	// column that benefits most.
+	sorted := slices.Clone(rows)
+	slices.SortStableFunc(sorted, func(a, b EventRow) int {
+		return cmp.Compare(a.TimestampMS, b.TimestampMS)
+	})
 
 	// Create Parquet writer
-	writer := parquet.NewGenericWriter[EventRow](&buf,
-		parquet.Compression(codec),
-		parquet.CreatedBy("causality-warehouse-sink", "1.0.0", ""),
-	)
+	writer := parquet.NewGenericWriter[EventRow](&buf, w.writerOptions()...)
 
 	// Write rows
-	if _, err := writer.Write(rows); err != nil {
+	if _, err := writer.Write(sorted); err != nil {
 		return nil, fmt.Errorf("failed to write rows: %w", err)
 	}
 
@@ -160,6 +242,17 @@ func (w *ParquetWriter) Write(rows []EventRow) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// writerOptions returns the Parquet writer options shared by Write and
+// WritePromoted.
+func (w *ParquetWriter) writerOptions() []parquet.WriterOption {
+	options := []parquet.WriterOption{
+		parquet.Compression(w.getCompressionCodec()),
+		parquet.CreatedBy("causality-warehouse-sink", "1.0.0", ""),
+	}
+	options = append(options, w.config.StatisticsOptions()...)
+	return append(options, w.extra...)
+}
+
 // getCompressionCodec returns the compression codec based on config.
 func (w *ParquetWriter) getCompressionCodec() compress.Codec {
 	switch w.config.Compression {
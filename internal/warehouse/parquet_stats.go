@@ -0,0 +1,147 @@
+package warehouse
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+)
+
+// ColumnChunkStats describes the statistics of one column chunk in a row group.
+type ColumnChunkStats struct {
+	RowGroup  int
+	Column    string
+	NumValues int64
+	NullCount int64
+
+	// HasMinMax reports whether the chunk metadata carries min/max values.
+	HasMinMax bool
+	Min       string
+	Max       string
+
+	// Pages is the number of data pages listed in the offset index.
+	Pages int
+
+	// PagesWithBounds is the number of pages with min/max in the column index.
+	PagesWithBounds int
+}
+
+// ParquetFileStats summarizes the footer statistics of a Parquet file.
+type ParquetFileStats struct {
+	CreatedBy string
+	NumRows   int64
+	RowGroups int
+	Columns   []ColumnChunkStats
+}
+
+// InspectParquet reads the footer and page index of a Parquet file and
+// returns per-column-chunk statistics.
+func InspectParquet(r io.ReaderAt, size int64) (*ParquetFileStats, error) {
+	f, err := parquet.OpenFile(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open parquet file: %w", err)
+	}
+
+	md := f.Metadata()
+	stats := &ParquetFileStats{
+		CreatedBy: md.CreatedBy,
+		NumRows:   md.NumRows,
+		RowGroups: len(md.RowGroups),
+	}
+
+	columnIndexes := f.ColumnIndexes()
+	offsetIndexes := f.OffsetIndexes()
+
+	for i, rg := range md.RowGroups {
+		for j, chunk := range rg.Columns {
+			meta := chunk.MetaData
+			cs := ColumnChunkStats{
+				RowGroup:  i,
+				Column:    strings.Join(meta.PathInSchema, "."),
+				NumValues: meta.NumValues,
+				NullCount: meta.Statistics.NullCount,
+			}
+
+			if meta.Statistics.MinValue != nil && meta.Statistics.MaxValue != nil {
+				cs.HasMinMax = true
+				cs.Min = formatStatValue(meta.Type, meta.Statistics.MinValue)
+				cs.Max = formatStatValue(meta.Type, meta.Statistics.MaxValue)
+			}
+
+			idx := i*len(rg.Columns) + j
+			if idx < len(offsetIndexes) {
+				cs.Pages = len(offsetIndexes[idx].PageLocations)
+			}
+			if idx < len(columnIndexes) {
+				ci := columnIndexes[idx]
+				// Null pages have empty bounds. NullPages itself is not
+				// checked: parquet-go decodes the false values Apache
+				// Thrift writes (as in files of WriteArrow) as true
+				for p := range ci.MinValues {
+					if len(ci.MinValues[p]) > 0 || len(ci.MaxValues[p]) > 0 {
+						cs.PagesWithBounds++
+					}
+				}
+			}
+
+			stats.Columns = append(stats.Columns, cs)
+		}
+	}
+
+	return stats, nil
+}
+
+// MissingStats returns "column (row group N)" entries for each of the given
+// columns that lack chunk min/max statistics or page index bounds. Columns
+// whose chunks contain only nulls are not reported.
+func (s *ParquetFileStats) MissingStats(columns []string) []string {
+	var missing []string
+	for _, cs := range s.Columns {
+		if !slices.Contains(columns, cs.Column) || cs.NumValues == cs.NullCount {
+			continue
+		}
+		if !cs.HasMinMax {
+			missing = append(missing, fmt.Sprintf("%s (row group %d): no min/max statistics", cs.Column, cs.RowGroup))
+		}
+		if cs.PagesWithBounds == 0 {
+			missing = append(missing, fmt.Sprintf("%s (row group %d): no page index bounds", cs.Column, cs.RowGroup))
+		}
+	}
+	return missing
+}
+
+// formatStatValue renders a plain-encoded statistics value for display.
+func formatStatValue(t format.Type, v []byte) string {
+	switch t {
+	case format.Boolean:
+		if len(v) == 1 {
+			return strconv.FormatBool(v[0] != 0)
+		}
+	case format.Int32:
+		if len(v) == 4 {
+			return strconv.FormatInt(int64(int32(binary.LittleEndian.Uint32(v))), 10)
+		}
+	case format.Int64:
+		if len(v) == 8 {
+			return strconv.FormatInt(int64(binary.LittleEndian.Uint64(v)), 10)
+		}
+	case format.Float:
+		if len(v) == 4 {
+			return strconv.FormatFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(v))), 'g', -1, 32)
+		}
+	case format.Double:
+		if len(v) == 8 {
+			return strconv.FormatFloat(math.Float64frombits(binary.LittleEndian.Uint64(v)), 'g', -1, 64)
+		}
+	case format.ByteArray, format.FixedLenByteArray:
+		return strconv.Quote(string(v))
+	}
+	return "0x" + hex.EncodeToString(v)
+}
@@ -0,0 +1,68 @@
+package warehouse
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestInspectParquet_StatsColumns(t *testing.T) {
+	writer := NewParquetWriter(ParquetConfig{
+		Compression:          "snappy",
+		PageStatistics:       true,
+		ColumnIndexSizeLimit: 64,
+	})
+
+	rows := []EventRow{
+		{ID: "evt-2", AppID: "testapp", TimestampMS: 2000, EventCategory: "user", EventType: "login"},
+		{ID: "evt-1", AppID: "testapp", TimestampMS: 1000, EventCategory: "screen", EventType: "view"},
+		{ID: "evt-3", AppID: "testapp", TimestampMS: 3000, EventCategory: "screen", EventType: "view"},
+	}
+
+	data, err := writer.Write(rows)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	stats, err := InspectParquet(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("InspectParquet() error = %v", err)
+	}
+
+	if stats.NumRows != 3 {
+		t.Errorf("NumRows = %d, want 3", stats.NumRows)
+	}
+	if missing := stats.MissingStats(StatsColumns); len(missing) > 0 {
+		t.Errorf("MissingStats() = %v, want none", missing)
+	}
+
+	want := map[string][2]string{
+		"timestamp_ms": {"1000", "3000"},
+		"app_id":       {`"testapp"`, `"testapp"`},
+		"event_type":   {`"login"`, `"view"`},
+	}
+	for _, cs := range stats.Columns {
+		bounds, ok := want[cs.Column]
+		if !ok {
+			continue
+		}
+		if cs.Min != bounds[0] || cs.Max != bounds[1] {
+			t.Errorf("%s bounds = [%s, %s], want [%s, %s]", cs.Column, cs.Min, cs.Max, bounds[0], bounds[1])
+		}
+	}
+}
+
+func TestParquetWriter_WriteSortsByTimestamp(t *testing.T) {
+	writer := NewParquetWriter(ParquetConfig{Compression: "snappy"})
+
+	rows := []EventRow{
+		{ID: "b", AppID: "app", TimestampMS: 200},
+		{ID: "a", AppID: "app", TimestampMS: 100},
+	}
+
+	if _, err := writer.Write(rows); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if rows[0].ID != "b" {
+		t.Error("Write() must not reorder the caller's slice")
+	}
+}
@@ -1,6 +1,7 @@
 package warehouse
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -395,3 +396,85 @@ func TestParquetWriter_Compression(t *testing.T) {
 		})
 	}
 }
+
+// mixedTestEvents returns n events with a mix of set and unset optional
+// fields, in non-chronological order.
+func mixedTestEvents(n int) []*pb.EventEnvelope {
+	base := time.Date(2026, 3, 10, 14, 0, 0, 0, time.UTC).UnixMilli()
+	events := make([]*pb.EventEnvelope, n)
+	for i := range events {
+		event := &pb.EventEnvelope{
+			Id:          fmt.Sprintf("evt-%d", i),
+			AppId:       "testapp",
+			DeviceId:    fmt.Sprintf("dev-%d", i%7),
+			TimestampMs: base + int64((i*7919)%3600)*1000,
+			Payload: &pb.EventEnvelope_ScreenView{
+				ScreenView: &pb.ScreenView{ScreenName: fmt.Sprintf("screen-%d", i%5)},
+			},
+		}
+		if i%5 == 0 {
+			event.Payload = &pb.EventEnvelope_PurchaseComplete{
+				PurchaseComplete: &pb.PurchaseComplete{OrderId: fmt.Sprintf("order-%d", i), TotalCents: int64(250 * i), Currency: "EUR"},
+			}
+		}
+		if i%11 == 3 {
+			event.Payload = &pb.EventEnvelope_ExperimentExposure{
+				ExperimentExposure: &pb.ExperimentExposure{ExperimentId: "checkout-v2", Variant: fmt.Sprintf("variant-%d", i%2)},
+			}
+		}
+		if i%3 != 0 {
+			event.CorrelationId = fmt.Sprintf("corr-%d", i)
+			event.DeviceContext = &pb.DeviceContext{
+				Platform:     pb.Platform_PLATFORM_ANDROID,
+				OsVersion:    "14",
+				ScreenWidth:  int32(360 + i%3),
+				NetworkType:  pb.NetworkType_NETWORK_TYPE_WIFI,
+				IsJailbroken: i%2 == 0,
+				Locale:       "fr_FR",
+			}
+			if i%4 == 1 {
+				event.DeviceContext.Timezone = "America/Sao_Paulo"
+			}
+			if i%6 == 1 {
+				event.DeviceContext.ClockSkewMs = int64(-1500 * i)
+			}
+			if i%5 == 2 {
+				event.DeviceContext.SdkName = "causality-mobile"
+			}
+		}
+		events[i] = event
+	}
+	return events
+}
+
+// fixedConverter converts every currency at one unit per US dollar.
+type fixedConverter struct{}
+
+func (fixedConverter) ToUSD(_ string, minorUnits int64, _ time.Time) (float64, bool) {
+	return float64(minorUnits) / 100, true
+}
+
+// TestParquetWriter_GeoAndAmountUSD verifies the geo and amount_usd columns
+// are filled for the events that carry them.
+func TestParquetWriter_GeoAndAmountUSD(t *testing.T) {
+	events := mixedTestEvents(100)
+	rows := make([]EventRow, len(events))
+	for i, event := range events {
+		rows[i] = EventRowFromProto(event, 2026, 3, 10, 14)
+		rows[i].enrichGeo()
+		rows[i].AmountUSD = purchaseAmountUSD(event, fixedConverter{})
+	}
+
+	if rows[5].AmountUSD != 12.5 || rows[4].AmountUSD != 0 {
+		t.Errorf("amount_usd = %v, %v, want 12.5 for the purchase and 0 otherwise", rows[5].AmountUSD, rows[4].AmountUSD)
+	}
+	if rows[1].Country != "BR" || rows[1].Region != "South America" || rows[2].Country != "FR" || rows[0].Country != "" {
+		t.Errorf("geo = %q/%q, %q, %q, want BR/South America, FR, empty",
+			rows[1].Country, rows[1].Region, rows[2].Country, rows[0].Country)
+	}
+
+	writer := NewParquetWriter(ParquetConfig{Compression: "snappy"})
+	if _, err := writer.Write(rows); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+}
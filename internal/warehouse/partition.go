@@ -0,0 +1,316 @@
+package warehouse
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SebastienMelki/causality/internal/events"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// Partition template segments.
+const (
+	// SegmentAppID partitions by app: app_id={app}/
+	SegmentAppID = "app_id"
+
+	// SegmentCategory partitions by event category: event_category={category}/
+	SegmentCategory = "category"
+
+	// SegmentUserBucket partitions by a hash of the device ID:
+	// user_bucket={0..PartitionUserBuckets-1}/
+	SegmentUserBucket = "user_bucket"
+
+	// SegmentDate partitions by day: year={y}/month={m}/day={d}/
+	SegmentDate = "date"
+
+	// SegmentHour partitions by hour: hour={h}/
+	SegmentHour = "hour"
+)
+
+// DefaultPartitionTemplate is the hourly per-app layout.
+const DefaultPartitionTemplate = "app_id/date/hour"
+
+// ErrInvalidPartitionTemplate is returned for malformed partition templates.
+var ErrInvalidPartitionTemplate = errors.New("invalid partition template")
+
+// Partition identifies one partition of the event lake. Fields for segments
+// the scheme does not use are zero, so Partition values compare equal for
+// events belonging to the same partition.
+type Partition struct {
+	AppID      string
+	Category   string
+	UserBucket int
+	Year       int
+	Month      int
+	Day        int
+	Hour       int
+}
+
+// PartitionScheme maps events to Hive-style partition paths according to a
+// template such as "app_id/date/hour" or "app_id/category/date". It is
+// shared by the sink, compaction, Delta log and readers so that all of them
+// agree on the layout. Templates must start with app_id and contain date;
+// hour, if present, must directly follow date.
+type PartitionScheme struct {
+	template    string
+	segments    []string
+	userBuckets int
+	regex       *regexp.Regexp
+}
+
+// DefaultPartitionScheme returns the scheme for DefaultPartitionTemplate.
+func DefaultPartitionScheme() *PartitionScheme {
+	scheme, _ := ParsePartitionScheme(DefaultPartitionTemplate, 0)
+	return scheme
+}
+
+// ParsePartitionScheme parses a partition template. userBuckets is the number
+// of user buckets and must be positive if the template uses user_bucket.
+func ParsePartitionScheme(template string, userBuckets int) (*PartitionScheme, error) {
+	segments := strings.Split(strings.Trim(template, "/"), "/")
+	if len(segments) == 0 || segments[0] != SegmentAppID {
+		return nil, fmt.Errorf("%w: %q must start with %s", ErrInvalidPartitionTemplate, template, SegmentAppID)
+	}
+
+	var pattern strings.Builder
+	pattern.WriteString(`(.*?/`)
+	seen := make(map[string]bool, len(segments))
+	for i, segment := range segments {
+		if seen[segment] {
+			return nil, fmt.Errorf("%w: %q repeats %s", ErrInvalidPartitionTemplate, template, segment)
+		}
+		seen[segment] = true
+
+		switch segment {
+		case SegmentAppID:
+			pattern.WriteString(`app_id=([^/]+)/`)
+		case SegmentCategory:
+			pattern.WriteString(`event_category=([^/]+)/`)
+		case SegmentUserBucket:
+			if userBuckets <= 0 {
+				return nil, fmt.Errorf("%w: %s requires a positive bucket count", ErrInvalidPartitionTemplate, SegmentUserBucket)
+			}
+			pattern.WriteString(`user_bucket=(\d+)/`)
+		case SegmentDate:
+			pattern.WriteString(`year=(\d{4})/month=(\d{2})/day=(\d{2})/`)
+		case SegmentHour:
+			if i == 0 || segments[i-1] != SegmentDate {
+				return nil, fmt.Errorf("%w: %q: %s must directly follow %s", ErrInvalidPartitionTemplate, template, SegmentHour, SegmentDate)
+			}
+			pattern.WriteString(`hour=(\d{2})/`)
+		default:
+			return nil, fmt.Errorf("%w: %q: unknown segment %q", ErrInvalidPartitionTemplate, template, segment)
+		}
+	}
+	if !seen[SegmentDate] {
+		return nil, fmt.Errorf("%w: %q must contain %s", ErrInvalidPartitionTemplate, template, SegmentDate)
+	}
+	pattern.WriteString(`)`)
+
+	return &PartitionScheme{
+		template:    strings.Join(segments, "/"),
+		segments:    segments,
+		userBuckets: userBuckets,
+		regex:       regexp.MustCompile(pattern.String()),
+	}, nil
+}
+
+// String returns the normalized template.
+func (s *PartitionScheme) String() string {
+	return s.template
+}
+
+// Hourly reports whether partitions cover one hour rather than one day.
+func (s *PartitionScheme) Hourly() bool {
+	return s.has(SegmentHour)
+}
+
+// Columns returns the Hive partition columns in path order, for table
+// definitions and the Delta log.
+func (s *PartitionScheme) Columns() []string {
+	columns := make([]string, 0, len(s.segments)+2)
+	for _, segment := range s.segments {
+		switch segment {
+		case SegmentAppID:
+			columns = append(columns, "app_id")
+		case SegmentCategory:
+			columns = append(columns, "event_category")
+		case SegmentUserBucket:
+			columns = append(columns, "user_bucket")
+		case SegmentDate:
+			columns = append(columns, "year", "month", "day")
+		case SegmentHour:
+			columns = append(columns, "hour")
+		}
+	}
+	return columns
+}
+
+// PartitionOf returns the partition an event belongs to.
+func (s *PartitionScheme) PartitionOf(event *pb.EventEnvelope) Partition {
+	ts := time.UnixMilli(event.GetTimestampMs()).UTC()
+	p := Partition{
+		AppID: event.GetAppId(),
+		Year:  ts.Year(),
+		Month: int(ts.Month()),
+		Day:   ts.Day(),
+	}
+	if s.Hourly() {
+		p.Hour = ts.Hour()
+	}
+	if s.has(SegmentCategory) {
+		p.Category, _ = events.GetCategoryAndType(event)
+	}
+	if s.has(SegmentUserBucket) {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(event.GetDeviceId()))
+		p.UserBucket = int(h.Sum32() % uint32(s.userBuckets))
+	}
+	return p
+}
+
+// Path returns the partition path relative to the lake prefix, with a
+// trailing slash, e.g. "app_id=demo/year=2026/month=01/day=15/hour=10/".
+func (s *PartitionScheme) Path(p Partition) string {
+	var b strings.Builder
+	for _, segment := range s.segments {
+		switch segment {
+		case SegmentAppID:
+			fmt.Fprintf(&b, "app_id=%s/", p.AppID)
+		case SegmentCategory:
+			fmt.Fprintf(&b, "event_category=%s/", p.Category)
+		case SegmentUserBucket:
+			fmt.Fprintf(&b, "user_bucket=%02d/", p.UserBucket)
+		case SegmentDate:
+			fmt.Fprintf(&b, "year=%d/month=%02d/day=%02d/", p.Year, p.Month, p.Day)
+		case SegmentHour:
+			fmt.Fprintf(&b, "hour=%02d/", p.Hour)
+		}
+	}
+	return b.String()
+}
+
+// PartitionPrefix returns the partition prefix of key, including everything
+// before it, or "" if key is not inside a partition of this scheme. For
+// example "events/app_id=demo/year=2026/month=01/day=15/hour=10/events_uuid.parquet"
+// returns "events/app_id=demo/year=2026/month=01/day=15/hour=10/".
+func (s *PartitionScheme) PartitionPrefix(key string) string {
+	matches := s.regex.FindStringSubmatch(key)
+	if matches == nil {
+		return ""
+	}
+	return matches[1]
+}
+
+// Parse returns the partition of key, which may be a partition prefix or an
+// object key inside a partition.
+func (s *PartitionScheme) Parse(key string) (Partition, bool) {
+	matches := s.regex.FindStringSubmatch(key)
+	if matches == nil {
+		return Partition{}, false
+	}
+
+	var p Partition
+	values := matches[2:]
+	atoi := func() int {
+		n, _ := strconv.Atoi(values[0])
+		values = values[1:]
+		return n
+	}
+	for _, segment := range s.segments {
+		switch segment {
+		case SegmentAppID:
+			p.AppID = values[0]
+			values = values[1:]
+		case SegmentCategory:
+			p.Category = values[0]
+			values = values[1:]
+		case SegmentUserBucket:
+			p.UserBucket = atoi()
+		case SegmentDate:
+			p.Year, p.Month, p.Day = atoi(), atoi(), atoi()
+		case SegmentHour:
+			p.Hour = atoi()
+		}
+	}
+	return p, true
+}
+
+// Window returns the time range [start, end) covered by a partition.
+func (s *PartitionScheme) Window(p Partition) (start, end time.Time) {
+	start = time.Date(p.Year, time.Month(p.Month), p.Day, p.Hour, 0, 0, 0, time.UTC)
+	if s.Hourly() {
+		return start, start.Add(time.Hour)
+	}
+	return start, start.AddDate(0, 0, 1)
+}
+
+// ColumnValues returns the partition column values of p, keyed by Columns.
+// Numbers are not zero padded.
+func (s *PartitionScheme) ColumnValues(p Partition) map[string]string {
+	values := make(map[string]string, len(s.segments)+2)
+	for _, segment := range s.segments {
+		switch segment {
+		case SegmentAppID:
+			values["app_id"] = p.AppID
+		case SegmentCategory:
+			values["event_category"] = p.Category
+		case SegmentUserBucket:
+			values["user_bucket"] = strconv.Itoa(p.UserBucket)
+		case SegmentDate:
+			values["year"] = strconv.Itoa(p.Year)
+			values["month"] = strconv.Itoa(p.Month)
+			values["day"] = strconv.Itoa(p.Day)
+		case SegmentHour:
+			values["hour"] = strconv.Itoa(p.Hour)
+		}
+	}
+	return values
+}
+
+// AppPrefix returns the path of all partitions of an app, relative to the
+// lake prefix.
+func (s *PartitionScheme) AppPrefix(appID string) string {
+	return "app_id=" + appID + "/"
+}
+
+// DayPrefix returns the longest path, relative to the lake prefix, under
+// which all of an app's partitions for day t are found. Segments between
+// app_id and date (category, user_bucket) have no fixed value, in which case
+// this is the app prefix.
+func (s *PartitionScheme) DayPrefix(appID string, t time.Time) string {
+	return s.fixedPrefix(appID, t, false)
+}
+
+// HourPrefix is like DayPrefix for the partitions covering the hour t.
+func (s *PartitionScheme) HourPrefix(appID string, t time.Time) string {
+	return s.fixedPrefix(appID, t, true)
+}
+
+// fixedPrefix builds the path of leading segments whose values are known.
+func (s *PartitionScheme) fixedPrefix(appID string, t time.Time, withHour bool) string {
+	t = t.UTC()
+	p := Partition{AppID: appID, Year: t.Year(), Month: int(t.Month()), Day: t.Day(), Hour: t.Hour()}
+
+	known := 0
+	for _, segment := range s.segments {
+		if segment == SegmentCategory || segment == SegmentUserBucket || (segment == SegmentHour && !withHour) {
+			break
+		}
+		known++
+	}
+
+	prefix := &PartitionScheme{segments: s.segments[:known]}
+	return prefix.Path(p)
+}
+
+// has reports whether the template contains segment.
+func (s *PartitionScheme) has(segment string) bool {
+	return slices.Contains(s.segments, segment)
+}
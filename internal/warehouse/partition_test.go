@@ -0,0 +1,166 @@
+package warehouse
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// TestParsePartitionScheme_Invalid verifies malformed templates are rejected.
+func TestParsePartitionScheme_Invalid(t *testing.T) {
+	tests := []struct {
+		template    string
+		userBuckets int
+	}{
+		{template: ""},
+		{template: "date/app_id"},
+		{template: "app_id/hour"},
+		{template: "app_id/date/category/hour"},
+		{template: "app_id/date/date"},
+		{template: "app_id/region/date"},
+		{template: "app_id/user_bucket/date", userBuckets: 0},
+	}
+
+	for _, tc := range tests {
+		if _, err := ParsePartitionScheme(tc.template, tc.userBuckets); !errors.Is(err, ErrInvalidPartitionTemplate) {
+			t.Errorf("ParsePartitionScheme(%q) error = %v, want %v", tc.template, err, ErrInvalidPartitionTemplate)
+		}
+	}
+}
+
+// TestPartitionScheme_RoundTrip verifies that the path generated for an
+// event parses back to the same partition, for each supported layout.
+func TestPartitionScheme_RoundTrip(t *testing.T) {
+	ts := time.Date(2026, 1, 15, 10, 30, 0, 0, time.UTC)
+	event := &pb.EventEnvelope{
+		AppId:       "demo",
+		DeviceId:    "device-1",
+		TimestampMs: ts.UnixMilli(),
+		Payload:     &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}},
+	}
+
+	tests := []struct {
+		template    string
+		wantPath    string
+		wantColumns string
+		wantEnd     time.Time
+	}{
+		{
+			template:    DefaultPartitionTemplate,
+			wantPath:    "app_id=demo/year=2026/month=01/day=15/hour=10/",
+			wantColumns: "app_id,year,month,day,hour",
+			wantEnd:     time.Date(2026, 1, 15, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			template:    "app_id/date",
+			wantPath:    "app_id=demo/year=2026/month=01/day=15/",
+			wantColumns: "app_id,year,month,day",
+			wantEnd:     time.Date(2026, 1, 16, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			template:    "app_id/category/date/hour",
+			wantPath:    "app_id=demo/event_category=screen/year=2026/month=01/day=15/hour=10/",
+			wantColumns: "app_id,event_category,year,month,day,hour",
+			wantEnd:     time.Date(2026, 1, 15, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			template:    "app_id/date/user_bucket",
+			wantPath:    "app_id=demo/year=2026/month=01/day=15/user_bucket=",
+			wantColumns: "app_id,year,month,day,user_bucket",
+			wantEnd:     time.Date(2026, 1, 16, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.template, func(t *testing.T) {
+			scheme, err := ParsePartitionScheme(tc.template, 16)
+			if err != nil {
+				t.Fatalf("ParsePartitionScheme() error = %v", err)
+			}
+
+			p := scheme.PartitionOf(event)
+			path := scheme.Path(p)
+			if !strings.HasPrefix(path, tc.wantPath) {
+				t.Errorf("Path() = %q, want prefix %q", path, tc.wantPath)
+			}
+			if got := strings.Join(scheme.Columns(), ","); got != tc.wantColumns {
+				t.Errorf("Columns() = %q, want %q", got, tc.wantColumns)
+			}
+
+			key := "events/" + path + "events_uuid.parquet"
+			if got := scheme.PartitionPrefix(key); got != "events/"+path {
+				t.Errorf("PartitionPrefix() = %q, want %q", got, "events/"+path)
+			}
+			parsed, ok := scheme.Parse(key)
+			if !ok || parsed != p {
+				t.Errorf("Parse() = %+v, %v, want %+v", parsed, ok, p)
+			}
+			if _, end := scheme.Window(p); !end.Equal(tc.wantEnd) {
+				t.Errorf("Window() end = %v, want %v", end, tc.wantEnd)
+			}
+		})
+	}
+}
+
+// TestPartitionScheme_UserBucketStable verifies a device always maps to the
+// same bucket within range.
+func TestPartitionScheme_UserBucketStable(t *testing.T) {
+	scheme, err := ParsePartitionScheme("app_id/user_bucket/date", 8)
+	if err != nil {
+		t.Fatalf("ParsePartitionScheme() error = %v", err)
+	}
+
+	seen := make(map[int]bool)
+	for i := range 200 {
+		event := &pb.EventEnvelope{AppId: "demo", DeviceId: strings.Repeat("d", i%50)}
+		a, b := scheme.PartitionOf(event), scheme.PartitionOf(event)
+		if a != b {
+			t.Fatalf("PartitionOf() not stable: %+v != %+v", a, b)
+		}
+		if a.UserBucket < 0 || a.UserBucket >= 8 {
+			t.Fatalf("UserBucket = %d, want [0, 8)", a.UserBucket)
+		}
+		seen[a.UserBucket] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("devices spread over %d buckets, want several", len(seen))
+	}
+}
+
+// TestPartitionScheme_FixedPrefix verifies listing prefixes stop at
+// segments without a known value.
+func TestPartitionScheme_FixedPrefix(t *testing.T) {
+	ts := time.Date(2026, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	hourly := DefaultPartitionScheme()
+	if got, want := hourly.DayPrefix("demo", ts), "app_id=demo/year=2026/month=01/day=15/"; got != want {
+		t.Errorf("DayPrefix() = %q, want %q", got, want)
+	}
+	if got, want := hourly.HourPrefix("demo", ts), "app_id=demo/year=2026/month=01/day=15/hour=10/"; got != want {
+		t.Errorf("HourPrefix() = %q, want %q", got, want)
+	}
+
+	byCategory, err := ParsePartitionScheme("app_id/category/date/hour", 0)
+	if err != nil {
+		t.Fatalf("ParsePartitionScheme() error = %v", err)
+	}
+	if got, want := byCategory.HourPrefix("demo", ts), "app_id=demo/"; got != want {
+		t.Errorf("HourPrefix() = %q, want %q", got, want)
+	}
+}
+
+// TestS3Config_Partitioning verifies the template is validated and an
+// unset template falls back to the default layout.
+func TestS3Config_Partitioning(t *testing.T) {
+	if got := (S3Config{}).Partitioning().String(); got != DefaultPartitionTemplate {
+		t.Errorf("Partitioning() = %q, want %q", got, DefaultPartitionTemplate)
+	}
+
+	cfg := S3Config{PartitionTemplate: "app_id/hour"}
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidS3Config) || !errors.Is(err, ErrInvalidPartitionTemplate) {
+		t.Errorf("Validate() error = %v, want %v", err, ErrInvalidPartitionTemplate)
+	}
+}
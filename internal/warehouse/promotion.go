@@ -0,0 +1,646 @@
+package warehouse
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/parquet-go/parquet-go"
+
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// promotedColumnPrefix prefixes the names of promoted property columns.
+const promotedColumnPrefix = "prop_"
+
+// promotionManifestName is the object under the S3 prefix recording which
+// custom event property keys are promoted to which columns. The leading
+// underscore keeps Hive-style readers from treating it as data.
+const promotionManifestName = "_promoted_columns.json"
+
+// Promoted column types, named after the CustomEvent parameter maps.
+const (
+	PromotedString = "string"
+	PromotedInt64  = "int64"
+	PromotedDouble = "double"
+	PromotedBool   = "bool"
+)
+
+// promotedTypes lists the promoted column types, indexing keyStats.types.
+var promotedTypes = []string{PromotedString, PromotedInt64, PromotedDouble, PromotedBool}
+
+// PromotedColumn maps a custom event property key to its dedicated column.
+type PromotedColumn struct {
+	Key    string `json:"key"`
+	Column string `json:"column"`
+	Type   string `json:"type"`
+}
+
+// PromotionManifest records the promoted columns. Columns are shared by all
+// apps, so that a key is written to the same column with the same type
+// whichever app sends it; Apps lists the keys each app promoted. Promotions
+// are never revoked, so files only ever gain columns.
+type PromotionManifest struct {
+	UpdatedAt time.Time           `json:"updated_at"`
+	Columns   []PromotedColumn    `json:"columns"`
+	Apps      map[string][]string `json:"apps"`
+}
+
+// column returns the promoted column of key.
+func (m *PromotionManifest) column(key string) (PromotedColumn, bool) {
+	for _, c := range m.Columns {
+		if c.Key == key {
+			return c, true
+		}
+	}
+	return PromotedColumn{}, false
+}
+
+// promote records key as promoted for appID, assigning it a column if no app
+// promoted it before. It reports whether the manifest changed.
+func (m *PromotionManifest) promote(appID, key, typ string) bool {
+	if slices.Contains(m.Apps[appID], key) {
+		return false
+	}
+	if _, ok := m.column(key); !ok {
+		m.Columns = append(m.Columns, PromotedColumn{
+			Key:    key,
+			Column: m.columnName(key),
+			Type:   typ,
+		})
+	}
+	if m.Apps == nil {
+		m.Apps = make(map[string][]string)
+	}
+	m.Apps[appID] = append(m.Apps[appID], key)
+	return true
+}
+
+// columnName returns a free column name for key: prop_ followed by the key
+// lowercased with characters outside [a-z0-9_] replaced by underscores, and
+// a numeric suffix when another key maps to the same name.
+func (m *PromotionManifest) columnName(key string) string {
+	base := promotedColumnPrefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		default:
+			return '_'
+		}
+	}, key)
+
+	name := base
+	for i := 2; slices.ContainsFunc(m.Columns, func(c PromotedColumn) bool { return c.Column == name }); i++ {
+		name = base + "_" + strconv.Itoa(i)
+	}
+	return name
+}
+
+// merge adds the promotions of other that m lacks, keeping m's column for
+// keys both promoted.
+func (m *PromotionManifest) merge(other *PromotionManifest) {
+	for appID, keys := range other.Apps {
+		for _, key := range keys {
+			typ := PromotedString
+			if c, ok := other.column(key); ok {
+				typ = c.Type
+			}
+			m.promote(appID, key, typ)
+		}
+	}
+}
+
+// KeyFrequency is how often an app's custom events carry a property key.
+type KeyFrequency struct {
+	Key string
+	// Count is the number of custom events carrying the key.
+	Count uint64
+	// Share is Count over the app's custom events.
+	Share float64
+	// Type is the promoted column type matching most of the key's values.
+	Type string
+}
+
+// keyStats counts the values seen for a property key, by promoted type.
+type keyStats struct {
+	count uint64
+	types [4]uint64
+}
+
+// appStats counts the custom events of an app and their property keys.
+type appStats struct {
+	events uint64
+	keys   map[string]*keyStats
+}
+
+// PropertyAnalyzer tracks how often each custom_event property key occurs,
+// per app. The number of distinct keys tracked per app is bounded; keys first
+// seen once the bound is reached are not counted.
+type PropertyAnalyzer struct {
+	maxKeys int
+
+	mu   sync.Mutex
+	apps map[string]*appStats
+}
+
+// NewPropertyAnalyzer creates an analyzer tracking up to maxKeys distinct
+// keys per app.
+func NewPropertyAnalyzer(maxKeys int) *PropertyAnalyzer {
+	return &PropertyAnalyzer{
+		maxKeys: maxKeys,
+		apps:    make(map[string]*appStats),
+	}
+}
+
+// Observe counts the property keys of a custom event. Other events are
+// ignored.
+func (a *PropertyAnalyzer) Observe(event *pb.EventEnvelope) {
+	custom := event.GetCustomEvent()
+	if custom == nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	app := a.apps[event.GetAppId()]
+	if app == nil {
+		app = &appStats{keys: make(map[string]*keyStats)}
+		a.apps[event.GetAppId()] = app
+	}
+	app.events++
+
+	observe := func(key string, typ int) {
+		stats := app.keys[key]
+		if stats == nil {
+			if len(app.keys) >= a.maxKeys {
+				return
+			}
+			stats = &keyStats{}
+			app.keys[key] = stats
+		}
+		stats.count++
+		stats.types[typ]++
+	}
+	for key := range custom.GetStringParams() {
+		observe(key, 0)
+	}
+	for key := range custom.GetIntParams() {
+		observe(key, 1)
+	}
+	for key := range custom.GetFloatParams() {
+		observe(key, 2)
+	}
+	for key := range custom.GetBoolParams() {
+		observe(key, 3)
+	}
+}
+
+// Events returns the number of custom events observed for appID.
+func (a *PropertyAnalyzer) Events(appID string) uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if app := a.apps[appID]; app != nil {
+		return app.events
+	}
+	return 0
+}
+
+// Apps returns the apps with observed custom events, sorted.
+func (a *PropertyAnalyzer) Apps() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	apps := make([]string, 0, len(a.apps))
+	for appID := range a.apps {
+		apps = append(apps, appID)
+	}
+	slices.Sort(apps)
+	return apps
+}
+
+// TopKeys returns the n most frequent property keys of appID, most frequent
+// first, ties broken by key.
+func (a *PropertyAnalyzer) TopKeys(appID string, n int) []KeyFrequency {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	app := a.apps[appID]
+	if app == nil || app.events == 0 {
+		return nil
+	}
+
+	keys := make([]KeyFrequency, 0, len(app.keys))
+	for key, stats := range app.keys {
+		typ := 0
+		for i, count := range stats.types {
+			if count > stats.types[typ] {
+				typ = i
+			}
+		}
+		keys = append(keys, KeyFrequency{
+			Key:   key,
+			Count: stats.count,
+			Share: float64(stats.count) / float64(app.events),
+			Type:  promotedTypes[typ],
+		})
+	}
+	slices.SortFunc(keys, func(x, y KeyFrequency) int {
+		if c := cmp.Compare(y.Count, x.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(x.Key, y.Key)
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+// ColumnPromoter decides which custom event property keys get dedicated
+// Parquet columns. It feeds a PropertyAnalyzer with the events written and,
+// on Update, promotes each app's most frequent keys, recording the mapping in
+// a manifest stored next to the data under the S3 prefix.
+type ColumnPromoter struct {
+	store    DeltaObjectStore
+	bucket   string
+	key      string
+	config   PromotionConfig
+	analyzer *PropertyAnalyzer
+	logger   *slog.Logger
+
+	mu       sync.RWMutex
+	manifest *PromotionManifest
+}
+
+// NewColumnPromoter creates a promoter whose manifest is stored at
+// {prefix}/_promoted_columns.json in the configured bucket.
+func NewColumnPromoter(store DeltaObjectStore, s3Cfg S3Config, cfg PromotionConfig, logger *slog.Logger) *ColumnPromoter {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &ColumnPromoter{
+		store:    store,
+		bucket:   s3Cfg.Bucket,
+		key:      strings.TrimSuffix(s3Cfg.Prefix, "/") + "/" + promotionManifestName,
+		config:   cfg,
+		analyzer: NewPropertyAnalyzer(cfg.MaxTrackedKeys),
+		logger:   logger.With("component", "column-promoter"),
+		manifest: &PromotionManifest{},
+	}
+}
+
+// Load reads the manifest, if one was written before.
+func (p *ColumnPromoter) Load(ctx context.Context) error {
+	manifest, err := p.read(ctx)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.manifest = manifest
+	p.mu.Unlock()
+
+	p.logger.Info("column promotion manifest loaded", "key", p.key, "columns", len(manifest.Columns))
+	return nil
+}
+
+// Observe counts the property keys of an event written to the lake.
+func (p *ColumnPromoter) Observe(event *pb.EventEnvelope) {
+	p.analyzer.Observe(event)
+}
+
+// Columns returns the promoted columns for a file holding events, sorted by
+// column name: the union of the keys promoted by the apps of the events.
+func (p *ColumnPromoter) Columns(events []*pb.EventEnvelope) []PromotedColumn {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if len(p.manifest.Columns) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var columns []PromotedColumn
+	for _, event := range events {
+		appID := event.GetAppId()
+		if seen[appID] {
+			continue
+		}
+		seen[appID] = true
+		for _, key := range p.manifest.Apps[appID] {
+			if c, ok := p.manifest.column(key); ok && !slices.Contains(columns, c) {
+				columns = append(columns, c)
+			}
+		}
+	}
+	slices.SortFunc(columns, func(x, y PromotedColumn) int { return cmp.Compare(x.Column, y.Column) })
+	return columns
+}
+
+// Update promotes, for each app with at least MinEvents custom events, the
+// most frequent keys carried by at least MinShare of them, up to MaxColumns
+// keys per app. When keys are promoted, the manifest is merged with the
+// stored one (other sinks may have promoted keys meanwhile) and saved; the
+// new columns are written from the next flush on.
+func (p *ColumnPromoter) Update(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	next := p.clone()
+	var promoted []string
+	for _, appID := range p.analyzer.Apps() {
+		if p.analyzer.Events(appID) < p.config.MinEvents {
+			continue
+		}
+		for _, kf := range p.analyzer.TopKeys(appID, p.config.MaxColumns) {
+			if len(next.Apps[appID]) >= p.config.MaxColumns {
+				break
+			}
+			if kf.Share < p.config.MinShare {
+				break
+			}
+			if next.promote(appID, kf.Key, kf.Type) {
+				promoted = append(promoted, appID+"."+kf.Key)
+			}
+		}
+	}
+	if len(promoted) == 0 {
+		return nil
+	}
+
+	stored, err := p.read(ctx)
+	if err != nil {
+		return err
+	}
+	stored.merge(next)
+	stored.UpdatedAt = time.Now().UTC()
+	if err := p.write(ctx, stored); err != nil {
+		return err
+	}
+	p.manifest = stored
+
+	p.logger.Info("promoted custom event properties to columns", "keys", promoted)
+	return nil
+}
+
+// Manifest returns a copy of the current manifest.
+func (p *ColumnPromoter) Manifest() PromotionManifest {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return *p.clone()
+}
+
+// clone returns a deep copy of the manifest. The caller holds mu.
+func (p *ColumnPromoter) clone() *PromotionManifest {
+	m := &PromotionManifest{
+		UpdatedAt: p.manifest.UpdatedAt,
+		Columns:   slices.Clone(p.manifest.Columns),
+		Apps:      make(map[string][]string, len(p.manifest.Apps)),
+	}
+	for appID, keys := range p.manifest.Apps {
+		m.Apps[appID] = slices.Clone(keys)
+	}
+	return m
+}
+
+// read reads the stored manifest, or returns an empty one if none exists.
+func (p *ColumnPromoter) read(ctx context.Context) (*PromotionManifest, error) {
+	out, err := p.store.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(p.key),
+	})
+	if err != nil {
+		if IsNotFound(err) {
+			return &PromotionManifest{}, nil
+		}
+		return nil, fmt.Errorf("failed to read column promotion manifest: %w", err)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read column promotion manifest: %w", err)
+	}
+	var m PromotionManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse column promotion manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// write stores the manifest.
+func (p *ColumnPromoter) write(ctx context.Context, m *PromotionManifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode column promotion manifest: %w", err)
+	}
+	_, err = p.store.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(p.bucket),
+		Key:         aws.String(p.key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write column promotion manifest: %w", err)
+	}
+	return nil
+}
+
+// promotedGoTypes maps promoted column types to the Go types their schema
+// fields are built from.
+var promotedGoTypes = map[string]reflect.Type{
+	PromotedString: reflect.TypeOf(""),
+	PromotedInt64:  reflect.TypeOf(int64(0)),
+	PromotedDouble: reflect.TypeOf(float64(0)),
+	PromotedBool:   reflect.TypeOf(false),
+}
+
+// eventRowSchema is the Parquet schema of EventRow.
+var eventRowSchema = parquet.SchemaOf(EventRow{})
+
+// promotedWriteChunk is the number of rows handed to the Parquet writer at a
+// time by WritePromoted; the row buffers are reused between chunks.
+const promotedWriteChunk = 1024
+
+// EventRowSchema returns the EventRow schema extended with optional promoted
+// columns after the EventRow columns, in the order given.
+func EventRowSchema(columns []PromotedColumn) *parquet.Schema {
+	if len(columns) == 0 {
+		return eventRowSchema
+	}
+
+	base := reflect.TypeOf(EventRow{})
+	fields := make([]reflect.StructField, 0, base.NumField()+len(columns))
+	for i := 0; i < base.NumField(); i++ {
+		fields = append(fields, base.Field(i))
+	}
+	for i, c := range columns {
+		typ, ok := promotedGoTypes[c.Type]
+		if !ok {
+			typ = promotedGoTypes[PromotedString]
+		}
+		tag := c.Column + ",optional"
+		if typ.Kind() == reflect.String {
+			tag = c.Column + ",snappy,optional"
+		}
+		fields = append(fields, reflect.StructField{
+			Name: "Promoted" + strconv.Itoa(i),
+			Type: typ,
+			Tag:  reflect.StructTag(`parquet:"` + tag + `"`),
+		})
+	}
+	return parquet.SchemaOf(reflect.New(reflect.StructOf(fields)).Elem().Interface())
+}
+
+// PromotedColumnsOf returns the promoted columns present in the given file
+// schemas, in order of first appearance. Files do not record property keys,
+// so Key is the column name without its prefix. Compaction merges files with
+// the schema EventRowSchema builds from them, so promoted columns survive.
+func PromotedColumnsOf(schemas ...*parquet.Schema) []PromotedColumn {
+	var columns []PromotedColumn
+	for _, schema := range schemas {
+		for _, field := range schema.Fields() {
+			name := field.Name()
+			if !strings.HasPrefix(name, promotedColumnPrefix) || !field.Leaf() {
+				continue
+			}
+			if slices.ContainsFunc(columns, func(c PromotedColumn) bool { return c.Column == name }) {
+				continue
+			}
+			typ := PromotedString
+			switch field.Type().Kind() {
+			case parquet.Int64:
+				typ = PromotedInt64
+			case parquet.Double:
+				typ = PromotedDouble
+			case parquet.Boolean:
+				typ = PromotedBool
+			}
+			columns = append(columns, PromotedColumn{
+				Key:    strings.TrimPrefix(name, promotedColumnPrefix),
+				Column: name,
+				Type:   typ,
+			})
+		}
+	}
+	return columns
+}
+
+// appendPromoted appends the promoted column values of an event to row. The
+// first promoted column has index first. Values missing from the event, or
+// not convertible to the column type, are written as nulls; they remain in
+// payload_json either way.
+func appendPromoted(row parquet.Row, event *pb.EventEnvelope, columns []PromotedColumn, first int) parquet.Row {
+	custom := event.GetCustomEvent()
+	for i, c := range columns {
+		column := first + i
+		v, ok := promotedValue(custom, c)
+		if !ok {
+			row = append(row, parquet.NullValue().Level(0, 0, column))
+			continue
+		}
+		row = append(row, v.Level(0, 1, column))
+	}
+	return row
+}
+
+// promotedValue returns the value of a promoted column for a custom event,
+// converting between the parameter maps where no precision is lost.
+func promotedValue(custom *pb.CustomEvent, c PromotedColumn) (parquet.Value, bool) {
+	if custom == nil {
+		return parquet.Value{}, false
+	}
+	s, isString := custom.GetStringParams()[c.Key]
+	n, isInt := custom.GetIntParams()[c.Key]
+	f, isFloat := custom.GetFloatParams()[c.Key]
+	b, isBool := custom.GetBoolParams()[c.Key]
+
+	switch c.Type {
+	case PromotedInt64:
+		switch {
+		case isInt:
+			return parquet.Int64Value(n), true
+		case isFloat && f == math.Trunc(f) && math.Abs(f) < 1<<53:
+			return parquet.Int64Value(int64(f)), true
+		}
+	case PromotedDouble:
+		switch {
+		case isFloat:
+			return parquet.DoubleValue(f), true
+		case isInt:
+			return parquet.DoubleValue(float64(n)), true
+		}
+	case PromotedBool:
+		if isBool {
+			return parquet.BooleanValue(b), true
+		}
+	default:
+		switch {
+		case isString:
+			return parquet.ByteArrayValue([]byte(s)), true
+		case isInt:
+			return parquet.ByteArrayValue(strconv.AppendInt(nil, n, 10)), true
+		case isFloat:
+			return parquet.ByteArrayValue(strconv.AppendFloat(nil, f, 'g', -1, 64)), true
+		case isBool:
+			return parquet.ByteArrayValue(strconv.AppendBool(nil, b)), true
+		}
+	}
+	return parquet.Value{}, false
+}
+
+// WritePromoted writes rows like Write, adding the promoted columns filled
+// from events, which hold the event of each row.
+func (w *ParquetWriter) WritePromoted(rows []EventRow, events []*pb.EventEnvelope, columns []PromotedColumn) ([]byte, error) {
+	n := len(rows)
+	if n == 0 {
+		return nil, ErrNoRowsToWrite
+	}
+
+	// Sort by timestamp (see Write) through a permutation, keeping each row
+	// next to its event
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(rows[a].TimestampMS, rows[b].TimestampMS)
+	})
+
+	var buf bytes.Buffer
+	options := append([]parquet.WriterOption{EventRowSchema(columns)}, w.writerOptions()...)
+	writer := parquet.NewWriter(&buf, options...)
+
+	first := len(eventRowSchema.Columns())
+	buffer := make([]parquet.Row, min(n, promotedWriteChunk))
+	for start := 0; start < n; start += promotedWriteChunk {
+		chunk := order[start:min(start+promotedWriteChunk, n)]
+		for j, i := range chunk {
+			buffer[j] = appendPromoted(eventRowSchema.Deconstruct(buffer[j][:0], &rows[i]), events[i], columns, first)
+		}
+		if _, err := writer.WriteRows(buffer[:len(chunk)]); err != nil {
+			return nil, fmt.Errorf("failed to write rows: %w", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close writer: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,234 @@
+package warehouse
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// promotionTestEvent returns a custom event of appID with the given
+// parameters, sorted into the parameter maps by value type.
+func promotionTestEvent(appID string, ts int64, params map[string]interface{}) *pb.EventEnvelope {
+	custom := &pb.CustomEvent{EventName: "level_up"}
+	for key, value := range params {
+		switch v := value.(type) {
+		case string:
+			if custom.StringParams == nil {
+				custom.StringParams = make(map[string]string)
+			}
+			custom.StringParams[key] = v
+		case int64:
+			if custom.IntParams == nil {
+				custom.IntParams = make(map[string]int64)
+			}
+			custom.IntParams[key] = v
+		case float64:
+			if custom.FloatParams == nil {
+				custom.FloatParams = make(map[string]float64)
+			}
+			custom.FloatParams[key] = v
+		case bool:
+			if custom.BoolParams == nil {
+				custom.BoolParams = make(map[string]bool)
+			}
+			custom.BoolParams[key] = v
+		}
+	}
+	return &pb.EventEnvelope{
+		Id:          fmt.Sprintf("%s-%d", appID, ts),
+		AppId:       appID,
+		DeviceId:    "dev-1",
+		TimestampMs: ts,
+		Payload:     &pb.EventEnvelope_CustomEvent{CustomEvent: custom},
+	}
+}
+
+func TestPropertyAnalyzer_TopKeys(t *testing.T) {
+	a := NewPropertyAnalyzer(3)
+	for i := 0; i < 10; i++ {
+		params := map[string]interface{}{"level": int64(i)}
+		if i%2 == 0 {
+			params["character"] = "mage"
+		}
+		if i < 3 {
+			params["score"] = 1.5
+		}
+		if i == 9 {
+			// Beyond the tracked key bound.
+			params["rare"] = true
+		}
+		a.Observe(promotionTestEvent("app", int64(i), params))
+	}
+	a.Observe(&pb.EventEnvelope{AppId: "app", Payload: &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{}}})
+
+	if got := a.Events("app"); got != 10 {
+		t.Errorf("Events() = %d, want 10", got)
+	}
+
+	want := []KeyFrequency{
+		{Key: "level", Count: 10, Share: 1, Type: PromotedInt64},
+		{Key: "character", Count: 5, Share: 0.5, Type: PromotedString},
+	}
+	if got := a.TopKeys("app", 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("TopKeys() = %+v, want %+v", got, want)
+	}
+	if got := a.TopKeys("app", 10); len(got) != 3 {
+		t.Errorf("TopKeys() returned %d keys, want 3", len(got))
+	}
+	if got := a.TopKeys("other", 2); got != nil {
+		t.Errorf("TopKeys() of unknown app = %+v, want nil", got)
+	}
+}
+
+func TestPromotionManifest_ColumnNames(t *testing.T) {
+	var m PromotionManifest
+	if !m.promote("a", "Level", PromotedInt64) {
+		t.Fatal("promote() = false for a new key")
+	}
+	m.promote("a", "level", PromotedInt64)
+	m.promote("a", "play-time", PromotedDouble)
+	m.promote("b", "Level", PromotedString)
+	if m.promote("a", "level", PromotedInt64) {
+		t.Error("promote() = true for a key already promoted")
+	}
+
+	want := []PromotedColumn{
+		{Key: "Level", Column: "prop_level", Type: PromotedInt64},
+		{Key: "level", Column: "prop_level_2", Type: PromotedInt64},
+		{Key: "play-time", Column: "prop_play_time", Type: PromotedDouble},
+	}
+	if !reflect.DeepEqual(m.Columns, want) {
+		t.Errorf("Columns = %+v, want %+v", m.Columns, want)
+	}
+	if got := m.Apps["b"]; !reflect.DeepEqual(got, []string{"Level"}) {
+		t.Errorf("Apps[b] = %v, want [Level]", got)
+	}
+}
+
+// TestColumnPromoter_Update verifies keys are promoted once an app reaches
+// the thresholds, and that the manifest is merged with the stored one.
+func TestColumnPromoter_Update(t *testing.T) {
+	ctx := context.Background()
+	store := newTestFSStore(t)
+	s3Cfg := S3Config{Bucket: "b", Prefix: "events"}
+	cfg := PromotionConfig{Enabled: true, MaxColumns: 2, MinShare: 0.5, MinEvents: 4, MaxTrackedKeys: 16}
+
+	other := NewColumnPromoter(store, s3Cfg, cfg, nil)
+	for i := 0; i < 4; i++ {
+		other.Observe(promotionTestEvent("web", int64(i), map[string]interface{}{"plan": "pro"}))
+	}
+	if err := other.Update(ctx); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	p := NewColumnPromoter(store, s3Cfg, cfg, nil)
+	for i := 0; i < 3; i++ {
+		p.Observe(promotionTestEvent("ios", int64(i), map[string]interface{}{
+			"level": int64(i), "score": 2.5, "hard": true,
+		}))
+	}
+	if err := p.Update(ctx); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got := p.Manifest(); len(got.Columns) != 0 {
+		t.Fatalf("columns promoted below MinEvents: %+v", got.Columns)
+	}
+
+	p.Observe(promotionTestEvent("ios", 3, map[string]interface{}{"level": int64(3)}))
+	if err := p.Update(ctx); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	loaded := NewColumnPromoter(store, s3Cfg, cfg, nil)
+	if err := loaded.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	manifest := loaded.Manifest()
+	wantApps := map[string][]string{
+		"web": {"plan"},
+		"ios": {"level", "hard"},
+	}
+	if !reflect.DeepEqual(manifest.Apps, wantApps) {
+		t.Errorf("Apps = %v, want %v", manifest.Apps, wantApps)
+	}
+	if manifest.UpdatedAt.IsZero() {
+		t.Error("UpdatedAt not set")
+	}
+
+	events := []*pb.EventEnvelope{promotionTestEvent("ios", 0, nil)}
+	want := []PromotedColumn{
+		{Key: "hard", Column: "prop_hard", Type: PromotedBool},
+		{Key: "level", Column: "prop_level", Type: PromotedInt64},
+	}
+	if got := loaded.Columns(events); !reflect.DeepEqual(got, want) {
+		t.Errorf("Columns() = %+v, want %+v", got, want)
+	}
+}
+
+// promotedTestRow is a row read back from a file with promoted columns.
+type promotedTestRow struct {
+	ID        string   `parquet:"id"`
+	Character *string  `parquet:"prop_character,optional"`
+	Level     *int64   `parquet:"prop_level,optional"`
+	Score     *float64 `parquet:"prop_score,optional"`
+}
+
+// TestWritePromoted verifies the promoted columns are filled, converting
+// values where possible.
+func TestWritePromoted(t *testing.T) {
+	writer := NewParquetWriter(ParquetConfig{Compression: "snappy"})
+	base := time.Date(2026, 3, 10, 14, 0, 0, 0, time.UTC).UnixMilli()
+	events := []*pb.EventEnvelope{
+		promotionTestEvent("app", base+2000, map[string]interface{}{"character": "mage", "level": int64(3), "score": 2.5}),
+		promotionTestEvent("app", base+1000, map[string]interface{}{"character": int64(7), "level": 4.0, "score": int64(9)}),
+		promotionTestEvent("app", base+3000, map[string]interface{}{"level": 4.5}),
+		{Id: "screen", AppId: "app", TimestampMs: base + 4000, Payload: &pb.EventEnvelope_ScreenView{ScreenView: &pb.ScreenView{ScreenName: "home"}}},
+	}
+	columns := []PromotedColumn{
+		{Key: "character", Column: "prop_character", Type: PromotedString},
+		{Key: "level", Column: "prop_level", Type: PromotedInt64},
+		{Key: "score", Column: "prop_score", Type: PromotedDouble},
+	}
+
+	rows := make([]EventRow, len(events))
+	for i, event := range events {
+		rows[i] = EventRowFromProto(event, 2026, 3, 10, 14)
+	}
+
+	data, err := writer.WritePromoted(rows, events, columns)
+	if err != nil {
+		t.Fatalf("WritePromoted() error = %v", err)
+	}
+
+	file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	if got := PromotedColumnsOf(file.Schema()); !reflect.DeepEqual(got, columns) {
+		t.Errorf("PromotedColumnsOf() = %+v, want %+v", got, columns)
+	}
+
+	got, err := parquet.Read[promotedTestRow](bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	str := func(s string) *string { return &s }
+	i64 := func(n int64) *int64 { return &n }
+	f64 := func(f float64) *float64 { return &f }
+	want := []promotedTestRow{
+		{ID: "app-" + fmt.Sprint(base+1000), Character: str("7"), Level: i64(4), Score: f64(9)},
+		{ID: "app-" + fmt.Sprint(base+2000), Character: str("mage"), Level: i64(3), Score: f64(2.5)},
+		{ID: "app-" + fmt.Sprint(base+3000)},
+		{ID: "screen"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %+v, want %+v", got, want)
+	}
+}
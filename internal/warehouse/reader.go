@@ -0,0 +1,157 @@
+package warehouse
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// PartitionReader lists and downloads the Parquet files of the event lake's
+// partitions for batch jobs. With a Delta log, files removed by compaction
+// but not yet vacuumed are skipped so rows are not read twice.
+type PartitionReader struct {
+	s3Client   ObjectAPI
+	s3Config   S3Config
+	partitions *PartitionScheme
+	deltaLog   *DeltaLog
+}
+
+// NewPartitionReader creates a reader for the event lake described by
+// s3Config. deltaLog may be nil.
+func NewPartitionReader(s3Client ObjectAPI, s3Config S3Config, deltaLog *DeltaLog) *PartitionReader {
+	return &PartitionReader{
+		s3Client:   s3Client,
+		s3Config:   s3Config,
+		partitions: s3Config.Partitioning(),
+		deltaLog:   deltaLog,
+	}
+}
+
+// Window returns the duration of a partition window: an hour, or a day when
+// partitions span a day.
+func (r *PartitionReader) Window() time.Duration {
+	if r.partitions.Hourly() {
+		return time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// ListApps returns the app IDs with data in the warehouse.
+func (r *PartitionReader) ListApps(ctx context.Context) ([]string, error) {
+	root := strings.TrimSuffix(r.s3Config.Prefix, "/") + "/"
+	paginator := s3.NewListObjectsV2Paginator(r.s3Client, &s3.ListObjectsV2Input{
+		Bucket:    aws.String(r.s3Config.Bucket),
+		Prefix:    aws.String(root + "app_id="),
+		Delimiter: aws.String("/"),
+	})
+
+	var apps []string
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list apps: %w", err)
+		}
+		for _, cp := range page.CommonPrefixes {
+			if cp.Prefix == nil {
+				continue
+			}
+			appID := strings.TrimSuffix(strings.TrimPrefix(*cp.Prefix, root+"app_id="), "/")
+			if appID != "" {
+				apps = append(apps, appID)
+			}
+		}
+	}
+
+	return apps, nil
+}
+
+// ListWindow returns the keys of the Parquet files in an app's partitions
+// whose window starts at start, sorted.
+func (r *PartitionReader) ListWindow(ctx context.Context, appID string, start time.Time) ([]string, error) {
+	return r.list(ctx, appID, start, func(windowStart, _ time.Time) bool {
+		return windowStart.Equal(start)
+	})
+}
+
+// ListCovering returns the keys of the Parquet files in an app's partitions
+// whose window covers t, sorted. With daily partitions the files also hold
+// rows of other hours.
+func (r *PartitionReader) ListCovering(ctx context.Context, appID string, t time.Time) ([]string, error) {
+	return r.list(ctx, appID, t, func(windowStart, windowEnd time.Time) bool {
+		return !t.Before(windowStart) && t.Before(windowEnd)
+	})
+}
+
+// list returns the sorted keys of the active Parquet files in an app's
+// partitions of the hour t whose window matches.
+func (r *PartitionReader) list(ctx context.Context, appID string, t time.Time, match func(start, end time.Time) bool) ([]string, error) {
+	prefix := strings.TrimSuffix(r.s3Config.Prefix, "/") + "/" + r.partitions.HourPrefix(appID, t)
+	paginator := s3.NewListObjectsV2Paginator(r.s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(r.s3Config.Bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	var keys []string
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list objects in %s: %w", prefix, err)
+		}
+		for _, obj := range page.Contents {
+			if obj.Key == nil || !strings.HasSuffix(*obj.Key, ".parquet") {
+				continue
+			}
+			// The listed prefix may hold other partitions when the template
+			// has segments such as category before the date.
+			p, ok := r.partitions.Parse(*obj.Key)
+			if !ok {
+				continue
+			}
+			if match(r.partitions.Window(p)) {
+				keys = append(keys, *obj.Key)
+			}
+		}
+	}
+
+	if r.deltaLog != nil && len(keys) > 0 {
+		snapshot, err := r.deltaLog.Snapshot(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("read delta snapshot: %w", err)
+		}
+		active := keys[:0]
+		for _, key := range keys {
+			if snapshot.Contains(key) {
+				active = append(active, key)
+			}
+		}
+		keys = active
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Download returns the contents of an S3 object.
+func (r *PartitionReader) Download(ctx context.Context, key string) ([]byte, error) {
+	result, err := r.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r.s3Config.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get object %s: %w", key, err)
+	}
+	defer result.Body.Close()
+
+	data, err := io.ReadAll(result.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read object %s: %w", key, err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,127 @@
+package warehouse
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPartitionReader_List(t *testing.T) {
+	ctx := context.Background()
+	store := newTestFSStore(t)
+	for _, key := range []string{
+		"events/app_id=demo/year=2026/month=01/day=05/hour=07/events_b.parquet",
+		"events/app_id=demo/year=2026/month=01/day=05/hour=07/events_a.parquet",
+		"events/app_id=demo/year=2026/month=01/day=05/hour=07/_SUCCESS",
+		"events/app_id=demo/year=2026/month=01/day=05/hour=08/events_c.parquet",
+		"events/app_id=other/year=2026/month=01/day=05/hour=07/events_d.parquet",
+	} {
+		putFS(t, store, key, "data")
+	}
+	r := NewPartitionReader(store, S3Config{Bucket: "b", Prefix: "events/", PartitionTemplate: "app_id/date/hour"}, nil)
+
+	apps, err := r.ListApps(ctx)
+	if err != nil {
+		t.Fatalf("ListApps() error = %v", err)
+	}
+	if want := []string{"demo", "other"}; !reflect.DeepEqual(apps, want) {
+		t.Errorf("ListApps() = %v, want %v", apps, want)
+	}
+
+	if r.Window() != time.Hour {
+		t.Errorf("Window() = %v, want 1h", r.Window())
+	}
+
+	hour := time.Date(2026, 1, 5, 7, 0, 0, 0, time.UTC)
+	want := []string{
+		"events/app_id=demo/year=2026/month=01/day=05/hour=07/events_a.parquet",
+		"events/app_id=demo/year=2026/month=01/day=05/hour=07/events_b.parquet",
+	}
+	keys, err := r.ListWindow(ctx, "demo", hour)
+	if err != nil {
+		t.Fatalf("ListWindow() error = %v", err)
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("ListWindow() = %v, want %v", keys, want)
+	}
+	keys, err = r.ListCovering(ctx, "demo", hour.Add(30*time.Minute))
+	if err != nil {
+		t.Fatalf("ListCovering() error = %v", err)
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("ListCovering() = %v, want %v", keys, want)
+	}
+
+	data, err := r.Download(ctx, want[0])
+	if err != nil || string(data) != "data" {
+		t.Errorf("Download() = %q, %v; want data", data, err)
+	}
+}
+
+// TestPartitionReader_DailyCategory verifies files of other days under the
+// listed prefix are skipped when partitions span a day.
+func TestPartitionReader_DailyCategory(t *testing.T) {
+	ctx := context.Background()
+	store := newTestFSStore(t)
+	for _, key := range []string{
+		"events/app_id=demo/event_category=screen/year=2026/month=01/day=05/events_a.parquet",
+		"events/app_id=demo/event_category=commerce/year=2026/month=01/day=05/events_b.parquet",
+		"events/app_id=demo/event_category=screen/year=2026/month=01/day=06/events_c.parquet",
+	} {
+		putFS(t, store, key, "data")
+	}
+	r := NewPartitionReader(store, S3Config{Bucket: "b", Prefix: "events", PartitionTemplate: "app_id/category/date"}, nil)
+
+	if r.Window() != 24*time.Hour {
+		t.Errorf("Window() = %v, want 24h", r.Window())
+	}
+
+	want := []string{
+		"events/app_id=demo/event_category=commerce/year=2026/month=01/day=05/events_b.parquet",
+		"events/app_id=demo/event_category=screen/year=2026/month=01/day=05/events_a.parquet",
+	}
+	keys, err := r.ListCovering(ctx, "demo", time.Date(2026, 1, 5, 7, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("ListCovering() error = %v", err)
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("ListCovering() = %v, want %v", keys, want)
+	}
+
+	keys, err = r.ListWindow(ctx, "demo", time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("ListWindow() error = %v", err)
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("ListWindow() = %v, want %v", keys, want)
+	}
+}
+
+// TestPartitionReader_DeltaLog verifies files removed from the Delta log are
+// skipped.
+func TestPartitionReader_DeltaLog(t *testing.T) {
+	ctx := context.Background()
+	store := newTestFSStore(t)
+	cfg := S3Config{Bucket: "b", Prefix: "events", PartitionTemplate: "app_id/date/hour"}
+	active := "events/app_id=demo/year=2026/month=01/day=05/hour=07/events_a.parquet"
+	removed := "events/app_id=demo/year=2026/month=01/day=05/hour=07/events_b.parquet"
+	putFS(t, store, active, "data")
+	putFS(t, store, removed, "data")
+
+	deltaLog := NewDeltaLog(store, cfg, DeltaConfig{Enabled: true, MaxCommitRetries: 5}, nil)
+	if err := deltaLog.Init(ctx); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if err := deltaLog.Append(ctx, DeltaFile{Key: active, Size: 4, NumRecords: 1}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	keys, err := NewPartitionReader(store, cfg, deltaLog).ListWindow(ctx, "demo", time.Date(2026, 1, 5, 7, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("ListWindow() error = %v", err)
+	}
+	if want := []string{active}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("ListWindow() = %v, want %v", keys, want)
+	}
+}
@@ -14,19 +14,55 @@ import (
 	"github.com/google/uuid"
 )
 
-// S3Client handles S3/MinIO operations.
+// ObjectAPI is the subset of the S3 API used by the warehouse, compaction and
+// forecast modules. *s3.Client and *FSStore satisfy it.
+type ObjectAPI interface {
+	DeltaObjectStore
+	s3.ListObjectsV2APIClient
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+	HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+	CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error)
+}
+
+// S3Client handles S3/MinIO operations. With the fs backend it operates on a
+// local directory through FSStore instead.
 type S3Client struct {
-	client *s3.Client
-	config S3Config
-	logger *slog.Logger
+	client     ObjectAPI
+	config     S3Config
+	partitions *PartitionScheme
+	logger     *slog.Logger
 }
 
-// NewS3Client creates a new S3 client.
+// NewS3Client creates a new S3 client, or a client backed by FSStore when
+// cfg.Backend is fs.
 func NewS3Client(ctx context.Context, cfg S3Config, logger *slog.Logger) (*S3Client, error) {
 	if logger == nil {
 		logger = slog.Default()
 	}
 
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if cfg.Backend == BackendFS {
+		store, err := NewFSStore(cfg.FSRoot)
+		if err != nil {
+			return nil, err
+		}
+
+		logger.Info("filesystem object store created",
+			"root", cfg.FSRoot,
+			"bucket", cfg.Bucket,
+		)
+
+		return &S3Client{
+			client:     store,
+			config:     cfg,
+			partitions: cfg.Partitioning(),
+			logger:     logger.With("component", "s3-client"),
+		}, nil
+	}
+
 	// Create AWS config with custom endpoint
 	awsCfg, err := config.LoadDefaultConfig(ctx,
 		config.WithRegion(cfg.Region),
@@ -47,23 +83,26 @@ func NewS3Client(ctx context.Context, cfg S3Config, logger *slog.Logger) (*S3Cli
 	})
 
 	s3Client := &S3Client{
-		client: client,
-		config: cfg,
-		logger: logger.With("component", "s3-client"),
+		client:     client,
+		config:     cfg,
+		partitions: cfg.Partitioning(),
+		logger:     logger.With("component", "s3-client"),
 	}
 
 	logger.Info("S3 client created",
 		"endpoint", cfg.Endpoint,
 		"bucket", cfg.Bucket,
 		"region", cfg.Region,
+		"sse", cfg.SSE,
+		"tagging", cfg.TaggingEnabled,
 	)
 
 	return s3Client, nil
 }
 
-// RawClient returns the underlying AWS S3 client for use by other modules
+// RawClient returns the underlying object API for use by other modules
 // (e.g., compaction) that need direct S3 API access.
-func (c *S3Client) RawClient() *s3.Client {
+func (c *S3Client) RawClient() ObjectAPI {
 	return c.client
 }
 
@@ -91,14 +130,18 @@ func (c *S3Client) EnsureBucket(ctx context.Context) error {
 	return nil
 }
 
-// Upload uploads data to S3.
-func (c *S3Client) Upload(ctx context.Context, key string, data []byte) error {
-	_, err := c.client.PutObject(ctx, &s3.PutObjectInput{
+// Upload uploads data to S3, applying the configured server-side encryption
+// and tagging the object with the owning app_id.
+func (c *S3Client) Upload(ctx context.Context, key, appID string, data []byte) error {
+	input := &s3.PutObjectInput{
 		Bucket:      aws.String(c.config.Bucket),
 		Key:         aws.String(key),
 		Body:        bytes.NewReader(data),
 		ContentType: aws.String("application/x-parquet"),
-	})
+	}
+	c.config.ApplyObjectOptions(input, appID)
+
+	_, err := c.client.PutObject(ctx, input)
 	if err != nil {
 		return fmt.Errorf("failed to upload to S3: %w", err)
 	}
@@ -112,21 +155,24 @@ func (c *S3Client) Upload(ctx context.Context, key string, data []byte) error {
 }
 
 // GenerateKey generates an S3 key for the given partition.
-// Format: {prefix}/app_id={app}/year={y}/month={m}/day={d}/hour={h}/events_{uuid}.parquet.
-func (c *S3Client) GenerateKey(appID string, year, month, day, hour int) string {
+// Format: {prefix}/{partition path}/events_{uuid}.parquet, where the partition
+// path follows the configured template, by default
+// app_id={app}/year={y}/month={m}/day={d}/hour={h}.
+func (c *S3Client) GenerateKey(partition Partition) string {
 	fileUUID := uuid.New().String()
 	return fmt.Sprintf(
-		"%s/app_id=%s/year=%d/month=%02d/day=%02d/hour=%02d/events_%s.parquet",
+		"%s/%sevents_%s.parquet",
 		c.config.Prefix,
-		appID,
-		year,
-		month,
-		day,
-		hour,
+		c.partitions.Path(partition),
 		fileUUID,
 	)
 }
 
+// Partitioning returns the partition scheme keys are generated with.
+func (c *S3Client) Partitioning() *PartitionScheme {
+	return c.partitions
+}
+
 // HealthCheck performs a health check on the S3 connection.
 func (c *S3Client) HealthCheck(ctx context.Context) error {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
@@ -144,7 +190,7 @@ func (c *S3Client) HealthCheck(ctx context.Context) error {
 
 // ListPartitions lists all partitions in the bucket.
 func (c *S3Client) ListPartitions(ctx context.Context, appID string) ([]string, error) {
-	prefix := fmt.Sprintf("%s/app_id=%s/", c.config.Prefix, appID)
+	prefix := c.config.Prefix + "/" + c.partitions.AppPrefix(appID)
 
 	paginator := s3.NewListObjectsV2Paginator(c.client, &s3.ListObjectsV2Input{
 		Bucket:    aws.String(c.config.Bucket),
@@ -0,0 +1,132 @@
+package warehouse
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Object store backends accepted in S3Config.Backend.
+const (
+	BackendS3 = "s3"
+	BackendFS = "fs"
+)
+
+// Server-side encryption modes accepted in S3Config.SSE.
+const (
+	SSENone = "none"
+	SSES3   = "sse-s3"
+	SSEKMS  = "sse-kms"
+)
+
+// Object tag keys attached to uploaded Parquet files.
+const (
+	TagAppID          = "app_id"
+	TagRetentionClass = "retention-class"
+)
+
+// maxObjectTags is the S3 limit on tags per object.
+const maxObjectTags = 10
+
+// Validate checks the backend, server-side encryption and tagging settings.
+func (c S3Config) Validate() error {
+	switch c.Backend {
+	case "", BackendS3:
+	case BackendFS:
+		if c.FSRoot == "" {
+			return fmt.Errorf("%w: BACKEND=%s requires FS_ROOT", ErrInvalidS3Config, BackendFS)
+		}
+	default:
+		return fmt.Errorf("%w: unknown backend %q", ErrInvalidS3Config, c.Backend)
+	}
+
+	switch c.SSE {
+	case "", SSENone, SSES3:
+		if c.SSEKMSKeyID != "" {
+			return fmt.Errorf("%w: SSE_KMS_KEY_ID requires SSE=%s", ErrInvalidS3Config, SSEKMS)
+		}
+	case SSEKMS:
+	default:
+		return fmt.Errorf("%w: unknown SSE mode %q", ErrInvalidS3Config, c.SSE)
+	}
+
+	if _, err := c.partitionScheme(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidS3Config, err)
+	}
+
+	if c.TaggingEnabled {
+		if len(c.ExtraTags)+2 > maxObjectTags {
+			return fmt.Errorf("%w: at most %d extra object tags allowed", ErrInvalidS3Config, maxObjectTags-2)
+		}
+		for k := range c.ExtraTags {
+			if k == TagAppID || k == TagRetentionClass {
+				return fmt.Errorf("%w: object tag %q is reserved", ErrInvalidS3Config, k)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Partitioning returns the partition scheme of the lake. An empty template
+// uses DefaultPartitionTemplate; Validate reports invalid templates, for
+// which the default scheme is returned as well.
+func (c S3Config) Partitioning() *PartitionScheme {
+	scheme, err := c.partitionScheme()
+	if err != nil {
+		return DefaultPartitionScheme()
+	}
+	return scheme
+}
+
+// partitionScheme parses the configured partition template.
+func (c S3Config) partitionScheme() (*PartitionScheme, error) {
+	if c.PartitionTemplate == "" {
+		return DefaultPartitionScheme(), nil
+	}
+	return ParsePartitionScheme(c.PartitionTemplate, c.PartitionUserBuckets)
+}
+
+// ObjectTags returns the tags for an object belonging to appID, or nil if
+// tagging is disabled.
+func (c S3Config) ObjectTags(appID string) map[string]string {
+	if !c.TaggingEnabled {
+		return nil
+	}
+
+	tags := make(map[string]string, len(c.ExtraTags)+2)
+	for k, v := range c.ExtraTags {
+		tags[k] = v
+	}
+	tags[TagAppID] = appID
+	if c.RetentionClass != "" {
+		tags[TagRetentionClass] = c.RetentionClass
+	}
+	return tags
+}
+
+// ApplyObjectOptions sets server-side encryption and object tags on a
+// PutObject request. It is shared by the warehouse sink and compaction so
+// that compacted files carry the same encryption and tags as the originals.
+func (c S3Config) ApplyObjectOptions(input *s3.PutObjectInput, appID string) {
+	switch c.SSE {
+	case SSES3:
+		input.ServerSideEncryption = s3types.ServerSideEncryptionAes256
+	case SSEKMS:
+		input.ServerSideEncryption = s3types.ServerSideEncryptionAwsKms
+		if c.SSEKMSKeyID != "" {
+			input.SSEKMSKeyId = aws.String(c.SSEKMSKeyID)
+		}
+	}
+
+	if tags := c.ObjectTags(appID); len(tags) > 0 {
+		values := url.Values{}
+		for k, v := range tags {
+			values.Set(k, v)
+		}
+		input.Tagging = aws.String(values.Encode())
+	}
+}
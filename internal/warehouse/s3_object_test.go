@@ -0,0 +1,77 @@
+package warehouse
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestS3Config_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     S3Config
+		wantErr bool
+	}{
+		{name: "defaults", cfg: S3Config{SSE: SSENone, TaggingEnabled: true}},
+		{name: "sse-s3", cfg: S3Config{SSE: SSES3}},
+		{name: "sse-kms with key", cfg: S3Config{SSE: SSEKMS, SSEKMSKeyID: "arn:aws:kms:key"}},
+		{name: "unknown mode", cfg: S3Config{SSE: "sse-c"}, wantErr: true},
+		{name: "kms key without sse-kms", cfg: S3Config{SSE: SSES3, SSEKMSKeyID: "key"}, wantErr: true},
+		{name: "reserved tag", cfg: S3Config{TaggingEnabled: true, ExtraTags: map[string]string{TagAppID: "x"}}, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Validate()
+			if tc.wantErr && !errors.Is(err, ErrInvalidS3Config) {
+				t.Errorf("Validate() error = %v, want ErrInvalidS3Config", err)
+			}
+			if !tc.wantErr && err != nil {
+				t.Errorf("Validate() unexpected error = %v", err)
+			}
+		})
+	}
+}
+
+func TestS3Config_ApplyObjectOptions(t *testing.T) {
+	cfg := S3Config{
+		SSE:            SSEKMS,
+		SSEKMSKeyID:    "alias/causality",
+		TaggingEnabled: true,
+		RetentionClass: "long",
+		ExtraTags:      map[string]string{"env": "prod"},
+	}
+
+	input := &s3.PutObjectInput{}
+	cfg.ApplyObjectOptions(input, "demo app")
+
+	if input.ServerSideEncryption != s3types.ServerSideEncryptionAwsKms {
+		t.Errorf("ServerSideEncryption = %q, want aws:kms", input.ServerSideEncryption)
+	}
+	if input.SSEKMSKeyId == nil || *input.SSEKMSKeyId != "alias/causality" {
+		t.Errorf("SSEKMSKeyId = %v, want alias/causality", input.SSEKMSKeyId)
+	}
+	if input.Tagging == nil {
+		t.Fatal("Tagging not set")
+	}
+
+	tags, err := url.ParseQuery(*input.Tagging)
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+	if tags.Get(TagAppID) != "demo app" || tags.Get(TagRetentionClass) != "long" || tags.Get("env") != "prod" {
+		t.Errorf("Tagging = %q, unexpected tag values", *input.Tagging)
+	}
+}
+
+func TestS3Config_ApplyObjectOptions_Disabled(t *testing.T) {
+	input := &s3.PutObjectInput{}
+	S3Config{SSE: SSENone}.ApplyObjectOptions(input, "demo")
+
+	if input.ServerSideEncryption != "" || input.SSEKMSKeyId != nil || input.Tagging != nil {
+		t.Errorf("expected no encryption or tagging, got %+v", input)
+	}
+}
@@ -0,0 +1,50 @@
+package warehouse
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/SebastienMelki/causality/internal/events"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// TestSchemaCompat_LegacyEnvelope verifies an envelope from an SDK predating
+// schema versioning is written to the lake exactly like one at the current
+// version.
+func TestSchemaCompat_LegacyEnvelope(t *testing.T) {
+	writer := NewParquetWriter(ParquetConfig{Compression: "snappy"})
+
+	encode := func(version uint32) ([]byte, EventRow) {
+		t.Helper()
+		event := mixedTestEvents(1)[0]
+		event.SchemaVersion = version
+		data, err := proto.Marshal(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded pb.EventEnvelope
+		if err := events.Decode(data, &decoded); err != nil {
+			t.Fatalf("version %d: Decode() error = %v", version, err)
+		}
+		row := EventRowFromProto(&decoded, 2026, 3, 10, 14)
+		file, err := writer.Write([]EventRow{row})
+		if err != nil {
+			t.Fatalf("version %d: Write() error = %v", version, err)
+		}
+		return file, row
+	}
+
+	wantFile, wantRow := encode(events.CurrentSchemaVersion)
+	for _, version := range []uint32{0, events.SchemaVersionLegacy} {
+		file, row := encode(version)
+		if row != wantRow {
+			t.Errorf("version %d: row = %+v, want %+v", version, row, wantRow)
+		}
+		if !bytes.Equal(file, wantFile) {
+			t.Errorf("version %d: Parquet file differs from current version", version)
+		}
+	}
+}
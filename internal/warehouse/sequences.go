@@ -0,0 +1,145 @@
+package warehouse
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Parquet footer key/value metadata recording which JetStream messages a
+// file holds. Compaction carries them over to compacted files, so
+// partition integrity can be verified against the stream at any time.
+const (
+	// MetadataStream is the name of the stream the rows were consumed from.
+	MetadataStream = "causality.stream"
+
+	// MetadataStreamSequences lists the stream sequences of the rows as
+	// comma-separated ranges (see SequenceRanges.String).
+	MetadataStreamSequences = "causality.stream_sequences"
+
+	// MetadataDeduplicatedRows is the number of rows compaction dropped as
+	// duplicates of an event id, whose sequences remain listed.
+	MetadataDeduplicatedRows = "causality.deduplicated_rows"
+)
+
+// SequenceRange is an inclusive range of stream sequences.
+type SequenceRange struct {
+	First uint64 `json:"first"`
+	Last  uint64 `json:"last"`
+}
+
+// Count returns the number of sequences in the range.
+func (r SequenceRange) Count() uint64 {
+	return r.Last - r.First + 1
+}
+
+// SequenceRanges is a sorted list of disjoint, non-adjacent sequence ranges.
+type SequenceRanges []SequenceRange
+
+// NewSequenceRanges returns the ranges covering seqs, which may be unsorted
+// and contain duplicates.
+func NewSequenceRanges(seqs []uint64) SequenceRanges {
+	sorted := slices.Clone(seqs)
+	slices.Sort(sorted)
+
+	var ranges SequenceRanges
+	for _, seq := range sorted {
+		if n := len(ranges); n > 0 && seq <= ranges[n-1].Last+1 {
+			ranges[n-1].Last = max(ranges[n-1].Last, seq)
+			continue
+		}
+		ranges = append(ranges, SequenceRange{First: seq, Last: seq})
+	}
+	return ranges
+}
+
+// UnionSequenceRanges returns the ranges covering every sequence of sets.
+func UnionSequenceRanges(sets ...SequenceRanges) SequenceRanges {
+	var all []SequenceRange
+	for _, set := range sets {
+		all = append(all, set...)
+	}
+	slices.SortFunc(all, func(a, b SequenceRange) int { return cmp.Compare(a.First, b.First) })
+
+	var ranges SequenceRanges
+	for _, r := range all {
+		if n := len(ranges); n > 0 && r.First <= ranges[n-1].Last+1 {
+			ranges[n-1].Last = max(ranges[n-1].Last, r.Last)
+			continue
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// Count returns the number of sequences covered.
+func (s SequenceRanges) Count() uint64 {
+	var n uint64
+	for _, r := range s {
+		n += r.Count()
+	}
+	return n
+}
+
+// String formats the ranges as "first-last" or "seq" items separated by
+// commas, e.g. "1-40,42,45-60".
+func (s SequenceRanges) String() string {
+	var b strings.Builder
+	for i, r := range s {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatUint(r.First, 10))
+		if r.Last != r.First {
+			b.WriteByte('-')
+			b.WriteString(strconv.FormatUint(r.Last, 10))
+		}
+	}
+	return b.String()
+}
+
+// ParseSequenceRanges parses ranges formatted by SequenceRanges.String.
+func ParseSequenceRanges(s string) (SequenceRanges, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var ranges SequenceRanges
+	for _, item := range strings.Split(s, ",") {
+		first, last, isRange := strings.Cut(item, "-")
+		r := SequenceRange{}
+		var err error
+		if r.First, err = strconv.ParseUint(first, 10, 64); err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSequenceRanges, item)
+		}
+		r.Last = r.First
+		if isRange {
+			if r.Last, err = strconv.ParseUint(last, 10, 64); err != nil || r.Last < r.First {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidSequenceRanges, item)
+			}
+		}
+		if n := len(ranges); n > 0 && r.First <= ranges[n-1].Last {
+			return nil, fmt.Errorf("%w: %q is not after the previous range", ErrInvalidSequenceRanges, item)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// SequenceMetadata returns the footer metadata options recording that a
+// file holds the given sequences of stream, of which deduplicated rows were
+// dropped as duplicates.
+func SequenceMetadata(stream string, ranges SequenceRanges, deduplicated int64) []parquet.WriterOption {
+	options := []parquet.WriterOption{
+		parquet.KeyValueMetadata(MetadataStream, stream),
+		parquet.KeyValueMetadata(MetadataStreamSequences, ranges.String()),
+	}
+	if deduplicated > 0 {
+		options = append(options, parquet.KeyValueMetadata(MetadataDeduplicatedRows, strconv.FormatInt(deduplicated, 10)))
+	}
+	return options
+}
@@ -417,6 +417,16 @@ type EventEnvelope struct {
 	DeviceContext *DeviceContext `protobuf:"bytes,6,opt,name=device_context,json=deviceContext,proto3" json:"device_context,omitempty"`
 	// SDK-generated idempotency key (UUID). Used for server-side deduplication.
 	IdempotencyKey string `protobuf:"bytes,7,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	// Envelope schema version the event was produced with. Clients set it to
+	// the version they were built against; 0 means a client predating schema
+	// versioning and is stamped as version 1 by the gateway. Consumers upgrade
+	// older envelopes to the current version before processing.
+	SchemaVersion uint32 `protobuf:"varint,8,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+	// Fields the gateway received but this schema does not declare, keyed by
+	// their JSON path within the envelope (e.g. "button_tap.button_name")
+	// with their raw JSON value. Clients newer than the gateway may send
+	// fields it predates; they are kept here instead of being dropped.
+	Extras map[string]string `protobuf:"bytes,9,rep,name=extras,proto3" json:"extras,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	// Type-safe event payload using oneof
 	//
 	// Types that are valid to be assigned to Payload:
@@ -449,6 +459,8 @@ type EventEnvelope struct {
 	//	*EventEnvelope_PermissionResult
 	//	*EventEnvelope_MemoryWarning
 	//	*EventEnvelope_BatteryChange
+	//	*EventEnvelope_PushTokenUpdate
+	//	*EventEnvelope_ExperimentExposure
 	//	*EventEnvelope_CustomEvent
 	Payload       isEventEnvelope_Payload `protobuf_oneof:"payload"`
 	unknownFields protoimpl.UnknownFields
@@ -534,6 +546,20 @@ func (x *EventEnvelope) GetIdempotencyKey() string {
 	return ""
 }
 
+func (x *EventEnvelope) GetSchemaVersion() uint32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+func (x *EventEnvelope) GetExtras() map[string]string {
+	if x != nil {
+		return x.Extras
+	}
+	return nil
+}
+
 func (x *EventEnvelope) GetPayload() isEventEnvelope_Payload {
 	if x != nil {
 		return x.Payload
@@ -793,6 +819,24 @@ func (x *EventEnvelope) GetBatteryChange() *BatteryChange {
 	return nil
 }
 
+func (x *EventEnvelope) GetPushTokenUpdate() *PushTokenUpdate {
+	if x != nil {
+		if x, ok := x.Payload.(*EventEnvelope_PushTokenUpdate); ok {
+			return x.PushTokenUpdate
+		}
+	}
+	return nil
+}
+
+func (x *EventEnvelope) GetExperimentExposure() *ExperimentExposure {
+	if x != nil {
+		if x, ok := x.Payload.(*EventEnvelope_ExperimentExposure); ok {
+			return x.ExperimentExposure
+		}
+	}
+	return nil
+}
+
 func (x *EventEnvelope) GetCustomEvent() *CustomEvent {
 	if x != nil {
 		if x, ok := x.Payload.(*EventEnvelope_CustomEvent); ok {
@@ -923,6 +967,15 @@ type EventEnvelope_BatteryChange struct {
 	BatteryChange *BatteryChange `protobuf:"bytes,408,opt,name=battery_change,json=batteryChange,proto3,oneof"`
 }
 
+type EventEnvelope_PushTokenUpdate struct {
+	PushTokenUpdate *PushTokenUpdate `protobuf:"bytes,409,opt,name=push_token_update,json=pushTokenUpdate,proto3,oneof"`
+}
+
+type EventEnvelope_ExperimentExposure struct {
+	// Experiment events (500-599)
+	ExperimentExposure *ExperimentExposure `protobuf:"bytes,500,opt,name=experiment_exposure,json=experimentExposure,proto3,oneof"`
+}
+
 type EventEnvelope_CustomEvent struct {
 	// Custom events (900-999)
 	CustomEvent *CustomEvent `protobuf:"bytes,900,opt,name=custom_event,json=customEvent,proto3,oneof"`
@@ -984,6 +1037,10 @@ func (*EventEnvelope_MemoryWarning) isEventEnvelope_Payload() {}
 
 func (*EventEnvelope_BatteryChange) isEventEnvelope_Payload() {}
 
+func (*EventEnvelope_PushTokenUpdate) isEventEnvelope_Payload() {}
+
+func (*EventEnvelope_ExperimentExposure) isEventEnvelope_Payload() {}
+
 func (*EventEnvelope_CustomEvent) isEventEnvelope_Payload() {}
 
 // DeviceContext contains information about the device and app.
@@ -1018,7 +1075,14 @@ type DeviceContext struct {
 	// Whether device is an emulator (security signal)
 	IsEmulator bool `protobuf:"varint,14,opt,name=is_emulator,json=isEmulator,proto3" json:"is_emulator,omitempty"`
 	// SDK version used
-	SdkVersion    string `protobuf:"bytes,15,opt,name=sdk_version,json=sdkVersion,proto3" json:"sdk_version,omitempty"`
+	SdkVersion string `protobuf:"bytes,15,opt,name=sdk_version,json=sdkVersion,proto3" json:"sdk_version,omitempty"`
+	// Measured offset of the device clock from server time in milliseconds
+	// (device minus server), estimated by the SDK from gateway responses.
+	// Event timestamps are already corrected by it; 0 when not measured.
+	ClockSkewMs int64 `protobuf:"varint,16,opt,name=clock_skew_ms,json=clockSkewMs,proto3" json:"clock_skew_ms,omitempty"`
+	// Name of the SDK, from the X-Causality-SDK header of the request that
+	// carried the event (e.g., "causality-mobile")
+	SdkName       string `protobuf:"bytes,17,opt,name=sdk_name,json=sdkName,proto3" json:"sdk_name,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -1158,6 +1222,20 @@ func (x *DeviceContext) GetSdkVersion() string {
 	return ""
 }
 
+func (x *DeviceContext) GetClockSkewMs() int64 {
+	if x != nil {
+		return x.ClockSkewMs
+	}
+	return 0
+}
+
+func (x *DeviceContext) GetSdkName() string {
+	if x != nil {
+		return x.SdkName
+	}
+	return ""
+}
+
 type UserLogin struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
@@ -2764,6 +2842,8 @@ type AppCrash struct {
 	CrashMessage  string                 `protobuf:"bytes,2,opt,name=crash_message,json=crashMessage,proto3" json:"crash_message,omitempty"`
 	StackTrace    string                 `protobuf:"bytes,3,opt,name=stack_trace,json=stackTrace,proto3" json:"stack_trace,omitempty"`
 	CurrentScreen string                 `protobuf:"bytes,4,opt,name=current_screen,json=currentScreen,proto3" json:"current_screen,omitempty"`
+	// Events tracked before the crash, oldest first, to aid triage
+	Breadcrumbs   []*Breadcrumb `protobuf:"bytes,5,rep,name=breadcrumbs,proto3" json:"breadcrumbs,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -2826,6 +2906,67 @@ func (x *AppCrash) GetCurrentScreen() string {
 	return ""
 }
 
+func (x *AppCrash) GetBreadcrumbs() []*Breadcrumb {
+	if x != nil {
+		return x.Breadcrumbs
+	}
+	return nil
+}
+
+// Breadcrumb is a tracked event recorded for crash triage: its type and
+// time only, never its properties.
+type Breadcrumb struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EventType     string                 `protobuf:"bytes,1,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
+	TimestampMs   int64                  `protobuf:"varint,2,opt,name=timestamp_ms,json=timestampMs,proto3" json:"timestamp_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Breadcrumb) Reset() {
+	*x = Breadcrumb{}
+	mi := &file_causality_v1_events_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Breadcrumb) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Breadcrumb) ProtoMessage() {}
+
+func (x *Breadcrumb) ProtoReflect() protoreflect.Message {
+	mi := &file_causality_v1_events_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Breadcrumb.ProtoReflect.Descriptor instead.
+func (*Breadcrumb) Descriptor() ([]byte, []int) {
+	return file_causality_v1_events_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *Breadcrumb) GetEventType() string {
+	if x != nil {
+		return x.EventType
+	}
+	return ""
+}
+
+func (x *Breadcrumb) GetTimestampMs() int64 {
+	if x != nil {
+		return x.TimestampMs
+	}
+	return 0
+}
+
 type NetworkChange struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	PreviousType  NetworkType            `protobuf:"varint,1,opt,name=previous_type,json=previousType,proto3,enum=causality.v1.NetworkType" json:"previous_type,omitempty"`
@@ -2836,7 +2977,7 @@ type NetworkChange struct {
 
 func (x *NetworkChange) Reset() {
 	*x = NetworkChange{}
-	mi := &file_causality_v1_events_proto_msgTypes[27]
+	mi := &file_causality_v1_events_proto_msgTypes[28]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2848,7 +2989,7 @@ func (x *NetworkChange) String() string {
 func (*NetworkChange) ProtoMessage() {}
 
 func (x *NetworkChange) ProtoReflect() protoreflect.Message {
-	mi := &file_causality_v1_events_proto_msgTypes[27]
+	mi := &file_causality_v1_events_proto_msgTypes[28]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2861,7 +3002,7 @@ func (x *NetworkChange) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NetworkChange.ProtoReflect.Descriptor instead.
 func (*NetworkChange) Descriptor() ([]byte, []int) {
-	return file_causality_v1_events_proto_rawDescGZIP(), []int{27}
+	return file_causality_v1_events_proto_rawDescGZIP(), []int{28}
 }
 
 func (x *NetworkChange) GetPreviousType() NetworkType {
@@ -2888,7 +3029,7 @@ type PermissionRequest struct {
 
 func (x *PermissionRequest) Reset() {
 	*x = PermissionRequest{}
-	mi := &file_causality_v1_events_proto_msgTypes[28]
+	mi := &file_causality_v1_events_proto_msgTypes[29]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2900,7 +3041,7 @@ func (x *PermissionRequest) String() string {
 func (*PermissionRequest) ProtoMessage() {}
 
 func (x *PermissionRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_causality_v1_events_proto_msgTypes[28]
+	mi := &file_causality_v1_events_proto_msgTypes[29]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2913,7 +3054,7 @@ func (x *PermissionRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PermissionRequest.ProtoReflect.Descriptor instead.
 func (*PermissionRequest) Descriptor() ([]byte, []int) {
-	return file_causality_v1_events_proto_rawDescGZIP(), []int{28}
+	return file_causality_v1_events_proto_rawDescGZIP(), []int{29}
 }
 
 func (x *PermissionRequest) GetPermissionType() string {
@@ -2940,7 +3081,7 @@ type PermissionResult struct {
 
 func (x *PermissionResult) Reset() {
 	*x = PermissionResult{}
-	mi := &file_causality_v1_events_proto_msgTypes[29]
+	mi := &file_causality_v1_events_proto_msgTypes[30]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2952,7 +3093,7 @@ func (x *PermissionResult) String() string {
 func (*PermissionResult) ProtoMessage() {}
 
 func (x *PermissionResult) ProtoReflect() protoreflect.Message {
-	mi := &file_causality_v1_events_proto_msgTypes[29]
+	mi := &file_causality_v1_events_proto_msgTypes[30]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2965,7 +3106,7 @@ func (x *PermissionResult) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PermissionResult.ProtoReflect.Descriptor instead.
 func (*PermissionResult) Descriptor() ([]byte, []int) {
-	return file_causality_v1_events_proto_rawDescGZIP(), []int{29}
+	return file_causality_v1_events_proto_rawDescGZIP(), []int{30}
 }
 
 func (x *PermissionResult) GetPermissionType() string {
@@ -2993,7 +3134,7 @@ type MemoryWarning struct {
 
 func (x *MemoryWarning) Reset() {
 	*x = MemoryWarning{}
-	mi := &file_causality_v1_events_proto_msgTypes[30]
+	mi := &file_causality_v1_events_proto_msgTypes[31]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3005,7 +3146,7 @@ func (x *MemoryWarning) String() string {
 func (*MemoryWarning) ProtoMessage() {}
 
 func (x *MemoryWarning) ProtoReflect() protoreflect.Message {
-	mi := &file_causality_v1_events_proto_msgTypes[30]
+	mi := &file_causality_v1_events_proto_msgTypes[31]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3018,7 +3159,7 @@ func (x *MemoryWarning) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MemoryWarning.ProtoReflect.Descriptor instead.
 func (*MemoryWarning) Descriptor() ([]byte, []int) {
-	return file_causality_v1_events_proto_rawDescGZIP(), []int{30}
+	return file_causality_v1_events_proto_rawDescGZIP(), []int{31}
 }
 
 func (x *MemoryWarning) GetAvailableMemoryBytes() int64 {
@@ -3052,7 +3193,7 @@ type BatteryChange struct {
 
 func (x *BatteryChange) Reset() {
 	*x = BatteryChange{}
-	mi := &file_causality_v1_events_proto_msgTypes[31]
+	mi := &file_causality_v1_events_proto_msgTypes[32]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3064,7 +3205,7 @@ func (x *BatteryChange) String() string {
 func (*BatteryChange) ProtoMessage() {}
 
 func (x *BatteryChange) ProtoReflect() protoreflect.Message {
-	mi := &file_causality_v1_events_proto_msgTypes[31]
+	mi := &file_causality_v1_events_proto_msgTypes[32]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3077,7 +3218,7 @@ func (x *BatteryChange) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BatteryChange.ProtoReflect.Descriptor instead.
 func (*BatteryChange) Descriptor() ([]byte, []int) {
-	return file_causality_v1_events_proto_rawDescGZIP(), []int{31}
+	return file_causality_v1_events_proto_rawDescGZIP(), []int{32}
 }
 
 func (x *BatteryChange) GetBatteryLevel() int32 {
@@ -3094,6 +3235,119 @@ func (x *BatteryChange) GetState() BatteryState {
 	return BatteryState_BATTERY_STATE_UNSPECIFIED
 }
 
+// PushTokenUpdate reports the device's push notification token. Sent when the
+// app registers for remote notifications and whenever the token rotates.
+type PushTokenUpdate struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Token issued by the provider; empty when notifications were disabled.
+	Token string `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	// Provider that issued the token: "fcm" or "apns".
+	Provider      string `protobuf:"bytes,2,opt,name=provider,proto3" json:"provider,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PushTokenUpdate) Reset() {
+	*x = PushTokenUpdate{}
+	mi := &file_causality_v1_events_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PushTokenUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PushTokenUpdate) ProtoMessage() {}
+
+func (x *PushTokenUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_causality_v1_events_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PushTokenUpdate.ProtoReflect.Descriptor instead.
+func (*PushTokenUpdate) Descriptor() ([]byte, []int) {
+	return file_causality_v1_events_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *PushTokenUpdate) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *PushTokenUpdate) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+// ExperimentExposure records that a device was shown a variant of an
+// experiment or feature flag. Joined with outcome events on device_id, it
+// attributes outcomes to variants.
+type ExperimentExposure struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Experiment or feature flag key
+	ExperimentId string `protobuf:"bytes,1,opt,name=experiment_id,json=experimentId,proto3" json:"experiment_id,omitempty"`
+	// Variant the device saw, e.g. as assigned by /v1/experiments/assign
+	Variant       string `protobuf:"bytes,2,opt,name=variant,proto3" json:"variant,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExperimentExposure) Reset() {
+	*x = ExperimentExposure{}
+	mi := &file_causality_v1_events_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExperimentExposure) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExperimentExposure) ProtoMessage() {}
+
+func (x *ExperimentExposure) ProtoReflect() protoreflect.Message {
+	mi := &file_causality_v1_events_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExperimentExposure.ProtoReflect.Descriptor instead.
+func (*ExperimentExposure) Descriptor() ([]byte, []int) {
+	return file_causality_v1_events_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *ExperimentExposure) GetExperimentId() string {
+	if x != nil {
+		return x.ExperimentId
+	}
+	return ""
+}
+
+func (x *ExperimentExposure) GetVariant() string {
+	if x != nil {
+		return x.Variant
+	}
+	return ""
+}
+
 type CustomEvent struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// Custom event name
@@ -3109,7 +3363,7 @@ type CustomEvent struct {
 
 func (x *CustomEvent) Reset() {
 	*x = CustomEvent{}
-	mi := &file_causality_v1_events_proto_msgTypes[32]
+	mi := &file_causality_v1_events_proto_msgTypes[35]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3121,7 +3375,7 @@ func (x *CustomEvent) String() string {
 func (*CustomEvent) ProtoMessage() {}
 
 func (x *CustomEvent) ProtoReflect() protoreflect.Message {
-	mi := &file_causality_v1_events_proto_msgTypes[32]
+	mi := &file_causality_v1_events_proto_msgTypes[35]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3134,7 +3388,7 @@ func (x *CustomEvent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CustomEvent.ProtoReflect.Descriptor instead.
 func (*CustomEvent) Descriptor() ([]byte, []int) {
-	return file_causality_v1_events_proto_rawDescGZIP(), []int{32}
+	return file_causality_v1_events_proto_rawDescGZIP(), []int{35}
 }
 
 func (x *CustomEvent) GetEventName() string {
@@ -3176,7 +3430,7 @@ var File_causality_v1_events_proto protoreflect.FileDescriptor
 
 const file_causality_v1_events_proto_rawDesc = "" +
 	"\n" +
-	"\x19causality/v1/events.proto\x12\fcausality.v1\x1a\x1bbuf/validate/validate.proto\"\xbd\x11\n" +
+	"\x19causality/v1/events.proto\x12\fcausality.v1\x1a\x1bbuf/validate/validate.proto\"\x84\x14\n" +
 	"\rEventEnvelope\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1e\n" +
 	"\x06app_id\x18\x02 \x01(\tB\a\xbaH\x04r\x02\x10\x01R\x05appId\x12$\n" +
@@ -3184,7 +3438,9 @@ const file_causality_v1_events_proto_rawDesc = "" +
 	"\ftimestamp_ms\x18\x04 \x01(\x03R\vtimestampMs\x12%\n" +
 	"\x0ecorrelation_id\x18\x05 \x01(\tR\rcorrelationId\x12B\n" +
 	"\x0edevice_context\x18\x06 \x01(\v2\x1b.causality.v1.DeviceContextR\rdeviceContext\x12'\n" +
-	"\x0fidempotency_key\x18\a \x01(\tR\x0eidempotencyKey\x128\n" +
+	"\x0fidempotency_key\x18\a \x01(\tR\x0eidempotencyKey\x12%\n" +
+	"\x0eschema_version\x18\b \x01(\rR\rschemaVersion\x12?\n" +
+	"\x06extras\x18\t \x03(\v2'.causality.v1.EventEnvelope.ExtrasEntryR\x06extras\x128\n" +
 	"\n" +
 	"user_login\x18\n" +
 	" \x01(\v2\x17.causality.v1.UserLoginH\x00R\tuserLogin\x12;\n" +
@@ -3222,9 +3478,14 @@ const file_causality_v1_events_proto_rawDesc = "" +
 	"\x12permission_request\x18\x95\x03 \x01(\v2\x1f.causality.v1.PermissionRequestH\x00R\x11permissionRequest\x12N\n" +
 	"\x11permission_result\x18\x96\x03 \x01(\v2\x1e.causality.v1.PermissionResultH\x00R\x10permissionResult\x12E\n" +
 	"\x0ememory_warning\x18\x97\x03 \x01(\v2\x1b.causality.v1.MemoryWarningH\x00R\rmemoryWarning\x12E\n" +
-	"\x0ebattery_change\x18\x98\x03 \x01(\v2\x1b.causality.v1.BatteryChangeH\x00R\rbatteryChange\x12?\n" +
-	"\fcustom_event\x18\x84\a \x01(\v2\x19.causality.v1.CustomEventH\x00R\vcustomEventB\t\n" +
-	"\apayload\"\xa8\x04\n" +
+	"\x0ebattery_change\x18\x98\x03 \x01(\v2\x1b.causality.v1.BatteryChangeH\x00R\rbatteryChange\x12L\n" +
+	"\x11push_token_update\x18\x99\x03 \x01(\v2\x1d.causality.v1.PushTokenUpdateH\x00R\x0fpushTokenUpdate\x12T\n" +
+	"\x13experiment_exposure\x18\xf4\x03 \x01(\v2 .causality.v1.ExperimentExposureH\x00R\x12experimentExposure\x12?\n" +
+	"\fcustom_event\x18\x84\a \x01(\v2\x19.causality.v1.CustomEventH\x00R\vcustomEvent\x1a9\n" +
+	"\vExtrasEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\t\n" +
+	"\apayload\"\xe7\x04\n" +
 	"\rDeviceContext\x122\n" +
 	"\bplatform\x18\x01 \x01(\x0e2\x16.causality.v1.PlatformR\bplatform\x12\x1d\n" +
 	"\n" +
@@ -3245,7 +3506,9 @@ const file_causality_v1_events_proto_rawDesc = "" +
 	"\vis_emulator\x18\x0e \x01(\bR\n" +
 	"isEmulator\x12\x1f\n" +
 	"\vsdk_version\x18\x0f \x01(\tR\n" +
-	"sdkVersion\"\\\n" +
+	"sdkVersion\x12\"\n" +
+	"\rclock_skew_ms\x18\x10 \x01(\x03R\vclockSkewMs\x12\x19\n" +
+	"\bsdk_name\x18\x11 \x01(\tR\asdkName\"\\\n" +
 	"\tUserLogin\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x16\n" +
 	"\x06method\x18\x02 \x01(\tR\x06method\x12\x1e\n" +
@@ -3398,14 +3661,20 @@ const file_causality_v1_events_proto_rawDesc = "" +
 	"\x0ecurrent_screen\x18\x02 \x01(\tR\rcurrentScreen\"j\n" +
 	"\rAppForeground\x124\n" +
 	"\x16background_duration_ms\x18\x01 \x01(\x03R\x14backgroundDurationMs\x12#\n" +
-	"\rresume_screen\x18\x02 \x01(\tR\fresumeScreen\"\x96\x01\n" +
+	"\rresume_screen\x18\x02 \x01(\tR\fresumeScreen\"\xd2\x01\n" +
 	"\bAppCrash\x12\x1d\n" +
 	"\n" +
 	"crash_type\x18\x01 \x01(\tR\tcrashType\x12#\n" +
 	"\rcrash_message\x18\x02 \x01(\tR\fcrashMessage\x12\x1f\n" +
 	"\vstack_trace\x18\x03 \x01(\tR\n" +
 	"stackTrace\x12%\n" +
-	"\x0ecurrent_screen\x18\x04 \x01(\tR\rcurrentScreen\"\x8d\x01\n" +
+	"\x0ecurrent_screen\x18\x04 \x01(\tR\rcurrentScreen\x12:\n" +
+	"\vbreadcrumbs\x18\x05 \x03(\v2\x18.causality.v1.BreadcrumbR\vbreadcrumbs\"N\n" +
+	"\n" +
+	"Breadcrumb\x12\x1d\n" +
+	"\n" +
+	"event_type\x18\x01 \x01(\tR\teventType\x12!\n" +
+	"\ftimestamp_ms\x18\x02 \x01(\x03R\vtimestampMs\"\x8d\x01\n" +
 	"\rNetworkChange\x12>\n" +
 	"\rprevious_type\x18\x01 \x01(\x0e2\x19.causality.v1.NetworkTypeR\fpreviousType\x12<\n" +
 	"\fcurrent_type\x18\x02 \x01(\x0e2\x19.causality.v1.NetworkTypeR\vcurrentType\"c\n" +
@@ -3421,7 +3690,13 @@ const file_causality_v1_events_proto_rawDesc = "" +
 	"\x05level\x18\x03 \x01(\x0e2 .causality.v1.MemoryWarningLevelR\x05level\"f\n" +
 	"\rBatteryChange\x12#\n" +
 	"\rbattery_level\x18\x01 \x01(\x05R\fbatteryLevel\x120\n" +
-	"\x05state\x18\x02 \x01(\x0e2\x1a.causality.v1.BatteryStateR\x05state\"\xe9\x04\n" +
+	"\x05state\x18\x02 \x01(\x0e2\x1a.causality.v1.BatteryStateR\x05state\"C\n" +
+	"\x0fPushTokenUpdate\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\x12\x1a\n" +
+	"\bprovider\x18\x02 \x01(\tR\bprovider\"S\n" +
+	"\x12ExperimentExposure\x12#\n" +
+	"\rexperiment_id\x18\x01 \x01(\tR\fexperimentId\x12\x18\n" +
+	"\avariant\x18\x02 \x01(\tR\avariant\"\xe9\x04\n" +
 	"\vCustomEvent\x12&\n" +
 	"\n" +
 	"event_name\x18\x01 \x01(\tB\a\xbaH\x04r\x02\x10\x01R\teventName\x12P\n" +
@@ -3496,110 +3771,118 @@ func file_causality_v1_events_proto_rawDescGZIP() []byte {
 }
 
 var file_causality_v1_events_proto_enumTypes = make([]protoimpl.EnumInfo, 7)
-var file_causality_v1_events_proto_msgTypes = make([]protoimpl.MessageInfo, 38)
+var file_causality_v1_events_proto_msgTypes = make([]protoimpl.MessageInfo, 42)
 var file_causality_v1_events_proto_goTypes = []any{
-	(Platform)(0),             // 0: causality.v1.Platform
-	(NetworkType)(0),          // 1: causality.v1.NetworkType
-	(SwipeDirection)(0),       // 2: causality.v1.SwipeDirection
-	(ScrollDirection)(0),      // 3: causality.v1.ScrollDirection
-	(PermissionStatus)(0),     // 4: causality.v1.PermissionStatus
-	(MemoryWarningLevel)(0),   // 5: causality.v1.MemoryWarningLevel
-	(BatteryState)(0),         // 6: causality.v1.BatteryState
-	(*EventEnvelope)(nil),     // 7: causality.v1.EventEnvelope
-	(*DeviceContext)(nil),     // 8: causality.v1.DeviceContext
-	(*UserLogin)(nil),         // 9: causality.v1.UserLogin
-	(*UserLogout)(nil),        // 10: causality.v1.UserLogout
-	(*UserSignup)(nil),        // 11: causality.v1.UserSignup
-	(*UserProfileUpdate)(nil), // 12: causality.v1.UserProfileUpdate
-	(*ScreenView)(nil),        // 13: causality.v1.ScreenView
-	(*ScreenExit)(nil),        // 14: causality.v1.ScreenExit
-	(*ButtonTap)(nil),         // 15: causality.v1.ButtonTap
-	(*SwipeGesture)(nil),      // 16: causality.v1.SwipeGesture
-	(*ScrollEvent)(nil),       // 17: causality.v1.ScrollEvent
-	(*TextInput)(nil),         // 18: causality.v1.TextInput
-	(*LongPress)(nil),         // 19: causality.v1.LongPress
-	(*DoubleTap)(nil),         // 20: causality.v1.DoubleTap
-	(*Coordinates)(nil),       // 21: causality.v1.Coordinates
-	(*ProductView)(nil),       // 22: causality.v1.ProductView
-	(*AddToCart)(nil),         // 23: causality.v1.AddToCart
-	(*RemoveFromCart)(nil),    // 24: causality.v1.RemoveFromCart
-	(*CheckoutStart)(nil),     // 25: causality.v1.CheckoutStart
-	(*CheckoutStep)(nil),      // 26: causality.v1.CheckoutStep
-	(*PurchaseComplete)(nil),  // 27: causality.v1.PurchaseComplete
-	(*PurchaseFailed)(nil),    // 28: causality.v1.PurchaseFailed
-	(*PurchaseItem)(nil),      // 29: causality.v1.PurchaseItem
-	(*AppStart)(nil),          // 30: causality.v1.AppStart
-	(*AppBackground)(nil),     // 31: causality.v1.AppBackground
-	(*AppForeground)(nil),     // 32: causality.v1.AppForeground
-	(*AppCrash)(nil),          // 33: causality.v1.AppCrash
-	(*NetworkChange)(nil),     // 34: causality.v1.NetworkChange
-	(*PermissionRequest)(nil), // 35: causality.v1.PermissionRequest
-	(*PermissionResult)(nil),  // 36: causality.v1.PermissionResult
-	(*MemoryWarning)(nil),     // 37: causality.v1.MemoryWarning
-	(*BatteryChange)(nil),     // 38: causality.v1.BatteryChange
-	(*CustomEvent)(nil),       // 39: causality.v1.CustomEvent
-	nil,                       // 40: causality.v1.ScreenView.ParamsEntry
-	nil,                       // 41: causality.v1.CustomEvent.StringParamsEntry
-	nil,                       // 42: causality.v1.CustomEvent.IntParamsEntry
-	nil,                       // 43: causality.v1.CustomEvent.FloatParamsEntry
-	nil,                       // 44: causality.v1.CustomEvent.BoolParamsEntry
+	(Platform)(0),              // 0: causality.v1.Platform
+	(NetworkType)(0),           // 1: causality.v1.NetworkType
+	(SwipeDirection)(0),        // 2: causality.v1.SwipeDirection
+	(ScrollDirection)(0),       // 3: causality.v1.ScrollDirection
+	(PermissionStatus)(0),      // 4: causality.v1.PermissionStatus
+	(MemoryWarningLevel)(0),    // 5: causality.v1.MemoryWarningLevel
+	(BatteryState)(0),          // 6: causality.v1.BatteryState
+	(*EventEnvelope)(nil),      // 7: causality.v1.EventEnvelope
+	(*DeviceContext)(nil),      // 8: causality.v1.DeviceContext
+	(*UserLogin)(nil),          // 9: causality.v1.UserLogin
+	(*UserLogout)(nil),         // 10: causality.v1.UserLogout
+	(*UserSignup)(nil),         // 11: causality.v1.UserSignup
+	(*UserProfileUpdate)(nil),  // 12: causality.v1.UserProfileUpdate
+	(*ScreenView)(nil),         // 13: causality.v1.ScreenView
+	(*ScreenExit)(nil),         // 14: causality.v1.ScreenExit
+	(*ButtonTap)(nil),          // 15: causality.v1.ButtonTap
+	(*SwipeGesture)(nil),       // 16: causality.v1.SwipeGesture
+	(*ScrollEvent)(nil),        // 17: causality.v1.ScrollEvent
+	(*TextInput)(nil),          // 18: causality.v1.TextInput
+	(*LongPress)(nil),          // 19: causality.v1.LongPress
+	(*DoubleTap)(nil),          // 20: causality.v1.DoubleTap
+	(*Coordinates)(nil),        // 21: causality.v1.Coordinates
+	(*ProductView)(nil),        // 22: causality.v1.ProductView
+	(*AddToCart)(nil),          // 23: causality.v1.AddToCart
+	(*RemoveFromCart)(nil),     // 24: causality.v1.RemoveFromCart
+	(*CheckoutStart)(nil),      // 25: causality.v1.CheckoutStart
+	(*CheckoutStep)(nil),       // 26: causality.v1.CheckoutStep
+	(*PurchaseComplete)(nil),   // 27: causality.v1.PurchaseComplete
+	(*PurchaseFailed)(nil),     // 28: causality.v1.PurchaseFailed
+	(*PurchaseItem)(nil),       // 29: causality.v1.PurchaseItem
+	(*AppStart)(nil),           // 30: causality.v1.AppStart
+	(*AppBackground)(nil),      // 31: causality.v1.AppBackground
+	(*AppForeground)(nil),      // 32: causality.v1.AppForeground
+	(*AppCrash)(nil),           // 33: causality.v1.AppCrash
+	(*Breadcrumb)(nil),         // 34: causality.v1.Breadcrumb
+	(*NetworkChange)(nil),      // 35: causality.v1.NetworkChange
+	(*PermissionRequest)(nil),  // 36: causality.v1.PermissionRequest
+	(*PermissionResult)(nil),   // 37: causality.v1.PermissionResult
+	(*MemoryWarning)(nil),      // 38: causality.v1.MemoryWarning
+	(*BatteryChange)(nil),      // 39: causality.v1.BatteryChange
+	(*PushTokenUpdate)(nil),    // 40: causality.v1.PushTokenUpdate
+	(*ExperimentExposure)(nil), // 41: causality.v1.ExperimentExposure
+	(*CustomEvent)(nil),        // 42: causality.v1.CustomEvent
+	nil,                        // 43: causality.v1.EventEnvelope.ExtrasEntry
+	nil,                        // 44: causality.v1.ScreenView.ParamsEntry
+	nil,                        // 45: causality.v1.CustomEvent.StringParamsEntry
+	nil,                        // 46: causality.v1.CustomEvent.IntParamsEntry
+	nil,                        // 47: causality.v1.CustomEvent.FloatParamsEntry
+	nil,                        // 48: causality.v1.CustomEvent.BoolParamsEntry
 }
 var file_causality_v1_events_proto_depIdxs = []int32{
 	8,  // 0: causality.v1.EventEnvelope.device_context:type_name -> causality.v1.DeviceContext
-	9,  // 1: causality.v1.EventEnvelope.user_login:type_name -> causality.v1.UserLogin
-	10, // 2: causality.v1.EventEnvelope.user_logout:type_name -> causality.v1.UserLogout
-	11, // 3: causality.v1.EventEnvelope.user_signup:type_name -> causality.v1.UserSignup
-	12, // 4: causality.v1.EventEnvelope.user_profile_update:type_name -> causality.v1.UserProfileUpdate
-	13, // 5: causality.v1.EventEnvelope.screen_view:type_name -> causality.v1.ScreenView
-	14, // 6: causality.v1.EventEnvelope.screen_exit:type_name -> causality.v1.ScreenExit
-	15, // 7: causality.v1.EventEnvelope.button_tap:type_name -> causality.v1.ButtonTap
-	16, // 8: causality.v1.EventEnvelope.swipe_gesture:type_name -> causality.v1.SwipeGesture
-	17, // 9: causality.v1.EventEnvelope.scroll_event:type_name -> causality.v1.ScrollEvent
-	18, // 10: causality.v1.EventEnvelope.text_input:type_name -> causality.v1.TextInput
-	19, // 11: causality.v1.EventEnvelope.long_press:type_name -> causality.v1.LongPress
-	20, // 12: causality.v1.EventEnvelope.double_tap:type_name -> causality.v1.DoubleTap
-	22, // 13: causality.v1.EventEnvelope.product_view:type_name -> causality.v1.ProductView
-	23, // 14: causality.v1.EventEnvelope.add_to_cart:type_name -> causality.v1.AddToCart
-	24, // 15: causality.v1.EventEnvelope.remove_from_cart:type_name -> causality.v1.RemoveFromCart
-	25, // 16: causality.v1.EventEnvelope.checkout_start:type_name -> causality.v1.CheckoutStart
-	26, // 17: causality.v1.EventEnvelope.checkout_step:type_name -> causality.v1.CheckoutStep
-	27, // 18: causality.v1.EventEnvelope.purchase_complete:type_name -> causality.v1.PurchaseComplete
-	28, // 19: causality.v1.EventEnvelope.purchase_failed:type_name -> causality.v1.PurchaseFailed
-	30, // 20: causality.v1.EventEnvelope.app_start:type_name -> causality.v1.AppStart
-	31, // 21: causality.v1.EventEnvelope.app_background:type_name -> causality.v1.AppBackground
-	32, // 22: causality.v1.EventEnvelope.app_foreground:type_name -> causality.v1.AppForeground
-	33, // 23: causality.v1.EventEnvelope.app_crash:type_name -> causality.v1.AppCrash
-	34, // 24: causality.v1.EventEnvelope.network_change:type_name -> causality.v1.NetworkChange
-	35, // 25: causality.v1.EventEnvelope.permission_request:type_name -> causality.v1.PermissionRequest
-	36, // 26: causality.v1.EventEnvelope.permission_result:type_name -> causality.v1.PermissionResult
-	37, // 27: causality.v1.EventEnvelope.memory_warning:type_name -> causality.v1.MemoryWarning
-	38, // 28: causality.v1.EventEnvelope.battery_change:type_name -> causality.v1.BatteryChange
-	39, // 29: causality.v1.EventEnvelope.custom_event:type_name -> causality.v1.CustomEvent
-	0,  // 30: causality.v1.DeviceContext.platform:type_name -> causality.v1.Platform
-	1,  // 31: causality.v1.DeviceContext.network_type:type_name -> causality.v1.NetworkType
-	40, // 32: causality.v1.ScreenView.params:type_name -> causality.v1.ScreenView.ParamsEntry
-	21, // 33: causality.v1.ButtonTap.coordinates:type_name -> causality.v1.Coordinates
-	2,  // 34: causality.v1.SwipeGesture.direction:type_name -> causality.v1.SwipeDirection
-	21, // 35: causality.v1.SwipeGesture.start:type_name -> causality.v1.Coordinates
-	21, // 36: causality.v1.SwipeGesture.end:type_name -> causality.v1.Coordinates
-	3,  // 37: causality.v1.ScrollEvent.direction:type_name -> causality.v1.ScrollDirection
-	21, // 38: causality.v1.LongPress.coordinates:type_name -> causality.v1.Coordinates
-	21, // 39: causality.v1.DoubleTap.coordinates:type_name -> causality.v1.Coordinates
-	29, // 40: causality.v1.PurchaseComplete.items:type_name -> causality.v1.PurchaseItem
-	1,  // 41: causality.v1.NetworkChange.previous_type:type_name -> causality.v1.NetworkType
-	1,  // 42: causality.v1.NetworkChange.current_type:type_name -> causality.v1.NetworkType
-	4,  // 43: causality.v1.PermissionResult.status:type_name -> causality.v1.PermissionStatus
-	5,  // 44: causality.v1.MemoryWarning.level:type_name -> causality.v1.MemoryWarningLevel
-	6,  // 45: causality.v1.BatteryChange.state:type_name -> causality.v1.BatteryState
-	41, // 46: causality.v1.CustomEvent.string_params:type_name -> causality.v1.CustomEvent.StringParamsEntry
-	42, // 47: causality.v1.CustomEvent.int_params:type_name -> causality.v1.CustomEvent.IntParamsEntry
-	43, // 48: causality.v1.CustomEvent.float_params:type_name -> causality.v1.CustomEvent.FloatParamsEntry
-	44, // 49: causality.v1.CustomEvent.bool_params:type_name -> causality.v1.CustomEvent.BoolParamsEntry
-	50, // [50:50] is the sub-list for method output_type
-	50, // [50:50] is the sub-list for method input_type
-	50, // [50:50] is the sub-list for extension type_name
-	50, // [50:50] is the sub-list for extension extendee
-	0,  // [0:50] is the sub-list for field type_name
+	43, // 1: causality.v1.EventEnvelope.extras:type_name -> causality.v1.EventEnvelope.ExtrasEntry
+	9,  // 2: causality.v1.EventEnvelope.user_login:type_name -> causality.v1.UserLogin
+	10, // 3: causality.v1.EventEnvelope.user_logout:type_name -> causality.v1.UserLogout
+	11, // 4: causality.v1.EventEnvelope.user_signup:type_name -> causality.v1.UserSignup
+	12, // 5: causality.v1.EventEnvelope.user_profile_update:type_name -> causality.v1.UserProfileUpdate
+	13, // 6: causality.v1.EventEnvelope.screen_view:type_name -> causality.v1.ScreenView
+	14, // 7: causality.v1.EventEnvelope.screen_exit:type_name -> causality.v1.ScreenExit
+	15, // 8: causality.v1.EventEnvelope.button_tap:type_name -> causality.v1.ButtonTap
+	16, // 9: causality.v1.EventEnvelope.swipe_gesture:type_name -> causality.v1.SwipeGesture
+	17, // 10: causality.v1.EventEnvelope.scroll_event:type_name -> causality.v1.ScrollEvent
+	18, // 11: causality.v1.EventEnvelope.text_input:type_name -> causality.v1.TextInput
+	19, // 12: causality.v1.EventEnvelope.long_press:type_name -> causality.v1.LongPress
+	20, // 13: causality.v1.EventEnvelope.double_tap:type_name -> causality.v1.DoubleTap
+	22, // 14: causality.v1.EventEnvelope.product_view:type_name -> causality.v1.ProductView
+	23, // 15: causality.v1.EventEnvelope.add_to_cart:type_name -> causality.v1.AddToCart
+	24, // 16: causality.v1.EventEnvelope.remove_from_cart:type_name -> causality.v1.RemoveFromCart
+	25, // 17: causality.v1.EventEnvelope.checkout_start:type_name -> causality.v1.CheckoutStart
+	26, // 18: causality.v1.EventEnvelope.checkout_step:type_name -> causality.v1.CheckoutStep
+	27, // 19: causality.v1.EventEnvelope.purchase_complete:type_name -> causality.v1.PurchaseComplete
+	28, // 20: causality.v1.EventEnvelope.purchase_failed:type_name -> causality.v1.PurchaseFailed
+	30, // 21: causality.v1.EventEnvelope.app_start:type_name -> causality.v1.AppStart
+	31, // 22: causality.v1.EventEnvelope.app_background:type_name -> causality.v1.AppBackground
+	32, // 23: causality.v1.EventEnvelope.app_foreground:type_name -> causality.v1.AppForeground
+	33, // 24: causality.v1.EventEnvelope.app_crash:type_name -> causality.v1.AppCrash
+	35, // 25: causality.v1.EventEnvelope.network_change:type_name -> causality.v1.NetworkChange
+	36, // 26: causality.v1.EventEnvelope.permission_request:type_name -> causality.v1.PermissionRequest
+	37, // 27: causality.v1.EventEnvelope.permission_result:type_name -> causality.v1.PermissionResult
+	38, // 28: causality.v1.EventEnvelope.memory_warning:type_name -> causality.v1.MemoryWarning
+	39, // 29: causality.v1.EventEnvelope.battery_change:type_name -> causality.v1.BatteryChange
+	40, // 30: causality.v1.EventEnvelope.push_token_update:type_name -> causality.v1.PushTokenUpdate
+	41, // 31: causality.v1.EventEnvelope.experiment_exposure:type_name -> causality.v1.ExperimentExposure
+	42, // 32: causality.v1.EventEnvelope.custom_event:type_name -> causality.v1.CustomEvent
+	0,  // 33: causality.v1.DeviceContext.platform:type_name -> causality.v1.Platform
+	1,  // 34: causality.v1.DeviceContext.network_type:type_name -> causality.v1.NetworkType
+	44, // 35: causality.v1.ScreenView.params:type_name -> causality.v1.ScreenView.ParamsEntry
+	21, // 36: causality.v1.ButtonTap.coordinates:type_name -> causality.v1.Coordinates
+	2,  // 37: causality.v1.SwipeGesture.direction:type_name -> causality.v1.SwipeDirection
+	21, // 38: causality.v1.SwipeGesture.start:type_name -> causality.v1.Coordinates
+	21, // 39: causality.v1.SwipeGesture.end:type_name -> causality.v1.Coordinates
+	3,  // 40: causality.v1.ScrollEvent.direction:type_name -> causality.v1.ScrollDirection
+	21, // 41: causality.v1.LongPress.coordinates:type_name -> causality.v1.Coordinates
+	21, // 42: causality.v1.DoubleTap.coordinates:type_name -> causality.v1.Coordinates
+	29, // 43: causality.v1.PurchaseComplete.items:type_name -> causality.v1.PurchaseItem
+	34, // 44: causality.v1.AppCrash.breadcrumbs:type_name -> causality.v1.Breadcrumb
+	1,  // 45: causality.v1.NetworkChange.previous_type:type_name -> causality.v1.NetworkType
+	1,  // 46: causality.v1.NetworkChange.current_type:type_name -> causality.v1.NetworkType
+	4,  // 47: causality.v1.PermissionResult.status:type_name -> causality.v1.PermissionStatus
+	5,  // 48: causality.v1.MemoryWarning.level:type_name -> causality.v1.MemoryWarningLevel
+	6,  // 49: causality.v1.BatteryChange.state:type_name -> causality.v1.BatteryState
+	45, // 50: causality.v1.CustomEvent.string_params:type_name -> causality.v1.CustomEvent.StringParamsEntry
+	46, // 51: causality.v1.CustomEvent.int_params:type_name -> causality.v1.CustomEvent.IntParamsEntry
+	47, // 52: causality.v1.CustomEvent.float_params:type_name -> causality.v1.CustomEvent.FloatParamsEntry
+	48, // 53: causality.v1.CustomEvent.bool_params:type_name -> causality.v1.CustomEvent.BoolParamsEntry
+	54, // [54:54] is the sub-list for method output_type
+	54, // [54:54] is the sub-list for method input_type
+	54, // [54:54] is the sub-list for extension type_name
+	54, // [54:54] is the sub-list for extension extendee
+	0,  // [0:54] is the sub-list for field type_name
 }
 
 func init() { file_causality_v1_events_proto_init() }
@@ -3636,6 +3919,8 @@ func file_causality_v1_events_proto_init() {
 		(*EventEnvelope_PermissionResult)(nil),
 		(*EventEnvelope_MemoryWarning)(nil),
 		(*EventEnvelope_BatteryChange)(nil),
+		(*EventEnvelope_PushTokenUpdate)(nil),
+		(*EventEnvelope_ExperimentExposure)(nil),
 		(*EventEnvelope_CustomEvent)(nil),
 	}
 	type x struct{}
@@ -3644,7 +3929,7 @@ func file_causality_v1_events_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_causality_v1_events_proto_rawDesc), len(file_causality_v1_events_proto_rawDesc)),
 			NumEnums:      7,
-			NumMessages:   38,
+			NumMessages:   42,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
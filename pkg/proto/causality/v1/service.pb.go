@@ -74,7 +74,8 @@ type IngestEventResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// The assigned event ID (UUID v7)
 	EventId string `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
-	// Status of the ingestion
+	// Status of the ingestion: "accepted", or "deduplicated" if the
+	// idempotency key was already seen (not stored again, no retry needed)
 	Status        string `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -173,7 +174,7 @@ func (x *IngestEventBatchRequest) GetEvents() []*EventEnvelope {
 // IngestEventBatchResponse is the response for batch event ingestion.
 type IngestEventBatchResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
-	// Number of events accepted
+	// Number of events accepted, including deduplicated events
 	AcceptedCount int32 `protobuf:"varint,1,opt,name=accepted_count,json=acceptedCount,proto3" json:"accepted_count,omitempty"`
 	// Number of events rejected due to validation errors
 	RejectedCount int32 `protobuf:"varint,2,opt,name=rejected_count,json=rejectedCount,proto3" json:"rejected_count,omitempty"`
@@ -241,7 +242,10 @@ type EventResult struct {
 	Index int32 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
 	// The assigned event ID (UUID v7) if accepted
 	EventId string `protobuf:"bytes,2,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
-	// Status: "accepted" or "rejected"
+	// Status: "accepted", "deduplicated" (counted in accepted_count but not
+	// stored again), "rejected", or "publish_timeout" (counted in
+	// rejected_count; not published before the gateway's deadline and safe to
+	// retry)
 	Status string `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
 	// Error message if rejected
 	Error         string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
@@ -61,6 +61,13 @@ object Causality : DefaultLifecycleObserver {
         Bridge.initSDK(config)
         initialized = true
 
+        // Report network and battery changes for auto-tracking
+        Platform.startMonitoring(
+            context.applicationContext,
+            network = config.autoTrackNetwork == true,
+            battery = config.autoTrackBattery == true
+        )
+
         // Register lifecycle observer
         ProcessLifecycleOwner.get().lifecycle.addObserver(this)
     }
@@ -111,6 +118,17 @@ object Causality : DefaultLifecycleObserver {
         Bridge.setUser(userId, traits, aliases)
     }
 
+    /**
+     * Register the device's push token so rules can send it notifications.
+     *
+     * @param token FCM registration token, or APNs device token as a hex string
+     * @param provider "fcm" or "apns"
+     */
+    fun setPushToken(token: String, provider: String = "fcm") {
+        if (!initialized) throw CausalityException.NotInitialized()
+        Bridge.setPushToken(token, provider)
+    }
+
     /**
      * Clear user identity (soft reset - keeps device ID).
      */
@@ -137,6 +155,21 @@ object Causality : DefaultLifecycleObserver {
         }
     }
 
+    /**
+     * Send queued events from a background worker without outliving it.
+     *
+     * Call from a WorkManager `CoroutineWorker.doWork()`, passing the time the
+     * work may run. Sending stops before the deadline.
+     *
+     * @return true if events remain queued and the work should be retried
+     */
+    suspend fun flushInBackgroundTask(deadlineMs: Long): Boolean {
+        if (!initialized) return false
+        return withContext(Dispatchers.IO) {
+            Bridge.flushInBackgroundTask(deadlineMs)
+        }
+    }
+
     /**
      * Get the device identifier.
      */
@@ -14,7 +14,22 @@ data class Config(
     @SerialName("session_timeout_ms") val sessionTimeoutMs: Int? = null,
     @SerialName("debug_mode") val debugMode: Boolean? = null,
     @SerialName("enable_session_tracking") val enableSessionTracking: Boolean? = null,
-    @SerialName("persistent_device_id") val persistentDeviceId: Boolean? = null
+    @SerialName("persistent_device_id") val persistentDeviceId: Boolean? = null,
+    @SerialName("disable_compression") val disableCompression: Boolean? = null,
+    @SerialName("certificate_pins") val certificatePins: List<String>? = null,
+    @SerialName("max_retries") val maxRetries: Int? = null,
+    @SerialName("consent_required") val consentRequired: Boolean? = null,
+    @SerialName("auto_track_network") val autoTrackNetwork: Boolean? = null,
+    @SerialName("auto_track_battery") val autoTrackBattery: Boolean? = null,
+    @SerialName("auto_track_debounce_ms") val autoTrackDebounceMs: Int? = null,
+    @SerialName("max_breadcrumbs") val maxBreadcrumbs: Int? = null,
+    @SerialName("disable_breadcrumbs") val disableBreadcrumbs: Boolean? = null,
+    @SerialName("max_events_per_second") val maxEventsPerSecond: Int? = null,
+    @SerialName("max_event_burst") val maxEventBurst: Int? = null,
+    @SerialName("dedup_window_ms") val dedupWindowMs: Int? = null,
+    @SerialName("disable_clock_sync") val disableClockSync: Boolean? = null,
+    @SerialName("fallback_endpoints") val fallbackEndpoints: List<String>? = null,
+    @SerialName("endpoint_probe_interval_ms") val endpointProbeIntervalMs: Int? = null
 )
 
 class ConfigBuilder {
@@ -28,6 +43,21 @@ class ConfigBuilder {
     var debugMode: Boolean? = null
     var enableSessionTracking: Boolean? = null
     var persistentDeviceId: Boolean? = null
+    var disableCompression: Boolean? = null
+    var certificatePins: List<String>? = null
+    var maxRetries: Int? = null
+    var consentRequired: Boolean? = null
+    var autoTrackNetwork: Boolean? = null
+    var autoTrackBattery: Boolean? = null
+    var autoTrackDebounceMs: Int? = null
+    var maxBreadcrumbs: Int? = null
+    var disableBreadcrumbs: Boolean? = null
+    var maxEventsPerSecond: Int? = null
+    var maxEventBurst: Int? = null
+    var dedupWindowMs: Int? = null
+    var disableClockSync: Boolean? = null
+    var fallbackEndpoints: List<String>? = null
+    var endpointProbeIntervalMs: Int? = null
 
     fun build(): Config {
         require(apiKey.isNotBlank()) { "apiKey is required" }
@@ -44,7 +74,22 @@ class ConfigBuilder {
             sessionTimeoutMs = sessionTimeoutMs,
             debugMode = debugMode,
             enableSessionTracking = enableSessionTracking,
-            persistentDeviceId = persistentDeviceId
+            persistentDeviceId = persistentDeviceId,
+            disableCompression = disableCompression,
+            certificatePins = certificatePins,
+            maxRetries = maxRetries,
+            consentRequired = consentRequired,
+            autoTrackNetwork = autoTrackNetwork,
+            autoTrackBattery = autoTrackBattery,
+            autoTrackDebounceMs = autoTrackDebounceMs,
+            maxBreadcrumbs = maxBreadcrumbs,
+            disableBreadcrumbs = disableBreadcrumbs,
+            maxEventsPerSecond = maxEventsPerSecond,
+            maxEventBurst = maxEventBurst,
+            dedupWindowMs = dedupWindowMs,
+            disableClockSync = disableClockSync,
+            fallbackEndpoints = fallbackEndpoints,
+            endpointProbeIntervalMs = endpointProbeIntervalMs
         )
     }
 }
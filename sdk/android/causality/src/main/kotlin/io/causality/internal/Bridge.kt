@@ -44,6 +44,13 @@ internal object Bridge {
         }
     }
 
+    fun setPushToken(token: String, provider: String) {
+        val result = Mobile.setPushToken(token, provider)
+        if (result.isNotEmpty()) {
+            throw CausalityException.Tracking(result)
+        }
+    }
+
     fun reset() {
         val result = Mobile.reset()
         if (result.isNotEmpty()) {
@@ -65,6 +72,8 @@ internal object Bridge {
         }
     }
 
+    fun flushInBackgroundTask(deadlineMs: Long): Boolean = Mobile.flushInBackgroundTask(deadlineMs)
+
     fun getDeviceId(): String = Mobile.getDeviceId()
 
     fun isInitialized(): Boolean = Mobile.isInitialized()
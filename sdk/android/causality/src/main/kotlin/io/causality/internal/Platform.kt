@@ -1,8 +1,17 @@
 package io.causality.internal
 
+import android.content.BroadcastReceiver
 import android.content.Context
+import android.content.Intent
+import android.content.IntentFilter
 import android.content.pm.PackageManager
+import android.net.ConnectivityManager
+import android.net.Network
+import android.net.NetworkCapabilities
+import android.net.NetworkRequest
+import android.os.BatteryManager
 import android.os.Build
+import android.telephony.TelephonyManager
 import android.util.DisplayMetrics
 import android.view.WindowManager
 import mobile.Mobile
@@ -42,4 +51,75 @@ internal object Platform {
             TimeZone.getDefault().id                                // timezone
         )
     }
+
+    private var networkCallback: ConnectivityManager.NetworkCallback? = null
+    private var batteryReceiver: BroadcastReceiver? = null
+
+    /**
+     * Reports network and battery changes to the Go core, which emits
+     * network_change and battery_change events when auto-tracking is enabled.
+     */
+    fun startMonitoring(context: Context, network: Boolean, battery: Boolean) {
+        if (network && networkCallback == null) {
+            val connectivity = context.getSystemService(Context.CONNECTIVITY_SERVICE) as ConnectivityManager
+            val telephony = context.getSystemService(Context.TELEPHONY_SERVICE) as? TelephonyManager
+            val report = {
+                Mobile.setNetworkInfo(telephony?.networkOperatorName ?: "", networkType(connectivity))
+            }
+            val callback = object : ConnectivityManager.NetworkCallback() {
+                override fun onAvailable(network: Network) = report()
+                override fun onLost(network: Network) = report()
+                override fun onCapabilitiesChanged(network: Network, capabilities: NetworkCapabilities) = report()
+            }
+            if (Build.VERSION.SDK_INT >= Build.VERSION_CODES.N) {
+                connectivity.registerDefaultNetworkCallback(callback)
+            } else {
+                connectivity.registerNetworkCallback(NetworkRequest.Builder().build(), callback)
+            }
+            networkCallback = callback
+            report()
+        }
+
+        if (battery && batteryReceiver == null) {
+            val receiver = object : BroadcastReceiver() {
+                override fun onReceive(context: Context, intent: Intent) {
+                    val level = intent.getIntExtra(BatteryManager.EXTRA_LEVEL, -1)
+                    val scale = intent.getIntExtra(BatteryManager.EXTRA_SCALE, 100)
+                    if (level < 0 || scale <= 0) return
+                    val state = when (intent.getIntExtra(BatteryManager.EXTRA_STATUS, -1)) {
+                        BatteryManager.BATTERY_STATUS_CHARGING -> "charging"
+                        BatteryManager.BATTERY_STATUS_FULL -> "full"
+                        BatteryManager.BATTERY_STATUS_DISCHARGING,
+                        BatteryManager.BATTERY_STATUS_NOT_CHARGING -> "discharging"
+                        else -> ""
+                    }
+                    Mobile.setBatteryInfo(level * 100L / scale, state)
+                }
+            }
+            // ACTION_BATTERY_CHANGED is sticky: the current state is delivered on registration
+            context.registerReceiver(receiver, IntentFilter(Intent.ACTION_BATTERY_CHANGED))
+            batteryReceiver = receiver
+        }
+    }
+
+    @Suppress("DEPRECATION")
+    private fun networkType(connectivity: ConnectivityManager): String {
+        if (Build.VERSION.SDK_INT >= Build.VERSION_CODES.M) {
+            val capabilities = connectivity.getNetworkCapabilities(connectivity.activeNetwork) ?: return "offline"
+            return when {
+                capabilities.hasTransport(NetworkCapabilities.TRANSPORT_WIFI) -> "wifi"
+                capabilities.hasTransport(NetworkCapabilities.TRANSPORT_ETHERNET) -> "ethernet"
+                capabilities.hasTransport(NetworkCapabilities.TRANSPORT_CELLULAR) -> "cellular"
+                else -> ""
+            }
+        }
+        val info = connectivity.activeNetworkInfo
+        if (info == null || !info.isConnected) return "offline"
+        return when (info.type) {
+            ConnectivityManager.TYPE_WIFI -> "wifi"
+            ConnectivityManager.TYPE_ETHERNET -> "ethernet"
+            ConnectivityManager.TYPE_MOBILE -> "cellular"
+            else -> ""
+        }
+    }
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -12,6 +13,21 @@ import (
 	"time"
 )
 
+// sdkHeader is the SDK handshake header, identifying the SDK to the gateway
+// for its audit log and SDK version reports.
+const sdkHeader = "X-Causality-SDK"
+
+// sdkHandshake is the value of sdkHeader sent by this SDK.
+const sdkHandshake = "name=causality-go; version=" + SDKVersion + "; platform=go"
+
+// minSDKVersionHeader carries the app's minimum SDK version on responses to
+// requests from older SDKs.
+const minSDKVersionHeader = "Causality-Min-SDK-Version"
+
+// ErrSDKVersionUnsupported is returned when the server rejects events
+// because this SDK version is older than the app's minimum version.
+var ErrSDKVersionUnsupported = errors.New("causality: SDK version no longer supported by the server")
+
 // httpTransport handles HTTP communication with the Causality server.
 type httpTransport struct {
 	client     *http.Client
@@ -66,6 +82,7 @@ func (t *httpTransport) sendBatch(ctx context.Context, events []Event) error {
 
 		req.Header.Set("Content-Type", "application/json")
 		req.Header.Set("X-API-Key", t.apiKey)
+		req.Header.Set(sdkHeader, sdkHandshake)
 
 		resp, err := t.client.Do(req)
 		if err != nil {
@@ -82,6 +99,11 @@ func (t *httpTransport) sendBatch(ctx context.Context, events []Event) error {
 			return nil
 		}
 
+		// Outdated SDK: don't retry until upgraded
+		if resp.StatusCode == http.StatusUpgradeRequired {
+			return fmt.Errorf("%w: minimum version %s", ErrSDKVersionUnsupported, resp.Header.Get(minSDKVersionHeader))
+		}
+
 		// Client error (4xx): don't retry, return immediately
 		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
 			return fmt.Errorf("causality: client error: status %d", resp.StatusCode)
@@ -3,8 +3,10 @@ package causality
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -121,6 +123,36 @@ func TestSendBatch_ClientError_NoRetry(t *testing.T) {
 	}
 }
 
+// TestSendBatch_SDKVersionUnsupported verifies that 426 responses are not
+// retried and return ErrSDKVersionUnsupported.
+func TestSendBatch_SDKVersionUnsupported(t *testing.T) {
+	var requestCount atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount.Add(1)
+		w.Header().Set(minSDKVersionHeader, "1.0.0")
+		w.WriteHeader(http.StatusUpgradeRequired)
+	}))
+	defer server.Close()
+
+	transport := newHTTPTransport(Config{
+		Endpoint:   server.URL,
+		APIKey:     "test-api-key",
+		Timeout:    5 * time.Second,
+		MaxRetries: 5,
+	})
+
+	err := transport.sendBatch(context.Background(), []Event{{EventType: "screenView", AppID: "test-app"}})
+	if !errors.Is(err, ErrSDKVersionUnsupported) {
+		t.Fatalf("sendBatch() error = %v, want ErrSDKVersionUnsupported", err)
+	}
+	if !strings.Contains(err.Error(), "1.0.0") {
+		t.Errorf("sendBatch() error = %v, want the minimum version", err)
+	}
+	if requestCount.Load() != 1 {
+		t.Errorf("Expected 1 request (no retry for 426), got %d", requestCount.Load())
+	}
+}
+
 // TestSendBatch_SetsHeaders verifies that required headers are set.
 func TestSendBatch_SetsHeaders(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -131,6 +163,9 @@ func TestSendBatch_SetsHeaders(t *testing.T) {
 		if r.Header.Get("X-API-Key") != "my-secret-api-key" {
 			t.Errorf("X-API-Key = %q, want %q", r.Header.Get("X-API-Key"), "my-secret-api-key")
 		}
+		if want := "name=causality-go; version=" + SDKVersion + "; platform=go"; r.Header.Get("X-Causality-SDK") != want {
+			t.Errorf("X-Causality-SDK = %q, want %q", r.Header.Get("X-Causality-SDK"), want)
+		}
 
 		w.WriteHeader(http.StatusOK)
 	}))
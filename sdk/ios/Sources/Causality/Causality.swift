@@ -40,6 +40,12 @@ public final class Causality {
         // Initialize Go core
         try Bridge.initSDK(config: config)
         isInitialized = true
+
+        // Report network and battery changes for auto-tracking
+        Platform.startMonitoring(
+            network: config.autoTrackNetwork ?? false,
+            battery: config.autoTrackBattery ?? false
+        )
     }
 
     /// Track a freeform event
@@ -105,6 +111,17 @@ public final class Causality {
         try Bridge.setUser(userId: userId, traits: traits, aliases: aliases)
     }
 
+    /// Register the device's push token so rules can send it notifications
+    /// - Parameters:
+    ///   - token: APNs device token as a hex string, or an FCM registration token
+    ///   - provider: "apns" or "fcm"
+    public func setPushToken(_ token: String, provider: String = "apns") throws {
+        guard isInitialized else {
+            throw CausalityError.notInitialized
+        }
+        try Bridge.setPushToken(token: token, provider: provider)
+    }
+
     /// Clear user identity (soft reset - keeps device ID)
     public func reset() throws {
         guard isInitialized else {
@@ -139,6 +156,24 @@ public final class Causality {
         }
     }
 
+    /// Send queued events from a background task without outliving it.
+    ///
+    /// Call from a `BGAppRefreshTask` or `BGProcessingTask` handler, passing the
+    /// time the task may run. Sending stops before the deadline.
+    /// - Parameter deadline: Time left in the background task
+    /// - Returns: `true` if events remain queued and another task should be scheduled
+    public func flushInBackgroundTask(deadline: TimeInterval) async -> Bool {
+        guard isInitialized else {
+            return false
+        }
+
+        return await withCheckedContinuation { (continuation: CheckedContinuation<Bool, Never>) in
+            DispatchQueue.global(qos: .utility).async {
+                continuation.resume(returning: Bridge.flushInBackgroundTask(deadlineMs: Int(deadline * 1000)))
+            }
+        }
+    }
+
     /// Get the device identifier
     /// - Returns: Device ID, or empty string if not initialized
     public var deviceId: String {
@@ -32,6 +32,51 @@ public struct Config: Codable {
     /// Use persistent device ID across reinstalls (optional, default: false)
     public var persistentDeviceId: Bool?
 
+    /// Send batches as JSON instead of compressed protobuf (optional, default: false)
+    public var disableCompression: Bool?
+
+    /// Public-key pins as "sha256/<base64 SPKI hash>" (optional, requires https)
+    public var certificatePins: [String]?
+
+    /// Failed deliveries before an event is dropped (optional, default: 10)
+    public var maxRetries: Int?
+
+    /// Start with undetermined consent until setConsent is called (optional, default: false)
+    public var consentRequired: Bool?
+
+    /// Emit network_change events when the network type changes (optional, default: false)
+    public var autoTrackNetwork: Bool?
+
+    /// Emit battery_change events when the battery level or state changes (optional, default: false)
+    public var autoTrackBattery: Bool?
+
+    /// Quiet period before an auto-tracked event is emitted, in milliseconds (optional, default: 5000)
+    public var autoTrackDebounceMs: Int?
+
+    /// Recent events (type and time only) attached to app_crash events (optional, default: 20)
+    public var maxBreadcrumbs: Int?
+
+    /// Stop attaching breadcrumbs to app_crash events (optional, default: false)
+    public var disableBreadcrumbs: Bool?
+
+    /// Average events per second queued before low-priority events are dropped (optional, default: 100)
+    public var maxEventsPerSecond: Int?
+
+    /// Events that may be queued at once before maxEventsPerSecond applies (optional, default: 500)
+    public var maxEventBurst: Int?
+
+    /// Drop events identical to one tracked within this many milliseconds (optional, default: 0, disabled)
+    public var dedupWindowMs: Int?
+
+    /// Stop correcting event timestamps by the device clock offset from server time (optional, default: false)
+    public var disableClockSync: Bool?
+
+    /// Server endpoint URLs to fail over to, in order, when endpoint is unavailable (optional)
+    public var fallbackEndpoints: [String]?
+
+    /// How often endpoint is retried while a fallback is in use, in milliseconds (optional, default: 60000)
+    public var endpointProbeIntervalMs: Int?
+
     public init(
         apiKey: String,
         endpoint: String,
@@ -42,7 +87,22 @@ public struct Config: Codable {
         sessionTimeoutMs: Int? = nil,
         debugMode: Bool? = nil,
         enableSessionTracking: Bool? = nil,
-        persistentDeviceId: Bool? = nil
+        persistentDeviceId: Bool? = nil,
+        disableCompression: Bool? = nil,
+        certificatePins: [String]? = nil,
+        maxRetries: Int? = nil,
+        consentRequired: Bool? = nil,
+        autoTrackNetwork: Bool? = nil,
+        autoTrackBattery: Bool? = nil,
+        autoTrackDebounceMs: Int? = nil,
+        maxBreadcrumbs: Int? = nil,
+        disableBreadcrumbs: Bool? = nil,
+        maxEventsPerSecond: Int? = nil,
+        maxEventBurst: Int? = nil,
+        dedupWindowMs: Int? = nil,
+        disableClockSync: Bool? = nil,
+        fallbackEndpoints: [String]? = nil,
+        endpointProbeIntervalMs: Int? = nil
     ) {
         self.apiKey = apiKey
         self.endpoint = endpoint
@@ -54,6 +114,21 @@ public struct Config: Codable {
         self.debugMode = debugMode
         self.enableSessionTracking = enableSessionTracking
         self.persistentDeviceId = persistentDeviceId
+        self.disableCompression = disableCompression
+        self.certificatePins = certificatePins
+        self.maxRetries = maxRetries
+        self.consentRequired = consentRequired
+        self.autoTrackNetwork = autoTrackNetwork
+        self.autoTrackBattery = autoTrackBattery
+        self.autoTrackDebounceMs = autoTrackDebounceMs
+        self.maxBreadcrumbs = maxBreadcrumbs
+        self.disableBreadcrumbs = disableBreadcrumbs
+        self.maxEventsPerSecond = maxEventsPerSecond
+        self.maxEventBurst = maxEventBurst
+        self.dedupWindowMs = dedupWindowMs
+        self.disableClockSync = disableClockSync
+        self.fallbackEndpoints = fallbackEndpoints
+        self.endpointProbeIntervalMs = endpointProbeIntervalMs
     }
 
     private enum CodingKeys: String, CodingKey {
@@ -67,5 +142,20 @@ public struct Config: Codable {
         case debugMode = "debug_mode"
         case enableSessionTracking = "enable_session_tracking"
         case persistentDeviceId = "persistent_device_id"
+        case disableCompression = "disable_compression"
+        case certificatePins = "certificate_pins"
+        case maxRetries = "max_retries"
+        case consentRequired = "consent_required"
+        case autoTrackNetwork = "auto_track_network"
+        case autoTrackBattery = "auto_track_battery"
+        case autoTrackDebounceMs = "auto_track_debounce_ms"
+        case maxBreadcrumbs = "max_breadcrumbs"
+        case disableBreadcrumbs = "disable_breadcrumbs"
+        case maxEventsPerSecond = "max_events_per_second"
+        case maxEventBurst = "max_event_burst"
+        case dedupWindowMs = "dedup_window_ms"
+        case disableClockSync = "disable_clock_sync"
+        case fallbackEndpoints = "fallback_endpoints"
+        case endpointProbeIntervalMs = "endpoint_probe_interval_ms"
     }
 }
@@ -812,6 +812,25 @@ public struct BatteryChange: CausalityEvent, Codable, Sendable {
     }
 }
 
+// MARK: - Experiment Events
+
+public struct ExperimentExposure: CausalityEvent, Codable, Sendable {
+    public static let eventType = "experiment_exposure"
+
+    public var experimentId: String
+    public var variant: String
+
+    public init(experimentId: String, variant: String) {
+        self.experimentId = experimentId
+        self.variant = variant
+    }
+
+    enum CodingKeys: String, CodingKey {
+        case experimentId = "experiment_id"
+        case variant
+    }
+}
+
 // MARK: - Custom Event
 
 public struct CustomEvent: CausalityEvent, Codable, Sendable {
@@ -69,6 +69,13 @@ enum Bridge {
         }
     }
 
+    static func setPushToken(token: String, provider: String) throws {
+        let result = CAUMobileSetPushToken(token, provider)
+        if !result.isEmpty {
+            throw CausalityError.tracking(result)
+        }
+    }
+
     static func reset() throws {
         print("[Causality:Bridge] Reset called")
         let result = CAUMobileReset()
@@ -96,6 +103,13 @@ enum Bridge {
         }
     }
 
+    static func flushInBackgroundTask(deadlineMs: Int) -> Bool {
+        print("[Causality:Bridge] FlushInBackgroundTask called (deadline: \(deadlineMs)ms)")
+        let more = CAUMobileFlushInBackgroundTask(deadlineMs)
+        print("[Causality:Bridge] FlushInBackgroundTask more: \(more)")
+        return more
+    }
+
     static func getDeviceId() -> String {
         let result = CAUMobileGetDeviceId()
         print("[Causality:Bridge] GetDeviceId: '\(result)'")
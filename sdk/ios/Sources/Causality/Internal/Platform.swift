@@ -1,4 +1,5 @@
 import Foundation
+import Network
 import CausalityCore
 #if canImport(UIKit)
 import UIKit
@@ -6,6 +7,9 @@ import UIKit
 
 /// Collects iOS platform context
 enum Platform {
+    private static var pathMonitor: NWPathMonitor?
+    private static var batteryObservers: [NSObjectProtocol] = []
+
     static func collectContext() -> (
         platform: String,
         osVersion: String,
@@ -67,4 +71,54 @@ enum Platform {
             ctx.timezone
         )
     }
+
+    /// Reports network and battery changes to the Go core, which emits
+    /// network_change and battery_change events when auto-tracking is enabled.
+    @MainActor
+    static func startMonitoring(network: Bool, battery: Bool) {
+        if network && pathMonitor == nil {
+            let monitor = NWPathMonitor()
+            monitor.pathUpdateHandler = { path in
+                CAUMobileSetNetworkInfo("", networkType(path))
+            }
+            monitor.start(queue: DispatchQueue(label: "io.causality.network"))
+            pathMonitor = monitor
+        }
+
+        #if canImport(UIKit) && !os(tvOS)
+        if battery && batteryObservers.isEmpty {
+            let device = UIDevice.current
+            device.isBatteryMonitoringEnabled = true
+            let report = {
+                guard device.batteryLevel >= 0 else { return }
+                CAUMobileSetBatteryInfo(Int(device.batteryLevel * 100), batteryState(device.batteryState))
+            }
+            for name in [UIDevice.batteryLevelDidChangeNotification, UIDevice.batteryStateDidChangeNotification] {
+                batteryObservers.append(
+                    NotificationCenter.default.addObserver(forName: name, object: nil, queue: nil) { _ in report() }
+                )
+            }
+            report()
+        }
+        #endif
+    }
+
+    private static func networkType(_ path: NWPath) -> String {
+        guard path.status == .satisfied else { return "offline" }
+        if path.usesInterfaceType(.wifi) { return "wifi" }
+        if path.usesInterfaceType(.wiredEthernet) { return "ethernet" }
+        if path.usesInterfaceType(.cellular) { return "cellular" }
+        return ""
+    }
+
+    #if canImport(UIKit) && !os(tvOS)
+    private static func batteryState(_ state: UIDevice.BatteryState) -> String {
+        switch state {
+        case .charging: return "charging"
+        case .full: return "full"
+        case .unplugged: return "discharging"
+        default: return ""
+        }
+    }
+    #endif
 }
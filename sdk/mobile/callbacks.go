@@ -86,3 +86,49 @@ func logError(err *SDKError, debugMode bool) {
 		notifyErrorCallbacks(err)
 	}
 }
+
+// EventsDroppedCallback is invoked when queued events are dropped because
+// they exceeded the retry budget (reason "max_retries") or the offline
+// retention period (reason "max_age"), or when tracked events are dropped
+// beyond the event rate limit (reason "rate_limited"). Rate limited drops
+// are reported in bulk, at most about once per second.
+// This interface is gomobile-compatible (single method with basic types).
+type EventsDroppedCallback interface {
+	OnEventsDropped(count int, reason string)
+}
+
+var (
+	droppedCallbacksMu sync.RWMutex
+	droppedCallbacks   []EventsDroppedCallback
+)
+
+// RegisterEventsDroppedCallback adds a callback for dropped event notifications.
+// Multiple callbacks can be registered; all will be notified.
+func RegisterEventsDroppedCallback(callback EventsDroppedCallback) {
+	if callback == nil {
+		return
+	}
+	droppedCallbacksMu.Lock()
+	defer droppedCallbacksMu.Unlock()
+	droppedCallbacks = append(droppedCallbacks, callback)
+}
+
+// UnregisterEventsDroppedCallbacks clears all registered dropped event callbacks.
+func UnregisterEventsDroppedCallbacks() {
+	droppedCallbacksMu.Lock()
+	defer droppedCallbacksMu.Unlock()
+	droppedCallbacks = nil
+}
+
+// notifyEventsDropped dispatches a dropped event notification to all
+// registered callbacks asynchronously.
+func notifyEventsDropped(count int, reason string) {
+	droppedCallbacksMu.RLock()
+	callbacks := make([]EventsDroppedCallback, len(droppedCallbacks))
+	copy(callbacks, droppedCallbacks)
+	droppedCallbacksMu.RUnlock()
+
+	for _, cb := range callbacks {
+		go cb.OnEventsDropped(count, reason)
+	}
+}
@@ -2,6 +2,7 @@ package mobile
 
 import (
 	"encoding/json"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -317,6 +318,40 @@ func TestLogError_NilNoOp(t *testing.T) {
 	logError(nil, false)
 }
 
+// mockDroppedCallback implements EventsDroppedCallback for testing.
+type mockDroppedCallback struct {
+	calls chan mockDroppedCall
+}
+
+type mockDroppedCall struct {
+	Count  int
+	Reason string
+}
+
+func (m *mockDroppedCallback) OnEventsDropped(count int, reason string) {
+	m.calls <- mockDroppedCall{Count: count, Reason: reason}
+}
+
+func TestRegisterEventsDroppedCallback_ReceivesDrops(t *testing.T) {
+	UnregisterEventsDroppedCallbacks()
+	defer UnregisterEventsDroppedCallbacks()
+
+	cb := &mockDroppedCallback{calls: make(chan mockDroppedCall, 1)}
+	RegisterEventsDroppedCallback(cb)
+	RegisterEventsDroppedCallback(nil)
+
+	notifyEventsDropped(3, "max_retries")
+
+	select {
+	case call := <-cb.calls:
+		if call.Count != 3 || call.Reason != "max_retries" {
+			t.Errorf("call = %+v, want {3 max_retries}", call)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("callback not invoked within timeout")
+	}
+}
+
 func TestErrorSeverity_String(t *testing.T) {
 	tests := []struct {
 		severity ErrorSeverity
@@ -401,3 +436,40 @@ func TestConvenienceConstructors(t *testing.T) {
 		})
 	}
 }
+
+func TestSDKOutdatedNotifier_NotifiesOnChange(t *testing.T) {
+	UnregisterErrorCallbacks()
+	defer UnregisterErrorCallbacks()
+
+	cb := newMockCallback()
+	RegisterErrorCallback(cb)
+
+	n := &sdkOutdatedNotifier{}
+	n.notify("1.4.0", false)
+	n.notify("1.4.0", false)
+	n.notify("1.4.0", true)
+	n.notify("1.4.0", true)
+
+	if !cb.waitForCalls(2, time.Second) {
+		t.Fatal("callbacks not invoked within timeout")
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	calls := cb.getCalls()
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(calls))
+	}
+	severities := map[int]bool{}
+	for _, c := range calls {
+		if c.Code != ErrCodeSDKOutdated {
+			t.Errorf("Code = %q, want %q", c.Code, ErrCodeSDKOutdated)
+		}
+		if !strings.Contains(c.Message, "1.4.0") {
+			t.Errorf("Message = %q, want the minimum version", c.Message)
+		}
+		severities[c.Severity] = true
+	}
+	if !severities[int(SeverityWarning)] || !severities[int(SeverityCritical)] {
+		t.Errorf("severities = %v, want a warning when flagged and critical when rejected", severities)
+	}
+}
@@ -11,50 +11,25 @@
 package mobile
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
 	"sync"
-	"time"
 
-	"github.com/SebastienMelki/causality/sdk/mobile/internal/batch"
 	"github.com/SebastienMelki/causality/sdk/mobile/internal/device"
-	"github.com/SebastienMelki/causality/sdk/mobile/internal/identity"
-	"github.com/SebastienMelki/causality/sdk/mobile/internal/session"
-	"github.com/SebastienMelki/causality/sdk/mobile/internal/storage"
-	"github.com/SebastienMelki/causality/sdk/mobile/internal/transport"
-	"github.com/google/uuid"
 )
 
 // sdkInstance is the package-level singleton.
 var (
 	sdkMu    sync.RWMutex
-	instance *sdk
+	instance *Client
 )
 
-// sdk holds the initialized SDK state with all wired components.
-type sdk struct {
-	config          *Config
-	db              *storage.DB
-	queue           *storage.Queue
-	idManager       *device.IDManager
-	identityManager *identity.IdentityManager
-	sessionTracker  *session.Tracker
-	batcher         *batch.Batcher
-	transportClient *transport.Client
-	debugMode       bool
-
-	ctx    context.Context
-	cancel context.CancelFunc
-
-	mu sync.RWMutex
-}
-
 // Init initializes the SDK with a JSON configuration string.
 // Returns empty string on success, or an error message on failure.
-// Must be called before any other SDK function.
+// Must be called before any other SDK function. Calling Init again replaces
+// the default instance.
+//
+// The default instance stores its database directly under data_path. Use
+// NewClient for additional, independent instances.
 //
 // Example config JSON:
 //
@@ -62,101 +37,29 @@ type sdk struct {
 func Init(configJSON string) string {
 	cfg, err := parseConfig(configJSON)
 	if err != nil {
-		sdkErr := &SDKError{
-			Code:     ErrCodeInvalidConfig,
-			Message:  err.Error(),
-			Severity: SeverityFatal,
-		}
+		sdkErr := newInvalidConfigError(err)
 		notifyErrorCallbacks(sdkErr)
 		return sdkErr.Error()
 	}
 
-	// Determine data path for SQLite storage
-	dataPath := cfg.DataPath
-	if dataPath == "" {
-		tmpDir, err := os.MkdirTemp("", "causality-sdk-*")
-		if err != nil {
-			sdkErr := &SDKError{
-				Code:     ErrCodeDiskError,
-				Message:  fmt.Sprintf("failed to create temp directory: %s", err.Error()),
-				Severity: SeverityFatal,
-			}
-			notifyErrorCallbacks(sdkErr)
-			return sdkErr.Error()
-		}
-		dataPath = tmpDir
-	}
-
-	// Open SQLite database
-	dbPath := filepath.Join(dataPath, "causality.db")
-	db, err := storage.NewDB(dbPath)
-	if err != nil {
-		sdkErr := &SDKError{
-			Code:     ErrCodeDiskError,
-			Message:  fmt.Sprintf("failed to open database: %s", err.Error()),
-			Severity: SeverityFatal,
-		}
-		notifyErrorCallbacks(sdkErr)
+	dataPath, sdkErr := resolveDataPath(cfg)
+	if sdkErr != nil {
 		return sdkErr.Error()
 	}
 
-	// Create persistent event queue
-	queue := storage.NewQueue(db, cfg.MaxQueueSize)
-
-	// Create device ID manager
-	idManager := device.NewIDManager(db, cfg.PersistentDeviceID)
-
-	// Create identity manager and restore persisted identity
-	identityMgr := identity.NewIdentityManager(db)
-	if err := identityMgr.LoadFromDB(); err != nil {
-		// Non-fatal: identity will start fresh
-		if cfg.DebugMode {
-			debugLog("Failed to load persisted identity: %s", err.Error())
-		}
-	}
-
-	// Create session tracker if enabled
-	var sessionTracker *session.Tracker
-	if cfg.EnableSessionTracking != nil && *cfg.EnableSessionTracking {
-		timeout := time.Duration(cfg.SessionTimeoutMs) * time.Millisecond
-		sessionTracker = session.NewTracker(timeout, nil, nil)
-	}
-
-	// Create HTTP transport client
-	transportClient := transport.NewClient(
-		cfg.Endpoint,
-		cfg.APIKey,
-		30*time.Second, // HTTP request timeout
-		nil,            // Use default retry strategy
-	)
-
-	// Create context for background operations
-	ctx, cancel := context.WithCancel(context.Background())
-
-	// Create batcher with flush loop
-	flushInterval := time.Duration(cfg.FlushIntervalMs) * time.Millisecond
-	batcher := batch.NewBatcher(queue, transportClient, cfg.BatchSize, flushInterval)
-	batcher.StartFlushLoop(ctx)
-
 	sdkMu.Lock()
-	instance = &sdk{
-		config:          cfg,
-		db:              db,
-		queue:           queue,
-		idManager:       idManager,
-		identityManager: identityMgr,
-		sessionTracker:  sessionTracker,
-		batcher:         batcher,
-		transportClient: transportClient,
-		debugMode:       cfg.DebugMode,
-		ctx:             ctx,
-		cancel:          cancel,
+	defer sdkMu.Unlock()
+
+	if instance != nil {
+		instance.Close()
+		instance = nil
 	}
-	sdkMu.Unlock()
 
-	if cfg.DebugMode {
-		debugLog("SDK initialized for app %s at %s", cfg.AppID, cfg.Endpoint)
+	c, sdkErr := openClient(cfg, dataPath)
+	if sdkErr != nil {
+		return sdkErr.Error()
 	}
+	instance = c
 
 	return ""
 }
@@ -170,7 +73,7 @@ func Init(configJSON string) string {
 //   - session_id from the session tracker (if enabled)
 //   - user_id from the identity manager (if set)
 //   - idempotency_key (generated UUID)
-//   - timestamp (UTC RFC3339Nano)
+//   - timestamp (UTC RFC3339Nano, corrected by the clock sync offset)
 //   - app_id from config
 //
 // Example event JSON:
@@ -182,73 +85,11 @@ func Track(eventJSON string) string {
 		return notInitializedError()
 	}
 
-	event, err := parseEvent(eventJSON)
-	if err != nil {
-		sdkErr := &SDKError{
-			Code:     ErrCodeInvalidJSON,
-			Message:  fmt.Sprintf("invalid event: %s", err.Error()),
-			Severity: SeverityWarning,
-		}
-		logError(sdkErr, inst.debugMode)
-		return sdkErr.Error()
-	}
-
-	// Generate idempotency key
-	idempotencyKey := uuid.New().String()
-
-	// Inject metadata
-	event.Metadata = EventMetadata{
-		Timestamp:      time.Now().UTC().Format(time.RFC3339Nano),
-		IdempotencyKey: idempotencyKey,
-		AppID:          inst.config.AppID,
-	}
-
-	// Inject device_id from ID manager
-	event.Metadata.DeviceID = inst.idManager.GetOrCreateDeviceID()
-
-	// Inject session_id from session tracker (if enabled)
-	if inst.sessionTracker != nil {
-		event.Metadata.SessionID = inst.sessionTracker.RecordActivity()
-	}
-
-	// Inject user_id from identity manager (if set)
-	user := inst.identityManager.GetUser()
-	if user != nil {
-		event.Metadata.UserID = user.UserID
-	}
-
-	if inst.debugMode {
-		debugLog("Track: type=%s, idempotency_key=%s, device_id=%s, session_id=%s",
-			event.Type, event.Metadata.IdempotencyKey, event.Metadata.DeviceID, event.Metadata.SessionID)
-	}
-
-	// Serialize event with all metadata
-	eventData, err := json.Marshal(event)
-	if err != nil {
-		sdkErr := &SDKError{
-			Code:     ErrCodeInvalidJSON,
-			Message:  fmt.Sprintf("failed to serialize event: %s", err.Error()),
-			Severity: SeverityWarning,
-		}
-		logError(sdkErr, inst.debugMode)
-		return sdkErr.Error()
-	}
-
-	// Enqueue via batcher
-	if err := inst.batcher.Add(string(eventData), idempotencyKey); err != nil {
-		sdkErr := &SDKError{
-			Code:     ErrCodeDiskError,
-			Message:  fmt.Sprintf("failed to enqueue event: %s", err.Error()),
-			Severity: SeverityWarning,
-		}
-		logError(sdkErr, inst.debugMode)
-		return sdkErr.Error()
-	}
-
-	return ""
+	return inst.Track(eventJSON)
 }
 
-// TrackTyped tracks a typed event, validating the event type against known types.
+// TrackTyped tracks a typed event, validating the event type against known types
+// and its properties against the event schema's rules.
 // eventType is the event type constant (e.g., "screen_view").
 // eventJSON is the serialized typed event properties.
 // Returns empty string on success, or an error message on failure.
@@ -261,9 +102,12 @@ func TrackTyped(eventType string, eventJSON string) string {
 		return fmt.Sprintf("unknown event type: %s", eventType)
 	}
 
-	// Build full event JSON with type wrapper
-	fullJSON := fmt.Sprintf(`{"type":%q,"properties":%s}`, eventType, eventJSON)
-	return Track(fullJSON)
+	inst := getInstance()
+	if inst == nil {
+		return notInitializedError()
+	}
+
+	return inst.TrackTyped(eventType, eventJSON)
 }
 
 // SetUser sets the user identity for subsequent events.
@@ -279,32 +123,25 @@ func SetUser(userJSON string) string {
 		return notInitializedError()
 	}
 
-	user, err := parseUser(userJSON)
-	if err != nil {
-		sdkErr := &SDKError{
-			Code:     ErrCodeInvalidJSON,
-			Message:  fmt.Sprintf("invalid user: %s", err.Error()),
-			Severity: SeverityWarning,
-		}
-		logError(sdkErr, inst.debugMode)
-		return sdkErr.Error()
-	}
-
-	if err := inst.identityManager.SetUser(user.UserID, user.Traits, user.Aliases); err != nil {
-		sdkErr := &SDKError{
-			Code:     ErrCodeDiskError,
-			Message:  fmt.Sprintf("failed to persist user identity: %s", err.Error()),
-			Severity: SeverityWarning,
-		}
-		logError(sdkErr, inst.debugMode)
-		return sdkErr.Error()
-	}
+	return inst.SetUser(userJSON)
+}
 
-	if inst.debugMode {
-		debugLog("SetUser: user_id=%s", user.UserID)
+// SetPushToken reports the device's push notification token so rules can
+// send it notifications. provider is "fcm" or "apns"; an empty token
+// unregisters the device. Call it on every launch with the current token and
+// whenever the token rotates. The token is only sent under full consent.
+// Returns empty string on success, or an error message on failure.
+//
+// Example:
+//
+//	SetPushToken("f3c1...9ab2", "apns")
+func SetPushToken(token, provider string) string {
+	inst := getInstance()
+	if inst == nil {
+		return notInitializedError()
 	}
 
-	return ""
+	return inst.SetPushToken(token, provider)
 }
 
 // Reset clears the current user identity but preserves the device ID and session.
@@ -316,17 +153,11 @@ func Reset() string {
 		return notInitializedError()
 	}
 
-	inst.identityManager.Reset()
-
-	if inst.debugMode {
-		debugLog("Reset: user identity cleared")
-	}
-
-	return ""
+	return inst.Reset()
 }
 
 // ResetAll performs a full reset: clears user identity, regenerates device ID,
-// clears the event queue, and ends the session.
+// clears the event queue and breadcrumbs, and ends the session.
 // Use this for complete logout / privacy reset scenarios.
 // Returns empty string on success, or an error message on failure.
 func ResetAll() string {
@@ -335,55 +166,67 @@ func ResetAll() string {
 		return notInitializedError()
 	}
 
-	// Clear user identity
-	inst.identityManager.Reset()
-
-	// Regenerate device ID
-	inst.idManager.RegenerateDeviceID()
+	return inst.ResetAll()
+}
 
-	// Clear event queue
-	if err := inst.queue.Clear(); err != nil {
-		if inst.debugMode {
-			debugLog("ResetAll: failed to clear queue: %s", err.Error())
-		}
+// Flush forces an immediate flush of all queued events.
+// Returns empty string on success, or an error message on failure.
+func Flush() string {
+	inst := getInstance()
+	if inst == nil {
+		return notInitializedError()
 	}
 
-	// End session (disable and re-enable to force session rotation)
-	if inst.sessionTracker != nil {
-		inst.sessionTracker.SetEnabled(false)
-		inst.sessionTracker.SetEnabled(true)
-	}
+	return inst.Flush()
+}
 
-	if inst.debugMode {
-		debugLog("ResetAll: user, device ID, queue, and session cleared")
+// FlushInBackgroundTask sends queued events from an OS background task
+// (iOS BGTask, Android WorkManager), stopping before deadlineMs elapses.
+// Returns true if events remain queued and the wrapper should schedule
+// another task, false otherwise (including when the SDK is not initialized).
+func FlushInBackgroundTask(deadlineMs int) bool {
+	inst := getInstance()
+	if inst == nil {
+		return false
 	}
 
-	return ""
+	return inst.FlushInBackgroundTask(deadlineMs)
 }
 
-// Flush forces an immediate flush of all queued events.
+// SetConsent sets the data collection consent level ("none", "essential",
+// or "full"), purging stored events the new level does not cover.
+// See Client.SetConsent.
 // Returns empty string on success, or an error message on failure.
-func Flush() string {
+func SetConsent(level string) string {
 	inst := getInstance()
 	if inst == nil {
 		return notInitializedError()
 	}
 
-	if inst.debugMode {
-		debugLog("Flush: force flush requested")
+	return inst.SetConsent(level)
+}
+
+// GetConsent returns the current consent level, or empty string if
+// undetermined or the SDK is not initialized.
+func GetConsent() string {
+	inst := getInstance()
+	if inst == nil {
+		return ""
 	}
 
-	if err := inst.batcher.Flush(inst.ctx); err != nil {
-		sdkErr := &SDKError{
-			Code:     ErrCodeNetworkError,
-			Message:  fmt.Sprintf("flush failed: %s", err.Error()),
-			Severity: SeverityWarning,
-		}
-		logError(sdkErr, inst.debugMode)
-		return sdkErr.Error()
+	return inst.GetConsent()
+}
+
+// GetDroppedEventCount returns how many events have been dropped since Init
+// for exceeding the retry budget or offline retention period.
+// Returns 0 if SDK is not initialized.
+func GetDroppedEventCount() int {
+	inst := getInstance()
+	if inst == nil {
+		return 0
 	}
 
-	return ""
+	return inst.GetDroppedEventCount()
 }
 
 // GetDeviceId returns the current device identifier.
@@ -394,7 +237,7 @@ func GetDeviceId() string {
 		return ""
 	}
 
-	return inst.idManager.GetOrCreateDeviceID()
+	return inst.GetDeviceId()
 }
 
 // GetSessionId returns the current session identifier.
@@ -405,11 +248,7 @@ func GetSessionId() string {
 		return ""
 	}
 
-	if inst.sessionTracker == nil {
-		return ""
-	}
-
-	return inst.sessionTracker.CurrentSessionID()
+	return inst.GetSessionId()
 }
 
 // GetUserId returns the current user identifier.
@@ -420,11 +259,7 @@ func GetUserId() string {
 		return ""
 	}
 
-	user := inst.identityManager.GetUser()
-	if user == nil {
-		return ""
-	}
-	return user.UserID
+	return inst.GetUserId()
 }
 
 // IsInitialized returns true if the SDK has been initialized.
@@ -439,9 +274,7 @@ func SetDebugMode(enabled bool) {
 		return
 	}
 
-	inst.mu.Lock()
-	inst.debugMode = enabled
-	inst.mu.Unlock()
+	inst.SetDebugMode(enabled)
 }
 
 // AppDidEnterBackground notifies the SDK that the app went to background.
@@ -454,23 +287,7 @@ func AppDidEnterBackground() string {
 		return notInitializedError()
 	}
 
-	// Notify session tracker
-	if inst.sessionTracker != nil {
-		inst.sessionTracker.AppDidEnterBackground()
-	}
-
-	// Trigger a flush to send queued events while we can
-	if err := inst.batcher.Flush(inst.ctx); err != nil {
-		if inst.debugMode {
-			debugLog("AppDidEnterBackground: flush failed: %s", err.Error())
-		}
-	}
-
-	if inst.debugMode {
-		debugLog("AppDidEnterBackground: recorded")
-	}
-
-	return ""
+	return inst.AppDidEnterBackground()
 }
 
 // AppWillEnterForeground notifies the SDK that the app is returning from background.
@@ -483,16 +300,7 @@ func AppWillEnterForeground() string {
 		return notInitializedError()
 	}
 
-	// Notify session tracker
-	if inst.sessionTracker != nil {
-		inst.sessionTracker.AppWillEnterForeground()
-	}
-
-	if inst.debugMode {
-		debugLog("AppWillEnterForeground: recorded")
-	}
-
-	return ""
+	return inst.AppWillEnterForeground()
 }
 
 // SetPlatformContext sets platform-specific device information.
@@ -503,13 +311,21 @@ func SetPlatformContext(platform, osVersion, model, manufacturer, appVersion, bu
 }
 
 // SetNetworkInfo updates the carrier and network type information.
-// Called by native wrappers when network conditions change.
+// Called by native wrappers when network conditions change; instances with
+// auto_track_network emit network_change events when the type changes.
 func SetNetworkInfo(carrier, networkType string) {
 	device.SetNetworkInfo(carrier, networkType)
 }
 
+// SetBatteryInfo reports the battery level (0-100) and state ("charging",
+// "discharging", or "full"). Called by native wrappers when the battery
+// changes; instances with auto_track_battery emit battery_change events.
+func SetBatteryInfo(level int, state string) {
+	device.SetBatteryInfo(level, state)
+}
+
 // getInstance returns the SDK singleton, or nil if not initialized.
-func getInstance() *sdk {
+func getInstance() *Client {
 	sdkMu.RLock()
 	defer sdkMu.RUnlock()
 	return instance
@@ -536,18 +352,22 @@ func resetForTesting() {
 
 	// Clean up components if they exist
 	if inst != nil {
-		if inst.cancel != nil {
-			inst.cancel()
-		}
-		if inst.batcher != nil {
-			inst.batcher.Stop()
-		}
-		if inst.db != nil {
-			inst.db.Close()
-		}
+		inst.Close()
+	}
+
+	clientsMu.Lock()
+	open := make([]*Client, 0, len(clients))
+	for _, c := range clients {
+		open = append(open, c)
+	}
+	clientsMu.Unlock()
+	for _, c := range open {
+		c.Close()
 	}
 
 	errorCallbacksMu.Lock()
 	errorCallbacks = nil
 	errorCallbacksMu.Unlock()
+
+	UnregisterEventsDroppedCallbacks()
 }
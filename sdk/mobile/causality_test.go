@@ -483,6 +483,30 @@ func TestTrackTyped_UnknownType(t *testing.T) {
 	}
 }
 
+func TestTrackTyped_InvalidProperties(t *testing.T) {
+	resetForTesting()
+	defer resetForTesting()
+
+	Init(validConfigJSON())
+
+	result := TrackTyped("screen_view", `{"screen_class": "HomeViewController"}`)
+	if result == "" {
+		t.Fatal("TrackTyped should return error for a screen_view without screen_name")
+	}
+	if !strings.Contains(result, "screen_name is required") {
+		t.Errorf("error = %q, want to name screen_name", result)
+	}
+
+	inst := getInstance()
+	count, err := inst.queue.Count()
+	if err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("queue count = %d, want 0", count)
+	}
+}
+
 func TestSetUser_Valid(t *testing.T) {
 	resetForTesting()
 	defer resetForTesting()
@@ -701,6 +725,35 @@ func TestFlush_NotInitialized(t *testing.T) {
 	}
 }
 
+func TestFlushInBackgroundTask(t *testing.T) {
+	resetForTesting()
+	defer resetForTesting()
+
+	if FlushInBackgroundTask(30000) {
+		t.Error("FlushInBackgroundTask should report no work when not initialized")
+	}
+
+	Init(validConfigJSON())
+
+	if FlushInBackgroundTask(30000) {
+		t.Error("FlushInBackgroundTask should report no work for an empty queue")
+	}
+
+	Track(`{"type": "screen_view", "properties": {"screen_name": "Home"}}`)
+
+	// A deadline inside the safety margin sends nothing and asks for another run.
+	if !FlushInBackgroundTask(100) {
+		t.Error("FlushInBackgroundTask should report remaining work when out of time")
+	}
+	count, err := getInstance().queue.Count()
+	if err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("queue count = %d, want 1", count)
+	}
+}
+
 func TestGetDeviceId_NotInitialized(t *testing.T) {
 	resetForTesting()
 	defer resetForTesting()
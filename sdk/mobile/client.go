@@ -0,0 +1,907 @@
+package mobile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SebastienMelki/causality/sdk/mobile/internal/autotrack"
+	"github.com/SebastienMelki/causality/sdk/mobile/internal/batch"
+	"github.com/SebastienMelki/causality/sdk/mobile/internal/breadcrumb"
+	"github.com/SebastienMelki/causality/sdk/mobile/internal/clocksync"
+	"github.com/SebastienMelki/causality/sdk/mobile/internal/consent"
+	"github.com/SebastienMelki/causality/sdk/mobile/internal/dedup"
+	"github.com/SebastienMelki/causality/sdk/mobile/internal/device"
+	"github.com/SebastienMelki/causality/sdk/mobile/internal/identity"
+	"github.com/SebastienMelki/causality/sdk/mobile/internal/schema"
+	"github.com/SebastienMelki/causality/sdk/mobile/internal/session"
+	"github.com/SebastienMelki/causality/sdk/mobile/internal/storage"
+	"github.com/SebastienMelki/causality/sdk/mobile/internal/transport"
+	"github.com/google/uuid"
+)
+
+// Client is an independent SDK instance with its own configuration, event
+// queue, identity, and session. Apps embedding analytics for several
+// products create one Client per app_id with NewClient; the package-level
+// functions (Init, Track, ...) operate on a default Client.
+//
+// Error callbacks and platform context are shared by all instances.
+type Client struct {
+	config          *Config
+	db              *storage.DB
+	queue           *storage.Queue
+	idManager       *device.IDManager
+	identityManager *identity.IdentityManager
+	sessionTracker  *session.Tracker
+	consent         *consent.Manager
+	batcher         *batch.Batcher
+	transportClient *transport.Client
+	autoTracker     *autotrack.Tracker
+	breadcrumbs     *breadcrumb.Buffer   // nil when breadcrumbs are disabled
+	clock           *clocksync.Estimator // nil when clock sync is disabled
+	dedup           *dedup.Filter        // nil when deduplication is disabled
+	debugMode       bool
+
+	// removeListeners unregisters the instance's device change listeners.
+	removeListeners []func()
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	closeOnce sync.Once
+
+	// consentMu orders Track's consent check and enqueue against SetConsent's
+	// purge so no event slips in after consent is revoked.
+	consentMu sync.RWMutex
+
+	mu sync.RWMutex
+}
+
+// clients holds open instances keyed by app_id so that two instances never
+// share a queue or identity.
+var (
+	clientsMu sync.Mutex
+	clients   = make(map[string]*Client)
+)
+
+// NewClient creates an independent SDK instance from a JSON configuration
+// string. Its database is stored under data_path in a directory named after
+// the app_id, isolated from other instances and from the default instance.
+// Only one open instance per app_id is allowed; call Close to release it.
+//
+// Example config JSON:
+//
+//	{"api_key": "key123", "endpoint": "https://analytics.example.com", "app_id": "my-app"}
+func NewClient(configJSON string) (*Client, error) {
+	cfg, err := parseConfig(configJSON)
+	if err != nil {
+		sdkErr := newInvalidConfigError(err)
+		notifyErrorCallbacks(sdkErr)
+		return nil, sdkErr
+	}
+
+	dataPath, sdkErr := resolveDataPath(cfg)
+	if sdkErr != nil {
+		return nil, sdkErr
+	}
+
+	dir := filepath.Join(dataPath, instanceDirName(cfg.AppID))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		sdkErr := &SDKError{
+			Code:     ErrCodeDiskError,
+			Message:  fmt.Sprintf("failed to create instance directory: %s", err.Error()),
+			Severity: SeverityFatal,
+		}
+		notifyErrorCallbacks(sdkErr)
+		return nil, sdkErr
+	}
+
+	c, sdkErr := openClient(cfg, dir)
+	if sdkErr != nil {
+		return nil, sdkErr
+	}
+	return c, nil
+}
+
+// resolveDataPath returns the configured data path, or a new temporary
+// directory if none is set.
+func resolveDataPath(cfg *Config) (string, *SDKError) {
+	if cfg.DataPath != "" {
+		return cfg.DataPath, nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "causality-sdk-*")
+	if err != nil {
+		sdkErr := &SDKError{
+			Code:     ErrCodeDiskError,
+			Message:  fmt.Sprintf("failed to create temp directory: %s", err.Error()),
+			Severity: SeverityFatal,
+		}
+		notifyErrorCallbacks(sdkErr)
+		return "", sdkErr
+	}
+	return tmpDir, nil
+}
+
+// instanceDirName returns a file-system safe directory name for an app_id.
+func instanceDirName(appID string) string {
+	name := url.PathEscape(appID)
+	if name == "." || name == ".." {
+		name = strings.ReplaceAll(name, ".", "%2E")
+	}
+	return name
+}
+
+// sdkName identifies the mobile SDK in the SDK handshake header.
+const sdkName = "causality-mobile"
+
+// sdkInfo returns the SDK handshake of a client with cfg: the SDK version,
+// the platform set by the native wrapper, and the optional protocol
+// features cfg enables.
+func sdkInfo(cfg *Config) transport.SDKInfo {
+	info := transport.SDKInfo{
+		Name:     sdkName,
+		Version:  device.SDKVersion,
+		Platform: device.CollectContext().Platform,
+	}
+	if !cfg.DisableClockSync {
+		info.Capabilities = append(info.Capabilities, "clock_sync")
+	}
+	if !cfg.DisableCompression {
+		info.Capabilities = append(info.Capabilities, "gzip_batch")
+	}
+	if len(cfg.FallbackEndpoints) > 0 {
+		info.Capabilities = append(info.Capabilities, "failover")
+	}
+	return info
+}
+
+// sdkOutdatedNotifier reports the server's minimum SDK version through the
+// error callbacks. Every response from an outdated SDK carries it, so the
+// callbacks are only notified when the minimum version or whether batches
+// are rejected changes.
+type sdkOutdatedNotifier struct {
+	mu         sync.Mutex
+	minVersion string
+	rejected   bool
+}
+
+// notify is the transport's SDKOutdatedFunc.
+func (n *sdkOutdatedNotifier) notify(minVersion string, rejected bool) {
+	n.mu.Lock()
+	changed := minVersion != n.minVersion || rejected != n.rejected
+	n.minVersion, n.rejected = minVersion, rejected
+	n.mu.Unlock()
+
+	if changed {
+		notifyErrorCallbacks(newSDKOutdatedError(device.SDKVersion, minVersion, rejected))
+	}
+}
+
+// openClient wires all SDK components for cfg with the database stored in
+// dir and registers the instance under its app_id.
+func openClient(cfg *Config, dir string) (*Client, *SDKError) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+
+	if _, exists := clients[cfg.AppID]; exists {
+		sdkErr := &SDKError{
+			Code:     ErrCodeInvalidConfig,
+			Message:  fmt.Sprintf("an SDK instance for app_id %q is already open", cfg.AppID),
+			Severity: SeverityFatal,
+		}
+		notifyErrorCallbacks(sdkErr)
+		return nil, sdkErr
+	}
+
+	// Open SQLite database
+	dbPath := filepath.Join(dir, "causality.db")
+	db, err := storage.NewDB(dbPath)
+	if err != nil {
+		sdkErr := &SDKError{
+			Code:     ErrCodeDiskError,
+			Message:  fmt.Sprintf("failed to open database: %s", err.Error()),
+			Severity: SeverityFatal,
+		}
+		notifyErrorCallbacks(sdkErr)
+		return nil, sdkErr
+	}
+	if cause := db.RebuildCause(); cause != nil {
+		// Non-fatal: the SDK continues with an empty database
+		sdkErr := &SDKError{
+			Code:     ErrCodeDiskError,
+			Message:  fmt.Sprintf("database was unreadable and has been rebuilt, queued events were lost: %s", cause.Error()),
+			Severity: SeverityWarning,
+		}
+		logError(sdkErr, cfg.DebugMode)
+		notifyErrorCallbacks(sdkErr)
+	}
+
+	// Create persistent event queue
+	queue := storage.NewQueue(db, cfg.MaxQueueSize)
+	if err := queue.ReleaseAll(); err != nil {
+		// Non-fatal: leased events become available once their lease expires
+		if cfg.DebugMode {
+			debugLog("Failed to release leased events: %s", err.Error())
+		}
+	}
+
+	// Create device ID manager
+	idManager := device.NewIDManager(db, cfg.PersistentDeviceID)
+
+	// Create identity manager and restore persisted identity
+	identityMgr := identity.NewIdentityManager(db)
+	if err := identityMgr.LoadFromDB(); err != nil {
+		// Non-fatal: identity will start fresh
+		if cfg.DebugMode {
+			debugLog("Failed to load persisted identity: %s", err.Error())
+		}
+	}
+
+	// Restore consent level
+	consentMgr, err := consent.NewManager(db, cfg.ConsentRequired)
+	if err != nil {
+		// Non-fatal: falls back to the configured default
+		if cfg.DebugMode {
+			debugLog("Failed to load consent level: %s", err.Error())
+		}
+	}
+
+	// Create session tracker if enabled
+	var sessionTracker *session.Tracker
+	if cfg.EnableSessionTracking != nil && *cfg.EnableSessionTracking {
+		timeout := time.Duration(cfg.SessionTimeoutMs) * time.Millisecond
+		sessionTracker = session.NewTracker(timeout, nil, nil)
+	}
+
+	// Create HTTP transport client
+	transportClient := transport.NewClient(
+		cfg.Endpoint,
+		cfg.APIKey,
+		30*time.Second, // HTTP request timeout
+		nil,            // Use default retry strategy
+	)
+	if cfg.DisableCompression {
+		transportClient.SetCompression(false)
+	}
+	transportClient.SetFallbackEndpoints(cfg.FallbackEndpoints, time.Duration(cfg.EndpointProbeIntervalMs)*time.Millisecond)
+
+	// Restore the clock offset and keep it updated from server responses
+	var clock *clocksync.Estimator
+	if !cfg.DisableClockSync {
+		clock, err = clocksync.NewEstimator(db)
+		if err != nil {
+			// Non-fatal: the offset is measured again on the next send
+			if cfg.DebugMode {
+				debugLog("Failed to load clock offset: %s", err.Error())
+			}
+		}
+		transportClient.SetClockSampler(clock)
+	}
+	transportClient.SetSDKInfo(sdkInfo(cfg))
+	transportClient.SetSDKOutdatedHandler((&sdkOutdatedNotifier{}).notify)
+	if err := transportClient.SetPinning(cfg.CertificatePins, func(host string, err error) {
+		notifyErrorCallbacks(newPinMismatchError(err.Error()))
+	}); err != nil {
+		sdkErr := &SDKError{
+			Code:     ErrCodeInvalidConfig,
+			Message:  fmt.Sprintf("failed to configure certificate pinning: %s", err.Error()),
+			Severity: SeverityFatal,
+		}
+		notifyErrorCallbacks(sdkErr)
+		_ = db.Close()
+		return nil, sdkErr
+	}
+
+	// Create context for background operations
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Create batcher with flush loop
+	flushInterval := time.Duration(cfg.FlushIntervalMs) * time.Millisecond
+	batcher := batch.NewBatcher(queue, transportClient, cfg.BatchSize, flushInterval)
+	batcher.SetRetryLimits(cfg.MaxRetries, time.Duration(cfg.OfflineRetentionMs)*time.Millisecond)
+	batcher.SetRateLimit(float64(cfg.MaxEventsPerSecond), cfg.MaxEventBurst)
+	debugMode := cfg.DebugMode
+	batcher.SetOnDropped(func(count int, reason string) {
+		if debugMode {
+			debugLog("Dropped %d events (%s)", count, reason)
+		}
+		notifyEventsDropped(count, reason)
+	})
+	batcher.SetPaused(!consentMgr.AllowsSend())
+	batcher.StartFlushLoop(ctx)
+
+	c := &Client{
+		config:          cfg,
+		db:              db,
+		queue:           queue,
+		idManager:       idManager,
+		identityManager: identityMgr,
+		sessionTracker:  sessionTracker,
+		consent:         consentMgr,
+		batcher:         batcher,
+		transportClient: transportClient,
+		clock:           clock,
+		dedup:           dedup.NewFilter(time.Duration(cfg.DedupWindowMs) * time.Millisecond),
+		debugMode:       cfg.DebugMode,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+	if !cfg.DisableBreadcrumbs {
+		c.breadcrumbs = breadcrumb.NewBuffer(cfg.MaxBreadcrumbs)
+	}
+	clients[cfg.AppID] = c
+
+	c.startAutoTracking()
+
+	if cfg.DebugMode {
+		debugLog("SDK initialized for app %s at %s", cfg.AppID, cfg.Endpoint)
+	}
+
+	return c, nil
+}
+
+// Close stops background flushing, closes the database, and releases the
+// instance's app_id. Queued events stay on disk and are sent by the next
+// instance opened for the same app_id and data path. The Client must not be
+// used afterwards. Calling Close more than once is a no-op.
+func (c *Client) Close() {
+	c.closeOnce.Do(func() {
+		clientsMu.Lock()
+		if clients[c.config.AppID] == c {
+			delete(clients, c.config.AppID)
+		}
+		clientsMu.Unlock()
+
+		for _, remove := range c.removeListeners {
+			remove()
+		}
+		if c.autoTracker != nil {
+			c.autoTracker.Stop()
+		}
+
+		if c.cancel != nil {
+			c.cancel()
+		}
+		if c.batcher != nil {
+			c.batcher.Stop()
+		}
+		if c.db != nil {
+			c.db.Close()
+		}
+	})
+}
+
+// startAutoTracking subscribes to the network and battery updates reported by
+// native wrappers if auto-tracking is enabled.
+func (c *Client) startAutoTracking() {
+	if !c.config.AutoTrackNetwork && !c.config.AutoTrackBattery {
+		return
+	}
+
+	debounce := time.Duration(c.config.AutoTrackDebounceMs) * time.Millisecond
+	c.autoTracker = autotrack.NewTracker(debounce, c.trackAuto)
+
+	if c.config.AutoTrackNetwork {
+		c.removeListeners = append(c.removeListeners, device.OnNetworkChange(c.autoTracker.NetworkChanged))
+	}
+	if c.config.AutoTrackBattery {
+		c.removeListeners = append(c.removeListeners, device.OnBatteryChange(c.autoTracker.BatteryChanged))
+	}
+}
+
+// trackAuto tracks an automatically collected event.
+func (c *Client) trackAuto(eventType string, properties map[string]interface{}) {
+	props, err := json.Marshal(properties)
+	if err != nil {
+		return
+	}
+	if errMsg := c.TrackTyped(eventType, string(props)); errMsg != "" && c.isDebug() {
+		debugLog("Auto-track %s failed: %s", eventType, errMsg)
+	}
+}
+
+// AppID returns the app_id this instance was created for.
+func (c *Client) AppID() string {
+	return c.config.AppID
+}
+
+// Track enqueues an event for asynchronous batch sending.
+// The eventJSON string should be a serialized Event with type and properties.
+// Returns empty string on success, or an error message on failure.
+//
+// Track automatically injects metadata into every event:
+//   - idempotency_key (UUID v4)
+//   - device_id from the device ID manager
+//   - session_id from the session tracker (if enabled)
+//   - user_id from the identity manager (if set)
+//   - timestamp (UTC RFC3339Nano, corrected by the clock sync offset)
+//   - app_id from config
+func (c *Client) Track(eventJSON string) string {
+	event, err := parseEvent(eventJSON)
+	if err != nil {
+		sdkErr := &SDKError{
+			Code:     ErrCodeInvalidJSON,
+			Message:  fmt.Sprintf("invalid event: %s", err.Error()),
+			Severity: SeverityWarning,
+		}
+		logError(sdkErr, c.isDebug())
+		return sdkErr.Error()
+	}
+
+	c.consentMu.RLock()
+	defer c.consentMu.RUnlock()
+
+	// Drop events the user has not consented to
+	if !c.consent.AllowsStore(event.Type) {
+		if c.isDebug() {
+			debugLog("Track: type=%s skipped (consent=%q)", event.Type, c.consent.Level())
+		}
+		return ""
+	}
+
+	// Drop exact repeats of an event tracked moments ago
+	if c.dedup.Duplicate(event.Type, event.Properties) {
+		if c.isDebug() {
+			debugLog("Track: type=%s dropped (duplicate within %dms)", event.Type, c.config.DedupWindowMs)
+		}
+		return ""
+	}
+
+	// Generate idempotency key
+	idempotencyKey := uuid.New().String()
+	now := c.clock.Now().UTC()
+
+	// Inject metadata
+	event.Metadata = EventMetadata{
+		Timestamp:      now.Format(time.RFC3339Nano),
+		IdempotencyKey: idempotencyKey,
+		AppID:          c.config.AppID,
+	}
+
+	// Inject device_id from ID manager
+	event.Metadata.DeviceID = c.idManager.GetOrCreateDeviceID()
+
+	// Inject session_id from session tracker (if enabled)
+	if c.sessionTracker != nil {
+		event.Metadata.SessionID = c.sessionTracker.RecordActivity()
+	}
+
+	// Inject user_id from identity manager (if set)
+	user := c.identityManager.GetUser()
+	if user != nil {
+		event.Metadata.UserID = user.UserID
+	}
+
+	if c.isDebug() {
+		debugLog("Track: type=%s, idempotency_key=%s, device_id=%s, session_id=%s",
+			event.Type, event.Metadata.IdempotencyKey, event.Metadata.DeviceID, event.Metadata.SessionID)
+	}
+
+	// Attach the trail of previously tracked events to crashes
+	if c.breadcrumbs != nil && event.Type == EventTypeAppCrash {
+		if props, err := withBreadcrumbs(event.Properties, c.breadcrumbs.Trail()); err != nil {
+			if c.isDebug() {
+				debugLog("Track: breadcrumbs not attached: %s", err.Error())
+			}
+		} else {
+			event.Properties = props
+		}
+	}
+
+	// Serialize event with all metadata
+	eventData, err := json.Marshal(event)
+	if err != nil {
+		sdkErr := &SDKError{
+			Code:     ErrCodeInvalidJSON,
+			Message:  fmt.Sprintf("failed to serialize event: %s", err.Error()),
+			Severity: SeverityWarning,
+		}
+		logError(sdkErr, c.isDebug())
+		return sdkErr.Error()
+	}
+
+	// Enqueue via batcher, dropping low-priority events beyond the rate limit
+	add := c.batcher.AddThrottled
+	if isHighPriority(event.Type) {
+		add = c.batcher.Add
+	}
+	if err := add(string(eventData), idempotencyKey); errors.Is(err, batch.ErrRateLimited) {
+		if c.isDebug() {
+			debugLog("Track: type=%s dropped (rate limited)", event.Type)
+		}
+		return ""
+	} else if err != nil {
+		sdkErr := &SDKError{
+			Code:     ErrCodeDiskError,
+			Message:  fmt.Sprintf("failed to enqueue event: %s", err.Error()),
+			Severity: SeverityWarning,
+		}
+		logError(sdkErr, c.isDebug())
+		return sdkErr.Error()
+	}
+
+	if c.breadcrumbs != nil {
+		c.breadcrumbs.Add(event.Type, now.UnixMilli())
+	}
+
+	return ""
+}
+
+// isHighPriority reports whether events of eventType are queued even beyond
+// the event rate limit: essential events and crashes.
+func isHighPriority(eventType string) bool {
+	return eventType == EventTypeAppCrash || consent.IsEssential(eventType)
+}
+
+// withBreadcrumbs returns crash properties with the breadcrumb trail set.
+func withBreadcrumbs(props json.RawMessage, trail []breadcrumb.Crumb) (json.RawMessage, error) {
+	if len(trail) == 0 {
+		return props, nil
+	}
+
+	fields := make(map[string]json.RawMessage)
+	if len(props) > 0 {
+		if err := json.Unmarshal(props, &fields); err != nil {
+			return nil, err
+		}
+	}
+
+	encoded, err := json.Marshal(trail)
+	if err != nil {
+		return nil, err
+	}
+	fields["breadcrumbs"] = encoded
+
+	return json.Marshal(fields)
+}
+
+// TrackTyped tracks a typed event, validating the event type against known types
+// and its properties against the rules declared in the event schema (e.g., a
+// non-empty screen_name), so events the server would reject fail here instead.
+// eventType is the event type constant (e.g., "screen_view").
+// eventJSON is the serialized typed event properties.
+// Returns empty string on success, or an error message on failure.
+func (c *Client) TrackTyped(eventType string, eventJSON string) string {
+	if !isValidEventType(eventType) {
+		return fmt.Sprintf("unknown event type: %s", eventType)
+	}
+
+	if err := schema.Validate(eventType, json.RawMessage(eventJSON)); err != nil {
+		sdkErr := &SDKError{
+			Code:     ErrCodeInvalidEvent,
+			Message:  err.Error(),
+			Severity: SeverityWarning,
+		}
+		logError(sdkErr, c.isDebug())
+		return sdkErr.Error()
+	}
+
+	// Build full event JSON with type wrapper
+	fullJSON := fmt.Sprintf(`{"type":%q,"properties":%s}`, eventType, eventJSON)
+	return c.Track(fullJSON)
+}
+
+// SetPushToken tracks a push_token_update event with the device's push
+// notification token. provider is "fcm" or "apns"; an empty token
+// unregisters the device.
+// Returns empty string on success, or an error message on failure.
+func (c *Client) SetPushToken(token, provider string) string {
+	if provider != PushProviderFCM && provider != PushProviderAPNs {
+		sdkErr := &SDKError{
+			Code:     ErrCodeInvalidEvent,
+			Message:  fmt.Sprintf("unknown push provider %q (want fcm or apns)", provider),
+			Severity: SeverityWarning,
+		}
+		logError(sdkErr, c.isDebug())
+		return sdkErr.Error()
+	}
+
+	props, err := json.Marshal(PushTokenUpdateEvent{Token: token, Provider: provider})
+	if err != nil {
+		return err.Error()
+	}
+	return c.TrackTyped(EventTypePushTokenUpdate, string(props))
+}
+
+// SetUser sets the user identity for subsequent events.
+// The userJSON string should contain user_id and optional traits/aliases.
+// Returns empty string on success, or an error message on failure.
+func (c *Client) SetUser(userJSON string) string {
+	user, err := parseUser(userJSON)
+	if err != nil {
+		sdkErr := &SDKError{
+			Code:     ErrCodeInvalidJSON,
+			Message:  fmt.Sprintf("invalid user: %s", err.Error()),
+			Severity: SeverityWarning,
+		}
+		logError(sdkErr, c.isDebug())
+		return sdkErr.Error()
+	}
+
+	if err := c.identityManager.SetUser(user.UserID, user.Traits, user.Aliases); err != nil {
+		sdkErr := &SDKError{
+			Code:     ErrCodeDiskError,
+			Message:  fmt.Sprintf("failed to persist user identity: %s", err.Error()),
+			Severity: SeverityWarning,
+		}
+		logError(sdkErr, c.isDebug())
+		return sdkErr.Error()
+	}
+
+	if c.isDebug() {
+		debugLog("SetUser: user_id=%s", user.UserID)
+	}
+
+	return ""
+}
+
+// Reset clears the current user identity but preserves the device ID and session.
+// This is a "soft reset" for user logout scenarios.
+// Returns empty string on success, or an error message on failure.
+func (c *Client) Reset() string {
+	c.identityManager.Reset()
+
+	if c.isDebug() {
+		debugLog("Reset: user identity cleared")
+	}
+
+	return ""
+}
+
+// ResetAll performs a full reset: clears user identity, regenerates device ID,
+// clears the event queue and breadcrumbs, and ends the session.
+// Use this for complete logout / privacy reset scenarios.
+// Returns empty string on success, or an error message on failure.
+func (c *Client) ResetAll() string {
+	// Clear user identity
+	c.identityManager.Reset()
+
+	// Regenerate device ID
+	c.idManager.RegenerateDeviceID()
+
+	// Clear event queue
+	if err := c.queue.Clear(); err != nil {
+		if c.isDebug() {
+			debugLog("ResetAll: failed to clear queue: %s", err.Error())
+		}
+	}
+
+	// Clear breadcrumbs
+	if c.breadcrumbs != nil {
+		c.breadcrumbs.Clear()
+	}
+
+	// End session (disable and re-enable to force session rotation)
+	if c.sessionTracker != nil {
+		c.sessionTracker.SetEnabled(false)
+		c.sessionTracker.SetEnabled(true)
+	}
+
+	if c.isDebug() {
+		debugLog("ResetAll: user, device ID, queue, and session cleared")
+	}
+
+	return ""
+}
+
+// Flush forces an immediate flush of all queued events.
+// Returns empty string on success, or an error message on failure.
+func (c *Client) Flush() string {
+	if c.isDebug() {
+		debugLog("Flush: force flush requested")
+	}
+
+	if err := c.batcher.Flush(c.ctx); err != nil {
+		sdkErr := &SDKError{
+			Code:     ErrCodeNetworkError,
+			Message:  fmt.Sprintf("flush failed: %s", err.Error()),
+			Severity: SeverityWarning,
+		}
+		logError(sdkErr, c.isDebug())
+		return sdkErr.Error()
+	}
+
+	return ""
+}
+
+// backgroundFlushMargin is kept back from a background task's deadline so the
+// native wrapper can reschedule and complete the task before the OS expires it.
+const backgroundFlushMargin = 500 * time.Millisecond
+
+// FlushInBackgroundTask sends queued events from an OS background task
+// (iOS BGTask, Android WorkManager) without outliving it. deadlineMs is the
+// time the task has left; batches are sent until the queue is empty, a send
+// fails, or the deadline (less a safety margin) is reached.
+// Returns true if events remain queued and the wrapper should schedule another
+// task, false once the queue is drained or nothing can be sent.
+func (c *Client) FlushInBackgroundTask(deadlineMs int) bool {
+	// A deadline inside the margin yields an expired context, so nothing is
+	// sent and the result only reports whether events are waiting.
+	budget := time.Duration(deadlineMs)*time.Millisecond - backgroundFlushMargin
+	ctx, cancel := context.WithTimeout(c.ctx, budget)
+	defer cancel()
+
+	more, err := c.batcher.Drain(ctx)
+	if err != nil {
+		sdkErr := &SDKError{
+			Code:     ErrCodeNetworkError,
+			Message:  fmt.Sprintf("background flush failed: %s", err.Error()),
+			Severity: SeverityWarning,
+		}
+		logError(sdkErr, c.isDebug())
+	}
+
+	if c.isDebug() {
+		debugLog("FlushInBackgroundTask: deadline=%dms more=%t", deadlineMs, more)
+	}
+
+	return more
+}
+
+// SetConsent sets the data collection consent level: "none", "essential",
+// or "full". The level is persisted across launches.
+//
+//   - none: nothing is collected and all queued events are purged.
+//   - essential: only essential events (app lifecycle, purchases) are
+//     collected; queued non-essential events are purged.
+//   - full: all events are collected.
+//
+// Queued events are sent once consent is essential or full.
+// Returns empty string on success, or an error message on failure.
+func (c *Client) SetConsent(level string) string {
+	parsed, err := consent.ParseLevel(level)
+	if err != nil {
+		sdkErr := &SDKError{
+			Code:     ErrCodeInvalidConfig,
+			Message:  err.Error(),
+			Severity: SeverityWarning,
+		}
+		logError(sdkErr, c.isDebug())
+		return sdkErr.Error()
+	}
+
+	c.consentMu.Lock()
+	defer c.consentMu.Unlock()
+
+	if _, err := c.consent.Set(parsed); err != nil {
+		sdkErr := &SDKError{
+			Code:     ErrCodeDiskError,
+			Message:  fmt.Sprintf("failed to persist consent: %s", err.Error()),
+			Severity: SeverityWarning,
+		}
+		logError(sdkErr, c.isDebug())
+		return sdkErr.Error()
+	}
+
+	// Purge stored events the new level no longer covers
+	var purgeErr error
+	switch parsed {
+	case consent.None:
+		purgeErr = c.queue.Clear()
+	case consent.Essential:
+		_, purgeErr = c.queue.DeleteExceptTypes(consent.EssentialEventTypes)
+	}
+	if purgeErr != nil {
+		sdkErr := &SDKError{
+			Code:     ErrCodeDiskError,
+			Message:  fmt.Sprintf("failed to purge events after consent change: %s", purgeErr.Error()),
+			Severity: SeverityCritical,
+		}
+		logError(sdkErr, c.isDebug())
+		return sdkErr.Error()
+	}
+
+	c.batcher.SetPaused(!c.consent.AllowsSend())
+
+	if c.isDebug() {
+		debugLog("SetConsent: level=%s", parsed)
+	}
+
+	return ""
+}
+
+// GetConsent returns the current consent level: "none", "essential",
+// "full", or empty string if undetermined.
+func (c *Client) GetConsent() string {
+	return string(c.consent.Level())
+}
+
+// GetDroppedEventCount returns how many events this instance has dropped
+// for exceeding the retry budget or offline retention period.
+func (c *Client) GetDroppedEventCount() int {
+	return int(c.batcher.DroppedCount())
+}
+
+// GetDiagnostics returns a JSON snapshot of this instance's delivery health.
+// See the package-level GetDiagnostics for the format.
+func (c *Client) GetDiagnostics() string {
+	return marshalDiagnostics(collectDiagnostics(c, time.Now()))
+}
+
+// GetDeviceId returns the current device identifier.
+func (c *Client) GetDeviceId() string {
+	return c.idManager.GetOrCreateDeviceID()
+}
+
+// GetSessionId returns the current session identifier.
+// Returns empty string if no session is active.
+func (c *Client) GetSessionId() string {
+	if c.sessionTracker == nil {
+		return ""
+	}
+
+	return c.sessionTracker.CurrentSessionID()
+}
+
+// GetUserId returns the current user identifier.
+// Returns empty string if no user is set.
+func (c *Client) GetUserId() string {
+	user := c.identityManager.GetUser()
+	if user == nil {
+		return ""
+	}
+	return user.UserID
+}
+
+// SetDebugMode toggles debug logging at runtime.
+func (c *Client) SetDebugMode(enabled bool) {
+	c.mu.Lock()
+	c.debugMode = enabled
+	c.mu.Unlock()
+}
+
+// AppDidEnterBackground notifies the instance that the app went to background.
+// This triggers a flush of queued events and records the background transition
+// for session tracking.
+// Returns empty string on success, or an error message on failure.
+func (c *Client) AppDidEnterBackground() string {
+	// Notify session tracker
+	if c.sessionTracker != nil {
+		c.sessionTracker.AppDidEnterBackground()
+	}
+
+	// Trigger a flush to send queued events while we can
+	if err := c.batcher.Flush(c.ctx); err != nil {
+		if c.isDebug() {
+			debugLog("AppDidEnterBackground: flush failed: %s", err.Error())
+		}
+	}
+
+	if c.isDebug() {
+		debugLog("AppDidEnterBackground: recorded")
+	}
+
+	return ""
+}
+
+// AppWillEnterForeground notifies the instance that the app is returning from
+// background. If the background duration exceeded the session timeout, the
+// current session ends and a new one will be started on the next Track call.
+// Returns empty string on success, or an error message on failure.
+func (c *Client) AppWillEnterForeground() string {
+	// Notify session tracker
+	if c.sessionTracker != nil {
+		c.sessionTracker.AppWillEnterForeground()
+	}
+
+	if c.isDebug() {
+		debugLog("AppWillEnterForeground: recorded")
+	}
+
+	return ""
+}
+
+// isDebug reports whether debug logging is enabled.
+func (c *Client) isDebug() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.debugMode
+}
@@ -0,0 +1,306 @@
+package mobile
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// instanceConfigJSON returns a valid config for appID storing data under dir.
+func instanceConfigJSON(appID, dir string) string {
+	return `{"api_key": "test-key", "endpoint": "https://api.example.com", "app_id": "` + appID + `", "data_path": "` + dir + `"}`
+}
+
+func TestNewClient_IsolatedInstances(t *testing.T) {
+	resetForTesting()
+	defer resetForTesting()
+
+	dir := t.TempDir()
+	a, err := NewClient(instanceConfigJSON("app-a", dir))
+	if err != nil {
+		t.Fatalf("NewClient(app-a): %v", err)
+	}
+	b, err := NewClient(instanceConfigJSON("app-b", dir))
+	if err != nil {
+		t.Fatalf("NewClient(app-b): %v", err)
+	}
+
+	if result := a.Track(`{"type": "screen_view", "properties": {"screen_name": "Home"}}`); result != "" {
+		t.Fatalf("Track: %s", result)
+	}
+	if result := a.SetUser(`{"user_id": "user-a"}`); result != "" {
+		t.Fatalf("SetUser: %s", result)
+	}
+
+	countA, _ := a.queue.Count()
+	countB, _ := b.queue.Count()
+	if countA != 1 || countB != 0 {
+		t.Errorf("queue depths: got a=%d b=%d, want a=1 b=0", countA, countB)
+	}
+	if a.GetUserId() != "user-a" || b.GetUserId() != "" {
+		t.Errorf("user IDs: got a=%q b=%q, want a=user-a b=empty", a.GetUserId(), b.GetUserId())
+	}
+	if a.GetDeviceId() == b.GetDeviceId() {
+		t.Error("instances should have separate device IDs")
+	}
+
+	for _, appID := range []string{"app-a", "app-b"} {
+		if _, err := os.Stat(filepath.Join(dir, appID, "causality.db")); err != nil {
+			t.Errorf("database for %s: %v", appID, err)
+		}
+	}
+
+	// The singleton facade is independent of the instances.
+	if IsInitialized() {
+		t.Error("IsInitialized() = true without Init")
+	}
+}
+
+func TestNewClient_DuplicateAppID(t *testing.T) {
+	resetForTesting()
+	defer resetForTesting()
+
+	dir := t.TempDir()
+	c, err := NewClient(instanceConfigJSON("app-a", dir))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	if _, err := NewClient(instanceConfigJSON("app-a", dir)); err == nil {
+		t.Fatal("second NewClient for the same app_id should fail")
+	}
+
+	// Closing releases the app_id and keeps queued events on disk.
+	c.Track(`{"type": "screen_view", "properties": {"screen_name": "Home"}}`)
+	c.Close()
+	c.Close()
+
+	reopened, err := NewClient(instanceConfigJSON("app-a", dir))
+	if err != nil {
+		t.Fatalf("NewClient after Close: %v", err)
+	}
+	if count, _ := reopened.queue.Count(); count != 1 {
+		t.Errorf("queued events after reopen: got %d, want 1", count)
+	}
+}
+
+func TestNewClient_InvalidConfig(t *testing.T) {
+	resetForTesting()
+	defer resetForTesting()
+
+	if _, err := NewClient(`{"api_key": ""}`); err == nil {
+		t.Fatal("NewClient should fail for invalid config")
+	}
+}
+
+func TestInstanceDirName(t *testing.T) {
+	tests := map[string]string{
+		"my-app":  "my-app",
+		"a/b":     "a%2Fb",
+		"..":      "%2E%2E",
+		"app.ios": "app.ios",
+	}
+	for appID, want := range tests {
+		if got := instanceDirName(appID); got != want {
+			t.Errorf("instanceDirName(%q) = %q, want %q", appID, got, want)
+		}
+	}
+}
+
+func TestSDKInfo(t *testing.T) {
+	info := sdkInfo(&Config{DisableCompression: true, FallbackEndpoints: []string{"https://eu.example.com"}})
+	if info.Name != "causality-mobile" || info.Version == "" {
+		t.Errorf("sdkInfo() = %+v, want causality-mobile with a version", info)
+	}
+	if got := strings.Join(info.Capabilities, ","); got != "clock_sync,failover" {
+		t.Errorf("capabilities = %q, want clock_sync,failover", got)
+	}
+}
+
+func TestNewClient_AutoTracksNetworkAndBattery(t *testing.T) {
+	resetForTesting()
+	defer resetForTesting()
+
+	dir := t.TempDir()
+	c, err := NewClient(`{"api_key": "test-key", "endpoint": "https://api.example.com", "app_id": "app-auto", "data_path": "` + dir +
+		`", "auto_track_network": true, "auto_track_battery": true, "auto_track_debounce_ms": 10}`)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	other, err := NewClient(instanceConfigJSON("app-manual", dir))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	SetNetworkInfo("", "ethernet")
+	SetNetworkInfo("", "wifi")
+	SetBatteryInfo(42, "charging")
+	time.Sleep(100 * time.Millisecond)
+
+	events, err := c.queue.DequeueBatch(10)
+	if err != nil {
+		t.Fatalf("DequeueBatch: %v", err)
+	}
+	types := map[string]bool{}
+	for _, stored := range events {
+		var event Event
+		if err := json.Unmarshal([]byte(stored.EventJSON), &event); err != nil {
+			t.Fatalf("unmarshal event: %v", err)
+		}
+		types[event.Type] = true
+	}
+	if len(events) != 2 || !types[EventTypeNetworkChange] || !types[EventTypeBatteryChange] {
+		t.Errorf("auto-tracked events: got %v, want one network_change and one battery_change", types)
+	}
+	if count, _ := other.queue.Count(); count != 0 {
+		t.Errorf("instance without auto-tracking queued %d events", count)
+	}
+}
+
+func TestTrack_AttachesBreadcrumbsToCrash(t *testing.T) {
+	resetForTesting()
+	defer resetForTesting()
+
+	dir := t.TempDir()
+	c, err := NewClient(`{"api_key": "test-key", "endpoint": "https://api.example.com", "app_id": "app-crash", "data_path": "` + dir +
+		`", "max_breadcrumbs": 2}`)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	c.TrackTyped(EventTypeScreenView, `{"screen_name": "Home"}`)
+	c.TrackTyped(EventTypeButtonTap, `{"button_id": "buy"}`)
+	c.TrackTyped(EventTypeAddToCart, `{"product_id": "p1"}`)
+	if result := c.TrackTyped(EventTypeAppCrash, `{"crash_type": "exception", "crash_message": "boom"}`); result != "" {
+		t.Fatalf("TrackTyped(app_crash): %s", result)
+	}
+
+	stored, err := c.queue.DequeueBatch(10)
+	if err != nil {
+		t.Fatalf("DequeueBatch: %v", err)
+	}
+	if len(stored) != 4 {
+		t.Fatalf("queued events: got %d, want 4", len(stored))
+	}
+
+	var crash struct {
+		Type       string `json:"type"`
+		Properties struct {
+			CrashMessage string `json:"crash_message"`
+			Breadcrumbs  []struct {
+				EventType   string `json:"event_type"`
+				TimestampMs int64  `json:"timestamp_ms"`
+			} `json:"breadcrumbs"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal([]byte(stored[3].EventJSON), &crash); err != nil {
+		t.Fatalf("unmarshal crash: %v", err)
+	}
+	if crash.Type != EventTypeAppCrash || crash.Properties.CrashMessage != "boom" {
+		t.Errorf("crash properties not preserved: %+v", crash)
+	}
+	crumbs := crash.Properties.Breadcrumbs
+	if len(crumbs) != 2 || crumbs[0].EventType != EventTypeButtonTap || crumbs[1].EventType != EventTypeAddToCart {
+		t.Fatalf("breadcrumbs: got %+v, want button_tap then add_to_cart", crumbs)
+	}
+	if crumbs[0].TimestampMs == 0 || crumbs[1].TimestampMs < crumbs[0].TimestampMs {
+		t.Errorf("breadcrumb timestamps not ordered: %+v", crumbs)
+	}
+}
+
+func TestSetPushToken(t *testing.T) {
+	resetForTesting()
+	defer resetForTesting()
+
+	dir := t.TempDir()
+	c, err := NewClient(`{"api_key": "test-key", "endpoint": "https://api.example.com", "app_id": "app-push", "data_path": "` + dir + `"}`)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	c.SetConsent("full")
+
+	if result := c.SetPushToken("tok-1", "gcm"); result == "" {
+		t.Error("SetPushToken with unknown provider: got success, want error")
+	}
+	if result := c.SetPushToken("tok-1", PushProviderAPNs); result != "" {
+		t.Fatalf("SetPushToken: %s", result)
+	}
+
+	stored, err := c.queue.DequeueBatch(10)
+	if err != nil {
+		t.Fatalf("DequeueBatch: %v", err)
+	}
+	if len(stored) != 1 {
+		t.Fatalf("queued events: got %d, want 1", len(stored))
+	}
+	var event struct {
+		Type       string               `json:"type"`
+		Properties PushTokenUpdateEvent `json:"properties"`
+	}
+	if err := json.Unmarshal([]byte(stored[0].EventJSON), &event); err != nil {
+		t.Fatalf("unmarshal event: %v", err)
+	}
+	want := PushTokenUpdateEvent{Token: "tok-1", Provider: PushProviderAPNs}
+	if event.Type != EventTypePushTokenUpdate || event.Properties != want {
+		t.Errorf("event: got %s %+v, want push_token_update %+v", event.Type, event.Properties, want)
+	}
+}
+
+func TestTrack_RateLimitsLowPriorityEvents(t *testing.T) {
+	resetForTesting()
+	defer resetForTesting()
+
+	dir := t.TempDir()
+	c, err := NewClient(`{"api_key": "test-key", "endpoint": "https://api.example.com", "app_id": "app-throttle", "data_path": "` + dir +
+		`", "max_events_per_second": 1, "max_event_burst": 3}`)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	for range 10 {
+		if result := c.TrackTyped(EventTypeScreenView, `{"screen_name": "Home"}`); result != "" {
+			t.Fatalf("TrackTyped(screen_view): %s", result)
+		}
+	}
+	// Essential events and crashes are never dropped.
+	c.TrackTyped(EventTypeAppStart, `{}`)
+	c.TrackTyped(EventTypeAppCrash, `{"crash_type": "exception"}`)
+
+	if depth, _ := c.queue.Count(); depth != 5 {
+		t.Errorf("queue depth: got %d, want 5", depth)
+	}
+	if got := c.batcher.DroppedCount(); got < 1 {
+		t.Errorf("DroppedCount: got %d, want rate limited drops reported", got)
+	}
+}
+
+func TestTrack_DropsDuplicatesWithinWindow(t *testing.T) {
+	resetForTesting()
+	defer resetForTesting()
+
+	dir := t.TempDir()
+	c, err := NewClient(`{"api_key": "test-key", "endpoint": "https://api.example.com", "app_id": "app-dedup", "data_path": "` + dir +
+		`", "dedup_window_ms": 60000}`)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	c.TrackTyped(EventTypeButtonTap, `{"button_id": "buy"}`)
+	c.TrackTyped(EventTypeButtonTap, `{"button_id": "buy"}`)
+	c.TrackTyped(EventTypeButtonTap, `{"button_id": "cancel"}`)
+	c.TrackTyped(EventTypeScreenView, `{"screen_name": "Home"}`)
+
+	if depth, _ := c.queue.Count(); depth != 3 {
+		t.Errorf("queue depth: got %d, want 3", depth)
+	}
+	var diag Diagnostics
+	if err := json.Unmarshal([]byte(c.GetDiagnostics()), &diag); err != nil {
+		t.Fatalf("invalid diagnostics JSON: %v", err)
+	}
+	if diag.DuplicatesDropped != 1 {
+		t.Errorf("DuplicatesDropped = %d, want 1", diag.DuplicatesDropped)
+	}
+}
@@ -1,10 +1,12 @@
 package mobile
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"strings"
+
+	"github.com/SebastienMelki/causality/sdk/mobile/internal/transport"
 )
 
 // Config holds the SDK configuration.
@@ -17,6 +19,15 @@ type Config struct {
 	// Endpoint is the Causality server URL (required, e.g., "https://analytics.example.com").
 	Endpoint string `json:"endpoint"`
 
+	// FallbackEndpoints are Causality server URLs to fail over to, in order,
+	// when Endpoint is unreachable or returns server errors, e.g. during a
+	// regional outage. Events go to the endpoint that last succeeded.
+	FallbackEndpoints []string `json:"fallback_endpoints,omitempty"`
+
+	// EndpointProbeIntervalMs is how often Endpoint is tried again while a
+	// fallback endpoint is in use, in milliseconds (default: 60000).
+	EndpointProbeIntervalMs int `json:"endpoint_probe_interval_ms,omitempty"`
+
 	// AppID is the application identifier (required).
 	AppID string `json:"app_id"`
 
@@ -43,10 +54,67 @@ type Config struct {
 	PersistentDeviceID bool `json:"persistent_device_id,omitempty"`
 
 	// OfflineRetentionMs is how long to keep offline events in milliseconds (default: 86400000 = 24h).
+	// Older queued events are dropped instead of sent.
 	OfflineRetentionMs int `json:"offline_retention_ms,omitempty"`
 
+	// ConsentRequired starts with undetermined consent until SetConsent is
+	// called: only essential events are stored and nothing is sent. When
+	// false, collection defaults to full consent.
+	ConsentRequired bool `json:"consent_required,omitempty"`
+
+	// MaxRetries is how many failed deliveries an event survives before it is dropped (default: 10).
+	MaxRetries int `json:"max_retries,omitempty"`
+
 	// DataPath is the platform-specific path for SQLite storage (required for persistence).
 	DataPath string `json:"data_path,omitempty"`
+
+	// DisableCompression sends batches as JSON even when the server accepts
+	// gzip-compressed protobuf batches.
+	DisableCompression bool `json:"disable_compression,omitempty"`
+
+	// CertificatePins restricts TLS connections to servers whose certificate
+	// chain contains one of these public keys, as "sha256/<base64 SPKI hash>".
+	// Requires an https endpoint. Include a backup pin to allow key rotation.
+	CertificatePins []string `json:"certificate_pins,omitempty"`
+
+	// AutoTrackNetwork emits a network_change event when the network type
+	// reported through SetNetworkInfo changes (default: false).
+	AutoTrackNetwork bool `json:"auto_track_network,omitempty"`
+
+	// AutoTrackBattery emits a battery_change event for battery updates
+	// reported through SetBatteryInfo (default: false).
+	AutoTrackBattery bool `json:"auto_track_battery,omitempty"`
+
+	// AutoTrackDebounceMs is how long network and battery updates must settle
+	// before an event is emitted, in milliseconds (default: 5000).
+	AutoTrackDebounceMs int `json:"auto_track_debounce_ms,omitempty"`
+
+	// MaxBreadcrumbs is how many recently tracked events (type and timestamp
+	// only) are kept in memory and attached to app_crash events (default: 20).
+	MaxBreadcrumbs int `json:"max_breadcrumbs,omitempty"`
+
+	// DisableBreadcrumbs stops attaching the breadcrumb trail to app_crash events.
+	DisableBreadcrumbs bool `json:"disable_breadcrumbs,omitempty"`
+
+	// MaxEventsPerSecond limits how many events per second are queued on
+	// average (default: 100). Beyond it, events other than app lifecycle,
+	// purchase_complete and app_crash events are dropped and reported with
+	// the reason "rate_limited", so a runaway tracking loop cannot flood
+	// the server.
+	MaxEventsPerSecond int `json:"max_events_per_second,omitempty"`
+
+	// MaxEventBurst is how many events may be queued at once before
+	// MaxEventsPerSecond applies (default: 500).
+	MaxEventBurst int `json:"max_event_burst,omitempty"`
+
+	// DedupWindowMs drops an event when an identical one (same type and
+	// properties) was tracked less than this many milliseconds before, e.g.
+	// from a double-fired UI handler (default: 0, disabled).
+	DedupWindowMs int `json:"dedup_window_ms,omitempty"`
+
+	// DisableClockSync stops estimating the device clock offset from server
+	// time, so event timestamps use the uncorrected device clock.
+	DisableClockSync bool `json:"disable_clock_sync,omitempty"`
 }
 
 // Default configuration values.
@@ -56,6 +124,12 @@ const (
 	DefaultMaxQueueSize       = 1000
 	DefaultSessionTimeoutMs   = 1800000 // 30 minutes
 	DefaultOfflineRetentionMs = 86400000 // 24 hours
+	DefaultMaxRetries         = 10
+	DefaultAutoTrackDebounceMs = 5000 // 5 seconds
+	DefaultMaxBreadcrumbs     = 20
+	DefaultMaxEventsPerSecond = 100
+	DefaultMaxEventBurst      = 500
+	DefaultEndpointProbeIntervalMs = 60000 // 1 minute
 
 	MinBatchSize       = 1
 	MinFlushIntervalMs = 1000 // 1 second minimum
@@ -63,51 +137,94 @@ const (
 )
 
 // validate checks that required fields are set and values are valid.
-// Returns empty string on success, error message on failure.
-func (c *Config) validate() string {
-	if strings.TrimSpace(c.APIKey) == "" {
-		return "api_key is required"
+// It returns every problem found, or nil if the config is valid.
+func (c *Config) validate() []ConfigProblem {
+	var problems []ConfigProblem
+	add := func(field, format string, args ...interface{}) {
+		problems = append(problems, ConfigProblem{Field: field, Message: fmt.Sprintf(format, args...)})
 	}
-	if strings.TrimSpace(c.Endpoint) == "" {
-		return "endpoint is required"
+
+	if strings.TrimSpace(c.APIKey) == "" {
+		add("api_key", "api_key is required")
 	}
 	if strings.TrimSpace(c.AppID) == "" {
-		return "app_id is required"
+		add("app_id", "app_id is required")
 	}
 
-	// Validate endpoint is a valid URL with scheme
-	parsed, err := url.Parse(c.Endpoint)
-	if err != nil {
-		return fmt.Sprintf("endpoint is not a valid URL: %s", err.Error())
+	// Validate endpoints are valid URLs with scheme
+	var parsed []*url.URL
+	if strings.TrimSpace(c.Endpoint) == "" {
+		add("endpoint", "endpoint is required")
+	} else if u, err := parseEndpoint(c.Endpoint); err != nil {
+		add("endpoint", "endpoint %s", err.Error())
+	} else {
+		parsed = append(parsed, u)
 	}
-	if parsed.Scheme == "" || parsed.Host == "" {
-		return "endpoint must include scheme and host (e.g., https://analytics.example.com)"
+	for i, endpoint := range c.FallbackEndpoints {
+		if u, err := parseEndpoint(endpoint); err != nil {
+			add("fallback_endpoints", "fallback_endpoints[%d] %s", i, err.Error())
+		} else {
+			parsed = append(parsed, u)
+		}
 	}
 
 	// Validate optional numeric fields if set
-	if c.BatchSize < 0 {
-		return "batch_size must be non-negative"
+	for _, f := range []struct {
+		key   string
+		value int
+	}{
+		{"batch_size", c.BatchSize},
+		{"flush_interval_ms", c.FlushIntervalMs},
+		{"max_queue_size", c.MaxQueueSize},
+		{"session_timeout_ms", c.SessionTimeoutMs},
+		{"offline_retention_ms", c.OfflineRetentionMs},
+		{"max_retries", c.MaxRetries},
+		{"auto_track_debounce_ms", c.AutoTrackDebounceMs},
+		{"max_breadcrumbs", c.MaxBreadcrumbs},
+		{"max_events_per_second", c.MaxEventsPerSecond},
+		{"max_event_burst", c.MaxEventBurst},
+		{"dedup_window_ms", c.DedupWindowMs},
+		{"endpoint_probe_interval_ms", c.EndpointProbeIntervalMs},
+	} {
+		if f.value < 0 {
+			add(f.key, "%s must be non-negative", f.key)
+		}
 	}
-	if c.FlushIntervalMs < 0 {
-		return "flush_interval_ms must be non-negative"
-	}
-	if c.MaxQueueSize < 0 {
-		return "max_queue_size must be non-negative"
+
+	if len(c.CertificatePins) > 0 {
+		for _, u := range parsed {
+			if u.Scheme != "https" {
+				add("certificate_pins", "certificate_pins requires an https endpoint")
+				break
+			}
+		}
+		if err := transport.ValidatePins(c.CertificatePins); err != nil {
+			add("certificate_pins", "certificate_pins: %s", err.Error())
+		}
 	}
-	if c.SessionTimeoutMs < 0 {
-		return "session_timeout_ms must be non-negative"
+
+	return problems
+}
+
+// parseEndpoint parses a server URL, which must include scheme and host.
+func parseEndpoint(endpoint string) (*url.URL, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("is not a valid URL: %s", err.Error())
 	}
-	if c.OfflineRetentionMs < 0 {
-		return "offline_retention_ms must be non-negative"
+	if u.Scheme == "" || u.Host == "" {
+		return nil, errors.New("must include scheme and host (e.g., https://analytics.example.com)")
 	}
-
-	return ""
+	return u, nil
 }
 
 // applyDefaults fills in default values for unset optional fields.
 func (c *Config) applyDefaults() {
 	// Trim trailing slash from endpoint
 	c.Endpoint = strings.TrimSuffix(c.Endpoint, "/")
+	for i, endpoint := range c.FallbackEndpoints {
+		c.FallbackEndpoints[i] = strings.TrimSuffix(endpoint, "/")
+	}
 
 	if c.BatchSize == 0 {
 		c.BatchSize = DefaultBatchSize
@@ -124,6 +241,24 @@ func (c *Config) applyDefaults() {
 	if c.OfflineRetentionMs == 0 {
 		c.OfflineRetentionMs = DefaultOfflineRetentionMs
 	}
+	if c.MaxRetries == 0 {
+		c.MaxRetries = DefaultMaxRetries
+	}
+	if c.AutoTrackDebounceMs == 0 {
+		c.AutoTrackDebounceMs = DefaultAutoTrackDebounceMs
+	}
+	if c.MaxBreadcrumbs == 0 {
+		c.MaxBreadcrumbs = DefaultMaxBreadcrumbs
+	}
+	if c.MaxEventsPerSecond == 0 {
+		c.MaxEventsPerSecond = DefaultMaxEventsPerSecond
+	}
+	if c.MaxEventBurst == 0 {
+		c.MaxEventBurst = DefaultMaxEventBurst
+	}
+	if c.EndpointProbeIntervalMs == 0 {
+		c.EndpointProbeIntervalMs = DefaultEndpointProbeIntervalMs
+	}
 
 	// Session tracking defaults to true
 	if c.EnableSessionTracking == nil {
@@ -133,16 +268,17 @@ func (c *Config) applyDefaults() {
 }
 
 // configFromJSON parses a JSON config string and returns a validated Config.
+// All problems are reported together in a *ConfigError: unknown keys, values
+// of the wrong type, and invalid values.
 func configFromJSON(jsonStr string) (*Config, error) {
-	var cfg Config
-	if err := json.Unmarshal([]byte(jsonStr), &cfg); err != nil {
-		return nil, fmt.Errorf("invalid config JSON: %w", err)
+	cfg, problems := decodeConfig(jsonStr)
+	if cfg != nil {
+		problems = append(problems, withoutFields(cfg.validate(), problems)...)
 	}
-
-	if errMsg := cfg.validate(); errMsg != "" {
-		return nil, fmt.Errorf("config validation failed: %s", errMsg)
+	if len(problems) > 0 {
+		return nil, &ConfigError{Problems: problems}
 	}
 
 	cfg.applyDefaults()
-	return &cfg, nil
+	return cfg, nil
 }
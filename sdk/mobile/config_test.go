@@ -2,6 +2,8 @@ package mobile
 
 import (
 	"encoding/json"
+	"errors"
+	"reflect"
 	"testing"
 )
 
@@ -114,6 +116,9 @@ func TestConfigParsing_Defaults(t *testing.T) {
 	if cfg.OfflineRetentionMs != DefaultOfflineRetentionMs {
 		t.Errorf("OfflineRetentionMs = %d, want default %d", cfg.OfflineRetentionMs, DefaultOfflineRetentionMs)
 	}
+	if cfg.MaxRetries != DefaultMaxRetries {
+		t.Errorf("MaxRetries = %d, want default %d", cfg.MaxRetries, DefaultMaxRetries)
+	}
 	if cfg.EnableSessionTracking == nil || !*cfg.EnableSessionTracking {
 		t.Error("EnableSessionTracking should default to true")
 	}
@@ -230,6 +235,24 @@ func TestConfigValidation_InvalidEndpoint(t *testing.T) {
 	}
 }
 
+func TestConfigValidation_FallbackEndpoints(t *testing.T) {
+	cfg, err := configFromJSON(`{"api_key": "key", "endpoint": "https://us.example.com/", "fallback_endpoints": ["https://eu.example.com/"], "app_id": "app"}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.FallbackEndpoints) != 1 || cfg.FallbackEndpoints[0] != "https://eu.example.com" {
+		t.Errorf("FallbackEndpoints = %v, want [https://eu.example.com]", cfg.FallbackEndpoints)
+	}
+	if cfg.EndpointProbeIntervalMs != DefaultEndpointProbeIntervalMs {
+		t.Errorf("EndpointProbeIntervalMs = %d, want %d", cfg.EndpointProbeIntervalMs, DefaultEndpointProbeIntervalMs)
+	}
+
+	_, err = configFromJSON(`{"api_key": "key", "endpoint": "https://us.example.com", "fallback_endpoints": ["https://eu.example.com", "eu2.example.com"], "app_id": "app"}`)
+	if err == nil || !contains(err.Error(), "fallback_endpoints[1] must include scheme and host") {
+		t.Errorf("error = %v, want invalid fallback_endpoints[1]", err)
+	}
+}
+
 func TestConfigValidation_InvalidJSON(t *testing.T) {
 	_, err := configFromJSON("not valid json")
 	if err == nil {
@@ -278,6 +301,11 @@ func TestConfigValidation_NegativeValues(t *testing.T) {
 			config:  `{"api_key":"k","endpoint":"https://a.com","app_id":"a","offline_retention_ms":-1}`,
 			wantErr: "offline_retention_ms must be non-negative",
 		},
+		{
+			name:    "negative max_retries",
+			config:  `{"api_key":"k","endpoint":"https://a.com","app_id":"a","max_retries":-1}`,
+			wantErr: "max_retries must be non-negative",
+		},
 	}
 
 	for _, tt := range tests {
@@ -293,6 +321,57 @@ func TestConfigValidation_NegativeValues(t *testing.T) {
 	}
 }
 
+func TestConfigValidation_CertificatePins(t *testing.T) {
+	const pin = "sha256/AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{
+			name:   "valid pin",
+			config: `{"api_key":"k","endpoint":"https://a.com","app_id":"a","certificate_pins":["` + pin + `"]}`,
+		},
+		{
+			name:    "http endpoint",
+			config:  `{"api_key":"k","endpoint":"http://a.com","app_id":"a","certificate_pins":["` + pin + `"]}`,
+			wantErr: "certificate_pins requires an https endpoint",
+		},
+		{
+			name:    "http fallback endpoint",
+			config:  `{"api_key":"k","endpoint":"https://a.com","fallback_endpoints":["http://b.com"],"app_id":"a","certificate_pins":["` + pin + `"]}`,
+			wantErr: "certificate_pins requires an https endpoint",
+		},
+		{
+			name:    "malformed pin",
+			config:  `{"api_key":"k","endpoint":"https://a.com","app_id":"a","certificate_pins":["AAAA"]}`,
+			wantErr: "certificate_pins",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := configFromJSON(tt.config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(cfg.CertificatePins) != 1 {
+					t.Errorf("CertificatePins = %v, want 1 pin", cfg.CertificatePins)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if got := err.Error(); !contains(got, tt.wantErr) {
+				t.Errorf("error = %q, want to contain %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestConfigJSON_RoundTrip(t *testing.T) {
 	original := Config{
 		APIKey:             "test-key",
@@ -343,3 +422,62 @@ func searchString(s, substr string) bool {
 	}
 	return false
 }
+
+func TestConfigValidation_ReportsAllProblems(t *testing.T) {
+	_, err := configFromJSON(`{"api_key": 42, "endpoint": "api.example.com", "batchsize": 10, "Flush_Interval_MS": 100, "debug_mode": "yes", "max_retries": -1, "certificate_pins": "sha256/x"}`)
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("error = %v, want *ConfigError", err)
+	}
+
+	want := []ConfigProblem{
+		{Field: "Flush_Interval_MS", Message: `unknown key "Flush_Interval_MS" (did you mean "flush_interval_ms"?)`},
+		{Field: "api_key", Message: "api_key must be a string"},
+		{Field: "batchsize", Message: `unknown key "batchsize" (did you mean "batch_size"?)`},
+		{Field: "certificate_pins", Message: "certificate_pins must be a list of strings"},
+		{Field: "debug_mode", Message: "debug_mode must be a boolean"},
+		{Field: "app_id", Message: "app_id is required"},
+		{Field: "endpoint", Message: "endpoint must include scheme and host (e.g., https://analytics.example.com)"},
+		{Field: "max_retries", Message: "max_retries must be non-negative"},
+	}
+	if !reflect.DeepEqual(cfgErr.Problems, want) {
+		t.Errorf("problems:\n got %+v\nwant %+v", cfgErr.Problems, want)
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	if result := ValidateConfig(`{"api_key": "k", "endpoint": "https://a.com", "app_id": "a"}`); result != "" {
+		t.Errorf("ValidateConfig(valid) = %s, want empty", result)
+	}
+
+	var problems []ConfigProblem
+	if err := json.Unmarshal([]byte(ValidateConfig(`{"app_id": "a", "unknown_option": true}`)), &problems); err != nil {
+		t.Fatalf("invalid problems JSON: %v", err)
+	}
+	fields := make([]string, len(problems))
+	for i, p := range problems {
+		fields[i] = p.Field
+	}
+	if want := []string{"unknown_option", "api_key", "endpoint"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("problem fields = %v, want %v", fields, want)
+	}
+
+	var syntaxProblems []ConfigProblem
+	if err := json.Unmarshal([]byte(ValidateConfig("not json")), &syntaxProblems); err != nil || len(syntaxProblems) != 1 || syntaxProblems[0].Field != "" {
+		t.Errorf("ValidateConfig(not json) problems = %+v, %v", syntaxProblems, err)
+	}
+}
+
+func TestNewClient_InvalidConfigCarriesProblems(t *testing.T) {
+	_, err := NewClient(`{"api_key": "k", "app_id": "a", "endpoint": "https://a.com", "batch_size": "50", "flush_interval": 5}`)
+	var sdkErr *SDKError
+	if !errors.As(err, &sdkErr) {
+		t.Fatalf("error = %v, want *SDKError", err)
+	}
+	if sdkErr.Code != ErrCodeInvalidConfig || len(sdkErr.Problems) != 2 {
+		t.Fatalf("error = %s, want INVALID_CONFIG with 2 problems", sdkErr.ToJSON())
+	}
+	if got := sdkErr.Problems[0].Message; got != "batch_size must be an integer" {
+		t.Errorf("problems[0] = %q", got)
+	}
+}
@@ -0,0 +1,181 @@
+package mobile
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ConfigProblem is one problem found in an SDK config.
+type ConfigProblem struct {
+	// Field is the config key the problem is about, empty if it concerns
+	// the config as a whole (e.g. malformed JSON).
+	Field string `json:"field,omitempty"`
+
+	// Message describes the problem.
+	Message string `json:"message"`
+}
+
+// ConfigError lists every problem found in an SDK config, so integrators can
+// fix them all in one pass.
+type ConfigError struct {
+	Problems []ConfigProblem
+}
+
+// Error joins the messages of all problems.
+func (e *ConfigError) Error() string {
+	messages := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		messages[i] = p.Message
+	}
+	return "config validation failed: " + strings.Join(messages, "; ")
+}
+
+// ValidateConfig checks a JSON configuration string without initializing the
+// SDK. Returns empty string if it is valid, or a JSON array of every problem
+// found, each with the config key ("field") and a "message".
+//
+// Example result:
+//
+//	[{"field":"endpoint","message":"endpoint is required"},
+//	 {"field":"batchsize","message":"unknown key \"batchsize\" (did you mean \"batch_size\"?)"}]
+func ValidateConfig(configJSON string) string {
+	_, err := configFromJSON(configJSON)
+	if err == nil {
+		return ""
+	}
+	data, err := json.Marshal(newInvalidConfigError(err).Problems)
+	if err != nil {
+		return `[{"message":"config validation failed"}]`
+	}
+	return string(data)
+}
+
+// configFields maps each config key to its Config field.
+var configFields = func() map[string]reflect.StructField {
+	t := reflect.TypeOf(Config{})
+	fields := make(map[string]reflect.StructField, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if key != "" && key != "-" {
+			fields[key] = f
+		}
+	}
+	return fields
+}()
+
+// decodeConfig decodes a JSON config key by key, reporting unknown keys and
+// values of the wrong type instead of stopping at the first one. The
+// returned Config is nil only if jsonStr is not a JSON object.
+func decodeConfig(jsonStr string) (*Config, []ConfigProblem) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(jsonStr), &raw); err != nil {
+		return nil, []ConfigProblem{{Message: fmt.Sprintf("invalid config JSON: %s", err.Error())}}
+	}
+
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var cfg Config
+	var problems []ConfigProblem
+	v := reflect.ValueOf(&cfg).Elem()
+	for _, key := range keys {
+		field, ok := configFields[key]
+		if !ok {
+			message := fmt.Sprintf("unknown key %q", key)
+			if suggestion := suggestConfigKey(key); suggestion != "" {
+				message += fmt.Sprintf(" (did you mean %q?)", suggestion)
+			}
+			problems = append(problems, ConfigProblem{Field: key, Message: message})
+			continue
+		}
+		if err := json.Unmarshal(raw[key], v.FieldByIndex(field.Index).Addr().Interface()); err != nil {
+			problems = append(problems, ConfigProblem{
+				Field:   key,
+				Message: fmt.Sprintf("%s must be %s", key, describeConfigType(field.Type)),
+			})
+		}
+	}
+	return &cfg, problems
+}
+
+// withoutFields returns the problems whose field has no problem in reported,
+// so a value of the wrong type is not also reported as missing.
+func withoutFields(problems, reported []ConfigProblem) []ConfigProblem {
+	seen := make(map[string]bool, len(reported))
+	for _, p := range reported {
+		seen[p.Field] = true
+	}
+	var out []ConfigProblem
+	for _, p := range problems {
+		if !seen[p.Field] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// describeConfigType names the JSON type expected for a config field.
+func describeConfigType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return describeConfigType(t.Elem())
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return "an integer"
+	case reflect.Slice:
+		return "a list of " + strings.TrimPrefix(strings.TrimPrefix(describeConfigType(t.Elem()), "an "), "a ") + "s"
+	default:
+		return t.String()
+	}
+}
+
+// suggestConfigKey returns the known config key closest to an unknown key,
+// or "" if none is close: keys differing only in case, underscores or
+// dashes, or within two single-character edits.
+func suggestConfigKey(key string) string {
+	normalize := func(s string) string {
+		return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(s))
+	}
+
+	best, bestDistance := "", 3
+	for known := range configFields {
+		if normalize(known) == normalize(key) {
+			return known
+		}
+		if d := editDistance(known, key); d < bestDistance || (d == bestDistance && known < best) {
+			best, bestDistance = known, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
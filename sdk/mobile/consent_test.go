@@ -0,0 +1,120 @@
+package mobile
+
+import (
+	"strings"
+	"testing"
+)
+
+// consentConfigJSON returns a valid config that requires explicit consent.
+func consentConfigJSON() string {
+	return `{"api_key": "test-key", "endpoint": "https://api.example.com", "app_id": "test-app", "consent_required": true}`
+}
+
+func queueDepth(t *testing.T) int {
+	t.Helper()
+	count, err := getInstance().queue.Count()
+	if err != nil {
+		t.Fatalf("queue count: %v", err)
+	}
+	return count
+}
+
+func TestConsent_DefaultsToFull(t *testing.T) {
+	resetForTesting()
+	defer resetForTesting()
+
+	if result := Init(validConfigJSON()); result != "" {
+		t.Fatalf("Init: %s", result)
+	}
+	if got := GetConsent(); got != "full" {
+		t.Errorf("GetConsent() = %q, want full", got)
+	}
+}
+
+func TestConsent_UndeterminedQueuesEssentialOnly(t *testing.T) {
+	resetForTesting()
+	defer resetForTesting()
+
+	if result := Init(consentConfigJSON()); result != "" {
+		t.Fatalf("Init: %s", result)
+	}
+	if got := GetConsent(); got != "" {
+		t.Errorf("GetConsent() = %q, want undetermined", got)
+	}
+
+	Track(`{"type": "screen_view", "properties": {"screen_name": "Home"}}`)
+	Track(`{"type": "app_start"}`)
+
+	if got := queueDepth(t); got != 1 {
+		t.Errorf("queued events: got %d, want 1 (essential only)", got)
+	}
+
+	// Nothing is sent while consent is undetermined.
+	if result := Flush(); result != "" {
+		t.Errorf("Flush: %s", result)
+	}
+	if got := queueDepth(t); got != 1 {
+		t.Errorf("queued events after flush: got %d, want 1", got)
+	}
+}
+
+func TestConsent_RevokePurgesEvents(t *testing.T) {
+	resetForTesting()
+	defer resetForTesting()
+
+	if result := Init(consentConfigJSON()); result != "" {
+		t.Fatalf("Init: %s", result)
+	}
+	if result := SetConsent("full"); result != "" {
+		t.Fatalf("SetConsent(full): %s", result)
+	}
+
+	Track(`{"type": "screen_view", "properties": {"screen_name": "Home"}}`)
+	Track(`{"type": "app_start"}`)
+	if got := queueDepth(t); got != 2 {
+		t.Fatalf("queued events: got %d, want 2", got)
+	}
+
+	if result := SetConsent("essential"); result != "" {
+		t.Fatalf("SetConsent(essential): %s", result)
+	}
+	if got := queueDepth(t); got != 1 {
+		t.Errorf("queued events after essential: got %d, want 1", got)
+	}
+
+	if result := SetConsent("none"); result != "" {
+		t.Fatalf("SetConsent(none): %s", result)
+	}
+	if got := queueDepth(t); got != 0 {
+		t.Errorf("queued events after none: got %d, want 0", got)
+	}
+
+	Track(`{"type": "app_start"}`)
+	if got := queueDepth(t); got != 0 {
+		t.Errorf("queued events tracked under none: got %d, want 0", got)
+	}
+}
+
+func TestConsent_InvalidLevel(t *testing.T) {
+	resetForTesting()
+	defer resetForTesting()
+
+	if result := Init(validConfigJSON()); result != "" {
+		t.Fatalf("Init: %s", result)
+	}
+	if result := SetConsent("partial"); !strings.Contains(result, "invalid consent level") {
+		t.Errorf("SetConsent(partial) = %q, want invalid consent level error", result)
+	}
+	if got := GetConsent(); got != "full" {
+		t.Errorf("GetConsent() = %q, want unchanged full", got)
+	}
+}
+
+func TestConsent_NotInitialized(t *testing.T) {
+	resetForTesting()
+	defer resetForTesting()
+
+	if result := SetConsent("full"); !strings.Contains(result, "not initialized") {
+		t.Errorf("SetConsent before Init = %q, want not initialized error", result)
+	}
+}
@@ -0,0 +1,259 @@
+package mobile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/SebastienMelki/causality/internal/gateway"
+	pb "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// The contract tests send events through the public SDK path (TrackTyped and
+// Flush) to the real gateway event service and check that every property
+// reaches the proto payload. The SDK converts properties with encoding/json,
+// which silently drops keys the proto does not declare, so a renamed field
+// (button_name vs button_id) would otherwise only show up as empty columns.
+
+// contractPublisher records the envelopes the gateway accepts.
+type contractPublisher struct {
+	mu     sync.Mutex
+	events []*pb.EventEnvelope
+}
+
+func (p *contractPublisher) PublishEvent(_ context.Context, event *pb.EventEnvelope) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, proto.Clone(event).(*pb.EventEnvelope))
+	return nil
+}
+
+func (p *contractPublisher) take() []*pb.EventEnvelope {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	events := p.events
+	p.events = nil
+	return events
+}
+
+// contractEvent is a tracked event with every SDK field set.
+type contractEvent struct {
+	eventType  string
+	properties interface{}
+}
+
+func contractEvents() []contractEvent {
+	return []contractEvent{
+		{EventTypeScreenView, ScreenViewEvent{ScreenName: "checkout", ScreenClass: "CheckoutViewController", PreviousScreen: "cart"}},
+		{EventTypeScreenExit, ScreenExitEvent{ScreenName: "checkout", DurationMs: 4200, NextScreen: "receipt"}},
+		{EventTypeButtonTap, ButtonTapEvent{ButtonID: "pay", ButtonText: "Pay now", ScreenName: "checkout"}},
+		{EventTypeUserLogin, UserLoginEvent{UserID: "user-1", Method: "email", IsNewUser: true}},
+		{EventTypeUserLogout, UserLogoutEvent{UserID: "user-1", Reason: "manual"}},
+		{EventTypeUserSignup, UserSignupEvent{UserID: "user-1", Method: "apple", ReferralSource: "friend"}},
+		{EventTypeProductView, ProductViewEvent{ProductID: "sku-1", ProductName: "Mug", Category: "kitchen", PriceCents: 1299, Currency: "USD", Source: "search"}},
+		{EventTypeAddToCart, AddToCartEvent{ProductID: "sku-1", ProductName: "Mug", Quantity: 2, PriceCents: 1299, Currency: "USD", CartID: "cart-1"}},
+		{EventTypePurchaseComplete, PurchaseCompleteEvent{OrderID: "order-1", CartID: "cart-1", ItemCount: 2, TotalCents: 2598, Currency: "USD", PaymentMethod: "card"}},
+		{EventTypeAppStart, AppStartEvent{IsColdStart: true, LaunchDurationMs: 850, LaunchSource: "deeplink", DeeplinkURL: "app://promo"}},
+		{EventTypeAppBackground, AppBackgroundEvent{ForegroundDurationMs: 60000, CurrentScreen: "checkout"}},
+		{EventTypeAppForeground, AppForegroundEvent{BackgroundDurationMs: 30000, ResumeScreen: "checkout"}},
+		{EventTypeAppCrash, AppCrashEvent{CrashType: "exception", CrashMessage: "nil pointer", StackTrace: "main.go:42", CurrentScreen: "checkout"}},
+		{EventTypeNetworkChange, NetworkChangeEvent{PreviousType: "wifi", CurrentType: "cellular_5g"}},
+		{EventTypeBatteryChange, BatteryChangeEvent{BatteryLevel: 42, State: "charging"}},
+		{EventTypePushTokenUpdate, PushTokenUpdateEvent{Token: "fcm-token-1", Provider: PushProviderFCM}},
+		{EventTypeExperimentExposure, ExperimentExposureEvent{ExperimentID: "checkout-v2", Variant: "treatment"}},
+		{EventTypeCustom, map[string]interface{}{
+			"event_name": "level_up",
+			"level":      3,
+			"score":      12.5,
+			"hard_mode":  true,
+			"character":  "mage",
+		}},
+	}
+}
+
+// newContractGateway starts the gateway event service behind the batch
+// decoding middleware, so the SDK upgrades to compressed batches after its
+// first response.
+func newContractGateway(t *testing.T) (*httptest.Server, *contractPublisher) {
+	t.Helper()
+	pub := &contractPublisher{}
+	mux := http.NewServeMux()
+	svc := gateway.NewEventServiceWithPublisher(pub, nil, 0, nil)
+	if err := pb.RegisterEventServiceServer(svc, pb.WithMux(mux)); err != nil {
+		t.Fatalf("register event service: %v", err)
+	}
+	srv := httptest.NewServer(gateway.Chain(mux, gateway.BatchDecoding(1<<20)))
+	t.Cleanup(srv.Close)
+	return srv, pub
+}
+
+func TestContract_FixturesSetEveryField(t *testing.T) {
+	for _, ev := range contractEvents() {
+		v := reflect.ValueOf(ev.properties)
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).IsZero() {
+				t.Errorf("%s fixture leaves %s unset; set it so the contract covers it",
+					ev.eventType, v.Type().Field(i).Name)
+			}
+		}
+	}
+
+	covered := make(map[string]bool)
+	for _, ev := range contractEvents() {
+		covered[ev.eventType] = true
+	}
+	for eventType := range validEventTypes {
+		if !covered[eventType] {
+			t.Errorf("no contract fixture for event type %q", eventType)
+		}
+	}
+}
+
+func TestContract_TypedEventsReachProtoWithoutLoss(t *testing.T) {
+	resetForTesting()
+	defer resetForTesting()
+
+	srv, pub := newContractGateway(t)
+
+	cfg := fmt.Sprintf(`{"api_key": "test-key", "endpoint": %q, "app_id": "contract-app", "batch_size": 100, "enable_session_tracking": false, "data_path": %q}`,
+		srv.URL, t.TempDir())
+	if result := Init(cfg); result != "" {
+		t.Fatalf("Init returned error: %s", result)
+	}
+
+	// The first flush goes out as JSON, the second as a compressed batch.
+	for _, encoding := range []string{"json", "compressed"} {
+		t.Run(encoding, func(t *testing.T) {
+			want := make(map[string]map[string]interface{})
+			for _, ev := range contractEvents() {
+				data, err := json.Marshal(ev.properties)
+				if err != nil {
+					t.Fatalf("marshal %s: %v", ev.eventType, err)
+				}
+				if result := TrackTyped(ev.eventType, string(data)); result != "" {
+					t.Fatalf("TrackTyped(%s) returned error: %s", ev.eventType, result)
+				}
+
+				var props map[string]interface{}
+				if err := json.Unmarshal(data, &props); err != nil {
+					t.Fatalf("unmarshal %s: %v", ev.eventType, err)
+				}
+				want[ev.eventType] = props
+			}
+
+			if result := Flush(); result != "" {
+				t.Fatalf("Flush returned error: %s", result)
+			}
+
+			got := pub.take()
+			if len(got) != len(want) {
+				t.Fatalf("gateway accepted %d events, want %d", len(got), len(want))
+			}
+
+			for _, env := range got {
+				eventType, payload := contractPayload(t, env)
+				props, ok := want[eventType]
+				if !ok {
+					t.Errorf("unexpected %s event from gateway", eventType)
+					continue
+				}
+				assertContract(t, eventType, props, payload)
+
+				if eventType == EventTypeAppCrash && len(env.GetAppCrash().GetBreadcrumbs()) == 0 {
+					t.Error("app_crash lost its breadcrumbs")
+				}
+			}
+		})
+	}
+}
+
+// contractPayload returns the SDK event type of an envelope and its payload
+// rendered with proto field names. Custom event parameters are flattened so
+// they compare against the tracked properties.
+func contractPayload(t *testing.T, env *pb.EventEnvelope) (string, map[string]interface{}) {
+	t.Helper()
+
+	m := env.ProtoReflect()
+	fd := m.WhichOneof(m.Descriptor().Oneofs().ByName("payload"))
+	if fd == nil {
+		t.Fatalf("envelope %s has no payload", env.GetIdempotencyKey())
+	}
+
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(m.Get(fd).Message().Interface())
+	if err != nil {
+		t.Fatalf("marshal payload: %v", err)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+
+	eventType := string(fd.Name())
+	if eventType != "custom_event" {
+		return eventType, payload
+	}
+
+	flat := map[string]interface{}{"event_name": payload["event_name"]}
+	for _, key := range []string{"string_params", "int_params", "float_params", "bool_params"} {
+		params, _ := payload[key].(map[string]interface{})
+		for k, v := range params {
+			flat[k] = v
+		}
+	}
+	return EventTypeCustom, flat
+}
+
+// assertContract checks that every tracked property arrived in the payload
+// with its value.
+func assertContract(t *testing.T, eventType string, want, got map[string]interface{}) {
+	t.Helper()
+	for key, w := range want {
+		g, ok := got[key]
+		if !ok {
+			t.Errorf("%s.%s was dropped converting to proto", eventType, key)
+			continue
+		}
+		if !contractEqual(w, g) {
+			t.Errorf("%s.%s = %v in proto, want %v", eventType, key, g, w)
+		}
+	}
+}
+
+// contractEqual compares a tracked value with its protojson rendering, where
+// 64-bit integers are strings and enums carry their type prefix
+// ("wifi" becomes "NETWORK_TYPE_WIFI").
+func contractEqual(want, got interface{}) bool {
+	w, g := contractString(want), contractString(got)
+	if w == g {
+		return true
+	}
+	name, isString := want.(string)
+	return isString && strings.HasSuffix(g, "_"+strings.ToUpper(name))
+}
+
+func contractString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
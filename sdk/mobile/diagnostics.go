@@ -0,0 +1,135 @@
+package mobile
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/SebastienMelki/causality/sdk/mobile/internal/batch"
+)
+
+// Diagnostics is a point-in-time snapshot of SDK delivery health, returned
+// as JSON by GetDiagnostics for native debug screens and automated QA.
+type Diagnostics struct {
+	// Initialized is false when the SDK has not been initialized; all other
+	// fields are then zero.
+	Initialized bool `json:"initialized"`
+
+	// Consent is the current consent level (empty if undetermined).
+	Consent string `json:"consent"`
+
+	// QueueDepth is the number of events waiting to be sent.
+	QueueDepth int `json:"queue_depth"`
+
+	// OldestEventAgeMs is the age of the oldest queued event (0 if empty).
+	OldestEventAgeMs int64 `json:"oldest_event_age_ms"`
+
+	// LastFlush describes the most recent batch send, if any.
+	LastFlush *FlushDiagnostics `json:"last_flush,omitempty"`
+
+	// FlushCount and FlushFailures count batch sends since Init.
+	FlushCount    int64 `json:"flush_count"`
+	FlushFailures int64 `json:"flush_failures"`
+
+	// DeduplicatedCount is the number of delivered events the server had
+	// already stored (e.g. resent after a lost response).
+	DeduplicatedCount int64 `json:"deduplicated_count"`
+
+	// ActiveEndpoint is the server URL events are sent to: the configured
+	// endpoint, or a fallback endpoint after a failover.
+	ActiveEndpoint string `json:"active_endpoint"`
+
+	// BytesSent is the total request body bytes delivered since Init.
+	BytesSent int64 `json:"bytes_sent"`
+
+	// ClockSkewMs is the estimated device clock minus server time that
+	// event timestamps are corrected by (0 if unmeasured or disabled).
+	ClockSkewMs int64 `json:"clock_skew_ms"`
+
+	// DuplicatesDropped is the number of tracked events dropped as exact
+	// duplicates within the dedup window since Init.
+	DuplicatesDropped int64 `json:"duplicates_dropped"`
+
+	// Dropped counts events dropped since Init, keyed by reason
+	// ("max_retries", "max_age", "rate_limited").
+	Dropped map[string]int64 `json:"dropped"`
+
+	// Errors lists diagnostics that could not be collected.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// FlushDiagnostics describes a single batch send.
+type FlushDiagnostics struct {
+	// AtMs is the Unix millisecond timestamp of the send.
+	AtMs int64 `json:"at_ms"`
+
+	// Events is the number of events in the batch.
+	Events int `json:"events"`
+
+	// Success is true if the batch was delivered.
+	Success bool `json:"success"`
+
+	// Error is the delivery error, empty on success.
+	Error string `json:"error,omitempty"`
+}
+
+// GetDiagnostics returns a JSON snapshot of queue depth, oldest event age,
+// the last flush result, bytes sent, and dropped event counts.
+// Returns {"initialized":false,...} if the SDK is not initialized.
+func GetDiagnostics() string {
+	return marshalDiagnostics(collectDiagnostics(getInstance(), time.Now()))
+}
+
+// marshalDiagnostics serializes diag for native wrapper consumption.
+func marshalDiagnostics(diag *Diagnostics) string {
+	data, err := json.Marshal(diag)
+	if err != nil {
+		return `{"initialized":false}`
+	}
+	return string(data)
+}
+
+// collectDiagnostics gathers diagnostics from an SDK instance. A nil
+// instance yields an uninitialized snapshot.
+func collectDiagnostics(inst *Client, now time.Time) *Diagnostics {
+	diag := &Diagnostics{Dropped: map[string]int64{}}
+	if inst == nil {
+		return diag
+	}
+	diag.Initialized = true
+	diag.Consent = inst.GetConsent()
+
+	if depth, err := inst.queue.Count(); err != nil {
+		diag.Errors = append(diag.Errors, err.Error())
+	} else {
+		diag.QueueDepth = depth
+	}
+
+	if oldest, err := inst.queue.OldestCreatedAt(); err != nil {
+		diag.Errors = append(diag.Errors, err.Error())
+	} else if oldest > 0 {
+		diag.OldestEventAgeMs = max(now.UnixMilli()-oldest, 0)
+	}
+
+	stats := inst.batcher.Stats()
+	diag.FlushCount = stats.Sends
+	diag.FlushFailures = stats.SendFailures
+	diag.DeduplicatedCount = stats.Deduplicated
+	diag.Dropped[batch.DropReasonMaxRetries] = stats.DroppedMaxRetries
+	diag.Dropped[batch.DropReasonMaxAge] = stats.DroppedMaxAge
+	diag.Dropped[batch.DropReasonRateLimited] = stats.DroppedRateLimited
+	if !stats.LastSendAt.IsZero() {
+		diag.LastFlush = &FlushDiagnostics{
+			AtMs:    stats.LastSendAt.UnixMilli(),
+			Events:  stats.LastSendEvents,
+			Success: stats.LastSendError == "",
+			Error:   stats.LastSendError,
+		}
+	}
+
+	diag.ActiveEndpoint = inst.transportClient.ActiveEndpoint()
+	diag.BytesSent = inst.transportClient.BytesSent()
+	diag.DuplicatesDropped = inst.dedup.Dropped()
+	diag.ClockSkewMs = inst.clock.SkewMs()
+
+	return diag
+}
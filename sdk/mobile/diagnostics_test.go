@@ -0,0 +1,55 @@
+package mobile
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGetDiagnostics_NotInitialized(t *testing.T) {
+	resetForTesting()
+	defer resetForTesting()
+
+	var diag Diagnostics
+	if err := json.Unmarshal([]byte(GetDiagnostics()), &diag); err != nil {
+		t.Fatalf("invalid diagnostics JSON: %v", err)
+	}
+	if diag.Initialized {
+		t.Error("Initialized = true before Init")
+	}
+}
+
+func TestGetDiagnostics_ReportsQueue(t *testing.T) {
+	resetForTesting()
+	defer resetForTesting()
+
+	if result := Init(validConfigJSON()); result != "" {
+		t.Fatalf("Init returned error: %s", result)
+	}
+	if result := Track(`{"type": "screen_view", "properties": {"screen_name": "Home"}}`); result != "" {
+		t.Fatalf("Track returned error: %s", result)
+	}
+
+	var diag Diagnostics
+	if err := json.Unmarshal([]byte(GetDiagnostics()), &diag); err != nil {
+		t.Fatalf("invalid diagnostics JSON: %v", err)
+	}
+
+	if !diag.Initialized {
+		t.Error("Initialized = false after Init")
+	}
+	if diag.QueueDepth != 1 {
+		t.Errorf("QueueDepth = %d, want 1", diag.QueueDepth)
+	}
+	if diag.OldestEventAgeMs < 0 {
+		t.Errorf("OldestEventAgeMs = %d, want >= 0", diag.OldestEventAgeMs)
+	}
+	if diag.LastFlush != nil {
+		t.Errorf("LastFlush = %+v, want nil before any flush", diag.LastFlush)
+	}
+	if _, ok := diag.Dropped["max_retries"]; !ok {
+		t.Error("Dropped should report max_retries")
+	}
+	if len(diag.Errors) != 0 {
+		t.Errorf("Errors = %v, want none", diag.Errors)
+	}
+}
@@ -2,6 +2,7 @@ package mobile
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 )
@@ -49,6 +50,8 @@ const (
 	ErrCodeQueueFull      = "QUEUE_FULL"
 	ErrCodeServerError    = "SERVER_ERROR"
 	ErrCodeRateLimited    = "RATE_LIMITED"
+	ErrCodePinMismatch    = "PIN_MISMATCH"
+	ErrCodeSDKOutdated    = "SDK_OUTDATED"
 )
 
 // SDKError represents a structured error with severity and code.
@@ -56,6 +59,10 @@ type SDKError struct {
 	Code     string        `json:"code"`
 	Message  string        `json:"message"`
 	Severity ErrorSeverity `json:"severity"`
+
+	// Problems lists every problem found in the config for INVALID_CONFIG
+	// errors from config validation.
+	Problems []ConfigProblem `json:"problems,omitempty"`
 }
 
 // Error implements the error interface.
@@ -92,6 +99,17 @@ func newFatalError(code, message string) *SDKError {
 	return &SDKError{Code: code, Message: message, Severity: SeverityFatal}
 }
 
+// newInvalidConfigError creates a fatal error for a config that failed to
+// parse, carrying the problems of a *ConfigError.
+func newInvalidConfigError(err error) *SDKError {
+	sdkErr := newFatalError(ErrCodeInvalidConfig, err.Error())
+	var cfgErr *ConfigError
+	if errors.As(err, &cfgErr) {
+		sdkErr.Problems = cfgErr.Problems
+	}
+	return sdkErr
+}
+
 // Convenience constructors for common error scenarios.
 // These are used by the HTTP transport (Plan 02-05) and persistence layer (Plan 02-02).
 
@@ -120,6 +138,25 @@ func newQueueFullError(message string) *SDKError {
 	return newWarningError(ErrCodeQueueFull, message)
 }
 
+// newPinMismatchError creates a certificate pinning failure error.
+func newPinMismatchError(message string) *SDKError {
+	return newCriticalError(ErrCodePinMismatch, message)
+}
+
+// newSDKOutdatedError creates the error reporting that the server requires
+// at least minVersion of the SDK, which is at version: critical if it
+// rejects batches from this version, a warning if it only flags them.
+func newSDKOutdatedError(version, minVersion string, rejected bool) *SDKError {
+	if rejected {
+		return newCriticalError(ErrCodeSDKOutdated, fmt.Sprintf(
+			"SDK %s is no longer accepted by the server, which requires %s or later; upgrade the app to resume sending events",
+			version, minVersion))
+	}
+	return newWarningError(ErrCodeSDKOutdated, fmt.Sprintf(
+		"SDK %s is older than the minimum version %s set for this app; upgrade the app",
+		version, minVersion))
+}
+
 // newNetworkError creates a network connectivity error.
 func newNetworkError(message string) *SDKError {
 	return newWarningError(ErrCodeNetworkError, message)
@@ -132,6 +132,54 @@ type AppForegroundEvent struct {
 	ResumeScreen         string `json:"resume_screen,omitempty"`
 }
 
+// AppCrashEvent represents an app crash. The SDK attaches the trail of
+// recently tracked events as breadcrumbs unless disable_breadcrumbs is set.
+// Proto equivalent: causality.v1.AppCrash
+type AppCrashEvent struct {
+	CrashType     string `json:"crash_type,omitempty"`
+	CrashMessage  string `json:"crash_message,omitempty"`
+	StackTrace    string `json:"stack_trace,omitempty"`
+	CurrentScreen string `json:"current_screen,omitempty"`
+}
+
+// NetworkChangeEvent represents a change of the device's network type.
+// Emitted automatically when auto_track_network is enabled.
+// Proto equivalent: causality.v1.NetworkChange
+type NetworkChangeEvent struct {
+	PreviousType string `json:"previous_type,omitempty"`
+	CurrentType  string `json:"current_type,omitempty"`
+}
+
+// BatteryChangeEvent represents a battery level or state change.
+// Emitted automatically when auto_track_battery is enabled.
+// Proto equivalent: causality.v1.BatteryChange
+type BatteryChangeEvent struct {
+	BatteryLevel int32  `json:"battery_level"`
+	State        string `json:"state,omitempty"`
+}
+
+// PushTokenUpdateEvent reports the device's push notification token.
+// Emitted by SetPushToken.
+// Proto equivalent: causality.v1.PushTokenUpdate
+type PushTokenUpdateEvent struct {
+	Token    string `json:"token"`
+	Provider string `json:"provider"`
+}
+
+// Push notification providers accepted by SetPushToken.
+const (
+	PushProviderFCM  = "fcm"
+	PushProviderAPNs = "apns"
+)
+
+// ExperimentExposureEvent records that the user was exposed to a variant of
+// an experiment or feature flag.
+// Proto equivalent: causality.v1.ExperimentExposure
+type ExperimentExposureEvent struct {
+	ExperimentID string `json:"experiment_id"`
+	Variant      string `json:"variant"`
+}
+
 // CustomEvent represents a user-defined event with arbitrary properties.
 // Proto equivalent: causality.v1.CustomEvent
 type CustomEvent struct {
@@ -154,6 +202,11 @@ const (
 	EventTypeAppStart         = "app_start"
 	EventTypeAppBackground    = "app_background"
 	EventTypeAppForeground    = "app_foreground"
+	EventTypeAppCrash         = "app_crash"
+	EventTypeNetworkChange    = "network_change"
+	EventTypeBatteryChange    = "battery_change"
+	EventTypePushTokenUpdate  = "push_token_update"
+	EventTypeExperimentExposure = "experiment_exposure"
 	EventTypeCustom           = "custom"
 )
 
@@ -171,6 +224,11 @@ var validEventTypes = map[string]bool{
 	EventTypeAppStart:         true,
 	EventTypeAppBackground:    true,
 	EventTypeAppForeground:    true,
+	EventTypeAppCrash:         true,
+	EventTypeNetworkChange:    true,
+	EventTypeBatteryChange:    true,
+	EventTypePushTokenUpdate:  true,
+	EventTypeExperimentExposure: true,
 	EventTypeCustom:           true,
 }
 
@@ -0,0 +1,167 @@
+// Package autotrack turns network and battery updates reported by native
+// wrappers into network_change and battery_change events.
+//
+// Updates are debounced: an event is emitted once no update has arrived for
+// the debounce interval, so a flapping connection or a burst of battery
+// notifications produces one event describing the settled change.
+package autotrack
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultDebounce is the default quiet period before an event is emitted.
+const DefaultDebounce = 5 * time.Second
+
+// Event type names emitted by the tracker.
+const (
+	EventTypeNetworkChange = "network_change"
+	EventTypeBatteryChange = "battery_change"
+)
+
+// EmitFunc receives an event type and its properties.
+type EmitFunc func(eventType string, properties map[string]interface{})
+
+// battery is a battery reading.
+type battery struct {
+	level int
+	state string
+}
+
+// Tracker debounces network and battery updates into events.
+// It is safe for concurrent use by multiple goroutines.
+type Tracker struct {
+	mu sync.Mutex
+
+	debounce time.Duration
+	emit     EmitFunc
+	stopped  bool
+
+	// networkFrom is the network type before the pending burst of changes,
+	// and networkTo the latest one.
+	networkPending bool
+	networkFrom    string
+	networkTo      string
+	networkTimer   *time.Timer
+
+	batteryPending  bool
+	batteryLatest   battery
+	batteryEmitted  battery
+	batteryReported bool
+	batteryTimer    *time.Timer
+}
+
+// NewTracker creates a tracker that passes debounced events to emit.
+// If debounce is zero, DefaultDebounce is used.
+func NewTracker(debounce time.Duration, emit EmitFunc) *Tracker {
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+	return &Tracker{
+		debounce: debounce,
+		emit:     emit,
+	}
+}
+
+// NetworkChanged records a network type change. A network_change event from
+// the type before the first pending change to the latest type is emitted once
+// changes settle, unless the network ended up where it started.
+func (t *Tracker) NetworkChanged(previous, current string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped {
+		return
+	}
+
+	if !t.networkPending {
+		t.networkPending = true
+		t.networkFrom = previous
+	}
+	t.networkTo = current
+
+	if t.networkTimer != nil {
+		t.networkTimer.Stop()
+	}
+	t.networkTimer = time.AfterFunc(t.debounce, t.flushNetwork)
+}
+
+// BatteryChanged records a battery reading. A battery_change event with the
+// latest reading is emitted once readings settle, unless it matches the last
+// emitted one.
+func (t *Tracker) BatteryChanged(level int, state string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped {
+		return
+	}
+
+	t.batteryPending = true
+	t.batteryLatest = battery{level: level, state: state}
+
+	if t.batteryTimer != nil {
+		t.batteryTimer.Stop()
+	}
+	t.batteryTimer = time.AfterFunc(t.debounce, t.flushBattery)
+}
+
+// Stop cancels pending events. Later updates are ignored.
+func (t *Tracker) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stopped = true
+	if t.networkTimer != nil {
+		t.networkTimer.Stop()
+	}
+	if t.batteryTimer != nil {
+		t.batteryTimer.Stop()
+	}
+	t.networkPending = false
+	t.batteryPending = false
+}
+
+// flushNetwork emits the pending network change.
+func (t *Tracker) flushNetwork() {
+	t.mu.Lock()
+	if t.stopped || !t.networkPending {
+		t.mu.Unlock()
+		return
+	}
+	from, to := t.networkFrom, t.networkTo
+	t.networkPending = false
+	t.mu.Unlock()
+
+	if from == to {
+		return
+	}
+	t.emit(EventTypeNetworkChange, map[string]interface{}{
+		"previous_type": from,
+		"current_type":  to,
+	})
+}
+
+// flushBattery emits the pending battery reading.
+func (t *Tracker) flushBattery() {
+	t.mu.Lock()
+	if t.stopped || !t.batteryPending {
+		t.mu.Unlock()
+		return
+	}
+	latest := t.batteryLatest
+	t.batteryPending = false
+	if t.batteryReported && latest == t.batteryEmitted {
+		t.mu.Unlock()
+		return
+	}
+	t.batteryEmitted = latest
+	t.batteryReported = true
+	t.mu.Unlock()
+
+	t.emit(EventTypeBatteryChange, map[string]interface{}{
+		"battery_level": latest.level,
+		"state":         latest.state,
+	})
+}
@@ -0,0 +1,111 @@
+package autotrack
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+const testDebounce = 20 * time.Millisecond
+
+// recorder collects emitted events.
+type recorder struct {
+	mu     sync.Mutex
+	events []recordedEvent
+}
+
+type recordedEvent struct {
+	eventType  string
+	properties map[string]interface{}
+}
+
+func (r *recorder) emit(eventType string, properties map[string]interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, recordedEvent{eventType: eventType, properties: properties})
+}
+
+func (r *recorder) recorded() []recordedEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]recordedEvent(nil), r.events...)
+}
+
+// settle waits for pending debounced events.
+func settle() {
+	time.Sleep(5 * testDebounce)
+}
+
+func TestTracker_NetworkChangeDebounced(t *testing.T) {
+	rec := &recorder{}
+	tr := NewTracker(testDebounce, rec.emit)
+
+	tr.NetworkChanged("wifi", "offline")
+	tr.NetworkChanged("offline", "cellular")
+	settle()
+
+	events := rec.recorded()
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	if events[0].eventType != EventTypeNetworkChange {
+		t.Errorf("expected %s, got %s", EventTypeNetworkChange, events[0].eventType)
+	}
+	if events[0].properties["previous_type"] != "wifi" || events[0].properties["current_type"] != "cellular" {
+		t.Errorf("expected wifi -> cellular, got %v", events[0].properties)
+	}
+}
+
+func TestTracker_NetworkFlapBackIsDropped(t *testing.T) {
+	rec := &recorder{}
+	tr := NewTracker(testDebounce, rec.emit)
+
+	tr.NetworkChanged("wifi", "offline")
+	tr.NetworkChanged("offline", "wifi")
+	settle()
+
+	if events := rec.recorded(); len(events) != 0 {
+		t.Errorf("expected no events for a flap back to wifi, got %v", events)
+	}
+}
+
+func TestTracker_BatteryChangeDebouncedAndDeduplicated(t *testing.T) {
+	rec := &recorder{}
+	tr := NewTracker(testDebounce, rec.emit)
+
+	tr.BatteryChanged(80, "discharging")
+	tr.BatteryChanged(79, "discharging")
+	settle()
+
+	tr.BatteryChanged(79, "discharging")
+	settle()
+
+	tr.BatteryChanged(79, "charging")
+	settle()
+
+	events := rec.recorded()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d: %v", len(events), events)
+	}
+	if events[0].properties["battery_level"] != 79 || events[0].properties["state"] != "discharging" {
+		t.Errorf("unexpected first event: %v", events[0].properties)
+	}
+	if events[1].properties["state"] != "charging" {
+		t.Errorf("unexpected second event: %v", events[1].properties)
+	}
+}
+
+func TestTracker_StopCancelsPendingEvents(t *testing.T) {
+	rec := &recorder{}
+	tr := NewTracker(testDebounce, rec.emit)
+
+	tr.NetworkChanged("wifi", "cellular")
+	tr.BatteryChanged(50, "charging")
+	tr.Stop()
+	tr.NetworkChanged("cellular", "wifi")
+	settle()
+
+	if events := rec.recorded(); len(events) != 0 {
+		t.Errorf("expected no events after Stop, got %v", events)
+	}
+}
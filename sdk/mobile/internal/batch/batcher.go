@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/SebastienMelki/causality/sdk/mobile/internal/storage"
@@ -29,24 +30,78 @@ type EventSender interface {
 	SendBatch(ctx context.Context, events []string) (*transport.SendResult, error)
 }
 
+// Reasons passed to the dropped-events callback.
+const (
+	// DropReasonMaxRetries means delivery failed more than the retry budget allows.
+	DropReasonMaxRetries = "max_retries"
+
+	// DropReasonMaxAge means the event was queued longer than the maximum age.
+	DropReasonMaxAge = "max_age"
+
+	// DropReasonRateLimited means the event was tracked beyond the rate
+	// limit and never queued.
+	DropReasonRateLimited = "rate_limited"
+)
+
 // Batcher batches events by count and time, whichever trigger fires first.
 // Events are enqueued to the persistent queue immediately, then dequeued
-// and sent in batches. Failed events remain in the queue for retry.
+// and sent in batches. Failed events remain in the queue for retry until
+// they exceed the retry budget or maximum age set by SetRetryLimits.
 type Batcher struct {
 	queue         EventQueue
 	sender        EventSender
 	batchSize     int
 	flushInterval time.Duration
 
-	mu           sync.Mutex
-	pendingCount int
-	lastFlush    time.Time
+	mu        sync.Mutex
+	lastFlush time.Time
+
+	pendingCount atomic.Int64 // events added since the last flush; atomic so Add never waits on a send
 
 	flushCh chan struct{} // signals an async flush request
 	stopCh  chan struct{} // signals stop
 	doneCh  chan struct{} // closed when flush loop exits
 
 	onError func(err error) // optional error callback
+
+	maxRetries int           // failed deliveries before an event is dropped; 0 = unlimited
+	maxAge     time.Duration // queue age after which an event is dropped; 0 = unlimited
+	dropped    atomic.Int64
+
+	throttle *throttle // nil = no rate limit
+
+	paused atomic.Bool // when set, flushes keep events queued without sending
+
+	statsMu   sync.Mutex // guards stats and onDropped; separate from mu so Stats and Add never wait on a send
+	stats     Stats
+	onDropped func(count int, reason string)
+}
+
+// Stats summarizes delivery activity since the Batcher was created.
+type Stats struct {
+	// Sends is the number of batches handed to the sender.
+	Sends int64
+
+	// SendFailures is the number of batches the sender failed to deliver.
+	SendFailures int64
+
+	// LastSendAt is when the last batch was sent (zero if none).
+	LastSendAt time.Time
+
+	// LastSendEvents is the number of events in the last batch.
+	LastSendEvents int
+
+	// LastSendError is the error of the last batch, empty on success.
+	LastSendError string
+
+	// Deduplicated counts delivered events the server had already stored.
+	Deduplicated int64
+
+	// DroppedMaxRetries, DroppedMaxAge and DroppedRateLimited count events
+	// dropped per reason. Rate limited drops are counted once reported.
+	DroppedMaxRetries  int64
+	DroppedMaxAge      int64
+	DroppedRateLimited int64
 }
 
 // NewBatcher creates a new Batcher that batches events by count and time.
@@ -80,25 +135,115 @@ func (b *Batcher) SetOnError(fn func(err error)) {
 	b.onError = fn
 }
 
+// SetRetryLimits bounds how long undeliverable events stay queued. Events
+// are dropped after maxRetries failed deliveries or once they are older than
+// maxAge. Zero disables the respective limit.
+func (b *Batcher) SetRetryLimits(maxRetries int, maxAge time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.maxRetries = maxRetries
+	b.maxAge = maxAge
+}
+
+// SetOnDropped sets an optional callback invoked with the number of events
+// dropped and the reason (DropReasonMaxRetries, DropReasonMaxAge or
+// DropReasonRateLimited).
+func (b *Batcher) SetOnDropped(fn func(count int, reason string)) {
+	b.statsMu.Lock()
+	defer b.statsMu.Unlock()
+	b.onDropped = fn
+}
+
+// SetRateLimit limits AddThrottled to perSecond events on average, with
+// bursts of up to burst events. Events beyond the limit are dropped and
+// reported to the dropped-events callback at most once per second, so a
+// runaway tracking loop cannot flood the queue or the server. Add is never
+// limited but uses up the same budget. Zero perSecond disables the limit.
+// Call it before adding events.
+func (b *Batcher) SetRateLimit(perSecond float64, burst int) {
+	b.throttle = newThrottle(perSecond, burst)
+}
+
+// DroppedCount returns the total number of events dropped by retry and rate
+// limits.
+func (b *Batcher) DroppedCount() int64 {
+	return b.dropped.Load()
+}
+
+// SetPaused stops or resumes sending. While paused, events are still
+// enqueued and flushes leave them in the queue. Resuming requests a flush.
+func (b *Batcher) SetPaused(paused bool) {
+	if b.paused.Swap(paused) && !paused {
+		b.RequestFlush()
+	}
+}
+
+// RequestFlush asks the flush loop to flush soon without blocking.
+func (b *Batcher) RequestFlush() {
+	select {
+	case b.flushCh <- struct{}{}:
+	default:
+		// Flush already pending, skip
+	}
+}
+
+// Stats returns a snapshot of delivery statistics.
+func (b *Batcher) Stats() Stats {
+	b.statsMu.Lock()
+	defer b.statsMu.Unlock()
+	return b.stats
+}
+
+// recordSend updates the statistics after a send attempt.
+func (b *Batcher) recordSend(events int, result *transport.SendResult, err error) {
+	b.statsMu.Lock()
+	defer b.statsMu.Unlock()
+	if result != nil {
+		b.stats.Deduplicated += int64(result.Deduplicated)
+	}
+	b.stats.Sends++
+	b.stats.LastSendAt = time.Now()
+	b.stats.LastSendEvents = events
+	b.stats.LastSendError = ""
+	if err != nil {
+		b.stats.SendFailures++
+		b.stats.LastSendError = err.Error()
+	}
+}
+
 // Add enqueues an event to the persistent queue and checks if a
-// batch-size flush should be triggered. This method is non-blocking.
+// batch-size flush should be triggered. This method is non-blocking: it does
+// not wait for an in-progress flush.
 func (b *Batcher) Add(eventJSON, idempotencyKey string) error {
+	b.throttle.take()
+	return b.add(eventJSON, idempotencyKey)
+}
+
+// AddThrottled is Add for low-priority events: beyond the rate limit set by
+// SetRateLimit, the event is dropped and ErrRateLimited returned.
+func (b *Batcher) AddThrottled(eventJSON, idempotencyKey string) error {
+	if !b.throttle.allow() {
+		b.reportThrottled(false)
+		return ErrRateLimited
+	}
+	return b.add(eventJSON, idempotencyKey)
+}
+
+// reportThrottled reports the rate limited drops that are due, or all of
+// them if force is set.
+func (b *Batcher) reportThrottled(force bool) {
+	if n := b.throttle.due(force); n > 0 {
+		b.recordDropped(n, DropReasonRateLimited)
+	}
+}
+
+func (b *Batcher) add(eventJSON, idempotencyKey string) error {
 	if err := b.queue.Enqueue(eventJSON, idempotencyKey); err != nil {
 		return fmt.Errorf("enqueue event: %w", err)
 	}
 
-	b.mu.Lock()
-	b.pendingCount++
-	shouldFlush := b.pendingCount >= b.batchSize
-	b.mu.Unlock()
-
-	if shouldFlush {
-		// Non-blocking send to flush channel
-		select {
-		case b.flushCh <- struct{}{}:
-		default:
-			// Flush already pending, skip
-		}
+	if b.pendingCount.Add(1) >= int64(b.batchSize) {
+		b.RequestFlush()
 	}
 
 	return nil
@@ -114,15 +259,63 @@ func (b *Batcher) Flush(ctx context.Context) error {
 	return b.flushLocked(ctx)
 }
 
+// Drain sends batches until the queue is empty, a send fails, or ctx is done,
+// whichever comes first. It reports whether sendable events remain queued, so
+// a caller with a bounded time budget knows whether to schedule another run.
+// Nothing is sent while paused, and more is false since a retry would not
+// send anything either.
+func (b *Batcher) Drain(ctx context.Context) (more bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.paused.Load() {
+		return false, nil
+	}
+
+	queued, err := b.queue.Count()
+	if err != nil {
+		return true, fmt.Errorf("count queued events: %w", err)
+	}
+
+	for queued > 0 {
+		if ctx.Err() != nil {
+			return true, nil
+		}
+
+		flushErr := b.flushLocked(ctx)
+
+		remaining, err := b.queue.Count()
+		if err != nil {
+			return true, fmt.Errorf("count queued events: %w", err)
+		}
+		if flushErr != nil {
+			return remaining > 0, flushErr
+		}
+		if remaining >= queued {
+			// Nothing left the queue; stop rather than spin.
+			return true, nil
+		}
+		queued = remaining
+	}
+
+	return false, nil
+}
+
 // flushLocked performs the actual flush. Caller must hold b.mu.
 func (b *Batcher) flushLocked(ctx context.Context) error {
-	events, err := b.queue.DequeueBatch(b.batchSize)
+	b.reportThrottled(true)
+
+	if b.paused.Load() {
+		return nil
+	}
+
+	events, err := b.dequeueLive()
 	if err != nil {
-		return fmt.Errorf("dequeue batch: %w", err)
+		return err
 	}
 
 	if len(events) == 0 {
-		b.pendingCount = 0
+		b.pendingCount.Store(0)
 		b.lastFlush = time.Now()
 		return nil
 	}
@@ -134,38 +327,128 @@ func (b *Batcher) flushLocked(ctx context.Context) error {
 	}
 
 	// Send batch
-	_, sendErr := b.sender.SendBatch(ctx, payloads)
+	result, sendErr := b.sender.SendBatch(ctx, payloads)
+	b.recordSend(len(payloads), result, sendErr)
 	if sendErr != nil {
-		// Mark each event for retry (increment retry_count)
-		for _, e := range events {
-			if markErr := b.queue.MarkRetry(e.ID); markErr != nil {
-				// Log but don't fail: event stays in queue either way
-				if b.onError != nil {
-					b.onError(fmt.Errorf("mark retry for event %d: %w", e.ID, markErr))
-				}
-			}
-		}
-
+		b.markRetry(events)
 		b.lastFlush = time.Now()
 		return fmt.Errorf("send batch: %w", sendErr)
 	}
 
-	// Delete successfully sent events
-	ids := make([]int64, len(events))
+	// Keep events the server did not publish in time, delete the rest
+	retry := make(map[int]bool, len(result.Retry))
+	for _, i := range result.Retry {
+		retry[i] = true
+	}
+	ids := make([]int64, 0, len(events))
+	var unsent []storage.QueuedEvent
 	for i, e := range events {
-		ids[i] = e.ID
+		if retry[i] {
+			unsent = append(unsent, e)
+			continue
+		}
+		ids = append(ids, e.ID)
 	}
 
 	if delErr := b.queue.Delete(ids); delErr != nil {
 		return fmt.Errorf("delete sent events: %w", delErr)
 	}
+	b.markRetry(unsent)
 
-	b.pendingCount = 0
+	b.pendingCount.Store(0)
 	b.lastFlush = time.Now()
 
 	return nil
 }
 
+// markRetry returns events to the queue for another attempt (incrementing
+// retry_count), dropping the ones that have used up their retry budget.
+func (b *Batcher) markRetry(events []storage.QueuedEvent) {
+	var exhausted []int64
+	for _, e := range events {
+		if b.maxRetries > 0 && e.RetryCount+1 >= b.maxRetries {
+			exhausted = append(exhausted, e.ID)
+			continue
+		}
+		if markErr := b.queue.MarkRetry(e.ID); markErr != nil {
+			// Log but don't fail: event stays in queue either way
+			if b.onError != nil {
+				b.onError(fmt.Errorf("mark retry for event %d: %w", e.ID, markErr))
+			}
+		}
+	}
+
+	if err := b.drop(exhausted, DropReasonMaxRetries); err != nil && b.onError != nil {
+		b.onError(err)
+	}
+}
+
+// dequeueLive dequeues the next batch, dropping events older than maxAge.
+// It keeps dequeuing while whole batches expire so that a backlog of stale
+// events does not delay delivery of fresh ones.
+func (b *Batcher) dequeueLive() ([]storage.QueuedEvent, error) {
+	for {
+		events, err := b.queue.DequeueBatch(b.batchSize)
+		if err != nil {
+			return nil, fmt.Errorf("dequeue batch: %w", err)
+		}
+		if b.maxAge <= 0 || len(events) == 0 {
+			return events, nil
+		}
+
+		cutoff := time.Now().Add(-b.maxAge).UnixMilli()
+		live := events[:0]
+		var expired []int64
+		for _, e := range events {
+			if e.CreatedAt < cutoff {
+				expired = append(expired, e.ID)
+			} else {
+				live = append(live, e)
+			}
+		}
+
+		if err := b.drop(expired, DropReasonMaxAge); err != nil {
+			return nil, err
+		}
+		if len(live) > 0 || len(expired) == 0 {
+			return live, nil
+		}
+	}
+}
+
+// drop deletes events that exceeded a retry limit and reports them.
+func (b *Batcher) drop(ids []int64, reason string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := b.queue.Delete(ids); err != nil {
+		return fmt.Errorf("drop %s events: %w", reason, err)
+	}
+
+	b.recordDropped(len(ids), reason)
+	return nil
+}
+
+// recordDropped counts dropped events and reports them to the callback.
+func (b *Batcher) recordDropped(count int, reason string) {
+	b.dropped.Add(int64(count))
+	b.statsMu.Lock()
+	switch reason {
+	case DropReasonMaxRetries:
+		b.stats.DroppedMaxRetries += int64(count)
+	case DropReasonMaxAge:
+		b.stats.DroppedMaxAge += int64(count)
+	case DropReasonRateLimited:
+		b.stats.DroppedRateLimited += int64(count)
+	}
+	onDropped := b.onDropped
+	b.statsMu.Unlock()
+
+	if onDropped != nil {
+		onDropped(count, reason)
+	}
+}
+
 // Stop signals the flush loop to stop and waits for it to exit.
 // It performs a final flush attempt before returning.
 func (b *Batcher) Stop() {
@@ -2,6 +2,7 @@ package batch
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
@@ -372,6 +373,30 @@ func TestFlush_KeepsFailedEvents(t *testing.T) {
 	}
 }
 
+func TestFlush_KeepsPublishTimeoutEvents(t *testing.T) {
+	q := newMockQueue()
+	s := newMockSender()
+	s.result.Retry = []int{1}
+	b := NewBatcher(q, s, 100, 1*time.Minute)
+
+	q.Enqueue(`{"type":"e1"}`, "k1")
+	q.Enqueue(`{"type":"e2"}`, "k2")
+	q.Enqueue(`{"type":"e3"}`, "k3")
+
+	if err := b.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Only the event the server did not publish in time stays queued
+	remaining := q.getEvents()
+	if len(remaining) != 1 || remaining[0].IdempotencyKey != "k2" {
+		t.Fatalf("remaining events: got %v, want only k2", remaining)
+	}
+	if remaining[0].RetryCount != 1 {
+		t.Errorf("retry count: got %d, want 1", remaining[0].RetryCount)
+	}
+}
+
 func TestFlush_DequeueError(t *testing.T) {
 	q := newMockQueue()
 	q.dequeueErr = fmt.Errorf("db error")
@@ -585,3 +610,280 @@ func TestContextCancellation_StopsFlushLoop(t *testing.T) {
 		t.Fatal("flush loop did not exit after context cancellation")
 	}
 }
+
+func TestFlush_DropsEventsAfterMaxRetries(t *testing.T) {
+	q := newMockQueue()
+	s := newMockSender()
+	s.err = fmt.Errorf("network error")
+	b := NewBatcher(q, s, 100, 1*time.Minute)
+	b.SetRetryLimits(2, 0)
+
+	var droppedCount int
+	var droppedReason string
+	b.SetOnDropped(func(count int, reason string) {
+		droppedCount += count
+		droppedReason = reason
+	})
+
+	q.Enqueue(`{"type":"e1"}`, "k1")
+	q.Enqueue(`{"type":"e2"}`, "k2")
+
+	// First failure: events are kept for retry.
+	_ = b.Flush(context.Background())
+	if got := len(q.getEvents()); got != 2 {
+		t.Fatalf("remaining after first failure: got %d, want 2", got)
+	}
+
+	// Second failure exhausts the retry budget.
+	_ = b.Flush(context.Background())
+	if got := len(q.getEvents()); got != 0 {
+		t.Errorf("remaining after second failure: got %d, want 0", got)
+	}
+	if droppedCount != 2 || droppedReason != DropReasonMaxRetries {
+		t.Errorf("dropped callback: got (%d, %q), want (2, %q)", droppedCount, droppedReason, DropReasonMaxRetries)
+	}
+	if got := b.DroppedCount(); got != 2 {
+		t.Errorf("DroppedCount: got %d, want 2", got)
+	}
+}
+
+func TestFlush_DropsEventsOlderThanMaxAge(t *testing.T) {
+	q := newMockQueue()
+	s := newMockSender()
+	b := NewBatcher(q, s, 5, 1*time.Minute)
+	b.SetRetryLimits(0, time.Hour)
+
+	var droppedReason string
+	b.SetOnDropped(func(count int, reason string) {
+		droppedReason = reason
+	})
+
+	// A full batch of stale events followed by a fresh one.
+	for i := 0; i < 6; i++ {
+		q.Enqueue(fmt.Sprintf(`{"type":"e%d"}`, i), fmt.Sprintf("k%d", i))
+	}
+	q.mu.Lock()
+	for i := 0; i < 5; i++ {
+		q.events[i].CreatedAt = time.Now().Add(-2 * time.Hour).UnixMilli()
+	}
+	q.mu.Unlock()
+
+	if err := b.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	batch := s.getLastBatch()
+	if len(batch) != 1 || batch[0] != `{"type":"e5"}` {
+		t.Errorf("sent batch: got %v, want only the fresh event", batch)
+	}
+	if got := b.DroppedCount(); got != 5 {
+		t.Errorf("DroppedCount: got %d, want 5", got)
+	}
+	if droppedReason != DropReasonMaxAge {
+		t.Errorf("drop reason: got %q, want %q", droppedReason, DropReasonMaxAge)
+	}
+	if got := len(q.getEvents()); got != 0 {
+		t.Errorf("remaining events: got %d, want 0", got)
+	}
+}
+
+func TestStats_RecordsSends(t *testing.T) {
+	q := newMockQueue()
+	s := newMockSender()
+	b := NewBatcher(q, s, 100, 1*time.Minute)
+
+	q.Enqueue(`{"type":"e1"}`, "k1")
+	q.Enqueue(`{"type":"e2"}`, "k2")
+	if err := b.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stats := b.Stats()
+	if stats.Sends != 1 || stats.SendFailures != 0 || stats.LastSendEvents != 2 || stats.LastSendError != "" {
+		t.Errorf("after success: got %+v", stats)
+	}
+
+	s.mu.Lock()
+	s.err = fmt.Errorf("network error")
+	s.mu.Unlock()
+	q.Enqueue(`{"type":"e3"}`, "k3")
+	_ = b.Flush(context.Background())
+
+	stats = b.Stats()
+	if stats.Sends != 2 || stats.SendFailures != 1 || stats.LastSendError != "network error" {
+		t.Errorf("after failure: got %+v", stats)
+	}
+	if stats.LastSendAt.IsZero() {
+		t.Error("LastSendAt should be set")
+	}
+}
+
+func TestFlush_PausedKeepsEvents(t *testing.T) {
+	q := newMockQueue()
+	s := newMockSender()
+	b := NewBatcher(q, s, 100, 1*time.Minute)
+	b.SetPaused(true)
+
+	q.Enqueue(`{"type":"e1"}`, "k1")
+	if err := b.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.getCalls() != 0 {
+		t.Errorf("send calls while paused: got %d, want 0", s.getCalls())
+	}
+	if got := len(q.getEvents()); got != 1 {
+		t.Errorf("remaining events: got %d, want 1", got)
+	}
+
+	b.SetPaused(false)
+	if err := b.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.getCalls() != 1 {
+		t.Errorf("send calls after resume: got %d, want 1", s.getCalls())
+	}
+}
+
+func TestDrain_SendsAllBatches(t *testing.T) {
+	q := newMockQueue()
+	s := newMockSender()
+	b := NewBatcher(q, s, 5, 1*time.Minute)
+
+	for i := 0; i < 12; i++ {
+		q.Enqueue(fmt.Sprintf(`{"n":%d}`, i), fmt.Sprintf("k-%d", i))
+	}
+
+	more, err := b.Drain(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if more {
+		t.Error("more: got true, want false after draining the queue")
+	}
+	if s.getCalls() != 3 {
+		t.Errorf("send calls: got %d, want 3", s.getCalls())
+	}
+	if got := len(q.getEvents()); got != 0 {
+		t.Errorf("remaining events: got %d, want 0", got)
+	}
+}
+
+func TestDrain_StopsAtDeadline(t *testing.T) {
+	q := newMockQueue()
+	s := newMockSender()
+	b := NewBatcher(q, s, 5, 1*time.Minute)
+
+	for i := 0; i < 10; i++ {
+		q.Enqueue(fmt.Sprintf(`{"n":%d}`, i), fmt.Sprintf("k-%d", i))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	more, err := b.Drain(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !more {
+		t.Error("more: got false, want true when the deadline passed")
+	}
+	if s.getCalls() != 0 {
+		t.Errorf("send calls: got %d, want 0", s.getCalls())
+	}
+}
+
+func TestDrain_SendFailureLeavesMore(t *testing.T) {
+	q := newMockQueue()
+	s := newMockSender()
+	s.err = errors.New("offline")
+	b := NewBatcher(q, s, 5, 1*time.Minute)
+
+	for i := 0; i < 7; i++ {
+		q.Enqueue(fmt.Sprintf(`{"n":%d}`, i), fmt.Sprintf("k-%d", i))
+	}
+
+	more, err := b.Drain(context.Background())
+	if err == nil {
+		t.Fatal("expected send error")
+	}
+	if !more {
+		t.Error("more: got false, want true after a failed send")
+	}
+	if s.getCalls() != 1 {
+		t.Errorf("send calls: got %d, want 1", s.getCalls())
+	}
+}
+
+func TestDrain_PausedHasNoMore(t *testing.T) {
+	q := newMockQueue()
+	s := newMockSender()
+	b := NewBatcher(q, s, 5, 1*time.Minute)
+	b.SetPaused(true)
+
+	q.Enqueue(`{"type":"e1"}`, "k1")
+	more, err := b.Drain(context.Background())
+	if err != nil || more {
+		t.Errorf("Drain while paused: got (%v, %v), want (false, nil)", more, err)
+	}
+	if s.getCalls() != 0 {
+		t.Errorf("send calls while paused: got %d, want 0", s.getCalls())
+	}
+}
+
+func TestAddThrottled_DropsBeyondRateLimit(t *testing.T) {
+	q := newMockQueue()
+	s := newMockSender()
+	b := NewBatcher(q, s, 100, 1*time.Minute)
+	b.SetRateLimit(1, 3)
+	now := time.Unix(1_700_000_000, 0)
+	b.throttle.now = func() time.Time { return now }
+
+	var reports []int
+	b.SetOnDropped(func(count int, reason string) {
+		if reason != DropReasonRateLimited {
+			t.Errorf("dropped reason: got %q, want %q", reason, DropReasonRateLimited)
+		}
+		reports = append(reports, count)
+	})
+
+	// A high-priority event uses up one token of the burst of 3.
+	if err := b.Add(`{"type":"app_start"}`, "k0"); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	for i := 1; i <= 10; i++ {
+		err := b.AddThrottled(fmt.Sprintf(`{"type":"e%d"}`, i), fmt.Sprintf("k%d", i))
+		if i <= 2 && err != nil {
+			t.Fatalf("AddThrottled(%d): %v", i, err)
+		}
+		if i > 2 && !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("AddThrottled(%d): got %v, want ErrRateLimited", i, err)
+		}
+	}
+	// High-priority events are never dropped.
+	if err := b.Add(`{"type":"purchase_complete"}`, "k11"); err != nil {
+		t.Fatalf("Add beyond limit: %v", err)
+	}
+	if got := len(q.getEvents()); got != 4 {
+		t.Errorf("queued: got %d, want 4", got)
+	}
+
+	// The first drop is reported at once, the rest are coalesced.
+	if len(reports) != 1 || reports[0] != 1 {
+		t.Fatalf("reports before flush: got %v, want [1]", reports)
+	}
+	if err := b.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if len(reports) != 2 || reports[1] != 7 {
+		t.Errorf("reports after flush: got %v, want [1 7]", reports)
+	}
+	if got := b.Stats().DroppedRateLimited; got != 8 {
+		t.Errorf("DroppedRateLimited: got %d, want 8", got)
+	}
+
+	// Tokens refill over time.
+	now = now.Add(2 * time.Second)
+	if err := b.AddThrottled(`{"type":"e12"}`, "k12"); err != nil {
+		t.Errorf("AddThrottled after refill: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package batch
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrRateLimited is returned by AddThrottled for events beyond the rate limit.
+var ErrRateLimited = errors.New("event rate limit exceeded")
+
+// throttleReportInterval is how often rate limited drops are reported while
+// events keep being dropped. Drops are reported in bulk so that a runaway
+// loop does not also flood the dropped-events callbacks.
+const throttleReportInterval = time.Second
+
+// throttle is a token bucket over tracked events that counts the events it
+// turns away until they are reported.
+type throttle struct {
+	limiter *rate.Limiter
+	now     func() time.Time
+
+	mu         sync.Mutex
+	pending    int // dropped events not yet reported
+	lastReport time.Time
+}
+
+// newThrottle creates a throttle allowing perSecond events with bursts of up
+// to burst events. It returns nil (no limit) if perSecond is not positive.
+func newThrottle(perSecond float64, burst int) *throttle {
+	if perSecond <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &throttle{
+		limiter: rate.NewLimiter(rate.Limit(perSecond), burst),
+		now:     time.Now,
+	}
+}
+
+// take consumes a token, if one is left, for an event that is accepted
+// regardless of the limit.
+func (t *throttle) take() {
+	if t != nil {
+		t.limiter.AllowN(t.now(), 1)
+	}
+}
+
+// allow reports whether an event is within the limit, counting it as
+// dropped otherwise.
+func (t *throttle) allow() bool {
+	if t == nil || t.limiter.AllowN(t.now(), 1) {
+		return true
+	}
+	t.mu.Lock()
+	t.pending++
+	t.mu.Unlock()
+	return false
+}
+
+// due returns the number of dropped events to report now and resets it. The
+// first drop after a quiet period is due immediately; later ones are
+// collected for throttleReportInterval unless force is set.
+func (t *throttle) due(force bool) int {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if t.pending == 0 || (!force && now.Sub(t.lastReport) < throttleReportInterval) {
+		return 0
+	}
+	n := t.pending
+	t.pending = 0
+	t.lastReport = now
+	return n
+}
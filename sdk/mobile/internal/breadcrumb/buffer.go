@@ -0,0 +1,70 @@
+// Package breadcrumb keeps a bounded, in-memory trail of recently tracked
+// events for crash triage.
+//
+// Only each event's type and timestamp are kept, never its properties, so the
+// trail attached to an app_crash event carries no user data beyond what the
+// tracked events themselves already sent.
+package breadcrumb
+
+import "sync"
+
+// DefaultCapacity is the default number of breadcrumbs kept.
+const DefaultCapacity = 20
+
+// Crumb is one tracked event in the trail.
+type Crumb struct {
+	EventType   string `json:"event_type"`
+	TimestampMs int64  `json:"timestamp_ms"`
+}
+
+// Buffer is a ring buffer of the most recent crumbs.
+// It is safe for concurrent use by multiple goroutines.
+type Buffer struct {
+	mu     sync.Mutex
+	crumbs []Crumb
+	next   int
+	full   bool
+}
+
+// NewBuffer creates a buffer keeping the last capacity crumbs.
+// If capacity is zero or negative, DefaultCapacity is used.
+func NewBuffer(capacity int) *Buffer {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Buffer{crumbs: make([]Crumb, capacity)}
+}
+
+// Add records a tracked event, evicting the oldest crumb when full.
+func (b *Buffer) Add(eventType string, timestampMs int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.crumbs[b.next] = Crumb{EventType: eventType, TimestampMs: timestampMs}
+	b.next = (b.next + 1) % len(b.crumbs)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// Trail returns the recorded crumbs, oldest first.
+func (b *Buffer) Trail() []Crumb {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.full {
+		return append([]Crumb(nil), b.crumbs[:b.next]...)
+	}
+	trail := make([]Crumb, 0, len(b.crumbs))
+	trail = append(trail, b.crumbs[b.next:]...)
+	return append(trail, b.crumbs[:b.next]...)
+}
+
+// Clear removes all crumbs.
+func (b *Buffer) Clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.next = 0
+	b.full = false
+}
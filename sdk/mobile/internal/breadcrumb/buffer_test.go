@@ -0,0 +1,58 @@
+package breadcrumb
+
+import "testing"
+
+func TestBuffer_KeepsMostRecentOldestFirst(t *testing.T) {
+	b := NewBuffer(3)
+
+	if trail := b.Trail(); len(trail) != 0 {
+		t.Fatalf("expected empty trail, got %v", trail)
+	}
+
+	b.Add("screen_view", 1)
+	b.Add("button_tap", 2)
+	if trail := b.Trail(); len(trail) != 2 || trail[0].EventType != "screen_view" || trail[1].TimestampMs != 2 {
+		t.Errorf("unexpected partial trail: %v", trail)
+	}
+
+	b.Add("add_to_cart", 3)
+	b.Add("screen_view", 4)
+	b.Add("button_tap", 5)
+
+	trail := b.Trail()
+	if len(trail) != 3 {
+		t.Fatalf("expected 3 crumbs, got %d", len(trail))
+	}
+	for i, want := range []int64{3, 4, 5} {
+		if trail[i].TimestampMs != want {
+			t.Errorf("crumb %d: expected timestamp %d, got %d", i, want, trail[i].TimestampMs)
+		}
+	}
+}
+
+func TestBuffer_Clear(t *testing.T) {
+	b := NewBuffer(2)
+	b.Add("screen_view", 1)
+	b.Add("button_tap", 2)
+	b.Add("button_tap", 3)
+
+	b.Clear()
+	if trail := b.Trail(); len(trail) != 0 {
+		t.Errorf("expected empty trail after Clear, got %v", trail)
+	}
+
+	b.Add("screen_view", 4)
+	if trail := b.Trail(); len(trail) != 1 || trail[0].TimestampMs != 4 {
+		t.Errorf("unexpected trail after Clear: %v", trail)
+	}
+}
+
+func TestNewBuffer_DefaultCapacity(t *testing.T) {
+	b := NewBuffer(0)
+	for i := 0; i < DefaultCapacity+5; i++ {
+		b.Add("screen_view", int64(i))
+	}
+	if trail := b.Trail(); len(trail) != DefaultCapacity {
+		t.Errorf("expected %d crumbs, got %d", DefaultCapacity, len(trail))
+	}
+}
@@ -0,0 +1,136 @@
+// Package clocksync estimates how far the device clock is off from server
+// time for the Causality mobile SDK, so event timestamps from devices with a
+// wrong clock can be corrected before they are sent.
+//
+// The gateway returns its receive time with every response. Assuming the
+// request and response took equally long, the server read its clock halfway
+// through the round trip, so the offset is the device time at that midpoint
+// minus the server time. Samples are smoothed and the estimate is persisted
+// so corrections apply from the first event after a restart.
+package clocksync
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/SebastienMelki/causality/sdk/mobile/internal/storage"
+)
+
+// offsetKey is the key used to store the clock offset in the device_info table.
+const offsetKey = "clock_offset"
+
+const (
+	// maxRoundTrip is the longest round trip a sample is accepted from.
+	// The server time could have been read anywhere within the round trip,
+	// so slow ones say little about the offset.
+	maxRoundTrip = 5 * time.Second
+
+	// resetThreshold is how far a sample may be from the estimate and still
+	// be averaged in. Larger differences mean the device clock was changed,
+	// and the sample replaces the estimate.
+	resetThreshold = time.Second
+
+	// smoothing is the weight of a new sample in the moving average.
+	smoothing = 0.2
+)
+
+// persistedOffset is the JSON form of the estimate in device_info.
+type persistedOffset struct {
+	OffsetMs   int64 `json:"offset_ms"`
+	MeasuredAt int64 `json:"measured_at"`
+}
+
+// Estimator tracks the offset of the device clock from server time.
+// A nil *Estimator applies no correction.
+//
+// Estimator is safe for concurrent use by multiple goroutines.
+type Estimator struct {
+	db  *storage.DB
+	now func() time.Time
+
+	mu       sync.RWMutex
+	offsetMs float64 // device minus server time
+	measured bool
+}
+
+// NewEstimator creates an Estimator backed by the given database, restoring
+// a previously persisted offset. On error the Estimator starts unmeasured.
+func NewEstimator(db *storage.DB) (*Estimator, error) {
+	e := &Estimator{db: db, now: time.Now}
+
+	var value string
+	err := db.QueryRow("SELECT value FROM device_info WHERE key = ?", offsetKey).Scan(&value)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return e, fmt.Errorf("load clock offset: %w", err)
+	default:
+		var p persistedOffset
+		if err := json.Unmarshal([]byte(value), &p); err != nil {
+			return e, fmt.Errorf("decode clock offset: %w", err)
+		}
+		e.offsetMs = float64(p.OffsetMs)
+		e.measured = true
+	}
+
+	return e, nil
+}
+
+// Observe records a sample from a request sent at sent and answered at
+// received, both read from the device clock, that the server received at
+// serverMs (Unix milliseconds). Samples from round trips longer than
+// maxRoundTrip are discarded.
+func (e *Estimator) Observe(sent, received time.Time, serverMs int64) error {
+	rtt := received.Sub(sent)
+	if rtt < 0 || rtt > maxRoundTrip {
+		return nil
+	}
+	sample := float64(sent.Add(rtt/2).UnixMilli() - serverMs)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	diff := sample - e.offsetMs
+	if !e.measured || math.Abs(diff) > float64(resetThreshold.Milliseconds()) {
+		e.offsetMs = sample
+	} else {
+		e.offsetMs += smoothing * diff
+	}
+	e.measured = true
+
+	data, err := json.Marshal(persistedOffset{OffsetMs: int64(e.offsetMs), MeasuredAt: e.now().UnixMilli()})
+	if err != nil {
+		return fmt.Errorf("encode clock offset: %w", err)
+	}
+	if _, err := e.db.Exec(
+		"INSERT OR REPLACE INTO device_info (key, value) VALUES (?, ?)",
+		offsetKey, string(data),
+	); err != nil {
+		return fmt.Errorf("save clock offset: %w", err)
+	}
+	return nil
+}
+
+// SkewMs returns the estimated device clock minus server time in
+// milliseconds, or 0 if no sample has been observed.
+func (e *Estimator) SkewMs() int64 {
+	if e == nil {
+		return 0
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return int64(e.offsetMs)
+}
+
+// Now returns the current time corrected to server time.
+func (e *Estimator) Now() time.Time {
+	if e == nil {
+		return time.Now()
+	}
+	return e.now().Add(-time.Duration(e.SkewMs()) * time.Millisecond)
+}
@@ -0,0 +1,95 @@
+package clocksync
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SebastienMelki/causality/sdk/mobile/internal/storage"
+)
+
+func newTestDB(t *testing.T) *storage.DB {
+	t.Helper()
+	dir := t.TempDir()
+	db, err := storage.NewDB(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestEstimator_Unmeasured(t *testing.T) {
+	e, err := NewEstimator(newTestDB(t))
+	if err != nil {
+		t.Fatalf("NewEstimator: %v", err)
+	}
+	if e.SkewMs() != 0 {
+		t.Errorf("SkewMs() = %d, want 0 before any sample", e.SkewMs())
+	}
+
+	var nilEstimator *Estimator
+	if nilEstimator.SkewMs() != 0 {
+		t.Errorf("nil SkewMs() = %d, want 0", nilEstimator.SkewMs())
+	}
+	if d := time.Since(nilEstimator.Now()); d < 0 || d > time.Second {
+		t.Errorf("nil Now() is %v off the device clock", d)
+	}
+}
+
+func TestEstimator_Observe(t *testing.T) {
+	e, err := NewEstimator(newTestDB(t))
+	if err != nil {
+		t.Fatalf("NewEstimator: %v", err)
+	}
+	// The device clock runs 90s ahead of the server.
+	sent := time.UnixMilli(1_700_000_090_000)
+	server := int64(1_700_000_000_100)
+
+	tests := []struct {
+		name     string
+		rtt      time.Duration
+		serverMs int64
+		want     int64
+	}{
+		{"first sample sets offset", 200 * time.Millisecond, server, 90_000},
+		{"small change is smoothed", 200 * time.Millisecond, server - 500, 90_100},
+		{"slow round trip discarded", 6 * time.Second, server + 3000, 90_100},
+		{"clock change resets offset", 200 * time.Millisecond, server + 60_000, 30_000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := e.Observe(sent, sent.Add(tt.rtt), tt.serverMs); err != nil {
+				t.Fatalf("Observe: %v", err)
+			}
+			if got := e.SkewMs(); got != tt.want {
+				t.Errorf("SkewMs() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	e.now = func() time.Time { return sent }
+	if got := e.Now(); !got.Equal(sent.Add(-30 * time.Second)) {
+		t.Errorf("Now() = %v, want device time corrected by 30s", got)
+	}
+}
+
+func TestEstimator_Persisted(t *testing.T) {
+	db := newTestDB(t)
+	e, err := NewEstimator(db)
+	if err != nil {
+		t.Fatalf("NewEstimator: %v", err)
+	}
+	sent := time.UnixMilli(1_700_000_000_000)
+	if err := e.Observe(sent, sent.Add(100*time.Millisecond), 1_700_000_004_050); err != nil {
+		t.Fatalf("Observe: %v", err)
+	}
+
+	restored, err := NewEstimator(db)
+	if err != nil {
+		t.Fatalf("NewEstimator after restart: %v", err)
+	}
+	if got := restored.SkewMs(); got != -4000 {
+		t.Errorf("restored SkewMs() = %d, want -4000", got)
+	}
+}
@@ -0,0 +1,145 @@
+// Package consent tracks the user's data collection consent level for the
+// Causality mobile SDK and decides which events may be stored and sent.
+package consent
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/SebastienMelki/causality/sdk/mobile/internal/storage"
+)
+
+// Level is a data collection consent level.
+type Level string
+
+// Consent levels.
+const (
+	// Undetermined means the user has not answered yet. Essential events are
+	// stored but nothing is sent.
+	Undetermined Level = ""
+
+	// None means the user declined all collection.
+	None Level = "none"
+
+	// Essential allows only essential events (see EssentialEventTypes).
+	Essential Level = "essential"
+
+	// Full allows all events.
+	Full Level = "full"
+)
+
+// consentKey is the key used to store the consent level in the device_info table.
+const consentKey = "consent_level"
+
+// ErrInvalidLevel is returned for an unknown consent level.
+var ErrInvalidLevel = errors.New("invalid consent level")
+
+// EssentialEventTypes are the event types collected under Essential consent:
+// app lifecycle events needed to operate the service and completed purchases.
+var EssentialEventTypes = []string{
+	"app_start",
+	"app_background",
+	"app_foreground",
+	"purchase_complete",
+}
+
+var essentialSet = func() map[string]bool {
+	set := make(map[string]bool, len(EssentialEventTypes))
+	for _, t := range EssentialEventTypes {
+		set[t] = true
+	}
+	return set
+}()
+
+// ParseLevel parses "none", "essential", or "full".
+func ParseLevel(s string) (Level, error) {
+	switch Level(s) {
+	case None, Essential, Full:
+		return Level(s), nil
+	default:
+		return Undetermined, fmt.Errorf("%w: %q (want none, essential, or full)", ErrInvalidLevel, s)
+	}
+}
+
+// IsEssential reports whether eventType is collected under Essential consent.
+func IsEssential(eventType string) bool {
+	return essentialSet[eventType]
+}
+
+// Manager holds the current consent level and persists changes.
+//
+// Manager is safe for concurrent use by multiple goroutines.
+type Manager struct {
+	db    *storage.DB
+	mu    sync.RWMutex
+	level Level
+}
+
+// NewManager creates a Manager backed by the given database, restoring a
+// previously persisted level. Without one, the level is Undetermined if
+// required is true and Full otherwise, so apps that do not manage consent
+// keep collecting everything.
+func NewManager(db *storage.DB, required bool) (*Manager, error) {
+	m := &Manager{db: db, level: Full}
+	if required {
+		m.level = Undetermined
+	}
+
+	var value string
+	err := db.QueryRow("SELECT value FROM device_info WHERE key = ?", consentKey).Scan(&value)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return m, fmt.Errorf("load consent level: %w", err)
+	default:
+		if level, err := ParseLevel(value); err == nil {
+			m.level = level
+		}
+	}
+
+	return m, nil
+}
+
+// Level returns the current consent level.
+func (m *Manager) Level() Level {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.level
+}
+
+// Set changes and persists the consent level, returning the previous level.
+func (m *Manager) Set(level Level) (Level, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.db.Exec(
+		"INSERT OR REPLACE INTO device_info (key, value) VALUES (?, ?)",
+		consentKey, string(level),
+	); err != nil {
+		return m.level, fmt.Errorf("save consent level: %w", err)
+	}
+
+	previous := m.level
+	m.level = level
+	return previous, nil
+}
+
+// AllowsStore reports whether an event of eventType may be stored.
+func (m *Manager) AllowsStore(eventType string) bool {
+	switch m.Level() {
+	case Full:
+		return true
+	case Essential, Undetermined:
+		return IsEssential(eventType)
+	default:
+		return false
+	}
+}
+
+// AllowsSend reports whether stored events may be sent.
+func (m *Manager) AllowsSend() bool {
+	level := m.Level()
+	return level == Essential || level == Full
+}
@@ -0,0 +1,103 @@
+package consent
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/SebastienMelki/causality/sdk/mobile/internal/storage"
+)
+
+func newTestDB(t *testing.T) *storage.DB {
+	t.Helper()
+	dir := t.TempDir()
+	db, err := storage.NewDB(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestNewManager_DefaultLevel(t *testing.T) {
+	db := newTestDB(t)
+
+	m, err := NewManager(db, false)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	if m.Level() != Full {
+		t.Errorf("level without required consent: got %q, want %q", m.Level(), Full)
+	}
+
+	m, err = NewManager(db, true)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	if m.Level() != Undetermined {
+		t.Errorf("level with required consent: got %q, want undetermined", m.Level())
+	}
+}
+
+func TestManager_SetPersists(t *testing.T) {
+	db := newTestDB(t)
+
+	m, _ := NewManager(db, true)
+	previous, err := m.Set(Essential)
+	if err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if previous != Undetermined {
+		t.Errorf("previous: got %q, want undetermined", previous)
+	}
+
+	restored, err := NewManager(db, false)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	if restored.Level() != Essential {
+		t.Errorf("restored level: got %q, want %q", restored.Level(), Essential)
+	}
+}
+
+func TestManager_Gating(t *testing.T) {
+	tests := []struct {
+		level          Level
+		storeScreen    bool
+		storeEssential bool
+		send           bool
+	}{
+		{level: Undetermined, storeScreen: false, storeEssential: true, send: false},
+		{level: None, storeScreen: false, storeEssential: false, send: false},
+		{level: Essential, storeScreen: false, storeEssential: true, send: true},
+		{level: Full, storeScreen: true, storeEssential: true, send: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.level), func(t *testing.T) {
+			m := &Manager{level: tt.level}
+			if got := m.AllowsStore("screen_view"); got != tt.storeScreen {
+				t.Errorf("AllowsStore(screen_view) = %v, want %v", got, tt.storeScreen)
+			}
+			if got := m.AllowsStore("app_start"); got != tt.storeEssential {
+				t.Errorf("AllowsStore(app_start) = %v, want %v", got, tt.storeEssential)
+			}
+			if got := m.AllowsSend(); got != tt.send {
+				t.Errorf("AllowsSend() = %v, want %v", got, tt.send)
+			}
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, s := range []string{"none", "essential", "full"} {
+		if _, err := ParseLevel(s); err != nil {
+			t.Errorf("ParseLevel(%q): %v", s, err)
+		}
+	}
+	for _, s := range []string{"", "FULL", "partial"} {
+		if _, err := ParseLevel(s); !errors.Is(err, ErrInvalidLevel) {
+			t.Errorf("ParseLevel(%q) error = %v, want ErrInvalidLevel", s, err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Package dedup drops exact duplicate events tracked in quick succession,
+// such as those from a double-fired UI handler, before they are queued.
+//
+// Events are identified by a hash of their type and properties, so only the
+// hashes of events tracked within the window are kept in memory.
+package dedup
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Filter remembers the events tracked within a window and reports repeats.
+// A nil *Filter reports no duplicates.
+//
+// Filter is safe for concurrent use by multiple goroutines.
+type Filter struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	seen      map[[sha256.Size]byte]time.Time // hash -> last tracked
+	lastPrune time.Time
+
+	dropped atomic.Int64
+}
+
+// NewFilter creates a filter treating identical events tracked within window
+// of each other as duplicates. It returns nil (no deduplication) if window
+// is not positive.
+func NewFilter(window time.Duration) *Filter {
+	if window <= 0 {
+		return nil
+	}
+	return &Filter{
+		window: window,
+		now:    time.Now,
+		seen:   make(map[[sha256.Size]byte]time.Time),
+	}
+}
+
+// Duplicate reports whether an event with the same type and properties was
+// tracked within the window, counting it as dropped if so. Either way the
+// event restarts the window, so a burst of repeats is dropped as a whole.
+func (f *Filter) Duplicate(eventType string, properties json.RawMessage) bool {
+	if f == nil {
+		return false
+	}
+	key := hash(eventType, properties)
+	now := f.now()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.prune(now)
+	last, ok := f.seen[key]
+	f.seen[key] = now
+	if ok && now.Sub(last) < f.window {
+		f.dropped.Add(1)
+		return true
+	}
+	return false
+}
+
+// Dropped returns the number of duplicates reported since the filter was
+// created.
+func (f *Filter) Dropped() int64 {
+	if f == nil {
+		return 0
+	}
+	return f.dropped.Load()
+}
+
+// prune forgets events last tracked more than a window ago, at most once per
+// window. Caller must hold f.mu.
+func (f *Filter) prune(now time.Time) {
+	if now.Sub(f.lastPrune) < f.window {
+		return
+	}
+	for key, last := range f.seen {
+		if now.Sub(last) >= f.window {
+			delete(f.seen, key)
+		}
+	}
+	f.lastPrune = now
+}
+
+// hash identifies an event by its type and properties. Properties are
+// re-encoded when possible so that key order and whitespace do not matter.
+func hash(eventType string, properties json.RawMessage) [sha256.Size]byte {
+	var buf bytes.Buffer
+	buf.WriteString(eventType)
+	buf.WriteByte(0)
+
+	var decoded interface{}
+	if err := json.Unmarshal(properties, &decoded); err == nil {
+		if canonical, err := json.Marshal(decoded); err == nil {
+			properties = canonical
+		}
+	}
+	buf.Write(properties)
+	return sha256.Sum256(buf.Bytes())
+}
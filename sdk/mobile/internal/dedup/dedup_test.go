@@ -0,0 +1,59 @@
+package dedup
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestFilter_Duplicate(t *testing.T) {
+	f := NewFilter(500 * time.Millisecond)
+	now := time.Unix(1_700_000_000, 0)
+	f.now = func() time.Time { return now }
+
+	tap := json.RawMessage(`{"button_id":"buy","screen_name":"Cart"}`)
+	tests := []struct {
+		name       string
+		advance    time.Duration
+		eventType  string
+		properties json.RawMessage
+		want       bool
+	}{
+		{"first event", 0, "button_tap", tap, false},
+		{"double fire", 100 * time.Millisecond, "button_tap", tap, true},
+		{"reordered keys", 100 * time.Millisecond, "button_tap", json.RawMessage(`{ "screen_name": "Cart", "button_id": "buy" }`), true},
+		{"other properties", 0, "button_tap", json.RawMessage(`{"button_id":"cancel","screen_name":"Cart"}`), false},
+		{"other type", 0, "screen_view", tap, false},
+		{"repeat restarts window", 400 * time.Millisecond, "button_tap", tap, true},
+		{"after window", 500 * time.Millisecond, "button_tap", tap, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.advance)
+			if got := f.Duplicate(tt.eventType, tt.properties); got != tt.want {
+				t.Errorf("Duplicate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := f.Dropped(); got != 3 {
+		t.Errorf("Dropped() = %d, want 3", got)
+	}
+	if len(f.seen) > 3 {
+		t.Errorf("seen holds %d hashes, want expired ones pruned", len(f.seen))
+	}
+}
+
+func TestFilter_Disabled(t *testing.T) {
+	f := NewFilter(0)
+	if f != nil {
+		t.Fatalf("NewFilter(0) = %v, want nil", f)
+	}
+	props := json.RawMessage(`{"screen_name":"Home"}`)
+	if f.Duplicate("screen_view", props) || f.Duplicate("screen_view", props) {
+		t.Error("nil filter reported a duplicate")
+	}
+	if f.Dropped() != 0 {
+		t.Errorf("nil Dropped() = %d, want 0", f.Dropped())
+	}
+}
@@ -85,6 +85,22 @@ type platformInfo struct {
 	networkType        string
 }
 
+// NetworkListener is notified when the network type reported by native
+// wrappers changes.
+type NetworkListener func(previous, current string)
+
+// BatteryListener is notified of every battery update reported by native
+// wrappers.
+type BatteryListener func(level int, state string)
+
+// listeners holds the registered change listeners by registration ID.
+var (
+	listenersMu      sync.Mutex
+	nextListenerID   int
+	networkListeners = make(map[int]NetworkListener)
+	batteryListeners = make(map[int]BatteryListener)
+)
+
 // SetPlatformContext is called by native wrappers (Swift/Kotlin) during SDK initialization
 // to populate platform-specific device information. This function is thread-safe.
 func SetPlatformContext(platform, osVersion, model, manufacturer, appVersion, buildNumber string, screenW, screenH int, locale, timezone string) {
@@ -106,13 +122,79 @@ func SetPlatformContext(platform, osVersion, model, manufacturer, appVersion, bu
 }
 
 // SetNetworkInfo updates the carrier and network type. Called by native wrappers
-// when network conditions change.
+// when network conditions change. Network listeners are notified if the
+// network type changed.
 func SetNetworkInfo(carrier, networkType string) {
 	platformMu.Lock()
-	defer platformMu.Unlock()
-
+	previous := platformCtx.networkType
 	platformCtx.carrier = carrier
 	platformCtx.networkType = networkType
+	platformMu.Unlock()
+
+	if previous == networkType {
+		return
+	}
+
+	listenersMu.Lock()
+	notify := make([]NetworkListener, 0, len(networkListeners))
+	for _, fn := range networkListeners {
+		notify = append(notify, fn)
+	}
+	listenersMu.Unlock()
+
+	for _, fn := range notify {
+		fn(previous, networkType)
+	}
+}
+
+// SetBatteryInfo reports the battery level (0-100) and state ("charging",
+// "discharging", "full"). Called by native wrappers when the battery changes.
+// Battery listeners are notified of every update.
+func SetBatteryInfo(level int, state string) {
+	listenersMu.Lock()
+	notify := make([]BatteryListener, 0, len(batteryListeners))
+	for _, fn := range batteryListeners {
+		notify = append(notify, fn)
+	}
+	listenersMu.Unlock()
+
+	for _, fn := range notify {
+		fn(level, state)
+	}
+}
+
+// OnNetworkChange registers a listener for network type changes and returns
+// a function that removes it.
+func OnNetworkChange(fn NetworkListener) (remove func()) {
+	listenersMu.Lock()
+	defer listenersMu.Unlock()
+
+	id := nextListenerID
+	nextListenerID++
+	networkListeners[id] = fn
+
+	return func() {
+		listenersMu.Lock()
+		defer listenersMu.Unlock()
+		delete(networkListeners, id)
+	}
+}
+
+// OnBatteryChange registers a listener for battery updates and returns a
+// function that removes it.
+func OnBatteryChange(fn BatteryListener) (remove func()) {
+	listenersMu.Lock()
+	defer listenersMu.Unlock()
+
+	id := nextListenerID
+	nextListenerID++
+	batteryListeners[id] = fn
+
+	return func() {
+		listenersMu.Lock()
+		defer listenersMu.Unlock()
+		delete(batteryListeners, id)
+	}
 }
 
 // CollectContext returns a DeviceContext populated with Go-available fields.
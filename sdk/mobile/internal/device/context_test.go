@@ -95,6 +95,43 @@ func TestSetNetworkInfo_StoresValues(t *testing.T) {
 	}
 }
 
+func TestOnNetworkChange_NotifiesOnTypeChange(t *testing.T) {
+	resetPlatformContextForTesting()
+
+	var changes [][2]string
+	remove := OnNetworkChange(func(previous, current string) {
+		changes = append(changes, [2]string{previous, current})
+	})
+
+	SetNetworkInfo("Verizon", "wifi")
+	SetNetworkInfo("Verizon", "wifi") // unchanged type
+	SetNetworkInfo("Verizon", "cellular")
+	remove()
+	SetNetworkInfo("Verizon", "offline")
+
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %v", changes)
+	}
+	if changes[0] != [2]string{"", "wifi"} || changes[1] != [2]string{"wifi", "cellular"} {
+		t.Errorf("unexpected changes: %v", changes)
+	}
+}
+
+func TestOnBatteryChange_NotifiesEveryUpdate(t *testing.T) {
+	var levels []int
+	remove := OnBatteryChange(func(level int, state string) {
+		levels = append(levels, level)
+	})
+	defer remove()
+
+	SetBatteryInfo(80, "discharging")
+	SetBatteryInfo(80, "discharging")
+
+	if len(levels) != 2 {
+		t.Errorf("expected 2 updates, got %v", levels)
+	}
+}
+
 func TestSetPlatformContext_Overwrite(t *testing.T) {
 	resetPlatformContextForTesting()
 
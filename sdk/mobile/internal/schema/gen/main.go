@@ -0,0 +1,214 @@
+// Command gen writes the schema package's rule table from the buf.validate
+// field rules on the event messages in the compiled causality.v1 descriptors.
+//
+// Run it with go generate after changing validation rules in events.proto and
+// regenerating the protobuf code:
+//
+//	go generate ./sdk/mobile/internal/schema
+//
+// String min_len/max_len, required strings and numeric gt/gte/lt/lte rules are
+// supported; other rules are left to the server.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	causalityv1 "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// eventTypeNames maps payload field names to the event type names used by
+// the SDK bridge where the two differ.
+var eventTypeNames = map[string]string{
+	"custom_event": "custom",
+}
+
+// rule mirrors schema.Rule.
+type rule struct {
+	field          string
+	minLen, maxLen uint64
+	min, max       float64
+	hasMin, hasMax bool
+	exclMin        bool
+	exclMax        bool
+}
+
+func main() {
+	out := flag.String("out", "rules_gen.go", "output file")
+	flag.Parse()
+
+	src, err := generate()
+	if err != nil {
+		log.Fatalf("generate: %v", err)
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatalf("write %s: %v", *out, err)
+	}
+}
+
+// generate renders the rule table for every event payload with rules.
+func generate() ([]byte, error) {
+	envelope := (&causalityv1.EventEnvelope{}).ProtoReflect().Descriptor()
+	payload := envelope.Oneofs().ByName("payload")
+	if payload == nil {
+		return nil, fmt.Errorf("EventEnvelope has no payload oneof")
+	}
+
+	tables := make(map[string][]rule)
+	fields := payload.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.Message() == nil {
+			continue
+		}
+		eventType := string(fd.Name())
+		if name, ok := eventTypeNames[eventType]; ok {
+			eventType = name
+		}
+		if rules := messageRules(fd.Message()); len(rules) > 0 {
+			tables[eventType] = rules
+		}
+	}
+
+	eventTypes := make([]string, 0, len(tables))
+	for eventType := range tables {
+		eventTypes = append(eventTypes, eventType)
+	}
+	sort.Strings(eventTypes)
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gen from causality/v1/events.proto. DO NOT EDIT.\n\n")
+	buf.WriteString("package schema\n\n")
+	buf.WriteString("// rules holds the constraints on each event type's properties.\n")
+	buf.WriteString("var rules = map[string][]Rule{\n")
+	for _, eventType := range eventTypes {
+		fmt.Fprintf(&buf, "%q: {\n", eventType)
+		for _, r := range tables[eventType] {
+			fmt.Fprintf(&buf, "{%s},\n", r.literal())
+		}
+		buf.WriteString("},\n")
+	}
+	buf.WriteString("}\n")
+
+	return format.Source(buf.Bytes())
+}
+
+// messageRules collects the supported rules on the scalar fields of md.
+func messageRules(md protoreflect.MessageDescriptor) []rule {
+	var rules []rule
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.IsList() || fd.IsMap() {
+			continue
+		}
+		opts := fd.Options()
+		if opts == nil || !proto.HasExtension(opts, validate.E_Field) {
+			continue
+		}
+		fr, ok := proto.GetExtension(opts, validate.E_Field).(*validate.FieldRules)
+		if !ok {
+			continue
+		}
+
+		r := rule{field: string(fd.Name())}
+		if fd.Kind() == protoreflect.StringKind && fr.GetRequired() {
+			r.minLen = 1
+		}
+		if typed := typeRules(fr); typed != nil {
+			applyTypeRules(&r, typed)
+		}
+		if r.minLen > 0 || r.maxLen > 0 || r.hasMin || r.hasMax {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// typeRules returns the type-specific rules message set on fr, if any.
+func typeRules(fr *validate.FieldRules) protoreflect.Message {
+	m := fr.ProtoReflect()
+	oneof := m.Descriptor().Oneofs().ByName("type")
+	if oneof == nil {
+		return nil
+	}
+	fd := m.WhichOneof(oneof)
+	if fd == nil || fd.Message() == nil {
+		return nil
+	}
+	return m.Get(fd).Message()
+}
+
+// applyTypeRules copies the supported bounds from a string or numeric rules
+// message into r.
+func applyTypeRules(r *rule, typed protoreflect.Message) {
+	typed.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch fd.Name() {
+		case "min_len":
+			if n := v.Uint(); n > r.minLen {
+				r.minLen = n
+			}
+		case "max_len":
+			r.maxLen = v.Uint()
+		case "gt", "gte":
+			if f, ok := number(fd, v); ok {
+				r.min, r.hasMin, r.exclMin = f, true, fd.Name() == "gt"
+			}
+		case "lt", "lte":
+			if f, ok := number(fd, v); ok {
+				r.max, r.hasMax, r.exclMax = f, true, fd.Name() == "lt"
+			}
+		}
+		return true
+	})
+}
+
+// number converts a numeric rule value to float64.
+func number(fd protoreflect.FieldDescriptor, v protoreflect.Value) (float64, bool) {
+	switch fd.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Int64Kind,
+		protoreflect.Sint32Kind, protoreflect.Sint64Kind,
+		protoreflect.Sfixed32Kind, protoreflect.Sfixed64Kind:
+		return float64(v.Int()), true
+	case protoreflect.Uint32Kind, protoreflect.Uint64Kind,
+		protoreflect.Fixed32Kind, protoreflect.Fixed64Kind:
+		return float64(v.Uint()), true
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return v.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// literal renders r as the fields of a schema.Rule composite literal.
+func (r rule) literal() string {
+	s := fmt.Sprintf("Field: %q", r.field)
+	if r.minLen > 0 {
+		s += fmt.Sprintf(", MinLen: %d", r.minLen)
+	}
+	if r.maxLen > 0 {
+		s += fmt.Sprintf(", MaxLen: %d", r.maxLen)
+	}
+	if r.hasMin {
+		s += fmt.Sprintf(", Min: %g, HasMin: true", r.min)
+		if r.exclMin {
+			s += ", ExclusiveMin: true"
+		}
+	}
+	if r.hasMax {
+		s += fmt.Sprintf(", Max: %g, HasMax: true", r.max)
+		if r.exclMax {
+			s += ", ExclusiveMax: true"
+		}
+	}
+	return s
+}
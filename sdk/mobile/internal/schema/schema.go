@@ -0,0 +1,135 @@
+// Package schema validates typed event properties against the constraints
+// declared on the event messages in proto/causality/v1/events.proto.
+//
+// The constraints are buf.validate field rules, generated into Go tables by
+// the gen command so the SDK does not need protovalidate at runtime. Events
+// that would be rejected by the server are caught at the call site instead of
+// surfacing as 400s when the batch is flushed.
+package schema
+
+//go:generate go run ./gen -out rules_gen.go
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rule is the set of constraints on one event property.
+type Rule struct {
+	// Field is the property name, as used in the event JSON.
+	Field string
+
+	// String length bounds in characters. MaxLen zero means unbounded.
+	MinLen uint64
+	MaxLen uint64
+
+	// Numeric bounds, applied when HasMin or HasMax is set. Exclusive bounds
+	// come from gt and lt rules, inclusive ones from gte and lte.
+	Min          float64
+	Max          float64
+	HasMin       bool
+	HasMax       bool
+	ExclusiveMin bool
+	ExclusiveMax bool
+}
+
+// Violation describes a property that breaks a rule.
+type Violation struct {
+	Field   string
+	Message string
+}
+
+// ValidationError lists every violation found in an event.
+type ValidationError struct {
+	EventType  string
+	Violations []Violation
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.Field + " " + v.Message
+	}
+	return fmt.Sprintf("invalid %s event: %s", e.EventType, strings.Join(parts, "; "))
+}
+
+// Rules returns the rules for an event type, or nil if it has none.
+func Rules(eventType string) []Rule {
+	return rules[eventType]
+}
+
+// Validate checks the properties of a typed event against its rules.
+// It returns a *ValidationError listing all violations, or nil.
+// Properties not covered by a rule are not checked.
+func Validate(eventType string, properties json.RawMessage) error {
+	eventRules := rules[eventType]
+	if len(eventRules) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]json.RawMessage)
+	if len(properties) > 0 {
+		if err := json.Unmarshal(properties, &fields); err != nil {
+			return fmt.Errorf("invalid %s event: properties must be a JSON object", eventType)
+		}
+	}
+
+	var violations []Violation
+	for _, rule := range eventRules {
+		if msg := rule.check(fields[rule.Field]); msg != "" {
+			violations = append(violations, Violation{Field: rule.Field, Message: msg})
+		}
+	}
+	if len(violations) > 0 {
+		return &ValidationError{EventType: eventType, Violations: violations}
+	}
+	return nil
+}
+
+// check returns a message describing how raw breaks the rule, or "".
+// A missing or null property is checked as its proto3 zero value.
+func (r Rule) check(raw json.RawMessage) string {
+	present := len(raw) > 0 && string(raw) != "null"
+
+	if r.MinLen > 0 || r.MaxLen > 0 {
+		var s string
+		if present {
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return "must be a string"
+			}
+		}
+		n := uint64(utf8.RuneCountInString(s))
+		switch {
+		case n < r.MinLen && !present:
+			return "is required"
+		case n < r.MinLen:
+			return fmt.Sprintf("must be at least %d characters, got %d", r.MinLen, n)
+		case r.MaxLen > 0 && n > r.MaxLen:
+			return fmt.Sprintf("must be at most %d characters, got %d", r.MaxLen, n)
+		}
+	}
+
+	if r.HasMin || r.HasMax {
+		var v float64
+		if present {
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return "must be a number"
+			}
+		}
+		switch {
+		case r.HasMin && r.ExclusiveMin && v <= r.Min:
+			return fmt.Sprintf("must be greater than %g, got %g", r.Min, v)
+		case r.HasMin && !r.ExclusiveMin && v < r.Min:
+			return fmt.Sprintf("must be at least %g, got %g", r.Min, v)
+		case r.HasMax && r.ExclusiveMax && v >= r.Max:
+			return fmt.Sprintf("must be less than %g, got %g", r.Max, v)
+		case r.HasMax && !r.ExclusiveMax && v > r.Max:
+			return fmt.Sprintf("must be at most %g, got %g", r.Max, v)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,78 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate_GeneratedRules(t *testing.T) {
+	tests := []struct {
+		name      string
+		eventType string
+		props     string
+		wantErr   string
+	}{
+		{"valid screen view", "screen_view", `{"screen_name": "Home"}`, ""},
+		{"missing screen name", "screen_view", `{"screen_class": "HomeVC"}`, "screen_name is required"},
+		{"empty product id", "add_to_cart", `{"product_id": ""}`, "product_id must be at least 1 characters, got 0"},
+		{"wrong type", "button_tap", `{"button_id": 42}`, "button_id must be a string"},
+		{"custom without name", "custom", `{}`, "event_name is required"},
+		{"no rules", "app_start", `{}`, ""},
+		{"not an object", "screen_view", `"Home"`, "properties must be a JSON object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.eventType, json.RawMessage(tt.props))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_ListsAllViolations(t *testing.T) {
+	err := Validate("screen_view", json.RawMessage(`{"screen_name": null}`))
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Validate() error = %v, want *ValidationError", err)
+	}
+	if verr.EventType != "screen_view" || len(verr.Violations) != 1 || verr.Violations[0].Field != "screen_name" {
+		t.Errorf("unexpected error: %+v", verr)
+	}
+}
+
+func TestRule_Bounds(t *testing.T) {
+	level := Rule{Field: "battery_level", Min: 0, HasMin: true, Max: 100, HasMax: true}
+	quantity := Rule{Field: "quantity", Min: 0, HasMin: true, ExclusiveMin: true}
+	name := Rule{Field: "name", MinLen: 2, MaxLen: 3}
+
+	tests := []struct {
+		rule Rule
+		raw  string
+		want string
+	}{
+		{level, `50`, ""},
+		{level, `101`, "must be at most 100, got 101"},
+		{level, `-1`, "must be at least 0, got -1"},
+		{level, `"full"`, "must be a number"},
+		{quantity, `1`, ""},
+		{quantity, ``, "must be greater than 0, got 0"},
+		{name, `"日本"`, ""},
+		{name, `"abcd"`, "must be at most 3 characters, got 4"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.rule.check(json.RawMessage(tt.raw)); got != tt.want {
+			t.Errorf("%s.check(%s) = %q, want %q", tt.rule.Field, tt.raw, got, tt.want)
+		}
+	}
+}
@@ -2,12 +2,16 @@
 //
 // It uses modernc.org/sqlite (pure Go, no CGO) for gomobile cross-compilation
 // compatibility. The database operates in WAL mode for concurrent read/write access
-// and automatically runs schema migrations on open.
+// and automatically runs schema migrations on open. A database that is
+// corrupt or cannot be migrated is deleted and rebuilt empty, so the SDK keeps
+// working at the cost of the events queued in it.
 package storage
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"os"
 
 	// Register the pure-Go SQLite driver. This does NOT require CGO.
 	_ "modernc.org/sqlite"
@@ -18,15 +22,49 @@ import (
 type DB struct {
 	inner *sql.DB
 	path  string
+
+	// rebuildCause is why an existing database was discarded on open, or nil.
+	rebuildCause error
 }
 
 // NewDB opens (or creates) a SQLite database at dbPath with WAL mode and busy timeout.
 // Migrations are applied automatically on open.
+//
+// If an existing database fails to open, fails its integrity check, or cannot
+// be migrated, its files are deleted and a fresh database is created in its
+// place; RebuildCause then reports why. An error is returned only if the fresh
+// database cannot be set up either.
 func NewDB(dbPath string) (*DB, error) {
 	if dbPath == "" {
 		return nil, fmt.Errorf("database path must not be empty")
 	}
 
+	sqlDB, err := openAndMigrate(dbPath)
+	if err == nil {
+		return &DB{inner: sqlDB, path: dbPath}, nil
+	}
+
+	if _, statErr := os.Stat(dbPath); statErr != nil {
+		// Nothing to rebuild: the failure is not caused by existing data.
+		return nil, err
+	}
+
+	cause := err
+	if err := removeDBFiles(dbPath); err != nil {
+		return nil, fmt.Errorf("rebuild database after %v: %w", cause, err)
+	}
+
+	sqlDB, err = openAndMigrate(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("rebuild database after %v: %w", cause, err)
+	}
+
+	return &DB{inner: sqlDB, path: dbPath, rebuildCause: cause}, nil
+}
+
+// openAndMigrate opens the database at dbPath, checks its integrity and
+// applies pending migrations.
+func openAndMigrate(dbPath string) (*sql.DB, error) {
 	// WAL mode for concurrent access, 5s busy timeout for lock contention.
 	dsn := dbPath + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
 
@@ -41,16 +79,47 @@ func NewDB(dbPath string) (*DB, error) {
 		return nil, fmt.Errorf("ping database: %w", err)
 	}
 
+	if err := checkIntegrity(sqlDB); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+
 	// Run schema migrations.
 	if err := runMigrations(sqlDB); err != nil {
 		sqlDB.Close()
 		return nil, fmt.Errorf("run migrations: %w", err)
 	}
 
-	return &DB{
-		inner: sqlDB,
-		path:  dbPath,
-	}, nil
+	return sqlDB, nil
+}
+
+// checkIntegrity runs SQLite's quick_check, which catches corrupt pages and
+// indexes without the cost of a full integrity_check.
+func checkIntegrity(db *sql.DB) error {
+	var result string
+	if err := db.QueryRow("PRAGMA quick_check(1)").Scan(&result); err != nil {
+		return fmt.Errorf("integrity check: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("integrity check: %s", result)
+	}
+	return nil
+}
+
+// removeDBFiles deletes the database file and its WAL and shared-memory files.
+func removeDBFiles(dbPath string) error {
+	for _, path := range []string{dbPath, dbPath + "-wal", dbPath + "-shm"} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// RebuildCause returns why the existing database was discarded and rebuilt
+// on open, or nil if it was opened normally.
+func (db *DB) RebuildCause() error {
+	return db.rebuildCause
 }
 
 // Close closes the database connection.
@@ -5,10 +5,13 @@ import (
 	"fmt"
 )
 
-// migration represents a versioned schema change.
+// migration represents a versioned schema change. A migration is either SQL
+// (up) or, for changes that need to inspect or rewrite existing rows, a
+// function (apply). Both run inside the migration's transaction.
 type migration struct {
 	version int
 	up      string
+	apply   func(tx *sql.Tx) error
 }
 
 // migrations is the ordered list of schema migrations.
@@ -40,10 +43,53 @@ CREATE TABLE IF NOT EXISTS device_info (
 );
 `,
 	},
+	{
+		version: 3,
+		up: `
+ALTER TABLE events ADD COLUMN leased_until INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_events_lease ON events(leased_until, created_at);
+`,
+	},
+}
+
+// latestVersion returns the schema version the migrations produce.
+func latestVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].version
 }
 
-// runMigrations applies all pending migrations inside a transaction.
+// validateMigrations checks that versions start at 1 and increase by one, so a
+// version recorded in schema_version always identifies the same schema.
+func validateMigrations(ms []migration) error {
+	for i, m := range ms {
+		if m.version != i+1 {
+			return fmt.Errorf("migration %d has version %d, want %d", i, m.version, i+1)
+		}
+		if (m.up == "") == (m.apply == nil) {
+			return fmt.Errorf("migration v%d must have exactly one of up or apply", m.version)
+		}
+	}
+	return nil
+}
+
+// runMigrations brings the schema up to date, applying each pending migration
+// in its own transaction so a failure leaves the database at the last good
+// version. Migrations only move forward: a database already at a newer
+// version (written by a newer SDK before a downgrade) is left as is, since
+// migrations only add to the schema and older code can still use it.
 func runMigrations(db *sql.DB) error {
+	return applyMigrations(db, migrations)
+}
+
+// applyMigrations applies the pending migrations in ms.
+func applyMigrations(db *sql.DB, ms []migration) error {
+	if err := validateMigrations(ms); err != nil {
+		return err
+	}
+
 	// Ensure the schema_version table exists (bootstrap).
 	if _, err := db.Exec(`
 		CREATE TABLE IF NOT EXISTS schema_version (
@@ -58,31 +104,43 @@ func runMigrations(db *sql.DB) error {
 		return fmt.Errorf("get current version: %w", err)
 	}
 
-	for _, m := range migrations {
+	for _, m := range ms {
 		if m.version <= current {
 			continue
 		}
-
-		tx, err := db.Begin()
-		if err != nil {
-			return fmt.Errorf("begin migration v%d: %w", m.version, err)
+		if err := applyMigration(db, m); err != nil {
+			return err
 		}
+	}
 
-		if _, err := tx.Exec(m.up); err != nil {
-			tx.Rollback()
-			return fmt.Errorf("apply migration v%d: %w", m.version, err)
-		}
+	return nil
+}
 
-		if _, err := tx.Exec("INSERT INTO schema_version (version) VALUES (?)", m.version); err != nil {
-			tx.Rollback()
-			return fmt.Errorf("record migration v%d: %w", m.version, err)
-		}
+// applyMigration runs a single migration and records its version.
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin migration v%d: %w", m.version, err)
+	}
 
-		if err := tx.Commit(); err != nil {
-			return fmt.Errorf("commit migration v%d: %w", m.version, err)
-		}
+	if m.apply != nil {
+		err = m.apply(tx)
+	} else {
+		_, err = tx.Exec(m.up)
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("apply migration v%d: %w", m.version, err)
+	}
+
+	if _, err := tx.Exec("INSERT INTO schema_version (version) VALUES (?)", m.version); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("record migration v%d: %w", m.version, err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit migration v%d: %w", m.version, err)
+	}
 	return nil
 }
 
@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFixture creates a database at dbPath from an SQL fixture in testdata,
+// without running migrations.
+func writeFixture(t *testing.T, dbPath, fixture string) {
+	t.Helper()
+
+	script, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open fixture database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(string(script)); err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+}
+
+func TestMigrations_AreValid(t *testing.T) {
+	if err := validateMigrations(migrations); err != nil {
+		t.Fatalf("validateMigrations: %v", err)
+	}
+}
+
+func TestValidateMigrations_RejectsBadLists(t *testing.T) {
+	cases := map[string][]migration{
+		"gap":       {{version: 1, up: "SELECT 1"}, {version: 3, up: "SELECT 1"}},
+		"reordered": {{version: 2, up: "SELECT 1"}, {version: 1, up: "SELECT 1"}},
+		"empty":     {{version: 1}},
+		"both": {{version: 1, up: "SELECT 1", apply: func(tx *sql.Tx) error {
+			return nil
+		}}},
+	}
+	for name, ms := range cases {
+		if err := validateMigrations(ms); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestNewDB_MigratesFromV1Fixture(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	writeFixture(t, dbPath, "v1.sql")
+
+	db, err := NewDB(dbPath)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	defer db.Close()
+
+	if cause := db.RebuildCause(); cause != nil {
+		t.Fatalf("v1 database was rebuilt: %v", cause)
+	}
+
+	version, err := getCurrentVersion(db.Inner())
+	if err != nil {
+		t.Fatalf("getCurrentVersion: %v", err)
+	}
+	if version != latestVersion() {
+		t.Errorf("schema version = %d, want %d", version, latestVersion())
+	}
+
+	// Queued events survive the upgrade.
+	q := NewQueue(db, 100)
+	events, err := q.DequeueBatch(10)
+	if err != nil {
+		t.Fatalf("DequeueBatch: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("events = %d, want 2", len(events))
+	}
+	if events[0].IdempotencyKey != "v1-key-1" || events[1].RetryCount != 2 {
+		t.Errorf("unexpected events after migration: %+v", events)
+	}
+
+	// Tables added after v1 exist.
+	if _, err := db.Exec("INSERT INTO device_info (key, value) VALUES ('device_id', 'd1')"); err != nil {
+		t.Errorf("device_info after migration: %v", err)
+	}
+}
+
+func TestNewDB_KeepsNewerSchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	db, err := NewDB(dbPath)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	newer := latestVersion() + 1
+	if _, err := db.Exec("INSERT INTO schema_version (version) VALUES (?)", newer); err != nil {
+		t.Fatalf("record newer version: %v", err)
+	}
+	db.Close()
+
+	db, err = NewDB(dbPath)
+	if err != nil {
+		t.Fatalf("reopen NewDB: %v", err)
+	}
+	defer db.Close()
+
+	if cause := db.RebuildCause(); cause != nil {
+		t.Fatalf("newer database was rebuilt: %v", cause)
+	}
+	if version, _ := getCurrentVersion(db.Inner()); version != newer {
+		t.Errorf("schema version = %d, want %d", version, newer)
+	}
+}
+
+func TestNewDB_RebuildsCorruptDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	garbage := make([]byte, 8192)
+	for i := range garbage {
+		garbage[i] = byte(i * 31)
+	}
+	if err := os.WriteFile(dbPath, garbage, 0o600); err != nil {
+		t.Fatalf("write corrupt file: %v", err)
+	}
+
+	db, err := NewDB(dbPath)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	defer db.Close()
+
+	if db.RebuildCause() == nil {
+		t.Error("RebuildCause() = nil, want the corruption error")
+	}
+
+	// The rebuilt database is fully usable.
+	q := NewQueue(db, 100)
+	if err := q.Enqueue(`{"type":"test"}`, "after-rebuild"); err != nil {
+		t.Fatalf("Enqueue after rebuild: %v", err)
+	}
+	if version, _ := getCurrentVersion(db.Inner()); version != latestVersion() {
+		t.Errorf("schema version = %d, want %d", version, latestVersion())
+	}
+}
+
+func TestApplyMigrations_FailureKeepsLastGoodVersion(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	errBoom := errors.New("boom")
+	ms := []migration{
+		{version: 1, up: "CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT)"},
+		{version: 2, apply: func(tx *sql.Tx) error {
+			if _, err := tx.Exec("INSERT INTO t (name) VALUES ('partial')"); err != nil {
+				return err
+			}
+			return errBoom
+		}},
+	}
+
+	if err := applyMigrations(db, ms); !errors.Is(err, errBoom) {
+		t.Fatalf("applyMigrations error = %v, want %v", err, errBoom)
+	}
+
+	if version, _ := getCurrentVersion(db); version != 1 {
+		t.Errorf("schema version = %d, want 1", version)
+	}
+	var rows int
+	if err := db.QueryRow("SELECT COUNT(*) FROM t").Scan(&rows); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if rows != 0 {
+		t.Errorf("rows after rolled-back migration = %d, want 0", rows)
+	}
+
+	// A fixed migration applies on the next run.
+	ms[1].apply = func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT INTO t (name) VALUES ('done')")
+		return err
+	}
+	if err := applyMigrations(db, ms); err != nil {
+		t.Fatalf("applyMigrations retry: %v", err)
+	}
+	if version, _ := getCurrentVersion(db); version != 2 {
+		t.Errorf("schema version after retry = %d, want 2", version)
+	}
+}
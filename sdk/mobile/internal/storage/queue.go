@@ -1,7 +1,9 @@
 package storage
 
 import (
+	"database/sql"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 )
@@ -24,11 +26,21 @@ type QueuedEvent struct {
 	RetryCount int
 }
 
+// DefaultLeaseTimeout is how long dequeued events stay hidden from other
+// dequeues when their lease is not released. It must outlast a batch send
+// including its retries.
+const DefaultLeaseTimeout = 5 * time.Minute
+
 // Queue provides a FIFO persistent event queue backed by SQLite.
 // When the queue reaches maxSize, the oldest events are evicted to make room.
+//
+// Dequeued events are leased: they are hidden from further dequeues until
+// they are deleted, released (explicitly or by MarkRetry), or the lease times
+// out, so concurrent flushes never send the same event twice.
 type Queue struct {
-	db      *DB
-	maxSize int
+	db           *DB
+	maxSize      int
+	leaseTimeout time.Duration
 }
 
 // NewQueue creates a new Queue with the given DB and maximum size.
@@ -38,8 +50,17 @@ func NewQueue(db *DB, maxSize int) *Queue {
 		maxSize = 1000
 	}
 	return &Queue{
-		db:      db,
-		maxSize: maxSize,
+		db:           db,
+		maxSize:      maxSize,
+		leaseTimeout: DefaultLeaseTimeout,
+	}
+}
+
+// SetLeaseTimeout sets how long dequeued events stay leased.
+// Non-positive values are ignored.
+func (q *Queue) SetLeaseTimeout(d time.Duration) {
+	if d > 0 {
+		q.leaseTimeout = d
 	}
 }
 
@@ -74,23 +95,32 @@ func (q *Queue) Enqueue(eventJSON string, idempotencyKey string) error {
 	return nil
 }
 
-// DequeueBatch returns up to n events in FIFO order (oldest first).
-// Events are NOT removed; call Delete after successful delivery.
+// DequeueBatch leases and returns up to n unleased events in FIFO order
+// (oldest first). Events are NOT removed; call Delete after successful
+// delivery, or MarkRetry or Release after a failure to make them available
+// again. Unreleased leases expire after the lease timeout.
 // Returns an empty slice (not nil) if no events are available.
 func (q *Queue) DequeueBatch(n int) ([]QueuedEvent, error) {
 	if n <= 0 {
 		return []QueuedEvent{}, nil
 	}
 
+	// Select and lease in one statement so two dequeues cannot both claim
+	// the same rows.
+	now := time.Now()
 	rows, err := q.db.Query(
-		`SELECT id, event_json, idempotency_key, created_at, retry_count
-		 FROM events
-		 ORDER BY created_at ASC, id ASC
-		 LIMIT ?`,
-		n,
+		`UPDATE events SET leased_until = ?
+		 WHERE id IN (
+			SELECT id FROM events
+			WHERE leased_until <= ?
+			ORDER BY created_at ASC, id ASC
+			LIMIT ?
+		 )
+		 RETURNING id, event_json, idempotency_key, created_at, retry_count`,
+		now.Add(q.leaseTimeout).UnixMilli(), now.UnixMilli(), n,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("query events: %w", err)
+		return nil, fmt.Errorf("lease events: %w", err)
 	}
 	defer rows.Close()
 
@@ -111,9 +141,47 @@ func (q *Queue) DequeueBatch(n int) ([]QueuedEvent, error) {
 		events = []QueuedEvent{}
 	}
 
+	// RETURNING does not preserve the subquery's order.
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].CreatedAt != events[j].CreatedAt {
+			return events[i].CreatedAt < events[j].CreatedAt
+		}
+		return events[i].ID < events[j].ID
+	})
+
 	return events, nil
 }
 
+// Release ends the leases on the given events, making them available to the
+// next dequeue without counting a retry.
+func (q *Queue) Release(ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	query := fmt.Sprintf("UPDATE events SET leased_until = 0 WHERE id IN (%s)", strings.Join(placeholders, ","))
+	if _, err := q.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("release events: %w", err)
+	}
+	return nil
+}
+
+// ReleaseAll ends every lease. Called on startup, since leases taken by a
+// previous process cannot still be in flight.
+func (q *Queue) ReleaseAll() error {
+	if _, err := q.db.Exec("UPDATE events SET leased_until = 0 WHERE leased_until > 0"); err != nil {
+		return fmt.Errorf("release all events: %w", err)
+	}
+	return nil
+}
+
 // Delete removes events by their IDs. Call this after successful delivery.
 func (q *Queue) Delete(ids []int64) error {
 	if len(ids) == 0 {
@@ -137,11 +205,12 @@ func (q *Queue) Delete(ids []int64) error {
 	return nil
 }
 
-// MarkRetry increments the retry count and updates last_retry_at for an event.
+// MarkRetry increments the retry count and updates last_retry_at for an event,
+// and releases its lease so the next flush can pick it up again.
 func (q *Queue) MarkRetry(id int64) error {
 	now := time.Now().UnixMilli()
 	result, err := q.db.Exec(
-		`UPDATE events SET retry_count = retry_count + 1, last_retry_at = ? WHERE id = ?`,
+		`UPDATE events SET retry_count = retry_count + 1, last_retry_at = ?, leased_until = 0 WHERE id = ?`,
 		now, id,
 	)
 	if err != nil {
@@ -169,6 +238,44 @@ func (q *Queue) Count() (int, error) {
 	return count, nil
 }
 
+// OldestCreatedAt returns the enqueue time (Unix milliseconds) of the oldest
+// queued event, or 0 if the queue is empty.
+func (q *Queue) OldestCreatedAt() (int64, error) {
+	var oldest sql.NullInt64
+	err := q.db.QueryRow("SELECT MIN(created_at) FROM events").Scan(&oldest)
+	if err != nil {
+		return 0, fmt.Errorf("oldest event: %w", err)
+	}
+	return oldest.Int64, nil
+}
+
+// DeleteExceptTypes removes all events whose "type" field is not in types
+// and returns the number removed. Used to purge events after consent is
+// narrowed.
+func (q *Queue) DeleteExceptTypes(types []string) (int64, error) {
+	query := "DELETE FROM events"
+	args := make([]interface{}, len(types))
+	if len(types) > 0 {
+		placeholders := make([]string, len(types))
+		for i, t := range types {
+			placeholders[i] = "?"
+			args[i] = t
+		}
+		query += fmt.Sprintf(" WHERE CASE WHEN json_valid(event_json) THEN COALESCE(json_extract(event_json, '$.type'), '') ELSE '' END NOT IN (%s)", strings.Join(placeholders, ","))
+	}
+
+	result, err := q.db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("delete events by type: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("rows affected: %w", err)
+	}
+	return deleted, nil
+}
+
 // Clear removes all events from the queue. Used for ResetAll.
 func (q *Queue) Clear() error {
 	_, err := q.db.Exec("DELETE FROM events")
@@ -232,11 +232,14 @@ func TestDelete_RemovesEvents(t *testing.T) {
 		t.Fatalf("DequeueBatch: %v", err)
 	}
 
-	// Delete the first two events.
+	// Delete the first two events and return the third to the queue.
 	err = q.Delete([]int64{events[0].ID, events[1].ID})
 	if err != nil {
 		t.Fatalf("Delete: %v", err)
 	}
+	if err := q.Release([]int64{events[2].ID}); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
 
 	// Only the third event should remain.
 	remaining, err := q.DequeueBatch(10)
@@ -574,3 +577,144 @@ func TestQueue_PersistsSurvivesReopen(t *testing.T) {
 		}
 	}
 }
+
+func TestOldestCreatedAt(t *testing.T) {
+	q, _ := newTestQueue(t, 100)
+
+	oldest, err := q.OldestCreatedAt()
+	if err != nil {
+		t.Fatalf("OldestCreatedAt on empty queue: %v", err)
+	}
+	if oldest != 0 {
+		t.Errorf("empty queue oldest: got %d, want 0", oldest)
+	}
+
+	before := time.Now().UnixMilli()
+	if err := q.Enqueue(`{"type":"a"}`, "a"); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if err := q.Enqueue(`{"type":"b"}`, "b"); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	oldest, err = q.OldestCreatedAt()
+	if err != nil {
+		t.Fatalf("OldestCreatedAt: %v", err)
+	}
+	events, _ := q.DequeueBatch(1)
+	if oldest != events[0].CreatedAt || oldest < before {
+		t.Errorf("oldest: got %d, want %d", oldest, events[0].CreatedAt)
+	}
+}
+
+func TestDeleteExceptTypes(t *testing.T) {
+	q, _ := newTestQueue(t, 100)
+
+	q.Enqueue(`{"type":"screen_view"}`, "k1")
+	q.Enqueue(`{"type":"app_start"}`, "k2")
+	q.Enqueue(`{"type":"button_tap"}`, "k3")
+	q.Enqueue(`not json`, "k4")
+
+	deleted, err := q.DeleteExceptTypes([]string{"app_start"})
+	if err != nil {
+		t.Fatalf("DeleteExceptTypes: %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("deleted: got %d, want 3", deleted)
+	}
+
+	events, _ := q.DequeueBatch(10)
+	if len(events) != 1 || events[0].EventJSON != `{"type":"app_start"}` {
+		t.Errorf("remaining: got %+v, want only app_start", events)
+	}
+}
+
+func TestDequeueBatch_LeasesEvents(t *testing.T) {
+	q, _ := newTestQueue(t, 100)
+
+	for i := 0; i < 4; i++ {
+		if err := q.Enqueue(fmt.Sprintf(`{"n":%d}`, i), fmt.Sprintf("lease-key-%d", i)); err != nil {
+			t.Fatalf("Enqueue %d: %v", i, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	first, err := q.DequeueBatch(2)
+	if err != nil {
+		t.Fatalf("first DequeueBatch: %v", err)
+	}
+	second, err := q.DequeueBatch(10)
+	if err != nil {
+		t.Fatalf("second DequeueBatch: %v", err)
+	}
+	if len(first) != 2 || len(second) != 2 {
+		t.Fatalf("expected 2 and 2 events, got %d and %d", len(first), len(second))
+	}
+	if second[0].IdempotencyKey != "lease-key-2" || second[1].IdempotencyKey != "lease-key-3" {
+		t.Errorf("second dequeue returned leased events: %+v", second)
+	}
+
+	// Leased events are still queued.
+	if count, _ := q.Count(); count != 4 {
+		t.Errorf("Count = %d, want 4", count)
+	}
+	if events, _ := q.DequeueBatch(10); len(events) != 0 {
+		t.Errorf("expected no unleased events, got %d", len(events))
+	}
+
+	// A failed delivery returns events to the queue.
+	if err := q.MarkRetry(first[0].ID); err != nil {
+		t.Fatalf("MarkRetry: %v", err)
+	}
+	if err := q.Release([]int64{first[1].ID}); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	again, err := q.DequeueBatch(10)
+	if err != nil {
+		t.Fatalf("DequeueBatch after release: %v", err)
+	}
+	if len(again) != 2 || again[0].ID != first[0].ID || again[0].RetryCount != 1 || again[1].RetryCount != 0 {
+		t.Errorf("unexpected events after release: %+v", again)
+	}
+}
+
+func TestDequeueBatch_LeaseExpires(t *testing.T) {
+	q, _ := newTestQueue(t, 100)
+	q.SetLeaseTimeout(20 * time.Millisecond)
+
+	if err := q.Enqueue(`{"n":0}`, "expire-key"); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if events, _ := q.DequeueBatch(1); len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	if events, _ := q.DequeueBatch(1); len(events) != 0 {
+		t.Fatalf("expected leased event to be hidden, got %d", len(events))
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	if events, _ := q.DequeueBatch(1); len(events) != 1 {
+		t.Errorf("expected event after lease expiry, got %d", len(events))
+	}
+}
+
+func TestReleaseAll(t *testing.T) {
+	q, _ := newTestQueue(t, 100)
+
+	for i := 0; i < 3; i++ {
+		if err := q.Enqueue(fmt.Sprintf(`{"n":%d}`, i), fmt.Sprintf("release-key-%d", i)); err != nil {
+			t.Fatalf("Enqueue %d: %v", i, err)
+		}
+	}
+	if events, _ := q.DequeueBatch(3); len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+
+	if err := q.ReleaseAll(); err != nil {
+		t.Fatalf("ReleaseAll: %v", err)
+	}
+	if events, _ := q.DequeueBatch(10); len(events) != 3 {
+		t.Errorf("expected 3 events after ReleaseAll, got %d", len(events))
+	}
+}
@@ -0,0 +1,21 @@
+-- Database as written by an SDK release at schema version 1: the events queue
+-- only, with two queued events, one of which has already been retried.
+CREATE TABLE schema_version (
+    version INTEGER PRIMARY KEY
+);
+INSERT INTO schema_version (version) VALUES (1);
+
+CREATE TABLE events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_json TEXT NOT NULL,
+    idempotency_key TEXT NOT NULL UNIQUE,
+    created_at INTEGER NOT NULL,
+    retry_count INTEGER DEFAULT 0,
+    last_retry_at INTEGER DEFAULT 0
+);
+CREATE INDEX idx_events_created ON events(created_at);
+CREATE INDEX idx_events_retry ON events(retry_count, last_retry_at);
+
+INSERT INTO events (event_json, idempotency_key, created_at, retry_count, last_retry_at) VALUES
+    ('{"type":"screen_view","properties":{"screen_name":"Home"}}', 'v1-key-1', 1700000000000, 0, 0),
+    ('{"type":"button_tap","properties":{"button_id":"buy"}}', 'v1-key-2', 1700000001000, 2, 1700000005000);
@@ -1,48 +1,210 @@
 package transport
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+
 	causalityv1 "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
 )
 
+// delimitedBatchContentType is the gateway's length-prefixed EventEnvelope
+// batch format. It is sent gzip-compressed once the server advertises it.
+const delimitedBatchContentType = "application/x-causality-batch"
+
+// batchPath is the batch ingestion endpoint.
+const batchPath = "/v1/events/batch"
+
+// userAgent identifies the SDK in requests.
+const userAgent = "CausalitySDK/1.0.0 Go"
+
+// Clock sync headers: the device send time on requests, and the gateway
+// receive time and echoed send time on responses, in Unix milliseconds.
+const (
+	clientTimeHeader = "Causality-Client-Time"
+	serverTimeHeader = "Causality-Server-Time"
+)
+
+// sdkHeader is the SDK handshake header, identifying the SDK and the
+// protocol features it uses to the gateway.
+const sdkHeader = "X-Causality-SDK"
+
+// minSDKVersionHeader carries the app's minimum SDK version on responses to
+// requests from older SDKs.
+const minSDKVersionHeader = "Causality-Min-SDK-Version"
+
+// ErrSDKVersionUnsupported is returned when the server rejects batches
+// because the SDK is older than the app's minimum version.
+var ErrSDKVersionUnsupported = errors.New("SDK version no longer supported by the server")
+
+// SDKOutdatedFunc is called for every response reporting that the SDK is
+// older than the app's minimum version. rejected reports whether the
+// request was rejected rather than only flagged.
+type SDKOutdatedFunc func(minVersion string, rejected bool)
+
+// SDKInfo is the SDK handshake sent on every request.
+type SDKInfo struct {
+	Name     string
+	Version  string
+	Platform string
+
+	// Capabilities are the optional protocol features the SDK uses, such
+	// as "clock_sync" or "gzip_batch".
+	Capabilities []string
+}
+
+// header returns the SDKHeader value of info.
+func (i SDKInfo) header() string {
+	h := "name=" + i.Name + "; version=" + i.Version
+	if i.Platform != "" {
+		h += "; platform=" + i.Platform
+	}
+	if len(i.Capabilities) > 0 {
+		h += "; capabilities=" + strings.Join(i.Capabilities, ",")
+	}
+	return h
+}
+
+// ClockSampler estimates the device clock offset from server time.
+type ClockSampler interface {
+	// Observe records a request sent at sent and answered at received,
+	// both device times, that the server received at serverMs.
+	Observe(sent, received time.Time, serverMs int64) error
+
+	// SkewMs returns the estimated device minus server time in milliseconds.
+	SkewMs() int64
+}
+
 // SendResult holds the outcome of a batch send operation.
 type SendResult struct {
 	// StatusCode is the HTTP status code from the server.
 	StatusCode int
 
-	// Accepted is the number of events accepted by the server.
+	// Accepted is the number of events accepted by the server, including
+	// deduplicated events.
 	Accepted int
+
+	// Deduplicated is the number of accepted events the server dropped
+	// because it had already stored them.
+	Deduplicated int
+
+	// Retry holds the batch indexes of events the server did not publish
+	// before its deadline. They were not stored and should be sent again.
+	Retry []int
 }
 
-// statusCapture wraps an http.RoundTripper to capture the HTTP status code
-// and Retry-After header from responses. This enables retry decisions when
-// using the generated protobuf client, which doesn't expose raw HTTP details.
+// statusDeduplicated is the per-event status the gateway reports for events
+// whose idempotency key it has already seen.
+const statusDeduplicated = "deduplicated"
+
+// statusPublishTimeout is the per-event status the gateway reports for events
+// it could not publish before its per-request deadline.
+const statusPublishTimeout = "publish_timeout"
+
+// statusCapture wraps an http.RoundTripper to capture the HTTP status code,
+// Retry-After header, and advertised batch formats from responses. This
+// enables retry and format decisions when using the generated protobuf
+// client, which doesn't expose raw HTTP details.
 type statusCapture struct {
 	transport  http.RoundTripper
 	mu         sync.Mutex
 	lastStatus int
 	retryAfter string
+
+	// clock receives clock sync samples; nil disables clock sync.
+	clock ClockSampler
+
+	// sdk is the SDK handshake header value; empty sends none.
+	sdk string
+
+	// onOutdated is notified of responses flagging the SDK as outdated.
+	onOutdated SDKOutdatedFunc
+
+	// acceptsCompressed is set once a response advertises gzip-compressed
+	// delimited batches via Accept-Post and Accept-Encoding.
+	acceptsCompressed atomic.Bool
+
+	// bytesSent totals request body bytes of requests that got a response.
+	bytesSent atomic.Int64
 }
 
 func (s *statusCapture) RoundTrip(req *http.Request) (*http.Response, error) {
+	clock, sdk := s.getHeaders()
+	if clock != nil || sdk != "" {
+		// RoundTrippers must not modify the request.
+		req = req.Clone(req.Context())
+	}
+	if sdk != "" {
+		req.Header.Set(sdkHeader, sdk)
+	}
+	var sent time.Time
+	if clock != nil {
+		sent = time.Now()
+		req.Header.Set(clientTimeHeader, strconv.FormatInt(sent.UnixMilli(), 10))
+	}
+
 	resp, err := s.transport.RoundTrip(req)
 	if err != nil {
 		return resp, err
 	}
+	if clock != nil {
+		s.observeClock(clock, req, resp, sent, time.Now())
+	}
+	if req.ContentLength > 0 {
+		s.bytesSent.Add(req.ContentLength)
+	}
 	s.mu.Lock()
 	s.lastStatus = resp.StatusCode
 	s.retryAfter = resp.Header.Get("Retry-After")
+	onOutdated := s.onOutdated
 	s.mu.Unlock()
+
+	if minVersion := resp.Header.Get(minSDKVersionHeader); minVersion != "" && onOutdated != nil {
+		onOutdated(minVersion, resp.StatusCode == http.StatusUpgradeRequired)
+	}
+
+	if advertisesCompressedBatches(resp.Header) {
+		s.acceptsCompressed.Store(true)
+	}
 	return resp, nil
 }
 
+// observeClock passes the clock sync sample of a response to clock. Responses
+// that do not echo the request's send time, e.g. from a proxy or an older
+// gateway, are ignored.
+func (s *statusCapture) observeClock(clock ClockSampler, req *http.Request, resp *http.Response, sent, received time.Time) {
+	if resp.Header.Get(clientTimeHeader) != req.Header.Get(clientTimeHeader) {
+		return
+	}
+	serverMs, err := strconv.ParseInt(resp.Header.Get(serverTimeHeader), 10, 64)
+	if err != nil {
+		return
+	}
+	if err := clock.Observe(sent, received, serverMs); err != nil {
+		log.Printf("[Causality:Transport] Clock sync sample not recorded: %v", err)
+	}
+}
+
+// advertisesCompressedBatches reports whether response headers advertise
+// gzip-compressed delimited batches.
+func advertisesCompressedBatches(h http.Header) bool {
+	return strings.Contains(h.Get("Accept-Post"), delimitedBatchContentType) &&
+		strings.Contains(strings.ToLower(h.Get("Accept-Encoding")), "gzip")
+}
+
 func (s *statusCapture) reset() {
 	s.mu.Lock()
 	s.lastStatus = 0
@@ -50,6 +212,18 @@ func (s *statusCapture) reset() {
 	s.mu.Unlock()
 }
 
+func (s *statusCapture) getClock() ClockSampler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clock
+}
+
+func (s *statusCapture) getHeaders() (ClockSampler, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clock, s.sdk
+}
+
 func (s *statusCapture) getLastStatus() (int, string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -58,11 +232,39 @@ func (s *statusCapture) getLastStatus() (int, string) {
 
 // Client sends event batches to the Causality server using the generated
 // protobuf HTTP client. It handles retries with configurable backoff strategies.
+//
+// Batches start out as JSON. Once a server response advertises
+// gzip-compressed delimited protobuf batches, later batches use that format
+// instead; a 415 response switches the client back to JSON for good.
+//
+// Batches go to the primary endpoint until it fails; see SetFallbackEndpoints.
 type Client struct {
-	rpcClient causalityv1.EventServiceClient
-	capture   *statusCapture
-	retry     RetryStrategy
-	endpoint  string
+	httpClient *http.Client
+	capture    *statusCapture
+	retry      RetryStrategy
+	apiKey     string
+	now        func() time.Time
+
+	// failoverMu guards endpoint selection.
+	failoverMu sync.Mutex
+
+	// endpoints holds the primary endpoint followed by its fallbacks.
+	endpoints []*ingestEndpoint
+
+	// active is the index of the endpoint batches are sent to.
+	active int
+
+	// probeInterval is how often the primary endpoint is tried again while
+	// a fallback is active, and nextProbe when it is next tried.
+	probeInterval time.Duration
+	nextProbe     time.Time
+
+	// compression enables negotiating compressed batches (default: true).
+	compression atomic.Bool
+
+	// compressedRejected is set when the server answered a compressed
+	// batch with 415 Unsupported Media Type.
+	compressedRejected atomic.Bool
 }
 
 // NewClient creates a new transport client backed by the generated protobuf client.
@@ -83,20 +285,67 @@ func NewClient(endpoint, apiKey string, timeout time.Duration, retry RetryStrate
 		Transport: capture,
 	}
 
-	rpcClient := causalityv1.NewEventServiceClient(
-		endpoint,
-		causalityv1.WithEventServiceHTTPClient(httpClient),
-		causalityv1.WithEventServiceContentType(causalityv1.ContentTypeJSON),
-		causalityv1.WithEventServiceDefaultHeader("X-API-Key", apiKey),
-		causalityv1.WithEventServiceDefaultHeader("User-Agent", "CausalitySDK/1.0.0 Go"),
-	)
+	c := &Client{
+		httpClient:    httpClient,
+		capture:       capture,
+		retry:         retry,
+		apiKey:        apiKey,
+		now:           time.Now,
+		endpoints:     []*ingestEndpoint{newIngestEndpoint(endpoint, apiKey, httpClient)},
+		probeInterval: DefaultProbeInterval,
+	}
+	c.compression.Store(true)
+	return c
+}
+
+// BytesSent returns the total request body bytes delivered to the server,
+// after compression.
+func (c *Client) BytesSent() int64 {
+	return c.capture.bytesSent.Load()
+}
+
+// SetCompression enables or disables sending gzip-compressed protobuf
+// batches when the server supports them.
+func (c *Client) SetCompression(enabled bool) {
+	c.compression.Store(enabled)
+}
+
+// SetClockSampler enables clock sync: requests carry the device send time
+// and response server times are passed to clock, whose estimate is attached
+// to the device context of sent events. A nil clock disables clock sync.
+func (c *Client) SetClockSampler(clock ClockSampler) {
+	c.capture.mu.Lock()
+	c.capture.clock = clock
+	c.capture.mu.Unlock()
+}
+
+// SetSDKInfo sets the SDK handshake sent on every request, which the
+// gateway records in the device context of events and its audit log.
+func (c *Client) SetSDKInfo(info SDKInfo) {
+	c.capture.mu.Lock()
+	c.capture.sdk = info.header()
+	c.capture.mu.Unlock()
+}
 
-	return &Client{
-		rpcClient: rpcClient,
-		capture:   capture,
-		retry:     retry,
-		endpoint:  endpoint,
+// SetSDKOutdatedHandler sets the function notified when the server reports
+// that the SDK is older than the app's minimum version.
+func (c *Client) SetSDKOutdatedHandler(onOutdated SDKOutdatedFunc) {
+	c.capture.mu.Lock()
+	c.capture.onOutdated = onOutdated
+	c.capture.mu.Unlock()
+}
+
+// clockSkewMs returns the estimated device clock skew, or 0 without clock sync.
+func (c *Client) clockSkewMs() int64 {
+	if clock := c.capture.getClock(); clock != nil {
+		return clock.SkewMs()
 	}
+	return 0
+}
+
+// useCompressed reports whether the next batch should be sent compressed.
+func (c *Client) useCompressed() bool {
+	return c.compression.Load() && !c.compressedRejected.Load() && c.capture.acceptsCompressed.Load()
 }
 
 // SendBatch sends a batch of serialized event JSON strings to the server.
@@ -104,7 +353,8 @@ func NewClient(endpoint, apiKey string, timeout time.Duration, retry RetryStrate
 // Events are converted to protobuf EventEnvelopes and sent via IngestEventBatch.
 //
 // It retries on 5xx, 429, and network errors with the configured retry strategy.
-// Non-retryable errors (4xx except 429) return immediately.
+// Non-retryable errors (4xx except 429) return immediately; 426 Upgrade
+// Required returns ErrSDKVersionUnsupported.
 // The context can be used for cancellation.
 func (c *Client) SendBatch(ctx context.Context, events []string) (*SendResult, error) {
 	if len(events) == 0 {
@@ -112,7 +362,7 @@ func (c *Client) SendBatch(ctx context.Context, events []string) (*SendResult, e
 	}
 
 	// Convert SDK JSON events to protobuf EventEnvelopes
-	envelopes, err := convertEvents(events)
+	envelopes, err := convertEvents(events, c.clockSkewMs())
 	if err != nil {
 		return nil, fmt.Errorf("convert events: %w", err)
 	}
@@ -121,7 +371,7 @@ func (c *Client) SendBatch(ctx context.Context, events []string) (*SendResult, e
 		Events: envelopes,
 	}
 
-	log.Printf("[Causality:Transport] IngestEventBatch %s (%d events)", c.endpoint, len(events))
+	log.Printf("[Causality:Transport] IngestEventBatch %s (%d events)", c.ActiveEndpoint(), len(events))
 
 	var lastErr error
 	maxAttempts := c.retry.MaxAttempts()
@@ -134,18 +384,60 @@ func (c *Client) SendBatch(ctx context.Context, events []string) (*SendResult, e
 		// Reset captured status before each attempt
 		c.capture.reset()
 
-		resp, err := c.rpcClient.IngestEventBatch(ctx, req)
+		index, probe := c.selectEndpoint()
+		ep := c.endpoints[index]
+
+		compressed := c.useCompressed()
+		var resp *causalityv1.IngestEventBatchResponse
+		if compressed {
+			resp, err = c.sendCompressed(ctx, ep, envelopes)
+		} else {
+			resp, err = ep.rpc.IngestEventBatch(ctx, req)
+		}
 		if err != nil {
 			status, retryAfter := c.capture.getLastStatus()
 
 			log.Printf("[Causality:Transport] Error (HTTP %d): %v", status, err)
 
+			// Server stopped accepting compressed batches: fall back to
+			// JSON and resend immediately without using up an attempt.
+			if compressed && status == http.StatusUnsupportedMediaType {
+				log.Printf("[Causality:Transport] Compressed batches rejected, falling back to JSON")
+				c.compressedRejected.Store(true)
+				attempt--
+				continue
+			}
+
+			// A pin mismatch will not resolve itself on retry.
+			if errors.Is(err, ErrPinMismatch) {
+				return nil, fmt.Errorf("non-retryable error: %w", err)
+			}
+
+			// The app must be upgraded before the server accepts batches.
+			if status == http.StatusUpgradeRequired {
+				return nil, fmt.Errorf("non-retryable error: %w: %w", ErrSDKVersionUnsupported, err)
+			}
+
 			// Non-retryable client error (4xx except 429)
 			if status >= 400 && status < 500 && status != http.StatusTooManyRequests {
 				return nil, fmt.Errorf("non-retryable error: %w", err)
 			}
 
-			// Retryable: network error (status 0), 429, or 5xx
+			// Retryable: network error (status 0), 429, or 5xx. A
+			// rate-limiting endpoint is healthy, so only the others fail
+			// over to the next endpoint.
+			if status != http.StatusTooManyRequests {
+				c.endpointFailed(index)
+			}
+
+			// The primary endpoint has not recovered yet: resend to the
+			// active fallback immediately without using up an attempt.
+			if probe {
+				log.Printf("[Causality:Transport] Primary endpoint %s still unavailable", ep.url)
+				attempt--
+				continue
+			}
+
 			lastErr = err
 
 			delay := c.retryDelay(attempt, retryAfter)
@@ -161,12 +453,27 @@ func (c *Client) SendBatch(ctx context.Context, events []string) (*SendResult, e
 			continue
 		}
 
-		log.Printf("[Causality:Transport] Success: accepted=%d, rejected=%d",
-			resp.AcceptedCount, resp.RejectedCount)
+		c.endpointSucceeded(index)
+
+		deduplicated := 0
+		var retry []int
+		for i, r := range resp.GetResults() {
+			switch r.GetStatus() {
+			case statusDeduplicated:
+				deduplicated++
+			case statusPublishTimeout:
+				retry = append(retry, i)
+			}
+		}
+
+		log.Printf("[Causality:Transport] Success: accepted=%d, deduplicated=%d, rejected=%d, publish_timeout=%d",
+			resp.AcceptedCount, deduplicated, resp.RejectedCount, len(retry))
 
 		return &SendResult{
-			StatusCode: 200,
-			Accepted:   int(resp.AcceptedCount),
+			StatusCode:   200,
+			Accepted:     int(resp.AcceptedCount),
+			Deduplicated: deduplicated,
+			Retry:        retry,
 		}, nil
 	}
 
@@ -177,6 +484,60 @@ func (c *Client) SendBatch(ctx context.Context, events []string) (*SendResult, e
 	return nil, fmt.Errorf("all retries exhausted")
 }
 
+// sendCompressed posts envelopes as a gzip-compressed stream of
+// length-delimited protobuf messages and decodes the protobuf response.
+func (c *Client) sendCompressed(ctx context.Context, ep *ingestEndpoint, envelopes []*causalityv1.EventEnvelope) (*causalityv1.IngestEventBatchResponse, error) {
+	body, err := encodeCompressedBatch(envelopes)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.batchURL(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", delimitedBatchContentType)
+	httpReq.Header.Set("Content-Encoding", "gzip")
+	httpReq.Header.Set("X-API-Key", c.apiKey)
+	httpReq.Header.Set("User-Agent", userAgent)
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("send compressed batch: %w", err)
+	}
+	defer func() { _ = httpResp.Body.Close() }()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d: %s", httpResp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var resp causalityv1.IngestEventBatchResponse
+	if err := proto.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &resp, nil
+}
+
+// encodeCompressedBatch gzips the envelopes as varint length-prefixed
+// protobuf messages.
+func encodeCompressedBatch(envelopes []*causalityv1.EventEnvelope) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	for i, env := range envelopes {
+		if _, err := protodelim.MarshalTo(gz, env); err != nil {
+			return nil, fmt.Errorf("encode event %d: %w", i, err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("compress batch: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
 // retryDelay determines the delay before the next retry attempt.
 // If a Retry-After header is present and valid, it takes precedence over
 // the retry strategy's calculated delay.
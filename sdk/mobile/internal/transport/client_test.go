@@ -1,13 +1,24 @@
 package transport
 
 import (
+	"bufio"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	causalityv1 "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
 )
 
 // fastRetry is a quick retry strategy for tests to avoid slow test runs.
@@ -31,14 +42,14 @@ func batchResponse(accepted int) string {
 func TestNewClient(t *testing.T) {
 	c := NewClient("https://example.com/", "test-key", 5*time.Second, nil)
 
-	if c.endpoint != "https://example.com/" {
-		t.Errorf("endpoint: got %q, want %q", c.endpoint, "https://example.com/")
+	if got := c.ActiveEndpoint(); got != "https://example.com/" {
+		t.Errorf("endpoint: got %q, want %q", got, "https://example.com/")
 	}
 	if c.retry == nil {
 		t.Error("retry should default to DefaultRetry when nil")
 	}
-	if c.rpcClient == nil {
-		t.Error("rpcClient should be initialized")
+	if len(c.endpoints) != 1 || c.endpoints[0].rpc == nil {
+		t.Error("rpc client should be initialized")
 	}
 	if c.capture == nil {
 		t.Error("capture should be initialized")
@@ -452,3 +463,291 @@ func TestSendBatch_StatusCaptureResets(t *testing.T) {
 		t.Errorf("Accepted: got %d, want 1", result.Accepted)
 	}
 }
+
+// compressedServer advertises compressed batches and records the content
+// type of each request. It answers compressed batches with status.
+func compressedServer(t *testing.T, status int, contentTypes *[]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*contentTypes = append(*contentTypes, r.Header.Get("Content-Type"))
+		w.Header().Set("Accept-Post", "application/json, application/x-protobuf, "+delimitedBatchContentType)
+		w.Header().Set("Accept-Encoding", "gzip")
+
+		if r.Header.Get("Content-Type") != delimitedBatchContentType {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(batchResponse(1)))
+			return
+		}
+
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+
+		if r.Header.Get("Content-Encoding") != "gzip" {
+			t.Errorf("Content-Encoding: got %q, want gzip", r.Header.Get("Content-Encoding"))
+		}
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Fatalf("gzip reader: %v", err)
+		}
+		var env causalityv1.EventEnvelope
+		if err := protodelim.UnmarshalFrom(bufio.NewReader(gz), &env); err != nil {
+			t.Fatalf("decode delimited event: %v", err)
+		}
+		if env.AppId != "test-app" {
+			t.Errorf("AppId: got %q, want test-app", env.AppId)
+		}
+
+		body, _ := proto.Marshal(&causalityv1.IngestEventBatchResponse{AcceptedCount: 1})
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(body)
+	}))
+}
+
+func TestSendBatch_UpgradesToCompressed(t *testing.T) {
+	var contentTypes []string
+	server := compressedServer(t, http.StatusOK, &contentTypes)
+	defer server.Close()
+
+	c := NewClient(server.URL, "key", 5*time.Second, fastRetry)
+	for i := 0; i < 2; i++ {
+		result, err := c.SendBatch(context.Background(), []string{testScreenViewEvent("home")})
+		if err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+		if result.Accepted != 1 {
+			t.Errorf("send %d: Accepted: got %d, want 1", i, result.Accepted)
+		}
+	}
+
+	want := []string{"application/json", delimitedBatchContentType}
+	if fmt.Sprint(contentTypes) != fmt.Sprint(want) {
+		t.Errorf("content types: got %v, want %v", contentTypes, want)
+	}
+	if c.BytesSent() <= 0 {
+		t.Errorf("BytesSent: got %d, want > 0", c.BytesSent())
+	}
+}
+
+func TestSendBatch_CompressedRejectedFallsBackToJSON(t *testing.T) {
+	var contentTypes []string
+	server := compressedServer(t, http.StatusUnsupportedMediaType, &contentTypes)
+	defer server.Close()
+
+	c := NewClient(server.URL, "key", 5*time.Second, fastRetry)
+	for i := 0; i < 3; i++ {
+		if _, err := c.SendBatch(context.Background(), []string{testScreenViewEvent("home")}); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+
+	want := []string{"application/json", delimitedBatchContentType, "application/json", "application/json"}
+	if fmt.Sprint(contentTypes) != fmt.Sprint(want) {
+		t.Errorf("content types: got %v, want %v", contentTypes, want)
+	}
+}
+
+func TestSendBatch_CompressionDisabled(t *testing.T) {
+	var contentTypes []string
+	server := compressedServer(t, http.StatusOK, &contentTypes)
+	defer server.Close()
+
+	c := NewClient(server.URL, "key", 5*time.Second, fastRetry)
+	c.SetCompression(false)
+	for i := 0; i < 2; i++ {
+		if _, err := c.SendBatch(context.Background(), []string{testScreenViewEvent("home")}); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+
+	for _, ct := range contentTypes {
+		if ct != "application/json" {
+			t.Errorf("content type: got %q, want application/json", ct)
+		}
+	}
+}
+
+func TestSendBatch_ReportsDeduplicated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"acceptedCount":2,"results":[{"index":0,"status":"accepted"},{"index":1,"status":"deduplicated"}]}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "key", 5*time.Second, fastRetry)
+	result, err := c.SendBatch(context.Background(), []string{testScreenViewEvent("a"), testScreenViewEvent("b")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Accepted != 2 || result.Deduplicated != 1 {
+		t.Errorf("result: got accepted=%d deduplicated=%d, want 2 and 1", result.Accepted, result.Deduplicated)
+	}
+}
+
+func TestSendBatch_ReportsPublishTimeoutForRetry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"acceptedCount":1,"rejectedCount":2,"results":[{"index":0,"status":"accepted"},{"index":1,"status":"publish_timeout"},{"index":2,"status":"publish_timeout"}]}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "key", 5*time.Second, fastRetry)
+	result, err := c.SendBatch(context.Background(), []string{testScreenViewEvent("a"), testScreenViewEvent("b"), testScreenViewEvent("c")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Retry) != 2 || result.Retry[0] != 1 || result.Retry[1] != 2 {
+		t.Errorf("retry indexes: got %v, want [1 2]", result.Retry)
+	}
+}
+
+// fakeClock records clock sync samples and reports a fixed skew.
+type fakeClock struct {
+	samples []int64 // server minus send time of each sample, in ms
+	skewMs  int64
+}
+
+func (f *fakeClock) Observe(sent, _ time.Time, serverMs int64) error {
+	f.samples = append(f.samples, serverMs-sent.UnixMilli())
+	return nil
+}
+
+func (f *fakeClock) SkewMs() int64 { return f.skewMs }
+
+func TestSendBatch_ClockSync(t *testing.T) {
+	var echo atomic.Bool
+	echo.Store(true)
+	var skews []int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent, err := strconv.ParseInt(r.Header.Get(clientTimeHeader), 10, 64)
+		if err != nil {
+			t.Errorf("%s: %v", clientTimeHeader, err)
+		}
+		if echo.Load() {
+			w.Header().Set(clientTimeHeader, r.Header.Get(clientTimeHeader))
+		}
+		w.Header().Set(serverTimeHeader, strconv.FormatInt(sent-5000, 10))
+
+		body, _ := io.ReadAll(r.Body)
+		var req causalityv1.IngestEventBatchRequest
+		if err := protojson.Unmarshal(body, &req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		skews = append(skews, req.GetEvents()[0].GetDeviceContext().GetClockSkewMs())
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(batchResponse(1)))
+	}))
+	defer server.Close()
+
+	clock := &fakeClock{skewMs: 5000}
+	c := NewClient(server.URL, "test-key", 5*time.Second, fastRetry)
+	c.SetClockSampler(clock)
+
+	if _, err := c.SendBatch(context.Background(), []string{testScreenViewEvent("Home")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(clock.samples) != 1 || clock.samples[0] != -5000 {
+		t.Errorf("samples: got %v, want [-5000]", clock.samples)
+	}
+	if skews[0] != 5000 {
+		t.Errorf("device context clock_skew_ms: got %d, want 5000", skews[0])
+	}
+
+	// Responses that do not echo the send time are not sampled.
+	echo.Store(false)
+	if _, err := c.SendBatch(context.Background(), []string{testScreenViewEvent("Home")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(clock.samples) != 1 {
+		t.Errorf("samples without echo: got %v, want 1 sample", clock.samples)
+	}
+}
+
+func TestSendBatch_SDKHeader(t *testing.T) {
+	var headers []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Get(sdkHeader))
+		w.Header().Set("Accept-Post", delimitedBatchContentType)
+		w.Header().Set("Accept-Encoding", "gzip")
+		if r.Header.Get("Content-Type") == delimitedBatchContentType {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(batchResponse(1)))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "test-key", 5*time.Second, fastRetry)
+	c.SetSDKInfo(SDKInfo{Name: "causality-mobile", Version: "1.0.0", Platform: "android", Capabilities: []string{"clock_sync", "gzip_batch"}})
+	for i := 0; i < 2; i++ {
+		if _, err := c.SendBatch(context.Background(), []string{testScreenViewEvent("Home")}); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+
+	// JSON, then compressed (rejected) and JSON again: every format carries
+	// the handshake.
+	want := "name=causality-mobile; version=1.0.0; platform=android; capabilities=clock_sync,gzip_batch"
+	if len(headers) != 3 {
+		t.Fatalf("requests: got %d, want 3", len(headers))
+	}
+	for i, h := range headers {
+		if h != want {
+			t.Errorf("request %d %s: got %q, want %q", i, sdkHeader, h, want)
+		}
+	}
+}
+
+func TestSendBatch_SDKOutdated(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(minSDKVersionHeader, "1.4.0")
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error":"upgrade","code":"sdk_version_unsupported","min_version":"1.4.0"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(batchResponse(1)))
+	}))
+	defer server.Close()
+
+	type verdict struct {
+		minVersion string
+		rejected   bool
+	}
+	var got []verdict
+	c := NewClient(server.URL, "test-key", 5*time.Second, fastRetry)
+	c.SetSDKOutdatedHandler(func(minVersion string, rejected bool) {
+		got = append(got, verdict{minVersion, rejected})
+	})
+
+	// Flagged: the batch is accepted.
+	if _, err := c.SendBatch(context.Background(), []string{testScreenViewEvent("Home")}); err != nil {
+		t.Fatalf("flagged send: %v", err)
+	}
+
+	// Rejected: not retried.
+	status = http.StatusUpgradeRequired
+	_, err := c.SendBatch(context.Background(), []string{testScreenViewEvent("Home")})
+	if !errors.Is(err, ErrSDKVersionUnsupported) {
+		t.Fatalf("rejected send: got %v, want ErrSDKVersionUnsupported", err)
+	}
+
+	want := []verdict{{"1.4.0", false}, {"1.4.0", true}}
+	if len(got) != len(want) {
+		t.Fatalf("handler calls: got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("handler call %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+}
@@ -10,6 +10,10 @@ import (
 	"github.com/SebastienMelki/causality/sdk/mobile/internal/device"
 )
 
+// schemaVersion is the envelope schema version this SDK produces. It must
+// match events.CurrentSchemaVersion of the server it was built against.
+const schemaVersion = 2
+
 // sdkEvent mirrors the SDK's Event type for JSON parsing within the transport layer.
 type sdkEvent struct {
 	Type       string          `json:"type"`
@@ -27,9 +31,11 @@ type sdkMetadata struct {
 }
 
 // convertEvents parses SDK JSON event strings into protobuf EventEnvelopes.
-// Device context is collected once per batch and attached to all envelopes.
-func convertEvents(jsonEvents []string) ([]*causalityv1.EventEnvelope, error) {
+// Device context is collected once per batch and attached to all envelopes,
+// with clockSkewMs as the measured device clock skew.
+func convertEvents(jsonEvents []string, clockSkewMs int64) ([]*causalityv1.EventEnvelope, error) {
 	deviceCtx := buildDeviceContext()
+	deviceCtx.ClockSkewMs = clockSkewMs
 	envelopes := make([]*causalityv1.EventEnvelope, 0, len(jsonEvents))
 
 	for i, jsonStr := range jsonEvents {
@@ -43,6 +49,7 @@ func convertEvents(jsonEvents []string) ([]*causalityv1.EventEnvelope, error) {
 			DeviceId:       evt.Metadata.DeviceID,
 			IdempotencyKey: evt.Metadata.IdempotencyKey,
 			DeviceContext:  deviceCtx,
+			SchemaVersion:  schemaVersion,
 		}
 
 		// Parse timestamp from RFC3339Nano to milliseconds since epoch
@@ -115,6 +122,33 @@ func mapNetworkType(nt string) causalityv1.NetworkType {
 	}
 }
 
+func mapBatteryState(state string) causalityv1.BatteryState {
+	switch strings.ToLower(state) {
+	case "charging":
+		return causalityv1.BatteryState_BATTERY_STATE_CHARGING
+	case "discharging", "unplugged":
+		return causalityv1.BatteryState_BATTERY_STATE_DISCHARGING
+	case "full":
+		return causalityv1.BatteryState_BATTERY_STATE_FULL
+	default:
+		return causalityv1.BatteryState_BATTERY_STATE_UNSPECIFIED
+	}
+}
+
+// enumValue decodes an enum property sent either as a name, mapped with
+// byName, or as its number, as the generated native event types send it.
+func enumValue(raw json.RawMessage, byName func(string) int32) int32 {
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		return byName(name)
+	}
+	var number int32
+	if err := json.Unmarshal(raw, &number); err == nil {
+		return number
+	}
+	return 0
+}
+
 // setPayload unmarshals event properties and sets the correct oneof payload on the envelope.
 func setPayload(env *causalityv1.EventEnvelope, eventType string, props json.RawMessage) error {
 	switch eventType {
@@ -202,6 +236,53 @@ func setPayload(env *causalityv1.EventEnvelope, eventType string, props json.Raw
 		}
 		env.Payload = &causalityv1.EventEnvelope_AppForeground{AppForeground: &p}
 
+	case "app_crash":
+		var p causalityv1.AppCrash
+		if err := unmarshalProps(props, &p); err != nil {
+			return err
+		}
+		env.Payload = &causalityv1.EventEnvelope_AppCrash{AppCrash: &p}
+
+	case "network_change":
+		var p struct {
+			PreviousType json.RawMessage `json:"previous_type"`
+			CurrentType  json.RawMessage `json:"current_type"`
+		}
+		if err := unmarshalProps(props, &p); err != nil {
+			return err
+		}
+		env.Payload = &causalityv1.EventEnvelope_NetworkChange{NetworkChange: &causalityv1.NetworkChange{
+			PreviousType: causalityv1.NetworkType(enumValue(p.PreviousType, func(s string) int32 { return int32(mapNetworkType(s)) })),
+			CurrentType:  causalityv1.NetworkType(enumValue(p.CurrentType, func(s string) int32 { return int32(mapNetworkType(s)) })),
+		}}
+
+	case "battery_change":
+		var p struct {
+			BatteryLevel int32           `json:"battery_level"`
+			State        json.RawMessage `json:"state"`
+		}
+		if err := unmarshalProps(props, &p); err != nil {
+			return err
+		}
+		env.Payload = &causalityv1.EventEnvelope_BatteryChange{BatteryChange: &causalityv1.BatteryChange{
+			BatteryLevel: p.BatteryLevel,
+			State:        causalityv1.BatteryState(enumValue(p.State, func(s string) int32 { return int32(mapBatteryState(s)) })),
+		}}
+
+	case "push_token_update":
+		var p causalityv1.PushTokenUpdate
+		if err := unmarshalProps(props, &p); err != nil {
+			return err
+		}
+		env.Payload = &causalityv1.EventEnvelope_PushTokenUpdate{PushTokenUpdate: &p}
+
+	case "experiment_exposure":
+		var p causalityv1.ExperimentExposure
+		if err := unmarshalProps(props, &p); err != nil {
+			return err
+		}
+		env.Payload = &causalityv1.EventEnvelope_ExperimentExposure{ExperimentExposure: &p}
+
 	case "custom":
 		ce, err := convertCustomEvent(props)
 		if err != nil {
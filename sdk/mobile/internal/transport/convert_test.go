@@ -0,0 +1,43 @@
+package transport
+
+import (
+	"testing"
+
+	causalityv1 "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+func TestConvertEvents_SystemEvents(t *testing.T) {
+	envelopes, err := convertEvents([]string{
+		`{"type":"network_change","properties":{"previous_type":"wifi","current_type":"cellular"},"metadata":{"app_id":"a"}}`,
+		`{"type":"network_change","properties":{"previous_type":7,"current_type":1},"metadata":{"app_id":"a"}}`,
+		`{"type":"battery_change","properties":{"battery_level":42,"state":"charging"},"metadata":{"app_id":"a"}}`,
+		`{"type":"app_crash","properties":{"crash_type":"anr","breadcrumbs":[{"event_type":"screen_view","timestamp_ms":1700000000000}]},"metadata":{"app_id":"a"}}`,
+	}, 0)
+	if err != nil {
+		t.Fatalf("convertEvents: %v", err)
+	}
+
+	named := envelopes[0].GetNetworkChange()
+	if named.GetPreviousType() != causalityv1.NetworkType_NETWORK_TYPE_WIFI ||
+		named.GetCurrentType() != causalityv1.NetworkType_NETWORK_TYPE_CELLULAR_4G {
+		t.Errorf("network_change by name: got %v", named)
+	}
+
+	numbered := envelopes[1].GetNetworkChange()
+	if numbered.GetPreviousType() != causalityv1.NetworkType_NETWORK_TYPE_OFFLINE ||
+		numbered.GetCurrentType() != causalityv1.NetworkType_NETWORK_TYPE_WIFI {
+		t.Errorf("network_change by number: got %v", numbered)
+	}
+
+	battery := envelopes[2].GetBatteryChange()
+	if battery.GetBatteryLevel() != 42 || battery.GetState() != causalityv1.BatteryState_BATTERY_STATE_CHARGING {
+		t.Errorf("battery_change: got %v", battery)
+	}
+
+	crash := envelopes[3].GetAppCrash()
+	if crash.GetCrashType() != "anr" || len(crash.GetBreadcrumbs()) != 1 ||
+		crash.GetBreadcrumbs()[0].GetEventType() != "screen_view" ||
+		crash.GetBreadcrumbs()[0].GetTimestampMs() != 1700000000000 {
+		t.Errorf("app_crash: got %v", crash)
+	}
+}
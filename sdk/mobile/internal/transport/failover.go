@@ -0,0 +1,110 @@
+package transport
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	causalityv1 "github.com/SebastienMelki/causality/pkg/proto/causality/v1"
+)
+
+// DefaultProbeInterval is how often a client that failed over to a fallback
+// endpoint tries its primary endpoint again.
+const DefaultProbeInterval = time.Minute
+
+// ingestEndpoint is an ingest server batches can be sent to.
+type ingestEndpoint struct {
+	url string
+	rpc causalityv1.EventServiceClient
+}
+
+// newIngestEndpoint creates an endpoint whose generated client sends through
+// httpClient.
+func newIngestEndpoint(url, apiKey string, httpClient *http.Client) *ingestEndpoint {
+	return &ingestEndpoint{
+		url: url,
+		rpc: causalityv1.NewEventServiceClient(
+			url,
+			causalityv1.WithEventServiceHTTPClient(httpClient),
+			causalityv1.WithEventServiceContentType(causalityv1.ContentTypeJSON),
+			causalityv1.WithEventServiceDefaultHeader("X-API-Key", apiKey),
+			causalityv1.WithEventServiceDefaultHeader("User-Agent", userAgent),
+		),
+	}
+}
+
+// batchURL returns the batch ingestion URL of the endpoint.
+func (e *ingestEndpoint) batchURL() string {
+	return strings.TrimSuffix(e.url, "/") + batchPath
+}
+
+// SetFallbackEndpoints adds endpoints to fail over to, in order, when the
+// primary endpoint fails with a network error or 5xx response. The client
+// sticks to the endpoint that last succeeded and tries the primary endpoint
+// again every probeInterval (DefaultProbeInterval if not positive).
+//
+// SetFallbackEndpoints must be called before the first request.
+func (c *Client) SetFallbackEndpoints(urls []string, probeInterval time.Duration) {
+	if probeInterval <= 0 {
+		probeInterval = DefaultProbeInterval
+	}
+
+	c.failoverMu.Lock()
+	defer c.failoverMu.Unlock()
+	for _, url := range urls {
+		c.endpoints = append(c.endpoints, newIngestEndpoint(url, c.apiKey, c.httpClient))
+	}
+	c.probeInterval = probeInterval
+}
+
+// ActiveEndpoint returns the URL of the endpoint batches are sent to.
+func (c *Client) ActiveEndpoint() string {
+	c.failoverMu.Lock()
+	defer c.failoverMu.Unlock()
+	return c.endpoints[c.active].url
+}
+
+// selectEndpoint returns the index of the endpoint to send the next attempt
+// to. probe is true when it is the primary endpoint being tried again while
+// a fallback is active.
+func (c *Client) selectEndpoint() (index int, probe bool) {
+	c.failoverMu.Lock()
+	defer c.failoverMu.Unlock()
+
+	if c.active != 0 && !c.now().Before(c.nextProbe) {
+		c.nextProbe = c.now().Add(c.probeInterval)
+		return 0, true
+	}
+	return c.active, false
+}
+
+// endpointSucceeded makes the endpoint at index the active endpoint.
+func (c *Client) endpointSucceeded(index int) {
+	c.failoverMu.Lock()
+	defer c.failoverMu.Unlock()
+
+	if index == c.active {
+		return
+	}
+	log.Printf("[Causality:Transport] Switched back to endpoint %s", c.endpoints[index].url)
+	c.active = index
+}
+
+// endpointFailed fails over to the next endpoint if the endpoint at index is
+// the active one. Failures of a probed primary endpoint leave the active
+// fallback in place.
+func (c *Client) endpointFailed(index int) {
+	c.failoverMu.Lock()
+	defer c.failoverMu.Unlock()
+
+	if index != c.active || len(c.endpoints) < 2 {
+		return
+	}
+	c.active = (index + 1) % len(c.endpoints)
+	if index == 0 {
+		c.nextProbe = c.now().Add(c.probeInterval)
+	}
+	log.Printf("[Causality:Transport] Endpoint %s failed, failing over to %s",
+		c.endpoints[index].url, c.endpoints[c.active].url)
+}
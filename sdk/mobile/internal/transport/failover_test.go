@@ -0,0 +1,99 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingServer answers batches with status, counting requests.
+type countingServer struct {
+	*httptest.Server
+	status   atomic.Int32
+	requests atomic.Int32
+}
+
+func newCountingServer(t *testing.T) *countingServer {
+	s := &countingServer{}
+	s.status.Store(http.StatusOK)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(int(s.status.Load()))
+		if s.status.Load() == http.StatusOK {
+			_, _ = w.Write([]byte(batchResponse(1)))
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestSendBatch_FailsOverAndProbesPrimary(t *testing.T) {
+	primary := newCountingServer(t)
+	fallback := newCountingServer(t)
+	primary.status.Store(http.StatusServiceUnavailable)
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewClient(primary.URL, "key", 5*time.Second, fastRetry)
+	c.now = func() time.Time { return now }
+	c.SetFallbackEndpoints([]string{fallback.URL}, time.Minute)
+
+	send := func() {
+		t.Helper()
+		if _, err := c.SendBatch(context.Background(), []string{testScreenViewEvent("Home")}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	send()
+	if primary.requests.Load() != 1 || fallback.requests.Load() != 1 {
+		t.Errorf("requests: primary=%d fallback=%d, want 1 and 1", primary.requests.Load(), fallback.requests.Load())
+	}
+	if got := c.ActiveEndpoint(); got != fallback.URL {
+		t.Errorf("active endpoint: got %s, want fallback %s", got, fallback.URL)
+	}
+
+	// The client sticks to the fallback until the probe interval passes.
+	send()
+	if primary.requests.Load() != 1 || fallback.requests.Load() != 2 {
+		t.Errorf("requests before probe: primary=%d fallback=%d, want 1 and 2", primary.requests.Load(), fallback.requests.Load())
+	}
+
+	// A failed probe falls back without waiting or using up an attempt.
+	now = now.Add(time.Minute)
+	send()
+	if primary.requests.Load() != 2 || fallback.requests.Load() != 3 || c.ActiveEndpoint() != fallback.URL {
+		t.Errorf("failed probe: primary=%d fallback=%d active=%s", primary.requests.Load(), fallback.requests.Load(), c.ActiveEndpoint())
+	}
+
+	// Once the primary recovers, the next probe switches back to it.
+	primary.status.Store(http.StatusOK)
+	now = now.Add(time.Minute)
+	send()
+	send()
+	if primary.requests.Load() != 4 || fallback.requests.Load() != 3 {
+		t.Errorf("recovered: primary=%d fallback=%d, want 4 and 3", primary.requests.Load(), fallback.requests.Load())
+	}
+	if got := c.ActiveEndpoint(); got != primary.URL {
+		t.Errorf("active endpoint: got %s, want primary %s", got, primary.URL)
+	}
+}
+
+func TestSendBatch_RateLimitDoesNotFailOver(t *testing.T) {
+	primary := newCountingServer(t)
+	fallback := newCountingServer(t)
+	primary.status.Store(http.StatusTooManyRequests)
+
+	c := NewClient(primary.URL, "key", 5*time.Second, fastRetry)
+	c.SetFallbackEndpoints([]string{fallback.URL}, time.Minute)
+
+	if _, err := c.SendBatch(context.Background(), []string{testScreenViewEvent("Home")}); err == nil {
+		t.Fatal("expected error after retries against a rate-limiting endpoint")
+	}
+	if fallback.requests.Load() != 0 || c.ActiveEndpoint() != primary.URL {
+		t.Errorf("fallback requests: got %d, want 0 (active %s)", fallback.requests.Load(), c.ActiveEndpoint())
+	}
+}
@@ -0,0 +1,100 @@
+package transport
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// pinPrefix is the only supported pin hash algorithm, using the same
+// "sha256/<base64 SPKI hash>" format as OkHttp and TrustKit.
+const pinPrefix = "sha256/"
+
+// ErrPinMismatch is returned when no certificate in the server's verified
+// chain matches a configured pin.
+var ErrPinMismatch = errors.New("certificate pin mismatch")
+
+// PinFailureFunc is called when a TLS connection is rejected because of a
+// pin mismatch. host is the server name that was dialed.
+type PinFailureFunc func(host string, err error)
+
+// pinSet is a set of SHA-256 hashes of DER-encoded SubjectPublicKeyInfo.
+type pinSet map[[sha256.Size]byte]struct{}
+
+// ValidatePins reports whether pins are well-formed "sha256/<base64>" pins.
+func ValidatePins(pins []string) error {
+	_, err := parsePins(pins)
+	return err
+}
+
+// parsePins parses "sha256/<base64>" public-key pins.
+func parsePins(pins []string) (pinSet, error) {
+	set := make(pinSet, len(pins))
+	for _, pin := range pins {
+		encoded, ok := strings.CutPrefix(strings.TrimSpace(pin), pinPrefix)
+		if !ok {
+			return nil, fmt.Errorf("pin %q must start with %q", pin, pinPrefix)
+		}
+		hash, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("pin %q is not a base64 SHA-256 hash", pin)
+		}
+		set[[sha256.Size]byte(hash)] = struct{}{}
+	}
+	return set, nil
+}
+
+// SetPinning restricts TLS connections to servers whose verified
+// certificate chain contains a public key matching one of pins. Standard
+// certificate verification still applies; pinning is an extra check.
+// onFailure, if not nil, is called on every rejected connection.
+//
+// An empty pins list leaves the transport unchanged. SetPinning must be
+// called before the first request.
+func (c *Client) SetPinning(pins []string, onFailure PinFailureFunc) error {
+	if len(pins) == 0 {
+		return nil
+	}
+
+	set, err := parsePins(pins)
+	if err != nil {
+		return err
+	}
+
+	base, ok := c.capture.transport.(*http.Transport)
+	if !ok {
+		return errors.New("certificate pinning requires an *http.Transport")
+	}
+	transport := base.Clone()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+		if err := set.verify(cs); err != nil {
+			if onFailure != nil {
+				onFailure(cs.ServerName, err)
+			}
+			return err
+		}
+		return nil
+	}
+
+	c.capture.transport = transport
+	return nil
+}
+
+// verify checks that a verified chain contains a pinned public key.
+func (s pinSet) verify(cs tls.ConnectionState) error {
+	for _, chain := range cs.VerifiedChains {
+		for _, cert := range chain {
+			if _, ok := s[sha256.Sum256(cert.RawSubjectPublicKeyInfo)]; ok {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w for %s", ErrPinMismatch, cs.ServerName)
+}
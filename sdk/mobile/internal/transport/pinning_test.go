@@ -0,0 +1,107 @@
+package transport
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTLSBatchServer returns a TLS server that accepts every batch and a
+// client trusting its certificate.
+func newTLSBatchServer(t *testing.T, requests *atomic.Int32) (*httptest.Server, *Client) {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(batchResponse(1)))
+	}))
+	t.Cleanup(server.Close)
+
+	c := NewClient(server.URL, "key", 5*time.Second, fastRetry)
+	c.capture.transport = server.Client().Transport
+	return server, c
+}
+
+// serverPin returns the pin of the server's leaf certificate.
+func serverPin(server *httptest.Server) string {
+	hash := sha256.Sum256(server.Certificate().RawSubjectPublicKeyInfo)
+	return pinPrefix + base64.StdEncoding.EncodeToString(hash[:])
+}
+
+func TestValidatePins(t *testing.T) {
+	valid := pinPrefix + base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	tests := []struct {
+		name    string
+		pins    []string
+		wantErr bool
+	}{
+		{name: "empty", pins: nil},
+		{name: "valid", pins: []string{valid}},
+		{name: "missing prefix", pins: []string{base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))}, wantErr: true},
+		{name: "sha1", pins: []string{"sha1/" + base64.StdEncoding.EncodeToString(make([]byte, 20))}, wantErr: true},
+		{name: "not base64", pins: []string{pinPrefix + "!!!"}, wantErr: true},
+		{name: "wrong length", pins: []string{pinPrefix + base64.StdEncoding.EncodeToString(make([]byte, 16))}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePins(tt.pins)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePins() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSendBatch_PinMatch(t *testing.T) {
+	var requests atomic.Int32
+	server, c := newTLSBatchServer(t, &requests)
+
+	if err := c.SetPinning([]string{serverPin(server)}, nil); err != nil {
+		t.Fatalf("SetPinning: %v", err)
+	}
+
+	result, err := c.SendBatch(context.Background(), []string{testScreenViewEvent("home")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Accepted != 1 {
+		t.Errorf("Accepted: got %d, want 1", result.Accepted)
+	}
+}
+
+func TestSendBatch_PinMismatch(t *testing.T) {
+	var requests atomic.Int32
+	_, c := newTLSBatchServer(t, &requests)
+
+	var failures atomic.Int32
+	otherPin := pinPrefix + base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+	err := c.SetPinning([]string{otherPin}, func(host string, err error) {
+		failures.Add(1)
+		if !errors.Is(err, ErrPinMismatch) {
+			t.Errorf("callback error: got %v, want ErrPinMismatch", err)
+		}
+	})
+	if err != nil {
+		t.Fatalf("SetPinning: %v", err)
+	}
+
+	_, err = c.SendBatch(context.Background(), []string{testScreenViewEvent("home")})
+	if !errors.Is(err, ErrPinMismatch) {
+		t.Fatalf("error: got %v, want ErrPinMismatch", err)
+	}
+	if got := failures.Load(); got != 1 {
+		t.Errorf("failure callbacks: got %d, want 1 (pin mismatches are not retried)", got)
+	}
+	if got := requests.Load(); got != 0 {
+		t.Errorf("requests reaching server: got %d, want 0", got)
+	}
+}
@@ -18,7 +18,7 @@ CREATE EXTERNAL TABLE IF NOT EXISTS events (
   correlation_id STRING COMMENT 'Optional correlation ID for request tracing',
 
   -- Event type information
-  event_category STRING COMMENT 'Event category (user, screen, interaction, commerce, system, custom)',
+  event_category STRING COMMENT 'Event category (user, screen, interaction, commerce, system, experiment, custom)',
   event_type STRING COMMENT 'Specific event type within category',
 
   -- Device context fields
@@ -37,6 +37,19 @@ CREATE EXTERNAL TABLE IF NOT EXISTS events (
   is_jailbroken BOOLEAN COMMENT 'Whether device is jailbroken/rooted',
   is_emulator BOOLEAN COMMENT 'Whether device is an emulator',
   sdk_version STRING COMMENT 'SDK version used',
+  clock_skew_ms BIGINT COMMENT 'Device clock minus server time in ms, as estimated by the SDK',
+  sdk_name STRING COMMENT 'SDK name, from the X-Causality-SDK handshake header',
+
+  -- Geo enrichment (GEO_ENABLED)
+  country STRING COMMENT 'ISO 3166-1 alpha-2 country from device timezone or locale',
+  region STRING COMMENT 'Continental region of country',
+
+  -- Revenue normalization (FX_ENABLED)
+  amount_usd DOUBLE COMMENT 'purchase_complete total converted to USD',
+
+  -- Experiment exposures
+  experiment_id STRING COMMENT 'experiment_exposure experiment or feature flag key',
+  experiment_variant STRING COMMENT 'experiment_exposure variant',
 
   -- Payload as JSON
   payload_json STRING COMMENT 'Event payload serialized as JSON'
@@ -64,6 +77,50 @@ TBLPROPERTIES (
   'projection.hour.range' = '0,23'
 );
 
+-- Aggregate rollups written by the warehouse sink (ROLLUP_ENABLED)
+-- Rows with null event_category/event_type total a platform; the row with a
+-- null platform totals the app
+CREATE EXTERNAL TABLE IF NOT EXISTS rollups_hourly (
+  granularity STRING COMMENT 'hour',
+  window_start_ms BIGINT COMMENT 'Start of the hour in milliseconds since Unix epoch',
+  event_category STRING COMMENT 'Event category, null in total rows',
+  event_type STRING COMMENT 'Event type, null in total rows',
+  platform STRING COMMENT 'Platform (unknown without device context), null in the app total row',
+  events BIGINT COMMENT 'Number of events',
+  revenue_usd DOUBLE COMMENT 'Sum of amount_usd',
+  unique_devices BIGINT COMMENT 'Estimated distinct devices (HyperLogLog)',
+  device_sketch BINARY COMMENT 'HyperLogLog sketch of device IDs'
+)
+PARTITIONED BY (
+  app_id STRING COMMENT 'Application identifier',
+  year INT COMMENT 'Year',
+  month INT COMMENT 'Month',
+  day INT COMMENT 'Day',
+  hour INT COMMENT 'Hour'
+)
+STORED AS PARQUET
+LOCATION 's3a://causality-events/rollups/hourly/';
+
+CREATE EXTERNAL TABLE IF NOT EXISTS rollups_daily (
+  granularity STRING COMMENT 'day',
+  window_start_ms BIGINT COMMENT 'Start of the day in milliseconds since Unix epoch',
+  event_category STRING COMMENT 'Event category, null in total rows',
+  event_type STRING COMMENT 'Event type, null in total rows',
+  platform STRING COMMENT 'Platform (unknown without device context), null in the app total row',
+  events BIGINT COMMENT 'Number of events',
+  revenue_usd DOUBLE COMMENT 'Sum of amount_usd',
+  unique_devices BIGINT COMMENT 'Estimated distinct devices (HyperLogLog)',
+  device_sketch BINARY COMMENT 'HyperLogLog sketch of device IDs'
+)
+PARTITIONED BY (
+  app_id STRING COMMENT 'Application identifier',
+  year INT COMMENT 'Year',
+  month INT COMMENT 'Month',
+  day INT COMMENT 'Day'
+)
+STORED AS PARQUET
+LOCATION 's3a://causality-events/rollups/daily/';
+
 -- Repair partitions (discovers existing partitions in S3)
 -- Run this after data has been written
 -- MSCK REPAIR TABLE events;
@@ -95,6 +152,14 @@ TBLPROPERTIES (
 -- GROUP BY platform, device_model
 -- ORDER BY count DESC;
 
+-- Analyze country distribution (requires GEO_ENABLED)
+-- SELECT region, country, COUNT(DISTINCT device_id) as devices
+-- FROM events
+-- WHERE app_id = 'myapp'
+--   AND year = 2024
+-- GROUP BY region, country
+-- ORDER BY devices DESC;
+
 -- Extract data from JSON payload
 -- SELECT id, timestamp_ms,
 --        get_json_object(payload_json, '$.screen_name') as screen_name
@@ -102,3 +167,10 @@ TBLPROPERTIES (
 -- WHERE app_id = 'myapp'
 --   AND event_category = 'screen'
 --   AND event_type = 'view';
+
+-- Daily active devices per app from rollups (ROLLUP_ENABLED)
+-- SELECT year, month, day, unique_devices AS dau
+-- FROM rollups_daily
+-- WHERE app_id = 'myapp'
+--   AND platform IS NULL
+-- ORDER BY year, month, day;